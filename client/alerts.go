@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// AlertRequest creates an alert. Condition is one of price_above, price_below,
+// percent_move, rsi_above, rsi_below, volume_spike or news_mention.
+type AlertRequest struct {
+	Symbol          string  `json:"symbol"`
+	Condition       string  `json:"condition"`
+	Threshold       float64 `json:"threshold,omitempty"`
+	WindowMinutes   int     `json:"window_minutes,omitempty"`
+	Keyword         string  `json:"keyword,omitempty"`
+	Strategy        string  `json:"strategy,omitempty"`
+	Repeat          bool    `json:"repeat,omitempty"`
+	CooldownMinutes int     `json:"cooldown_minutes,omitempty"`
+	Note            string  `json:"note,omitempty"`
+}
+
+// Alert is a condition watched for one symbol
+type Alert struct {
+	ID              string     `json:"id"`
+	Symbol          string     `json:"symbol"`
+	Condition       string     `json:"condition"`
+	Threshold       float64    `json:"threshold"`
+	WindowMinutes   int        `json:"window_minutes,omitempty"`
+	Keyword         string     `json:"keyword,omitempty"`
+	Strategy        string     `json:"strategy,omitempty"`
+	Repeat          bool       `json:"repeat"`
+	CooldownMinutes int        `json:"cooldown_minutes,omitempty"`
+	Status          string     `json:"status"`
+	TriggerCount    int        `json:"trigger_count"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+	LastValue       float64    `json:"last_value"`
+	Note            string     `json:"note,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// AlertSignal is the decision of the strategy hook attached to an alert
+type AlertSignal struct {
+	Strategy string `json:"strategy"`
+	Action   string `json:"action"`
+	Error    string `json:"error,omitempty"`
+}
+
+// AlertEvent records one firing of an alert
+type AlertEvent struct {
+	AlertID   string       `json:"alert_id"`
+	Symbol    string       `json:"symbol"`
+	Condition string       `json:"condition"`
+	Value     float64      `json:"value"`
+	Message   string       `json:"message"`
+	Timestamp time.Time    `json:"timestamp"`
+	Signal    *AlertSignal `json:"signal,omitempty"`
+}
+
+// CreateAlert registers an alert (POST /alerts)
+func (c *Client) CreateAlert(ctx context.Context, req *AlertRequest) (*Alert, error) {
+	var alert Alert
+	if err := c.post(ctx, "/alerts", req, &alert); err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// ListAlerts lists alerts, optionally filtered by status (GET /alerts)
+func (c *Client) ListAlerts(ctx context.Context, status string) ([]*Alert, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	var resp struct {
+		Count  int      `json:"count"`
+		Alerts []*Alert `json:"alerts"`
+	}
+	if err := c.get(ctx, "/alerts", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Alerts, nil
+}
+
+// GetAlert returns a single alert (GET /alerts/:id)
+func (c *Client) GetAlert(ctx context.Context, alertID string) (*Alert, error) {
+	var alert Alert
+	if err := c.get(ctx, "/alerts/"+url.PathEscape(alertID), nil, &alert); err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// DeleteAlert removes an alert (DELETE /alerts/:id)
+func (c *Client) DeleteAlert(ctx context.Context, alertID string) error {
+	return c.delete(ctx, "/alerts/"+url.PathEscape(alertID), nil)
+}
+
+// ListAlertEvents returns recent alert firings, newest first (GET /alerts/events)
+func (c *Client) ListAlertEvents(ctx context.Context, limit int) ([]AlertEvent, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp struct {
+		Count  int          `json:"count"`
+		Events []AlertEvent `json:"events"`
+	}
+	if err := c.get(ctx, "/alerts/events", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// NewsRuleRequest creates or previews a news rule. Direction is bullish,
+// bearish or any; Action is alert or strategy.
+type NewsRuleRequest struct {
+	Name            string   `json:"name,omitempty"`
+	Symbols         []string `json:"symbols"`
+	Keywords        []string `json:"keywords,omitempty"`
+	Direction       string   `json:"direction,omitempty"`
+	MinSentiment    float64  `json:"min_sentiment"`
+	Action          string   `json:"action,omitempty"`
+	Strategy        string   `json:"strategy,omitempty"`
+	CooldownMinutes int      `json:"cooldown_minutes,omitempty"`
+	DryRun          bool     `json:"dry_run,omitempty"`
+}
+
+// NewsRule matches breaking headlines about watched symbols
+type NewsRule struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name,omitempty"`
+	Symbols         []string   `json:"symbols"`
+	Keywords        []string   `json:"keywords,omitempty"`
+	Direction       string     `json:"direction"`
+	MinSentiment    float64    `json:"min_sentiment"`
+	Action          string     `json:"action"`
+	Strategy        string     `json:"strategy,omitempty"`
+	CooldownMinutes int        `json:"cooldown_minutes"`
+	DryRun          bool       `json:"dry_run"`
+	TriggerCount    int        `json:"trigger_count"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// NewsRuleMatch is one headline a news rule matched
+type NewsRuleMatch struct {
+	RuleID         string       `json:"rule_id"`
+	Symbol         string       `json:"symbol"`
+	Title          string       `json:"title"`
+	Link           string       `json:"link"`
+	Source         string       `json:"source,omitempty"`
+	Sentiment      float64      `json:"sentiment"`
+	Keyword        string       `json:"keyword,omitempty"`
+	PublishedAt    time.Time    `json:"published_at"`
+	MatchedAt      time.Time    `json:"matched_at"`
+	LatencySeconds float64      `json:"latency_seconds"`
+	Action         string       `json:"action"`
+	DryRun         bool         `json:"dry_run"`
+	Suppressed     string       `json:"suppressed,omitempty"`
+	Signal         *AlertSignal `json:"signal,omitempty"`
+}
+
+// CreateNewsRule registers a news rule (POST /alerts/news-rules)
+func (c *Client) CreateNewsRule(ctx context.Context, req *NewsRuleRequest) (*NewsRule, error) {
+	var rule NewsRule
+	if err := c.post(ctx, "/alerts/news-rules", req, &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListNewsRules lists news rules (GET /alerts/news-rules)
+func (c *Client) ListNewsRules(ctx context.Context) ([]*NewsRule, error) {
+	var resp struct {
+		Count int         `json:"count"`
+		Rules []*NewsRule `json:"rules"`
+	}
+	if err := c.get(ctx, "/alerts/news-rules", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Rules, nil
+}
+
+// DeleteNewsRule removes a news rule (DELETE /alerts/news-rules/:id)
+func (c *Client) DeleteNewsRule(ctx context.Context, ruleID string) error {
+	return c.delete(ctx, "/alerts/news-rules/"+url.PathEscape(ruleID), nil)
+}
+
+// PreviewNewsRule runs req over the stored headlines of the last
+// lookbackHours without saving it (POST /alerts/news-rules/preview)
+func (c *Client) PreviewNewsRule(ctx context.Context, req *NewsRuleRequest, lookbackHours int) ([]NewsRuleMatch, error) {
+	path := "/alerts/news-rules/preview"
+	if lookbackHours > 0 {
+		path += "?lookback_hours=" + strconv.Itoa(lookbackHours)
+	}
+
+	var resp struct {
+		Count   int             `json:"count"`
+		Matches []NewsRuleMatch `json:"matches"`
+	}
+	if err := c.post(ctx, path, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Matches, nil
+}
+
+// ListNewsRuleMatches returns recent news rule matches, newest first
+// (GET /alerts/news-rules/events)
+func (c *Client) ListNewsRuleMatches(ctx context.Context, limit int) ([]NewsRuleMatch, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp struct {
+		Count   int             `json:"count"`
+		Matches []NewsRuleMatch `json:"matches"`
+	}
+	if err := c.get(ctx, "/alerts/news-rules/events", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Matches, nil
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AlgoOrderRequest creates a TWAP or VWAP algo order. VWAP orders need
+// MaxParticipationPct; TWAP orders default to one slice a minute.
+type AlgoOrderRequest struct {
+	Symbol              string           `json:"symbol"`
+	Side                string           `json:"side"`
+	Qty                 decimal.Decimal  `json:"qty"`
+	Algo                string           `json:"algo"` // twap or vwap
+	DurationMinutes     int              `json:"duration_minutes"`
+	Slices              int              `json:"slices,omitempty"`
+	MaxParticipationPct float64          `json:"max_participation_pct,omitempty"`
+	LimitPrice          *decimal.Decimal `json:"limit_price,omitempty"`
+	StartAt             *time.Time       `json:"start_at,omitempty"`
+	DryRun              bool             `json:"dry_run,omitempty"`
+}
+
+// AlgoOrder is a parent order with its execution progress. It is also the
+// data of an algo_progress event.
+type AlgoOrder struct {
+	ID                  string           `json:"id"`
+	Symbol              string           `json:"symbol"`
+	Side                string           `json:"side"`
+	Qty                 decimal.Decimal  `json:"qty"`
+	Algo                string           `json:"algo"`
+	LimitPrice          *decimal.Decimal `json:"limit_price,omitempty"`
+	MaxParticipationPct float64          `json:"max_participation_pct,omitempty"`
+	Slices              int              `json:"slices,omitempty"`
+	Book                string           `json:"book"`
+	Status              string           `json:"status"`
+	Reason              string           `json:"reason,omitempty"`
+	StartAt             time.Time        `json:"start_at"`
+	EndAt               time.Time        `json:"end_at"`
+	NextSliceAt         *time.Time       `json:"next_slice_at,omitempty"`
+	ArrivalPrice        decimal.Decimal  `json:"arrival_price"`
+	FilledQty           decimal.Decimal  `json:"filled_qty"`
+	AvgPrice            decimal.Decimal  `json:"avg_price"`
+	WorkingQty          decimal.Decimal  `json:"working_qty"`
+	RemainingQty        decimal.Decimal  `json:"remaining_qty"`
+	PercentComplete     float64          `json:"percent_complete"`
+	SlippageBps         float64          `json:"slippage_bps"`
+	ChildOrders         int              `json:"child_orders"`
+	Skipped             int              `json:"skipped"`
+	CreatedAt           time.Time        `json:"created_at"`
+	Children            []*AlgoSlice     `json:"children,omitempty"`
+}
+
+// AlgoSlice is one child order of an algo order
+type AlgoSlice struct {
+	Status         string           `json:"status"`
+	Qty            decimal.Decimal  `json:"qty"`
+	MarketVolume   decimal.Decimal  `json:"market_volume"`
+	OrderID        string           `json:"order_id,omitempty"`
+	FilledQty      decimal.Decimal  `json:"filled_qty"`
+	FilledAvgPrice *decimal.Decimal `json:"filled_avg_price,omitempty"`
+	Reason         string           `json:"reason,omitempty"`
+	Timestamp      time.Time        `json:"timestamp"`
+}
+
+// CreateAlgoOrder starts working an algo order (POST /algo/orders)
+func (c *Client) CreateAlgoOrder(ctx context.Context, req *AlgoOrderRequest) (*AlgoOrder, error) {
+	var order AlgoOrder
+	if err := c.post(ctx, "/algo/orders", req, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ListAlgoOrders lists algo orders, optionally filtered by status (GET /algo/orders)
+func (c *Client) ListAlgoOrders(ctx context.Context, status string) ([]*AlgoOrder, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	var resp struct {
+		Count  int          `json:"count"`
+		Orders []*AlgoOrder `json:"orders"`
+	}
+	if err := c.get(ctx, "/algo/orders", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Orders, nil
+}
+
+// GetAlgoOrder returns an algo order with its child orders (GET /algo/orders/:id)
+func (c *Client) GetAlgoOrder(ctx context.Context, id string) (*AlgoOrder, error) {
+	var order AlgoOrder
+	if err := c.get(ctx, "/algo/orders/"+url.PathEscape(id), nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CancelAlgoOrder stops an algo order and cancels its open child order
+// (DELETE /algo/orders/:id)
+func (c *Client) CancelAlgoOrder(ctx context.Context, id string) (*AlgoOrder, error) {
+	var order AlgoOrder
+	if err := c.delete(ctx, "/algo/orders/"+url.PathEscape(id), &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// VolumeLevel is the traded volume within one price bin
+type VolumeLevel struct {
+	PriceLow  float64 `json:"price_low"`
+	PriceHigh float64 `json:"price_high"`
+	Volume    float64 `json:"volume"`
+	Percent   float64 `json:"percent"`
+	InValue   bool    `json:"in_value_area"`
+}
+
+// VWAPPoint is the session VWAP and its bands at one bar
+type VWAPPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Close     float64   `json:"close"`
+	VWAP      float64   `json:"vwap"`
+	StdDev    float64   `json:"std_dev"`
+	Upper1    float64   `json:"upper_1"`
+	Lower1    float64   `json:"lower_1"`
+	Upper2    float64   `json:"upper_2"`
+	Lower2    float64   `json:"lower_2"`
+	Upper3    float64   `json:"upper_3"`
+	Lower3    float64   `json:"lower_3"`
+}
+
+// VolumeProfile is returned by GET /analysis/:symbol/volume-profile
+type VolumeProfile struct {
+	Symbol        string        `json:"symbol"`
+	Session       string        `json:"session"`
+	Timeframe     string        `json:"timeframe"`
+	Bars          int           `json:"bars"`
+	TotalVolume   float64       `json:"total_volume"`
+	CurrentPrice  float64       `json:"current_price"`
+	POC           float64       `json:"poc"`
+	ValueAreaHigh float64       `json:"value_area_high"`
+	ValueAreaLow  float64       `json:"value_area_low"`
+	ValueAreaPct  float64       `json:"value_area_percent"`
+	VWAP          *VWAPPoint    `json:"vwap"`
+	PricePosition string        `json:"price_position"`
+	VWAPPosition  string        `json:"vwap_position"`
+	Levels        []VolumeLevel `json:"levels"`
+	VWAPSeries    []VWAPPoint   `json:"vwap_series"`
+}
+
+// VolumeProfileOptions are optional query parameters; zero values use server defaults
+type VolumeProfileOptions struct {
+	Date      time.Time
+	Timeframe string
+	Bins      int
+	ValueArea float64
+	Extended  bool
+}
+
+// GetVolumeProfile returns the intraday volume profile and VWAP bands for a symbol
+func (c *Client) GetVolumeProfile(ctx context.Context, symbol string, opts VolumeProfileOptions) (*VolumeProfile, error) {
+	query := url.Values{}
+	if !opts.Date.IsZero() {
+		query.Set("date", opts.Date.Format("2006-01-02"))
+	}
+	if opts.Timeframe != "" {
+		query.Set("timeframe", opts.Timeframe)
+	}
+	if opts.Bins > 0 {
+		query.Set("bins", strconv.Itoa(opts.Bins))
+	}
+	if opts.ValueArea > 0 {
+		query.Set("value_area", strconv.FormatFloat(opts.ValueArea, 'f', -1, 64))
+	}
+	if opts.Extended {
+		query.Set("extended", "true")
+	}
+
+	var profile VolumeProfile
+	if err := c.get(ctx, "/analysis/"+url.PathEscape(symbol)+"/volume-profile", query, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// CandlestickPattern is a single- or two-bar candlestick formation
+type CandlestickPattern struct {
+	Name      string    `json:"name"`
+	Direction string    `json:"direction"`
+	Timestamp time.Time `json:"timestamp"`
+	BarsAgo   int       `json:"bars_ago"`
+}
+
+// ChartPattern is a multi-bar price formation such as a breakout or double top
+type ChartPattern struct {
+	Name        string    `json:"name"`
+	Direction   string    `json:"direction"`
+	Level       float64   `json:"level"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Confirmed   bool      `json:"confirmed"`
+	Description string    `json:"description"`
+}
+
+// PriceLevel is a support or resistance level
+type PriceLevel struct {
+	Type        string    `json:"type"`
+	Price       float64   `json:"price"`
+	Touches     int       `json:"touches"`
+	LastTouched time.Time `json:"last_touched"`
+	DistancePct float64   `json:"distance_percent"`
+}
+
+// PatternAnalysis is returned by GET /analysis/:symbol/patterns
+type PatternAnalysis struct {
+	Symbol        string               `json:"symbol"`
+	Timeframe     string               `json:"timeframe"`
+	Bars          int                  `json:"bars"`
+	CurrentPrice  float64              `json:"current_price"`
+	Candlesticks  []CandlestickPattern `json:"candlesticks"`
+	ChartPatterns []ChartPattern       `json:"chart_patterns"`
+	Levels        []PriceLevel         `json:"levels"`
+}
+
+// GetPatterns returns detected candlestick and chart patterns for a symbol;
+// empty timeframe and zero days use server defaults
+func (c *Client) GetPatterns(ctx context.Context, symbol, timeframe string, days int) (*PatternAnalysis, error) {
+	query := url.Values{}
+	if timeframe != "" {
+		query.Set("timeframe", timeframe)
+	}
+	if days > 0 {
+		query.Set("days", strconv.Itoa(days))
+	}
+
+	var analysis PatternAnalysis
+	if err := c.get(ctx, "/analysis/"+url.PathEscape(symbol)+"/patterns", query, &analysis); err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+// StrengthPeriod is a symbol's performance over one lookback in trading days
+type StrengthPeriod struct {
+	Days            int      `json:"days"`
+	ReturnPct       float64  `json:"return_percent"`
+	MarketReturnPct float64  `json:"market_return_percent"`
+	VsMarketPct     float64  `json:"vs_market_percent"`
+	SectorReturnPct *float64 `json:"sector_return_percent,omitempty"`
+	VsSectorPct     *float64 `json:"vs_sector_percent,omitempty"`
+}
+
+// RelativeStrength is returned by GET /analysis/:symbol/relative-strength
+type RelativeStrength struct {
+	Symbol        string           `json:"symbol"`
+	Sector        string           `json:"sector"`
+	SectorETF     string           `json:"sector_etf,omitempty"`
+	Price         float64          `json:"price"`
+	Periods       []StrengthPeriod `json:"periods"`
+	MomentumScore float64          `json:"momentum_score"`
+}
+
+// SectorMomentum is one ranked sector in the rotation dashboard
+type SectorMomentum struct {
+	Rank          int              `json:"rank"`
+	Sector        string           `json:"sector"`
+	ETF           string           `json:"etf"`
+	Price         float64          `json:"price"`
+	Periods       []StrengthPeriod `json:"periods"`
+	MomentumScore float64          `json:"momentum_score"`
+	Quadrant      string           `json:"quadrant"`
+}
+
+// SectorRotation is returned by GET /analysis/sectors
+type SectorRotation struct {
+	Benchmark   string           `json:"benchmark"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Sectors     []SectorMomentum `json:"sectors"`
+	Skipped     []string         `json:"skipped,omitempty"`
+}
+
+// GetRelativeStrength returns a symbol's strength versus its sector ETF and SPY
+func (c *Client) GetRelativeStrength(ctx context.Context, symbol string) (*RelativeStrength, error) {
+	var rs RelativeStrength
+	if err := c.get(ctx, "/analysis/"+url.PathEscape(symbol)+"/relative-strength", nil, &rs); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// GetSectorRotation returns sector ETFs ranked by momentum relative to SPY
+func (c *Client) GetSectorRotation(ctx context.Context) (*SectorRotation, error) {
+	var rotation SectorRotation
+	if err := c.get(ctx, "/analysis/sectors", nil, &rotation); err != nil {
+		return nil, err
+	}
+	return &rotation, nil
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// API roles
+const (
+	RoleViewer = "viewer"
+	RoleTrader = "trader"
+	RoleAdmin  = "admin"
+)
+
+// Principal is the caller identified by an API token
+type Principal struct {
+	TokenID string `json:"token_id"`
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+}
+
+// WhoAmIResponse is returned by GET /auth/me
+type WhoAmIResponse struct {
+	AuthEnabled bool       `json:"auth_enabled"`
+	Principal   *Principal `json:"principal,omitempty"`
+}
+
+// APIToken describes an issued token. Token is only set when it is created.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	Prefix     string     `json:"prefix"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIAudit is one recorded state-changing API call
+type APIAudit struct {
+	ID          uint      `json:"ID"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	TokenID     string    `json:"TokenID"`
+	Caller      string    `json:"Caller"`
+	Role        string    `json:"Role"`
+	ClientIP    string    `json:"ClientIP"`
+	Method      string    `json:"Method"`
+	Route       string    `json:"Route"`
+	Path        string    `json:"Path"`
+	PayloadHash string    `json:"PayloadHash"` // hex SHA-256 of the request body
+	PayloadSize int64     `json:"PayloadSize"`
+	Status      int       `json:"Status"`
+	ErrorCode   string    `json:"ErrorCode"`
+	DurationMs  int64     `json:"DurationMs"`
+}
+
+// APIAuditQuery filters the API audit log. Zero fields match everything.
+type APIAuditQuery struct {
+	TokenID  string
+	Method   string
+	Route    string // route pattern, e.g. /api/v1/orders/:id
+	Failed   bool
+	Since    time.Time
+	BeforeID uint // records older than this one, for paging
+	Limit    int
+}
+
+// WhoAmI returns the caller's token name and role (GET /auth/me)
+func (c *Client) WhoAmI(ctx context.Context) (*WhoAmIResponse, error) {
+	var resp WhoAmIResponse
+	if err := c.get(ctx, "/auth/me", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateAPIToken issues a token for name with a role (POST /admin/tokens).
+// The returned token secret cannot be retrieved again.
+func (c *Client) CreateAPIToken(ctx context.Context, name, role string) (*APIToken, error) {
+	var token APIToken
+	if err := c.post(ctx, "/admin/tokens", map[string]string{"name": name, "role": role}, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListAPITokens lists issued tokens without their secrets (GET /admin/tokens)
+func (c *Client) ListAPITokens(ctx context.Context) ([]*APIToken, error) {
+	var resp struct {
+		Tokens []*APIToken `json:"tokens"`
+	}
+	if err := c.get(ctx, "/admin/tokens", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Tokens, nil
+}
+
+// RevokeAPIToken revokes a token (DELETE /admin/tokens/:id)
+func (c *Client) RevokeAPIToken(ctx context.Context, id string) error {
+	return c.delete(ctx, "/admin/tokens/"+url.PathEscape(id), nil)
+}
+
+// ListAPIAudit returns state-changing API calls matching q, newest first
+// (GET /admin/audit). Pass the ID of the last record as BeforeID for the
+// next page.
+func (c *Client) ListAPIAudit(ctx context.Context, q APIAuditQuery) ([]*APIAudit, error) {
+	query := url.Values{}
+	if q.TokenID != "" {
+		query.Set("token", q.TokenID)
+	}
+	if q.Method != "" {
+		query.Set("method", q.Method)
+	}
+	if q.Route != "" {
+		query.Set("route", q.Route)
+	}
+	if q.Failed {
+		query.Set("failed", "true")
+	}
+	if !q.Since.IsZero() {
+		query.Set("since", q.Since.Format(time.RFC3339))
+	}
+	if q.BeforeID > 0 {
+		query.Set("before_id", strconv.FormatUint(uint64(q.BeforeID), 10))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	var resp struct {
+		Audits []*APIAudit `json:"audits"`
+	}
+	if err := c.get(ctx, "/admin/audit", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Audits, nil
+}
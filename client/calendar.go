@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// EconomicEvent is a scheduled macro release or central bank event
+type EconomicEvent struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Country  string    `json:"country"`
+	Impact   string    `json:"impact"`
+	Time     time.Time `json:"time"`
+	Forecast string    `json:"forecast,omitempty"`
+	Previous string    `json:"previous,omitempty"`
+	Actual   string    `json:"actual,omitempty"`
+	Source   string    `json:"source"`
+}
+
+// EconomicCalendarResponse is returned by GET /calendar/economic
+type EconomicCalendarResponse struct {
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Count  int              `json:"count"`
+	Events []*EconomicEvent `json:"events"`
+}
+
+// GetEconomicCalendar returns US economic events for the next days days
+// (GET /calendar/economic). impact is the minimum level ("high", "medium",
+// "low") and may be empty.
+func (c *Client) GetEconomicCalendar(ctx context.Context, days int, impact string) (*EconomicCalendarResponse, error) {
+	query := url.Values{}
+	if days > 0 {
+		query.Set("days", strconv.Itoa(days))
+	}
+	if impact != "" {
+		query.Set("impact", impact)
+	}
+
+	var resp EconomicCalendarResponse
+	if err := c.get(ctx, "/calendar/economic", query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,328 @@
+// Package client is a typed Go SDK for the Prophet Trader HTTP API.
+//
+// It mirrors every REST endpoint exposed under /api/v1 so external tooling can
+// talk to a running bot without maintaining its own request/response structs:
+//
+//	c := client.New("http://localhost:4534")
+//	account, err := c.GetAccount(ctx)
+//
+// Idempotent requests (GET/DELETE) are retried on network errors, 429s and 5xx
+// responses; order placement is never retried to avoid duplicate submissions.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the address the bot listens on by default
+	DefaultBaseURL = "http://localhost:4534"
+
+	apiPrefix = "/api/v1"
+)
+
+// Client talks to a Prophet Trader bot over HTTP
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+	userAgent    string
+	orderSource  string
+	book         string
+	token        string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient replaces the underlying HTTP client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTimeout sets the per-request timeout of the underlying HTTP client
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithRetries sets how many times idempotent requests are retried and the
+// initial backoff between attempts (doubled after each failure)
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryBackoff = backoff
+	}
+}
+
+// WithUserAgent overrides the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithOrderSource sets the X-Order-Source header recorded in the order audit
+// trail ("manual", "webhook", "strategy", "ai")
+func WithOrderSource(source string) Option {
+	return func(c *Client) {
+		c.orderSource = source
+	}
+}
+
+// WithBook sets the X-Book header, so orders and positions are placed in
+// that book and lists and reports only cover it
+func WithBook(book string) Option {
+	return func(c *Client) {
+		c.book = book
+	}
+}
+
+// WithToken sets the API token sent as a bearer token with every request
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New creates a new API client. An empty baseURL uses DefaultBaseURL.
+func New(baseURL string, opts ...Option) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		maxRetries:   2,
+		retryBackoff: 500 * time.Millisecond,
+		userAgent:    "prophet-trader-go-client",
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// APIError is returned when the bot responds with a non-2xx status
+type APIError struct {
+	StatusCode int           `json:"-"`
+	Code       string        `json:"code"`
+	Message    string        `json:"error"`
+	Details    string        `json:"details,omitempty"`
+	Fields     []FieldError  `json:"fields,omitempty"` // invalid request body fields
+	RetryAfter time.Duration `json:"-"`                // from the Retry-After header of 429 responses
+}
+
+// FieldError describes one invalid field of a rejected request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error codes reported in APIError.Code
+const (
+	ErrCodeInvalidRequest          = "INVALID_REQUEST"
+	ErrCodeUnauthorized            = "UNAUTHORIZED"
+	ErrCodeForbidden               = "FORBIDDEN"
+	ErrCodeNotFound                = "NOT_FOUND"
+	ErrCodeRateLimited             = "RATE_LIMITED"
+	ErrCodeRequestTooLarge         = "REQUEST_TOO_LARGE"
+	ErrCodeOrderRejected           = "ORDER_REJECTED"
+	ErrCodeBrokerRejected          = "BROKER_REJECTED"
+	ErrCodeInsufficientBuyingPower = "INSUFFICIENT_BUYING_POWER"
+	ErrCodeMarketClosed            = "MARKET_CLOSED"
+	ErrCodeUpstreamRateLimited     = "UPSTREAM_RATE_LIMITED"
+	ErrCodeUpstreamError           = "UPSTREAM_ERROR"
+	ErrCodeNotSupported            = "NOT_SUPPORTED"
+	ErrCodeUnavailable             = "SERVICE_UNAVAILABLE"
+	ErrCodeInternal                = "INTERNAL_ERROR"
+)
+
+func (e *APIError) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("api error (HTTP %d): %s: %s", e.StatusCode, e.Message, e.Details)
+	}
+	return fmt.Sprintf("api error (HTTP %d): %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an APIError with status 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// ErrorCode returns the error code of an APIError, or "" for other errors
+func ErrorCode(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// get issues a GET request against an /api/v1 path
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, apiPrefix+path, query, nil, out)
+}
+
+// post issues a POST request against an /api/v1 path
+func (c *Client) post(ctx context.Context, path string, body, out interface{}) error {
+	return c.do(ctx, http.MethodPost, apiPrefix+path, nil, body, out)
+}
+
+// put issues a PUT request against an /api/v1 path
+func (c *Client) put(ctx context.Context, path string, body, out interface{}) error {
+	return c.do(ctx, http.MethodPut, apiPrefix+path, nil, body, out)
+}
+
+// delete issues a DELETE request against an /api/v1 path
+func (c *Client) delete(ctx context.Context, path string, out interface{}) error {
+	return c.do(ctx, http.MethodDelete, apiPrefix+path, nil, nil, out)
+}
+
+// do executes a request, retrying idempotent methods on transient failures
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		payload = data
+	}
+
+	attempts := 1
+	if method == http.MethodGet || method == http.MethodDelete {
+		attempts += c.maxRetries
+	}
+
+	backoff := c.retryBackoff
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		retry, err := c.doOnce(ctx, method, path, query, payload, out)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+
+		// Wait at least as long as a rate-limited response asks
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > backoff {
+			backoff = apiErr.RetryAfter
+		}
+	}
+
+	return lastErr
+}
+
+// doOnce performs a single HTTP round trip and reports whether a failure is retryable
+func (c *Client) doOnce(ctx context.Context, method, path string, query url.Values, payload []byte, out interface{}) (bool, error) {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.orderSource != "" {
+		req.Header.Set("X-Order-Source", c.orderSource)
+	}
+	if c.book != "" {
+		req.Header.Set("X-Book", c.book)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, apiErr
+	}
+
+	if out == nil || len(data) == 0 {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return false, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return false, nil
+}
+
+// Health checks whether the bot is up
+func (c *Client) Health(ctx context.Context) error {
+	var resp struct {
+		Status string `json:"status"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/health", nil, nil, &resp); err != nil {
+		return err
+	}
+	if resp.Status != "healthy" {
+		return fmt.Errorf("bot reported status %q", resp.Status)
+	}
+	return nil
+}
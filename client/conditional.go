@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ConditionalOrderRequest creates an order held locally until its condition
+// fires on TriggerSymbol, the order's own symbol by default. Conditions are
+// price_above and price_below, with a price Threshold, and
+// day_change_above and day_change_below, with a percent Threshold from the
+// previous close such as -1.
+type ConditionalOrderRequest struct {
+	Symbol        string           `json:"symbol"`
+	Side          string           `json:"side"`
+	Qty           decimal.Decimal  `json:"qty"`
+	Type          string           `json:"type,omitempty"` // market (default) or limit
+	TimeInForce   string           `json:"time_in_force,omitempty"`
+	LimitPrice    *decimal.Decimal `json:"limit_price,omitempty"`
+	Condition     string           `json:"condition"`
+	Threshold     float64          `json:"threshold"`
+	TriggerSymbol string           `json:"trigger_symbol,omitempty"`
+	ExpiresAt     *time.Time       `json:"expires_at,omitempty"`
+	Strategy      string           `json:"strategy,omitempty"`
+	Note          string           `json:"note,omitempty"`
+}
+
+// ConditionalOrder is an order waiting for, or submitted on, its condition
+type ConditionalOrder struct {
+	ID            string           `json:"id"`
+	Symbol        string           `json:"symbol"`
+	Side          string           `json:"side"`
+	Qty           decimal.Decimal  `json:"qty"`
+	Type          string           `json:"type"`
+	LimitPrice    *decimal.Decimal `json:"limit_price,omitempty"`
+	TimeInForce   string           `json:"time_in_force"`
+	Strategy      string           `json:"strategy"`
+	Book          string           `json:"book"`
+	TriggerSymbol string           `json:"trigger_symbol"`
+	Condition     string           `json:"condition"`
+	Threshold     float64          `json:"threshold"`
+	Armed         bool             `json:"armed"`
+	LastValue     float64          `json:"last_value"`
+	Status        string           `json:"status"`
+	Reason        string           `json:"reason,omitempty"`
+	ExpiresAt     *time.Time       `json:"expires_at,omitempty"`
+	ExpiresIn     *int64           `json:"expires_in_seconds,omitempty"`
+	TriggeredAt   *time.Time       `json:"triggered_at,omitempty"`
+	TriggerValue  float64          `json:"trigger_value,omitempty"`
+	OrderID       string           `json:"order_id,omitempty"`
+	Note          string           `json:"note,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// CreateConditionalOrder stores a conditional order (POST /orders/conditional)
+func (c *Client) CreateConditionalOrder(ctx context.Context, req *ConditionalOrderRequest) (*ConditionalOrder, error) {
+	var order ConditionalOrder
+	if err := c.post(ctx, "/orders/conditional", req, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ListConditionalOrders lists conditional orders, optionally filtered by
+// status (GET /orders/conditional)
+func (c *Client) ListConditionalOrders(ctx context.Context, status string) ([]*ConditionalOrder, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	var resp struct {
+		Count  int                 `json:"count"`
+		Orders []*ConditionalOrder `json:"orders"`
+	}
+	if err := c.get(ctx, "/orders/conditional", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Orders, nil
+}
+
+// GetConditionalOrder returns a conditional order (GET /orders/conditional/:id)
+func (c *Client) GetConditionalOrder(ctx context.Context, id string) (*ConditionalOrder, error) {
+	var order ConditionalOrder
+	if err := c.get(ctx, "/orders/conditional/"+url.PathEscape(id), nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CancelConditionalOrder stops watching an active conditional order
+// (DELETE /orders/conditional/:id)
+func (c *Client) CancelConditionalOrder(ctx context.Context, id string) (*ConditionalOrder, error) {
+	var order ConditionalOrder
+	if err := c.delete(ctx, "/orders/conditional/"+url.PathEscape(id), &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
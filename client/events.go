@@ -0,0 +1,303 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Live event types delivered by StreamEvents
+const (
+	EventOrderUpdate     = "order_update"
+	EventPositionPnL     = "position_pnl"
+	EventAlertTriggered  = "alert_triggered"
+	EventNewsRuleMatched = "news_rule_matched"
+	EventMarketBrief     = "market_brief"
+	EventAlgoProgress    = "algo_progress"
+	EventResync          = "resync" // events were missed while disconnected
+
+	// Trading activity, also listed by ListActivityEvents
+	EventOrderPlaced       = "order_placed"
+	EventPositionClosed    = "position_closed"
+	EventRiskBreached      = "risk_breached"
+	EventAnalysisCompleted = "analysis_completed"
+)
+
+// Event is one Server-Sent Event from GET /events. Decode Data according to
+// Type, e.g. into OrderUpdate for order_update.
+type Event struct {
+	ID   uint64
+	Type string
+	Data json.RawMessage
+}
+
+// OrderUpdate is the data of an order_update event
+type OrderUpdate struct {
+	OrderID        string           `json:"order_id"`
+	Symbol         string           `json:"symbol"`
+	Side           string           `json:"side,omitempty"`
+	Type           string           `json:"type,omitempty"`
+	Qty            decimal.Decimal  `json:"qty"`
+	FilledQty      decimal.Decimal  `json:"filled_qty"`
+	FilledAvgPrice *decimal.Decimal `json:"filled_avg_price,omitempty"`
+	Status         string           `json:"status"`
+	PreviousStatus string           `json:"previous_status,omitempty"`
+}
+
+// PnLTick is the data of a position_pnl event
+type PnLTick struct {
+	Positions []struct {
+		Symbol         string  `json:"symbol"`
+		Qty            float64 `json:"qty"`
+		CurrentPrice   float64 `json:"current_price"`
+		MarketValue    float64 `json:"market_value"`
+		UnrealizedPL   float64 `json:"unrealized_pl"`
+		UnrealizedPLPC float64 `json:"unrealized_pl_percent"`
+	} `json:"positions"`
+	TotalMarketValue  float64 `json:"total_market_value"`
+	TotalUnrealizedPL float64 `json:"total_unrealized_pl"`
+}
+
+// OrderPlaced is the data of an order_placed event
+type OrderPlaced struct {
+	OrderID    string           `json:"order_id"`
+	Symbol     string           `json:"symbol"`
+	Side       string           `json:"side"`
+	Type       string           `json:"type"`
+	Qty        decimal.Decimal  `json:"qty"`
+	LimitPrice *decimal.Decimal `json:"limit_price,omitempty"`
+	AssetClass string           `json:"asset_class"`
+	Status     string           `json:"status"`
+	Source     string           `json:"source"`
+	Book       string           `json:"book"`
+}
+
+// PositionClosed is the data of a position_closed event
+type PositionClosed struct {
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"`
+	Qty        float64 `json:"qty"`
+	EntryPrice float64 `json:"entry_price"`
+	ExitPrice  float64 `json:"exit_price"`
+	PnL        float64 `json:"pnl"`
+	PnLPercent float64 `json:"pnl_percent"`
+}
+
+// RiskBreached is the data of a risk_breached event
+type RiskBreached struct {
+	Symbol     string          `json:"symbol"`
+	Side       string          `json:"side"`
+	Qty        decimal.Decimal `json:"qty"`
+	AssetClass string          `json:"asset_class"`
+	Notional   decimal.Decimal `json:"notional"`
+	Violations []struct {
+		Rule    string `json:"rule"`
+		Code    string `json:"code,omitempty"`
+		Message string `json:"message"`
+	} `json:"violations"`
+	Source string `json:"source"`
+	Book   string `json:"book"`
+}
+
+// AnalysisCompleted is the data of an analysis_completed event
+type AnalysisCompleted struct {
+	Symbol         string  `json:"symbol"`
+	Profile        string  `json:"profile"`
+	Price          float64 `json:"price"`
+	CompositeScore int     `json:"composite_score"`
+	Trend          string  `json:"trend"`
+	Notes          string  `json:"notes"`
+}
+
+// ActivityEvent is a recorded trading activity event. Decode Data according
+// to Type, e.g. into RiskBreached for risk_breached.
+type ActivityEvent struct {
+	ID        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ListActivityEvents returns the latest recorded trading activity events,
+// newest first. eventType and symbol are optional filters.
+// (GET /activity/events)
+func (c *Client) ListActivityEvents(ctx context.Context, eventType, symbol string, limit int) ([]ActivityEvent, error) {
+	query := url.Values{}
+	if eventType != "" {
+		query.Set("type", eventType)
+	}
+	if symbol != "" {
+		query.Set("symbol", symbol)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var resp struct {
+		Events []ActivityEvent `json:"events"`
+	}
+	if err := c.get(ctx, "/activity/events", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// EventUpdate is delivered by StreamEvents for every event or stream error
+type EventUpdate struct {
+	Event *Event
+	Err   error
+}
+
+// StreamEvents subscribes to live events (GET /events), optionally limited to
+// the given types. Dropped connections are retried with the last event ID so
+// nothing is missed while the server still holds the events; a resync event
+// reports a gap. Errors are delivered and followed by a reconnect, except
+// authentication and request errors, which end the stream. The channel is
+// closed when ctx is cancelled.
+func (c *Client) StreamEvents(ctx context.Context, types ...string) <-chan EventUpdate {
+	updates := make(chan EventUpdate)
+
+	go func() {
+		defer close(updates)
+
+		initialBackoff := c.retryBackoff
+		if initialBackoff <= 0 {
+			initialBackoff = time.Second
+		}
+
+		var lastID uint64
+		backoff := initialBackoff
+		for {
+			err := c.readEvents(ctx, types, &lastID, func(event *Event) bool {
+				backoff = initialBackoff
+				select {
+				case updates <- EventUpdate{Event: event}:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				select {
+				case updates <- EventUpdate{Err: err}:
+				case <-ctx.Done():
+					return
+				}
+				var apiErr *APIError
+				if errors.As(err, &apiErr) && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests {
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+		}
+	}()
+
+	return updates
+}
+
+// readEvents reads one SSE connection until it ends, passing events to emit
+func (c *Client) readEvents(ctx context.Context, types []string, lastID *uint64, emit func(*Event) bool) error {
+	endpoint := c.baseURL + apiPrefix + "/events"
+	if len(types) > 0 {
+		endpoint += "?" + url.Values{"types": {strings.Join(types, ",")}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if *lastID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(*lastID, 10))
+	}
+
+	// The stream stays open indefinitely, so the request timeout must not apply
+	streamClient := *c.httpClient
+	streamClient.Timeout = 0
+
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to event stream: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+
+	event := &Event{}
+	var data []string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// A blank line dispatches the event
+			if len(data) > 0 {
+				event.Data = json.RawMessage(strings.Join(data, "\n"))
+				if event.Type == "" {
+					event.Type = "message"
+				}
+				if event.ID > 0 {
+					*lastID = event.ID
+				}
+				if !emit(event) {
+					return nil
+				}
+			}
+			event, data = &Event{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+				event.ID = id
+			}
+		case "event":
+			event.Type = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("event stream interrupted: %w", err)
+	}
+	// The server closes the stream on shutdown or when the client falls
+	// behind; either way the caller reconnects from lastID
+	return nil
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Filing is an SEC filing for one symbol
+type Filing struct {
+	AccessionNumber string    `json:"accession_number"`
+	Symbol          string    `json:"symbol"`
+	Form            string    `json:"form"`
+	FiledAt         time.Time `json:"filed_at"`
+	ReportDate      string    `json:"report_date,omitempty"`
+	Items           []string  `json:"items,omitempty"`
+	Description     string    `json:"description,omitempty"`
+	URL             string    `json:"url"`
+	Summary         string    `json:"summary"`
+	Material        bool      `json:"material"`
+}
+
+// FilingsResponse is returned by GET /filings/:symbol
+type FilingsResponse struct {
+	Symbol  string    `json:"symbol"`
+	Count   int       `json:"count"`
+	Filings []*Filing `json:"filings"`
+}
+
+// GetFilings returns recent SEC filings for a symbol (GET /filings/:symbol).
+// form ("8-K", "10-Q", "10-K", "4") and limit are optional.
+func (c *Client) GetFilings(ctx context.Context, symbol, form string, limit int) (*FilingsResponse, error) {
+	query := url.Values{}
+	if form != "" {
+		query.Set("form", form)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp FilingsResponse
+	if err := c.get(ctx, "/filings/"+url.PathEscape(symbol), query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// Fundamentals are valuation, growth and analyst data for one symbol
+type Fundamentals struct {
+	Symbol            string     `json:"symbol"`
+	Name              string     `json:"name,omitempty"`
+	Currency          string     `json:"currency,omitempty"`
+	Price             float64    `json:"price"`
+	MarketCap         float64    `json:"market_cap"`
+	TrailingPE        float64    `json:"trailing_pe"`
+	ForwardPE         float64    `json:"forward_pe"`
+	PEGRatio          float64    `json:"peg_ratio"`
+	EPS               float64    `json:"eps_ttm"`
+	ForwardEPS        float64    `json:"forward_eps"`
+	RevenueGrowthPct  float64    `json:"revenue_growth_percent"`
+	EarningsGrowthPct float64    `json:"earnings_growth_percent"`
+	ProfitMarginPct   float64    `json:"profit_margin_percent"`
+	DividendYieldPct  float64    `json:"dividend_yield_percent"`
+	Beta              float64    `json:"beta"`
+	High52Week        float64    `json:"high_52_week"`
+	Low52Week         float64    `json:"low_52_week"`
+	TargetMeanPrice   float64    `json:"target_mean_price"`
+	TargetHighPrice   float64    `json:"target_high_price"`
+	TargetLowPrice    float64    `json:"target_low_price"`
+	TargetUpsidePct   float64    `json:"target_upside_percent"`
+	AnalystCount      int        `json:"analyst_count"`
+	Recommendation    string     `json:"recommendation,omitempty"`
+	NextEarningsDate  *time.Time `json:"next_earnings_date,omitempty"`
+	DaysToEarnings    *int       `json:"days_to_earnings,omitempty"`
+	Source            string     `json:"source"`
+	FetchedAt         time.Time  `json:"fetched_at"`
+}
+
+// GetFundamentals returns fundamentals for a symbol (GET /fundamentals/:symbol)
+func (c *Client) GetFundamentals(ctx context.Context, symbol string) (*Fundamentals, error) {
+	var fundamentals Fundamentals
+	if err := c.get(ctx, "/fundamentals/"+url.PathEscape(symbol), nil, &fundamentals); err != nil {
+		return nil, err
+	}
+	return &fundamentals, nil
+}
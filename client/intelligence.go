@@ -0,0 +1,309 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetNews returns the latest general news (GET /news)
+func (c *Client) GetNews(ctx context.Context, limit int) (*NewsResponse, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return c.getNews(ctx, "/news", query)
+}
+
+// GetNewsPage returns one page of the latest news, optionally filtered to
+// headlines mentioning opts.Symbol (GET /news)
+func (c *Client) GetNewsPage(ctx context.Context, opts ListOptions) (*NewsResponse, error) {
+	return c.getNews(ctx, "/news", opts.values())
+}
+
+// GetNewsByTopic returns Google News for a topic such as BUSINESS (GET /news/topic/:topic)
+func (c *Client) GetNewsByTopic(ctx context.Context, topic string, compact bool) (*NewsResponse, error) {
+	query := url.Values{}
+	if compact {
+		query.Set("compact", "true")
+	}
+	return c.getNews(ctx, "/news/topic/"+url.PathEscape(topic), query)
+}
+
+// SearchNews searches news by query (GET /news/search)
+func (c *Client) SearchNews(ctx context.Context, q string, limit int) (*NewsResponse, error) {
+	query := url.Values{}
+	query.Set("q", q)
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return c.getNews(ctx, "/news/search", query)
+}
+
+// SemanticSearch finds stored news and analyses similar in meaning to q.
+// kind ("news" or "analysis") and symbol are optional filters.
+// (GET /news/semantic-search)
+func (c *Client) SemanticSearch(ctx context.Context, q, kind, symbol string, limit int) (*SemanticSearchResponse, error) {
+	query := url.Values{}
+	query.Set("q", q)
+	if kind != "" {
+		query.Set("kind", kind)
+	}
+	if symbol != "" {
+		query.Set("symbol", symbol)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var resp SemanticSearchResponse
+	if err := c.get(ctx, "/news/semantic-search", query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetMarketNews returns market news, optionally for specific symbols (GET /news/market)
+func (c *Client) GetMarketNews(ctx context.Context, symbols []string) (*NewsResponse, error) {
+	query := url.Values{}
+	if len(symbols) > 0 {
+		query.Set("symbols", strings.Join(symbols, ","))
+	}
+	return c.getNews(ctx, "/news/market", query)
+}
+
+// GetMarketWatchNews returns a MarketWatch feed: "topstories", "realtime",
+// "bulletins", "marketpulse" or "all" (GET /news/marketwatch/:feed)
+func (c *Client) GetMarketWatchNews(ctx context.Context, feed string) (*NewsResponse, error) {
+	return c.getNews(ctx, "/news/marketwatch/"+url.PathEscape(feed), nil)
+}
+
+func (c *Client) getNews(ctx context.Context, path string, query url.Values) (*NewsResponse, error) {
+	var resp NewsResponse
+	if err := c.get(ctx, path, query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetCleanedNews aggregates and summarizes news (POST /intelligence/cleaned-news)
+func (c *Client) GetCleanedNews(ctx context.Context, req AggregateNewsRequest) (*CleanedNewsResponse, error) {
+	var resp CleanedNewsResponse
+	if err := c.post(ctx, "/intelligence/cleaned-news", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetQuickMarketIntelligence returns a quick market overview (GET /intelligence/quick-market)
+func (c *Client) GetQuickMarketIntelligence(ctx context.Context) (*CleanedNews, error) {
+	var resp CleanedNews
+	if err := c.get(ctx, "/intelligence/quick-market", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AnalyzeStock returns a comprehensive analysis of one symbol (GET /intelligence/analyze/:symbol)
+func (c *Client) AnalyzeStock(ctx context.Context, symbol string) (*StockAnalysis, error) {
+	var resp StockAnalysis
+	if err := c.get(ctx, "/intelligence/analyze/"+url.PathEscape(symbol), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AnalyzeStocks analyzes several symbols at once (POST /intelligence/analyze-multiple)
+func (c *Client) AnalyzeStocks(ctx context.Context, symbols []string) (map[string]*StockAnalysis, error) {
+	var resp struct {
+		Analyses map[string]*StockAnalysis `json:"analyses"`
+		Count    int                       `json:"count"`
+	}
+	body := map[string][]string{"symbols": symbols}
+	if err := c.post(ctx, "/intelligence/analyze-multiple", body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Analyses, nil
+}
+
+// GetMarketRegime returns the current market regime (GET /intelligence/regime)
+func (c *Client) GetMarketRegime(ctx context.Context) (*MarketRegime, error) {
+	var resp MarketRegime
+	if err := c.get(ctx, "/intelligence/regime", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetSocialSentiment returns social media mentions and sentiment for a symbol
+// (GET /intelligence/social/:symbol)
+func (c *Client) GetSocialSentiment(ctx context.Context, symbol string) (*SocialSentiment, error) {
+	var resp SocialSentiment
+	if err := c.get(ctx, "/intelligence/social/"+url.PathEscape(symbol), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetMarketBrief returns the rolling market brief (GET /intelligence/brief)
+func (c *Client) GetMarketBrief(ctx context.Context) (*MarketBrief, error) {
+	var resp MarketBrief
+	if err := c.get(ctx, "/intelligence/brief", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AnalysisProfileRequest creates or replaces an analysis profile. Omitted
+// settings take the server defaults.
+type AnalysisProfileRequest struct {
+	Symbols           []string `json:"symbols,omitempty"`
+	Sectors           []string `json:"sectors,omitempty"`
+	AssetClasses      []string `json:"asset_classes,omitempty"`
+	Timeframes        []string `json:"timeframes,omitempty"`
+	LookbackDays      int      `json:"lookback_days,omitempty"`
+	Indicators        []string `json:"indicators,omitempty"`
+	NewsLookbackHours int      `json:"news_lookback_hours,omitempty"`
+	MaxHeadlines      int      `json:"max_headlines,omitempty"`
+	NewsPrompt        string   `json:"news_prompt,omitempty"`
+	MinConfidence     int      `json:"min_confidence,omitempty"`
+	StopLossPct       float64  `json:"stop_loss_pct,omitempty"`
+	TakeProfitPct     float64  `json:"take_profit_pct,omitempty"`
+}
+
+// AnalysisProfile sets how stock analysis treats the symbols it matches
+type AnalysisProfile struct {
+	Name              string    `json:"name"`
+	Symbols           []string  `json:"symbols,omitempty"`
+	Sectors           []string  `json:"sectors,omitempty"`
+	AssetClasses      []string  `json:"asset_classes,omitempty"`
+	Timeframes        []string  `json:"timeframes"`
+	LookbackDays      int       `json:"lookback_days"`
+	Indicators        []string  `json:"indicators"`
+	NewsLookbackHours int       `json:"news_lookback_hours"`
+	MaxHeadlines      int       `json:"max_headlines"`
+	NewsPrompt        string    `json:"news_prompt,omitempty"`
+	MinConfidence     int       `json:"min_confidence"`
+	StopLossPct       float64   `json:"stop_loss_pct"`
+	TakeProfitPct     float64   `json:"take_profit_pct"`
+	UpdatedAt         time.Time `json:"updated_at,omitempty"`
+}
+
+// AnalysisProfilesResponse lists analysis profiles. Applied is set when a
+// symbol was given.
+type AnalysisProfilesResponse struct {
+	Profiles []*AnalysisProfile `json:"profiles"`
+	Count    int                `json:"count"`
+	Applied  *AnalysisProfile   `json:"applied,omitempty"`
+}
+
+// ListAnalysisProfiles lists analysis profiles, and the one symbol is
+// analysed with when symbol is not empty (GET /intelligence/profiles)
+func (c *Client) ListAnalysisProfiles(ctx context.Context, symbol string) (*AnalysisProfilesResponse, error) {
+	query := url.Values{}
+	if symbol != "" {
+		query.Set("symbol", symbol)
+	}
+	var resp AnalysisProfilesResponse
+	if err := c.get(ctx, "/intelligence/profiles", query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SaveAnalysisProfile creates or replaces an analysis profile
+// (PUT /intelligence/profiles/:name)
+func (c *Client) SaveAnalysisProfile(ctx context.Context, name string, req *AnalysisProfileRequest) (*AnalysisProfile, error) {
+	var profile AnalysisProfile
+	if err := c.put(ctx, "/intelligence/profiles/"+url.PathEscape(name), req, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// DeleteAnalysisProfile deletes an analysis profile
+// (DELETE /intelligence/profiles/:name)
+func (c *Client) DeleteAnalysisProfile(ctx context.Context, name string) error {
+	return c.delete(ctx, "/intelligence/profiles/"+url.PathEscape(name), nil)
+}
+
+// ChatMessage is one turn of an intelligence chat
+type ChatMessage struct {
+	Role string    `json:"role"`
+	Text string    `json:"text"`
+	At   time.Time `json:"at"`
+}
+
+// ChatReply is the answer to a chat message
+type ChatReply struct {
+	SessionID string    `json:"session_id"`
+	Reply     string    `json:"reply"`
+	Symbols   []string  `json:"symbols,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// ChatSession is a conversation with the intelligence chat
+type ChatSession struct {
+	SessionID string        `json:"session_id"`
+	Messages  []ChatMessage `json:"messages"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// Chat sends a message to the intelligence chat. An empty sessionID starts
+// a new conversation; pass the returned SessionID to follow up
+// (POST /intelligence/chat).
+func (c *Client) Chat(ctx context.Context, sessionID, message string) (*ChatReply, error) {
+	body := map[string]string{"message": message}
+	if sessionID != "" {
+		body["session_id"] = sessionID
+	}
+	var reply ChatReply
+	if err := c.post(ctx, "/intelligence/chat", body, &reply); err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// GetChatSession returns a chat session's messages
+// (GET /intelligence/chat/:session_id)
+func (c *Client) GetChatSession(ctx context.Context, sessionID string) (*ChatSession, error) {
+	var session ChatSession
+	if err := c.get(ctx, "/intelligence/chat/"+url.PathEscape(sessionID), nil, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// DeleteChatSession ends a chat session (DELETE /intelligence/chat/:session_id)
+func (c *Client) DeleteChatSession(ctx context.Context, sessionID string) error {
+	return c.delete(ctx, "/intelligence/chat/"+url.PathEscape(sessionID), nil)
+}
+
+// LLMToolCall is an audited data tool call made by the LLM
+type LLMToolCall struct {
+	Source     string    `json:"source"`
+	Tool       string    `json:"tool"`
+	Args       string    `json:"args"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	At         time.Time `json:"at"`
+}
+
+// ListLLMToolCalls returns the latest audited LLM tool calls, newest first
+// (GET /admin/llm/tool-calls)
+func (c *Client) ListLLMToolCalls(ctx context.Context, limit int) ([]*LLMToolCall, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var resp struct {
+		Calls []*LLMToolCall `json:"calls"`
+	}
+	if err := c.get(ctx, "/admin/llm/tool-calls", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Calls, nil
+}
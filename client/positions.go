@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// PlaceManagedPosition opens a position with automated stop/target management (POST /positions/managed)
+func (c *Client) PlaceManagedPosition(ctx context.Context, req ManagedPositionRequest) (*ManagedPosition, error) {
+	var resp struct {
+		Message  string           `json:"message"`
+		Position *ManagedPosition `json:"position"`
+	}
+	if err := c.post(ctx, "/positions/managed", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Position, nil
+}
+
+// ListManagedPositions lists all managed positions, optionally filtered by
+// status, following pagination to the last page (GET /positions/managed)
+func (c *Client) ListManagedPositions(ctx context.Context, status string) ([]*ManagedPosition, error) {
+	var all []*ManagedPosition
+	opts := ListOptions{Limit: 500}
+	for {
+		positions, page, err := c.ListManagedPositionsPage(ctx, status, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, positions...)
+		if !page.HasMore {
+			return all, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// ListManagedPositionsPage returns one page of managed positions (GET /positions/managed)
+func (c *Client) ListManagedPositionsPage(ctx context.Context, status string, opts ListOptions) ([]*ManagedPosition, *PageInfo, error) {
+	query := opts.values()
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	var resp struct {
+		Count      int                `json:"count"`
+		Positions  []*ManagedPosition `json:"positions"`
+		Pagination PageInfo           `json:"pagination"`
+	}
+	if err := c.get(ctx, "/positions/managed", query, &resp); err != nil {
+		return nil, nil, err
+	}
+	return resp.Positions, &resp.Pagination, nil
+}
+
+// GetManagedPosition returns a single managed position (GET /positions/managed/:id)
+func (c *Client) GetManagedPosition(ctx context.Context, positionID string) (*ManagedPosition, error) {
+	var position ManagedPosition
+	if err := c.get(ctx, "/positions/managed/"+url.PathEscape(positionID), nil, &position); err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+// CloseManagedPosition cancels a managed position's orders and exits it (DELETE /positions/managed/:id)
+func (c *Client) CloseManagedPosition(ctx context.Context, positionID string) error {
+	return c.delete(ctx, "/positions/managed/"+url.PathEscape(positionID), nil)
+}
+
+// ListSplits returns the stock splits applied to stored positions and
+// trades, optionally for one symbol (GET /positions/splits)
+func (c *Client) ListSplits(ctx context.Context, symbol string) ([]*SplitAdjustment, error) {
+	query := url.Values{}
+	if symbol != "" {
+		query.Set("symbol", symbol)
+	}
+	var resp struct {
+		Count       int                `json:"count"`
+		Adjustments []*SplitAdjustment `json:"adjustments"`
+	}
+	if err := c.get(ctx, "/positions/splits", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Adjustments, nil
+}
+
+// ApplySplit applies a stock split the data provider didn't report (POST /positions/splits)
+func (c *Client) ApplySplit(ctx context.Context, req SplitRequest) (*SplitAdjustment, error) {
+	var adjustment SplitAdjustment
+	if err := c.post(ctx, "/positions/splits", req, &adjustment); err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+// GetCurrentActivity returns today's activity log (GET /activity/current)
+func (c *Client) GetCurrentActivity(ctx context.Context) (*ActivityLog, error) {
+	var log ActivityLog
+	if err := c.get(ctx, "/activity/current", nil, &log); err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// GetActivityByDate returns the activity log for a YYYY-MM-DD date (GET /activity/:date)
+func (c *Client) GetActivityByDate(ctx context.Context, date string) (*ActivityLog, error) {
+	var log ActivityLog
+	if err := c.get(ctx, "/activity/"+url.PathEscape(date), nil, &log); err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// ListActivityLogs returns every date that has an activity log, newest
+// first (GET /activity)
+func (c *Client) ListActivityLogs(ctx context.Context) ([]string, error) {
+	var all []string
+	opts := ListOptions{Limit: 500}
+	for {
+		dates, page, err := c.ListActivityLogsPage(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, dates...)
+		if !page.HasMore {
+			return all, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// ListActivityLogsPage returns one page of activity log dates (GET /activity)
+func (c *Client) ListActivityLogsPage(ctx context.Context, opts ListOptions) ([]string, *PageInfo, error) {
+	var resp struct {
+		Dates      []string `json:"dates"`
+		Count      int      `json:"count"`
+		Pagination PageInfo `json:"pagination"`
+	}
+	if err := c.get(ctx, "/activity", opts.values(), &resp); err != nil {
+		return nil, nil, err
+	}
+	return resp.Dates, &resp.Pagination, nil
+}
+
+// ListSessions returns the latest trading session summaries, newest first
+// (GET /activity/sessions)
+func (c *Client) ListSessions(ctx context.Context, limit int) ([]Session, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var resp struct {
+		Sessions []Session `json:"sessions"`
+	}
+	if err := c.get(ctx, "/activity/sessions", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Sessions, nil
+}
+
+// StartSession starts a trading session (POST /activity/session/start)
+func (c *Client) StartSession(ctx context.Context, startingCapital float64) error {
+	body := map[string]float64{"starting_capital": startingCapital}
+	return c.post(ctx, "/activity/session/start", body, nil)
+}
+
+// EndSession ends the current trading session (POST /activity/session/end)
+func (c *Client) EndSession(ctx context.Context, endingCapital float64, activePositions int) error {
+	body := map[string]interface{}{
+		"ending_capital":   endingCapital,
+		"active_positions": activePositions,
+	}
+	return c.post(ctx, "/activity/session/end", body, nil)
+}
+
+// LogActivity records a general activity in the journal (POST /activity/log)
+func (c *Client) LogActivity(ctx context.Context, req ActivityRequest) error {
+	return c.post(ctx, "/activity/log", req, nil)
+}
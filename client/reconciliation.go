@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// Discrepancy is a divergence between local and broker state
+type Discrepancy struct {
+	Type       string `json:"type"`
+	Severity   string `json:"severity"`
+	Symbol     string `json:"symbol,omitempty"`
+	PositionID string `json:"position_id,omitempty"`
+	OrderID    string `json:"order_id,omitempty"`
+	Message    string `json:"message"`
+	Healed     bool   `json:"healed"`
+	HealAction string `json:"heal_action,omitempty"`
+}
+
+// ReconciliationReport is the result of one reconciliation pass
+type ReconciliationReport struct {
+	StartedAt        time.Time     `json:"started_at"`
+	CompletedAt      time.Time     `json:"completed_at"`
+	BrokerPositions  int           `json:"broker_positions"`
+	BrokerOpenOrders int           `json:"broker_open_orders"`
+	ManagedPositions int           `json:"managed_positions"`
+	LocalOpenOrders  int           `json:"local_open_orders"`
+	Discrepancies    []Discrepancy `json:"discrepancies"`
+	Error            string        `json:"error,omitempty"`
+}
+
+// RecoveryReport is the result of the startup recovery of managed
+// positions. Discrepancies that are not healed could not be restored.
+type RecoveryReport struct {
+	StartedAt         time.Time     `json:"started_at"`
+	CompletedAt       time.Time     `json:"completed_at"`
+	RestoredPositions int           `json:"restored_positions"`
+	BrokerOrders      int           `json:"broker_orders"`
+	Unrestored        int           `json:"unrestored"`
+	Discrepancies     []Discrepancy `json:"discrepancies"`
+	Error             string        `json:"error,omitempty"`
+}
+
+// GetReconciliationStatus returns the latest reconciliation report
+// (GET /reconciliation/status). StartedAt is zero if none has run yet.
+func (c *Client) GetReconciliationStatus(ctx context.Context) (*ReconciliationReport, error) {
+	var report ReconciliationReport
+	if err := c.get(ctx, "/reconciliation/status", nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// RunReconciliation triggers an immediate reconciliation (POST /reconciliation/run)
+func (c *Client) RunReconciliation(ctx context.Context) (*ReconciliationReport, error) {
+	var report ReconciliationReport
+	if err := c.post(ctx, "/reconciliation/run", nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetRecoveryReport returns the report of the startup recovery of managed
+// positions (GET /reconciliation/recovery). StartedAt is zero if none has
+// run yet.
+func (c *Client) GetRecoveryReport(ctx context.Context) (*RecoveryReport, error) {
+	var report RecoveryReport
+	if err := c.get(ctx, "/reconciliation/recovery", nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
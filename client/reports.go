@@ -0,0 +1,464 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RBucket counts closed trades whose R-multiple falls in [Min, Max)
+type RBucket struct {
+	Label  string   `json:"label"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+	Trades int      `json:"trades"`
+}
+
+// StrategyPerformance is the P&L attribution for one strategy tag
+type StrategyPerformance struct {
+	Strategy      string  `json:"strategy"`
+	Trades        int     `json:"trades"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	WinRate       float64 `json:"win_rate"`
+	RealizedPnL   float64 `json:"realized_pnl"`
+	AvgWin        float64 `json:"avg_win"`
+	AvgLoss       float64 `json:"avg_loss"`
+	ProfitFactor  float64 `json:"profit_factor"`
+	Orders        int     `json:"orders"`
+	OpenPositions int     `json:"open_positions"`
+	Exposure      float64 `json:"exposure"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+	Dividends     float64 `json:"dividends"`
+	Fees          float64 `json:"fees"`
+	NetPnL        float64 `json:"net_pnl"`
+
+	RTrades            int       `json:"r_trades"`
+	ExpectancyR        float64   `json:"expectancy_r"`
+	RollingExpectancyR float64   `json:"rolling_expectancy_r"`
+	RDistribution      []RBucket `json:"r_distribution,omitempty"`
+}
+
+// StrategyReport is returned by GET /reports/strategies
+type StrategyReport struct {
+	Start      time.Time             `json:"start"`
+	End        time.Time             `json:"end"`
+	Book       string                `json:"book,omitempty"`
+	Strategies []StrategyPerformance `json:"strategies"`
+}
+
+// BookPerformance is the P&L attribution for one book
+type BookPerformance struct {
+	Book          string  `json:"book"`
+	Trades        int     `json:"trades"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	WinRate       float64 `json:"win_rate"`
+	RealizedPnL   float64 `json:"realized_pnl"`
+	AvgWin        float64 `json:"avg_win"`
+	AvgLoss       float64 `json:"avg_loss"`
+	ProfitFactor  float64 `json:"profit_factor"`
+	Orders        int     `json:"orders"`
+	OpenPositions int     `json:"open_positions"`
+	Exposure      float64 `json:"exposure"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+	Dividends     float64 `json:"dividends"`
+	Fees          float64 `json:"fees"`
+	NetPnL        float64 `json:"net_pnl"`
+
+	RTrades            int       `json:"r_trades"`
+	ExpectancyR        float64   `json:"expectancy_r"`
+	RollingExpectancyR float64   `json:"rolling_expectancy_r"`
+	RDistribution      []RBucket `json:"r_distribution,omitempty"`
+}
+
+// BookReport is returned by GET /reports/books
+type BookReport struct {
+	Start time.Time         `json:"start"`
+	End   time.Time         `json:"end"`
+	Books []BookPerformance `json:"books"`
+}
+
+// reportWindow encodes an optional date range; zero times use the server default
+func reportWindow(start, end time.Time) url.Values {
+	query := url.Values{}
+	if !start.IsZero() {
+		query.Set("start", start.Format("2006-01-02"))
+	}
+	if !end.IsZero() {
+		query.Set("end", end.Format("2006-01-02"))
+	}
+	return query
+}
+
+// GetStrategyReport returns per-strategy P&L attribution (GET /reports/strategies)
+func (c *Client) GetStrategyReport(ctx context.Context, start, end time.Time) (*StrategyReport, error) {
+	var report StrategyReport
+	if err := c.get(ctx, "/reports/strategies", reportWindow(start, end), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetBookReport returns per-book P&L attribution (GET /reports/books)
+func (c *Client) GetBookReport(ctx context.Context, start, end time.Time) (*BookReport, error) {
+	var report BookReport
+	if err := c.get(ctx, "/reports/books", reportWindow(start, end), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// TimingBucket aggregates the closed trades in one time-of-day slot or weekday
+type TimingBucket struct {
+	Label       string  `json:"label"`
+	Trades      int     `json:"trades"`
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	WinRate     float64 `json:"win_rate"`
+	RealizedPnL float64 `json:"realized_pnl"`
+	AvgPnL      float64 `json:"avg_pnl"`
+}
+
+// TimingReport is returned by GET /reports/timing
+type TimingReport struct {
+	Start         time.Time      `json:"start"`
+	End           time.Time      `json:"end"`
+	Book          string         `json:"book,omitempty"`
+	By            string         `json:"by"`
+	Timezone      string         `json:"timezone"`
+	BucketMinutes int            `json:"bucket_minutes"`
+	Trades        int            `json:"trades"`
+	TimeOfDay     []TimingBucket `json:"time_of_day"`
+	DayOfWeek     []TimingBucket `json:"day_of_week"`
+}
+
+// GetTimingReport buckets realized P&L by time of day and weekday
+// (GET /reports/timing); by is "entry" or "exit" and bucketMinutes <= 0 or an
+// empty by use the server defaults
+func (c *Client) GetTimingReport(ctx context.Context, start, end time.Time, by string, bucketMinutes int) (*TimingReport, error) {
+	query := reportWindow(start, end)
+	if by != "" {
+		query.Set("by", by)
+	}
+	if bucketMinutes > 0 {
+		query.Set("bucket_minutes", strconv.Itoa(bucketMinutes))
+	}
+	var report TimingReport
+	if err := c.get(ctx, "/reports/timing", query, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// PositionWeight is one holding's share of gross exposure
+type PositionWeight struct {
+	Symbol      string  `json:"symbol"`
+	Underlying  string  `json:"underlying"`
+	AssetClass  string  `json:"asset_class"`
+	Sector      string  `json:"sector"`
+	MarketValue float64 `json:"market_value"`
+	Weight      float64 `json:"weight"`
+	Beta        float64 `json:"beta"`
+}
+
+// CorrelationPair is the return correlation between two held underlyings
+type CorrelationPair struct {
+	SymbolA     string  `json:"symbol_a"`
+	SymbolB     string  `json:"symbol_b"`
+	Correlation float64 `json:"correlation"`
+}
+
+// PortfolioRiskReport is returned by GET /reports/portfolio-risk
+type PortfolioRiskReport struct {
+	GeneratedAt          time.Time          `json:"generated_at"`
+	LookbackDays         int                `json:"lookback_days"`
+	GrossExposure        float64            `json:"gross_exposure"`
+	NetExposure          float64            `json:"net_exposure"`
+	Positions            []PositionWeight   `json:"positions"`
+	SectorWeights        map[string]float64 `json:"sector_weights"`
+	AssetClassWeights    map[string]float64 `json:"asset_class_weights"`
+	Correlations         []CorrelationPair  `json:"correlations"`
+	AverageCorrelation   float64            `json:"average_correlation"`
+	PortfolioBeta        float64            `json:"portfolio_beta"`
+	HerfindahlIndex      float64            `json:"herfindahl_index"`
+	DiversificationScore float64            `json:"diversification_score"`
+	Warnings             []string           `json:"warnings,omitempty"`
+}
+
+// GetPortfolioRisk returns correlation and concentration of current holdings;
+// lookbackDays <= 0 uses the server default
+func (c *Client) GetPortfolioRisk(ctx context.Context, lookbackDays int) (*PortfolioRiskReport, error) {
+	query := url.Values{}
+	if lookbackDays > 0 {
+		query.Set("lookback_days", strconv.Itoa(lookbackDays))
+	}
+	var report PortfolioRiskReport
+	if err := c.get(ctx, "/reports/portfolio-risk", query, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// BenchmarkPoint is one day of the overlaid equity curves, each rebased to 100
+type BenchmarkPoint struct {
+	Date       string             `json:"date"`
+	Account    float64            `json:"account"`
+	Benchmarks map[string]float64 `json:"benchmarks"`
+}
+
+// BenchmarkStats compares the account against a single benchmark
+type BenchmarkStats struct {
+	Symbol              string  `json:"symbol"`
+	ReturnPct           float64 `json:"return_percent"`
+	ExcessReturnPct     float64 `json:"excess_return_percent"`
+	Alpha               float64 `json:"alpha"`
+	Beta                float64 `json:"beta"`
+	Correlation         float64 `json:"correlation"`
+	TrackingErrorPct    float64 `json:"tracking_error_percent"`
+	InformationRatio    float64 `json:"information_ratio"`
+	MaxDrawdownPct      float64 `json:"max_drawdown_percent"`
+	MaxRelativeDrawdown float64 `json:"max_relative_drawdown_percent"`
+	RelativeDrawdownPct float64 `json:"relative_drawdown_percent"`
+}
+
+// BenchmarkReport is returned by GET /reports/benchmark
+type BenchmarkReport struct {
+	Start                 time.Time        `json:"start"`
+	End                   time.Time        `json:"end"`
+	Days                  int              `json:"days"`
+	AccountReturnPct      float64          `json:"account_return_percent"`
+	AccountMaxDrawdownPct float64          `json:"account_max_drawdown_percent"`
+	NetCashFlows          float64          `json:"net_cash_flows"`
+	Benchmarks            []BenchmarkStats `json:"benchmarks"`
+	Curve                 []BenchmarkPoint `json:"curve"`
+}
+
+// GetBenchmarkReport compares account equity against benchmarks; an empty
+// benchmarks list uses the server's configured defaults
+func (c *Client) GetBenchmarkReport(ctx context.Context, start, end time.Time, benchmarks ...string) (*BenchmarkReport, error) {
+	query := reportWindow(start, end)
+	if len(benchmarks) > 0 {
+		query.Set("benchmarks", strings.Join(benchmarks, ","))
+	}
+	var report BenchmarkReport
+	if err := c.get(ctx, "/reports/benchmark", query, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// HeatmapTile is one symbol of a heatmap; ChangePct is nil without a
+// previous close
+type HeatmapTile struct {
+	Symbol           string   `json:"symbol"`
+	Sector           string   `json:"sector"`
+	Size             float64  `json:"size"`
+	Weight           float64  `json:"weight"`
+	Price            float64  `json:"price"`
+	ChangePct        *float64 `json:"change_percent"`
+	Contribution     float64  `json:"contribution"`
+	UnrealizedPnL    float64  `json:"unrealized_pnl,omitempty"`
+	UnrealizedPnLPct float64  `json:"unrealized_pnl_percent,omitempty"`
+}
+
+// HeatmapGroup is the tiles of one sector
+type HeatmapGroup struct {
+	Name          string        `json:"name"`
+	Size          float64       `json:"size"`
+	Weight        float64       `json:"weight"`
+	ChangePct     float64       `json:"change_percent"`
+	UnrealizedPnL float64       `json:"unrealized_pnl,omitempty"`
+	Children      []HeatmapTile `json:"children"`
+}
+
+// Heatmap is returned by GET /reports/heatmap
+type Heatmap struct {
+	View          string         `json:"view"`
+	GeneratedAt   time.Time      `json:"generated_at"`
+	Size          float64        `json:"size"`
+	ChangePct     float64        `json:"change_percent"`
+	UnrealizedPnL float64        `json:"unrealized_pnl,omitempty"`
+	Groups        []HeatmapGroup `json:"groups"`
+	Errors        []string       `json:"errors,omitempty"`
+}
+
+// GetHeatmap returns the "portfolio" or "market" heatmap. symbols override
+// the server's watchlist for the market view.
+func (c *Client) GetHeatmap(ctx context.Context, view string, symbols ...string) (*Heatmap, error) {
+	query := url.Values{}
+	if view != "" {
+		query.Set("view", view)
+	}
+	if len(symbols) > 0 {
+		query.Set("symbols", strings.Join(symbols, ","))
+	}
+	var heatmap Heatmap
+	if err := c.get(ctx, "/reports/heatmap", query, &heatmap); err != nil {
+		return nil, err
+	}
+	return &heatmap, nil
+}
+
+// PremarketMover is one symbol of a pre-market scan; LatestHeadlines
+// carry title, link and source only
+type PremarketMover struct {
+	Symbol          string     `json:"symbol"`
+	Sector          string     `json:"sector"`
+	PreviousClose   float64    `json:"previous_close"`
+	Price           float64    `json:"price"`
+	GapPct          float64    `json:"gap_percent"`
+	Volume          int64      `json:"premarket_volume"`
+	Headlines       int        `json:"headlines"`
+	LatestHeadlines []NewsItem `json:"latest_headlines,omitempty"`
+	Score           float64    `json:"score"`
+}
+
+// PremarketScan is returned by GET /screener/premarket
+type PremarketScan struct {
+	Date        string           `json:"date"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	NewsSince   time.Time        `json:"news_since"`
+	Published   bool             `json:"published"`
+	Movers      []PremarketMover `json:"movers"`
+	Errors      []string         `json:"errors,omitempty"`
+}
+
+// PremarketScanOptions selects the order and symbols of a pre-market scan.
+// Refresh, or any Symbols, runs a fresh scan instead of returning the one
+// published before the open.
+type PremarketScanOptions struct {
+	Sort    string // score (default), gap, volume or news
+	Limit   int
+	Symbols []string
+	Refresh bool
+}
+
+// GetPremarketScan returns the watchlist ranked by gap, pre-market volume
+// and overnight headlines (GET /screener/premarket)
+func (c *Client) GetPremarketScan(ctx context.Context, opts PremarketScanOptions) (*PremarketScan, error) {
+	query := url.Values{}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if len(opts.Symbols) > 0 {
+		query.Set("symbols", strings.Join(opts.Symbols, ","))
+	}
+	if opts.Refresh {
+		query.Set("refresh", "true")
+	}
+	var scan PremarketScan
+	if err := c.get(ctx, "/screener/premarket", query, &scan); err != nil {
+		return nil, err
+	}
+	return &scan, nil
+}
+
+// AccountActivity is a dividend, fee, interest payment, journal or transfer
+type AccountActivity struct {
+	ID             string          `json:"id"`
+	Type           string          `json:"type"`
+	Category       string          `json:"category"` // cash_flow, dividend, interest, fee or other
+	Date           time.Time       `json:"date"`
+	Symbol         string          `json:"symbol,omitempty"`
+	Qty            decimal.Decimal `json:"qty"`
+	Price          decimal.Decimal `json:"price"`
+	PerShareAmount decimal.Decimal `json:"per_share_amount"`
+	NetAmount      decimal.Decimal `json:"net_amount"`
+	Description    string          `json:"description,omitempty"`
+	Status         string          `json:"status,omitempty"`
+}
+
+// CashFlowSummary totals account activities by kind
+type CashFlowSummary struct {
+	Deposits     decimal.Decimal `json:"deposits"`
+	Withdrawals  decimal.Decimal `json:"withdrawals"`
+	NetCashFlows decimal.Decimal `json:"net_cash_flows"`
+	Dividends    decimal.Decimal `json:"dividends"`
+	Interest     decimal.Decimal `json:"interest"`
+	Fees         decimal.Decimal `json:"fees"`
+	Other        decimal.Decimal `json:"other"`
+	Activities   int             `json:"activities"`
+}
+
+// AccountActivities is returned by GET /account/activities
+type AccountActivities struct {
+	Start      time.Time          `json:"start"`
+	End        time.Time          `json:"end"`
+	Activities []*AccountActivity `json:"activities"`
+	Count      int                `json:"count"`
+	Summary    CashFlowSummary    `json:"summary"`
+}
+
+// ListAccountActivities lists the account's dividends, fees, interest,
+// journals and transfers in a window, optionally of one category
+// (GET /account/activities)
+func (c *Client) ListAccountActivities(ctx context.Context, start, end time.Time, category string) (*AccountActivities, error) {
+	query := reportWindow(start, end)
+	if category != "" {
+		query.Set("category", category)
+	}
+	var activities AccountActivities
+	if err := c.get(ctx, "/account/activities", query, &activities); err != nil {
+		return nil, err
+	}
+	return &activities, nil
+}
+
+// PortfolioHistoryPoint is the account equity at one point of the history.
+// Source is broker, or snapshot when filled in from a local snapshot.
+type PortfolioHistoryPoint struct {
+	Time          time.Time       `json:"time"`
+	Equity        decimal.Decimal `json:"equity"`
+	ProfitLoss    decimal.Decimal `json:"profit_loss"`
+	ProfitLossPct float64         `json:"profit_loss_pct"`
+	Source        string          `json:"source"`
+}
+
+// TradeMarker is a filled order placed on the equity curve; PointIndex is
+// the point it falls on, -1 when before them all
+type TradeMarker struct {
+	Time       time.Time       `json:"time"`
+	OrderID    string          `json:"order_id"`
+	Symbol     string          `json:"symbol"`
+	Side       string          `json:"side"`
+	Qty        decimal.Decimal `json:"qty"`
+	Price      decimal.Decimal `json:"price"`
+	Strategy   string          `json:"strategy,omitempty"`
+	Book       string          `json:"book,omitempty"`
+	PointIndex int             `json:"point_index"`
+}
+
+// PortfolioHistory is the account equity curve with the trades made along it
+type PortfolioHistory struct {
+	Start     time.Time                `json:"start"`
+	End       time.Time                `json:"end"`
+	Timeframe string                   `json:"timeframe"`
+	BaseValue decimal.Decimal          `json:"base_value"`
+	Points    []*PortfolioHistoryPoint `json:"points"`
+	Trades    []*TradeMarker           `json:"trades"`
+	Filled    int                      `json:"filled_from_snapshots"`
+	Warnings  []string                 `json:"warnings,omitempty"`
+}
+
+// GetAccountHistory returns the account equity curve at timeframe (1Min,
+// 5Min, 15Min, 1H or 1D, the default when empty) with trade markers
+// (GET /account/history)
+func (c *Client) GetAccountHistory(ctx context.Context, start, end time.Time, timeframe string) (*PortfolioHistory, error) {
+	query := reportWindow(start, end)
+	if timeframe != "" {
+		query.Set("timeframe", timeframe)
+	}
+	var history PortfolioHistory
+	if err := c.get(ctx, "/account/history", query, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// StressScenario is a hypothetical shock applied to every open position
+type StressScenario struct {
+	Name           string             `json:"name"`
+	MarketShockPct float64            `json:"market_shock_percent"`
+	IVShockPct     float64            `json:"iv_shock_percent"`
+	SymbolShocks   map[string]float64 `json:"symbol_shocks_percent,omitempty"`
+}
+
+// StressRequest configures a stress test; zero values use server defaults
+type StressRequest struct {
+	Scenarios    []StressScenario `json:"scenarios,omitempty"`
+	Confidence   float64          `json:"confidence,omitempty"`
+	LookbackDays int              `json:"lookback_days,omitempty"`
+}
+
+// PositionStress is the estimated P&L of one position under a scenario
+type PositionStress struct {
+	Symbol      string  `json:"symbol"`
+	Underlying  string  `json:"underlying"`
+	AssetClass  string  `json:"asset_class"`
+	MarketValue float64 `json:"market_value"`
+	PriceShock  float64 `json:"price_shock_percent"`
+	PnL         float64 `json:"pnl"`
+	Method      string  `json:"method"`
+}
+
+// ScenarioResult is the portfolio impact of one scenario
+type ScenarioResult struct {
+	Scenario  StressScenario   `json:"scenario"`
+	PnL       float64          `json:"pnl"`
+	PnLPct    float64          `json:"pnl_percent"`
+	Positions []PositionStress `json:"positions"`
+}
+
+// HistoricalVaR is the one-day value at risk from replaying past returns
+type HistoricalVaR struct {
+	Confidence        float64 `json:"confidence"`
+	LookbackDays      int     `json:"lookback_days"`
+	Observations      int     `json:"observations"`
+	VaR               float64 `json:"var"`
+	VaRPct            float64 `json:"var_percent"`
+	ExpectedShortfall float64 `json:"expected_shortfall"`
+	WorstDay          float64 `json:"worst_day"`
+}
+
+// StressReport is returned by POST /risk/stress
+type StressReport struct {
+	GeneratedAt           time.Time        `json:"generated_at"`
+	PortfolioValue        float64          `json:"portfolio_value"`
+	GrossExposure         float64          `json:"gross_exposure"`
+	NetExposure           float64          `json:"net_exposure"`
+	DeltaAdjustedExposure float64          `json:"delta_adjusted_exposure"`
+	Scenarios             []ScenarioResult `json:"scenarios"`
+	WorstScenario         string           `json:"worst_scenario"`
+	VaR                   *HistoricalVaR   `json:"var,omitempty"`
+	Warnings              []string         `json:"warnings,omitempty"`
+}
+
+// RunStressTest applies shock scenarios to current positions (POST /risk/stress)
+func (c *Client) RunStressTest(ctx context.Context, req *StressRequest) (*StressReport, error) {
+	if req == nil {
+		req = &StressRequest{}
+	}
+	var report StressReport
+	if err := c.post(ctx, "/risk/stress", req, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// MarginStatus is the account's margin position at one point in time
+type MarginStatus struct {
+	Time              time.Time       `json:"time"`
+	Equity            decimal.Decimal `json:"equity"`
+	BuyingPower       decimal.Decimal `json:"buying_power"`
+	RegTBuyingPower   decimal.Decimal `json:"regt_buying_power"`
+	Multiplier        decimal.Decimal `json:"multiplier"`
+	InitialMargin     decimal.Decimal `json:"initial_margin"`
+	MaintenanceMargin decimal.Decimal `json:"maintenance_margin"`
+	UtilizationPct    float64         `json:"utilization_pct"`
+}
+
+// MarginReport is the current margin status with its recorded history
+type MarginReport struct {
+	Current      *MarginStatus   `json:"current"`
+	Thresholds   []float64       `json:"thresholds_pct"`
+	AlertLevel   float64         `json:"alert_level_pct"`
+	History      []*MarginStatus `json:"history"`
+	HistoryStart time.Time       `json:"history_start"`
+	HistoryEnd   time.Time       `json:"history_end"`
+}
+
+// GetMargin returns current margin utilization and its history between
+// start and end (GET /account/margin)
+func (c *Client) GetMargin(ctx context.Context, start, end time.Time) (*MarginReport, error) {
+	var report MarginReport
+	if err := c.get(ctx, "/account/margin", reportWindow(start, end), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// SymbolRule is the set of trading restrictions for one symbol. Zero limits
+// and an empty order type list mean no restriction.
+type SymbolRule struct {
+	Symbol            string          `json:"symbol,omitempty"`
+	Blocked           bool            `json:"blocked"`
+	MaxPositionQty    decimal.Decimal `json:"max_position_qty"`
+	MaxPositionValue  decimal.Decimal `json:"max_position_value"`
+	AllowedOrderTypes []string        `json:"allowed_order_types,omitempty"`
+	MinPrice          decimal.Decimal `json:"min_price"`
+	LongOnly          bool            `json:"long_only"`
+	Note              string          `json:"note,omitempty"`
+}
+
+// EffectiveSymbolRule is the rule that applies to a symbol, combining its
+// stored rule with the configured blocklist and minimum price
+type EffectiveSymbolRule struct {
+	SymbolRule
+	Stored        bool   `json:"stored"`
+	BlockedReason string `json:"blocked_reason,omitempty"`
+}
+
+// ListSymbolRules returns the stored per-symbol rules and the configured
+// blocklist (GET /risk/symbol-rules)
+func (c *Client) ListSymbolRules(ctx context.Context) ([]*EffectiveSymbolRule, error) {
+	var resp struct {
+		Count int                    `json:"count"`
+		Rules []*EffectiveSymbolRule `json:"rules"`
+	}
+	if err := c.get(ctx, "/risk/symbol-rules", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Rules, nil
+}
+
+// GetSymbolRule returns the rule that applies to a symbol (GET /risk/symbol-rules/:symbol)
+func (c *Client) GetSymbolRule(ctx context.Context, symbol string) (*EffectiveSymbolRule, error) {
+	var rule EffectiveSymbolRule
+	if err := c.get(ctx, "/risk/symbol-rules/"+url.PathEscape(symbol), nil, &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SaveSymbolRule creates or replaces a symbol's rule (PUT /risk/symbol-rules/:symbol)
+func (c *Client) SaveSymbolRule(ctx context.Context, symbol string, rule SymbolRule) (*EffectiveSymbolRule, error) {
+	var effective EffectiveSymbolRule
+	if err := c.put(ctx, "/risk/symbol-rules/"+url.PathEscape(symbol), rule, &effective); err != nil {
+		return nil, err
+	}
+	return &effective, nil
+}
+
+// DeleteSymbolRule removes a symbol's stored rule (DELETE /risk/symbol-rules/:symbol)
+func (c *Client) DeleteSymbolRule(ctx context.Context, symbol string) error {
+	return c.delete(ctx, "/risk/symbol-rules/"+url.PathEscape(symbol), nil)
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"prophet-trader/interfaces"
+)
+
+// QuoteUpdate is delivered by WatchQuotes for every poll of a symbol
+type QuoteUpdate struct {
+	Symbol string
+	Quote  *interfaces.Quote
+	Err    error
+}
+
+// WatchQuotes polls the latest quote for each symbol every interval and
+// streams the results until ctx is cancelled. The channel is closed on exit.
+func (c *Client) WatchQuotes(ctx context.Context, symbols []string, interval time.Duration) <-chan QuoteUpdate {
+	updates := make(chan QuoteUpdate)
+
+	go func() {
+		defer close(updates)
+		poll(ctx, interval, func() bool {
+			for _, symbol := range symbols {
+				quote, err := c.GetQuote(ctx, symbol)
+				select {
+				case updates <- QuoteUpdate{Symbol: symbol, Quote: quote, Err: err}:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		})
+	}()
+
+	return updates
+}
+
+// ManagedPositionsUpdate is delivered by WatchManagedPositions on every poll
+type ManagedPositionsUpdate struct {
+	Positions []*ManagedPosition
+	Err       error
+}
+
+// WatchManagedPositions polls managed positions with the given status filter
+// every interval until ctx is cancelled. The channel is closed on exit.
+func (c *Client) WatchManagedPositions(ctx context.Context, status string, interval time.Duration) <-chan ManagedPositionsUpdate {
+	updates := make(chan ManagedPositionsUpdate)
+
+	go func() {
+		defer close(updates)
+		poll(ctx, interval, func() bool {
+			positions, err := c.ListManagedPositions(ctx, status)
+			select {
+			case updates <- ManagedPositionsUpdate{Positions: positions, Err: err}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return updates
+}
+
+// poll runs fn immediately and then on every tick until ctx is done or fn returns false
+func poll(ctx context.Context, interval time.Duration, fn func() bool) {
+	if !fn() {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !fn() {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"prophet-trader/interfaces"
+)
+
+// Buy places a buy order (POST /orders/buy)
+func (c *Client) Buy(ctx context.Context, req OrderRequest) (*OrderResponse, error) {
+	var result OrderResponse
+	if err := c.post(ctx, "/orders/buy", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Sell places a sell order (POST /orders/sell)
+func (c *Client) Sell(ctx context.Context, req OrderRequest) (*OrderResponse, error) {
+	var result OrderResponse
+	if err := c.post(ctx, "/orders/sell", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SubmitBasket risk-checks several orders together and submits them
+// (POST /orders/basket)
+func (c *Client) SubmitBasket(ctx context.Context, req BasketRequest) (*BasketResult, error) {
+	var result BasketResult
+	if err := c.post(ctx, "/orders/basket", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CancelOrder cancels an open order (DELETE /orders/:id)
+func (c *Client) CancelOrder(ctx context.Context, orderID string) error {
+	return c.delete(ctx, "/orders/"+url.PathEscape(orderID), nil)
+}
+
+// ListOrders lists all orders, optionally filtered by status, following
+// pagination to the last page (GET /orders)
+func (c *Client) ListOrders(ctx context.Context, status string) ([]*interfaces.Order, error) {
+	var all []*interfaces.Order
+	opts := ListOptions{Limit: 500}
+	for {
+		orders, page, err := c.ListOrdersPage(ctx, status, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, orders...)
+		if !page.HasMore {
+			return all, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// ListOrdersPage returns one page of orders (GET /orders)
+func (c *Client) ListOrdersPage(ctx context.Context, status string, opts ListOptions) ([]*interfaces.Order, *PageInfo, error) {
+	query := opts.values()
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	var resp struct {
+		Orders     []*interfaces.Order `json:"orders"`
+		Pagination PageInfo            `json:"pagination"`
+	}
+	if err := c.get(ctx, "/orders", query, &resp); err != nil {
+		return nil, nil, err
+	}
+	return resp.Orders, &resp.Pagination, nil
+}
+
+// GetOrderAudit returns every recorded attempt for an order (GET /orders/:id/audit)
+func (c *Client) GetOrderAudit(ctx context.Context, orderID string) ([]OrderAudit, error) {
+	var resp struct {
+		Audits []OrderAudit `json:"audits"`
+	}
+	if err := c.get(ctx, "/orders/"+url.PathEscape(orderID)+"/audit", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Audits, nil
+}
+
+// GetOrderFills returns an order's executions, oldest first (GET /orders/:id/audit)
+func (c *Client) GetOrderFills(ctx context.Context, orderID string) ([]OrderFill, error) {
+	var resp struct {
+		Fills []OrderFill `json:"fills"`
+	}
+	if err := c.get(ctx, "/orders/"+url.PathEscape(orderID)+"/audit", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Fills, nil
+}
+
+// ListOrderChases lists pegged orders being chased and recently finished
+// chases, newest first (GET /orders/chases)
+func (c *Client) ListOrderChases(ctx context.Context) ([]*OrderChase, error) {
+	var resp struct {
+		Count  int           `json:"count"`
+		Chases []*OrderChase `json:"chases"`
+	}
+	if err := c.get(ctx, "/orders/chases", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Chases, nil
+}
+
+// GetPositions lists open broker positions (GET /positions)
+func (c *Client) GetPositions(ctx context.Context) ([]*interfaces.Position, error) {
+	var positions []*interfaces.Position
+	if err := c.get(ctx, "/positions", nil, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// GetAccount returns account balances (GET /account)
+func (c *Client) GetAccount(ctx context.Context) (*interfaces.Account, error) {
+	var account interfaces.Account
+	if err := c.get(ctx, "/account", nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetQuote returns the latest quote for a symbol (GET /market/quote/:symbol)
+func (c *Client) GetQuote(ctx context.Context, symbol string) (*interfaces.Quote, error) {
+	var quote interfaces.Quote
+	if err := c.get(ctx, "/market/quote/"+url.PathEscape(symbol), nil, &quote); err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// GetLatestBar returns the latest bar for a symbol (GET /market/bar/:symbol)
+func (c *Client) GetLatestBar(ctx context.Context, symbol string) (*interfaces.Bar, error) {
+	var bar interfaces.Bar
+	if err := c.get(ctx, "/market/bar/"+url.PathEscape(symbol), nil, &bar); err != nil {
+		return nil, err
+	}
+	return &bar, nil
+}
+
+// GetBars returns historical bars (GET /market/bars/:symbol). Zero start/end
+// times and an empty timeframe fall back to the server defaults.
+func (c *Client) GetBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) (*BarsResponse, error) {
+	return c.GetBarsWithOptions(ctx, symbol, start, end, timeframe, BarOptions{})
+}
+
+// GetBarsWithOptions returns historical bars with the given adjustment and
+// feed (GET /market/bars/:symbol)
+func (c *Client) GetBarsWithOptions(ctx context.Context, symbol string, start, end time.Time, timeframe string, opts BarOptions) (*BarsResponse, error) {
+	query := url.Values{}
+	if opts.Adjustment != "" {
+		query.Set("adjustment", opts.Adjustment)
+	}
+	if opts.Feed != "" {
+		query.Set("feed", opts.Feed)
+	}
+	if !start.IsZero() {
+		query.Set("start", start.Format("2006-01-02"))
+	}
+	if !end.IsZero() {
+		query.Set("end", end.Format("2006-01-02"))
+	}
+	if timeframe != "" {
+		query.Set("timeframe", timeframe)
+	}
+
+	var resp BarsResponse
+	if err := c.get(ctx, "/market/bars/"+url.PathEscape(symbol), query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListAggregatedBars returns the symbols and custom bars built live from
+// streamed trades (GET /market/aggregated-bars)
+func (c *Client) ListAggregatedBars(ctx context.Context) ([]AggregatedSeries, error) {
+	var resp struct {
+		Series []AggregatedSeries `json:"series"`
+	}
+	if err := c.get(ctx, "/market/aggregated-bars", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Series, nil
+}
+
+// GetAggregatedBars returns the recent custom bars of spec, such as
+// "500Tick", built live for symbol (GET /market/aggregated-bars/:symbol)
+func (c *Client) GetAggregatedBars(ctx context.Context, symbol, spec string) (*AggregatedBarsResponse, error) {
+	var resp AggregatedBarsResponse
+	if err := c.get(ctx, "/market/aggregated-bars/"+url.PathEscape(symbol), url.Values{"bars": {spec}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListTimeframes returns the timeframes GetBars accepts (GET /market/timeframes)
+func (c *Client) ListTimeframes(ctx context.Context) (*Timeframes, error) {
+	var timeframes Timeframes
+	if err := c.get(ctx, "/market/timeframes", nil, &timeframes); err != nil {
+		return nil, err
+	}
+	return &timeframes, nil
+}
+
+// GetMarketHours returns the trading session each asset class is in now
+// (GET /market/hours)
+func (c *Client) GetMarketHours(ctx context.Context) (*MarketHours, error) {
+	var hours MarketHours
+	if err := c.get(ctx, "/market/hours", nil, &hours); err != nil {
+		return nil, err
+	}
+	return &hours, nil
+}
+
+// GetDataProviders reports which market data provider served recent
+// requests (GET /market/providers)
+func (c *Client) GetDataProviders(ctx context.Context) (*DataProviderStats, error) {
+	var stats DataProviderStats
+	if err := c.get(ctx, "/market/providers", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// ListSymbolAliases returns the tickers that changed, with the symbols they
+// trade under now (GET /symbols/aliases)
+func (c *Client) ListSymbolAliases(ctx context.Context) ([]*SymbolAlias, error) {
+	var resp struct {
+		Count   int            `json:"count"`
+		Aliases []*SymbolAlias `json:"aliases"`
+	}
+	if err := c.get(ctx, "/symbols/aliases", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Aliases, nil
+}
+
+// AddSymbolAlias records a ticker change, moving the old symbol's stored
+// history and managed positions to the new one (POST /symbols/aliases)
+func (c *Client) AddSymbolAlias(ctx context.Context, req SymbolAliasRequest) (*SymbolAlias, error) {
+	var alias SymbolAlias
+	if err := c.post(ctx, "/symbols/aliases", req, &alias); err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// PlaceOptionsOrder places an options order (POST /options/order)
+func (c *Client) PlaceOptionsOrder(ctx context.Context, req OptionsOrderRequest) (*OrderResponse, error) {
+	var result OrderResponse
+	if err := c.post(ctx, "/options/order", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListOptionsPositions lists open options positions with their greeks
+// (GET /options/positions)
+func (c *Client) ListOptionsPositions(ctx context.Context) ([]*OptionsPosition, error) {
+	var positions []*OptionsPosition
+	if err := c.get(ctx, "/options/positions", nil, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// GetOptionsExposure returns the net greeks of the options book per
+// underlying and in total (GET /options/exposure)
+func (c *Client) GetOptionsExposure(ctx context.Context) (*OptionsExposure, error) {
+	var exposure OptionsExposure
+	if err := c.get(ctx, "/options/exposure", nil, &exposure); err != nil {
+		return nil, err
+	}
+	return &exposure, nil
+}
+
+// GetCoveredCalls returns the covered calls held and their recent
+// assignments and expirations (GET /options/covered-calls)
+func (c *Client) GetCoveredCalls(ctx context.Context) (*CoveredCallsResponse, error) {
+	var result CoveredCallsResponse
+	if err := c.get(ctx, "/options/covered-calls", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AnalyzeOptions returns the probability of profit, breakevens and
+// bounds of a proposed options structure (POST /options/analyze)
+func (c *Client) AnalyzeOptions(ctx context.Context, req OptionsAnalysisRequest) (*OptionsAnalysis, error) {
+	var analysis OptionsAnalysis
+	if err := c.post(ctx, "/options/analyze", req, &analysis); err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
+
+// GetExpectedMove returns the move priced into the at-the-money straddle.
+// A zero expiration means next Friday's (GET /options/expected-move/:symbol).
+func (c *Client) GetExpectedMove(ctx context.Context, symbol string, expiration time.Time) (*ExpectedMove, error) {
+	query := url.Values{}
+	if !expiration.IsZero() {
+		query.Set("expiration", expiration.Format("2006-01-02"))
+	}
+	var move ExpectedMove
+	if err := c.get(ctx, "/options/expected-move/"+url.PathEscape(symbol), query, &move); err != nil {
+		return nil, err
+	}
+	return &move, nil
+}
+
+// GetOptionsPosition returns a single options position (GET /options/position/:symbol)
+func (c *Client) GetOptionsPosition(ctx context.Context, symbol string) (*interfaces.OptionsPosition, error) {
+	var position interfaces.OptionsPosition
+	if err := c.get(ctx, "/options/position/"+url.PathEscape(symbol), nil, &position); err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+// GetOptionsChain returns a filtered options chain (GET /options/chain/:symbol)
+func (c *Client) GetOptionsChain(ctx context.Context, symbol string, filter OptionsChainFilter) (*OptionsChainResponse, error) {
+	query := url.Values{}
+	if !filter.Expiration.IsZero() {
+		query.Set("expiration", filter.Expiration.Format("2006-01-02"))
+	}
+	if filter.DeltaMin != nil {
+		query.Set("delta_min", strconv.FormatFloat(*filter.DeltaMin, 'f', -1, 64))
+	}
+	if filter.DeltaMax != nil {
+		query.Set("delta_max", strconv.FormatFloat(*filter.DeltaMax, 'f', -1, 64))
+	}
+	if filter.MinBid != nil {
+		query.Set("min_bid", strconv.FormatFloat(*filter.MinBid, 'f', -1, 64))
+	}
+	if filter.Type != "" {
+		query.Set("type", filter.Type)
+	}
+
+	var resp OptionsChainResponse
+	if err := c.get(ctx, "/options/chain/"+url.PathEscape(symbol), query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,871 @@
+package client
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"prophet-trader/interfaces"
+
+	"github.com/shopspring/decimal"
+)
+
+// OrderRequest is the body of POST /orders/buy and POST /orders/sell
+type OrderRequest struct {
+	Symbol      string            `json:"symbol"`
+	Qty         decimal.Decimal   `json:"qty"`
+	Type        string            `json:"type,omitempty"`          // "market", "limit", "stop", "stop_limit"
+	TimeInForce string            `json:"time_in_force,omitempty"` // "day", "gtc", "ioc", "fok"
+	LimitPrice  *decimal.Decimal  `json:"limit_price,omitempty"`
+	StopPrice   *decimal.Decimal  `json:"stop_price,omitempty"`
+	Strategy    string            `json:"strategy,omitempty"` // P&L attribution tag
+	Tags        []string          `json:"tags,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`   // e.g. signal_id, echoed on listings and audits
+	Peg         *PegRequest       `json:"peg,omitempty"`        // place a limit at the quote and re-peg it until it fills
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"` // good-till-date for an order held in the order queue
+	DryRun      bool              `json:"dry_run,omitempty"`
+}
+
+// PegRequest makes an order a limit that follows the quote. Leave Type and
+// LimitPrice unset.
+type PegRequest struct {
+	Reference      string          `json:"reference,omitempty"` // "mid" (default), "bid" or "ask"
+	Offset         decimal.Decimal `json:"offset"`              // dollars toward the other side of the book
+	MaxChase       decimal.Decimal `json:"max_chase"`           // dollars the limit may move from its first price, zero for no limit
+	TimeoutSeconds int             `json:"timeout_seconds,omitempty"`
+	Fallback       string          `json:"fallback,omitempty"` // "market" (default) or "cancel"
+}
+
+// OrderChase is a pegged order being re-priced as the quote moves
+type OrderChase struct {
+	ID            string           `json:"id"`
+	OrderID       string           `json:"order_id"`
+	MarketOrderID string           `json:"market_order_id,omitempty"`
+	Symbol        string           `json:"symbol"`
+	Side          string           `json:"side"`
+	Qty           decimal.Decimal  `json:"qty"`
+	Book          string           `json:"book"`
+	Peg           PegRequest       `json:"peg"`
+	StartPrice    decimal.Decimal  `json:"start_price"`
+	LimitPrice    decimal.Decimal  `json:"limit_price"`
+	Bound         *decimal.Decimal `json:"bound,omitempty"`
+	Repegs        int              `json:"repegs"`
+	FilledQty     decimal.Decimal  `json:"filled_qty"`
+	Status        string           `json:"status"` // CHASING, FILLED, MARKET, CANCELED or FAILED
+	Reason        string           `json:"reason,omitempty"`
+	StartedAt     time.Time        `json:"started_at"`
+	Deadline      time.Time        `json:"deadline"`
+	EndedAt       *time.Time       `json:"ended_at,omitempty"`
+}
+
+// OrderResponse is returned by the order endpoints. In dry-run mode OrderID
+// is empty and Request holds the body that would have been sent to Alpaca.
+type OrderResponse struct {
+	interfaces.OrderResult
+	DryRun  bool            `json:"dry_run,omitempty"`
+	Request json.RawMessage `json:"request,omitempty"`
+	Risk    json.RawMessage `json:"risk,omitempty"`
+	Chase   *OrderChase     `json:"chase,omitempty"`
+}
+
+// BasketRequest is the body of POST /orders/basket
+type BasketRequest struct {
+	Orders    []BasketLeg `json:"orders"`
+	AllOrNone bool        `json:"all_or_none,omitempty"` // submit nothing, or cancel what was submitted, if any leg is rejected
+	DryRun    bool        `json:"dry_run,omitempty"`
+}
+
+// BasketLeg is one order of a basket
+type BasketLeg struct {
+	Symbol      string           `json:"symbol"`
+	Side        string           `json:"side"` // "buy" or "sell"
+	Qty         decimal.Decimal  `json:"qty"`
+	Type        string           `json:"type,omitempty"`
+	TimeInForce string           `json:"time_in_force,omitempty"`
+	LimitPrice  *decimal.Decimal `json:"limit_price,omitempty"`
+	StopPrice   *decimal.Decimal `json:"stop_price,omitempty"`
+	Strategy    string           `json:"strategy,omitempty"`
+}
+
+// BasketResult reports what happened to every leg of a basket
+type BasketResult struct {
+	BasketID  string             `json:"basket_id"` // stored as basket_id metadata on every leg
+	Status    string             `json:"status"`    // SUBMITTED, PARTIAL, REJECTED or DRY_RUN
+	AllOrNone bool               `json:"all_or_none"`
+	Risk      json.RawMessage    `json:"risk"`
+	Legs      []*BasketLegResult `json:"legs"`
+	Submitted int                `json:"submitted"`
+}
+
+// BasketLegResult is the outcome of one basket leg
+type BasketLegResult struct {
+	Index   int             `json:"index"`
+	Symbol  string          `json:"symbol"`
+	Side    string          `json:"side"`
+	Qty     decimal.Decimal `json:"qty"`
+	Status  string          `json:"status"` // submitted, rejected, failed, skipped, canceled or dry_run
+	OrderID string          `json:"order_id,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Risk    json.RawMessage `json:"risk,omitempty"`
+}
+
+// OrderAudit is one recorded order attempt. Payload fields hold raw JSON.
+type OrderAudit struct {
+	ID              uint      `json:"ID"`
+	CreatedAt       time.Time `json:"CreatedAt"`
+	OrderID         string    `json:"OrderID"`
+	Source          string    `json:"Source"`
+	Symbol          string    `json:"Symbol"`
+	Side            string    `json:"Side"`
+	AssetClass      string    `json:"AssetClass"`
+	Outcome         string    `json:"Outcome"`
+	RequestPayload  string    `json:"RequestPayload"`
+	RiskDecision    string    `json:"RiskDecision"`
+	ResponsePayload string    `json:"ResponsePayload"`
+	Error           string    `json:"Error"`
+	LatencyMs       int64     `json:"LatencyMs"`
+	Tags            string    `json:"Tags"`     // JSON array
+	Metadata        string    `json:"Metadata"` // JSON object
+}
+
+// OrderFill is one execution of an order, with the order's running filled
+// quantity and average price after it
+type OrderFill struct {
+	ID             uint            `json:"ID"`
+	OrderID        string          `json:"OrderID"`
+	ExecutionID    string          `json:"ExecutionID"`
+	Symbol         string          `json:"Symbol"`
+	Side           string          `json:"Side"`
+	Event          string          `json:"Event"` // partial_fill or fill
+	Qty            decimal.Decimal `json:"Qty"`
+	Price          decimal.Decimal `json:"Price"`
+	FilledQty      decimal.Decimal `json:"FilledQty"`
+	FilledAvgPrice decimal.Decimal `json:"FilledAvgPrice"`
+	FilledAt       time.Time       `json:"FilledAt"`
+	Inferred       bool            `json:"Inferred"` // derived from polling rather than reported by the broker
+}
+
+// OptionsOrderRequest is the body of POST /options/order
+type OptionsOrderRequest struct {
+	Symbol         string            `json:"symbol"`
+	Underlying     string            `json:"underlying,omitempty"`
+	Qty            decimal.Decimal   `json:"qty"`
+	Side           string            `json:"side"`                      // "buy" or "sell"
+	PositionIntent string            `json:"position_intent,omitempty"` // "buy_to_open", "sell_to_close", ...
+	Type           string            `json:"type,omitempty"`
+	TimeInForce    string            `json:"time_in_force,omitempty"`
+	LimitPrice     *decimal.Decimal  `json:"limit_price,omitempty"`
+	Tags           []string          `json:"tags,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	DryRun         bool              `json:"dry_run,omitempty"`
+}
+
+// BarsResponse is returned by GET /market/bars/:symbol
+type BarsResponse struct {
+	Symbol     string            `json:"symbol"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Timeframe  string            `json:"timeframe"`
+	Adjustment string            `json:"adjustment,omitempty"` // empty when the provider default was used
+	Feed       string            `json:"feed,omitempty"`
+	Count      int               `json:"count"`
+	Bars       []*interfaces.Bar `json:"bars"`
+}
+
+// AggregatedSeries lists the custom bar specs built live for a symbol
+type AggregatedSeries struct {
+	Symbol string   `json:"symbol"`
+	Specs  []string `json:"specs"`
+}
+
+// AggregatedBarsResponse is returned by GET /market/aggregated-bars/:symbol
+type AggregatedBarsResponse struct {
+	Symbol string            `json:"symbol"`
+	Spec   string            `json:"spec"`
+	Count  int               `json:"count"`
+	Bars   []*interfaces.Bar `json:"bars"`
+}
+
+// BarOptions override the provider's default adjustment (raw, split,
+// dividend or all) and feed for one bars request
+type BarOptions struct {
+	Adjustment string
+	Feed       string
+}
+
+// TimeframeUnit describes one timeframe unit, the amounts it takes and the
+// aliases it may be written with
+type TimeframeUnit struct {
+	Unit    string   `json:"unit"`
+	Amounts []int    `json:"amounts"`
+	Aliases []string `json:"aliases"`
+}
+
+// Timeframes is returned by GET /market/timeframes
+type Timeframes struct {
+	Common []string        `json:"common"` // served by every data provider
+	Units  []TimeframeUnit `json:"units"`
+}
+
+// Trading sessions reported by GET /market/hours
+const (
+	SessionClosed     = "closed"
+	SessionPreMarket  = "pre_market"
+	SessionRegular    = "regular"
+	SessionAfterHours = "after_hours"
+	SessionContinuous = "continuous"
+)
+
+// MarketHours is returned by GET /market/hours
+type MarketHours struct {
+	Time           time.Time         `json:"time"`            // now, in New York time
+	Sessions       map[string]string `json:"sessions"`        // us_equity, us_option and crypto -> session
+	Today          *MarketDay        `json:"today,omitempty"` // nil on weekends and holidays
+	BrokerClock    bool              `json:"broker_clock"`    // the broker's clock decides whether the market is open
+	BrokerCalendar bool              `json:"broker_calendar"` // holidays and early closes come from the broker
+}
+
+// MarketDay is the regular session of one trading day, which closes early
+// on half-days
+type MarketDay struct {
+	Open  time.Time `json:"open"`
+	Close time.Time `json:"close"`
+}
+
+// DataProviderUsage records which market data provider served one request
+type DataProviderUsage struct {
+	Method    string    `json:"method"`
+	Symbol    string    `json:"symbol"`
+	Provider  string    `json:"provider"`
+	Failed    []string  `json:"failed,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// DataProviderStats is returned by GET /market/providers
+type DataProviderStats struct {
+	Providers []string            `json:"providers"`
+	Served    map[string]int      `json:"served"`
+	Failures  map[string]int      `json:"failures"`
+	Recent    []DataProviderUsage `json:"recent"`
+}
+
+// OptionsChainFilter narrows GET /options/chain/:symbol results
+type OptionsChainFilter struct {
+	Expiration time.Time // zero value lets the server pick next Friday
+	DeltaMin   *float64
+	DeltaMax   *float64
+	MinBid     *float64
+	Type       string // "call" or "put"
+}
+
+// OptionsChainResponse is returned by GET /options/chain/:symbol
+type OptionsChainResponse struct {
+	Symbol     string                       `json:"symbol"`
+	Expiration string                       `json:"expiration"`
+	Total      int                          `json:"total"`
+	Filtered   int                          `json:"filtered"`
+	Contracts  []*interfaces.OptionContract `json:"contracts"`
+}
+
+// OptionGreeks are the greeks of one contract, from its quote ("quote") or
+// the server's Black-Scholes model ("model"), or "unavailable"
+type OptionGreeks struct {
+	Delta             float64 `json:"delta"`
+	Gamma             float64 `json:"gamma"`
+	Theta             float64 `json:"theta"`
+	Vega              float64 `json:"vega"`
+	ImpliedVolatility float64 `json:"implied_volatility"`
+	Source            string  `json:"source"`
+}
+
+// GreekExposure is net greeks in shares of the underlying, negative for
+// shorts
+type GreekExposure struct {
+	Delta        float64 `json:"delta"`
+	Gamma        float64 `json:"gamma"`
+	Theta        float64 `json:"theta"`
+	Vega         float64 `json:"vega"`
+	DeltaDollars float64 `json:"delta_dollars"`
+}
+
+// OptionsPosition is returned by GET /options/positions
+type OptionsPosition struct {
+	interfaces.OptionsPosition
+	UnderlyingPrice float64       `json:"underlying_price"`
+	DaysToExpiry    float64       `json:"days_to_expiry"`
+	Greeks          OptionGreeks  `json:"greeks"`
+	Exposure        GreekExposure `json:"exposure"`
+}
+
+// UnderlyingExposure is the net exposure of every option on one underlying
+type UnderlyingExposure struct {
+	Underlying      string  `json:"underlying"`
+	UnderlyingPrice float64 `json:"underlying_price"`
+	Positions       int     `json:"positions"`
+	Contracts       float64 `json:"contracts"`
+	MarketValue     float64 `json:"market_value"`
+	UnrealizedPL    float64 `json:"unrealized_pl"`
+	GreekExposure
+}
+
+// OptionsExposure is returned by GET /options/exposure
+type OptionsExposure struct {
+	GeneratedAt  time.Time             `json:"generated_at"`
+	Positions    int                   `json:"positions"`
+	MarketValue  float64               `json:"market_value"`
+	UnrealizedPL float64               `json:"unrealized_pl"`
+	Total        GreekExposure         `json:"total"`
+	Underlyings  []*UnderlyingExposure `json:"underlyings"`
+	Warnings     []string              `json:"warnings,omitempty"`
+}
+
+// CoveredCall is a short call paired with the long shares covering it
+type CoveredCall struct {
+	Underlying   string    `json:"underlying"`
+	CallSymbol   string    `json:"call_symbol"`
+	Strike       float64   `json:"strike"`
+	Expiration   time.Time `json:"expiration"`
+	Contracts    float64   `json:"contracts"`
+	Shares       float64   `json:"shares"`
+	ShareCost    float64   `json:"share_cost"`
+	SharePrice   float64   `json:"share_price"`
+	Premium      float64   `json:"premium"`
+	CallPrice    float64   `json:"call_price"`
+	SharePnL     float64   `json:"share_pnl"`
+	CallPnL      float64   `json:"call_pnl"`
+	CombinedPnL  float64   `json:"combined_pnl"`
+	MaxProfit    float64   `json:"max_profit"`
+	InTheMoney   bool      `json:"in_the_money"`
+	DaysToExpiry int       `json:"days_to_expiry"`
+}
+
+// CoveredCallEvent is a covered call that was assigned, expired or
+// disappeared ("unexplained")
+type CoveredCallEvent struct {
+	Type             string    `json:"type"`
+	Underlying       string    `json:"underlying"`
+	CallSymbol       string    `json:"call_symbol"`
+	Strike           float64   `json:"strike"`
+	Expiration       time.Time `json:"expiration"`
+	Contracts        float64   `json:"contracts"`
+	SharesDelivered  float64   `json:"shares_delivered,omitempty"`
+	ShareCost        float64   `json:"share_cost"`
+	Premium          float64   `json:"premium"`
+	SharePnL         float64   `json:"share_pnl"`
+	PremiumPnL       float64   `json:"premium_pnl"`
+	CombinedPnL      float64   `json:"combined_pnl"`
+	ManagedPositions []string  `json:"managed_positions,omitempty"`
+	DetectedAt       time.Time `json:"detected_at"`
+	Message          string    `json:"message"`
+}
+
+// CoveredCallsResponse is returned by GET /options/covered-calls
+type CoveredCallsResponse struct {
+	CoveredCalls []*CoveredCall     `json:"covered_calls"`
+	Count        int                `json:"count"`
+	Events       []CoveredCallEvent `json:"events"`
+}
+
+// OptionsAnalysisLeg is one option of a structure sent to AnalyzeOptions
+type OptionsAnalysisLeg struct {
+	Symbol string  `json:"symbol"`
+	Side   string  `json:"side"`            // "buy" or "sell"
+	Qty    float64 `json:"qty"`             // contracts, default 1
+	Price  float64 `json:"price,omitempty"` // per share, defaults to the quote midpoint
+}
+
+// OptionsAnalysisRequest is the body of POST /options/analyze
+type OptionsAnalysisRequest struct {
+	Legs       []OptionsAnalysisLeg `json:"legs"`
+	Shares     float64              `json:"shares,omitempty"`     // underlying shares held with the structure
+	Volatility float64              `json:"volatility,omitempty"` // annualized, defaults to the straddle's
+}
+
+// AnalyzedLeg is a leg with its quote and the chance it expires in the money
+type AnalyzedLeg struct {
+	OptionsAnalysisLeg
+	Type              string    `json:"type"`
+	Strike            float64   `json:"strike"`
+	Expiration        time.Time `json:"expiration"`
+	Bid               float64   `json:"bid"`
+	Ask               float64   `json:"ask"`
+	ImpliedVolatility float64   `json:"implied_volatility"`
+	Delta             float64   `json:"delta"`
+	ProbITM           float64   `json:"prob_itm"`
+}
+
+// ExpectedMove is returned by GET /options/expected-move/:symbol
+type ExpectedMove struct {
+	Underlying        string    `json:"underlying"`
+	UnderlyingPrice   float64   `json:"underlying_price"`
+	Expiration        time.Time `json:"expiration"`
+	DaysToExpiry      float64   `json:"days_to_expiry"`
+	Strike            float64   `json:"strike"`
+	CallSymbol        string    `json:"call_symbol"`
+	PutSymbol         string    `json:"put_symbol"`
+	CallPrice         float64   `json:"call_price"`
+	PutPrice          float64   `json:"put_price"`
+	Move              float64   `json:"move"`
+	MovePct           float64   `json:"move_percent"`
+	Low               float64   `json:"low"`
+	High              float64   `json:"high"`
+	ImpliedVolatility float64   `json:"implied_volatility"`
+	OneSigmaMove      float64   `json:"one_sigma_move"`
+}
+
+// OptionsAnalysis is returned by POST /options/analyze. MaxProfit and
+// MaxLoss are nil when unbounded.
+type OptionsAnalysis struct {
+	Underlying          string         `json:"underlying"`
+	UnderlyingPrice     float64        `json:"underlying_price"`
+	Horizon             time.Time      `json:"horizon"`
+	DaysToExpiry        float64        `json:"days_to_expiry"`
+	Volatility          float64        `json:"volatility"`
+	VolatilitySource    string         `json:"volatility_source"`
+	Legs                []*AnalyzedLeg `json:"legs"`
+	Shares              float64        `json:"shares,omitempty"`
+	NetPremium          float64        `json:"net_premium"`
+	ProbabilityOfProfit float64        `json:"probability_of_profit"`
+	ExpectedPnL         float64        `json:"expected_pnl"`
+	MaxProfit           *float64       `json:"max_profit"`
+	MaxLoss             *float64       `json:"max_loss"`
+	Breakevens          []float64      `json:"breakevens"`
+	ExpectedMove        *ExpectedMove  `json:"expected_move,omitempty"`
+	Warnings            []string       `json:"warnings,omitempty"`
+}
+
+// NewsItem represents a single news article
+type NewsItem struct {
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Description string    `json:"description,omitempty"`
+	PubDate     string    `json:"pub_date,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	GUID        string    `json:"guid,omitempty"`
+	PublishedAt time.Time `json:"published_at,omitempty"`
+	Seq         uint64    `json:"seq"`
+}
+
+// NewsResponse is returned by the /news endpoints
+type NewsResponse struct {
+	Topic      string     `json:"topic,omitempty"`
+	Query      string     `json:"query,omitempty"`
+	Symbols    string     `json:"symbols,omitempty"`
+	Source     string     `json:"source,omitempty"`
+	Count      int        `json:"count"`
+	News       []NewsItem `json:"news"`
+	Pagination *PageInfo  `json:"pagination,omitempty"` // set by GET /news only
+	LatestSeq  uint64     `json:"latest_seq,omitempty"` // GET /news only: pass as ListOptions.SinceSeq
+}
+
+// AggregateNewsRequest is the body of POST /intelligence/cleaned-news
+type AggregateNewsRequest struct {
+	IncludeGoogle        bool     `json:"include_google"`
+	IncludeMarketWatch   bool     `json:"include_marketwatch"`
+	GoogleTopics         []string `json:"google_topics,omitempty"`
+	Symbols              []string `json:"symbols,omitempty"`
+	MaxArticlesPerSource int      `json:"max_articles_per_source,omitempty"`
+}
+
+// CleanedNews is a token-efficient news summary produced by the LLM
+type CleanedNews struct {
+	GeneratedAt      time.Time         `json:"generated_at"`
+	SourceCount      int               `json:"source_count"`
+	ArticleCount     int               `json:"article_count"`
+	MarketSentiment  string            `json:"market_sentiment"`
+	KeyThemes        []string          `json:"key_themes"`
+	StockMentions    map[string]string `json:"stock_mentions"`
+	ActionableItems  []string          `json:"actionable_items"`
+	ExecutiveSummary string            `json:"executive_summary"`
+	FullAnalysis     string            `json:"full_analysis"`
+}
+
+// CleanedNewsResponse is returned by POST /intelligence/cleaned-news
+type CleanedNewsResponse struct {
+	Message         string       `json:"message,omitempty"`
+	CleanedNews     *CleanedNews `json:"cleaned_news"`
+	RawArticleCount int          `json:"raw_article_count"`
+}
+
+// StockAnalysis is returned by the /intelligence/analyze endpoints
+type StockAnalysis struct {
+	Symbol         string                 `json:"symbol"`
+	Profile        string                 `json:"profile"`
+	CurrentPrice   float64                `json:"current_price"`
+	MarketCap      string                 `json:"market_cap_estimate"`
+	Technical      TechnicalAnalysis      `json:"technical"`
+	Timeframes     []*TimeframeTechnicals `json:"timeframes,omitempty"`
+	Fundamentals   *Fundamentals          `json:"fundamentals,omitempty"`
+	Social         *SocialSentiment       `json:"social,omitempty"`
+	NewsSummary    string                 `json:"news_summary"`
+	RelatedHistory []*SemanticMatch       `json:"related_history,omitempty"`
+	TradeSetup     TradeSetup             `json:"trade_setup"`
+	Timestamp      time.Time              `json:"timestamp"`
+}
+
+// SemanticMatch is a stored news item or analysis similar to a query
+type SemanticMatch struct {
+	Kind        string    `json:"kind"` // "news" or "analysis"
+	Title       string    `json:"title"`
+	Text        string    `json:"text,omitempty"`
+	Link        string    `json:"link,omitempty"`
+	Symbols     []string  `json:"symbols,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	Score       float64   `json:"score"`
+}
+
+// SemanticSearchResponse is returned by GET /news/semantic-search
+type SemanticSearchResponse struct {
+	Query    string           `json:"query"`
+	Provider string           `json:"provider"`
+	Indexed  int              `json:"indexed"`
+	Count    int              `json:"count"`
+	Results  []*SemanticMatch `json:"results"`
+}
+
+// TimeframeTechnicals summarizes one additional timeframe of an analysis
+// profile
+type TimeframeTechnicals struct {
+	Timeframe string  `json:"timeframe"`
+	Trend     string  `json:"trend"`
+	RSI       float64 `json:"rsi_14"`
+	ChangePct float64 `json:"change_percent"`
+	Bars      int     `json:"bars"`
+}
+
+// TechnicalAnalysis contains technical indicators for a stock
+type TechnicalAnalysis struct {
+	Price         float64  `json:"price"`
+	DayChange     float64  `json:"day_change_percent"`
+	Volume        int64    `json:"volume"`
+	AvgVolume     int64    `json:"avg_volume_30d"`
+	VolumeRatio   float64  `json:"volume_ratio"`
+	Trend         string   `json:"trend"`
+	Support       float64  `json:"support_level"`
+	Resistance    float64  `json:"resistance_level"`
+	Volatility    float64  `json:"volatility_30d"`
+	RSI           float64  `json:"rsi_14"`
+	PriceStrength string   `json:"price_strength"`
+	Patterns      []string `json:"patterns,omitempty"`
+}
+
+// SocialWindow is mention and sentiment activity over one rolling window
+type SocialWindow struct {
+	Window     string  `json:"window"`
+	Mentions   int     `json:"mentions"`
+	Bullish    int     `json:"bullish"`
+	Bearish    int     `json:"bearish"`
+	Sentiment  float64 `json:"sentiment"`
+	Engagement int     `json:"engagement"`
+}
+
+// SocialSentiment is returned by GET /intelligence/social/:symbol
+type SocialSentiment struct {
+	Symbol          string          `json:"symbol"`
+	Windows         []*SocialWindow `json:"windows"`
+	MentionVelocity float64         `json:"mention_velocity"`
+	Label           string          `json:"label"` // BULLISH, BEARISH, NEUTRAL, QUIET
+	Sources         map[string]int  `json:"sources"`
+	LastCollected   *time.Time      `json:"last_collected,omitempty"`
+	Timestamp       time.Time       `json:"timestamp"`
+}
+
+// SymbolBrief is the rolling news summary of one symbol
+type SymbolBrief struct {
+	Symbol    string     `json:"symbol"`
+	Summary   string     `json:"summary,omitempty"`
+	Sentiment float64    `json:"sentiment"` // mean headline score, -1 to 1
+	Headlines int        `json:"headlines"` // in the last 24 hours
+	Latest    []NewsItem `json:"latest"`
+	UpdatedAt time.Time  `json:"updated_at,omitempty"`
+}
+
+// MarketBrief is the market-wide and per-symbol news summary, also pushed
+// as market_brief events
+type MarketBrief struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Summary     string         `json:"summary"`
+	Headlines   int            `json:"headlines"`
+	Symbols     []*SymbolBrief `json:"symbols"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// MarketRegime is returned by GET /intelligence/regime
+type MarketRegime struct {
+	Regime string `json:"regime"` // risk_on, neutral, risk_off
+	Score  int    `json:"score"`
+	Trend  struct {
+		Price       float64 `json:"price"`
+		SMA50       float64 `json:"sma_50"`
+		SMA200      float64 `json:"sma_200"`
+		AboveSMA50  bool    `json:"above_sma_50"`
+		AboveSMA200 bool    `json:"above_sma_200"`
+		GoldenCross bool    `json:"golden_cross"`
+	} `json:"trend"`
+	Breadth struct {
+		Symbols       int      `json:"symbols"`
+		Advancing     int      `json:"advancing"`
+		Declining     int      `json:"declining"`
+		AdvanceRatio  float64  `json:"advance_ratio"`
+		AboveSMA50Pct float64  `json:"above_sma50_percent"`
+		Unavailable   []string `json:"unavailable,omitempty"`
+	} `json:"breadth"`
+	Volatility struct {
+		RealizedVol20d float64 `json:"realized_vol_20d"`
+		Level          string  `json:"level"`
+		VIXYPrice      float64 `json:"vixy_price,omitempty"`
+		VIXYvsSMA20Pct float64 `json:"vixy_vs_sma20_percent,omitempty"`
+	} `json:"volatility"`
+	Signals        []string  `json:"signals"`
+	SizeMultiplier float64   `json:"size_multiplier"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TradeSetup provides neutral trading data for a stock
+type TradeSetup struct {
+	Entry          float64  `json:"entry"`
+	StopLoss       float64  `json:"stop_loss"`
+	TakeProfit     float64  `json:"take_profit"`
+	RiskReward     float64  `json:"risk_reward"`
+	RecentNews     []string `json:"recent_news"`
+	KeyCatalysts   []string `json:"key_catalysts"`
+	TechnicalScore int      `json:"technical_score"`
+	CatalystScore  int      `json:"catalyst_score"`
+	VolumeScore    int      `json:"volume_score"`
+	CompositeScore int      `json:"composite_score"`
+	BelowThreshold bool     `json:"below_threshold,omitempty"`
+	Notes          string   `json:"notes"`
+}
+
+// PartialExitConfig defines a partial profit taking strategy
+type PartialExitConfig struct {
+	Enabled       bool    `json:"enabled"`
+	Percent       float64 `json:"percent"`
+	TargetPercent float64 `json:"target_percent"`
+	TargetPrice   float64 `json:"target_price"`
+}
+
+// ManagedPositionRequest is the body of POST /positions/managed
+type ManagedPositionRequest struct {
+	Symbol            string             `json:"symbol"`
+	Side              string             `json:"side"`
+	Strategy          string             `json:"strategy,omitempty"`
+	AllocationDollars float64            `json:"allocation_dollars"`
+	EntryStrategy     string             `json:"entry_strategy,omitempty"`
+	EntryPrice        *float64           `json:"entry_price,omitempty"`
+	StopLossPrice     *float64           `json:"stop_loss_price,omitempty"`
+	StopLossPercent   *float64           `json:"stop_loss_percent,omitempty"`
+	TrailingStop      bool               `json:"trailing_stop"`
+	TrailingPercent   float64            `json:"trailing_percent,omitempty"`
+	TakeProfitPrice   *float64           `json:"take_profit_price,omitempty"`
+	TakeProfitPercent *float64           `json:"take_profit_percent,omitempty"`
+	PartialExit       *PartialExitConfig `json:"partial_exit,omitempty"`
+	Notes             string             `json:"notes,omitempty"`
+	Tags              []string           `json:"tags,omitempty"`
+	DryRun            bool               `json:"dry_run,omitempty"`
+}
+
+// ManagedPosition is a position with automated risk management
+type ManagedPosition struct {
+	ID                string             `json:"id"`
+	Symbol            string             `json:"symbol"`
+	Side              string             `json:"side"`
+	Strategy          string             `json:"strategy"`
+	Quantity          float64            `json:"quantity"`
+	EntryPrice        float64            `json:"entry_price"`
+	EntryOrderID      string             `json:"entry_order_id"`
+	EntryOrderType    string             `json:"entry_order_type"`
+	AllocationDollars float64            `json:"allocation_dollars"`
+	StopLossPrice     float64            `json:"stop_loss_price"`
+	StopLossPercent   float64            `json:"stop_loss_percent"`
+	StopLossOrderID   string             `json:"stop_loss_order_id,omitempty"`
+	TrailingStop      bool               `json:"trailing_stop"`
+	TrailingPercent   float64            `json:"trailing_percent,omitempty"`
+	TakeProfitPrice   float64            `json:"take_profit_price"`
+	TakeProfitPercent float64            `json:"take_profit_percent"`
+	TakeProfitOrderID string             `json:"take_profit_order_id,omitempty"`
+	PartialExit       *PartialExitConfig `json:"partial_exit,omitempty"`
+	PartialExitOrders []string           `json:"partial_exit_orders,omitempty"`
+	Status            string             `json:"status"`
+	CurrentPrice      float64            `json:"current_price"`
+	UnrealizedPL      float64            `json:"unrealized_pl"`
+	UnrealizedPLPC    float64            `json:"unrealized_pl_percent"`
+	RemainingQty      float64            `json:"remaining_qty"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+	ClosedAt          *time.Time         `json:"closed_at,omitempty"`
+	Notes             string             `json:"notes,omitempty"`
+	Tags              []string           `json:"tags,omitempty"`
+}
+
+// SymbolAliasRequest records a ticker change, such as FB to META
+type SymbolAliasRequest struct {
+	OldSymbol     string `json:"old_symbol"`
+	NewSymbol     string `json:"new_symbol"`
+	EffectiveDate string `json:"effective_date,omitempty"` // YYYY-MM-DD
+	Note          string `json:"note,omitempty"`
+}
+
+// SymbolAlias maps a ticker that changed to the symbol it trades under now
+type SymbolAlias struct {
+	OldSymbol        string           `json:"old_symbol"`
+	NewSymbol        string           `json:"new_symbol"`
+	EffectiveDate    string           `json:"effective_date,omitempty"`
+	Note             string           `json:"note,omitempty"`
+	Migrated         map[string]int64 `json:"migrated"` // rows moved to NewSymbol, by table
+	ManagedPositions []string         `json:"managed_positions,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+}
+
+// SplitRequest applies a stock split by hand
+type SplitRequest struct {
+	Symbol  string  `json:"symbol"`
+	ExDate  string  `json:"ex_date"` // YYYY-MM-DD
+	OldRate float64 `json:"old_rate"`
+	NewRate float64 `json:"new_rate"`
+}
+
+// SplitFieldChange is one value a split rescaled
+type SplitFieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// SplitChange lists the values rescaled on one stored record: a
+// position_snapshot, managed_position or trade
+type SplitChange struct {
+	Kind   string             `json:"kind"`
+	ID     string             `json:"id"`
+	Fields []SplitFieldChange `json:"fields"`
+	Note   string             `json:"note,omitempty"`
+}
+
+// SplitAdjustment is a stock split applied to stored positions and trades
+type SplitAdjustment struct {
+	Symbol    string        `json:"symbol"`
+	ExDate    string        `json:"ex_date"`
+	OldRate   float64       `json:"old_rate"`
+	NewRate   float64       `json:"new_rate"`
+	Ratio     float64       `json:"ratio"`
+	Source    string        `json:"source"` // "detected" or "manual"
+	Changes   []SplitChange `json:"changes"`
+	AppliedAt time.Time     `json:"applied_at"`
+}
+
+// ActivityLog is a day's worth of trading activity
+type ActivityLog struct {
+	Date               string                   `json:"date"`
+	SessionStart       time.Time                `json:"session_start"`
+	SessionEnd         time.Time                `json:"session_end,omitempty"`
+	Summary            map[string]interface{}   `json:"summary"`
+	Activities         []map[string]interface{} `json:"activities"`
+	PositionsOpened    []map[string]interface{} `json:"positions_opened"`
+	PositionsClosed    []map[string]interface{} `json:"positions_closed"`
+	MarketIntelligence []map[string]interface{} `json:"market_intelligence"`
+	Decisions          []map[string]interface{} `json:"decisions"`
+}
+
+// SessionSummary is the P&L and statistics of a trading session
+type SessionSummary struct {
+	StartingCapital float64 `json:"starting_capital"`
+	EndingCapital   float64 `json:"ending_capital"`
+	CurrentValue    float64 `json:"current_value,omitempty"`
+	TotalPnL        float64 `json:"total_pnl"`
+	TotalPnLPercent float64 `json:"total_pnl_percent"`
+	RealizedPnL     float64 `json:"realized_pnl"`
+	UnrealizedPnL   float64 `json:"unrealized_pnl"`
+	FilledOrders    int     `json:"filled_orders"`
+	Fees            float64 `json:"fees"`
+	PeakValue       float64 `json:"peak_value,omitempty"`
+	MaxDrawdown     float64 `json:"max_drawdown"`
+	MaxDrawdownPct  float64 `json:"max_drawdown_percent"`
+	TotalTrades     int     `json:"total_trades"`
+	PositionsClosed int     `json:"positions_closed"`
+	WinningTrades   int     `json:"winning_trades"`
+	LosingTrades    int     `json:"losing_trades"`
+	StocksAnalyzed  int     `json:"stocks_analyzed"`
+}
+
+// Session is a trading session from GET /activity/sessions
+type Session struct {
+	ID           uint           `json:"id"`
+	Date         string         `json:"date"`
+	Environment  string         `json:"environment,omitempty"`
+	SessionStart time.Time      `json:"session_start"`
+	SessionEnd   *time.Time     `json:"session_end,omitempty"`
+	Active       bool           `json:"active"`
+	Summary      SessionSummary `json:"summary"`
+}
+
+// ActivityRequest is the body of POST /activity/log
+type ActivityRequest struct {
+	Type      string                 `json:"type"`
+	Action    string                 `json:"action"`
+	Symbol    string                 `json:"symbol,omitempty"`
+	Reasoning string                 `json:"reasoning,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// PageInfo is the pagination metadata of list responses
+type PageInfo struct {
+	Limit      int    `json:"limit"`
+	Total      int    `json:"total"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Sort       string `json:"sort"`
+}
+
+// ListOptions are the pagination, filter and sort parameters accepted by
+// list endpoints. Zero values use the server defaults.
+type ListOptions struct {
+	Limit  int
+	Cursor string            // PageInfo.NextCursor of the previous page
+	From   time.Time         // inclusive
+	To     time.Time         // exclusive
+	Symbol string            // comma separated
+	Sort   string            // field name, "-" prefix for descending, e.g. "-created_at"
+	Tag    string            // orders only: carrying this tag
+	Meta   map[string]string // orders only: carrying every key/value
+	Fields []string          // orders and managed positions: only these keys are sent, the rest decode as zero values
+
+	SinceSeq uint64 // news only: items stored after NewsResponse.LatestSeq
+}
+
+func (o ListOptions) values() url.Values {
+	query := url.Values{}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		query.Set("cursor", o.Cursor)
+	}
+	if !o.From.IsZero() {
+		query.Set("from", o.From.Format(time.RFC3339))
+	}
+	if !o.To.IsZero() {
+		query.Set("to", o.To.Format(time.RFC3339))
+	}
+	if o.Symbol != "" {
+		query.Set("symbol", o.Symbol)
+	}
+	if o.Sort != "" {
+		query.Set("sort", o.Sort)
+	}
+	if o.Tag != "" {
+		query.Set("tag", o.Tag)
+	}
+	for key, value := range o.Meta {
+		query.Set("meta["+key+"]", value)
+	}
+	if len(o.Fields) > 0 {
+		query.Set("fields", strings.Join(o.Fields, ","))
+	}
+	if o.SinceSeq > 0 {
+		query.Set("since_seq", strconv.FormatUint(o.SinceSeq, 10))
+	}
+	return query
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// Worker is the status of one background worker
+type Worker struct {
+	Name            string     `json:"name"`
+	Kind            string     `json:"kind"`  // periodic or stream
+	State           string     `json:"state"` // running, paused, stopped or failed
+	IntervalSeconds float64    `json:"interval_seconds,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	LastTickAt      *time.Time `json:"last_tick_at,omitempty"`
+	LastDurationMs  int64      `json:"last_duration_ms,omitempty"`
+	NextTickAt      *time.Time `json:"next_tick_at,omitempty"`
+	RetryAt         *time.Time `json:"retry_at,omitempty"`
+	Ticks           int64      `json:"ticks"`
+	Errors          int64      `json:"errors"`
+	Panics          int64      `json:"panics"`
+	Restarts        int64      `json:"restarts"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+}
+
+// ListWorkers lists the background workers (GET /admin/workers)
+func (c *Client) ListWorkers(ctx context.Context) ([]*Worker, error) {
+	var resp struct {
+		Workers []*Worker `json:"workers"`
+	}
+	if err := c.get(ctx, "/admin/workers", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Workers, nil
+}
+
+// PauseWorker pauses a worker until it is resumed
+// (POST /admin/workers/:name/pause)
+func (c *Client) PauseWorker(ctx context.Context, name string) (*Worker, error) {
+	return c.workerAction(ctx, name, "pause")
+}
+
+// ResumeWorker resumes a paused worker (POST /admin/workers/:name/resume)
+func (c *Client) ResumeWorker(ctx context.Context, name string) (*Worker, error) {
+	return c.workerAction(ctx, name, "resume")
+}
+
+// TriggerWorker runs a periodic worker's next pass now
+// (POST /admin/workers/:name/trigger)
+func (c *Client) TriggerWorker(ctx context.Context, name string) (*Worker, error) {
+	return c.workerAction(ctx, name, "trigger")
+}
+
+func (c *Client) workerAction(ctx context.Context, name, action string) (*Worker, error) {
+	var worker Worker
+	if err := c.post(ctx, "/admin/workers/"+url.PathEscape(name)+"/"+action, nil, &worker); err != nil {
+		return nil, err
+	}
+	return &worker, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newAnalyzeCmd(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "analyze SYMBOL",
+		Short: "Run the AI stock analysis for a symbol and print it as JSON",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := newApp(c)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			analysis, err := a.stockAnalysisService.AnalyzeStock(context.Background(), strings.ToUpper(args[0]))
+			if err != nil {
+				return err
+			}
+
+			return printJSON(analysis)
+		},
+	}
+}
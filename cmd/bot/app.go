@@ -0,0 +1,433 @@
+package main
+
+import (
+	"fmt"
+	"prophet-trader/config"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/services"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// app bundles the services shared by the server and the CLI subcommands
+type app struct {
+	cfg                  *config.Config
+	logger               *logrus.Logger
+	tradingService       interfaces.TradingService
+	dataService          interfaces.DataService
+	storageService       *database.LocalStorage
+	newsService          *services.NewsService
+	geminiService        *services.GeminiService
+	promptStore          *services.PromptStore
+	llmTools             *services.LLMToolBridge
+	semanticIndex        *services.SemanticIndex
+	analysisService      *services.TechnicalAnalysisService
+	stockAnalysisService *services.StockAnalysisService
+	analysisProfiles     *services.AnalysisProfileService
+	riskManager          *services.RiskManager
+	symbolRules          *services.SymbolRuleService
+	regimeService        *services.MarketRegimeService
+	fundamentalsService  *services.FundamentalsService
+	socialService        *services.SocialSentimentService
+	calendarService      *services.EconomicCalendarService
+	orderAuditor         *services.OrderAuditor
+	shadowTrading        *services.ShadowTradingService // nil unless shadow mode is on
+	notifier             *services.Notifier
+	backupService        *services.BackupService
+	credentialRotators   []services.CredentialRotator   // services holding rotatable API keys
+	marketHours          *services.MarketHours          // when each asset class trades, from the broker clock and calendar when it has them
+	optionsQuotes        services.OptionsQuoteSource    // nil when the broker cannot batch options quotes
+	tradeUpdates         services.TradeUpdateStream     // nil when the broker doesn't push order updates
+	orderReplacer        services.OrderReplacer         // nil when the broker can't move an open order's limit, or in shadow mode
+	accountActivities    services.AccountActivitySource // nil when the broker doesn't report dividends, fees and transfers
+	intradayHistory      services.IntradayHistorySource // nil when the broker only has daily portfolio history
+	tradeSource          services.TradeSource           // nil when no data provider serves trades
+	splitSource          services.SplitSource           // nil when no data provider reports stock splits
+}
+
+// newApp validates credentials and constructs the core services
+func newApp(c *cli) (*app, error) {
+	cfg := c.cfg
+
+	// Validate required configuration. Alpaca credentials are needed when
+	// Alpaca is either the broker or the market data provider.
+	usesAlpaca := strings.EqualFold(cfg.Broker, "alpaca") || strings.EqualFold(cfg.DataProvider, "alpaca")
+	for _, name := range cfg.DataFallbackProviders {
+		usesAlpaca = usesAlpaca || strings.EqualFold(name, "alpaca")
+	}
+	if usesAlpaca && (cfg.AlpacaAPIKey == "" || cfg.AlpacaSecretKey == "") {
+		return nil, fmt.Errorf("Alpaca API credentials not configured. Please set ALPACA_API_KEY and ALPACA_SECRET_KEY")
+	}
+
+	// Trading a live account must be confirmed explicitly, and the first
+	// days of live trading are limited to guard against fat-finger orders
+	var liveRestrictedUntil time.Time
+	if cfg.Environment() == config.EnvironmentLive {
+		if !cfg.LiveTradingConfirmed {
+			return nil, fmt.Errorf("%s is configured for a live account. Set LIVE_TRADING_CONFIRMED=true to trade real money", cfg.Broker)
+		}
+		if cfg.LiveRestrictedDays > 0 {
+			start, err := time.ParseInLocation("2006-01-02", cfg.LiveStartDate, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("LIVE_TRADING_START_DATE must be set as YYYY-MM-DD while LIVE_RESTRICTED_DAYS is %d", cfg.LiveRestrictedDays)
+			}
+			liveRestrictedUntil = start.AddDate(0, 0, cfg.LiveRestrictedDays)
+		}
+	}
+
+	// Create trading service for the configured broker
+	brokerTrading, err := services.NewBroker(cfg.Broker, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trading service: %w", err)
+	}
+
+	// Everything that needs to know whether the market is open asks
+	// marketHours, which asks the broker when it has a clock or calendar
+	marketHours := services.NewMarketHours()
+	if marketClock, ok := brokerTrading.(services.MarketClock); ok {
+		marketHours.SetMarketClock(marketClock)
+	}
+	if marketCalendar, ok := brokerTrading.(services.MarketCalendar); ok {
+		marketHours.SetMarketCalendar(marketCalendar)
+	}
+
+	// Order fills stream in when the broker pushes order updates
+	tradeUpdates, _ := brokerTrading.(services.TradeUpdateStream)
+
+	// Pegged orders are re-priced in place when the broker can replace
+	// orders. Shadowed orders never reach it, so they are re-placed instead.
+	orderReplacer, _ := brokerTrading.(services.OrderReplacer)
+	if cfg.ShadowMode {
+		orderReplacer = nil
+	}
+
+	// Dividends, fees and transfers are ingested when the broker reports them
+	accountActivities, _ := brokerTrading.(services.AccountActivitySource)
+	intradayHistory, _ := brokerTrading.(services.IntradayHistorySource)
+
+	// Cached options chains refresh just their quotes when the broker can
+	// fetch them in bulk
+	optionsQuotes, _ := brokerTrading.(services.OptionsQuoteSource)
+	if optionsQuotes != nil {
+		optionsQuotes = services.NewInstrumentedOptionsQuotes(optionsQuotes, strings.ToLower(cfg.Broker))
+	}
+
+	// Create data service for the configured provider, failing over to the
+	// fallback providers in order
+	primaryData, err := services.NewDataProvider(cfg.DataProvider, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data service: %w", err)
+	}
+
+	// Services whose keys can be rotated from the secrets backend
+	var credentialRotators []services.CredentialRotator
+	for _, service := range []interface{}{brokerTrading, primaryData} {
+		if rotator, ok := service.(services.CredentialRotator); ok {
+			credentialRotators = append(credentialRotators, rotator)
+		}
+	}
+
+	// Tick and volume bars are built from the trades of the first provider
+	// that serves them
+	tradeSource, _ := primaryData.(services.TradeSource)
+	splitSource, _ := primaryData.(services.SplitSource)
+
+	// Broker and data provider calls are counted against the HTTP request
+	// that made them
+	brokerTrading = services.NewInstrumentedTradingService(brokerTrading, strings.ToLower(cfg.Broker))
+	failoverData := services.NewFailoverDataService(cfg.DataProvider, services.NewInstrumentedDataService(primaryData, strings.ToLower(cfg.DataProvider)))
+	for _, name := range cfg.DataFallbackProviders {
+		fallback, err := services.NewDataProvider(name, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fallback data service: %w", err)
+		}
+		failoverData.AddFallback(name, services.NewInstrumentedDataService(fallback, strings.ToLower(name)))
+		if rotator, ok := fallback.(services.CredentialRotator); ok {
+			credentialRotators = append(credentialRotators, rotator)
+		}
+		if trades, ok := fallback.(services.TradeSource); ok && tradeSource == nil {
+			tradeSource = trades
+		}
+		if splits, ok := fallback.(services.SplitSource); ok && splitSource == nil {
+			splitSource = splits
+		}
+	}
+
+	// Callers asking for the same quote within a moment share one request
+	cachedData := services.NewCachedDataService(failoverData, time.Duration(cfg.QuoteCacheTTLMillis)*time.Millisecond)
+	switch cfg.QuoteCacheBackend {
+	case "memory":
+	case "redis":
+		// Instances behind a load balancer share quotes too
+		sharedCache, err := services.NewRedisCache(cfg.RedisURL, cfg.RedisPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create quote cache: %w", err)
+		}
+		cachedData.SetSharedCache(sharedCache)
+	default:
+		return nil, fmt.Errorf("unknown QUOTE_CACHE_BACKEND %q: use memory or redis", cfg.QuoteCacheBackend)
+	}
+
+	// Anything taking a timeframe also takes tick, volume and Heikin-Ashi bars
+	dataService := services.NewAggregatedDataService(cachedData, tradeSource)
+
+	// Swap in a restore staged by POST /admin/restore or the restore command
+	// before anything opens the database
+	if cfg.DatabaseURL == "" {
+		if manifest, err := services.ApplyPendingRestore(cfg.DatabasePath, cfg.ActivityLogDir); err != nil {
+			return nil, fmt.Errorf("failed to apply staged restore: %w", err)
+		} else if manifest != nil {
+			c.logger.WithField("backup_created_at", manifest.CreatedAt).Warn("Restored database and activity logs from backup")
+		}
+	}
+
+	// Sensitive columns are sealed when a key is configured
+	if cfg.EncryptionKey != "" {
+		key, err := database.ParseEncryptionKey(cfg.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := database.SetEncryptionKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create storage service: PostgreSQL when DATABASE_URL is set, else SQLite
+	storageService, err := database.NewStorage(cfg.DatabaseURL, cfg.DatabasePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage service: %w", err)
+	}
+
+	if cfg.EncryptionKey != "" {
+		encrypted, err := storageService.EncryptExistingRecords()
+		if err != nil {
+			storageService.Close()
+			return nil, fmt.Errorf("failed to encrypt existing records: %w", err)
+		}
+		if encrypted > 0 {
+			c.logger.WithField("records", encrypted).Info("Encrypted existing plaintext records")
+		}
+	}
+
+	// Record every order submission in the audit trail. Positions are
+	// cached briefly below it so concurrent readers share one request.
+	orderAuditor := services.NewOrderAuditor(storageService)
+	cachedTrading := services.NewCachedTradingService(brokerTrading, time.Duration(cfg.PositionCacheTTLSeconds)*time.Second)
+	var tradingService interfaces.TradingService = services.NewAuditedTradingService(cachedTrading, orderAuditor)
+	var shadowTrading *services.ShadowTradingService
+	if cfg.ShadowMode {
+		// Shadowed orders are simulated and audited here, never reaching the broker
+		shadowTrading = services.NewShadowTradingService(tradingService, dataService, storageService, orderAuditor, cfg.ShadowSources)
+		tradingService = shadowTrading
+	}
+	if cfg.ObserverMode {
+		// Orders are rejected before they reach the audit trail or broker
+		tradingService = services.NewReadOnlyTradingService(tradingService)
+	}
+
+	// Create notifier; each channel applies its own throttling rules
+	logChannel, err := withNotifyRule(services.NewLogChannel(), cfg.NotifyRules["log"])
+	if err != nil {
+		return nil, err
+	}
+	notifier := services.NewNotifier(logChannel)
+	if cfg.NotifyWebhookURL != "" {
+		webhookChannel, err := withNotifyRule(services.NewWebhookChannel(cfg.NotifyWebhookURL), cfg.NotifyRules["webhook"])
+		if err != nil {
+			return nil, err
+		}
+		notifier.AddChannel(webhookChannel)
+	}
+
+	// Backups go to the S3-compatible bucket when one is configured
+	var backupStore services.BackupStore = services.NewDirBackupStore(cfg.BackupDir)
+	if cfg.BackupS3Bucket != "" {
+		backupStore, err = services.NewS3BackupStore(cfg.BackupS3Endpoint, cfg.BackupS3Bucket, cfg.BackupS3Prefix,
+			cfg.BackupS3Region, cfg.BackupS3AccessKey, cfg.BackupS3SecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backup store: %w", err)
+		}
+	}
+	backupService := services.NewBackupService(storageService, cfg.ActivityLogDir, backupStore, cfg.BackupKeep, notifier)
+
+	newsService := services.NewNewsService()
+	geminiService := services.NewGeminiService(cfg.GeminiAPIKey)
+	promptStore := services.NewPromptStore(storageService, cfg.PromptTemplateDir)
+	geminiService.SetPromptStore(promptStore)
+	var llmTools *services.LLMToolBridge
+	if cfg.LLMToolMaxCalls > 0 {
+		llmTools = services.NewLLMToolBridge(dataService, tradingService, newsService, storageService, cfg.LLMToolMaxCalls)
+		geminiService.SetToolBridge(llmTools)
+	}
+
+	// Semantic search is optional; without a usable provider it stays off
+	var semanticIndex *services.SemanticIndex
+	if cfg.EmbeddingProvider != "" && cfg.EmbeddingProvider != "none" {
+		embeddingProvider, err := services.NewEmbeddingProvider(cfg.EmbeddingProvider, cfg)
+		if err != nil {
+			c.logger.WithError(err).Warn("Semantic search disabled")
+		} else {
+			semanticIndex = services.NewSemanticIndex(embeddingProvider, storageService, newsService)
+			if rotator, ok := embeddingProvider.(services.CredentialRotator); ok {
+				credentialRotators = append(credentialRotators, rotator)
+			}
+		}
+	}
+
+	fundamentalsProvider, err := services.NewFundamentalsProvider(cfg.FundamentalsProvider, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fundamentals service: %w", err)
+	}
+	fundamentalsService := services.NewFundamentalsService(fundamentalsProvider)
+
+	// Social sentiment sources: subreddits always, X lists when a token is set
+	socialSources := []services.SocialSource{}
+	if len(cfg.SocialSubreddits) > 0 {
+		socialSources = append(socialSources, services.NewRedditSource(cfg.SocialSubreddits))
+	}
+	if cfg.XBearerToken != "" && len(cfg.SocialXListIDs) > 0 {
+		socialSources = append(socialSources, services.NewXSource(cfg.XBearerToken, cfg.SocialXListIDs))
+	}
+	socialService := services.NewSocialSentimentService(storageService, socialSources...)
+
+	stockAnalysisService := services.NewStockAnalysisService(dataService, newsService, geminiService, fundamentalsService)
+	if cfg.SocialInAnalysis {
+		stockAnalysisService.SetSocialSentiment(socialService)
+	}
+	analysisProfiles := services.NewAnalysisProfileService(storageService)
+	stockAnalysisService.SetProfiles(analysisProfiles)
+	stockAnalysisService.SetStorage(storageService)
+	if semanticIndex != nil {
+		stockAnalysisService.SetSemanticIndex(semanticIndex)
+	}
+
+	calendarSource, err := services.NewCalendarSource(cfg.EconomicCalendarSource, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create economic calendar: %w", err)
+	}
+	calendarService := services.NewEconomicCalendarService(calendarSource)
+
+	regimeService := services.NewMarketRegimeService(dataService, cfg.RegimeNeutralSizePct/100, cfg.RegimeRiskOffSizePct/100)
+
+	// Create risk manager with the default pre-trade rules
+	riskManager := services.NewRiskManager(tradingService, dataService,
+		services.BuyingPowerRule{},
+		services.MaxOrderValueRule{Max: decimal.NewFromFloat(cfg.MaxOrderValue)},
+		services.ConcentrationRule{
+			MaxSymbolPct: cfg.MaxSymbolConcentrationPct,
+			MaxSectorPct: cfg.MaxSectorConcentrationPct,
+			Block:        cfg.BlockOnConcentration,
+		},
+	)
+	symbolRules := services.NewSymbolRuleService(storageService, cfg.SymbolBlocklist, cfg.MinSharePrice)
+	riskManager.AddRule(services.SymbolRulesRule{Rules: symbolRules})
+	if cfg.RegimeRuleEnabled {
+		riskManager.AddRule(services.RegimeRule{Regime: regimeService, BlockRiskOff: cfg.RegimeBlockRiskOff})
+	}
+	if time.Now().Before(liveRestrictedUntil) {
+		riskManager.AddRule(services.LiveTradingRule{
+			AllowedSymbols: cfg.LiveAllowedSymbols,
+			MaxOrderValue:  decimal.NewFromFloat(cfg.LiveMaxOrderValue),
+			Until:          liveRestrictedUntil,
+		})
+		c.logger.WithFields(logrus.Fields{
+			"until":           liveRestrictedUntil.Format("2006-01-02"),
+			"allowed_symbols": strings.Join(cfg.LiveAllowedSymbols, ","),
+			"max_order_value": cfg.LiveMaxOrderValue,
+		}).Warn("Live trading restrictions are in effect")
+	}
+	if cfg.MacroRuleEnabled {
+		riskManager.AddRule(services.MacroEventRule{
+			Calendar:     calendarService,
+			Before:       time.Duration(cfg.MacroWindowBeforeMinutes) * time.Minute,
+			After:        time.Duration(cfg.MacroWindowAfterMinutes) * time.Minute,
+			BlockEntries: cfg.MacroBlockEntries,
+			StopWidenPct: cfg.MacroStopWidenPct,
+		})
+	}
+	if cfg.TradingHoursRuleEnabled {
+		riskManager.AddRule(services.TradingHoursRule{Hours: marketHours, Block: cfg.TradingHoursBlock})
+	}
+	if cfg.MarginMaxUtilizationPct > 0 {
+		riskManager.AddRule(services.MarginRule{MaxUtilizationPct: cfg.MarginMaxUtilizationPct, Block: cfg.MarginBlock})
+	}
+	if cfg.ShortDTERuleEnabled {
+		cutoff, err := time.Parse("15:04", cfg.ZeroDTEEntryCutoff)
+		if err != nil {
+			return nil, fmt.Errorf("ZERO_DTE_ENTRY_CUTOFF must be HH:MM, got %q", cfg.ZeroDTEEntryCutoff)
+		}
+		riskManager.AddRule(services.ShortDTERule{
+			MaxDays:          cfg.ShortDTEMaxDays,
+			MaxOrderValue:    decimal.NewFromFloat(cfg.ShortDTEMaxOrderValue),
+			MaxRiskPct:       cfg.ShortDTEMaxRiskPct,
+			EntryCutoff:      time.Duration(cutoff.Hour())*time.Hour + time.Duration(cutoff.Minute())*time.Minute,
+			BlockAfterCutoff: cfg.ZeroDTEBlockAfterCutoff,
+			Hours:            marketHours,
+		})
+	}
+
+	return &app{
+		cfg:                  cfg,
+		logger:               c.logger,
+		tradingService:       tradingService,
+		dataService:          dataService,
+		storageService:       storageService,
+		newsService:          newsService,
+		geminiService:        geminiService,
+		promptStore:          promptStore,
+		llmTools:             llmTools,
+		semanticIndex:        semanticIndex,
+		analysisService:      services.NewTechnicalAnalysisService(dataService),
+		stockAnalysisService: stockAnalysisService,
+		analysisProfiles:     analysisProfiles,
+		riskManager:          riskManager,
+		regimeService:        regimeService,
+		fundamentalsService:  fundamentalsService,
+		socialService:        socialService,
+		calendarService:      calendarService,
+		orderAuditor:         orderAuditor,
+		shadowTrading:        shadowTrading,
+		notifier:             notifier,
+		backupService:        backupService,
+		credentialRotators:   append(credentialRotators, geminiService),
+		tradeSource:          tradeSource,
+		splitSource:          splitSource,
+		symbolRules:          symbolRules,
+		marketHours:          marketHours,
+		optionsQuotes:        optionsQuotes,
+		tradeUpdates:         tradeUpdates,
+		orderReplacer:        orderReplacer,
+		accountActivities:    accountActivities,
+		intradayHistory:      intradayHistory,
+	}, nil
+}
+
+// withNotifyRule wraps channel with rule's throttling
+func withNotifyRule(channel services.NotificationChannel, rule config.NotifyRule) (services.NotificationChannel, error) {
+	quietStart, quietEnd, err := services.ParseQuietHours(rule.QuietHours)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s notification rules: %w", channel.Name(), err)
+	}
+	rules := services.NotificationRules{
+		MinLevel:     rule.MinLevel,
+		QuietStart:   quietStart,
+		QuietEnd:     quietEnd,
+		MaxPerMinute: rule.MaxPerMinute,
+		DigestWindow: time.Duration(rule.DigestSeconds) * time.Second,
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s notification rules: %w", channel.Name(), err)
+	}
+	return services.NewRuledChannel(channel, rules), nil
+}
+
+// Close releases resources held by the app
+func (a *app) Close() {
+	if err := a.storageService.Close(); err != nil {
+		a.logger.WithError(err).Warn("Failed to close storage")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"prophet-trader/services"
+	"prophet-trader/strategies"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newBacktestCmd(c *cli) *cobra.Command {
+	var (
+		symbol    string
+		strategy  string
+		start     string
+		end       string
+		timeframe string
+		capital   float64
+		lookback  int
+		params    map[string]string
+		fills     services.FillModel
+		options   services.OptionsBacktestConfig
+		adjust    string
+		feed      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "backtest",
+		Short: "Replay historical bars through a registered strategy",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			startTime, err := time.Parse("2006-01-02", start)
+			if err != nil {
+				return fmt.Errorf("invalid --start date: %w", err)
+			}
+			endTime := time.Now()
+			if end != "" {
+				if endTime, err = time.Parse("2006-01-02", end); err != nil {
+					return fmt.Errorf("invalid --end date: %w", err)
+				}
+			}
+
+			barOptions, err := services.ParseBarOptions(adjust, feed)
+			if err != nil {
+				return err
+			}
+			ctx := services.WithBarOptions(context.Background(), barOptions)
+
+			if options.Structure != "" {
+				return runOptionsBacktest(ctx, c, symbol, startTime, endTime, capital, fills, options)
+			}
+
+			config := make(map[string]interface{}, len(params))
+			for key, value := range params {
+				config[key] = value
+			}
+			strat, err := strategies.New(strategy, config)
+			if err != nil {
+				return err
+			}
+
+			a, err := newApp(c)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			// History fetched with the download command is read locally
+			engine := services.NewBacktestEngine(services.NewBarCacheDataService(historyDataService(a), a.storageService))
+			result, err := engine.Run(ctx, strat, services.BacktestConfig{
+				Symbol:         strings.ToUpper(symbol),
+				Start:          startTime,
+				End:            endTime,
+				Timeframe:      timeframe,
+				InitialCapital: capital,
+				Lookback:       lookback,
+				Fills:          fills,
+			})
+			if err != nil {
+				return err
+			}
+
+			return printJSON(result)
+		},
+	}
+
+	cmd.Flags().StringVar(&symbol, "symbol", "", "symbol to backtest (required)")
+	cmd.Flags().StringVar(&strategy, "strategy", "buy_and_hold", fmt.Sprintf("strategy name %v", strategies.Names()))
+	cmd.Flags().StringVar(&start, "start", time.Now().AddDate(-1, 0, 0).Format("2006-01-02"), "start date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&end, "end", "", "end date (YYYY-MM-DD), defaults to now")
+	cmd.Flags().StringVar(&timeframe, "timeframe", "1Day", "bar timeframe")
+	cmd.Flags().Float64Var(&capital, "capital", 100000, "initial capital")
+	cmd.Flags().IntVar(&lookback, "lookback", 50, "bars of history passed to the strategy")
+	cmd.Flags().StringToStringVar(&params, "param", nil, "strategy parameter as key=value, repeatable")
+	cmd.Flags().StringVar(&adjust, "adjustment", "", "price adjustment: raw, split, dividend or all (default the provider's); anything but the default skips downloaded bars")
+	cmd.Flags().StringVar(&feed, "feed", "", "Alpaca feed such as iex or sip (default ALPACA_DATA_FEED)")
+	addFillModelFlags(cmd, &fills)
+	cmd.Flags().StringVar(&options.Structure, "structure", "",
+		fmt.Sprintf("options structure to backtest on --symbol instead of a strategy (%s, %s, %s, %s, %s)",
+			services.StructureCoveredCall, services.StructureBullCallSpread, services.StructureBearPutSpread,
+			services.StructureBullPutSpread, services.StructureBearCallSpread))
+	cmd.Flags().IntVar(&options.DTE, "dte", 30, "target days to expiration; the first Friday on or after it is used")
+	cmd.Flags().Float64Var(&options.StrikeOffsetPct, "otm-pct", 5, "first leg's strike, percent out of the money")
+	cmd.Flags().Float64Var(&options.Width, "width", 5, "spread width in dollars")
+	cmd.Flags().Float64Var(&options.StrikeStep, "strike-step", 1, "strike increment strikes are rounded to")
+	cmd.Flags().IntVar(&options.Contracts, "contracts", 1, "contracts per leg")
+	cmd.Flags().Float64Var(&options.ProfitTakePct, "profit-take-pct", 0, "close at this percent of the maximum profit (0 holds on)")
+	cmd.Flags().IntVar(&options.CloseDaysBefore, "close-days-before", 0, "close this many days before expiration (0 holds to expiration)")
+	cmd.MarkFlagRequired("symbol")
+
+	return cmd
+}
+
+// runOptionsBacktest opens the options structure on the underlying cycle
+// after cycle. Contract bars come from storage when downloaded, otherwise
+// from Alpaca's options data.
+func runOptionsBacktest(ctx context.Context, c *cli, underlying string, start, end time.Time, capital float64, fills services.FillModel, options services.OptionsBacktestConfig) error {
+	a, err := newApp(c)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	options.Underlying = underlying
+	options.Start = start
+	options.End = end
+	options.InitialCapital = capital
+	options.Fills = fills
+
+	engine := services.NewBacktestEngine(services.NewBarCacheDataService(historyDataService(a), a.storageService))
+	result, err := engine.RunOptions(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(result)
+}
+
+// addFillModelFlags registers the simulated fill model flags shared by
+// backtest and replay
+func addFillModelFlags(cmd *cobra.Command, fills *services.FillModel) {
+	cmd.Flags().StringVar(&fills.Price, "fill-price", services.FillAtLast,
+		fmt.Sprintf("fill price model (%s, %s, %s)", services.FillAtLast, services.FillAtMidpoint, services.FillCrossSpread))
+	cmd.Flags().Float64Var(&fills.SpreadBps, "spread-bps", 0, "bid/ask spread assumed by the cross model when there is no quote")
+	cmd.Flags().Float64Var(&fills.SlippageBps, "slippage-bps", 0, "adverse slippage per fill in basis points")
+	cmd.Flags().Float64Var(&fills.SlippageTicks, "slippage-ticks", 0, "adverse slippage per fill in ticks")
+	cmd.Flags().Float64Var(&fills.TickSize, "tick-size", 0.01, "tick size for --slippage-ticks")
+	cmd.Flags().Float64Var(&fills.MaxVolumePct, "max-volume-pct", 0, "most of a bar's volume one order may take, in percent (0 is no cap)")
+	cmd.Flags().Float64Var(&fills.CommissionPerShare, "commission-per-share", 0, "stock commission per share")
+	cmd.Flags().Float64Var(&fills.MinCommission, "min-commission", 0, "minimum stock commission per order")
+	cmd.Flags().Float64Var(&fills.OptionsFeePerContract, "options-fee", 0, "options fee per contract")
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func newBackupCmd(c *cli) *cobra.Command {
+	var (
+		list   bool
+		verify string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up the database and activity logs, list backups or verify one",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := newApp(c)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			ctx := context.Background()
+			switch {
+			case list:
+				backups, err := a.backupService.List(ctx)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "NAME\tSIZE\tMODIFIED")
+				for _, b := range backups {
+					fmt.Fprintf(w, "%s\t%d\t%s\n", b.Name, b.Size, b.ModTime.Format("2006-01-02 15:04:05"))
+				}
+				return w.Flush()
+
+			case verify != "":
+				manifest, err := a.backupService.Verify(ctx, verify)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s is intact: %d files, created %s\n", verify, len(manifest.Files), manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+				return nil
+
+			default:
+				result, err := a.backupService.Backup(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Wrote %s (%d bytes, %d files) to %s\n", result.Name, result.Size, len(result.Manifest.Files), result.Location)
+				return nil
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&list, "list", false, "list stored backups instead of taking one")
+	cmd.Flags().StringVar(&verify, "verify", "", "verify the named backup instead of taking one")
+
+	return cmd
+}
+
+func newRestoreCmd(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <backup-name>",
+		Short: "Verify a backup and stage it to replace the database on the next start",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := newApp(c)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			result, err := a.backupService.Restore(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Staged %s (created %s). It will be applied the next time the bot starts.\n",
+				result.Name, result.Manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+			return nil
+		},
+	}
+}
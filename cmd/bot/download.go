@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"prophet-trader/interfaces"
+	"prophet-trader/services"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newDownloadCmd(c *cli) *cobra.Command {
+	var (
+		symbols   []string
+		from      string
+		to        string
+		timeframe string
+		rate      int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "download",
+		Short: "Backfill historical bars into the local database for backtests",
+		Long: "Downloads bars window by window, recording progress after each one. " +
+			"Running the same command again after an interruption resumes where it stopped, " +
+			"and later runs only fetch history not already stored.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fromTime, err := time.Parse("2006-01-02", from)
+			if err != nil {
+				return fmt.Errorf("invalid --from date: %w", err)
+			}
+			// Up to the start of today, so no partial bar is stored
+			toTime := time.Now().UTC().Truncate(24 * time.Hour)
+			if to != "" {
+				if toTime, err = time.Parse("2006-01-02", to); err != nil {
+					return fmt.Errorf("invalid --to date: %w", err)
+				}
+			}
+
+			a, err := newApp(c)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			downloader := services.NewBarDownloader(historyDataService(a), a.storageService, rate)
+			downloader.OnProgress(func(p services.BarDownloadProgress) {
+				fmt.Fprintf(os.Stderr, "%-6s %s %s to %s  %6d bars  %5.1f%%\n",
+					p.Symbol, p.Timeframe, p.Start.Format("2006-01-02"), p.End.Format("2006-01-02"), p.Bars, p.Percent)
+			})
+
+			results := make([]*services.BarDownloadResult, 0, len(symbols))
+			for _, symbol := range symbols {
+				result, err := downloader.Download(ctx, strings.ToUpper(symbol), timeframe, fromTime, toTime)
+				if err != nil {
+					if errors.Is(err, context.Canceled) {
+						fmt.Fprintln(os.Stderr, "Interrupted; run the same command again to resume")
+					}
+					return err
+				}
+				results = append(results, result)
+			}
+
+			return printJSON(results)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&symbols, "symbols", nil, "symbols to download, comma separated; OCC option symbols are fetched from the options data API (required)")
+	cmd.Flags().StringVar(&from, "from", time.Now().AddDate(-5, 0, 0).Format("2006-01-02"), "first day to download (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&to, "to", "", "day to stop before (YYYY-MM-DD), defaults to today")
+	cmd.Flags().StringVar(&timeframe, "timeframe", "1Day", "bar timeframe, such as 1Min, 5Min, 1Hour, 1Day or 1Week")
+	cmd.Flags().IntVar(&rate, "rate", 150, "most data requests per minute, 0 for no pacing")
+	cmd.MarkFlagRequired("symbols")
+
+	return cmd
+}
+
+// historyDataService serves historical bars from the configured data
+// provider, and for OCC option symbols from Alpaca's options data
+func historyDataService(a *app) interfaces.DataService {
+	return services.NewOptionBarsDataService(a.dataService,
+		services.NewAlpacaOptionsDataService(a.cfg.AlpacaAPIKey, a.cfg.AlpacaSecretKey))
+}
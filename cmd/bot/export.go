@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"prophet-trader/services"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd(c *cli) *cobra.Command {
+	var (
+		outDir string
+		days   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export orders, snapshots, managed positions and trades as JSON files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := newApp(c)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			if err := os.MkdirAll(outDir, 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+
+			end := time.Now()
+			start := end.AddDate(0, 0, -days)
+
+			orders, err := a.storageService.GetOrders("")
+			if err != nil {
+				return err
+			}
+			positions, err := a.storageService.GetPositionSnapshots()
+			if err != nil {
+				return err
+			}
+			accounts, err := a.storageService.GetAccountSnapshots(start, end)
+			if err != nil {
+				return err
+			}
+			managed, err := a.storageService.GetAllManagedPositions("")
+			if err != nil {
+				return err
+			}
+			trades, err := a.storageService.GetTrades(start, end)
+			if err != nil {
+				return err
+			}
+
+			exports := map[string]interface{}{
+				"orders.json":             orders,
+				"position_snapshots.json": positions,
+				"account_snapshots.json":  accounts,
+				"managed_positions.json":  managed,
+				"trades.json":             trades,
+			}
+			for name, data := range exports {
+				if err := writeJSONFile(filepath.Join(outDir, name), data); err != nil {
+					return err
+				}
+			}
+
+			a.logger.WithField("dir", outDir).Info("Export complete")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&outDir, "out", "./export", "output directory")
+	cmd.Flags().IntVar(&days, "days", 90, "days of account snapshots and trades to include")
+	cmd.AddCommand(newExportDatasetCmd(c))
+
+	return cmd
+}
+
+func newExportDatasetCmd(c *cli) *cobra.Command {
+	var (
+		outDir string
+		req    services.DatasetExportRequest
+		start  string
+		end    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "dataset",
+		Short: "Export bars, trades and sentiment as Parquet or CSV partitioned by symbol and date",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if start != "" {
+				t, err := time.Parse("2006-01-02", start)
+				if err != nil {
+					return fmt.Errorf("invalid --start date: %w", err)
+				}
+				req.Start = t
+			}
+			if end != "" {
+				t, err := time.Parse("2006-01-02", end)
+				if err != nil {
+					return fmt.Errorf("invalid --end date: %w", err)
+				}
+				req.End = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+
+			a, err := newApp(c)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			exporter := services.NewDatasetExporter(a.storageService)
+			manifest, err := exporter.Export(context.Background(), req, services.DirSink(outDir))
+			if err != nil {
+				return err
+			}
+
+			a.logger.WithFields(logrus.Fields{
+				"dir":   outDir,
+				"files": len(manifest.Files),
+			}).Info("Dataset export complete")
+			return printJSON(manifest.Rows)
+		},
+	}
+
+	cmd.Flags().StringVar(&outDir, "out", "./dataset", "output directory")
+	cmd.Flags().StringSliceVar(&req.Datasets, "datasets", nil, "datasets to export (bars, trades, sentiment), defaults to all")
+	cmd.Flags().StringSliceVar(&req.Symbols, "symbols", nil, "symbols to export, defaults to all")
+	cmd.Flags().StringVar(&start, "start", "", "first day to export (YYYY-MM-DD), defaults to everything stored")
+	cmd.Flags().StringVar(&end, "end", "", "last day to export (YYYY-MM-DD), defaults to today")
+	cmd.Flags().StringVar(&req.Format, "format", services.DatasetParquet, "file format (parquet, csv)")
+	cmd.Flags().StringVar(&req.Partition, "partition", services.PartitionMonth, "date partition (day, month, year)")
+
+	return cmd
+}
+
+// writeJSONFile writes v to path as indented JSON
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/services"
+
+	"github.com/spf13/cobra"
+)
+
+func newFlattenCmd(c *cli) *cobra.Command {
+	var confirm bool
+	var filter services.FlattenFilter
+
+	cmd := &cobra.Command{
+		Use:   "flatten",
+		Short: "Cancel open orders and market-close positions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !confirm {
+				return fmt.Errorf("refusing to flatten without --yes")
+			}
+			if filter.AssetClass != "" && filter.AssetClass != "us_equity" && filter.AssetClass != "us_option" {
+				return fmt.Errorf("--asset-class must be us_equity or us_option")
+			}
+			if c.cfg.DryRun {
+				return fmt.Errorf("refusing to flatten while DRY_RUN is enabled")
+			}
+
+			a, err := newApp(c)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			// Managed positions left open are closed by the server's reconciler
+			flatten := services.NewFlattenService(a.tradingService, a.orderAuditor, nil)
+			result, err := flatten.Flatten(context.Background(), filter, "cli")
+			if err != nil {
+				return err
+			}
+
+			return printJSON(result)
+		},
+	}
+
+	cmd.Flags().BoolVar(&confirm, "yes", false, "confirm closing all positions")
+	cmd.Flags().StringSliceVar(&filter.Symbols, "symbol", nil, "only flatten these symbols and options on them")
+	cmd.Flags().StringVar(&filter.AssetClass, "asset-class", "", "only flatten us_equity or us_option")
+
+	return cmd
+}
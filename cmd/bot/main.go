@@ -2,15 +2,12 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"os"
-	"os/signal"
-	"prophet-trader/config"
 	"prophet-trader/controllers"
 	"prophet-trader/database"
 	"prophet-trader/interfaces"
 	"prophet-trader/services"
-	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,273 +15,289 @@ import (
 )
 
 func main() {
-	// Load configuration
-	if err := config.Load(); err != nil {
-		log.Fatal("Failed to load configuration:", err)
-	}
-
-	cfg := config.AppConfig
-
-	// Initialize logger
-	logger := logrus.New()
-	logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-	})
-
-	if cfg.EnableLogging {
-		level, _ := logrus.ParseLevel(cfg.LogLevel)
-		logger.SetLevel(level)
-	}
-
-	logger.Info("Starting Prophet Trader Bot...")
-
-	// Validate required configuration
-	if cfg.AlpacaAPIKey == "" || cfg.AlpacaSecretKey == "" {
-		logger.Fatal("Alpaca API credentials not configured. Please set ALPACA_API_KEY and ALPACA_SECRET_KEY")
-	}
-
-	// Initialize services
-	logger.Info("Initializing services...")
-
-	// Create trading service
-	tradingService, err := services.NewAlpacaTradingService(
-		cfg.AlpacaAPIKey,
-		cfg.AlpacaSecretKey,
-		cfg.AlpacaBaseURL,
-		cfg.AlpacaPaper,
-		cfg.AlpacaDataFeed,
-	)
-	if err != nil {
-		logger.Fatal("Failed to create trading service:", err)
-	}
-
-	// Create data service
-	dataService := services.NewAlpacaDataService(
-		cfg.AlpacaAPIKey,
-		cfg.AlpacaSecretKey,
-		cfg.AlpacaDataFeed,
-	)
-
-	// Create storage service
-	storageService, err := database.NewLocalStorage(cfg.DatabasePath)
-	if err != nil {
-		logger.Fatal("Failed to create storage service:", err)
-	}
-
-	// Create order controller
-	orderController := controllers.NewOrderController(
-		tradingService,
-		dataService,
-		storageService,
-	)
-
-	// Create news service and controller
-	newsService := services.NewNewsService()
-	newsController := controllers.NewNewsController(newsService)
-
-	// Create Gemini service and intelligence controller
-	geminiService := services.NewGeminiService(cfg.GeminiAPIKey)
-	analysisService := services.NewTechnicalAnalysisService(dataService)
-	stockAnalysisService := services.NewStockAnalysisService(dataService, newsService, geminiService)
-	intelligenceController := controllers.NewIntelligenceController(newsService, geminiService, analysisService, stockAnalysisService, dataService)
-
-	// Test account connection
-	logger.Info("Testing Alpaca connection...")
-	if account, err := orderController.GetAccount(); err != nil {
-		logger.Fatal("Failed to connect to Alpaca:", err)
-	} else {
-		logger.WithFields(logrus.Fields{
-			"cash":           account.Cash,
-			"buying_power":   account.BuyingPower,
-			"portfolio_value": account.PortfolioValue,
-		}).Info("Successfully connected to Alpaca")
-	}
-
-	// Start background tasks
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Create position manager
-	positionManager := services.NewPositionManager(tradingService, dataService, storageService)
-	positionController := controllers.NewPositionManagementController(positionManager)
-
-	// Create activity logger
-	activityLogger := services.NewActivityLogger("./activity_logs")
-	activityController := controllers.NewActivityController(activityLogger)
-
-	// Start trading session automatically
-	if account, err := orderController.GetAccount(); err == nil {
-		activityLogger.StartSession(ctx, account.PortfolioValue)
-		logger.Info("Activity logging session started")
-	}
-
-	// Setup HTTP server
-	router := setupRouter(orderController, newsController, intelligenceController, positionController, activityController)
-
-	// Start data cleanup routine
-	go startDataCleanup(ctx, storageService, cfg.DataRetentionDays, logger)
-
-	// Start position monitor
-	go startPositionMonitor(ctx, orderController, storageService, logger)
-
-	// Start managed position monitoring
-	go positionManager.MonitorPositions(ctx)
-
-	// Setup graceful shutdown
-	shutdown := make(chan os.Signal, 1)
-	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		<-shutdown
-		logger.Info("Shutting down gracefully...")
-		cancel()
-		time.Sleep(2 * time.Second)
-		os.Exit(0)
-	}()
-
-	// Start HTTP server
-	logger.WithField("port", cfg.ServerPort).Info("Starting HTTP server...")
-	if err := router.Run(":" + cfg.ServerPort); err != nil {
-		logger.Fatal("Failed to start server:", err)
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
 	}
 }
 
-func setupRouter(orderController *controllers.OrderController, newsController *controllers.NewsController, intelligenceController *controllers.IntelligenceController, positionController *controllers.PositionManagementController, activityController *controllers.ActivityController) *gin.Engine {
+func setupRouter(orderController *controllers.OrderController, newsController *controllers.NewsController, intelligenceController *controllers.IntelligenceController, positionController *controllers.PositionManagementController, activityController *controllers.ActivityController, reconciliationController *controllers.ReconciliationController, reportController *controllers.ReportController, riskController *controllers.RiskController, alertController *controllers.AlertController, dcaController *controllers.DCAController, algoController *controllers.AlgoController, conditionalController *controllers.ConditionalController, rebalanceController *controllers.RebalanceController, gridController *controllers.GridController, backtestController *controllers.BacktestController, analysisController *controllers.AnalysisController, fundamentalsController *controllers.FundamentalsController, filingsController *controllers.FilingsController, calendarController *controllers.CalendarController, eventsController *controllers.EventsController, dashboardController *controllers.DashboardController, backupController *controllers.BackupController, exportController *controllers.ExportController, authController *controllers.AuthController, workerController *controllers.WorkerController, symbolAliases *services.SymbolAliasService, apiAudit *services.APIAuditLog, rateLimiter *services.RateLimiter, writerLock *services.LeaderElector, cors controllers.CORSPolicy, maxBodyBytes int64, compressMinBytes int, cacheMaxAge time.Duration, environment string, observerMode bool) *gin.Engine {
 	router := gin.Default()
 
-	// Enable CORS
+	// Browsers may only call the API from the configured origins
+	cors.ExposedHeaders = []string{"X-Trading-Environment", "X-Upstream-Calls", "X-Upstream-Time-Ms", "Server-Timing", "ETag", "Last-Modified", "X-Symbol-Aliases"}
+	router.Use(controllers.CORS(cors))
+
+	// Every response says whether it came from a paper or a live account
 	router.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+		c.Writer.Header().Set("X-Trading-Environment", environment)
 		c.Next()
 	})
 
+	// Large responses are gzipped for clients that accept it
+	router.Use(controllers.Compress(compressMinBytes))
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
+		if writerLock != nil {
+			c.JSON(200, gin.H{"status": "healthy", "writer_lock": writerLock.Status()})
+			return
+		}
 		c.JSON(200, gin.H{"status": "healthy"})
 	})
 
 	// Trading endpoints
 	api := router.Group("/api/v1")
+	api.Use(controllers.UpstreamUsage(services.DefaultUpstreamMetrics), controllers.MaxBodySize(maxBodyBytes), controllers.APIAudit(apiAudit), authController.Authenticate(), controllers.RateLimit(rateLimiter, controllers.RateLimitDefault), controllers.BookSelector(), controllers.SymbolAliases(symbolAliases))
+	adminOnly := authController.RequireRole(services.RoleAdmin)
+	orderLimit := controllers.RateLimit(rateLimiter, controllers.RateLimitOrders)
+	// Every route that trades is a 403 in observer mode
+	tradingOnly := controllers.ObserverMode(observerMode)
+	intelligenceLimit := controllers.RateLimit(rateLimiter, controllers.RateLimitIntelligence)
+	// Bars, news and options chains may be reused briefly and revalidated by ETag
+	cacheable := controllers.Cacheable(cacheMaxAge)
 	{
+		// Auth endpoints
+		api.GET("/auth/me", authController.HandleWhoAmI)
+
+		// Upstream calls and latency by route since startup
+		api.GET("/metrics/upstream", controllers.HandleUpstreamMetrics(services.DefaultUpstreamMetrics))
+
+		// Live event stream (Server-Sent Events)
+		api.GET("/events", eventsController.HandleEvents)
+		api.GET("/admin/tokens", adminOnly, authController.HandleListTokens)
+		api.POST("/admin/tokens", adminOnly, authController.HandleCreateToken)
+		api.DELETE("/admin/tokens/:id", adminOnly, authController.HandleRevokeToken)
+
+		// Who changed what through the API, newest first
+		api.GET("/admin/audit", adminOnly, controllers.HandleListAPIAudit(apiAudit))
+
+		// Backup and restore endpoints
+		api.POST("/admin/backup", adminOnly, backupController.HandleBackup)
+		api.GET("/admin/backups", adminOnly, backupController.HandleListBackups)
+		api.POST("/admin/backups/:name/verify", adminOnly, backupController.HandleVerifyBackup)
+		api.POST("/admin/restore", adminOnly, backupController.HandleRestore)
+		api.GET("/admin/prompts", adminOnly, intelligenceController.HandleListPrompts)
+		api.GET("/admin/prompts/:name", adminOnly, intelligenceController.HandleGetPrompt)
+		api.PUT("/admin/prompts/:name", adminOnly, intelligenceController.HandleSavePrompt)
+		api.POST("/admin/prompts/:name/rollback", adminOnly, intelligenceController.HandleRollbackPrompt)
+		api.GET("/admin/llm/tool-calls", adminOnly, intelligenceController.HandleListToolCalls)
+		api.GET("/admin/workers", adminOnly, workerController.HandleListWorkers)
+		api.GET("/admin/workers/:name", adminOnly, workerController.HandleGetWorker)
+		api.POST("/admin/workers/:name/pause", adminOnly, workerController.HandlePauseWorker)
+		api.POST("/admin/workers/:name/resume", adminOnly, workerController.HandleResumeWorker)
+		api.POST("/admin/workers/:name/trigger", adminOnly, workerController.HandleTriggerWorker)
+
 		// Order endpoints
-		api.POST("/orders/buy", orderController.HandleBuy)
-		api.POST("/orders/sell", orderController.HandleSell)
-		api.DELETE("/orders/:id", orderController.HandleCancelOrder)
+		api.POST("/orders/buy", tradingOnly, orderLimit, orderController.HandleBuy)
+		api.POST("/orders/sell", tradingOnly, orderLimit, orderController.HandleSell)
+		api.POST("/orders/basket", tradingOnly, orderLimit, orderController.HandleBasket)
+		api.DELETE("/orders/:id", tradingOnly, orderLimit, orderController.HandleCancelOrder)
 		api.GET("/orders", orderController.HandleGetOrders)
+		api.GET("/orders/:id/audit", orderController.HandleGetOrderAudit)
+		api.GET("/orders/queued", orderController.HandleGetQueuedOrders)
+		api.GET("/orders/chases", orderController.HandleGetChases)
+		api.POST("/orders/conditional", tradingOnly, orderLimit, conditionalController.HandleCreateOrder)
+		api.GET("/orders/conditional", conditionalController.HandleListOrders)
+		api.GET("/orders/conditional/:id", conditionalController.HandleGetOrder)
+		api.DELETE("/orders/conditional/:id", tradingOnly, orderLimit, conditionalController.HandleCancelOrder)
+		api.DELETE("/orders/queued/:id", tradingOnly, orderLimit, orderController.HandleCancelQueuedOrder)
 
 		// Position and account endpoints
 		api.GET("/positions", orderController.HandleGetPositions)
 		api.GET("/account", orderController.HandleGetAccount)
+		api.GET("/account/activities", reportController.HandleGetAccountActivities)
+		api.GET("/account/margin", riskController.HandleGetMargin)
+		api.GET("/account/history", reportController.HandleGetAccountHistory)
 
 		// Market data endpoints
 		api.GET("/market/quote/:symbol", orderController.HandleGetQuote)
 		api.GET("/market/bar/:symbol", orderController.HandleGetBar)
-		api.GET("/market/bars/:symbol", orderController.HandleGetBars)
+		api.GET("/market/bars/:symbol", cacheable, orderController.HandleGetBars)
+		api.GET("/market/aggregated-bars", orderController.HandleListAggregatedBars)
+		api.GET("/market/aggregated-bars/:symbol", orderController.HandleGetAggregatedBars)
+		api.GET("/market/timeframes", orderController.HandleGetTimeframes)
+		api.GET("/market/hours", orderController.HandleGetMarketHours)
+		api.GET("/market/providers", orderController.HandleGetDataProviders)
+
+		// Tickers that changed; queries for an old symbol are answered for the new one
+		api.GET("/symbols/aliases", controllers.HandleListSymbolAliases(symbolAliases))
+		api.POST("/symbols/aliases", adminOnly, controllers.HandleAddSymbolAlias(symbolAliases))
 
 		// Options trading endpoints
-		api.POST("/options/order", orderController.PlaceOptionsOrder)
+		api.POST("/options/order", tradingOnly, orderLimit, orderController.PlaceOptionsOrder)
 		api.GET("/options/positions", orderController.ListOptionsPositions)
+		api.GET("/options/exposure", orderController.GetOptionsExposure)
+		api.GET("/options/covered-calls", orderController.GetCoveredCalls)
+		api.POST("/options/analyze", orderController.AnalyzeOptions)
+		api.GET("/options/expected-move/:symbol", orderController.GetExpectedMove)
 		api.GET("/options/position/:symbol", orderController.GetOptionsPosition)
-		api.GET("/options/chain/:symbol", orderController.GetOptionsChain)
+		api.GET("/options/chain/:symbol", cacheable, orderController.GetOptionsChain)
 
 		// News endpoints
-		api.GET("/news", newsController.HandleGetNews)
-		api.GET("/news/topic/:topic", newsController.HandleGetNewsByTopic)
-		api.GET("/news/search", newsController.HandleSearchNews)
-		api.GET("/news/market", newsController.HandleGetMarketNews)
+		api.GET("/news", cacheable, newsController.HandleGetNews)
+		api.GET("/news/topic/:topic", cacheable, newsController.HandleGetNewsByTopic)
+		api.GET("/news/search", cacheable, newsController.HandleSearchNews)
+		api.GET("/news/semantic-search", intelligenceLimit, newsController.HandleSemanticSearch)
+		api.GET("/news/market", cacheable, newsController.HandleGetMarketNews)
 
 		// MarketWatch endpoints
-		api.GET("/news/marketwatch/topstories", newsController.HandleGetMarketWatchTopStories)
-		api.GET("/news/marketwatch/realtime", newsController.HandleGetMarketWatchRealtimeHeadlines)
-		api.GET("/news/marketwatch/bulletins", newsController.HandleGetMarketWatchBulletins)
-		api.GET("/news/marketwatch/marketpulse", newsController.HandleGetMarketWatchMarketPulse)
-		api.GET("/news/marketwatch/all", newsController.HandleGetAllMarketWatchNews)
+		api.GET("/news/marketwatch/topstories", cacheable, newsController.HandleGetMarketWatchTopStories)
+		api.GET("/news/marketwatch/realtime", cacheable, newsController.HandleGetMarketWatchRealtimeHeadlines)
+		api.GET("/news/marketwatch/bulletins", cacheable, newsController.HandleGetMarketWatchBulletins)
+		api.GET("/news/marketwatch/marketpulse", cacheable, newsController.HandleGetMarketWatchMarketPulse)
+		api.GET("/news/marketwatch/all", cacheable, newsController.HandleGetAllMarketWatchNews)
 
 		// Intelligence endpoints (AI-powered)
-		api.POST("/intelligence/cleaned-news", intelligenceController.HandleGetCleanedNews)
-		api.GET("/intelligence/quick-market", intelligenceController.HandleGetQuickMarketIntelligence)
-		api.GET("/intelligence/analyze/:symbol", intelligenceController.HandleAnalyzeStock)
-		api.POST("/intelligence/analyze-multiple", intelligenceController.HandleAnalyzeMultipleStocks)
+		api.POST("/intelligence/cleaned-news", intelligenceLimit, intelligenceController.HandleGetCleanedNews)
+		api.GET("/intelligence/quick-market", intelligenceLimit, intelligenceController.HandleGetQuickMarketIntelligence)
+		api.GET("/intelligence/analyze/:symbol", intelligenceLimit, intelligenceController.HandleAnalyzeStock)
+		api.POST("/intelligence/analyze-multiple", intelligenceLimit, intelligenceController.HandleAnalyzeMultipleStocks)
+		api.GET("/intelligence/regime", intelligenceLimit, intelligenceController.HandleGetMarketRegime)
+		api.GET("/intelligence/social/:symbol", intelligenceLimit, intelligenceController.HandleGetSocialSentiment)
+		api.GET("/intelligence/brief", intelligenceController.HandleGetMarketBrief)
+		api.POST("/intelligence/chat", intelligenceLimit, intelligenceController.HandleChat)
+		api.GET("/intelligence/chat/:session_id", intelligenceController.HandleGetChatSession)
+		api.DELETE("/intelligence/chat/:session_id", intelligenceController.HandleDeleteChatSession)
+		api.GET("/intelligence/profiles", intelligenceController.HandleListAnalysisProfiles)
+		api.PUT("/intelligence/profiles/:name", adminOnly, intelligenceController.HandleSaveAnalysisProfile)
+		api.DELETE("/intelligence/profiles/:name", adminOnly, intelligenceController.HandleDeleteAnalysisProfile)
 
 		// Position management endpoints
-		api.POST("/positions/managed", positionController.HandlePlaceManagedPosition)
+		api.POST("/positions/managed", tradingOnly, orderLimit, positionController.HandlePlaceManagedPosition)
 		api.GET("/positions/managed", positionController.HandleListManagedPositions)
 		api.GET("/positions/managed/:id", positionController.HandleGetManagedPosition)
-		api.DELETE("/positions/managed/:id", positionController.HandleCloseManagedPosition)
+		api.DELETE("/positions/managed/:id", tradingOnly, orderLimit, positionController.HandleCloseManagedPosition)
+		api.POST("/positions/flatten", tradingOnly, orderLimit, positionController.HandleFlatten)
+		api.GET("/positions/splits", positionController.HandleListSplits)
+		api.POST("/positions/splits", tradingOnly, orderLimit, positionController.HandleApplySplit)
 
 		// Activity logging endpoints
 		api.GET("/activity/current", activityController.HandleGetCurrentActivity)
+		api.GET("/activity/events", activityController.HandleGetActivityEvents)
+		api.GET("/activity/sessions", activityController.HandleListSessions)
 		api.GET("/activity/:date", activityController.HandleGetActivityByDate)
 		api.GET("/activity", activityController.HandleListActivityLogs)
 		api.POST("/activity/session/start", activityController.HandleStartSession)
 		api.POST("/activity/session/end", activityController.HandleEndSession)
 		api.POST("/activity/log", activityController.HandleLogActivity)
+
+		// Reconciliation endpoints
+		api.GET("/reconciliation/status", reconciliationController.HandleGetStatus)
+		api.GET("/reconciliation/recovery", reconciliationController.HandleGetRecovery)
+		api.POST("/reconciliation/run", adminOnly, reconciliationController.HandleRun)
+
+		// Reporting endpoints
+		api.GET("/reports/strategies", reportController.HandleGetStrategyPerformance)
+		api.GET("/reports/books", reportController.HandleGetBookPerformance)
+		api.GET("/reports/shadow", reportController.HandleGetShadowComparison)
+		api.GET("/reports/timing", reportController.HandleGetTimingPerformance)
+		api.GET("/reports/portfolio-risk", reportController.HandleGetPortfolioRisk)
+		api.GET("/reports/benchmark", reportController.HandleGetBenchmarkComparison)
+		api.GET("/reports/heatmap", reportController.HandleGetHeatmap)
+
+		// Screener endpoints
+		api.GET("/screener/premarket", reportController.HandleGetPremarketScan)
+
+		// Technical analysis endpoints
+		api.GET("/analysis/:symbol/volume-profile", analysisController.HandleGetVolumeProfile)
+		api.GET("/analysis/:symbol/patterns", analysisController.HandleGetPatterns)
+		api.GET("/analysis/:symbol/relative-strength", analysisController.HandleGetRelativeStrength)
+		api.GET("/analysis/sectors", analysisController.HandleGetSectorRotation)
+
+		// Fundamentals endpoints
+		api.GET("/fundamentals/:symbol", fundamentalsController.HandleGetFundamentals)
+
+		// SEC filings endpoints
+		api.GET("/filings/:symbol", filingsController.HandleGetFilings)
+
+		// Economic calendar endpoints
+		api.GET("/calendar/economic", calendarController.HandleGetEconomicCalendar)
+
+		// Risk analysis endpoints
+		api.POST("/risk/stress", adminOnly, riskController.HandleStressTest)
+		api.GET("/risk/symbol-rules", riskController.HandleListSymbolRules)
+		api.GET("/risk/symbol-rules/:symbol", riskController.HandleGetSymbolRule)
+		api.PUT("/risk/symbol-rules/:symbol", adminOnly, riskController.HandleSaveSymbolRule)
+		api.DELETE("/risk/symbol-rules/:symbol", adminOnly, riskController.HandleDeleteSymbolRule)
+
+		// Alert endpoints
+		api.POST("/alerts", alertController.HandleCreateAlert)
+		api.GET("/alerts", alertController.HandleListAlerts)
+		api.GET("/alerts/events", alertController.HandleListEvents)
+		api.POST("/alerts/news-rules", alertController.HandleCreateNewsRule)
+		api.GET("/alerts/news-rules", alertController.HandleListNewsRules)
+		api.POST("/alerts/news-rules/preview", alertController.HandlePreviewNewsRule)
+		api.GET("/alerts/news-rules/events", alertController.HandleListNewsRuleMatches)
+		api.DELETE("/alerts/news-rules/:id", alertController.HandleDeleteNewsRule)
+		api.GET("/alerts/:id", alertController.HandleGetAlert)
+		api.DELETE("/alerts/:id", alertController.HandleDeleteAlert)
+
+		// Dollar-cost averaging endpoints
+		api.POST("/dca", tradingOnly, orderLimit, dcaController.HandleCreatePlan)
+		api.GET("/dca", dcaController.HandleListPlans)
+		api.GET("/dca/:id", dcaController.HandleGetPlan)
+		api.DELETE("/dca/:id", tradingOnly, orderLimit, dcaController.HandleCancelPlan)
+
+		// TWAP and VWAP algo order endpoints
+		api.POST("/algo/orders", tradingOnly, orderLimit, algoController.HandleCreateOrder)
+		api.GET("/algo/orders", algoController.HandleListOrders)
+		api.GET("/algo/orders/:id", algoController.HandleGetOrder)
+		api.DELETE("/algo/orders/:id", tradingOnly, orderLimit, algoController.HandleCancelOrder)
+
+		// Portfolio rebalancing endpoints
+		api.GET("/rebalance/targets", rebalanceController.HandleGetTargets)
+		api.PUT("/rebalance/targets", rebalanceController.HandleSetTargets)
+		api.POST("/rebalance", tradingOnly, orderLimit, rebalanceController.HandleRebalance)
+
+		// Grid trading endpoints
+		api.POST("/grids", tradingOnly, orderLimit, gridController.HandleCreateGrid)
+		api.GET("/grids", gridController.HandleListGrids)
+		api.GET("/grids/:id", gridController.HandleGetGrid)
+		api.POST("/grids/:id/pause", tradingOnly, orderLimit, gridController.HandlePauseGrid)
+		api.POST("/grids/:id/resume", tradingOnly, orderLimit, gridController.HandleResumeGrid)
+		api.POST("/grids/:id/stop", tradingOnly, orderLimit, gridController.HandleStopGrid)
+
+		// Backtesting endpoints
+		api.POST("/backtest/optimize", intelligenceLimit, backtestController.HandleOptimize)
+
+		// Research dataset export
+		api.GET("/export/dataset", intelligenceLimit, exportController.HandleExportDataset)
 	}
 
 	// Serve dashboard
-	router.Static("/dashboard", "./web")
+	dashboardController.RegisterRoutes(router)
 
 	return router
 }
 
 // Background task to clean up old data
-func startDataCleanup(ctx context.Context, storage interfaces.StorageService, retentionDays int, logger *logrus.Logger) {
-	ticker := time.NewTicker(24 * time.Hour) // Run daily
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			cutoff := time.Now().AddDate(0, 0, -retentionDays)
-			logger.WithField("cutoff", cutoff).Info("Running data cleanup")
+func cleanupOldData(storage interfaces.StorageService, retentionDays int, logger *logrus.Logger) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		cutoff := time.Now().AddDate(0, 0, -retentionDays)
+		logger.WithField("cutoff", cutoff).Info("Running data cleanup")
 
-			if err := storage.CleanupOldData(cutoff); err != nil {
-				logger.WithError(err).Error("Failed to cleanup old data")
-			}
-		}
+		return storage.CleanupOldData(cutoff)
 	}
 }
 
 // Background task to monitor and save positions
-func startPositionMonitor(ctx context.Context, orderController *controllers.OrderController, storage *database.LocalStorage, logger *logrus.Logger) {
-	ticker := time.NewTicker(5 * time.Minute) // Check every 5 minutes
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			// Get current positions
-			positions, err := orderController.GetPositions()
-			if err != nil {
-				logger.WithError(err).Error("Failed to get positions")
-				continue
-			}
-
-			// Save position snapshots
-			for _, position := range positions {
-				if err := storage.SavePosition(position); err != nil {
-					logger.WithError(err).Error("Failed to save position snapshot")
-				}
-			}
+func savePositionSnapshots(orderController *controllers.OrderController, storage *database.LocalStorage, logger *logrus.Logger) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		// Get current positions
+		positions, err := orderController.GetPositions()
+		if err != nil {
+			return fmt.Errorf("failed to get positions: %w", err)
+		}
 
-			// Get and save account snapshot
-			if account, err := orderController.GetAccount(); err == nil {
-				if err := storage.SaveAccountSnapshot(account); err != nil {
-					logger.WithError(err).Error("Failed to save account snapshot")
-				}
+		// Save position snapshots
+		var saveErr error
+		for _, position := range positions {
+			if err := storage.SavePosition(position); err != nil {
+				saveErr = fmt.Errorf("failed to save position snapshot: %w", err)
 			}
-
-			logger.WithField("positions", len(positions)).Debug("Position monitor update complete")
 		}
+
+		logger.WithField("positions", len(positions)).Debug("Position monitor update complete")
+		return saveErr
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func newPositionsCmd(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "positions",
+		Short: "List broker positions and active managed positions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := newApp(c)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			positions, err := a.tradingService.GetPositions(context.Background())
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SYMBOL\tSIDE\tQTY\tAVG ENTRY\tCURRENT\tMARKET VALUE\tUNREALIZED P&L")
+			for _, p := range positions {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					p.Symbol, p.Side, p.Qty.String(), p.AvgEntryPrice.StringFixed(2), p.CurrentPrice.StringFixed(2),
+					p.MarketValue.StringFixed(2), p.UnrealizedPL.StringFixed(2))
+			}
+			w.Flush()
+
+			managed, err := a.storageService.GetAllManagedPositions("ACTIVE")
+			if err != nil {
+				return err
+			}
+			if len(managed) == 0 {
+				return nil
+			}
+
+			fmt.Println()
+			w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "MANAGED ID\tSYMBOL\tSIDE\tQTY\tENTRY\tSTOP\tTARGET\tSTATUS")
+			for _, m := range managed {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%g\t%.2f\t%.2f\t%.2f\t%s\n",
+					m.PositionID, m.Symbol, m.Side, m.Quantity, m.EntryPrice,
+					m.StopLossPrice, m.TakeProfitPrice, m.Status)
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"prophet-trader/database"
+	"prophet-trader/services"
+	"prophet-trader/strategies"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+func newReplayCmd(c *cli) *cobra.Command {
+	var (
+		date          string
+		symbols       []string
+		from          string
+		to            string
+		speed         float64
+		capital       float64
+		historyDays   int
+		strategy      string
+		params        map[string]string
+		orderValue    float64
+		lookback      int
+		stopLossPct   float64
+		takeProfitPct float64
+		fills         services.FillModel
+	)
+
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Replay a past trading day through the stream hub against a simulated broker",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loc, err := time.LoadLocation("America/New_York")
+			if err != nil {
+				loc = time.UTC
+			}
+			day, err := time.ParseInLocation("2006-01-02", date, loc)
+			if err != nil {
+				return fmt.Errorf("invalid --date: %w", err)
+			}
+			fromTime, err := replayTime(day, from)
+			if err != nil {
+				return fmt.Errorf("invalid --from: %w", err)
+			}
+			toTime, err := replayTime(day, to)
+			if err != nil {
+				return fmt.Errorf("invalid --to: %w", err)
+			}
+			if (stopLossPct > 0) != (takeProfitPct > 0) {
+				return fmt.Errorf("--stop-loss-pct and --take-profit-pct must be set together")
+			}
+			for i := range symbols {
+				symbols[i] = strings.ToUpper(symbols[i])
+			}
+
+			a, err := newApp(c)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			// Orders, managed positions and trades from the replay go to a
+			// scratch database so the real one only gains the cached bars
+			scratchDir, err := os.MkdirTemp("", "prophet-replay-")
+			if err != nil {
+				return fmt.Errorf("failed to create replay database: %w", err)
+			}
+			defer os.RemoveAll(scratchDir)
+			scratch, err := database.NewLocalStorage(filepath.Join(scratchDir, "replay.db"))
+			if err != nil {
+				return err
+			}
+			defer scratch.Close()
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			clock := &services.ReplayClock{}
+			data := services.NewReplayDataService(a.dataService, clock)
+			// Earlier days are loaded as history for indicators
+			if err := data.Load(ctx, a.storageService, symbols, day.AddDate(0, 0, -historyDays), day.AddDate(0, 0, 1)); err != nil {
+				return err
+			}
+
+			broker := services.NewSimulatedBroker(data, decimal.NewFromFloat(capital), clock.Now)
+			if err := broker.SetFillModel(fills); err != nil {
+				return err
+			}
+			auditor := services.NewOrderAuditor(scratch)
+			trading := services.NewAuditedTradingService(broker, auditor)
+			riskManager := services.NewRiskManager(trading, data,
+				services.BuyingPowerRule{},
+				services.MaxOrderValueRule{Max: decimal.NewFromFloat(a.cfg.MaxOrderValue)},
+			)
+
+			replay := services.NewMarketReplay(clock, data, broker, services.NewStreamHub(data), riskManager)
+			if strategy != "" {
+				config := make(map[string]interface{}, len(params))
+				for key, value := range params {
+					config[key] = value
+				}
+				strat, err := strategies.New(strategy, config)
+				if err != nil {
+					return err
+				}
+				if orderValue <= 0 {
+					orderValue = capital / float64(len(symbols))
+				}
+				replay.SetStrategy(strat, decimal.NewFromFloat(orderValue), lookback)
+			}
+			if stopLossPct > 0 {
+				pm := services.NewPositionManager(trading, data, scratch, riskManager, auditor, false)
+				defer pm.Stop()
+				replay.SetPositionManager(pm, stopLossPct, takeProfitPct)
+			}
+
+			summary, err := replay.Run(ctx, fromTime, toTime, speed)
+			if err != nil {
+				return err
+			}
+			return printJSON(summary)
+		},
+	}
+
+	cmd.Flags().StringVar(&date, "date", "", "trading day to replay (YYYY-MM-DD, required)")
+	cmd.Flags().StringSliceVar(&symbols, "symbols", nil, "symbols to replay, comma separated (required)")
+	cmd.Flags().StringVar(&from, "from", "09:30", "replay start, Eastern time")
+	cmd.Flags().StringVar(&to, "to", "16:00", "replay end, Eastern time")
+	cmd.Flags().Float64Var(&speed, "speed", 10, fmt.Sprintf("multiple of real time (%d-%d)", services.MinReplaySpeed, services.MaxReplaySpeed))
+	cmd.Flags().Float64Var(&capital, "capital", 100000, "simulated starting cash")
+	cmd.Flags().IntVar(&historyDays, "history-days", 5, "calendar days of bars loaded before the replay for indicators")
+	cmd.Flags().StringVar(&strategy, "strategy", "", fmt.Sprintf("strategy to run on every bar %v", strategies.Names()))
+	cmd.Flags().StringToStringVar(&params, "param", nil, "strategy parameter as key=value, repeatable")
+	cmd.Flags().Float64Var(&orderValue, "order-value", 0, "dollars per strategy entry, defaults to capital split across symbols")
+	cmd.Flags().IntVar(&lookback, "lookback", 50, "bars of history passed to the strategy")
+	cmd.Flags().Float64Var(&stopLossPct, "stop-loss-pct", 0, "open strategy entries as managed positions with this stop loss")
+	cmd.Flags().Float64Var(&takeProfitPct, "take-profit-pct", 0, "take profit for managed positions, required with --stop-loss-pct")
+	addFillModelFlags(cmd, &fills)
+	cmd.MarkFlagRequired("date")
+	cmd.MarkFlagRequired("symbols")
+
+	return cmd
+}
+
+// replayTime is clock time HH:MM on day
+func replayTime(day time.Time, clock string) (time.Time, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location()), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"prophet-trader/config"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// cli holds state shared by every subcommand once configuration is loaded
+type cli struct {
+	cfg    *config.Config
+	logger *logrus.Logger
+}
+
+// newRootCmd builds the bot command tree. Running the binary without a
+// subcommand starts the HTTP server, matching the historical behaviour.
+func newRootCmd() *cobra.Command {
+	c := &cli{}
+
+	root := &cobra.Command{
+		Use:           "bot",
+		Short:         "Prophet Trader trading bot",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return c.load()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(c)
+		},
+	}
+
+	root.AddCommand(
+		newServeCmd(c),
+		newBacktestCmd(c),
+		newReplayCmd(c),
+		newDownloadCmd(c),
+		newAnalyzeCmd(c),
+		newPositionsCmd(c),
+		newFlattenCmd(c),
+		newExportCmd(c),
+		newBackupCmd(c),
+		newRestoreCmd(c),
+	)
+
+	return root
+}
+
+// load reads configuration and initializes the logger
+func (c *cli) load() error {
+	if err := config.Load(); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	c.cfg = config.AppConfig
+
+	c.logger = logrus.New()
+	c.logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	if c.cfg.EnableLogging {
+		level, _ := logrus.ParseLevel(c.cfg.LogLevel)
+		c.logger.SetLevel(level)
+	}
+
+	return nil
+}
@@ -0,0 +1,644 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"prophet-trader/config"
+	"prophet-trader/controllers"
+	"prophet-trader/services"
+	"prophet-trader/strategies"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Start the HTTP API server and background monitors",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(c)
+		},
+	}
+}
+
+// runServe starts the HTTP server and blocks until it exits
+func runServe(c *cli) error {
+	logger := c.logger
+	logger.Info("Starting Prophet Trader Bot...")
+
+	// Initialize services
+	logger.Info("Initializing services...")
+	a, err := newApp(c)
+	if err != nil {
+		return err
+	}
+	cfg := a.cfg
+
+	if cfg.Environment() == config.EnvironmentLive {
+		logger.Warn("LIVE TRADING: orders are placed with real money")
+	} else {
+		logger.Info("Paper trading")
+	}
+	if cfg.DryRun {
+		logger.Warn("DRY_RUN enabled: orders will be validated but never submitted")
+	}
+	if cfg.ObserverMode {
+		logger.Warn("OBSERVER_MODE enabled: trading endpoints are disabled and no orders will be placed")
+	}
+	if a.shadowTrading != nil {
+		logger.WithField("sources", strings.Join(cfg.ShadowSources, ",")).Warn("SHADOW_MODE enabled: orders from these sources are simulated, not sent")
+	}
+
+	// With INSTANCE_LOCK set, only the instance holding the writer lock
+	// trades; the others stand by, serve reads and reject orders
+	var writerLock *services.LeaderElector
+	if cfg.InstanceLock != "" && cfg.InstanceLock != "none" {
+		lock, err := services.NewInstanceLock(cfg.InstanceLock, cfg, a.storageService)
+		if err != nil {
+			return fmt.Errorf("failed to create instance lock: %w", err)
+		}
+		writerLock = services.NewLeaderElector(lock)
+		a.tradingService = writerLock.Guard(a.tradingService)
+	}
+
+	// Live events: orders placed through the API or the position manager are
+	// reported immediately, everything else is picked up by polling
+	eventBus := services.NewEventBus(cfg.EventHistorySize)
+	switch cfg.EventBusBackend {
+	case "memory":
+	case "redis":
+		// Events from every instance reach the API clients of each
+		relay, err := services.NewRedisEventRelay(cfg.RedisURL, cfg.RedisPrefix)
+		if err != nil {
+			return fmt.Errorf("failed to create event relay: %w", err)
+		}
+		eventBus.SetRelay(relay)
+	default:
+		return fmt.Errorf("unknown EVENT_BUS_BACKEND %q: use memory or redis", cfg.EventBusBackend)
+	}
+	liveEvents := services.NewLiveEventMonitor(a.tradingService, eventBus)
+	a.riskManager.SetEventBus(eventBus)
+	a.stockAnalysisService.SetEventBus(eventBus)
+	a.tradingService = services.NewEventedTradingService(a.tradingService, liveEvents)
+
+	// Each execution of an order is recorded from the broker's trade
+	// updates, or inferred from polled orders when it has none
+	fillTracker := services.NewFillTracker(a.storageService)
+	fillTracker.SetLiveEvents(liveEvents)
+	liveEvents.SetFillTracker(fillTracker)
+	eventsController := controllers.NewEventsController(eventBus)
+
+	// Create order controller
+	orderController := controllers.NewOrderController(
+		a.tradingService,
+		a.dataService,
+		a.storageService,
+		a.riskManager,
+		a.orderAuditor,
+		cfg.DryRun,
+	)
+
+	// Scheduled orders, and with ORDER_QUEUE_ENABLED orders placed while the
+	// market is closed, wait in the queue until the open
+	orderQueue := services.NewOrderQueue(a.storageService, a.tradingService, a.riskManager, a.orderAuditor, a.notifier)
+	orderQueue.SetMarketHours(a.marketHours)
+	switch cfg.OrderQueueBackend {
+	case "local":
+	case "redis":
+		// Queued orders go to the trading instance, whichever accepted them
+		jobs, err := services.NewRedisJobQueue(cfg.RedisURL, cfg.RedisPrefix, "order_queue")
+		if err != nil {
+			return fmt.Errorf("failed to create order job queue: %w", err)
+		}
+		orderQueue.SetJobQueue(jobs)
+	default:
+		return fmt.Errorf("unknown ORDER_QUEUE_BACKEND %q: use local or redis", cfg.OrderQueueBackend)
+	}
+	orderController.SetOrderQueue(orderQueue, cfg.OrderQueueEnabled)
+	orderController.SetMarketHours(a.marketHours)
+	orderController.SetFillTracker(fillTracker)
+	orderChaser := services.NewOrderChaser(a.tradingService, a.dataService, a.storageService)
+	if a.orderReplacer != nil {
+		orderChaser.SetOrderReplacer(a.orderReplacer)
+	}
+	orderController.SetOrderChaser(orderChaser)
+	orderController.SetBasketService(services.NewBasketService(a.tradingService, a.storageService, a.riskManager, a.orderAuditor, cfg.DryRun))
+	optionsChains := services.NewOptionsChainCache(
+		a.tradingService,
+		a.optionsQuotes,
+		time.Duration(cfg.OptionsChainTTLSeconds)*time.Second,
+		time.Duration(cfg.OptionsQuoteTTLSeconds)*time.Second,
+	)
+	orderController.SetOptionsChainCache(optionsChains)
+	orderController.SetOptionsAnalyzer(services.NewOptionsAnalyzer(a.tradingService, a.dataService, optionsChains))
+
+	newsController := controllers.NewNewsController(a.newsService)
+	newsController.SetSemanticIndex(a.semanticIndex)
+	intelligenceController := controllers.NewIntelligenceController(a.newsService, a.geminiService, a.analysisService, a.stockAnalysisService, a.dataService, a.regimeService, a.socialService)
+	intelligenceController.SetPromptStore(a.promptStore)
+	intelligenceController.SetAnalysisProfiles(a.analysisProfiles)
+	intelligenceController.SetToolBridge(a.llmTools)
+	chatService := services.NewChatService(a.tradingService, a.storageService, a.geminiService)
+	if a.semanticIndex != nil {
+		chatService.SetSemanticIndex(a.semanticIndex)
+	}
+	intelligenceController.SetChatService(chatService)
+	analysisController := controllers.NewAnalysisController(a.analysisService)
+	fundamentalsController := controllers.NewFundamentalsController(a.fundamentalsService)
+	calendarController := controllers.NewCalendarController(a.calendarService)
+	authService := services.NewAuthService(a.storageService, cfg.APIAdminToken)
+	authController := controllers.NewAuthController(authService)
+	if cfg.APIAdminToken == "" {
+		logger.Warn("API_ADMIN_TOKEN not set: API authentication is disabled")
+	}
+
+	// Test account connection
+	logger.Info("Testing Alpaca connection...")
+	if account, err := orderController.GetAccount(); err != nil {
+		return fmt.Errorf("failed to connect to Alpaca: %w", err)
+	} else {
+		logger.WithFields(logrus.Fields{
+			"cash":            account.Cash,
+			"buying_power":    account.BuyingPower,
+			"portfolio_value": account.PortfolioValue,
+		}).Info("Successfully connected to Alpaca")
+	}
+
+	// Start background tasks
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Create position manager
+	positionManager := services.NewPositionManager(a.tradingService, a.dataService, a.storageService, a.riskManager, a.orderAuditor, cfg.DryRun)
+	positionManager.SetExpiryExit(time.Duration(cfg.ZeroDTEExitMinutes) * time.Minute)
+	positionManager.SetMarketHours(a.marketHours)
+	positionController := controllers.NewPositionManagementController(positionManager)
+	positionController.SetFlattenService(services.NewFlattenService(a.tradingService, a.orderAuditor, positionManager))
+
+	// Create the split adjuster, which rescales stored positions and trades
+	// when a held symbol splits
+	splitAdjuster := services.NewSplitAdjuster(a.splitSource, a.tradingService, a.storageService, positionManager, a.notifier)
+	positionController.SetSplitAdjuster(splitAdjuster)
+
+	// Create the symbol alias map, which moves a changed ticker's history
+	// and managed positions to its new symbol
+	symbolAliases := services.NewSymbolAliasService(a.storageService, positionManager, a.notifier)
+
+	// Create reconciler
+	reconciler := services.NewReconciler(a.tradingService, a.storageService, positionManager, a.notifier, cfg.ReconcileAutoHeal && !cfg.ObserverMode)
+	reconciliationController := controllers.NewReconciliationController(reconciler)
+
+	// Create covered call monitor, which settles managed positions whose
+	// shares are called away
+	coveredCalls := services.NewCoveredCallMonitor(a.tradingService, positionManager, a.notifier)
+	orderController.SetCoveredCalls(coveredCalls)
+
+	// Create reporting service
+	reportingService := services.NewReportingService(a.tradingService, a.dataService, a.storageService, positionManager)
+	portfolioRiskService := services.NewPortfolioRiskService(a.tradingService, a.dataService, cfg.MaxSymbolConcentrationPct, cfg.MaxSectorConcentrationPct)
+	accountActivities := services.NewAccountActivityService(a.accountActivities, a.storageService)
+	reportingService.SetAccountActivities(accountActivities)
+	reportController := controllers.NewReportController(reportingService, portfolioRiskService, cfg.BenchmarkSymbols, cfg.HeatmapWatchlist)
+	reportController.SetAccountActivities(accountActivities)
+	portfolioHistory := services.NewPortfolioHistoryService(a.tradingService, a.storageService)
+	if a.intradayHistory != nil {
+		portfolioHistory.SetIntradaySource(a.intradayHistory)
+	}
+	reportController.SetPortfolioHistory(portfolioHistory)
+
+	// Create the pre-market scanner, which ranks the watchlist by gap and
+	// publishes before each open
+	var premarketScanner *services.PremarketScanner
+	if cfg.PremarketScanMinutes > 0 {
+		premarketWatchlist := cfg.PremarketWatchlist
+		if len(premarketWatchlist) == 0 {
+			premarketWatchlist = cfg.HeatmapWatchlist
+		}
+		premarketScanner = services.NewPremarketScanner(a.dataService, a.newsService, a.marketHours, a.notifier, premarketWatchlist,
+			time.Duration(cfg.PremarketScanMinutes)*time.Minute, cfg.PremarketMinGapPct, cfg.PremarketTopN)
+		reportController.SetPremarketScanner(premarketScanner)
+	}
+
+	// Create stress tester
+	optionsDataService := services.NewAlpacaOptionsDataService(cfg.AlpacaAPIKey, cfg.AlpacaSecretKey)
+	stressTestService := services.NewStressTestService(a.tradingService, a.dataService, optionsDataService, a.storageService)
+	orderController.SetOptionsExposure(services.NewOptionsExposureService(a.tradingService, a.dataService, optionsDataService))
+	riskController := controllers.NewRiskController(stressTestService)
+
+	// Create the margin monitor, alerting as utilization crosses thresholds
+	marginMonitor := services.NewMarginMonitor(a.tradingService, a.storageService, a.notifier, cfg.MarginAlertThresholds)
+	riskController.SetMarginMonitor(marginMonitor)
+	riskController.SetSymbolRules(a.symbolRules)
+
+	// Create alerting engine fed by the market data stream hub
+	streamHub := services.NewStreamHub(a.dataService)
+	alertEngine := services.NewAlertEngine(streamHub, a.dataService, a.newsService, a.storageService, a.notifier, strategies.New)
+	if cfg.RSFilterEnabled {
+		alertEngine.SetSignalFilter(relativeStrengthFilter(a.analysisService, cfg))
+	}
+	alertEngine.SetEventBus(eventBus)
+	alertController := controllers.NewAlertController(alertEngine)
+
+	// Build custom bars live from streamed trades
+	var barAggregator *services.BarAggregator
+	if len(cfg.BarAggregatorSymbols) > 0 {
+		if a.tradeSource == nil {
+			return fmt.Errorf("BAR_AGGREGATOR_SYMBOLS needs a data provider that streams trades (alpaca or polygon)")
+		}
+		barAggregator = services.NewBarAggregator(a.tradeSource)
+		for _, symbol := range cfg.BarAggregatorSymbols {
+			if err := barAggregator.Track(symbol, cfg.BarAggregatorSpecs...); err != nil {
+				return fmt.Errorf("invalid BAR_AGGREGATOR_BARS: %w", err)
+			}
+		}
+		barAggregator.SetEventBus(eventBus)
+		orderController.SetBarAggregator(barAggregator)
+	}
+
+	// Create news rules evaluated against headlines as the news store
+	// receives them
+	newsPipeline := services.NewNewsPipeline(a.newsService, alertEngine, a.storageService, a.notifier)
+	newsPipeline.SetEventBus(eventBus)
+	alertController.SetNewsPipeline(newsPipeline)
+
+	// Create dollar-cost averaging plans, bought through the same risk
+	// checks as every other order
+	dcaService := services.NewDCAService(a.storageService, a.tradingService, a.dataService, a.riskManager, a.regimeService, a.notifier, cfg.DryRun)
+	dcaService.SetMarketHours(a.marketHours)
+	dcaController := controllers.NewDCAController(dcaService)
+
+	algoService := services.NewAlgoOrderService(a.storageService, a.tradingService, a.dataService, a.riskManager, a.notifier, cfg.DryRun)
+	algoService.SetMarketHours(a.marketHours)
+	algoService.SetEventBus(eventBus)
+	algoController := controllers.NewAlgoController(algoService)
+
+	conditionalService := services.NewConditionalOrderService(streamHub, a.tradingService, a.dataService, a.storageService, a.riskManager, a.notifier, cfg.DryRun)
+	conditionalController := controllers.NewConditionalController(conditionalService)
+
+	// Create the rebalancing engine for target allocations
+	rebalanceService := services.NewRebalanceService(a.storageService, a.tradingService, a.dataService, a.riskManager, cfg.RebalanceTolerancePct, cfg.RebalanceMinTradeValue, cfg.DryRun)
+	rebalanceService.SetMarketHours(a.marketHours)
+	rebalanceController := controllers.NewRebalanceController(rebalanceService)
+
+	// Create grid trading ladders
+	gridService := services.NewGridService(a.storageService, a.tradingService, a.dataService, a.riskManager, a.notifier, cfg.DryRun)
+	gridController := controllers.NewGridController(gridService)
+
+	// Create the strategy parameter optimizer
+	optimizer := services.NewStrategyOptimizer(services.NewBarCacheDataService(a.dataService, a.storageService), strategies.New, cfg.OptimizerWorkers)
+	backtestController := controllers.NewBacktestController(optimizer)
+	exportController := controllers.NewExportController(services.NewDatasetExporter(a.storageService))
+
+	// Create SEC filings monitor
+	filingsMonitor := services.NewFilingsMonitor(a.storageService, a.tradingService, a.geminiService, a.notifier, cfg.FilingsWatchlist, cfg.SECUserAgent, cfg.FilingsAISummaries)
+	filingsController := controllers.NewFilingsController(filingsMonitor)
+
+	// Create activity logger
+	activityLogger := services.NewActivityLogger(cfg.ActivityLogDir)
+	activityLogger.SetEnvironment(cfg.Environment())
+	activityLogger.SetTradingService(a.tradingService)
+	activityLogger.SetStorage(a.storageService)
+	activityLogger.SetFeeModel(services.FillModel{
+		CommissionPerShare:    cfg.CommissionPerShare,
+		MinCommission:         cfg.MinCommission,
+		OptionsFeePerContract: cfg.OptionsFeePerContract,
+	})
+	activityController := controllers.NewActivityController(activityLogger)
+	activityEvents := services.NewActivityEventStore(a.storageService)
+	activityController.SetEventStore(activityEvents)
+
+	backupController := controllers.NewBackupController(a.backupService)
+
+	dashboardController, err := controllers.NewDashboardController(a.tradingService, positionManager, activityLogger, authService, cfg.DashboardDir, cfg.Environment(), cfg.DryRun)
+	if err != nil {
+		return err
+	}
+	dashboardController.SetObserverMode(cfg.ObserverMode)
+	dashboardController.SetPortfolioHistory(portfolioHistory)
+
+	// Create the market brief, revised from stored news in the background
+	var briefService *services.MarketBriefService
+	if cfg.BriefIntervalMinutes > 0 {
+		briefService = services.NewMarketBriefService(a.newsService, a.geminiService)
+		briefService.SetEventBus(eventBus)
+		intelligenceController.SetMarketBrief(briefService)
+		dashboardController.SetMarketBrief(briefService)
+	}
+
+	// Start trading session automatically
+	if account, err := orderController.GetAccount(); err == nil {
+		activityLogger.StartSession(ctx, account.PortfolioValue.InexactFloat64())
+		logger.Info("Activity logging session started")
+	}
+
+	// Per-caller request limits; orders and the Gemini-backed intelligence
+	// endpoints get stricter buckets on top of the default one
+	rateLimiter := services.NewRateLimiter()
+	rateLimiter.SetLimit(controllers.RateLimitDefault, services.RateLimit{PerMinute: cfg.RateLimitPerMinute})
+	rateLimiter.SetLimit(controllers.RateLimitOrders, services.RateLimit{PerMinute: cfg.OrderRateLimit})
+	rateLimiter.SetLimit(controllers.RateLimitIntelligence, services.RateLimit{PerMinute: cfg.IntelligenceRateLimit})
+
+	// Background workers report their status to GET /admin/workers and are
+	// restarted with backoff when they panic
+	workers := services.NewWorkerRegistry()
+	workers.SetNotifier(a.notifier)
+	workerController := controllers.NewWorkerController(workers)
+
+	if cfg.CORSAllowCredentials && slices.Contains(cfg.CORSAllowedOrigins, "*") {
+		logger.Warn("CORS_ALLOW_CREDENTIALS with CORS_ALLOWED_ORIGINS=*: any website can make authenticated API calls from a signed-in browser")
+	}
+	cors := controllers.CORSPolicy{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+	}
+
+	// Every state-changing API call is recorded for GET /admin/audit
+	apiAudit := services.NewAPIAuditLog(a.storageService)
+
+	// Setup HTTP server
+	router := setupRouter(orderController, newsController, intelligenceController, positionController, activityController, reconciliationController, reportController, riskController, alertController, dcaController, algoController, conditionalController, rebalanceController, gridController, backtestController, analysisController, fundamentalsController, filingsController, calendarController, eventsController, dashboardController, backupController, exportController, authController, workerController, symbolAliases, apiAudit, rateLimiter, writerLock, cors, cfg.MaxRequestBodyBytes, cfg.CompressMinBytes, time.Duration(cfg.ResponseCacheMaxAge)*time.Second, cfg.Environment(), cfg.ObserverMode)
+
+	// Create account snapshots for the equity curve
+	snapshotter := services.NewAccountSnapshotter(a.tradingService, a.storageService, services.SnapshotPolicy{
+		Interval:            time.Duration(cfg.SnapshotIntervalSeconds) * time.Second,
+		MinuteRetentionDays: cfg.SnapshotMinuteDays,
+		HourlyRetentionDays: cfg.SnapshotHourlyDays,
+	})
+	snapshotter.SetMarketHours(a.marketHours)
+
+	// Jobs that trade or write shared state; with INSTANCE_LOCK set they
+	// run on the instance holding the writer lock only
+	startWriters := func(ctx context.Context) {
+		// Start data cleanup routine
+		workers.Every(ctx, "data_cleanup", 24*time.Hour, cleanupOldData(a.storageService, cfg.DataRetentionDays, logger))
+
+		// Start position monitor
+		workers.Every(ctx, "position_snapshots", 5*time.Minute, savePositionSnapshots(orderController, a.storageService, logger))
+
+		// Start account snapshots
+		workers.Go(ctx, "account_snapshots", snapshotter.Run)
+
+		// Watch margin utilization
+		if cfg.MarginCheckSeconds > 0 {
+			workers.Every(ctx, "margin_monitor", time.Duration(cfg.MarginCheckSeconds)*time.Second, marginMonitor.Check)
+		}
+
+		// Ingest dividends, fees and transfers
+		if a.accountActivities != nil {
+			go func() {
+				if err := accountActivities.RunSync(ctx); err != nil {
+					logger.WithError(err).Warn("Initial account activity sync failed")
+				}
+			}()
+			workers.Every(ctx, "account_activities", time.Hour, accountActivities.RunSync)
+		}
+
+		// Re-associate the managed positions loaded from storage with their
+		// broker orders before monitoring resumes
+		if _, err := reconciler.Recover(ctx); err != nil {
+			logger.WithError(err).Error("Managed position recovery failed")
+		}
+
+		// Nothing that places orders runs in observer mode
+		if !cfg.ObserverMode {
+			// Start managed position monitoring
+			workers.Every(services.WithOrderSource(ctx, services.OrderSourcePositionManager), "managed_positions", 10*time.Second, func(ctx context.Context) error {
+				positionManager.CheckPositions(ctx)
+				return nil
+			})
+
+			// Adjust stored positions and trades for splits of held
+			// symbols, replacing managed stops and targets at the new prices
+			if a.splitSource != nil {
+				splitCtx := services.WithOrderSource(ctx, services.OrderSourcePositionManager)
+				go func() {
+					if err := splitAdjuster.Check(splitCtx); err != nil {
+						logger.WithError(err).Warn("Initial split check failed")
+					}
+				}()
+				workers.Every(splitCtx, "split_adjustments", time.Hour, splitAdjuster.Check)
+			}
+
+			// Start submitting queued orders at the open
+			workers.Every(ctx, "order_queue", 30*time.Second, func(ctx context.Context) error {
+				_, err := orderQueue.ProcessDue(ctx)
+				return err
+			})
+
+			// Start DCA purchases
+			workers.Every(ctx, "dca", time.Minute, dcaService.RunDue)
+
+			// Re-peg chased orders as the quote moves
+			workers.Every(ctx, "order_chaser", time.Second, orderChaser.Run)
+
+			// Work TWAP and VWAP orders
+			workers.Every(ctx, "algo_orders", 15*time.Second, algoService.RunDue)
+
+			// Submit conditional orders as their conditions fire
+			workers.Go(ctx, "conditional_orders", conditionalService.Run)
+
+			// Start watching covered calls for assignment
+			workers.Every(services.WithOrderSource(ctx, services.OrderSourcePositionManager), "covered_calls", time.Minute, coveredCalls.Check)
+
+			// Start answering grid fills
+			workers.Every(ctx, "grid", 30*time.Second, gridService.ProcessFills)
+
+			// Start filling resting shadow orders
+			if a.shadowTrading != nil {
+				workers.Every(ctx, "shadow_orders", 30*time.Second, func(ctx context.Context) error {
+					_, err := a.shadowTrading.Match(ctx)
+					return err
+				})
+			}
+		}
+
+		// Start broker reconciliation
+		if cfg.ReconcileInterval > 0 {
+			workers.Every(services.WithOrderSource(ctx, services.OrderSourcePositionManager), "reconciler", time.Duration(cfg.ReconcileInterval)*time.Minute, func(ctx context.Context) error {
+				_, err := reconciler.Reconcile(ctx)
+				return err
+			})
+		}
+
+		// Start scheduled backups; PostgreSQL is backed up with its own tooling
+		if cfg.BackupIntervalHours > 0 && cfg.DatabaseURL == "" {
+			workers.Every(ctx, "backups", time.Duration(cfg.BackupIntervalHours)*time.Hour, a.backupService.ScheduledBackup)
+		}
+
+		// Start social sentiment collection
+		if cfg.SocialPollMinutes > 0 {
+			workers.Every(ctx, "social_sentiment", time.Duration(cfg.SocialPollMinutes)*time.Minute, func(ctx context.Context) error {
+				a.socialService.Collect(ctx)
+				return nil
+			})
+			workers.Trigger("social_sentiment")
+		}
+
+		// Start SEC filings monitor
+		if cfg.FilingsPollMinutes > 0 {
+			workers.Every(ctx, "filings", time.Duration(cfg.FilingsPollMinutes)*time.Minute, func(ctx context.Context) error {
+				filingsMonitor.Poll(ctx)
+				return nil
+			})
+			workers.Trigger("filings")
+		}
+	}
+	if writerLock == nil {
+		startWriters(ctx)
+	} else {
+		workers.Go(ctx, "writer_lock", func(ctx context.Context) {
+			writerLock.Run(ctx, 10*time.Second, func(ctx context.Context) {
+				// Another instance may have changed positions while this one
+				// stood by
+				if err := positionManager.Reload(); err != nil {
+					logger.WithError(err).Error("Failed to reload managed positions")
+				}
+				startWriters(ctx)
+			})
+		})
+	}
+
+	// Start market data stream and alert evaluation
+	streamInterval := time.Duration(cfg.StreamPollSeconds) * time.Second
+	if streamInterval <= 0 {
+		streamInterval = 15 * time.Second
+	}
+	workers.Every(ctx, "market_stream", streamInterval, func(ctx context.Context) error {
+		streamHub.Poll(ctx)
+		return nil
+	})
+	workers.Go(ctx, "alerts", alertEngine.Run)
+	if barAggregator != nil {
+		workers.Go(ctx, "bar_aggregator", barAggregator.Run)
+	}
+	workers.Go(ctx, "news_pipeline", newsPipeline.Run)
+
+	// Start live event polling
+	eventsInterval := time.Duration(cfg.EventsPollSeconds) * time.Second
+	if eventsInterval <= 0 {
+		eventsInterval = 5 * time.Second
+	}
+	workers.Every(ctx, "live_events", eventsInterval, func(ctx context.Context) error {
+		liveEvents.Poll(ctx)
+		return nil
+	})
+
+	if a.tradeUpdates != nil {
+		workers.Go(ctx, "trade_updates", func(ctx context.Context) {
+			fillTracker.Run(ctx, a.tradeUpdates)
+		})
+	}
+
+	// Trading activity events go to the activity log, storage and
+	// notifications; the event stream delivers them to API clients
+	if cfg.EventBusBackend == "redis" {
+		workers.Go(ctx, "event_relay", eventBus.RunRelay)
+	}
+	workers.Go(ctx, "activity_log", func(ctx context.Context) {
+		eventBus.Listen(ctx, "activity_log", activityLogger.HandleEvent, services.ActivityLogEventTypes...)
+	})
+	workers.Every(ctx, "session_sampler", time.Minute, func(ctx context.Context) error {
+		activityLogger.Sample(ctx)
+		return nil
+	})
+	workers.Go(ctx, "activity_store", func(ctx context.Context) {
+		eventBus.Listen(ctx, "activity_store", activityEvents.HandleEvent, services.ActivityEventTypes...)
+	})
+	eventNotifier := services.NewEventNotifier(a.notifier)
+	workers.Go(ctx, "notifications", func(ctx context.Context) {
+		eventBus.Listen(ctx, "notifications", eventNotifier.HandleEvent, services.EventNotifierTypes...)
+	})
+
+	// Start news feed polling
+	if cfg.NewsPollSeconds > 0 {
+		interval := time.Duration(cfg.NewsPollSeconds) * time.Second
+		a.newsService.SetPollInterval(interval)
+		workers.Every(ctx, "news_feeds", interval, func(ctx context.Context) error {
+			a.newsService.Poll(ctx)
+			return nil
+		})
+		workers.Trigger("news_feeds")
+	}
+
+	// Start embedding news and analyses for semantic search
+	if a.semanticIndex != nil {
+		workers.Go(ctx, "semantic_index", func(ctx context.Context) {
+			a.semanticIndex.Run(ctx, 30*time.Second)
+		})
+	}
+
+	// Start the pre-market scan
+	if premarketScanner != nil {
+		workers.Every(ctx, "premarket_scanner", time.Minute, premarketScanner.Tick)
+	}
+
+	// Start market brief updates
+	if briefService != nil {
+		workers.Go(ctx, "market_brief", func(ctx context.Context) {
+			briefService.Run(ctx, time.Duration(cfg.BriefIntervalMinutes)*time.Minute)
+		})
+	}
+
+	// Start credential rotation from the secrets backend
+	if cfg.SecretsBackend != "" && cfg.SecretsRefreshMinutes > 0 {
+		rotator := services.NewSecretsRotator(append(a.credentialRotators, optionsDataService)...)
+		workers.Every(ctx, "secrets_rotation", time.Duration(cfg.SecretsRefreshMinutes)*time.Minute, func(ctx context.Context) error {
+			refreshCtx, cancel := context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+			return rotator.Refresh(refreshCtx)
+		})
+	}
+
+	// Setup graceful shutdown
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-shutdown
+		logger.Info("Shutting down gracefully...")
+		cancel()
+		time.Sleep(2 * time.Second)
+		os.Exit(0)
+	}()
+
+	// Start HTTP server
+	logger.WithField("port", cfg.ServerPort).Info("Starting HTTP server...")
+	if err := router.Run(":" + cfg.ServerPort); err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+
+	return nil
+}
+
+// relativeStrengthFilter drops strategy buy signals for symbols lagging SPY,
+// their sector ETF or outside the top-ranked sectors. Sells always pass.
+func relativeStrengthFilter(analysisService *services.TechnicalAnalysisService, cfg *config.Config) services.SignalFilter {
+	minVsSPY, minVsSector := cfg.RSFilterMinVsSPYPct, cfg.RSFilterMinVsSectorPct
+	filter := services.RelativeStrengthFilter{
+		LookbackDays:   cfg.RSFilterLookbackDays,
+		MinVsMarketPct: &minVsSPY,
+		MinVsSectorPct: &minVsSector,
+		TopSectors:     cfg.RSFilterTopSectors,
+	}
+
+	return func(ctx context.Context, symbol, action string) (bool, string) {
+		if action != "buy" {
+			return true, ""
+		}
+		ok, reason, err := analysisService.CheckRelativeStrength(ctx, symbol, filter)
+		if err != nil {
+			return false, fmt.Sprintf("relative strength unavailable: %v", err)
+		}
+		return ok, reason
+	}
+}
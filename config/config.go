@@ -3,22 +3,177 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	AlpacaAPIKey      string
-	AlpacaSecretKey   string
-	AlpacaBaseURL     string
-	AlpacaPaper       bool
-	GeminiAPIKey      string
-	DatabasePath      string
-	ServerPort        string
-	EnableLogging     bool
-	LogLevel          string
-	DataRetentionDays int
-	AlpacaDataFeed    string
+	AlpacaAPIKey              string
+	AlpacaSecretKey           string
+	AlpacaBaseURL             string
+	AlpacaPaper               bool
+	GeminiAPIKey              string
+	DatabasePath              string
+	DatabaseURL               string // PostgreSQL connection URL, overrides DatabasePath
+	ServerPort                string
+	EnableLogging             bool
+	LogLevel                  string
+	DataRetentionDays         int
+	AlpacaDataFeed            string
+	DryRun                    bool     // validate and risk-check orders without submitting them
+	ObserverMode              bool     // disable trading entirely; data, analysis and reports keep working
+	ShadowMode                bool     // simulate orders from ShadowSources instead of sending them
+	ShadowSources             []string // order sources shadowed in shadow mode
+	MaxOrderValue             float64  // per-order notional limit, 0 disables
+	NotifyWebhookURL          string
+	NotifyRules               map[string]NotifyRule // per channel: "log" or "webhook"
+	ReconcileInterval         int                   // minutes between broker reconciliations, 0 disables
+	ReconcileAutoHeal         bool
+	MaxSymbolConcentrationPct float64 // percent of portfolio, 0 disables
+	MaxSectorConcentrationPct float64 // percent of portfolio, 0 disables
+	BlockOnConcentration      bool    // reject instead of warn when limits are exceeded
+	BenchmarkSymbols          []string
+	HeatmapWatchlist          []string // symbols on the market heatmap
+	PremarketWatchlist        []string // symbols ranked by the pre-market scanner, empty uses HeatmapWatchlist
+	PremarketScanMinutes      int      // minutes before the open the pre-market scan is published, 0 disables
+	PremarketMinGapPct        float64  // smallest absolute gap named in the pre-market notification
+	PremarketTopN             int      // movers named in the pre-market notification
+	StreamPollSeconds         int      // how often the stream hub polls tracked symbols
+	EventsPollSeconds         int      // how often orders and positions are polled for live events
+	NewsPollSeconds           int      // how often stored news feeds are refreshed, 0 refreshes on request
+	BriefIntervalMinutes      int      // how often the market brief is revised, 0 disables it
+	EventHistorySize          int      // events kept for Last-Event-ID replay
+	SnapshotIntervalSeconds   int      // account snapshot cadence during market hours
+	SnapshotMinuteDays        int      // days to keep full-resolution snapshots
+	SnapshotHourlyDays        int      // days to keep hourly snapshots, daily after that
+	ActivityLogDir            string
+	BackupDir                 string // local backup directory, used when no S3 bucket is set
+	BackupIntervalHours       int    // hours between scheduled backups, 0 disables
+	BackupKeep                int    // newest backups kept, 0 keeps all
+	BackupS3Endpoint          string
+	BackupS3Bucket            string
+	BackupS3Prefix            string
+	BackupS3Region            string
+	BackupS3AccessKey         string
+	BackupS3SecretKey         string
+	DashboardDir              string  // serve dashboard templates from disk, empty uses embedded assets
+	PromptTemplateDir         string  // <name>.tmpl files overriding the built-in LLM prompts
+	LLMToolMaxCalls           int     // data tool calls the LLM may make per chat answer, 0 disables tools
+	EmbeddingProvider         string  // registered EmbeddingProvider for semantic search: "gemini" or "hashing", "none" disables
+	CommissionPerShare        float64 // broker commission schedule, for session fee estimates
+	MinCommission             float64 // per stock order
+	OptionsFeePerContract     float64
+	RSFilterEnabled           bool    // drop strategy buy signals for relatively weak symbols
+	RSFilterLookbackDays      int     // trading days: 21, 63, 126 or 252
+	RSFilterMinVsSPYPct       float64 // minimum excess return over SPY
+	RSFilterMinVsSectorPct    float64 // minimum excess return over the sector ETF
+	RSFilterTopSectors        int     // require the sector to rank in the top N, 0 disables
+	RegimeRuleEnabled         bool    // apply market regime sizing and checks to new entries
+	RegimeNeutralSizePct      float64 // percent of requested size in a neutral regime
+	RegimeRiskOffSizePct      float64 // percent of requested size in a risk-off regime
+	RegimeBlockRiskOff        bool    // reject new buys while risk-off
+	Broker                    string  // registered TradingService: "alpaca", "ibkr" or "tradier"
+	IBKRBaseURL               string  // Client Portal Gateway API root
+	IBKRAccountID             string  // empty uses the gateway's selected account
+	IBKRInsecureTLS           bool    // accept the gateway's self-signed certificate
+	DataProvider              string  // registered DataService: "alpaca", "tradier" or "polygon"
+	TradierAccessToken        string
+	TradierAccountID          string
+	TradierSandbox            bool     // use the Tradier sandbox (paper) environment
+	DataFallbackProviders     []string // providers tried in order when DataProvider errors
+	AlpacaDataRateLimit       int      // Alpaca bar chunks fetched per minute, 0 is unpaced
+	BarAggregatorSymbols      []string // symbols whose custom bars are built live from trades
+	BarAggregatorSpecs        []string // custom bars built for each, such as "500Tick"
+	PolygonAPIKey             string
+	PolygonDelayed            bool     // stream from the 15-minute delayed cluster
+	FundamentalsProvider      string   // registered FundamentalsProvider: "yahoo"
+	FilingsPollMinutes        int      // minutes between EDGAR polls, 0 disables the monitor
+	FilingsWatchlist          []string // symbols watched besides open positions
+	FilingsAISummaries        bool     // summarize recent filings with Gemini
+	SECUserAgent              string   // "name email" sent to EDGAR as required by the SEC
+	SocialSubreddits          []string
+	SocialXListIDs            []string
+	XBearerToken              string
+	SocialPollMinutes         int       // minutes between social collections, 0 disables background collection
+	SocialInAnalysis          bool      // include social sentiment in stock analyses
+	EconomicCalendarSource    string    // registered CalendarSource: "forexfactory" or "file"
+	EconomicCalendarFile      string    // JSON events file for the "file" source
+	MacroRuleEnabled          bool      // apply the macro event rule to new entries
+	MacroWindowBeforeMinutes  int       // minutes before a high-impact event the window opens
+	MacroWindowAfterMinutes   int       // minutes after a high-impact event the window closes
+	MacroBlockEntries         bool      // reject new buys inside the window instead of warning
+	MacroStopWidenPct         float64   // percent added to stop distances inside the window, 0 disables
+	ShortDTERuleEnabled       bool      // apply the short-dated options rule to option entries
+	ShortDTEMaxDays           int       // options expiring within this many days are short-dated
+	ShortDTEMaxOrderValue     float64   // per-trade notional limit on short-dated entries, 0 disables
+	ShortDTEMaxRiskPct        float64   // per-trade notional limit as a percent of portfolio value, 0 disables
+	ZeroDTEEntryCutoff        string    // HH:MM ET after which same-day expiry entries are blocked or warned
+	ZeroDTEBlockAfterCutoff   bool      // reject same-day expiry entries after the cutoff instead of warning
+	ZeroDTEExitMinutes        int       // minutes before the close managed same-day expiries are closed, 0 disables
+	TradingHoursRuleEnabled   bool      // flag orders placed outside their asset class's trading session
+	TradingHoursBlock         bool      // reject such orders instead of warning
+	MarginCheckSeconds        int       // how often margin utilization is checked for alerts, 0 disables
+	MarginAlertThresholds     []float64 // maintenance margin as a percent of equity at which to notify
+	MarginMaxUtilizationPct   float64   // flag margin-increasing orders above this utilization, 0 disables
+	MarginBlock               bool      // reject such orders instead of warning
+	SymbolBlocklist           []string  // symbols never opened or added to
+	MinSharePrice             float64   // reject share buys and short sales below this price, 0 disables
+	APIAdminToken             string    // bootstrap admin token; setting it enables API authentication
+	EncryptionKey             string    // base64 or hex AES-256 key for sensitive columns, empty disables
+	SecretsBackend            string    // "aws", "gcp" or "vault" to load credentials from a secret manager
+	SecretsRefreshMinutes     int       // minutes between secret rotation checks, 0 disables
+	LiveTradingConfirmed      bool      // required to start against a live (non-paper) account
+	LiveStartDate             string    // YYYY-MM-DD the account went live, starts the restricted period
+	LiveRestrictedDays        int       // days after LiveStartDate the live limits apply, 0 disables
+	LiveAllowedSymbols        []string  // symbols tradable during the restricted period, empty allows all
+	LiveMaxOrderValue         float64   // per-order notional limit during the restricted period, 0 disables
+	OrderQueueEnabled         bool      // queue orders placed while the market is closed until the open
+	RebalanceTolerancePct     float64   // drift in percentage points tolerated before a symbol is rebalanced
+	RebalanceMinTradeValue    float64   // smallest rebalance trade in dollars
+	OptimizerWorkers          int       // parallel backtests per optimization, 0 uses one per CPU
+	RateLimitPerMinute        int       // requests per minute per token or IP, 0 disables
+	OrderRateLimit            int       // order placement and cancellation requests per minute, 0 disables
+	IntelligenceRateLimit     int       // intelligence requests per minute, 0 disables
+	MaxRequestBodyBytes       int64     // largest accepted request body, 0 disables
+	CompressMinBytes          int       // smallest response body that is gzipped, 0 disables compression
+	ResponseCacheMaxAge       int       // seconds clients may reuse cacheable GET responses before revalidating
+	CORSAllowedOrigins        []string  // browser origins allowed to call the API; host:* matches any port, * any origin
+	CORSAllowedMethods        []string  // methods allowed in cross-origin requests
+	CORSAllowedHeaders        []string  // request headers allowed in cross-origin requests
+	CORSAllowCredentials      bool      // let cross-origin requests carry cookies and auth headers
+	QuoteCacheTTLMillis       int       // how long a latest quote is reused, 0 only merges concurrent requests
+	PositionCacheTTLSeconds   int       // how long broker positions are reused, 0 only merges concurrent requests
+	OptionsChainTTLSeconds    int       // how long a cached options chain is kept before a full refetch
+	OptionsQuoteTTLSeconds    int       // how often a cached options chain's quotes are refreshed
+	InstanceLock              string    // single-writer lock: "file", "postgres" or "redis", "none" disables
+	InstanceLockFile          string    // lock file for the "file" lock
+	InstanceLockKey           string    // lock name for the "postgres" and "redis" locks
+	RedisURL                  string    // redis://[:password@]host:port[/db] for the "redis" lock, cache, event bus and order queue
+	RedisPrefix               string    // prefix of every Redis key and channel
+	QuoteCacheBackend         string    // "memory" or "redis" to share latest quotes between instances
+	EventBusBackend           string    // "memory" or "redis" to relay live events between instances
+	OrderQueueBackend         string    // "local" or "redis" to hand queued orders to the trading instance
+}
+
+// NotifyRule throttles one notification channel
+type NotifyRule struct {
+	MinLevel      string // "info", "warning" or "critical"
+	QuietHours    string // "22:00-07:00" in market time; only critical notifications get through
+	MaxPerMinute  int    // 0 disables the cap
+	DigestSeconds int    // batch notifications with the same title, 0 disables
+}
+
+// notifyRuleFromEnv reads NOTIFY_<CHANNEL>_* settings
+func notifyRuleFromEnv(channel string) NotifyRule {
+	prefix := "NOTIFY_" + channel + "_"
+	return NotifyRule{
+		MinLevel:      strings.ToLower(getEnvOrDefault(prefix+"MIN_LEVEL", "info")),
+		QuietHours:    os.Getenv(prefix + "QUIET_HOURS"),
+		MaxPerMinute:  int(getEnvFloatOrDefault(prefix+"MAX_PER_MINUTE", 0)),
+		DigestSeconds: int(getEnvFloatOrDefault(prefix+"DIGEST_SECONDS", 0)),
+	}
 }
 
 var AppConfig *Config
@@ -29,26 +184,239 @@ func Load() error {
 		return fmt.Errorf("error loading .env file: %v", err)
 	}
 
-	AppConfig = &Config{
-		AlpacaAPIKey:      os.Getenv("ALPACA_API_KEY"),
-		AlpacaSecretKey:   os.Getenv("ALPACA_SECRET_KEY"),
-		AlpacaBaseURL:     getEnvOrDefault("ALPACA_BASE_URL", "https://paper-api.alpaca.markets"),
-		AlpacaPaper:       getEnvOrDefault("ALPACA_PAPER", "true") == "true",
-		GeminiAPIKey:      os.Getenv("GEMINI_API_KEY"),
-		DatabasePath:      getEnvOrDefault("DATABASE_PATH", "./data/prophet_trader.db"),
-		ServerPort:        getEnvOrDefault("SERVER_PORT", "4534"),
-		EnableLogging:     getEnvOrDefault("ENABLE_LOGGING", "true") == "true",
-		LogLevel:          getEnvOrDefault("LOG_LEVEL", "info"),
-		DataRetentionDays: 90,
-		AlpacaDataFeed:    getEnvOrDefault("ALPACA_DATA_FEED", "iex"),
+	// Credentials kept in a secret manager override the environment
+	if err := loadSecrets(); err != nil {
+		return err
 	}
 
+	cfg, err := fromEnv()
+	if err != nil {
+		return err
+	}
+	AppConfig = cfg
 	return nil
 }
 
+// fromEnv builds a Config from the environment
+func fromEnv() (*Config, error) {
+	cfg := &Config{
+		AlpacaAPIKey:              os.Getenv("ALPACA_API_KEY"),
+		AlpacaSecretKey:           os.Getenv("ALPACA_SECRET_KEY"),
+		AlpacaBaseURL:             getEnvOrDefault("ALPACA_BASE_URL", "https://paper-api.alpaca.markets"),
+		AlpacaPaper:               getEnvOrDefault("ALPACA_PAPER", "true") == "true",
+		GeminiAPIKey:              os.Getenv("GEMINI_API_KEY"),
+		DatabasePath:              getEnvOrDefault("DATABASE_PATH", "./data/prophet_trader.db"),
+		DatabaseURL:               os.Getenv("DATABASE_URL"),
+		ServerPort:                getEnvOrDefault("SERVER_PORT", "4534"),
+		EnableLogging:             getEnvOrDefault("ENABLE_LOGGING", "true") == "true",
+		LogLevel:                  getEnvOrDefault("LOG_LEVEL", "info"),
+		DataRetentionDays:         90,
+		AlpacaDataFeed:            getEnvOrDefault("ALPACA_DATA_FEED", "iex"),
+		DryRun:                    getEnvOrDefault("DRY_RUN", "false") == "true",
+		ObserverMode:              getEnvOrDefault("OBSERVER_MODE", "false") == "true",
+		ShadowMode:                getEnvOrDefault("SHADOW_MODE", "false") == "true",
+		ShadowSources:             strings.FieldsFunc(getEnvOrDefault("SHADOW_SOURCES", "strategy,ai"), func(r rune) bool { return r == ',' || r == ' ' }),
+		MaxOrderValue:             getEnvFloatOrDefault("MAX_ORDER_VALUE", 0),
+		NotifyWebhookURL:          os.Getenv("NOTIFY_WEBHOOK_URL"),
+		NotifyRules:               map[string]NotifyRule{"log": notifyRuleFromEnv("LOG"), "webhook": notifyRuleFromEnv("WEBHOOK")},
+		ReconcileInterval:         int(getEnvFloatOrDefault("RECONCILE_INTERVAL_MINUTES", 15)),
+		ReconcileAutoHeal:         getEnvOrDefault("RECONCILE_AUTO_HEAL", "true") == "true",
+		MaxSymbolConcentrationPct: getEnvFloatOrDefault("MAX_SYMBOL_CONCENTRATION_PCT", 25),
+		MaxSectorConcentrationPct: getEnvFloatOrDefault("MAX_SECTOR_CONCENTRATION_PCT", 50),
+		BlockOnConcentration:      getEnvOrDefault("BLOCK_ON_CONCENTRATION", "false") == "true",
+		BenchmarkSymbols:          strings.Split(getEnvOrDefault("BENCHMARK_SYMBOLS", "SPY,QQQ"), ","),
+		HeatmapWatchlist:          strings.Split(getEnvOrDefault("HEATMAP_WATCHLIST", "SPY,QQQ,IWM,AAPL,MSFT,NVDA,AMZN,GOOGL,META,TSLA,JPM,XOM"), ","),
+		PremarketWatchlist:        strings.FieldsFunc(strings.ToUpper(os.Getenv("PREMARKET_WATCHLIST")), func(r rune) bool { return r == ',' || r == ' ' }),
+		PremarketScanMinutes:      int(getEnvFloatOrDefault("PREMARKET_SCAN_MINUTES_BEFORE_OPEN", 30)),
+		PremarketMinGapPct:        getEnvFloatOrDefault("PREMARKET_MIN_GAP_PCT", 2),
+		PremarketTopN:             int(getEnvFloatOrDefault("PREMARKET_TOP_N", 5)),
+		StreamPollSeconds:         int(getEnvFloatOrDefault("STREAM_POLL_SECONDS", 15)),
+		EventsPollSeconds:         int(getEnvFloatOrDefault("EVENTS_POLL_SECONDS", 5)),
+		NewsPollSeconds:           int(getEnvFloatOrDefault("NEWS_POLL_SECONDS", 120)),
+		BriefIntervalMinutes:      int(getEnvFloatOrDefault("BRIEF_INTERVAL_MINUTES", 15)),
+		EventHistorySize:          int(getEnvFloatOrDefault("EVENT_HISTORY_SIZE", 1000)),
+		SnapshotIntervalSeconds:   int(getEnvFloatOrDefault("SNAPSHOT_INTERVAL_SECONDS", 60)),
+		SnapshotMinuteDays:        int(getEnvFloatOrDefault("SNAPSHOT_MINUTE_RETENTION_DAYS", 7)),
+		SnapshotHourlyDays:        int(getEnvFloatOrDefault("SNAPSHOT_HOURLY_RETENTION_DAYS", 90)),
+		ActivityLogDir:            getEnvOrDefault("ACTIVITY_LOG_DIR", "./activity_logs"),
+		BackupDir:                 getEnvOrDefault("BACKUP_DIR", "./backups"),
+		BackupIntervalHours:       int(getEnvFloatOrDefault("BACKUP_INTERVAL_HOURS", 24)),
+		BackupKeep:                int(getEnvFloatOrDefault("BACKUP_KEEP", 14)),
+		BackupS3Endpoint:          os.Getenv("BACKUP_S3_ENDPOINT"),
+		BackupS3Bucket:            os.Getenv("BACKUP_S3_BUCKET"),
+		BackupS3Prefix:            os.Getenv("BACKUP_S3_PREFIX"),
+		BackupS3Region:            getEnvOrDefault("BACKUP_S3_REGION", "us-east-1"),
+		BackupS3AccessKey:         os.Getenv("BACKUP_S3_ACCESS_KEY"),
+		BackupS3SecretKey:         os.Getenv("BACKUP_S3_SECRET_KEY"),
+		DashboardDir:              os.Getenv("DASHBOARD_DIR"),
+		PromptTemplateDir:         os.Getenv("PROMPT_TEMPLATE_DIR"),
+		LLMToolMaxCalls:           int(getEnvFloatOrDefault("LLM_TOOL_MAX_CALLS", 6)),
+		EmbeddingProvider:         getEnvOrDefault("EMBEDDING_PROVIDER", "gemini"),
+		CommissionPerShare:        getEnvFloatOrDefault("COMMISSION_PER_SHARE", 0),
+		MinCommission:             getEnvFloatOrDefault("MIN_COMMISSION", 0),
+		OptionsFeePerContract:     getEnvFloatOrDefault("OPTIONS_FEE_PER_CONTRACT", 0),
+		RSFilterEnabled:           getEnvOrDefault("RS_FILTER_ENABLED", "false") == "true",
+		RSFilterLookbackDays:      int(getEnvFloatOrDefault("RS_FILTER_LOOKBACK_DAYS", 63)),
+		RSFilterMinVsSPYPct:       getEnvFloatOrDefault("RS_FILTER_MIN_VS_SPY_PCT", 0),
+		RSFilterMinVsSectorPct:    getEnvFloatOrDefault("RS_FILTER_MIN_VS_SECTOR_PCT", 0),
+		RSFilterTopSectors:        int(getEnvFloatOrDefault("RS_FILTER_TOP_SECTORS", 0)),
+		RegimeRuleEnabled:         getEnvOrDefault("REGIME_RULE_ENABLED", "false") == "true",
+		RegimeNeutralSizePct:      getEnvFloatOrDefault("REGIME_NEUTRAL_SIZE_PCT", 75),
+		RegimeRiskOffSizePct:      getEnvFloatOrDefault("REGIME_RISK_OFF_SIZE_PCT", 50),
+		RegimeBlockRiskOff:        getEnvOrDefault("REGIME_BLOCK_RISK_OFF", "false") == "true",
+		Broker:                    getEnvOrDefault("BROKER", "alpaca"),
+		IBKRBaseURL:               getEnvOrDefault("IBKR_BASE_URL", "https://localhost:5000/v1/api"),
+		IBKRAccountID:             os.Getenv("IBKR_ACCOUNT_ID"),
+		IBKRInsecureTLS:           getEnvOrDefault("IBKR_INSECURE_TLS", "true") == "true",
+		DataProvider:              getEnvOrDefault("DATA_PROVIDER", "alpaca"),
+		TradierAccessToken:        os.Getenv("TRADIER_ACCESS_TOKEN"),
+		TradierAccountID:          os.Getenv("TRADIER_ACCOUNT_ID"),
+		TradierSandbox:            getEnvOrDefault("TRADIER_SANDBOX", "true") == "true",
+		DataFallbackProviders:     strings.FieldsFunc(os.Getenv("DATA_FALLBACK_PROVIDERS"), func(r rune) bool { return r == ',' || r == ' ' }),
+		AlpacaDataRateLimit:       int(getEnvFloatOrDefault("ALPACA_DATA_RATE_LIMIT", 180)),
+		BarAggregatorSymbols:      strings.FieldsFunc(os.Getenv("BAR_AGGREGATOR_SYMBOLS"), func(r rune) bool { return r == ',' || r == ' ' }),
+		BarAggregatorSpecs:        strings.FieldsFunc(getEnvOrDefault("BAR_AGGREGATOR_BARS", "1Min,500Tick"), func(r rune) bool { return r == ',' || r == ' ' }),
+		PolygonAPIKey:             os.Getenv("POLYGON_API_KEY"),
+		PolygonDelayed:            getEnvOrDefault("POLYGON_DELAYED", "false") == "true",
+		FundamentalsProvider:      getEnvOrDefault("FUNDAMENTALS_PROVIDER", "yahoo"),
+		FilingsPollMinutes:        int(getEnvFloatOrDefault("FILINGS_POLL_MINUTES", 0)),
+		FilingsWatchlist:          strings.FieldsFunc(os.Getenv("FILINGS_WATCHLIST"), func(r rune) bool { return r == ',' || r == ' ' }),
+		FilingsAISummaries:        getEnvOrDefault("FILINGS_AI_SUMMARIES", "false") == "true",
+		SECUserAgent:              getEnvOrDefault("SEC_USER_AGENT", "prophet-trader admin@example.com"),
+		SocialSubreddits:          strings.FieldsFunc(getEnvOrDefault("SOCIAL_SUBREDDITS", "wallstreetbets,stocks,investing,options"), func(r rune) bool { return r == ',' || r == ' ' }),
+		SocialXListIDs:            strings.FieldsFunc(os.Getenv("SOCIAL_X_LIST_IDS"), func(r rune) bool { return r == ',' || r == ' ' }),
+		XBearerToken:              os.Getenv("X_BEARER_TOKEN"),
+		SocialPollMinutes:         int(getEnvFloatOrDefault("SOCIAL_POLL_MINUTES", 0)),
+		SocialInAnalysis:          getEnvOrDefault("SOCIAL_IN_ANALYSIS", "false") == "true",
+		EconomicCalendarSource:    getEnvOrDefault("ECONOMIC_CALENDAR_SOURCE", "forexfactory"),
+		EconomicCalendarFile:      os.Getenv("ECONOMIC_CALENDAR_FILE"),
+		MacroRuleEnabled:          getEnvOrDefault("MACRO_RULE_ENABLED", "false") == "true",
+		MacroWindowBeforeMinutes:  int(getEnvFloatOrDefault("MACRO_WINDOW_BEFORE_MINUTES", 30)),
+		MacroWindowAfterMinutes:   int(getEnvFloatOrDefault("MACRO_WINDOW_AFTER_MINUTES", 30)),
+		MacroBlockEntries:         getEnvOrDefault("MACRO_BLOCK_ENTRIES", "true") == "true",
+		MacroStopWidenPct:         getEnvFloatOrDefault("MACRO_STOP_WIDEN_PCT", 50),
+		ShortDTERuleEnabled:       getEnvOrDefault("SHORT_DTE_RULE_ENABLED", "false") == "true",
+		ShortDTEMaxDays:           int(getEnvFloatOrDefault("SHORT_DTE_MAX_DAYS", 1)),
+		ShortDTEMaxOrderValue:     getEnvFloatOrDefault("SHORT_DTE_MAX_ORDER_VALUE", 500),
+		ShortDTEMaxRiskPct:        getEnvFloatOrDefault("SHORT_DTE_MAX_RISK_PCT", 1),
+		ZeroDTEEntryCutoff:        getEnvOrDefault("ZERO_DTE_ENTRY_CUTOFF", "14:30"),
+		ZeroDTEBlockAfterCutoff:   getEnvOrDefault("ZERO_DTE_BLOCK_AFTER_CUTOFF", "true") == "true",
+		ZeroDTEExitMinutes:        int(getEnvFloatOrDefault("ZERO_DTE_EXIT_MINUTES", 15)),
+		TradingHoursRuleEnabled:   getEnvOrDefault("TRADING_HOURS_RULE_ENABLED", "true") == "true",
+		TradingHoursBlock:         getEnvOrDefault("TRADING_HOURS_BLOCK", "false") == "true",
+		MarginCheckSeconds:        int(getEnvFloatOrDefault("MARGIN_CHECK_SECONDS", 60)),
+		MarginAlertThresholds:     getEnvFloatsOrDefault("MARGIN_ALERT_THRESHOLDS", []float64{50, 75, 90}),
+		MarginMaxUtilizationPct:   getEnvFloatOrDefault("MARGIN_MAX_UTILIZATION_PCT", 0),
+		MarginBlock:               getEnvOrDefault("MARGIN_BLOCK", "false") == "true",
+		SymbolBlocklist:           strings.FieldsFunc(strings.ToUpper(os.Getenv("SYMBOL_BLOCKLIST")), func(r rune) bool { return r == ',' || r == ' ' }),
+		MinSharePrice:             getEnvFloatOrDefault("MIN_SHARE_PRICE", 0),
+		APIAdminToken:             os.Getenv("API_ADMIN_TOKEN"),
+		RateLimitPerMinute:        int(getEnvFloatOrDefault("RATE_LIMIT_PER_MINUTE", 300)),
+		OrderRateLimit:            int(getEnvFloatOrDefault("RATE_LIMIT_ORDERS_PER_MINUTE", 30)),
+		IntelligenceRateLimit:     int(getEnvFloatOrDefault("RATE_LIMIT_INTELLIGENCE_PER_MINUTE", 10)),
+		MaxRequestBodyBytes:       int64(getEnvFloatOrDefault("MAX_REQUEST_BODY_BYTES", 1<<20)),
+		CompressMinBytes:          int(getEnvFloatOrDefault("RESPONSE_COMPRESSION_MIN_BYTES", 1024)),
+		ResponseCacheMaxAge:       int(getEnvFloatOrDefault("RESPONSE_CACHE_MAX_AGE_SECONDS", 15)),
+		CORSAllowedOrigins:        strings.FieldsFunc(getEnvOrDefault("CORS_ALLOWED_ORIGINS", "http://localhost:*,http://127.0.0.1:*"), func(r rune) bool { return r == ',' || r == ' ' }),
+		CORSAllowedMethods:        strings.FieldsFunc(getEnvOrDefault("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE,OPTIONS"), func(r rune) bool { return r == ',' || r == ' ' }),
+		CORSAllowedHeaders:        strings.FieldsFunc(getEnvOrDefault("CORS_ALLOWED_HEADERS", "Content-Type,Authorization,Last-Event-ID,If-None-Match,If-Modified-Since,X-Book"), func(r rune) bool { return r == ',' || r == ' ' }),
+		CORSAllowCredentials:      getEnvOrDefault("CORS_ALLOW_CREDENTIALS", "false") == "true",
+		EncryptionKey:             os.Getenv("ENCRYPTION_KEY"),
+		SecretsBackend:            os.Getenv("CONFIG_SECRETS_BACKEND"),
+		SecretsRefreshMinutes:     int(getEnvFloatOrDefault("CONFIG_SECRETS_REFRESH_MINUTES", 60)),
+		LiveTradingConfirmed:      getEnvOrDefault("LIVE_TRADING_CONFIRMED", "false") == "true",
+		LiveStartDate:             os.Getenv("LIVE_TRADING_START_DATE"),
+		LiveRestrictedDays:        int(getEnvFloatOrDefault("LIVE_RESTRICTED_DAYS", 14)),
+		LiveAllowedSymbols:        strings.FieldsFunc(strings.ToUpper(os.Getenv("LIVE_ALLOWED_SYMBOLS")), func(r rune) bool { return r == ',' || r == ' ' }),
+		LiveMaxOrderValue:         getEnvFloatOrDefault("LIVE_MAX_ORDER_VALUE", 1000),
+		OrderQueueEnabled:         getEnvOrDefault("ORDER_QUEUE_ENABLED", "false") == "true",
+		RebalanceTolerancePct:     getEnvFloatOrDefault("REBALANCE_TOLERANCE_PCT", 5),
+		RebalanceMinTradeValue:    getEnvFloatOrDefault("REBALANCE_MIN_TRADE_VALUE", 10),
+		OptimizerWorkers:          int(getEnvFloatOrDefault("OPTIMIZER_WORKERS", 0)),
+		QuoteCacheTTLMillis:       int(getEnvFloatOrDefault("QUOTE_CACHE_TTL_MS", 1500)),
+		PositionCacheTTLSeconds:   int(getEnvFloatOrDefault("POSITION_CACHE_TTL_SECONDS", 10)),
+		OptionsChainTTLSeconds:    int(getEnvFloatOrDefault("OPTIONS_CHAIN_TTL_SECONDS", 300)),
+		OptionsQuoteTTLSeconds:    int(getEnvFloatOrDefault("OPTIONS_QUOTE_TTL_SECONDS", 2)),
+		InstanceLock:              strings.ToLower(getEnvOrDefault("INSTANCE_LOCK", "none")),
+		InstanceLockFile:          getEnvOrDefault("INSTANCE_LOCK_FILE", "./data/prophet_trader.lock"),
+		InstanceLockKey:           getEnvOrDefault("INSTANCE_LOCK_KEY", "prophet-trader"),
+		RedisURL:                  os.Getenv("REDIS_URL"),
+		RedisPrefix:               getEnvOrDefault("REDIS_PREFIX", "prophet:"),
+		QuoteCacheBackend:         strings.ToLower(getEnvOrDefault("QUOTE_CACHE_BACKEND", "memory")),
+		EventBusBackend:           strings.ToLower(getEnvOrDefault("EVENT_BUS_BACKEND", "memory")),
+		OrderQueueBackend:         strings.ToLower(getEnvOrDefault("ORDER_QUEUE_BACKEND", "local")),
+	}
+
+	// A key file lets a KMS-backed secret mount supply the key without it
+	// ever being written to .env
+	if path := os.Getenv("ENCRYPTION_KEY_FILE"); path != "" {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ENCRYPTION_KEY_FILE: %w", err)
+		}
+		cfg.EncryptionKey = strings.TrimSpace(string(key))
+	}
+
+	return cfg, nil
+}
+
+// Trading environments reported by Environment
+const (
+	EnvironmentPaper = "paper"
+	EnvironmentLive  = "live"
+)
+
+// Environment reports whether the configured broker trades a paper or a
+// live account
+func (c *Config) Environment() string {
+	switch strings.ToLower(c.Broker) {
+	case "tradier":
+		if c.TradierSandbox {
+			return EnvironmentPaper
+		}
+	case "ibkr":
+		// IBKR paper account IDs start with "DU"
+		if strings.HasPrefix(strings.ToUpper(c.IBKRAccountID), "DU") {
+			return EnvironmentPaper
+		}
+	default:
+		if c.AlpacaPaper {
+			return EnvironmentPaper
+		}
+	}
+	return EnvironmentLive
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvFloatOrDefault(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+// getEnvFloatsOrDefault reads a comma or space separated list of numbers,
+// returning defaultValue when unset or when any entry isn't a number
+func getEnvFloatsOrDefault(key string, defaultValue []float64) []float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var floats []float64
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		f, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return defaultValue
+		}
+		floats = append(floats, f)
+	}
+	return floats
+}
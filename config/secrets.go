@@ -0,0 +1,71 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Mask hides a secret, keeping a few characters at the ends of longer ones,
+// which is enough to tell keys apart in logs without revealing them
+func Mask(secret string) string {
+	switch {
+	case len(secret) < 12:
+		return strings.Repeat("*", len(secret))
+	case len(secret) < 24:
+		return "****" + secret[len(secret)-4:]
+	default:
+		return secret[:4] + "..." + secret[len(secret)-4:]
+	}
+}
+
+// Secrets returns the credentials held in the configuration
+func (c *Config) Secrets() []string {
+	secrets := []string{
+		c.AlpacaAPIKey,
+		c.AlpacaSecretKey,
+		c.GeminiAPIKey,
+		c.TradierAccessToken,
+		c.PolygonAPIKey,
+		c.XBearerToken,
+		c.APIAdminToken,
+		c.BackupS3AccessKey,
+		c.BackupS3SecretKey,
+		c.EncryptionKey,
+		c.NotifyWebhookURL,
+	}
+	for _, connectionURL := range []string{c.DatabaseURL, c.RedisURL} {
+		if u, err := url.Parse(connectionURL); err == nil && u.User != nil {
+			if password, ok := u.User.Password(); ok {
+				secrets = append(secrets, password)
+			}
+		}
+	}
+
+	nonEmpty := secrets[:0]
+	for _, secret := range secrets {
+		if secret != "" {
+			nonEmpty = append(nonEmpty, secret)
+		}
+	}
+	return nonEmpty
+}
+
+// Redact replaces every configured secret in text with its masked form.
+// Use it on anything built from upstream errors before it is logged or
+// returned to an API client.
+func Redact(text string) string {
+	if AppConfig == nil {
+		return text
+	}
+	for _, secret := range append(AppConfig.Secrets(), fetchedSecrets()...) {
+		// Very short values would mask unrelated text
+		if len(secret) < 8 {
+			continue
+		}
+		text = strings.ReplaceAll(text, secret, Mask(secret))
+		if escaped := url.QueryEscape(secret); escaped != secret {
+			text = strings.ReplaceAll(text, escaped, Mask(secret))
+		}
+	}
+	return text
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// awsSecretsManager reads a secret from AWS Secrets Manager. Credentials come
+// from AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY, the ECS task role or the EC2
+// instance profile, in that order.
+type awsSecretsManager struct {
+	secretID string
+	region   string
+	endpoint string
+}
+
+func newAWSSecretsManager(secretID string) (*awsSecretsManager, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+	if parts := strings.Split(secretID, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return nil, fmt.Errorf("AWS_REGION is required for the aws secrets backend")
+	}
+
+	return &awsSecretsManager{
+		secretID: secretID,
+		region:   region,
+		endpoint: fmt.Sprintf("https://secretsmanager.%s.amazonaws.com/", region),
+	}, nil
+}
+
+func (a *awsSecretsManager) Name() string {
+	return "AWS Secrets Manager"
+}
+
+func (a *awsSecretsManager) Fetch(ctx context.Context) (map[string]string, error) {
+	creds, err := awsCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": a.secretID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signAWSRequest(req, body, creds, a.region, "secretsmanager", time.Now().UTC())
+
+	resp, err := secretsHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Secrets Manager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Secrets Manager error (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return parseSecretPayload([]byte(result.SecretString))
+}
+
+type awsCreds struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"Token"`
+}
+
+// awsCredentials resolves credentials from the environment, the ECS
+// container endpoint or EC2 instance metadata (IMDSv2)
+func awsCredentials(ctx context.Context) (*awsCreds, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return &awsCreds{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		creds := &awsCreds{}
+		if err := getJSON(ctx, "http://169.254.170.2"+uri, nil, creds); err != nil {
+			return nil, fmt.Errorf("failed to get ECS task credentials: %w", err)
+		}
+		return creds, nil
+	}
+
+	const imds = "http://169.254.169.254/latest"
+	tokenReq, err := http.NewRequestWithContext(ctx, http.MethodPut, imds+"/api/token", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	tokenReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	resp, err := secretsHTTPClient.Do(tokenReq)
+	if err != nil {
+		return nil, fmt.Errorf("no AWS credentials: set AWS_ACCESS_KEY_ID or run with an instance or task role (%w)", err)
+	}
+	token, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get instance metadata token (HTTP %d)", resp.StatusCode)
+	}
+	header := http.Header{"X-Aws-Ec2-Metadata-Token": {string(token)}}
+
+	roleReq, err := http.NewRequestWithContext(ctx, http.MethodGet, imds+"/meta-data/iam/security-credentials/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	roleReq.Header = header
+	resp, err = secretsHTTPClient.Do(roleReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance role: %w", err)
+	}
+	role, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("no IAM role attached to this instance (HTTP %d)", resp.StatusCode)
+	}
+
+	creds := &awsCreds{}
+	roleName := strings.TrimSpace(strings.SplitN(string(role), "\n", 2)[0])
+	if err := getJSON(ctx, imds+"/meta-data/iam/security-credentials/"+roleName, header, creds); err != nil {
+		return nil, fmt.Errorf("failed to get instance role credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// signAWSRequest adds AWS Signature Version 4 headers for a request to the
+// root path of a JSON-protocol service
+func signAWSRequest(req *http.Request, body []byte, creds *awsCreds, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	names := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	if creds.SessionToken != "" {
+		names = []string{"content-type", "host", "x-amz-date", "x-amz-security-token", "x-amz-target"}
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), day)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// getJSON fetches url and decodes a JSON response into out
+func getJSON(ctx context.Context, url string, header http.Header, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := secretsHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
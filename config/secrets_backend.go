@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretsBackend fetches credentials from a secret manager. The secret is a
+// JSON object keyed by environment variable name, e.g.
+// {"ALPACA_API_KEY": "...", "ALPACA_SECRET_KEY": "...", "GEMINI_API_KEY": "..."}.
+type SecretsBackend interface {
+	Name() string
+	Fetch(ctx context.Context) (map[string]string, error)
+}
+
+// NewSecretsBackend creates the backend selected by CONFIG_SECRETS_BACKEND
+// for the secret named by CONFIG_SECRETS_ID
+func NewSecretsBackend(name, secretID string) (SecretsBackend, error) {
+	if secretID == "" {
+		return nil, fmt.Errorf("CONFIG_SECRETS_ID is required with CONFIG_SECRETS_BACKEND=%s", name)
+	}
+	switch strings.ToLower(name) {
+	case "aws":
+		return newAWSSecretsManager(secretID)
+	case "gcp":
+		return newGCPSecretManager(secretID)
+	case "vault":
+		return newVaultKV(secretID)
+	default:
+		return nil, fmt.Errorf("unknown secrets backend %q (valid: aws, gcp, vault)", name)
+	}
+}
+
+var secretsState struct {
+	sync.Mutex
+	backend SecretsBackend
+	values  map[string]string
+	history []string // every value fetched, so Redact still masks rotated-out keys
+}
+
+// loadSecrets fetches the configured secret and exports its values as
+// environment variables, overriding .env, before the Config is built
+func loadSecrets() error {
+	name := os.Getenv("CONFIG_SECRETS_BACKEND")
+	if name == "" {
+		return nil
+	}
+
+	backend, err := NewSecretsBackend(name, os.Getenv("CONFIG_SECRETS_ID"))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	values, err := backend.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load secrets from %s: %w", backend.Name(), err)
+	}
+
+	secretsState.Lock()
+	defer secretsState.Unlock()
+	secretsState.backend = backend
+	secretsState.values = values
+	for key, value := range values {
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to apply secret %s: %w", key, err)
+		}
+		secretsState.history = append(secretsState.history, value)
+	}
+	return nil
+}
+
+// RefreshSecrets fetches the secret again. When any value changed it exports
+// the new values and returns a Config rebuilt from them along with the names
+// that changed; otherwise it returns a nil Config. AppConfig is left as is,
+// so callers hand the new credentials to the services that hold them.
+func RefreshSecrets(ctx context.Context) (*Config, []string, error) {
+	secretsState.Lock()
+	defer secretsState.Unlock()
+
+	if secretsState.backend == nil {
+		return nil, nil, nil
+	}
+
+	values, err := secretsState.backend.Fetch(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to refresh secrets from %s: %w", secretsState.backend.Name(), err)
+	}
+
+	var changed []string
+	for key, value := range values {
+		if previous, ok := secretsState.values[key]; ok && previous == value {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return nil, nil, fmt.Errorf("failed to apply secret %s: %w", key, err)
+		}
+		secretsState.history = append(secretsState.history, value)
+		changed = append(changed, key)
+	}
+	secretsState.values = values
+	if len(changed) == 0 {
+		return nil, nil, nil
+	}
+	sort.Strings(changed)
+
+	cfg, err := fromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, changed, nil
+}
+
+// fetchedSecrets returns every value the secrets backend has supplied
+func fetchedSecrets() []string {
+	secretsState.Lock()
+	defer secretsState.Unlock()
+	return append([]string(nil), secretsState.history...)
+}
+
+// parseSecretPayload decodes a secret holding a JSON object of strings
+func parseSecretPayload(payload []byte) (map[string]string, error) {
+	var values map[string]string
+	if err := json.Unmarshal(payload, &values); err != nil {
+		return nil, fmt.Errorf("secret must be a JSON object of string values: %w", err)
+	}
+	return values, nil
+}
+
+var secretsHTTPClient = &http.Client{Timeout: 10 * time.Second}
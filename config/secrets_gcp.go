@@ -0,0 +1,167 @@
+package config
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// gcpSecretManager reads a secret version from Google Secret Manager. It
+// authenticates with the service account key in GOOGLE_APPLICATION_CREDENTIALS,
+// or with the attached service account via the metadata server on GCE, GKE
+// and Cloud Run.
+type gcpSecretManager struct {
+	version string // projects/<project>/secrets/<name>/versions/<version>
+}
+
+func newGCPSecretManager(secretID string) (*gcpSecretManager, error) {
+	if !strings.HasPrefix(secretID, "projects/") {
+		project := os.Getenv("GOOGLE_CLOUD_PROJECT")
+		if project == "" {
+			return nil, fmt.Errorf("CONFIG_SECRETS_ID must be projects/<project>/secrets/<name>, or set GOOGLE_CLOUD_PROJECT")
+		}
+		secretID = "projects/" + project + "/secrets/" + secretID
+	}
+	if !strings.Contains(secretID, "/versions/") {
+		secretID += "/versions/latest"
+	}
+	return &gcpSecretManager{version: secretID}, nil
+}
+
+func (g *gcpSecretManager) Name() string {
+	return "Google Secret Manager"
+}
+
+func (g *gcpSecretManager) Fetch(ctx context.Context) (map[string]string, error) {
+	token, err := gcpAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	header := http.Header{"Authorization": {"Bearer " + token}}
+	if err := getJSON(ctx, "https://secretmanager.googleapis.com/v1/"+g.version+":access", header, &result); err != nil {
+		return nil, fmt.Errorf("failed to access %s: %w", g.version, err)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(result.Payload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret payload: %w", err)
+	}
+	return parseSecretPayload(payload)
+}
+
+// gcpAccessToken gets an OAuth token for the cloud-platform scope
+func gcpAccessToken(ctx context.Context) (string, error) {
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		header := http.Header{"Metadata-Flavor": {"Google"}}
+		if err := getJSON(ctx, "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", header, &result); err != nil {
+			return "", fmt.Errorf("no GCP credentials: set GOOGLE_APPLICATION_CREDENTIALS or run with an attached service account (%w)", err)
+		}
+		return result.AccessToken, nil
+	}
+
+	assertion, tokenURI, err := gcpServiceAccountAssertion(path, time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := secretsHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("GCP token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GCP token error (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result.AccessToken, nil
+}
+
+// gcpServiceAccountAssertion builds the signed JWT a service account key
+// exchanges for an access token
+func gcpServiceAccountAssertion(path string, now time.Time) (string, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+	}
+	var key struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return "", "", fmt.Errorf("failed to parse service account key: %w", err)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return "", "", fmt.Errorf("service account key has no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse service account private key: %w", err)
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", "", fmt.Errorf("service account private key is not RSA")
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   key.ClientEmail,
+		"scope": "https://www.googleapis.com/auth/cloud-platform",
+		"aud":   key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), key.TokenURI, nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// vaultKV reads a secret from a HashiCorp Vault KV engine. The token is
+// VAULT_TOKEN, or is re-read from VAULT_TOKEN_FILE on every fetch so a Vault
+// Agent sink can renew it.
+type vaultKV struct {
+	addr      string
+	path      string // API path below /v1, e.g. secret/data/prophet-trader
+	namespace string
+	tokenFile string
+	token     string
+}
+
+func newVaultKV(secretID string) (*vaultKV, error) {
+	addr := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is required for the vault secrets backend")
+	}
+	v := &vaultKV{
+		addr:      addr,
+		path:      strings.Trim(secretID, "/"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		tokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+		token:     os.Getenv("VAULT_TOKEN"),
+	}
+	if v.token == "" && v.tokenFile == "" {
+		return nil, fmt.Errorf("VAULT_TOKEN or VAULT_TOKEN_FILE is required for the vault secrets backend")
+	}
+	return v, nil
+}
+
+func (v *vaultKV) Name() string {
+	return "Vault"
+}
+
+func (v *vaultKV) Fetch(ctx context.Context) (map[string]string, error) {
+	token := v.token
+	if v.tokenFile != "" {
+		data, err := os.ReadFile(v.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read VAULT_TOKEN_FILE: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	header := http.Header{"X-Vault-Token": {token}}
+	if v.namespace != "" {
+		header.Set("X-Vault-Namespace", v.namespace)
+	}
+	var result struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := getJSON(ctx, v.addr+"/v1/"+v.path, header, &result); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", v.path, err)
+	}
+
+	// KV version 2 nests the secret under data.data alongside its metadata
+	var kv2 struct {
+		Data     json.RawMessage `json:"data"`
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := json.Unmarshal(result.Data, &kv2); err == nil && kv2.Data != nil && kv2.Metadata != nil {
+		return parseSecretPayload(kv2.Data)
+	}
+	return parseSecretPayload(result.Data)
+}
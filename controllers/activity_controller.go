@@ -1,8 +1,13 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 	"prophet-trader/services"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,6 +15,7 @@ import (
 // ActivityController handles activity logging endpoints
 type ActivityController struct {
 	activityLogger *services.ActivityLogger
+	eventStore     *services.ActivityEventStore
 }
 
 // NewActivityController creates a new activity controller
@@ -19,15 +25,76 @@ func NewActivityController(activityLogger *services.ActivityLogger) *ActivityCon
 	}
 }
 
+// SetEventStore enables the recorded activity events endpoint
+func (ac *ActivityController) SetEventStore(eventStore *services.ActivityEventStore) {
+	ac.eventStore = eventStore
+}
+
+// HandleGetActivityEvents returns the latest recorded trading activity
+// events, newest first
+// GET /api/v1/activity/events?type=risk_breached&symbol=AAPL&limit=50
+func (ac *ActivityController) HandleGetActivityEvents(c *gin.Context) {
+	if ac.eventStore == nil {
+		respondError(c, services.ErrCodeUnavailable, "activity events not enabled", "")
+		return
+	}
+
+	eventType := c.Query("type")
+	if eventType != "" && !slices.Contains(services.ActivityEventTypes, eventType) {
+		respondBadRequest(c, "Invalid event type", fmt.Errorf("type must be one of %s", strings.Join(services.ActivityEventTypes, ", ")))
+		return
+	}
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	events, err := ac.eventStore.Recent(eventType, strings.ToUpper(c.Query("symbol")), limit)
+	if err != nil {
+		respondServiceError(c, "Failed to get activity events", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":  len(events),
+		"events": events,
+	})
+}
+
+// HandleListSessions returns the latest trading session summaries, newest
+// first, including the active session
+// GET /api/v1/activity/sessions?limit=30
+func (ac *ActivityController) HandleListSessions(c *gin.Context) {
+	limit := 30
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	sessions, err := ac.activityLogger.ListSessions(limit)
+	if err != nil {
+		respondServiceError(c, "Failed to list sessions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":    len(sessions),
+		"sessions": sessions,
+	})
+}
+
 // HandleGetCurrentActivity returns the current day's activity log
 func (ac *ActivityController) HandleGetCurrentActivity(c *gin.Context) {
 	log, err := ac.activityLogger.GetCurrentLog()
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondNotFound(c, "No activity log for today", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, log)
+	c.JSON(http.StatusOK, logForBook(c, log))
 }
 
 // HandleGetActivityByDate returns activity log for a specific date
@@ -36,40 +103,62 @@ func (ac *ActivityController) HandleGetActivityByDate(c *gin.Context) {
 
 	log, err := ac.activityLogger.GetLogForDate(date)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondNotFound(c, "No activity log for date", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, log)
+	c.JSON(http.StatusOK, logForBook(c, log))
 }
 
-// HandleListActivityLogs returns list of available activity log dates
+// HandleListActivityLogs returns list of available activity log dates.
+// Supports limit, cursor, from, to and sort=date|-date.
 func (ac *ActivityController) HandleListActivityLogs(c *gin.Context) {
+	query, err := parseListQuery(c, activityListSpec, defaultPageLimit)
+	if err != nil {
+		respondBadRequest(c, "Invalid list parameters", err)
+		return
+	}
+
 	dates, err := ac.activityLogger.ListAvailableLogs()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to list activity logs", err)
 		return
 	}
 
+	page, info := paginate(dates, query, activityListSpec)
 	c.JSON(http.StatusOK, gin.H{
-		"dates": dates,
-		"count": len(dates),
+		"dates":      page,
+		"count":      len(page),
+		"pagination": info,
 	})
 }
 
+// activityListSpec defines the filters and sorts of GET /activity. Log dates
+// are YYYY-MM-DD, so they sort as strings.
+var activityListSpec = listSpec[string]{
+	id: func(date string) string { return date },
+	time: func(date string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+		return t
+	},
+	sorts: map[string]func(string) string{
+		"date": func(date string) string { return date },
+	},
+	defaultSort: "-date",
+}
+
 // HandleStartSession starts a new trading session
 func (ac *ActivityController) HandleStartSession(c *gin.Context) {
 	var req struct {
-		StartingCapital float64 `json:"starting_capital" binding:"required"`
+		StartingCapital float64 `json:"starting_capital" binding:"required,gt=0"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	if err := ac.activityLogger.StartSession(c.Request.Context(), req.StartingCapital); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to start session", err)
 		return
 	}
 
@@ -79,17 +168,16 @@ func (ac *ActivityController) HandleStartSession(c *gin.Context) {
 // HandleEndSession ends the current trading session
 func (ac *ActivityController) HandleEndSession(c *gin.Context) {
 	var req struct {
-		EndingCapital   float64 `json:"ending_capital" binding:"required"`
-		ActivePositions int     `json:"active_positions"`
+		EndingCapital   float64 `json:"ending_capital" binding:"required,gt=0"`
+		ActivePositions int     `json:"active_positions" binding:"gte=0"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	if err := ac.activityLogger.EndSession(c.Request.Context(), req.EndingCapital, req.ActivePositions); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to end session", err)
 		return
 	}
 
@@ -99,22 +187,29 @@ func (ac *ActivityController) HandleEndSession(c *gin.Context) {
 // HandleLogActivity logs a general activity
 func (ac *ActivityController) HandleLogActivity(c *gin.Context) {
 	var req struct {
-		Type      string                 `json:"type" binding:"required"`
-		Action    string                 `json:"action" binding:"required"`
-		Symbol    string                 `json:"symbol"`
+		Type      string                 `json:"type" binding:"required,max=64"`
+		Action    string                 `json:"action" binding:"required,max=64"`
+		Symbol    string                 `json:"symbol" binding:"omitempty,symbol"`
 		Reasoning string                 `json:"reasoning"`
 		Details   map[string]interface{} `json:"details"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	if err := ac.activityLogger.LogActivity(req.Type, req.Action, req.Symbol, req.Reasoning, req.Details); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := ac.activityLogger.LogActivity(c.Request.Context(), req.Type, req.Action, req.Symbol, req.Reasoning, req.Details); err != nil {
+		respondServiceError(c, "Failed to log activity", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Activity logged"})
 }
+
+// logForBook narrows a log to the selected book, if any
+func logForBook(c *gin.Context, log *services.DailyActivityLog) *services.DailyActivityLog {
+	if book, ok := services.SelectedBook(c.Request.Context()); ok {
+		return log.ForBook(book)
+	}
+	return log
+}
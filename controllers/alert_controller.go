@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AlertController handles watchlist alert endpoints
+type AlertController struct {
+	alertEngine  *services.AlertEngine
+	newsPipeline *services.NewsPipeline
+}
+
+// NewAlertController creates a new alert controller
+func NewAlertController(alertEngine *services.AlertEngine) *AlertController {
+	return &AlertController{
+		alertEngine: alertEngine,
+	}
+}
+
+// SetNewsPipeline enables the news rule endpoints
+func (ac *AlertController) SetNewsPipeline(newsPipeline *services.NewsPipeline) {
+	ac.newsPipeline = newsPipeline
+}
+
+// HandleCreateAlert creates an alert
+// POST /api/v1/alerts
+func (ac *AlertController) HandleCreateAlert(c *gin.Context) {
+	var req services.AlertRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	alert, err := ac.alertEngine.CreateAlert(&req)
+	if err != nil {
+		respondBadRequest(c, "Failed to create alert", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, alert)
+}
+
+// HandleListAlerts lists alerts, optionally filtered by status
+// GET /api/v1/alerts?status=ACTIVE
+func (ac *AlertController) HandleListAlerts(c *gin.Context) {
+	alerts := ac.alertEngine.ListAlerts(c.Query("status"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts": alerts,
+		"count":  len(alerts),
+	})
+}
+
+// HandleGetAlert returns a single alert
+// GET /api/v1/alerts/:id
+func (ac *AlertController) HandleGetAlert(c *gin.Context) {
+	alert, err := ac.alertEngine.GetAlert(c.Param("id"))
+	if err != nil {
+		respondNotFound(c, "Alert not found", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// HandleDeleteAlert removes an alert
+// DELETE /api/v1/alerts/:id
+func (ac *AlertController) HandleDeleteAlert(c *gin.Context) {
+	if err := ac.alertEngine.DeleteAlert(c.Param("id")); err != nil {
+		respondNotFound(c, "Alert not found", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Alert deleted",
+	})
+}
+
+// HandleListEvents returns recent alert firings
+// GET /api/v1/alerts/events?limit=50
+func (ac *AlertController) HandleListEvents(c *gin.Context) {
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	events := ac.alertEngine.RecentEvents(limit)
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// HandleCreateNewsRule creates a news rule
+// POST /api/v1/alerts/news-rules
+func (ac *AlertController) HandleCreateNewsRule(c *gin.Context) {
+	var req services.NewsRuleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	rule, err := ac.newsPipeline.CreateRule(&req)
+	if err != nil {
+		respondBadRequest(c, "Failed to create news rule", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// HandleListNewsRules lists news rules
+// GET /api/v1/alerts/news-rules
+func (ac *AlertController) HandleListNewsRules(c *gin.Context) {
+	rules := ac.newsPipeline.ListRules()
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+// HandleDeleteNewsRule removes a news rule
+// DELETE /api/v1/alerts/news-rules/:id
+func (ac *AlertController) HandleDeleteNewsRule(c *gin.Context) {
+	if err := ac.newsPipeline.DeleteRule(c.Param("id")); err != nil {
+		respondNotFound(c, "News rule not found", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "News rule deleted",
+	})
+}
+
+// HandlePreviewNewsRule dry-runs a rule over the stored headlines without
+// saving it
+// POST /api/v1/alerts/news-rules/preview?lookback_hours=24
+func (ac *AlertController) HandlePreviewNewsRule(c *gin.Context) {
+	lookback := 24
+	if lookbackStr := c.Query("lookback_hours"); lookbackStr != "" {
+		h, err := strconv.Atoi(lookbackStr)
+		if err != nil || h < 1 {
+			respondBadRequest(c, "lookback_hours must be a positive integer", nil)
+			return
+		}
+		lookback = h
+	}
+
+	var req services.NewsRuleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	matches, err := ac.newsPipeline.Preview(&req, time.Duration(lookback)*time.Hour)
+	if err != nil {
+		respondBadRequest(c, "Failed to preview news rule", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"matches":        matches,
+		"count":          len(matches),
+		"lookback_hours": lookback,
+	})
+}
+
+// HandleListNewsRuleMatches returns recent news rule matches
+// GET /api/v1/alerts/news-rules/events?limit=50
+func (ac *AlertController) HandleListNewsRuleMatches(c *gin.Context) {
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	matches := ac.newsPipeline.RecentMatches(limit)
+	c.JSON(http.StatusOK, gin.H{
+		"matches": matches,
+		"count":   len(matches),
+	})
+}
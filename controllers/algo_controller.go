@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AlgoController handles TWAP and VWAP algo order endpoints
+type AlgoController struct {
+	algoService *services.AlgoOrderService
+}
+
+// NewAlgoController creates a new algo order controller
+func NewAlgoController(algoService *services.AlgoOrderService) *AlgoController {
+	return &AlgoController{
+		algoService: algoService,
+	}
+}
+
+// HandleCreateOrder creates an algo order
+// POST /api/v1/algo/orders
+func (ac *AlgoController) HandleCreateOrder(c *gin.Context) {
+	var req services.AlgoOrderRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	order, err := ac.algoService.Create(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, "Failed to create algo order", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// HandleListOrders lists algo orders with their progress, optionally
+// filtered by status
+// GET /api/v1/algo/orders?status=WORKING
+func (ac *AlgoController) HandleListOrders(c *gin.Context) {
+	orders, err := ac.algoService.List(strings.ToUpper(c.Query("status")))
+	if err != nil {
+		respondServiceError(c, "Failed to get algo orders", err)
+		return
+	}
+	orders = filterBook(c, orders, func(o *services.AlgoOrder) string { return o.Book })
+
+	c.JSON(http.StatusOK, gin.H{
+		"orders": orders,
+		"count":  len(orders),
+	})
+}
+
+// HandleGetOrder returns an algo order with every child order
+// GET /api/v1/algo/orders/:id
+func (ac *AlgoController) HandleGetOrder(c *gin.Context) {
+	order, err := ac.algoService.Get(c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to get algo order", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// HandleCancelOrder stops an algo order and cancels its open child order
+// DELETE /api/v1/algo/orders/:id
+func (ac *AlgoController) HandleCancelOrder(c *gin.Context) {
+	order, err := ac.algoService.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to cancel algo order", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnalysisController handles technical analysis endpoints
+type AnalysisController struct {
+	analysisService *services.TechnicalAnalysisService
+}
+
+// NewAnalysisController creates a new analysis controller
+func NewAnalysisController(analysisService *services.TechnicalAnalysisService) *AnalysisController {
+	return &AnalysisController{
+		analysisService: analysisService,
+	}
+}
+
+// HandleGetVolumeProfile returns the intraday volume profile (POC, value area)
+// and session VWAP with standard deviation bands
+// GET /api/v1/analysis/:symbol/volume-profile?date=2025-01-15&timeframe=5Min&bins=24&extended=false
+func (ac *AnalysisController) HandleGetVolumeProfile(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+
+	opts := services.VolumeProfileOptions{
+		Timeframe: c.DefaultQuery("timeframe", "5Min"),
+		Extended:  c.Query("extended") == "true",
+	}
+	if dateStr := c.Query("date"); dateStr != "" {
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			respondBadRequest(c, "Invalid date, use YYYY-MM-DD", err)
+			return
+		}
+		opts.Date = date
+	}
+	if binsStr := c.Query("bins"); binsStr != "" {
+		bins, err := strconv.Atoi(binsStr)
+		if err != nil || bins < 5 || bins > 200 {
+			respondBadRequest(c, "bins must be an integer between 5 and 200", nil)
+			return
+		}
+		opts.Bins = bins
+	}
+	if vaStr := c.Query("value_area"); vaStr != "" {
+		va, err := strconv.ParseFloat(vaStr, 64)
+		if err != nil || va <= 0 || va >= 1 {
+			respondBadRequest(c, "value_area must be a fraction between 0 and 1", nil)
+			return
+		}
+		opts.ValueArea = va
+	}
+
+	result, err := ac.analysisService.VolumeProfile(c.Request.Context(), symbol, opts)
+	if err != nil {
+		respondServiceError(c, "Failed to build volume profile", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// HandleGetPatterns returns candlestick patterns, chart patterns and
+// support/resistance levels for a symbol
+// GET /api/v1/analysis/:symbol/patterns?timeframe=1Day&days=180
+func (ac *AnalysisController) HandleGetPatterns(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+	timeframe := c.DefaultQuery("timeframe", "1Day")
+
+	days := 180
+	if daysStr := c.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed < 10 || parsed > 1000 {
+			respondBadRequest(c, "days must be an integer between 10 and 1000", nil)
+			return
+		}
+		days = parsed
+	}
+
+	result, err := ac.analysisService.Patterns(c.Request.Context(), symbol, timeframe, days)
+	if err != nil {
+		respondServiceError(c, "Failed to detect patterns", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// HandleGetSectorRotation ranks sector ETFs by momentum relative to SPY
+// GET /api/v1/analysis/sectors
+func (ac *AnalysisController) HandleGetSectorRotation(c *gin.Context) {
+	rotation, err := ac.analysisService.SectorRotation(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, "Failed to build sector rotation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rotation)
+}
+
+// HandleGetRelativeStrength returns a symbol's relative strength versus its
+// sector ETF and SPY over 1, 3, 6 and 12 months
+// GET /api/v1/analysis/:symbol/relative-strength
+func (ac *AnalysisController) HandleGetRelativeStrength(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+
+	result, err := ac.analysisService.RelativeStrength(c.Request.Context(), symbol)
+	if err != nil {
+		respondServiceError(c, "Failed to compute relative strength", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"prophet-trader/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIAudit records every state-changing request, whether or not it succeeds,
+// in the API audit log. It must run before AuthController.Authenticate so
+// refused tokens are recorded too; the caller is read once the chain has
+// run. The body is hashed, never stored, since it may hold secrets.
+func APIAudit(log *services.APIAuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if log == nil || method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		hash := sha256.New()
+		var size int64
+		if c.Request.Body != nil {
+			// MaxBodySize has already capped the body, so it is safe to hold
+			body, err := io.ReadAll(c.Request.Body)
+			hash.Write(body)
+			size = int64(len(body))
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err}))
+		}
+
+		started := time.Now()
+		c.Next()
+
+		audit := &models.DBAPIAudit{
+			ClientIP:    c.ClientIP(),
+			Method:      method,
+			Route:       c.FullPath(),
+			Path:        c.Request.URL.RequestURI(),
+			PayloadHash: hex.EncodeToString(hash.Sum(nil)),
+			PayloadSize: size,
+			Status:      c.Writer.Status(),
+			ErrorCode:   c.GetString(errorCodeKey),
+			DurationMs:  time.Since(started).Milliseconds(),
+		}
+		if audit.Route == "" {
+			audit.Route = "unmatched"
+		}
+		if principal, ok := c.Get(principalKey); ok {
+			p := principal.(*services.Principal)
+			audit.TokenID, audit.Caller, audit.Role = p.TokenID, p.Name, p.Role
+		}
+		log.Record(audit)
+	}
+}
+
+// errReader returns err once the buffered body is used up, or io.EOF
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}
+
+// HandleListAPIAudit lists state-changing API calls, newest first. Pass the
+// returned next_before_id as before_id for older records.
+// GET /api/v1/admin/audit?token=tok_1&method=POST&route=/api/v1/orders/buy&failed=true&since=2024-01-02&limit=100
+func HandleListAPIAudit(log *services.APIAuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := database.APIAuditQuery{
+			TokenID: c.Query("token"),
+			Method:  strings.ToUpper(c.Query("method")),
+			Route:   c.Query("route"),
+			Failed:  c.Query("failed") == "true",
+			Limit:   100,
+		}
+		if limitStr := c.Query("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+				q.Limit = min(l, 1000)
+			}
+		}
+		if raw := c.Query("since"); raw != "" {
+			since, err := parseListTime(raw, false)
+			if err != nil {
+				respondBadRequest(c, "Invalid since", err)
+				return
+			}
+			q.Since = since
+		}
+		if raw := c.Query("before_id"); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				respondBadRequest(c, "Invalid before_id", fmt.Errorf("before_id must be a record id"))
+				return
+			}
+			q.BeforeID = uint(id)
+		}
+
+		audits, err := log.List(q)
+		if err != nil {
+			respondServiceError(c, "Failed to get API audit log", err)
+			return
+		}
+
+		resp := gin.H{
+			"count":  len(audits),
+			"audits": audits,
+		}
+		if len(audits) == q.Limit {
+			resp["next_before_id"] = audits[len(audits)-1].ID
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey is the gin context key holding the authenticated caller
+const principalKey = "principal"
+
+// AuthController authenticates API requests and manages API tokens
+type AuthController struct {
+	authService *services.AuthService
+}
+
+// NewAuthController creates a new auth controller
+func NewAuthController(authService *services.AuthService) *AuthController {
+	return &AuthController{
+		authService: authService,
+	}
+}
+
+// Authenticate resolves the caller from the "Authorization: Bearer" header.
+// Browser EventSource clients cannot set headers, so event stream requests
+// may pass the token as ?access_token= instead. Viewers may only make GET
+// requests; routes needing more than trader access add RequireRole.
+// Requests pass through when authentication is disabled.
+func (ac *AuthController) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !ac.authService.Enabled() {
+			c.Next()
+			return
+		}
+
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if token == "" && c.Request.Method == http.MethodGet && strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+			token = c.Query("access_token")
+		}
+		principal, err := ac.authService.Authenticate(token)
+		if err != nil {
+			abortWithError(c, services.ErrCodeUnauthorized, "Unauthorized", "a valid API token is required in the Authorization header")
+			return
+		}
+		c.Set(principalKey, principal)
+
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead && !services.RoleAllows(principal.Role, services.RoleTrader) {
+			abortWithError(c, services.ErrCodeForbidden, "Forbidden", "role "+principal.Role+" has read-only access")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole rejects callers without at least the given role
+func (ac *AuthController) RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !ac.authService.Enabled() {
+			c.Next()
+			return
+		}
+
+		principal, ok := c.Get(principalKey)
+		if !ok || !services.RoleAllows(principal.(*services.Principal).Role, role) {
+			abortWithError(c, services.ErrCodeForbidden, "Forbidden", "requires the "+role+" role")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HandleWhoAmI returns the caller's token name and role
+// GET /api/v1/auth/me
+func (ac *AuthController) HandleWhoAmI(c *gin.Context) {
+	principal, ok := c.Get(principalKey)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"auth_enabled": false,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"auth_enabled": true,
+		"principal":    principal,
+	})
+}
+
+// CreateTokenRequest is the body of a token creation request
+type CreateTokenRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+	Role string `json:"role" binding:"required,oneof=viewer trader admin"`
+}
+
+// HandleCreateToken issues a new API token. The token is only returned here.
+// POST /api/v1/admin/tokens
+func (ac *AuthController) HandleCreateToken(c *gin.Context) {
+	var req CreateTokenRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	token, err := ac.authService.CreateToken(req.Name, req.Role)
+	if err != nil {
+		respondBadRequest(c, "Failed to create token", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// HandleListTokens lists issued API tokens without their secrets
+// GET /api/v1/admin/tokens
+func (ac *AuthController) HandleListTokens(c *gin.Context) {
+	tokens, err := ac.authService.ListTokens()
+	if err != nil {
+		respondServiceError(c, "Failed to list tokens", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+		"count":  len(tokens),
+	})
+}
+
+// HandleRevokeToken revokes an API token
+// DELETE /api/v1/admin/tokens/:id
+func (ac *AuthController) HandleRevokeToken(c *gin.Context) {
+	if err := ac.authService.RevokeToken(c.Param("id")); err != nil {
+		respondServiceError(c, "Failed to revoke token", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Token revoked",
+	})
+}
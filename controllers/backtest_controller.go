@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BacktestController handles backtesting endpoints
+type BacktestController struct {
+	optimizer *services.StrategyOptimizer
+}
+
+// NewBacktestController creates a new backtest controller
+func NewBacktestController(optimizer *services.StrategyOptimizer) *BacktestController {
+	return &BacktestController{
+		optimizer: optimizer,
+	}
+}
+
+// HandleOptimize sweeps strategy parameters with walk-forward validation
+// POST /api/v1/backtest/optimize
+func (bc *BacktestController) HandleOptimize(c *gin.Context) {
+	var req services.OptimizeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := bc.optimizer.Optimize(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, "Failed to optimize strategy", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BackupController exposes backup, verification and restore of the
+// database and activity logs
+type BackupController struct {
+	backupService *services.BackupService
+}
+
+// NewBackupController creates a new backup controller
+func NewBackupController(backupService *services.BackupService) *BackupController {
+	return &BackupController{
+		backupService: backupService,
+	}
+}
+
+// RestoreRequest names the backup to restore
+type RestoreRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// HandleBackup takes a backup immediately
+// POST /api/v1/admin/backup
+func (bc *BackupController) HandleBackup(c *gin.Context) {
+	result, err := bc.backupService.Backup(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, "Backup failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// HandleListBackups lists stored backups, newest first
+// GET /api/v1/admin/backups
+func (bc *BackupController) HandleListBackups(c *gin.Context) {
+	backups, err := bc.backupService.List(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, "Failed to list backups", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"backups":  backups,
+		"count":    len(backups),
+		"location": bc.backupService.Location(),
+	})
+}
+
+// HandleVerifyBackup downloads a backup and checks it against its manifest
+// POST /api/v1/admin/backups/:name/verify
+func (bc *BackupController) HandleVerifyBackup(c *gin.Context) {
+	name := c.Param("name")
+	manifest, err := bc.backupService.Verify(c.Request.Context(), name)
+	if err != nil {
+		respondServiceError(c, "Backup verification failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":     name,
+		"valid":    true,
+		"manifest": manifest,
+	})
+}
+
+// HandleRestore verifies a backup and stages it. The restore is applied on
+// the next restart.
+// POST /api/v1/admin/restore
+func (bc *BackupController) HandleRestore(c *gin.Context) {
+	var req RestoreRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := bc.backupService.Restore(c.Request.Context(), req.Name)
+	if err != nil {
+		respondServiceError(c, "Restore failed", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":          "Restore staged; restart the bot to apply it",
+		"name":             result.Name,
+		"manifest":         result.Manifest,
+		"restart_required": result.RestartRequired,
+	})
+}
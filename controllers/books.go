@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"prophet-trader/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookSelector puts the book named by the X-Book header, or the book query
+// parameter, on the request context. Writes are tagged with it and reads are
+// filtered by it; requests without one write to the default book and read
+// every book.
+func BookSelector() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		book := c.GetHeader("X-Book")
+		if book == "" {
+			book = c.Query("book")
+		}
+		if book == "" {
+			c.Next()
+			return
+		}
+		if err := services.ValidateBook(book); err != nil {
+			abortWithError(c, services.ErrCodeInvalidRequest, "Invalid book", errorDetails(err))
+			return
+		}
+		c.Request = c.Request.WithContext(services.WithBook(c.Request.Context(), book))
+		c.Next()
+	}
+}
+
+// filterBook keeps the items of the selected book, or all of them when the
+// request did not select one
+func filterBook[T any](c *gin.Context, items []T, bookOf func(T) string) []T {
+	book, ok := services.SelectedBook(c.Request.Context())
+	if !ok {
+		return items
+	}
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		if bookOf(item) == book {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
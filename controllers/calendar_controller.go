@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalendarController handles economic calendar endpoints
+type CalendarController struct {
+	calendarService *services.EconomicCalendarService
+}
+
+// NewCalendarController creates a new calendar controller
+func NewCalendarController(calendarService *services.EconomicCalendarService) *CalendarController {
+	return &CalendarController{
+		calendarService: calendarService,
+	}
+}
+
+// HandleGetEconomicCalendar returns upcoming economic events such as FOMC,
+// CPI and NFP. Optional query params: days (default 7), impact (minimum
+// level: high, medium, low), country (default USD, "all" for every country)
+// and type (comma separated, e.g. FOMC,CPI).
+// GET /api/v1/calendar/economic
+func (cc *CalendarController) HandleGetEconomicCalendar(c *gin.Context) {
+	days := 7
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			respondBadRequest(c, "days must be a positive integer", nil)
+			return
+		}
+		days = parsed
+	}
+
+	// Include events from earlier today so just-released actuals are visible
+	now := time.Now()
+	filter := services.EconomicCalendarFilter{
+		From:      time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+		To:        now.AddDate(0, 0, days),
+		MinImpact: strings.ToLower(c.Query("impact")),
+	}
+	if country := c.DefaultQuery("country", "USD"); !strings.EqualFold(country, "all") {
+		filter.Countries = strings.Split(country, ",")
+	}
+	if types := c.Query("type"); types != "" {
+		filter.Types = strings.Split(strings.ToUpper(types), ",")
+	}
+
+	events, err := cc.calendarService.Events(c.Request.Context(), filter)
+	if err != nil {
+		respondServiceError(c, "Failed to get economic calendar", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":   filter.From,
+		"to":     filter.To,
+		"count":  len(events),
+		"events": events,
+	})
+}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pooled encoders, since a compressor allocates several hundred KB
+var (
+	gzipWriters  = sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+	flateWriters = sync.Pool{New: func() interface{} {
+		w, _ := flate.NewWriter(io.Discard, flate.DefaultCompression)
+		return w
+	}}
+)
+
+// Compress gzips or deflates responses of at least minBytes for clients that
+// accept it. Event streams, already compressed downloads and partial content
+// pass through untouched. minBytes of 0 disables compression.
+func Compress(minBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if minBytes <= 0 || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, minBytes: minBytes}
+		c.Writer = writer
+		defer func() {
+			writer.finish()
+			c.Writer = writer.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks gzip, then deflate, from an Accept-Encoding header,
+// skipping any the client refused with q=0
+func negotiateEncoding(header string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q > 0
+	}
+	for _, encoding := range []string{"gzip", "deflate"} {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressWriter holds back the first minBytes of a response so small bodies
+// go out as they are, then switches to the encoder once the body is known to
+// be worth compressing
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minBytes int
+
+	decided bool // whether the response compresses or passes through
+	encoder io.WriteCloser
+	pending bytes.Buffer
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided && !w.compressible() {
+		w.decided = true
+	}
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.pending.Write(data)
+	if w.pending.Len() >= w.minBytes {
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow is held back until the body decides the headers
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Flush compresses whatever is pending, since a flushing handler is streaming
+// and won't wait for minBytes
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if w.compressible() && w.pending.Len() > 0 {
+			w.start()
+		} else {
+			w.passThrough()
+		}
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// compressible reports whether the response so far may be compressed
+func (w *compressWriter) compressible() bool {
+	status := w.ResponseWriter.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusPartialContent || status == http.StatusNotModified {
+		return false
+	}
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, skip := range []string{"text/event-stream", "application/gzip", "application/zip", "application/x-gzip", "image/", "video/", "audio/"} {
+		if strings.HasPrefix(contentType, skip) {
+			return false
+		}
+	}
+	return true
+}
+
+// start switches to the encoder and writes the pending bytes through it
+func (w *compressWriter) start() error {
+	w.decided = true
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Encoding", w.encoding)
+	header.Add("Vary", "Accept-Encoding")
+	header.Del("Content-Length")
+
+	switch w.encoding {
+	case "gzip":
+		gz := gzipWriters.Get().(*gzip.Writer)
+		gz.Reset(w.ResponseWriter)
+		w.encoder = gz
+	default:
+		fl := flateWriters.Get().(*flate.Writer)
+		fl.Reset(w.ResponseWriter)
+		w.encoder = fl
+	}
+	_, err := w.encoder.Write(w.pending.Bytes())
+	w.pending.Reset()
+	return err
+}
+
+// passThrough sends the pending bytes as they are
+func (w *compressWriter) passThrough() {
+	w.decided = true
+	if w.pending.Len() > 0 {
+		w.ResponseWriter.Write(w.pending.Bytes())
+		w.pending.Reset()
+	}
+}
+
+// finish ends the response: bodies shorter than minBytes go out as they are,
+// and the encoder is closed and returned to its pool
+func (w *compressWriter) finish() {
+	if !w.decided {
+		if w.pending.Len() > 0 && w.compressible() {
+			// Short bodies still vary by encoding for caches
+			w.ResponseWriter.Header().Add("Vary", "Accept-Encoding")
+		}
+		w.passThrough()
+		return
+	}
+	if w.encoder == nil {
+		return
+	}
+	w.encoder.Close()
+	switch encoder := w.encoder.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(encoder)
+	case *flate.Writer:
+		flateWriters.Put(encoder)
+	}
+	w.encoder = nil
+}
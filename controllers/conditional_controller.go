@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConditionalController handles locally evaluated conditional order endpoints
+type ConditionalController struct {
+	conditionalService *services.ConditionalOrderService
+}
+
+// NewConditionalController creates a new conditional order controller
+func NewConditionalController(conditionalService *services.ConditionalOrderService) *ConditionalController {
+	return &ConditionalController{
+		conditionalService: conditionalService,
+	}
+}
+
+// HandleCreateOrder creates a conditional order
+// POST /api/v1/orders/conditional
+func (cc *ConditionalController) HandleCreateOrder(c *gin.Context) {
+	var req services.ConditionalOrderRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	order, err := cc.conditionalService.Create(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, "Failed to create conditional order", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// HandleListOrders lists conditional orders, optionally filtered by status
+// GET /api/v1/orders/conditional?status=ACTIVE
+func (cc *ConditionalController) HandleListOrders(c *gin.Context) {
+	orders, err := cc.conditionalService.List(strings.ToUpper(c.Query("status")))
+	if err != nil {
+		respondServiceError(c, "Failed to get conditional orders", err)
+		return
+	}
+	orders = filterBook(c, orders, func(o *services.ConditionalOrder) string { return o.Book })
+
+	c.JSON(http.StatusOK, gin.H{
+		"orders": orders,
+		"count":  len(orders),
+	})
+}
+
+// HandleGetOrder returns a conditional order
+// GET /api/v1/orders/conditional/:id
+func (cc *ConditionalController) HandleGetOrder(c *gin.Context) {
+	order, err := cc.conditionalService.Get(c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to get conditional order", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// HandleCancelOrder stops watching an active conditional order
+// DELETE /api/v1/orders/conditional/:id
+func (cc *ConditionalController) HandleCancelOrder(c *gin.Context) {
+	order, err := cc.conditionalService.Cancel(c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to cancel conditional order", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
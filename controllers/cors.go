@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsMaxAge is how long browsers may reuse a preflight answer, in seconds
+const corsMaxAge = 600
+
+// CORSPolicy says which browser origins may call the API and how
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins such as https://dash.example.com.
+	// An entry ending in :* matches any port, e.g. http://localhost:*, and
+	// "*" matches every origin.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool // let browsers send cookies and auth headers
+}
+
+// allows reports whether origin matches one of the allowed origins
+func (p CORSPolicy) allows(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, ":*"); ok {
+			if port, ok := strings.CutPrefix(origin, prefix+":"); ok {
+				if _, err := strconv.Atoi(port); err == nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// CORS answers browser cross-origin requests according to policy. Allowed
+// origins are echoed back rather than answered with *, so credentials work
+// and caches keep answers for different origins apart. Requests from other
+// origins get no CORS headers, which the browser treats as a refusal.
+// Preflight OPTIONS requests are answered with 204 here.
+func CORS(policy CORSPolicy) gin.HandlerFunc {
+	methods := strings.Join(policy.AllowedMethods, ", ")
+	headers := strings.Join(policy.AllowedHeaders, ", ")
+	exposed := strings.Join(policy.ExposedHeaders, ", ")
+	wildcard := !policy.AllowCredentials && len(policy.AllowedOrigins) == 1 && policy.AllowedOrigins[0] == "*"
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		if origin := c.GetHeader("Origin"); origin != "" {
+			switch {
+			case wildcard:
+				header.Set("Access-Control-Allow-Origin", "*")
+			case policy.allows(origin):
+				header.Set("Access-Control-Allow-Origin", origin)
+				header.Add("Vary", "Origin")
+				if policy.AllowCredentials {
+					header.Set("Access-Control-Allow-Credentials", "true")
+				}
+			default:
+				header.Add("Vary", "Origin")
+				origin = ""
+			}
+
+			if origin != "" {
+				if c.Request.Method == http.MethodOptions {
+					header.Set("Access-Control-Allow-Methods", methods)
+					header.Set("Access-Control-Allow-Headers", headers)
+					header.Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+				} else if exposed != "" {
+					header.Set("Access-Control-Expose-Headers", exposed)
+				}
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,535 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"prophet-trader/interfaces"
+	"prophet-trader/services"
+	"prophet-trader/web"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// dashboardCookie holds the API token of a signed-in dashboard user
+const dashboardCookie = "prophet_dashboard_token"
+
+// Equity chart dimensions in SVG user units
+const (
+	equityChartWidth  = 800
+	equityChartHeight = 200
+)
+
+// DashboardController serves the server-rendered dashboard. Pages and the
+// fragments they refresh are rendered from templates, so the dashboard works
+// without JavaScript build tooling.
+type DashboardController struct {
+	tradingService  interfaces.TradingService
+	positionManager *services.PositionManager
+	activityLogger  *services.ActivityLogger
+	authService     *services.AuthService
+	assets          fs.FS
+	fromDisk        bool   // re-parse templates on every request
+	environment     string // "paper" or "live", shown as a banner
+	dryRun          bool
+	observer        bool // trading disabled, shown next to the update time
+	briefService    *services.MarketBriefService
+	history         *services.PortfolioHistoryService // nil charts the broker's daily history alone
+	templates       *template.Template
+	mu              sync.Mutex
+	logger          *logrus.Logger
+}
+
+// NewDashboardController creates a dashboard controller. Assets come from
+// the binary unless dir is set, in which case templates and static files
+// are read from dir on every request for development.
+func NewDashboardController(
+	tradingService interfaces.TradingService,
+	positionManager *services.PositionManager,
+	activityLogger *services.ActivityLogger,
+	authService *services.AuthService,
+	dir string,
+	environment string,
+	dryRun bool,
+) (*DashboardController, error) {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	dc := &DashboardController{
+		tradingService:  tradingService,
+		positionManager: positionManager,
+		activityLogger:  activityLogger,
+		authService:     authService,
+		assets:          web.Assets,
+		environment:     environment,
+		dryRun:          dryRun,
+		logger:          logger,
+	}
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("failed to open dashboard directory: %w", err)
+		}
+		dc.assets = os.DirFS(dir)
+		dc.fromDisk = true
+	}
+
+	// Parse once up front so template errors surface at startup
+	templates, err := dc.parseTemplates()
+	if err != nil {
+		return nil, err
+	}
+	dc.templates = templates
+
+	return dc, nil
+}
+
+// SetObserverMode marks the dashboard as served by a bot that cannot trade
+func (dc *DashboardController) SetObserverMode(observer bool) {
+	dc.observer = observer
+}
+
+// SetMarketBrief shows the market brief on the dashboard
+func (dc *DashboardController) SetMarketBrief(briefService *services.MarketBriefService) {
+	dc.briefService = briefService
+}
+
+// SetPortfolioHistory charts equity with gaps filled from local snapshots
+// and marks the trades made along the curve
+func (dc *DashboardController) SetPortfolioHistory(history *services.PortfolioHistoryService) {
+	dc.history = history
+}
+
+// RegisterRoutes mounts the dashboard under /dashboard
+func (dc *DashboardController) RegisterRoutes(router *gin.Engine) {
+	static, _ := fs.Sub(dc.assets, "static")
+	router.StaticFS("/dashboard/static", http.FS(static))
+	router.GET("/dashboard/login", dc.HandleLoginPage)
+	router.POST("/dashboard/login", dc.HandleLogin)
+	router.POST("/dashboard/logout", dc.HandleLogout)
+
+	dashboard := router.Group("/dashboard", dc.requireLogin())
+	{
+		dashboard.GET("", dc.HandleDashboard)
+		dashboard.GET("/", dc.HandleDashboard)
+		dashboard.GET("/positions", dc.HandlePositions)
+		dashboard.GET("/equity", dc.HandleEquity)
+		dashboard.GET("/activity", dc.HandleActivity)
+		dashboard.GET("/brief", dc.HandleBrief)
+	}
+}
+
+// requireLogin checks the dashboard cookie when authentication is enabled.
+// Pages redirect to the sign-in form; fragments answer 401 so the refresh
+// script can redirect instead.
+func (dc *DashboardController) requireLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !dc.authService.Enabled() {
+			c.Next()
+			return
+		}
+
+		token, _ := c.Cookie(dashboardCookie)
+		if _, err := dc.authService.Authenticate(token); err != nil {
+			if p := c.Request.URL.Path; p == "/dashboard" || p == "/dashboard/" {
+				c.Redirect(http.StatusSeeOther, "/dashboard/login")
+				c.Abort()
+				return
+			}
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HandleLoginPage renders the sign-in form
+// GET /dashboard/login
+func (dc *DashboardController) HandleLoginPage(c *gin.Context) {
+	if !dc.authService.Enabled() {
+		c.Redirect(http.StatusSeeOther, "/dashboard")
+		return
+	}
+	dc.render(c, http.StatusOK, "login", gin.H{})
+}
+
+// HandleLogin validates an API token and stores it in an HttpOnly cookie.
+// The cookie is only honoured by the dashboard, never by /api/v1.
+// POST /dashboard/login
+func (dc *DashboardController) HandleLogin(c *gin.Context) {
+	token := strings.TrimSpace(c.PostForm("token"))
+	if _, err := dc.authService.Authenticate(token); err != nil {
+		dc.render(c, http.StatusUnauthorized, "login", gin.H{"Error": "Invalid token"})
+		return
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     dashboardCookie,
+		Value:    token,
+		Path:     "/dashboard",
+		MaxAge:   int((7 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	c.Redirect(http.StatusSeeOther, "/dashboard")
+}
+
+// HandleLogout clears the dashboard cookie
+// POST /dashboard/logout
+func (dc *DashboardController) HandleLogout(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     dashboardCookie,
+		Path:     "/dashboard",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	c.Redirect(http.StatusSeeOther, "/dashboard/login")
+}
+
+// HandleDashboard renders the full dashboard page
+// GET /dashboard?days=30
+func (dc *DashboardController) HandleDashboard(c *gin.Context) {
+	ctx := c.Request.Context()
+	dc.render(c, http.StatusOK, "dashboard", gin.H{
+		"GeneratedAt": time.Now(),
+		"Environment": dc.environment,
+		"DryRun":      dc.dryRun,
+		"Observer":    dc.observer,
+		"Positions":   dc.positionsView(ctx),
+		"Equity":      dc.equityView(ctx, equityDays(c)),
+		"Activity":    dc.activityView(),
+		"Brief":       dc.briefView(),
+	})
+}
+
+// HandlePositions renders the positions table fragment
+// GET /dashboard/positions
+func (dc *DashboardController) HandlePositions(c *gin.Context) {
+	dc.render(c, http.StatusOK, "positions", dc.positionsView(c.Request.Context()))
+}
+
+// HandleEquity renders the equity curve fragment
+// GET /dashboard/equity?days=30
+func (dc *DashboardController) HandleEquity(c *gin.Context) {
+	dc.render(c, http.StatusOK, "equity", dc.equityView(c.Request.Context(), equityDays(c)))
+}
+
+// HandleActivity renders today's activity feed fragment
+// GET /dashboard/activity
+func (dc *DashboardController) HandleActivity(c *gin.Context) {
+	dc.render(c, http.StatusOK, "activity", dc.activityView())
+}
+
+// HandleBrief renders the market brief fragment
+// GET /dashboard/brief
+func (dc *DashboardController) HandleBrief(c *gin.Context) {
+	brief := dc.briefView()
+	if brief == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	dc.render(c, http.StatusOK, "brief", brief)
+}
+
+// positionRow is one row of the positions table
+type positionRow struct {
+	Symbol         string
+	Qty            float64
+	AvgEntryPrice  float64
+	CurrentPrice   float64
+	MarketValue    float64
+	UnrealizedPL   float64
+	UnrealizedPLPC float64
+	StopLoss       float64
+	TakeProfit     float64
+	ManagedStatus  string
+	Strategy       string
+}
+
+type positionsView struct {
+	Rows              []positionRow
+	TotalMarketValue  float64
+	TotalUnrealizedPL float64
+	Error             string
+}
+
+func (dc *DashboardController) positionsView(ctx context.Context) positionsView {
+	positions, err := dc.tradingService.GetPositions(ctx)
+	if err != nil {
+		dc.logger.WithError(err).Warn("Failed to get positions for dashboard")
+		return positionsView{Error: "Positions unavailable: " + err.Error()}
+	}
+
+	managed := make(map[string]*services.ManagedPosition)
+	for _, mp := range dc.positionManager.ListManagedPositions("") {
+		if mp.Status == "ACTIVE" || mp.Status == "PARTIAL" {
+			managed[mp.Symbol] = mp
+		}
+	}
+
+	view := positionsView{Rows: make([]positionRow, 0, len(positions))}
+	for _, p := range positions {
+		row := positionRow{
+			Symbol:         p.Symbol,
+			Qty:            p.Qty.InexactFloat64(),
+			AvgEntryPrice:  p.AvgEntryPrice.InexactFloat64(),
+			CurrentPrice:   p.CurrentPrice.InexactFloat64(),
+			MarketValue:    p.MarketValue.InexactFloat64(),
+			UnrealizedPL:   p.UnrealizedPL.InexactFloat64(),
+			UnrealizedPLPC: p.UnrealizedPLPC.InexactFloat64() * 100,
+		}
+		if mp, ok := managed[p.Symbol]; ok {
+			row.StopLoss = mp.StopLossPrice
+			row.TakeProfit = mp.TakeProfitPrice
+			row.ManagedStatus = mp.Status
+			row.Strategy = mp.Strategy
+		}
+		view.Rows = append(view.Rows, row)
+		view.TotalMarketValue += row.MarketValue
+		view.TotalUnrealizedPL += row.UnrealizedPL
+	}
+
+	sort.Slice(view.Rows, func(i, j int) bool {
+		return math.Abs(view.Rows[i].MarketValue) > math.Abs(view.Rows[j].MarketValue)
+	})
+	return view
+}
+
+type equityView struct {
+	Days          int
+	Start, End    time.Time
+	EndEquity     float64
+	Change        float64
+	ChangePct     float64
+	MinEquity     float64
+	MaxEquity     float64
+	Points        string // SVG polyline points
+	Markers       []equityMarker
+	Width, Height int
+	Error         string
+}
+
+// equityMarker is a trade drawn as a tick on the equity curve
+type equityMarker struct {
+	X, Y  float64
+	Side  string
+	Label string
+}
+
+func (dc *DashboardController) equityView(ctx context.Context, days int) equityView {
+	view := equityView{Days: days, Width: equityChartWidth, Height: equityChartHeight}
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -days)
+	var (
+		times  []time.Time
+		values []float64
+		trades []*services.TradeMarker
+	)
+	if dc.history != nil {
+		history, err := dc.history.History(ctx, start, end, "1D")
+		if err != nil {
+			dc.logger.WithError(err).Warn("Failed to get portfolio history for dashboard")
+			view.Error = "Equity history unavailable: " + err.Error()
+			return view
+		}
+		for _, point := range history.Points {
+			times = append(times, point.Time)
+			values = append(values, point.Equity.InexactFloat64())
+		}
+		trades = history.Trades
+	} else {
+		history, err := dc.tradingService.GetPortfolioHistory(ctx, start, end)
+		if err != nil {
+			dc.logger.WithError(err).Warn("Failed to get portfolio history for dashboard")
+			view.Error = "Equity history unavailable: " + err.Error()
+			return view
+		}
+		for _, snapshot := range history {
+			if snapshot.Equity.IsPositive() {
+				times = append(times, snapshot.Timestamp)
+				values = append(values, snapshot.Equity.InexactFloat64())
+			}
+		}
+	}
+	if len(values) < 2 {
+		view.Error = "Not enough equity history yet"
+		return view
+	}
+
+	view.Start = times[0]
+	view.End = times[len(times)-1]
+	view.EndEquity = values[len(values)-1]
+	view.Change = view.EndEquity - values[0]
+	view.ChangePct = view.Change / values[0] * 100
+	view.MinEquity, view.MaxEquity = values[0], values[0]
+	for _, v := range values {
+		view.MinEquity = math.Min(view.MinEquity, v)
+		view.MaxEquity = math.Max(view.MaxEquity, v)
+	}
+	view.Points = equityPoints(values, view.MinEquity, view.MaxEquity)
+	for _, trade := range trades {
+		if trade.PointIndex < 0 {
+			continue
+		}
+		x, y := equityXY(trade.PointIndex, len(values), values[trade.PointIndex], view.MinEquity, view.MaxEquity)
+		view.Markers = append(view.Markers, equityMarker{
+			X:     x,
+			Y:     y,
+			Side:  trade.Side,
+			Label: fmt.Sprintf("%s %s %s @ %s", strings.ToUpper(trade.Side), trade.Qty.String(), trade.Symbol, trade.Price.StringFixed(2)),
+		})
+	}
+	return view
+}
+
+// equityPoints scales values into the chart box
+func equityPoints(values []float64, min, max float64) string {
+	var b strings.Builder
+	for i, v := range values {
+		x, y := equityXY(i, len(values), v, min, max)
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%.1f,%.1f", x, y)
+	}
+	return b.String()
+}
+
+// equityXY places the i-th of n values in the chart box, leaving a small
+// margin so the line never touches the edges
+func equityXY(i, n int, v, min, max float64) (float64, float64) {
+	const margin = 8.0
+	span := max - min
+	if span == 0 {
+		span = 1
+	}
+	x := float64(i) * float64(equityChartWidth) / float64(n-1)
+	y := margin + (1-(v-min)/span)*(equityChartHeight-2*margin)
+	return x, y
+}
+
+type activityView struct {
+	Items []services.Activity
+	Error string
+}
+
+func (dc *DashboardController) activityView() activityView {
+	log, err := dc.activityLogger.GetCurrentLog()
+	if err != nil {
+		return activityView{Error: "Activity unavailable: " + err.Error()}
+	}
+	if log == nil {
+		return activityView{}
+	}
+
+	// Newest first, capped to keep the page light
+	items := make([]services.Activity, 0, len(log.Activities))
+	for i := len(log.Activities) - 1; i >= 0 && len(items) < 50; i-- {
+		items = append(items, log.Activities[i])
+	}
+	return activityView{Items: items}
+}
+
+// briefView is nil when the market brief is disabled
+func (dc *DashboardController) briefView() *services.MarketBrief {
+	if dc.briefService == nil {
+		return nil
+	}
+	brief := dc.briefService.Brief()
+	// The ten most covered symbols keep the section short
+	brief.Symbols = brief.Symbols[:min(len(brief.Symbols), 10)]
+	return brief
+}
+
+// render executes a template into a buffer first so a template error
+// produces a clean 500 instead of a half-written page
+func (dc *DashboardController) render(c *gin.Context, status int, name string, data interface{}) {
+	templates := dc.templates
+	if dc.fromDisk {
+		parsed, err := dc.parseTemplates()
+		if err != nil {
+			dc.logger.WithError(err).Error("Failed to parse dashboard templates")
+			c.String(http.StatusInternalServerError, "template error: %v", err)
+			return
+		}
+		templates = parsed
+	}
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		dc.logger.WithError(err).WithField("template", name).Error("Failed to render dashboard")
+		c.String(http.StatusInternalServerError, "failed to render %s", name)
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+func (dc *DashboardController) parseTemplates() (*template.Template, error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	templates, err := template.New("").Funcs(dashboardFuncs).ParseFS(dc.assets, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dashboard templates: %w", err)
+	}
+	return templates, nil
+}
+
+// equityDays reads ?days= for the equity curve, defaulting to 30
+func equityDays(c *gin.Context) int {
+	days, err := strconv.Atoi(c.Query("days"))
+	if err != nil || days <= 0 {
+		return 30
+	}
+	if days > 365*5 {
+		return 365 * 5
+	}
+	return days
+}
+
+var dashboardFuncs = template.FuncMap{
+	"money": func(v float64) string {
+		sign := ""
+		if v < 0 {
+			sign, v = "-", -v
+		}
+		cents := int64(math.Round(v * 100))
+		whole := strconv.FormatInt(cents/100, 10)
+		for i := len(whole) - 3; i > 0; i -= 3 {
+			whole = whole[:i] + "," + whole[i:]
+		}
+		return fmt.Sprintf("%s$%s.%02d", sign, whole, cents%100)
+	},
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%+.2f%%", v)
+	},
+	"number": func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	},
+	"signClass": func(v float64) string {
+		switch {
+		case v > 0:
+			return "pos"
+		case v < 0:
+			return "neg"
+		default:
+			return ""
+		}
+	},
+}
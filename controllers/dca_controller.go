@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DCAController handles dollar-cost averaging plan endpoints
+type DCAController struct {
+	dcaService *services.DCAService
+}
+
+// NewDCAController creates a new DCA controller
+func NewDCAController(dcaService *services.DCAService) *DCAController {
+	return &DCAController{
+		dcaService: dcaService,
+	}
+}
+
+// HandleCreatePlan creates a DCA plan
+// POST /api/v1/dca
+func (dc *DCAController) HandleCreatePlan(c *gin.Context) {
+	var req services.DCAPlanRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	plan, err := dc.dcaService.CreatePlan(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, "Failed to create DCA plan", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, plan)
+}
+
+// HandleListPlans lists DCA plans with their progress, optionally filtered
+// by status
+// GET /api/v1/dca?status=ACTIVE
+func (dc *DCAController) HandleListPlans(c *gin.Context) {
+	plans, err := dc.dcaService.ListPlans(strings.ToUpper(c.Query("status")))
+	if err != nil {
+		respondServiceError(c, "Failed to get DCA plans", err)
+		return
+	}
+	plans = filterBook(c, plans, func(p *services.DCAPlan) string { return p.Book })
+
+	c.JSON(http.StatusOK, gin.H{
+		"plans": plans,
+		"count": len(plans),
+	})
+}
+
+// HandleGetPlan returns a DCA plan with every purchase
+// GET /api/v1/dca/:id
+func (dc *DCAController) HandleGetPlan(c *gin.Context) {
+	plan, err := dc.dcaService.GetPlan(c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to get DCA plan", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// HandleCancelPlan stops a DCA plan
+// DELETE /api/v1/dca/:id
+func (dc *DCAController) HandleCancelPlan(c *gin.Context) {
+	plan, err := dc.dcaService.CancelPlan(c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to cancel DCA plan", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"prophet-trader/config"
+	"prophet-trader/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorCodeKey is the gin context key holding the code of the error written,
+// for the API audit log
+const errorCodeKey = "error_code"
+
+// ErrorResponse is the body of every API error. Error is a short summary and
+// Details the underlying cause; clients should branch on Code.
+type ErrorResponse struct {
+	Code    services.ErrorCode     `json:"code"`
+	Error   string                 `json:"error"`
+	Details string                 `json:"details,omitempty"`
+	Risk    *services.RiskDecision `json:"risk,omitempty"`   // set for ORDER_REJECTED
+	Fields  []FieldError           `json:"fields,omitempty"` // set for invalid request bodies
+}
+
+// errorStatuses maps error codes to HTTP statuses
+var errorStatuses = map[services.ErrorCode]int{
+	services.ErrCodeInvalidRequest:          http.StatusBadRequest,
+	services.ErrCodeUnauthorized:            http.StatusUnauthorized,
+	services.ErrCodeForbidden:               http.StatusForbidden,
+	services.ErrCodeNotFound:                http.StatusNotFound,
+	services.ErrCodeRateLimited:             http.StatusTooManyRequests,
+	services.ErrCodeRequestTooLarge:         http.StatusRequestEntityTooLarge,
+	services.ErrCodeOrderRejected:           http.StatusUnprocessableEntity,
+	services.ErrCodeBrokerRejected:          http.StatusUnprocessableEntity,
+	services.ErrCodeInsufficientBuyingPower: http.StatusUnprocessableEntity,
+	services.ErrCodeMarketClosed:            http.StatusConflict,
+	services.ErrCodeUpstreamRateLimited:     http.StatusServiceUnavailable,
+	services.ErrCodeUpstreamError:           http.StatusBadGateway,
+	services.ErrCodeNotSupported:            http.StatusNotImplemented,
+	services.ErrCodeIntegrityFailed:         http.StatusUnprocessableEntity,
+	services.ErrCodeUnavailable:             http.StatusServiceUnavailable,
+	services.ErrCodeInternal:                http.StatusInternalServerError,
+}
+
+func statusForCode(code services.ErrorCode) int {
+	if status, ok := errorStatuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// respondError writes an error envelope with the status for code
+func respondError(c *gin.Context, code services.ErrorCode, message, details string) {
+	c.Set(errorCodeKey, string(code))
+	c.JSON(statusForCode(code), ErrorResponse{Code: code, Error: message, Details: details})
+}
+
+// abortWithError writes an error envelope and stops the handler chain
+func abortWithError(c *gin.Context, code services.ErrorCode, message, details string) {
+	c.Set(errorCodeKey, string(code))
+	c.AbortWithStatusJSON(statusForCode(code), ErrorResponse{Code: code, Error: message, Details: details})
+}
+
+// respondBadRequest reports invalid input; err may be nil
+func respondBadRequest(c *gin.Context, message string, err error) {
+	respondError(c, services.ErrCodeInvalidRequest, message, errorDetails(err))
+}
+
+// respondNotFound reports a missing resource; err may be nil
+func respondNotFound(c *gin.Context, message string, err error) {
+	respondError(c, services.ErrCodeNotFound, message, errorDetails(err))
+}
+
+// respondServiceError classifies a service failure and writes it with the
+// matching status. message summarizes the operation that failed.
+func respondServiceError(c *gin.Context, message string, err error) {
+	resp := ErrorResponse{
+		Code:    services.ErrorCodeOf(err),
+		Error:   message,
+		Details: errorDetails(err),
+	}
+
+	var rejected *services.RiskRejectedError
+	if errors.As(err, &rejected) {
+		resp.Risk = rejected.Decision
+	}
+
+	c.Set(errorCodeKey, string(resp.Code))
+	c.JSON(statusForCode(resp.Code), resp)
+}
+
+// errorDetails renders err for a client with any configured credentials
+// masked, since upstream errors can echo request URLs and headers
+func errorDetails(err error) string {
+	if err == nil {
+		return ""
+	}
+	return config.Redact(err.Error())
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"prophet-trader/services"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeat keeps idle connections open through proxies
+const sseHeartbeat = 15 * time.Second
+
+// EventsController streams live events over Server-Sent Events
+type EventsController struct {
+	eventBus *services.EventBus
+}
+
+// streamEventTypes are the event types clients may filter on
+var streamEventTypes = append([]string{
+	services.EventOrderUpdate, services.EventPositionPnL, services.EventAlertTriggered, services.EventNewsRuleMatched, services.EventMarketBrief, services.EventAlgoProgress,
+}, services.ActivityEventTypes...)
+
+// NewEventsController creates a new events controller
+func NewEventsController(eventBus *services.EventBus) *EventsController {
+	return &EventsController{
+		eventBus: eventBus,
+	}
+}
+
+// HandleEvents streams order updates, position P&L ticks, alert firings,
+// news rule matches, market brief updates, algo order progress and
+// trading activity events.
+// Optional query params: types (comma separated event types) and
+// last_event_id, for clients that cannot set the Last-Event-ID header.
+// Events published after Last-Event-ID are replayed first; a "resync" event
+// is sent when some of them are no longer available.
+// GET /api/v1/events
+func (ec *EventsController) HandleEvents(c *gin.Context) {
+	var types map[string]bool
+	if raw := c.Query("types"); raw != "" {
+		types = make(map[string]bool)
+		for _, eventType := range strings.Split(raw, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if !slices.Contains(streamEventTypes, eventType) {
+				respondBadRequest(c, "Invalid event type", fmt.Errorf("types must be a list of %s", strings.Join(streamEventTypes, ", ")))
+				return
+			}
+			types[eventType] = true
+		}
+	}
+
+	var lastEventID uint64
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	if raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			respondBadRequest(c, "Invalid Last-Event-ID", err)
+			return
+		}
+		lastEventID = id
+	}
+
+	subID, replay, complete, events := ec.eventBus.Subscribe(lastEventID, 256)
+	defer ec.eventBus.Unsubscribe(subID)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: 3000\n\n")
+	if !complete {
+		fmt.Fprintf(c.Writer, "event: resync\ndata: {\"reason\":\"events after %d are no longer available\"}\n\n", lastEventID)
+	}
+	for _, event := range replay {
+		if types == nil || types[event.Type] {
+			writeSSE(c, event)
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprintf(c.Writer, ": keepalive\n\n")
+			c.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				// Disconnected for falling behind; the client resumes from its last ID
+				return
+			}
+			if types == nil || types[event.Type] {
+				writeSSE(c, event)
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+func writeSSE(c *gin.Context, event *services.Event) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"archive/zip"
+	"fmt"
+	"net/http"
+	"prophet-trader/services"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportController exports the bot's stored data for offline research
+type ExportController struct {
+	exporter *services.DatasetExporter
+	logger   *logrus.Logger
+}
+
+// NewExportController creates a new export controller
+func NewExportController(exporter *services.DatasetExporter) *ExportController {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &ExportController{
+		exporter: exporter,
+		logger:   logger,
+	}
+}
+
+// HandleExportDataset streams bars, trades and sentiment as a zip of
+// partitioned Parquet or CSV files
+// GET /api/v1/export/dataset?datasets=bars,trades&symbols=SPY&start=2026-01-01&format=parquet&partition=month
+func (ec *ExportController) HandleExportDataset(c *gin.Context) {
+	req := services.DatasetExportRequest{
+		Datasets:  splitList(c.Query("datasets")),
+		Symbols:   splitList(c.Query("symbols")),
+		Format:    c.Query("format"),
+		Partition: c.Query("partition"),
+	}
+	if start := c.Query("start"); start != "" {
+		t, err := time.Parse("2006-01-02", start)
+		if err != nil {
+			respondBadRequest(c, "invalid start date format, use YYYY-MM-DD", nil)
+			return
+		}
+		req.Start = t
+	}
+	if end := c.Query("end"); end != "" {
+		t, err := time.Parse("2006-01-02", end)
+		if err != nil {
+			respondBadRequest(c, "invalid end date format, use YYYY-MM-DD", nil)
+			return
+		}
+		req.End = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	if err := req.Normalize(); err != nil {
+		respondServiceError(c, "Invalid export request", err)
+		return
+	}
+
+	// Errors after the first file can no longer change the status, so
+	// they end the archive early and are only logged
+	name := fmt.Sprintf("prophet-dataset-%s.zip", time.Now().UTC().Format("20060102T150405Z"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	if _, err := ec.exporter.Export(c.Request.Context(), req, services.ZipSink{Writer: archive}); err != nil {
+		ec.logger.WithError(err).Error("Dataset export failed")
+		c.Abort()
+		return
+	}
+	if err := archive.Close(); err != nil {
+		ec.logger.WithError(err).Error("Failed to finish dataset archive")
+	}
+}
+
+// splitList splits a comma separated query value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
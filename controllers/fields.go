@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldSelection holds the ?fields= and ?expand= parameters of endpoints
+// with heavy items. fields keeps only the named keys of each item, matched
+// case-insensitively; expand embeds related data that is left out by
+// default.
+type fieldSelection struct {
+	fields map[string]bool // lowercase keys to keep; nil keeps every key
+	expand map[string]bool
+}
+
+// parseFieldSelection reads fields and expand from the query string. Both
+// are comma separated, and expand only takes the names in expandable.
+func parseFieldSelection(c *gin.Context, expandable ...string) (fieldSelection, error) {
+	var sel fieldSelection
+	if raw := c.Query("fields"); raw != "" {
+		sel.fields = make(map[string]bool)
+		for _, field := range strings.Split(raw, ",") {
+			if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+				sel.fields[field] = true
+			}
+		}
+	}
+
+	if raw := c.Query("expand"); raw != "" {
+		sel.expand = make(map[string]bool)
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if !slices.Contains(expandable, name) {
+				if len(expandable) == 0 {
+					return fieldSelection{}, fmt.Errorf("expand is not supported here")
+				}
+				return fieldSelection{}, fmt.Errorf("unknown expand %q: use %s", name, strings.Join(expandable, ", "))
+			}
+			sel.expand[name] = true
+		}
+	}
+	return sel, nil
+}
+
+// expanded reports whether name was asked for in expand
+func (s fieldSelection) expanded(name string) bool {
+	return s.expand[name]
+}
+
+// apply encodes items, a slice, as JSON objects, runs embed on each to add
+// expanded data under its expand name, and drops the keys fields left out.
+// Expanded keys are kept whatever fields says. Items are returned as they are
+// when there is nothing to do.
+func (s fieldSelection) apply(items interface{}, embed func(i int, item map[string]interface{})) (interface{}, error) {
+	if s.fields == nil && (embed == nil || len(s.expand) == 0) {
+		return items, nil
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	// Numbers are kept as written, so decimals and large integers survive
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var objects []map[string]interface{}
+	if err := decoder.Decode(&objects); err != nil {
+		return nil, fmt.Errorf("field selection needs a list of objects: %w", err)
+	}
+
+	for i, object := range objects {
+		if embed != nil && len(s.expand) > 0 {
+			embed(i, object)
+		}
+		if s.fields == nil {
+			continue
+		}
+		for key := range object {
+			if !s.fields[strings.ToLower(key)] && !s.expand[key] {
+				delete(object, key)
+			}
+		}
+	}
+	return objects, nil
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FilingsController handles SEC filings endpoints
+type FilingsController struct {
+	filingsMonitor *services.FilingsMonitor
+}
+
+// NewFilingsController creates a new filings controller
+func NewFilingsController(filingsMonitor *services.FilingsMonitor) *FilingsController {
+	return &FilingsController{
+		filingsMonitor: filingsMonitor,
+	}
+}
+
+// HandleGetFilings returns recent 8-K, 10-Q, 10-K and Form 4 filings for a
+// symbol with their summaries. Optional query params: form, limit (default 20).
+// GET /api/v1/filings/:symbol
+func (fc *FilingsController) HandleGetFilings(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+	form := c.Query("form")
+
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			respondBadRequest(c, "limit must be a positive integer", nil)
+			return
+		}
+		limit = parsed
+	}
+
+	filings, err := fc.filingsMonitor.GetFilings(c.Request.Context(), symbol, form, limit)
+	if err != nil {
+		respondServiceError(c, "Failed to get filings", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":  symbol,
+		"count":   len(filings),
+		"filings": filings,
+	})
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FundamentalsController handles company fundamentals endpoints
+type FundamentalsController struct {
+	fundamentalsService *services.FundamentalsService
+}
+
+// NewFundamentalsController creates a new fundamentals controller
+func NewFundamentalsController(fundamentalsService *services.FundamentalsService) *FundamentalsController {
+	return &FundamentalsController{
+		fundamentalsService: fundamentalsService,
+	}
+}
+
+// HandleGetFundamentals returns valuation, growth, analyst targets and the
+// next earnings date for a symbol
+// GET /api/v1/fundamentals/:symbol
+func (fc *FundamentalsController) HandleGetFundamentals(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+
+	fundamentals, err := fc.fundamentalsService.Get(c.Request.Context(), symbol)
+	if err != nil {
+		respondServiceError(c, "Failed to get fundamentals", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fundamentals)
+}
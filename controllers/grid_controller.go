@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GridController handles grid trading endpoints
+type GridController struct {
+	gridService *services.GridService
+}
+
+// NewGridController creates a new grid controller
+func NewGridController(gridService *services.GridService) *GridController {
+	return &GridController{
+		gridService: gridService,
+	}
+}
+
+// HandleCreateGrid starts a grid, or previews its ladder with dry_run
+// POST /api/v1/grids
+func (gc *GridController) HandleCreateGrid(c *gin.Context) {
+	var req services.GridRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	grid, err := gc.gridService.CreateGrid(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, "Failed to create grid", err)
+		return
+	}
+
+	status := http.StatusCreated
+	if grid.Status == services.GridDryRun {
+		status = http.StatusOK
+	}
+	c.JSON(status, grid)
+}
+
+// HandleListGrids lists grids with their P&L, optionally filtered by status
+// GET /api/v1/grids?status=ACTIVE
+func (gc *GridController) HandleListGrids(c *gin.Context) {
+	grids, err := gc.gridService.ListGrids(c.Request.Context(), strings.ToUpper(c.Query("status")))
+	if err != nil {
+		respondServiceError(c, "Failed to get grids", err)
+		return
+	}
+	grids = filterBook(c, grids, func(g *services.Grid) string { return g.Book })
+
+	c.JSON(http.StatusOK, gin.H{
+		"grids": grids,
+		"count": len(grids),
+	})
+}
+
+// HandleGetGrid returns a grid with every order it placed
+// GET /api/v1/grids/:id
+func (gc *GridController) HandleGetGrid(c *gin.Context) {
+	grid, err := gc.gridService.GetGrid(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to get grid", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, grid)
+}
+
+// HandlePauseGrid withdraws a grid's orders until it is resumed
+// POST /api/v1/grids/:id/pause
+func (gc *GridController) HandlePauseGrid(c *gin.Context) {
+	grid, err := gc.gridService.PauseGrid(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to pause grid", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, grid)
+}
+
+// HandleResumeGrid re-places the orders of a paused grid
+// POST /api/v1/grids/:id/resume
+func (gc *GridController) HandleResumeGrid(c *gin.Context) {
+	grid, err := gc.gridService.ResumeGrid(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to resume grid", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, grid)
+}
+
+// HandleStopGrid cancels a grid's orders for good
+// POST /api/v1/grids/:id/stop
+func (gc *GridController) HandleStopGrid(c *gin.Context) {
+	grid, err := gc.gridService.StopGrid(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to stop grid", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, grid)
+}
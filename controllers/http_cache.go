@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cacheable lets clients keep a GET response for maxAge and revalidate it
+// afterwards. Responses without a validator of their own get a weak ETag
+// hashed from the body, and a matching If-None-Match is answered with 304
+// and no body. Responses are private, since they depend on the caller's
+// token. A maxAge of 0 makes clients revalidate every time.
+func Cacheable(maxAge time.Duration) gin.HandlerFunc {
+	cacheControl := "private, no-cache"
+	if maxAge > 0 {
+		cacheControl = fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds()))
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		writer := &etagWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		header := c.Writer.Header()
+		status := writer.Status()
+		if status != http.StatusOK && status != http.StatusNotModified {
+			c.Writer.Write(writer.body.Bytes())
+			return
+		}
+
+		header.Set("Cache-Control", cacheControl)
+		etag := header.Get("ETag")
+		if etag == "" && status == http.StatusOK {
+			sum := sha256.Sum256(writer.body.Bytes())
+			etag = `W/"` + hex.EncodeToString(sum[:16]) + `"`
+			header.Set("ETag", etag)
+			if etagMatches(c.GetHeader("If-None-Match"), etag) {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				c.Writer.WriteHeader(http.StatusNotModified)
+				c.Writer.WriteHeaderNow()
+				return
+			}
+		}
+		c.Writer.Write(writer.body.Bytes())
+	}
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing
+// weakly as RFC 9110 asks for GET
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter holds the body back until it can be hashed
+type etagWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// WriteHeaderNow is held back until the body is hashed
+func (w *etagWriter) WriteHeaderNow() {}
@@ -2,9 +2,12 @@ package controllers
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"prophet-trader/interfaces"
 	"prophet-trader/services"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,37 +20,67 @@ type IntelligenceController struct {
 	analysisService      *services.TechnicalAnalysisService
 	stockAnalysisService *services.StockAnalysisService
 	dataService          interfaces.DataService
+	regimeService        *services.MarketRegimeService
+	socialService        *services.SocialSentimentService
+	briefService         *services.MarketBriefService
+	promptStore          *services.PromptStore
+	analysisProfiles     *services.AnalysisProfileService
+	chatService          *services.ChatService
+	llmTools             *services.LLMToolBridge
 }
 
 // NewIntelligenceController creates a new intelligence controller
-func NewIntelligenceController(newsService *services.NewsService, geminiService *services.GeminiService, analysisService *services.TechnicalAnalysisService, stockAnalysisService *services.StockAnalysisService, dataService interfaces.DataService) *IntelligenceController {
+func NewIntelligenceController(newsService *services.NewsService, geminiService *services.GeminiService, analysisService *services.TechnicalAnalysisService, stockAnalysisService *services.StockAnalysisService, dataService interfaces.DataService, regimeService *services.MarketRegimeService, socialService *services.SocialSentimentService) *IntelligenceController {
 	return &IntelligenceController{
 		newsService:          newsService,
 		geminiService:        geminiService,
 		analysisService:      analysisService,
 		stockAnalysisService: stockAnalysisService,
 		dataService:          dataService,
+		regimeService:        regimeService,
+		socialService:        socialService,
 	}
 }
 
+// SetMarketBrief enables the market brief endpoint
+func (ic *IntelligenceController) SetMarketBrief(briefService *services.MarketBriefService) {
+	ic.briefService = briefService
+}
+
+// SetPromptStore enables the prompt template admin endpoints
+func (ic *IntelligenceController) SetPromptStore(promptStore *services.PromptStore) {
+	ic.promptStore = promptStore
+}
+
+// SetAnalysisProfiles enables the analysis profile endpoints
+func (ic *IntelligenceController) SetAnalysisProfiles(analysisProfiles *services.AnalysisProfileService) {
+	ic.analysisProfiles = analysisProfiles
+}
+
+// SetToolBridge enables the LLM tool call audit endpoint
+func (ic *IntelligenceController) SetToolBridge(llmTools *services.LLMToolBridge) {
+	ic.llmTools = llmTools
+}
+
+// SetChatService enables the chat endpoints
+func (ic *IntelligenceController) SetChatService(chatService *services.ChatService) {
+	ic.chatService = chatService
+}
+
 // AggregateNewsRequest represents a request to aggregate news from multiple sources
 type AggregateNewsRequest struct {
 	IncludeGoogle        bool     `json:"include_google"`
 	IncludeMarketWatch   bool     `json:"include_marketwatch"`
-	GoogleTopics         []string `json:"google_topics"`           // BUSINESS, TECHNOLOGY, etc.
-	Symbols              []string `json:"symbols"`                 // Stock symbols to search for
-	MaxArticlesPerSource int      `json:"max_articles_per_source"` // Default 10
+	GoogleTopics         []string `json:"google_topics" binding:"omitempty,max=8,dive,oneof=WORLD NATION BUSINESS TECHNOLOGY ENTERTAINMENT SPORTS SCIENCE HEALTH"`
+	Symbols              []string `json:"symbols" binding:"omitempty,max=20,dive,symbol"`           // Stock symbols to search for
+	MaxArticlesPerSource int      `json:"max_articles_per_source" binding:"omitempty,min=1,max=50"` // Default 10
 }
 
 // HandleGetCleanedNews aggregates news from multiple sources and returns a cleaned summary
 // POST /api/v1/intelligence/cleaned-news
 func (ic *IntelligenceController) HandleGetCleanedNews(c *gin.Context) {
 	var req AggregateNewsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -107,12 +140,10 @@ func (ic *IntelligenceController) HandleGetCleanedNews(c *gin.Context) {
 	}
 
 	// Clean the news using Gemini
-	cleanedNews, err := ic.geminiService.CleanNewsForTrading(allNews)
+	ctx := services.WithPromptEndpoint(c.Request.Context(), "cleaned-news")
+	cleanedNews, err := ic.geminiService.CleanNewsForTrading(ctx, allNews)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to clean news",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to clean news", err)
 		return
 	}
 
@@ -151,12 +182,10 @@ func (ic *IntelligenceController) HandleGetQuickMarketIntelligence(c *gin.Contex
 	}
 
 	// Clean the news
-	cleanedNews, err := ic.geminiService.CleanNewsForTrading(allNews)
+	ctx := services.WithPromptEndpoint(c.Request.Context(), "quick-market")
+	cleanedNews, err := ic.geminiService.CleanNewsForTrading(ctx, allNews)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to generate intelligence",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to generate intelligence", err)
 		return
 	}
 
@@ -168,22 +197,17 @@ func (ic *IntelligenceController) HandleGetQuickMarketIntelligence(c *gin.Contex
 func (ic *IntelligenceController) HandleAnalyzeStock(c *gin.Context) {
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "symbol required",
-		})
+		respondBadRequest(c, "symbol required", nil)
 		return
 	}
 
 	// Add timeout to prevent indefinite hangs
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
 	analysis, err := ic.stockAnalysisService.AnalyzeStock(ctx, symbol)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to analyze stock",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to analyze stock", err)
 		return
 	}
 
@@ -192,38 +216,29 @@ func (ic *IntelligenceController) HandleAnalyzeStock(c *gin.Context) {
 
 // AnalyzeStocksRequest represents a request to analyze multiple stocks
 type AnalyzeStocksRequest struct {
-	Symbols []string `json:"symbols" binding:"required"`
+	Symbols []string `json:"symbols" binding:"required,min=1,max=20,dive,symbol"`
 }
 
 // HandleAnalyzeMultipleStocks provides comprehensive analysis for multiple stocks
 // POST /api/v1/intelligence/analyze-multiple
 func (ic *IntelligenceController) HandleAnalyzeMultipleStocks(c *gin.Context) {
 	var req AnalyzeStocksRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	if len(req.Symbols) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "At least one symbol required",
-		})
+		respondBadRequest(c, "At least one symbol required", nil)
 		return
 	}
 
 	// Add timeout to prevent indefinite hangs
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
 	defer cancel()
 
 	analyses, err := ic.stockAnalysisService.AnalyzeStocks(ctx, req.Symbols)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to analyze stocks",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to analyze stocks", err)
 		return
 	}
 
@@ -239,3 +254,252 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+// HandleGetMarketRegime returns the market regime (risk-on, neutral or
+// risk-off) with its trend, breadth and volatility inputs
+// GET /api/v1/intelligence/regime
+func (ic *IntelligenceController) HandleGetMarketRegime(c *gin.Context) {
+	regime, err := ic.regimeService.Current(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, "Failed to determine market regime", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, regime)
+}
+
+// HandleGetSocialSentiment returns social media mentions and sentiment for a
+// symbol over 1h, 24h and 7d windows
+// GET /api/v1/intelligence/social/:symbol
+func (ic *IntelligenceController) HandleGetSocialSentiment(c *gin.Context) {
+	sentiment, err := ic.socialService.Get(c.Request.Context(), c.Param("symbol"))
+	if err != nil {
+		respondServiceError(c, "Failed to get social sentiment", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sentiment)
+}
+
+// HandleGetMarketBrief returns the rolling market-wide and per-symbol news
+// summaries. It never calls the LLM; the brief is revised in the background.
+// GET /api/v1/intelligence/brief
+func (ic *IntelligenceController) HandleGetMarketBrief(c *gin.Context) {
+	if ic.briefService == nil {
+		respondError(c, services.ErrCodeUnavailable, "market brief not enabled", "")
+		return
+	}
+
+	c.JSON(http.StatusOK, ic.briefService.Brief())
+}
+
+// PromptTemplateRequest saves a new version of a prompt template
+type PromptTemplateRequest struct {
+	Template string `json:"template" binding:"required,max=20000"`
+	Note     string `json:"note" binding:"max=500"`
+}
+
+// PromptRollbackRequest selects the version to restore. Without a version
+// the one before the active version is restored; 0 restores the file or
+// built-in template.
+type PromptRollbackRequest struct {
+	Version *int `json:"version" binding:"omitempty,gte=0"`
+}
+
+// HandleListPrompts lists the prompt templates in use
+// GET /api/v1/admin/prompts
+func (ic *IntelligenceController) HandleListPrompts(c *gin.Context) {
+	prompts := ic.promptStore.List()
+
+	c.JSON(http.StatusOK, gin.H{
+		"prompts": prompts,
+		"count":   len(prompts),
+	})
+}
+
+// HandleGetPrompt returns a prompt template with its saved versions
+// GET /api/v1/admin/prompts/:name
+func (ic *IntelligenceController) HandleGetPrompt(c *gin.Context) {
+	prompt, err := ic.promptStore.Get(c.Param("name"))
+	if err != nil {
+		respondServiceError(c, "Failed to get prompt template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
+
+// HandleSavePrompt saves and activates a new version of a prompt template.
+// Name may be "name@endpoint" to override the prompt for one endpoint.
+// PUT /api/v1/admin/prompts/:name
+func (ic *IntelligenceController) HandleSavePrompt(c *gin.Context) {
+	var req PromptTemplateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	author := "ip:" + c.ClientIP()
+	if principal, ok := c.Get(principalKey); ok {
+		author = "token:" + principal.(*services.Principal).Name
+	}
+
+	prompt, err := ic.promptStore.Save(c.Param("name"), req.Template, req.Note, author)
+	if err != nil {
+		respondServiceError(c, "Failed to save prompt template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
+
+// HandleRollbackPrompt restores an earlier version of a prompt template
+// POST /api/v1/admin/prompts/:name/rollback
+func (ic *IntelligenceController) HandleRollbackPrompt(c *gin.Context) {
+	var req PromptRollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		respondBindError(c, err)
+		return
+	}
+
+	prompt, err := ic.promptStore.Rollback(c.Param("name"), req.Version)
+	if err != nil {
+		respondServiceError(c, "Failed to roll back prompt template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
+
+// HandleListAnalysisProfiles lists the stock analysis profiles. With
+// ?symbol= it also returns the profile that symbol is analysed with.
+// GET /api/v1/intelligence/profiles
+func (ic *IntelligenceController) HandleListAnalysisProfiles(c *gin.Context) {
+	profiles := ic.analysisProfiles.ListProfiles()
+	response := gin.H{
+		"profiles": profiles,
+		"count":    len(profiles),
+	}
+	if symbol := strings.ToUpper(c.Query("symbol")); symbol != "" {
+		response["applied"] = ic.analysisProfiles.ProfileFor(symbol)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleSaveAnalysisProfile creates or replaces an analysis profile
+// PUT /api/v1/intelligence/profiles/:name
+func (ic *IntelligenceController) HandleSaveAnalysisProfile(c *gin.Context) {
+	var req services.AnalysisProfileRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	profile, err := ic.analysisProfiles.SaveProfile(c.Param("name"), &req)
+	if err != nil {
+		respondServiceError(c, "Failed to save analysis profile", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// HandleDeleteAnalysisProfile deletes an analysis profile
+// DELETE /api/v1/intelligence/profiles/:name
+func (ic *IntelligenceController) HandleDeleteAnalysisProfile(c *gin.Context) {
+	if err := ic.analysisProfiles.DeleteProfile(c.Param("name")); err != nil {
+		respondServiceError(c, "Failed to delete analysis profile", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Analysis profile deleted",
+	})
+}
+
+// ChatRequest is one message to the intelligence chat. Omit session_id to
+// start a new conversation.
+type ChatRequest struct {
+	SessionID string `json:"session_id" binding:"omitempty,max=64"`
+	Message   string `json:"message" binding:"required,max=2000"`
+}
+
+// chatOwner names the caller a chat session belongs to
+func chatOwner(c *gin.Context) string {
+	if principal, ok := c.Get(principalKey); ok {
+		return principal.(*services.Principal).Name
+	}
+	return ""
+}
+
+// HandleChat answers a chat message with the current portfolio, open orders
+// and recent analyses as context
+// POST /api/v1/intelligence/chat
+func (ic *IntelligenceController) HandleChat(c *gin.Context) {
+	var req ChatRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	reply, err := ic.chatService.Chat(ctx, req.SessionID, chatOwner(c), req.Message)
+	if err != nil {
+		respondServiceError(c, "Failed to answer chat message", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reply)
+}
+
+// HandleGetChatSession returns a chat session's messages
+// GET /api/v1/intelligence/chat/:session_id
+func (ic *IntelligenceController) HandleGetChatSession(c *gin.Context) {
+	session, err := ic.chatService.GetSession(c.Param("session_id"), chatOwner(c))
+	if err != nil {
+		respondServiceError(c, "Failed to get chat session", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// HandleDeleteChatSession ends a chat session
+// DELETE /api/v1/intelligence/chat/:session_id
+func (ic *IntelligenceController) HandleDeleteChatSession(c *gin.Context) {
+	if err := ic.chatService.DeleteSession(c.Param("session_id"), chatOwner(c)); err != nil {
+		respondServiceError(c, "Failed to delete chat session", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Chat session deleted",
+	})
+}
+
+// HandleListToolCalls lists the latest data tool calls made by the LLM
+// GET /api/v1/admin/llm/tool-calls?limit=100
+func (ic *IntelligenceController) HandleListToolCalls(c *gin.Context) {
+	if ic.llmTools == nil {
+		respondError(c, services.ErrCodeUnavailable, "LLM tools not enabled", "")
+		return
+	}
+
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = min(l, 1000)
+		}
+	}
+
+	calls, err := ic.llmTools.RecentCalls(limit)
+	if err != nil {
+		respondServiceError(c, "Failed to get LLM tool calls", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"calls": calls,
+		"count": len(calls),
+	})
+}
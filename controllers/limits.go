@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"prophet-trader/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rate limit names used by the router
+const (
+	RateLimitDefault      = "default"
+	RateLimitOrders       = "orders"
+	RateLimitIntelligence = "intelligence"
+)
+
+// RateLimit rejects callers that exceed the named limit with 429 and a
+// Retry-After header. Callers are keyed by API token when authenticated and
+// by client IP otherwise, so it must run after AuthController.Authenticate.
+func RateLimit(limiter *services.RateLimiter, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, enabled := limiter.Limit(name)
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		caller := "ip:" + c.ClientIP()
+		if principal, ok := c.Get(principalKey); ok {
+			caller = "token:" + principal.(*services.Principal).TokenID
+		}
+
+		allowed, remaining, wait := limiter.Allow(name, caller)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.PerMinute))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if !allowed {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			abortWithError(c, services.ErrCodeRateLimited, "Rate limit exceeded",
+				fmt.Sprintf("%s limit is %d requests per minute, retry in %ds", name, limit.PerMinute, retryAfter))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// MaxBodySize rejects request bodies larger than maxBytes with 413. Bodies
+// without a declared length are cut off at maxBytes while being read.
+func MaxBodySize(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			abortWithError(c, services.ErrCodeRequestTooLarge, "Request body too large",
+				fmt.Sprintf("maximum request body size is %d bytes", maxBytes))
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
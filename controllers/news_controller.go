@@ -4,13 +4,16 @@ import (
 	"net/http"
 	"prophet-trader/services"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // NewsController handles news-related HTTP requests
 type NewsController struct {
-	newsService *services.NewsService
+	newsService   *services.NewsService
+	semanticIndex *services.SemanticIndex
 }
 
 // NewNewsController creates a new news controller
@@ -20,30 +23,71 @@ func NewNewsController(newsService *services.NewsService) *NewsController {
 	}
 }
 
-// HandleGetNews fetches the latest news
-// GET /api/v1/news?limit=10
+// SetSemanticIndex enables semantic search
+func (nc *NewsController) SetSemanticIndex(semanticIndex *services.SemanticIndex) {
+	nc.semanticIndex = semanticIndex
+}
+
+// HandleGetNews fetches the latest news. since_seq returns only the items
+// stored after the latest_seq of an earlier response.
+// GET /api/v1/news?limit=10&symbol=AAPL&sort=-published_at&since_seq=1200
 func (nc *NewsController) HandleGetNews(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "20")
-	limit, err := strconv.Atoi(limitStr)
+	query, err := parseListQuery(c, newsListSpec, 20)
 	if err != nil {
-		limit = 20
+		respondBadRequest(c, "Invalid list parameters", err)
+		return
+	}
+	var sinceSeq uint64
+	if raw := c.Query("since_seq"); raw != "" {
+		if sinceSeq, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			respondBadRequest(c, "since_seq must be a non-negative integer", nil)
+			return
+		}
 	}
 
-	news, err := nc.newsService.GetLatestNews(limit)
+	news, err := nc.newsService.GetLatestNews(0)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch news",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to fetch news", err)
 		return
 	}
 
+	latestSeq := sinceSeq
+	for _, item := range news {
+		latestSeq = max(latestSeq, item.Seq)
+	}
+
+	// Headlines carry no symbol field, so match tickers in the text
+	news = nc.newsService.FilterNewsByKeywords(news, query.Symbols)
+	if sinceSeq > 0 {
+		fresh := make([]services.NewsItem, 0, len(news))
+		for _, item := range news {
+			if item.Seq > sinceSeq {
+				fresh = append(fresh, item)
+			}
+		}
+		news = fresh
+	}
+	page, info := paginate(news, query, newsListSpec)
+
 	c.JSON(http.StatusOK, gin.H{
-		"count": len(news),
-		"news":  news,
+		"count":      len(page),
+		"news":       page,
+		"pagination": info,
+		"latest_seq": latestSeq,
 	})
 }
 
+// newsListSpec defines the filters and sorts of GET /news
+var newsListSpec = listSpec[services.NewsItem]{
+	id:   func(item services.NewsItem) string { return item.Link },
+	time: func(item services.NewsItem) time.Time { return item.PublishedAt },
+	sorts: map[string]func(services.NewsItem) string{
+		"published_at": func(item services.NewsItem) string { return sortTimeKey(item.PublishedAt) },
+		"source":       func(item services.NewsItem) string { return item.Source },
+	},
+	defaultSort: "-published_at",
+}
+
 // HandleGetNewsByTopic fetches news for a specific topic
 // GET /api/v1/news/topic/:topic?compact=true
 // Topics: WORLD, NATION, BUSINESS, TECHNOLOGY, ENTERTAINMENT, SPORTS, SCIENCE, HEALTH
@@ -53,10 +97,7 @@ func (nc *NewsController) HandleGetNewsByTopic(c *gin.Context) {
 
 	news, err := nc.newsService.GetGoogleNewsByTopic(topic)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch topic news",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to fetch topic news", err)
 		return
 	}
 
@@ -91,9 +132,7 @@ func (nc *NewsController) HandleGetNewsByTopic(c *gin.Context) {
 func (nc *NewsController) HandleSearchNews(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Query parameter 'q' is required",
-		})
+		respondBadRequest(c, "Query parameter 'q' is required", nil)
 		return
 	}
 
@@ -105,10 +144,7 @@ func (nc *NewsController) HandleSearchNews(c *gin.Context) {
 
 	news, err := nc.newsService.GetGoogleNewsSearch(query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to search news",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to search news", err)
 		return
 	}
 
@@ -124,6 +160,49 @@ func (nc *NewsController) HandleSearchNews(c *gin.Context) {
 	})
 }
 
+// HandleSemanticSearch finds stored news and analyses with a similar meaning
+// to the query
+// GET /api/v1/news/semantic-search?q=chip+export+limits&kind=news&symbol=NVDA&limit=10
+func (nc *NewsController) HandleSemanticSearch(c *gin.Context) {
+	if nc.semanticIndex == nil {
+		respondError(c, services.ErrCodeUnavailable, "semantic search not enabled", "")
+		return
+	}
+
+	query := services.SemanticQuery{
+		Text:   c.Query("q"),
+		Kind:   c.Query("kind"),
+		Symbol: strings.ToUpper(c.Query("symbol")),
+	}
+	if query.Text == "" {
+		respondBadRequest(c, "Query parameter 'q' is required", nil)
+		return
+	}
+	if query.Kind != "" && query.Kind != services.SemanticKindNews && query.Kind != services.SemanticKindAnalysis {
+		respondBadRequest(c, "kind must be news or analysis", nil)
+		return
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			query.Limit = l
+		}
+	}
+
+	matches, err := nc.semanticIndex.Search(c.Request.Context(), query)
+	if err != nil {
+		respondServiceError(c, "Failed to search news", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":    query.Text,
+		"provider": nc.semanticIndex.Provider(),
+		"indexed":  nc.semanticIndex.Size(),
+		"count":    len(matches),
+		"results":  matches,
+	})
+}
+
 // HandleGetMarketNews fetches market-related news
 // GET /api/v1/news/market?symbols=TSLA,NVDA,AAPL
 func (nc *NewsController) HandleGetMarketNews(c *gin.Context) {
@@ -132,10 +211,7 @@ func (nc *NewsController) HandleGetMarketNews(c *gin.Context) {
 		// Default to general market news
 		news, err := nc.newsService.GetGoogleNewsByTopic("BUSINESS")
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to fetch market news",
-				"details": err.Error(),
-			})
+			respondServiceError(c, "Failed to fetch market news", err)
 			return
 		}
 
@@ -149,10 +225,7 @@ func (nc *NewsController) HandleGetMarketNews(c *gin.Context) {
 	// Search for specific symbols
 	news, err := nc.newsService.GetGoogleNewsSearch(symbols)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch symbol news",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to fetch symbol news", err)
 		return
 	}
 
@@ -168,10 +241,7 @@ func (nc *NewsController) HandleGetMarketNews(c *gin.Context) {
 func (nc *NewsController) HandleGetMarketWatchTopStories(c *gin.Context) {
 	news, err := nc.newsService.GetMarketWatchTopStories()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to fetch MarketWatch top stories",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to fetch MarketWatch top stories", err)
 		return
 	}
 
@@ -187,10 +257,7 @@ func (nc *NewsController) HandleGetMarketWatchTopStories(c *gin.Context) {
 func (nc *NewsController) HandleGetMarketWatchRealtimeHeadlines(c *gin.Context) {
 	news, err := nc.newsService.GetMarketWatchRealtimeHeadlines()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to fetch MarketWatch realtime headlines",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to fetch MarketWatch realtime headlines", err)
 		return
 	}
 
@@ -206,10 +273,7 @@ func (nc *NewsController) HandleGetMarketWatchRealtimeHeadlines(c *gin.Context)
 func (nc *NewsController) HandleGetMarketWatchBulletins(c *gin.Context) {
 	news, err := nc.newsService.GetMarketWatchBulletins()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to fetch MarketWatch bulletins",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to fetch MarketWatch bulletins", err)
 		return
 	}
 
@@ -225,10 +289,7 @@ func (nc *NewsController) HandleGetMarketWatchBulletins(c *gin.Context) {
 func (nc *NewsController) HandleGetMarketWatchMarketPulse(c *gin.Context) {
 	news, err := nc.newsService.GetMarketWatchMarketPulse()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to fetch MarketWatch market pulse",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to fetch MarketWatch market pulse", err)
 		return
 	}
 
@@ -244,10 +305,7 @@ func (nc *NewsController) HandleGetMarketWatchMarketPulse(c *gin.Context) {
 func (nc *NewsController) HandleGetAllMarketWatchNews(c *gin.Context) {
 	news, err := nc.newsService.GetAllMarketWatchNews()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to fetch all MarketWatch news",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to fetch all MarketWatch news", err)
 		return
 	}
 
@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"prophet-trader/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ObserverMode rejects trading requests with 403 while observer mode is on.
+// It goes on every route that places, cancels or schedules orders.
+func ObserverMode(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if enabled {
+			abortWithError(c, services.ErrCodeForbidden, "Forbidden", services.ErrObserverMode.Error())
+			return
+		}
+		c.Next()
+	}
+}
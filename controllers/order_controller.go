@@ -2,28 +2,55 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"math"
+	"net/http"
 	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"prophet-trader/services"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 )
 
 // OrderController handles trading operations
 type OrderController struct {
-	tradingService interfaces.TradingService
-	dataService    interfaces.DataService
-	storageService interfaces.StorageService
-	logger         *logrus.Logger
+	tradingService  interfaces.TradingService
+	dataService     interfaces.DataService
+	storageService  interfaces.StorageService
+	riskManager     *services.RiskManager
+	auditor         *services.OrderAuditor
+	orderQueue      *services.OrderQueue
+	queueClosed     bool // queue orders placed while the market is closed
+	optionsChains   *services.OptionsChainCache
+	optionsGreeks   *services.OptionsExposureService
+	coveredCalls    *services.CoveredCallMonitor
+	optionsAnalyzer *services.OptionsAnalyzer
+	barAggregator   *services.BarAggregator
+	marketHours     *services.MarketHours
+	fills           *services.FillTracker
+	orderChaser     *services.OrderChaser
+	baskets         *services.BasketService
+	dryRun          bool
+	logger          *logrus.Logger
 }
 
-// NewOrderController creates a new order controller
+// NewOrderController creates a new order controller.
+// When dryRun is true no order is ever submitted to the broker.
 func NewOrderController(
 	trading interfaces.TradingService,
 	data interfaces.DataService,
 	storage interfaces.StorageService,
+	riskManager *services.RiskManager,
+	auditor *services.OrderAuditor,
+	dryRun bool,
 ) *OrderController {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.TextFormatter{
@@ -34,32 +61,118 @@ func NewOrderController(
 		tradingService: trading,
 		dataService:    data,
 		storageService: storage,
+		riskManager:    riskManager,
+		auditor:        auditor,
+		dryRun:         dryRun,
 		logger:         logger,
 	}
 }
 
+// SetOrderQueue holds scheduled orders, and with queueClosed every order
+// placed while the market is closed, in the local queue
+func (oc *OrderController) SetOrderQueue(queue *services.OrderQueue, queueClosed bool) {
+	oc.orderQueue = queue
+	oc.queueClosed = queueClosed
+}
+
+// SetOptionsChainCache serves options chains from cache, with ETag and
+// Last-Modified validators so unchanged chains answer 304
+func (oc *OrderController) SetOptionsChainCache(cache *services.OptionsChainCache) {
+	oc.optionsChains = cache
+}
+
+// SetOptionsExposure enriches options positions with their greeks and
+// serves the aggregated greek exposure
+func (oc *OrderController) SetOptionsExposure(exposure *services.OptionsExposureService) {
+	oc.optionsGreeks = exposure
+}
+
+// SetCoveredCalls serves the covered calls held and their recent
+// assignments and expirations
+func (oc *OrderController) SetCoveredCalls(monitor *services.CoveredCallMonitor) {
+	oc.coveredCalls = monitor
+}
+
+// SetOptionsAnalyzer serves expected moves and the probability of profit
+// of proposed options structures
+func (oc *OrderController) SetOptionsAnalyzer(analyzer *services.OptionsAnalyzer) {
+	oc.optionsAnalyzer = analyzer
+}
+
+// SetBarAggregator serves the custom bars built live from streamed trades
+func (oc *OrderController) SetBarAggregator(aggregator *services.BarAggregator) {
+	oc.barAggregator = aggregator
+}
+
+// SetMarketHours serves the current trading session of each asset class
+func (oc *OrderController) SetMarketHours(hours *services.MarketHours) {
+	oc.marketHours = hours
+}
+
+// SetFillTracker adds each order's fill timeline to its audit trail
+func (oc *OrderController) SetFillTracker(fills *services.FillTracker) {
+	oc.fills = fills
+}
+
+// SetOrderChaser accepts pegged orders, which follow the quote until they
+// fill
+func (oc *OrderController) SetOrderChaser(chaser *services.OrderChaser) {
+	oc.orderChaser = chaser
+}
+
+// SetBasketService accepts baskets of orders submitted together
+func (oc *OrderController) SetBasketService(baskets *services.BasketService) {
+	oc.baskets = baskets
+}
+
 // BuyRequest represents a buy order request
+// Qty and prices accept either JSON numbers or strings ("0.125")
 type BuyRequest struct {
-	Symbol      string   `json:"symbol" binding:"required"`
-	Qty         float64  `json:"qty" binding:"required,gt=0"`
-	Type        string   `json:"type"` // "market", "limit", "stop", "stop_limit"
-	TimeInForce string   `json:"time_in_force"` // "day", "gtc", "ioc", "fok"
-	LimitPrice  *float64 `json:"limit_price,omitempty"`
-	StopPrice   *float64 `json:"stop_price,omitempty"`
+	Symbol      string           `json:"symbol" binding:"required,symbol"`
+	Qty         decimal.Decimal  `json:"qty" binding:"gt=0"`
+	Type        string           `json:"type" binding:"omitempty,oneof=market limit stop stop_limit"`
+	TimeInForce string           `json:"time_in_force" binding:"omitempty,oneof=day gtc ioc fok opg cls"`
+	LimitPrice  *decimal.Decimal `json:"limit_price,omitempty" binding:"omitempty,gt=0"` // required for limit and stop_limit
+	StopPrice   *decimal.Decimal `json:"stop_price,omitempty" binding:"omitempty,gt=0"`  // required for stop and stop_limit
+	Strategy    string           `json:"strategy,omitempty" binding:"omitempty,max=64"`  // P&L attribution tag, defaults to the order source
+	Tags        []string          `json:"tags,omitempty" binding:"omitempty,max=16,dive,min=1,max=64"`
+	Metadata    map[string]string `json:"metadata,omitempty" binding:"omitempty,max=32,dive,keys,min=1,max=64,endkeys,max=256"` // e.g. signal_id, echoed on listings and audits
+	SubmitAt    *time.Time       `json:"submit_at,omitempty"`                            // queue the order until this time, then submit once the market is open
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`                           // good-till-date: a queued order still unsubmitted then expires
+	Peg         *services.PegRequest `json:"peg,omitempty"`                              // place a limit at the quote and re-peg it as the quote moves
+	DryRun      bool             `json:"dry_run"`
 }
 
 // SellRequest represents a sell order request
 type SellRequest struct {
-	Symbol      string   `json:"symbol" binding:"required"`
-	Qty         float64  `json:"qty" binding:"required,gt=0"`
-	Type        string   `json:"type"` // "market", "limit", "stop", "stop_limit"
-	TimeInForce string   `json:"time_in_force"` // "day", "gtc", "ioc", "fok"
-	LimitPrice  *float64 `json:"limit_price,omitempty"`
-	StopPrice   *float64 `json:"stop_price,omitempty"`
+	Symbol      string           `json:"symbol" binding:"required,symbol"`
+	Qty         decimal.Decimal  `json:"qty" binding:"gt=0"`
+	Type        string           `json:"type" binding:"omitempty,oneof=market limit stop stop_limit"`
+	TimeInForce string           `json:"time_in_force" binding:"omitempty,oneof=day gtc ioc fok opg cls"`
+	LimitPrice  *decimal.Decimal `json:"limit_price,omitempty" binding:"omitempty,gt=0"` // required for limit and stop_limit
+	StopPrice   *decimal.Decimal `json:"stop_price,omitempty" binding:"omitempty,gt=0"`  // required for stop and stop_limit
+	Strategy    string           `json:"strategy,omitempty" binding:"omitempty,max=64"`  // P&L attribution tag, defaults to the order source
+	Tags        []string          `json:"tags,omitempty" binding:"omitempty,max=16,dive,min=1,max=64"`
+	Metadata    map[string]string `json:"metadata,omitempty" binding:"omitempty,max=32,dive,keys,min=1,max=64,endkeys,max=256"` // e.g. signal_id, echoed on listings and audits
+	SubmitAt    *time.Time       `json:"submit_at,omitempty"`                            // queue the order until this time, then submit once the market is open
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`                           // good-till-date: a queued order still unsubmitted then expires
+	Peg         *services.PegRequest `json:"peg,omitempty"`                              // place a limit at the quote and re-peg it as the quote moves
+	DryRun      bool             `json:"dry_run"`
+}
+
+// OrderResponse is the broker result, or in dry-run mode the request that
+// would have been sent to Alpaca
+type OrderResponse struct {
+	*interfaces.OrderResult
+	DryRun  bool                      `json:"dry_run,omitempty"`
+	Request *alpaca.PlaceOrderRequest `json:"request,omitempty"`
+	Risk    *services.RiskDecision    `json:"risk,omitempty"`
+	Queued  *services.QueuedOrder     `json:"queued,omitempty"`
+	Chase   *services.OrderChase      `json:"chase,omitempty"`
 }
 
 // Buy executes a buy order
-func (oc *OrderController) Buy(ctx context.Context, req BuyRequest) (*interfaces.OrderResult, error) {
+func (oc *OrderController) Buy(ctx context.Context, req BuyRequest) (*OrderResponse, error) {
 	// Set defaults
 	if req.Type == "" {
 		req.Type = "market"
@@ -70,7 +183,7 @@ func (oc *OrderController) Buy(ctx context.Context, req BuyRequest) (*interfaces
 
 	oc.logger.WithFields(logrus.Fields{
 		"symbol": req.Symbol,
-		"qty":    req.Qty,
+		"qty":    req.Qty.String(),
 		"type":   req.Type,
 	}).Info("Processing buy order")
 
@@ -84,10 +197,39 @@ func (oc *OrderController) Buy(ctx context.Context, req BuyRequest) (*interfaces
 		StopPrice:   req.StopPrice,
 		Status:      "pending",
 		SubmittedAt: time.Now(),
+		Strategy:    strategyTag(ctx, req.Strategy),
+		Book:        services.BookFrom(ctx),
+		Tags:        req.Tags,
+		Metadata:    req.Metadata,
+	}
+
+	if req.Peg != nil {
+		if err := oc.pegOrder(ctx, order, req.Peg, req.SubmitAt); err != nil {
+			return nil, err
+		}
+	}
+
+	// Scheduled and after-hours orders are risk-checked when they are submitted
+	queue := oc.shouldQueue(ctx, order, req.SubmitAt)
+	if err := checkOrderExpiry(req.ExpiresAt, queue); err != nil {
+		return nil, err
+	}
+	if !oc.dryRun && !req.DryRun && queue {
+		return oc.queueOrder(ctx, order, req.SubmitAt, req.ExpiresAt)
+	}
+
+	// Run risk checks
+	decision, err := oc.checkRisk(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	if oc.dryRun || req.DryRun {
+		return oc.dryRunResponse(ctx, order, decision), nil
 	}
 
 	// Place the order
-	result, err := oc.tradingService.PlaceOrder(ctx, order)
+	result, err := oc.tradingService.PlaceOrder(services.WithRiskDecision(ctx, decision), order)
 	if err != nil {
 		oc.logger.WithError(err).Error("Failed to place buy order")
 		return nil, err
@@ -101,11 +243,15 @@ func (oc *OrderController) Buy(ctx context.Context, req BuyRequest) (*interfaces
 	}
 
 	oc.logger.WithField("orderID", result.OrderID).Info("Buy order placed successfully")
-	return result, nil
+	resp := &OrderResponse{OrderResult: result, Risk: decision}
+	if req.Peg != nil {
+		resp.Chase = oc.orderChaser.Chase(services.WithRiskDecision(ctx, decision), order, req.Peg)
+	}
+	return resp, nil
 }
 
 // Sell executes a sell order
-func (oc *OrderController) Sell(ctx context.Context, req SellRequest) (*interfaces.OrderResult, error) {
+func (oc *OrderController) Sell(ctx context.Context, req SellRequest) (*OrderResponse, error) {
 	// Set defaults
 	if req.Type == "" {
 		req.Type = "market"
@@ -116,7 +262,7 @@ func (oc *OrderController) Sell(ctx context.Context, req SellRequest) (*interfac
 
 	oc.logger.WithFields(logrus.Fields{
 		"symbol": req.Symbol,
-		"qty":    req.Qty,
+		"qty":    req.Qty.String(),
 		"type":   req.Type,
 	}).Info("Processing sell order")
 
@@ -130,10 +276,39 @@ func (oc *OrderController) Sell(ctx context.Context, req SellRequest) (*interfac
 		StopPrice:   req.StopPrice,
 		Status:      "pending",
 		SubmittedAt: time.Now(),
+		Strategy:    strategyTag(ctx, req.Strategy),
+		Book:        services.BookFrom(ctx),
+		Tags:        req.Tags,
+		Metadata:    req.Metadata,
+	}
+
+	if req.Peg != nil {
+		if err := oc.pegOrder(ctx, order, req.Peg, req.SubmitAt); err != nil {
+			return nil, err
+		}
+	}
+
+	// Scheduled and after-hours orders are risk-checked when they are submitted
+	queue := oc.shouldQueue(ctx, order, req.SubmitAt)
+	if err := checkOrderExpiry(req.ExpiresAt, queue); err != nil {
+		return nil, err
+	}
+	if !oc.dryRun && !req.DryRun && queue {
+		return oc.queueOrder(ctx, order, req.SubmitAt, req.ExpiresAt)
+	}
+
+	// Run risk checks
+	decision, err := oc.checkRisk(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	if oc.dryRun || req.DryRun {
+		return oc.dryRunResponse(ctx, order, decision), nil
 	}
 
 	// Place the order
-	result, err := oc.tradingService.PlaceOrder(ctx, order)
+	result, err := oc.tradingService.PlaceOrder(services.WithRiskDecision(ctx, decision), order)
 	if err != nil {
 		oc.logger.WithError(err).Error("Failed to place sell order")
 		return nil, err
@@ -147,11 +322,138 @@ func (oc *OrderController) Sell(ctx context.Context, req SellRequest) (*interfac
 	}
 
 	oc.logger.WithField("orderID", result.OrderID).Info("Sell order placed successfully")
-	return result, nil
+	resp := &OrderResponse{OrderResult: result, Risk: decision}
+	if req.Peg != nil {
+		resp.Chase = oc.orderChaser.Chase(services.WithRiskDecision(ctx, decision), order, req.Peg)
+	}
+	return resp, nil
+}
+
+// strategyTag returns the attribution label for an order, falling back to its source
+func strategyTag(ctx context.Context, strategy string) string {
+	if strategy != "" {
+		return strategy
+	}
+	return services.OrderSourceFrom(ctx)
+}
+
+// pegOrder turns an order into a limit at its pegged price. Pegged orders
+// follow the live quote, so they are never queued.
+func (oc *OrderController) pegOrder(ctx context.Context, order *interfaces.Order, peg *services.PegRequest, submitAt *time.Time) error {
+	if oc.orderChaser == nil {
+		return services.WithErrorCode(services.ErrCodeNotSupported, errors.New("pegged orders are not available"))
+	}
+	if (order.Type != "market" && order.Type != "limit") || order.LimitPrice != nil {
+		return services.WithErrorCode(services.ErrCodeInvalidRequest, errors.New("pegged orders set their own limit price; leave type and limit_price unset"))
+	}
+	if _, err := services.ParseOCCSymbol(order.Symbol); err == nil {
+		return services.WithErrorCode(services.ErrCodeNotSupported, errors.New("options orders can't be pegged"))
+	}
+	if submitAt != nil || oc.shouldQueue(ctx, order, nil) {
+		return services.WithErrorCode(services.ErrCodeMarketClosed, errors.New("pegged orders can't be queued; place them while the market is open"))
+	}
+
+	price, err := oc.orderChaser.PegPrice(ctx, order.Symbol, order.Side, peg)
+	if err != nil {
+		return err
+	}
+	order.Type = "limit"
+	order.LimitPrice = &price
+	return nil
+}
+
+// shouldQueue reports whether an order goes to the local queue instead of
+// straight to the broker
+func (oc *OrderController) shouldQueue(ctx context.Context, order *interfaces.Order, submitAt *time.Time) bool {
+	if submitAt != nil {
+		return true
+	}
+	// Opening and closing auction orders are meant to reach the broker early
+	if oc.orderQueue == nil || !oc.queueClosed || order.TimeInForce == "opg" || order.TimeInForce == "cls" {
+		return false
+	}
+	return !oc.orderQueue.MarketOpen(ctx)
+}
+
+// checkOrderExpiry rejects a good-till-date on an order that goes straight
+// to the broker. Only the local queue expires orders, so the date would
+// otherwise be dropped.
+func checkOrderExpiry(expiresAt *time.Time, queued bool) error {
+	if expiresAt == nil || queued {
+		return nil
+	}
+	return services.WithErrorCode(services.ErrCodeInvalidRequest, errors.New("expires_at only applies to queued orders and this one would be sent straight to the broker; use submit_at to queue it, or drop expires_at"))
+}
+
+// queueOrder holds an order in the local queue
+func (oc *OrderController) queueOrder(ctx context.Context, order *interfaces.Order, submitAt, expiresAt *time.Time) (*OrderResponse, error) {
+	if oc.orderQueue == nil {
+		return nil, services.WithErrorCode(services.ErrCodeNotSupported, errors.New("order queue is not available"))
+	}
+
+	queued, err := oc.orderQueue.Enqueue(ctx, order, submitAt, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	message := "Order queued for submission at the next market open"
+	if submitAt != nil {
+		message = "Order queued for submission at " + submitAt.Format(time.RFC3339) + " or the next market open after it"
+	}
+	if expiresAt != nil {
+		message += ", expiring at " + expiresAt.Format(time.RFC3339) + " if still unsubmitted"
+	}
+	return &OrderResponse{
+		OrderResult: &interfaces.OrderResult{
+			Status:  "queued",
+			Message: message,
+		},
+		Queued: queued,
+	}, nil
+}
+
+// checkRisk evaluates an order and returns a RiskRejectedError if any rule fails
+func (oc *OrderController) checkRisk(ctx context.Context, order *interfaces.Order) (*services.RiskDecision, error) {
+	if oc.riskManager == nil {
+		return nil, nil
+	}
+
+	decision, err := oc.riskManager.Evaluate(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	if !decision.Approved {
+		rejected := &services.RiskRejectedError{Decision: decision}
+		oc.auditor.RecordUnsubmitted(ctx, order, services.AuditOutcomeRejected, decision, rejected)
+		return nil, rejected
+	}
+	return decision, nil
+}
+
+// dryRunResponse reports the request that would have been submitted
+func (oc *OrderController) dryRunResponse(ctx context.Context, order *interfaces.Order, decision *services.RiskDecision) *OrderResponse {
+	req := services.BuildOrderRequest(order)
+	oc.auditor.RecordUnsubmitted(ctx, order, services.AuditOutcomeDryRun, decision, nil)
+
+	oc.logger.WithFields(logrus.Fields{
+		"symbol": order.Symbol,
+		"side":   order.Side,
+		"qty":    order.Qty.String(),
+	}).Info("Dry run: order not submitted")
+
+	return &OrderResponse{
+		OrderResult: &interfaces.OrderResult{
+			Status:  "dry_run",
+			Message: "Dry run: order validated but not submitted",
+		},
+		DryRun:  true,
+		Request: &req,
+		Risk:    decision,
+	}
 }
 
 // QuickBuy executes a simple market buy order
-func (oc *OrderController) QuickBuy(symbol string, qty float64) (*interfaces.OrderResult, error) {
+func (oc *OrderController) QuickBuy(symbol string, qty decimal.Decimal) (*OrderResponse, error) {
 	return oc.Buy(context.Background(), BuyRequest{
 		Symbol: symbol,
 		Qty:    qty,
@@ -160,7 +462,7 @@ func (oc *OrderController) QuickBuy(symbol string, qty float64) (*interfaces.Ord
 }
 
 // QuickSell executes a simple market sell order
-func (oc *OrderController) QuickSell(symbol string, qty float64) (*interfaces.OrderResult, error) {
+func (oc *OrderController) QuickSell(symbol string, qty decimal.Decimal) (*OrderResponse, error) {
 	return oc.Sell(context.Background(), SellRequest{
 		Symbol: symbol,
 		Qty:    qty,
@@ -169,8 +471,7 @@ func (oc *OrderController) QuickSell(symbol string, qty float64) (*interfaces.Or
 }
 
 // CancelOrder cancels an existing order
-func (oc *OrderController) CancelOrder(orderID string) error {
-	ctx := context.Background()
+func (oc *OrderController) CancelOrder(ctx context.Context, orderID string) error {
 	err := oc.tradingService.CancelOrder(ctx, orderID)
 	if err != nil {
 		oc.logger.WithError(err).Error("Failed to cancel order")
@@ -206,87 +507,369 @@ func (oc *OrderController) GetAccount() (*interfaces.Account, error) {
 // HandleBuy handles HTTP buy requests
 func (oc *OrderController) HandleBuy(c *gin.Context) {
 	var req BuyRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	result, err := oc.Buy(c.Request.Context(), req)
+	req.DryRun = req.DryRun || c.Query("dry_run") == "true"
+
+	result, err := oc.Buy(orderContext(c), req)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondOrderError(c, err)
 		return
 	}
 
-	c.JSON(200, result)
+	c.JSON(orderStatus(result), result)
 }
 
 // HandleSell handles HTTP sell requests
 func (oc *OrderController) HandleSell(c *gin.Context) {
 	var req SellRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	result, err := oc.Sell(c.Request.Context(), req)
+	req.DryRun = req.DryRun || c.Query("dry_run") == "true"
+
+	result, err := oc.Sell(orderContext(c), req)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondOrderError(c, err)
 		return
 	}
 
-	c.JSON(200, result)
+	c.JSON(orderStatus(result), result)
+}
+
+// orderStatus is 202 for orders held in the queue and 200 otherwise
+func orderStatus(result *OrderResponse) int {
+	if result.Queued != nil {
+		return http.StatusAccepted
+	}
+	return http.StatusOK
+}
+
+// orderContext tags the request context with the order source taken from the
+// X-Order-Source header (manual, webhook, strategy, ai)
+func orderContext(c *gin.Context) context.Context {
+	source := c.GetHeader("X-Order-Source")
+	if source == "" {
+		source = services.OrderSourceManual
+	}
+	return services.WithOrderSource(c.Request.Context(), source)
+}
+
+// respondOrderError maps order placement errors to HTTP responses
+func respondOrderError(c *gin.Context, err error) {
+	var rejected *services.RiskRejectedError
+	if errors.As(err, &rejected) {
+		respondServiceError(c, "Order rejected by risk checks", err)
+		return
+	}
+	respondServiceError(c, "Failed to place order", err)
+}
+
+// HandleBasket risk-checks a basket of orders together and submits them,
+// reporting every leg
+// POST /api/v1/orders/basket
+func (oc *OrderController) HandleBasket(c *gin.Context) {
+	if oc.baskets == nil {
+		respondError(c, services.ErrCodeUnavailable, "basket orders not enabled", "")
+		return
+	}
+
+	var req services.BasketRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	req.DryRun = req.DryRun || c.Query("dry_run") == "true"
+
+	result, err := oc.baskets.Submit(orderContext(c), &req)
+	if err != nil {
+		respondServiceError(c, "Failed to submit basket", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
 }
 
 // HandleCancelOrder handles HTTP cancel order requests
 func (oc *OrderController) HandleCancelOrder(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
-		c.JSON(400, gin.H{"error": "order ID required"})
+		respondBadRequest(c, "order ID required", nil)
 		return
 	}
 
-	if err := oc.CancelOrder(orderID); err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := oc.CancelOrder(c.Request.Context(), orderID); err != nil {
+		respondServiceError(c, "Failed to cancel order", err)
 		return
 	}
 
 	c.JSON(200, gin.H{"message": "Order canceled successfully"})
 }
 
-// HandleGetPositions handles HTTP get positions requests
+// HandleGetOrderAudit returns the audit trail for an order and its fill
+// timeline, oldest first
+// GET /api/v1/orders/:id/audit
+func (oc *OrderController) HandleGetOrderAudit(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		respondBadRequest(c, "order ID required", nil)
+		return
+	}
+	if oc.auditor == nil {
+		respondError(c, services.ErrCodeUnavailable, "order auditing not enabled", "")
+		return
+	}
+
+	audits, err := oc.auditor.GetOrderAudits(orderID)
+	if err != nil {
+		respondServiceError(c, "Failed to get order audit", err)
+		return
+	}
+	fills := []*models.DBOrderFill{}
+	if oc.fills != nil {
+		if fills, err = oc.fills.Fills(orderID); err != nil {
+			respondServiceError(c, "Failed to get order fills", err)
+			return
+		}
+	}
+	if len(audits) == 0 && len(fills) == 0 {
+		respondNotFound(c, "no audit records for order", nil)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"order_id": orderID,
+		"count":    len(audits),
+		"audits":   audits,
+		"fills":    fills,
+	})
+}
+
+// HandleGetQueuedOrders lists orders waiting for the market to open
+// GET /api/v1/orders/queued?status=QUEUED
+func (oc *OrderController) HandleGetQueuedOrders(c *gin.Context) {
+	if oc.orderQueue == nil {
+		respondError(c, services.ErrCodeUnavailable, "order queue not enabled", "")
+		return
+	}
+
+	orders, err := oc.orderQueue.List(strings.ToUpper(c.Query("status")))
+	if err != nil {
+		respondServiceError(c, "Failed to get queued orders", err)
+		return
+	}
+	orders = filterBook(c, orders, func(o *services.QueuedOrder) string { return o.Book })
+
+	c.JSON(200, gin.H{
+		"orders": orders,
+		"count":  len(orders),
+	})
+}
+
+// HandleGetChases lists pegged orders being chased and the most recent
+// finished chases
+// GET /api/v1/orders/chases
+func (oc *OrderController) HandleGetChases(c *gin.Context) {
+	if oc.orderChaser == nil {
+		respondError(c, services.ErrCodeUnavailable, "order chasing not enabled", "")
+		return
+	}
+
+	chases := filterBook(c, oc.orderChaser.Chases(), func(ch *services.OrderChase) string { return ch.Book })
+	c.JSON(200, gin.H{
+		"chases": chases,
+		"count":  len(chases),
+	})
+}
+
+// HandleCancelQueuedOrder withdraws a queued order before it is submitted
+// DELETE /api/v1/orders/queued/:id
+func (oc *OrderController) HandleCancelQueuedOrder(c *gin.Context) {
+	if oc.orderQueue == nil {
+		respondError(c, services.ErrCodeUnavailable, "order queue not enabled", "")
+		return
+	}
+
+	order, err := oc.orderQueue.Cancel(c.Param("id"))
+	if err != nil {
+		respondServiceError(c, "Failed to cancel queued order", err)
+		return
+	}
+
+	c.JSON(200, order)
+}
+
+// HandleGetPositions handles HTTP get positions requests. fields trims each
+// position; expand=analysis embeds the latest stored stock analysis of the
+// symbol.
+// GET /api/v1/positions?fields=Symbol,Qty,UnrealizedPL&expand=analysis
 func (oc *OrderController) HandleGetPositions(c *gin.Context) {
-	positions, err := oc.GetPositions()
+	sel, err := parseFieldSelection(c, "analysis")
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondBadRequest(c, "Invalid field selection", err)
 		return
 	}
 
-	c.JSON(200, positions)
+	positions, err := oc.tradingService.GetPositions(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, "Failed to get positions", err)
+		return
+	}
+
+	var embed func(int, map[string]interface{})
+	if analyses, ok := oc.storageService.(stockAnalysisStore); ok && sel.expanded("analysis") {
+		embed = func(i int, item map[string]interface{}) {
+			item["analysis"] = latestStockAnalysis(analyses, positions[i].Symbol)
+		}
+	}
+	body, err := sel.apply(positions, embed)
+	if err != nil {
+		respondServiceError(c, "Failed to select position fields", err)
+		return
+	}
+
+	c.JSON(200, body)
+}
+
+// stockAnalysisStore is the storage of stock analyses, which the local
+// storage provides
+type stockAnalysisStore interface {
+	GetStockAnalyses(symbol string, since time.Time, limit int) ([]*models.DBStockAnalysis, error)
+}
+
+// latestStockAnalysis returns the newest stored analysis of symbol, or nil
+func latestStockAnalysis(store stockAnalysisStore, symbol string) gin.H {
+	analyses, err := store.GetStockAnalyses(strings.ToUpper(symbol), time.Time{}, 1)
+	if err != nil || len(analyses) == 0 {
+		return nil
+	}
+	return gin.H{
+		"created_at":      analyses[0].CreatedAt,
+		"profile":         analyses[0].Profile,
+		"composite_score": analyses[0].CompositeScore,
+		"analysis":        json.RawMessage(analyses[0].Analysis),
+	}
 }
 
 // HandleGetAccount handles HTTP get account requests
 func (oc *OrderController) HandleGetAccount(c *gin.Context) {
-	account, err := oc.GetAccount()
+	account, err := oc.tradingService.GetAccount(c.Request.Context())
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to get account", err)
 		return
 	}
 
 	c.JSON(200, account)
 }
 
-// HandleGetOrders handles HTTP get orders requests
+// HandleGetOrders handles HTTP get orders requests. Supports status plus the
+// shared list parameters: limit, cursor, from, to, symbol and sort. fields
+// trims each order; expand=audit embeds each order's audit records.
+// GET /api/v1/orders?status=open&symbol=AAPL&sort=-submitted_at&fields=ID,Symbol,Status
 func (oc *OrderController) HandleGetOrders(c *gin.Context) {
-	status := c.Query("status")
+	query, err := parseListQuery(c, orderListSpec, defaultPageLimit)
+	if err != nil {
+		respondBadRequest(c, "Invalid list parameters", err)
+		return
+	}
+	sel, err := parseFieldSelection(c, "audit")
+	if err != nil {
+		respondBadRequest(c, "Invalid field selection", err)
+		return
+	}
 
-	ctx := context.Background()
-	orders, err := oc.tradingService.ListOrders(ctx, status)
+	ctx := c.Request.Context()
+	orders, err := oc.tradingService.ListOrders(ctx, c.Query("status"))
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to get orders", err)
 		return
 	}
 
-	c.JSON(200, orders)
+	// Broker orders carry no book, strategy, tags or metadata, so they are
+	// taken from the stored orders
+	stored, err := oc.storageService.GetOrders("")
+	if err != nil {
+		respondServiceError(c, "Failed to get orders", err)
+		return
+	}
+	storedByID := make(map[string]*interfaces.Order, len(stored))
+	for _, order := range stored {
+		storedByID[order.ID] = order
+	}
+	for _, order := range orders {
+		if local, ok := storedByID[order.ID]; ok {
+			order.Book = local.Book
+			order.Strategy = local.Strategy
+			order.Tags = local.Tags
+			order.Metadata = local.Metadata
+		}
+	}
+	orders = filterBook(c, orders, func(o *interfaces.Order) string { return o.Book })
+	orders = filterOrderTags(orders, c.Query("tag"), c.QueryMap("meta"))
+
+	page, info := paginate(orders, query, orderListSpec)
+	var embed func(int, map[string]interface{})
+	if oc.auditor != nil && sel.expanded("audit") {
+		embed = func(i int, item map[string]interface{}) {
+			audits, err := oc.auditor.GetOrderAudits(page[i].ID)
+			if err != nil {
+				oc.logger.WithError(err).WithField("order_id", page[i].ID).Warn("Failed to get order audit")
+			}
+			item["audit"] = audits
+		}
+	}
+	body, err := sel.apply(page, embed)
+	if err != nil {
+		respondServiceError(c, "Failed to select order fields", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"orders":     body,
+		"count":      len(page),
+		"pagination": info,
+	})
+}
+
+// filterOrderTags keeps orders carrying tag, when set, and every key/value
+// of meta, given as ?meta[signal_id]=abc
+func filterOrderTags(orders []*interfaces.Order, tag string, meta map[string]string) []*interfaces.Order {
+	if tag == "" && len(meta) == 0 {
+		return orders
+	}
+
+	filtered := make([]*interfaces.Order, 0, len(orders))
+	for _, order := range orders {
+		if tag != "" && !slices.Contains(order.Tags, tag) {
+			continue
+		}
+		matches := true
+		for key, value := range meta {
+			if order.Metadata[key] != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, order)
+		}
+	}
+	return filtered
+}
+
+// orderListSpec defines the filters and sorts of GET /orders
+var orderListSpec = listSpec[*interfaces.Order]{
+	id:     func(o *interfaces.Order) string { return o.ID },
+	time:   func(o *interfaces.Order) time.Time { return o.SubmittedAt },
+	symbol: func(o *interfaces.Order) string { return o.Symbol },
+	sorts: map[string]func(*interfaces.Order) string{
+		"submitted_at": func(o *interfaces.Order) string { return sortTimeKey(o.SubmittedAt) },
+		"symbol":       func(o *interfaces.Order) string { return o.Symbol },
+		"status":       func(o *interfaces.Order) string { return o.Status },
+	},
+	defaultSort: "-submitted_at",
 }
 
 // HandleGetQuote handles HTTP get quote requests
@@ -294,14 +877,14 @@ func (oc *OrderController) HandleGetOrders(c *gin.Context) {
 func (oc *OrderController) HandleGetQuote(c *gin.Context) {
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(400, gin.H{"error": "symbol required"})
+		respondBadRequest(c, "symbol required", nil)
 		return
 	}
 
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	quote, err := oc.dataService.GetLatestQuote(ctx, symbol)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to get quote", err)
 		return
 	}
 
@@ -313,33 +896,43 @@ func (oc *OrderController) HandleGetQuote(c *gin.Context) {
 func (oc *OrderController) HandleGetBar(c *gin.Context) {
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(400, gin.H{"error": "symbol required"})
+		respondBadRequest(c, "symbol required", nil)
 		return
 	}
 
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	bar, err := oc.dataService.GetLatestBar(ctx, symbol)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to get bar", err)
 		return
 	}
 
 	c.JSON(200, bar)
 }
 
-// HandleGetBars handles HTTP get historical bars requests
-// GET /api/v1/market/bars/:symbol?start=2025-01-01&end=2025-01-10&timeframe=1D
+// HandleGetBars handles HTTP get historical bars requests. adjustment (raw,
+// split, dividend or all) and feed override the provider defaults.
+// GET /api/v1/market/bars/:symbol?start=2025-01-01&end=2025-01-10&timeframe=1Day&adjustment=raw
 func (oc *OrderController) HandleGetBars(c *gin.Context) {
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(400, gin.H{"error": "symbol required"})
+		respondBadRequest(c, "symbol required", nil)
 		return
 	}
 
 	// Parse query parameters
 	startStr := c.Query("start")
 	endStr := c.Query("end")
-	timeframe := c.DefaultQuery("timeframe", "1D")
+	timeframe, err := services.NormalizeBarSpec(c.DefaultQuery("timeframe", "1Day"))
+	if err != nil {
+		respondServiceError(c, "Invalid timeframe", err)
+		return
+	}
+	opts, err := services.ParseBarOptions(c.Query("adjustment"), c.Query("feed"))
+	if err != nil {
+		respondServiceError(c, "Invalid bar options", err)
+		return
+	}
 
 	// Default to last 30 days if not specified
 	end := time.Now()
@@ -357,40 +950,126 @@ func (oc *OrderController) HandleGetBars(c *gin.Context) {
 		}
 	}
 
-	ctx := context.Background()
+	ctx := services.WithBarOptions(c.Request.Context(), opts)
 	bars, err := oc.dataService.GetHistoricalBars(ctx, symbol, start, end, timeframe)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to get bars", err)
 		return
 	}
 
 	c.JSON(200, gin.H{
-		"symbol":    symbol,
-		"start":     start,
-		"end":       end,
-		"timeframe": timeframe,
-		"count":     len(bars),
-		"bars":      bars,
+		"symbol":     symbol,
+		"start":      start,
+		"end":        end,
+		"timeframe":  timeframe,
+		"adjustment": opts.Adjustment,
+		"feed":       opts.Feed,
+		"count":      len(bars),
+		"bars":       bars,
+	})
+}
+
+// HandleGetTimeframes lists the timeframes the bar endpoints accept: each
+// unit with its amounts and aliases, and the timeframes every provider serves
+// GET /api/v1/market/timeframes
+func (oc *OrderController) HandleGetTimeframes(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"common": services.CommonTimeframes,
+		"units":  services.TimeframeUnits(),
 	})
 }
 
+// HandleGetMarketHours returns the trading session each asset class is in:
+// pre_market, regular, after_hours, closed or continuous
+// GET /api/v1/market/hours
+func (oc *OrderController) HandleGetMarketHours(c *gin.Context) {
+	if oc.marketHours == nil {
+		respondError(c, services.ErrCodeUnavailable, "market hours not enabled", "")
+		return
+	}
+	c.JSON(200, oc.marketHours.Status(c.Request.Context()))
+}
+
+// HandleListAggregatedBars lists the symbols and bars built live from
+// streamed trades
+// GET /api/v1/market/aggregated-bars
+func (oc *OrderController) HandleListAggregatedBars(c *gin.Context) {
+	if oc.barAggregator == nil {
+		respondError(c, services.ErrCodeUnavailable, "bar aggregation not enabled", "")
+		return
+	}
+
+	c.JSON(200, gin.H{"series": oc.barAggregator.Tracked()})
+}
+
+// HandleGetAggregatedBars returns the recent bars built live from streamed
+// trades, oldest first
+// GET /api/v1/market/aggregated-bars/:symbol?bars=500Tick
+func (oc *OrderController) HandleGetAggregatedBars(c *gin.Context) {
+	if oc.barAggregator == nil {
+		respondError(c, services.ErrCodeUnavailable, "bar aggregation not enabled", "")
+		return
+	}
+	symbol := strings.ToUpper(c.Param("symbol"))
+	spec := c.Query("bars")
+	if spec == "" {
+		respondBadRequest(c, "bars required", nil)
+		return
+	}
+
+	bars, err := oc.barAggregator.Bars(symbol, spec)
+	if err != nil {
+		respondServiceError(c, "Failed to get aggregated bars", err)
+		return
+	}
+	spec, _ = services.NormalizeBarSpec(spec)
+
+	c.JSON(200, gin.H{
+		"symbol": symbol,
+		"spec":   spec,
+		"count":  len(bars),
+		"bars":   bars,
+	})
+}
+
+// HandleGetDataProviders reports which market data provider served recent requests
+// GET /api/v1/market/providers
+func (oc *OrderController) HandleGetDataProviders(c *gin.Context) {
+	data := oc.dataService
+	if aggregated, ok := data.(*services.AggregatedDataService); ok {
+		data = aggregated.DataService
+	}
+	if cached, ok := data.(*services.CachedDataService); ok {
+		data = cached.DataService
+	}
+	failover, ok := data.(*services.FailoverDataService)
+	if !ok {
+		respondNotFound(c, "provider failover not enabled", nil)
+		return
+	}
+
+	c.JSON(200, failover.Stats())
+}
+
 // OptionsOrderRequest represents an options order request
 type OptionsOrderRequest struct {
-	Symbol        string   `json:"symbol" binding:"required"`
-	Underlying    string   `json:"underlying"`
-	Qty           float64  `json:"qty" binding:"required,gt=0"`
+	Symbol        string   `json:"symbol" binding:"required,occ_symbol"`
+	Underlying    string   `json:"underlying" binding:"omitempty,symbol"`
+	Qty           decimal.Decimal `json:"qty" binding:"gt=0"`
 	Side          string   `json:"side" binding:"required,oneof=buy sell"`
-	PositionIntent string  `json:"position_intent"` // "buy_to_open", "buy_to_close", "sell_to_open", "sell_to_close"
-	Type          string   `json:"type"` // "market", "limit"
-	TimeInForce   string   `json:"time_in_force"` // "day", "gtc"
-	LimitPrice    *float64 `json:"limit_price,omitempty"`
+	PositionIntent string  `json:"position_intent" binding:"omitempty,oneof=buy_to_open buy_to_close sell_to_open sell_to_close"`
+	Type          string   `json:"type" binding:"omitempty,oneof=market limit"`
+	TimeInForce   string   `json:"time_in_force" binding:"omitempty,oneof=day gtc"`
+	LimitPrice    *decimal.Decimal `json:"limit_price,omitempty" binding:"required_if=Type limit,omitempty,gt=0"`
+	Tags          []string          `json:"tags,omitempty" binding:"omitempty,max=16,dive,min=1,max=64"`
+	Metadata      map[string]string `json:"metadata,omitempty" binding:"omitempty,max=32,dive,keys,min=1,max=64,endkeys,max=256"`
+	DryRun        bool     `json:"dry_run"`
 }
 
 // PlaceOptionsOrder handles POST /api/options/order
 func (oc *OrderController) PlaceOptionsOrder(c *gin.Context) {
 	var req OptionsOrderRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -418,31 +1097,100 @@ func (oc *OrderController) PlaceOptionsOrder(c *gin.Context) {
 		Type:          req.Type,
 		TimeInForce:   req.TimeInForce,
 		LimitPrice:    req.LimitPrice,
+		Tags:          req.Tags,
+		Metadata:      req.Metadata,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(orderContext(c), 10*time.Second)
 	defer cancel()
 
-	result, err := oc.tradingService.PlaceOptionsOrder(ctx, order)
+	var decision *services.RiskDecision
+	if oc.riskManager != nil {
+		var err error
+		decision, err = oc.riskManager.EvaluateOptions(ctx, order)
+		if err != nil {
+			respondServiceError(c, "Failed to evaluate risk", err)
+			return
+		}
+		if !decision.Approved {
+			rejected := &services.RiskRejectedError{Decision: decision}
+			oc.recordOptionsAttempt(ctx, order, services.AuditOutcomeRejected, decision, rejected)
+			respondOrderError(c, rejected)
+			return
+		}
+	}
+
+	if oc.dryRun || req.DryRun || c.Query("dry_run") == "true" {
+		request := services.BuildOptionsOrderRequest(order)
+		oc.recordOptionsAttempt(ctx, order, services.AuditOutcomeDryRun, decision, nil)
+		oc.logger.WithField("symbol", order.Symbol).Info("Dry run: options order not submitted")
+		c.JSON(200, &OrderResponse{
+			OrderResult: &interfaces.OrderResult{
+				Status:  "dry_run",
+				Message: "Dry run: options order validated but not submitted",
+			},
+			DryRun:  true,
+			Request: &request,
+			Risk:    decision,
+		})
+		return
+	}
+
+	result, err := oc.tradingService.PlaceOptionsOrder(services.WithRiskDecision(ctx, decision), order)
 	if err != nil {
 		oc.logger.WithError(err).Error("Failed to place options order")
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to place options order", err)
 		return
 	}
 
+	// Saved so listings can echo the book, strategy, tags and metadata
+	saved := &interfaces.Order{
+		ID:          result.OrderID,
+		Symbol:      order.Symbol,
+		Qty:         order.Qty,
+		Side:        order.Side,
+		Type:        order.Type,
+		TimeInForce: order.TimeInForce,
+		LimitPrice:  order.LimitPrice,
+		Status:      result.Status,
+		SubmittedAt: time.Now(),
+		Strategy:    strategyTag(ctx, ""),
+		Book:        services.BookFrom(ctx),
+		Tags:        order.Tags,
+		Metadata:    order.Metadata,
+	}
+	if err := oc.storageService.SaveOrder(saved); err != nil {
+		oc.logger.WithError(err).Warn("Failed to save options order to database")
+	}
+
 	c.JSON(200, result)
 }
 
+// recordOptionsAttempt audits an options order that was not submitted
+func (oc *OrderController) recordOptionsAttempt(ctx context.Context, order *interfaces.OptionsOrder, outcome string, decision *services.RiskDecision, err error) {
+	oc.auditor.Record(ctx, services.OrderAttempt{
+		Symbol:     order.Symbol,
+		Side:       order.Side,
+		AssetClass: "us_option",
+		Outcome:    outcome,
+		Request:    services.BuildOptionsOrderRequest(order),
+		Risk:       decision,
+		Err:        err,
+		Tags:       order.Tags,
+		Metadata:   order.Metadata,
+	})
+}
+
 // GetOptionsPosition handles GET /api/options/position/:symbol
 func (oc *OrderController) GetOptionsPosition(c *gin.Context) {
 	symbol := c.Param("symbol")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
 	position, err := oc.tradingService.GetOptionsPosition(ctx, symbol)
 	if err != nil {
-		c.JSON(404, gin.H{"error": err.Error()})
+		respondNotFound(c, "Options position not found", err)
 		return
 	}
 
@@ -451,35 +1199,152 @@ func (oc *OrderController) GetOptionsPosition(c *gin.Context) {
 
 // ListOptionsPositions handles GET /api/options/positions
 func (oc *OrderController) ListOptionsPositions(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
+	if oc.optionsGreeks != nil {
+		positions, err := oc.optionsGreeks.ListPositions(ctx)
+		if err != nil {
+			respondServiceError(c, "Failed to list options positions", err)
+			return
+		}
+		c.JSON(200, positions)
+		return
+	}
+
 	positions, err := oc.tradingService.ListOptionsPositions(ctx)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to list options positions", err)
 		return
 	}
 
 	c.JSON(200, positions)
 }
 
+// GetOptionsExposure handles GET /api/options/exposure, the net delta,
+// gamma, theta and vega of the options book per underlying and in total
+func (oc *OrderController) GetOptionsExposure(c *gin.Context) {
+	if oc.optionsGreeks == nil {
+		respondError(c, services.ErrCodeUnavailable, "options exposure not enabled", "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+
+	exposure, err := oc.optionsGreeks.Exposure(ctx)
+	if err != nil {
+		respondServiceError(c, "Failed to get options exposure", err)
+		return
+	}
+
+	c.JSON(200, exposure)
+}
+
+// GetCoveredCalls handles GET /api/options/covered-calls, the short calls
+// paired with the shares covering them and recent assignments and
+// expirations
+func (oc *OrderController) GetCoveredCalls(c *gin.Context) {
+	if oc.coveredCalls == nil {
+		respondError(c, services.ErrCodeUnavailable, "covered call monitor not enabled", "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	pairs, err := oc.coveredCalls.Pairs(ctx)
+	if err != nil {
+		respondServiceError(c, "Failed to get covered calls", err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"covered_calls": pairs,
+		"count":         len(pairs),
+		"events":        oc.coveredCalls.Events(),
+	})
+}
+
+// AnalyzeOptions handles POST /api/options/analyze, the probability of
+// profit, breakevens and bounds of a proposed structure at its first
+// expiration, with each leg's chance of expiring in the money
+func (oc *OrderController) AnalyzeOptions(c *gin.Context) {
+	if oc.optionsAnalyzer == nil {
+		respondError(c, services.ErrCodeUnavailable, "options analysis not enabled", "")
+		return
+	}
+
+	var req services.OptionsAnalysisRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	analysis, err := oc.optionsAnalyzer.Analyze(ctx, req)
+	if err != nil {
+		respondServiceError(c, "Failed to analyze options structure", err)
+		return
+	}
+
+	c.JSON(200, analysis)
+}
+
+// GetExpectedMove handles GET /api/options/expected-move/:symbol?expiration=2025-11-21,
+// the move priced into the at-the-money straddle, by default for next
+// Friday's expiration
+func (oc *OrderController) GetExpectedMove(c *gin.Context) {
+	if oc.optionsAnalyzer == nil {
+		respondError(c, services.ErrCodeUnavailable, "options analysis not enabled", "")
+		return
+	}
+
+	expiration := getNextFriday()
+	if expirationStr := c.Query("expiration"); expirationStr != "" {
+		var err error
+		if expiration, err = time.Parse("2006-01-02", expirationStr); err != nil {
+			respondBadRequest(c, "invalid expiration date format, use YYYY-MM-DD", nil)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	move, err := oc.optionsAnalyzer.ExpectedMove(ctx, c.Param("symbol"), expiration)
+	if err != nil {
+		respondServiceError(c, "Failed to get expected move", err)
+		return
+	}
+
+	c.JSON(200, move)
+}
+
 // GetOptionsChain handles GET /api/options/chain/:symbol?expiration=2025-11-22&delta_min=0.4&delta_max=0.6&min_bid=0.1
+// fields trims each contract, such as fields=Symbol,StrikePrice,Bid,Ask to
+// leave out the greeks.
 func (oc *OrderController) GetOptionsChain(c *gin.Context) {
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(400, gin.H{"error": "symbol required"})
+		respondBadRequest(c, "symbol required", nil)
+		return
+	}
+	sel, err := parseFieldSelection(c)
+	if err != nil {
+		respondBadRequest(c, "Invalid field selection", err)
 		return
 	}
 
 	// Get expiration date from query parameter
 	expirationStr := c.Query("expiration")
 	var expiration time.Time
-	var err error
 
 	if expirationStr != "" {
 		expiration, err = time.Parse("2006-01-02", expirationStr)
 		if err != nil {
-			c.JSON(400, gin.H{"error": "invalid expiration date format, use YYYY-MM-DD"})
+			respondBadRequest(c, "invalid expiration date format, use YYYY-MM-DD", nil)
 			return
 		}
 	} else {
@@ -487,16 +1352,37 @@ func (oc *OrderController) GetOptionsChain(c *gin.Context) {
 		expiration = getNextFriday()
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
-	chain, err := oc.tradingService.GetOptionsChain(ctx, symbol, expiration)
+	var chain []*interfaces.OptionContract
+	var cached *services.OptionsChain
+	if oc.optionsChains != nil {
+		cached, err = oc.optionsChains.Get(ctx, symbol, expiration)
+		if cached != nil {
+			chain = cached.Contracts
+		}
+	} else {
+		chain, err = oc.tradingService.GetOptionsChain(ctx, symbol, expiration)
+	}
 	if err != nil {
 		oc.logger.WithError(err).Error("Failed to get options chain")
-		c.JSON(500, gin.H{"error": err.Error()})
+		respondServiceError(c, "Failed to get options chain", err)
 		return
 	}
 
+	// The filters are part of the URL, so the chain version alone
+	// identifies the response
+	if cached != nil {
+		etag := `W/"` + cached.Version + `"`
+		c.Header("ETag", etag)
+		c.Header("Last-Modified", cached.ModifiedAt.UTC().Format(http.TimeFormat))
+		if optionsChainNotModified(c.Request, etag, cached.ModifiedAt) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
 	// Apply filters for token efficiency
 	filtered := make([]*interfaces.OptionContract, 0)
 
@@ -562,13 +1448,38 @@ func (oc *OrderController) GetOptionsChain(c *gin.Context) {
 		filtered = append(filtered, contract)
 	}
 
-	c.JSON(200, gin.H{
+	contracts, err := sel.apply(filtered, nil)
+	if err != nil {
+		respondServiceError(c, "Failed to select contract fields", err)
+		return
+	}
+
+	response := gin.H{
 		"symbol":     symbol,
 		"expiration": expiration.Format("2006-01-02"),
 		"total":      len(chain),
 		"filtered":   len(filtered),
-		"contracts":  filtered,
-	})
+		"contracts":  contracts,
+	}
+	if cached != nil {
+		response["fetched_at"] = cached.FetchedAt
+		response["quoted_at"] = cached.QuotedAt
+	}
+	c.JSON(200, response)
+}
+
+// optionsChainNotModified reports whether the client's validators match.
+// If-None-Match takes precedence over If-Modified-Since, as in RFC 9110.
+func optionsChainNotModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		return etagMatches(match, etag)
+	}
+	if since := r.Header.Get("If-Modified-Since"); since != "" {
+		if t, err := http.ParseTime(since); err == nil {
+			return !modified.Truncate(time.Second).After(t)
+		}
+	}
+	return false
 }
 
 // getNextFriday returns the date of the next Friday
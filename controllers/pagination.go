@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// List endpoint defaults
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// PageInfo is the pagination metadata returned by list endpoints. Pass
+// NextCursor back as ?cursor= to fetch the following page.
+type PageInfo struct {
+	Limit      int    `json:"limit"`
+	Total      int    `json:"total"` // items matching the filters across all pages
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Sort       string `json:"sort"`
+}
+
+// ListQuery holds the pagination, filter and sort parameters shared by list
+// endpoints: limit, cursor, from, to, symbol and sort
+type ListQuery struct {
+	Limit      int
+	From       time.Time // inclusive; zero means unbounded
+	To         time.Time // exclusive; zero means unbounded
+	Symbols    []string  // upper-cased
+	SortField  string
+	Descending bool
+	cursor     *pageCursor
+}
+
+// pageCursor identifies the last item of a page. Cursors are keyset based,
+// so items added or removed between requests do not shift later pages.
+type pageCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"i"`
+}
+
+// listSpec describes how to filter and sort the items of one endpoint
+type listSpec[T any] struct {
+	id          func(T) string
+	time        func(T) time.Time         // used by from/to; nil disables date filters
+	symbol      func(T) string            // used by symbol; nil disables the symbol filter
+	sorts       map[string]func(T) string // sort field -> order-preserving string key
+	defaultSort string                    // e.g. "-created_at"
+}
+
+// parseListQuery reads list parameters from the query string. sort takes a
+// field name, prefixed with "-" for descending order.
+func parseListQuery[T any](c *gin.Context, spec listSpec[T], defaultLimit int) (*ListQuery, error) {
+	q := &ListQuery{Limit: defaultLimit}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("limit must be a positive integer")
+		}
+		q.Limit = limit
+	}
+	if q.Limit > maxPageLimit {
+		q.Limit = maxPageLimit
+	}
+
+	sortParam := c.DefaultQuery("sort", spec.defaultSort)
+	q.SortField = strings.TrimPrefix(sortParam, "-")
+	q.Descending = strings.HasPrefix(sortParam, "-")
+	if _, ok := spec.sorts[q.SortField]; !ok {
+		return nil, fmt.Errorf("sort must be one of: %s (prefix with - for descending)", strings.Join(sortFields(spec.sorts), ", "))
+	}
+
+	var err error
+	if raw := c.Query("from"); raw != "" {
+		if spec.time == nil {
+			return nil, fmt.Errorf("from is not supported by this endpoint")
+		}
+		if q.From, err = parseListTime(raw, false); err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		if spec.time == nil {
+			return nil, fmt.Errorf("to is not supported by this endpoint")
+		}
+		if q.To, err = parseListTime(raw, true); err != nil {
+			return nil, fmt.Errorf("to: %w", err)
+		}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	if raw := c.Query("symbol"); raw != "" {
+		for _, symbol := range strings.Split(raw, ",") {
+			if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+				q.Symbols = append(q.Symbols, symbol)
+			}
+		}
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := decodeCursor(raw)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != sortParam {
+			return nil, fmt.Errorf("cursor was issued for sort %q; repeat the original sort or drop the cursor", cursor.Sort)
+		}
+		q.cursor = cursor
+	}
+
+	return q, nil
+}
+
+// parseListTime accepts RFC 3339 timestamps or YYYY-MM-DD dates. A bare date
+// used as an upper bound covers the whole day.
+func parseListTime(raw string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or an RFC 3339 timestamp, got %q", raw)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// paginate filters, sorts and slices items according to q
+func paginate[T any](items []T, q *ListQuery, spec listSpec[T]) ([]T, PageInfo) {
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		if spec.time != nil && (!q.From.IsZero() || !q.To.IsZero()) {
+			t := spec.time(item)
+			if (!q.From.IsZero() && t.Before(q.From)) || (!q.To.IsZero() && !t.Before(q.To)) {
+				continue
+			}
+		}
+		if spec.symbol != nil && len(q.Symbols) > 0 && !containsSymbol(q.Symbols, spec.symbol(item)) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+
+	keyOf := spec.sorts[q.SortField]
+	less := func(aKey, aID, bKey, bID string) bool {
+		if aKey != bKey {
+			return (aKey < bKey) != q.Descending
+		}
+		if aID == bID {
+			return false
+		}
+		return (aID < bID) != q.Descending
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return less(keyOf(filtered[i]), spec.id(filtered[i]), keyOf(filtered[j]), spec.id(filtered[j]))
+	})
+
+	sortParam := q.SortField
+	if q.Descending {
+		sortParam = "-" + sortParam
+	}
+	info := PageInfo{Limit: q.Limit, Total: len(filtered), Sort: sortParam}
+
+	start := 0
+	if q.cursor != nil {
+		start = sort.Search(len(filtered), func(i int) bool {
+			return less(q.cursor.Key, q.cursor.ID, keyOf(filtered[i]), spec.id(filtered[i]))
+		})
+	}
+	end := start + q.Limit
+	if end >= len(filtered) {
+		return filtered[start:], info
+	}
+
+	last := filtered[end-1]
+	info.HasMore = true
+	info.NextCursor = encodeCursor(&pageCursor{Sort: sortParam, Key: keyOf(last), ID: spec.id(last)})
+	return filtered[start:end], info
+}
+
+// sortTimeKey formats t so that string order matches chronological order
+func sortTimeKey(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+func encodeCursor(cursor *pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+func containsSymbol(symbols []string, symbol string) bool {
+	symbol = strings.ToUpper(symbol)
+	for _, s := range symbols {
+		if s == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+func sortFields[T any](sorts map[string]func(T) string) []string {
+	fields := make([]string, 0, len(sorts))
+	for field := range sorts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"prophet-trader/services"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,6 +12,8 @@ import (
 // PositionManagementController handles managed position operations
 type PositionManagementController struct {
 	positionManager *services.PositionManager
+	flattenService  *services.FlattenService
+	splitAdjuster   *services.SplitAdjuster
 }
 
 // NewPositionManagementController creates a new position management controller
@@ -19,24 +23,134 @@ func NewPositionManagementController(positionManager *services.PositionManager)
 	}
 }
 
+// SetFlattenService enables the emergency flatten endpoint
+func (pmc *PositionManagementController) SetFlattenService(flattenService *services.FlattenService) {
+	pmc.flattenService = flattenService
+}
+
+// SetSplitAdjuster enables the stock split endpoints
+func (pmc *PositionManagementController) SetSplitAdjuster(splitAdjuster *services.SplitAdjuster) {
+	pmc.splitAdjuster = splitAdjuster
+}
+
+// HandleListSplits lists the stock splits applied to stored positions and
+// trades, with what each one rescaled. Supports symbol.
+// GET /api/v1/positions/splits?symbol=NVDA
+func (pmc *PositionManagementController) HandleListSplits(c *gin.Context) {
+	if pmc.splitAdjuster == nil {
+		respondError(c, services.ErrCodeUnavailable, "split adjustments not enabled", "")
+		return
+	}
+
+	adjustments, err := pmc.splitAdjuster.List(c.Query("symbol"))
+	if err != nil {
+		respondServiceError(c, "Failed to list split adjustments", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":       len(adjustments),
+		"adjustments": adjustments,
+	})
+}
+
+// HandleApplySplit applies a stock split by hand, rescaling the position
+// snapshots, managed positions and trades recorded before its ex-date
+// POST /api/v1/positions/splits
+func (pmc *PositionManagementController) HandleApplySplit(c *gin.Context) {
+	if pmc.splitAdjuster == nil {
+		respondError(c, services.ErrCodeUnavailable, "split adjustments not enabled", "")
+		return
+	}
+
+	var req services.SplitRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	adjustment, err := pmc.splitAdjuster.Apply(orderContext(c), &req)
+	if err != nil {
+		respondServiceError(c, "Failed to apply split", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustment)
+}
+
+// FlattenRequest selects what to flatten. Without ConfirmToken it only
+// previews and issues a token for the same filter.
+type FlattenRequest struct {
+	services.FlattenFilter
+	ConfirmToken string `json:"confirm_token"`
+}
+
+// HandleFlatten cancels open orders and market-closes positions, optionally
+// only for some symbols or one asset class. The first call returns a preview
+// and a confirmation token; repeating it with the token executes.
+// POST /api/v1/positions/flatten
+func (pmc *PositionManagementController) HandleFlatten(c *gin.Context) {
+	if pmc.flattenService == nil {
+		respondError(c, services.ErrCodeUnavailable, "flatten not enabled", "")
+		return
+	}
+
+	var req FlattenRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.ConfirmToken == "" {
+		preview, err := pmc.flattenService.Preview(c.Request.Context(), req.FlattenFilter)
+		if err != nil {
+			respondServiceError(c, "Failed to preview flatten", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"executed": false,
+			"message":  "Repeat the request with confirm_token to flatten",
+			"preview":  preview,
+		})
+		return
+	}
+
+	requestedBy := "ip:" + c.ClientIP()
+	if principal, ok := c.Get(principalKey); ok {
+		requestedBy = "token:" + principal.(*services.Principal).Name
+	}
+
+	result, err := pmc.flattenService.Confirm(c.Request.Context(), req.ConfirmToken, req.FlattenFilter, requestedBy)
+	if err != nil {
+		respondServiceError(c, "Failed to flatten", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"executed": true,
+		"result":   result,
+	})
+}
+
 // HandlePlaceManagedPosition handles placing a new managed position
 // POST /api/v1/positions/managed
 func (pmc *PositionManagementController) HandlePlaceManagedPosition(c *gin.Context) {
 	var req services.PlaceManagedPositionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	position, err := pmc.positionManager.PlaceManagedPosition(c.Request.Context(), &req)
+	if req.DryRun || pmc.positionManager.IsDryRun() || c.Query("dry_run") == "true" {
+		preview, err := pmc.positionManager.PreviewManagedPosition(orderContext(c), &req)
+		if err != nil {
+			respondManagedPositionError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, preview)
+		return
+	}
+
+	position, err := pmc.positionManager.PlaceManagedPosition(orderContext(c), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to place managed position",
-			"details": err.Error(),
-		})
+		respondManagedPositionError(c, err)
 		return
 	}
 
@@ -46,58 +160,92 @@ func (pmc *PositionManagementController) HandlePlaceManagedPosition(c *gin.Conte
 	})
 }
 
+// respondManagedPositionError maps placement errors to HTTP responses
+func respondManagedPositionError(c *gin.Context, err error) {
+	var rejected *services.RiskRejectedError
+	if errors.As(err, &rejected) {
+		respondServiceError(c, "Order rejected by risk checks", err)
+		return
+	}
+
+	respondServiceError(c, "Failed to place managed position", err)
+}
+
 // HandleGetManagedPosition retrieves a specific managed position
 // GET /api/v1/positions/managed/:id
 func (pmc *PositionManagementController) HandleGetManagedPosition(c *gin.Context) {
 	positionID := c.Param("id")
 	if positionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "position ID required",
-		})
+		respondBadRequest(c, "position ID required", nil)
 		return
 	}
 
 	position, err := pmc.positionManager.GetManagedPosition(positionID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "Position not found",
-			"details": err.Error(),
-		})
+		respondNotFound(c, "Position not found", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, position)
 }
 
-// HandleListManagedPositions lists all managed positions
-// GET /api/v1/positions/managed?status=ACTIVE
+// HandleListManagedPositions lists managed positions. Supports status plus
+// the shared list parameters: limit, cursor, from, to, symbol and sort, and
+// fields to trim each position.
+// GET /api/v1/positions/managed?status=ACTIVE&fields=id,symbol,status,unrealized_pl
 func (pmc *PositionManagementController) HandleListManagedPositions(c *gin.Context) {
-	status := c.Query("status")
+	query, err := parseListQuery(c, managedPositionListSpec, defaultPageLimit)
+	if err != nil {
+		respondBadRequest(c, "Invalid list parameters", err)
+		return
+	}
+	sel, err := parseFieldSelection(c)
+	if err != nil {
+		respondBadRequest(c, "Invalid field selection", err)
+		return
+	}
 
-	positions := pmc.positionManager.ListManagedPositions(status)
+	positions := pmc.positionManager.ListManagedPositions(c.Query("status"))
+	positions = filterBook(c, positions, func(p *services.ManagedPosition) string { return p.Book })
+	page, info := paginate(positions, query, managedPositionListSpec)
+	body, err := sel.apply(page, nil)
+	if err != nil {
+		respondServiceError(c, "Failed to select position fields", err)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"count":     len(positions),
-		"positions": positions,
+		"count":      len(page),
+		"positions":  body,
+		"pagination": info,
 	})
 }
 
+// managedPositionListSpec defines the filters and sorts of GET /positions/managed
+var managedPositionListSpec = listSpec[*services.ManagedPosition]{
+	id:     func(p *services.ManagedPosition) string { return p.ID },
+	time:   func(p *services.ManagedPosition) time.Time { return p.CreatedAt },
+	symbol: func(p *services.ManagedPosition) string { return p.Symbol },
+	sorts: map[string]func(*services.ManagedPosition) string{
+		"created_at": func(p *services.ManagedPosition) string { return sortTimeKey(p.CreatedAt) },
+		"updated_at": func(p *services.ManagedPosition) string { return sortTimeKey(p.UpdatedAt) },
+		"symbol":     func(p *services.ManagedPosition) string { return p.Symbol },
+		"status":     func(p *services.ManagedPosition) string { return p.Status },
+	},
+	defaultSort: "-created_at",
+}
+
 // HandleCloseManagedPosition manually closes a managed position
 // DELETE /api/v1/positions/managed/:id
 func (pmc *PositionManagementController) HandleCloseManagedPosition(c *gin.Context) {
 	positionID := c.Param("id")
 	if positionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "position ID required",
-		})
+		respondBadRequest(c, "position ID required", nil)
 		return
 	}
 
 	if err := pmc.positionManager.CloseManagedPosition(c.Request.Context(), positionID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to close position",
-			"details": err.Error(),
-		})
+		respondServiceError(c, "Failed to close position", err)
 		return
 	}
 
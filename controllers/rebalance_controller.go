@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RebalanceController handles target allocation and rebalancing endpoints
+type RebalanceController struct {
+	rebalanceService *services.RebalanceService
+}
+
+// NewRebalanceController creates a new rebalance controller
+func NewRebalanceController(rebalanceService *services.RebalanceService) *RebalanceController {
+	return &RebalanceController{
+		rebalanceService: rebalanceService,
+	}
+}
+
+// TargetsRequest sets the target allocation in percent of equity
+type TargetsRequest struct {
+	Targets map[string]float64 `json:"targets" binding:"required,min=1,dive,keys,symbol,endkeys,gte=0,lte=100"`
+}
+
+// HandleGetTargets returns the target allocation
+// GET /api/v1/rebalance/targets
+func (rc *RebalanceController) HandleGetTargets(c *gin.Context) {
+	targets, err := rc.rebalanceService.Targets()
+	if err != nil {
+		respondServiceError(c, "Failed to get target allocation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"targets": targets})
+}
+
+// HandleSetTargets replaces the target allocation
+// PUT /api/v1/rebalance/targets
+func (rc *RebalanceController) HandleSetTargets(c *gin.Context) {
+	var req TargetsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	targets, err := rc.rebalanceService.SetTargets(req.Targets)
+	if err != nil {
+		respondServiceError(c, "Failed to set target allocation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"targets": targets})
+}
+
+// HandleRebalance computes the drift from the targets and places the orders
+// that restore them, or only previews them with dry_run
+// POST /api/v1/rebalance
+func (rc *RebalanceController) HandleRebalance(c *gin.Context) {
+	var req services.RebalanceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	plan, err := rc.rebalanceService.Rebalance(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, "Failed to rebalance", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
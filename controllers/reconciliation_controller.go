@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReconciliationController exposes local/broker reconciliation results
+type ReconciliationController struct {
+	reconciler *services.Reconciler
+}
+
+// NewReconciliationController creates a new reconciliation controller
+func NewReconciliationController(reconciler *services.Reconciler) *ReconciliationController {
+	return &ReconciliationController{
+		reconciler: reconciler,
+	}
+}
+
+// HandleGetStatus returns the most recent reconciliation report
+// GET /api/v1/reconciliation/status
+func (rc *ReconciliationController) HandleGetStatus(c *gin.Context) {
+	report := rc.reconciler.LastReport()
+	if report == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "No reconciliation has run yet",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleGetRecovery returns the report of the startup recovery of managed
+// positions, listing anything it could not restore
+// GET /api/v1/reconciliation/recovery
+func (rc *ReconciliationController) HandleGetRecovery(c *gin.Context) {
+	report := rc.reconciler.LastRecovery()
+	if report == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "No recovery has run yet",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleRun triggers an immediate reconciliation pass
+// POST /api/v1/reconciliation/run
+func (rc *ReconciliationController) HandleRun(c *gin.Context) {
+	report, err := rc.reconciler.Reconcile(c.Request.Context())
+	if err != nil {
+		code := services.ErrorCodeOf(err)
+		c.JSON(statusForCode(code), gin.H{
+			"code":    code,
+			"error":   "Reconciliation failed",
+			"details": err.Error(),
+			"report":  report,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,366 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"prophet-trader/database"
+	"prophet-trader/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportController handles performance reporting endpoints
+type ReportController struct {
+	reportingService     *services.ReportingService
+	portfolioRiskService *services.PortfolioRiskService
+	benchmarks           []string
+	heatmapWatchlist     []string
+	activities           *services.AccountActivityService
+	history              *services.PortfolioHistoryService
+	premarket            *services.PremarketScanner
+}
+
+// NewReportController creates a new report controller. benchmarks are the
+// default symbols for the benchmark comparison and heatmapWatchlist those
+// of the market heatmap.
+func NewReportController(reportingService *services.ReportingService, portfolioRiskService *services.PortfolioRiskService, benchmarks, heatmapWatchlist []string) *ReportController {
+	return &ReportController{
+		reportingService:     reportingService,
+		portfolioRiskService: portfolioRiskService,
+		benchmarks:           benchmarks,
+		heatmapWatchlist:     heatmapWatchlist,
+	}
+}
+
+// SetAccountActivities serves the broker's stored account activities
+func (rc *ReportController) SetAccountActivities(activities *services.AccountActivityService) {
+	rc.activities = activities
+}
+
+// SetPortfolioHistory serves the account equity curve
+func (rc *ReportController) SetPortfolioHistory(history *services.PortfolioHistoryService) {
+	rc.history = history
+}
+
+// SetPremarketScanner serves the pre-market gap scan
+func (rc *ReportController) SetPremarketScanner(scanner *services.PremarketScanner) {
+	rc.premarket = scanner
+}
+
+// HandleGetAccountHistory returns the account equity curve from the broker's
+// portfolio history, with gaps filled from local snapshots and the trades
+// that filled along it. timeframe is 1Min, 5Min, 15Min, 1H or 1D (default).
+// GET /api/v1/account/history?days=30&timeframe=1D
+func (rc *ReportController) HandleGetAccountHistory(c *gin.Context) {
+	if rc.history == nil {
+		respondError(c, services.ErrCodeUnavailable, "portfolio history not enabled", "")
+		return
+	}
+
+	start, end, err := parseReportWindow(c)
+	if err != nil {
+		respondBadRequest(c, "Invalid date range", err)
+		return
+	}
+
+	history, err := rc.history.History(c.Request.Context(), start, end, c.Query("timeframe"))
+	if err != nil {
+		respondServiceError(c, "Failed to get portfolio history", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// HandleGetAccountActivities lists dividends, fees, interest, journals and
+// transfers in a window, newest first, with their totals
+// GET /api/v1/account/activities?category=dividend&symbol=AAPL&type=DIV,FEE&days=90
+func (rc *ReportController) HandleGetAccountActivities(c *gin.Context) {
+	if rc.activities == nil {
+		respondError(c, services.ErrCodeUnavailable, "account activities not enabled", "")
+		return
+	}
+
+	start, end, err := parseReportWindow(c)
+	if err != nil {
+		respondBadRequest(c, "Invalid date range", err)
+		return
+	}
+	query := database.AccountActivityQuery{
+		Category: strings.ToLower(c.Query("category")),
+		Symbol:   strings.ToUpper(c.Query("symbol")),
+		Start:    start,
+		End:      end,
+	}
+	if types := c.Query("type"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			query.Types = append(query.Types, strings.ToUpper(strings.TrimSpace(t)))
+		}
+	}
+
+	activities, err := rc.activities.List(query)
+	if err != nil {
+		respondServiceError(c, "Failed to get account activities", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"start":      start,
+		"end":        end,
+		"activities": activities,
+		"count":      len(activities),
+		"summary":    services.SummarizeActivities(activities),
+	})
+}
+
+// HandleGetStrategyPerformance returns P&L, win rate and exposure per strategy tag
+// GET /api/v1/reports/strategies?start=2025-01-01&end=2025-02-01 (or ?days=30)
+func (rc *ReportController) HandleGetStrategyPerformance(c *gin.Context) {
+	start, end, err := parseReportWindow(c)
+	if err != nil {
+		respondBadRequest(c, "Invalid date range", err)
+		return
+	}
+
+	report, err := rc.reportingService.StrategyPerformance(c.Request.Context(), start, end)
+	if err != nil {
+		respondServiceError(c, "Failed to build strategy report", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleGetBookPerformance returns P&L, win rate and exposure per book
+// GET /api/v1/reports/books?start=2025-01-01&end=2025-02-01 (or ?days=30)
+func (rc *ReportController) HandleGetBookPerformance(c *gin.Context) {
+	start, end, err := parseReportWindow(c)
+	if err != nil {
+		respondBadRequest(c, "Invalid date range", err)
+		return
+	}
+
+	report, err := rc.reportingService.BookPerformance(c.Request.Context(), start, end)
+	if err != nil {
+		respondServiceError(c, "Failed to build book report", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleGetBenchmarkComparison compares the account equity curve against benchmarks
+// GET /api/v1/reports/benchmark?days=90&benchmarks=SPY,QQQ
+func (rc *ReportController) HandleGetBenchmarkComparison(c *gin.Context) {
+	start, end, err := parseReportWindow(c)
+	if err != nil {
+		respondBadRequest(c, "Invalid date range", err)
+		return
+	}
+
+	benchmarks := rc.benchmarks
+	if symbols := c.Query("benchmarks"); symbols != "" {
+		benchmarks = strings.Split(symbols, ",")
+	}
+
+	report, err := rc.reportingService.BenchmarkComparison(c.Request.Context(), start, end, benchmarks)
+	if err != nil {
+		respondServiceError(c, "Failed to build benchmark comparison", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleGetHeatmap returns a sector treemap of positions or a watchlist
+// GET /api/v1/reports/heatmap?view=portfolio (or ?view=market&symbols=SPY,QQQ)
+func (rc *ReportController) HandleGetHeatmap(c *gin.Context) {
+	var (
+		heatmap *services.Heatmap
+		err     error
+	)
+	switch view := c.DefaultQuery("view", services.HeatmapPortfolio); view {
+	case services.HeatmapPortfolio:
+		heatmap, err = rc.reportingService.PortfolioHeatmap(c.Request.Context())
+	case services.HeatmapMarket:
+		symbols := rc.heatmapWatchlist
+		if s := c.Query("symbols"); s != "" {
+			symbols = strings.Split(s, ",")
+		}
+		heatmap, err = rc.reportingService.MarketHeatmap(c.Request.Context(), symbols)
+	default:
+		respondBadRequest(c, fmt.Sprintf("view must be %q or %q", services.HeatmapPortfolio, services.HeatmapMarket), nil)
+		return
+	}
+	if err != nil {
+		respondServiceError(c, "Failed to build heatmap", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, heatmap)
+}
+
+// HandleGetPremarketScan returns the scan published before today's open,
+// ranked by score, gap, volume or news. A fresh scan is run with
+// refresh=true, for the given symbols, or before the first publication.
+// GET /api/v1/screener/premarket?sort=gap&limit=10 (or ?refresh=true&symbols=AAPL,TSLA)
+func (rc *ReportController) HandleGetPremarketScan(c *gin.Context) {
+	if rc.premarket == nil {
+		respondError(c, services.ErrCodeUnavailable, "pre-market scanner not enabled", "")
+		return
+	}
+
+	sortBy := c.DefaultQuery("sort", services.PremarketSortScore)
+	switch sortBy {
+	case services.PremarketSortScore, services.PremarketSortGap, services.PremarketSortVolume, services.PremarketSortNews:
+	default:
+		respondBadRequest(c, "sort must be score, gap, volume or news", nil)
+		return
+	}
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			respondBadRequest(c, "limit must be a positive integer", nil)
+			return
+		}
+		limit = l
+	}
+
+	var symbols []string
+	if s := c.Query("symbols"); s != "" {
+		symbols = strings.Split(s, ",")
+	}
+	scan := rc.premarket.Latest()
+	if scan == nil || len(symbols) > 0 || c.Query("refresh") == "true" {
+		var err error
+		scan, err = rc.premarket.Scan(c.Request.Context(), symbols)
+		if err != nil {
+			respondServiceError(c, "Failed to scan pre-market movers", err)
+			return
+		}
+	}
+
+	// The published scan is shared, so it is ordered and cut on a copy
+	result := *scan
+	result.Movers = append([]*services.PremarketMover(nil), scan.Movers...)
+	services.SortPremarketMovers(result.Movers, sortBy)
+	if limit > 0 && limit < len(result.Movers) {
+		result.Movers = result.Movers[:limit]
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// HandleGetShadowComparison compares simulated shadow orders with actual fills
+// GET /api/v1/reports/shadow?days=30&match_minutes=60
+func (rc *ReportController) HandleGetShadowComparison(c *gin.Context) {
+	start, end, err := parseReportWindow(c)
+	if err != nil {
+		respondBadRequest(c, "Invalid date range", err)
+		return
+	}
+
+	matchMinutes := 60
+	if minutesStr := c.Query("match_minutes"); minutesStr != "" {
+		m, err := strconv.Atoi(minutesStr)
+		if err != nil || m <= 0 {
+			respondBadRequest(c, "match_minutes must be a positive integer", nil)
+			return
+		}
+		matchMinutes = m
+	}
+
+	report, err := rc.reportingService.ShadowComparison(c.Request.Context(), start, end, time.Duration(matchMinutes)*time.Minute)
+	if err != nil {
+		respondServiceError(c, "Failed to build shadow comparison", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleGetTimingPerformance buckets realized P&L by time of day and weekday
+// GET /api/v1/reports/timing?days=90&bucket_minutes=30&by=entry
+func (rc *ReportController) HandleGetTimingPerformance(c *gin.Context) {
+	start, end, err := parseReportWindow(c)
+	if err != nil {
+		respondBadRequest(c, "Invalid date range", err)
+		return
+	}
+
+	bucketMinutes := 30
+	if minutesStr := c.Query("bucket_minutes"); minutesStr != "" {
+		m, err := strconv.Atoi(minutesStr)
+		if err != nil {
+			respondBadRequest(c, "bucket_minutes must be an integer", nil)
+			return
+		}
+		bucketMinutes = m
+	}
+
+	report, err := rc.reportingService.TimingPerformance(c.Request.Context(), start, end, c.DefaultQuery("by", services.TimingByEntry), bucketMinutes)
+	if err != nil {
+		respondServiceError(c, "Failed to build timing report", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleGetPortfolioRisk returns correlation, concentration and beta of current holdings
+// GET /api/v1/reports/portfolio-risk?lookback_days=90
+func (rc *ReportController) HandleGetPortfolioRisk(c *gin.Context) {
+	lookback := 90
+	if lookbackStr := c.Query("lookback_days"); lookbackStr != "" {
+		d, err := strconv.Atoi(lookbackStr)
+		if err != nil || d < 10 {
+			respondBadRequest(c, "lookback_days must be an integer >= 10", nil)
+			return
+		}
+		lookback = d
+	}
+
+	report, err := rc.portfolioRiskService.Analyze(c.Request.Context(), lookback)
+	if err != nil {
+		respondServiceError(c, "Failed to analyze portfolio risk", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseReportWindow reads start/end dates or a days lookback, defaulting to 30 days
+func parseReportWindow(c *gin.Context) (time.Time, time.Time, error) {
+	end := time.Now()
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("days must be a positive integer")
+		}
+		days = d
+	}
+	start := end.AddDate(0, 0, -days)
+
+	if startStr := c.Query("start"); startStr != "" {
+		t, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		start = t
+	}
+	if endStr := c.Query("end"); endStr != "" {
+		t, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		// Include the whole end day
+		end = t.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	return start, end, nil
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"prophet-trader/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RiskController handles portfolio risk analysis endpoints
+type RiskController struct {
+	stressTestService *services.StressTestService
+	marginMonitor     *services.MarginMonitor
+	symbolRules       *services.SymbolRuleService
+}
+
+// NewRiskController creates a new risk controller
+func NewRiskController(stressTestService *services.StressTestService) *RiskController {
+	return &RiskController{
+		stressTestService: stressTestService,
+	}
+}
+
+// SetMarginMonitor serves margin utilization and its history
+func (rc *RiskController) SetMarginMonitor(monitor *services.MarginMonitor) {
+	rc.marginMonitor = monitor
+}
+
+// SetSymbolRules serves the per-symbol trading rules
+func (rc *RiskController) SetSymbolRules(rules *services.SymbolRuleService) {
+	rc.symbolRules = rules
+}
+
+// HandleStressTest applies shock scenarios to current positions and returns
+// per-position P&L impact plus historical VaR. An empty body runs the
+// default scenarios.
+// POST /api/v1/risk/stress
+func (rc *RiskController) HandleStressTest(c *gin.Context) {
+	var req services.StressRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		respondBindError(c, err)
+		return
+	}
+
+	report, err := rc.stressTestService.Run(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, "Stress test failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleGetMargin returns the account's current margin utilization and
+// buying power with their recorded history over a window
+// GET /api/v1/account/margin?days=30
+func (rc *RiskController) HandleGetMargin(c *gin.Context) {
+	if rc.marginMonitor == nil {
+		respondError(c, services.ErrCodeUnavailable, "margin monitoring not enabled", "")
+		return
+	}
+
+	start, end, err := parseReportWindow(c)
+	if err != nil {
+		respondBadRequest(c, "Invalid date range", err)
+		return
+	}
+
+	report, err := rc.marginMonitor.Report(c.Request.Context(), start, end)
+	if err != nil {
+		respondServiceError(c, "Failed to get margin report", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// HandleListSymbolRules lists the stored per-symbol trading rules and the
+// configured blocklist
+// GET /api/v1/risk/symbol-rules
+func (rc *RiskController) HandleListSymbolRules(c *gin.Context) {
+	if rc.symbolRules == nil {
+		respondError(c, services.ErrCodeUnavailable, "symbol rules not enabled", "")
+		return
+	}
+
+	rules := rc.symbolRules.List()
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(rules),
+		"rules": rules,
+	})
+}
+
+// HandleGetSymbolRule returns the rule that applies to a symbol, including
+// the configured blocklist and minimum price
+// GET /api/v1/risk/symbol-rules/:symbol
+func (rc *RiskController) HandleGetSymbolRule(c *gin.Context) {
+	if rc.symbolRules == nil {
+		respondError(c, services.ErrCodeUnavailable, "symbol rules not enabled", "")
+		return
+	}
+
+	c.JSON(http.StatusOK, rc.symbolRules.Effective(c.Param("symbol")))
+}
+
+// HandleSaveSymbolRule creates or replaces a symbol's trading rule
+// PUT /api/v1/risk/symbol-rules/:symbol
+func (rc *RiskController) HandleSaveSymbolRule(c *gin.Context) {
+	if rc.symbolRules == nil {
+		respondError(c, services.ErrCodeUnavailable, "symbol rules not enabled", "")
+		return
+	}
+
+	var rule services.SymbolRule
+	if !bindJSON(c, &rule) {
+		return
+	}
+	rule.Symbol = c.Param("symbol")
+
+	effective, err := rc.symbolRules.Save(&rule)
+	if err != nil {
+		respondServiceError(c, "Failed to save symbol rule", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, effective)
+}
+
+// HandleDeleteSymbolRule removes a symbol's stored trading rule
+// DELETE /api/v1/risk/symbol-rules/:symbol
+func (rc *RiskController) HandleDeleteSymbolRule(c *gin.Context) {
+	if rc.symbolRules == nil {
+		respondError(c, services.ErrCodeUnavailable, "symbol rules not enabled", "")
+		return
+	}
+
+	if err := rc.symbolRules.Delete(c.Param("symbol")); err != nil {
+		respondServiceError(c, "Failed to delete symbol rule", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Symbol rule deleted",
+	})
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SymbolAliases answers queries for a ticker that changed as if they named
+// the symbol it trades under now. It rewrites the :symbol path parameter
+// and the symbol and symbols query parameters, and lists each rewrite in
+// the X-Symbol-Aliases header, e.g. "FB=META".
+func SymbolAliases(aliases *services.SymbolAliasService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if aliases == nil {
+			c.Next()
+			return
+		}
+
+		var rewritten []string
+		seen := make(map[string]bool)
+		resolve := func(symbol string) string {
+			resolved, ok := aliases.Resolve(symbol)
+			if rewrite := strings.ToUpper(symbol) + "=" + resolved; ok && !seen[rewrite] {
+				seen[rewrite] = true
+				rewritten = append(rewritten, rewrite)
+			}
+			return resolved
+		}
+
+		for i, param := range c.Params {
+			if param.Key == "symbol" {
+				c.Params[i].Value = resolve(param.Value)
+			}
+		}
+
+		query := c.Request.URL.Query()
+		changed := false
+		if symbol := query.Get("symbol"); symbol != "" {
+			if resolved := resolve(symbol); resolved != symbol {
+				query.Set("symbol", resolved)
+				changed = true
+			}
+		}
+		if symbols := query.Get("symbols"); symbols != "" {
+			list := strings.Split(symbols, ",")
+			for i, symbol := range list {
+				if resolved := resolve(strings.TrimSpace(symbol)); resolved != strings.TrimSpace(symbol) {
+					list[i] = resolved
+					changed = true
+				}
+			}
+			query.Set("symbols", strings.Join(list, ","))
+		}
+		if changed {
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		if len(rewritten) > 0 {
+			c.Writer.Header().Set("X-Symbol-Aliases", strings.Join(rewritten, ","))
+		}
+		c.Next()
+	}
+}
+
+// HandleListSymbolAliases lists the tickers that changed and what moving
+// each one's history changed
+// GET /api/v1/symbols/aliases
+func HandleListSymbolAliases(aliases *services.SymbolAliasService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := aliases.List()
+		if err != nil {
+			respondServiceError(c, "Failed to list symbol aliases", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"count":   len(list),
+			"aliases": list,
+		})
+	}
+}
+
+// HandleAddSymbolAlias records a ticker change and moves the old symbol's
+// stored history and managed positions to the new one
+// POST /api/v1/symbols/aliases
+func HandleAddSymbolAlias(aliases *services.SymbolAliasService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req services.SymbolAliasRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+
+		alias, err := aliases.Add(c.Request.Context(), &req)
+		if err != nil {
+			respondServiceError(c, "Failed to add symbol alias", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, alias)
+	}
+}
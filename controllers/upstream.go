@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"prophet-trader/services"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response headers reporting a request's upstream usage
+const (
+	HeaderUpstreamCalls = "X-Upstream-Calls"
+	HeaderUpstreamTime  = "X-Upstream-Time-Ms"
+	HeaderServerTiming  = "Server-Timing"
+)
+
+// UpstreamUsage counts the broker, market data and AI calls each request
+// makes. The totals are sent as X-Upstream-Calls and X-Upstream-Time-Ms,
+// with a per-upstream Server-Timing breakdown that browser dev tools show,
+// and added to metrics by route. Handlers must pass c.Request.Context() on
+// to the services for their calls to be counted.
+func UpstreamUsage(metrics *services.UpstreamMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		usage := services.NewUpstreamUsage()
+		c.Request = c.Request.WithContext(services.WithUpstreamUsage(c.Request.Context(), usage))
+
+		// Headers have to go out before the body, so they are set on the
+		// first write with the usage up to that point
+		writer := &upstreamUsageWriter{ResponseWriter: c.Writer, usage: usage}
+		c.Writer = writer
+
+		c.Next()
+
+		writer.setHeaders()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.RecordRequest(c.Request.Method, route, time.Since(started), usage.Snapshot())
+	}
+}
+
+// upstreamUsageWriter sets the upstream usage headers just before the
+// response is written
+type upstreamUsageWriter struct {
+	gin.ResponseWriter
+	usage *services.UpstreamUsage
+	once  sync.Once
+}
+
+func (w *upstreamUsageWriter) setHeaders() {
+	w.once.Do(func() {
+		if w.ResponseWriter.Written() {
+			return
+		}
+		stats := w.usage.Snapshot()
+		upstreams := make([]string, 0, len(stats))
+		for upstream := range stats {
+			upstreams = append(upstreams, upstream)
+		}
+		sort.Strings(upstreams)
+
+		calls := 0
+		latency := 0.0
+		timings := make([]string, 0, len(upstreams))
+		for _, upstream := range upstreams {
+			stat := stats[upstream]
+			calls += stat.Calls
+			latency += stat.LatencyMs
+			noun := "calls"
+			if stat.Calls == 1 {
+				noun = "call"
+			}
+			timings = append(timings, fmt.Sprintf("%s;dur=%.1f;desc=\"%d %s\"", upstream, stat.LatencyMs, stat.Calls, noun))
+		}
+
+		header := w.Header()
+		header.Set(HeaderUpstreamCalls, strconv.Itoa(calls))
+		header.Set(HeaderUpstreamTime, strconv.FormatFloat(latency, 'f', 1, 64))
+		if len(timings) > 0 {
+			header.Set(HeaderServerTiming, strings.Join(timings, ", "))
+		}
+	})
+}
+
+func (w *upstreamUsageWriter) WriteHeaderNow() {
+	w.setHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *upstreamUsageWriter) Write(data []byte) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *upstreamUsageWriter) WriteString(s string) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *upstreamUsageWriter) Flush() {
+	w.setHeaders()
+	w.ResponseWriter.Flush()
+}
+
+// HandleUpstreamMetrics reports upstream usage by upstream and by route
+// GET /api/v1/metrics/upstream
+func HandleUpstreamMetrics(metrics *services.UpstreamMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, metrics.Snapshot())
+	}
+}
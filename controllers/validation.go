@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"prophet-trader/services"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+)
+
+// FieldError describes one invalid field of a request body
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, e.g. "qty" or "scenarios[0].name"
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// symbolPattern accepts equity tickers with an optional share class or
+// crypto quote suffix: AAPL, BRK.B, BTC/USD
+var symbolPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]{0,5}([./-][A-Za-z0-9]{1,4})?$`)
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// Report fields by their JSON names
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	// Let numeric rules such as gt=0 apply to decimal quantities and prices
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if d, ok := field.Interface().(decimal.Decimal); ok {
+			f, _ := d.Float64()
+			return f
+		}
+		return nil
+	}, decimal.Decimal{})
+
+	v.RegisterValidation("symbol", func(fl validator.FieldLevel) bool {
+		return symbolPattern.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("occ_symbol", func(fl validator.FieldLevel) bool {
+		return services.IsOCCSymbol(strings.ToUpper(fl.Field().String()))
+	})
+
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		req := sl.Current().Interface().(BuyRequest)
+		validateOrderPrices(sl, req.Type, req.LimitPrice, req.StopPrice)
+	}, BuyRequest{})
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		req := sl.Current().Interface().(SellRequest)
+		validateOrderPrices(sl, req.Type, req.LimitPrice, req.StopPrice)
+	}, SellRequest{})
+}
+
+// validateOrderPrices requires the prices an order type needs
+func validateOrderPrices(sl validator.StructLevel, orderType string, limitPrice, stopPrice *decimal.Decimal) {
+	if (orderType == "limit" || orderType == "stop_limit") && limitPrice == nil {
+		sl.ReportError(limitPrice, "limit_price", "LimitPrice", "required_for_type", orderType)
+	}
+	if (orderType == "stop" || orderType == "stop_limit") && stopPrice == nil {
+		sl.ReportError(stopPrice, "stop_price", "StopPrice", "required_for_type", orderType)
+	}
+}
+
+// bindJSON decodes and validates the request body into obj. On failure it
+// writes a 400 listing every invalid field and returns false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		respondBindError(c, err)
+		return false
+	}
+	return true
+}
+
+// respondBindError writes the error envelope for a body that failed to bind
+func respondBindError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondError(c, services.ErrCodeRequestTooLarge, "Request body too large",
+			fmt.Sprintf("maximum request body size is %d bytes", tooLarge.Limit))
+		return
+	}
+
+	fields := fieldErrors(err)
+	details := err.Error()
+	if len(fields) > 0 {
+		messages := make([]string, len(fields))
+		for i, field := range fields {
+			messages[i] = field.Message
+		}
+		details = strings.Join(messages, "; ")
+	} else if errors.Is(err, io.EOF) {
+		details = "request body is required"
+	}
+
+	c.Set(errorCodeKey, string(services.ErrCodeInvalidRequest))
+	c.JSON(http.StatusBadRequest, ErrorResponse{
+		Code:    services.ErrCodeInvalidRequest,
+		Error:   "Invalid request",
+		Details: details,
+		Fields:  fields,
+	})
+}
+
+// fieldErrors converts validation and decoding errors to field errors
+func fieldErrors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			name := fieldPath(fe.Namespace())
+			fields = append(fields, FieldError{
+				Field:   name,
+				Rule:    fe.Tag(),
+				Message: fieldMessage(name, fe),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		name := typeErr.Field
+		if name == "" {
+			name = "body"
+		}
+		return []FieldError{{
+			Field:   name,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be a %s, got %s", name, jsonTypeName(typeErr.Type), typeErr.Value),
+		}}
+	}
+
+	return nil
+}
+
+// fieldPath drops the struct name from a validator namespace
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func fieldMessage(name string, fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return name + " is required"
+	case "required_if", "required_for_type":
+		return fmt.Sprintf("%s is required when %s", name, requiredCondition(param))
+	case "required_without":
+		return fmt.Sprintf("%s is required when %s is not set", name, jsonName(param))
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", name, param)
+	case "gte":
+		return fmt.Sprintf("%s must be at least %s", name, param)
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s", name, param)
+	case "lte":
+		return fmt.Sprintf("%s must be at most %s", name, param)
+	case "min", "max":
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("%s must be %s %s characters", name, bound, param)
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return fmt.Sprintf("%s must have %s %s entries", name, bound, param)
+		default:
+			return fmt.Sprintf("%s must be %s %s", name, bound, param)
+		}
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", name, strings.ReplaceAll(param, " ", ", "))
+	case "symbol":
+		return fmt.Sprintf("%s must be a ticker symbol such as AAPL or BRK.B, got %q", name, fe.Value())
+	case "occ_symbol":
+		return fmt.Sprintf("%s must be an OCC option symbol such as AAPL250117C00150000, got %q", name, fe.Value())
+	default:
+		return fmt.Sprintf("%s failed the %s rule", name, fe.Tag())
+	}
+}
+
+// requiredCondition renders a required_if param ("Type limit") or an order
+// type reported by validateOrderPrices
+func requiredCondition(param string) string {
+	parts := strings.Fields(param)
+	if len(parts) == 2 {
+		return fmt.Sprintf("%s is %s", jsonName(parts[0]), parts[1])
+	}
+	return "type is " + param
+}
+
+// jsonName converts a Go field name in a validator param to snake case
+func jsonName(field string) string {
+	var b strings.Builder
+	for i, r := range field {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func jsonTypeName(t reflect.Type) string {
+	if t == reflect.TypeOf(decimal.Decimal{}) {
+		return "number"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"net/http"
+	"prophet-trader/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkerController exposes the status of the background workers and lets
+// admins pause, resume and trigger them
+type WorkerController struct {
+	workers *services.WorkerRegistry
+}
+
+// NewWorkerController creates a new worker controller
+func NewWorkerController(workers *services.WorkerRegistry) *WorkerController {
+	return &WorkerController{
+		workers: workers,
+	}
+}
+
+// HandleListWorkers lists every background worker with its last tick,
+// error counts and state, with the number failing and panics in total
+// GET /api/v1/admin/workers
+func (wc *WorkerController) HandleListWorkers(c *gin.Context) {
+	workers := wc.workers.List()
+	var failed int
+	var panics int64
+	for _, worker := range workers {
+		if worker.State == services.WorkerFailed {
+			failed++
+		}
+		panics += worker.Panics
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workers": workers,
+		"count":   len(workers),
+		"failed":  failed,
+		"panics":  panics,
+	})
+}
+
+// HandleGetWorker returns one worker
+// GET /api/v1/admin/workers/:name
+func (wc *WorkerController) HandleGetWorker(c *gin.Context) {
+	worker, err := wc.workers.Get(c.Param("name"))
+	if err != nil {
+		respondServiceError(c, "Failed to get worker", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, worker)
+}
+
+// HandlePauseWorker pauses a worker until it is resumed
+// POST /api/v1/admin/workers/:name/pause
+func (wc *WorkerController) HandlePauseWorker(c *gin.Context) {
+	worker, err := wc.workers.Pause(c.Param("name"))
+	if err != nil {
+		respondServiceError(c, "Failed to pause worker", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, worker)
+}
+
+// HandleResumeWorker resumes a paused worker
+// POST /api/v1/admin/workers/:name/resume
+func (wc *WorkerController) HandleResumeWorker(c *gin.Context) {
+	worker, err := wc.workers.Resume(c.Param("name"))
+	if err != nil {
+		respondServiceError(c, "Failed to resume worker", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, worker)
+}
+
+// HandleTriggerWorker runs a periodic worker's next pass now. The pass runs
+// in the background; poll the worker for its outcome.
+// POST /api/v1/admin/workers/:name/trigger
+func (wc *WorkerController) HandleTriggerWorker(c *gin.Context) {
+	worker, err := wc.workers.Trigger(c.Param("name"))
+	if err != nil {
+		respondServiceError(c, "Failed to trigger worker", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, worker)
+}
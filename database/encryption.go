@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// encryptedPrefix marks a column value sealed with the field cipher, so
+// plaintext rows written before encryption was enabled still read back
+const encryptedPrefix = "enc:v1:"
+
+// fieldCipher seals columns tagged `serializer:encrypted`; nil leaves them
+// in plaintext
+var fieldCipher cipher.AEAD
+
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{})
+}
+
+// ParseEncryptionKey decodes a 256-bit key given as base64 or hex
+func ParseEncryptionKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be 32 bytes encoded as base64 or hex")
+}
+
+// SetEncryptionKey enables AES-256-GCM encryption of sensitive columns. It
+// must be called before the database is opened.
+func SetEncryptionKey(key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("invalid encryption key: %w", err)
+	}
+	fieldCipher = aead
+	return nil
+}
+
+// encryptedSerializer stores string and decimal fields sealed with AES-GCM.
+// The table and column name are bound in as associated data, so a value
+// copied into another column fails to decrypt.
+type encryptedSerializer struct{}
+
+func (encryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plain string
+	switch v := fieldValue.(type) {
+	case string:
+		plain = v
+	case fmt.Stringer:
+		plain = v.String()
+	default:
+		return nil, fmt.Errorf("cannot encrypt %s of type %T", field.Name, fieldValue)
+	}
+
+	if fieldCipher == nil || plain == "" {
+		return plain, nil
+	}
+
+	nonce := make([]byte, fieldCipher.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s: %w", field.Name, err)
+	}
+	sealed := fieldCipher.Seal(nonce, nonce, []byte(plain), columnAAD(field))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+
+	if dbValue != nil {
+		var raw string
+		switch v := dbValue.(type) {
+		case string:
+			raw = v
+		case []byte:
+			raw = string(v)
+		case float64:
+			raw = strconv.FormatFloat(v, 'f', -1, 64)
+		case int64:
+			raw = strconv.FormatInt(v, 10)
+		default:
+			raw = fmt.Sprint(v)
+		}
+
+		if strings.HasPrefix(raw, encryptedPrefix) {
+			plain, err := openColumn(field, raw)
+			if err != nil {
+				return err
+			}
+			raw = plain
+		}
+
+		switch target := fieldValue.Interface().(type) {
+		case *string:
+			*target = raw
+		case sql.Scanner:
+			if err := target.Scan(raw); err != nil {
+				return fmt.Errorf("failed to decode %s: %w", field.Name, err)
+			}
+		default:
+			return fmt.Errorf("cannot decrypt into %s of type %s", field.Name, field.FieldType)
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+func openColumn(field *schema.Field, value string) (string, error) {
+	if fieldCipher == nil {
+		return "", fmt.Errorf("%s.%s is encrypted but no ENCRYPTION_KEY is configured", field.Schema.Table, field.DBName)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < fieldCipher.NonceSize() {
+		return "", fmt.Errorf("failed to decrypt %s.%s: malformed value", field.Schema.Table, field.DBName)
+	}
+	nonce, ciphertext := sealed[:fieldCipher.NonceSize()], sealed[fieldCipher.NonceSize():]
+	plain, err := fieldCipher.Open(nil, nonce, ciphertext, columnAAD(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s.%s: wrong key or tampered value", field.Schema.Table, field.DBName)
+	}
+	return string(plain), nil
+}
+
+func columnAAD(field *schema.Field) []byte {
+	return []byte(field.Schema.Table + "." + field.DBName)
+}
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS social_mentions;
+DROP TABLE IF EXISTS filings;
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS order_audits;
+DROP TABLE IF EXISTS managed_positions;
+DROP TABLE IF EXISTS signals;
+DROP TABLE IF EXISTS account_snapshots;
+DROP TABLE IF EXISTS trades;
+DROP TABLE IF EXISTS positions;
+DROP TABLE IF EXISTS bars;
+DROP TABLE IF EXISTS orders;
@@ -0,0 +1,273 @@
+-- Initial schema, matching the gorm models in models/models.go. SQLite
+-- databases are still migrated by gorm's AutoMigrate; PostgreSQL schema
+-- changes need a new numbered migration here.
+
+CREATE TABLE IF NOT EXISTS orders (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    order_id TEXT,
+    symbol TEXT,
+    qty DECIMAL(20,8),
+    side TEXT,
+    type TEXT,
+    time_in_force TEXT,
+    limit_price DECIMAL(20,8),
+    stop_price DECIMAL(20,8),
+    status TEXT,
+    filled_qty DECIMAL(20,8),
+    filled_avg_price DECIMAL(20,8),
+    submitted_at TIMESTAMPTZ,
+    filled_at TIMESTAMPTZ,
+    canceled_at TIMESTAMPTZ,
+    strategy_name TEXT,
+    metadata TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_order_id ON orders (order_id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status);
+CREATE INDEX IF NOT EXISTS idx_orders_symbol ON orders (symbol);
+
+CREATE TABLE IF NOT EXISTS bars (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    symbol TEXT,
+    timestamp TIMESTAMPTZ,
+    open DOUBLE PRECISION,
+    high DOUBLE PRECISION,
+    low DOUBLE PRECISION,
+    close DOUBLE PRECISION,
+    volume BIGINT,
+    vwap DOUBLE PRECISION,
+    timeframe TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_bars_deleted_at ON bars (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_symbol_timestamp ON bars (symbol, timestamp);
+
+CREATE TABLE IF NOT EXISTS positions (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    symbol TEXT,
+    qty DECIMAL(20,8),
+    avg_entry_price DECIMAL(20,8),
+    market_value DECIMAL(20,8),
+    cost_basis DECIMAL(20,8),
+    unrealized_pl DECIMAL(20,8),
+    unrealized_plpc DECIMAL(20,8),
+    current_price DECIMAL(20,8),
+    side TEXT,
+    snapshot_time TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_positions_deleted_at ON positions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_positions_snapshot_time ON positions (snapshot_time);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_positions_symbol ON positions (symbol);
+
+CREATE TABLE IF NOT EXISTS trades (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    symbol TEXT,
+    entry_price DECIMAL(20,8),
+    exit_price DECIMAL(20,8),
+    qty DECIMAL(20,8),
+    side TEXT,
+    pn_l DECIMAL(20,8),
+    pn_l_percent DOUBLE PRECISION,
+    entry_time TIMESTAMPTZ,
+    exit_time TIMESTAMPTZ,
+    duration BIGINT,
+    strategy_name TEXT,
+    position_id TEXT,
+    metadata TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_trades_deleted_at ON trades (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_trades_position_id ON trades (position_id);
+CREATE INDEX IF NOT EXISTS idx_trades_symbol ON trades (symbol);
+
+CREATE TABLE IF NOT EXISTS account_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    cash DECIMAL(20,8),
+    portfolio_value DECIMAL(20,8),
+    buying_power DECIMAL(20,8),
+    day_trade_count BIGINT,
+    pattern_day_trader BOOLEAN,
+    snapshot_time TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_account_snapshots_deleted_at ON account_snapshots (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_account_snapshots_snapshot_time ON account_snapshots (snapshot_time);
+
+CREATE TABLE IF NOT EXISTS signals (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    symbol TEXT,
+    signal_type TEXT,
+    strength DOUBLE PRECISION,
+    strategy_name TEXT,
+    reason TEXT,
+    metadata TEXT,
+    executed BOOLEAN,
+    executed_at TIMESTAMPTZ,
+    order_id TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_signals_deleted_at ON signals (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_signals_strategy_name ON signals (strategy_name);
+CREATE INDEX IF NOT EXISTS idx_signals_symbol ON signals (symbol);
+
+CREATE TABLE IF NOT EXISTS managed_positions (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    position_id TEXT,
+    symbol TEXT,
+    side TEXT,
+    strategy TEXT,
+    quantity DOUBLE PRECISION,
+    entry_price DOUBLE PRECISION,
+    entry_order_id TEXT,
+    entry_order_type TEXT,
+    allocation_dollars DOUBLE PRECISION,
+    stop_loss_price DOUBLE PRECISION,
+    stop_loss_percent DOUBLE PRECISION,
+    stop_loss_order_id TEXT,
+    trailing_stop BOOLEAN,
+    trailing_percent DOUBLE PRECISION,
+    take_profit_price DOUBLE PRECISION,
+    take_profit_percent DOUBLE PRECISION,
+    take_profit_order_id TEXT,
+    partial_exit_enabled BOOLEAN,
+    partial_exit_percent DOUBLE PRECISION,
+    partial_exit_target_percent DOUBLE PRECISION,
+    partial_exit_target_price DOUBLE PRECISION,
+    partial_exit_orders TEXT,
+    status TEXT,
+    current_price DOUBLE PRECISION,
+    unrealized_pl DOUBLE PRECISION,
+    unrealized_plpc DOUBLE PRECISION,
+    remaining_qty DOUBLE PRECISION,
+    notes TEXT,
+    tags TEXT,
+    closed_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_managed_positions_deleted_at ON managed_positions (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_managed_positions_position_id ON managed_positions (position_id);
+CREATE INDEX IF NOT EXISTS idx_managed_positions_status ON managed_positions (status);
+CREATE INDEX IF NOT EXISTS idx_managed_positions_symbol ON managed_positions (symbol);
+
+CREATE TABLE IF NOT EXISTS order_audits (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    order_id TEXT,
+    source TEXT,
+    symbol TEXT,
+    side TEXT,
+    asset_class TEXT,
+    outcome TEXT,
+    request_payload TEXT,
+    risk_decision TEXT,
+    response_payload TEXT,
+    error TEXT,
+    latency_ms BIGINT
+);
+CREATE INDEX IF NOT EXISTS idx_order_audits_deleted_at ON order_audits (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_order_audits_order_id ON order_audits (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_audits_outcome ON order_audits (outcome);
+CREATE INDEX IF NOT EXISTS idx_order_audits_source ON order_audits (source);
+CREATE INDEX IF NOT EXISTS idx_order_audits_symbol ON order_audits (symbol);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    alert_id TEXT,
+    symbol TEXT,
+    condition TEXT,
+    threshold DOUBLE PRECISION,
+    window_minutes BIGINT,
+    keyword TEXT,
+    strategy TEXT,
+    repeat BOOLEAN,
+    cooldown_minutes BIGINT,
+    status TEXT,
+    trigger_count BIGINT,
+    last_triggered_at TIMESTAMPTZ,
+    last_value DOUBLE PRECISION,
+    note TEXT
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_alert_id ON alerts (alert_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_deleted_at ON alerts (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_alerts_status ON alerts (status);
+CREATE INDEX IF NOT EXISTS idx_alerts_symbol ON alerts (symbol);
+
+CREATE TABLE IF NOT EXISTS filings (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    accession_number TEXT,
+    symbol TEXT,
+    cik TEXT,
+    form TEXT,
+    filed_at TIMESTAMPTZ,
+    report_date TEXT,
+    items TEXT,
+    description TEXT,
+    url TEXT,
+    summary TEXT,
+    material BOOLEAN
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_filings_accession_number ON filings (accession_number);
+CREATE INDEX IF NOT EXISTS idx_filings_deleted_at ON filings (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_filings_filed_at ON filings (filed_at);
+CREATE INDEX IF NOT EXISTS idx_filings_form ON filings (form);
+CREATE INDEX IF NOT EXISTS idx_filings_symbol ON filings (symbol);
+
+CREATE TABLE IF NOT EXISTS social_mentions (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    source TEXT,
+    post_id TEXT,
+    symbol TEXT,
+    channel TEXT,
+    sentiment DOUBLE PRECISION,
+    engagement BIGINT,
+    url TEXT,
+    posted_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_social_mention ON social_mentions (source, post_id, symbol);
+CREATE INDEX IF NOT EXISTS idx_social_mentions_deleted_at ON social_mentions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_social_mentions_posted_at ON social_mentions (posted_at);
+CREATE INDEX IF NOT EXISTS idx_social_mentions_symbol ON social_mentions (symbol);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    token_id TEXT,
+    name TEXT,
+    role TEXT,
+    token_hash TEXT,
+    prefix TEXT,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_deleted_at ON api_tokens (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_tokens_token_hash ON api_tokens (token_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_tokens_token_id ON api_tokens (token_id);
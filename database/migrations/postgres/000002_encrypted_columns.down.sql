@@ -0,0 +1,5 @@
+-- Fails while any row is still encrypted
+ALTER TABLE account_snapshots
+    ALTER COLUMN cash TYPE DECIMAL(20,8) USING cash::DECIMAL(20,8),
+    ALTER COLUMN portfolio_value TYPE DECIMAL(20,8) USING portfolio_value::DECIMAL(20,8),
+    ALTER COLUMN buying_power TYPE DECIMAL(20,8) USING buying_power::DECIMAL(20,8);
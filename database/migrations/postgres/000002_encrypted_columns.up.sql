@@ -0,0 +1,6 @@
+-- Encrypted values are stored as text, so columns that may hold them can no
+-- longer be numeric
+ALTER TABLE account_snapshots
+    ALTER COLUMN cash TYPE TEXT USING cash::TEXT,
+    ALTER COLUMN portfolio_value TYPE TEXT USING portfolio_value::TEXT,
+    ALTER COLUMN buying_power TYPE TEXT USING buying_power::TEXT;
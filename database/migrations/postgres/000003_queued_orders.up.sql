@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS queued_orders (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    queue_id TEXT,
+    symbol TEXT,
+    qty DECIMAL(20,8),
+    side TEXT,
+    type TEXT,
+    time_in_force TEXT,
+    limit_price DECIMAL(20,8),
+    stop_price DECIMAL(20,8),
+    strategy TEXT,
+    source TEXT,
+    submit_at TIMESTAMPTZ,
+    status TEXT,
+    order_id TEXT,
+    error TEXT,
+    processed_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_queued_orders_deleted_at ON queued_orders (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_queued_orders_queue_id ON queued_orders (queue_id);
+CREATE INDEX IF NOT EXISTS idx_queued_orders_status ON queued_orders (status);
+CREATE INDEX IF NOT EXISTS idx_queued_orders_symbol ON queued_orders (symbol);
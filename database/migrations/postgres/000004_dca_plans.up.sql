@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS dca_plans (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    plan_id TEXT,
+    symbol TEXT,
+    amount DECIMAL(20,8),
+    cadence TEXT,
+    max_total DECIMAL(20,8),
+    skip_risk_off BOOLEAN,
+    status TEXT,
+    next_run_at TIMESTAMPTZ,
+    last_run_at TIMESTAMPTZ,
+    last_skip_reason TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_dca_plans_deleted_at ON dca_plans (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dca_plans_plan_id ON dca_plans (plan_id);
+CREATE INDEX IF NOT EXISTS idx_dca_plans_symbol ON dca_plans (symbol);
+CREATE INDEX IF NOT EXISTS idx_dca_plans_status ON dca_plans (status);
+
+CREATE TABLE IF NOT EXISTS dca_executions (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    plan_id TEXT,
+    symbol TEXT,
+    status TEXT,
+    amount DECIMAL(20,8),
+    qty DECIMAL(20,8),
+    estimated_price DECIMAL(20,8),
+    order_id TEXT,
+    filled_qty DECIMAL(20,8),
+    filled_avg_price DECIMAL(20,8),
+    reason TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_dca_executions_deleted_at ON dca_executions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_dca_executions_plan_id ON dca_executions (plan_id);
+CREATE INDEX IF NOT EXISTS idx_dca_executions_status ON dca_executions (status);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS target_allocations (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    symbol TEXT,
+    weight DOUBLE PRECISION
+);
+CREATE INDEX IF NOT EXISTS idx_target_allocations_deleted_at ON target_allocations (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_target_allocations_symbol ON target_allocations (symbol);
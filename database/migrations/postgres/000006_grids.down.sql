@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS grid_orders;
+DROP TABLE IF EXISTS grids;
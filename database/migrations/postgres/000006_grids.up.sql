@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS grids (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    grid_id TEXT,
+    symbol TEXT,
+    reference_price DECIMAL(20,8),
+    spacing DECIMAL(20,8),
+    levels BIGINT,
+    qty_per_level DECIMAL(20,8),
+    seeded BOOLEAN,
+    status TEXT,
+    realized_pn_l DECIMAL(20,8),
+    round_trips BIGINT,
+    stopped_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_grids_deleted_at ON grids (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_grids_grid_id ON grids (grid_id);
+CREATE INDEX IF NOT EXISTS idx_grids_symbol ON grids (symbol);
+CREATE INDEX IF NOT EXISTS idx_grids_status ON grids (status);
+
+CREATE TABLE IF NOT EXISTS grid_orders (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    grid_id TEXT,
+    level BIGINT,
+    side TEXT,
+    price DECIMAL(20,8),
+    qty DECIMAL(20,8),
+    entry_price DECIMAL(20,8),
+    order_id TEXT,
+    status TEXT,
+    filled_price DECIMAL(20,8),
+    filled_at TIMESTAMPTZ,
+    error TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_grid_orders_deleted_at ON grid_orders (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_grid_orders_grid_id ON grid_orders (grid_id);
+CREATE INDEX IF NOT EXISTS idx_grid_orders_order_id ON grid_orders (order_id);
+CREATE INDEX IF NOT EXISTS idx_grid_orders_status ON grid_orders (status);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_bars_symbol_timeframe_timestamp;
+DROP TABLE IF EXISTS bar_downloads;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS bar_downloads (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    symbol TEXT,
+    timeframe TEXT,
+    start TIMESTAMPTZ,
+    through TIMESTAMPTZ,
+    bars BIGINT
+);
+CREATE INDEX IF NOT EXISTS idx_bar_downloads_deleted_at ON bar_downloads (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bar_downloads_symbol_timeframe ON bar_downloads (symbol, timeframe);
+CREATE INDEX IF NOT EXISTS idx_bars_symbol_timeframe_timestamp ON bars (symbol, timeframe, timestamp);
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_grids_book;
+DROP INDEX IF EXISTS idx_dca_plans_book;
+DROP INDEX IF EXISTS idx_queued_orders_book;
+DROP INDEX IF EXISTS idx_order_audits_book;
+DROP INDEX IF EXISTS idx_managed_positions_book;
+DROP INDEX IF EXISTS idx_trades_book;
+DROP INDEX IF EXISTS idx_orders_book;
+ALTER TABLE grids DROP COLUMN IF EXISTS book;
+ALTER TABLE dca_plans DROP COLUMN IF EXISTS book;
+ALTER TABLE queued_orders DROP COLUMN IF EXISTS book;
+ALTER TABLE order_audits DROP COLUMN IF EXISTS book;
+ALTER TABLE managed_positions DROP COLUMN IF EXISTS book;
+ALTER TABLE trades DROP COLUMN IF EXISTS book;
+ALTER TABLE orders DROP COLUMN IF EXISTS book;
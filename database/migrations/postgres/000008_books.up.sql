@@ -0,0 +1,16 @@
+-- Books split one broker account into logical sub-accounts. Rows written
+-- before books existed belong to the default book.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS book TEXT DEFAULT 'default';
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS book TEXT DEFAULT 'default';
+ALTER TABLE managed_positions ADD COLUMN IF NOT EXISTS book TEXT DEFAULT 'default';
+ALTER TABLE order_audits ADD COLUMN IF NOT EXISTS book TEXT DEFAULT 'default';
+ALTER TABLE queued_orders ADD COLUMN IF NOT EXISTS book TEXT DEFAULT 'default';
+ALTER TABLE dca_plans ADD COLUMN IF NOT EXISTS book TEXT DEFAULT 'default';
+ALTER TABLE grids ADD COLUMN IF NOT EXISTS book TEXT DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS idx_orders_book ON orders (book);
+CREATE INDEX IF NOT EXISTS idx_trades_book ON trades (book);
+CREATE INDEX IF NOT EXISTS idx_managed_positions_book ON managed_positions (book);
+CREATE INDEX IF NOT EXISTS idx_order_audits_book ON order_audits (book);
+CREATE INDEX IF NOT EXISTS idx_queued_orders_book ON queued_orders (book);
+CREATE INDEX IF NOT EXISTS idx_dca_plans_book ON dca_plans (book);
+CREATE INDEX IF NOT EXISTS idx_grids_book ON grids (book);
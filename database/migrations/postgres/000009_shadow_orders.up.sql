@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS shadow_orders (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    shadow_id TEXT,
+    symbol TEXT,
+    asset_class TEXT,
+    side TEXT,
+    type TEXT,
+    qty DECIMAL(20,8),
+    limit_price DECIMAL(20,8),
+    stop_price DECIMAL(20,8),
+    source TEXT,
+    strategy TEXT,
+    book TEXT DEFAULT 'default',
+    quote_price DECIMAL(20,8),
+    status TEXT,
+    filled_price DECIMAL(20,8),
+    filled_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_shadow_orders_deleted_at ON shadow_orders (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shadow_orders_shadow_id ON shadow_orders (shadow_id);
+CREATE INDEX IF NOT EXISTS idx_shadow_orders_symbol ON shadow_orders (symbol);
+CREATE INDEX IF NOT EXISTS idx_shadow_orders_source ON shadow_orders (source);
+CREATE INDEX IF NOT EXISTS idx_shadow_orders_book ON shadow_orders (book);
+CREATE INDEX IF NOT EXISTS idx_shadow_orders_status ON shadow_orders (status);
@@ -0,0 +1,5 @@
+ALTER TABLE queued_orders DROP COLUMN IF EXISTS metadata;
+ALTER TABLE queued_orders DROP COLUMN IF EXISTS tags;
+ALTER TABLE order_audits DROP COLUMN IF EXISTS metadata;
+ALTER TABLE order_audits DROP COLUMN IF EXISTS tags;
+ALTER TABLE orders DROP COLUMN IF EXISTS tags;
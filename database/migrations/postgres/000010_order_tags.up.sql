@@ -0,0 +1,7 @@
+-- Client tags and metadata on orders, kept through the order queue and
+-- copied into the audit trail. orders.metadata already exists.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tags TEXT;
+ALTER TABLE order_audits ADD COLUMN IF NOT EXISTS tags TEXT;
+ALTER TABLE order_audits ADD COLUMN IF NOT EXISTS metadata TEXT;
+ALTER TABLE queued_orders ADD COLUMN IF NOT EXISTS tags TEXT;
+ALTER TABLE queued_orders ADD COLUMN IF NOT EXISTS metadata TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE trades DROP COLUMN IF EXISTS r_multiple;
+ALTER TABLE trades DROP COLUMN IF EXISTS initial_risk;
+ALTER TABLE managed_positions DROP COLUMN IF EXISTS initial_risk;
@@ -0,0 +1,5 @@
+-- Initial risk (1R) of managed positions, and each closed trade's risk and
+-- result in R-multiples
+ALTER TABLE managed_positions ADD COLUMN IF NOT EXISTS initial_risk DOUBLE PRECISION;
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS initial_risk DECIMAL(20,8);
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS r_multiple DOUBLE PRECISION;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS news_rules (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    rule_id TEXT,
+    name TEXT,
+    symbols TEXT,
+    keywords TEXT,
+    direction TEXT,
+    min_sentiment DOUBLE PRECISION,
+    action TEXT,
+    strategy TEXT,
+    cooldown_minutes BIGINT,
+    dry_run BOOLEAN,
+    trigger_count BIGINT,
+    last_triggered_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_news_rules_deleted_at ON news_rules (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_news_rules_rule_id ON news_rules (rule_id);
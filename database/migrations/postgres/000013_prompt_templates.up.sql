@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS prompt_templates (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    name TEXT,
+    version BIGINT,
+    template TEXT,
+    note TEXT,
+    author TEXT,
+    active BOOLEAN
+);
+CREATE INDEX IF NOT EXISTS idx_prompt_templates_deleted_at ON prompt_templates (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompt_template_version ON prompt_templates (name, version);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS analysis_profiles (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    name TEXT,
+    definition TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_analysis_profiles_deleted_at ON analysis_profiles (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_analysis_profiles_name ON analysis_profiles (name);
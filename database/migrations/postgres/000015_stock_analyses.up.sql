@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS stock_analyses (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    symbol TEXT,
+    profile TEXT,
+    composite_score BIGINT,
+    analysis TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_stock_analyses_deleted_at ON stock_analyses (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_stock_analyses_symbol ON stock_analyses (symbol);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS llm_tool_calls (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    source TEXT,
+    tool TEXT,
+    args TEXT,
+    status TEXT,
+    error TEXT,
+    duration_ms BIGINT
+);
+CREATE INDEX IF NOT EXISTS idx_llm_tool_calls_deleted_at ON llm_tool_calls (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_llm_tool_calls_source ON llm_tool_calls (source);
+CREATE INDEX IF NOT EXISTS idx_llm_tool_calls_tool ON llm_tool_calls (tool);
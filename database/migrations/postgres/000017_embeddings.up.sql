@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS embeddings (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    provider TEXT,
+    kind TEXT,
+    item_key TEXT,
+    symbols TEXT,
+    title TEXT,
+    text TEXT,
+    link TEXT,
+    published_at TIMESTAMPTZ,
+    vector BYTEA
+);
+CREATE INDEX IF NOT EXISTS idx_embeddings_deleted_at ON embeddings (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_embedding_item ON embeddings (provider, kind, item_key);
+CREATE INDEX IF NOT EXISTS idx_embeddings_published_at ON embeddings (published_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS activity_events (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    event_id BIGINT,
+    type TEXT,
+    symbol TEXT,
+    book TEXT,
+    data TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_activity_events_deleted_at ON activity_events (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_activity_events_event_id ON activity_events (event_id);
+CREATE INDEX IF NOT EXISTS idx_activity_events_type ON activity_events (type);
+CREATE INDEX IF NOT EXISTS idx_activity_events_symbol ON activity_events (symbol);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS activity_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    date TEXT,
+    environment TEXT,
+    started_at TIMESTAMPTZ,
+    ended_at TIMESTAMPTZ,
+    summary TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_activity_sessions_deleted_at ON activity_sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_activity_sessions_date ON activity_sessions (date);
+CREATE INDEX IF NOT EXISTS idx_activity_sessions_started_at ON activity_sessions (started_at);
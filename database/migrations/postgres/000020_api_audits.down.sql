@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS api_audits;
+DROP FUNCTION IF EXISTS api_audits_immutable();
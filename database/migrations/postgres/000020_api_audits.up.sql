@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS api_audits (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    token_id TEXT,
+    caller TEXT,
+    role TEXT,
+    client_ip TEXT,
+    method TEXT,
+    route TEXT,
+    path TEXT,
+    payload_hash TEXT,
+    payload_size BIGINT,
+    status BIGINT,
+    error_code TEXT,
+    duration_ms BIGINT
+);
+CREATE INDEX IF NOT EXISTS idx_api_audits_created_at ON api_audits (created_at);
+CREATE INDEX IF NOT EXISTS idx_api_audits_token_id ON api_audits (token_id);
+CREATE INDEX IF NOT EXISTS idx_api_audits_route ON api_audits (route);
+CREATE INDEX IF NOT EXISTS idx_api_audits_status ON api_audits (status);
+
+-- Audit records are append-only
+CREATE OR REPLACE FUNCTION api_audits_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'api audit records are immutable';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER api_audits_no_change BEFORE UPDATE OR DELETE ON api_audits
+    FOR EACH ROW EXECUTE FUNCTION api_audits_immutable();
+CREATE TRIGGER api_audits_no_truncate BEFORE TRUNCATE ON api_audits
+    FOR EACH STATEMENT EXECUTE FUNCTION api_audits_immutable();
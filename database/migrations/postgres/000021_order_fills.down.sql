@@ -0,0 +1,2 @@
+ALTER TABLE managed_positions DROP COLUMN IF EXISTS entry_filled_qty;
+DROP TABLE IF EXISTS order_fills;
@@ -0,0 +1,26 @@
+-- Each execution of an order, and how much of a managed position's entry
+-- has filled so far
+CREATE TABLE IF NOT EXISTS order_fills (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    order_id TEXT,
+    execution_id TEXT,
+    symbol TEXT,
+    side TEXT,
+    event TEXT,
+    qty DECIMAL(20,8),
+    price DECIMAL(20,8),
+    filled_qty DECIMAL(20,8),
+    filled_avg_price DECIMAL(20,8),
+    filled_at TIMESTAMPTZ,
+    inferred BOOLEAN
+);
+CREATE INDEX IF NOT EXISTS idx_order_fills_deleted_at ON order_fills (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_order_fills_order_id ON order_fills (order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_fills_execution_id ON order_fills (execution_id);
+CREATE INDEX IF NOT EXISTS idx_order_fills_symbol ON order_fills (symbol);
+CREATE INDEX IF NOT EXISTS idx_order_fills_filled_at ON order_fills (filled_at);
+
+ALTER TABLE managed_positions ADD COLUMN IF NOT EXISTS entry_filled_qty DOUBLE PRECISION;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS algo_slices;
+DROP TABLE IF EXISTS algo_orders;
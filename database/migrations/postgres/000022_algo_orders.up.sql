@@ -0,0 +1,48 @@
+-- Parent orders worked by the TWAP and VWAP execution algorithms, and
+-- their child orders
+CREATE TABLE IF NOT EXISTS algo_orders (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    algo_id TEXT,
+    symbol TEXT,
+    side TEXT,
+    qty DECIMAL(20,8),
+    algo TEXT,
+    limit_price DECIMAL(20,8),
+    max_participation DOUBLE PRECISION,
+    slices BIGINT,
+    arrival_price DECIMAL(20,8),
+    book TEXT DEFAULT 'default',
+    status TEXT,
+    reason TEXT,
+    start_at TIMESTAMPTZ,
+    end_at TIMESTAMPTZ,
+    next_slice_at TIMESTAMPTZ,
+    volume_since TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_algo_orders_deleted_at ON algo_orders (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_algo_orders_algo_id ON algo_orders (algo_id);
+CREATE INDEX IF NOT EXISTS idx_algo_orders_symbol ON algo_orders (symbol);
+CREATE INDEX IF NOT EXISTS idx_algo_orders_book ON algo_orders (book);
+CREATE INDEX IF NOT EXISTS idx_algo_orders_status ON algo_orders (status);
+
+CREATE TABLE IF NOT EXISTS algo_slices (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    algo_id TEXT,
+    symbol TEXT,
+    status TEXT,
+    qty DECIMAL(20,8),
+    market_volume DECIMAL(20,8),
+    order_id TEXT,
+    filled_qty DECIMAL(20,8),
+    filled_avg_price DECIMAL(20,8),
+    reason TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_algo_slices_deleted_at ON algo_slices (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_algo_slices_algo_id ON algo_slices (algo_id);
+CREATE INDEX IF NOT EXISTS idx_algo_slices_status ON algo_slices (status);
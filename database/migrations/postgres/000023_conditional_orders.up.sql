@@ -0,0 +1,34 @@
+-- Orders held locally until a price condition is met
+CREATE TABLE IF NOT EXISTS conditional_orders (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    conditional_id TEXT,
+    symbol TEXT,
+    side TEXT,
+    qty DECIMAL(20,8),
+    type TEXT,
+    limit_price DECIMAL(20,8),
+    time_in_force TEXT,
+    strategy TEXT,
+    book TEXT DEFAULT 'default',
+    trigger_symbol TEXT,
+    condition TEXT,
+    threshold DOUBLE PRECISION,
+    armed BOOLEAN,
+    last_value DOUBLE PRECISION,
+    status TEXT,
+    reason TEXT,
+    expires_at TIMESTAMPTZ,
+    triggered_at TIMESTAMPTZ,
+    trigger_value DOUBLE PRECISION,
+    order_id TEXT,
+    note TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_conditional_orders_deleted_at ON conditional_orders (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_conditional_orders_conditional_id ON conditional_orders (conditional_id);
+CREATE INDEX IF NOT EXISTS idx_conditional_orders_symbol ON conditional_orders (symbol);
+CREATE INDEX IF NOT EXISTS idx_conditional_orders_book ON conditional_orders (book);
+CREATE INDEX IF NOT EXISTS idx_conditional_orders_trigger_symbol ON conditional_orders (trigger_symbol);
+CREATE INDEX IF NOT EXISTS idx_conditional_orders_status ON conditional_orders (status);
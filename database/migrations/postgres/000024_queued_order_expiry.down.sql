@@ -0,0 +1 @@
+ALTER TABLE queued_orders DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,2 @@
+-- Good-till-date expiry for queued orders
+ALTER TABLE queued_orders ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
@@ -0,0 +1,25 @@
+-- Non-trade account activities from the broker: dividends, fees, interest,
+-- journals and transfers
+CREATE TABLE IF NOT EXISTS account_activities (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    activity_id TEXT,
+    type TEXT,
+    category TEXT,
+    date TIMESTAMPTZ,
+    symbol TEXT,
+    qty DECIMAL(20,8),
+    price DECIMAL(20,8),
+    per_share_amount DECIMAL(20,8),
+    net_amount DECIMAL(20,8),
+    description TEXT,
+    status TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_account_activities_deleted_at ON account_activities (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_activities_activity_id ON account_activities (activity_id);
+CREATE INDEX IF NOT EXISTS idx_account_activities_type ON account_activities (type);
+CREATE INDEX IF NOT EXISTS idx_account_activities_category ON account_activities (category);
+CREATE INDEX IF NOT EXISTS idx_account_activities_date ON account_activities (date);
+CREATE INDEX IF NOT EXISTS idx_account_activities_symbol ON account_activities (symbol);
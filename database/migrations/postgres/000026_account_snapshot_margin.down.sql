@@ -0,0 +1,6 @@
+ALTER TABLE account_snapshots
+    DROP COLUMN IF EXISTS maintenance_margin,
+    DROP COLUMN IF EXISTS initial_margin,
+    DROP COLUMN IF EXISTS multiplier,
+    DROP COLUMN IF EXISTS reg_t_buying_power,
+    DROP COLUMN IF EXISTS equity;
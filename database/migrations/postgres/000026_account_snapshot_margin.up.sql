@@ -0,0 +1,8 @@
+-- Margin figures on account snapshots. Balances may be encrypted, so they
+-- are stored as text like the other snapshot balances.
+ALTER TABLE account_snapshots
+    ADD COLUMN IF NOT EXISTS equity TEXT,
+    ADD COLUMN IF NOT EXISTS reg_t_buying_power TEXT,
+    ADD COLUMN IF NOT EXISTS multiplier DECIMAL(20,8),
+    ADD COLUMN IF NOT EXISTS initial_margin TEXT,
+    ADD COLUMN IF NOT EXISTS maintenance_margin TEXT;
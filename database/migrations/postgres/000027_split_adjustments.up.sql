@@ -0,0 +1,18 @@
+-- Stock splits applied to stored position snapshots, managed positions and
+-- trades, with a JSON description of every rescaled value
+CREATE TABLE IF NOT EXISTS split_adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    symbol TEXT,
+    ex_date TEXT,
+    old_rate DOUBLE PRECISION,
+    new_rate DOUBLE PRECISION,
+    ratio DOUBLE PRECISION,
+    source TEXT,
+    changes TEXT,
+    applied_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_split_adjustments_deleted_at ON split_adjustments (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_split_adjustments_symbol_ex_date ON split_adjustments (symbol, ex_date);
@@ -0,0 +1,15 @@
+-- Tickers that changed, mapped to the symbol they trade under now
+CREATE TABLE IF NOT EXISTS symbol_aliases (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    old_symbol TEXT,
+    new_symbol TEXT,
+    effective_date TEXT,
+    note TEXT,
+    migrated TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_symbol_aliases_deleted_at ON symbol_aliases (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_symbol_aliases_old_symbol ON symbol_aliases (old_symbol);
+CREATE INDEX IF NOT EXISTS idx_symbol_aliases_new_symbol ON symbol_aliases (new_symbol);
@@ -0,0 +1,17 @@
+-- Per-symbol trading restrictions checked before every order
+CREATE TABLE IF NOT EXISTS symbol_rules (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    symbol TEXT,
+    blocked BOOLEAN,
+    max_position_qty DECIMAL(20,8),
+    max_position_value DECIMAL(20,8),
+    allowed_order_types TEXT,
+    min_price DECIMAL(20,8),
+    long_only BOOLEAN,
+    note TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_symbol_rules_deleted_at ON symbol_rules (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_symbol_rules_symbol ON symbol_rules (symbol);
@@ -0,0 +1,56 @@
+//go:build postgres
+
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+func init() {
+	openPostgres = func(databaseURL string) (*gorm.DB, error) {
+		if err := migratePostgres(databaseURL); err != nil {
+			return nil, err
+		}
+
+		db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		return db, nil
+	}
+}
+
+// migratePostgres applies pending migrations on a connection of its own, so
+// closing the migrator never closes the pool gorm uses. golang-migrate takes
+// an advisory lock, so instances starting together migrate only once.
+func migratePostgres(databaseURL string) error {
+	source, err := iofs.New(postgresMigrations, "migrations/postgres")
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect for migrations: %w", err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return nil
+}
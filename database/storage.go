@@ -1,13 +1,18 @@
 package database
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"prophet-trader/interfaces"
 	"prophet-trader/models"
+	"strings"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -17,9 +22,40 @@ import (
 // LocalStorage implements the StorageService interface using SQLite
 type LocalStorage struct {
 	db     *gorm.DB
+	path   string
 	logger *logrus.Logger
 }
 
+// openPostgres is set when the binary is built with -tags postgres
+var openPostgres func(databaseURL string) (*gorm.DB, error)
+
+// NewStorage opens the PostgreSQL database at databaseURL when it is set and
+// the SQLite file at dbPath otherwise. Both are served by LocalStorage since
+// every query goes through gorm.
+func NewStorage(databaseURL, dbPath string) (*LocalStorage, error) {
+	if databaseURL == "" {
+		return NewLocalStorage(dbPath)
+	}
+	if openPostgres == nil {
+		return nil, fmt.Errorf("DATABASE_URL is set but this binary was built without PostgreSQL support; rebuild with -tags postgres")
+	}
+
+	db, err := openPostgres(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &LocalStorage{
+		db:     db,
+		logger: logger,
+	}, nil
+}
+
 // NewLocalStorage creates a new local storage service
 func NewLocalStorage(dbPath string) (*LocalStorage, error) {
 	// Ensure the directory exists
@@ -45,10 +81,50 @@ func NewLocalStorage(dbPath string) (*LocalStorage, error) {
 		&models.DBAccountSnapshot{},
 		&models.DBSignal{},
 		&models.DBManagedPosition{},
+		&models.DBOrderAudit{},
+		&models.DBOrderFill{},
+		&models.DBAccountActivity{},
+		&models.DBSplitAdjustment{},
+		&models.DBSymbolAlias{},
+		&models.DBSymbolRule{},
+		&models.DBAlert{},
+		&models.DBNewsRule{},
+		&models.DBPromptTemplate{},
+		&models.DBAnalysisProfile{},
+		&models.DBStockAnalysis{},
+		&models.DBLLMToolCall{},
+		&models.DBEmbedding{},
+		&models.DBActivityEvent{},
+		&models.DBActivitySession{},
+		&models.DBFiling{},
+		&models.DBSocialMention{},
+		&models.DBAPIToken{},
+		&models.DBQueuedOrder{},
+		&models.DBDCAPlan{},
+		&models.DBDCAExecution{},
+		&models.DBConditionalOrder{},
+		&models.DBAlgoOrder{},
+		&models.DBAlgoSlice{},
+		&models.DBTargetAllocation{},
+		&models.DBGrid{},
+		&models.DBGridOrder{},
+		&models.DBBarDownload{},
+		&models.DBShadowOrder{},
+		&models.DBAPIAudit{},
 	); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// API audit records are append-only
+	for _, trigger := range []string{
+		"CREATE TRIGGER IF NOT EXISTS api_audits_no_update BEFORE UPDATE ON api_audits BEGIN SELECT RAISE(ABORT, 'api audit records are immutable'); END",
+		"CREATE TRIGGER IF NOT EXISTS api_audits_no_delete BEFORE DELETE ON api_audits BEGIN SELECT RAISE(ABORT, 'api audit records are immutable'); END",
+	} {
+		if err := db.Exec(trigger).Error; err != nil {
+			return nil, fmt.Errorf("failed to protect API audit log: %w", err)
+		}
+	}
+
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
@@ -56,10 +132,112 @@ func NewLocalStorage(dbPath string) (*LocalStorage, error) {
 
 	return &LocalStorage{
 		db:     db,
+		path:   dbPath,
 		logger: logger,
 	}, nil
 }
 
+// Path returns the database file path, empty for PostgreSQL
+func (s *LocalStorage) Path() string {
+	return s.path
+}
+
+// Dialect returns the database in use, "sqlite" or "postgres"
+func (s *LocalStorage) Dialect() string {
+	return s.db.Dialector.Name()
+}
+
+// SQLDB returns the connection pool under gorm, for callers that need a
+// dedicated connection such as a session-level advisory lock
+func (s *LocalStorage) SQLDB() (*sql.DB, error) {
+	return s.db.DB()
+}
+
+// BackupTo writes a consistent copy of the database to path. VACUUM INTO
+// runs inside a read transaction, so writers are never blocked for long and
+// the copy never contains a half-applied write.
+func (s *LocalStorage) BackupTo(path string) error {
+	if s.Dialect() != "sqlite" {
+		return fmt.Errorf("database backups are only supported for SQLite; use pg_dump for %s", s.Dialect())
+	}
+	if err := s.db.Exec("VACUUM INTO ?", path).Error; err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// EncryptExistingRecords seals sensitive columns in rows written before
+// encryption was enabled. It does nothing without an encryption key.
+func (s *LocalStorage) EncryptExistingRecords() (int, error) {
+	if fieldCipher == nil {
+		return 0, nil
+	}
+
+	pending := func(columns ...string) string {
+		conditions := make([]string, len(columns))
+		for i, column := range columns {
+			conditions[i] = fmt.Sprintf("(CAST(%s AS TEXT) <> '' AND CAST(%s AS TEXT) NOT LIKE '%s%%')", column, column, encryptedPrefix)
+		}
+		return strings.Join(conditions, " OR ")
+	}
+
+	total := 0
+	var snapshots []*models.DBAccountSnapshot
+	result := s.db.Unscoped().Where(pending("cash", "portfolio_value", "buying_power")).
+		FindInBatches(&snapshots, 500, func(tx *gorm.DB, batch int) error {
+			for _, snapshot := range snapshots {
+				if err := s.db.Unscoped().Model(snapshot).Select("cash", "portfolio_value", "buying_power").UpdateColumns(snapshot).Error; err != nil {
+					return err
+				}
+			}
+			total += len(snapshots)
+			return nil
+		})
+	if result.Error != nil {
+		return total, fmt.Errorf("failed to encrypt account snapshots: %w", result.Error)
+	}
+
+	var audits []*models.DBOrderAudit
+	result = s.db.Unscoped().Where(pending("request_payload", "risk_decision", "response_payload")).
+		FindInBatches(&audits, 500, func(tx *gorm.DB, batch int) error {
+			for _, audit := range audits {
+				if err := s.db.Unscoped().Model(audit).Select("request_payload", "risk_decision", "response_payload").UpdateColumns(audit).Error; err != nil {
+					return err
+				}
+			}
+			total += len(audits)
+			return nil
+		})
+	if result.Error != nil {
+		return total, fmt.Errorf("failed to encrypt order audits: %w", result.Error)
+	}
+
+	return total, nil
+}
+
+// VerifyDatabase runs SQLite's integrity check against the database file at
+// path without migrating or otherwise modifying it
+func VerifyDatabase(path string) error {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	var results []string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&results).Error; err != nil {
+		return fmt.Errorf("failed to check database integrity: %w", err)
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("database integrity check failed: %s", strings.Join(results, "; "))
+	}
+	return nil
+}
+
 // SaveBars saves multiple bars to the database
 func (s *LocalStorage) SaveBars(bars []*interfaces.Bar) error {
 	if len(bars) == 0 {
@@ -105,7 +283,89 @@ func (s *LocalStorage) GetBars(symbol string, start, end time.Time) ([]*interfac
 		return nil, fmt.Errorf("failed to get bars: %w", result.Error)
 	}
 
-	// Convert DB bars to interface bars
+	return toInterfaceBars(dbBars), nil
+}
+
+// GetTimeframeBars retrieves bars of one timeframe for a symbol within a
+// time range
+func (s *LocalStorage) GetTimeframeBars(symbol, timeframe string, start, end time.Time) ([]*interfaces.Bar, error) {
+	var dbBars []*models.DBBar
+
+	result := s.db.Where("symbol = ? AND timeframe = ? AND timestamp >= ? AND timestamp <= ?", symbol, timeframe, start, end).
+		Order("timestamp ASC").
+		Find(&dbBars)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get bars: %w", result.Error)
+	}
+
+	return toInterfaceBars(dbBars), nil
+}
+
+// ReplaceBars stores bars of one timeframe for a symbol, replacing those
+// already stored from start up to, not including, end. Replacing a range
+// twice leaves the same rows, so an interrupted download can simply be
+// repeated.
+func (s *LocalStorage) ReplaceBars(symbol, timeframe string, start, end time.Time, bars []*interfaces.Bar) error {
+	dbBars := make([]*models.DBBar, len(bars))
+	for i, bar := range bars {
+		dbBars[i] = &models.DBBar{
+			Symbol:    symbol,
+			Timestamp: bar.Timestamp,
+			Open:      bar.Open,
+			High:      bar.High,
+			Low:       bar.Low,
+			Close:     bar.Close,
+			Volume:    bar.Volume,
+			VWAP:      bar.VWAP,
+			Timeframe: timeframe,
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().
+			Where("symbol = ? AND timeframe = ? AND timestamp >= ? AND timestamp < ?", symbol, timeframe, start, end).
+			Delete(&models.DBBar{}).Error; err != nil {
+			return err
+		}
+		if len(dbBars) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(dbBars, 500).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace bars: %w", err)
+	}
+	return nil
+}
+
+// EachBar calls fn for every stored bar between start and end, ordered by
+// symbol and time, without loading them all at once. No symbols means all.
+func (s *LocalStorage) EachBar(symbols []string, start, end time.Time, fn func(*models.DBBar) error) error {
+	query := s.db.Model(&models.DBBar{}).Where("timestamp >= ? AND timestamp <= ?", start, end)
+	if len(symbols) > 0 {
+		query = query.Where("symbol IN ?", symbols)
+	}
+	rows, err := query.Order("symbol ASC").Order("timestamp ASC").Rows()
+	if err != nil {
+		return fmt.Errorf("failed to read bars: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bar models.DBBar
+		if err := s.db.ScanRows(rows, &bar); err != nil {
+			return fmt.Errorf("failed to read bars: %w", err)
+		}
+		if err := fn(&bar); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// toInterfaceBars converts DB bars to interface bars
+func toInterfaceBars(dbBars []*models.DBBar) []*interfaces.Bar {
 	bars := make([]*interfaces.Bar, len(dbBars))
 	for i, dbBar := range dbBars {
 		bars[i] = &interfaces.Bar{
@@ -119,8 +379,37 @@ func (s *LocalStorage) GetBars(symbol string, start, end time.Time) ([]*interfac
 			VWAP:      dbBar.VWAP,
 		}
 	}
+	return bars
+}
+
+// SaveBarDownload creates or updates the download record for its symbol
+// and timeframe
+func (s *LocalStorage) SaveBarDownload(download *models.DBBarDownload) error {
+	var existing models.DBBarDownload
+	if err := s.db.Where("symbol = ? AND timeframe = ?", download.Symbol, download.Timeframe).First(&existing).Error; err == nil {
+		download.ID = existing.ID
+		download.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(download)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save bar download: %w", result.Error)
+	}
+	return nil
+}
 
-	return bars, nil
+// GetBarDownload retrieves the download record for a symbol and timeframe,
+// nil when nothing has been downloaded
+func (s *LocalStorage) GetBarDownload(symbol, timeframe string) (*models.DBBarDownload, error) {
+	var download models.DBBarDownload
+	result := s.db.Where("symbol = ? AND timeframe = ?", symbol, timeframe).First(&download)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get bar download: %w", result.Error)
+	}
+	return &download, nil
 }
 
 // SaveOrder saves an order to the database
@@ -140,6 +429,29 @@ func (s *LocalStorage) SaveOrder(order *interfaces.Order) error {
 		SubmittedAt:    order.SubmittedAt,
 		FilledAt:       order.FilledAt,
 		CanceledAt:     order.CanceledAt,
+		StrategyName:   order.Strategy,
+		Book:           order.Book,
+		Tags:           EncodeOrderTags(order.Tags),
+		Metadata:       EncodeOrderMetadata(order.Metadata),
+	}
+
+	// Update in place if the order was saved before, keeping its tags
+	var existing models.DBOrder
+	if err := s.db.Where("order_id = ?", order.ID).First(&existing).Error; err == nil {
+		dbOrder.ID = existing.ID
+		dbOrder.CreatedAt = existing.CreatedAt
+		if dbOrder.StrategyName == "" {
+			dbOrder.StrategyName = existing.StrategyName
+		}
+		if dbOrder.Book == "" {
+			dbOrder.Book = existing.Book
+		}
+		if dbOrder.Tags == "" {
+			dbOrder.Tags = existing.Tags
+		}
+		if dbOrder.Metadata == "" {
+			dbOrder.Metadata = existing.Metadata
+		}
 	}
 
 	result := s.db.Save(dbOrder)
@@ -159,6 +471,52 @@ func (s *LocalStorage) GetOrder(orderID string) (*interfaces.Order, error) {
 		return nil, fmt.Errorf("failed to get order: %w", result.Error)
 	}
 
+	return orderFromDB(&dbOrder), nil
+}
+
+// GetOrders retrieves orders by status
+func (s *LocalStorage) GetOrders(status string) ([]*interfaces.Order, error) {
+	var dbOrders []*models.DBOrder
+
+	query := s.db.Model(&models.DBOrder{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("submitted_at DESC").Find(&dbOrders)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", result.Error)
+	}
+
+	orders := make([]*interfaces.Order, len(dbOrders))
+	for i, dbOrder := range dbOrders {
+		orders[i] = orderFromDB(dbOrder)
+	}
+
+	return orders, nil
+}
+
+// GetFilledOrders retrieves the orders that filled between start and end,
+// oldest fill first
+func (s *LocalStorage) GetFilledOrders(start, end time.Time) ([]*interfaces.Order, error) {
+	var dbOrders []*models.DBOrder
+
+	result := s.db.Where("filled_at >= ? AND filled_at <= ?", start, end).
+		Order("filled_at ASC").
+		Find(&dbOrders)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get filled orders: %w", result.Error)
+	}
+
+	orders := make([]*interfaces.Order, len(dbOrders))
+	for i, dbOrder := range dbOrders {
+		orders[i] = orderFromDB(dbOrder)
+	}
+
+	return orders, nil
+}
+
+func orderFromDB(dbOrder *models.DBOrder) *interfaces.Order {
 	return &interfaces.Order{
 		ID:             dbOrder.OrderID,
 		Symbol:         dbOrder.Symbol,
@@ -174,65 +532,81 @@ func (s *LocalStorage) GetOrder(orderID string) (*interfaces.Order, error) {
 		SubmittedAt:    dbOrder.SubmittedAt,
 		FilledAt:       dbOrder.FilledAt,
 		CanceledAt:     dbOrder.CanceledAt,
-	}, nil
+		Strategy:       dbOrder.StrategyName,
+		Book:           dbOrder.Book,
+		Tags:           DecodeOrderTags(dbOrder.Tags),
+		Metadata:       DecodeOrderMetadata(dbOrder.Metadata),
+	}
 }
 
-// GetOrders retrieves orders by status
-func (s *LocalStorage) GetOrders(status string) ([]*interfaces.Order, error) {
-	var dbOrders []*models.DBOrder
-
-	query := s.db.Model(&models.DBOrder{})
-	if status != "" {
-		query = query.Where("status = ?", status)
+// EncodeOrderTags stores order tags as a JSON array, empty when there are none
+func EncodeOrderTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
 	}
+	data, _ := json.Marshal(tags)
+	return string(data)
+}
 
-	result := query.Order("submitted_at DESC").Find(&dbOrders)
-	if result.Error != nil {
-		return nil, fmt.Errorf("failed to get orders: %w", result.Error)
+// DecodeOrderTags reads tags written by EncodeOrderTags
+func DecodeOrderTags(s string) []string {
+	var tags []string
+	if s != "" {
+		json.Unmarshal([]byte(s), &tags)
 	}
+	return tags
+}
 
-	orders := make([]*interfaces.Order, len(dbOrders))
-	for i, dbOrder := range dbOrders {
-		orders[i] = &interfaces.Order{
-			ID:             dbOrder.OrderID,
-			Symbol:         dbOrder.Symbol,
-			Qty:            dbOrder.Qty,
-			Side:           dbOrder.Side,
-			Type:           dbOrder.Type,
-			TimeInForce:    dbOrder.TimeInForce,
-			LimitPrice:     dbOrder.LimitPrice,
-			StopPrice:      dbOrder.StopPrice,
-			Status:         dbOrder.Status,
-			FilledQty:      dbOrder.FilledQty,
-			FilledAvgPrice: dbOrder.FilledAvgPrice,
-			SubmittedAt:    dbOrder.SubmittedAt,
-			FilledAt:       dbOrder.FilledAt,
-			CanceledAt:     dbOrder.CanceledAt,
-		}
+// EncodeOrderMetadata stores order metadata as a JSON object, empty when
+// there is none
+func EncodeOrderMetadata(metadata map[string]string) string {
+	if len(metadata) == 0 {
+		return ""
 	}
+	data, _ := json.Marshal(metadata)
+	return string(data)
+}
 
-	return orders, nil
+// DecodeOrderMetadata reads metadata written by EncodeOrderMetadata
+func DecodeOrderMetadata(s string) map[string]string {
+	var metadata map[string]string
+	if s != "" {
+		json.Unmarshal([]byte(s), &metadata)
+	}
+	return metadata
 }
 
 // CleanupOldData removes data older than the specified time
 func (s *LocalStorage) CleanupOldData(before time.Time) error {
 	s.logger.WithField("before", before).Info("Cleaning up old data")
 
-	// Delete old bars
-	if err := s.db.Where("timestamp < ?", before).Delete(&models.DBBar{}).Error; err != nil {
+	// Delete old bars, keeping downloaded history for backtests
+	if err := s.db.Where("timestamp < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM bar_downloads WHERE bar_downloads.symbol = bars.symbol AND bar_downloads.timeframe = bars.timeframe AND bar_downloads.deleted_at IS NULL)").
+		Delete(&models.DBBar{}).Error; err != nil {
 		return fmt.Errorf("failed to delete old bars: %w", err)
 	}
 
-	// Delete old account snapshots
-	if err := s.db.Where("snapshot_time < ?", before).Delete(&models.DBAccountSnapshot{}).Error; err != nil {
-		return fmt.Errorf("failed to delete old snapshots: %w", err)
-	}
-
 	// Delete old signals
 	if err := s.db.Where("created_at < ?", before).Delete(&models.DBSignal{}).Error; err != nil {
 		return fmt.Errorf("failed to delete old signals: %w", err)
 	}
 
+	// Delete old stock analyses
+	if err := s.db.Where("created_at < ?", before).Delete(&models.DBStockAnalysis{}).Error; err != nil {
+		return fmt.Errorf("failed to delete old stock analyses: %w", err)
+	}
+
+	// Delete old LLM tool call audit records
+	if err := s.db.Where("created_at < ?", before).Delete(&models.DBLLMToolCall{}).Error; err != nil {
+		return fmt.Errorf("failed to delete old LLM tool calls: %w", err)
+	}
+
+	// Delete old activity events
+	if err := s.db.Where("created_at < ?", before).Delete(&models.DBActivityEvent{}).Error; err != nil {
+		return fmt.Errorf("failed to delete old activity events: %w", err)
+	}
+
 	s.logger.Info("Old data cleaned up successfully")
 	return nil
 }
@@ -265,12 +639,17 @@ func (s *LocalStorage) SavePosition(position *interfaces.Position) error {
 // SaveAccountSnapshot saves an account snapshot
 func (s *LocalStorage) SaveAccountSnapshot(account *interfaces.Account) error {
 	dbSnapshot := &models.DBAccountSnapshot{
-		Cash:             account.Cash,
-		PortfolioValue:   account.PortfolioValue,
-		BuyingPower:      account.BuyingPower,
-		DayTradeCount:    account.DayTradeCount,
-		PatternDayTrader: account.PatternDayTrader,
-		SnapshotTime:     time.Now(),
+		Cash:              account.Cash,
+		PortfolioValue:    account.PortfolioValue,
+		BuyingPower:       account.BuyingPower,
+		DayTradeCount:     account.DayTradeCount,
+		PatternDayTrader:  account.PatternDayTrader,
+		Equity:            account.Equity,
+		RegTBuyingPower:   account.RegTBuyingPower,
+		Multiplier:        account.Multiplier,
+		InitialMargin:     account.InitialMargin,
+		MaintenanceMargin: account.MaintenanceMargin,
+		SnapshotTime:      time.Now(),
 	}
 
 	result := s.db.Save(dbSnapshot)
@@ -281,68 +660,1322 @@ func (s *LocalStorage) SaveAccountSnapshot(account *interfaces.Account) error {
 	return nil
 }
 
-// SaveSignal saves a trading signal
-func (s *LocalStorage) SaveSignal(symbol, signalType, strategyName, reason string, strength float64) error {
-	dbSignal := &models.DBSignal{
-		Symbol:       symbol,
-		SignalType:   signalType,
-		Strength:     strength,
-		StrategyName: strategyName,
-		Reason:       reason,
-		Executed:     false,
+// GetPositionSnapshots retrieves the stored position snapshots
+func (s *LocalStorage) GetPositionSnapshots() ([]*models.DBPosition, error) {
+	var dbPositions []*models.DBPosition
+
+	result := s.db.Order("symbol ASC").Find(&dbPositions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get position snapshots: %w", result.Error)
 	}
 
-	result := s.db.Save(dbSignal)
+	return dbPositions, nil
+}
+
+// GetAccountSnapshots retrieves account snapshots within a time range
+func (s *LocalStorage) GetAccountSnapshots(start, end time.Time) ([]*models.DBAccountSnapshot, error) {
+	var snapshots []*models.DBAccountSnapshot
+
+	result := s.db.Where("snapshot_time >= ? AND snapshot_time <= ?", start, end).
+		Order("snapshot_time ASC").
+		Find(&snapshots)
 	if result.Error != nil {
-		return fmt.Errorf("failed to save signal: %w", result.Error)
+		return nil, fmt.Errorf("failed to get account snapshots: %w", result.Error)
 	}
 
-	return nil
+	return snapshots, nil
 }
 
-// SaveManagedPosition saves a managed position to the database
-func (s *LocalStorage) SaveManagedPosition(position *models.DBManagedPosition) error {
-	result := s.db.Save(position)
+// CompactAccountSnapshots downsamples account snapshots taken in
+// [start, end), keeping only the latest snapshot in each bucket. bucket maps
+// a snapshot time to the start of its bucket. Removed rows are hard deleted
+// so compaction actually reclaims space. Returns the number of rows removed.
+func (s *LocalStorage) CompactAccountSnapshots(start, end time.Time, bucket func(time.Time) time.Time) (int, error) {
+	var snapshots []*models.DBAccountSnapshot
+
+	result := s.db.Select("id", "snapshot_time").
+		Where("snapshot_time >= ? AND snapshot_time < ?", start, end).
+		Order("snapshot_time ASC").
+		Find(&snapshots)
 	if result.Error != nil {
-		return fmt.Errorf("failed to save managed position: %w", result.Error)
+		return 0, fmt.Errorf("failed to get account snapshots: %w", result.Error)
 	}
-	return nil
+
+	// Snapshots are sorted, so every snapshot followed by one in the same
+	// bucket is superseded
+	var stale []uint
+	for i := 0; i+1 < len(snapshots); i++ {
+		if bucket(snapshots[i].SnapshotTime).Equal(bucket(snapshots[i+1].SnapshotTime)) {
+			stale = append(stale, snapshots[i].ID)
+		}
+	}
+
+	const batchSize = 500
+	for i := 0; i < len(stale); i += batchSize {
+		batch := stale[i:min(i+batchSize, len(stale))]
+		if err := s.db.Unscoped().Delete(&models.DBAccountSnapshot{}, batch).Error; err != nil {
+			return 0, fmt.Errorf("failed to delete compacted snapshots: %w", err)
+		}
+	}
+
+	return len(stale), nil
 }
 
-// GetManagedPosition retrieves a managed position by ID
-func (s *LocalStorage) GetManagedPosition(positionID string) (*models.DBManagedPosition, error) {
-	var dbPosition models.DBManagedPosition
+// GetTrades retrieves closed trades from the trade ledger within a time range
+func (s *LocalStorage) GetTrades(start, end time.Time) ([]*models.DBTrade, error) {
+	var trades []*models.DBTrade
 
-	result := s.db.Where("position_id = ?", positionID).First(&dbPosition)
+	result := s.db.Where("exit_time >= ? AND exit_time <= ?", start, end).
+		Order("exit_time ASC").
+		Find(&trades)
 	if result.Error != nil {
-		return nil, fmt.Errorf("failed to get managed position: %w", result.Error)
+		return nil, fmt.Errorf("failed to get trades: %w", result.Error)
 	}
 
-	return &dbPosition, nil
+	return trades, nil
 }
 
-// GetAllManagedPositions retrieves all managed positions with optional status filter
-func (s *LocalStorage) GetAllManagedPositions(status string) ([]*models.DBManagedPosition, error) {
-	var dbPositions []*models.DBManagedPosition
+// SaveTrade appends a closed trade to the trade ledger
+func (s *LocalStorage) SaveTrade(trade *models.DBTrade) error {
+	result := s.db.Create(trade)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save trade: %w", result.Error)
+	}
+	return nil
+}
 
-	query := s.db.Model(&models.DBManagedPosition{})
-	if status != "" {
-		query = query.Where("status = ?", status)
+// HasTradeForPosition reports whether the ledger has a trade for a managed position
+func (s *LocalStorage) HasTradeForPosition(positionID string) (bool, error) {
+	var count int64
+	result := s.db.Model(&models.DBTrade{}).Where("position_id = ?", positionID).Count(&count)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to check trade ledger: %w", result.Error)
 	}
+	return count > 0, nil
+}
 
-	result := query.Order("created_at DESC").Find(&dbPositions)
+// GetSymbolTrades retrieves every trade of a symbol in the trade ledger
+func (s *LocalStorage) GetSymbolTrades(symbol string) ([]*models.DBTrade, error) {
+	var trades []*models.DBTrade
+
+	result := s.db.Where("symbol = ?", symbol).Order("exit_time ASC").Find(&trades)
 	if result.Error != nil {
-		return nil, fmt.Errorf("failed to get managed positions: %w", result.Error)
+		return nil, fmt.Errorf("failed to get trades: %w", result.Error)
 	}
 
-	return dbPositions, nil
+	return trades, nil
 }
 
-// DeleteManagedPosition deletes a managed position by ID
-func (s *LocalStorage) DeleteManagedPosition(positionID string) error {
-	result := s.db.Where("position_id = ?", positionID).Delete(&models.DBManagedPosition{})
+// HasSplitAdjustment reports whether a split of symbol on exDate was applied
+func (s *LocalStorage) HasSplitAdjustment(symbol, exDate string) (bool, error) {
+	var count int64
+	result := s.db.Model(&models.DBSplitAdjustment{}).Where("symbol = ? AND ex_date = ?", symbol, exDate).Count(&count)
 	if result.Error != nil {
-		return fmt.Errorf("failed to delete managed position: %w", result.Error)
+		return false, fmt.Errorf("failed to check split adjustments: %w", result.Error)
+	}
+	return count > 0, nil
+}
+
+// GetSplitAdjustments retrieves applied splits, newest first. An empty
+// symbol returns every symbol's.
+func (s *LocalStorage) GetSplitAdjustments(symbol string) ([]*models.DBSplitAdjustment, error) {
+	var adjustments []*models.DBSplitAdjustment
+
+	query := s.db.Order("ex_date DESC, id DESC")
+	if symbol != "" {
+		query = query.Where("symbol = ?", symbol)
+	}
+	if err := query.Find(&adjustments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get split adjustments: %w", err)
+	}
+
+	return adjustments, nil
+}
+
+// ApplySplitAdjustment saves the rescaled position snapshots and trades of a
+// split together with its audit record, so a split is applied exactly once
+func (s *LocalStorage) ApplySplitAdjustment(adjustment *models.DBSplitAdjustment, positions []*models.DBPosition, trades []*models.DBTrade) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, position := range positions {
+			if err := tx.Save(position).Error; err != nil {
+				return err
+			}
+		}
+		for _, trade := range trades {
+			if err := tx.Save(trade).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(adjustment).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply split adjustment: %w", err)
+	}
+	return nil
+}
+
+// SaveSplitAdjustment updates a split's audit record
+func (s *LocalStorage) SaveSplitAdjustment(adjustment *models.DBSplitAdjustment) error {
+	if err := s.db.Save(adjustment).Error; err != nil {
+		return fmt.Errorf("failed to save split adjustment: %w", err)
+	}
+	return nil
+}
+
+// symbolHistoryTables are the tables whose rows move to the new symbol when
+// a ticker changes
+var symbolHistoryTables = []string{
+	"orders", "order_audits", "order_fills", "shadow_orders", "trades", "positions",
+	"managed_positions", "signals", "bars", "bar_downloads", "account_activities",
+	"split_adjustments", "stock_analyses", "activity_events", "filings", "social_mentions",
+}
+
+// symbolDuplicates finds the rows of a table under the old symbol that
+// would collide with a row already stored under the new one: the unique
+// keys, and bars the new symbol already has
+var symbolDuplicates = map[string]string{
+	"positions":         "SELECT 1 FROM positions n WHERE n.symbol = @new",
+	"bars":              "SELECT 1 FROM bars n WHERE n.symbol = @new AND n.timestamp = bars.timestamp AND n.timeframe = bars.timeframe",
+	"bar_downloads":     "SELECT 1 FROM bar_downloads n WHERE n.symbol = @new AND n.timeframe = bar_downloads.timeframe",
+	"split_adjustments": "SELECT 1 FROM split_adjustments n WHERE n.symbol = @new AND n.ex_date = split_adjustments.ex_date",
+	"social_mentions":   "SELECT 1 FROM social_mentions n WHERE n.symbol = @new AND n.source = social_mentions.source AND n.post_id = social_mentions.post_id",
+}
+
+// MigrateSymbol moves the stored history of oldSymbol to alias.NewSymbol
+// and saves the alias, all in one transaction. Rows that would duplicate
+// one already stored under the new symbol are dropped, since the new
+// symbol's copy is the fresher one. It returns the rows moved by table.
+func (s *LocalStorage) MigrateSymbol(alias *models.DBSymbolAlias) (map[string]int64, error) {
+	moved := make(map[string]int64)
+	args := map[string]interface{}{"old": alias.OldSymbol, "new": alias.NewSymbol}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range symbolHistoryTables {
+			if duplicate, ok := symbolDuplicates[table]; ok {
+				if err := tx.Exec("DELETE FROM "+table+" WHERE symbol = @old AND EXISTS ("+duplicate+")", args).Error; err != nil {
+					return fmt.Errorf("%s: %w", table, err)
+				}
+			}
+			result := tx.Exec("UPDATE "+table+" SET symbol = @new WHERE symbol = @old", args)
+			if result.Error != nil {
+				return fmt.Errorf("%s: %w", table, result.Error)
+			}
+			if result.RowsAffected > 0 {
+				moved[table] = result.RowsAffected
+			}
+		}
+
+		data, err := json.Marshal(moved)
+		if err != nil {
+			return err
+		}
+		alias.Migrated = string(data)
+		return tx.Create(alias).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate symbol %s: %w", alias.OldSymbol, err)
+	}
+	return moved, nil
+}
+
+// GetSymbolRules retrieves every per-symbol trading rule
+func (s *LocalStorage) GetSymbolRules() ([]*models.DBSymbolRule, error) {
+	var rules []*models.DBSymbolRule
+	if err := s.db.Order("symbol ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get symbol rules: %w", err)
+	}
+	return rules, nil
+}
+
+// SaveSymbolRule creates or replaces the trading rule of a symbol
+func (s *LocalStorage) SaveSymbolRule(rule *models.DBSymbolRule) error {
+	var existing models.DBSymbolRule
+	err := s.db.Unscoped().Where("symbol = ?", rule.Symbol).First(&existing).Error
+	switch {
+	case err == nil:
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("failed to save symbol rule: %w", err)
+	}
+
+	if err := s.db.Unscoped().Save(rule).Error; err != nil {
+		return fmt.Errorf("failed to save symbol rule: %w", err)
+	}
+	return nil
+}
+
+// DeleteSymbolRule removes the trading rule of a symbol
+func (s *LocalStorage) DeleteSymbolRule(symbol string) error {
+	if err := s.db.Unscoped().Where("symbol = ?", symbol).Delete(&models.DBSymbolRule{}).Error; err != nil {
+		return fmt.Errorf("failed to delete symbol rule: %w", err)
+	}
+	return nil
+}
+
+// GetSymbolAliases retrieves every symbol alias, oldest first
+func (s *LocalStorage) GetSymbolAliases() ([]*models.DBSymbolAlias, error) {
+	var aliases []*models.DBSymbolAlias
+	if err := s.db.Order("id ASC").Find(&aliases).Error; err != nil {
+		return nil, fmt.Errorf("failed to get symbol aliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// SaveSignal saves a trading signal
+func (s *LocalStorage) SaveSignal(symbol, signalType, strategyName, reason string, strength float64) error {
+	dbSignal := &models.DBSignal{
+		Symbol:       symbol,
+		SignalType:   signalType,
+		Strength:     strength,
+		StrategyName: strategyName,
+		Reason:       reason,
+		Executed:     false,
+	}
+
+	result := s.db.Save(dbSignal)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save signal: %w", result.Error)
+	}
+
+	return nil
+}
+
+// GetSignals retrieves up to limit signals created since, newest first
+func (s *LocalStorage) GetSignals(since time.Time, limit int) ([]*models.DBSignal, error) {
+	var signals []*models.DBSignal
+	if err := s.db.Where("created_at >= ?", since).Order("created_at DESC").Limit(limit).Find(&signals).Error; err != nil {
+		return nil, fmt.Errorf("failed to get signals: %w", err)
+	}
+	return signals, nil
+}
+
+// SaveStockAnalysis records a stock analysis
+func (s *LocalStorage) SaveStockAnalysis(analysis *models.DBStockAnalysis) error {
+	result := s.db.Create(analysis)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save stock analysis: %w", result.Error)
+	}
+	return nil
+}
+
+// GetStockAnalyses retrieves up to limit stock analyses made since, newest
+// first. An empty symbol returns every symbol's.
+func (s *LocalStorage) GetStockAnalyses(symbol string, since time.Time, limit int) ([]*models.DBStockAnalysis, error) {
+	var analyses []*models.DBStockAnalysis
+	query := s.db.Where("created_at >= ?", since)
+	if symbol != "" {
+		query = query.Where("symbol = ?", symbol)
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Find(&analyses).Error; err != nil {
+		return nil, fmt.Errorf("failed to get stock analyses: %w", err)
+	}
+	return analyses, nil
+}
+
+// SaveLLMToolCall records a function call made by the LLM
+func (s *LocalStorage) SaveLLMToolCall(call *models.DBLLMToolCall) error {
+	result := s.db.Create(call)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save LLM tool call: %w", result.Error)
+	}
+	return nil
+}
+
+// GetLLMToolCalls retrieves the latest LLM tool calls, newest first
+func (s *LocalStorage) GetLLMToolCalls(limit int) ([]*models.DBLLMToolCall, error) {
+	var calls []*models.DBLLMToolCall
+	if err := s.db.Order("created_at DESC").Limit(limit).Find(&calls).Error; err != nil {
+		return nil, fmt.Errorf("failed to get LLM tool calls: %w", err)
+	}
+	return calls, nil
+}
+
+// APIAuditQuery filters API audit records. Empty fields match everything.
+type APIAuditQuery struct {
+	TokenID  string
+	Method   string
+	Route    string
+	Failed   bool      // only calls answered with a 4xx or 5xx status
+	Since    time.Time // inclusive
+	BeforeID uint      // only records older than this one, for paging
+	Limit    int
+}
+
+// SaveAPIAudit appends an API call to the audit log
+func (s *LocalStorage) SaveAPIAudit(audit *models.DBAPIAudit) error {
+	if err := s.db.Create(audit).Error; err != nil {
+		return fmt.Errorf("failed to save API audit: %w", err)
+	}
+	return nil
+}
+
+// GetAPIAudits retrieves API audit records matching q, newest first
+func (s *LocalStorage) GetAPIAudits(q APIAuditQuery) ([]*models.DBAPIAudit, error) {
+	query := s.db.Order("id DESC").Limit(q.Limit)
+	if q.TokenID != "" {
+		query = query.Where("token_id = ?", q.TokenID)
+	}
+	if q.Method != "" {
+		query = query.Where("method = ?", q.Method)
+	}
+	if q.Route != "" {
+		query = query.Where("route = ?", q.Route)
+	}
+	if q.Failed {
+		query = query.Where("status >= ?", 400)
+	}
+	if !q.Since.IsZero() {
+		query = query.Where("created_at >= ?", q.Since)
+	}
+	if q.BeforeID > 0 {
+		query = query.Where("id < ?", q.BeforeID)
+	}
+
+	var audits []*models.DBAPIAudit
+	if err := query.Find(&audits).Error; err != nil {
+		return nil, fmt.Errorf("failed to get API audits: %w", err)
+	}
+	return audits, nil
+}
+
+// SaveEmbeddings stores semantic index vectors
+func (s *LocalStorage) SaveEmbeddings(embeddings []*models.DBEmbedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+	if err := s.db.CreateInBatches(embeddings, 100).Error; err != nil {
+		return fmt.Errorf("failed to save embeddings: %w", err)
+	}
+	return nil
+}
+
+// GetEmbeddings retrieves a provider's vectors for items published since,
+// oldest first
+func (s *LocalStorage) GetEmbeddings(provider string, since time.Time) ([]*models.DBEmbedding, error) {
+	var embeddings []*models.DBEmbedding
+	if err := s.db.Where("provider = ? AND published_at >= ?", provider, since).Order("published_at ASC").Find(&embeddings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get embeddings: %w", err)
+	}
+	return embeddings, nil
+}
+
+// DeleteEmbeddingsBefore permanently deletes vectors for items published
+// before the cutoff
+func (s *LocalStorage) DeleteEmbeddingsBefore(before time.Time) (int64, error) {
+	result := s.db.Unscoped().Where("published_at < ?", before).Delete(&models.DBEmbedding{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete old embeddings: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// SaveActivityEvent stores an activity event
+func (s *LocalStorage) SaveActivityEvent(event *models.DBActivityEvent) error {
+	if err := s.db.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to save activity event: %w", err)
+	}
+	return nil
+}
+
+// GetActivityEvents retrieves the latest activity events, newest first.
+// Empty eventType or symbol match any.
+func (s *LocalStorage) GetActivityEvents(eventType, symbol string, limit int) ([]*models.DBActivityEvent, error) {
+	query := s.db.Order("created_at DESC").Limit(limit)
+	if eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+	if symbol != "" {
+		query = query.Where("symbol = ?", symbol)
+	}
+
+	var events []*models.DBActivityEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get activity events: %w", err)
+	}
+	return events, nil
+}
+
+// SaveActivitySession creates or updates a trading session summary
+func (s *LocalStorage) SaveActivitySession(session *models.DBActivitySession) error {
+	if err := s.db.Save(session).Error; err != nil {
+		return fmt.Errorf("failed to save activity session: %w", err)
+	}
+	return nil
+}
+
+// GetActivitySessions retrieves the latest trading sessions, newest first
+func (s *LocalStorage) GetActivitySessions(limit int) ([]*models.DBActivitySession, error) {
+	var sessions []*models.DBActivitySession
+	if err := s.db.Order("started_at DESC").Limit(limit).Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get activity sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// SaveManagedPosition saves a managed position to the database
+func (s *LocalStorage) SaveManagedPosition(position *models.DBManagedPosition) error {
+	// Update in place if the position was saved before
+	var existing models.DBManagedPosition
+	if err := s.db.Where("position_id = ?", position.PositionID).First(&existing).Error; err == nil {
+		position.ID = existing.ID
+		position.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(position)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save managed position: %w", result.Error)
+	}
+	return nil
+}
+
+// GetManagedPosition retrieves a managed position by ID
+func (s *LocalStorage) GetManagedPosition(positionID string) (*models.DBManagedPosition, error) {
+	var dbPosition models.DBManagedPosition
+
+	result := s.db.Where("position_id = ?", positionID).First(&dbPosition)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get managed position: %w", result.Error)
+	}
+
+	return &dbPosition, nil
+}
+
+// GetAllManagedPositions retrieves all managed positions with optional status filter
+func (s *LocalStorage) GetAllManagedPositions(status string) ([]*models.DBManagedPosition, error) {
+	var dbPositions []*models.DBManagedPosition
+
+	query := s.db.Model(&models.DBManagedPosition{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at DESC").Find(&dbPositions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get managed positions: %w", result.Error)
+	}
+
+	return dbPositions, nil
+}
+
+// DeleteManagedPosition deletes a managed position by ID
+func (s *LocalStorage) DeleteManagedPosition(positionID string) error {
+	result := s.db.Where("position_id = ?", positionID).Delete(&models.DBManagedPosition{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete managed position: %w", result.Error)
+	}
+	return nil
+}
+
+// SaveOrderAudit records an order attempt
+func (s *LocalStorage) SaveOrderAudit(audit *models.DBOrderAudit) error {
+	result := s.db.Create(audit)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save order audit: %w", result.Error)
+	}
+	return nil
+}
+
+// GetOrderAudits retrieves all audit records for an order, oldest first
+func (s *LocalStorage) GetOrderAudits(orderID string) ([]*models.DBOrderAudit, error) {
+	var audits []*models.DBOrderAudit
+
+	result := s.db.Where("order_id = ?", orderID).Order("created_at ASC").Find(&audits)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get order audits: %w", result.Error)
+	}
+
+	return audits, nil
+}
+
+// SaveOrderFill records an execution. It reports false without saving when
+// the execution was recorded before.
+func (s *LocalStorage) SaveOrderFill(fill *models.DBOrderFill) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.DBOrderFill{}).Where("execution_id = ?", fill.ExecutionID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check order fill: %w", err)
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := s.db.Create(fill).Error; err != nil {
+		return false, fmt.Errorf("failed to save order fill: %w", err)
+	}
+	return true, nil
+}
+
+// GetOrderFills retrieves the executions of an order, oldest first
+func (s *LocalStorage) GetOrderFills(orderID string) ([]*models.DBOrderFill, error) {
+	var fills []*models.DBOrderFill
+
+	result := s.db.Where("order_id = ?", orderID).Order("filled_at ASC, id ASC").Find(&fills)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get order fills: %w", result.Error)
+	}
+
+	return fills, nil
+}
+
+// UpdateOrderFill sets a stored order's running filled quantity, average
+// price and status. Orders that were never stored are left alone.
+func (s *LocalStorage) UpdateOrderFill(orderID, status string, filledQty, avgPrice decimal.Decimal, filledAt *time.Time) error {
+	updates := map[string]interface{}{
+		"filled_qty":       filledQty,
+		"filled_avg_price": avgPrice,
+	}
+	if status != "" {
+		updates["status"] = status
+	}
+	if filledAt != nil {
+		updates["filled_at"] = *filledAt
+	}
+
+	result := s.db.Model(&models.DBOrder{}).Where("order_id = ?", orderID).Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update order fill: %w", result.Error)
+	}
+	return nil
+}
+
+// AccountActivityQuery filters account activities. Empty fields match
+// everything.
+type AccountActivityQuery struct {
+	Types    []string
+	Category string
+	Symbol   string
+	Start    time.Time // inclusive
+	End      time.Time // inclusive
+	Limit    int
+}
+
+// SaveAccountActivity records a broker account activity. It reports false
+// without saving when the activity was recorded before.
+func (s *LocalStorage) SaveAccountActivity(activity *models.DBAccountActivity) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.DBAccountActivity{}).Where("activity_id = ?", activity.ActivityID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check account activity: %w", err)
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := s.db.Create(activity).Error; err != nil {
+		return false, fmt.Errorf("failed to save account activity: %w", err)
+	}
+	return true, nil
+}
+
+// GetAccountActivities retrieves account activities matching q, newest first
+func (s *LocalStorage) GetAccountActivities(q AccountActivityQuery) ([]*models.DBAccountActivity, error) {
+	query := s.db.Order("date DESC").Order("id DESC")
+	if len(q.Types) > 0 {
+		query = query.Where("type IN ?", q.Types)
+	}
+	if q.Category != "" {
+		query = query.Where("category = ?", q.Category)
+	}
+	if q.Symbol != "" {
+		query = query.Where("symbol = ?", q.Symbol)
+	}
+	if !q.Start.IsZero() {
+		query = query.Where("date >= ?", q.Start)
+	}
+	if !q.End.IsZero() {
+		query = query.Where("date <= ?", q.End)
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+
+	var activities []*models.DBAccountActivity
+	if err := query.Find(&activities).Error; err != nil {
+		return nil, fmt.Errorf("failed to get account activities: %w", err)
+	}
+	return activities, nil
+}
+
+// LatestAccountActivityDate returns the date of the newest stored account
+// activity, or the zero time when there is none
+func (s *LocalStorage) LatestAccountActivityDate() (time.Time, error) {
+	var activity models.DBAccountActivity
+	result := s.db.Order("date DESC").Limit(1).Find(&activity)
+	if result.Error != nil {
+		return time.Time{}, fmt.Errorf("failed to get latest account activity: %w", result.Error)
+	}
+	return activity.Date, nil
+}
+
+// SaveAlert creates or updates an alert
+func (s *LocalStorage) SaveAlert(alert *models.DBAlert) error {
+	var existing models.DBAlert
+	if err := s.db.Where("alert_id = ?", alert.AlertID).First(&existing).Error; err == nil {
+		alert.ID = existing.ID
+		alert.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(alert)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save alert: %w", result.Error)
+	}
+	return nil
+}
+
+// GetAlerts retrieves alerts with optional status filter, newest first
+func (s *LocalStorage) GetAlerts(status string) ([]*models.DBAlert, error) {
+	var alerts []*models.DBAlert
+
+	query := s.db.Model(&models.DBAlert{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at DESC").Find(&alerts)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get alerts: %w", result.Error)
+	}
+
+	return alerts, nil
+}
+
+// SaveQueuedOrder creates or updates a queued order
+func (s *LocalStorage) SaveQueuedOrder(order *models.DBQueuedOrder) error {
+	var existing models.DBQueuedOrder
+	if err := s.db.Where("queue_id = ?", order.QueueID).First(&existing).Error; err == nil {
+		order.ID = existing.ID
+		order.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(order)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save queued order: %w", result.Error)
+	}
+	return nil
+}
+
+// GetQueuedOrder retrieves a queued order by its queue ID
+func (s *LocalStorage) GetQueuedOrder(queueID string) (*models.DBQueuedOrder, error) {
+	var order models.DBQueuedOrder
+	result := s.db.Where("queue_id = ?", queueID).First(&order)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get queued order: %w", result.Error)
+	}
+	return &order, nil
+}
+
+// GetQueuedOrders retrieves queued orders with optional status filter, in
+// the order they were accepted
+func (s *LocalStorage) GetQueuedOrders(status string) ([]*models.DBQueuedOrder, error) {
+	var orders []*models.DBQueuedOrder
+
+	query := s.db.Model(&models.DBQueuedOrder{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at ASC").Order("id ASC").Find(&orders)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get queued orders: %w", result.Error)
+	}
+
+	return orders, nil
+}
+
+// SaveConditionalOrder creates or updates a conditional order
+func (s *LocalStorage) SaveConditionalOrder(order *models.DBConditionalOrder) error {
+	var existing models.DBConditionalOrder
+	if err := s.db.Where("conditional_id = ?", order.ConditionalID).First(&existing).Error; err == nil {
+		order.ID = existing.ID
+		order.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(order)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save conditional order: %w", result.Error)
+	}
+	return nil
+}
+
+// GetConditionalOrders retrieves conditional orders with optional status
+// filter, newest first
+func (s *LocalStorage) GetConditionalOrders(status string) ([]*models.DBConditionalOrder, error) {
+	var orders []*models.DBConditionalOrder
+
+	query := s.db.Model(&models.DBConditionalOrder{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at DESC").Order("id DESC").Find(&orders)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get conditional orders: %w", result.Error)
+	}
+
+	return orders, nil
+}
+
+// SaveAlgoOrder creates or updates an algo order
+func (s *LocalStorage) SaveAlgoOrder(order *models.DBAlgoOrder) error {
+	var existing models.DBAlgoOrder
+	if err := s.db.Where("algo_id = ?", order.AlgoID).First(&existing).Error; err == nil {
+		order.ID = existing.ID
+		order.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(order)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save algo order: %w", result.Error)
+	}
+	return nil
+}
+
+// GetAlgoOrder retrieves an algo order by its algo ID
+func (s *LocalStorage) GetAlgoOrder(algoID string) (*models.DBAlgoOrder, error) {
+	var order models.DBAlgoOrder
+	result := s.db.Where("algo_id = ?", algoID).First(&order)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get algo order: %w", result.Error)
+	}
+	return &order, nil
+}
+
+// GetAlgoOrders retrieves algo orders with optional status filter, newest
+// first
+func (s *LocalStorage) GetAlgoOrders(status string) ([]*models.DBAlgoOrder, error) {
+	var orders []*models.DBAlgoOrder
+
+	query := s.db.Model(&models.DBAlgoOrder{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at DESC").Order("id DESC").Find(&orders)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get algo orders: %w", result.Error)
+	}
+
+	return orders, nil
+}
+
+// SaveAlgoSlice creates or updates an algo order's child
+func (s *LocalStorage) SaveAlgoSlice(slice *models.DBAlgoSlice) error {
+	result := s.db.Save(slice)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save algo slice: %w", result.Error)
+	}
+	return nil
+}
+
+// GetAlgoSlices retrieves the children of an algo order, or of all algo
+// orders when algoID is empty, with optional status filter, oldest first
+func (s *LocalStorage) GetAlgoSlices(algoID, status string) ([]*models.DBAlgoSlice, error) {
+	var slices []*models.DBAlgoSlice
+
+	query := s.db.Model(&models.DBAlgoSlice{})
+	if algoID != "" {
+		query = query.Where("algo_id = ?", algoID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at ASC").Order("id ASC").Find(&slices)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get algo slices: %w", result.Error)
+	}
+
+	return slices, nil
+}
+
+// SaveDCAPlan creates or updates a DCA plan
+func (s *LocalStorage) SaveDCAPlan(plan *models.DBDCAPlan) error {
+	var existing models.DBDCAPlan
+	if err := s.db.Where("plan_id = ?", plan.PlanID).First(&existing).Error; err == nil {
+		plan.ID = existing.ID
+		plan.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(plan)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save DCA plan: %w", result.Error)
+	}
+	return nil
+}
+
+// GetDCAPlan retrieves a DCA plan by its plan ID
+func (s *LocalStorage) GetDCAPlan(planID string) (*models.DBDCAPlan, error) {
+	var plan models.DBDCAPlan
+	result := s.db.Where("plan_id = ?", planID).First(&plan)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get DCA plan: %w", result.Error)
+	}
+	return &plan, nil
+}
+
+// GetDCAPlans retrieves DCA plans with optional status filter, oldest first
+func (s *LocalStorage) GetDCAPlans(status string) ([]*models.DBDCAPlan, error) {
+	var plans []*models.DBDCAPlan
+
+	query := s.db.Model(&models.DBDCAPlan{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at ASC").Order("id ASC").Find(&plans)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get DCA plans: %w", result.Error)
+	}
+
+	return plans, nil
+}
+
+// SaveDCAExecution creates or updates a DCA execution
+func (s *LocalStorage) SaveDCAExecution(execution *models.DBDCAExecution) error {
+	result := s.db.Save(execution)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save DCA execution: %w", result.Error)
+	}
+	return nil
+}
+
+// GetDCAExecutions retrieves the executions of a plan, or of all plans when
+// planID is empty, with optional status filter, oldest first
+func (s *LocalStorage) GetDCAExecutions(planID, status string) ([]*models.DBDCAExecution, error) {
+	var executions []*models.DBDCAExecution
+
+	query := s.db.Model(&models.DBDCAExecution{})
+	if planID != "" {
+		query = query.Where("plan_id = ?", planID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at ASC").Order("id ASC").Find(&executions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get DCA executions: %w", result.Error)
+	}
+
+	return executions, nil
+}
+
+// SaveTargetAllocations replaces the target portfolio allocation
+func (s *LocalStorage) SaveTargetAllocations(targets []*models.DBTargetAllocation) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("1 = 1").Delete(&models.DBTargetAllocation{}).Error; err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return nil
+		}
+		return tx.Create(&targets).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save target allocations: %w", err)
+	}
+	return nil
+}
+
+// GetTargetAllocations retrieves the target portfolio allocation
+func (s *LocalStorage) GetTargetAllocations() ([]*models.DBTargetAllocation, error) {
+	var targets []*models.DBTargetAllocation
+
+	result := s.db.Order("symbol ASC").Find(&targets)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get target allocations: %w", result.Error)
+	}
+
+	return targets, nil
+}
+
+// SaveGrid creates or updates a grid
+func (s *LocalStorage) SaveGrid(grid *models.DBGrid) error {
+	var existing models.DBGrid
+	if err := s.db.Where("grid_id = ?", grid.GridID).First(&existing).Error; err == nil {
+		grid.ID = existing.ID
+		grid.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(grid)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save grid: %w", result.Error)
+	}
+	return nil
+}
+
+// GetGrid retrieves a grid by its grid ID
+func (s *LocalStorage) GetGrid(gridID string) (*models.DBGrid, error) {
+	var grid models.DBGrid
+	result := s.db.Where("grid_id = ?", gridID).First(&grid)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get grid: %w", result.Error)
+	}
+	return &grid, nil
+}
+
+// GetGrids retrieves grids with optional status filter, oldest first
+func (s *LocalStorage) GetGrids(status string) ([]*models.DBGrid, error) {
+	var grids []*models.DBGrid
+
+	query := s.db.Model(&models.DBGrid{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at ASC").Order("id ASC").Find(&grids)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get grids: %w", result.Error)
+	}
+
+	return grids, nil
+}
+
+// SaveGridOrder creates or updates a grid order
+func (s *LocalStorage) SaveGridOrder(order *models.DBGridOrder) error {
+	result := s.db.Save(order)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save grid order: %w", result.Error)
+	}
+	return nil
+}
+
+// GetGridOrders retrieves the orders of a grid with optional status filter,
+// oldest first
+func (s *LocalStorage) GetGridOrders(gridID, status string) ([]*models.DBGridOrder, error) {
+	var orders []*models.DBGridOrder
+
+	query := s.db.Where("grid_id = ?", gridID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at ASC").Order("id ASC").Find(&orders)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get grid orders: %w", result.Error)
+	}
+
+	return orders, nil
+}
+
+// SaveShadowOrder creates or updates a shadow order
+func (s *LocalStorage) SaveShadowOrder(order *models.DBShadowOrder) error {
+	var existing models.DBShadowOrder
+	if err := s.db.Where("shadow_id = ?", order.ShadowID).First(&existing).Error; err == nil {
+		order.ID = existing.ID
+		order.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(order)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save shadow order: %w", result.Error)
+	}
+	return nil
+}
+
+// GetShadowOrder retrieves a shadow order by its shadow ID
+func (s *LocalStorage) GetShadowOrder(shadowID string) (*models.DBShadowOrder, error) {
+	var order models.DBShadowOrder
+	result := s.db.Where("shadow_id = ?", shadowID).First(&order)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get shadow order: %w", result.Error)
+	}
+	return &order, nil
+}
+
+// GetShadowOrders retrieves shadow orders placed within a time range with
+// optional status filter, oldest first
+func (s *LocalStorage) GetShadowOrders(status string, start, end time.Time) ([]*models.DBShadowOrder, error) {
+	var orders []*models.DBShadowOrder
+
+	query := s.db.Where("created_at >= ? AND created_at <= ?", start, end)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Order("created_at ASC").Order("id ASC").Find(&orders)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get shadow orders: %w", result.Error)
+	}
+
+	return orders, nil
+}
+
+// SaveNewsRule creates or updates a news rule
+func (s *LocalStorage) SaveNewsRule(rule *models.DBNewsRule) error {
+	var existing models.DBNewsRule
+	if err := s.db.Where("rule_id = ?", rule.RuleID).First(&existing).Error; err == nil {
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(rule)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save news rule: %w", result.Error)
+	}
+	return nil
+}
+
+// GetNewsRules retrieves every news rule, oldest first
+func (s *LocalStorage) GetNewsRules() ([]*models.DBNewsRule, error) {
+	var rules []*models.DBNewsRule
+	if err := s.db.Order("created_at ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get news rules: %w", err)
+	}
+	return rules, nil
+}
+
+// DeleteNewsRule deletes a news rule by ID
+func (s *LocalStorage) DeleteNewsRule(ruleID string) error {
+	result := s.db.Where("rule_id = ?", ruleID).Delete(&models.DBNewsRule{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete news rule: %w", result.Error)
+	}
+	return nil
+}
+
+// SaveAnalysisProfile creates or replaces an analysis profile by name
+func (s *LocalStorage) SaveAnalysisProfile(profile *models.DBAnalysisProfile) error {
+	var existing models.DBAnalysisProfile
+	if err := s.db.Where("name = ?", profile.Name).First(&existing).Error; err == nil {
+		profile.ID = existing.ID
+		profile.CreatedAt = existing.CreatedAt
+	}
+
+	result := s.db.Save(profile)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save analysis profile: %w", result.Error)
+	}
+	return nil
+}
+
+// GetAnalysisProfiles retrieves every analysis profile by name
+func (s *LocalStorage) GetAnalysisProfiles() ([]*models.DBAnalysisProfile, error) {
+	var profiles []*models.DBAnalysisProfile
+	if err := s.db.Order("name ASC").Find(&profiles).Error; err != nil {
+		return nil, fmt.Errorf("failed to get analysis profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// DeleteAnalysisProfile permanently deletes an analysis profile, so the name
+// can be reused
+func (s *LocalStorage) DeleteAnalysisProfile(name string) error {
+	result := s.db.Unscoped().Where("name = ?", name).Delete(&models.DBAnalysisProfile{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete analysis profile: %w", result.Error)
+	}
+	return nil
+}
+
+// CreatePromptTemplateVersion saves template as the next version of name and
+// makes it the active one
+func (s *LocalStorage) CreatePromptTemplateVersion(name, template, note, author string) (*models.DBPromptTemplate, error) {
+	version := &models.DBPromptTemplate{Name: name, Template: template, Note: note, Author: author, Active: true}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&models.DBPromptTemplate{}).Where("name = ?", name).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.DBPromptTemplate{}).Where("name = ?", name).Update("active", false).Error; err != nil {
+			return err
+		}
+		version.Version = latest + 1
+		return tx.Create(version).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save prompt template: %w", err)
+	}
+	return version, nil
+}
+
+// GetPromptTemplateVersions retrieves every version of a prompt template,
+// newest first
+func (s *LocalStorage) GetPromptTemplateVersions(name string) ([]*models.DBPromptTemplate, error) {
+	var versions []*models.DBPromptTemplate
+	if err := s.db.Where("name = ?", name).Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get prompt template versions: %w", err)
+	}
+	return versions, nil
+}
+
+// GetActivePromptTemplates retrieves the active version of every prompt
+// template that has one
+func (s *LocalStorage) GetActivePromptTemplates() ([]*models.DBPromptTemplate, error) {
+	var versions []*models.DBPromptTemplate
+	if err := s.db.Where("active = ?", true).Order("name ASC").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get active prompt templates: %w", err)
+	}
+	return versions, nil
+}
+
+// ActivatePromptTemplateVersion makes version the active version of name.
+// Version 0 deactivates every saved version.
+func (s *LocalStorage) ActivatePromptTemplateVersion(name string, version int) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DBPromptTemplate{}).Where("name = ?", name).Update("active", false).Error; err != nil {
+			return err
+		}
+		if version == 0 {
+			return nil
+		}
+		result := tx.Model(&models.DBPromptTemplate{}).Where("name = ? AND version = ?", name, version).Update("active", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("version %d of %s not found", version, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to activate prompt template: %w", err)
+	}
+	return nil
+}
+
+// DeleteAlert deletes an alert by ID
+func (s *LocalStorage) DeleteAlert(alertID string) error {
+	result := s.db.Where("alert_id = ?", alertID).Delete(&models.DBAlert{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete alert: %w", result.Error)
+	}
+	return nil
+}
+
+// SaveFiling stores a filing, ignoring filings already stored
+func (s *LocalStorage) SaveFiling(filing *models.DBFiling) error {
+	result := s.db.Where("accession_number = ?", filing.AccessionNumber).FirstOrCreate(filing)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save filing: %w", result.Error)
+	}
+	return nil
+}
+
+// HasFiling reports whether a filing with the accession number is stored
+func (s *LocalStorage) HasFiling(accessionNumber string) (bool, error) {
+	var count int64
+	result := s.db.Model(&models.DBFiling{}).Where("accession_number = ?", accessionNumber).Count(&count)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to check filing: %w", result.Error)
+	}
+	return count > 0, nil
+}
+
+// GetFilings retrieves filings for a symbol, newest first, optionally
+// limited to one form type
+func (s *LocalStorage) GetFilings(symbol, form string, limit int) ([]*models.DBFiling, error) {
+	var filings []*models.DBFiling
+
+	query := s.db.Where("symbol = ?", symbol)
+	if form != "" {
+		query = query.Where("form = ?", form)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	result := query.Order("filed_at DESC").Find(&filings)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get filings: %w", result.Error)
+	}
+
+	return filings, nil
+}
+
+// SaveSocialMention stores a mention, refreshing the engagement of one
+// already stored
+func (s *LocalStorage) SaveSocialMention(mention *models.DBSocialMention) error {
+	result := s.db.Where("source = ? AND post_id = ? AND symbol = ?", mention.Source, mention.PostID, mention.Symbol).
+		Assign(models.DBSocialMention{Engagement: mention.Engagement}).
+		FirstOrCreate(mention)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save social mention: %w", result.Error)
+	}
+	return nil
+}
+
+// GetSocialMentions retrieves mentions of a symbol posted since the given time
+func (s *LocalStorage) GetSocialMentions(symbol string, since time.Time) ([]*models.DBSocialMention, error) {
+	var mentions []*models.DBSocialMention
+
+	result := s.db.Where("symbol = ? AND posted_at >= ?", symbol, since).Order("posted_at DESC").Find(&mentions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get social mentions: %w", result.Error)
+	}
+
+	return mentions, nil
+}
+
+// GetAllSocialMentions retrieves mentions posted between start and end,
+// ordered by symbol and time. No symbols means all.
+func (s *LocalStorage) GetAllSocialMentions(symbols []string, start, end time.Time) ([]*models.DBSocialMention, error) {
+	var mentions []*models.DBSocialMention
+
+	query := s.db.Where("posted_at >= ? AND posted_at <= ?", start, end)
+	if len(symbols) > 0 {
+		query = query.Where("symbol IN ?", symbols)
+	}
+	result := query.Order("symbol ASC").Order("posted_at ASC").Find(&mentions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get social mentions: %w", result.Error)
+	}
+
+	return mentions, nil
+}
+
+// CleanupSocialMentions deletes mentions posted before the given time
+func (s *LocalStorage) CleanupSocialMentions(before time.Time) error {
+	result := s.db.Unscoped().Where("posted_at < ?", before).Delete(&models.DBSocialMention{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to clean up social mentions: %w", result.Error)
+	}
+	return nil
+}
+
+// SaveAPIToken stores a new API token
+func (s *LocalStorage) SaveAPIToken(token *models.DBAPIToken) error {
+	result := s.db.Create(token)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save API token: %w", result.Error)
+	}
+	return nil
+}
+
+// GetAPITokenByHash retrieves the token with the given hash
+func (s *LocalStorage) GetAPITokenByHash(hash string) (*models.DBAPIToken, error) {
+	var token models.DBAPIToken
+
+	result := s.db.Where("token_hash = ?", hash).First(&token)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get API token: %w", result.Error)
+	}
+
+	return &token, nil
+}
+
+// GetAPITokens retrieves all API tokens, newest first
+func (s *LocalStorage) GetAPITokens() ([]*models.DBAPIToken, error) {
+	var tokens []*models.DBAPIToken
+
+	result := s.db.Order("created_at DESC").Find(&tokens)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get API tokens: %w", result.Error)
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken marks a token as revoked, reporting whether it existed
+func (s *LocalStorage) RevokeAPIToken(tokenID string, at time.Time) (bool, error) {
+	result := s.db.Model(&models.DBAPIToken{}).Where("token_id = ? AND revoked_at IS NULL", tokenID).Update("revoked_at", at)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to revoke API token: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// TouchAPIToken records when a token was last used
+func (s *LocalStorage) TouchAPIToken(tokenID string, at time.Time) error {
+	result := s.db.Model(&models.DBAPIToken{}).Where("token_id = ?", tokenID).Update("last_used_at", at)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update API token: %w", result.Error)
 	}
 	return nil
 }
@@ -3,6 +3,8 @@ package interfaces
 import (
 	"context"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // TradingService defines the interface for executing trades
@@ -13,6 +15,7 @@ type TradingService interface {
 	ListOrders(ctx context.Context, status string) ([]*Order, error)
 	GetPositions(ctx context.Context) ([]*Position, error)
 	GetAccount(ctx context.Context) (*Account, error)
+	GetPortfolioHistory(ctx context.Context, start, end time.Time) ([]*EquitySnapshot, error)
 
 	// Options trading methods
 	PlaceOptionsOrder(ctx context.Context, order *OptionsOrder) (*OrderResult, error)
@@ -53,31 +56,38 @@ type StrategyExecutor interface {
 }
 
 // Common data structures used across interfaces
+// Monetary and quantity fields use decimal.Decimal so fractional shares and
+// option premiums survive round trips exactly; they marshal to JSON as strings.
 type Order struct {
 	ID            string
+	ClientOrderID string // caller-chosen ID the broker echoes back, used to find orders after a restart
 	Symbol        string
-	Qty           float64
+	Qty           decimal.Decimal
 	Side          string // "buy" or "sell"
 	Type          string // "market", "limit", etc.
 	TimeInForce   string // "day", "gtc", etc.
-	LimitPrice    *float64
-	StopPrice     *float64
+	LimitPrice    *decimal.Decimal
+	StopPrice     *decimal.Decimal
 	Status        string
-	FilledQty     float64
-	FilledAvgPrice *float64
+	FilledQty     decimal.Decimal
+	FilledAvgPrice *decimal.Decimal
 	SubmittedAt   time.Time
 	FilledAt      *time.Time
 	CanceledAt    *time.Time
+	Strategy      string // originating strategy or source label, used for P&L attribution
+	Book          string // logical sub-account the order was placed for
+	Tags          []string          // client labels, echoed on listings and in the audit trail
+	Metadata      map[string]string // client key/values such as a signal ID, echoed like Tags
 }
 
 type OrderRequest struct {
 	Symbol      string
-	Qty         float64
+	Qty         decimal.Decimal
 	Side        string
 	Type        string
 	TimeInForce string
-	LimitPrice  *float64
-	StopPrice   *float64
+	LimitPrice  *decimal.Decimal
+	StopPrice   *decimal.Decimal
 }
 
 type OrderResult struct {
@@ -88,23 +98,35 @@ type OrderResult struct {
 
 type Position struct {
 	Symbol           string
-	Qty              float64
-	AvgEntryPrice    float64
-	MarketValue      float64
-	CostBasis        float64
-	UnrealizedPL     float64
-	UnrealizedPLPC   float64
-	CurrentPrice     float64
+	Qty              decimal.Decimal
+	AvgEntryPrice    decimal.Decimal
+	MarketValue      decimal.Decimal
+	CostBasis        decimal.Decimal
+	UnrealizedPL     decimal.Decimal
+	UnrealizedPLPC   decimal.Decimal
+	CurrentPrice     decimal.Decimal
 	Side             string
 }
 
 type Account struct {
-	ID               string
-	Cash             float64
-	PortfolioValue   float64
-	BuyingPower      float64
-	DayTradeCount    int
-	PatternDayTrader bool
+	ID                string
+	Cash              decimal.Decimal
+	PortfolioValue    decimal.Decimal
+	BuyingPower       decimal.Decimal
+	DayTradeCount     int
+	PatternDayTrader  bool
+	Equity            decimal.Decimal
+	RegTBuyingPower   decimal.Decimal // overnight buying power under Reg T
+	Multiplier        decimal.Decimal // 1 for a cash account, 2 or 4 on margin; zero when not reported
+	InitialMargin     decimal.Decimal
+	MaintenanceMargin decimal.Decimal // zero when the broker doesn't report margin
+}
+
+// EquitySnapshot is the account equity at a point in time, the end of the
+// trading day in daily history
+type EquitySnapshot struct {
+	Timestamp time.Time
+	Equity    decimal.Decimal
 }
 
 type Bar struct {
@@ -147,12 +169,14 @@ type MarketData struct {
 type OptionsOrder struct {
 	Symbol        string  // Options symbol in OCC format (e.g., TSLA251219C00400000)
 	Underlying    string  // Underlying stock symbol
-	Qty           float64
+	Qty           decimal.Decimal
 	Side          string // "buy" or "sell"
 	PositionIntent string // "buy_to_open", "buy_to_close", "sell_to_open", "sell_to_close"
 	Type          string // "market", "limit"
 	TimeInForce   string // "day", "gtc"
-	LimitPrice    *float64
+	LimitPrice    *decimal.Decimal
+	Tags          []string
+	Metadata      map[string]string
 }
 
 type OptionsQuote struct {
@@ -169,13 +193,13 @@ type OptionsQuote struct {
 type OptionsPosition struct {
 	Symbol        string
 	Underlying    string
-	Qty           float64
-	AvgEntryPrice float64
-	MarketValue   float64
-	CostBasis     float64
-	UnrealizedPL  float64
-	UnrealizedPLPC float64
-	CurrentPrice  float64
+	Qty           decimal.Decimal
+	AvgEntryPrice decimal.Decimal
+	MarketValue   decimal.Decimal
+	CostBasis     decimal.Decimal
+	UnrealizedPL  decimal.Decimal
+	UnrealizedPLPC decimal.Decimal
+	CurrentPrice  decimal.Decimal
 	Side          string // "long" or "short"
 	Expiration    time.Time
 	Strike        float64
@@ -14,6 +14,7 @@ import { storeTrade, findSimilarTrades, getTradeStats, getEmbeddingCount } from
 
 // Configuration
 const TRADING_BOT_URL = process.env.TRADING_BOT_URL || 'http://localhost:4534';
+const TRADING_BOT_TOKEN = process.env.TRADING_BOT_TOKEN;
 const GEMINI_API_KEY = process.env.GEMINI_API_KEY;
 const SUMMARIES_DIR = path.join(process.cwd(), 'news_summaries');
 const DECISIONS_DIR = path.join(process.cwd(), 'decisive_actions');
@@ -34,6 +35,9 @@ async function callTradingBot(endpoint, method = 'GET', data = null) {
       url: `${TRADING_BOT_URL}/api/v1${endpoint}`,
       headers: { 'Content-Type': 'application/json' },
     };
+    if (TRADING_BOT_TOKEN) {
+      config.headers.Authorization = `Bearer ${TRADING_BOT_TOKEN}`;
+    }
     if (data) {
       config.data = data;
     }
@@ -79,10 +83,27 @@ server.setRequestHandler(ListToolsRequestSchema, async () => {
       },
       {
         name: 'get_orders',
-        description: 'Get all orders (open, filled, cancelled)',
+        description: 'Get orders (open, filled, cancelled), newest first. Results are paginated; pass pagination.next_cursor back as cursor for more',
         inputSchema: {
           type: 'object',
-          properties: {},
+          properties: {
+            status: {
+              type: 'string',
+              description: 'Filter by status: open, closed or all',
+            },
+            symbol: {
+              type: 'string',
+              description: 'Comma separated symbols to filter by',
+            },
+            limit: {
+              type: 'number',
+              description: 'Orders per page (default: 50, max: 500)',
+            },
+            cursor: {
+              type: 'string',
+              description: 'next_cursor from a previous page',
+            },
+          },
         },
       },
       {
@@ -855,7 +876,15 @@ server.setRequestHandler(CallToolRequestSchema, async (request) => {
       }
 
       case 'get_orders': {
-        const data = await callTradingBot('/orders');
+        let endpoint = '/orders';
+        const params = new URLSearchParams();
+        if (args?.status) params.append('status', args.status);
+        if (args?.symbol) params.append('symbol', args.symbol);
+        if (args?.limit) params.append('limit', args.limit);
+        if (args?.cursor) params.append('cursor', args.cursor);
+        if (params.toString()) endpoint += `?${params.toString()}`;
+
+        const data = await callTradingBot(endpoint);
         return {
           content: [
             {
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -10,22 +11,24 @@ import (
 type DBOrder struct {
 	gorm.Model
 	OrderID        string `gorm:"uniqueIndex"`
-	Symbol         string `gorm:"index"`
-	Qty            float64
+	Symbol         string           `gorm:"index"`
+	Qty            decimal.Decimal  `gorm:"type:decimal(20,8)"`
 	Side           string
 	Type           string
 	TimeInForce    string
-	LimitPrice     *float64
-	StopPrice      *float64
-	Status         string `gorm:"index"`
-	FilledQty      float64
-	FilledAvgPrice *float64
+	LimitPrice     *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	StopPrice      *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	Status         string           `gorm:"index"`
+	FilledQty      decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	FilledAvgPrice *decimal.Decimal `gorm:"type:decimal(20,8)"`
 	SubmittedAt    time.Time
 	FilledAt       *time.Time
 	CanceledAt     *time.Time
 	// Metadata for strategy tracking
 	StrategyName string
 	Metadata     string // JSON string for flexible data
+	Tags         string // JSON array of client tags
+	Book         string `gorm:"index;default:default"` // book the order was placed for
 }
 
 // DBBar represents historical price data in the database
@@ -45,14 +48,14 @@ type DBBar struct {
 // DBPosition represents a position snapshot in the database
 type DBPosition struct {
 	gorm.Model
-	Symbol         string `gorm:"uniqueIndex"`
-	Qty            float64
-	AvgEntryPrice  float64
-	MarketValue    float64
-	CostBasis      float64
-	UnrealizedPL   float64
-	UnrealizedPLPC float64
-	CurrentPrice   float64
+	Symbol         string          `gorm:"uniqueIndex"`
+	Qty            decimal.Decimal `gorm:"type:decimal(20,8)"`
+	AvgEntryPrice  decimal.Decimal `gorm:"type:decimal(20,8)"`
+	MarketValue    decimal.Decimal `gorm:"type:decimal(20,8)"`
+	CostBasis      decimal.Decimal `gorm:"type:decimal(20,8)"`
+	UnrealizedPL   decimal.Decimal `gorm:"type:decimal(20,8)"`
+	UnrealizedPLPC decimal.Decimal `gorm:"type:decimal(20,8)"`
+	CurrentPrice   decimal.Decimal `gorm:"type:decimal(20,8)"`
 	Side           string
 	SnapshotTime   time.Time `gorm:"index"`
 }
@@ -60,29 +63,38 @@ type DBPosition struct {
 // DBTrade represents executed trades for analysis
 type DBTrade struct {
 	gorm.Model
-	Symbol       string `gorm:"index"`
-	EntryPrice   float64
-	ExitPrice    float64
-	Qty          float64
+	Symbol       string          `gorm:"index"`
+	EntryPrice   decimal.Decimal `gorm:"type:decimal(20,8)"`
+	ExitPrice    decimal.Decimal `gorm:"type:decimal(20,8)"`
+	Qty          decimal.Decimal `gorm:"type:decimal(20,8)"`
 	Side         string
-	PnL          float64
+	PnL          decimal.Decimal `gorm:"type:decimal(20,8)"`
 	PnLPercent   float64
 	EntryTime    time.Time
 	ExitTime     time.Time
 	Duration     int64 // seconds
 	StrategyName string
+	PositionID   string `gorm:"index"` // managed position that produced the trade, if any
+	Book         string `gorm:"index;default:default"`
 	Metadata     string
+	InitialRisk  decimal.Decimal `gorm:"type:decimal(20,8)"` // dollars lost had the initial stop filled, zero without a stop
+	RMultiple    *float64        // PnL in units of InitialRisk
 }
 
 // DBAccountSnapshot represents account state at a point in time
 type DBAccountSnapshot struct {
 	gorm.Model
-	Cash             float64
-	PortfolioValue   float64
-	BuyingPower      float64
-	DayTradeCount    int
-	PatternDayTrader bool
-	SnapshotTime     time.Time `gorm:"index"`
+	Cash              decimal.Decimal `gorm:"type:decimal(20,8);serializer:encrypted"`
+	PortfolioValue    decimal.Decimal `gorm:"type:decimal(20,8);serializer:encrypted"`
+	BuyingPower       decimal.Decimal `gorm:"type:decimal(20,8);serializer:encrypted"`
+	DayTradeCount     int
+	PatternDayTrader  bool
+	Equity            decimal.Decimal `gorm:"type:decimal(20,8);serializer:encrypted"`
+	RegTBuyingPower   decimal.Decimal `gorm:"type:decimal(20,8);serializer:encrypted"`
+	Multiplier        decimal.Decimal `gorm:"type:decimal(20,8)"`
+	InitialMargin     decimal.Decimal `gorm:"type:decimal(20,8);serializer:encrypted"`
+	MaintenanceMargin decimal.Decimal `gorm:"type:decimal(20,8);serializer:encrypted"`
+	SnapshotTime      time.Time `gorm:"index"`
 }
 
 // DBSignal represents trading signals for audit/analysis
@@ -106,18 +118,21 @@ type DBManagedPosition struct {
 	Symbol            string `gorm:"index"`
 	Side              string
 	Strategy          string
+	Book              string `gorm:"index;default:default"`
 
 	// Entry details
 	Quantity          float64
 	EntryPrice        float64
 	EntryOrderID      string
 	EntryOrderType    string
+	EntryFilledQty    float64 // entry quantity filled so far
 	AllocationDollars float64
 
 	// Risk management
 	StopLossPrice     float64
 	StopLossPercent   float64
 	StopLossOrderID   string
+	InitialRisk       float64 // per share, entry to the stop at entry
 	TrailingStop      bool
 	TrailingPercent   float64
 
@@ -146,6 +161,452 @@ type DBManagedPosition struct {
 	ClosedAt  *time.Time
 }
 
+// DBOrderAudit records a single order attempt for compliance-style review
+type DBOrderAudit struct {
+	gorm.Model
+	OrderID         string `gorm:"index"` // empty when the order never reached the broker
+	Source          string `gorm:"index"` // "manual", "webhook", "strategy", "ai", "position_manager"
+	Book            string `gorm:"index;default:default"`
+	Symbol          string `gorm:"index"`
+	Side            string
+	AssetClass      string
+	Outcome         string `gorm:"index"` // "submitted", "failed", "rejected", "dry_run", "queued"
+	RequestPayload  string `gorm:"serializer:encrypted"` // JSON of the request sent (or that would be sent) to Alpaca
+	RiskDecision    string `gorm:"serializer:encrypted"` // JSON of the risk check outcome
+	ResponsePayload string `gorm:"serializer:encrypted"` // JSON of the broker response
+	Error           string
+	LatencyMs       int64
+	Tags            string // JSON array of the order's client tags
+	Metadata        string // JSON object of the order's client metadata
+}
+
+// DBOrderFill is one execution of an order, from the broker's trade updates
+// or inferred from a rise in the order's filled quantity between polls
+type DBOrderFill struct {
+	gorm.Model
+	OrderID        string          `gorm:"index"`
+	ExecutionID    string          `gorm:"uniqueIndex"` // broker execution ID, or the order ID and cumulative quantity when inferred
+	Symbol         string          `gorm:"index"`
+	Side           string
+	Event          string          // "partial_fill" or "fill"
+	Qty            decimal.Decimal `gorm:"type:decimal(20,8)"` // quantity of this execution
+	Price          decimal.Decimal `gorm:"type:decimal(20,8)"`
+	FilledQty      decimal.Decimal `gorm:"type:decimal(20,8)"` // order's cumulative filled quantity after this execution
+	FilledAvgPrice decimal.Decimal `gorm:"type:decimal(20,8)"` // order's average fill price after this execution
+	FilledAt       time.Time       `gorm:"index"`
+	Inferred       bool            // derived from polling rather than reported by the broker
+}
+
+// DBAccountActivity is a non-trade account activity reported by the broker,
+// such as a dividend, fee, journal or transfer
+type DBAccountActivity struct {
+	gorm.Model
+	ActivityID     string          `gorm:"uniqueIndex"`
+	Type           string          `gorm:"index"` // broker activity type, e.g. DIV, FEE, CSD, JNLC
+	Category       string          `gorm:"index"` // cash_flow, dividend, interest, fee or other
+	Date           time.Time       `gorm:"index"`
+	Symbol         string          `gorm:"index"`
+	Qty            decimal.Decimal `gorm:"type:decimal(20,8)"`
+	Price          decimal.Decimal `gorm:"type:decimal(20,8)"`
+	PerShareAmount decimal.Decimal `gorm:"type:decimal(20,8)"`
+	NetAmount      decimal.Decimal `gorm:"type:decimal(20,8)"` // cash in (positive) or out (negative) of the account
+	Description    string
+	Status         string
+}
+
+// DBSplitAdjustment records a stock split applied to the stored position
+// snapshots, managed positions and trades of a symbol
+type DBSplitAdjustment struct {
+	gorm.Model
+	Symbol    string `gorm:"uniqueIndex:idx_split_adjustments_symbol_ex_date"`
+	ExDate    string `gorm:"uniqueIndex:idx_split_adjustments_symbol_ex_date"` // YYYY-MM-DD
+	OldRate   float64
+	NewRate   float64
+	Ratio     float64 // new shares per old share
+	Source    string  // "detected" or "manual"
+	Changes   string  // JSON list of every rescaled record with its before and after values
+	AppliedAt time.Time
+}
+
+// DBSymbolAlias maps a ticker that changed, through a rename or merger, to
+// the symbol it trades under now
+type DBSymbolAlias struct {
+	gorm.Model
+	OldSymbol     string `gorm:"uniqueIndex"`
+	NewSymbol     string `gorm:"index"`
+	EffectiveDate string // YYYY-MM-DD, informational
+	Note          string
+	Migrated      string // JSON object of rows moved to NewSymbol, by table
+}
+
+// DBSymbolRule holds the trading restrictions for one symbol, checked
+// before every order
+type DBSymbolRule struct {
+	gorm.Model
+	Symbol            string          `gorm:"uniqueIndex"`
+	Blocked           bool            // never open or add to a position
+	MaxPositionQty    decimal.Decimal `gorm:"type:decimal(20,8)"` // shares, zero for no limit
+	MaxPositionValue  decimal.Decimal `gorm:"type:decimal(20,8)"` // dollars, zero for no limit
+	AllowedOrderTypes string          // comma separated, empty allows all
+	MinPrice          decimal.Decimal `gorm:"type:decimal(20,8)"` // zero uses the configured default
+	LongOnly          bool
+	Note              string
+}
+
+// DBAlert is a user-defined market condition evaluated in real time
+type DBAlert struct {
+	gorm.Model
+	AlertID         string `gorm:"uniqueIndex"`
+	Symbol          string `gorm:"index"`
+	Condition       string // "price_above", "price_below", "percent_move", "rsi_above", "rsi_below", "volume_spike", "news_mention"
+	Threshold       float64
+	WindowMinutes   int
+	Keyword         string
+	Strategy        string // strategy evaluated when the alert fires
+	Repeat          bool
+	CooldownMinutes int
+	Status          string `gorm:"index"` // ACTIVE, TRIGGERED
+	TriggerCount    int
+	LastTriggeredAt *time.Time
+	LastValue       float64
+	Note            string
+}
+
+// DBNewsRule is a rule matching breaking news to alerts or strategy hooks
+type DBNewsRule struct {
+	gorm.Model
+	RuleID          string `gorm:"uniqueIndex"`
+	Name            string
+	Symbols         string // comma separated
+	Keywords        string // JSON array
+	Direction       string // "bullish", "bearish" or "any"
+	MinSentiment    float64
+	Action          string // "alert" or "strategy"
+	Strategy        string
+	CooldownMinutes int
+	DryRun          bool
+	TriggerCount    int
+	LastTriggeredAt *time.Time
+}
+
+// DBPromptTemplate is one saved version of an LLM prompt template. At most
+// one version per name is active; with none active the built-in or file
+// template is used.
+type DBPromptTemplate struct {
+	gorm.Model
+	Name     string `gorm:"uniqueIndex:idx_prompt_template_version"` // prompt name, optionally "name@endpoint"
+	Version  int    `gorm:"uniqueIndex:idx_prompt_template_version"`
+	Template string
+	Note     string
+	Author   string
+	Active   bool
+}
+
+// DBAnalysisProfile is a named stock analysis profile
+type DBAnalysisProfile struct {
+	gorm.Model
+	Name       string `gorm:"uniqueIndex"`
+	Definition string // JSON encoded services.AnalysisProfile
+}
+
+// DBStockAnalysis is a stock analysis as it was returned
+type DBStockAnalysis struct {
+	gorm.Model
+	Symbol         string `gorm:"index"`
+	Profile        string
+	CompositeScore int
+	Analysis       string // JSON encoded services.StockAnalysis
+}
+
+// DBLLMToolCall is the audit record of a function the LLM called
+type DBLLMToolCall struct {
+	gorm.Model
+	Source     string `gorm:"index"` // prompt that was being answered
+	Tool       string `gorm:"index"`
+	Args       string // JSON
+	Status     string // "ok", "error" or "limited"
+	Error      string
+	DurationMs int64
+}
+
+// DBEmbedding is the embedding vector of a news item or stock analysis in
+// the semantic index
+type DBEmbedding struct {
+	gorm.Model
+	Provider    string    `gorm:"uniqueIndex:idx_embedding_item"` // vector space, e.g. "gemini/text-embedding-004"
+	Kind        string    `gorm:"uniqueIndex:idx_embedding_item"` // "news" or "analysis"
+	ItemKey     string    `gorm:"uniqueIndex:idx_embedding_item"`
+	Symbols     string    // comma separated
+	Title       string
+	Text        string
+	Link        string
+	PublishedAt time.Time `gorm:"index"`
+	Vector      []byte    // little-endian float32s
+}
+
+// DBActivityEvent is a trading activity event recorded from the event bus
+type DBActivityEvent struct {
+	gorm.Model
+	EventID uint64 `gorm:"index"` // ID on the event bus
+	Type    string `gorm:"index"` // e.g. "order_placed", "risk_breached"
+	Symbol  string `gorm:"index"`
+	Book    string
+	Data    string // JSON payload
+}
+
+// DBActivitySession is the summary of a trading session in the activity log
+type DBActivitySession struct {
+	gorm.Model
+	Date        string    `gorm:"index"` // YYYY-MM-DD of the session's activity log
+	Environment string    // "paper" or "live"
+	StartedAt   time.Time `gorm:"index"`
+	EndedAt     *time.Time
+	Summary     string // JSON session summary
+}
+
+// DBFiling is an SEC filing seen by the EDGAR filings monitor
+type DBFiling struct {
+	gorm.Model
+	AccessionNumber string `gorm:"uniqueIndex"`
+	Symbol          string `gorm:"index"`
+	CIK             string
+	Form            string    `gorm:"index"` // "8-K", "10-Q", "10-K", "4", with "/A" for amendments
+	FiledAt         time.Time `gorm:"index"`
+	ReportDate      string
+	Items           string // comma-separated 8-K item numbers
+	Description     string
+	URL             string
+	Summary         string
+	Material        bool
+}
+
+// DBSocialMention is one ticker mention in a social media post
+type DBSocialMention struct {
+	gorm.Model
+	Source     string  `gorm:"uniqueIndex:idx_social_mention"` // "reddit", "x"
+	PostID     string  `gorm:"uniqueIndex:idx_social_mention"`
+	Symbol     string  `gorm:"uniqueIndex:idx_social_mention;index"`
+	Channel    string  // subreddit or list ID
+	Sentiment  float64 // -1 (bearish) to 1 (bullish)
+	Engagement int
+	URL        string
+	PostedAt   time.Time `gorm:"index"`
+}
+
+// DBQueuedOrder is an order accepted while the market was closed and held
+// locally until it is risk-checked and submitted
+type DBQueuedOrder struct {
+	gorm.Model
+	QueueID     string          `gorm:"uniqueIndex"`
+	Symbol      string          `gorm:"index"`
+	Qty         decimal.Decimal `gorm:"type:decimal(20,8)"`
+	Side        string
+	Type        string
+	TimeInForce string
+	LimitPrice  *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	StopPrice   *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	Strategy    string
+	Tags        string     // JSON array
+	Metadata    string     // JSON object
+	Source      string     // order source recorded in the audit trail
+	Book        string     `gorm:"index;default:default"`
+	SubmitAt    *time.Time // earliest submission time, nil submits at the next open
+	ExpiresAt   *time.Time // good-till-date, the order expires unsubmitted after it
+	Status      string     `gorm:"index"` // QUEUED, SUBMITTED, REJECTED, FAILED, CANCELED, EXPIRED
+	OrderID     string     // broker order ID once submitted
+	Error       string
+	ProcessedAt *time.Time
+}
+
+// DBDCAPlan is a recurring fixed-amount purchase of one symbol
+type DBDCAPlan struct {
+	gorm.Model
+	PlanID         string          `gorm:"uniqueIndex"`
+	Symbol         string          `gorm:"index"`
+	Amount         decimal.Decimal `gorm:"type:decimal(20,8)"` // dollars per purchase
+	Cadence        string          // daily, weekly, biweekly, monthly
+	MaxTotal       decimal.Decimal `gorm:"type:decimal(20,8)"` // total dollars to invest, zero for no limit
+	SkipRiskOff    bool            // skip purchases while the market regime is risk-off
+	Book           string          `gorm:"index;default:default"` // book the purchases are placed for
+	Status         string          `gorm:"index"` // ACTIVE, COMPLETED, CANCELED
+	NextRunAt      time.Time
+	LastRunAt      *time.Time
+	LastSkipReason string
+}
+
+// DBDCAExecution is one scheduled purchase of a DCA plan, whether it was
+// submitted or skipped
+type DBDCAExecution struct {
+	gorm.Model
+	PlanID         string           `gorm:"index"`
+	Symbol         string
+	Status         string           `gorm:"index"` // SUBMITTED, FILLED, CANCELED, SKIPPED, FAILED
+	Amount         decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	Qty            decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	EstimatedPrice decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	OrderID        string
+	FilledQty      decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	FilledAvgPrice *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	Reason         string           // why the purchase was skipped or failed
+}
+
+// DBConditionalOrder is an order held locally until a price condition is
+// met, then submitted to the broker
+type DBConditionalOrder struct {
+	gorm.Model
+	ConditionalID string `gorm:"uniqueIndex"`
+	Symbol        string `gorm:"index"`
+	Side          string
+	Qty           decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	Type          string           // market or limit
+	LimitPrice    *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	TimeInForce   string
+	Strategy      string
+	Book          string  `gorm:"index;default:default"`
+	TriggerSymbol string  `gorm:"index"` // symbol whose price is watched
+	Condition     string  // "price_above", "price_below", "day_change_above", "day_change_below"
+	Threshold     float64 // a price, or a percent change from the previous close
+	Armed         bool    // the condition has been seen false, so it can fire
+	LastValue     float64
+	Status        string `gorm:"index"` // ACTIVE, TRIGGERED, FAILED, CANCELED, EXPIRED
+	Reason        string // why it failed
+	ExpiresAt     *time.Time
+	TriggeredAt   *time.Time
+	TriggerValue  float64
+	OrderID       string // the broker order placed when it triggered
+	Note          string
+}
+
+// DBAlgoOrder is a parent order an execution algorithm works by slicing it
+// into child orders over time
+type DBAlgoOrder struct {
+	gorm.Model
+	AlgoID           string           `gorm:"uniqueIndex"`
+	Symbol           string           `gorm:"index"`
+	Side             string
+	Qty              decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	Algo             string           // twap or vwap
+	LimitPrice       *decimal.Decimal `gorm:"type:decimal(20,8)"` // children are limit orders at this price, market orders without it
+	MaxParticipation float64          // percent of market volume one child may take, 0 for no cap
+	Slices           int              // TWAP child orders
+	ArrivalPrice     decimal.Decimal  `gorm:"type:decimal(20,8)"` // mid quote when the order was created, the slippage benchmark
+	Book             string           `gorm:"index;default:default"`
+	Status           string           `gorm:"index"` // WORKING, COMPLETED, CANCELED, EXPIRED, FAILED, DRY_RUN
+	Reason           string           // why it stopped early
+	StartAt          time.Time
+	EndAt            time.Time
+	NextSliceAt      time.Time
+	VolumeSince      time.Time // market volume after this counts toward the next VWAP child
+}
+
+// DBAlgoSlice is one child order of an algo order, whether it was placed or
+// skipped
+type DBAlgoSlice struct {
+	gorm.Model
+	AlgoID         string           `gorm:"index"`
+	Symbol         string
+	Status         string           `gorm:"index"` // SUBMITTED, FILLED, CANCELED, SKIPPED, FAILED
+	Qty            decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	MarketVolume   decimal.Decimal  `gorm:"type:decimal(20,8)"` // volume traded in the slice's window
+	OrderID        string
+	FilledQty      decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	FilledAvgPrice *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	Reason         string           // why the child was skipped or failed
+}
+
+// DBTargetAllocation is the target weight of one symbol in the portfolio.
+// The CASH symbol holds the target cash weight.
+type DBTargetAllocation struct {
+	gorm.Model
+	Symbol string  `gorm:"uniqueIndex"`
+	Weight float64 // percent of equity
+}
+
+// DBGrid is a grid trading ladder on one symbol
+type DBGrid struct {
+	gorm.Model
+	GridID         string          `gorm:"uniqueIndex"`
+	Symbol         string          `gorm:"index"`
+	ReferencePrice decimal.Decimal `gorm:"type:decimal(20,8)"`
+	Spacing        decimal.Decimal `gorm:"type:decimal(20,8)"` // dollars between levels
+	Levels         int             // levels on each side of the reference price
+	QtyPerLevel    decimal.Decimal `gorm:"type:decimal(20,8)"`
+	Seeded         bool            // shares were bought at the start to back the sell ladder
+	Book           string          `gorm:"index;default:default"` // book the grid's orders are placed for
+	Status         string          `gorm:"index"` // ACTIVE, PAUSED, STOPPED
+	RealizedPnL    decimal.Decimal `gorm:"type:decimal(20,8)"`
+	RoundTrips     int
+	StoppedAt      *time.Time
+}
+
+// DBGridOrder is one order a grid placed at one of its levels
+type DBGridOrder struct {
+	gorm.Model
+	GridID      string           `gorm:"index"`
+	Level       int              // negative below the reference price, positive above
+	Side        string
+	Price       decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	Qty         decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	EntryPrice  *decimal.Decimal `gorm:"type:decimal(20,8)"` // cost of the shares a sell closes
+	OrderID     string           `gorm:"index"`
+	Status      string           `gorm:"index"` // OPEN, FILLED, CANCELED, REJECTED
+	FilledPrice *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	FilledAt    *time.Time
+	Error       string
+}
+
+// DBBarDownload records the bars downloaded for one symbol and timeframe.
+// Bars from Start up to, not including, Through are all stored.
+type DBBarDownload struct {
+	gorm.Model
+	Symbol    string `gorm:"uniqueIndex:idx_bar_downloads_symbol_timeframe"`
+	Timeframe string `gorm:"uniqueIndex:idx_bar_downloads_symbol_timeframe"`
+	Start     time.Time
+	Through   time.Time
+	Bars      int64
+}
+
+// DBShadowOrder is an order from a shadowed source that was simulated
+// against live quotes instead of being sent to the broker
+type DBShadowOrder struct {
+	gorm.Model
+	ShadowID    string           `gorm:"uniqueIndex"`
+	Symbol      string           `gorm:"index"`
+	AssetClass  string           // us_equity or us_option
+	Side        string
+	Type        string
+	Qty         decimal.Decimal  `gorm:"type:decimal(20,8)"`
+	LimitPrice  *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	StopPrice   *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	Source      string           `gorm:"index"`
+	Strategy    string
+	Book        string           `gorm:"index;default:default"`
+	QuotePrice  decimal.Decimal  `gorm:"type:decimal(20,8)"` // price it would have filled at when placed
+	Status      string           `gorm:"index"`              // OPEN, FILLED, CANCELED
+	FilledPrice *decimal.Decimal `gorm:"type:decimal(20,8)"`
+	FilledAt    *time.Time
+}
+
+// DBAPIAudit records one state-changing API call. Records are append-only:
+// there is no UpdatedAt or DeletedAt, and the database rejects updates and
+// deletes.
+type DBAPIAudit struct {
+	ID          uint      `gorm:"primarykey"`
+	CreatedAt   time.Time `gorm:"index"`
+	TokenID     string    `gorm:"index"` // empty when authentication is off or the token was refused
+	Caller      string    // token name
+	Role        string
+	ClientIP    string
+	Method      string
+	Route       string `gorm:"index"` // route pattern, e.g. /api/v1/orders/:id
+	Path        string // path and query as requested
+	PayloadHash string // hex SHA-256 of the request body
+	PayloadSize int64
+	Status      int    `gorm:"index"`
+	ErrorCode   string // code of the error envelope for failed calls
+	DurationMs  int64
+}
+
 // TableName overrides for cleaner table names
 func (DBOrder) TableName() string {
 	return "orders"
@@ -159,6 +620,19 @@ func (DBPosition) TableName() string {
 	return "positions"
 }
 
+// DBAPIToken is an API token issued to a user. Only the SHA-256 hash of the
+// token is stored; the token itself is shown once at creation.
+type DBAPIToken struct {
+	gorm.Model
+	TokenID    string `gorm:"uniqueIndex"`
+	Name       string // user or client the token was issued to
+	Role       string // "viewer", "trader" or "admin"
+	TokenHash  string `gorm:"uniqueIndex"`
+	Prefix     string // first characters of the token, to tell tokens apart
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
 func (DBTrade) TableName() string {
 	return "trades"
 }
@@ -173,4 +647,123 @@ func (DBSignal) TableName() string {
 
 func (DBManagedPosition) TableName() string {
 	return "managed_positions"
-}
\ No newline at end of file
+}
+func (DBOrderAudit) TableName() string {
+	return "order_audits"
+}
+
+func (DBAlert) TableName() string {
+	return "alerts"
+}
+
+func (DBNewsRule) TableName() string {
+	return "news_rules"
+}
+
+func (DBPromptTemplate) TableName() string {
+	return "prompt_templates"
+}
+
+func (DBAnalysisProfile) TableName() string {
+	return "analysis_profiles"
+}
+
+func (DBStockAnalysis) TableName() string {
+	return "stock_analyses"
+}
+
+func (DBLLMToolCall) TableName() string {
+	return "llm_tool_calls"
+}
+
+func (DBEmbedding) TableName() string {
+	return "embeddings"
+}
+
+func (DBActivityEvent) TableName() string {
+	return "activity_events"
+}
+
+func (DBActivitySession) TableName() string {
+	return "activity_sessions"
+}
+
+func (DBFiling) TableName() string {
+	return "filings"
+}
+
+func (DBSocialMention) TableName() string {
+	return "social_mentions"
+}
+
+func (DBAPIToken) TableName() string {
+	return "api_tokens"
+}
+
+func (DBAccountActivity) TableName() string {
+	return "account_activities"
+}
+
+func (DBQueuedOrder) TableName() string {
+	return "queued_orders"
+}
+
+func (DBDCAPlan) TableName() string {
+	return "dca_plans"
+}
+
+func (DBDCAExecution) TableName() string {
+	return "dca_executions"
+}
+
+func (DBTargetAllocation) TableName() string {
+	return "target_allocations"
+}
+
+func (DBGrid) TableName() string {
+	return "grids"
+}
+
+func (DBGridOrder) TableName() string {
+	return "grid_orders"
+}
+
+func (DBBarDownload) TableName() string {
+	return "bar_downloads"
+}
+
+func (DBShadowOrder) TableName() string {
+	return "shadow_orders"
+}
+
+func (DBAPIAudit) TableName() string {
+	return "api_audits"
+}
+
+func (DBOrderFill) TableName() string {
+	return "order_fills"
+}
+
+func (DBAlgoOrder) TableName() string {
+	return "algo_orders"
+}
+
+func (DBAlgoSlice) TableName() string {
+	return "algo_slices"
+}
+
+func (DBConditionalOrder) TableName() string {
+	return "conditional_orders"
+}
+
+func (DBSplitAdjustment) TableName() string {
+	return "split_adjustments"
+}
+
+func (DBSymbolAlias) TableName() string {
+	return "symbol_aliases"
+}
+
+func (DBSymbolRule) TableName() string {
+	return "symbol_rules"
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Account activity categories
+const (
+	ActivityCashFlow = "cash_flow" // deposits, withdrawals, journals and transfers in or out of the account
+	ActivityDividend = "dividend"
+	ActivityInterest = "interest"
+	ActivityFee      = "fee"
+	ActivityOther    = "other" // corporate actions, option expiries and the rest
+)
+
+// accountActivityOverlap is how far before the newest stored activity a sync
+// asks again, since activities can be reported a day or two late
+const accountActivityOverlap = 3 * 24 * time.Hour
+
+// AccountActivity is a non-trade account activity: a dividend, fee,
+// interest payment, journal or transfer
+type AccountActivity struct {
+	ID             string          `json:"id"`
+	Type           string          `json:"type"` // broker activity type, e.g. DIV, FEE, CSD, JNLC
+	Category       string          `json:"category"`
+	Date           time.Time       `json:"date"`
+	Symbol         string          `json:"symbol,omitempty"`
+	Qty            decimal.Decimal `json:"qty"`
+	Price          decimal.Decimal `json:"price"`
+	PerShareAmount decimal.Decimal `json:"per_share_amount"`
+	NetAmount      decimal.Decimal `json:"net_amount"` // positive into the account, negative out
+	Description    string          `json:"description,omitempty"`
+	Status         string          `json:"status,omitempty"`
+}
+
+// CashFlow is the cash an activity moved in or out of the account. Stock
+// journaled in or out without a cash amount is valued at its price.
+func (a *AccountActivity) CashFlow() decimal.Decimal {
+	if a.NetAmount.IsZero() && !a.Qty.IsZero() {
+		return a.Qty.Mul(a.Price)
+	}
+	return a.NetAmount
+}
+
+// AccountActivitySource is implemented by brokers that report non-trade
+// account activities. It returns the activities dated after after, or all
+// of them for the zero time, oldest first.
+type AccountActivitySource interface {
+	GetAccountActivities(ctx context.Context, after time.Time) ([]*AccountActivity, error)
+}
+
+// ActivityCategory groups a broker activity type
+func ActivityCategory(activityType string) string {
+	activityType = strings.ToUpper(activityType)
+	switch {
+	case activityType == "DIVFEE" || activityType == "DIVTW" || activityType == "DIVNRA":
+		return ActivityFee // withholding and fees charged on dividends
+	case strings.HasPrefix(activityType, "DIV"):
+		return ActivityDividend
+	case strings.HasPrefix(activityType, "INT"):
+		return ActivityInterest
+	case activityType == "FEE" || activityType == "CFEE" || activityType == "PTC":
+		return ActivityFee
+	case activityType == "CSD" || activityType == "CSW" || activityType == "TRANS" ||
+		activityType == "JNL" || activityType == "JNLC" || activityType == "JNLS" ||
+		activityType == "ACATC" || activityType == "ACATS":
+		return ActivityCashFlow
+	default:
+		return ActivityOther
+	}
+}
+
+// CashFlowSummary totals account activities by kind
+type CashFlowSummary struct {
+	Deposits     decimal.Decimal `json:"deposits"`
+	Withdrawals  decimal.Decimal `json:"withdrawals"` // negative
+	NetCashFlows decimal.Decimal `json:"net_cash_flows"`
+	Dividends    decimal.Decimal `json:"dividends"`
+	Interest     decimal.Decimal `json:"interest"`
+	Fees         decimal.Decimal `json:"fees"` // negative
+	Other        decimal.Decimal `json:"other"`
+	Activities   int             `json:"activities"`
+}
+
+// SummarizeActivities totals activities into a cash flow summary
+func SummarizeActivities(activities []*AccountActivity) *CashFlowSummary {
+	summary := &CashFlowSummary{Activities: len(activities)}
+	for _, activity := range activities {
+		switch activity.Category {
+		case ActivityCashFlow:
+			flow := activity.CashFlow()
+			if flow.IsPositive() {
+				summary.Deposits = summary.Deposits.Add(flow)
+			} else {
+				summary.Withdrawals = summary.Withdrawals.Add(flow)
+			}
+			summary.NetCashFlows = summary.NetCashFlows.Add(flow)
+		case ActivityDividend:
+			summary.Dividends = summary.Dividends.Add(activity.NetAmount)
+		case ActivityInterest:
+			summary.Interest = summary.Interest.Add(activity.NetAmount)
+		case ActivityFee:
+			summary.Fees = summary.Fees.Add(activity.NetAmount)
+		default:
+			summary.Other = summary.Other.Add(activity.NetAmount)
+		}
+	}
+	return summary
+}
+
+// AccountActivityService copies the broker's non-trade account activities
+// into storage, so reports can separate deposits and withdrawals from
+// returns and credit dividends and fees to the positions that earned them
+type AccountActivityService struct {
+	source  AccountActivitySource // nil when the broker doesn't report activities
+	storage *database.LocalStorage
+	logger  *logrus.Logger
+}
+
+// NewAccountActivityService creates an account activity service. source
+// may be nil, in which case only activities already stored are served.
+func NewAccountActivityService(source AccountActivitySource, storage *database.LocalStorage) *AccountActivityService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &AccountActivityService{
+		source:  source,
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// Sync fetches the activities since shortly before the newest one stored
+// and saves those not seen before. It returns how many were new.
+func (s *AccountActivityService) Sync(ctx context.Context) (int, error) {
+	if s.source == nil {
+		return 0, WithErrorCode(ErrCodeNotSupported, fmt.Errorf("the broker doesn't report account activities"))
+	}
+
+	latest, err := s.storage.LatestAccountActivityDate()
+	if err != nil {
+		return 0, err
+	}
+	var after time.Time
+	if !latest.IsZero() {
+		after = latest.Add(-accountActivityOverlap)
+	}
+
+	activities, err := s.source.GetAccountActivities(ctx, after)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, activity := range activities {
+		saved, err := s.storage.SaveAccountActivity(&models.DBAccountActivity{
+			ActivityID:     activity.ID,
+			Type:           activity.Type,
+			Category:       ActivityCategory(activity.Type),
+			Date:           activity.Date,
+			Symbol:         activity.Symbol,
+			Qty:            activity.Qty,
+			Price:          activity.Price,
+			PerShareAmount: activity.PerShareAmount,
+			NetAmount:      activity.NetAmount,
+			Description:    activity.Description,
+			Status:         activity.Status,
+		})
+		if err != nil {
+			return added, err
+		}
+		if saved {
+			added++
+		}
+	}
+
+	if added > 0 {
+		s.logger.WithField("activities", added).Info("Stored new account activities")
+	}
+	return added, nil
+}
+
+// RunSync is Sync for the worker pool
+func (s *AccountActivityService) RunSync(ctx context.Context) error {
+	_, err := s.Sync(ctx)
+	return err
+}
+
+// List returns stored activities matching q, newest first
+func (s *AccountActivityService) List(q database.AccountActivityQuery) ([]*AccountActivity, error) {
+	dbActivities, err := s.storage.GetAccountActivities(q)
+	if err != nil {
+		return nil, err
+	}
+
+	activities := make([]*AccountActivity, len(dbActivities))
+	for i, dbActivity := range dbActivities {
+		activities[i] = accountActivityFromDB(dbActivity)
+	}
+	return activities, nil
+}
+
+// Between returns every stored activity dated in [start, end]
+func (s *AccountActivityService) Between(start, end time.Time) ([]*AccountActivity, error) {
+	return s.List(database.AccountActivityQuery{Start: start, End: end})
+}
+
+func accountActivityFromDB(dbActivity *models.DBAccountActivity) *AccountActivity {
+	return &AccountActivity{
+		ID:             dbActivity.ActivityID,
+		Type:           dbActivity.Type,
+		Category:       dbActivity.Category,
+		Date:           dbActivity.Date,
+		Symbol:         dbActivity.Symbol,
+		Qty:            dbActivity.Qty,
+		Price:          dbActivity.Price,
+		PerShareAmount: dbActivity.PerShareAmount,
+		NetAmount:      dbActivity.NetAmount,
+		Description:    dbActivity.Description,
+		Status:         dbActivity.Status,
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SnapshotPolicy controls how often account snapshots are taken and how
+// long each resolution is kept. Snapshots older than MinuteRetentionDays are
+// reduced to one per hour, and older than HourlyRetentionDays to one per
+// trading day, which is kept forever.
+type SnapshotPolicy struct {
+	Interval            time.Duration // cadence during regular market hours
+	MinuteRetentionDays int
+	HourlyRetentionDays int
+}
+
+// offHoursSnapshotInterval is the cadence outside regular market hours, when
+// equity barely moves
+const offHoursSnapshotInterval = time.Hour
+
+// compactionInterval is how often old snapshots are downsampled
+const compactionInterval = 6 * time.Hour
+
+// AccountSnapshotter records account equity at a fine granularity during
+// market hours and compacts old snapshots so the table stays bounded
+type AccountSnapshotter struct {
+	tradingService interfaces.TradingService
+	storage        *database.LocalStorage
+	policy         SnapshotPolicy
+	location       *time.Location
+	hours          *MarketHours // nil assumes 9:30-16:00 on weekdays
+	lastSaved      time.Time
+	logger         *logrus.Logger
+}
+
+// NewAccountSnapshotter creates a new account snapshotter
+func NewAccountSnapshotter(tradingService interfaces.TradingService, storage *database.LocalStorage, policy SnapshotPolicy) *AccountSnapshotter {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.UTC
+	}
+
+	if policy.Interval <= 0 {
+		policy.Interval = time.Minute
+	}
+	if policy.HourlyRetentionDays < policy.MinuteRetentionDays {
+		policy.HourlyRetentionDays = policy.MinuteRetentionDays
+	}
+
+	return &AccountSnapshotter{
+		tradingService: tradingService,
+		storage:        storage,
+		policy:         policy,
+		location:       loc,
+		logger:         logger,
+	}
+}
+
+// SetMarketHours takes minute snapshots only while the market is actually
+// open, so holidays and the afternoon of a half-day drop to the hourly
+// cadence
+func (as *AccountSnapshotter) SetMarketHours(hours *MarketHours) {
+	as.hours = hours
+}
+
+// Run takes snapshots and compacts old ones until ctx is cancelled
+func (as *AccountSnapshotter) Run(ctx context.Context) {
+	snapshotTicker := time.NewTicker(as.policy.Interval)
+	defer snapshotTicker.Stop()
+	compactTicker := time.NewTicker(compactionInterval)
+	defer compactTicker.Stop()
+
+	as.compact()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-snapshotTicker.C:
+			if !as.isRegularSession(ctx, now) && now.Sub(as.lastSaved) < offHoursSnapshotInterval {
+				continue
+			}
+			if err := as.Snapshot(ctx); err != nil {
+				as.logger.WithError(err).Error("Failed to save account snapshot")
+			}
+		case <-compactTicker.C:
+			as.compact()
+		}
+	}
+}
+
+// Snapshot saves the current account state
+func (as *AccountSnapshotter) Snapshot(ctx context.Context) error {
+	account, err := as.tradingService.GetAccount(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get account: %w", err)
+	}
+	if err := as.storage.SaveAccountSnapshot(account); err != nil {
+		return err
+	}
+	as.lastSaved = time.Now()
+	return nil
+}
+
+// Compact downsamples snapshots that have aged out of the finer tiers
+func (as *AccountSnapshotter) Compact(now time.Time) (int, error) {
+	minuteCutoff := now.AddDate(0, 0, -as.policy.MinuteRetentionDays)
+	hourlyCutoff := now.AddDate(0, 0, -as.policy.HourlyRetentionDays)
+
+	hourly, err := as.storage.CompactAccountSnapshots(hourlyCutoff, minuteCutoff, func(t time.Time) time.Time {
+		return t.Truncate(time.Hour)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Daily buckets follow the exchange's calendar day, so the kept snapshot
+	// is the one closest to the close
+	daily, err := as.storage.CompactAccountSnapshots(time.Time{}, hourlyCutoff, func(t time.Time) time.Time {
+		t = t.In(as.location)
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, as.location)
+	})
+	if err != nil {
+		return hourly, err
+	}
+
+	return hourly + daily, nil
+}
+
+func (as *AccountSnapshotter) compact() {
+	removed, err := as.Compact(time.Now())
+	if err != nil {
+		as.logger.WithError(err).Error("Failed to compact account snapshots")
+		return
+	}
+	if removed > 0 {
+		as.logger.WithField("removed", removed).Info("Compacted account snapshots")
+	}
+}
+
+// isRegularSession reports whether t falls within the regular session. Without
+// market hours that is 9:30-16:00 ET on a weekday, and holidays just get
+// minute snapshots of an unchanged account.
+func (as *AccountSnapshotter) isRegularSession(ctx context.Context, t time.Time) bool {
+	if as.hours != nil {
+		if day, ok := as.hours.Day(ctx, t); ok {
+			return sessionOn(t, day, AssetClassEquity) == SessionRegular
+		}
+		return false
+	}
+	return inRegularSession(t, as.location)
+}
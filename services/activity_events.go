@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// ActivityEventTypes are the trading activity events services publish
+var ActivityEventTypes = []string{EventOrderPlaced, EventPositionClosed, EventRiskBreached, EventAnalysisCompleted}
+
+// OrderPlaced is the payload of an order_placed event
+type OrderPlaced struct {
+	OrderID    string           `json:"order_id"`
+	Symbol     string           `json:"symbol"`
+	Side       string           `json:"side"`
+	Type       string           `json:"type"`
+	Qty        decimal.Decimal  `json:"qty"`
+	LimitPrice *decimal.Decimal `json:"limit_price,omitempty"`
+	AssetClass string           `json:"asset_class"`
+	Status     string           `json:"status"`
+	Source     string           `json:"source"`
+	Book       string           `json:"book"`
+}
+
+// PositionClosed is the payload of a position_closed event. Prices and P&L
+// are from the last poll that still saw the position, or from the fill that
+// closed it when one was seen.
+type PositionClosed struct {
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"` // "long" or "short"
+	Qty        float64 `json:"qty"`
+	EntryPrice float64 `json:"entry_price"`
+	ExitPrice  float64 `json:"exit_price"`
+	PnL        float64 `json:"pnl"`
+	PnLPercent float64 `json:"pnl_percent"`
+}
+
+// RiskBreached is the payload of a risk_breached event
+type RiskBreached struct {
+	Symbol     string          `json:"symbol"`
+	Side       string          `json:"side"`
+	Qty        decimal.Decimal `json:"qty"`
+	AssetClass string          `json:"asset_class"`
+	Notional   decimal.Decimal `json:"notional"`
+	Violations []RiskViolation `json:"violations"`
+	Source     string          `json:"source"`
+	Book       string          `json:"book"`
+}
+
+// Messages joins the violation messages
+func (r RiskBreached) Messages() string {
+	messages := make([]string, len(r.Violations))
+	for i, v := range r.Violations {
+		messages[i] = v.Rule + ": " + v.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// AnalysisCompleted is the payload of an analysis_completed event
+type AnalysisCompleted struct {
+	Symbol         string  `json:"symbol"`
+	Profile        string  `json:"profile"`
+	Price          float64 `json:"price"`
+	CompositeScore int     `json:"composite_score"`
+	Trend          string  `json:"trend"`
+	Notes          string  `json:"notes"`
+}
+
+// activityEventSymbol returns the symbol and book of an activity event
+func activityEventSymbol(event *Event) (string, string) {
+	switch data := event.Data.(type) {
+	case OrderPlaced:
+		return data.Symbol, data.Book
+	case PositionClosed:
+		return data.Symbol, ""
+	case RiskBreached:
+		return data.Symbol, data.Book
+	case AnalysisCompleted:
+		return data.Symbol, ""
+	}
+	return "", ""
+}
+
+// ActivityEventStore records activity events in the database
+type ActivityEventStore struct {
+	storageService *database.LocalStorage
+	logger         *logrus.Logger
+}
+
+// NewActivityEventStore creates an activity event store
+func NewActivityEventStore(storageService *database.LocalStorage) *ActivityEventStore {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &ActivityEventStore{
+		storageService: storageService,
+		logger:         logger,
+	}
+}
+
+// HandleEvent stores an event
+func (s *ActivityEventStore) HandleEvent(event *Event) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		s.logger.WithError(err).WithField("type", event.Type).Warn("Failed to encode activity event")
+		return
+	}
+	symbol, book := activityEventSymbol(event)
+	if err := s.storageService.SaveActivityEvent(&models.DBActivityEvent{
+		EventID: event.ID,
+		Type:    event.Type,
+		Symbol:  symbol,
+		Book:    book,
+		Data:    string(data),
+	}); err != nil {
+		s.logger.WithError(err).WithField("type", event.Type).Warn("Failed to store activity event")
+	}
+}
+
+// Recent returns the latest stored events, newest first. Empty eventType
+// or symbol match any.
+func (s *ActivityEventStore) Recent(eventType, symbol string, limit int) ([]*Event, error) {
+	stored, err := s.storageService.GetActivityEvents(eventType, symbol, limit)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*Event, 0, len(stored))
+	for _, row := range stored {
+		events = append(events, &Event{
+			ID:        row.EventID,
+			Type:      row.Type,
+			Timestamp: row.CreatedAt,
+			Data:      json.RawMessage(row.Data),
+		})
+	}
+	return events, nil
+}
+
+// EventNotifier turns events into notifications
+type EventNotifier struct {
+	notifier *Notifier
+}
+
+// NewEventNotifier creates an event notifier sending through notifier
+func NewEventNotifier(notifier *Notifier) *EventNotifier {
+	return &EventNotifier{notifier: notifier}
+}
+
+// EventNotifierTypes are the event types EventNotifier sends notifications for
+var EventNotifierTypes = []string{EventOrderUpdate, EventPositionClosed, EventRiskBreached}
+
+// HandleEvent sends the notification for an event: order fills, closed
+// positions and orders rejected by risk checks. Channels with a digest
+// window batch a burst of them into one message.
+func (en *EventNotifier) HandleEvent(event *Event) {
+	ctx := context.Background()
+	switch data := event.Data.(type) {
+	case OrderUpdate:
+		if data.Status != "filled" {
+			return
+		}
+		price := "market"
+		if data.FilledAvgPrice != nil {
+			price = data.FilledAvgPrice.String()
+		}
+		en.notifier.Notify(ctx, NotifyInfo, "Order filled",
+			fmt.Sprintf("%s %s %s @ %s", data.Side, data.FilledQty.String(), data.Symbol, price),
+			map[string]interface{}{
+				"order_id": data.OrderID,
+				"symbol":   data.Symbol,
+				"side":     data.Side,
+			})
+	case PositionClosed:
+		en.notifier.Notify(ctx, NotifyInfo, "Position closed",
+			fmt.Sprintf("%s %s closed @ %.2f, P&L $%.2f (%.2f%%)", data.Symbol, data.Side, data.ExitPrice, data.PnL, data.PnLPercent),
+			map[string]interface{}{
+				"symbol": data.Symbol,
+				"pnl":    data.PnL,
+			})
+	case RiskBreached:
+		en.notifier.Notify(ctx, NotifyWarning, "Order rejected by risk checks",
+			fmt.Sprintf("%s %s %s: %s", data.Side, data.Qty.String(), data.Symbol, data.Messages()),
+			map[string]interface{}{
+				"symbol": data.Symbol,
+				"source": data.Source,
+				"book":   data.Book,
+			})
+	}
+}
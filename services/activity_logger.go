@@ -6,16 +6,30 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// ActivityLogEventTypes are the events the activity log records
+var ActivityLogEventTypes = append([]string{EventOrderUpdate}, ActivityEventTypes...)
+
 // ActivityLogger logs all AI trading activities to files and database
 type ActivityLogger struct {
-	logger     *logrus.Logger
-	logDir     string
-	currentLog *DailyActivityLog
+	logger         *logrus.Logger
+	logDir         string
+	environment    string // "paper" or "live", stamped on every entry
+	currentLog     *DailyActivityLog
+	tradingService interfaces.TradingService // samples the portfolio for session P&L when set
+	storage        *database.LocalStorage    // keeps session summaries when set
+	session        *models.DBActivitySession // stored row of the current session
+	fees           FillModel                 // commission schedule fills are charged at
+	mu             sync.Mutex
 }
 
 // DailyActivityLog represents a day's worth of trading activity
@@ -31,7 +45,10 @@ type DailyActivityLog struct {
 	Decisions         []DecisionLog       `json:"decisions"`
 }
 
-// SessionSummary provides high-level stats for the session
+// SessionSummary provides high-level stats for the session. Realized P&L
+// comes from closed positions, unrealized P&L and drawdown from sampling the
+// portfolio, and fees are estimated from fills with the configured
+// commission schedule.
 type SessionSummary struct {
 	TotalTrades       int     `json:"total_trades"`
 	PositionsOpened   int     `json:"positions_opened"`
@@ -49,16 +66,37 @@ type SessionSummary struct {
 	StocksAnalyzed    int     `json:"stocks_analyzed"`
 	NewsArticlesRead  int     `json:"news_articles_read"`
 	WebSearches       int     `json:"web_searches"`
+	RealizedPnL       float64 `json:"realized_pnl"`
+	UnrealizedPnL     float64 `json:"unrealized_pnl"`
+	FilledOrders      int     `json:"filled_orders"`
+	Fees              float64 `json:"fees"`
+	CurrentValue      float64 `json:"current_value,omitempty"`
+	PeakValue         float64 `json:"peak_value,omitempty"`
+	MaxDrawdown       float64 `json:"max_drawdown"`
+	MaxDrawdownPct    float64 `json:"max_drawdown_percent"`
+}
+
+// SessionRecord is a stored trading session summary
+type SessionRecord struct {
+	ID           uint           `json:"id"`
+	Date         string         `json:"date"`
+	Environment  string         `json:"environment,omitempty"`
+	SessionStart time.Time      `json:"session_start"`
+	SessionEnd   *time.Time     `json:"session_end,omitempty"`
+	Active       bool           `json:"active"`
+	Summary      SessionSummary `json:"summary"`
 }
 
 // Activity represents a single action taken by the AI
 type Activity struct {
 	Timestamp   time.Time              `json:"timestamp"`
-	Type        string                 `json:"type"` // POSITION_OPENED, POSITION_CLOSED, ANALYSIS, INTELLIGENCE, DECISION
+	Type        string                 `json:"type"` // POSITION_OPENED, POSITION_CLOSED, ORDER_PLACED, RISK_BREACHED, ANALYSIS, INTELLIGENCE, DECISION
 	Action      string                 `json:"action"`
 	Symbol      string                 `json:"symbol,omitempty"`
 	Details     map[string]interface{} `json:"details"`
 	Reasoning   string                 `json:"reasoning,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Book        string                 `json:"book,omitempty"`
 }
 
 // PositionActivity represents opening or closing a position
@@ -78,15 +116,19 @@ type PositionActivity struct {
 	Reasoning        string    `json:"reasoning"`
 	Tags             []string  `json:"tags,omitempty"`
 	Conviction       int       `json:"conviction"`
+	Environment      string    `json:"environment,omitempty"`
+	Book             string    `json:"book,omitempty"`
 }
 
 // IntelligenceNote represents market intelligence gathered
 type IntelligenceNote struct {
-	Timestamp time.Time `json:"timestamp"`
-	Source    string    `json:"source"` // NEWS, WEBSEARCH, MARKET_DATA, ANALYSIS
-	Topic     string    `json:"topic"`
-	Summary   string    `json:"summary"`
-	Symbols   []string  `json:"symbols,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	Source      string    `json:"source"` // NEWS, WEBSEARCH, MARKET_DATA, ANALYSIS
+	Topic       string    `json:"topic"`
+	Summary     string    `json:"summary"`
+	Symbols     []string  `json:"symbols,omitempty"`
+	Environment string    `json:"environment,omitempty"`
+	Book        string    `json:"book,omitempty"`
 }
 
 // DecisionLog represents a trading decision (buy, sell, hold, pass)
@@ -97,6 +139,8 @@ type DecisionLog struct {
 	Reasoning   string                 `json:"reasoning"`
 	Conviction  int                    `json:"conviction"`
 	MarketData  map[string]interface{} `json:"market_data,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Book        string                 `json:"book,omitempty"`
 }
 
 // NewActivityLogger creates a new activity logger
@@ -115,8 +159,44 @@ func NewActivityLogger(logDir string) *ActivityLogger {
 	}
 }
 
-// StartSession initializes a new trading session for the day
+// SetEnvironment records the trading environment ("paper" or "live") on
+// every entry logged from now on
+func (al *ActivityLogger) SetEnvironment(environment string) {
+	al.environment = environment
+}
+
+// SetTradingService samples the portfolio while a session is active, for
+// its unrealized P&L and drawdown
+func (al *ActivityLogger) SetTradingService(tradingService interfaces.TradingService) {
+	al.tradingService = tradingService
+}
+
+// SetStorage keeps every session's summary for ListSessions
+func (al *ActivityLogger) SetStorage(storage *database.LocalStorage) {
+	al.storage = storage
+}
+
+// SetFeeModel sets the commission schedule session fees are estimated with
+func (al *ActivityLogger) SetFeeModel(fees FillModel) {
+	al.fees = fees
+}
+
+// StartSession initializes a new trading session for the day. A session
+// still open is closed as of its last sample.
 func (al *ActivityLogger) StartSession(ctx context.Context, startingCapital float64) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if al.currentLog != nil && al.currentLog.SessionEnd.IsZero() {
+		al.currentLog.SessionEnd = time.Now()
+		al.currentLog.Summary.EndingCapital = al.currentLog.Summary.CurrentValue
+		if err := al.saveLog(); err != nil {
+			al.logger.WithError(err).Warn("Failed to close previous session log")
+		}
+		al.saveSession()
+	}
+	al.session = nil
+
 	date := time.Now().Format("2006-01-02")
 
 	al.currentLog = &DailyActivityLog{
@@ -124,6 +204,8 @@ func (al *ActivityLogger) StartSession(ctx context.Context, startingCapital floa
 		SessionStart: time.Now(),
 		Summary: SessionSummary{
 			StartingCapital: startingCapital,
+			CurrentValue:    startingCapital,
+			PeakValue:       startingCapital,
 		},
 		Activities:        make([]Activity, 0),
 		PositionsOpened:   make([]PositionActivity, 0),
@@ -137,11 +219,17 @@ func (al *ActivityLogger) StartSession(ctx context.Context, startingCapital floa
 		"starting_capital": startingCapital,
 	}).Info("Trading session started")
 
+	al.saveSession()
 	return al.saveLog()
 }
 
 // EndSession closes the current trading session
 func (al *ActivityLogger) EndSession(ctx context.Context, endingCapital float64, activePositions int) error {
+	unrealized, sampled := al.unrealizedPnL(ctx)
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
 	if al.currentLog == nil {
 		return fmt.Errorf("no active session")
 	}
@@ -149,6 +237,10 @@ func (al *ActivityLogger) EndSession(ctx context.Context, endingCapital float64,
 	al.currentLog.SessionEnd = time.Now()
 	al.currentLog.Summary.EndingCapital = endingCapital
 	al.currentLog.Summary.ActivePositions = activePositions
+	al.recordValue(endingCapital)
+	if sampled {
+		al.currentLog.Summary.UnrealizedPnL = unrealized
+	}
 
 	// Calculate total P&L
 	if al.currentLog.Summary.StartingCapital > 0 {
@@ -160,24 +252,178 @@ func (al *ActivityLogger) EndSession(ctx context.Context, endingCapital float64,
 		"ending_capital": endingCapital,
 		"total_pnl":      al.currentLog.Summary.TotalPnL,
 		"pnl_percent":    al.currentLog.Summary.TotalPnLPercent,
+		"max_drawdown":   al.currentLog.Summary.MaxDrawdown,
 	}).Info("Trading session ended")
 
+	al.saveSession()
+	return al.saveLog()
+}
+
+// Sample records the portfolio value and unrealized P&L of the active
+// session, if any
+func (al *ActivityLogger) Sample(ctx context.Context) {
+	al.mu.Lock()
+	active := al.currentLog != nil && al.currentLog.SessionEnd.IsZero()
+	al.mu.Unlock()
+	if !active || al.tradingService == nil {
+		return
+	}
+
+	account, err := al.tradingService.GetAccount(ctx)
+	if err != nil {
+		al.logger.WithError(err).Debug("Failed to sample account for session")
+		return
+	}
+	unrealized, sampled := al.unrealizedPnL(ctx)
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if al.currentLog == nil || !al.currentLog.SessionEnd.IsZero() {
+		return
+	}
+	al.recordValue(account.PortfolioValue.InexactFloat64())
+	if sampled {
+		al.currentLog.Summary.UnrealizedPnL = unrealized
+	}
+	al.saveSession()
+	if err := al.saveLog(); err != nil {
+		al.logger.WithError(err).Warn("Failed to save session sample")
+	}
+}
+
+// unrealizedPnL sums the unrealized P&L of open positions
+func (al *ActivityLogger) unrealizedPnL(ctx context.Context) (float64, bool) {
+	if al.tradingService == nil {
+		return 0, false
+	}
+	positions, err := al.tradingService.GetPositions(ctx)
+	if err != nil {
+		al.logger.WithError(err).Debug("Failed to sample positions for session")
+		return 0, false
+	}
+	total := 0.0
+	for _, p := range positions {
+		total += p.UnrealizedPL.InexactFloat64()
+	}
+	return total, true
+}
+
+// recordValue updates the session's peak and drawdown with a portfolio
+// value. The caller holds al.mu.
+func (al *ActivityLogger) recordValue(value float64) {
+	if value <= 0 {
+		return
+	}
+	summary := &al.currentLog.Summary
+	summary.CurrentValue = value
+	if value > summary.PeakValue {
+		summary.PeakValue = value
+	}
+	drawdown := summary.PeakValue - value
+	if drawdown > summary.MaxDrawdown {
+		summary.MaxDrawdown = drawdown
+	}
+	if pct := drawdown / summary.PeakValue * 100; pct > summary.MaxDrawdownPct {
+		summary.MaxDrawdownPct = pct
+	}
+	if summary.StartingCapital > 0 {
+		summary.TotalPnL = value - summary.StartingCapital
+		summary.TotalPnLPercent = summary.TotalPnL / summary.StartingCapital * 100
+	}
+}
+
+// logFill counts a filled order and its estimated fees
+func (al *ActivityLogger) logFill(symbol string, qty float64) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if al.currentLog == nil {
+		return fmt.Errorf("no active session")
+	}
+	al.currentLog.Summary.FilledOrders++
+	al.currentLog.Summary.Fees += al.fees.Fees(symbol, qty)
 	return al.saveLog()
 }
 
+// ListSessions returns the latest stored session summaries, newest first
+func (al *ActivityLogger) ListSessions(limit int) ([]*SessionRecord, error) {
+	if al.storage == nil {
+		return nil, WithErrorCode(ErrCodeUnavailable, fmt.Errorf("session history not enabled"))
+	}
+	rows, err := al.storage.GetActivitySessions(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	records := make([]*SessionRecord, 0, len(rows))
+	for _, row := range rows {
+		record := &SessionRecord{
+			ID:           row.ID,
+			Date:         row.Date,
+			Environment:  row.Environment,
+			SessionStart: row.StartedAt,
+			SessionEnd:   row.EndedAt,
+		}
+		if al.session != nil && row.ID == al.session.ID && al.currentLog != nil {
+			// The live summary is newer than the last stored sample
+			record.Active = al.currentLog.SessionEnd.IsZero()
+			record.Summary = al.currentLog.Summary
+		} else if err := json.Unmarshal([]byte(row.Summary), &record.Summary); err != nil {
+			al.logger.WithError(err).WithField("session_id", row.ID).Warn("Failed to decode session summary")
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// saveSession stores the current session's summary. Failures are logged.
+// The caller holds al.mu.
+func (al *ActivityLogger) saveSession() {
+	if al.storage == nil || al.currentLog == nil {
+		return
+	}
+	summary, err := json.Marshal(al.currentLog.Summary)
+	if err != nil {
+		return
+	}
+	if al.session == nil {
+		al.session = &models.DBActivitySession{
+			Date:        al.currentLog.Date,
+			Environment: al.environment,
+			StartedAt:   al.currentLog.SessionStart,
+		}
+	}
+	al.session.Summary = string(summary)
+	if !al.currentLog.SessionEnd.IsZero() {
+		end := al.currentLog.SessionEnd
+		al.session.EndedAt = &end
+	}
+	if err := al.storage.SaveActivitySession(al.session); err != nil {
+		al.logger.WithError(err).Warn("Failed to store session summary")
+	}
+}
+
 // LogActivity logs a general activity
-func (al *ActivityLogger) LogActivity(activityType, action, symbol, reasoning string, details map[string]interface{}) error {
+func (al *ActivityLogger) LogActivity(ctx context.Context, activityType, action, symbol, reasoning string, details map[string]interface{}) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
 	if al.currentLog == nil {
 		return fmt.Errorf("no active session - call StartSession first")
 	}
 
 	activity := Activity{
-		Timestamp: time.Now(),
-		Type:      activityType,
-		Action:    action,
-		Symbol:    symbol,
-		Details:   details,
-		Reasoning: reasoning,
+		Timestamp:   time.Now(),
+		Type:        activityType,
+		Action:      action,
+		Symbol:      symbol,
+		Details:     details,
+		Reasoning:   reasoning,
+		Environment: al.environment,
+		Book:        BookFrom(ctx),
 	}
 
 	al.currentLog.Activities = append(al.currentLog.Activities, activity)
@@ -192,7 +438,10 @@ func (al *ActivityLogger) LogActivity(activityType, action, symbol, reasoning st
 }
 
 // LogPositionOpened logs when a new position is opened
-func (al *ActivityLogger) LogPositionOpened(symbol, side string, quantity, entryPrice, allocation, stopLoss, takeProfit float64, conviction int, reasoning string, tags []string) error {
+func (al *ActivityLogger) LogPositionOpened(ctx context.Context, symbol, side string, quantity, entryPrice, allocation, stopLoss, takeProfit float64, conviction int, reasoning string, tags []string) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
 	if al.currentLog == nil {
 		return fmt.Errorf("no active session")
 	}
@@ -209,6 +458,8 @@ func (al *ActivityLogger) LogPositionOpened(symbol, side string, quantity, entry
 		Conviction:       conviction,
 		Reasoning:        reasoning,
 		Tags:             tags,
+		Environment:      al.environment,
+		Book:             BookFrom(ctx),
 	}
 
 	al.currentLog.PositionsOpened = append(al.currentLog.PositionsOpened, position)
@@ -226,7 +477,10 @@ func (al *ActivityLogger) LogPositionOpened(symbol, side string, quantity, entry
 }
 
 // LogPositionClosed logs when a position is closed
-func (al *ActivityLogger) LogPositionClosed(symbol, side string, quantity, entryPrice, exitPrice, allocation float64, holdDays int, reasoning string, tags []string) error {
+func (al *ActivityLogger) LogPositionClosed(ctx context.Context, symbol, side string, quantity, entryPrice, exitPrice, allocation float64, holdDays int, reasoning string, tags []string) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
 	if al.currentLog == nil {
 		return fmt.Errorf("no active session")
 	}
@@ -259,10 +513,13 @@ func (al *ActivityLogger) LogPositionClosed(symbol, side string, quantity, entry
 		HoldDays:         holdDays,
 		Reasoning:        reasoning,
 		Tags:             tags,
+		Environment:      al.environment,
+		Book:             BookFrom(ctx),
 	}
 
 	al.currentLog.PositionsClosed = append(al.currentLog.PositionsClosed, position)
 	al.currentLog.Summary.PositionsClosed++
+	al.currentLog.Summary.RealizedPnL += pnl
 
 	// Update win/loss stats
 	if pnl > 0 {
@@ -288,17 +545,22 @@ func (al *ActivityLogger) LogPositionClosed(symbol, side string, quantity, entry
 }
 
 // LogIntelligence logs market intelligence gathering
-func (al *ActivityLogger) LogIntelligence(source, topic, summary string, symbols []string) error {
+func (al *ActivityLogger) LogIntelligence(ctx context.Context, source, topic, summary string, symbols []string) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
 	if al.currentLog == nil {
 		return fmt.Errorf("no active session")
 	}
 
 	intel := IntelligenceNote{
-		Timestamp: time.Now(),
-		Source:    source,
-		Topic:     topic,
-		Summary:   summary,
-		Symbols:   symbols,
+		Timestamp:   time.Now(),
+		Source:      source,
+		Topic:       topic,
+		Summary:     summary,
+		Symbols:     symbols,
+		Environment: al.environment,
+		Book:        BookFrom(ctx),
 	}
 
 	al.currentLog.MarketIntelligence = append(al.currentLog.MarketIntelligence, intel)
@@ -314,18 +576,23 @@ func (al *ActivityLogger) LogIntelligence(source, topic, summary string, symbols
 }
 
 // LogDecision logs a trading decision
-func (al *ActivityLogger) LogDecision(action, symbol, reasoning string, conviction int, marketData map[string]interface{}) error {
+func (al *ActivityLogger) LogDecision(ctx context.Context, action, symbol, reasoning string, conviction int, marketData map[string]interface{}) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
 	if al.currentLog == nil {
 		return fmt.Errorf("no active session")
 	}
 
 	decision := DecisionLog{
-		Timestamp:  time.Now(),
-		Action:     action,
-		Symbol:     symbol,
-		Reasoning:  reasoning,
-		Conviction: conviction,
-		MarketData: marketData,
+		Timestamp:   time.Now(),
+		Action:      action,
+		Symbol:      symbol,
+		Reasoning:   reasoning,
+		Conviction:  conviction,
+		MarketData:  marketData,
+		Environment: al.environment,
+		Book:        BookFrom(ctx),
 	}
 
 	al.currentLog.Decisions = append(al.currentLog.Decisions, decision)
@@ -335,6 +602,9 @@ func (al *ActivityLogger) LogDecision(action, symbol, reasoning string, convicti
 
 // LogStocksAnalyzed updates the count of stocks analyzed
 func (al *ActivityLogger) LogStocksAnalyzed(count int) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
 	if al.currentLog == nil {
 		return fmt.Errorf("no active session")
 	}
@@ -344,8 +614,99 @@ func (al *ActivityLogger) LogStocksAnalyzed(count int) error {
 	return al.saveLog()
 }
 
+// HandleEvent writes a trading activity event from the event bus to the
+// session log. Events arriving while no session is active are dropped.
+func (al *ActivityLogger) HandleEvent(event *Event) {
+	var err error
+	switch data := event.Data.(type) {
+	case OrderUpdate:
+		if isFinalOrderStatus(data.Status) && data.FilledQty.IsPositive() {
+			err = al.logFill(data.Symbol, data.FilledQty.InexactFloat64())
+		}
+	case OrderPlaced:
+		ctx := WithBook(context.Background(), data.Book)
+		err = al.LogActivity(ctx, "ORDER_PLACED", strings.ToUpper(data.Side), data.Symbol, "", map[string]interface{}{
+			"order_id":    data.OrderID,
+			"qty":         data.Qty.String(),
+			"type":        data.Type,
+			"limit_price": data.LimitPrice,
+			"asset_class": data.AssetClass,
+			"source":      data.Source,
+		})
+	case PositionClosed:
+		// LogPositionClosed takes the opening side
+		side := "buy"
+		if data.Side == "short" {
+			side = "sell"
+		}
+		err = al.LogPositionClosed(context.Background(), data.Symbol, side, data.Qty, data.EntryPrice, data.ExitPrice, data.EntryPrice*data.Qty, 0, "", nil)
+	case RiskBreached:
+		ctx := WithBook(context.Background(), data.Book)
+		err = al.LogActivity(ctx, "RISK_BREACHED", "REJECTED", data.Symbol, data.Messages(), map[string]interface{}{
+			"side":        data.Side,
+			"qty":         data.Qty.String(),
+			"notional":    data.Notional.StringFixed(2),
+			"asset_class": data.AssetClass,
+			"source":      data.Source,
+		})
+	case AnalysisCompleted:
+		if err = al.LogStocksAnalyzed(1); err == nil {
+			err = al.LogActivity(context.Background(), "ANALYSIS", "ANALYZED", data.Symbol, data.Notes, map[string]interface{}{
+				"profile":         data.Profile,
+				"price":           data.Price,
+				"composite_score": data.CompositeScore,
+				"trend":           data.Trend,
+			})
+		}
+	}
+	if err != nil {
+		al.logger.WithError(err).WithField("event", event.Type).Debug("Activity event not logged")
+	}
+}
+
+// ForBook returns a copy of the log holding only the entries logged for
+// book. Entries written before books existed belong to DefaultBook. The
+// summary still covers the whole session.
+func (l *DailyActivityLog) ForBook(book string) *DailyActivityLog {
+	filtered := *l
+	filtered.Activities = make([]Activity, 0)
+	for _, entry := range l.Activities {
+		if bookOrDefault(entry.Book) == book {
+			filtered.Activities = append(filtered.Activities, entry)
+		}
+	}
+	filtered.PositionsOpened = positionActivitiesForBook(l.PositionsOpened, book)
+	filtered.PositionsClosed = positionActivitiesForBook(l.PositionsClosed, book)
+	filtered.MarketIntelligence = make([]IntelligenceNote, 0)
+	for _, entry := range l.MarketIntelligence {
+		if bookOrDefault(entry.Book) == book {
+			filtered.MarketIntelligence = append(filtered.MarketIntelligence, entry)
+		}
+	}
+	filtered.Decisions = make([]DecisionLog, 0)
+	for _, entry := range l.Decisions {
+		if bookOrDefault(entry.Book) == book {
+			filtered.Decisions = append(filtered.Decisions, entry)
+		}
+	}
+	return &filtered
+}
+
+func positionActivitiesForBook(entries []PositionActivity, book string) []PositionActivity {
+	filtered := make([]PositionActivity, 0)
+	for _, entry := range entries {
+		if bookOrDefault(entry.Book) == book {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
 // GetCurrentLog returns the current session's log
 func (al *ActivityLogger) GetCurrentLog() (*DailyActivityLog, error) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
 	if al.currentLog == nil {
 		return nil, fmt.Errorf("no active session")
 	}
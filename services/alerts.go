@@ -0,0 +1,748 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Alert conditions
+const (
+	AlertPriceAbove  = "price_above"  // price crosses above Threshold
+	AlertPriceBelow  = "price_below"  // price crosses below Threshold
+	AlertPercentMove = "percent_move" // price moves Threshold% within WindowMinutes
+	AlertRSIAbove    = "rsi_above"    // 14-period RSI on 5Min bars rises above Threshold
+	AlertRSIBelow    = "rsi_below"    // 14-period RSI on 5Min bars falls below Threshold
+	AlertVolumeSpike = "volume_spike" // minute volume reaches Threshold x the 20-bar average
+	AlertNewsMention = "news_mention" // a new headline mentions Keyword (or the symbol)
+)
+
+// Alert statuses
+const (
+	AlertStatusActive    = "ACTIVE"
+	AlertStatusTriggered = "TRIGGERED"
+)
+
+// newsPollInterval limits how often news alerts hit the news feeds
+const newsPollInterval = 5 * time.Minute
+
+// StrategyResolver builds a strategy by name, e.g. strategies.New
+type StrategyResolver func(name string, config map[string]interface{}) (interfaces.StrategyExecutor, error)
+
+// SignalFilter vetoes a strategy signal, returning false and a reason to drop it
+type SignalFilter func(ctx context.Context, symbol, action string) (bool, string)
+
+// Alert is a condition watched for one symbol
+type Alert struct {
+	ID              string     `json:"id"`
+	Symbol          string     `json:"symbol"`
+	Condition       string     `json:"condition"`
+	Threshold       float64    `json:"threshold"`
+	WindowMinutes   int        `json:"window_minutes,omitempty"`
+	Keyword         string     `json:"keyword,omitempty"`
+	Strategy        string     `json:"strategy,omitempty"`
+	Repeat          bool       `json:"repeat"`
+	CooldownMinutes int        `json:"cooldown_minutes,omitempty"`
+	Status          string     `json:"status"`
+	TriggerCount    int        `json:"trigger_count"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+	LastValue       float64    `json:"last_value"`
+	Note            string     `json:"note,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// AlertRequest is the payload for creating an alert
+type AlertRequest struct {
+	Symbol          string  `json:"symbol" binding:"required,symbol"`
+	Condition       string  `json:"condition" binding:"required,oneof=price_above price_below percent_move rsi_above rsi_below volume_spike news_mention"`
+	Threshold       float64 `json:"threshold" binding:"gte=0"`
+	WindowMinutes   int     `json:"window_minutes" binding:"omitempty,min=1,max=1440"`
+	Keyword         string  `json:"keyword"`
+	Strategy        string  `json:"strategy"`
+	Repeat          bool    `json:"repeat"`
+	CooldownMinutes int     `json:"cooldown_minutes" binding:"gte=0"`
+	Note            string  `json:"note"`
+}
+
+// AlertSignal is the decision of the strategy hook attached to an alert
+type AlertSignal struct {
+	Strategy string `json:"strategy"`
+	Action   string `json:"action"`             // "buy", "sell" or "none"
+	Filtered string `json:"filtered,omitempty"` // why a signal was dropped by the filter
+	Error    string `json:"error,omitempty"`
+}
+
+// AlertEvent records one firing of an alert
+type AlertEvent struct {
+	AlertID   string       `json:"alert_id"`
+	Symbol    string       `json:"symbol"`
+	Condition string       `json:"condition"`
+	Value     float64      `json:"value"`
+	Message   string       `json:"message"`
+	Timestamp time.Time    `json:"timestamp"`
+	Signal    *AlertSignal `json:"signal,omitempty"`
+}
+
+type pricePoint struct {
+	price float64
+	at    time.Time
+}
+
+type cachedIndicator struct {
+	value float64
+	at    time.Time
+}
+
+// AlertEngine evaluates alerts against ticks from the stream hub and
+// notifies when conditions are met
+type AlertEngine struct {
+	hub             *StreamHub
+	dataService     interfaces.DataService
+	newsService     *NewsService
+	storageService  *database.LocalStorage
+	notifier        *Notifier
+	resolveStrategy StrategyResolver
+	signalFilter    SignalFilter
+	eventBus        *EventBus
+	alerts          map[string]*Alert
+	lastPrice       map[string]float64
+	priceHistory    map[string][]pricePoint
+	rsiCache        map[string]cachedIndicator
+	avgVolumeCache  map[string]cachedIndicator
+	lastVolumeBar   map[string]time.Time
+	lastNewsCheck   map[string]time.Time
+	events          []AlertEvent
+	mu              sync.RWMutex
+	logger          *logrus.Logger
+}
+
+// NewAlertEngine creates an alert engine and loads persisted alerts.
+// resolveStrategy may be nil to disable strategy hooks.
+func NewAlertEngine(
+	hub *StreamHub,
+	dataService interfaces.DataService,
+	newsService *NewsService,
+	storageService *database.LocalStorage,
+	notifier *Notifier,
+	resolveStrategy StrategyResolver,
+) *AlertEngine {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	ae := &AlertEngine{
+		hub:             hub,
+		dataService:     dataService,
+		newsService:     newsService,
+		storageService:  storageService,
+		notifier:        notifier,
+		resolveStrategy: resolveStrategy,
+		alerts:          make(map[string]*Alert),
+		lastPrice:       make(map[string]float64),
+		priceHistory:    make(map[string][]pricePoint),
+		rsiCache:        make(map[string]cachedIndicator),
+		avgVolumeCache:  make(map[string]cachedIndicator),
+		lastVolumeBar:   make(map[string]time.Time),
+		lastNewsCheck:   make(map[string]time.Time),
+		events:          make([]AlertEvent, 0),
+		logger:          logger,
+	}
+
+	if err := ae.loadAlertsFromDB(); err != nil {
+		logger.WithError(err).Error("Failed to load alerts from database")
+	}
+
+	return ae
+}
+
+// SetSignalFilter installs a filter applied to strategy signals before they
+// are stored
+func (ae *AlertEngine) SetSignalFilter(filter SignalFilter) {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	ae.signalFilter = filter
+}
+
+// SetEventBus publishes alert firings as alert_triggered events
+func (ae *AlertEngine) SetEventBus(bus *EventBus) {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+	ae.eventBus = bus
+}
+
+// CreateAlert validates and registers a new alert
+func (ae *AlertEngine) CreateAlert(req *AlertRequest) (*Alert, error) {
+	alert := &Alert{
+		ID:              fmt.Sprintf("alert_%d", time.Now().UnixNano()),
+		Symbol:          strings.ToUpper(req.Symbol),
+		Condition:       req.Condition,
+		Threshold:       req.Threshold,
+		WindowMinutes:   req.WindowMinutes,
+		Keyword:         req.Keyword,
+		Strategy:        req.Strategy,
+		Repeat:          req.Repeat,
+		CooldownMinutes: req.CooldownMinutes,
+		Status:          AlertStatusActive,
+		Note:            req.Note,
+		CreatedAt:       time.Now(),
+	}
+
+	switch alert.Condition {
+	case AlertPriceAbove, AlertPriceBelow:
+		if alert.Threshold <= 0 {
+			return nil, fmt.Errorf("threshold must be a positive price")
+		}
+	case AlertPercentMove:
+		if alert.Threshold <= 0 {
+			return nil, fmt.Errorf("threshold must be a positive percentage")
+		}
+		if alert.WindowMinutes <= 0 {
+			alert.WindowMinutes = 5
+		}
+	case AlertRSIAbove, AlertRSIBelow:
+		if alert.Threshold <= 0 || alert.Threshold >= 100 {
+			return nil, fmt.Errorf("threshold must be an RSI level between 0 and 100")
+		}
+	case AlertVolumeSpike:
+		if alert.Threshold <= 0 {
+			alert.Threshold = 3
+		}
+	case AlertNewsMention:
+		if alert.Keyword == "" {
+			alert.Keyword = alert.Symbol
+		}
+	default:
+		return nil, fmt.Errorf("unknown condition %q", alert.Condition)
+	}
+
+	if alert.Strategy != "" {
+		if ae.resolveStrategy == nil {
+			return nil, fmt.Errorf("strategy hooks are not enabled")
+		}
+		if _, err := ae.resolveStrategy(alert.Strategy, nil); err != nil {
+			return nil, err
+		}
+	}
+	if alert.Repeat && alert.CooldownMinutes <= 0 {
+		alert.CooldownMinutes = 15
+	}
+
+	if err := ae.saveAlertToDB(alert); err != nil {
+		return nil, fmt.Errorf("failed to save alert: %w", err)
+	}
+
+	ae.mu.Lock()
+	ae.alerts[alert.ID] = alert
+	ae.mu.Unlock()
+
+	if alert.Condition != AlertNewsMention {
+		ae.hub.Track(alert.Symbol)
+	}
+
+	ae.logger.WithFields(logrus.Fields{
+		"alert_id":  alert.ID,
+		"symbol":    alert.Symbol,
+		"condition": alert.Condition,
+		"threshold": alert.Threshold,
+	}).Info("Alert created")
+
+	copied := *alert
+	return &copied, nil
+}
+
+// ListAlerts returns alerts with an optional status filter
+func (ae *AlertEngine) ListAlerts(status string) []*Alert {
+	ae.mu.RLock()
+	defer ae.mu.RUnlock()
+
+	alerts := make([]*Alert, 0, len(ae.alerts))
+	for _, alert := range ae.alerts {
+		if status != "" && alert.Status != status {
+			continue
+		}
+		copied := *alert
+		alerts = append(alerts, &copied)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].CreatedAt.After(alerts[j].CreatedAt)
+	})
+	return alerts
+}
+
+// GetAlert returns an alert by ID
+func (ae *AlertEngine) GetAlert(id string) (*Alert, error) {
+	ae.mu.RLock()
+	defer ae.mu.RUnlock()
+
+	alert, ok := ae.alerts[id]
+	if !ok {
+		return nil, fmt.Errorf("alert not found: %s", id)
+	}
+	copied := *alert
+	return &copied, nil
+}
+
+// DeleteAlert removes an alert
+func (ae *AlertEngine) DeleteAlert(id string) error {
+	ae.mu.Lock()
+	alert, ok := ae.alerts[id]
+	if ok {
+		delete(ae.alerts, id)
+	}
+	ae.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("alert not found: %s", id)
+	}
+	if alert.Status == AlertStatusActive && alert.Condition != AlertNewsMention {
+		ae.hub.Untrack(alert.Symbol)
+	}
+
+	return ae.storageService.DeleteAlert(id)
+}
+
+// RecentEvents returns the most recent alert firings, newest first
+func (ae *AlertEngine) RecentEvents(limit int) []AlertEvent {
+	ae.mu.RLock()
+	defer ae.mu.RUnlock()
+
+	if limit <= 0 || limit > len(ae.events) {
+		limit = len(ae.events)
+	}
+	events := make([]AlertEvent, 0, limit)
+	for i := len(ae.events) - 1; i >= 0 && len(events) < limit; i-- {
+		events = append(events, ae.events[i])
+	}
+	return events
+}
+
+// Run evaluates alerts on every hub tick, and news alerts on their own
+// schedule, until ctx is cancelled
+func (ae *AlertEngine) Run(ctx context.Context) {
+	id, ticks := ae.hub.Subscribe(100)
+	defer ae.hub.Unsubscribe(id)
+
+	newsTicker := time.NewTicker(time.Minute)
+	defer newsTicker.Stop()
+
+	ae.logger.Info("Alert engine started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			ae.logger.Info("Alert engine stopped")
+			return
+		case tick, ok := <-ticks:
+			if !ok {
+				return
+			}
+			ae.evaluateTick(ctx, tick)
+		case <-newsTicker.C:
+			ae.evaluateNews(ctx)
+		}
+	}
+}
+
+// evaluateTick checks every active market alert for the tick's symbol
+func (ae *AlertEngine) evaluateTick(ctx context.Context, tick *MarketTick) {
+	ae.mu.Lock()
+	previous, hasPrevious := ae.lastPrice[tick.Symbol]
+	ae.lastPrice[tick.Symbol] = tick.Price
+	history := append(ae.priceHistory[tick.Symbol], pricePoint{price: tick.Price, at: time.Now()})
+	// Keep at most a day of samples for percent-move windows
+	cutoff := time.Now().Add(-24 * time.Hour)
+	for len(history) > 0 && history[0].at.Before(cutoff) {
+		history = history[1:]
+	}
+	ae.priceHistory[tick.Symbol] = history
+
+	alerts := make([]*Alert, 0)
+	for _, alert := range ae.alerts {
+		if alert.Symbol == tick.Symbol && alert.Status == AlertStatusActive && alert.Condition != AlertNewsMention {
+			alerts = append(alerts, alert)
+		}
+	}
+	ae.mu.Unlock()
+
+	newVolumeBar := false
+	if tick.Bar != nil {
+		ae.mu.Lock()
+		newVolumeBar = tick.Bar.Timestamp.After(ae.lastVolumeBar[tick.Symbol])
+		ae.lastVolumeBar[tick.Symbol] = tick.Bar.Timestamp
+		ae.mu.Unlock()
+	}
+
+	for _, alert := range alerts {
+		var value float64
+		var message string
+		fired := false
+
+		switch alert.Condition {
+		case AlertPriceAbove:
+			value = tick.Price
+			fired = hasPrevious && previous < alert.Threshold && tick.Price >= alert.Threshold
+			message = fmt.Sprintf("%s crossed above %.2f (now %.2f)", alert.Symbol, alert.Threshold, tick.Price)
+
+		case AlertPriceBelow:
+			value = tick.Price
+			fired = hasPrevious && previous > alert.Threshold && tick.Price <= alert.Threshold
+			message = fmt.Sprintf("%s crossed below %.2f (now %.2f)", alert.Symbol, alert.Threshold, tick.Price)
+
+		case AlertPercentMove:
+			windowStart := time.Now().Add(-time.Duration(alert.WindowMinutes) * time.Minute)
+			for _, point := range history {
+				if !point.at.Before(windowStart) {
+					if point.price > 0 {
+						value = (tick.Price - point.price) / point.price * 100
+					}
+					break
+				}
+			}
+			fired = math.Abs(value) >= alert.Threshold
+			message = fmt.Sprintf("%s moved %.2f%% in %d minutes (now %.2f)", alert.Symbol, value, alert.WindowMinutes, tick.Price)
+
+		case AlertRSIAbove, AlertRSIBelow:
+			rsi, err := ae.rsi(ctx, alert.Symbol)
+			if err != nil {
+				ae.logger.WithError(err).WithField("symbol", alert.Symbol).Debug("RSI unavailable for alert")
+				continue
+			}
+			value = rsi
+			if alert.Condition == AlertRSIAbove {
+				fired = alert.LastValue > 0 && alert.LastValue < alert.Threshold && rsi >= alert.Threshold
+				message = fmt.Sprintf("%s RSI rose above %.0f (now %.1f)", alert.Symbol, alert.Threshold, rsi)
+			} else {
+				fired = alert.LastValue > 0 && alert.LastValue > alert.Threshold && rsi <= alert.Threshold
+				message = fmt.Sprintf("%s RSI fell below %.0f (now %.1f)", alert.Symbol, alert.Threshold, rsi)
+			}
+
+		case AlertVolumeSpike:
+			if !newVolumeBar {
+				continue
+			}
+			avg, err := ae.averageVolume(ctx, alert.Symbol)
+			if err != nil || avg <= 0 {
+				continue
+			}
+			value = float64(tick.Bar.Volume) / avg
+			fired = value >= alert.Threshold
+			message = fmt.Sprintf("%s volume %d is %.1fx the recent average", alert.Symbol, tick.Bar.Volume, value)
+		}
+
+		ae.recordValue(alert.ID, value)
+		if fired {
+			ae.fire(ctx, alert.ID, value, message, tick)
+		}
+	}
+}
+
+// evaluateNews checks news alerts no more often than newsPollInterval
+func (ae *AlertEngine) evaluateNews(ctx context.Context) {
+	ae.mu.Lock()
+	due := make([]*Alert, 0)
+	for _, alert := range ae.alerts {
+		if alert.Condition != AlertNewsMention || alert.Status != AlertStatusActive {
+			continue
+		}
+		if time.Since(ae.lastNewsCheck[alert.ID]) < newsPollInterval {
+			continue
+		}
+		copied := *alert
+		due = append(due, &copied)
+	}
+	ae.mu.Unlock()
+
+	for _, alert := range due {
+		items, err := ae.newsService.GetGoogleNewsSearch(alert.Keyword)
+		if err != nil {
+			ae.logger.WithError(err).WithField("keyword", alert.Keyword).Warn("Failed to search news for alert")
+			continue
+		}
+
+		ae.mu.Lock()
+		since := ae.lastNewsCheck[alert.ID]
+		if since.IsZero() {
+			since = alert.CreatedAt
+		}
+		ae.lastNewsCheck[alert.ID] = time.Now()
+		ae.mu.Unlock()
+
+		keyword := strings.ToLower(alert.Keyword)
+		for _, item := range items {
+			if item.PublishedAt.IsZero() || !item.PublishedAt.After(since) {
+				continue
+			}
+			if !strings.Contains(strings.ToLower(item.Title+" "+item.Description), keyword) {
+				continue
+			}
+			ae.fire(ctx, alert.ID, 1, fmt.Sprintf("%s in the news: %s", alert.Symbol, item.Title), nil)
+			break
+		}
+	}
+}
+
+// fire records an event, notifies, runs the strategy hook and updates status
+func (ae *AlertEngine) fire(ctx context.Context, alertID string, value float64, message string, tick *MarketTick) {
+	ae.mu.Lock()
+	alert, ok := ae.alerts[alertID]
+	if !ok || alert.Status != AlertStatusActive {
+		ae.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if alert.LastTriggeredAt != nil && now.Sub(*alert.LastTriggeredAt) < time.Duration(alert.CooldownMinutes)*time.Minute {
+		ae.mu.Unlock()
+		return
+	}
+
+	alert.TriggerCount++
+	alert.LastTriggeredAt = &now
+	if !alert.Repeat {
+		alert.Status = AlertStatusTriggered
+	}
+	snapshot := *alert
+	ae.mu.Unlock()
+
+	if snapshot.Status != AlertStatusActive && snapshot.Condition != AlertNewsMention {
+		ae.hub.Untrack(snapshot.Symbol)
+	}
+
+	event := AlertEvent{
+		AlertID:   snapshot.ID,
+		Symbol:    snapshot.Symbol,
+		Condition: snapshot.Condition,
+		Value:     value,
+		Message:   message,
+		Timestamp: now,
+	}
+	if snapshot.Strategy != "" {
+		event.Signal = ae.runStrategyHook(ctx, &snapshot, tick, message)
+	}
+
+	ae.mu.Lock()
+	ae.events = append(ae.events, event)
+	if len(ae.events) > 200 {
+		ae.events = ae.events[len(ae.events)-200:]
+	}
+	eventBus := ae.eventBus
+	ae.mu.Unlock()
+
+	eventBus.Publish(EventAlertTriggered, event)
+
+	if err := ae.saveAlertToDB(&snapshot); err != nil {
+		ae.logger.WithError(err).WithField("alert_id", snapshot.ID).Error("Failed to persist alert")
+	}
+
+	fields := map[string]interface{}{
+		"alert_id":  snapshot.ID,
+		"symbol":    snapshot.Symbol,
+		"condition": snapshot.Condition,
+		"value":     value,
+	}
+	if event.Signal != nil {
+		fields["strategy"] = event.Signal.Strategy
+		fields["signal"] = event.Signal.Action
+	}
+	ae.notifier.Notify(ctx, NotifyWarning, "Alert triggered", message, fields)
+}
+
+// runStrategyHook asks the alert's strategy whether to act on the alert
+func (ae *AlertEngine) runStrategyHook(ctx context.Context, alert *Alert, tick *MarketTick, message string) *AlertSignal {
+	return ae.RunStrategy(ctx, alert.Strategy, alert.Symbol, tick, fmt.Sprintf("alert %s: %s", alert.ID, message))
+}
+
+// RunStrategy asks the named strategy whether to act on symbol now and
+// stores any buy/sell decision as a signal with reason. It never places
+// orders. tick may be nil.
+func (ae *AlertEngine) RunStrategy(ctx context.Context, name, symbol string, tick *MarketTick, reason string) *AlertSignal {
+	signal := &AlertSignal{Strategy: name, Action: "none"}
+	if ae.resolveStrategy == nil {
+		signal.Error = "strategy hooks are not enabled"
+		return signal
+	}
+
+	strategy, err := ae.resolveStrategy(name, nil)
+	if err != nil {
+		signal.Error = err.Error()
+		return signal
+	}
+
+	bars, err := ae.dataService.GetHistoricalBars(ctx, symbol, time.Now().AddDate(0, 0, -90), time.Now(), "1Day")
+	if err != nil {
+		signal.Error = err.Error()
+		return signal
+	}
+	data := &interfaces.MarketData{
+		Symbol:     symbol,
+		RecentBars: bars,
+		Indicators: PatternIndicators(bars),
+	}
+	if tick != nil {
+		data.CurrentBar = tick.Bar
+		data.LatestTrade = &interfaces.Trade{Symbol: tick.Symbol, Price: tick.Price, Timestamp: tick.Timestamp}
+	} else if len(bars) > 0 {
+		data.CurrentBar = bars[len(bars)-1]
+	}
+
+	strategy.OnMarketData(data)
+	if ok, _ := strategy.ShouldBuy(ctx, symbol, data); ok {
+		signal.Action = "buy"
+	} else if ok, _ := strategy.ShouldSell(ctx, symbol, data); ok {
+		signal.Action = "sell"
+	}
+
+	ae.mu.RLock()
+	filter := ae.signalFilter
+	ae.mu.RUnlock()
+	if signal.Action != "none" && filter != nil {
+		if ok, why := filter(ctx, symbol, signal.Action); !ok {
+			signal.Filtered = fmt.Sprintf("%s dropped: %s", signal.Action, why)
+			signal.Action = "none"
+		}
+	}
+
+	if signal.Action != "none" {
+		if err := ae.storageService.SaveSignal(symbol, signal.Action, strategy.GetName(), reason, 1); err != nil {
+			ae.logger.WithError(err).Error("Failed to save alert signal")
+		}
+	}
+
+	return signal
+}
+
+// rsi returns the 14-period RSI on 5-minute bars, cached for a minute
+func (ae *AlertEngine) rsi(ctx context.Context, symbol string) (float64, error) {
+	ae.mu.RLock()
+	cached, ok := ae.rsiCache[symbol]
+	ae.mu.RUnlock()
+	if ok && time.Since(cached.at) < time.Minute {
+		return cached.value, nil
+	}
+
+	bars, err := ae.dataService.GetHistoricalBars(ctx, symbol, time.Now().AddDate(0, 0, -5), time.Now(), "5Min")
+	if err != nil {
+		return 0, err
+	}
+	if len(bars) < 15 {
+		return 0, fmt.Errorf("not enough bars for RSI")
+	}
+	value := CalculateRSI(bars, 14)
+
+	ae.mu.Lock()
+	ae.rsiCache[symbol] = cachedIndicator{value: value, at: time.Now()}
+	ae.mu.Unlock()
+
+	return value, nil
+}
+
+// averageVolume returns the mean volume of the last 20 minute bars, cached for a minute
+func (ae *AlertEngine) averageVolume(ctx context.Context, symbol string) (float64, error) {
+	ae.mu.RLock()
+	cached, ok := ae.avgVolumeCache[symbol]
+	ae.mu.RUnlock()
+	if ok && time.Since(cached.at) < time.Minute {
+		return cached.value, nil
+	}
+
+	bars, err := ae.dataService.GetHistoricalBars(ctx, symbol, time.Now().AddDate(0, 0, -3), time.Now(), "1Min")
+	if err != nil {
+		return 0, err
+	}
+	// Exclude the bar being compared
+	if len(bars) > 1 {
+		bars = bars[:len(bars)-1]
+	}
+	if len(bars) > 20 {
+		bars = bars[len(bars)-20:]
+	}
+	if len(bars) == 0 {
+		return 0, fmt.Errorf("no bars for volume average")
+	}
+
+	total := 0.0
+	for _, bar := range bars {
+		total += float64(bar.Volume)
+	}
+	value := total / float64(len(bars))
+
+	ae.mu.Lock()
+	ae.avgVolumeCache[symbol] = cachedIndicator{value: value, at: time.Now()}
+	ae.mu.Unlock()
+
+	return value, nil
+}
+
+// recordValue stores the latest evaluated value, used for crossing detection
+func (ae *AlertEngine) recordValue(alertID string, value float64) {
+	ae.mu.Lock()
+	defer ae.mu.Unlock()
+
+	if alert, ok := ae.alerts[alertID]; ok {
+		alert.LastValue = value
+	}
+}
+
+// loadAlertsFromDB restores active alerts on startup
+func (ae *AlertEngine) loadAlertsFromDB() error {
+	dbAlerts, err := ae.storageService.GetAlerts("")
+	if err != nil {
+		return err
+	}
+
+	for _, dbAlert := range dbAlerts {
+		alert := &Alert{
+			ID:              dbAlert.AlertID,
+			Symbol:          dbAlert.Symbol,
+			Condition:       dbAlert.Condition,
+			Threshold:       dbAlert.Threshold,
+			WindowMinutes:   dbAlert.WindowMinutes,
+			Keyword:         dbAlert.Keyword,
+			Strategy:        dbAlert.Strategy,
+			Repeat:          dbAlert.Repeat,
+			CooldownMinutes: dbAlert.CooldownMinutes,
+			Status:          dbAlert.Status,
+			TriggerCount:    dbAlert.TriggerCount,
+			LastTriggeredAt: dbAlert.LastTriggeredAt,
+			LastValue:       dbAlert.LastValue,
+			Note:            dbAlert.Note,
+			CreatedAt:       dbAlert.CreatedAt,
+		}
+		ae.alerts[alert.ID] = alert
+		if alert.Status == AlertStatusActive && alert.Condition != AlertNewsMention {
+			ae.hub.Track(alert.Symbol)
+		}
+	}
+
+	ae.logger.WithField("count", len(dbAlerts)).Info("Loaded alerts from database")
+	return nil
+}
+
+// saveAlertToDB persists an alert
+func (ae *AlertEngine) saveAlertToDB(alert *Alert) error {
+	return ae.storageService.SaveAlert(&models.DBAlert{
+		AlertID:         alert.ID,
+		Symbol:          alert.Symbol,
+		Condition:       alert.Condition,
+		Threshold:       alert.Threshold,
+		WindowMinutes:   alert.WindowMinutes,
+		Keyword:         alert.Keyword,
+		Strategy:        alert.Strategy,
+		Repeat:          alert.Repeat,
+		CooldownMinutes: alert.CooldownMinutes,
+		Status:          alert.Status,
+		TriggerCount:    alert.TriggerCount,
+		LastTriggeredAt: alert.LastTriggeredAt,
+		LastValue:       alert.LastValue,
+		Note:            alert.Note,
+	})
+}
@@ -0,0 +1,679 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Execution algorithms
+const (
+	AlgoTWAP = "twap" // even slices spread over the window
+	AlgoVWAP = "vwap" // slices sized to a share of the market volume
+)
+
+// Algo order statuses
+const (
+	AlgoWorking   = "WORKING"
+	AlgoCompleted = "COMPLETED" // the whole quantity filled
+	AlgoCanceled  = "CANCELED"
+	AlgoExpired   = "EXPIRED" // the window ended before the quantity filled
+	AlgoFailed    = "FAILED"  // the broker refused a child order
+	AlgoDryRun    = "DRY_RUN" // stored for review, never worked
+)
+
+// Algo child order statuses
+const (
+	AlgoSliceSubmitted = "SUBMITTED" // waiting for the fill
+	AlgoSliceFilled    = "FILLED"
+	AlgoSliceCanceled  = "CANCELED" // closed before filling, possibly partially filled
+	AlgoSliceSkipped   = "SKIPPED"  // held back by the risk checks
+	AlgoSliceFailed    = "FAILED"   // the broker refused the order
+)
+
+// algoVWAPInterval is how often a VWAP order places a child sized to the
+// volume traded since the last one
+const algoVWAPInterval = time.Minute
+
+// AlgoOrderRequest is the payload for creating an algo order
+type AlgoOrderRequest struct {
+	Symbol              string           `json:"symbol" binding:"required,symbol"`
+	Side                string           `json:"side" binding:"required,oneof=buy sell"`
+	Qty                 decimal.Decimal  `json:"qty" binding:"gt=0"`
+	Algo                string           `json:"algo" binding:"required,oneof=twap vwap"`
+	DurationMinutes     int              `json:"duration_minutes" binding:"required,min=1,max=1440"`               // window the order is worked over
+	Slices              int              `json:"slices,omitempty" binding:"omitempty,min=1,max=1440"`              // TWAP child orders, one a minute by default
+	MaxParticipationPct float64          `json:"max_participation_pct,omitempty" binding:"omitempty,gt=0,lte=100"` // cap on each child as a percent of market volume, required for VWAP
+	LimitPrice          *decimal.Decimal `json:"limit_price,omitempty" binding:"omitempty,gt=0"`                   // children are limit orders at this price, market orders without it
+	StartAt             *time.Time       `json:"start_at,omitempty"`                                               // defaults to now
+	DryRun              bool             `json:"dry_run,omitempty"`                                                // store the order without working it
+}
+
+// AlgoOrder is a parent order with its execution progress
+type AlgoOrder struct {
+	ID                  string           `json:"id"`
+	Symbol              string           `json:"symbol"`
+	Side                string           `json:"side"`
+	Qty                 decimal.Decimal  `json:"qty"`
+	Algo                string           `json:"algo"`
+	LimitPrice          *decimal.Decimal `json:"limit_price,omitempty"`
+	MaxParticipationPct float64          `json:"max_participation_pct,omitempty"`
+	Slices              int              `json:"slices,omitempty"`
+	Book                string           `json:"book"`
+	Status              string           `json:"status"`
+	Reason              string           `json:"reason,omitempty"`
+	StartAt             time.Time        `json:"start_at"`
+	EndAt               time.Time        `json:"end_at"`
+	NextSliceAt         *time.Time       `json:"next_slice_at,omitempty"`
+	ArrivalPrice        decimal.Decimal  `json:"arrival_price"`
+	FilledQty           decimal.Decimal  `json:"filled_qty"`
+	AvgPrice            decimal.Decimal  `json:"avg_price"`
+	WorkingQty          decimal.Decimal  `json:"working_qty"`   // quantity of the child awaiting its fill
+	RemainingQty        decimal.Decimal  `json:"remaining_qty"` // Qty not yet filled
+	PercentComplete     float64          `json:"percent_complete"`
+	SlippageBps         float64          `json:"slippage_bps"` // average price against the arrival price, positive when worse
+	ChildOrders         int              `json:"child_orders"`
+	Skipped             int              `json:"skipped"`
+	CreatedAt           time.Time        `json:"created_at"`
+	Children            []*AlgoSlice     `json:"children,omitempty"`
+}
+
+// AlgoSlice is one child order of an algo order
+type AlgoSlice struct {
+	Status         string           `json:"status"`
+	Qty            decimal.Decimal  `json:"qty"`
+	MarketVolume   decimal.Decimal  `json:"market_volume"`
+	OrderID        string           `json:"order_id,omitempty"`
+	FilledQty      decimal.Decimal  `json:"filled_qty"`
+	FilledAvgPrice *decimal.Decimal `json:"filled_avg_price,omitempty"`
+	Reason         string           `json:"reason,omitempty"`
+	Timestamp      time.Time        `json:"timestamp"`
+}
+
+// AlgoOrderService works large orders through execution algorithms. A TWAP
+// order places an even share of what is left at regular intervals over its
+// window; a VWAP order places, every minute, a share of the volume the
+// market traded since its last child, so it trades more when the market
+// does. Either can cap each child at a percent of market volume, which keeps
+// thin names from being pushed around. One child is open at a time: one
+// still open when the next is due is canceled and its rest sliced again.
+type AlgoOrderService struct {
+	storage     *database.LocalStorage
+	trading     interfaces.TradingService
+	dataService interfaces.DataService
+	riskManager *RiskManager
+	notifier    *Notifier
+	hours       *MarketHours
+	eventBus    *EventBus
+	dryRun      bool
+	mu          sync.Mutex // keeps cancellation from racing a child order
+	logger      *logrus.Logger
+}
+
+// NewAlgoOrderService creates an algo order service
+func NewAlgoOrderService(storage *database.LocalStorage, trading interfaces.TradingService, dataService interfaces.DataService, riskManager *RiskManager, notifier *Notifier, dryRun bool) *AlgoOrderService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &AlgoOrderService{
+		storage:     storage,
+		trading:     trading,
+		dataService: dataService,
+		riskManager: riskManager,
+		notifier:    notifier,
+		hours:       NewMarketHours(),
+		dryRun:      dryRun,
+		logger:      logger,
+	}
+}
+
+// SetMarketHours decides when the market is open, by default the weekday
+// calendar
+func (s *AlgoOrderService) SetMarketHours(hours *MarketHours) {
+	s.hours = hours
+}
+
+// SetEventBus publishes the progress of algo orders as algo_progress events
+func (s *AlgoOrderService) SetEventBus(bus *EventBus) {
+	s.eventBus = bus
+}
+
+// Create validates and stores a new algo order. Its first child goes out on
+// the next run at or after StartAt.
+func (s *AlgoOrderService) Create(ctx context.Context, req *AlgoOrderRequest) (*AlgoOrder, error) {
+	if req.Algo == AlgoVWAP && req.MaxParticipationPct <= 0 {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("max_participation_pct is required for VWAP orders"))
+	}
+	if _, err := ParseOCCSymbol(req.Symbol); err == nil {
+		return nil, WithErrorCode(ErrCodeNotSupported, fmt.Errorf("algo orders work stocks, ETFs and crypto, not options"))
+	}
+
+	symbol := strings.ToUpper(req.Symbol)
+	start := time.Now()
+	if req.StartAt != nil && req.StartAt.After(start) {
+		start = *req.StartAt
+	}
+
+	slices := 0
+	if req.Algo == AlgoTWAP {
+		slices = req.Slices
+		if slices == 0 {
+			slices = req.DurationMinutes
+		}
+	}
+
+	// The arrival price is only the slippage benchmark, so an order is
+	// still created without a quote
+	arrival := decimal.Zero
+	if quote, err := s.dataService.GetLatestQuote(ctx, symbol); err != nil {
+		s.logger.WithError(err).WithField("symbol", symbol).Warn("No quote for the arrival price")
+	} else if quote.BidPrice > 0 && quote.AskPrice > 0 {
+		arrival = decimal.NewFromFloat((quote.BidPrice + quote.AskPrice) / 2).Round(4)
+	}
+
+	status := AlgoWorking
+	if req.DryRun || s.dryRun {
+		status = AlgoDryRun
+	}
+
+	dbOrder := &models.DBAlgoOrder{
+		AlgoID:           fmt.Sprintf("algo_%d", time.Now().UnixNano()),
+		Symbol:           symbol,
+		Side:             req.Side,
+		Qty:              req.Qty,
+		Algo:             req.Algo,
+		LimitPrice:       req.LimitPrice,
+		MaxParticipation: req.MaxParticipationPct,
+		Slices:           slices,
+		ArrivalPrice:     arrival,
+		Book:             BookFrom(ctx),
+		Status:           status,
+		StartAt:          start,
+		EndAt:            start.Add(time.Duration(req.DurationMinutes) * time.Minute),
+		NextSliceAt:      start,
+		VolumeSince:      start,
+	}
+	if err := s.storage.SaveAlgoOrder(dbOrder); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"id":      dbOrder.AlgoID,
+		"symbol":  dbOrder.Symbol,
+		"side":    dbOrder.Side,
+		"qty":     dbOrder.Qty.String(),
+		"algo":    dbOrder.Algo,
+		"end_at":  dbOrder.EndAt,
+		"dry_run": status == AlgoDryRun,
+	}).Info("Algo order created")
+
+	order := algoOrderFromDB(dbOrder, nil)
+	s.eventBus.Publish(EventAlgoProgress, order)
+	return order, nil
+}
+
+// List returns algo orders with their progress, optionally filtered by
+// status
+func (s *AlgoOrderService) List(status string) ([]*AlgoOrder, error) {
+	dbOrders, err := s.storage.GetAlgoOrders(status)
+	if err != nil {
+		return nil, err
+	}
+	slices, err := s.storage.GetAlgoSlices("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	byOrder := make(map[string][]*models.DBAlgoSlice)
+	for _, slice := range slices {
+		byOrder[slice.AlgoID] = append(byOrder[slice.AlgoID], slice)
+	}
+
+	orders := make([]*AlgoOrder, len(dbOrders))
+	for i, dbOrder := range dbOrders {
+		orders[i] = algoOrderFromDB(dbOrder, byOrder[dbOrder.AlgoID])
+	}
+	return orders, nil
+}
+
+// Get returns an algo order with its progress and every child
+func (s *AlgoOrderService) Get(id string) (*AlgoOrder, error) {
+	dbOrder, err := s.storage.GetAlgoOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	slices, err := s.storage.GetAlgoSlices(id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	order := algoOrderFromDB(dbOrder, slices)
+	order.Children = make([]*AlgoSlice, len(slices))
+	for i, slice := range slices {
+		order.Children[i] = algoSliceFromDB(slice)
+	}
+	return order, nil
+}
+
+// Cancel stops an algo order and cancels its open child. What already
+// filled stays filled.
+func (s *AlgoOrderService) Cancel(ctx context.Context, id string) (*AlgoOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dbOrder, err := s.storage.GetAlgoOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if dbOrder.Status != AlgoWorking {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("algo order %s is already %s", id, dbOrder.Status))
+	}
+
+	if err := s.finish(ctx, dbOrder, AlgoCanceled, "canceled by request"); err != nil {
+		return nil, err
+	}
+	order, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	s.eventBus.Publish(EventAlgoProgress, order)
+	return order, nil
+}
+
+// RunDue records the fills of open children and places the children that
+// are due. Children only go out while the symbol's market is open; the
+// window keeps running while it is closed.
+func (s *AlgoOrderService) RunDue(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed, err := s.refreshFills(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to refresh algo fills")
+	}
+
+	orders, err := s.storage.GetAlgoOrders(AlgoWorking)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, order := range orders {
+		if s.work(ctx, order, now) || changed[order.AlgoID] {
+			if progress, err := s.Get(order.AlgoID); err == nil {
+				s.eventBus.Publish(EventAlgoProgress, progress)
+			}
+		}
+	}
+	return nil
+}
+
+// work advances one algo order: it finishes the order when it is filled or
+// its window has ended, and otherwise places its next child when one is
+// due. It reports whether anything changed.
+func (s *AlgoOrderService) work(ctx context.Context, order *models.DBAlgoOrder, now time.Time) bool {
+	logger := s.logger.WithFields(logrus.Fields{"id": order.AlgoID, "symbol": order.Symbol})
+
+	slices, err := s.storage.GetAlgoSlices(order.AlgoID, "")
+	if err != nil {
+		logger.WithError(err).Error("Failed to load algo slices")
+		return false
+	}
+	progress := algoOrderFromDB(order, slices)
+
+	switch {
+	case !progress.RemainingQty.IsPositive():
+		if err := s.finish(ctx, order, AlgoCompleted, ""); err != nil {
+			logger.WithError(err).Error("Failed to complete algo order")
+		}
+		return true
+	case !now.Before(order.EndAt):
+		reason := fmt.Sprintf("window ended with %s of %s filled", progress.FilledQty.String(), order.Qty.String())
+		if err := s.finish(ctx, order, AlgoExpired, reason); err != nil {
+			logger.WithError(err).Error("Failed to expire algo order")
+		}
+		return true
+	case now.Before(order.NextSliceAt):
+		return false
+	case !s.hours.IsOpen(ctx, marketAssetClass(order.Symbol)):
+		return false
+	}
+
+	// A child still open when the next is due is canceled; the next run
+	// slices what it left unfilled
+	if open := openAlgoSlice(slices); open != nil {
+		if err := s.trading.CancelOrder(ctx, open.OrderID); err != nil {
+			logger.WithError(err).WithField("order_id", open.OrderID).Warn("Failed to cancel algo child order")
+		}
+		return false
+	}
+
+	qty, volume, err := s.sliceQty(ctx, order, progress.RemainingQty, now)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to size algo child order")
+		return false
+	}
+
+	interval := algoVWAPInterval
+	if order.Algo == AlgoTWAP {
+		interval = twapInterval(order)
+	}
+	order.NextSliceAt = now.Add(interval)
+
+	changed := false
+	if qty.IsPositive() {
+		slice := &models.DBAlgoSlice{
+			AlgoID:       order.AlgoID,
+			Symbol:       order.Symbol,
+			Qty:          qty,
+			MarketVolume: volume,
+		}
+		s.place(ctx, order, slice)
+		if err := s.storage.SaveAlgoSlice(slice); err != nil {
+			logger.WithError(err).Error("Failed to save algo slice")
+		}
+		// Volume keeps counting toward the next child until one is sized
+		// from it
+		order.VolumeSince = now
+		changed = true
+
+		if slice.Status == AlgoSliceFailed {
+			if err := s.finish(ctx, order, AlgoFailed, slice.Reason); err != nil {
+				logger.WithError(err).Error("Failed to stop algo order")
+			}
+			return true
+		}
+	}
+
+	if err := s.storage.SaveAlgoOrder(order); err != nil {
+		logger.WithError(err).Error("Failed to update algo order")
+	}
+	return changed
+}
+
+// sliceQty sizes the next child: an even share of what is left over the
+// TWAP slices remaining, or the participation rate times the volume traded
+// since the last VWAP child. The participation cap applies to both. All
+// but the final child are whole shares.
+func (s *AlgoOrderService) sliceQty(ctx context.Context, order *models.DBAlgoOrder, remaining decimal.Decimal, now time.Time) (qty, volume decimal.Decimal, err error) {
+	participation := decimal.NewFromFloat(order.MaxParticipation / 100)
+	if order.MaxParticipation > 0 {
+		volume, err = s.marketVolume(ctx, order.Symbol, order.VolumeSince, now)
+		if err != nil {
+			return decimal.Zero, decimal.Zero, err
+		}
+	}
+
+	switch order.Algo {
+	case AlgoVWAP:
+		qty = volume.Mul(participation)
+	default:
+		left := int64(math.Ceil(float64(order.EndAt.Sub(now)) / float64(twapInterval(order))))
+		if left < 1 {
+			left = 1
+		}
+		qty = remaining.Div(decimal.NewFromInt(left))
+		if order.MaxParticipation > 0 {
+			qty = decimal.Min(qty, volume.Mul(participation))
+		}
+	}
+
+	if !qty.LessThan(remaining) {
+		return remaining, volume, nil
+	}
+	return qty.Floor(), volume, nil
+}
+
+// marketVolume sums the volume of the minute bars that started after since
+func (s *AlgoOrderService) marketVolume(ctx context.Context, symbol string, since, now time.Time) (decimal.Decimal, error) {
+	bars, err := s.dataService.GetHistoricalBars(ctx, symbol, since, now, "1Min")
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	var volume int64
+	for _, bar := range bars {
+		if !bar.Timestamp.Before(since) {
+			volume += bar.Volume
+		}
+	}
+	return decimal.NewFromInt(volume), nil
+}
+
+// place runs the risk checks and places the order for one child, filling
+// in its status
+func (s *AlgoOrderService) place(ctx context.Context, algo *models.DBAlgoOrder, slice *models.DBAlgoSlice) {
+	ctx = WithBook(WithOrderSource(ctx, OrderSourceAlgo), bookOrDefault(algo.Book))
+	logger := s.logger.WithFields(logrus.Fields{
+		"id":     algo.AlgoID,
+		"symbol": algo.Symbol,
+		"qty":    slice.Qty.String(),
+	})
+
+	order := &interfaces.Order{
+		Symbol:      algo.Symbol,
+		Qty:         slice.Qty,
+		Side:        algo.Side,
+		Type:        "market",
+		TimeInForce: "day",
+		Status:      "pending",
+		SubmittedAt: time.Now(),
+		Strategy:    OrderSourceAlgo,
+		Book:        BookFrom(ctx),
+	}
+	if algo.LimitPrice != nil {
+		order.Type = "limit"
+		order.LimitPrice = algo.LimitPrice
+	}
+	if marketAssetClass(algo.Symbol) == AssetClassCrypto {
+		order.TimeInForce = "gtc"
+	}
+
+	decision, err := s.riskManager.Evaluate(ctx, order)
+	if err != nil {
+		slice.Status = AlgoSliceSkipped
+		slice.Reason = fmt.Sprintf("risk checks failed to run: %v", err)
+	} else if !decision.Approved {
+		slice.Status = AlgoSliceSkipped
+		slice.Reason = (&RiskRejectedError{Decision: decision}).Error()
+	}
+	if slice.Status == AlgoSliceSkipped {
+		logger.WithField("reason", slice.Reason).Warn("Algo child order skipped")
+		return
+	}
+
+	result, err := s.trading.PlaceOrder(WithRiskDecision(ctx, decision), order)
+	if err != nil {
+		slice.Status = AlgoSliceFailed
+		slice.Reason = err.Error()
+		logger.WithError(err).Error("Algo child order failed")
+		s.notifier.Notify(ctx, NotifyWarning, "Algo order stopped",
+			fmt.Sprintf("%s %s %s %s: child order failed: %s", algo.AlgoID, algo.Side, algo.Qty.String(), algo.Symbol, err.Error()),
+			logrus.Fields{"id": algo.AlgoID, "symbol": algo.Symbol})
+		return
+	}
+
+	order.ID = result.OrderID
+	order.Status = result.Status
+	if err := s.storage.SaveOrder(order); err != nil {
+		logger.WithError(err).Warn("Failed to save order to database")
+	}
+	slice.Status = AlgoSliceSubmitted
+	slice.OrderID = result.OrderID
+	logger.WithField("order_id", result.OrderID).Info("Algo child order submitted")
+}
+
+// finish ends an algo order with status, canceling its open child
+func (s *AlgoOrderService) finish(ctx context.Context, order *models.DBAlgoOrder, status, reason string) error {
+	slices, err := s.storage.GetAlgoSlices(order.AlgoID, AlgoSliceSubmitted)
+	if err != nil {
+		return err
+	}
+	for _, slice := range slices {
+		if err := s.trading.CancelOrder(ctx, slice.OrderID); err != nil {
+			s.logger.WithError(err).WithField("order_id", slice.OrderID).Warn("Failed to cancel algo child order")
+		}
+	}
+
+	order.Status = status
+	order.Reason = reason
+	if err := s.storage.SaveAlgoOrder(order); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"id":     order.AlgoID,
+		"symbol": order.Symbol,
+		"status": status,
+		"reason": reason,
+	}).Info("Algo order finished")
+	if status == AlgoExpired {
+		s.notifier.Notify(ctx, NotifyWarning, "Algo order expired",
+			fmt.Sprintf("%s %s %s %s: %s", order.AlgoID, order.Side, order.Qty.String(), order.Symbol, reason),
+			logrus.Fields{"id": order.AlgoID, "symbol": order.Symbol})
+	}
+	return nil
+}
+
+// refreshFills records the fills of submitted children from the broker and
+// reports which algo orders changed
+func (s *AlgoOrderService) refreshFills(ctx context.Context) (map[string]bool, error) {
+	changed := make(map[string]bool)
+	pending, err := s.storage.GetAlgoSlices("", AlgoSliceSubmitted)
+	if err != nil {
+		return changed, err
+	}
+
+	for _, slice := range pending {
+		order, err := s.trading.GetOrder(ctx, slice.OrderID)
+		if err != nil {
+			s.logger.WithError(err).WithField("order_id", slice.OrderID).Warn("Failed to get algo child order")
+			continue
+		}
+
+		status := slice.Status
+		switch strings.ToLower(order.Status) {
+		case "filled":
+			status = AlgoSliceFilled
+		case "canceled", "expired", "rejected", "done_for_day":
+			status = AlgoSliceCanceled
+			slice.Reason = "order " + strings.ToLower(order.Status)
+		}
+		if status == slice.Status && order.FilledQty.Equal(slice.FilledQty) {
+			continue
+		}
+
+		slice.Status = status
+		slice.FilledQty = order.FilledQty
+		slice.FilledAvgPrice = order.FilledAvgPrice
+		if err := s.storage.SaveAlgoSlice(slice); err != nil {
+			s.logger.WithError(err).WithField("order_id", slice.OrderID).Warn("Failed to update algo slice")
+			continue
+		}
+		changed[slice.AlgoID] = true
+	}
+	return changed, nil
+}
+
+// twapInterval is the time between a TWAP order's children
+func twapInterval(order *models.DBAlgoOrder) time.Duration {
+	if order.Slices <= 0 {
+		return algoVWAPInterval
+	}
+	return order.EndAt.Sub(order.StartAt) / time.Duration(order.Slices)
+}
+
+// openAlgoSlice returns the child still waiting for its fill, if any
+func openAlgoSlice(slices []*models.DBAlgoSlice) *models.DBAlgoSlice {
+	for _, slice := range slices {
+		if slice.Status == AlgoSliceSubmitted {
+			return slice
+		}
+	}
+	return nil
+}
+
+// algoOrderFromDB converts an algo order and totals its children
+func algoOrderFromDB(dbOrder *models.DBAlgoOrder, slices []*models.DBAlgoSlice) *AlgoOrder {
+	order := &AlgoOrder{
+		ID:                  dbOrder.AlgoID,
+		Symbol:              dbOrder.Symbol,
+		Side:                dbOrder.Side,
+		Qty:                 dbOrder.Qty,
+		Algo:                dbOrder.Algo,
+		LimitPrice:          dbOrder.LimitPrice,
+		MaxParticipationPct: dbOrder.MaxParticipation,
+		Slices:              dbOrder.Slices,
+		Book:                bookOrDefault(dbOrder.Book),
+		Status:              dbOrder.Status,
+		Reason:              dbOrder.Reason,
+		StartAt:             dbOrder.StartAt,
+		EndAt:               dbOrder.EndAt,
+		ArrivalPrice:        dbOrder.ArrivalPrice,
+		CreatedAt:           dbOrder.CreatedAt,
+	}
+	if dbOrder.Status == AlgoWorking {
+		next := dbOrder.NextSliceAt
+		order.NextSliceAt = &next
+	}
+
+	notional := decimal.Zero
+	for _, slice := range slices {
+		switch slice.Status {
+		case AlgoSliceSkipped:
+			order.Skipped++
+			continue
+		case AlgoSliceFailed:
+			continue
+		}
+
+		order.ChildOrders++
+		if slice.FilledAvgPrice != nil && slice.FilledQty.IsPositive() {
+			order.FilledQty = order.FilledQty.Add(slice.FilledQty)
+			notional = notional.Add(slice.FilledQty.Mul(*slice.FilledAvgPrice))
+		}
+		if slice.Status == AlgoSliceSubmitted {
+			order.WorkingQty = order.WorkingQty.Add(decimal.Max(slice.Qty.Sub(slice.FilledQty), decimal.Zero))
+		}
+	}
+
+	order.RemainingQty = decimal.Max(dbOrder.Qty.Sub(order.FilledQty), decimal.Zero)
+	if dbOrder.Qty.IsPositive() {
+		order.PercentComplete, _ = order.FilledQty.Div(dbOrder.Qty).Mul(decimal.NewFromInt(100)).Round(2).Float64()
+	}
+	if order.FilledQty.IsPositive() {
+		order.AvgPrice = notional.Div(order.FilledQty).Round(4)
+		if dbOrder.ArrivalPrice.IsPositive() {
+			slippage := order.AvgPrice.Sub(dbOrder.ArrivalPrice).Div(dbOrder.ArrivalPrice).Mul(decimal.NewFromInt(10000))
+			if dbOrder.Side == "sell" {
+				slippage = slippage.Neg()
+			}
+			order.SlippageBps, _ = slippage.Round(2).Float64()
+		}
+	}
+	return order
+}
+
+func algoSliceFromDB(slice *models.DBAlgoSlice) *AlgoSlice {
+	return &AlgoSlice{
+		Status:         slice.Status,
+		Qty:            slice.Qty,
+		MarketVolume:   slice.MarketVolume,
+		OrderID:        slice.OrderID,
+		FilledQty:      slice.FilledQty,
+		FilledAvgPrice: slice.FilledAvgPrice,
+		Reason:         slice.Reason,
+		Timestamp:      slice.CreatedAt,
+	}
+}
@@ -3,25 +3,56 @@ package services
 import (
 	"context"
 	"fmt"
+	"prophet-trader/config"
 	"prophet-trader/interfaces"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+func init() {
+	RegisterDataProvider("alpaca", func(cfg *config.Config) (interfaces.DataService, error) {
+		data := NewAlpacaDataService(cfg.AlpacaAPIKey, cfg.AlpacaSecretKey, cfg.AlpacaDataFeed)
+		data.SetRequestLimit(cfg.AlpacaDataRateLimit)
+		return data, nil
+	})
+}
+
+// Long bar ranges are split into chunks of at most one page of bars, and
+// up to alpacaBarChunkWorkers chunks are fetched at once
+const (
+	alpacaBarsPageLimit   = 10000
+	alpacaBarChunkWorkers = 4
+)
+
+// Alpaca real-time stock data stream, by feed
+const (
+	alpacaStreamURL            = "wss://stream.data.alpaca.markets/v2/"
+	alpacaStreamReconnectDelay = 5 * time.Second
+	alpacaStreamMaxReconnect   = 2 * time.Minute
 )
 
 // AlpacaDataService implements DataService using Alpaca Market Data API
 type AlpacaDataService struct {
-	client *marketdata.Client
-	logger *logrus.Logger
+	client  *marketdata.Client
+	keys    *alpacaKeys
+	feed    string
+	limiter *RateLimiter // paces chunked bar fetches, nil when unlimited
+	logger  *logrus.Logger
 }
 
 // NewAlpacaDataService creates a new Alpaca data service
 func NewAlpacaDataService(apiKey, secretKey, dataFeed string) *AlpacaDataService {
+	keys := newAlpacaKeys(apiKey, secretKey)
 	client := marketdata.NewClient(marketdata.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: secretKey,
-		Feed:      marketdata.Feed(dataFeed),
+		APIKey:     apiKey,
+		APISecret:  secretKey,
+		Feed:       marketdata.Feed(dataFeed),
+		HTTPClient: keys.httpClient(10 * time.Second),
 	})
 
 	logger := logrus.New()
@@ -31,11 +62,31 @@ func NewAlpacaDataService(apiKey, secretKey, dataFeed string) *AlpacaDataService
 
 	return &AlpacaDataService{
 		client: client,
+		keys:   keys,
+		feed:   dataFeed,
 		logger: logger,
 	}
 }
 
-// GetHistoricalBars retrieves historical bar data
+// SetRequestLimit paces chunked bar fetches to perMinute chunks. Zero or
+// less does not pace them.
+func (s *AlpacaDataService) SetRequestLimit(perMinute int) {
+	if perMinute <= 0 {
+		s.limiter = nil
+		return
+	}
+	s.limiter = NewRateLimiter()
+	s.limiter.SetLimit("bars", RateLimit{PerMinute: perMinute})
+}
+
+// RotateCredentials switches to the Alpaca keys in cfg
+func (s *AlpacaDataService) RotateCredentials(cfg *config.Config) {
+	s.keys.set(cfg.AlpacaAPIKey, cfg.AlpacaSecretKey)
+}
+
+// GetHistoricalBars retrieves historical bar data. Ranges longer than a
+// page of bars are fetched in chunks, several at a time, and joined in
+// order, so the series is complete however long the range.
 func (s *AlpacaDataService) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
 	s.logger.WithFields(logrus.Fields{
 		"symbol":    symbol,
@@ -45,23 +96,103 @@ func (s *AlpacaDataService) GetHistoricalBars(ctx context.Context, symbol string
 	}).Info("Fetching historical bars")
 
 	// Convert timeframe string to Alpaca TimeFrame
-	tf := s.parseTimeframe(timeframe)
+	tf, err := s.parseTimeframe(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	parsed, _ := ParseTimeframe(timeframe)
+
+	chunks := barChunks(start, end, parsed)
+
+	results := make([][]*interfaces.Bar, len(chunks))
+	errs := make([]error, len(chunks))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < alpacaBarChunkWorkers && w < len(chunks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = s.fetchBars(ctx, symbol, chunks[i][0], chunks[i][1], tf)
+			}
+		}()
+	}
+send:
+	for i := range chunks {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	bars := make([]*interfaces.Bar, 0)
+	for i, chunk := range results {
+		if errs[i] != nil {
+			s.logger.WithError(errs[i]).Error("Failed to fetch historical bars")
+			return nil, fmt.Errorf("failed to get historical bars: %w", errs[i])
+		}
+		bars = append(bars, chunk...)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"count":  len(bars),
+		"chunks": len(chunks),
+	}).Info("Fetched historical bars")
+	return bars, nil
+}
 
+// barChunks splits start to end into windows of at most a page of bars,
+// counting bars as if the market never closed. Ends are inclusive, so each
+// window ends just before the next one starts.
+func barChunks(start, end time.Time, tf Timeframe) [][2]time.Time {
+	// Also avoids overflowing the span of a page of monthly bars
+	if end.Sub(start)/alpacaBarsPageLimit <= tf.Duration() {
+		return [][2]time.Time{{start, end}}
+	}
+	span := tf.Duration() * alpacaBarsPageLimit
+	var chunks [][2]time.Time
+	for chunkStart := start; ; chunkStart = chunkStart.Add(span) {
+		chunkEnd := chunkStart.Add(span)
+		if !chunkEnd.Before(end) {
+			return append(chunks, [2]time.Time{chunkStart, end})
+		}
+		chunks = append(chunks, [2]time.Time{chunkStart, chunkEnd.Add(-time.Nanosecond)})
+	}
+}
+
+// fetchBars fetches one chunk, following pages, once the rate limit
+// allows
+func (s *AlpacaDataService) fetchBars(ctx context.Context, symbol string, start, end time.Time, tf marketdata.TimeFrame) ([]*interfaces.Bar, error) {
+	if err := s.waitForRequest(ctx); err != nil {
+		return nil, err
+	}
+
+	opts := barOptionsFrom(ctx)
+	adjustment := marketdata.All
+	if opts.Adjustment != "" {
+		adjustment = marketdata.Adjustment(opts.Adjustment)
+	}
 	req := marketdata.GetBarsRequest{
 		TimeFrame:  tf,
 		Start:      start,
 		End:        end,
-		PageLimit:  10000, // Max allowed
-		Adjustment: marketdata.All,
+		PageLimit:  alpacaBarsPageLimit, // Max allowed
+		Adjustment: adjustment,
+		Feed:       marketdata.Feed(opts.Feed), // empty uses the client's feed
 	}
 
 	barsResp, err := s.client.GetBars(symbol, req)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to fetch historical bars")
-		return nil, fmt.Errorf("failed to get historical bars: %w", err)
+		return nil, err
 	}
 
-	bars := make([]*interfaces.Bar, 0)
+	bars := make([]*interfaces.Bar, 0, len(barsResp))
 	for _, bar := range barsResp {
 		bars = append(bars, &interfaces.Bar{
 			Symbol:    symbol,
@@ -74,11 +205,29 @@ func (s *AlpacaDataService) GetHistoricalBars(ctx context.Context, symbol string
 			VWAP:      bar.VWAP,
 		})
 	}
-
-	s.logger.WithField("count", len(bars)).Info("Fetched historical bars")
 	return bars, nil
 }
 
+// waitForRequest blocks until the request limit allows another chunk
+func (s *AlpacaDataService) waitForRequest(ctx context.Context) error {
+	if s.limiter == nil {
+		return nil
+	}
+	for {
+		allowed, _, wait := s.limiter.Allow("bars", "alpaca")
+		if allowed {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
 // GetLatestBar retrieves the most recent bar for a symbol
 func (s *AlpacaDataService) GetLatestBar(ctx context.Context, symbol string) (*interfaces.Bar, error) {
 	req := marketdata.GetLatestBarRequest{}
@@ -148,6 +297,43 @@ func (s *AlpacaDataService) GetLatestTrade(ctx context.Context, symbol string) (
 	return nil, fmt.Errorf("no trade data found for symbol: %s", symbol)
 }
 
+// GetSplits retrieves the forward and reverse splits of symbols with an
+// ex-date between start and end
+func (s *AlpacaDataService) GetSplits(ctx context.Context, symbols []string, start, end time.Time) ([]*StockSplit, error) {
+	req := marketdata.GetCorporateActionsRequest{
+		Symbols: symbols,
+		Types:   []string{"forward_split", "reverse_split"},
+	}
+	start, end = start.In(marketLocation), end.In(marketLocation)
+	req.Start.Year, req.Start.Month, req.Start.Day = start.Date()
+	req.End.Year, req.End.Month, req.End.Day = end.Date()
+
+	actions, err := s.client.GetCorporateActions(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get corporate actions: %w", err)
+	}
+
+	splits := make([]*StockSplit, 0, len(actions.ForwardSplits)+len(actions.ReverseSplits))
+	for _, split := range actions.ForwardSplits {
+		splits = append(splits, &StockSplit{
+			Symbol:  split.Symbol,
+			ExDate:  split.ExDate.In(marketLocation),
+			OldRate: split.OldRate,
+			NewRate: split.NewRate,
+		})
+	}
+	for _, split := range actions.ReverseSplits {
+		splits = append(splits, &StockSplit{
+			Symbol:  split.Symbol,
+			ExDate:  split.ExDate.In(marketLocation),
+			OldRate: split.OldRate,
+			NewRate: split.NewRate,
+		})
+	}
+
+	return splits, nil
+}
+
 // StreamBars starts streaming bar data for specified symbols
 func (s *AlpacaDataService) StreamBars(ctx context.Context, symbols []string) (<-chan *interfaces.Bar, error) {
 	// This would require websocket connection setup
@@ -168,28 +354,195 @@ func (s *AlpacaDataService) StreamBars(ctx context.Context, symbols []string) (<
 	return barChan, nil
 }
 
+// GetHistoricalTrades retrieves up to limit trades, following pagination.
+// A feed set with WithBarOptions applies.
+func (s *AlpacaDataService) GetHistoricalTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*interfaces.Trade, error) {
+	s.logger.WithFields(logrus.Fields{
+		"symbol": symbol,
+		"start":  start,
+		"end":    end,
+	}).Info("Fetching historical trades")
+
+	if err := s.waitForRequest(ctx); err != nil {
+		return nil, err
+	}
+	trades, err := s.client.GetTrades(symbol, marketdata.GetTradesRequest{
+		Start:      start,
+		End:        end.Add(-time.Nanosecond), // ends are inclusive
+		TotalLimit: limit,
+		PageLimit:  alpacaBarsPageLimit,
+		Feed:       marketdata.Feed(barOptionsFrom(ctx).Feed),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get historical trades: %w", err)
+	}
+
+	result := make([]*interfaces.Trade, 0, len(trades))
+	for _, trade := range trades {
+		result = append(result, &interfaces.Trade{
+			Symbol:    symbol,
+			Price:     trade.Price,
+			Size:      int64(trade.Size),
+			Timestamp: trade.Timestamp,
+		})
+	}
+	return result, nil
+}
+
+// alpacaStreamMessage is a stream message; control messages and trades
+// ("t") share the envelope
+type alpacaStreamMessage struct {
+	Type      string    `json:"T"`
+	Message   string    `json:"msg"`
+	Code      int       `json:"code"`
+	Symbol    string    `json:"S"`
+	Price     float64   `json:"p"`
+	Size      int64     `json:"s"`
+	Timestamp time.Time `json:"t"`
+}
+
+// StreamTrades streams trades over the Alpaca data stream for the
+// configured feed. The connection is re-established with backoff until ctx
+// is cancelled, which also closes the returned channel.
+func (s *AlpacaDataService) StreamTrades(ctx context.Context, symbols []string) (<-chan *interfaces.Trade, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("no symbols to stream")
+	}
+
+	// Connect once up front so bad keys fail the call instead of the stream
+	ws, err := s.connectStream(symbols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream trades: %w", err)
+	}
+
+	tradeChan := make(chan *interfaces.Trade, 1000)
+	go func() {
+		defer close(tradeChan)
+
+		delay := alpacaStreamReconnectDelay
+		for {
+			if err := callSafely(func() error { return s.readTrades(ctx, ws, tradeChan) }); err != nil && ctx.Err() == nil {
+				s.logger.WithError(err).Warn("Alpaca trade stream disconnected")
+			}
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(delay):
+				}
+				if ws, err = s.connectStream(symbols); err == nil {
+					delay = alpacaStreamReconnectDelay
+					break
+				}
+				s.logger.WithError(err).Warn("Alpaca trade stream reconnect failed")
+				if delay *= 2; delay > alpacaStreamMaxReconnect {
+					delay = alpacaStreamMaxReconnect
+				}
+			}
+		}
+	}()
+
+	return tradeChan, nil
+}
+
+// connectStream opens an authenticated stream subscribed to trades
+func (s *AlpacaDataService) connectStream(symbols []string) (*websocket.Conn, error) {
+	feed := s.feed
+	if feed == "" {
+		feed = "iex"
+	}
+	ws, err := websocket.Dial(alpacaStreamURL+feed, "", "https://localhost/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	// The server greets with "connected", then answers auth
+	apiKey, secretKey := s.keys.get()
+	if err := websocket.JSON.Send(ws, map[string]string{"action": "auth", "key": apiKey, "secret": secretKey}); err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	ws.SetReadDeadline(time.Now().Add(30 * time.Second))
+	for authed := false; !authed; {
+		var messages []alpacaStreamMessage
+		if err := websocket.JSON.Receive(ws, &messages); err != nil {
+			ws.Close()
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+		for _, message := range messages {
+			switch {
+			case message.Type == "success" && message.Message == "authenticated":
+				authed = true
+			case message.Type == "error":
+				ws.Close()
+				return nil, fmt.Errorf("authentication failed: %s (code %d)", message.Message, message.Code)
+			}
+		}
+	}
+	ws.SetReadDeadline(time.Time{})
+
+	upper := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		upper[i] = strings.ToUpper(symbol)
+	}
+	if err := websocket.JSON.Send(ws, map[string]interface{}{"action": "subscribe", "trades": upper}); err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"symbols": upper,
+		"feed":    feed,
+	}).Info("Streaming trades from Alpaca")
+	return ws, nil
+}
+
+// readTrades forwards trades until the connection fails or ctx ends
+func (s *AlpacaDataService) readTrades(ctx context.Context, ws *websocket.Conn, tradeChan chan<- *interfaces.Trade) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		ws.Close()
+	}()
+
+	for {
+		var messages []alpacaStreamMessage
+		if err := websocket.JSON.Receive(ws, &messages); err != nil {
+			return err
+		}
+		for _, message := range messages {
+			switch message.Type {
+			case "t":
+			case "error":
+				return fmt.Errorf("stream error: %s (code %d)", message.Message, message.Code)
+			default:
+				continue
+			}
+			trade := &interfaces.Trade{
+				Symbol:    message.Symbol,
+				Price:     message.Price,
+				Size:      message.Size,
+				Timestamp: message.Timestamp,
+			}
+			select {
+			case tradeChan <- trade:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
 // parseTimeframe converts string timeframe to Alpaca TimeFrame
-func (s *AlpacaDataService) parseTimeframe(tf string) marketdata.TimeFrame {
-	switch tf {
-	case "1Min":
-		return marketdata.OneMin
-	case "5Min":
-		return marketdata.NewTimeFrame(5, marketdata.Min)
-	case "15Min":
-		return marketdata.NewTimeFrame(15, marketdata.Min)
-	case "30Min":
-		return marketdata.NewTimeFrame(30, marketdata.Min)
-	case "1Hour":
-		return marketdata.OneHour
-	case "4Hour":
-		return marketdata.NewTimeFrame(4, marketdata.Hour)
-	case "1Day":
-		return marketdata.OneDay
-	case "1Week":
-		return marketdata.OneWeek
-	case "1Month":
-		return marketdata.OneMonth
-	default:
-		return marketdata.OneDay
+func (s *AlpacaDataService) parseTimeframe(timeframe string) (marketdata.TimeFrame, error) {
+	tf, err := ParseTimeframe(timeframe)
+	if err != nil {
+		return marketdata.TimeFrame{}, err
 	}
+	return marketdata.NewTimeFrame(tf.Amount, marketdata.TimeFrameUnit(tf.Unit)), nil
 }
\ No newline at end of file
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"prophet-trader/config"
 	"prophet-trader/interfaces"
 	"time"
 
@@ -14,11 +16,10 @@ import (
 
 // AlpacaOptionsDataService fetches real historical options data from Alpaca
 type AlpacaOptionsDataService struct {
-	apiKey    string
-	secretKey string
-	baseURL   string
-	logger    *logrus.Logger
-	client    *http.Client
+	keys    *alpacaKeys
+	baseURL string
+	logger  *logrus.Logger
+	client  *http.Client
 }
 
 // NewAlpacaOptionsDataService creates a new Alpaca options data service
@@ -29,15 +30,20 @@ func NewAlpacaOptionsDataService(apiKey, secretKey string) *AlpacaOptionsDataSer
 	})
 
 	// Note: Options data API might require different subscription
+	keys := newAlpacaKeys(apiKey, secretKey)
 	return &AlpacaOptionsDataService{
-		apiKey:    apiKey,
-		secretKey: secretKey,
-		baseURL:   "https://data.alpaca.markets", // Options data endpoint
-		logger:    logger,
-		client:    &http.Client{Timeout: 30 * time.Second},
+		keys:    keys,
+		baseURL: "https://data.alpaca.markets", // Options data endpoint
+		logger:  logger,
+		client:  keys.httpClient(30 * time.Second),
 	}
 }
 
+// RotateCredentials switches to the Alpaca keys in cfg
+func (s *AlpacaOptionsDataService) RotateCredentials(cfg *config.Config) {
+	s.keys.set(cfg.AlpacaAPIKey, cfg.AlpacaSecretKey)
+}
+
 // AlpacaOptionsSnapshot represents Alpaca's options snapshot response
 type AlpacaOptionsSnapshot struct {
 	Snapshots map[string]AlpacaOptionContract `json:"snapshots"`
@@ -103,9 +109,6 @@ func (s *AlpacaOptionsDataService) GetOptionSnapshot(ctx context.Context, option
 		return nil, err
 	}
 
-	req.Header.Set("APCA-API-KEY-ID", s.apiKey)
-	req.Header.Set("APCA-API-SECRET-KEY", s.secretKey)
-
 	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch snapshot: %w", err)
@@ -163,9 +166,6 @@ func (s *AlpacaOptionsDataService) GetOptionChain(ctx context.Context, underlyin
 		return nil, err
 	}
 
-	req.Header.Set("APCA-API-KEY-ID", s.apiKey)
-	req.Header.Set("APCA-API-SECRET-KEY", s.secretKey)
-
 	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch option chain: %w", err)
@@ -232,9 +232,6 @@ func (s *AlpacaOptionsDataService) FindOptionsNearDTE(ctx context.Context, under
 		return nil, err
 	}
 
-	req.Header.Set("APCA-API-KEY-ID", s.apiKey)
-	req.Header.Set("APCA-API-SECRET-KEY", s.secretKey)
-
 	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch options: %w", err)
@@ -271,4 +268,83 @@ func (s *AlpacaOptionsDataService) FindOptionsNearDTE(ctx context.Context, under
 
 	s.logger.WithField("count", len(contracts)).Info("Found option contracts")
 	return contracts, nil
-}
\ No newline at end of file
+}
+
+// alpacaOptionBarsResponse is a page of Alpaca's historical options bars
+type alpacaOptionBarsResponse struct {
+	Bars          map[string][]alpacaOptionBar `json:"bars"`
+	NextPageToken string                       `json:"next_page_token"`
+}
+
+type alpacaOptionBar struct {
+	Timestamp time.Time `json:"t"`
+	Open      float64   `json:"o"`
+	High      float64   `json:"h"`
+	Low       float64   `json:"l"`
+	Close     float64   `json:"c"`
+	Volume    int64     `json:"v"`
+	VWAP      float64   `json:"vw"`
+}
+
+// GetHistoricalBars fetches bars for an OCC option symbol, following pages.
+// Alpaca keeps options history from February 2024; a contract that did not
+// trade in the range has no bars.
+func (s *AlpacaOptionsDataService) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
+	bars := make([]*interfaces.Bar, 0)
+	pageToken := ""
+	for {
+		query := url.Values{}
+		query.Set("symbols", symbol)
+		query.Set("timeframe", timeframe)
+		query.Set("start", start.UTC().Format(time.RFC3339))
+		query.Set("end", end.UTC().Format(time.RFC3339))
+		query.Set("limit", "10000")
+		query.Set("sort", "asc")
+		if pageToken != "" {
+			query.Set("page_token", pageToken)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+"/v1beta1/options/bars?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch option bars: %w", err)
+		}
+
+		var page alpacaOptionBarsResponse
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			err := fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+			if resp.StatusCode == http.StatusTooManyRequests {
+				return nil, WithErrorCode(ErrCodeUpstreamRateLimited, err)
+			}
+			return nil, WithErrorCode(ErrCodeUpstreamError, err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode option bars: %w", err)
+		}
+
+		for _, bar := range page.Bars[symbol] {
+			bars = append(bars, &interfaces.Bar{
+				Symbol:    symbol,
+				Timestamp: bar.Timestamp,
+				Open:      bar.Open,
+				High:      bar.High,
+				Low:       bar.Low,
+				Close:     bar.Close,
+				Volume:    bar.Volume,
+				VWAP:      bar.VWAP,
+			})
+		}
+
+		if page.NextPageToken == "" {
+			return bars, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"prophet-trader/config"
 	"prophet-trader/interfaces"
+	"strings"
 	"time"
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
@@ -15,28 +17,36 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterBroker("alpaca", func(cfg *config.Config) (interfaces.TradingService, error) {
+		return NewAlpacaTradingService(cfg.AlpacaAPIKey, cfg.AlpacaSecretKey, cfg.AlpacaBaseURL, cfg.AlpacaPaper, cfg.AlpacaDataFeed)
+	})
+}
+
 // AlpacaTradingService implements TradingService using Alpaca API
 type AlpacaTradingService struct {
 	client     *alpaca.Client
 	dataClient *marketdata.Client
-	apiKey     string
-	apiSecret  string
+	keys       *alpacaKeys
 	logger     *logrus.Logger
 }
 
 // NewAlpacaTradingService creates a new Alpaca trading service
 func NewAlpacaTradingService(apiKey, secretKey, baseURL string, isPaper bool, dataFeed string) (*AlpacaTradingService, error) {
+	keys := newAlpacaKeys(apiKey, secretKey)
 	client := alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: secretKey,
-		BaseURL:   baseURL,
+		APIKey:     apiKey,
+		APISecret:  secretKey,
+		BaseURL:    baseURL,
+		HTTPClient: keys.httpClient(10 * time.Second),
 	})
 
 	// Create data client
 	dataClient := marketdata.NewClient(marketdata.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: secretKey,
-		Feed:      marketdata.Feed(dataFeed),
+		APIKey:     apiKey,
+		APISecret:  secretKey,
+		Feed:       marketdata.Feed(dataFeed),
+		HTTPClient: keys.httpClient(10 * time.Second),
 	})
 
 	logger := logrus.New()
@@ -47,37 +57,126 @@ func NewAlpacaTradingService(apiKey, secretKey, baseURL string, isPaper bool, da
 	return &AlpacaTradingService{
 		client:     client,
 		dataClient: dataClient,
-		apiKey:     apiKey,
-		apiSecret:  secretKey,
+		keys:       keys,
 		logger:     logger,
 	}, nil
 }
 
-// PlaceOrder places a new order
-func (s *AlpacaTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
-	qty := decimal.NewFromFloat(order.Qty)
-	req := alpaca.PlaceOrderRequest{
-		Symbol:      order.Symbol,
-		Qty:         &qty,
-		Side:        alpaca.Side(order.Side),
-		Type:        alpaca.OrderType(order.Type),
-		TimeInForce: alpaca.TimeInForce(order.TimeInForce),
+// IsMarketOpen reports whether the market is open now. Alpaca's clock
+// accounts for holidays and early closes.
+func (s *AlpacaTradingService) IsMarketOpen(ctx context.Context) (bool, error) {
+	clock, err := s.client.GetClock()
+	if err != nil {
+		return false, fmt.Errorf("failed to get market clock: %w", err)
 	}
+	return clock.IsOpen, nil
+}
 
-	if order.LimitPrice != nil {
-		limitPrice := decimal.NewFromFloat(*order.LimitPrice)
-		req.LimitPrice = &limitPrice
+// GetMarketCalendar returns the trading days between start and end with
+// their session times, which are earlier on half-days
+func (s *AlpacaTradingService) GetMarketCalendar(ctx context.Context, start, end time.Time) ([]MarketDay, error) {
+	calendar, err := s.client.GetCalendar(alpaca.GetCalendarRequest{Start: start, End: end})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market calendar: %w", err)
 	}
 
-	if order.StopPrice != nil {
-		stopPrice := decimal.NewFromFloat(*order.StopPrice)
-		req.StopPrice = &stopPrice
+	days := make([]MarketDay, 0, len(calendar))
+	for _, day := range calendar {
+		opens, err := time.ParseInLocation(marketDateFormat+" "+marketTimeFormat, day.Date+" "+day.Open, marketLocation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid market calendar open on %s: %w", day.Date, err)
+		}
+		closes, err := time.ParseInLocation(marketDateFormat+" "+marketTimeFormat, day.Date+" "+day.Close, marketLocation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid market calendar close on %s: %w", day.Date, err)
+		}
+		days = append(days, MarketDay{Open: opens, Close: closes})
 	}
+	return days, nil
+}
+
+// alpacaActivityPageSize is how many account activities each request pages
+const alpacaActivityPageSize = 100
+
+// GetAccountActivities returns the account's non-trade activities dated
+// after after, oldest first, following Alpaca's pages to the end
+func (s *AlpacaTradingService) GetAccountActivities(ctx context.Context, after time.Time) ([]*AccountActivity, error) {
+	req := alpaca.GetAccountActivitiesRequest{
+		Category:  "non_trade_activity",
+		After:     after,
+		Direction: "asc",
+		PageSize:  alpacaActivityPageSize,
+	}
+
+	var activities []*AccountActivity
+	for {
+		page, err := s.client.GetAccountActivities(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account activities: %w", err)
+		}
+		for _, a := range page {
+			date := a.TransactionTime
+			if date.IsZero() {
+				date = a.Date.In(marketLocation)
+			}
+			activities = append(activities, &AccountActivity{
+				ID:             a.ID,
+				Type:           a.ActivityType,
+				Category:       ActivityCategory(a.ActivityType),
+				Date:           date,
+				Symbol:         a.Symbol,
+				Qty:            a.Qty,
+				Price:          a.Price,
+				PerShareAmount: a.PerShareAmount,
+				NetAmount:      a.NetAmount,
+				Description:    a.Description,
+				Status:         a.Status,
+			})
+		}
+		if len(page) < alpacaActivityPageSize {
+			return activities, nil
+		}
+		req.PageToken = page[len(page)-1].ID
+	}
+}
+
+// StreamTradeUpdates streams the account's order updates from Alpaca's
+// trade_updates feed, replaying those after since when it is set
+func (s *AlpacaTradingService) StreamTradeUpdates(ctx context.Context, since time.Time, handle func(TradeUpdate)) error {
+	req := alpaca.StreamTradeUpdatesRequest{}
+	if !since.IsZero() {
+		req.Since = since.Add(time.Nanosecond)
+	}
+	return s.client.StreamTradeUpdates(ctx, func(tu alpaca.TradeUpdate) {
+		update := TradeUpdate{
+			Event:       tu.Event,
+			Order:       s.convertAlpacaOrder(&tu.Order),
+			ExecutionID: tu.ExecutionID,
+			At:          tu.At,
+		}
+		if tu.Price != nil {
+			update.Price = *tu.Price
+		}
+		if tu.Qty != nil {
+			update.Qty = *tu.Qty
+		}
+		handle(update)
+	}, req)
+}
+
+// RotateCredentials switches to the Alpaca keys in cfg
+func (s *AlpacaTradingService) RotateCredentials(cfg *config.Config) {
+	s.keys.set(cfg.AlpacaAPIKey, cfg.AlpacaSecretKey)
+}
+
+// PlaceOrder places a new order
+func (s *AlpacaTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	req := BuildOrderRequest(order)
 
 	s.logger.WithFields(logrus.Fields{
 		"symbol": order.Symbol,
 		"side":   order.Side,
-		"qty":    order.Qty,
+		"qty":    order.Qty.String(),
 		"type":   order.Type,
 	}).Info("Placing order")
 
@@ -90,7 +189,7 @@ func (s *AlpacaTradingService) PlaceOrder(ctx context.Context, order *interfaces
 	return &interfaces.OrderResult{
 		OrderID: alpacaOrder.ID,
 		Status:  string(alpacaOrder.Status),
-		Message: fmt.Sprintf("Order placed successfully: %s %v shares of %s", order.Side, order.Qty, order.Symbol),
+		Message: fmt.Sprintf("Order placed successfully: %s %s shares of %s", order.Side, order.Qty, order.Symbol),
 	}, nil
 }
 
@@ -107,6 +206,20 @@ func (s *AlpacaTradingService) CancelOrder(ctx context.Context, orderID string)
 	return nil
 }
 
+// ReplaceOrder moves an open limit order to limitPrice. Alpaca gives the
+// replacement a new order ID.
+func (s *AlpacaTradingService) ReplaceOrder(ctx context.Context, order *interfaces.Order, limitPrice decimal.Decimal) (*interfaces.Order, error) {
+	replaced, err := s.client.ReplaceOrder(order.ID, alpaca.ReplaceOrderRequest{
+		LimitPrice:  &limitPrice,
+		TimeInForce: alpaca.TimeInForce(order.TimeInForce),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace order: %w", err)
+	}
+
+	return s.convertAlpacaOrder(replaced), nil
+}
+
 // GetOrder retrieves a specific order
 func (s *AlpacaTradingService) GetOrder(ctx context.Context, orderID string) (*interfaces.Order, error) {
 	alpacaOrder, err := s.client.GetOrder(orderID)
@@ -151,13 +264,13 @@ func (s *AlpacaTradingService) GetPositions(ctx context.Context) ([]*interfaces.
 	for i, ap := range alpacaPositions {
 		positions[i] = &interfaces.Position{
 			Symbol:           ap.Symbol,
-			Qty:              ap.Qty.InexactFloat64(),
-			AvgEntryPrice:    ap.AvgEntryPrice.InexactFloat64(),
-			MarketValue:      ap.MarketValue.InexactFloat64(),
-			CostBasis:        ap.CostBasis.InexactFloat64(),
-			UnrealizedPL:     ap.UnrealizedPL.InexactFloat64(),
-			UnrealizedPLPC:   ap.UnrealizedIntradayPLPC.InexactFloat64(),
-			CurrentPrice:     ap.CurrentPrice.InexactFloat64(),
+			Qty:              ap.Qty,
+			AvgEntryPrice:    ap.AvgEntryPrice,
+			MarketValue:      decimalOrZero(ap.MarketValue),
+			CostBasis:        ap.CostBasis,
+			UnrealizedPL:     decimalOrZero(ap.UnrealizedPL),
+			UnrealizedPLPC:   decimalOrZero(ap.UnrealizedIntradayPLPC),
+			CurrentPrice:     decimalOrZero(ap.CurrentPrice),
 			Side:             string(ap.Side),
 		}
 	}
@@ -173,46 +286,80 @@ func (s *AlpacaTradingService) GetAccount(ctx context.Context) (*interfaces.Acco
 	}
 
 	return &interfaces.Account{
-		ID:               alpacaAccount.ID,
-		Cash:             alpacaAccount.Cash.InexactFloat64(),
-		PortfolioValue:   alpacaAccount.PortfolioValue.InexactFloat64(),
-		BuyingPower:      alpacaAccount.BuyingPower.InexactFloat64(),
-		DayTradeCount:    int(alpacaAccount.DaytradeCount),
-		PatternDayTrader: alpacaAccount.PatternDayTrader,
+		ID:                alpacaAccount.ID,
+		Cash:              alpacaAccount.Cash,
+		PortfolioValue:    alpacaAccount.PortfolioValue,
+		BuyingPower:       alpacaAccount.BuyingPower,
+		DayTradeCount:     int(alpacaAccount.DaytradeCount),
+		PatternDayTrader:  alpacaAccount.PatternDayTrader,
+		Equity:            alpacaAccount.Equity,
+		RegTBuyingPower:   alpacaAccount.RegTBuyingPower,
+		Multiplier:        alpacaAccount.Multiplier,
+		InitialMargin:     alpacaAccount.InitialMargin,
+		MaintenanceMargin: alpacaAccount.MaintenanceMargin,
 	}, nil
 }
 
-// Helper function to convert Alpaca order to our interface
-func (s *AlpacaTradingService) convertAlpacaOrder(ao *alpaca.Order) *interfaces.Order {
-	order := &interfaces.Order{
-		ID:          ao.ID,
-		Symbol:      ao.Symbol,
-		Qty:         ao.Qty.InexactFloat64(),
-		Side:        string(ao.Side),
-		Type:        string(ao.Type),
-		TimeInForce: string(ao.TimeInForce),
-		Status:      string(ao.Status),
-		SubmittedAt: ao.SubmittedAt,
-	}
+// GetPortfolioHistory retrieves daily account equity between start and end
+func (s *AlpacaTradingService) GetPortfolioHistory(ctx context.Context, start, end time.Time) ([]*interfaces.EquitySnapshot, error) {
+	return s.portfolioHistory(start, end, alpaca.Day1)
+}
 
-	if ao.LimitPrice != nil {
-		val := ao.LimitPrice.InexactFloat64()
-		order.LimitPrice = &val
+// GetIntradayPortfolioHistory retrieves account equity between start and
+// end at a 1Min, 5Min, 15Min or 1H timeframe. Alpaca keeps intraday history
+// for the last 30 days.
+func (s *AlpacaTradingService) GetIntradayPortfolioHistory(ctx context.Context, start, end time.Time, timeframe string) ([]*interfaces.EquitySnapshot, error) {
+	return s.portfolioHistory(start, end, alpaca.TimeFrame(timeframe))
+}
+
+func (s *AlpacaTradingService) portfolioHistory(start, end time.Time, timeframe alpaca.TimeFrame) ([]*interfaces.EquitySnapshot, error) {
+	days := int(end.Sub(start).Hours()/24) + 1
+	history, err := s.client.GetPortfolioHistory(alpaca.GetPortfolioHistoryRequest{
+		Period:    fmt.Sprintf("%dD", days),
+		TimeFrame: timeframe,
+		DateEnd:   end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio history: %w", err)
 	}
 
-	if ao.StopPrice != nil {
-		val := ao.StopPrice.InexactFloat64()
-		order.StopPrice = &val
+	snapshots := make([]*interfaces.EquitySnapshot, 0, len(history.Timestamp))
+	for i, ts := range history.Timestamp {
+		if i >= len(history.Equity) {
+			break
+		}
+		t := time.Unix(ts, 0)
+		if t.Before(start) || history.Equity[i].IsZero() {
+			continue
+		}
+		snapshots = append(snapshots, &interfaces.EquitySnapshot{
+			Timestamp: t,
+			Equity:    history.Equity[i],
+		})
 	}
 
-	// FilledQty is not a pointer, it's a decimal.Decimal
-	if !ao.FilledQty.IsZero() {
-		order.FilledQty = ao.FilledQty.InexactFloat64()
+	return snapshots, nil
+}
+
+// Helper function to convert Alpaca order to our interface
+func (s *AlpacaTradingService) convertAlpacaOrder(ao *alpaca.Order) *interfaces.Order {
+	order := &interfaces.Order{
+		ID:            ao.ID,
+		ClientOrderID: ao.ClientOrderID,
+		Symbol:        ao.Symbol,
+		Qty:           decimalOrZero(ao.Qty),
+		Side:          string(ao.Side),
+		Type:          string(ao.Type),
+		TimeInForce:   string(ao.TimeInForce),
+		Status:        string(ao.Status),
+		SubmittedAt:   ao.SubmittedAt,
+		LimitPrice:    ao.LimitPrice,
+		StopPrice:     ao.StopPrice,
+		FilledQty:     ao.FilledQty,
 	}
 
 	if ao.FilledAvgPrice != nil {
-		val := ao.FilledAvgPrice.InexactFloat64()
-		order.FilledAvgPrice = &val
+		order.FilledAvgPrice = ao.FilledAvgPrice
 	}
 
 	if ao.FilledAt != nil {
@@ -226,26 +373,50 @@ func (s *AlpacaTradingService) convertAlpacaOrder(ao *alpaca.Order) *interfaces.
 	return order
 }
 
-// PlaceOptionsOrder places a new options order
-func (s *AlpacaTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
-	qty := decimal.NewFromFloat(order.Qty)
-	req := alpaca.PlaceOrderRequest{
+// decimalOrZero dereferences optional Alpaca decimal fields
+func decimalOrZero(d *decimal.Decimal) decimal.Decimal {
+	if d == nil {
+		return decimal.Zero
+	}
+	return *d
+}
+
+// BuildOrderRequest converts an order into the request sent to Alpaca
+func BuildOrderRequest(order *interfaces.Order) alpaca.PlaceOrderRequest {
+	qty := order.Qty
+	return alpaca.PlaceOrderRequest{
+		Symbol:        order.Symbol,
+		Qty:           &qty,
+		Side:          alpaca.Side(order.Side),
+		Type:          alpaca.OrderType(order.Type),
+		TimeInForce:   alpaca.TimeInForce(order.TimeInForce),
+		LimitPrice:    order.LimitPrice,
+		StopPrice:     order.StopPrice,
+		ClientOrderID: order.ClientOrderID,
+	}
+}
+
+// BuildOptionsOrderRequest converts an options order into the request sent to Alpaca
+func BuildOptionsOrderRequest(order *interfaces.OptionsOrder) alpaca.PlaceOrderRequest {
+	qty := order.Qty
+	return alpaca.PlaceOrderRequest{
 		Symbol:      order.Symbol,
 		Qty:         &qty,
 		Side:        alpaca.Side(order.Side),
 		Type:        alpaca.OrderType(order.Type),
 		TimeInForce: alpaca.TimeInForce(order.TimeInForce),
+		LimitPrice:  order.LimitPrice,
 	}
+}
 
-	if order.LimitPrice != nil {
-		limitPrice := decimal.NewFromFloat(*order.LimitPrice)
-		req.LimitPrice = &limitPrice
-	}
+// PlaceOptionsOrder places a new options order
+func (s *AlpacaTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	req := BuildOptionsOrderRequest(order)
 
 	s.logger.WithFields(logrus.Fields{
 		"symbol": order.Symbol,
 		"side":   order.Side,
-		"qty":    order.Qty,
+		"qty":    order.Qty.String(),
 		"type":   order.Type,
 	}).Info("Placing options order")
 
@@ -258,7 +429,7 @@ func (s *AlpacaTradingService) PlaceOptionsOrder(ctx context.Context, order *int
 	return &interfaces.OrderResult{
 		OrderID: alpacaOrder.ID,
 		Status:  string(alpacaOrder.Status),
-		Message: fmt.Sprintf("Options order placed successfully: %s %v contracts of %s", order.Side, order.Qty, order.Symbol),
+		Message: fmt.Sprintf("Options order placed successfully: %s %s contracts of %s", order.Side, order.Qty, order.Symbol),
 	}, nil
 }
 
@@ -309,13 +480,10 @@ func (s *AlpacaTradingService) GetOptionsChain(ctx context.Context, underlying s
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Add Alpaca API headers
-	req.Header.Set("APCA-API-KEY-ID", s.apiKey)
-	req.Header.Set("APCA-API-SECRET-KEY", s.apiSecret)
 	req.Header.Set("Accept", "application/json")
 
-	// Execute request
-	client := &http.Client{Timeout: 30 * time.Second}
+	// Execute request; the client adds the Alpaca API headers
+	client := s.keys.httpClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch options chain: %w", err)
@@ -341,9 +509,6 @@ func (s *AlpacaTradingService) GetOptionsChain(ctx context.Context, underlying s
 	// Convert to our OptionContract format
 	contracts := make([]*interfaces.OptionContract, 0, len(snapshot.Snapshots))
 	for symbol, data := range snapshot.Snapshots {
-		// Parse the OCC symbol to extract strike, expiration, and type
-		// OCC format: TSLA251219C00400000
-		// This is a simplified parser - you may want to use a proper OCC parser library
 		contract := &interfaces.OptionContract{
 			Symbol:           symbol,
 			UnderlyingSymbol: underlying,
@@ -356,8 +521,13 @@ func (s *AlpacaTradingService) GetOptionsChain(ctx context.Context, underlying s
 			Theta:            data.Greeks.Theta,
 			Vega:             data.Greeks.Vega,
 			ExpirationDate:   expiration,
-			// TODO: Parse strike price and option type from OCC symbol
 		}
+		if occ, err := ParseOCCSymbol(symbol); err == nil {
+			contract.ContractType = occ.Type
+			contract.StrikePrice = occ.Strike
+			contract.ExpirationDate = occ.Expiration
+		}
+		contract.DTE = int(time.Until(contract.ExpirationDate).Hours() / 24)
 		contracts = append(contracts, contract)
 	}
 
@@ -374,6 +544,61 @@ func (s *AlpacaTradingService) GetOptionsQuote(ctx context.Context, symbol strin
 	return nil, fmt.Errorf("options quote not implemented yet")
 }
 
+// GetOptionsQuotes implements OptionsQuoteSource with Alpaca's latest
+// options quotes, 100 contracts per request
+func (s *AlpacaTradingService) GetOptionsQuotes(ctx context.Context, symbols []string) (map[string]*interfaces.OptionsQuote, error) {
+	quotes := make(map[string]*interfaces.OptionsQuote, len(symbols))
+	client := s.keys.httpClient(30 * time.Second)
+
+	for start := 0; start < len(symbols); start += optionsQuoteBatchSize {
+		batch := symbols[start:min(start+optionsQuoteBatchSize, len(symbols))]
+		url := "https://data.alpaca.markets/v1beta1/options/quotes/latest?symbols=" + strings.Join(batch, ",")
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch options quotes: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("options quotes API error (HTTP %d): %s", resp.StatusCode, string(body))
+		}
+
+		var latest struct {
+			Quotes map[string]struct {
+				Ask     float64   `json:"ap"`
+				AskSize int64     `json:"as"`
+				Bid     float64   `json:"bp"`
+				BidSize int64     `json:"bs"`
+				T       time.Time `json:"t"`
+			} `json:"quotes"`
+		}
+		if err := json.Unmarshal(body, &latest); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		for symbol, q := range latest.Quotes {
+			quotes[symbol] = &interfaces.OptionsQuote{
+				Symbol:    symbol,
+				BidPrice:  q.Bid,
+				BidSize:   q.BidSize,
+				AskPrice:  q.Ask,
+				AskSize:   q.AskSize,
+				Timestamp: q.T,
+			}
+		}
+	}
+
+	return quotes, nil
+}
+
 // GetOptionsPosition retrieves a specific options position
 func (s *AlpacaTradingService) GetOptionsPosition(ctx context.Context, symbol string) (*interfaces.OptionsPosition, error) {
 	positions, err := s.client.GetPositions()
@@ -383,17 +608,19 @@ func (s *AlpacaTradingService) GetOptionsPosition(ctx context.Context, symbol st
 
 	for _, pos := range positions {
 		if pos.Symbol == symbol && pos.AssetClass == "us_option" {
-			return &interfaces.OptionsPosition{
+			option := &interfaces.OptionsPosition{
 				Symbol:        pos.Symbol,
-				Qty:           pos.Qty.InexactFloat64(),
-				AvgEntryPrice: pos.AvgEntryPrice.InexactFloat64(),
-				MarketValue:   pos.MarketValue.InexactFloat64(),
-				CostBasis:     pos.CostBasis.InexactFloat64(),
-				UnrealizedPL:  pos.UnrealizedPL.InexactFloat64(),
-				UnrealizedPLPC: pos.UnrealizedIntradayPLPC.InexactFloat64(),
-				CurrentPrice:  pos.CurrentPrice.InexactFloat64(),
+				Qty:           pos.Qty,
+				AvgEntryPrice: pos.AvgEntryPrice,
+				MarketValue:   decimalOrZero(pos.MarketValue),
+				CostBasis:     pos.CostBasis,
+				UnrealizedPL:  decimalOrZero(pos.UnrealizedPL),
+				UnrealizedPLPC: decimalOrZero(pos.UnrealizedIntradayPLPC),
+				CurrentPrice:  decimalOrZero(pos.CurrentPrice),
 				Side:          string(pos.Side),
-			}, nil
+			}
+			fillOptionDetails(option)
+			return option, nil
 		}
 	}
 
@@ -410,17 +637,19 @@ func (s *AlpacaTradingService) ListOptionsPositions(ctx context.Context) ([]*int
 	optionsPositions := []*interfaces.OptionsPosition{}
 	for _, pos := range positions {
 		if pos.AssetClass == "us_option" {
-			optionsPositions = append(optionsPositions, &interfaces.OptionsPosition{
+			option := &interfaces.OptionsPosition{
 				Symbol:        pos.Symbol,
-				Qty:           pos.Qty.InexactFloat64(),
-				AvgEntryPrice: pos.AvgEntryPrice.InexactFloat64(),
-				MarketValue:   pos.MarketValue.InexactFloat64(),
-				CostBasis:     pos.CostBasis.InexactFloat64(),
-				UnrealizedPL:  pos.UnrealizedPL.InexactFloat64(),
-				UnrealizedPLPC: pos.UnrealizedIntradayPLPC.InexactFloat64(),
-				CurrentPrice:  pos.CurrentPrice.InexactFloat64(),
+				Qty:           pos.Qty,
+				AvgEntryPrice: pos.AvgEntryPrice,
+				MarketValue:   decimalOrZero(pos.MarketValue),
+				CostBasis:     pos.CostBasis,
+				UnrealizedPL:  decimalOrZero(pos.UnrealizedPL),
+				UnrealizedPLPC: decimalOrZero(pos.UnrealizedIntradayPLPC),
+				CurrentPrice:  decimalOrZero(pos.CurrentPrice),
 				Side:          string(pos.Side),
-			})
+			}
+			fillOptionDetails(option)
+			optionsPositions = append(optionsPositions, option)
 		}
 	}
 
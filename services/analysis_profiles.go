@@ -0,0 +1,298 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAnalysisProfileName is the profile used for symbols no other
+// profile matches. Saving a profile with this name replaces the defaults.
+const DefaultAnalysisProfileName = "default"
+
+// profileNamePattern limits profile names to short lowercase slugs
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Analysis indicators a profile can include
+const (
+	IndicatorTrend      = "trend"
+	IndicatorRSI        = "rsi"
+	IndicatorVolume     = "volume"
+	IndicatorVolatility = "volatility"
+	IndicatorLevels     = "support_resistance"
+	IndicatorPatterns   = "patterns"
+)
+
+// AllIndicators lists every analysis indicator
+var AllIndicators = []string{IndicatorTrend, IndicatorRSI, IndicatorVolume, IndicatorVolatility, IndicatorLevels, IndicatorPatterns}
+
+// analysisTimeframeWindows is the history fetched for a timeframe beyond a
+// profile's first one, about 40-60 bars each
+var analysisTimeframeWindows = map[string]time.Duration{
+	"1Min":   2 * 24 * time.Hour,
+	"5Min":   4 * 24 * time.Hour,
+	"15Min":  7 * 24 * time.Hour,
+	"30Min":  14 * 24 * time.Hour,
+	"1Hour":  21 * 24 * time.Hour,
+	"4Hour":  60 * 24 * time.Hour,
+	"1Day":   90 * 24 * time.Hour,
+	"1Week":  365 * 24 * time.Hour,
+	"1Month": 5 * 365 * 24 * time.Hour,
+}
+
+// AnalysisProfile sets how StockAnalysisService analyses the symbols it
+// matches. A symbol uses the profile listing it, else one listing its
+// sector, else one listing its asset class, else the default profile.
+type AnalysisProfile struct {
+	Name              string    `json:"name"`
+	Symbols           []string  `json:"symbols,omitempty"`
+	Sectors           []string  `json:"sectors,omitempty"`       // as reported by the sector map, e.g. "Utilities"
+	AssetClasses      []string  `json:"asset_classes,omitempty"` // us_equity, us_option or crypto
+	Timeframes        []string  `json:"timeframes"`              // the first drives the technicals, the rest are summarized
+	LookbackDays      int       `json:"lookback_days"`           // history behind the first timeframe
+	Indicators        []string  `json:"indicators"`              // used for scoring and notes
+	NewsLookbackHours int       `json:"news_lookback_hours"`
+	MaxHeadlines      int       `json:"max_headlines"`
+	NewsPrompt        string    `json:"news_prompt,omitempty"` // summarize headlines with Gemini using cleaned_news@<news_prompt>
+	MinConfidence     int       `json:"min_confidence"`        // composite scores below this are flagged, 0-10
+	StopLossPct       float64   `json:"stop_loss_pct"`
+	TakeProfitPct     float64   `json:"take_profit_pct"`
+	UpdatedAt         time.Time `json:"updated_at,omitempty"`
+}
+
+// AnalysisProfileRequest creates or replaces a profile. Omitted settings
+// take the built-in defaults.
+type AnalysisProfileRequest struct {
+	Symbols           []string `json:"symbols" binding:"omitempty,max=200,dive,symbol"`
+	Sectors           []string `json:"sectors" binding:"omitempty,max=20,dive,min=1,max=64"`
+	AssetClasses      []string `json:"asset_classes" binding:"omitempty,dive,oneof=us_equity us_option crypto"`
+	Timeframes        []string `json:"timeframes" binding:"omitempty,max=4,dive,oneof=1Min 5Min 15Min 30Min 1Hour 4Hour 1Day 1Week 1Month"`
+	LookbackDays      int      `json:"lookback_days" binding:"omitempty,min=5,max=3650"`
+	Indicators        []string `json:"indicators" binding:"omitempty,dive,oneof=trend rsi volume volatility support_resistance patterns"`
+	NewsLookbackHours int      `json:"news_lookback_hours" binding:"omitempty,min=1,max=720"`
+	MaxHeadlines      int      `json:"max_headlines" binding:"omitempty,min=1,max=20"`
+	NewsPrompt        string   `json:"news_prompt" binding:"omitempty,max=32"`
+	MinConfidence     int      `json:"min_confidence" binding:"omitempty,min=0,max=10"`
+	StopLossPct       float64  `json:"stop_loss_pct" binding:"omitempty,gt=0,lt=100"`
+	TakeProfitPct     float64  `json:"take_profit_pct" binding:"omitempty,gt=0"`
+}
+
+// DefaultAnalysisProfile returns the built-in default profile
+func DefaultAnalysisProfile() *AnalysisProfile {
+	return &AnalysisProfile{
+		Name:              DefaultAnalysisProfileName,
+		Timeframes:        []string{"1Day"},
+		LookbackDays:      30,
+		Indicators:        AllIndicators,
+		NewsLookbackHours: 48,
+		MaxHeadlines:      3,
+		StopLossPct:       15,
+		TakeProfitPct:     30,
+	}
+}
+
+// uses reports whether the profile includes an indicator
+func (p *AnalysisProfile) uses(indicator string) bool {
+	for _, i := range p.Indicators {
+		if i == indicator {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRank is 3 when the profile lists symbol, 2 for its sector, 1 for its
+// asset class and 0 otherwise
+func (p *AnalysisProfile) matchRank(symbol string) int {
+	for _, s := range p.Symbols {
+		if strings.EqualFold(s, symbol) {
+			return 3
+		}
+	}
+	sector := SectorFor(symbol)
+	for _, s := range p.Sectors {
+		if strings.EqualFold(s, sector) {
+			return 2
+		}
+	}
+	class := marketAssetClass(symbol)
+	for _, c := range p.AssetClasses {
+		if c == class {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AnalysisProfileService stores analysis profiles and picks the one for a
+// symbol
+type AnalysisProfileService struct {
+	storageService *database.LocalStorage
+	profiles       map[string]*AnalysisProfile
+	mu             sync.RWMutex
+	logger         *logrus.Logger
+}
+
+// NewAnalysisProfileService creates a profile service and loads persisted
+// profiles
+func NewAnalysisProfileService(storageService *database.LocalStorage) *AnalysisProfileService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	ps := &AnalysisProfileService{
+		storageService: storageService,
+		profiles:       make(map[string]*AnalysisProfile),
+		logger:         logger,
+	}
+
+	if err := ps.loadFromDB(); err != nil {
+		logger.WithError(err).Error("Failed to load analysis profiles from database")
+	}
+
+	return ps
+}
+
+// ProfileFor returns the profile that applies to symbol
+func (ps *AnalysisProfileService) ProfileFor(symbol string) *AnalysisProfile {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	best, bestRank := ps.profiles[DefaultAnalysisProfileName], 0
+	for _, profile := range ps.profiles {
+		rank := profile.matchRank(symbol)
+		// Ties go to the alphabetically first profile so the choice is stable
+		if rank > bestRank || rank == bestRank && rank > 0 && profile.Name < best.Name {
+			best, bestRank = profile, rank
+		}
+	}
+	if best == nil {
+		return DefaultAnalysisProfile()
+	}
+	copied := *best
+	return &copied
+}
+
+// ListProfiles returns every profile by name, including the default
+func (ps *AnalysisProfileService) ListProfiles() []*AnalysisProfile {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	profiles := make([]*AnalysisProfile, 0, len(ps.profiles)+1)
+	if _, ok := ps.profiles[DefaultAnalysisProfileName]; !ok {
+		profiles = append(profiles, DefaultAnalysisProfile())
+	}
+	for _, profile := range ps.profiles {
+		copied := *profile
+		profiles = append(profiles, &copied)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// SaveProfile creates or replaces the profile name
+func (ps *AnalysisProfileService) SaveProfile(name string, req *AnalysisProfileRequest) (*AnalysisProfile, error) {
+	if !profileNamePattern.MatchString(name) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("invalid profile name %q: use up to 32 lowercase letters, digits, '-' or '_'", name))
+	}
+	if name != DefaultAnalysisProfileName && len(req.Symbols)+len(req.Sectors)+len(req.AssetClasses) == 0 {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("a profile needs symbols, sectors or asset_classes to match"))
+	}
+
+	profile := DefaultAnalysisProfile()
+	profile.Name = name
+	profile.Sectors = req.Sectors
+	profile.AssetClasses = req.AssetClasses
+	profile.NewsPrompt = req.NewsPrompt
+	profile.MinConfidence = req.MinConfidence
+	profile.UpdatedAt = time.Now()
+	for _, symbol := range req.Symbols {
+		profile.Symbols = append(profile.Symbols, strings.ToUpper(symbol))
+	}
+	if len(req.Timeframes) > 0 {
+		profile.Timeframes = req.Timeframes
+	}
+	if req.LookbackDays > 0 {
+		profile.LookbackDays = req.LookbackDays
+	}
+	if len(req.Indicators) > 0 {
+		profile.Indicators = req.Indicators
+	}
+	if req.NewsLookbackHours > 0 {
+		profile.NewsLookbackHours = req.NewsLookbackHours
+	}
+	if req.MaxHeadlines > 0 {
+		profile.MaxHeadlines = req.MaxHeadlines
+	}
+	if req.StopLossPct > 0 {
+		profile.StopLossPct = req.StopLossPct
+	}
+	if req.TakeProfitPct > 0 {
+		profile.TakeProfitPct = req.TakeProfitPct
+	}
+	if profile.NewsPrompt != "" && !promptEndpointPattern.MatchString(profile.NewsPrompt) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("invalid news_prompt %q: use up to 32 lowercase letters, digits or '-'", profile.NewsPrompt))
+	}
+
+	definition, err := json.Marshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode analysis profile: %w", err)
+	}
+	if err := ps.storageService.SaveAnalysisProfile(&models.DBAnalysisProfile{Name: name, Definition: string(definition)}); err != nil {
+		return nil, err
+	}
+
+	ps.mu.Lock()
+	ps.profiles[name] = profile
+	ps.mu.Unlock()
+
+	ps.logger.WithField("profile", name).Info("Analysis profile saved")
+
+	copied := *profile
+	return &copied, nil
+}
+
+// DeleteProfile removes a profile. Deleting "default" restores the built-in
+// defaults.
+func (ps *AnalysisProfileService) DeleteProfile(name string) error {
+	ps.mu.Lock()
+	_, ok := ps.profiles[name]
+	delete(ps.profiles, name)
+	ps.mu.Unlock()
+
+	if !ok {
+		return WithErrorCode(ErrCodeNotFound, fmt.Errorf("analysis profile %s not found", name))
+	}
+	return ps.storageService.DeleteAnalysisProfile(name)
+}
+
+// loadFromDB restores profiles on startup
+func (ps *AnalysisProfileService) loadFromDB() error {
+	saved, err := ps.storageService.GetAnalysisProfiles()
+	if err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, dbProfile := range saved {
+		var profile AnalysisProfile
+		if err := json.Unmarshal([]byte(dbProfile.Definition), &profile); err != nil {
+			ps.logger.WithError(err).WithField("profile", dbProfile.Name).Warn("Skipping unreadable analysis profile")
+			continue
+		}
+		ps.profiles[dbProfile.Name] = &profile
+	}
+
+	ps.logger.WithField("count", len(ps.profiles)).Info("Loaded analysis profiles from database")
+	return nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"prophet-trader/database"
+	"prophet-trader/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// APIAuditLog keeps the append-only record of every state-changing API
+// call: who made it, which endpoint, a hash of the payload and the result.
+// It is separate from the activity log, which describes trading decisions
+// rather than who asked for them.
+type APIAuditLog struct {
+	storage *database.LocalStorage
+	logger  *logrus.Logger
+}
+
+// NewAPIAuditLog creates an API audit log stored in storage
+func NewAPIAuditLog(storage *database.LocalStorage) *APIAuditLog {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &APIAuditLog{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// Record appends an API call. Failures are logged, never returned, so
+// auditing cannot block trading.
+func (l *APIAuditLog) Record(audit *models.DBAPIAudit) {
+	if err := l.storage.SaveAPIAudit(audit); err != nil {
+		l.logger.WithError(err).WithFields(logrus.Fields{
+			"method": audit.Method,
+			"route":  audit.Route,
+		}).Error("Failed to save API audit")
+	}
+}
+
+// List returns the audit records matching q, newest first
+func (l *APIAuditLog) List(q database.APIAuditQuery) ([]*models.DBAPIAudit, error) {
+	return l.storage.GetAPIAudits(q)
+}
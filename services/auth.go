@@ -0,0 +1,223 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// API roles, in increasing order of privilege
+const (
+	RoleViewer = "viewer" // read-only (GET) access
+	RoleTrader = "trader" // may place and cancel orders
+	RoleAdmin  = "admin"  // may manage tokens, risk and reconciliation
+)
+
+// tokenTouchInterval limits how often a token's last-used time is written
+const tokenTouchInterval = time.Minute
+
+var (
+	// ErrInvalidToken is returned for unknown or revoked tokens
+	ErrInvalidToken = errors.New("invalid or revoked API token")
+	// ErrTokenNotFound is returned when revoking a token that does not exist
+	ErrTokenNotFound = errors.New("API token not found")
+)
+
+func roleRank(role string) int {
+	switch role {
+	case RoleViewer:
+		return 1
+	case RoleTrader:
+		return 2
+	case RoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// RoleAllows reports whether role has at least the privileges of required
+func RoleAllows(role, required string) bool {
+	return roleRank(role) > 0 && roleRank(role) >= roleRank(required)
+}
+
+// Principal is the caller identified by an API token
+type Principal struct {
+	TokenID string `json:"token_id"`
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+}
+
+// APIToken describes an issued token. The secret is only set in the response
+// to its creation.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	Prefix     string     `json:"prefix"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// AuthService issues and verifies per-user API tokens. Authentication is
+// enabled by configuring an admin token, which is always accepted with the
+// admin role and is used to create the first user tokens.
+type AuthService struct {
+	storage    *database.LocalStorage
+	adminToken string
+	lastTouch  map[string]time.Time
+	mu         sync.Mutex
+	logger     *logrus.Logger
+}
+
+// NewAuthService creates an auth service. An empty adminToken disables
+// authentication.
+func NewAuthService(storage *database.LocalStorage, adminToken string) *AuthService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &AuthService{
+		storage:    storage,
+		adminToken: adminToken,
+		lastTouch:  make(map[string]time.Time),
+		logger:     logger,
+	}
+}
+
+// Enabled reports whether requests must carry a token
+func (as *AuthService) Enabled() bool {
+	return as.adminToken != ""
+}
+
+// Authenticate resolves a bearer token to its principal
+func (as *AuthService) Authenticate(token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(as.adminToken)) == 1 {
+		return &Principal{TokenID: "admin", Name: "admin", Role: RoleAdmin}, nil
+	}
+
+	dbToken, err := as.storage.GetAPITokenByHash(hashToken(token))
+	if err != nil || dbToken.RevokedAt != nil {
+		return nil, ErrInvalidToken
+	}
+
+	as.touch(dbToken.TokenID)
+	return &Principal{TokenID: dbToken.TokenID, Name: dbToken.Name, Role: dbToken.Role}, nil
+}
+
+// CreateToken issues a new token for name with the given role. The returned
+// APIToken is the only place the secret is ever available.
+func (as *AuthService) CreateToken(name, role string) (*APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if roleRank(role) == 0 {
+		return nil, fmt.Errorf("invalid role: %s (must be %s, %s or %s)", role, RoleViewer, RoleTrader, RoleAdmin)
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := "pt_" + hex.EncodeToString(secret)
+
+	dbToken := &models.DBAPIToken{
+		TokenID:   fmt.Sprintf("tok_%d", time.Now().UnixNano()),
+		Name:      name,
+		Role:      role,
+		TokenHash: hashToken(token),
+		Prefix:    token[:10],
+	}
+	if err := as.storage.SaveAPIToken(dbToken); err != nil {
+		return nil, err
+	}
+
+	as.logger.WithFields(logrus.Fields{
+		"token_id": dbToken.TokenID,
+		"name":     name,
+		"role":     role,
+	}).Info("API token created")
+
+	created := toAPIToken(dbToken)
+	created.Token = token
+	return created, nil
+}
+
+// ListTokens returns every issued token, including revoked ones
+func (as *AuthService) ListTokens() ([]*APIToken, error) {
+	dbTokens, err := as.storage.GetAPITokens()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]*APIToken, len(dbTokens))
+	for i, t := range dbTokens {
+		tokens[i] = toAPIToken(t)
+	}
+	return tokens, nil
+}
+
+// RevokeToken disables a token immediately
+func (as *AuthService) RevokeToken(id string) error {
+	revoked, err := as.storage.RevokeAPIToken(id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrTokenNotFound
+	}
+
+	as.logger.WithField("token_id", id).Info("API token revoked")
+	return nil
+}
+
+// touch records token use, writing at most once per interval per token
+func (as *AuthService) touch(tokenID string) {
+	now := time.Now()
+
+	as.mu.Lock()
+	if now.Sub(as.lastTouch[tokenID]) < tokenTouchInterval {
+		as.mu.Unlock()
+		return
+	}
+	as.lastTouch[tokenID] = now
+	as.mu.Unlock()
+
+	if err := as.storage.TouchAPIToken(tokenID, now); err != nil {
+		as.logger.WithError(err).Warn("Failed to record API token use")
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func toAPIToken(t *models.DBAPIToken) *APIToken {
+	return &APIToken{
+		ID:         t.TokenID,
+		Name:       t.Name,
+		Role:       t.Role,
+		Prefix:     t.Prefix,
+		CreatedAt:  t.CreatedAt,
+		LastUsedAt: t.LastUsedAt,
+		RevokedAt:  t.RevokedAt,
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// BacktestConfig describes a single-symbol backtest run
+type BacktestConfig struct {
+	Symbol         string    `json:"symbol"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	Timeframe      string    `json:"timeframe"`
+	InitialCapital float64   `json:"initial_capital"`
+	Lookback       int       `json:"lookback"`         // bars of history passed to the strategy
+	Warmup         int       `json:"warmup,omitempty"` // leading bars shown to the strategy but not traded
+	Fills          FillModel `json:"fills"`
+}
+
+// BacktestTrade is a completed round trip in a backtest
+type BacktestTrade struct {
+	EntryTime  time.Time `json:"entry_time"`
+	ExitTime   time.Time `json:"exit_time"`
+	EntryPrice float64   `json:"entry_price"`
+	ExitPrice  float64   `json:"exit_price"`
+	Qty        float64   `json:"qty"`
+	Fees       float64   `json:"fees"`
+	PnL        float64   `json:"pnl"` // after fees
+	PnLPercent float64   `json:"pnl_percent"`
+}
+
+// EquityPoint is one sample of the simulated equity curve
+type EquityPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Equity    float64   `json:"equity"`
+}
+
+// BacktestResult summarizes a backtest run
+type BacktestResult struct {
+	Strategy       string          `json:"strategy"`
+	Config         BacktestConfig  `json:"config"`
+	BarsProcessed  int             `json:"bars_processed"`
+	FinalEquity    float64         `json:"final_equity"`
+	TotalReturnPct float64         `json:"total_return_percent"`
+	MaxDrawdownPct float64         `json:"max_drawdown_percent"`
+	WinRate        float64         `json:"win_rate"`
+	TotalFees      float64         `json:"total_fees"`
+	Trades         []BacktestTrade `json:"trades"`
+	EquityCurve    []EquityPoint   `json:"equity_curve"`
+}
+
+// BacktestEngine replays historical bars through a strategy with a simple
+// long-only simulated account that fills market orders at the bar close,
+// adjusted by the configured fill model
+type BacktestEngine struct {
+	dataService interfaces.DataService
+	logger      *logrus.Logger
+}
+
+// NewBacktestEngine creates a new backtest engine
+func NewBacktestEngine(dataService interfaces.DataService) *BacktestEngine {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &BacktestEngine{
+		dataService: dataService,
+		logger:      logger,
+	}
+}
+
+// Run fetches bars for the configured window and runs the strategy over them
+func (be *BacktestEngine) Run(ctx context.Context, strategy interfaces.StrategyExecutor, cfg BacktestConfig) (*BacktestResult, error) {
+	if cfg.Timeframe == "" {
+		cfg.Timeframe = "1Day"
+	}
+	timeframe, err := NormalizeBarSpec(cfg.Timeframe)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timeframe = timeframe
+	if cfg.InitialCapital <= 0 {
+		cfg.InitialCapital = 100000
+	}
+	if cfg.Lookback <= 0 {
+		cfg.Lookback = 50
+	}
+	if err := cfg.Fills.Validate(); err != nil {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, err)
+	}
+
+	bars, err := be.dataService.GetHistoricalBars(ctx, cfg.Symbol, cfg.Start, cfg.End, cfg.Timeframe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bars: %w", err)
+	}
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no bars available for %s", cfg.Symbol)
+	}
+
+	return be.RunBars(ctx, strategy, cfg, bars), nil
+}
+
+// RunBars runs the strategy over an already loaded bar series
+func (be *BacktestEngine) RunBars(ctx context.Context, strategy interfaces.StrategyExecutor, cfg BacktestConfig, bars []*interfaces.Bar) *BacktestResult {
+	result := &BacktestResult{
+		Strategy:    strategy.GetName(),
+		Config:      cfg,
+		Trades:      make([]BacktestTrade, 0),
+		EquityCurve: make([]EquityPoint, 0, len(bars)),
+	}
+
+	cash := cfg.InitialCapital
+	qty := 0.0
+	entryPrice := 0.0
+	entryFees := 0.0 // entry commission not yet charged to a closed trade
+	var entryTime time.Time
+	exiting := 0.0 // shares of a volume-capped exit still to sell
+	exitFilled := 0.0
+	peak := cfg.InitialCapital
+	wins := 0
+	multiplier := contractMultiplier(cfg.Symbol)
+
+	// sell exits sellQty at this bar, spreading the entry commission over
+	// the shares sold
+	sell := func(bar *interfaces.Bar, sellQty float64) {
+		price := cfg.Fills.FillPrice("sell", bar.Close, nil)
+		fees := cfg.Fills.Fees(cfg.Symbol, exitFilled+sellQty) - cfg.Fills.Fees(cfg.Symbol, exitFilled)
+		exitFilled += sellQty
+		entryShare := entryFees * sellQty / qty
+		entryFees -= entryShare
+
+		pnl := (price-entryPrice)*sellQty*multiplier - entryShare - fees
+		result.Trades = append(result.Trades, BacktestTrade{
+			EntryTime:  entryTime,
+			ExitTime:   bar.Timestamp,
+			EntryPrice: entryPrice,
+			ExitPrice:  price,
+			Qty:        sellQty,
+			Fees:       entryShare + fees,
+			PnL:        pnl,
+			PnLPercent: pnl / (entryPrice * sellQty * multiplier) * 100,
+		})
+		if pnl > 0 {
+			wins++
+		}
+
+		cash += sellQty*price*multiplier - fees
+		result.TotalFees += fees
+		qty -= sellQty
+		exiting -= sellQty
+		strategy.OnOrderFilled(be.simulatedFill(cfg.Symbol, "sell", sellQty, price, bar))
+	}
+
+	for i, bar := range bars {
+		if ctx.Err() != nil {
+			break
+		}
+
+		start := i + 1 - cfg.Lookback
+		if start < 0 {
+			start = 0
+		}
+		data := &interfaces.MarketData{
+			Symbol:     cfg.Symbol,
+			CurrentBar: bar,
+			RecentBars: bars[start : i+1],
+			Indicators: PatternIndicators(bars[start : i+1]),
+		}
+		strategy.OnMarketData(data)
+		if i < cfg.Warmup {
+			continue
+		}
+
+		if exiting > 0 {
+			// Keep working an exit the volume cap cut short
+			if sellQty := be.volumeCapped(cfg, bar, exiting); sellQty > 0 {
+				sell(bar, sellQty)
+			}
+		} else if qty == 0 {
+			if ok, req := strategy.ShouldBuy(ctx, cfg.Symbol, data); ok {
+				price := cfg.Fills.FillPrice("buy", bar.Close, nil)
+				buyQty := math.Floor(cash / (price * multiplier))
+				if req != nil && req.Qty.IsPositive() {
+					buyQty = math.Min(buyQty, req.Qty.InexactFloat64())
+				}
+				buyQty = be.volumeCapped(cfg, bar, buyQty)
+				for buyQty > 0 && buyQty*price*multiplier+cfg.Fills.Fees(cfg.Symbol, buyQty) > cash {
+					buyQty--
+				}
+				if buyQty > 0 {
+					fees := cfg.Fills.Fees(cfg.Symbol, buyQty)
+					qty = buyQty
+					entryPrice = price
+					entryFees = fees
+					entryTime = bar.Timestamp
+					cash -= qty*price*multiplier + fees
+					result.TotalFees += fees
+					strategy.OnOrderFilled(be.simulatedFill(cfg.Symbol, "buy", qty, price, bar))
+				}
+			}
+		} else if ok, req := strategy.ShouldSell(ctx, cfg.Symbol, data); ok {
+			exiting = qty
+			if req != nil && req.Qty.IsPositive() {
+				exiting = math.Min(exiting, req.Qty.InexactFloat64())
+			}
+			exitFilled = 0
+			if sellQty := be.volumeCapped(cfg, bar, exiting); sellQty > 0 {
+				sell(bar, sellQty)
+			}
+		}
+
+		equity := cash + qty*bar.Close*multiplier
+		result.EquityCurve = append(result.EquityCurve, EquityPoint{Timestamp: bar.Timestamp, Equity: equity})
+		if equity > peak {
+			peak = equity
+		}
+		if drawdown := (peak - equity) / peak * 100; drawdown > result.MaxDrawdownPct {
+			result.MaxDrawdownPct = drawdown
+		}
+		result.BarsProcessed++
+	}
+
+	if result.BarsProcessed > 0 {
+		result.FinalEquity = result.EquityCurve[len(result.EquityCurve)-1].Equity
+	} else {
+		result.FinalEquity = cfg.InitialCapital
+	}
+	result.TotalReturnPct = (result.FinalEquity - cfg.InitialCapital) / cfg.InitialCapital * 100
+	if len(result.Trades) > 0 {
+		result.WinRate = float64(wins) / float64(len(result.Trades)) * 100
+	}
+
+	be.logger.WithFields(logrus.Fields{
+		"strategy":     result.Strategy,
+		"symbol":       cfg.Symbol,
+		"bars":         result.BarsProcessed,
+		"trades":       len(result.Trades),
+		"return_pct":   result.TotalReturnPct,
+		"fees":         result.TotalFees,
+		"max_drawdown": result.MaxDrawdownPct,
+	}).Info("Backtest complete")
+
+	return result
+}
+
+// volumeCapped limits qty to the share of the bar's volume the fill model
+// allows
+func (be *BacktestEngine) volumeCapped(cfg BacktestConfig, bar *interfaces.Bar, qty float64) float64 {
+	if limit, ok := cfg.Fills.VolumeCap(bar.Volume); ok {
+		return math.Min(qty, limit)
+	}
+	return qty
+}
+
+// simulatedFill builds the filled order handed back to the strategy
+func (be *BacktestEngine) simulatedFill(symbol, side string, qty, fillPrice float64, bar *interfaces.Bar) *interfaces.Order {
+	price := decimal.NewFromFloat(fillPrice)
+	filledAt := bar.Timestamp
+
+	return &interfaces.Order{
+		ID:             fmt.Sprintf("bt_%s_%d", side, bar.Timestamp.UnixNano()),
+		Symbol:         symbol,
+		Qty:            decimal.NewFromFloat(qty),
+		Side:           side,
+		Type:           "market",
+		TimeInForce:    "day",
+		Status:         "filled",
+		FilledQty:      decimal.NewFromFloat(qty),
+		FilledAvgPrice: &price,
+		SubmittedAt:    bar.Timestamp,
+		FilledAt:       &filledAt,
+	}
+}
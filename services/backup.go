@@ -0,0 +1,564 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"prophet-trader/database"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Backup archives are gzipped tarballs holding a consistent copy of the
+// database, the activity log files and a manifest of SHA-256 checksums
+const (
+	backupManifestVersion = 1
+	backupManifestEntry   = "manifest.json"
+	backupDatabaseEntry   = "database/prophet_trader.db"
+	backupActivityDir     = "activity_logs"
+)
+
+var backupNamePattern = regexp.MustCompile(`^prophet-backup-\d{8}T\d{6}Z\.tar\.gz$`)
+
+func isBackupName(name string) bool {
+	return backupNamePattern.MatchString(name)
+}
+
+// BackupFile is one file recorded in a backup manifest
+type BackupFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupManifest lists the contents of a backup archive
+type BackupManifest struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Files     []BackupFile `json:"files"`
+}
+
+// BackupResult describes a completed backup
+type BackupResult struct {
+	Name     string          `json:"name"`
+	Location string          `json:"location"`
+	Size     int64           `json:"size"`
+	Manifest *BackupManifest `json:"manifest"`
+	Pruned   []string        `json:"pruned,omitempty"`
+}
+
+// RestoreResult describes a staged restore
+type RestoreResult struct {
+	Name            string          `json:"name"`
+	Manifest        *BackupManifest `json:"manifest"`
+	RestartRequired bool            `json:"restart_required"`
+}
+
+// BackupService backs up the database and activity logs to a BackupStore
+// and stages restores from it. A restore replaces the live files on the next
+// start, because several services keep database state in memory.
+type BackupService struct {
+	storage     *database.LocalStorage
+	activityDir string
+	store       BackupStore
+	keep        int // newest backups kept, 0 keeps all
+	notifier    *Notifier
+	mu          sync.Mutex
+	logger      *logrus.Logger
+}
+
+// NewBackupService creates a new backup service
+func NewBackupService(storage *database.LocalStorage, activityDir string, store BackupStore, keep int, notifier *Notifier) *BackupService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &BackupService{
+		storage:     storage,
+		activityDir: activityDir,
+		store:       store,
+		keep:        keep,
+		notifier:    notifier,
+		logger:      logger,
+	}
+}
+
+// Location describes where backups are stored
+func (bs *BackupService) Location() string {
+	return bs.store.Location()
+}
+
+// ScheduledBackup takes a backup and notifies when it fails
+func (bs *BackupService) ScheduledBackup(ctx context.Context) error {
+	if _, err := bs.Backup(ctx); err != nil {
+		bs.logger.WithError(err).Error("Scheduled backup failed")
+		if bs.notifier != nil {
+			bs.notifier.Notify(ctx, NotifyWarning, "Backup failed", err.Error(), map[string]interface{}{
+				"location": bs.store.Location(),
+			})
+		}
+		return err
+	}
+	return nil
+}
+
+// Backup archives the database and activity logs, verifies the archive and
+// uploads it to the store, then prunes old backups
+func (bs *BackupService) Backup(ctx context.Context) (*BackupResult, error) {
+	if err := bs.requireSQLite(); err != nil {
+		return nil, err
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	createdAt := time.Now().UTC()
+	name := "prophet-backup-" + createdAt.Format("20060102T150405Z") + ".tar.gz"
+
+	workDir, err := os.MkdirTemp("", "prophet-backup-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	dbCopy := filepath.Join(workDir, "prophet_trader.db")
+	if err := bs.storage.BackupTo(dbCopy); err != nil {
+		return nil, err
+	}
+	if err := database.VerifyDatabase(dbCopy); err != nil {
+		return nil, WithErrorCode(ErrCodeIntegrityFailed, err)
+	}
+
+	archive, err := os.Create(filepath.Join(workDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup archive: %w", err)
+	}
+	defer archive.Close()
+
+	manifest, err := writeBackupArchive(archive, dbCopy, bs.activityDir, createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the archive back before shipping it so a bad write is caught now
+	// rather than on the day it is needed
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind backup archive: %w", err)
+	}
+	if _, err := readBackupArchive(archive, filepath.Join(workDir, "verify")); err != nil {
+		return nil, err
+	}
+
+	if err := bs.store.Put(ctx, name, archive); err != nil {
+		return nil, err
+	}
+	info, err := archive.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat backup archive: %w", err)
+	}
+
+	result := &BackupResult{
+		Name:     name,
+		Location: bs.store.Location(),
+		Size:     info.Size(),
+		Manifest: manifest,
+		Pruned:   bs.prune(ctx),
+	}
+
+	bs.logger.WithFields(logrus.Fields{
+		"name":     name,
+		"location": result.Location,
+		"size":     result.Size,
+		"files":    len(manifest.Files),
+	}).Info("Backup complete")
+
+	return result, nil
+}
+
+// List returns the stored backups, newest first
+func (bs *BackupService) List(ctx context.Context) ([]BackupObject, error) {
+	objects, err := bs.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Names embed the UTC timestamp, so they sort chronologically
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name > objects[j].Name })
+	return objects, nil
+}
+
+// Verify downloads a backup and checks every file against the manifest and
+// the database with SQLite's integrity check
+func (bs *BackupService) Verify(ctx context.Context, name string) (*BackupManifest, error) {
+	if !isBackupName(name) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("invalid backup name %q", name))
+	}
+
+	workDir, err := os.MkdirTemp("", "prophet-verify-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verify work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	return bs.fetch(ctx, name, workDir)
+}
+
+// Restore verifies a backup and stages it next to the database. The staged
+// files replace the live ones when ApplyPendingRestore runs at the next start.
+func (bs *BackupService) Restore(ctx context.Context, name string) (*RestoreResult, error) {
+	if !isBackupName(name) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("invalid backup name %q", name))
+	}
+	if err := bs.requireSQLite(); err != nil {
+		return nil, err
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	// Extract beside the database so staging can be renamed into place
+	stage := pendingRestoreDir(bs.storage.Path())
+	tmp := stage + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return nil, fmt.Errorf("failed to clear restore staging: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	manifest, err := bs.fetch(ctx, name, tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.RemoveAll(stage); err != nil {
+		return nil, fmt.Errorf("failed to clear restore staging: %w", err)
+	}
+	if err := os.Rename(tmp, stage); err != nil {
+		return nil, fmt.Errorf("failed to stage restore: %w", err)
+	}
+
+	bs.logger.WithFields(logrus.Fields{
+		"name":       name,
+		"created_at": manifest.CreatedAt,
+	}).Warn("Restore staged; it will be applied on the next restart")
+
+	return &RestoreResult{Name: name, Manifest: manifest, RestartRequired: true}, nil
+}
+
+// requireSQLite rejects backup and restore for PostgreSQL, which is backed
+// up with its own tooling
+func (bs *BackupService) requireSQLite() error {
+	if dialect := bs.storage.Dialect(); dialect != "sqlite" {
+		return WithErrorCode(ErrCodeNotSupported, fmt.Errorf("backups are only supported for SQLite; use pg_dump or provider snapshots for %s", dialect))
+	}
+	return nil
+}
+
+// fetch downloads a backup and extracts it into dir, verifying it on the way
+func (bs *BackupService) fetch(ctx context.Context, name, dir string) (*BackupManifest, error) {
+	body, err := bs.store.Open(ctx, name)
+	if errors.Is(err, errBackupNotFound) {
+		return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("backup %s not found", name))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return readBackupArchive(body, dir)
+}
+
+// prune deletes all but the newest keep backups
+func (bs *BackupService) prune(ctx context.Context) []string {
+	if bs.keep <= 0 {
+		return nil
+	}
+
+	objects, err := bs.List(ctx)
+	if err != nil {
+		bs.logger.WithError(err).Warn("Failed to list backups for pruning")
+		return nil
+	}
+
+	var pruned []string
+	for i := bs.keep; i < len(objects); i++ {
+		if err := bs.store.Delete(ctx, objects[i].Name); err != nil {
+			bs.logger.WithError(err).WithField("name", objects[i].Name).Warn("Failed to prune backup")
+			continue
+		}
+		pruned = append(pruned, objects[i].Name)
+	}
+	return pruned
+}
+
+// writeBackupArchive writes the database copy, the activity logs and the
+// manifest to w as a gzipped tarball
+func writeBackupArchive(w io.Writer, dbPath, activityDir string, createdAt time.Time) (*BackupManifest, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest := &BackupManifest{Version: backupManifestVersion, CreatedAt: createdAt}
+
+	addFile := func(entry, source string) error {
+		f, err := os.Open(source)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", source, err)
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", source, err)
+		}
+		header := &tar.Header{Name: entry, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write backup archive: %w", err)
+		}
+
+		h := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(tw, h), f); err != nil {
+			return fmt.Errorf("failed to write backup archive: %w", err)
+		}
+		manifest.Files = append(manifest.Files, BackupFile{Path: entry, Size: info.Size(), SHA256: hex.EncodeToString(h.Sum(nil))})
+		return nil
+	}
+
+	if err := addFile(backupDatabaseEntry, dbPath); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(activityDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read activity logs: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if err := addFile(path.Join(backupActivityDir, entry.Name()), filepath.Join(activityDir, entry.Name())); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode backup manifest: %w", err)
+	}
+	header := &tar.Header{Name: backupManifestEntry, Mode: 0644, Size: int64(len(data)), ModTime: createdAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, fmt.Errorf("failed to write backup archive: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write backup archive: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write backup archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write backup archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// readBackupArchive extracts an archive into dir and verifies it: every
+// entry must be listed in the manifest with a matching size and checksum,
+// nothing may be missing, and the database must pass an integrity check
+func readBackupArchive(r io.Reader, dir string) (*BackupManifest, error) {
+	corrupt := func(format string, args ...interface{}) error {
+		return WithErrorCode(ErrCodeIntegrityFailed, fmt.Errorf("backup verification failed: "+format, args...))
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, corrupt("%v", err)
+	}
+	defer gz.Close()
+
+	actual := make(map[string]BackupFile)
+	var manifest *BackupManifest
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, corrupt("%v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, corrupt("unexpected entry %s", header.Name)
+		}
+
+		if header.Name == backupManifestEntry {
+			manifest = &BackupManifest{}
+			if err := json.NewDecoder(io.LimitReader(tr, 10<<20)).Decode(manifest); err != nil {
+				return nil, corrupt("invalid manifest: %v", err)
+			}
+			continue
+		}
+		if !isBackupEntry(header.Name) {
+			return nil, corrupt("unexpected entry %s", header.Name)
+		}
+		if _, seen := actual[header.Name]; seen {
+			return nil, corrupt("duplicate entry %s", header.Name)
+		}
+
+		file, err := extractBackupEntry(tr, filepath.Join(dir, filepath.FromSlash(header.Name)))
+		if err != nil {
+			return nil, err
+		}
+		file.Path = header.Name
+		actual[header.Name] = file
+	}
+
+	if manifest == nil {
+		return nil, corrupt("manifest is missing")
+	}
+	if manifest.Version != backupManifestVersion {
+		return nil, corrupt("unsupported manifest version %d", manifest.Version)
+	}
+	if len(manifest.Files) != len(actual) {
+		return nil, corrupt("manifest lists %d files, archive has %d", len(manifest.Files), len(actual))
+	}
+	for _, expected := range manifest.Files {
+		got, ok := actual[expected.Path]
+		if !ok {
+			return nil, corrupt("%s is missing", expected.Path)
+		}
+		if got.Size != expected.Size || got.SHA256 != expected.SHA256 {
+			return nil, corrupt("%s does not match its checksum", expected.Path)
+		}
+	}
+	if _, ok := actual[backupDatabaseEntry]; !ok {
+		return nil, corrupt("database is missing")
+	}
+
+	if err := database.VerifyDatabase(filepath.Join(dir, filepath.FromSlash(backupDatabaseEntry))); err != nil {
+		return nil, WithErrorCode(ErrCodeIntegrityFailed, err)
+	}
+
+	if err := writeManifestFile(filepath.Join(dir, backupManifestEntry), manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// isBackupEntry accepts the database and files directly under activity_logs,
+// which also rules out path traversal
+func isBackupEntry(name string) bool {
+	if name == backupDatabaseEntry {
+		return true
+	}
+	dir, file := path.Split(name)
+	return dir == backupActivityDir+"/" && file != "" && file != "." && file != ".." && !strings.Contains(file, "\\")
+}
+
+func extractBackupEntry(r io.Reader, dest string) (BackupFile, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return BackupFile{}, fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return BackupFile{}, fmt.Errorf("failed to extract backup: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return BackupFile{}, WithErrorCode(ErrCodeIntegrityFailed, fmt.Errorf("backup verification failed: %w", err))
+	}
+	if err := f.Sync(); err != nil {
+		return BackupFile{}, fmt.Errorf("failed to extract backup: %w", err)
+	}
+	return BackupFile{Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+func writeManifestFile(dest string, manifest *BackupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup manifest: %w", err)
+	}
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	return nil
+}
+
+// pendingRestoreDir is where a verified restore waits for the next start
+func pendingRestoreDir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), ".restore")
+}
+
+// ApplyPendingRestore swaps a staged restore into place. It must run before
+// the database is opened. The replaced database and activity logs are kept
+// with a .pre-restore suffix. Returns nil when nothing was staged.
+func ApplyPendingRestore(dbPath, activityDir string) (*BackupManifest, error) {
+	stage := pendingRestoreDir(dbPath)
+	data, err := os.ReadFile(filepath.Join(stage, backupManifestEntry))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read staged restore: %w", err)
+	}
+	manifest := &BackupManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to read staged restore: %w", err)
+	}
+
+	// SQLite keeps uncheckpointed writes beside the main file; move them
+	// with it so the kept copy stays usable
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(dbPath+suffix, dbPath+".pre-restore"+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to move current database aside: %w", err)
+		}
+	}
+	if err := os.Rename(filepath.Join(stage, filepath.FromSlash(backupDatabaseEntry)), dbPath); err != nil {
+		return nil, fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	// Activity logs are copied rather than renamed since they may live on a
+	// different filesystem from the database
+	previous := strings.TrimSuffix(activityDir, string(filepath.Separator)) + ".pre-restore"
+	if err := os.RemoveAll(previous); err != nil {
+		return nil, fmt.Errorf("failed to remove old activity log copy: %w", err)
+	}
+	if err := os.Rename(activityDir, previous); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to move current activity logs aside: %w", err)
+	}
+	if err := os.MkdirAll(activityDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create activity log directory: %w", err)
+	}
+	staged, err := os.ReadDir(filepath.Join(stage, backupActivityDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read staged activity logs: %w", err)
+	}
+	for _, entry := range staged {
+		data, err := os.ReadFile(filepath.Join(stage, backupActivityDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read staged activity log: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(activityDir, entry.Name()), data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to restore activity log: %w", err)
+		}
+	}
+
+	if err := os.RemoveAll(stage); err != nil {
+		return nil, fmt.Errorf("failed to clear restore staging: %w", err)
+	}
+	return manifest, nil
+}
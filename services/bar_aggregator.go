@@ -0,0 +1,520 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of bars the aggregator builds
+const (
+	BarKindTime   = "time"   // one bar per clock interval
+	BarKindTick   = "tick"   // one bar per Size trades
+	BarKindVolume = "volume" // one bar per Size shares
+)
+
+// Trade-built bars are capped so a long range of a busy symbol fails fast
+// instead of downloading millions of trades
+const maxAggregatedTrades = 1000000
+
+// maxAggregatedBars is how many completed bars the live aggregator keeps per
+// symbol and bar spec
+const maxAggregatedBars = 500
+
+// barAggregatorRetry is how long the live aggregator waits before reopening
+// a trade stream that failed to open
+const barAggregatorRetry = 30 * time.Second
+
+// TradeSource is a data provider that serves individual trades, which
+// custom bars are built from
+type TradeSource interface {
+	// GetHistoricalTrades returns up to limit trades from start up to end,
+	// oldest first
+	GetHistoricalTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*interfaces.Trade, error)
+	// StreamTrades streams trades until ctx ends, which closes the channel
+	StreamTrades(ctx context.Context, symbols []string) (<-chan *interfaces.Trade, error)
+}
+
+// BarSpec describes a bar that may not be offered by the data providers:
+// a timeframe, a tick bar such as "500Tick", a volume bar such as
+// "10000Volume", or any of those as Heikin-Ashi bars with an "HA:" prefix
+type BarSpec struct {
+	Kind       string
+	Timeframe  Timeframe // time bars
+	Size       int64     // trades or shares per tick or volume bar
+	HeikinAshi bool
+}
+
+var barSizePattern = regexp.MustCompile(`^(\d+)\s*([A-Za-z]+)$`)
+
+// barSizeUnits maps the lowercase units of tick and volume bars to kinds
+var barSizeUnits = map[string]string{
+	"tick":   BarKindTick,
+	"ticks":  BarKindTick,
+	"vol":    BarKindVolume,
+	"volume": BarKindVolume,
+}
+
+// String formats the spec canonically, such as "500Tick" or "HA:5Min"
+func (b BarSpec) String() string {
+	var s string
+	switch b.Kind {
+	case BarKindTick:
+		s = strconv.FormatInt(b.Size, 10) + "Tick"
+	case BarKindVolume:
+		s = strconv.FormatInt(b.Size, 10) + "Volume"
+	default:
+		s = b.Timeframe.String()
+	}
+	if b.HeikinAshi {
+		return "HA:" + s
+	}
+	return s
+}
+
+// Custom reports whether the bars are built locally rather than served by
+// the data provider as they are
+func (b BarSpec) Custom() bool {
+	return b.Kind != BarKindTime || b.HeikinAshi
+}
+
+// ParseBarSpec parses a timeframe, tick bar, volume bar or Heikin-Ashi bar
+func ParseBarSpec(s string) (BarSpec, error) {
+	var spec BarSpec
+	rest := strings.TrimSpace(s)
+	if prefix, after, ok := strings.Cut(rest, ":"); ok {
+		if !strings.EqualFold(prefix, "HA") {
+			return BarSpec{}, invalidBarSpec(s)
+		}
+		spec.HeikinAshi = true
+		rest = strings.TrimSpace(after)
+	}
+
+	if m := barSizePattern.FindStringSubmatch(rest); m != nil {
+		if kind, ok := barSizeUnits[strings.ToLower(m[2])]; ok {
+			size, err := strconv.ParseInt(m[1], 10, 64)
+			if err != nil || size <= 0 {
+				return BarSpec{}, invalidBarSpec(s)
+			}
+			spec.Kind = kind
+			spec.Size = size
+			return spec, nil
+		}
+	}
+
+	tf, err := ParseTimeframe(rest)
+	if err != nil {
+		if spec.HeikinAshi {
+			return BarSpec{}, invalidBarSpec(s)
+		}
+		return BarSpec{}, err
+	}
+	spec.Kind = BarKindTime
+	spec.Timeframe = tf
+	return spec, nil
+}
+
+// NormalizeBarSpec parses a bar spec and formats it canonically
+func NormalizeBarSpec(s string) (string, error) {
+	spec, err := ParseBarSpec(s)
+	if err != nil {
+		return "", err
+	}
+	return spec.String(), nil
+}
+
+func invalidBarSpec(s string) error {
+	return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf(
+		"invalid bars %q: use a timeframe such as 5Min, a tick bar such as 500Tick, a volume bar such as 10000Volume, or any of them prefixed with HA: for Heikin-Ashi", s))
+}
+
+// barBuilder folds trades into bars of one spec for one symbol
+type barBuilder struct {
+	symbol   string
+	spec     BarSpec
+	current  *interfaces.Bar
+	trades   int64 // in the current bar
+	notional float64
+	prevHA   *interfaces.Bar // last Heikin-Ashi bar emitted
+}
+
+func newBarBuilder(symbol string, spec BarSpec) *barBuilder {
+	return &barBuilder{symbol: symbol, spec: spec}
+}
+
+// add folds in a trade and returns the bars it completed. A trade larger
+// than what a volume bar still needs is split across bars.
+func (b *barBuilder) add(trade *interfaces.Trade) []*interfaces.Bar {
+	var completed []*interfaces.Bar
+	if b.spec.Kind == BarKindTime {
+		start := trade.Timestamp.Truncate(b.spec.Timeframe.Duration())
+		if b.current != nil && start.After(b.current.Timestamp) {
+			completed = append(completed, b.close())
+		}
+		b.fold(trade, trade.Size, start)
+		return completed
+	}
+
+	remaining := trade.Size
+	for {
+		take := remaining
+		if b.spec.Kind == BarKindVolume {
+			need := b.spec.Size
+			if b.current != nil {
+				need -= b.current.Volume
+			}
+			if take > need {
+				take = need
+			}
+		}
+		b.fold(trade, take, trade.Timestamp)
+		remaining -= take
+
+		full := b.trades >= b.spec.Size
+		if b.spec.Kind == BarKindVolume {
+			full = b.current.Volume >= b.spec.Size
+		}
+		if full {
+			completed = append(completed, b.close())
+		}
+		if remaining <= 0 || b.spec.Kind == BarKindTick {
+			return completed
+		}
+	}
+}
+
+func (b *barBuilder) fold(trade *interfaces.Trade, size int64, start time.Time) {
+	if b.current == nil {
+		b.current = &interfaces.Bar{
+			Symbol:    b.symbol,
+			Timestamp: start,
+			Open:      trade.Price,
+			High:      trade.Price,
+			Low:       trade.Price,
+		}
+		b.trades = 0
+		b.notional = 0
+	}
+	b.current.High = math.Max(b.current.High, trade.Price)
+	b.current.Low = math.Min(b.current.Low, trade.Price)
+	b.current.Close = trade.Price
+	b.current.Volume += size
+	b.notional += trade.Price * float64(size)
+	b.trades++
+}
+
+// due returns the current time bar once its interval has ended by now
+func (b *barBuilder) due(now time.Time) *interfaces.Bar {
+	if b.spec.Kind != BarKindTime || b.current == nil ||
+		now.Before(b.current.Timestamp.Add(b.spec.Timeframe.Duration())) {
+		return nil
+	}
+	return b.close()
+}
+
+// close completes the current bar, converting it to Heikin-Ashi if asked
+func (b *barBuilder) close() *interfaces.Bar {
+	bar := b.current
+	b.current = nil
+	if bar.Volume > 0 {
+		bar.VWAP = b.notional / float64(bar.Volume)
+	}
+	if b.spec.HeikinAshi {
+		bar = heikinAshiBar(bar, b.prevHA)
+		b.prevHA = bar
+	}
+	return bar
+}
+
+// BuildBars builds bars of spec from trades, oldest first. The last bar may
+// be incomplete, as the newest bar from a data provider is.
+func BuildBars(symbol string, trades []*interfaces.Trade, spec BarSpec) []*interfaces.Bar {
+	builder := newBarBuilder(symbol, spec)
+	bars := make([]*interfaces.Bar, 0)
+	for _, trade := range trades {
+		bars = append(bars, builder.add(trade)...)
+	}
+	if builder.current != nil {
+		bars = append(bars, builder.close())
+	}
+	return bars
+}
+
+// HeikinAshi converts bars, oldest first, to Heikin-Ashi bars. Volume and
+// VWAP are kept as they are.
+func HeikinAshi(bars []*interfaces.Bar) []*interfaces.Bar {
+	converted := make([]*interfaces.Bar, len(bars))
+	var prev *interfaces.Bar
+	for i, bar := range bars {
+		prev = heikinAshiBar(bar, prev)
+		converted[i] = prev
+	}
+	return converted
+}
+
+func heikinAshiBar(bar, prev *interfaces.Bar) *interfaces.Bar {
+	ha := *bar
+	ha.Close = (bar.Open + bar.High + bar.Low + bar.Close) / 4
+	ha.Open = (bar.Open + bar.Close) / 2
+	if prev != nil {
+		ha.Open = (prev.Open + prev.Close) / 2
+	}
+	ha.High = math.Max(bar.High, math.Max(ha.Open, ha.Close))
+	ha.Low = math.Min(bar.Low, math.Min(ha.Open, ha.Close))
+	return &ha
+}
+
+// AggregatedDataService serves custom bars wherever a timeframe is taken:
+// Heikin-Ashi bars from the provider's bars, and tick and volume bars from
+// its trades. Other timeframes pass through.
+type AggregatedDataService struct {
+	interfaces.DataService
+	trades TradeSource // nil when no provider serves trades
+}
+
+// NewAggregatedDataService wraps data; trades may be nil, which makes tick
+// and volume bars unavailable
+func NewAggregatedDataService(data interfaces.DataService, trades TradeSource) *AggregatedDataService {
+	return &AggregatedDataService{DataService: data, trades: trades}
+}
+
+// GetHistoricalBars implements interfaces.DataService
+func (s *AggregatedDataService) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
+	spec, err := ParseBarSpec(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	if !spec.Custom() {
+		return s.DataService.GetHistoricalBars(ctx, symbol, start, end, spec.String())
+	}
+
+	if spec.Kind == BarKindTime {
+		bars, err := s.DataService.GetHistoricalBars(ctx, symbol, start, end, spec.Timeframe.String())
+		if err != nil {
+			return nil, err
+		}
+		return HeikinAshi(bars), nil
+	}
+
+	if s.trades == nil {
+		return nil, fmt.Errorf("%s bars: %w", spec, ErrNotSupported)
+	}
+	trades, err := s.trades.GetHistoricalTrades(ctx, symbol, start, end, maxAggregatedTrades+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trades for %s bars: %w", spec, err)
+	}
+	if len(trades) > maxAggregatedTrades {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf(
+			"%s bars: more than %d trades in range, request a shorter range", spec, maxAggregatedTrades))
+	}
+	return BuildBars(strings.ToUpper(symbol), trades, spec), nil
+}
+
+// AggregatedBar is a custom bar completed by the live aggregator
+type AggregatedBar struct {
+	Symbol string          `json:"symbol"`
+	Spec   string          `json:"spec"` // such as "500Tick"
+	Bar    *interfaces.Bar `json:"bar"`
+}
+
+// AggregatedSeries lists the bar specs the live aggregator builds for a
+// symbol
+type AggregatedSeries struct {
+	Symbol string   `json:"symbol"`
+	Specs  []string `json:"specs"`
+}
+
+// BarAggregator builds custom bars from streamed trades as they happen.
+// Completed bars are kept for the bars endpoint and published on the event
+// bus for strategies and other listeners.
+type BarAggregator struct {
+	trades   TradeSource
+	builders map[string]map[string]*barBuilder // symbol, then bar spec
+	recent   map[string][]*interfaces.Bar      // symbol + "|" + bar spec, oldest first
+	eventBus *EventBus
+	mu       sync.RWMutex
+	logger   *logrus.Logger
+}
+
+// NewBarAggregator creates an aggregator streaming from trades
+func NewBarAggregator(trades TradeSource) *BarAggregator {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &BarAggregator{
+		trades:   trades,
+		builders: make(map[string]map[string]*barBuilder),
+		recent:   make(map[string][]*interfaces.Bar),
+		logger:   logger,
+	}
+}
+
+// SetEventBus publishes completed bars as bar_completed events
+func (a *BarAggregator) SetEventBus(bus *EventBus) {
+	a.eventBus = bus
+}
+
+// Track builds bars of every spec for symbol. Time bars built from trades
+// must be minutes or hours. Call before Run.
+func (a *BarAggregator) Track(symbol string, specs ...string) error {
+	symbol = strings.ToUpper(symbol)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, s := range specs {
+		spec, err := ParseBarSpec(s)
+		if err != nil {
+			return err
+		}
+		if spec.Kind == BarKindTime && spec.Timeframe.Unit != TimeframeMinute && spec.Timeframe.Unit != TimeframeHour {
+			return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("%s bars can't be built from streamed trades: use minutes or hours", spec))
+		}
+		if a.builders[symbol] == nil {
+			a.builders[symbol] = make(map[string]*barBuilder)
+		}
+		a.builders[symbol][spec.String()] = newBarBuilder(symbol, spec)
+	}
+	return nil
+}
+
+// Tracked lists the symbols and bar specs being built
+func (a *BarAggregator) Tracked() []AggregatedSeries {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	series := make([]AggregatedSeries, 0, len(a.builders))
+	for symbol, builders := range a.builders {
+		entry := AggregatedSeries{Symbol: symbol, Specs: make([]string, 0, len(builders))}
+		for spec := range builders {
+			entry.Specs = append(entry.Specs, spec)
+		}
+		sort.Strings(entry.Specs)
+		series = append(series, entry)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Symbol < series[j].Symbol })
+	return series
+}
+
+// Bars returns the completed bars of spec for symbol, oldest first
+func (a *BarAggregator) Bars(symbol, spec string) ([]*interfaces.Bar, error) {
+	symbol = strings.ToUpper(symbol)
+	spec, err := NormalizeBarSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.builders[symbol][spec] == nil {
+		return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("%s %s bars are not being built", symbol, spec))
+	}
+	bars := a.recent[symbol+"|"+spec]
+	return append(make([]*interfaces.Bar, 0, len(bars)), bars...), nil
+}
+
+// Run streams trades for the tracked symbols into bars until ctx ends
+func (a *BarAggregator) Run(ctx context.Context) {
+	a.mu.RLock()
+	symbols := make([]string, 0, len(a.builders))
+	for symbol := range a.builders {
+		symbols = append(symbols, symbol)
+	}
+	a.mu.RUnlock()
+	if len(symbols) == 0 {
+		return
+	}
+
+	for {
+		trades, err := a.trades.StreamTrades(ctx, symbols)
+		if err != nil {
+			a.logger.WithError(err).Warn("Failed to stream trades for bar aggregation")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(barAggregatorRetry):
+				continue
+			}
+		}
+		a.logger.WithField("symbols", symbols).Info("Aggregating bars from streamed trades")
+		a.consume(ctx, trades)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// consume folds trades until the stream closes, closing time bars on the
+// clock so a quiet symbol's bar doesn't wait for its next trade
+func (a *BarAggregator) consume(ctx context.Context, trades <-chan *interfaces.Trade) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case trade, ok := <-trades:
+			if !ok {
+				return
+			}
+			a.addTrade(trade)
+		case now := <-ticker.C:
+			a.closeDue(now)
+		}
+	}
+}
+
+func (a *BarAggregator) addTrade(trade *interfaces.Trade) {
+	symbol := strings.ToUpper(trade.Symbol)
+	a.mu.Lock()
+	var completed []AggregatedBar
+	for spec, builder := range a.builders[symbol] {
+		for _, bar := range builder.add(trade) {
+			completed = append(completed, a.keep(symbol, spec, bar))
+		}
+	}
+	a.mu.Unlock()
+	a.publish(completed)
+}
+
+func (a *BarAggregator) closeDue(now time.Time) {
+	a.mu.Lock()
+	var completed []AggregatedBar
+	for symbol, builders := range a.builders {
+		for spec, builder := range builders {
+			if bar := builder.due(now); bar != nil {
+				completed = append(completed, a.keep(symbol, spec, bar))
+			}
+		}
+	}
+	a.mu.Unlock()
+	a.publish(completed)
+}
+
+// keep stores a completed bar; the caller holds the lock
+func (a *BarAggregator) keep(symbol, spec string, bar *interfaces.Bar) AggregatedBar {
+	key := symbol + "|" + spec
+	bars := append(a.recent[key], bar)
+	if len(bars) > maxAggregatedBars {
+		bars = bars[len(bars)-maxAggregatedBars:]
+	}
+	a.recent[key] = bars
+	return AggregatedBar{Symbol: symbol, Spec: spec, Bar: bar}
+}
+
+func (a *BarAggregator) publish(completed []AggregatedBar) {
+	for _, bar := range completed {
+		a.eventBus.Publish(EventBarCompleted, bar)
+	}
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// barDownloadWindows is how much history one request asks for per
+// timeframe, keeping each response to roughly one page of bars
+var barDownloadWindows = map[string]time.Duration{
+	"1Min":   7 * 24 * time.Hour,
+	"5Min":   30 * 24 * time.Hour,
+	"15Min":  90 * 24 * time.Hour,
+	"30Min":  180 * 24 * time.Hour,
+	"1Hour":  365 * 24 * time.Hour,
+	"4Hour":  365 * 24 * time.Hour,
+	"1Day":   5 * 365 * 24 * time.Hour,
+	"1Week":  20 * 365 * 24 * time.Hour,
+	"1Month": 20 * 365 * 24 * time.Hour,
+}
+
+// Rate limited requests are retried this many times, backing off from
+// barDownloadBackoff
+const (
+	barDownloadRetries = 5
+	barDownloadBackoff = 5 * time.Second
+)
+
+// BarDownloadProgress is reported after every stored window
+type BarDownloadProgress struct {
+	Symbol    string    `json:"symbol"`
+	Timeframe string    `json:"timeframe"`
+	Start     time.Time `json:"start"` // window just stored
+	End       time.Time `json:"end"`
+	Bars      int       `json:"bars"`
+	Percent   float64   `json:"percent"` // of this run's range
+}
+
+// BarDownloadResult summarizes one symbol's download
+type BarDownloadResult struct {
+	Symbol     string    `json:"symbol"`
+	Timeframe  string    `json:"timeframe"`
+	Start      time.Time `json:"start"`   // stored history now begins here
+	Through    time.Time `json:"through"` // and runs up to here
+	Downloaded int       `json:"downloaded"`
+	StoredBars int64     `json:"stored_bars"`
+	Requests   int       `json:"requests"`
+}
+
+// BarDownloader backfills historical bars into local storage for the
+// backtester. Progress is recorded after every window, so an interrupted
+// download resumes where it stopped when run again.
+type BarDownloader struct {
+	data     interfaces.DataService
+	storage  *database.LocalStorage
+	pace     time.Duration // minimum time between requests
+	next     time.Time
+	progress func(BarDownloadProgress)
+	logger   *logrus.Logger
+}
+
+// NewBarDownloader creates a downloader making at most requestsPerMinute
+// data requests. Zero or less does not pace requests.
+func NewBarDownloader(data interfaces.DataService, storage *database.LocalStorage, requestsPerMinute int) *BarDownloader {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	var pace time.Duration
+	if requestsPerMinute > 0 {
+		pace = time.Minute / time.Duration(requestsPerMinute)
+	}
+
+	return &BarDownloader{
+		data:    data,
+		storage: storage,
+		pace:    pace,
+		logger:  logger,
+	}
+}
+
+// OnProgress sets a callback run after every stored window
+func (d *BarDownloader) OnProgress(fn func(BarDownloadProgress)) {
+	d.progress = fn
+}
+
+// Download stores bars for symbol from from up to, not including, to.
+// Only the parts of the range not already stored are fetched: history
+// before what is stored is fetched newest first and history after it oldest
+// first, so the stored range always stays contiguous.
+func (d *BarDownloader) Download(ctx context.Context, symbol, timeframe string, from, to time.Time) (*BarDownloadResult, error) {
+	tf, err := ParseTimeframe(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	timeframe = tf.String()
+	window, ok := barDownloadWindows[timeframe]
+	if !ok {
+		// Scaled from the unit's window, such as 14 days for 2Min
+		window = barDownloadWindows["1"+string(tf.Unit)] * time.Duration(tf.Amount)
+	}
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+	if !from.Before(to) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("start must be before end"))
+	}
+	symbol = strings.ToUpper(symbol)
+
+	record, err := d.storage.GetBarDownload(symbol, timeframe)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		record = &models.DBBarDownload{Symbol: symbol, Timeframe: timeframe, Start: from, Through: from}
+	}
+
+	var remaining time.Duration
+	if record.Start.After(from) {
+		remaining += record.Start.Sub(from)
+	}
+	if record.Through.Before(to) {
+		remaining += to.Sub(record.Through)
+	}
+	var done time.Duration
+
+	result := &BarDownloadResult{Symbol: symbol, Timeframe: timeframe}
+	store := func(start, end time.Time) error {
+		count, err := d.fetch(ctx, symbol, timeframe, start, end)
+		result.Requests++
+		if err != nil {
+			return err
+		}
+		if start.Before(record.Start) {
+			record.Start = start
+		}
+		if end.After(record.Through) {
+			record.Through = end
+		}
+		record.Bars += int64(count)
+		if err := d.storage.SaveBarDownload(record); err != nil {
+			return err
+		}
+		result.Downloaded += count
+		done += end.Sub(start)
+		if d.progress != nil {
+			d.progress(BarDownloadProgress{
+				Symbol:    symbol,
+				Timeframe: timeframe,
+				Start:     start,
+				End:       end,
+				Bars:      count,
+				Percent:   float64(done) / float64(remaining) * 100,
+			})
+		}
+		return nil
+	}
+
+	for record.Start.After(from) {
+		start := record.Start.Add(-window)
+		if start.Before(from) {
+			start = from
+		}
+		if err := store(start, record.Start); err != nil {
+			return nil, err
+		}
+	}
+	for record.Through.Before(to) {
+		end := record.Through.Add(window)
+		if end.After(to) {
+			end = to
+		}
+		if err := store(record.Through, end); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Start = record.Start
+	result.Through = record.Through
+	result.StoredBars = record.Bars
+
+	d.logger.WithFields(logrus.Fields{
+		"symbol":     symbol,
+		"timeframe":  timeframe,
+		"downloaded": result.Downloaded,
+		"requests":   result.Requests,
+	}).Info("Bar download complete")
+
+	return result, nil
+}
+
+// fetch stores the bars from start up to, not including, end and returns
+// how many there were
+func (d *BarDownloader) fetch(ctx context.Context, symbol, timeframe string, start, end time.Time) (int, error) {
+	backoff := barDownloadBackoff
+	for attempt := 0; ; attempt++ {
+		if err := d.wait(ctx); err != nil {
+			return 0, err
+		}
+
+		bars, err := d.data.GetHistoricalBars(ctx, symbol, start, end, timeframe)
+		if err == nil {
+			inRange := make([]*interfaces.Bar, 0, len(bars))
+			for _, bar := range bars {
+				if !bar.Timestamp.Before(start) && bar.Timestamp.Before(end) {
+					inRange = append(inRange, bar)
+				}
+			}
+			if err := d.storage.ReplaceBars(symbol, timeframe, start, end, inRange); err != nil {
+				return 0, err
+			}
+			return len(inRange), nil
+		}
+
+		if ErrorCodeOf(err) != ErrCodeUpstreamRateLimited || attempt >= barDownloadRetries {
+			return 0, fmt.Errorf("failed to download %s bars from %s: %w", symbol, start.Format("2006-01-02"), err)
+		}
+		d.logger.WithFields(logrus.Fields{
+			"symbol":  symbol,
+			"backoff": backoff,
+		}).Warn("Bar download rate limited, backing off")
+		d.next = time.Now().Add(backoff)
+		backoff *= 2
+	}
+}
+
+// wait blocks until the next request is allowed
+func (d *BarDownloader) wait(ctx context.Context) error {
+	if delay := time.Until(d.next); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	d.next = time.Now().Add(d.pace)
+	return nil
+}
+
+// BarCacheDataService serves historical bars from storage when a download
+// covers the start of the requested range, so backtests over downloaded
+// history only request the bars newer than the download. Downloads use the
+// provider's default adjustment, so requests with bar options bypass them.
+type BarCacheDataService struct {
+	interfaces.DataService
+	storage *database.LocalStorage
+}
+
+// NewBarCacheDataService wraps data, serving downloaded bars from storage
+func NewBarCacheDataService(data interfaces.DataService, storage *database.LocalStorage) *BarCacheDataService {
+	return &BarCacheDataService{DataService: data, storage: storage}
+}
+
+// GetHistoricalBars implements interfaces.DataService
+func (s *BarCacheDataService) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
+	symbol = strings.ToUpper(symbol)
+	timeframe, err := NormalizeBarSpec(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	if !barOptionsFrom(ctx).IsZero() {
+		return s.DataService.GetHistoricalBars(ctx, symbol, start, end, timeframe)
+	}
+	record, err := s.storage.GetBarDownload(symbol, timeframe)
+	if err != nil || record == nil || start.Before(record.Start) || !start.Before(record.Through) {
+		return s.DataService.GetHistoricalBars(ctx, symbol, start, end, timeframe)
+	}
+
+	bars, err := s.storage.GetTimeframeBars(symbol, timeframe, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if end.Before(record.Through) {
+		return bars, nil
+	}
+
+	// Bars past the download come from the data service
+	newer, err := s.DataService.GetHistoricalBars(ctx, symbol, record.Through, end, timeframe)
+	if err != nil {
+		return nil, err
+	}
+	stored := bars[:0]
+	for _, bar := range bars {
+		if bar.Timestamp.Before(record.Through) {
+			stored = append(stored, bar)
+		}
+	}
+	for _, bar := range newer {
+		if !bar.Timestamp.Before(record.Through) {
+			stored = append(stored, bar)
+		}
+	}
+	return stored, nil
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Corporate action adjustments a bar request may ask for
+const (
+	BarAdjustmentRaw      = "raw"      // prices as traded
+	BarAdjustmentSplit    = "split"    // adjusted for splits only
+	BarAdjustmentDividend = "dividend" // adjusted for dividends only
+	BarAdjustmentAll      = "all"      // adjusted for splits and dividends
+)
+
+// BarAdjustments lists the accepted adjustments
+var BarAdjustments = []string{BarAdjustmentRaw, BarAdjustmentSplit, BarAdjustmentDividend, BarAdjustmentAll}
+
+// BarFeeds lists the Alpaca feeds a bar request may select
+var BarFeeds = []string{"iex", "sip", "delayed_sip", "otc", "boats", "overnight"}
+
+// BarOptions tune one historical bars request. Empty fields use the
+// provider's defaults: all adjustments on Alpaca, splits only on Polygon and
+// Tradier, and the configured ALPACA_DATA_FEED.
+type BarOptions struct {
+	Adjustment string `json:"adjustment,omitempty"`
+	Feed       string `json:"feed,omitempty"`
+}
+
+// IsZero reports whether every option is left to the provider default
+func (o BarOptions) IsZero() bool {
+	return o.Adjustment == "" && o.Feed == ""
+}
+
+// ParseBarOptions validates and lowercases an adjustment and feed, either of
+// which may be empty
+func ParseBarOptions(adjustment, feed string) (BarOptions, error) {
+	opts := BarOptions{
+		Adjustment: strings.ToLower(strings.TrimSpace(adjustment)),
+		Feed:       strings.ToLower(strings.TrimSpace(feed)),
+	}
+	if opts.Adjustment != "" && !slices.Contains(BarAdjustments, opts.Adjustment) {
+		return BarOptions{}, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf(
+			"invalid adjustment %q: use one of %s", adjustment, strings.Join(BarAdjustments, ", ")))
+	}
+	if opts.Feed != "" && !slices.Contains(BarFeeds, opts.Feed) {
+		return BarOptions{}, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf(
+			"invalid feed %q: use one of %s", feed, strings.Join(BarFeeds, ", ")))
+	}
+	return opts, nil
+}
+
+type barOptionsKey struct{}
+
+// WithBarOptions applies opts to the historical bars requests made with ctx
+func WithBarOptions(ctx context.Context, opts BarOptions) context.Context {
+	return context.WithValue(ctx, barOptionsKey{}, opts)
+}
+
+func barOptionsFrom(ctx context.Context) BarOptions {
+	opts, _ := ctx.Value(barOptionsKey{}).(BarOptions)
+	return opts
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Basket statuses
+const (
+	BasketSubmitted = "SUBMITTED" // every leg was submitted
+	BasketPartial   = "PARTIAL"   // some legs were submitted and some were not
+	BasketRejected  = "REJECTED"  // no leg was submitted, or all-or-none canceled the ones that were
+	BasketDryRun    = "DRY_RUN"
+)
+
+// Basket leg statuses
+const (
+	BasketLegSubmitted = "submitted"
+	BasketLegRejected  = "rejected" // held back by the risk checks
+	BasketLegFailed    = "failed"   // refused by the broker
+	BasketLegSkipped   = "skipped"  // not submitted because another leg was rejected
+	BasketLegCanceled  = "canceled" // submitted, then canceled because another leg failed
+	BasketLegDryRun    = "dry_run"
+)
+
+// BasketLeg is one order of a basket
+type BasketLeg struct {
+	Symbol      string           `json:"symbol" binding:"required,symbol"`
+	Side        string           `json:"side" binding:"required,oneof=buy sell"`
+	Qty         decimal.Decimal  `json:"qty" binding:"gt=0"`
+	Type        string           `json:"type" binding:"omitempty,oneof=market limit stop stop_limit"`
+	TimeInForce string           `json:"time_in_force" binding:"omitempty,oneof=day gtc ioc fok opg cls"`
+	LimitPrice  *decimal.Decimal `json:"limit_price,omitempty" binding:"omitempty,gt=0"`
+	StopPrice   *decimal.Decimal `json:"stop_price,omitempty" binding:"omitempty,gt=0"`
+	Strategy    string           `json:"strategy,omitempty" binding:"omitempty,max=64"`
+}
+
+// BasketRequest is the payload for submitting a basket of orders
+type BasketRequest struct {
+	Orders    []BasketLeg `json:"orders" binding:"required,min=1,max=50,dive"`
+	AllOrNone bool        `json:"all_or_none"` // submit nothing, or cancel what was submitted, if any leg is rejected
+	DryRun    bool        `json:"dry_run"`
+}
+
+// BasketLegResult is the outcome of one leg
+type BasketLegResult struct {
+	Index   int             `json:"index"`
+	Symbol  string          `json:"symbol"`
+	Side    string          `json:"side"`
+	Qty     decimal.Decimal `json:"qty"`
+	Status  string          `json:"status"`
+	OrderID string          `json:"order_id,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Risk    *RiskDecision   `json:"risk,omitempty"`
+}
+
+// BasketRisk is the combined pre-trade check of a basket
+type BasketRisk struct {
+	Approved    bool            `json:"approved"`
+	BuyNotional decimal.Decimal `json:"buy_notional"` // estimated cost of every buy leg together
+	BuyingPower decimal.Decimal `json:"buying_power"`
+	Violations  []RiskViolation `json:"violations,omitempty"`
+}
+
+// BasketResult reports what happened to every leg of a basket
+type BasketResult struct {
+	BasketID  string             `json:"basket_id"` // stored as basket_id metadata on every leg
+	Status    string             `json:"status"`
+	AllOrNone bool               `json:"all_or_none"`
+	Risk      *BasketRisk        `json:"risk"`
+	Legs      []*BasketLegResult `json:"legs"`
+	Submitted int                `json:"submitted"`
+}
+
+// BasketService submits several orders together. Each leg passes the
+// usual risk checks, and the buy legs together must fit in buying power.
+// Legs are submitted concurrently.
+type BasketService struct {
+	trading     interfaces.TradingService
+	storage     *database.LocalStorage
+	riskManager *RiskManager
+	auditor     *OrderAuditor
+	dryRun      bool
+	logger      *logrus.Logger
+}
+
+// NewBasketService creates a basket service
+func NewBasketService(trading interfaces.TradingService, storage *database.LocalStorage, riskManager *RiskManager, auditor *OrderAuditor, dryRun bool) *BasketService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &BasketService{
+		trading:     trading,
+		storage:     storage,
+		riskManager: riskManager,
+		auditor:     auditor,
+		dryRun:      dryRun,
+		logger:      logger,
+	}
+}
+
+// Submit risk-checks and submits a basket. Without AllOrNone the legs that
+// pass are submitted and the rest reported; with it, one rejected leg
+// holds back the whole basket, and one leg refused by the broker cancels
+// the legs already submitted. The combined buying power check always
+// applies to the whole basket.
+func (s *BasketService) Submit(ctx context.Context, req *BasketRequest) (*BasketResult, error) {
+	result := &BasketResult{
+		BasketID:  fmt.Sprintf("basket_%d", time.Now().UnixNano()),
+		AllOrNone: req.AllOrNone,
+		Legs:      make([]*BasketLegResult, len(req.Orders)),
+	}
+
+	orders := make([]*interfaces.Order, len(req.Orders))
+	for i, leg := range req.Orders {
+		order, err := s.buildOrder(ctx, leg, result.BasketID)
+		if err != nil {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("order %d: %w", i, err))
+		}
+		orders[i] = order
+		result.Legs[i] = &BasketLegResult{
+			Index:  i,
+			Symbol: order.Symbol,
+			Side:   order.Side,
+			Qty:    order.Qty,
+		}
+	}
+
+	risk, err := s.checkRisk(ctx, orders, result.Legs)
+	if err != nil {
+		return nil, err
+	}
+	result.Risk = risk
+
+	dryRun := s.dryRun || req.DryRun
+	rejected := !risk.Approved
+	for _, leg := range result.Legs {
+		if leg.Status == BasketLegRejected {
+			rejected = true
+		}
+	}
+
+	// Decide which legs go out
+	for i, leg := range result.Legs {
+		if leg.Status == BasketLegRejected {
+			s.auditor.RecordUnsubmitted(ctx, orders[i], AuditOutcomeRejected, leg.Risk, &RiskRejectedError{Decision: leg.Risk})
+			continue
+		}
+		switch {
+		case dryRun:
+			leg.Status = BasketLegDryRun
+			s.auditor.RecordUnsubmitted(ctx, orders[i], AuditOutcomeDryRun, leg.Risk, nil)
+		case !risk.Approved || (rejected && req.AllOrNone):
+			leg.Status = BasketLegSkipped
+			leg.Error = "not submitted: the basket was rejected"
+		}
+	}
+
+	if dryRun {
+		result.Status = BasketDryRun
+		return result, nil
+	}
+
+	s.place(ctx, orders, result.Legs)
+
+	failed := false
+	for _, leg := range result.Legs {
+		if leg.Status == BasketLegFailed {
+			failed = true
+		}
+	}
+	if failed && req.AllOrNone {
+		s.unwind(ctx, result.Legs)
+	}
+
+	for _, leg := range result.Legs {
+		if leg.Status == BasketLegSubmitted {
+			result.Submitted++
+		}
+	}
+	switch {
+	case result.Submitted == len(result.Legs):
+		result.Status = BasketSubmitted
+	case result.Submitted > 0:
+		result.Status = BasketPartial
+	default:
+		result.Status = BasketRejected
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"basket_id": result.BasketID,
+		"legs":      len(result.Legs),
+		"submitted": result.Submitted,
+		"status":    result.Status,
+	}).Info("Basket processed")
+
+	return result, nil
+}
+
+// buildOrder turns a leg into a broker order
+func (s *BasketService) buildOrder(ctx context.Context, leg BasketLeg, basketID string) (*interfaces.Order, error) {
+	symbol := strings.ToUpper(leg.Symbol)
+	if _, err := ParseOCCSymbol(symbol); err == nil {
+		return nil, fmt.Errorf("options can't be traded in a basket")
+	}
+
+	order := &interfaces.Order{
+		Symbol:      symbol,
+		Qty:         leg.Qty,
+		Side:        leg.Side,
+		Type:        leg.Type,
+		TimeInForce: leg.TimeInForce,
+		LimitPrice:  leg.LimitPrice,
+		StopPrice:   leg.StopPrice,
+		Status:      "pending",
+		SubmittedAt: time.Now(),
+		Strategy:    leg.Strategy,
+		Book:        BookFrom(ctx),
+		Metadata:    map[string]string{"basket_id": basketID},
+	}
+	if order.Type == "" {
+		order.Type = "market"
+	}
+	if order.TimeInForce == "" {
+		order.TimeInForce = "day"
+	}
+	if order.Strategy == "" {
+		order.Strategy = OrderSourceFrom(ctx)
+	}
+	if (order.Type == "limit" || order.Type == "stop_limit") && order.LimitPrice == nil {
+		return nil, fmt.Errorf("limit_price is required for %s orders", order.Type)
+	}
+	if (order.Type == "stop" || order.Type == "stop_limit") && order.StopPrice == nil {
+		return nil, fmt.Errorf("stop_price is required for %s orders", order.Type)
+	}
+	return order, nil
+}
+
+// checkRisk evaluates every leg, marking the ones the rules reject, then
+// checks the buy legs together against buying power. Sells don't add to
+// buying power since they may fill after the buys.
+func (s *BasketService) checkRisk(ctx context.Context, orders []*interfaces.Order, legs []*BasketLegResult) (*BasketRisk, error) {
+	risk := &BasketRisk{Approved: true}
+
+	for i, order := range orders {
+		decision, err := s.riskManager.Evaluate(ctx, order)
+		if err != nil {
+			return nil, err
+		}
+		legs[i].Risk = decision
+		if !decision.Approved {
+			legs[i].Status = BasketLegRejected
+			legs[i].Error = (&RiskRejectedError{Decision: decision}).Error()
+			continue
+		}
+		if order.Side == "buy" {
+			risk.BuyNotional = risk.BuyNotional.Add(decision.Notional)
+		}
+	}
+
+	account, err := s.trading.GetAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account for basket risk checks: %w", err)
+	}
+	risk.BuyingPower = account.BuyingPower
+	if risk.BuyNotional.GreaterThan(account.BuyingPower) {
+		risk.Approved = false
+		risk.Violations = append(risk.Violations, RiskViolation{
+			Rule: "basket_buying_power",
+			Message: fmt.Sprintf("buy legs notional %s exceeds buying power %s",
+				risk.BuyNotional.StringFixed(2), account.BuyingPower.StringFixed(2)),
+		})
+	}
+	return risk, nil
+}
+
+// place submits every leg without a status concurrently
+func (s *BasketService) place(ctx context.Context, orders []*interfaces.Order, legs []*BasketLegResult) {
+	var wg sync.WaitGroup
+	for i, leg := range legs {
+		if leg.Status != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(order *interfaces.Order, leg *BasketLegResult) {
+			defer wg.Done()
+
+			result, err := s.trading.PlaceOrder(WithRiskDecision(ctx, leg.Risk), order)
+			if err != nil {
+				s.logger.WithError(err).WithField("symbol", order.Symbol).Warn("Basket leg failed")
+				leg.Status = BasketLegFailed
+				leg.Error = err.Error()
+				return
+			}
+
+			order.ID = result.OrderID
+			order.Status = result.Status
+			if err := s.storage.SaveOrder(order); err != nil {
+				s.logger.WithError(err).Warn("Failed to save order to database")
+			}
+			leg.Status = BasketLegSubmitted
+			leg.OrderID = result.OrderID
+		}(orders[i], leg)
+	}
+	wg.Wait()
+}
+
+// unwind cancels the submitted legs of an all-or-none basket. A leg that
+// already filled can't be canceled and stays submitted.
+func (s *BasketService) unwind(ctx context.Context, legs []*BasketLegResult) {
+	for _, leg := range legs {
+		if leg.Status != BasketLegSubmitted {
+			continue
+		}
+		if err := s.trading.CancelOrder(ctx, leg.OrderID); err != nil {
+			s.logger.WithError(err).WithField("order_id", leg.OrderID).Warn("Failed to cancel basket leg")
+			leg.Error = fmt.Sprintf("another leg failed, but canceling this one failed: %v", err)
+			continue
+		}
+		leg.Status = BasketLegCanceled
+		leg.Error = "canceled: another leg failed"
+
+		if order, err := s.storage.GetOrder(leg.OrderID); err == nil {
+			now := time.Now()
+			order.Status = "canceled"
+			order.CanceledAt = &now
+			s.storage.SaveOrder(order)
+		}
+	}
+}
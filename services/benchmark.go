@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tradingDaysPerYear annualizes daily statistics
+const tradingDaysPerYear = 252
+
+// BenchmarkPoint is one day of the overlaid equity curves, each rebased to 100
+type BenchmarkPoint struct {
+	Date       string             `json:"date"`
+	Account    float64            `json:"account"`
+	Benchmarks map[string]float64 `json:"benchmarks"`
+}
+
+// BenchmarkStats compares the account against a single benchmark
+type BenchmarkStats struct {
+	Symbol              string  `json:"symbol"`
+	ReturnPct           float64 `json:"return_percent"`
+	ExcessReturnPct     float64 `json:"excess_return_percent"`
+	Alpha               float64 `json:"alpha"` // annualized Jensen's alpha, percent
+	Beta                float64 `json:"beta"`
+	Correlation         float64 `json:"correlation"`
+	TrackingErrorPct    float64 `json:"tracking_error_percent"` // annualized
+	InformationRatio    float64 `json:"information_ratio"`
+	MaxDrawdownPct      float64 `json:"max_drawdown_percent"`
+	MaxRelativeDrawdown float64 `json:"max_relative_drawdown_percent"` // worst underperformance from a relative peak
+	RelativeDrawdownPct float64 `json:"relative_drawdown_percent"`     // current underperformance from the relative peak
+}
+
+// BenchmarkReport overlays the account equity curve against benchmarks
+type BenchmarkReport struct {
+	Start                 time.Time        `json:"start"`
+	End                   time.Time        `json:"end"`
+	Days                  int              `json:"days"`
+	AccountReturnPct      float64          `json:"account_return_percent"`
+	AccountMaxDrawdownPct float64          `json:"account_max_drawdown_percent"`
+	NetCashFlows          float64          `json:"net_cash_flows"` // deposits less withdrawals taken out of the account's returns
+	Benchmarks            []BenchmarkStats `json:"benchmarks"`
+	Curve                 []BenchmarkPoint `json:"curve"`
+}
+
+// BenchmarkComparison compares daily account equity against each benchmark
+// symbol over the window. With account activities, deposits and withdrawals
+// are taken out of the daily returns, so the account's curve is time
+// weighted; without them returns are only meaningful for windows without
+// external cash flows.
+func (rs *ReportingService) BenchmarkComparison(ctx context.Context, start, end time.Time, benchmarks []string) (*BenchmarkReport, error) {
+	history, err := rs.tradingService.GetPortfolioHistory(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	account := make(map[string]float64, len(history))
+	for _, snapshot := range history {
+		account[snapshot.Timestamp.Format("2006-01-02")] = snapshot.Equity.InexactFloat64()
+	}
+	dates := sortedDates(account)
+	if len(dates) < 2 {
+		return nil, fmt.Errorf("not enough equity history between %s and %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+
+	report := &BenchmarkReport{
+		Start:      start,
+		End:        end,
+		Days:       len(dates),
+		Benchmarks: make([]BenchmarkStats, 0, len(benchmarks)),
+		Curve:      make([]BenchmarkPoint, 0, len(dates)),
+	}
+	if rs.activities != nil {
+		activities, err := rs.activities.Between(start, end)
+		if err != nil {
+			return nil, err
+		}
+		account, report.NetCashFlows = adjustForCashFlows(account, dates, activities)
+	}
+	report.AccountReturnPct = (account[dates[len(dates)-1]]/account[dates[0]] - 1) * 100
+	report.AccountMaxDrawdownPct = maxDrawdownPct(seriesValues(account, dates))
+
+	closes := make(map[string]map[string]float64, len(benchmarks))
+	for _, symbol := range benchmarks {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" {
+			continue
+		}
+
+		bars, err := rs.dataService.GetHistoricalBars(ctx, symbol, start, end, "1Day")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bars for benchmark %s: %w", symbol, err)
+		}
+		series := make(map[string]float64, len(bars))
+		for _, bar := range bars {
+			series[bar.Timestamp.Format("2006-01-02")] = bar.Close
+		}
+		closes[symbol] = series
+
+		// Compare only on days both series have a value
+		common := make([]string, 0, len(dates))
+		for _, date := range dates {
+			if _, ok := series[date]; ok {
+				common = append(common, date)
+			}
+		}
+		if len(common) < 2 {
+			rs.logger.WithField("symbol", symbol).Warn("Not enough overlapping bars for benchmark")
+			continue
+		}
+
+		report.Benchmarks = append(report.Benchmarks, compareToBenchmark(symbol, account, series, common))
+	}
+
+	// Overlay rebased to 100 on the first day of the window
+	for _, date := range dates {
+		point := BenchmarkPoint{
+			Date:       date,
+			Account:    account[date] / account[dates[0]] * 100,
+			Benchmarks: make(map[string]float64, len(closes)),
+		}
+		for symbol, series := range closes {
+			first := firstValue(series, dates)
+			if value, ok := series[date]; ok && first > 0 {
+				point.Benchmarks[symbol] = value / first * 100
+			}
+		}
+		report.Curve = append(report.Curve, point)
+	}
+
+	return report, nil
+}
+
+// adjustForCashFlows chains daily returns with each day's external cash
+// flows taken out into a curve starting at the first day's equity. A flow
+// on a day without equity, such as a weekend deposit, counts on the next
+// day that has one; flows up to the first day are already in its equity.
+func adjustForCashFlows(equity map[string]float64, dates []string, activities []*AccountActivity) (map[string]float64, float64) {
+	flows := make(map[string]float64)
+	total := 0.0
+	for _, activity := range activities {
+		if activity.Category != ActivityCashFlow {
+			continue
+		}
+		day := activity.Date.In(marketLocation).Format("2006-01-02")
+		i := sort.SearchStrings(dates, day)
+		if i == 0 || i == len(dates) {
+			continue
+		}
+		flow := activity.CashFlow().InexactFloat64()
+		flows[dates[i]] += flow
+		total += flow
+	}
+	if len(flows) == 0 {
+		return equity, 0
+	}
+
+	adjusted := make(map[string]float64, len(dates))
+	adjusted[dates[0]] = equity[dates[0]]
+	for i := 1; i < len(dates); i++ {
+		prev, day := dates[i-1], dates[i]
+		growth := 1.0
+		if equity[prev] > 0 {
+			growth = (equity[day] - flows[day]) / equity[prev]
+		}
+		adjusted[day] = adjusted[prev] * growth
+	}
+	return adjusted, total
+}
+
+// compareToBenchmark computes relative statistics over the common dates
+func compareToBenchmark(symbol string, account, benchmark map[string]float64, dates []string) BenchmarkStats {
+	stats := BenchmarkStats{Symbol: symbol}
+
+	accountReturns := returnsByDate(account, dates)
+	benchmarkReturns := returnsByDate(benchmark, dates)
+
+	first, last := dates[0], dates[len(dates)-1]
+	stats.ReturnPct = (benchmark[last]/benchmark[first] - 1) * 100
+	accountReturnPct := (account[last]/account[first] - 1) * 100
+	stats.ExcessReturnPct = accountReturnPct - stats.ReturnPct
+	stats.MaxDrawdownPct = maxDrawdownPct(seriesValues(benchmark, dates))
+
+	stats.Beta = beta(accountReturns, benchmarkReturns)
+	stats.Correlation = correlation(accountReturns, benchmarkReturns)
+
+	xs, ys := alignedReturns(accountReturns, benchmarkReturns)
+	stats.Alpha = (average(xs) - stats.Beta*average(ys)) * tradingDaysPerYear * 100
+
+	active := make([]float64, len(xs))
+	for i := range xs {
+		active[i] = xs[i] - ys[i]
+	}
+	trackingError := stdDev(active) * math.Sqrt(tradingDaysPerYear)
+	stats.TrackingErrorPct = trackingError * 100
+	if trackingError > 0 {
+		stats.InformationRatio = average(active) * tradingDaysPerYear / trackingError
+	}
+
+	// Relative curve: account growth divided by benchmark growth
+	relative := make([]float64, len(dates))
+	for i, date := range dates {
+		relative[i] = (account[date] / account[first]) / (benchmark[date] / benchmark[first])
+	}
+	stats.MaxRelativeDrawdown = maxDrawdownPct(relative)
+	peak := 0.0
+	for _, r := range relative {
+		peak = math.Max(peak, r)
+	}
+	if peak > 0 {
+		stats.RelativeDrawdownPct = (peak - relative[len(relative)-1]) / peak * 100
+	}
+
+	return stats
+}
+
+// sortedDates returns the keys of a date-keyed series in order
+func sortedDates(series map[string]float64) []string {
+	dates := make([]string, 0, len(series))
+	for date := range series {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// seriesValues returns the values of a series in date order
+func seriesValues(series map[string]float64, dates []string) []float64 {
+	values := make([]float64, 0, len(dates))
+	for _, date := range dates {
+		values = append(values, series[date])
+	}
+	return values
+}
+
+// returnsByDate computes day-over-day returns keyed by the later date
+func returnsByDate(series map[string]float64, dates []string) map[string]float64 {
+	returns := make(map[string]float64, len(dates))
+	for i := 1; i < len(dates); i++ {
+		prev := series[dates[i-1]]
+		if prev == 0 {
+			continue
+		}
+		returns[dates[i]] = series[dates[i]]/prev - 1
+	}
+	return returns
+}
+
+// firstValue returns the series value on the earliest of dates it covers
+func firstValue(series map[string]float64, dates []string) float64 {
+	for _, date := range dates {
+		if value, ok := series[date]; ok {
+			return value
+		}
+	}
+	return 0
+}
+
+// maxDrawdownPct returns the largest peak-to-trough decline in percent
+func maxDrawdownPct(values []float64) float64 {
+	peak, worst := 0.0, 0.0
+	for _, v := range values {
+		if v > peak {
+			peak = v
+		}
+		if peak > 0 {
+			worst = math.Max(worst, (peak-v)/peak*100)
+		}
+	}
+	return worst
+}
+
+// stdDev returns the sample standard deviation
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := average(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
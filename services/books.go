@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// DefaultBook holds everything placed without a book selector
+const DefaultBook = "default"
+
+// bookPattern limits book IDs to short lowercase slugs
+var bookPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+type bookKey struct{}
+
+// ValidateBook checks that book is a usable book ID
+func ValidateBook(book string) error {
+	if !bookPattern.MatchString(book) {
+		return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("invalid book %q: use up to 32 lowercase letters, digits, '-' or '_'", book))
+	}
+	return nil
+}
+
+// WithBook tags positions, orders and journal entries created with ctx with
+// a book, a logical sub-account for keeping experiments apart
+func WithBook(ctx context.Context, book string) context.Context {
+	return context.WithValue(ctx, bookKey{}, book)
+}
+
+// BookFrom returns the book carried by ctx, defaulting to DefaultBook
+func BookFrom(ctx context.Context) string {
+	if book, ok := SelectedBook(ctx); ok {
+		return book
+	}
+	return DefaultBook
+}
+
+// SelectedBook returns the book carried by ctx and whether one was set.
+// Reads use it to filter by book only when the caller asked for one.
+func SelectedBook(ctx context.Context) (string, bool) {
+	book, ok := ctx.Value(bookKey{}).(string)
+	return book, ok && book != ""
+}
+
+// bookOrDefault treats records saved before books existed as DefaultBook
+func bookOrDefault(book string) string {
+	if book == "" {
+		return DefaultBook
+	}
+	return book
+}
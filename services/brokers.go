@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"prophet-trader/config"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+)
+
+// ErrNotSupported is returned by brokers for operations they do not offer
+var ErrNotSupported = errors.New("not supported by this broker")
+
+// BrokerFactory creates a trading service from the application config
+type BrokerFactory func(cfg *config.Config) (interfaces.TradingService, error)
+
+var brokers = map[string]BrokerFactory{}
+
+// RegisterBroker makes a TradingService implementation selectable by name
+func RegisterBroker(name string, factory BrokerFactory) {
+	brokers[strings.ToLower(name)] = factory
+}
+
+// NewBroker creates the trading service registered under name
+func NewBroker(name string, cfg *config.Config) (interfaces.TradingService, error) {
+	factory, ok := brokers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown broker: %s (available: %v)", name, BrokerNames())
+	}
+
+	trading, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s broker: %w", name, err)
+	}
+	return trading, nil
+}
+
+// BrokerNames returns the registered broker names in sorted order
+func BrokerNames() []string {
+	names := make([]string, 0, len(brokers))
+	for name := range brokers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DataProviderFactory creates a market data service from the application config
+type DataProviderFactory func(cfg *config.Config) (interfaces.DataService, error)
+
+var dataProviders = map[string]DataProviderFactory{}
+
+// RegisterDataProvider makes a DataService implementation selectable by name
+func RegisterDataProvider(name string, factory DataProviderFactory) {
+	dataProviders[strings.ToLower(name)] = factory
+}
+
+// NewDataProvider creates the market data service registered under name
+func NewDataProvider(name string, cfg *config.Config) (interfaces.DataService, error) {
+	factory, ok := dataProviders[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown data provider: %s (available: %v)", name, DataProviderNames())
+	}
+
+	data, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s data provider: %w", name, err)
+	}
+	return data, nil
+}
+
+// DataProviderNames returns the registered data provider names in sorted order
+func DataProviderNames() []string {
+	names := make([]string, 0, len(dataProviders))
+	for name := range dataProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
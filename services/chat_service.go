@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Chat session limits
+const (
+	chatSessionTTL         = 2 * time.Hour      // idle sessions are forgotten after this
+	chatMaxSessions        = 200                // the least recently used session is dropped beyond this
+	chatMaxMessages        = 20                 // turns kept per session, oldest dropped first
+	chatHistoryWindow      = 7 * 24 * time.Hour // analyses and signals offered as context
+	chatRecentAnalyses     = 10                 // newest analyses of any symbol
+	chatAnalysesPerSymbol  = 5                  // analyses of each symbol the conversation mentions
+	chatRecentSignals      = 20
+	chatContextNotesLength = 400
+	chatRelatedItems       = 5
+)
+
+// ChatSession is a conversation with the intelligence chat
+type ChatSession struct {
+	ID        string        `json:"session_id"`
+	Owner     string        `json:"-"` // token name of the caller that started it
+	Messages  []ChatMessage `json:"messages"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// ChatReply is the answer to one chat message
+type ChatReply struct {
+	SessionID string    `json:"session_id"`
+	Reply     string    `json:"reply"`
+	Symbols   []string  `json:"symbols,omitempty"` // symbols whose analyses were included
+	At        time.Time `json:"at"`
+}
+
+// ChatService keeps per-session conversations with the LLM and gives every
+// question the current portfolio, open orders and recent analyses
+type ChatService struct {
+	tradingService interfaces.TradingService
+	storageService *database.LocalStorage
+	geminiService  *GeminiService
+	semanticIndex  *SemanticIndex
+	sessions       map[string]*ChatSession
+	mu             sync.Mutex
+	logger         *logrus.Logger
+}
+
+// NewChatService creates a chat service
+func NewChatService(tradingService interfaces.TradingService, storageService *database.LocalStorage, geminiService *GeminiService) *ChatService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &ChatService{
+		tradingService: tradingService,
+		storageService: storageService,
+		geminiService:  geminiService,
+		sessions:       make(map[string]*ChatSession),
+		logger:         logger,
+	}
+}
+
+// SetSemanticIndex adds news and analyses similar to each question to its
+// context
+func (cs *ChatService) SetSemanticIndex(semanticIndex *SemanticIndex) {
+	cs.semanticIndex = semanticIndex
+}
+
+// Chat answers message in session sessionID, starting a new session when
+// sessionID is empty. Sessions belong to the owner that started them.
+func (cs *ChatService) Chat(ctx context.Context, sessionID, owner, message string) (*ChatReply, error) {
+	cs.mu.Lock()
+	cs.expire()
+	var session *ChatSession
+	if sessionID == "" {
+		session = cs.start(owner)
+	} else if session = cs.sessions[sessionID]; session == nil || session.Owner != owner {
+		cs.mu.Unlock()
+		return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("chat session %s not found or expired", sessionID))
+	}
+	history := append([]ChatMessage(nil), session.Messages...)
+	cs.mu.Unlock()
+
+	// Symbols asked about earlier stay in context for follow-up questions
+	var conversation strings.Builder
+	for _, m := range history {
+		if m.Role == "user" {
+			conversation.WriteString(m.Text + " ")
+		}
+	}
+	conversation.WriteString(message)
+	symbols := extractTickers(conversation.String())
+
+	reply, err := cs.geminiService.Chat(ctx, history, cs.buildContext(ctx, symbols, message), message)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cs.mu.Lock()
+	session.Messages = append(session.Messages,
+		ChatMessage{Role: "user", Text: message, At: now},
+		ChatMessage{Role: "model", Text: reply, At: now},
+	)
+	if len(session.Messages) > chatMaxMessages {
+		session.Messages = session.Messages[len(session.Messages)-chatMaxMessages:]
+	}
+	session.UpdatedAt = now
+	cs.mu.Unlock()
+
+	return &ChatReply{SessionID: session.ID, Reply: reply, Symbols: symbols, At: now}, nil
+}
+
+// GetSession returns a copy of the owner's session
+func (cs *ChatService) GetSession(sessionID, owner string) (*ChatSession, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.expire()
+
+	session := cs.sessions[sessionID]
+	if session == nil || session.Owner != owner {
+		return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("chat session %s not found or expired", sessionID))
+	}
+	copied := *session
+	copied.Messages = append([]ChatMessage(nil), session.Messages...)
+	return &copied, nil
+}
+
+// DeleteSession ends the owner's session
+func (cs *ChatService) DeleteSession(sessionID, owner string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	session := cs.sessions[sessionID]
+	if session == nil || session.Owner != owner {
+		return WithErrorCode(ErrCodeNotFound, fmt.Errorf("chat session %s not found or expired", sessionID))
+	}
+	delete(cs.sessions, sessionID)
+	return nil
+}
+
+// start creates a session, dropping the least recently used one when full.
+// The caller holds cs.mu.
+func (cs *ChatService) start(owner string) *ChatSession {
+	if len(cs.sessions) >= chatMaxSessions {
+		var oldest *ChatSession
+		for _, session := range cs.sessions {
+			if oldest == nil || session.UpdatedAt.Before(oldest.UpdatedAt) {
+				oldest = session
+			}
+		}
+		delete(cs.sessions, oldest.ID)
+	}
+
+	id := make([]byte, 12)
+	rand.Read(id)
+	now := time.Now()
+	session := &ChatSession{
+		ID:        "chat_" + hex.EncodeToString(id),
+		Owner:     owner,
+		Messages:  make([]ChatMessage, 0),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	cs.sessions[session.ID] = session
+	return session
+}
+
+// expire drops idle sessions. The caller holds cs.mu.
+func (cs *ChatService) expire() {
+	cutoff := time.Now().Add(-chatSessionTTL)
+	for id, session := range cs.sessions {
+		if session.UpdatedAt.Before(cutoff) {
+			delete(cs.sessions, id)
+		}
+	}
+}
+
+// buildContext describes the account, positions, open orders, the recent
+// analyses and signals, favouring the symbols in the conversation, and the
+// indexed items most like the question. Parts that fail to load are noted
+// and skipped.
+func (cs *ChatService) buildContext(ctx context.Context, symbols []string, question string) string {
+	var b strings.Builder
+	now := time.Now()
+	fmt.Fprintf(&b, "CURRENT TIME: %s\n\n", now.Format("Mon 2006-01-02 15:04 MST"))
+
+	b.WriteString("ACCOUNT:\n")
+	if account, err := cs.tradingService.GetAccount(ctx); err != nil {
+		b.WriteString("(unavailable)\n")
+	} else {
+		fmt.Fprintf(&b, "Portfolio value $%s | Cash $%s | Buying power $%s\n",
+			account.PortfolioValue.StringFixed(2), account.Cash.StringFixed(2), account.BuyingPower.StringFixed(2))
+	}
+
+	b.WriteString("\nPOSITIONS:\n")
+	if positions, err := cs.tradingService.GetPositions(ctx); err != nil {
+		b.WriteString("(unavailable)\n")
+	} else if len(positions) == 0 {
+		b.WriteString("(none)\n")
+	} else {
+		for _, p := range positions {
+			fmt.Fprintf(&b, "- %s %s %s @ $%s avg, now $%s, unrealized $%s (%s%%)\n",
+				p.Symbol, p.Side, p.Qty.String(), p.AvgEntryPrice.StringFixed(2), p.CurrentPrice.StringFixed(2),
+				p.UnrealizedPL.StringFixed(2), p.UnrealizedPLPC.Shift(2).StringFixed(1))
+		}
+	}
+
+	b.WriteString("\nOPEN ORDERS:\n")
+	if orders, err := cs.tradingService.ListOrders(ctx, "open"); err != nil {
+		b.WriteString("(unavailable)\n")
+	} else if len(orders) == 0 {
+		b.WriteString("(none)\n")
+	} else {
+		for _, o := range orders {
+			fmt.Fprintf(&b, "- %s %s %s %s", o.Side, o.Qty.String(), o.Symbol, o.Type)
+			if o.LimitPrice != nil {
+				fmt.Fprintf(&b, " limit $%s", o.LimitPrice.StringFixed(2))
+			}
+			if o.StopPrice != nil {
+				fmt.Fprintf(&b, " stop $%s", o.StopPrice.StringFixed(2))
+			}
+			fmt.Fprintf(&b, " (%s, submitted %s)\n", o.Status, o.SubmittedAt.Format("2006-01-02 15:04"))
+		}
+	}
+
+	since := now.Add(-chatHistoryWindow)
+
+	b.WriteString("\nRECENT ANALYSES (past 7 days, newest first):\n")
+	seen := make(map[uint]bool)
+	count := 0
+	writeAnalyses := func(symbol string, limit int) {
+		analyses, err := cs.storageService.GetStockAnalyses(symbol, since, limit)
+		if err != nil {
+			cs.logger.WithError(err).Warn("Failed to load analyses for chat")
+			return
+		}
+		for _, stored := range analyses {
+			if seen[stored.ID] {
+				continue
+			}
+			seen[stored.ID] = true
+			var analysis StockAnalysis
+			if err := json.Unmarshal([]byte(stored.Analysis), &analysis); err != nil {
+				continue
+			}
+			setup := analysis.TradeSetup
+			fmt.Fprintf(&b, "- %s %s (profile %s): price $%.2f, composite %d/10 (technical %d, catalyst %d, volume %d), trend %s, RSI %.0f\n",
+				stored.CreatedAt.Format("2006-01-02 15:04"), stored.Symbol, stored.Profile, analysis.CurrentPrice,
+				setup.CompositeScore, setup.TechnicalScore, setup.CatalystScore, setup.VolumeScore,
+				analysis.Technical.Trend, analysis.Technical.RSI)
+			fmt.Fprintf(&b, "  Notes: %s\n", clipText(setup.Notes, chatContextNotesLength))
+			if analysis.NewsSummary != "" {
+				fmt.Fprintf(&b, "  News: %s\n", clipText(analysis.NewsSummary, chatContextNotesLength))
+			}
+			count++
+		}
+	}
+	for _, symbol := range symbols {
+		writeAnalyses(symbol, chatAnalysesPerSymbol)
+	}
+	writeAnalyses("", chatRecentAnalyses)
+	if count == 0 {
+		b.WriteString("(none)\n")
+	}
+
+	b.WriteString("\nRECENT STRATEGY SIGNALS (past 7 days, newest first):\n")
+	if signals, err := cs.storageService.GetSignals(since, chatRecentSignals); err != nil {
+		b.WriteString("(unavailable)\n")
+	} else if len(signals) == 0 {
+		b.WriteString("(none)\n")
+	} else {
+		for _, s := range signals {
+			fmt.Fprintf(&b, "- %s %s %s %s (strength %.2f): %s\n",
+				s.CreatedAt.Format("2006-01-02 15:04"), s.StrategyName, s.SignalType, s.Symbol, s.Strength, clipText(s.Reason, chatContextNotesLength))
+		}
+	}
+
+	if cs.semanticIndex != nil {
+		b.WriteString("\nRELATED NEWS AND ANALYSES (most similar to the question):\n")
+		if related, err := cs.semanticIndex.Search(ctx, SemanticQuery{Text: question, Limit: chatRelatedItems}); err != nil {
+			b.WriteString("(unavailable)\n")
+		} else if len(related) == 0 {
+			b.WriteString("(none)\n")
+		} else {
+			b.WriteString(formatRelatedHistory(related))
+		}
+	}
+
+	return b.String()
+}
+
+// clipText shortens text to at most n bytes, marking the cut
+func clipText(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	return strings.ToValidUTF8(text[:n], "") + "..."
+}
@@ -0,0 +1,571 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Conditional order conditions, evaluated on the trigger symbol's price
+const (
+	ConditionPriceAbove     = "price_above"      // price rises to Threshold or above
+	ConditionPriceBelow     = "price_below"      // price falls to Threshold or below
+	ConditionDayChangeAbove = "day_change_above" // change from the previous close rises to Threshold% or above
+	ConditionDayChangeBelow = "day_change_below" // change from the previous close falls to Threshold% or below, e.g. -1
+)
+
+// Conditional order statuses
+const (
+	ConditionalActive    = "ACTIVE"
+	ConditionalTriggered = "TRIGGERED" // the order was submitted
+	ConditionalFailed    = "FAILED"    // the order was held back by the risk checks or refused by the broker
+	ConditionalCanceled  = "CANCELED"
+	ConditionalExpired   = "EXPIRED"
+)
+
+// ConditionalOrderRequest is the payload for creating a conditional order
+type ConditionalOrderRequest struct {
+	Symbol        string           `json:"symbol" binding:"required,symbol"`
+	Side          string           `json:"side" binding:"required,oneof=buy sell"`
+	Qty           decimal.Decimal  `json:"qty" binding:"gt=0"`
+	Type          string           `json:"type" binding:"omitempty,oneof=market limit"`
+	TimeInForce   string           `json:"time_in_force" binding:"omitempty,oneof=day gtc ioc fok"`
+	LimitPrice    *decimal.Decimal `json:"limit_price,omitempty" binding:"required_if=Type limit,omitempty,gt=0"`
+	Condition     string           `json:"condition" binding:"required,oneof=price_above price_below day_change_above day_change_below"`
+	Threshold     float64          `json:"threshold"`                                           // a price, or a percent change from the previous close
+	TriggerSymbol string           `json:"trigger_symbol,omitempty" binding:"omitempty,symbol"` // symbol whose price is watched, the order's own by default
+	ExpiresAt     *time.Time       `json:"expires_at,omitempty"`
+	Strategy      string           `json:"strategy,omitempty" binding:"omitempty,max=64"` // P&L attribution tag, defaults to conditional
+	Note          string           `json:"note,omitempty" binding:"max=256"`
+}
+
+// ConditionalOrder is an order waiting for its condition
+type ConditionalOrder struct {
+	ID            string           `json:"id"`
+	Symbol        string           `json:"symbol"`
+	Side          string           `json:"side"`
+	Qty           decimal.Decimal  `json:"qty"`
+	Type          string           `json:"type"`
+	LimitPrice    *decimal.Decimal `json:"limit_price,omitempty"`
+	TimeInForce   string           `json:"time_in_force"`
+	Strategy      string           `json:"strategy"`
+	Book          string           `json:"book"`
+	TriggerSymbol string           `json:"trigger_symbol"`
+	Condition     string           `json:"condition"`
+	Threshold     float64          `json:"threshold"`
+	Armed         bool             `json:"armed"`      // the condition has been seen false, so it can fire
+	LastValue     float64          `json:"last_value"` // the latest price or change seen
+	Status        string           `json:"status"`
+	Reason        string           `json:"reason,omitempty"`
+	ExpiresAt     *time.Time       `json:"expires_at,omitempty"`
+	ExpiresIn     *int64           `json:"expires_in_seconds,omitempty"` // remaining lifetime while active
+	TriggeredAt   *time.Time       `json:"triggered_at,omitempty"`
+	TriggerValue  float64          `json:"trigger_value,omitempty"`
+	OrderID       string           `json:"order_id,omitempty"`
+	Note          string           `json:"note,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// previousCloseCache is a symbol's previous close for one trading date
+type previousCloseCache struct {
+	date  string
+	close float64
+}
+
+// ConditionalOrderService holds orders locally until a price condition is
+// met on the stream hub's ticks, covering triggers the broker can't
+// express, such as one symbol's order on another symbol's price or on the
+// day's change. A condition only fires after it has been seen false, so an
+// order created while its condition already holds waits for it to happen
+// again. Triggered orders go through the risk checks before they are
+// submitted.
+type ConditionalOrderService struct {
+	hub         *StreamHub
+	trading     interfaces.TradingService
+	dataService interfaces.DataService
+	storage     *database.LocalStorage
+	riskManager *RiskManager
+	notifier    *Notifier
+	dryRun      bool
+
+	mu         sync.Mutex
+	orders     map[string]*models.DBConditionalOrder // active orders
+	triggering map[string]bool                       // active orders being risk-checked and submitted
+	prevCloses map[string]previousCloseCache
+	logger     *logrus.Logger
+}
+
+// NewConditionalOrderService creates a conditional order service and
+// starts watching the active orders stored. In dry-run mode a triggered
+// order is logged instead of submitted.
+func NewConditionalOrderService(hub *StreamHub, trading interfaces.TradingService, dataService interfaces.DataService, storage *database.LocalStorage, riskManager *RiskManager, notifier *Notifier, dryRun bool) *ConditionalOrderService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	s := &ConditionalOrderService{
+		hub:         hub,
+		trading:     trading,
+		dataService: dataService,
+		storage:     storage,
+		riskManager: riskManager,
+		notifier:    notifier,
+		dryRun:      dryRun,
+		orders:      make(map[string]*models.DBConditionalOrder),
+		triggering:  make(map[string]bool),
+		prevCloses:  make(map[string]previousCloseCache),
+		logger:      logger,
+	}
+
+	active, err := storage.GetConditionalOrders(ConditionalActive)
+	if err != nil {
+		logger.WithError(err).Error("Failed to load conditional orders")
+	}
+	for _, order := range active {
+		s.orders[order.ConditionalID] = order
+		hub.Track(order.TriggerSymbol)
+	}
+	return s
+}
+
+// Create validates and stores a conditional order and starts watching its
+// trigger symbol
+func (s *ConditionalOrderService) Create(ctx context.Context, req *ConditionalOrderRequest) (*ConditionalOrder, error) {
+	symbol := strings.ToUpper(req.Symbol)
+	trigger := strings.ToUpper(req.TriggerSymbol)
+	if trigger == "" {
+		trigger = symbol
+	}
+	if _, err := ParseOCCSymbol(symbol); err == nil {
+		return nil, WithErrorCode(ErrCodeNotSupported, fmt.Errorf("conditional orders trade stocks, ETFs and crypto, not options"))
+	}
+	if _, err := ParseOCCSymbol(trigger); err == nil {
+		return nil, WithErrorCode(ErrCodeNotSupported, fmt.Errorf("conditional orders can't watch options prices"))
+	}
+	switch req.Condition {
+	case ConditionPriceAbove, ConditionPriceBelow:
+		if req.Threshold <= 0 {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("threshold must be a positive price"))
+		}
+	case ConditionDayChangeAbove, ConditionDayChangeBelow:
+		if req.Threshold == 0 {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("threshold must be a nonzero percent change, e.g. -1 for down 1%%"))
+		}
+	}
+	if err := ValidateExpiry(req.ExpiresAt, nil); err != nil {
+		return nil, err
+	}
+
+	orderType := req.Type
+	if orderType == "" {
+		orderType = "market"
+	}
+	timeInForce := req.TimeInForce
+	if timeInForce == "" {
+		timeInForce = "day"
+	}
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = OrderSourceConditional
+	}
+
+	order := &models.DBConditionalOrder{
+		ConditionalID: fmt.Sprintf("cond_%d", time.Now().UnixNano()),
+		Symbol:        symbol,
+		Side:          req.Side,
+		Qty:           req.Qty,
+		Type:          orderType,
+		LimitPrice:    req.LimitPrice,
+		TimeInForce:   timeInForce,
+		Strategy:      strategy,
+		Book:          BookFrom(ctx),
+		TriggerSymbol: trigger,
+		Condition:     req.Condition,
+		Threshold:     req.Threshold,
+		Status:        ConditionalActive,
+		ExpiresAt:     req.ExpiresAt,
+		Note:          req.Note,
+	}
+	if orderType == "market" {
+		order.LimitPrice = nil
+	}
+	if err := s.storage.SaveConditionalOrder(order); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.orders[order.ConditionalID] = order
+	s.mu.Unlock()
+	s.hub.Track(trigger)
+
+	s.logger.WithFields(logrus.Fields{
+		"id":        order.ConditionalID,
+		"symbol":    order.Symbol,
+		"side":      order.Side,
+		"qty":       order.Qty.String(),
+		"trigger":   trigger,
+		"condition": order.Condition,
+		"threshold": order.Threshold,
+	}).Info("Conditional order created")
+
+	return conditionalOrderFromDB(order), nil
+}
+
+// List returns conditional orders with an optional status filter, newest
+// first
+func (s *ConditionalOrderService) List(status string) ([]*ConditionalOrder, error) {
+	dbOrders, err := s.storage.GetConditionalOrders(status)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := make([]*ConditionalOrder, len(dbOrders))
+	for i, dbOrder := range dbOrders {
+		// Active orders carry their latest value in memory
+		if active, ok := s.orders[dbOrder.ConditionalID]; ok {
+			dbOrder = active
+		}
+		orders[i] = conditionalOrderFromDB(dbOrder)
+	}
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+	return orders, nil
+}
+
+// Get returns a conditional order by ID
+func (s *ConditionalOrderService) Get(id string) (*ConditionalOrder, error) {
+	orders, err := s.List("")
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		if order.ID == id {
+			return order, nil
+		}
+	}
+	return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("conditional order not found: %s", id))
+}
+
+// Cancel stops watching an active conditional order
+func (s *ConditionalOrderService) Cancel(id string) (*ConditionalOrder, error) {
+	s.mu.Lock()
+	order, ok := s.orders[id]
+	if !ok {
+		s.mu.Unlock()
+		if _, err := s.Get(id); err != nil {
+			return nil, err
+		}
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("conditional order %s is no longer active", id))
+	}
+	if s.triggering[id] {
+		s.mu.Unlock()
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("conditional order %s has triggered and is being submitted", id))
+	}
+	s.finish(order, ConditionalCanceled, "")
+	copied := conditionalOrderFromDB(order)
+	s.mu.Unlock()
+
+	s.logger.WithField("id", id).Info("Conditional order canceled")
+	return copied, nil
+}
+
+// Run evaluates conditional orders on every hub tick, and expires them
+// once a minute, until ctx is cancelled
+func (s *ConditionalOrderService) Run(ctx context.Context) {
+	id, ticks := s.hub.Subscribe(100)
+	defer s.hub.Unsubscribe(id)
+
+	expiry := time.NewTicker(time.Minute)
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tick, ok := <-ticks:
+			if !ok {
+				return
+			}
+			s.evaluateTick(ctx, tick)
+		case <-expiry.C:
+			s.expire(ctx)
+		}
+	}
+}
+
+// evaluateTick checks every active order watching the tick's symbol and
+// submits the ones whose condition fired
+func (s *ConditionalOrderService) evaluateTick(ctx context.Context, tick *MarketTick) {
+	if tick.Price <= 0 {
+		return
+	}
+	now := time.Now()
+
+	// The previous close may need a network round trip, so it is fetched
+	// before the lock is taken
+	s.mu.Lock()
+	needsClose := false
+	for _, order := range s.orders {
+		if order.TriggerSymbol == tick.Symbol && (order.Condition == ConditionDayChangeAbove || order.Condition == ConditionDayChangeBelow) {
+			needsClose = true
+			break
+		}
+	}
+	s.mu.Unlock()
+	var prevClose float64
+	var prevCloseErr error
+	if needsClose {
+		prevClose, prevCloseErr = s.previousClose(ctx, tick.Symbol, now)
+	}
+
+	type firing struct {
+		order models.DBConditionalOrder
+		value float64
+	}
+	fired := make([]firing, 0)
+
+	s.mu.Lock()
+	for _, order := range s.orders {
+		if order.TriggerSymbol != tick.Symbol || s.triggering[order.ConditionalID] {
+			continue
+		}
+		if order.ExpiresAt != nil && !now.Before(*order.ExpiresAt) {
+			s.expireOrder(ctx, order)
+			continue
+		}
+
+		value := tick.Price
+		if order.Condition == ConditionDayChangeAbove || order.Condition == ConditionDayChangeBelow {
+			if prevCloseErr != nil {
+				s.logger.WithError(prevCloseErr).WithField("symbol", tick.Symbol).Debug("Previous close unavailable for conditional order")
+				continue
+			}
+			value = (tick.Price/prevClose - 1) * 100
+		}
+		order.LastValue = value
+
+		met := false
+		switch order.Condition {
+		case ConditionPriceAbove, ConditionDayChangeAbove:
+			met = value >= order.Threshold
+		case ConditionPriceBelow, ConditionDayChangeBelow:
+			met = value <= order.Threshold
+		}
+
+		if !met {
+			if !order.Armed {
+				order.Armed = true
+				s.save(order)
+			}
+			continue
+		}
+		if order.Armed {
+			// Marked so later ticks, expiry and Cancel leave it alone
+			// while it is submitted without the lock
+			s.triggering[order.ConditionalID] = true
+			fired = append(fired, firing{order: *order, value: value})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, f := range fired {
+		s.trigger(ctx, &f.order, f.value, tick.Price)
+	}
+}
+
+// trigger risk-checks and submits an order whose condition fired. It runs
+// without s.mu on a copy of the order, which is marked triggering, and
+// records the outcome under the lock.
+func (s *ConditionalOrderService) trigger(ctx context.Context, conditional *models.DBConditionalOrder, value, price float64) {
+	ctx = WithBook(WithOrderSource(ctx, OrderSourceConditional), bookOrDefault(conditional.Book))
+	logger := s.logger.WithFields(logrus.Fields{
+		"id":        conditional.ConditionalID,
+		"symbol":    conditional.Symbol,
+		"trigger":   conditional.TriggerSymbol,
+		"condition": conditional.Condition,
+		"value":     value,
+	})
+
+	now := time.Now()
+	description := fmt.Sprintf("%s %s %s: %s %s %g (now %.2f)", conditional.Side, conditional.Qty.String(), conditional.Symbol,
+		conditional.TriggerSymbol, conditional.Condition, conditional.Threshold, price)
+
+	order := &interfaces.Order{
+		Symbol:      conditional.Symbol,
+		Qty:         conditional.Qty,
+		Side:        conditional.Side,
+		Type:        conditional.Type,
+		TimeInForce: conditional.TimeInForce,
+		LimitPrice:  conditional.LimitPrice,
+		Status:      "pending",
+		SubmittedAt: now,
+		Strategy:    conditional.Strategy,
+		Book:        BookFrom(ctx),
+	}
+
+	// record ends the order with the outcome of the trigger
+	record := func(status, reason, orderID string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.triggering, conditional.ConditionalID)
+		active, ok := s.orders[conditional.ConditionalID]
+		if !ok {
+			return
+		}
+		active.TriggeredAt = &now
+		active.TriggerValue = value
+		active.OrderID = orderID
+		s.finish(active, status, reason)
+	}
+	fail := func(reason string) {
+		logger.WithField("reason", reason).Warn("Conditional order failed")
+		record(ConditionalFailed, reason, "")
+		s.notifier.Notify(ctx, NotifyWarning, "Conditional order failed", description+": "+reason,
+			logrus.Fields{"id": conditional.ConditionalID, "symbol": conditional.Symbol})
+	}
+
+	decision, err := s.riskManager.Evaluate(ctx, order)
+	if err != nil {
+		fail(fmt.Sprintf("risk checks failed to run: %v", err))
+		return
+	}
+	if !decision.Approved {
+		fail((&RiskRejectedError{Decision: decision}).Error())
+		return
+	}
+
+	if s.dryRun {
+		logger.Info("Dry run: conditional order not submitted")
+		record(ConditionalTriggered, "dry run, order not submitted", "")
+		return
+	}
+
+	result, err := s.trading.PlaceOrder(WithRiskDecision(ctx, decision), order)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+
+	order.ID = result.OrderID
+	order.Status = result.Status
+	if err := s.storage.SaveOrder(order); err != nil {
+		logger.WithError(err).Warn("Failed to save order to database")
+	}
+	record(ConditionalTriggered, "", result.OrderID)
+
+	logger.WithField("order_id", result.OrderID).Info("Conditional order triggered")
+	s.notifier.Notify(ctx, NotifyInfo, "Conditional order triggered", description,
+		logrus.Fields{"id": conditional.ConditionalID, "symbol": conditional.Symbol, "order_id": result.OrderID})
+}
+
+// expire ends the orders past their good-till-date whose trigger symbol
+// hasn't ticked since
+func (s *ConditionalOrderService) expire(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, order := range s.orders {
+		if order.ExpiresAt != nil && !now.Before(*order.ExpiresAt) && !s.triggering[order.ConditionalID] {
+			s.expireOrder(ctx, order)
+		}
+	}
+}
+
+// expireOrder ends an order that reached its good-till-date untriggered.
+// The caller holds s.mu.
+func (s *ConditionalOrderService) expireOrder(ctx context.Context, order *models.DBConditionalOrder) {
+	s.finish(order, ConditionalExpired, "")
+
+	fields := logrus.Fields{"id": order.ConditionalID, "symbol": order.Symbol}
+	s.logger.WithFields(fields).Info("Conditional order expired")
+	s.notifier.Notify(ctx, NotifyInfo, "Conditional order expired",
+		fmt.Sprintf("%s %s %s expired at %s without %s %s %g", order.Side, order.Qty.String(), order.Symbol,
+			order.ExpiresAt.Format(time.RFC3339), order.TriggerSymbol, order.Condition, order.Threshold), fields)
+}
+
+// finish ends an active order and stops watching its trigger symbol. The
+// caller holds s.mu.
+func (s *ConditionalOrderService) finish(order *models.DBConditionalOrder, status, reason string) {
+	order.Status = status
+	order.Reason = reason
+	s.save(order)
+	delete(s.orders, order.ConditionalID)
+	s.hub.Untrack(order.TriggerSymbol)
+}
+
+func (s *ConditionalOrderService) save(order *models.DBConditionalOrder) {
+	if err := s.storage.SaveConditionalOrder(order); err != nil {
+		s.logger.WithError(err).WithField("id", order.ConditionalID).Error("Failed to save conditional order")
+	}
+}
+
+// previousClose returns symbol's last daily close before today in New
+// York, fetched once a day. The caller must not hold s.mu.
+func (s *ConditionalOrderService) previousClose(ctx context.Context, symbol string, now time.Time) (float64, error) {
+	today := now.In(marketLocation).Format(marketDateFormat)
+	s.mu.Lock()
+	cached, ok := s.prevCloses[symbol]
+	s.mu.Unlock()
+	if ok && cached.date == today {
+		return cached.close, nil
+	}
+
+	bars, err := s.dataService.GetHistoricalBars(ctx, symbol, now.AddDate(0, 0, -10), now, "1Day")
+	if err != nil {
+		return 0, err
+	}
+	for i := len(bars) - 1; i >= 0; i-- {
+		if bars[i].Timestamp.In(marketLocation).Format(marketDateFormat) < today && bars[i].Close > 0 {
+			s.mu.Lock()
+			s.prevCloses[symbol] = previousCloseCache{date: today, close: bars[i].Close}
+			s.mu.Unlock()
+			return bars[i].Close, nil
+		}
+	}
+	return 0, fmt.Errorf("no previous close for %s", symbol)
+}
+
+func conditionalOrderFromDB(order *models.DBConditionalOrder) *ConditionalOrder {
+	conditional := &ConditionalOrder{
+		ID:            order.ConditionalID,
+		Symbol:        order.Symbol,
+		Side:          order.Side,
+		Qty:           order.Qty,
+		Type:          order.Type,
+		LimitPrice:    order.LimitPrice,
+		TimeInForce:   order.TimeInForce,
+		Strategy:      order.Strategy,
+		Book:          bookOrDefault(order.Book),
+		TriggerSymbol: order.TriggerSymbol,
+		Condition:     order.Condition,
+		Threshold:     order.Threshold,
+		Armed:         order.Armed,
+		LastValue:     order.LastValue,
+		Status:        order.Status,
+		Reason:        order.Reason,
+		ExpiresAt:     order.ExpiresAt,
+		TriggeredAt:   order.TriggeredAt,
+		TriggerValue:  order.TriggerValue,
+		OrderID:       order.OrderID,
+		Note:          order.Note,
+		CreatedAt:     order.CreatedAt,
+	}
+	if order.Status == ConditionalActive {
+		conditional.ExpiresIn = RemainingLifetime(order.ExpiresAt)
+	}
+	return conditional
+}
@@ -0,0 +1,447 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxCoveredCallEvents is how many assignments and expirations are kept
+const maxCoveredCallEvents = 100
+
+// Outcomes of a short call that left the book
+const (
+	CoveredCallAssigned    = "assigned"    // shares were called away at the strike
+	CoveredCallExpired     = "expired"     // expired worthless, premium kept
+	CoveredCallUnexplained = "unexplained" // gone without a buy-back, delivery or expiry
+)
+
+// CoveredCall is a short call paired with the long shares that cover it.
+// Prices are per share; P&L is in dollars for the covered contracts.
+type CoveredCall struct {
+	Underlying   string    `json:"underlying"`
+	CallSymbol   string    `json:"call_symbol"`
+	Strike       float64   `json:"strike"`
+	Expiration   time.Time `json:"expiration"`
+	Contracts    float64   `json:"contracts"` // covered contracts
+	Shares       float64   `json:"shares"`    // shares pledged, contracts × 100
+	ShareCost    float64   `json:"share_cost"`
+	SharePrice   float64   `json:"share_price"`
+	Premium      float64   `json:"premium"` // received at the open
+	CallPrice    float64   `json:"call_price"`
+	SharePnL     float64   `json:"share_pnl"`
+	CallPnL      float64   `json:"call_pnl"`
+	CombinedPnL  float64   `json:"combined_pnl"`
+	MaxProfit    float64   `json:"max_profit"` // if called away at the strike
+	InTheMoney   bool      `json:"in_the_money"`
+	DaysToExpiry int       `json:"days_to_expiry"`
+}
+
+// CoveredCallEvent records a covered call that was assigned, expired or
+// disappeared. For assignments, SharePnL is the shares' gain at the strike
+// and PremiumPnL the premium kept on the assigned contracts.
+type CoveredCallEvent struct {
+	Type             string    `json:"type"`
+	Underlying       string    `json:"underlying"`
+	CallSymbol       string    `json:"call_symbol"`
+	Strike           float64   `json:"strike"`
+	Expiration       time.Time `json:"expiration"`
+	Contracts        float64   `json:"contracts"`
+	SharesDelivered  float64   `json:"shares_delivered,omitempty"`
+	ShareCost        float64   `json:"share_cost"`
+	Premium          float64   `json:"premium"`
+	SharePnL         float64   `json:"share_pnl"`
+	PremiumPnL       float64   `json:"premium_pnl"`
+	CombinedPnL      float64   `json:"combined_pnl"`
+	ManagedPositions []string  `json:"managed_positions,omitempty"` // settled at the strike
+	DetectedAt       time.Time `json:"detected_at"`
+	Message          string    `json:"message"`
+}
+
+// CoveredCallMonitor pairs short calls with the shares covering them and
+// notices when a call leaves the book. Each pass compares the broker
+// positions with the previous pass: a call that went without being bought
+// back, while the shares it covered went too, was assigned. The managed
+// positions on those shares are settled at the strike so they don't stay
+// open against shares that are gone. The first pass after a start only
+// records the pairs, so an assignment while the bot was down is left to the
+// reconciler.
+type CoveredCallMonitor struct {
+	tradingService  interfaces.TradingService
+	positionManager *PositionManager
+	notifier        *Notifier
+	location        *time.Location
+	logger          *logrus.Logger
+
+	mu        sync.RWMutex
+	tracked   map[string]*CoveredCall
+	shares    map[string]float64
+	lastCheck time.Time
+	events    []CoveredCallEvent
+}
+
+// NewCoveredCallMonitor creates a new covered call monitor
+func NewCoveredCallMonitor(tradingService interfaces.TradingService, positionManager *PositionManager, notifier *Notifier) *CoveredCallMonitor {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.UTC
+	}
+
+	return &CoveredCallMonitor{
+		tradingService:  tradingService,
+		positionManager: positionManager,
+		notifier:        notifier,
+		location:        loc,
+		logger:          logger,
+		events:          make([]CoveredCallEvent, 0),
+	}
+}
+
+// Pairs returns the covered calls held now
+func (m *CoveredCallMonitor) Pairs(ctx context.Context) ([]*CoveredCall, error) {
+	positions, err := m.tradingService.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+	pairs, _, _ := m.pair(positions)
+	return pairs, nil
+}
+
+// Events returns recent assignments and expirations, newest first
+func (m *CoveredCallMonitor) Events() []CoveredCallEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := make([]CoveredCallEvent, len(m.events))
+	for i, event := range m.events {
+		events[len(m.events)-1-i] = event
+	}
+	return events
+}
+
+// Check runs one pass: it pairs the current positions and accounts for
+// every covered call that left the book since the last pass
+func (m *CoveredCallMonitor) Check(ctx context.Context) error {
+	started := time.Now()
+	positions, err := m.tradingService.GetPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get positions: %w", err)
+	}
+	pairs, shares, shortCalls := m.pair(positions)
+
+	m.mu.RLock()
+	tracked, previousShares, lastCheck := m.tracked, m.shares, m.lastCheck
+	m.mu.RUnlock()
+
+	if tracked != nil {
+		var fills []*interfaces.Order
+		fillsLoaded := false
+		for _, previous := range tracked {
+			gone := previous.Contracts - shortCalls[previous.CallSymbol]
+			if gone <= 0 {
+				continue
+			}
+			if !fillsLoaded {
+				if fills, err = m.tradingService.ListOrders(ctx, "closed"); err != nil {
+					return fmt.Errorf("failed to get closed orders: %w", err)
+				}
+				fillsLoaded = true
+			}
+
+			// Calls bought back and shares sold by orders aren't assignments
+			gone -= filledSince(fills, previous.CallSymbol, "buy", lastCheck)
+			if gone <= 0 {
+				continue
+			}
+			delivered := previousShares[previous.Underlying] - shares[previous.Underlying] - filledSince(fills, previous.Underlying, "sell", lastCheck)
+			delivered = math.Min(math.Floor(delivered/100)*100, gone*100)
+
+			// Shares already explained by this pass are not delivered twice
+			if delivered > 0 {
+				previousShares[previous.Underlying] -= delivered
+			}
+			m.record(ctx, m.outcome(ctx, previous, gone, delivered, started))
+		}
+	}
+
+	current := make(map[string]*CoveredCall, len(pairs))
+	for _, pair := range pairs {
+		current[pair.CallSymbol] = pair
+	}
+	m.mu.Lock()
+	m.tracked = current
+	m.shares = shares
+	m.lastCheck = started
+	m.mu.Unlock()
+
+	return nil
+}
+
+// outcome works out what happened to gone contracts of a covered call, and
+// settles the managed positions on delivered shares
+func (m *CoveredCallMonitor) outcome(ctx context.Context, previous *CoveredCall, gone, delivered float64, now time.Time) CoveredCallEvent {
+	event := CoveredCallEvent{
+		Underlying: previous.Underlying,
+		CallSymbol: previous.CallSymbol,
+		Strike:     previous.Strike,
+		Expiration: previous.Expiration,
+		Contracts:  gone,
+		ShareCost:  previous.ShareCost,
+		Premium:    previous.Premium,
+		DetectedAt: now,
+	}
+	name := fmt.Sprintf("%s %s %g call", previous.Underlying, previous.Expiration.Format("2006-01-02"), previous.Strike)
+
+	switch {
+	case delivered > 0:
+		event.Type = CoveredCallAssigned
+		event.Contracts = delivered / 100
+		event.SharesDelivered = delivered
+		event.SharePnL = (previous.Strike - previous.ShareCost) * delivered
+		event.PremiumPnL = previous.Premium * delivered
+		event.ManagedPositions = m.positionManager.settleAssignment(ctx, previous.Underlying, delivered, previous.Strike, "shares called away by "+previous.CallSymbol)
+		event.Message = fmt.Sprintf("%s assigned: %g shares delivered at $%.2f", name, delivered, previous.Strike)
+	case !now.Before(m.expiryClose(previous.Expiration)):
+		event.Type = CoveredCallExpired
+		event.PremiumPnL = previous.Premium * gone * 100
+		event.Message = fmt.Sprintf("%s expired worthless: $%.2f premium kept", name, event.PremiumPnL)
+	default:
+		event.Type = CoveredCallUnexplained
+		event.Message = fmt.Sprintf("%s left the book without a buy-back, share delivery or expiry", name)
+	}
+	event.CombinedPnL = event.SharePnL + event.PremiumPnL
+	if event.Type == CoveredCallAssigned {
+		event.Message += fmt.Sprintf(", combined P&L $%.2f", event.CombinedPnL)
+	}
+	return event
+}
+
+// record keeps an event and tells the user about it
+func (m *CoveredCallMonitor) record(ctx context.Context, event CoveredCallEvent) {
+	m.mu.Lock()
+	m.events = append(m.events, event)
+	if len(m.events) > maxCoveredCallEvents {
+		m.events = m.events[len(m.events)-maxCoveredCallEvents:]
+	}
+	m.mu.Unlock()
+
+	m.logger.WithFields(logrus.Fields{
+		"type":         event.Type,
+		"call":         event.CallSymbol,
+		"contracts":    event.Contracts,
+		"combined_pnl": event.CombinedPnL,
+	}).Info("Covered call left the book")
+
+	level, title := NotifyInfo, "Covered call expired"
+	switch event.Type {
+	case CoveredCallAssigned:
+		level, title = NotifyWarning, "Covered call assigned"
+	case CoveredCallUnexplained:
+		level, title = NotifyWarning, "Covered call disappeared"
+	}
+	m.notifier.Notify(ctx, level, title, event.Message, map[string]interface{}{
+		"symbol":            event.Underlying,
+		"call":              event.CallSymbol,
+		"contracts":         event.Contracts,
+		"shares_delivered":  event.SharesDelivered,
+		"combined_pnl":      event.CombinedPnL,
+		"managed_positions": strings.Join(event.ManagedPositions, ","),
+	})
+}
+
+// pair matches short calls with long shares of their underlying, nearest
+// expiration first. It also returns the long shares per symbol and the
+// short contracts per call.
+func (m *CoveredCallMonitor) pair(positions []*interfaces.Position) ([]*CoveredCall, map[string]float64, map[string]float64) {
+	shares := make(map[string]float64)
+	stocks := make(map[string]*interfaces.Position)
+	shortCalls := make(map[string]float64)
+	calls := make([]*CoveredCall, 0)
+	callPositions := make(map[string]*interfaces.Position)
+
+	for _, p := range positions {
+		qty := p.Qty.Abs().InexactFloat64()
+		short := p.Side == "short" || p.Qty.IsNegative()
+		occ, err := ParseOCCSymbol(p.Symbol)
+		switch {
+		case err != nil:
+			if !short {
+				shares[p.Symbol] = qty
+				stocks[p.Symbol] = p
+			}
+		case short && occ.Type == "call":
+			shortCalls[p.Symbol] = qty
+			callPositions[p.Symbol] = p
+			calls = append(calls, &CoveredCall{
+				Underlying: occ.Underlying,
+				CallSymbol: p.Symbol,
+				Strike:     occ.Strike,
+				Expiration: occ.Expiration,
+			})
+		}
+	}
+
+	sort.Slice(calls, func(i, j int) bool {
+		if !calls[i].Expiration.Equal(calls[j].Expiration) {
+			return calls[i].Expiration.Before(calls[j].Expiration)
+		}
+		return calls[i].CallSymbol < calls[j].CallSymbol
+	})
+
+	free := make(map[string]float64, len(shares))
+	for symbol, qty := range shares {
+		free[symbol] = qty
+	}
+	now := time.Now()
+	pairs := make([]*CoveredCall, 0)
+	for _, call := range calls {
+		call.Contracts = math.Min(shortCalls[call.CallSymbol], math.Floor(free[call.Underlying]/100))
+		if call.Contracts <= 0 {
+			continue
+		}
+		call.Shares = call.Contracts * 100
+		free[call.Underlying] -= call.Shares
+
+		stock, option := stocks[call.Underlying], callPositions[call.CallSymbol]
+		call.ShareCost = stock.AvgEntryPrice.InexactFloat64()
+		call.SharePrice = stock.CurrentPrice.InexactFloat64()
+		call.Premium = option.AvgEntryPrice.InexactFloat64()
+		call.CallPrice = option.CurrentPrice.InexactFloat64()
+		call.SharePnL = (call.SharePrice - call.ShareCost) * call.Shares
+		call.CallPnL = (call.Premium - call.CallPrice) * call.Shares
+		call.CombinedPnL = call.SharePnL + call.CallPnL
+		call.MaxProfit = (call.Strike - call.ShareCost + call.Premium) * call.Shares
+		call.InTheMoney = call.SharePrice > call.Strike
+		call.DaysToExpiry = int(math.Ceil(m.expiryClose(call.Expiration).Sub(now).Hours() / 24))
+		pairs = append(pairs, call)
+	}
+
+	return pairs, shares, shortCalls
+}
+
+// expiryClose is the 4pm ET close on an expiration date
+func (m *CoveredCallMonitor) expiryClose(expiration time.Time) time.Time {
+	return time.Date(expiration.Year(), expiration.Month(), expiration.Day(), 16, 0, 0, 0, m.location)
+}
+
+// filledSince sums the filled quantity of orders on symbol and side that
+// filled after since
+func filledSince(orders []*interfaces.Order, symbol, side string, since time.Time) float64 {
+	var qty float64
+	for _, order := range orders {
+		if order.Symbol != symbol || order.Side != side || order.FilledAt == nil || order.FilledAt.Before(since) {
+			continue
+		}
+		qty += order.FilledQty.InexactFloat64()
+	}
+	return qty
+}
+
+// settleAssignment accounts for shares of symbol called away at price. Open
+// long managed positions are settled oldest first: their exit orders are
+// cancelled, since they would sell shares that are gone, and each one that
+// is covered in full is closed with a trade at price. A position covered
+// in part keeps its remaining shares, with a trade recorded for the part
+// delivered and its stop and target re-placed for the rest. It returns the
+// IDs of the positions settled.
+func (pm *PositionManager) settleAssignment(ctx context.Context, symbol string, shares, price float64, note string) []string {
+	positions := make([]*ManagedPosition, 0)
+	for _, position := range pm.allPositions() {
+		if !position.Shadow && position.Side == "buy" {
+			positions = append(positions, position)
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].CreatedAt.Before(positions[j].CreatedAt)
+	})
+
+	settled := make([]string, 0)
+	for _, position := range positions {
+		if shares <= 0 {
+			break
+		}
+		// Locked so the monitor doesn't re-place exit orders sized for
+		// shares that are gone
+		unlock := pm.lockPosition(position.ID)
+		taken, ok := pm.settleAssigned(ctx, position, symbol, shares, price, note)
+		unlock()
+		if ok {
+			shares -= taken
+			settled = append(settled, position.ID)
+		}
+	}
+
+	return settled
+}
+
+// settleAssigned settles up to shares of one position called away at price
+// and returns the shares it took. The caller holds the position's lock, so
+// the symbol and status checked here can't change underneath it.
+func (pm *PositionManager) settleAssigned(ctx context.Context, position *ManagedPosition, symbol string, shares, price float64, note string) (float64, bool) {
+	if position.Symbol != symbol || (position.Status != "ACTIVE" && position.Status != "PARTIAL") {
+		return 0, false
+	}
+
+	ctx = positionContext(ctx, position)
+	for _, orderID := range append([]string{position.StopLossOrderID, position.TakeProfitOrderID}, position.PartialExitOrders...) {
+		if orderID == "" {
+			continue
+		}
+		if err := pm.tradingService.CancelOrder(ctx, orderID); err != nil {
+			pm.logger.WithError(err).WithField("order_id", orderID).Warn("Failed to cancel exit order of assigned position (may already be cancelled)")
+		}
+	}
+	position.StopLossOrderID = ""
+	position.TakeProfitOrderID = ""
+
+	now := time.Now()
+	position.UpdatedAt = now
+	position.CurrentPrice = price
+	if position.Notes != "" {
+		position.Notes += "; "
+	}
+	position.Notes += note
+
+	taken := shares
+	if position.RemainingQty <= shares {
+		taken = position.RemainingQty
+		position.Status = "CLOSED"
+		position.ClosedAt = &now
+		if err := pm.savePositionToDB(position); err != nil {
+			pm.logger.WithError(err).WithField("position_id", position.ID).Error("Failed to save assigned position")
+		}
+		pm.recordTrade(position, price)
+	} else {
+		delivered := *position
+		delivered.Quantity = shares
+		delivered.ClosedAt = &now
+		pm.recordTrade(&delivered, price)
+
+		position.Quantity -= shares
+		position.RemainingQty -= shares
+		position.Status = "PARTIAL"
+		pm.placeRiskOrders(ctx, position)
+		if err := pm.savePositionToDB(position); err != nil {
+			pm.logger.WithError(err).WithField("position_id", position.ID).Error("Failed to save assigned position")
+		}
+	}
+
+	pm.logger.WithFields(logrus.Fields{
+		"position_id": position.ID,
+		"symbol":      position.Symbol,
+		"status":      position.Status,
+	}).Info("Managed position settled for a call assignment")
+	return taken, true
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"prophet-trader/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CredentialRotator is implemented by services that can switch to new API
+// credentials while running
+type CredentialRotator interface {
+	RotateCredentials(cfg *config.Config)
+}
+
+// alpacaKeys holds the key pair Alpaca requests authenticate with. The SDK
+// clients are built on an HTTP client that stamps the current pair on every
+// request, so rotated keys apply without rebuilding them.
+type alpacaKeys struct {
+	mu        sync.RWMutex
+	apiKey    string
+	secretKey string
+}
+
+func newAlpacaKeys(apiKey, secretKey string) *alpacaKeys {
+	return &alpacaKeys{apiKey: apiKey, secretKey: secretKey}
+}
+
+func (k *alpacaKeys) get() (string, string) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.apiKey, k.secretKey
+}
+
+func (k *alpacaKeys) set(apiKey, secretKey string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.apiKey, k.secretKey = apiKey, secretKey
+}
+
+// httpClient returns a client whose requests carry the current keys
+func (k *alpacaKeys) httpClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: alpacaKeyTransport{keys: k}}
+}
+
+type alpacaKeyTransport struct {
+	keys *alpacaKeys
+}
+
+func (t alpacaKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	apiKey, secretKey := t.keys.get()
+	req = req.Clone(req.Context())
+	req.Header.Set("APCA-API-KEY-ID", apiKey)
+	req.Header.Set("APCA-API-SECRET-KEY", secretKey)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// SecretsRotator polls the secrets backend and hands changed credentials to
+// the services that hold them
+type SecretsRotator struct {
+	rotators []CredentialRotator
+	logger   *logrus.Logger
+}
+
+// NewSecretsRotator creates a rotator for the given services
+func NewSecretsRotator(rotators ...CredentialRotator) *SecretsRotator {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &SecretsRotator{
+		rotators: rotators,
+		logger:   logger,
+	}
+}
+
+// Refresh fetches the secrets once and applies any that changed
+func (r *SecretsRotator) Refresh(ctx context.Context) error {
+	cfg, changed, err := config.RefreshSecrets(ctx)
+	if err != nil || cfg == nil {
+		return err
+	}
+
+	for _, rotator := range r.rotators {
+		rotator.RotateCredentials(cfg)
+	}
+	r.logger.WithField("changed", strings.Join(changed, ",")).Info("Rotated credentials from secrets backend")
+	return nil
+}
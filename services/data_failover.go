@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/interfaces"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxProviderUsage bounds the request history kept by FailoverDataService
+const maxProviderUsage = 200
+
+// DataProviderUsage records which provider served one data request
+type DataProviderUsage struct {
+	Method    string    `json:"method"`
+	Symbol    string    `json:"symbol"`
+	Provider  string    `json:"provider"`         // empty when every provider failed
+	Failed    []string  `json:"failed,omitempty"` // providers tried first that errored
+	Error     string    `json:"error,omitempty"`  // last error when nothing served the request
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// DataProviderStats summarizes failover activity since startup
+type DataProviderStats struct {
+	Providers []string            `json:"providers"` // in failover order
+	Served    map[string]int      `json:"served"`    // requests served per provider
+	Failures  map[string]int      `json:"failures"`  // errors per provider
+	Recent    []DataProviderUsage `json:"recent"`    // newest first
+}
+
+type namedDataService struct {
+	name string
+	data interfaces.DataService
+}
+
+// FailoverDataService implements DataService over an ordered list of
+// providers. Each request goes to the first provider and falls through to
+// the next one on error; the provider that answered is recorded.
+type FailoverDataService struct {
+	providers []namedDataService
+	served    map[string]int
+	failures  map[string]int
+	recent    []DataProviderUsage
+	mu        sync.Mutex
+	logger    *logrus.Logger
+}
+
+// NewFailoverDataService creates a failover wrapper around a primary provider
+func NewFailoverDataService(name string, primary interfaces.DataService) *FailoverDataService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &FailoverDataService{
+		providers: []namedDataService{{name: strings.ToLower(name), data: primary}},
+		served:    make(map[string]int),
+		failures:  make(map[string]int),
+		logger:    logger,
+	}
+}
+
+// AddFallback appends a provider tried after the ones already added
+func (f *FailoverDataService) AddFallback(name string, data interfaces.DataService) {
+	f.providers = append(f.providers, namedDataService{name: strings.ToLower(name), data: data})
+}
+
+// Stats returns per-provider counters and the most recent requests
+func (f *FailoverDataService) Stats() *DataProviderStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stats := &DataProviderStats{
+		Providers: make([]string, len(f.providers)),
+		Served:    make(map[string]int, len(f.served)),
+		Failures:  make(map[string]int, len(f.failures)),
+		Recent:    make([]DataProviderUsage, 0, len(f.recent)),
+	}
+	for i, p := range f.providers {
+		stats.Providers[i] = p.name
+	}
+	for name, n := range f.served {
+		stats.Served[name] = n
+	}
+	for name, n := range f.failures {
+		stats.Failures[name] = n
+	}
+	for i := len(f.recent) - 1; i >= 0; i-- {
+		stats.Recent = append(stats.Recent, f.recent[i])
+	}
+	return stats
+}
+
+func (f *FailoverDataService) record(usage DataProviderUsage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if usage.Provider != "" {
+		f.served[usage.Provider]++
+	}
+	for _, name := range usage.Failed {
+		f.failures[name]++
+	}
+	f.recent = append(f.recent, usage)
+	if len(f.recent) > maxProviderUsage {
+		f.recent = f.recent[len(f.recent)-maxProviderUsage:]
+	}
+}
+
+// failover runs call against each provider in order until one succeeds
+func failover[T any](f *FailoverDataService, ctx context.Context, method, symbol string, call func(interfaces.DataService) (T, error)) (T, error) {
+	usage := DataProviderUsage{Method: method, Symbol: symbol, Timestamp: time.Now()}
+
+	var lastErr error
+	for _, p := range f.providers {
+		result, err := call(p.data)
+		if err == nil {
+			usage.Provider = p.name
+			if len(usage.Failed) > 0 {
+				f.logger.WithFields(logrus.Fields{
+					"method":   method,
+					"symbol":   symbol,
+					"provider": p.name,
+					"failed":   usage.Failed,
+				}).Warn("Served market data from fallback provider")
+			}
+			f.record(usage)
+			return result, nil
+		}
+
+		lastErr = fmt.Errorf("%s: %w", p.name, err)
+		// Another provider would reject a bad request too, and it is no
+		// fault of this one
+		if ErrorCodeOf(err) == ErrCodeInvalidRequest {
+			break
+		}
+		usage.Failed = append(usage.Failed, p.name)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	usage.Error = lastErr.Error()
+	f.record(usage)
+	var zero T
+	return zero, lastErr
+}
+
+// GetHistoricalBars retrieves historical bars from the first healthy provider
+func (f *FailoverDataService) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
+	return failover(f, ctx, "GetHistoricalBars", symbol, func(d interfaces.DataService) ([]*interfaces.Bar, error) {
+		return d.GetHistoricalBars(ctx, symbol, start, end, timeframe)
+	})
+}
+
+// GetLatestBar retrieves the latest bar from the first healthy provider
+func (f *FailoverDataService) GetLatestBar(ctx context.Context, symbol string) (*interfaces.Bar, error) {
+	return failover(f, ctx, "GetLatestBar", symbol, func(d interfaces.DataService) (*interfaces.Bar, error) {
+		return d.GetLatestBar(ctx, symbol)
+	})
+}
+
+// GetLatestQuote retrieves the latest quote from the first healthy provider
+func (f *FailoverDataService) GetLatestQuote(ctx context.Context, symbol string) (*interfaces.Quote, error) {
+	return failover(f, ctx, "GetLatestQuote", symbol, func(d interfaces.DataService) (*interfaces.Quote, error) {
+		return d.GetLatestQuote(ctx, symbol)
+	})
+}
+
+// GetLatestTrade retrieves the latest trade from the first healthy provider
+func (f *FailoverDataService) GetLatestTrade(ctx context.Context, symbol string) (*interfaces.Trade, error) {
+	return failover(f, ctx, "GetLatestTrade", symbol, func(d interfaces.DataService) (*interfaces.Trade, error) {
+		return d.GetLatestTrade(ctx, symbol)
+	})
+}
+
+// StreamBars opens the stream on the first provider that supports it
+func (f *FailoverDataService) StreamBars(ctx context.Context, symbols []string) (<-chan *interfaces.Bar, error) {
+	return failover(f, ctx, "StreamBars", strings.Join(symbols, ","), func(d interfaces.DataService) (<-chan *interfaces.Bar, error) {
+		return d.StreamBars(ctx, symbols)
+	})
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Datasets available for export
+const (
+	DatasetBars      = "bars"
+	DatasetTrades    = "trades"
+	DatasetSentiment = "sentiment"
+)
+
+// Dataset file formats
+const (
+	DatasetCSV     = "csv"
+	DatasetParquet = "parquet"
+)
+
+// Dataset partition periods
+const (
+	PartitionDay   = "day"
+	PartitionMonth = "month"
+	PartitionYear  = "year"
+)
+
+// DatasetExportRequest selects what to export. Empty Datasets and Symbols
+// mean all of them.
+type DatasetExportRequest struct {
+	Datasets  []string  `json:"datasets"`
+	Symbols   []string  `json:"symbols"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Format    string    `json:"format"`    // "parquet" (default) or "csv"
+	Partition string    `json:"partition"` // "day", "month" (default) or "year"
+}
+
+// DatasetFile is one file written by an export
+type DatasetFile struct {
+	Path string `json:"path"`
+	Rows int    `json:"rows"`
+}
+
+// DatasetManifest describes an export. It is written last, as
+// manifest.json.
+type DatasetManifest struct {
+	CreatedAt time.Time            `json:"created_at"`
+	Request   DatasetExportRequest `json:"request"`
+	Files     []DatasetFile        `json:"files"`
+	Rows      map[string]int       `json:"rows"` // by dataset
+}
+
+// DatasetSink receives the files of an export
+type DatasetSink interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+// DirSink writes export files under a directory
+type DirSink string
+
+// Create implements DatasetSink
+func (d DirSink) Create(name string) (io.WriteCloser, error) {
+	target := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+	}
+	return os.Create(target)
+}
+
+// ZipSink writes export files into a zip archive
+type ZipSink struct {
+	*zip.Writer
+}
+
+// Create implements DatasetSink
+func (z ZipSink) Create(name string) (io.WriteCloser, error) {
+	w, err := z.Writer.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return nopWriteCloser{w}, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// Column kinds of a dataset table
+const (
+	columnString = iota
+	columnInt
+	columnFloat
+	columnTime
+)
+
+type datasetColumn struct {
+	name string
+	kind int
+}
+
+// datasetTable is the rows of one partition file
+type datasetTable struct {
+	columns []datasetColumn
+	rows    [][]interface{}
+}
+
+var (
+	barColumns = []datasetColumn{
+		{"timeframe", columnString}, {"timestamp", columnTime},
+		{"open", columnFloat}, {"high", columnFloat}, {"low", columnFloat}, {"close", columnFloat},
+		{"volume", columnInt}, {"vwap", columnFloat},
+	}
+	tradeColumns = []datasetColumn{
+		{"side", columnString}, {"qty", columnFloat},
+		{"entry_price", columnFloat}, {"exit_price", columnFloat}, {"pnl", columnFloat}, {"pnl_percent", columnFloat},
+		{"entry_time", columnTime}, {"exit_time", columnTime}, {"duration_seconds", columnInt},
+		{"strategy", columnString}, {"position_id", columnString},
+	}
+	sentimentColumns = []datasetColumn{
+		{"source", columnString}, {"channel", columnString}, {"post_id", columnString},
+		{"posted_at", columnTime}, {"sentiment", columnFloat}, {"engagement", columnInt}, {"url", columnString},
+	}
+)
+
+// DatasetExporter writes stored bars, the trade ledger and social sentiment
+// as CSV or Parquet files partitioned by symbol and date, in the Hive layout
+// (bars/symbol=SPY/date=2026-10/part-0.parquet) that pandas, polars and
+// duckdb read as one table. As usual for that layout, the symbol is in the
+// path rather than in the files.
+type DatasetExporter struct {
+	storage *database.LocalStorage
+	logger  *logrus.Logger
+}
+
+// NewDatasetExporter creates a dataset exporter over storage
+func NewDatasetExporter(storage *database.LocalStorage) *DatasetExporter {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &DatasetExporter{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// Normalize fills in defaults and validates req
+func (req *DatasetExportRequest) Normalize() error {
+	if len(req.Datasets) == 0 {
+		req.Datasets = []string{DatasetBars, DatasetTrades, DatasetSentiment}
+	}
+	for _, dataset := range req.Datasets {
+		switch dataset {
+		case DatasetBars, DatasetTrades, DatasetSentiment:
+		default:
+			return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("unknown dataset %q (%s, %s, %s)", dataset, DatasetBars, DatasetTrades, DatasetSentiment))
+		}
+	}
+	for i := range req.Symbols {
+		req.Symbols[i] = strings.ToUpper(strings.TrimSpace(req.Symbols[i]))
+	}
+	if req.Format == "" {
+		req.Format = DatasetParquet
+	}
+	if req.Format != DatasetParquet && req.Format != DatasetCSV {
+		return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("format must be %s or %s", DatasetParquet, DatasetCSV))
+	}
+	if req.Partition == "" {
+		req.Partition = PartitionMonth
+	}
+	if _, ok := partitionLayouts[req.Partition]; !ok {
+		return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("partition must be %s, %s or %s", PartitionDay, PartitionMonth, PartitionYear))
+	}
+	if req.End.IsZero() {
+		req.End = time.Now()
+	}
+	if req.End.Before(req.Start) {
+		return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("start must be before end"))
+	}
+	return nil
+}
+
+var partitionLayouts = map[string]string{
+	PartitionDay:   "2006-01-02",
+	PartitionMonth: "2006-01",
+	PartitionYear:  "2006",
+}
+
+// Export writes the requested datasets to sink, followed by manifest.json
+func (e *DatasetExporter) Export(ctx context.Context, req DatasetExportRequest, sink DatasetSink) (*DatasetManifest, error) {
+	if err := req.Normalize(); err != nil {
+		return nil, err
+	}
+
+	manifest := &DatasetManifest{
+		CreatedAt: time.Now().UTC(),
+		Request:   req,
+		Files:     []DatasetFile{},
+		Rows:      make(map[string]int),
+	}
+	for _, dataset := range req.Datasets {
+		p := &datasetPartitioner{
+			dataset:  dataset,
+			format:   req.Format,
+			layout:   partitionLayouts[req.Partition],
+			sink:     sink,
+			manifest: manifest,
+		}
+		var err error
+		switch dataset {
+		case DatasetBars:
+			p.columns = barColumns
+			err = e.storage.EachBar(req.Symbols, req.Start, req.End, func(bar *models.DBBar) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				return p.add(bar.Symbol, bar.Timestamp, []interface{}{
+					bar.Timeframe, bar.Timestamp, bar.Open, bar.High, bar.Low, bar.Close, bar.Volume, bar.VWAP,
+				})
+			})
+		case DatasetTrades:
+			p.columns = tradeColumns
+			err = e.exportTrades(req, p)
+		case DatasetSentiment:
+			p.columns = sentimentColumns
+			err = e.exportSentiment(req, p)
+		}
+		if err == nil {
+			err = p.flush()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", dataset, err)
+		}
+	}
+
+	w, err := sink.Create("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"files": len(manifest.Files),
+		"rows":  manifest.Rows,
+	}).Info("Dataset export complete")
+
+	return manifest, nil
+}
+
+// exportTrades adds the trade ledger, partitioned by exit time
+func (e *DatasetExporter) exportTrades(req DatasetExportRequest, p *datasetPartitioner) error {
+	trades, err := e.storage.GetTrades(req.Start, req.End)
+	if err != nil {
+		return err
+	}
+	symbols := make(map[string]bool, len(req.Symbols))
+	for _, symbol := range req.Symbols {
+		symbols[symbol] = true
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Symbol < trades[j].Symbol })
+
+	for _, trade := range trades {
+		if len(symbols) > 0 && !symbols[trade.Symbol] {
+			continue
+		}
+		err := p.add(trade.Symbol, trade.ExitTime, []interface{}{
+			trade.Side, trade.Qty.InexactFloat64(),
+			trade.EntryPrice.InexactFloat64(), trade.ExitPrice.InexactFloat64(), trade.PnL.InexactFloat64(), trade.PnLPercent,
+			trade.EntryTime, trade.ExitTime, trade.Duration, trade.StrategyName, trade.PositionID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportSentiment adds scored social media mentions, partitioned by post time
+func (e *DatasetExporter) exportSentiment(req DatasetExportRequest, p *datasetPartitioner) error {
+	mentions, err := e.storage.GetAllSocialMentions(req.Symbols, req.Start, req.End)
+	if err != nil {
+		return err
+	}
+	for _, mention := range mentions {
+		err := p.add(mention.Symbol, mention.PostedAt, []interface{}{
+			mention.Source, mention.Channel, mention.PostID,
+			mention.PostedAt, mention.Sentiment, int64(mention.Engagement), mention.URL,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// datasetPartitioner collects rows arriving ordered by symbol and time and
+// writes a file whenever the partition changes
+type datasetPartitioner struct {
+	dataset  string
+	format   string
+	layout   string
+	columns  []datasetColumn
+	sink     DatasetSink
+	manifest *DatasetManifest
+
+	key   string
+	table *datasetTable
+}
+
+func (p *datasetPartitioner) add(symbol string, at time.Time, row []interface{}) error {
+	key := path.Join(p.dataset, "symbol="+symbol, "date="+at.UTC().Format(p.layout))
+	if key != p.key {
+		if err := p.flush(); err != nil {
+			return err
+		}
+		p.key = key
+		p.table = &datasetTable{columns: p.columns}
+	}
+	p.table.rows = append(p.table.rows, row)
+	return nil
+}
+
+func (p *datasetPartitioner) flush() error {
+	if p.table == nil || len(p.table.rows) == 0 {
+		return nil
+	}
+	name := path.Join(p.key, "part-0."+p.format)
+	w, err := p.sink.Create(name)
+	if err != nil {
+		return err
+	}
+	if p.format == DatasetCSV {
+		err = writeDatasetCSV(w, p.table)
+	} else {
+		err = writeParquet(w, p.table)
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	p.manifest.Files = append(p.manifest.Files, DatasetFile{Path: name, Rows: len(p.table.rows)})
+	p.manifest.Rows[p.dataset] += len(p.table.rows)
+	p.table = nil
+	return nil
+}
+
+// writeDatasetCSV writes table as CSV with a header row. Times are RFC 3339
+// in UTC.
+func writeDatasetCSV(w io.Writer, table *datasetTable) error {
+	writer := csv.NewWriter(w)
+	header := make([]string, len(table.columns))
+	for i, column := range table.columns {
+		header[i] = column.name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(table.columns))
+	for _, row := range table.rows {
+		for i, value := range row {
+			switch v := value.(type) {
+			case string:
+				record[i] = v
+			case int64:
+				record[i] = strconv.FormatInt(v, 10)
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			case time.Time:
+				record[i] = v.UTC().Format(time.RFC3339Nano)
+			default:
+				return fmt.Errorf("unsupported value %T in column %s", value, table.columns[i].name)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,531 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// DCA cadences
+const (
+	DCACadenceDaily    = "daily"
+	DCACadenceWeekly   = "weekly"
+	DCACadenceBiweekly = "biweekly"
+	DCACadenceMonthly  = "monthly"
+)
+
+// DCA plan statuses
+const (
+	DCAPlanActive    = "ACTIVE"
+	DCAPlanCompleted = "COMPLETED" // MaxTotal has been invested
+	DCAPlanCanceled  = "CANCELED"
+)
+
+// DCA execution statuses
+const (
+	DCAExecutionSubmitted = "SUBMITTED" // waiting for the fill
+	DCAExecutionFilled    = "FILLED"
+	DCAExecutionCanceled  = "CANCELED" // closed by the broker, possibly partially filled
+	DCAExecutionSkipped   = "SKIPPED"  // held back by the risk checks or the regime gate
+	DCAExecutionFailed    = "FAILED"   // the broker refused the order
+)
+
+// dcaMinPurchase is the smallest notional a purchase is placed for; a plan
+// with less than this left before its MaxTotal completes
+var dcaMinPurchase = decimal.NewFromInt(1)
+
+// DCAPlanRequest is the payload for creating a DCA plan
+type DCAPlanRequest struct {
+	Symbol      string          `json:"symbol" binding:"required,symbol"`
+	Amount      decimal.Decimal `json:"amount" binding:"gte=1"` // dollars per purchase
+	Cadence     string          `json:"cadence" binding:"required,oneof=daily weekly biweekly monthly"`
+	MaxTotal    decimal.Decimal `json:"max_total" binding:"gte=0"` // total dollars to invest, zero for no limit
+	StartAt     *time.Time      `json:"start_at,omitempty"`        // first purchase, defaults to now
+	SkipRiskOff bool            `json:"skip_risk_off,omitempty"`   // skip purchases while the market regime is risk-off
+}
+
+// DCAPlan is a recurring purchase with its progress so far
+type DCAPlan struct {
+	ID             string           `json:"id"`
+	Symbol         string           `json:"symbol"`
+	Amount         decimal.Decimal  `json:"amount"`
+	Cadence        string           `json:"cadence"`
+	MaxTotal       decimal.Decimal  `json:"max_total"`
+	SkipRiskOff    bool             `json:"skip_risk_off"`
+	Book           string           `json:"book"`
+	Status         string           `json:"status"`
+	NextRunAt      *time.Time       `json:"next_run_at,omitempty"`
+	LastRunAt      *time.Time       `json:"last_run_at,omitempty"`
+	LastSkipReason string           `json:"last_skip_reason,omitempty"`
+	Purchases      int              `json:"purchases"`
+	Skipped        int              `json:"skipped"`
+	Invested       decimal.Decimal  `json:"invested"`            // filled notional
+	Pending        decimal.Decimal  `json:"pending"`             // notional of purchases awaiting their fill
+	Shares         decimal.Decimal  `json:"shares"`              // filled quantity
+	AverageCost    decimal.Decimal  `json:"average_cost"`        // Invested / Shares
+	Remaining      *decimal.Decimal `json:"remaining,omitempty"` // MaxTotal left to invest
+	CreatedAt      time.Time        `json:"created_at"`
+	Executions     []*DCAExecution  `json:"executions,omitempty"`
+}
+
+// DCAExecution is one scheduled purchase of a plan
+type DCAExecution struct {
+	Status         string           `json:"status"`
+	Amount         decimal.Decimal  `json:"amount"`
+	Qty            decimal.Decimal  `json:"qty"`
+	EstimatedPrice decimal.Decimal  `json:"estimated_price"`
+	OrderID        string           `json:"order_id,omitempty"`
+	FilledQty      decimal.Decimal  `json:"filled_qty"`
+	FilledAvgPrice *decimal.Decimal `json:"filled_avg_price,omitempty"`
+	Reason         string           `json:"reason,omitempty"`
+	Timestamp      time.Time        `json:"timestamp"`
+}
+
+// DCAService runs dollar-cost averaging plans. Each purchase buys a
+// fractional quantity worth the plan amount at market while the market is
+// open, after the pre-trade risk checks and the optional regime gate.
+type DCAService struct {
+	storage     *database.LocalStorage
+	trading     interfaces.TradingService
+	dataService interfaces.DataService
+	riskManager *RiskManager
+	regime      *MarketRegimeService
+	notifier    *Notifier
+	hours       *MarketHours
+	dryRun      bool
+	mu          sync.Mutex // keeps cancellation from racing a purchase
+	logger      *logrus.Logger
+}
+
+// NewDCAService creates a DCA service. regime may be nil, in which case
+// plans never skip for the market regime.
+func NewDCAService(storage *database.LocalStorage, trading interfaces.TradingService, dataService interfaces.DataService, riskManager *RiskManager, regime *MarketRegimeService, notifier *Notifier, dryRun bool) *DCAService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &DCAService{
+		storage:     storage,
+		trading:     trading,
+		dataService: dataService,
+		riskManager: riskManager,
+		regime:      regime,
+		notifier:    notifier,
+		hours:       NewMarketHours(),
+		dryRun:      dryRun,
+		logger:      logger,
+	}
+}
+
+// SetMarketHours decides when the market is open, by default the weekday
+// calendar
+func (s *DCAService) SetMarketHours(hours *MarketHours) {
+	s.hours = hours
+}
+
+// CreatePlan validates and stores a new plan
+func (s *DCAService) CreatePlan(ctx context.Context, req *DCAPlanRequest) (*DCAPlan, error) {
+	if req.Amount.LessThan(dcaMinPurchase) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("amount must be at least %s", dcaMinPurchase.String()))
+	}
+	if req.MaxTotal.IsNegative() {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("max_total cannot be negative"))
+	}
+	if _, err := ParseOCCSymbol(req.Symbol); err == nil {
+		return nil, WithErrorCode(ErrCodeNotSupported, fmt.Errorf("DCA plans buy stocks and ETFs, not options"))
+	}
+
+	nextRun := time.Now()
+	if req.StartAt != nil && req.StartAt.After(nextRun) {
+		nextRun = *req.StartAt
+	}
+
+	dbPlan := &models.DBDCAPlan{
+		PlanID:      fmt.Sprintf("dca_%d", time.Now().UnixNano()),
+		Symbol:      strings.ToUpper(req.Symbol),
+		Amount:      req.Amount,
+		Cadence:     req.Cadence,
+		MaxTotal:    req.MaxTotal,
+		SkipRiskOff: req.SkipRiskOff,
+		Book:        BookFrom(ctx),
+		Status:      DCAPlanActive,
+		NextRunAt:   nextRun,
+	}
+	if err := s.storage.SaveDCAPlan(dbPlan); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"id":      dbPlan.PlanID,
+		"symbol":  dbPlan.Symbol,
+		"amount":  dbPlan.Amount.String(),
+		"cadence": dbPlan.Cadence,
+	}).Info("DCA plan created")
+
+	return dcaPlanFromDB(dbPlan, nil), nil
+}
+
+// ListPlans returns plans with their progress, optionally filtered by status
+func (s *DCAService) ListPlans(status string) ([]*DCAPlan, error) {
+	dbPlans, err := s.storage.GetDCAPlans(status)
+	if err != nil {
+		return nil, err
+	}
+	executions, err := s.storage.GetDCAExecutions("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	byPlan := make(map[string][]*models.DBDCAExecution)
+	for _, execution := range executions {
+		byPlan[execution.PlanID] = append(byPlan[execution.PlanID], execution)
+	}
+
+	plans := make([]*DCAPlan, len(dbPlans))
+	for i, dbPlan := range dbPlans {
+		plans[i] = dcaPlanFromDB(dbPlan, byPlan[dbPlan.PlanID])
+	}
+	return plans, nil
+}
+
+// GetPlan returns a plan with its progress and every execution
+func (s *DCAService) GetPlan(id string) (*DCAPlan, error) {
+	dbPlan, err := s.storage.GetDCAPlan(id)
+	if err != nil {
+		return nil, err
+	}
+	executions, err := s.storage.GetDCAExecutions(id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	plan := dcaPlanFromDB(dbPlan, executions)
+	plan.Executions = make([]*DCAExecution, len(executions))
+	for i, execution := range executions {
+		plan.Executions[i] = dcaExecutionFromDB(execution)
+	}
+	return plan, nil
+}
+
+// CancelPlan stops a plan. Purchases already submitted are not canceled.
+func (s *DCAService) CancelPlan(id string) (*DCAPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dbPlan, err := s.storage.GetDCAPlan(id)
+	if err != nil {
+		return nil, err
+	}
+	if dbPlan.Status != DCAPlanActive {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("DCA plan %s is already %s", id, dbPlan.Status))
+	}
+
+	dbPlan.Status = DCAPlanCanceled
+	if err := s.storage.SaveDCAPlan(dbPlan); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithField("id", id).Info("DCA plan canceled")
+	return s.GetPlan(id)
+}
+
+// RunDue places the purchases of every plan that is due. Purchases only go
+// out while the market is open; a plan that came due while it was closed is
+// bought at the next open.
+func (s *DCAService) RunDue(ctx context.Context) error {
+	if err := s.refreshFills(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to refresh DCA fills")
+	}
+	if !s.hours.IsOpen(ctx, AssetClassEquity) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plans, err := s.storage.GetDCAPlans(DCAPlanActive)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, plan := range plans {
+		if plan.NextRunAt.After(now) {
+			continue
+		}
+		s.execute(ctx, plan, now)
+	}
+	return nil
+}
+
+// execute makes one purchase for a due plan and schedules the next one
+func (s *DCAService) execute(ctx context.Context, plan *models.DBDCAPlan, now time.Time) {
+	ctx = WithBook(WithOrderSource(ctx, OrderSourceDCA), bookOrDefault(plan.Book))
+
+	executions, err := s.storage.GetDCAExecutions(plan.PlanID, "")
+	if err != nil {
+		s.logger.WithError(err).WithField("id", plan.PlanID).Error("Failed to load DCA executions")
+		return
+	}
+
+	amount := plan.Amount
+	if plan.MaxTotal.IsPositive() {
+		progress := dcaPlanFromDB(plan, executions)
+		remaining := plan.MaxTotal.Sub(progress.Invested).Sub(progress.Pending)
+		if remaining.LessThan(dcaMinPurchase) {
+			s.complete(plan)
+			return
+		}
+		amount = decimal.Min(amount, remaining)
+	}
+
+	execution := &models.DBDCAExecution{
+		PlanID: plan.PlanID,
+		Symbol: plan.Symbol,
+		Amount: amount,
+	}
+	s.buy(ctx, plan, execution)
+
+	if err := s.storage.SaveDCAExecution(execution); err != nil {
+		s.logger.WithError(err).WithField("id", plan.PlanID).Error("Failed to save DCA execution")
+	}
+
+	plan.LastRunAt = &now
+	plan.LastSkipReason = ""
+	if execution.Status == DCAExecutionSkipped {
+		plan.LastSkipReason = execution.Reason
+	}
+	plan.NextRunAt = nextDCARun(plan.NextRunAt, plan.Cadence, now)
+	if err := s.storage.SaveDCAPlan(plan); err != nil {
+		s.logger.WithError(err).WithField("id", plan.PlanID).Error("Failed to update DCA plan")
+	}
+}
+
+// buy runs the gates and places the order for one execution, filling in
+// its status
+func (s *DCAService) buy(ctx context.Context, plan *models.DBDCAPlan, execution *models.DBDCAExecution) {
+	logger := s.logger.WithFields(logrus.Fields{
+		"id":     plan.PlanID,
+		"symbol": plan.Symbol,
+		"amount": execution.Amount.String(),
+	})
+	skip := func(reason string) {
+		execution.Status = DCAExecutionSkipped
+		execution.Reason = reason
+		logger.WithField("reason", reason).Warn("DCA purchase skipped")
+	}
+
+	if s.dryRun {
+		skip("DRY_RUN is enabled")
+		return
+	}
+
+	if plan.SkipRiskOff && s.regime != nil {
+		regime, err := s.regime.Current(ctx)
+		if err != nil {
+			logger.WithError(err).Warn("Market regime unavailable, buying anyway")
+		} else if regime.Regime == RegimeRiskOff {
+			skip(fmt.Sprintf("market regime is risk-off (score %d)", regime.Score))
+			return
+		}
+	}
+
+	quote, err := s.dataService.GetLatestQuote(ctx, plan.Symbol)
+	if err != nil {
+		skip(fmt.Sprintf("no quote: %v", err))
+		return
+	}
+	price := quote.AskPrice
+	if price <= 0 {
+		price = quote.BidPrice
+	}
+	if price <= 0 {
+		skip("no price for " + plan.Symbol)
+		return
+	}
+	execution.EstimatedPrice = decimal.NewFromFloat(price)
+	execution.Qty = execution.Amount.Div(execution.EstimatedPrice).Truncate(6)
+
+	order := &interfaces.Order{
+		Symbol:      plan.Symbol,
+		Qty:         execution.Qty,
+		Side:        "buy",
+		Type:        "market",
+		TimeInForce: "day", // fractional orders must be day orders
+		Status:      "pending",
+		SubmittedAt: time.Now(),
+		Strategy:    OrderSourceDCA,
+		Book:        BookFrom(ctx),
+	}
+
+	decision, err := s.riskManager.Evaluate(ctx, order)
+	if err != nil {
+		skip(fmt.Sprintf("risk checks failed to run: %v", err))
+		return
+	}
+	if !decision.Approved {
+		skip((&RiskRejectedError{Decision: decision}).Error())
+		return
+	}
+
+	result, err := s.trading.PlaceOrder(WithRiskDecision(ctx, decision), order)
+	if err != nil {
+		execution.Status = DCAExecutionFailed
+		execution.Reason = err.Error()
+		logger.WithError(err).Error("DCA purchase failed")
+		s.notifier.Notify(ctx, NotifyWarning, "DCA purchase failed",
+			fmt.Sprintf("%s %s of %s: %s", plan.PlanID, execution.Amount.StringFixed(2), plan.Symbol, err.Error()),
+			logrus.Fields{"id": plan.PlanID, "symbol": plan.Symbol})
+		return
+	}
+
+	order.ID = result.OrderID
+	order.Status = result.Status
+	if err := s.storage.SaveOrder(order); err != nil {
+		logger.WithError(err).Warn("Failed to save order to database")
+	}
+	execution.Status = DCAExecutionSubmitted
+	execution.OrderID = result.OrderID
+	logger.WithFields(logrus.Fields{
+		"order_id": result.OrderID,
+		"qty":      execution.Qty.String(),
+	}).Info("DCA purchase submitted")
+}
+
+// complete marks a plan whose MaxTotal has been invested as done
+func (s *DCAService) complete(plan *models.DBDCAPlan) {
+	plan.Status = DCAPlanCompleted
+	if err := s.storage.SaveDCAPlan(plan); err != nil {
+		s.logger.WithError(err).WithField("id", plan.PlanID).Error("Failed to update DCA plan")
+		return
+	}
+	s.logger.WithFields(logrus.Fields{
+		"id":        plan.PlanID,
+		"symbol":    plan.Symbol,
+		"max_total": plan.MaxTotal.String(),
+	}).Info("DCA plan completed")
+}
+
+// refreshFills records the fills of submitted purchases from the broker
+func (s *DCAService) refreshFills(ctx context.Context) error {
+	pending, err := s.storage.GetDCAExecutions("", DCAExecutionSubmitted)
+	if err != nil {
+		return err
+	}
+
+	for _, execution := range pending {
+		order, err := s.trading.GetOrder(ctx, execution.OrderID)
+		if err != nil {
+			s.logger.WithError(err).WithField("order_id", execution.OrderID).Warn("Failed to get DCA order")
+			continue
+		}
+
+		execution.FilledQty = order.FilledQty
+		execution.FilledAvgPrice = order.FilledAvgPrice
+		switch strings.ToLower(order.Status) {
+		case "filled":
+			execution.Status = DCAExecutionFilled
+		case "canceled", "expired", "rejected", "done_for_day":
+			execution.Status = DCAExecutionCanceled
+			execution.Reason = "order " + strings.ToLower(order.Status)
+		}
+		if err := s.storage.SaveDCAExecution(execution); err != nil {
+			s.logger.WithError(err).WithField("order_id", execution.OrderID).Warn("Failed to update DCA execution")
+		}
+	}
+	return nil
+}
+
+// nextDCARun advances a plan's schedule past now. Runs missed while the bot
+// was down are not made up.
+func nextDCARun(last time.Time, cadence string, now time.Time) time.Time {
+	next := last
+	for !next.After(now) {
+		switch cadence {
+		case DCACadenceDaily:
+			next = next.AddDate(0, 0, 1)
+		case DCACadenceWeekly:
+			next = next.AddDate(0, 0, 7)
+		case DCACadenceBiweekly:
+			next = next.AddDate(0, 0, 14)
+		default:
+			next = next.AddDate(0, 1, 0)
+		}
+	}
+	return next
+}
+
+// dcaPlanFromDB converts a plan and totals its executions
+func dcaPlanFromDB(dbPlan *models.DBDCAPlan, executions []*models.DBDCAExecution) *DCAPlan {
+	plan := &DCAPlan{
+		ID:             dbPlan.PlanID,
+		Symbol:         dbPlan.Symbol,
+		Amount:         dbPlan.Amount,
+		Cadence:        dbPlan.Cadence,
+		MaxTotal:       dbPlan.MaxTotal,
+		SkipRiskOff:    dbPlan.SkipRiskOff,
+		Book:           bookOrDefault(dbPlan.Book),
+		Status:         dbPlan.Status,
+		LastRunAt:      dbPlan.LastRunAt,
+		LastSkipReason: dbPlan.LastSkipReason,
+		CreatedAt:      dbPlan.CreatedAt,
+	}
+	if dbPlan.Status == DCAPlanActive {
+		next := dbPlan.NextRunAt
+		plan.NextRunAt = &next
+	}
+
+	for _, execution := range executions {
+		switch execution.Status {
+		case DCAExecutionSkipped:
+			plan.Skipped++
+			continue
+		case DCAExecutionFailed:
+			continue
+		}
+
+		plan.Purchases++
+		filled := decimal.Zero
+		if execution.FilledAvgPrice != nil && execution.FilledQty.IsPositive() {
+			filled = execution.FilledQty.Mul(*execution.FilledAvgPrice)
+			plan.Shares = plan.Shares.Add(execution.FilledQty)
+			plan.Invested = plan.Invested.Add(filled)
+		}
+		if execution.Status == DCAExecutionSubmitted {
+			plan.Pending = plan.Pending.Add(decimal.Max(execution.Amount.Sub(filled), decimal.Zero))
+		}
+	}
+
+	if plan.Shares.IsPositive() {
+		plan.AverageCost = plan.Invested.Div(plan.Shares).Round(4)
+	}
+	plan.Invested = plan.Invested.Round(2)
+	if dbPlan.MaxTotal.IsPositive() {
+		remaining := decimal.Max(dbPlan.MaxTotal.Sub(plan.Invested).Sub(plan.Pending), decimal.Zero)
+		plan.Remaining = &remaining
+	}
+	return plan
+}
+
+func dcaExecutionFromDB(execution *models.DBDCAExecution) *DCAExecution {
+	return &DCAExecution{
+		Status:         execution.Status,
+		Amount:         execution.Amount,
+		Qty:            execution.Qty,
+		EstimatedPrice: execution.EstimatedPrice,
+		OrderID:        execution.OrderID,
+		FilledQty:      execution.FilledQty,
+		FilledAvgPrice: execution.FilledAvgPrice,
+		Reason:         execution.Reason,
+		Timestamp:      execution.CreatedAt,
+	}
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"prophet-trader/config"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterCalendarSource("forexfactory", func(cfg *config.Config) (CalendarSource, error) {
+		return NewForexFactoryCalendarSource(), nil
+	})
+	RegisterCalendarSource("file", func(cfg *config.Config) (CalendarSource, error) {
+		if cfg.EconomicCalendarFile == "" {
+			return nil, fmt.Errorf("ECONOMIC_CALENDAR_FILE not configured")
+		}
+		return NewFileCalendarSource(cfg.EconomicCalendarFile), nil
+	})
+}
+
+// economicCalendarTTL bounds how often the calendar is refetched; schedules
+// rarely change but actual values are filled in as releases come out
+const economicCalendarTTL = time.Hour
+
+// Economic event impact levels
+const (
+	ImpactHigh    = "high"
+	ImpactMedium  = "medium"
+	ImpactLow     = "low"
+	ImpactHoliday = "holiday"
+)
+
+// EconomicEvent is a scheduled macro release or central bank event
+type EconomicEvent struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`    // FOMC, CPI, PPI, NFP, GDP, PCE, RETAIL_SALES, JOBLESS_CLAIMS or OTHER
+	Country  string    `json:"country"` // currency code, e.g. "USD"
+	Impact   string    `json:"impact"`  // high, medium, low or holiday
+	Time     time.Time `json:"time"`
+	Forecast string    `json:"forecast,omitempty"`
+	Previous string    `json:"previous,omitempty"`
+	Actual   string    `json:"actual,omitempty"`
+	Source   string    `json:"source"`
+}
+
+// eventTypes maps name fragments to event types, checked in order
+var eventTypes = []struct {
+	fragment  string
+	eventType string
+}{
+	{"fomc", "FOMC"},
+	{"federal funds rate", "FOMC"},
+	{"fed chair", "FOMC"},
+	{"non-farm", "NFP"},
+	{"nonfarm", "NFP"},
+	{"core cpi", "CPI"},
+	{"cpi", "CPI"},
+	{"consumer price", "CPI"},
+	{"ppi", "PPI"},
+	{"producer price", "PPI"},
+	{"pce", "PCE"},
+	{"gdp", "GDP"},
+	{"retail sales", "RETAIL_SALES"},
+	{"jobless claims", "JOBLESS_CLAIMS"},
+}
+
+// classifyEvent derives the event type from its name
+func classifyEvent(name string) string {
+	lower := strings.ToLower(name)
+	for _, t := range eventTypes {
+		if strings.Contains(lower, t.fragment) {
+			return t.eventType
+		}
+	}
+	return "OTHER"
+}
+
+// impactRank orders impact levels so they can be compared
+func impactRank(impact string) int {
+	switch strings.ToLower(impact) {
+	case ImpactHigh:
+		return 3
+	case ImpactMedium:
+		return 2
+	case ImpactLow:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// CalendarSource fetches scheduled economic events
+type CalendarSource interface {
+	GetEvents(ctx context.Context) ([]EconomicEvent, error)
+}
+
+// CalendarSourceFactory creates a calendar source from the application config
+type CalendarSourceFactory func(cfg *config.Config) (CalendarSource, error)
+
+var calendarSources = map[string]CalendarSourceFactory{}
+
+// RegisterCalendarSource makes a CalendarSource selectable by name
+func RegisterCalendarSource(name string, factory CalendarSourceFactory) {
+	calendarSources[strings.ToLower(name)] = factory
+}
+
+// NewCalendarSource creates the calendar source registered under name
+func NewCalendarSource(name string, cfg *config.Config) (CalendarSource, error) {
+	factory, ok := calendarSources[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown economic calendar source: %s (available: %v)", name, CalendarSourceNames())
+	}
+
+	source, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s calendar source: %w", name, err)
+	}
+	return source, nil
+}
+
+// CalendarSourceNames returns the registered source names in sorted order
+func CalendarSourceNames() []string {
+	names := make([]string, 0, len(calendarSources))
+	for name := range calendarSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForexFactoryCalendarSource reads the weekly calendar published by Forex
+// Factory, which covers FOMC, CPI, NFP and the other major US releases
+type ForexFactoryCalendarSource struct {
+	httpClient *http.Client
+	urls       []string
+}
+
+// NewForexFactoryCalendarSource creates a Forex Factory calendar source for
+// this week and next
+func NewForexFactoryCalendarSource() *ForexFactoryCalendarSource {
+	return &ForexFactoryCalendarSource{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		urls: []string{
+			"https://nfs.faireconomy.media/ff_calendar_thisweek.json",
+			"https://nfs.faireconomy.media/ff_calendar_nextweek.json",
+		},
+	}
+}
+
+type forexFactoryEvent struct {
+	Title    string `json:"title"`
+	Country  string `json:"country"`
+	Date     string `json:"date"` // RFC 3339 with the publisher's offset
+	Impact   string `json:"impact"`
+	Forecast string `json:"forecast"`
+	Previous string `json:"previous"`
+	Actual   string `json:"actual"`
+}
+
+// GetEvents fetches the weekly feeds. Next week's feed is only published
+// part way through the week, so it is skipped when unavailable.
+func (s *ForexFactoryCalendarSource) GetEvents(ctx context.Context) ([]EconomicEvent, error) {
+	events := make([]EconomicEvent, 0)
+	for i, endpoint := range s.urls {
+		raw, err := s.fetch(ctx, endpoint)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, e := range raw {
+			t, err := time.Parse(time.RFC3339, e.Date)
+			if err != nil {
+				continue
+			}
+			events = append(events, EconomicEvent{
+				Name:     e.Title,
+				Type:     classifyEvent(e.Title),
+				Country:  strings.ToUpper(e.Country),
+				Impact:   strings.ToLower(e.Impact),
+				Time:     t,
+				Forecast: e.Forecast,
+				Previous: e.Previous,
+				Actual:   e.Actual,
+				Source:   "forexfactory",
+			})
+		}
+	}
+	return events, nil
+}
+
+func (s *ForexFactoryCalendarSource) fetch(ctx context.Context, endpoint string) ([]forexFactoryEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "prophet-trader")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch economic calendar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("economic calendar error (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var events []forexFactoryEvent
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		return nil, fmt.Errorf("failed to parse economic calendar: %w", err)
+	}
+	return events, nil
+}
+
+// FileCalendarSource reads events from a JSON file of EconomicEvent objects,
+// for schedules maintained by hand such as the published FOMC meeting dates
+type FileCalendarSource struct {
+	path string
+}
+
+// NewFileCalendarSource creates a calendar source backed by a JSON file
+func NewFileCalendarSource(path string) *FileCalendarSource {
+	return &FileCalendarSource{path: path}
+}
+
+// GetEvents reads the file on every call so edits are picked up on refresh
+func (s *FileCalendarSource) GetEvents(ctx context.Context) ([]EconomicEvent, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read economic calendar file: %w", err)
+	}
+
+	var events []EconomicEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, fmt.Errorf("failed to parse economic calendar file: %w", err)
+	}
+
+	for i := range events {
+		if events[i].Type == "" {
+			events[i].Type = classifyEvent(events[i].Name)
+		}
+		if events[i].Country == "" {
+			events[i].Country = "USD"
+		}
+		events[i].Impact = strings.ToLower(events[i].Impact)
+		if events[i].Impact == "" {
+			events[i].Impact = ImpactHigh
+		}
+		if events[i].Source == "" {
+			events[i].Source = "file"
+		}
+	}
+	return events, nil
+}
+
+// EconomicCalendarFilter narrows the events returned by the calendar
+type EconomicCalendarFilter struct {
+	From      time.Time
+	To        time.Time
+	MinImpact string   // empty returns every impact level
+	Countries []string // empty returns every country
+	Types     []string // empty returns every type
+}
+
+// EconomicCalendarService serves cached economic events from a source
+type EconomicCalendarService struct {
+	source    CalendarSource
+	events    []EconomicEvent
+	fetchedAt time.Time
+	mu        sync.Mutex
+	logger    *logrus.Logger
+}
+
+// NewEconomicCalendarService creates an economic calendar service
+func NewEconomicCalendarService(source CalendarSource) *EconomicCalendarService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &EconomicCalendarService{
+		source: source,
+		logger: logger,
+	}
+}
+
+// Events returns the events matching filter, sorted by time
+func (ecs *EconomicCalendarService) Events(ctx context.Context, filter EconomicCalendarFilter) ([]EconomicEvent, error) {
+	events, err := ecs.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	minRank := impactRank(filter.MinImpact)
+	matched := make([]EconomicEvent, 0)
+	for _, e := range events {
+		if !filter.From.IsZero() && e.Time.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && e.Time.After(filter.To) {
+			continue
+		}
+		if impactRank(e.Impact) < minRank {
+			continue
+		}
+		if len(filter.Countries) > 0 && !containsFold(filter.Countries, e.Country) {
+			continue
+		}
+		if len(filter.Types) > 0 && !containsFold(filter.Types, e.Type) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	return matched, nil
+}
+
+// HighImpactEventsNear returns US high-impact events scheduled between after
+// ago and before from now, i.e. events whose risk window contains now
+func (ecs *EconomicCalendarService) HighImpactEventsNear(ctx context.Context, before, after time.Duration) ([]EconomicEvent, error) {
+	now := time.Now()
+	return ecs.Events(ctx, EconomicCalendarFilter{
+		From:      now.Add(-after),
+		To:        now.Add(before),
+		MinImpact: ImpactHigh,
+		Countries: []string{"USD"},
+	})
+}
+
+// load returns the cached events, refreshing them once the cache expires.
+// A failed refresh keeps serving the previous events when there are any.
+func (ecs *EconomicCalendarService) load(ctx context.Context) ([]EconomicEvent, error) {
+	ecs.mu.Lock()
+	defer ecs.mu.Unlock()
+
+	if ecs.events != nil && time.Since(ecs.fetchedAt) < economicCalendarTTL {
+		return ecs.events, nil
+	}
+
+	events, err := ecs.source.GetEvents(ctx)
+	if err != nil {
+		if ecs.events != nil {
+			ecs.logger.WithError(err).Warn("Failed to refresh economic calendar, serving cached events")
+			return ecs.events, nil
+		}
+		return nil, fmt.Errorf("failed to get economic calendar: %w", err)
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	ecs.events = events
+	ecs.fetchedAt = time.Now()
+	ecs.logger.WithField("events", len(events)).Debug("Economic calendar refreshed")
+	return events, nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventNames lists events with their times for risk messages
+func eventNames(events []EconomicEvent) string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = fmt.Sprintf("%s %s", e.Name, e.Time.Format("Jan 2 15:04 MST"))
+	}
+	return strings.Join(names, ", ")
+}
+
+// MacroEventRule pauses, or warns on, new buys around high-impact economic
+// events and asks for wider stops on positions opened inside the window
+type MacroEventRule struct {
+	Calendar     *EconomicCalendarService
+	Before       time.Duration // window before the event
+	After        time.Duration // window after the event
+	BlockEntries bool
+	StopWidenPct float64 // percent added to stop distances inside the window, 0 disables
+}
+
+func (MacroEventRule) Name() string { return "macro_event" }
+
+func (r MacroEventRule) Check(ctx context.Context, check *RiskCheck) error {
+	if r.Calendar == nil || check.Order.Side != "buy" {
+		return nil
+	}
+
+	events, err := r.Calendar.HighImpactEventsNear(ctx, r.Before, r.After)
+	if err != nil {
+		return &RiskWarning{Message: fmt.Sprintf("economic calendar unavailable: %v", err)}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	message := "high-impact economic event nearby: " + eventNames(events)
+	if r.BlockEntries {
+		return fmt.Errorf("%s, new entries are paused", message)
+	}
+	return &RiskWarning{Message: message}
+}
+
+// StopMultiplier lets the risk manager widen stops on new positions while a
+// high-impact event is near
+func (r MacroEventRule) StopMultiplier(ctx context.Context) (float64, string) {
+	if r.Calendar == nil || r.StopWidenPct <= 0 {
+		return 1, ""
+	}
+
+	events, err := r.Calendar.HighImpactEventsNear(ctx, r.Before, r.After)
+	if err != nil || len(events) == 0 {
+		return 1, ""
+	}
+	return 1 + r.StopWidenPct/100, eventNames(events)
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"prophet-trader/config"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterEmbeddingProvider("gemini", func(cfg *config.Config) (EmbeddingProvider, error) {
+		if cfg.GeminiAPIKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY not configured")
+		}
+		return NewGeminiEmbeddingProvider(cfg.GeminiAPIKey), nil
+	})
+	RegisterEmbeddingProvider("hashing", func(cfg *config.Config) (EmbeddingProvider, error) {
+		return NewHashingEmbeddingProvider(), nil
+	})
+}
+
+// EmbeddingProvider turns texts into vectors whose cosine similarity
+// reflects how related the texts are
+type EmbeddingProvider interface {
+	// Name identifies the vector space; vectors from different names are
+	// never compared
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbeddingProviderFactory creates an embedding provider from the
+// application config
+type EmbeddingProviderFactory func(cfg *config.Config) (EmbeddingProvider, error)
+
+var embeddingProviders = map[string]EmbeddingProviderFactory{}
+
+// RegisterEmbeddingProvider makes an EmbeddingProvider selectable by name
+func RegisterEmbeddingProvider(name string, factory EmbeddingProviderFactory) {
+	embeddingProviders[strings.ToLower(name)] = factory
+}
+
+// NewEmbeddingProvider creates the embedding provider registered under name
+func NewEmbeddingProvider(name string, cfg *config.Config) (EmbeddingProvider, error) {
+	factory, ok := embeddingProviders[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown embedding provider: %s (available: %v)", name, EmbeddingProviderNames())
+	}
+
+	provider, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s embedding provider: %w", name, err)
+	}
+	return provider, nil
+}
+
+// EmbeddingProviderNames returns the registered provider names in sorted order
+func EmbeddingProviderNames() []string {
+	names := make([]string, 0, len(embeddingProviders))
+	for name := range embeddingProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// geminiEmbeddingBatch is the most texts batchEmbedContents accepts
+const geminiEmbeddingBatch = 100
+
+// GeminiEmbeddingProvider embeds texts with Gemini's text-embedding-004
+type GeminiEmbeddingProvider struct {
+	apiKey     string
+	keyMu      sync.RWMutex
+	model      string
+	httpClient *http.Client
+}
+
+// NewGeminiEmbeddingProvider creates a Gemini embedding provider
+func NewGeminiEmbeddingProvider(apiKey string) *GeminiEmbeddingProvider {
+	return &GeminiEmbeddingProvider{
+		apiKey:     apiKey,
+		model:      "text-embedding-004",
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// RotateCredentials switches to the Gemini key in cfg
+func (p *GeminiEmbeddingProvider) RotateCredentials(cfg *config.Config) {
+	p.keyMu.Lock()
+	defer p.keyMu.Unlock()
+	p.apiKey = cfg.GeminiAPIKey
+}
+
+// Name identifies the model's vector space
+func (p *GeminiEmbeddingProvider) Name() string {
+	return "gemini/" + p.model
+}
+
+// Embed embeds texts in batches
+func (p *GeminiEmbeddingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += geminiEmbeddingBatch {
+		batch, err := p.embedBatch(ctx, texts[start:min(len(texts), start+geminiEmbeddingBatch)])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (p *GeminiEmbeddingProvider) embedBatch(ctx context.Context, texts []string) (vectors [][]float32, err error) {
+	started := time.Now()
+	defer func() { RecordUpstreamCall(ctx, UpstreamGemini, started, err) }()
+
+	type request struct {
+		Model   string        `json:"model"`
+		Content GeminiContent `json:"content"`
+	}
+	body := struct {
+		Requests []request `json:"requests"`
+	}{}
+	for _, text := range texts {
+		body.Requests = append(body.Requests, request{
+			Model:   "models/" + p.model,
+			Content: GeminiContent{Parts: []GeminiPart{{Text: text}}},
+		})
+	}
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:batchEmbedContents", p.model)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.keyMu.RLock()
+	req.Header.Set("x-goog-api-key", p.apiKey)
+	p.keyMu.RUnlock()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(data))
+	}
+
+	var parsed struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(parsed.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(parsed.Embeddings))
+	}
+	for _, e := range parsed.Embeddings {
+		vectors = append(vectors, e.Values)
+	}
+	return vectors, nil
+}
+
+// hashingDimensions is the vector size of the hashing provider
+const hashingDimensions = 512
+
+// HashingEmbeddingProvider embeds texts locally by hashing their words and
+// word pairs into a fixed-size vector. It needs no API, but only finds texts
+// that share vocabulary.
+type HashingEmbeddingProvider struct{}
+
+// NewHashingEmbeddingProvider creates a hashing embedding provider
+func NewHashingEmbeddingProvider() *HashingEmbeddingProvider {
+	return &HashingEmbeddingProvider{}
+}
+
+// Name identifies the vector space
+func (p *HashingEmbeddingProvider) Name() string {
+	return fmt.Sprintf("hashing/%d", hashingDimensions)
+}
+
+// Embed embeds each text
+func (p *HashingEmbeddingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, hashingDimensions)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+		})
+		add := func(term string) {
+			h := fnv.New32a()
+			h.Write([]byte(term))
+			sum := h.Sum32()
+			sign := float32(1)
+			if sum&1 == 1 {
+				sign = -1
+			}
+			vector[(sum>>1)%hashingDimensions] += sign
+		}
+		for j, word := range words {
+			if len(word) < 2 {
+				continue
+			}
+			add(word)
+			if j > 0 {
+				add(words[j-1] + " " + word)
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// normalizeVector scales v to unit length so a dot product is the cosine
+// similarity. A zero vector is returned unchanged.
+func normalizeVector(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+// dotProduct is the cosine similarity of two unit vectors
+func dotProduct(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return float64(sum)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"gorm.io/gorm"
+)
+
+// ErrorCode classifies a failure so API clients can branch on it instead of
+// parsing messages
+type ErrorCode string
+
+// Error codes returned by the API
+const (
+	ErrCodeInvalidRequest          ErrorCode = "INVALID_REQUEST"
+	ErrCodeUnauthorized            ErrorCode = "UNAUTHORIZED"
+	ErrCodeForbidden               ErrorCode = "FORBIDDEN"
+	ErrCodeNotFound                ErrorCode = "NOT_FOUND"
+	ErrCodeRateLimited             ErrorCode = "RATE_LIMITED"
+	ErrCodeRequestTooLarge         ErrorCode = "REQUEST_TOO_LARGE"
+	ErrCodeOrderRejected           ErrorCode = "ORDER_REJECTED" // failed pre-trade risk checks
+	ErrCodeBrokerRejected          ErrorCode = "BROKER_REJECTED"
+	ErrCodeInsufficientBuyingPower ErrorCode = "INSUFFICIENT_BUYING_POWER"
+	ErrCodeMarketClosed            ErrorCode = "MARKET_CLOSED"
+	ErrCodeUpstreamRateLimited     ErrorCode = "UPSTREAM_RATE_LIMITED" // broker, data or AI provider quota hit
+	ErrCodeUpstreamError           ErrorCode = "UPSTREAM_ERROR"
+	ErrCodeNotSupported            ErrorCode = "NOT_SUPPORTED"
+	ErrCodeIntegrityFailed         ErrorCode = "INTEGRITY_CHECK_FAILED" // backup archive is corrupt or tampered with
+	ErrCodeUnavailable             ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrCodeInternal                ErrorCode = "INTERNAL_ERROR"
+)
+
+// CodedError attaches an error code to an error
+type CodedError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *CodedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// WithErrorCode wraps err with an explicit error code
+func WithErrorCode(code ErrorCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &CodedError{Code: code, Err: err}
+}
+
+// ErrorCodeOf classifies err. Explicit codes win, then known error types,
+// then the messages brokers and providers use for common failures.
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	var rejected *RiskRejectedError
+	if errors.As(err, &rejected) {
+		return ErrCodeOrderRejected
+	}
+	if errors.Is(err, ErrNotSupported) {
+		return ErrCodeNotSupported
+	}
+	if errors.Is(err, ErrTokenNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCodeNotFound
+	}
+
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "insufficient buying power"), strings.Contains(message, "insufficient funds"):
+		return ErrCodeInsufficientBuyingPower
+	case strings.Contains(message, "market is closed"), strings.Contains(message, "market closed"),
+		strings.Contains(message, "outside of market hours"):
+		return ErrCodeMarketClosed
+	case strings.Contains(message, "rate limit"), strings.Contains(message, "too many requests"),
+		strings.Contains(message, "status 429"), strings.Contains(message, "http 429"):
+		return ErrCodeUpstreamRateLimited
+	}
+
+	var alpacaErr *alpaca.APIError
+	if errors.As(err, &alpacaErr) {
+		switch {
+		case alpacaErr.StatusCode == http.StatusTooManyRequests:
+			return ErrCodeUpstreamRateLimited
+		case alpacaErr.StatusCode == http.StatusNotFound:
+			return ErrCodeNotFound
+		case alpacaErr.StatusCode == http.StatusForbidden, alpacaErr.StatusCode == http.StatusUnprocessableEntity:
+			return ErrCodeBrokerRejected
+		default:
+			return ErrCodeUpstreamError
+		}
+	}
+
+	if strings.Contains(message, "not found") {
+		return ErrCodeNotFound
+	}
+	return ErrCodeInternal
+}
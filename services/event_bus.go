@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Live event types
+const (
+	EventOrderUpdate     = "order_update"      // an order was submitted or changed status
+	EventPositionPnL     = "position_pnl"      // open position P&L changed
+	EventAlertTriggered  = "alert_triggered"   // an alert condition was met
+	EventNewsRuleMatched = "news_rule_matched" // a news rule matched a breaking headline
+	EventMarketBrief     = "market_brief"      // the market brief was revised
+	EventBarCompleted    = "bar_completed"     // the bar aggregator completed a custom bar
+	EventAlgoProgress    = "algo_progress"     // an algo order placed or filled a child, or finished
+
+	// Trading activity, recorded by the activity log, storage and notifications
+	EventOrderPlaced       = "order_placed"       // an order was accepted by the broker
+	EventPositionClosed    = "position_closed"    // a position is no longer held
+	EventRiskBreached      = "risk_breached"      // risk checks rejected an order
+	EventAnalysisCompleted = "analysis_completed" // a stock analysis was made
+)
+
+// listenerBuffer is the channel size of in-process listeners
+const listenerBuffer = 1024
+
+// relayBuffer is how many events may wait to be relayed to other instances
+const relayBuffer = 1024
+
+// Event is a live update delivered to API subscribers
+type Event struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+
+	// remote is set on events relayed from another instance. They reach API
+	// subscribers but not listeners, which the publishing instance runs.
+	remote bool
+}
+
+// EventRelay carries events between instances, such as over Redis pub/sub
+type EventRelay interface {
+	// Send publishes an encoded event to the other instances
+	Send(ctx context.Context, data []byte) error
+	// Receive calls handle with every encoded event published, including
+	// this instance's own, until ctx ends or the connection fails
+	Receive(ctx context.Context, handle func([]byte)) error
+}
+
+// relayedEvent is an event as sent between instances
+type relayedEvent struct {
+	Origin    string          `json:"origin"`
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// EventBus fans live events out to subscribers and keeps a bounded history
+// so reconnecting clients can resume after the last event they saw.
+//
+// Event IDs start from the boot time in microseconds, so they keep
+// increasing across restarts and an ID from a previous process is never
+// mistaken for a recent one.
+type EventBus struct {
+	history     []*Event // oldest first, at most historySize
+	historySize int
+	lastID      uint64
+	subscribers map[int]chan *Event
+	nextSubID   int
+	mu          sync.Mutex
+	logger      *logrus.Logger
+
+	relay  EventRelay
+	origin string      // identifies this instance's events on the relay
+	outbox chan *Event // events waiting to be relayed
+}
+
+// NewEventBus creates an event bus keeping the last historySize events
+func NewEventBus(historySize int) *EventBus {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	if historySize <= 0 {
+		historySize = 1000
+	}
+
+	return &EventBus{
+		historySize: historySize,
+		lastID:      uint64(time.Now().UnixMicro()),
+		subscribers: make(map[int]chan *Event),
+		logger:      logger,
+	}
+}
+
+// SetRelay shares events with other instances through relay. Run
+// RunRelay to carry them.
+func (b *EventBus) SetRelay(relay EventRelay) {
+	origin := make([]byte, 8)
+	rand.Read(origin)
+
+	b.relay = relay
+	b.origin = hex.EncodeToString(origin)
+	b.outbox = make(chan *Event, relayBuffer)
+}
+
+// Publish records an event and delivers it to every subscriber. A
+// subscriber whose buffer is full is disconnected rather than allowed to
+// block publishers; it can resume from its last event ID.
+func (b *EventBus) Publish(eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	event := b.publish(eventType, time.Now(), data, false)
+	if b.outbox != nil {
+		select {
+		case b.outbox <- event:
+		default:
+			b.logger.WithField("type", eventType).Warn("Event relay too slow, dropping event")
+		}
+	}
+}
+
+// publish records and delivers an event under a new ID
+func (b *EventBus) publish(eventType string, timestamp time.Time, data interface{}, remote bool) *Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := &Event{
+		ID:        b.lastID,
+		Type:      eventType,
+		Timestamp: timestamp,
+		Data:      data,
+		remote:    remote,
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for id, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			b.logger.WithField("subscriber", id).Warn("Event subscriber too slow, disconnecting")
+			close(ch)
+			delete(b.subscribers, id)
+		}
+	}
+	return event
+}
+
+// RunRelay sends this instance's events to the relay and publishes the
+// events of other instances here until ctx is done. Relayed events get IDs
+// of this bus, so clients resume against the instance they reconnect to.
+func (b *EventBus) RunRelay(ctx context.Context) {
+	if b.relay == nil {
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-b.outbox:
+				if err := callSafely(func() error { return b.send(ctx, event) }); err != nil {
+					b.logger.WithError(err).WithField("type", event.Type).Warn("Failed to relay event")
+				}
+			}
+		}
+	}()
+
+	for {
+		err := b.relay.Receive(ctx, func(message []byte) {
+			var event relayedEvent
+			if err := json.Unmarshal(message, &event); err != nil || event.Origin == b.origin {
+				return
+			}
+			b.publish(event.Type, event.Timestamp, event.Data, true)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		b.logger.WithError(err).Warn("Event relay disconnected, reconnecting")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// send relays one event to the other instances
+func (b *EventBus) send(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	message, _ := json.Marshal(relayedEvent{Origin: b.origin, Type: event.Type, Timestamp: event.Timestamp, Data: data})
+	return b.relay.Send(ctx, message)
+}
+
+// Subscribe registers a subscriber. When lastEventID is non-zero the events
+// published after it are returned for replay; complete is false when some of
+// them have already been dropped from the history.
+func (b *EventBus) Subscribe(lastEventID uint64, buffer int) (id int, replay []*Event, complete bool, events <-chan *Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastEventID > 0 && lastEventID < b.lastID {
+		for _, event := range b.history {
+			if event.ID > lastEventID {
+				replay = append(replay, event)
+			}
+		}
+		// The first retained event must directly follow the one the client saw
+		complete = len(replay) > 0 && replay[0].ID == lastEventID+1
+	}
+
+	b.nextSubID++
+	ch := make(chan *Event, buffer)
+	b.subscribers[b.nextSubID] = ch
+	return b.nextSubID, replay, complete, ch
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (b *EventBus) Unsubscribe(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch, ok := b.subscribers[id]; ok {
+		close(ch)
+		delete(b.subscribers, id)
+	}
+}
+
+// Listen calls handler with every event of the given types, or of every type
+// when none are given, until ctx is done. Events are handled one at a time
+// in order. A listener that falls too far behind is disconnected like any
+// subscriber and resumes after the last event it saw.
+func (b *EventBus) Listen(ctx context.Context, name string, handler func(*Event), eventTypes ...string) {
+	wanted := make(map[string]bool, len(eventTypes))
+	for _, eventType := range eventTypes {
+		wanted[eventType] = true
+	}
+
+	var lastID uint64
+	handle := func(event *Event) {
+		lastID = event.ID
+		if event.remote {
+			return
+		}
+		if len(wanted) == 0 || wanted[event.Type] {
+			// One malformed event must not stop the listener
+			err := callSafely(func() error {
+				handler(event)
+				return nil
+			})
+			if panicErr, ok := err.(*PanicError); ok {
+				b.logger.WithFields(logrus.Fields{
+					"listener": name,
+					"event_id": event.ID,
+					"type":     event.Type,
+					"panic":    panicErr.Value,
+					"stack":    panicErr.Stack,
+				}).Error("Event listener panicked, skipping event")
+			}
+		}
+	}
+
+	for {
+		id, replay, complete, events := b.Subscribe(lastID, listenerBuffer)
+		if !complete {
+			b.logger.WithField("listener", name).Warn("Event listener missed events")
+		}
+		for _, event := range replay {
+			handle(event)
+		}
+
+	receive:
+		for {
+			select {
+			case <-ctx.Done():
+				b.Unsubscribe(id)
+				return
+			case event, ok := <-events:
+				if !ok {
+					break receive
+				}
+				handle(event)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !unix
+
+package services
+
+import (
+	"context"
+	"fmt"
+)
+
+// FileLock needs flock, which this platform lacks; use the postgres or
+// redis lock instead
+type FileLock struct {
+	path string
+}
+
+// NewFileLock creates a lock on the file at path
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path}
+}
+
+// Backend implements InstanceLock
+func (l *FileLock) Backend() string {
+	return "file"
+}
+
+// TryLock implements InstanceLock
+func (l *FileLock) TryLock(ctx context.Context) (bool, error) {
+	return false, fmt.Errorf("file locks are not supported on this platform; use INSTANCE_LOCK=postgres or redis")
+}
+
+// Refresh implements InstanceLock
+func (l *FileLock) Refresh(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+// Unlock implements InstanceLock
+func (l *FileLock) Unlock(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,70 @@
+//go:build unix
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// FileLock is an exclusive flock on a file, guarding instances that share a
+// host or a filesystem with working locks. The kernel releases it when the
+// process exits, however it exits.
+type FileLock struct {
+	path string
+	file *os.File
+}
+
+// NewFileLock creates a lock on the file at path
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path}
+}
+
+// Backend implements InstanceLock
+func (l *FileLock) Backend() string {
+	return "file"
+}
+
+// TryLock implements InstanceLock. The holder's PID is written to the
+// file to help find it.
+func (l *FileLock) TryLock(ctx context.Context) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return false, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lock %s: %w", l.path, err)
+	}
+	file.Truncate(0)
+	file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	l.file = file
+	return true, nil
+}
+
+// Refresh implements InstanceLock. A flock has no lease to extend.
+func (l *FileLock) Refresh(ctx context.Context) (bool, error) {
+	return l.file != nil, nil
+}
+
+// Unlock implements InstanceLock
+func (l *FileLock) Unlock(ctx context.Context) error {
+	if l.file == nil {
+		return nil
+	}
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+	l.file = nil
+	return err
+}
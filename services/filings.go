@@ -0,0 +1,496 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	secTickersURL     = "https://www.sec.gov/files/company_tickers.json"
+	secSubmissionsURL = "https://data.sec.gov/submissions/CIK%s.json"
+	secArchivesURL    = "https://www.sec.gov/Archives/edgar/data/%s/%s/%s"
+
+	// SEC asks for at most 10 requests per second
+	secRequestDelay = 150 * time.Millisecond
+
+	// filingsLookbackDays bounds how far back a symbol's history is stored
+	filingsLookbackDays = 90
+
+	// filingsAlertWindow is how recent a filing must be to raise an alert or
+	// get an AI summary, so first-time backfills stay quiet
+	filingsAlertWindow = 72 * time.Hour
+
+	// secTickersTTL bounds how long the ticker to CIK map is reused
+	secTickersTTL = 24 * time.Hour
+)
+
+// watchedForms are the filing types the monitor stores
+var watchedForms = map[string]bool{
+	"8-K": true, "8-K/A": true,
+	"10-Q": true, "10-Q/A": true,
+	"10-K": true, "10-K/A": true,
+	"4": true, "4/A": true,
+}
+
+// form8KItems names the 8-K items
+var form8KItems = map[string]string{
+	"1.01": "Entry into a Material Definitive Agreement",
+	"1.02": "Termination of a Material Definitive Agreement",
+	"1.03": "Bankruptcy or Receivership",
+	"1.05": "Material Cybersecurity Incident",
+	"2.01": "Completion of Acquisition or Disposition of Assets",
+	"2.02": "Results of Operations and Financial Condition",
+	"2.03": "Creation of a Direct Financial Obligation",
+	"2.04": "Triggering Events That Accelerate a Financial Obligation",
+	"2.05": "Costs Associated with Exit or Disposal Activities",
+	"2.06": "Material Impairments",
+	"3.01": "Notice of Delisting or Failure to Satisfy a Listing Rule",
+	"3.02": "Unregistered Sales of Equity Securities",
+	"3.03": "Material Modification to Rights of Security Holders",
+	"4.01": "Changes in Registrant's Certifying Accountant",
+	"4.02": "Non-Reliance on Previously Issued Financial Statements",
+	"5.01": "Changes in Control of Registrant",
+	"5.02": "Departure or Appointment of Directors or Officers",
+	"5.03": "Amendments to Articles of Incorporation or Bylaws",
+	"5.07": "Submission of Matters to a Vote of Security Holders",
+	"7.01": "Regulation FD Disclosure",
+	"8.01": "Other Events",
+	"9.01": "Financial Statements and Exhibits",
+}
+
+// material8KItems are the 8-K items that raise an alert
+var material8KItems = map[string]bool{
+	"1.01": true, "1.02": true, "1.03": true, "1.05": true,
+	"2.01": true, "2.02": true, "2.04": true, "2.05": true, "2.06": true,
+	"3.01": true, "4.01": true, "4.02": true, "5.01": true, "5.02": true,
+}
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]+>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// Filing is an SEC filing for one symbol
+type Filing struct {
+	AccessionNumber string    `json:"accession_number"`
+	Symbol          string    `json:"symbol"`
+	Form            string    `json:"form"`
+	FiledAt         time.Time `json:"filed_at"`
+	ReportDate      string    `json:"report_date,omitempty"`
+	Items           []string  `json:"items,omitempty"`
+	Description     string    `json:"description,omitempty"`
+	URL             string    `json:"url"`
+	Summary         string    `json:"summary"`
+	Material        bool      `json:"material"`
+}
+
+// FilingsMonitor polls SEC EDGAR for new filings on watched symbols, stores
+// them with a summary and notifies on material events
+type FilingsMonitor struct {
+	storageService *database.LocalStorage
+	tradingService interfaces.TradingService
+	geminiService  *GeminiService
+	notifier       *Notifier
+	watchlist      []string
+	aiSummaries    bool
+	userAgent      string
+	httpClient     *http.Client
+	ciks           map[string]string
+	ciksLoadedAt   time.Time
+	mu             sync.Mutex
+	logger         *logrus.Logger
+}
+
+// NewFilingsMonitor creates a filings monitor. Open stock positions are
+// watched in addition to watchlist. userAgent identifies the caller to the
+// SEC, which requires a name and contact address. With aiSummaries, recent
+// filings are summarized by Gemini.
+func NewFilingsMonitor(
+	storageService *database.LocalStorage,
+	tradingService interfaces.TradingService,
+	geminiService *GeminiService,
+	notifier *Notifier,
+	watchlist []string,
+	userAgent string,
+	aiSummaries bool,
+) *FilingsMonitor {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	symbols := make([]string, 0, len(watchlist))
+	for _, symbol := range watchlist {
+		if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	return &FilingsMonitor{
+		storageService: storageService,
+		tradingService: tradingService,
+		geminiService:  geminiService,
+		notifier:       notifier,
+		watchlist:      symbols,
+		aiSummaries:    aiSummaries,
+		userAgent:      userAgent,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		logger:         logger,
+	}
+}
+
+// Poll refreshes filings for the watchlist and open positions, notifying on
+// new material filings
+func (fm *FilingsMonitor) Poll(ctx context.Context) {
+	for _, symbol := range fm.symbols(ctx) {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := fm.Refresh(ctx, symbol, true); err != nil {
+			fm.logger.WithError(err).WithField("symbol", symbol).Warn("Failed to refresh filings")
+		}
+	}
+}
+
+// symbols returns the watchlist plus the underlying symbols of open positions
+func (fm *FilingsMonitor) symbols(ctx context.Context) []string {
+	seen := make(map[string]bool)
+	symbols := []string{}
+	add := func(symbol string) {
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	for _, symbol := range fm.watchlist {
+		add(symbol)
+	}
+	if positions, err := fm.tradingService.GetPositions(ctx); err == nil {
+		for _, pos := range positions {
+			add(underlyingSymbol(pos.Symbol))
+		}
+	} else {
+		fm.logger.WithError(err).Warn("Failed to get positions for filings watch")
+	}
+	return symbols
+}
+
+// GetFilings returns stored filings for symbol, newest first. A symbol
+// without stored filings is fetched from EDGAR first.
+func (fm *FilingsMonitor) GetFilings(ctx context.Context, symbol, form string, limit int) ([]*Filing, error) {
+	symbol = strings.ToUpper(symbol)
+
+	stored, err := fm.storageService.GetFilings(symbol, "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 {
+		if _, err := fm.Refresh(ctx, symbol, false); err != nil {
+			return nil, err
+		}
+	}
+
+	records, err := fm.storageService.GetFilings(symbol, strings.ToUpper(form), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	filings := make([]*Filing, 0, len(records))
+	for _, record := range records {
+		filings = append(filings, filingFromDB(record))
+	}
+	return filings, nil
+}
+
+// Refresh fetches symbol's recent filings from EDGAR and stores the new ones.
+// With notify, new material filings inside the alert window raise a notification.
+func (fm *FilingsMonitor) Refresh(ctx context.Context, symbol string, notify bool) ([]*Filing, error) {
+	symbol = strings.ToUpper(symbol)
+
+	cik, err := fm.lookupCIK(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	var submissions struct {
+		Filings struct {
+			Recent struct {
+				AccessionNumber       []string `json:"accessionNumber"`
+				FilingDate            []string `json:"filingDate"`
+				ReportDate            []string `json:"reportDate"`
+				AcceptanceDateTime    []string `json:"acceptanceDateTime"`
+				Form                  []string `json:"form"`
+				Items                 []string `json:"items"`
+				PrimaryDocument       []string `json:"primaryDocument"`
+				PrimaryDocDescription []string `json:"primaryDocDescription"`
+			} `json:"recent"`
+		} `json:"filings"`
+	}
+	if err := fm.getJSON(ctx, fmt.Sprintf(secSubmissionsURL, cik), &submissions); err != nil {
+		return nil, fmt.Errorf("failed to get filings for %s: %w", symbol, err)
+	}
+
+	recent := submissions.Filings.Recent
+	cutoff := time.Now().AddDate(0, 0, -filingsLookbackDays)
+	at := func(values []string, i int) string {
+		if i < len(values) {
+			return values[i]
+		}
+		return ""
+	}
+
+	newFilings := []*Filing{}
+	for i, accession := range recent.AccessionNumber {
+		form := at(recent.Form, i)
+		if !watchedForms[form] {
+			continue
+		}
+
+		filedAt, err := time.Parse(time.RFC3339, at(recent.AcceptanceDateTime, i))
+		if err != nil {
+			if filedAt, err = time.Parse("2006-01-02", at(recent.FilingDate, i)); err != nil {
+				continue
+			}
+		}
+		if filedAt.Before(cutoff) {
+			// Recent filings are listed newest first
+			break
+		}
+
+		exists, err := fm.storageService.HasFiling(accession)
+		if err != nil {
+			return newFilings, err
+		}
+		if exists {
+			continue
+		}
+
+		filing := &Filing{
+			AccessionNumber: accession,
+			Symbol:          symbol,
+			Form:            form,
+			FiledAt:         filedAt,
+			ReportDate:      at(recent.ReportDate, i),
+			Description:     at(recent.PrimaryDocDescription, i),
+			URL:             fmt.Sprintf(secArchivesURL, strings.TrimLeft(cik, "0"), strings.ReplaceAll(accession, "-", ""), at(recent.PrimaryDocument, i)),
+		}
+		if items := at(recent.Items, i); items != "" {
+			filing.Items = strings.Split(items, ",")
+		}
+		filing.Material = isMaterialFiling(filing)
+		filing.Summary = describeFiling(filing)
+
+		isRecent := time.Since(filedAt) < filingsAlertWindow
+		if fm.aiSummaries && isRecent {
+			if summary, err := fm.summarize(ctx, filing); err == nil {
+				filing.Summary = summary
+			} else {
+				fm.logger.WithError(err).WithField("accession", accession).Warn("Failed to summarize filing")
+			}
+		}
+
+		if err := fm.storageService.SaveFiling(filingToDB(filing, cik)); err != nil {
+			return newFilings, err
+		}
+		newFilings = append(newFilings, filing)
+
+		if notify && isRecent && filing.Material {
+			fm.notifier.Notify(ctx, NotifyWarning,
+				fmt.Sprintf("%s filed %s", symbol, filing.Form),
+				filing.Summary,
+				map[string]interface{}{
+					"symbol":    symbol,
+					"form":      filing.Form,
+					"items":     strings.Join(filing.Items, ","),
+					"filed_at":  filing.FiledAt,
+					"url":       filing.URL,
+					"accession": accession,
+				})
+		}
+	}
+
+	if len(newFilings) > 0 {
+		fm.logger.WithFields(logrus.Fields{
+			"symbol": symbol,
+			"count":  len(newFilings),
+		}).Info("Stored new SEC filings")
+	}
+	return newFilings, nil
+}
+
+// isMaterialFiling flags 8-Ks with a material item and annual reports
+func isMaterialFiling(f *Filing) bool {
+	switch strings.TrimSuffix(f.Form, "/A") {
+	case "8-K":
+		for _, item := range f.Items {
+			if material8KItems[item] {
+				return true
+			}
+		}
+	case "10-K":
+		return true
+	}
+	return false
+}
+
+// describeFiling builds a summary from the form type and 8-K items
+func describeFiling(f *Filing) string {
+	switch strings.TrimSuffix(f.Form, "/A") {
+	case "8-K":
+		names := []string{}
+		for _, item := range f.Items {
+			if name, ok := form8KItems[item]; ok && item != "9.01" {
+				names = append(names, fmt.Sprintf("Item %s %s", item, name))
+			}
+		}
+		if len(names) > 0 {
+			return "Current report: " + strings.Join(names, "; ")
+		}
+		return "Current report"
+	case "10-Q":
+		return "Quarterly report for period ending " + f.ReportDate
+	case "10-K":
+		return "Annual report for fiscal year ending " + f.ReportDate
+	case "4":
+		return "Insider transaction report (Form 4)"
+	}
+	return f.Form
+}
+
+// summarize asks Gemini for a summary of the filing's primary document
+func (fm *FilingsMonitor) summarize(ctx context.Context, f *Filing) (string, error) {
+	if fm.geminiService == nil {
+		return "", fmt.Errorf("Gemini not configured")
+	}
+
+	body, err := fm.get(ctx, f.URL)
+	if err != nil {
+		return "", fmt.Errorf("failed to get filing document: %w", err)
+	}
+	text := htmlTagPattern.ReplaceAllString(string(body), " ")
+	text = strings.TrimSpace(whitespacePattern.ReplaceAllString(html.UnescapeString(text), " "))
+
+	summary, err := fm.geminiService.SummarizeFiling(ctx, f.Symbol, f.Form, text)
+	if err != nil {
+		return "", err
+	}
+	return summary, nil
+}
+
+// lookupCIK maps a ticker to its zero-padded SEC CIK
+func (fm *FilingsMonitor) lookupCIK(ctx context.Context, symbol string) (string, error) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	if fm.ciks == nil || time.Since(fm.ciksLoadedAt) > secTickersTTL {
+		var tickers map[string]struct {
+			CIK    int64  `json:"cik_str"`
+			Ticker string `json:"ticker"`
+		}
+		if err := fm.getJSON(ctx, secTickersURL, &tickers); err != nil {
+			return "", fmt.Errorf("failed to load SEC ticker map: %w", err)
+		}
+
+		ciks := make(map[string]string, len(tickers))
+		for _, t := range tickers {
+			ciks[strings.ToUpper(t.Ticker)] = fmt.Sprintf("%010d", t.CIK)
+		}
+		fm.ciks = ciks
+		fm.ciksLoadedAt = time.Now()
+	}
+
+	cik, ok := fm.ciks[symbol]
+	if !ok {
+		return "", fmt.Errorf("no SEC registrant found for symbol: %s", symbol)
+	}
+	return cik, nil
+}
+
+func (fm *FilingsMonitor) getJSON(ctx context.Context, url string, out interface{}) error {
+	body, err := fm.get(ctx, url)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// get fetches an SEC URL, pacing requests to stay under the fair access limit
+func (fm *FilingsMonitor) get(ctx context.Context, url string) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(secRequestDelay):
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", fm.userAgent)
+
+	resp, err := fm.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SEC API error (status %d)", resp.StatusCode)
+	}
+	return body, nil
+}
+
+func filingToDB(f *Filing, cik string) *models.DBFiling {
+	return &models.DBFiling{
+		AccessionNumber: f.AccessionNumber,
+		Symbol:          f.Symbol,
+		CIK:             cik,
+		Form:            f.Form,
+		FiledAt:         f.FiledAt,
+		ReportDate:      f.ReportDate,
+		Items:           strings.Join(f.Items, ","),
+		Description:     f.Description,
+		URL:             f.URL,
+		Summary:         f.Summary,
+		Material:        f.Material,
+	}
+}
+
+func filingFromDB(record *models.DBFiling) *Filing {
+	f := &Filing{
+		AccessionNumber: record.AccessionNumber,
+		Symbol:          record.Symbol,
+		Form:            record.Form,
+		FiledAt:         record.FiledAt,
+		ReportDate:      record.ReportDate,
+		Description:     record.Description,
+		URL:             record.URL,
+		Summary:         record.Summary,
+		Material:        record.Material,
+	}
+	if record.Items != "" {
+		f.Items = strings.Split(record.Items, ",")
+	}
+	return f
+}
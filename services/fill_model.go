@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+)
+
+// Fill price models for simulated orders
+const (
+	FillAtLast      = "last"     // latest trade or bar close
+	FillAtMidpoint  = "midpoint" // bid/ask midpoint
+	FillCrossSpread = "cross"    // buys pay the ask, sells hit the bid
+)
+
+const (
+	defaultTickSize      = 0.01
+	optionContractShares = 100
+)
+
+// FillModel describes how the simulated broker and the backtester fill
+// orders. The zero value fills everything at the last price with no costs,
+// which flatters less liquid symbols.
+type FillModel struct {
+	Price string `json:"price,omitempty"` // "last" (default), "midpoint" or "cross"
+	// Spread assumed by "cross" when there is no quote with a positive
+	// spread, such as in a backtest over bars
+	SpreadBps     float64 `json:"spread_bps,omitempty"`
+	SlippageBps   float64 `json:"slippage_bps,omitempty"`
+	SlippageTicks float64 `json:"slippage_ticks,omitempty"`
+	TickSize      float64 `json:"tick_size,omitempty"` // default 0.01
+	// Most of one bar's volume an order may take, in percent; 0 is no cap.
+	// The rest of a capped order fills on later bars.
+	MaxVolumePct          float64 `json:"max_volume_pct,omitempty"`
+	CommissionPerShare    float64 `json:"commission_per_share,omitempty"`
+	MinCommission         float64 `json:"min_commission,omitempty"` // per stock order
+	OptionsFeePerContract float64 `json:"options_fee_per_contract,omitempty"`
+}
+
+// Validate checks the model's settings
+func (m FillModel) Validate() error {
+	switch m.Price {
+	case "", FillAtLast, FillAtMidpoint, FillCrossSpread:
+	default:
+		return fmt.Errorf("fill price must be %s, %s or %s", FillAtLast, FillAtMidpoint, FillCrossSpread)
+	}
+	if m.SpreadBps < 0 || m.SlippageBps < 0 || m.SlippageTicks < 0 || m.TickSize < 0 ||
+		m.CommissionPerShare < 0 || m.MinCommission < 0 || m.OptionsFeePerContract < 0 {
+		return fmt.Errorf("spread, slippage, tick size and fees cannot be negative")
+	}
+	if m.MaxVolumePct < 0 || m.MaxVolumePct > 100 {
+		return fmt.Errorf("max volume percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// FillPrice returns the price side fills at, given the last price and the
+// latest quote (nil when there is none). Slippage always moves the price
+// against the order.
+func (m FillModel) FillPrice(side string, last float64, quote *interfaces.Quote) float64 {
+	price := m.QuotedPrice(side, last, quote)
+
+	tick := m.TickSize
+	if tick <= 0 {
+		tick = defaultTickSize
+	}
+	slippage := price*m.SlippageBps/10000 + m.SlippageTicks*tick
+	if side == "buy" {
+		return price + slippage
+	}
+	return math.Max(price-slippage, 0)
+}
+
+// QuotedPrice is the fill price before slippage
+func (m FillModel) QuotedPrice(side string, last float64, quote *interfaces.Quote) float64 {
+	hasSpread := quote != nil && quote.BidPrice > 0 && quote.AskPrice > quote.BidPrice
+
+	switch m.Price {
+	case FillAtMidpoint:
+		if hasSpread {
+			return (quote.BidPrice + quote.AskPrice) / 2
+		}
+	case FillCrossSpread:
+		if hasSpread {
+			if side == "buy" {
+				return quote.AskPrice
+			}
+			return quote.BidPrice
+		}
+		half := last * m.SpreadBps / 20000
+		if side == "buy" {
+			return last + half
+		}
+		return last - half
+	}
+	return last
+}
+
+// VolumeCap returns the most shares an order may take from a bar with the
+// given volume, and false when volume is not capped
+func (m FillModel) VolumeCap(volume int64) (float64, bool) {
+	if m.MaxVolumePct <= 0 {
+		return 0, false
+	}
+	return math.Floor(float64(volume) * m.MaxVolumePct / 100), true
+}
+
+// Fees returns the commission on an order for qty of symbol. OCC option
+// symbols pay per contract, everything else per share.
+func (m FillModel) Fees(symbol string, qty float64) float64 {
+	if qty <= 0 {
+		return 0
+	}
+	if IsOCCSymbol(symbol) {
+		return qty * m.OptionsFeePerContract
+	}
+	if m.CommissionPerShare <= 0 {
+		return 0
+	}
+	return math.Max(qty*m.CommissionPerShare, m.MinCommission)
+}
+
+// contractMultiplier is the shares one unit of symbol stands for: 100 for
+// an option contract, 1 for stock
+func contractMultiplier(symbol string) float64 {
+	if IsOCCSymbol(symbol) {
+		return optionContractShares
+	}
+	return 1
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"prophet-trader/interfaces"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// flattenTokenTTL is how long a flatten confirmation token stays valid
+const flattenTokenTTL = 2 * time.Minute
+
+// FlattenFilter limits a flatten to some symbols or one asset class. A
+// symbol also matches options on it. The zero value matches everything.
+type FlattenFilter struct {
+	Symbols    []string `json:"symbols,omitempty"`
+	AssetClass string   `json:"asset_class,omitempty" binding:"omitempty,oneof=us_equity us_option"`
+}
+
+// Matches reports whether symbol is covered by the filter
+func (f FlattenFilter) Matches(symbol string) bool {
+	if f.AssetClass != "" && assetClassFor(symbol) != f.AssetClass {
+		return false
+	}
+	if len(f.Symbols) == 0 {
+		return true
+	}
+	for _, s := range f.Symbols {
+		if strings.EqualFold(s, symbol) || strings.EqualFold(s, underlyingSymbol(symbol)) {
+			return true
+		}
+	}
+	return false
+}
+
+// key identifies the filter a confirmation token was issued for
+func (f FlattenFilter) key() string {
+	symbols := make([]string, len(f.Symbols))
+	for i, s := range f.Symbols {
+		symbols[i] = strings.ToUpper(s)
+	}
+	return f.AssetClass + "|" + strings.Join(symbols, ",")
+}
+
+// FlattenPreview lists what a flatten would cancel and close. Repeating the
+// request with ConfirmToken before ExpiresAt executes it.
+type FlattenPreview struct {
+	Filter       FlattenFilter          `json:"filter"`
+	OpenOrders   []*interfaces.Order    `json:"open_orders"`
+	Positions    []*interfaces.Position `json:"positions"`
+	ConfirmToken string                 `json:"confirm_token"`
+	ExpiresAt    time.Time              `json:"expires_at"`
+}
+
+// FlattenResult reports what a flatten cancelled and closed
+type FlattenResult struct {
+	FlattenID       string                    `json:"flatten_id"` // audit trail: GET /orders/:flatten_id/audit
+	Filter          FlattenFilter             `json:"filter"`
+	CanceledOrders  []string                  `json:"canceled_orders"`
+	ClosedOrders    []*interfaces.OrderResult `json:"closed_orders"`
+	ReleasedManaged []string                  `json:"released_managed_positions,omitempty"`
+	Errors          []string                  `json:"errors,omitempty"`
+}
+
+// flattenAuditRequest is the request side of a flatten's audit record
+type flattenAuditRequest struct {
+	Filter      FlattenFilter          `json:"filter"`
+	RequestedBy string                 `json:"requested_by,omitempty"`
+	OpenOrders  []*interfaces.Order    `json:"open_orders"`
+	Positions   []*interfaces.Position `json:"positions"`
+}
+
+type pendingFlatten struct {
+	filter    string
+	expiresAt time.Time
+}
+
+// FlattenService cancels open orders and market-closes positions, the
+// emergency exit. Over the API it takes two steps: a preview that issues a
+// single-use confirmation token, then the flatten itself.
+type FlattenService struct {
+	trading         interfaces.TradingService
+	auditor         *OrderAuditor
+	positionManager *PositionManager // nil when managed positions are not loaded
+	pending         map[string]pendingFlatten
+	mu              sync.Mutex
+	logger          *logrus.Logger
+}
+
+// NewFlattenService creates a flatten service. positionManager may be nil.
+func NewFlattenService(trading interfaces.TradingService, auditor *OrderAuditor, positionManager *PositionManager) *FlattenService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &FlattenService{
+		trading:         trading,
+		auditor:         auditor,
+		positionManager: positionManager,
+		pending:         make(map[string]pendingFlatten),
+		logger:          logger,
+	}
+}
+
+// Preview lists the orders and positions filter covers and issues a token
+// confirming a flatten of exactly that filter
+func (fs *FlattenService) Preview(ctx context.Context, filter FlattenFilter) (*FlattenPreview, error) {
+	orders, positions, err := fs.targets(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	token := hex.EncodeToString(secret)
+	expiresAt := time.Now().Add(flattenTokenTTL)
+
+	fs.mu.Lock()
+	for t, p := range fs.pending {
+		if time.Now().After(p.expiresAt) {
+			delete(fs.pending, t)
+		}
+	}
+	fs.pending[token] = pendingFlatten{filter: filter.key(), expiresAt: expiresAt}
+	fs.mu.Unlock()
+
+	return &FlattenPreview{
+		Filter:       filter,
+		OpenOrders:   orders,
+		Positions:    positions,
+		ConfirmToken: token,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// Confirm flattens filter if token was issued for it by Preview and has not
+// expired or been used
+func (fs *FlattenService) Confirm(ctx context.Context, token string, filter FlattenFilter, requestedBy string) (*FlattenResult, error) {
+	fs.mu.Lock()
+	pending, ok := fs.pending[token]
+	delete(fs.pending, token)
+	fs.mu.Unlock()
+
+	if !ok || time.Now().After(pending.expiresAt) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("confirmation token is invalid or expired, request a new preview"))
+	}
+	if pending.filter != filter.key() {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("confirmation token was issued for a different filter"))
+	}
+
+	return fs.Flatten(ctx, filter, requestedBy)
+}
+
+// Flatten cancels every open order and market-closes every position filter
+// covers. It keeps going on individual failures and reports them in the
+// result. Matching managed positions are released first so nothing re-places
+// their stops.
+func (fs *FlattenService) Flatten(ctx context.Context, filter FlattenFilter, requestedBy string) (*FlattenResult, error) {
+	start := time.Now()
+	ctx = WithOrderSource(ctx, OrderSourceFlatten)
+	result := &FlattenResult{
+		FlattenID:      fmt.Sprintf("flatten-%d", start.UnixNano()),
+		Filter:         filter,
+		CanceledOrders: make([]string, 0),
+		ClosedOrders:   make([]*interfaces.OrderResult, 0),
+	}
+
+	// Released before the open orders are listed, so a stop the monitor
+	// places in between is still canceled
+	if fs.positionManager != nil {
+		result.ReleasedManaged = fs.positionManager.ReleaseManagedPositions(filter.Matches, "closed by flatten "+result.FlattenID)
+	}
+
+	openOrders, positions, err := fs.targets(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range openOrders {
+		if err := fs.trading.CancelOrder(ctx, order.ID); err != nil {
+			fs.logger.WithError(err).WithField("order_id", order.ID).Warn("Failed to cancel order during flatten")
+			result.Errors = append(result.Errors, fmt.Sprintf("cancel %s: %v", order.ID, err))
+			continue
+		}
+		result.CanceledOrders = append(result.CanceledOrders, order.ID)
+	}
+
+	for _, position := range positions {
+		orderResult, err := fs.closePosition(ctx, position)
+		if err != nil {
+			fs.logger.WithError(err).WithField("symbol", position.Symbol).Error("Failed to close position during flatten")
+			result.Errors = append(result.Errors, fmt.Sprintf("close %s: %v", position.Symbol, err))
+			continue
+		}
+		result.ClosedOrders = append(result.ClosedOrders, orderResult)
+	}
+
+	attempt := OrderAttempt{
+		OrderID:    result.FlattenID,
+		Symbol:     strings.ToUpper(strings.Join(filter.Symbols, ",")),
+		Side:       "flatten",
+		AssetClass: filter.AssetClass,
+		Outcome:    AuditOutcomeSubmitted,
+		Request: flattenAuditRequest{
+			Filter:      filter,
+			RequestedBy: requestedBy,
+			OpenOrders:  openOrders,
+			Positions:   positions,
+		},
+		Response: result,
+		Latency:  time.Since(start),
+	}
+	if attempt.Symbol == "" {
+		attempt.Symbol = "*"
+	}
+	if len(result.Errors) > 0 {
+		attempt.Outcome = AuditOutcomeFailed
+		attempt.Err = fmt.Errorf("%d of %d actions failed", len(result.Errors), len(openOrders)+len(positions))
+	}
+	fs.auditor.Record(ctx, attempt)
+
+	fs.logger.WithFields(logrus.Fields{
+		"flatten_id":   result.FlattenID,
+		"requested_by": requestedBy,
+		"canceled":     len(result.CanceledOrders),
+		"closed":       len(result.ClosedOrders),
+		"errors":       len(result.Errors),
+	}).Warn("Flatten completed")
+
+	return result, nil
+}
+
+// targets returns the open orders and positions filter covers
+func (fs *FlattenService) targets(ctx context.Context, filter FlattenFilter) ([]*interfaces.Order, []*interfaces.Position, error) {
+	allOrders, err := fs.trading.ListOrders(ctx, "open")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list open orders: %w", err)
+	}
+	orders := make([]*interfaces.Order, 0, len(allOrders))
+	for _, order := range allOrders {
+		if filter.Matches(order.Symbol) {
+			orders = append(orders, order)
+		}
+	}
+
+	allPositions, err := fs.trading.GetPositions(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+	positions := make([]*interfaces.Position, 0, len(allPositions))
+	for _, position := range allPositions {
+		if filter.Matches(position.Symbol) {
+			positions = append(positions, position)
+		}
+	}
+
+	return orders, positions, nil
+}
+
+// closePosition places a market order for the whole position
+func (fs *FlattenService) closePosition(ctx context.Context, position *interfaces.Position) (*interfaces.OrderResult, error) {
+	side := "sell"
+	if position.Qty.IsNegative() || position.Side == "short" {
+		side = "buy"
+	}
+
+	if IsOCCSymbol(position.Symbol) {
+		return fs.trading.PlaceOptionsOrder(ctx, &interfaces.OptionsOrder{
+			Symbol:         position.Symbol,
+			Underlying:     underlyingSymbol(position.Symbol),
+			Qty:            position.Qty.Abs(),
+			Side:           side,
+			PositionIntent: side + "_to_close",
+			Type:           "market",
+			TimeInForce:    "day",
+		})
+	}
+
+	return fs.trading.PlaceOrder(ctx, &interfaces.Order{
+		Symbol:      position.Symbol,
+		Qty:         position.Qty.Abs(),
+		Side:        side,
+		Type:        "market",
+		TimeInForce: "day",
+		Status:      "pending",
+		SubmittedAt: time.Now(),
+	})
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/config"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fundamentalsCacheTTL bounds how often a symbol's fundamentals are refetched;
+// they only change with filings, estimates and price
+const fundamentalsCacheTTL = 6 * time.Hour
+
+// Fundamentals are valuation, growth and analyst data for one symbol.
+// Fields a provider does not report are left at zero.
+type Fundamentals struct {
+	Symbol            string     `json:"symbol"`
+	Name              string     `json:"name,omitempty"`
+	Currency          string     `json:"currency,omitempty"`
+	Price             float64    `json:"price"`
+	MarketCap         float64    `json:"market_cap"`
+	TrailingPE        float64    `json:"trailing_pe"`
+	ForwardPE         float64    `json:"forward_pe"`
+	PEGRatio          float64    `json:"peg_ratio"`
+	EPS               float64    `json:"eps_ttm"`
+	ForwardEPS        float64    `json:"forward_eps"`
+	RevenueGrowthPct  float64    `json:"revenue_growth_percent"`  // year over year, latest quarter
+	EarningsGrowthPct float64    `json:"earnings_growth_percent"` // year over year, latest quarter
+	ProfitMarginPct   float64    `json:"profit_margin_percent"`
+	DividendYieldPct  float64    `json:"dividend_yield_percent"`
+	Beta              float64    `json:"beta"`
+	High52Week        float64    `json:"high_52_week"`
+	Low52Week         float64    `json:"low_52_week"`
+	TargetMeanPrice   float64    `json:"target_mean_price"`
+	TargetHighPrice   float64    `json:"target_high_price"`
+	TargetLowPrice    float64    `json:"target_low_price"`
+	TargetUpsidePct   float64    `json:"target_upside_percent"` // mean target vs price
+	AnalystCount      int        `json:"analyst_count"`
+	Recommendation    string     `json:"recommendation,omitempty"` // e.g. "buy", "hold"
+	NextEarningsDate  *time.Time `json:"next_earnings_date,omitempty"`
+	DaysToEarnings    *int       `json:"days_to_earnings,omitempty"`
+	Source            string     `json:"source"`
+	FetchedAt         time.Time  `json:"fetched_at"`
+}
+
+// MarketCapLabel classifies the market cap into the usual size buckets
+func (f *Fundamentals) MarketCapLabel() string {
+	mc := f.MarketCap
+	switch {
+	case mc <= 0:
+		return ""
+	case mc >= 200e9:
+		return fmt.Sprintf("Mega-cap (%s)", formatDollars(mc))
+	case mc >= 10e9:
+		return fmt.Sprintf("Large-cap (%s)", formatDollars(mc))
+	case mc >= 2e9:
+		return fmt.Sprintf("Mid-cap (%s)", formatDollars(mc))
+	case mc >= 300e6:
+		return fmt.Sprintf("Small-cap (%s)", formatDollars(mc))
+	default:
+		return fmt.Sprintf("Micro-cap (%s)", formatDollars(mc))
+	}
+}
+
+// Summary condenses the fundamentals into one line for AI prompts
+func (f *Fundamentals) Summary() string {
+	parts := []string{}
+	if f.MarketCap > 0 {
+		parts = append(parts, "Mkt cap "+formatDollars(f.MarketCap))
+	}
+	if f.TrailingPE > 0 {
+		parts = append(parts, fmt.Sprintf("P/E %.1f", f.TrailingPE))
+	}
+	if f.ForwardPE > 0 {
+		parts = append(parts, fmt.Sprintf("Fwd P/E %.1f", f.ForwardPE))
+	}
+	if f.EPS != 0 {
+		parts = append(parts, fmt.Sprintf("EPS %.2f", f.EPS))
+	}
+	if f.RevenueGrowthPct != 0 {
+		parts = append(parts, fmt.Sprintf("Rev growth %+.1f%%", f.RevenueGrowthPct))
+	}
+	if f.TargetMeanPrice > 0 {
+		parts = append(parts, fmt.Sprintf("Target %.2f (%+.1f%%, %d analysts)", f.TargetMeanPrice, f.TargetUpsidePct, f.AnalystCount))
+	}
+	if f.NextEarningsDate != nil {
+		parts = append(parts, "Earnings "+f.NextEarningsDate.Format("2006-01-02"))
+	}
+	return strings.Join(parts, " | ")
+}
+
+func formatDollars(v float64) string {
+	switch {
+	case v >= 1e12:
+		return fmt.Sprintf("$%.2fT", v/1e12)
+	case v >= 1e9:
+		return fmt.Sprintf("$%.2fB", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("$%.1fM", v/1e6)
+	default:
+		return fmt.Sprintf("$%.0f", v)
+	}
+}
+
+// FundamentalsProvider fetches fundamentals from an external source
+type FundamentalsProvider interface {
+	GetFundamentals(ctx context.Context, symbol string) (*Fundamentals, error)
+}
+
+// FundamentalsProviderFactory creates a fundamentals provider from the application config
+type FundamentalsProviderFactory func(cfg *config.Config) (FundamentalsProvider, error)
+
+var fundamentalsProviders = map[string]FundamentalsProviderFactory{}
+
+// RegisterFundamentalsProvider makes a FundamentalsProvider selectable by name
+func RegisterFundamentalsProvider(name string, factory FundamentalsProviderFactory) {
+	fundamentalsProviders[strings.ToLower(name)] = factory
+}
+
+// NewFundamentalsProvider creates the fundamentals provider registered under name
+func NewFundamentalsProvider(name string, cfg *config.Config) (FundamentalsProvider, error) {
+	factory, ok := fundamentalsProviders[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown fundamentals provider: %s (available: %v)", name, FundamentalsProviderNames())
+	}
+
+	provider, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s fundamentals provider: %w", name, err)
+	}
+	return provider, nil
+}
+
+// FundamentalsProviderNames returns the registered provider names in sorted order
+func FundamentalsProviderNames() []string {
+	names := make([]string, 0, len(fundamentalsProviders))
+	for name := range fundamentalsProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FundamentalsService serves cached fundamentals from a provider
+type FundamentalsService struct {
+	provider FundamentalsProvider
+	cache    map[string]*Fundamentals
+	mu       sync.Mutex
+	logger   *logrus.Logger
+}
+
+// NewFundamentalsService creates a fundamentals service
+func NewFundamentalsService(provider FundamentalsProvider) *FundamentalsService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &FundamentalsService{
+		provider: provider,
+		cache:    make(map[string]*Fundamentals),
+		logger:   logger,
+	}
+}
+
+// Get returns fundamentals for symbol, fetching at most every few hours
+func (fs *FundamentalsService) Get(ctx context.Context, symbol string) (*Fundamentals, error) {
+	symbol = strings.ToUpper(symbol)
+
+	fs.mu.Lock()
+	cached, ok := fs.cache[symbol]
+	fs.mu.Unlock()
+	if ok && time.Since(cached.FetchedAt) < fundamentalsCacheTTL {
+		return cached, nil
+	}
+
+	started := time.Now()
+	f, err := fs.provider.GetFundamentals(ctx, symbol)
+	RecordUpstreamCall(ctx, UpstreamFundamentals, started, err)
+	if err != nil {
+		fs.logger.WithError(err).WithField("symbol", symbol).Warn("Failed to fetch fundamentals")
+		return nil, fmt.Errorf("failed to get fundamentals for %s: %w", symbol, err)
+	}
+
+	if f.Price > 0 && f.TargetMeanPrice > 0 {
+		f.TargetUpsidePct = (f.TargetMeanPrice/f.Price - 1) * 100
+	}
+	if f.NextEarningsDate != nil {
+		days := int(time.Until(*f.NextEarningsDate).Hours() / 24)
+		f.DaysToEarnings = &days
+	}
+	if f.FetchedAt.IsZero() {
+		f.FetchedAt = time.Now()
+	}
+
+	fs.mu.Lock()
+	fs.cache[symbol] = f
+	fs.mu.Unlock()
+
+	return f, nil
+}
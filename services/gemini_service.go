@@ -2,44 +2,84 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"prophet-trader/config"
 	"strings"
+	"sync"
 	"time"
 )
 
 // GeminiService handles interactions with Google's Gemini AI API
 type GeminiService struct {
 	apiKey     string
+	keyMu      sync.RWMutex
 	httpClient *http.Client
 	model      string
+	prompts    *PromptStore   // nil uses the built-in prompts
+	tools      *LLMToolBridge // nil answers from the prompt alone
 }
 
 // GeminiRequest represents a request to Gemini API
 type GeminiRequest struct {
-	Contents []GeminiContent `json:"contents"`
+	Contents   []GeminiContent   `json:"contents"`
+	Tools      []GeminiTool      `json:"tools,omitempty"`
+	ToolConfig *GeminiToolConfig `json:"toolConfig,omitempty"`
 }
 
 // GeminiContent represents content in the request
 type GeminiContent struct {
+	Role  string       `json:"role,omitempty"` // "user" or "model" in multi-turn requests
 	Parts []GeminiPart `json:"parts"`
 }
 
 // GeminiPart represents a part of the content
 type GeminiPart struct {
-	Text string `json:"text"`
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiFunctionCall is a tool call requested by the model
+type GeminiFunctionCall struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse returns a tool result to the model
+type GeminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+// GeminiTool declares the functions the model may call
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GeminiFunctionDeclaration describes one callable function
+type GeminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"` // OpenAPI schema object
+}
+
+// GeminiToolConfig controls whether the model may call functions
+type GeminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode string `json:"mode"` // "AUTO" or "NONE"
+	} `json:"functionCallingConfig"`
 }
 
 // GeminiResponse represents the response from Gemini API
 type GeminiResponse struct {
 	Candidates []struct {
 		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
+			Parts []GeminiPart `json:"parts"`
 		} `json:"content"`
 	} `json:"candidates"`
 }
@@ -72,9 +112,39 @@ func NewGeminiService(apiKey string) *GeminiService {
 	}
 }
 
+// RotateCredentials switches to the Gemini key in cfg
+func (gs *GeminiService) RotateCredentials(cfg *config.Config) {
+	gs.keyMu.Lock()
+	defer gs.keyMu.Unlock()
+	gs.apiKey = cfg.GeminiAPIKey
+}
+
+func (gs *GeminiService) key() string {
+	gs.keyMu.RLock()
+	defer gs.keyMu.RUnlock()
+	return gs.apiKey
+}
+
+// SetToolBridge lets the model call read-only data tools while answering
+// chat messages
+func (gs *GeminiService) SetToolBridge(tools *LLMToolBridge) {
+	gs.tools = tools
+}
+
+// SetPromptStore renders prompts from editable templates
+func (gs *GeminiService) SetPromptStore(prompts *PromptStore) {
+	gs.prompts = prompts
+}
+
 // CleanNewsForTrading takes raw news items and creates a token-efficient summary
 // optimized for trading decisions
-func (gs *GeminiService) CleanNewsForTrading(newsItems []NewsItem) (*CleanedNews, error) {
+func (gs *GeminiService) CleanNewsForTrading(ctx context.Context, newsItems []NewsItem) (*CleanedNews, error) {
+	return gs.CleanNewsWithHistory(ctx, newsItems, nil)
+}
+
+// CleanNewsWithHistory is CleanNewsForTrading with earlier related news and
+// analyses given to the model as background
+func (gs *GeminiService) CleanNewsWithHistory(ctx context.Context, newsItems []NewsItem, history []*SemanticMatch) (*CleanedNews, error) {
 	if len(newsItems) == 0 {
 		return nil, fmt.Errorf("no news items provided")
 	}
@@ -93,32 +163,17 @@ func (gs *GeminiService) CleanNewsForTrading(newsItems []NewsItem) (*CleanedNews
 	}
 
 	// Create a trading-focused prompt
-	prompt := fmt.Sprintf(`You are a financial analyst AI. Analyze the following %d news articles and create a CONCISE trading intelligence report.
-
-NEWS ARTICLES:
-%s
-
-Provide a JSON response with this EXACT structure:
-{
-  "market_sentiment": "BULLISH|BEARISH|NEUTRAL",
-  "key_themes": ["theme1", "theme2", "theme3"],
-  "stock_mentions": {
-    "SYMBOL": "POSITIVE|NEGATIVE|NEUTRAL with 1-sentence reason"
-  },
-  "actionable_items": ["brief actionable insight 1", "brief actionable insight 2"],
-  "executive_summary": "2-3 sentence summary of the market situation"
-}
-
-Focus on:
-- Stock symbols and their sentiment
-- Market-moving themes
-- Actionable trading insights
-- Overall market direction
-
-Keep it BRIEF and DENSE. Maximum 200 tokens total.`, len(newsItems), newsText.String())
+	prompt, err := gs.prompts.Render(ctx, PromptCleanedNews, map[string]interface{}{
+		"Count":    len(newsItems),
+		"Articles": newsText.String(),
+		"History":  formatRelatedHistory(history),
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Call Gemini
-	response, err := gs.generateContent(prompt)
+	response, err := gs.generateContent(ctx, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate content: %w", err)
 	}
@@ -154,59 +209,242 @@ Keep it BRIEF and DENSE. Maximum 200 tokens total.`, len(newsItems), newsText.St
 	return &cleanedNews, nil
 }
 
+// SummarizeFiling condenses the text of an SEC filing into a short factual
+// summary for traders
+func (gs *GeminiService) SummarizeFiling(ctx context.Context, symbol, form, text string) (string, error) {
+	if gs.key() == "" {
+		return "", fmt.Errorf("Gemini API key not configured")
+	}
+
+	prompt, err := gs.prompts.Render(ctx, PromptFilingSummary, map[string]interface{}{
+		"Symbol": symbol,
+		"Form":   form,
+		"Text":   text[:min(15000, len(text))],
+	})
+	if err != nil {
+		return "", err
+	}
+
+	response, err := gs.generateContent(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	return strings.TrimSpace(response), nil
+}
+
+// NewsBriefSummary is the market-wide and per-symbol text of a market brief
+type NewsBriefSummary struct {
+	Market  string            `json:"market"`
+	Symbols map[string]string `json:"symbols"`
+}
+
+// SummarizeNewsBrief revises a rolling market brief with new headlines. The
+// previous summaries are carried forward so each update only sends what is
+// new; symbols without new headlines are not rewritten.
+func (gs *GeminiService) SummarizeNewsBrief(ctx context.Context, previous *NewsBriefSummary, market []NewsItem, symbols map[string][]NewsItem) (*NewsBriefSummary, error) {
+	if gs.key() == "" {
+		return nil, fmt.Errorf("Gemini API key not configured")
+	}
+
+	headlines := func(b *strings.Builder, items []NewsItem) {
+		for _, item := range items {
+			fmt.Fprintf(b, "- %s", item.Title)
+			if item.Source != "" {
+				fmt.Fprintf(b, " (%s)", item.Source)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	var text strings.Builder
+	text.WriteString("PREVIOUS MARKET SUMMARY:\n")
+	if previous != nil && previous.Market != "" {
+		text.WriteString(previous.Market + "\n")
+	} else {
+		text.WriteString("(none)\n")
+	}
+	text.WriteString("\nNEW MARKET HEADLINES:\n")
+	headlines(&text, market)
+	for symbol, items := range symbols {
+		fmt.Fprintf(&text, "\n%s\nPrevious summary: ", symbol)
+		if previous != nil && previous.Symbols[symbol] != "" {
+			text.WriteString(previous.Symbols[symbol] + "\n")
+		} else {
+			text.WriteString("(none)\n")
+		}
+		text.WriteString("New headlines:\n")
+		headlines(&text, items)
+	}
+
+	prompt, err := gs.prompts.Render(ctx, PromptMarketBrief, map[string]interface{}{
+		"Context": text.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := gs.generateContent(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	jsonStart := strings.Index(response, "{")
+	jsonEnd := strings.LastIndex(response, "}")
+	if jsonStart < 0 || jsonEnd <= jsonStart {
+		return nil, fmt.Errorf("no JSON in brief response")
+	}
+	var summary NewsBriefSummary
+	if err := json.Unmarshal([]byte(response[jsonStart:jsonEnd+1]), &summary); err != nil {
+		return nil, fmt.Errorf("failed to parse brief response: %w", err)
+	}
+	return &summary, nil
+}
+
+// ChatMessage is one turn of an intelligence chat
+type ChatMessage struct {
+	Role string    `json:"role"` // "user" or "model"
+	Text string    `json:"text"`
+	At   time.Time `json:"at"`
+}
+
+// Chat answers question as the next turn after history. The trading context
+// is rendered into the question only, so earlier turns are sent as asked.
+func (gs *GeminiService) Chat(ctx context.Context, history []ChatMessage, tradingContext, question string) (string, error) {
+	prompt, err := gs.prompts.Render(ctx, PromptChat, map[string]interface{}{
+		"Context":  tradingContext,
+		"Question": question,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	contents := make([]GeminiContent, 0, len(history)+1)
+	for _, message := range history {
+		contents = append(contents, GeminiContent{Role: message.Role, Parts: []GeminiPart{{Text: message.Text}}})
+	}
+	contents = append(contents, GeminiContent{Role: "user", Parts: []GeminiPart{{Text: prompt}}})
+
+	response, err := gs.generateWithTools(ctx, PromptChat, contents)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate content: %w", err)
+	}
+	return strings.TrimSpace(response), nil
+}
+
 // generateContent calls the Gemini API
-func (gs *GeminiService) generateContent(prompt string) (string, error) {
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
-		gs.model, gs.apiKey)
-
-	reqBody := GeminiRequest{
-		Contents: []GeminiContent{
-			{
-				Parts: []GeminiPart{
-					{Text: prompt},
-				},
+func (gs *GeminiService) generateContent(ctx context.Context, prompt string) (text string, err error) {
+	return gs.generate(ctx, []GeminiContent{
+		{
+			Parts: []GeminiPart{
+				{Text: prompt},
 			},
 		},
+	})
+}
+
+// generate sends contents, a single prompt or a conversation, to the model
+func (gs *GeminiService) generate(ctx context.Context, contents []GeminiContent) (string, error) {
+	resp, err := gs.send(ctx, &GeminiRequest{Contents: contents})
+	if err != nil {
+		return "", err
+	}
+	return resp.Candidates[0].Content.Parts[0].Text, nil
+}
+
+// generateWithTools is generate, letting the model call the tool bridge's
+// functions first. Once the bridge's call limit is used up the model must
+// answer with what it has.
+func (gs *GeminiService) generateWithTools(ctx context.Context, source string, contents []GeminiContent) (string, error) {
+	if gs.tools == nil {
+		return gs.generate(ctx, contents)
+	}
+
+	run := gs.tools.newRun(source)
+	tools := []GeminiTool{{FunctionDeclarations: gs.tools.declarations()}}
+	for {
+		final := run.remaining() == 0
+		req := &GeminiRequest{Contents: contents, Tools: tools, ToolConfig: &GeminiToolConfig{}}
+		req.ToolConfig.FunctionCallingConfig.Mode = "AUTO"
+		if final {
+			req.ToolConfig.FunctionCallingConfig.Mode = "NONE"
+		}
+
+		resp, err := gs.send(ctx, req)
+		if err != nil {
+			return "", err
+		}
+
+		parts := resp.Candidates[0].Content.Parts
+		var text strings.Builder
+		var results []GeminiPart
+		for _, part := range parts {
+			if part.FunctionCall == nil || final {
+				text.WriteString(part.Text)
+				continue
+			}
+			results = append(results, GeminiPart{FunctionResponse: &GeminiFunctionResponse{
+				Name:     part.FunctionCall.Name,
+				Response: run.invoke(ctx, part.FunctionCall.Name, part.FunctionCall.Args),
+			}})
+		}
+		if len(results) == 0 {
+			return text.String(), nil
+		}
+
+		contents = append(contents,
+			GeminiContent{Role: "model", Parts: parts},
+			GeminiContent{Role: "user", Parts: results},
+		)
 	}
+}
+
+// send makes one generateContent request
+func (gs *GeminiService) send(ctx context.Context, reqBody *GeminiRequest) (geminiResp *GeminiResponse, err error) {
+	started := time.Now()
+	defer func() { RecordUpstreamCall(ctx, UpstreamGemini, started, err) }()
+
+	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent", gs.model)
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", gs.key())
 
 	resp, err := gs.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var geminiResp GeminiResponse
-	if err := json.Unmarshal(body, &geminiResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	geminiResp = &GeminiResponse{}
+	if err := json.Unmarshal(body, geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no content in response")
+		return nil, fmt.Errorf("no content in response")
 	}
 
-	return geminiResp.Candidates[0].Content.Parts[0].Text, nil
+	return geminiResp, nil
 }
 
 // Helper functions
@@ -0,0 +1,688 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Grid statuses
+const (
+	GridActive  = "ACTIVE"
+	GridPaused  = "PAUSED" // orders withdrawn from the broker, restored on resume
+	GridStopped = "STOPPED"
+	GridDryRun  = "DRY_RUN"
+)
+
+// Grid order statuses
+const (
+	GridOrderOpen      = "OPEN"
+	GridOrderFilled    = "FILLED"
+	GridOrderSuspended = "SUSPENDED" // canceled at the broker while the grid is paused
+	GridOrderCanceled  = "CANCELED"
+	GridOrderRejected  = "REJECTED" // failed the risk checks or refused by the broker
+	GridOrderPlanned   = "PLANNED"  // dry run, never placed
+)
+
+// GridStrategy tags grid orders and trades for P&L attribution
+const GridStrategy = "grid"
+
+// GridRequest is the payload for starting a grid
+type GridRequest struct {
+	Symbol         string           `json:"symbol" binding:"required,symbol"`
+	ReferencePrice *decimal.Decimal `json:"reference_price,omitempty" binding:"omitempty,gt=0"`   // defaults to the current price
+	Spacing        *decimal.Decimal `json:"spacing,omitempty" binding:"omitempty,gt=0"`           // dollars between levels
+	SpacingPct     *float64         `json:"spacing_pct,omitempty" binding:"omitempty,gt=0,lt=50"` // or percent of the reference price
+	Levels         int              `json:"levels" binding:"required,min=1,max=50"`               // levels on each side of the reference price
+	QtyPerLevel    decimal.Decimal  `json:"qty_per_level" binding:"gt=0"`
+	Seed           bool             `json:"seed,omitempty"` // buy levels x qty_per_level at market to back a sell ladder above the reference
+	DryRun         bool             `json:"dry_run"`
+}
+
+// Grid is a grid with its bookkeeping and P&L
+type Grid struct {
+	ID             string          `json:"id"`
+	Symbol         string          `json:"symbol"`
+	ReferencePrice decimal.Decimal `json:"reference_price"`
+	Spacing        decimal.Decimal `json:"spacing"`
+	Levels         int             `json:"levels"`
+	QtyPerLevel    decimal.Decimal `json:"qty_per_level"`
+	LowerPrice     decimal.Decimal `json:"lower_price"`
+	UpperPrice     decimal.Decimal `json:"upper_price"`
+	Seeded         bool            `json:"seeded"`
+	Book           string          `json:"book"`
+	Status         string          `json:"status"`
+	OpenOrders     int             `json:"open_orders"`
+	RoundTrips     int             `json:"round_trips"`
+	RealizedPnL    decimal.Decimal `json:"realized_pnl"`
+	Inventory      decimal.Decimal `json:"inventory"` // shares bought by the grid and not yet sold
+	AverageCost    decimal.Decimal `json:"average_cost"`
+	CurrentPrice   decimal.Decimal `json:"current_price,omitempty"`
+	UnrealizedPnL  decimal.Decimal `json:"unrealized_pnl"`
+	CreatedAt      time.Time       `json:"created_at"`
+	StoppedAt      *time.Time      `json:"stopped_at,omitempty"`
+	Orders         []*GridOrder    `json:"orders,omitempty"`
+}
+
+// GridOrder is one order a grid placed
+type GridOrder struct {
+	Level       int              `json:"level"`
+	Side        string           `json:"side"`
+	Price       decimal.Decimal  `json:"price"`
+	Qty         decimal.Decimal  `json:"qty"`
+	EntryPrice  *decimal.Decimal `json:"entry_price,omitempty"`
+	OrderID     string           `json:"order_id,omitempty"`
+	Status      string           `json:"status"`
+	FilledPrice *decimal.Decimal `json:"filled_price,omitempty"`
+	FilledAt    *time.Time       `json:"filled_at,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// GridService runs grid trading ladders. A grid rests buy limits below its
+// reference price and, when seeded, sell limits above it. Every filled buy
+// is answered with a sell one level up and every filled sell with a buy one
+// level down, so each completed pair books one spacing of profit.
+type GridService struct {
+	storage     *database.LocalStorage
+	trading     interfaces.TradingService
+	dataService interfaces.DataService
+	riskManager *RiskManager
+	notifier    *Notifier
+	dryRun      bool
+	mu          sync.Mutex
+	logger      *logrus.Logger
+}
+
+// NewGridService creates a grid service
+func NewGridService(storage *database.LocalStorage, trading interfaces.TradingService, dataService interfaces.DataService, riskManager *RiskManager, notifier *Notifier, dryRun bool) *GridService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &GridService{
+		storage:     storage,
+		trading:     trading,
+		dataService: dataService,
+		riskManager: riskManager,
+		notifier:    notifier,
+		dryRun:      dryRun,
+		logger:      logger,
+	}
+}
+
+// CreateGrid validates a grid and places its ladder. In dry-run mode the
+// ladder is returned without placing or saving anything.
+func (s *GridService) CreateGrid(ctx context.Context, req *GridRequest) (*Grid, error) {
+	symbol := strings.ToUpper(req.Symbol)
+	if IsOCCSymbol(symbol) {
+		return nil, WithErrorCode(ErrCodeNotSupported, fmt.Errorf("grids trade stocks and ETFs, not options"))
+	}
+	if (req.Spacing == nil) == (req.SpacingPct == nil) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("set exactly one of spacing or spacing_pct"))
+	}
+
+	reference := decimal.Zero
+	if req.ReferencePrice != nil {
+		reference = *req.ReferencePrice
+	} else {
+		quote, err := s.dataService.GetLatestQuote(ctx, symbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get quote for reference price: %w", err)
+		}
+		reference = decimal.NewFromFloat((quote.BidPrice + quote.AskPrice) / 2)
+	}
+	reference = reference.Round(2)
+
+	spacing := decimal.Zero
+	if req.Spacing != nil {
+		spacing = *req.Spacing
+	} else {
+		spacing = reference.Mul(decimal.NewFromFloat(*req.SpacingPct)).Div(decimal.NewFromInt(100))
+	}
+	spacing = spacing.Round(2)
+	if spacing.LessThan(decimal.NewFromFloat(0.01)) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("spacing must be at least $0.01"))
+	}
+
+	dbGrid := &models.DBGrid{
+		GridID:         fmt.Sprintf("grid_%d", time.Now().UnixNano()),
+		Symbol:         symbol,
+		ReferencePrice: reference,
+		Spacing:        spacing,
+		Levels:         req.Levels,
+		QtyPerLevel:    req.QtyPerLevel,
+		Seeded:         req.Seed,
+		Book:           BookFrom(ctx),
+		Status:         GridActive,
+	}
+	if !gridPrice(dbGrid, -dbGrid.Levels).IsPositive() {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("the lowest level would be at or below $0; use fewer levels or a smaller spacing"))
+	}
+
+	if req.DryRun || s.dryRun {
+		dbGrid.Status = GridDryRun
+		grid := gridFromDB(dbGrid, nil)
+		for _, order := range s.initialOrders(dbGrid) {
+			order.Status = GridOrderPlanned
+			grid.Orders = append(grid.Orders, gridOrderFromDB(order))
+		}
+		return grid, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.storage.SaveGrid(dbGrid); err != nil {
+		return nil, err
+	}
+	ctx = WithOrderSource(ctx, OrderSourceGrid)
+	for _, order := range s.initialOrders(dbGrid) {
+		s.place(ctx, dbGrid, order, order.Level == 0)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"id":        dbGrid.GridID,
+		"symbol":    symbol,
+		"reference": reference.String(),
+		"spacing":   spacing.String(),
+		"levels":    req.Levels,
+	}).Info("Grid started")
+
+	return s.getGrid(ctx, dbGrid.GridID)
+}
+
+// initialOrders is the starting ladder: buys below the reference price, or
+// the seed purchase whose fill places the sells above it
+func (s *GridService) initialOrders(grid *models.DBGrid) []*models.DBGridOrder {
+	orders := make([]*models.DBGridOrder, 0, grid.Levels+1)
+	if grid.Seeded {
+		orders = append(orders, &models.DBGridOrder{
+			GridID: grid.GridID,
+			Level:  0,
+			Side:   "buy",
+			Price:  grid.ReferencePrice,
+			Qty:    grid.QtyPerLevel.Mul(decimal.NewFromInt(int64(grid.Levels))),
+		})
+	}
+	for level := -1; level >= -grid.Levels; level-- {
+		orders = append(orders, &models.DBGridOrder{
+			GridID: grid.GridID,
+			Level:  level,
+			Side:   "buy",
+			Price:  gridPrice(grid, level),
+			Qty:    grid.QtyPerLevel,
+		})
+	}
+	return orders
+}
+
+// place risk-checks and submits one grid order and records it. Only the
+// seed purchase goes out at market.
+func (s *GridService) place(ctx context.Context, grid *models.DBGrid, order *models.DBGridOrder, market bool) {
+	ctx = WithBook(ctx, bookOrDefault(grid.Book))
+	order.Status = GridOrderOpen
+	brokerOrder := &interfaces.Order{
+		Symbol:      grid.Symbol,
+		Qty:         order.Qty,
+		Side:        order.Side,
+		Type:        "limit",
+		TimeInForce: "gtc",
+		Status:      "pending",
+		SubmittedAt: time.Now(),
+		Strategy:    GridStrategy,
+		Book:        BookFrom(ctx),
+	}
+	if market {
+		brokerOrder.Type = "market"
+		brokerOrder.TimeInForce = "day"
+	} else {
+		price := order.Price
+		brokerOrder.LimitPrice = &price
+	}
+	// Fractional quantities can only rest as day orders
+	if !order.Qty.Equal(order.Qty.Floor()) {
+		brokerOrder.TimeInForce = "day"
+	}
+
+	reject := func(err error) {
+		order.Status = GridOrderRejected
+		order.Error = err.Error()
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"id":    grid.GridID,
+			"level": order.Level,
+			"side":  order.Side,
+		}).Warn("Grid order not placed")
+		s.notifier.Notify(ctx, NotifyWarning, "Grid order not placed",
+			fmt.Sprintf("%s %s %s %s @ %s: %s", grid.GridID, order.Side, order.Qty.String(), grid.Symbol, order.Price.StringFixed(2), err.Error()),
+			logrus.Fields{"id": grid.GridID, "symbol": grid.Symbol, "level": order.Level})
+	}
+
+	decision, err := s.riskManager.Evaluate(ctx, brokerOrder)
+	if err != nil {
+		reject(err)
+	} else if !decision.Approved {
+		reject(&RiskRejectedError{Decision: decision})
+	} else if result, err := s.trading.PlaceOrder(WithRiskDecision(ctx, decision), brokerOrder); err != nil {
+		reject(err)
+	} else {
+		order.OrderID = result.OrderID
+		brokerOrder.ID = result.OrderID
+		brokerOrder.Status = result.Status
+		if err := s.storage.SaveOrder(brokerOrder); err != nil {
+			s.logger.WithError(err).Warn("Failed to save order to database")
+		}
+	}
+
+	if err := s.storage.SaveGridOrder(order); err != nil {
+		s.logger.WithError(err).WithField("id", grid.GridID).Error("Failed to save grid order")
+	}
+}
+
+// ProcessFills checks the open orders of active grids and answers each fill
+// with the opposite order one level away
+func (s *GridService) ProcessFills(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	grids, err := s.storage.GetGrids(GridActive)
+	if err != nil {
+		return err
+	}
+
+	ctx = WithOrderSource(ctx, OrderSourceGrid)
+	for _, grid := range grids {
+		orders, err := s.storage.GetGridOrders(grid.GridID, GridOrderOpen)
+		if err != nil {
+			s.logger.WithError(err).WithField("id", grid.GridID).Error("Failed to load grid orders")
+			continue
+		}
+		changed := false
+		for _, order := range orders {
+			if s.checkOrder(ctx, grid, order) {
+				changed = true
+			}
+		}
+		if changed {
+			if err := s.storage.SaveGrid(grid); err != nil {
+				s.logger.WithError(err).WithField("id", grid.GridID).Error("Failed to update grid")
+			}
+		}
+	}
+	return nil
+}
+
+// checkOrder updates one open grid order from the broker. It returns true
+// when the grid's totals changed.
+func (s *GridService) checkOrder(ctx context.Context, grid *models.DBGrid, order *models.DBGridOrder) bool {
+	brokerOrder, err := s.trading.GetOrder(ctx, order.OrderID)
+	if err != nil {
+		s.logger.WithError(err).WithField("order_id", order.OrderID).Warn("Failed to get grid order")
+		return false
+	}
+
+	status := strings.ToLower(brokerOrder.Status)
+	switch status {
+	case "filled":
+	case "canceled", "expired", "rejected", "done_for_day":
+		if !brokerOrder.FilledQty.IsPositive() {
+			// Day orders expire every session; put the level back
+			order.Status = GridOrderCanceled
+			order.Error = "order " + status
+			s.saveGridOrder(grid, order)
+			if status != "rejected" {
+				s.place(ctx, grid, &models.DBGridOrder{
+					GridID:     grid.GridID,
+					Level:      order.Level,
+					Side:       order.Side,
+					Price:      order.Price,
+					Qty:        order.Qty,
+					EntryPrice: order.EntryPrice,
+				}, false)
+			}
+			return false
+		}
+		// A partial fill is answered for the filled quantity only
+		order.Qty = brokerOrder.FilledQty
+	default:
+		return false
+	}
+
+	fillPrice := order.Price
+	if brokerOrder.FilledAvgPrice != nil && brokerOrder.FilledAvgPrice.IsPositive() {
+		fillPrice = *brokerOrder.FilledAvgPrice
+	}
+	filledAt := time.Now()
+	if brokerOrder.FilledAt != nil {
+		filledAt = *brokerOrder.FilledAt
+	}
+	order.Status = GridOrderFilled
+	order.FilledPrice = &fillPrice
+	order.FilledAt = &filledAt
+	s.saveGridOrder(grid, order)
+
+	s.logger.WithFields(logrus.Fields{
+		"id":    grid.GridID,
+		"level": order.Level,
+		"side":  order.Side,
+		"qty":   order.Qty.String(),
+		"price": fillPrice.String(),
+	}).Info("Grid order filled")
+
+	if order.Side == "sell" {
+		s.recordRoundTrip(grid, order)
+		s.place(ctx, grid, &models.DBGridOrder{
+			GridID: grid.GridID,
+			Level:  order.Level - 1,
+			Side:   "buy",
+			Price:  gridPrice(grid, order.Level-1),
+			Qty:    order.Qty,
+		}, false)
+		return true
+	}
+
+	// The seed purchase backs one sell at every level above the reference
+	if grid.Seeded && order.Level == 0 && order.EntryPrice == nil && order.Qty.GreaterThan(grid.QtyPerLevel) {
+		remaining := order.Qty
+		for level := 1; level <= grid.Levels && remaining.IsPositive(); level++ {
+			qty := decimal.Min(grid.QtyPerLevel, remaining)
+			remaining = remaining.Sub(qty)
+			entry := fillPrice
+			s.place(ctx, grid, &models.DBGridOrder{
+				GridID:     grid.GridID,
+				Level:      level,
+				Side:       "sell",
+				Price:      gridPrice(grid, level),
+				Qty:        qty,
+				EntryPrice: &entry,
+			}, false)
+		}
+		return false
+	}
+
+	entry := fillPrice
+	s.place(ctx, grid, &models.DBGridOrder{
+		GridID:     grid.GridID,
+		Level:      order.Level + 1,
+		Side:       "sell",
+		Price:      gridPrice(grid, order.Level+1),
+		Qty:        order.Qty,
+		EntryPrice: &entry,
+	}, false)
+	return false
+}
+
+// recordRoundTrip books a filled sell against the buy it closes in the
+// grid's totals and the trade ledger
+func (s *GridService) recordRoundTrip(grid *models.DBGrid, sell *models.DBGridOrder) {
+	entry := sell.Price.Sub(grid.Spacing)
+	if sell.EntryPrice != nil {
+		entry = *sell.EntryPrice
+	}
+	pnl := sell.FilledPrice.Sub(entry).Mul(sell.Qty)
+	grid.RealizedPnL = grid.RealizedPnL.Add(pnl)
+	grid.RoundTrips++
+
+	pnlPercent := 0.0
+	if entry.IsPositive() {
+		pnlPercent = sell.FilledPrice.Sub(entry).Div(entry).Mul(decimal.NewFromInt(100)).InexactFloat64()
+	}
+	// The sell was placed the moment its buy filled
+	trade := &models.DBTrade{
+		Symbol:       grid.Symbol,
+		EntryPrice:   entry,
+		ExitPrice:    *sell.FilledPrice,
+		Qty:          sell.Qty,
+		Side:         "buy",
+		PnL:          pnl,
+		PnLPercent:   pnlPercent,
+		EntryTime:    sell.CreatedAt,
+		ExitTime:     *sell.FilledAt,
+		Duration:     int64(sell.FilledAt.Sub(sell.CreatedAt).Seconds()),
+		StrategyName: GridStrategy,
+		PositionID:   grid.GridID,
+		Book:         bookOrDefault(grid.Book),
+	}
+	if err := s.storage.SaveTrade(trade); err != nil {
+		s.logger.WithError(err).WithField("id", grid.GridID).Error("Failed to record trade")
+	}
+}
+
+func (s *GridService) saveGridOrder(grid *models.DBGrid, order *models.DBGridOrder) {
+	if err := s.storage.SaveGridOrder(order); err != nil {
+		s.logger.WithError(err).WithField("id", grid.GridID).Error("Failed to save grid order")
+	}
+}
+
+// PauseGrid withdraws a grid's open orders from the broker. ResumeGrid puts
+// them back at the same levels.
+func (s *GridService) PauseGrid(ctx context.Context, id string) (*Grid, error) {
+	return s.transition(ctx, id, GridActive, GridPaused, GridOrderSuspended)
+}
+
+// ResumeGrid re-places the orders of a paused grid
+func (s *GridService) ResumeGrid(ctx context.Context, id string) (*Grid, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	grid, err := s.storage.GetGrid(id)
+	if err != nil {
+		return nil, err
+	}
+	if grid.Status != GridPaused {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("grid %s is %s, not %s", id, grid.Status, GridPaused))
+	}
+	suspended, err := s.storage.GetGridOrders(id, GridOrderSuspended)
+	if err != nil {
+		return nil, err
+	}
+
+	grid.Status = GridActive
+	if err := s.storage.SaveGrid(grid); err != nil {
+		return nil, err
+	}
+	ctx = WithOrderSource(ctx, OrderSourceGrid)
+	for _, order := range suspended {
+		order.Status = GridOrderCanceled
+		s.saveGridOrder(grid, order)
+		s.place(ctx, grid, &models.DBGridOrder{
+			GridID:     grid.GridID,
+			Level:      order.Level,
+			Side:       order.Side,
+			Price:      order.Price,
+			Qty:        order.Qty,
+			EntryPrice: order.EntryPrice,
+		}, false)
+	}
+
+	s.logger.WithField("id", id).Info("Grid resumed")
+	return s.getGrid(ctx, id)
+}
+
+// StopGrid cancels a grid's orders for good. Shares the grid bought stay in
+// the account.
+func (s *GridService) StopGrid(ctx context.Context, id string) (*Grid, error) {
+	return s.transition(ctx, id, "", GridStopped, GridOrderCanceled)
+}
+
+// transition cancels the open orders of a grid and moves it to status.
+// from restricts the starting status; empty allows any but STOPPED.
+func (s *GridService) transition(ctx context.Context, id, from, status, orderStatus string) (*Grid, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	grid, err := s.storage.GetGrid(id)
+	if err != nil {
+		return nil, err
+	}
+	if grid.Status == GridStopped || (from != "" && grid.Status != from) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("grid %s is already %s", id, grid.Status))
+	}
+
+	orders, err := s.storage.GetGridOrders(id, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		switch order.Status {
+		case GridOrderOpen:
+			if err := s.trading.CancelOrder(ctx, order.OrderID); err != nil {
+				// The order may have filled in the meantime; let the next
+				// fill check pick it up rather than lose track of it
+				return nil, fmt.Errorf("failed to cancel grid order %s: %w", order.OrderID, err)
+			}
+		case GridOrderSuspended:
+		default:
+			continue
+		}
+		order.Status = orderStatus
+		s.saveGridOrder(grid, order)
+	}
+
+	grid.Status = status
+	if status == GridStopped {
+		now := time.Now()
+		grid.StoppedAt = &now
+	}
+	if err := s.storage.SaveGrid(grid); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"id":     id,
+		"status": status,
+	}).Info("Grid orders withdrawn")
+	return s.getGrid(ctx, id)
+}
+
+// ListGrids returns grids with their P&L, optionally filtered by status
+func (s *GridService) ListGrids(ctx context.Context, status string) ([]*Grid, error) {
+	dbGrids, err := s.storage.GetGrids(status)
+	if err != nil {
+		return nil, err
+	}
+
+	grids := make([]*Grid, 0, len(dbGrids))
+	for _, dbGrid := range dbGrids {
+		orders, err := s.storage.GetGridOrders(dbGrid.GridID, "")
+		if err != nil {
+			return nil, err
+		}
+		grid := gridFromDB(dbGrid, orders)
+		s.markToMarket(ctx, grid)
+		grids = append(grids, grid)
+	}
+	return grids, nil
+}
+
+// GetGrid returns a grid with its P&L and every order it placed
+func (s *GridService) GetGrid(ctx context.Context, id string) (*Grid, error) {
+	return s.getGrid(ctx, id)
+}
+
+func (s *GridService) getGrid(ctx context.Context, id string) (*Grid, error) {
+	dbGrid, err := s.storage.GetGrid(id)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.storage.GetGridOrders(id, "")
+	if err != nil {
+		return nil, err
+	}
+
+	grid := gridFromDB(dbGrid, orders)
+	s.markToMarket(ctx, grid)
+	grid.Orders = make([]*GridOrder, len(orders))
+	for i, order := range orders {
+		grid.Orders[i] = gridOrderFromDB(order)
+	}
+	return grid, nil
+}
+
+// markToMarket prices the grid's inventory when a quote is available
+func (s *GridService) markToMarket(ctx context.Context, grid *Grid) {
+	if !grid.Inventory.IsPositive() {
+		return
+	}
+	quote, err := s.dataService.GetLatestQuote(ctx, grid.Symbol)
+	if err != nil || quote.BidPrice <= 0 {
+		return
+	}
+	grid.CurrentPrice = decimal.NewFromFloat(quote.BidPrice)
+	grid.UnrealizedPnL = grid.CurrentPrice.Sub(grid.AverageCost).Mul(grid.Inventory).Round(2)
+}
+
+// gridPrice is the limit price of a level, rounded to the cent
+func gridPrice(grid *models.DBGrid, level int) decimal.Decimal {
+	return grid.ReferencePrice.Add(grid.Spacing.Mul(decimal.NewFromInt(int64(level)))).Round(2)
+}
+
+func gridFromDB(dbGrid *models.DBGrid, orders []*models.DBGridOrder) *Grid {
+	grid := &Grid{
+		ID:             dbGrid.GridID,
+		Symbol:         dbGrid.Symbol,
+		ReferencePrice: dbGrid.ReferencePrice,
+		Spacing:        dbGrid.Spacing,
+		Levels:         dbGrid.Levels,
+		QtyPerLevel:    dbGrid.QtyPerLevel,
+		LowerPrice:     gridPrice(dbGrid, -dbGrid.Levels),
+		UpperPrice:     gridPrice(dbGrid, dbGrid.Levels),
+		Seeded:         dbGrid.Seeded,
+		Book:           bookOrDefault(dbGrid.Book),
+		Status:         dbGrid.Status,
+		RoundTrips:     dbGrid.RoundTrips,
+		RealizedPnL:    dbGrid.RealizedPnL.Round(2),
+		CreatedAt:      dbGrid.CreatedAt,
+		StoppedAt:      dbGrid.StoppedAt,
+	}
+
+	// Inventory is what the filled buys added less what the filled sells
+	// took out, at the cost recorded on each sell
+	cost := decimal.Zero
+	for _, order := range orders {
+		switch {
+		case order.Status == GridOrderOpen:
+			grid.OpenOrders++
+		case order.Status != GridOrderFilled || order.FilledPrice == nil:
+		case order.Side == "buy":
+			grid.Inventory = grid.Inventory.Add(order.Qty)
+			cost = cost.Add(order.Qty.Mul(*order.FilledPrice))
+		default:
+			entry := order.Price.Sub(dbGrid.Spacing)
+			if order.EntryPrice != nil {
+				entry = *order.EntryPrice
+			}
+			grid.Inventory = grid.Inventory.Sub(order.Qty)
+			cost = cost.Sub(order.Qty.Mul(entry))
+		}
+	}
+	if grid.Inventory.IsPositive() {
+		grid.AverageCost = cost.Div(grid.Inventory).Round(4)
+	}
+	return grid
+}
+
+func gridOrderFromDB(order *models.DBGridOrder) *GridOrder {
+	return &GridOrder{
+		Level:       order.Level,
+		Side:        order.Side,
+		Price:       order.Price,
+		Qty:         order.Qty,
+		EntryPrice:  order.EntryPrice,
+		OrderID:     order.OrderID,
+		Status:      order.Status,
+		FilledPrice: order.FilledPrice,
+		FilledAt:    order.FilledAt,
+		Error:       order.Error,
+		CreatedAt:   order.CreatedAt,
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Heatmap views
+const (
+	HeatmapPortfolio = "portfolio" // open positions sized by market value
+	HeatmapMarket    = "market"    // a watchlist sized by today's dollar volume
+)
+
+// HeatmapTile is one rectangle of a treemap. Size sets its area and
+// ChangePct its color; ChangePct is nil when there is no previous close to
+// compare with, as for options.
+type HeatmapTile struct {
+	Symbol           string   `json:"symbol"`
+	Sector           string   `json:"sector"`
+	Size             float64  `json:"size"`
+	Weight           float64  `json:"weight"` // percent of the map's total size
+	Price            float64  `json:"price"`
+	ChangePct        *float64 `json:"change_percent"`
+	Contribution     float64  `json:"contribution"` // weight times change, in percentage points
+	UnrealizedPnL    float64  `json:"unrealized_pnl,omitempty"`
+	UnrealizedPnLPct float64  `json:"unrealized_pnl_percent,omitempty"`
+}
+
+// HeatmapGroup is the tiles of one sector
+type HeatmapGroup struct {
+	Name          string         `json:"name"`
+	Size          float64        `json:"size"`
+	Weight        float64        `json:"weight"`
+	ChangePct     float64        `json:"change_percent"` // size-weighted over tiles with a change
+	UnrealizedPnL float64        `json:"unrealized_pnl,omitempty"`
+	Children      []*HeatmapTile `json:"children"`
+}
+
+// Heatmap is a two-level treemap: sectors, then symbols
+type Heatmap struct {
+	View          string          `json:"view"`
+	GeneratedAt   time.Time       `json:"generated_at"`
+	Size          float64         `json:"size"`
+	ChangePct     float64         `json:"change_percent"` // size-weighted over tiles with a change
+	UnrealizedPnL float64         `json:"unrealized_pnl,omitempty"`
+	Groups        []*HeatmapGroup `json:"groups"`
+	Errors        []string        `json:"errors,omitempty"`
+}
+
+// PortfolioHeatmap maps open positions by sector, sized by absolute market
+// value and colored by today's change
+func (rs *ReportingService) PortfolioHeatmap(ctx context.Context) (*Heatmap, error) {
+	positions, err := rs.tradingService.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	heatmap := &Heatmap{View: HeatmapPortfolio, GeneratedAt: time.Now()}
+	tiles := make([]*HeatmapTile, 0, len(positions))
+	for _, position := range positions {
+		tile := &HeatmapTile{
+			Symbol:           position.Symbol,
+			Sector:           SectorFor(position.Symbol),
+			Size:             position.MarketValue.Abs().InexactFloat64(),
+			Price:            position.CurrentPrice.InexactFloat64(),
+			UnrealizedPnL:    position.UnrealizedPL.InexactFloat64(),
+			UnrealizedPnLPct: position.UnrealizedPLPC.InexactFloat64() * 100,
+		}
+		if !IsOCCSymbol(position.Symbol) {
+			prevClose, _, err := rs.previousClose(ctx, position.Symbol)
+			if err != nil {
+				heatmap.Errors = append(heatmap.Errors, fmt.Sprintf("%s: %v", position.Symbol, err))
+			} else {
+				change := (tile.Price/prevClose - 1) * 100
+				// Shorts gain when the price falls
+				if position.Qty.IsNegative() || position.Side == "short" {
+					change = -change
+				}
+				tile.ChangePct = &change
+			}
+		}
+		tiles = append(tiles, tile)
+	}
+
+	buildHeatmap(heatmap, tiles)
+	return heatmap, nil
+}
+
+// MarketHeatmap maps symbols by sector, sized by today's dollar volume and
+// colored by today's change
+func (rs *ReportingService) MarketHeatmap(ctx context.Context, symbols []string) (*Heatmap, error) {
+	heatmap := &Heatmap{View: HeatmapMarket, GeneratedAt: time.Now()}
+	tiles := make([]*HeatmapTile, 0, len(symbols))
+	seen := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+
+		prevClose, latest, err := rs.previousClose(ctx, symbol)
+		if err != nil {
+			heatmap.Errors = append(heatmap.Errors, fmt.Sprintf("%s: %v", symbol, err))
+			continue
+		}
+		price := latest.Close
+		if trade, err := rs.dataService.GetLatestTrade(ctx, symbol); err == nil && trade.Price > 0 {
+			price = trade.Price
+		}
+		change := (price/prevClose - 1) * 100
+		tiles = append(tiles, &HeatmapTile{
+			Symbol:    symbol,
+			Sector:    SectorFor(symbol),
+			Size:      latest.Close * float64(latest.Volume),
+			Price:     price,
+			ChangePct: &change,
+		})
+	}
+	if len(tiles) == 0 && len(heatmap.Errors) > 0 {
+		return nil, fmt.Errorf("no market data for the watchlist: %s", strings.Join(heatmap.Errors, "; "))
+	}
+
+	buildHeatmap(heatmap, tiles)
+	return heatmap, nil
+}
+
+// previousClose returns the last daily close before today and the latest
+// daily bar
+func (rs *ReportingService) previousClose(ctx context.Context, symbol string) (float64, *interfaces.Bar, error) {
+	bars, err := rs.dataService.GetHistoricalBars(ctx, symbol, time.Now().AddDate(0, 0, -10), time.Now(), "1Day")
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(bars) == 0 {
+		return 0, nil, fmt.Errorf("no daily bars")
+	}
+	latest := bars[len(bars)-1]
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.UTC
+	}
+	today := time.Now().In(loc).Format("2006-01-02")
+	prev := latest
+	if latest.Timestamp.In(loc).Format("2006-01-02") == today {
+		if len(bars) < 2 {
+			return 0, nil, fmt.Errorf("no previous close")
+		}
+		prev = bars[len(bars)-2]
+	}
+	if prev.Close <= 0 {
+		return 0, nil, fmt.Errorf("no previous close")
+	}
+	return prev.Close, latest, nil
+}
+
+// buildHeatmap groups tiles by sector and fills in weights and size-weighted
+// changes, largest first
+func buildHeatmap(heatmap *Heatmap, tiles []*HeatmapTile) {
+	groups := make(map[string]*HeatmapGroup)
+	for _, tile := range tiles {
+		heatmap.Size += tile.Size
+		group, ok := groups[tile.Sector]
+		if !ok {
+			group = &HeatmapGroup{Name: tile.Sector, Children: make([]*HeatmapTile, 0)}
+			groups[tile.Sector] = group
+		}
+		group.Size += tile.Size
+		group.UnrealizedPnL += tile.UnrealizedPnL
+		group.Children = append(group.Children, tile)
+	}
+
+	heatmap.Groups = make([]*HeatmapGroup, 0, len(groups))
+	var totalChanged, totalWeighted float64
+	for _, group := range groups {
+		var changed, weighted float64
+		for _, tile := range group.Children {
+			if heatmap.Size > 0 {
+				tile.Weight = tile.Size / heatmap.Size * 100
+			}
+			if tile.ChangePct != nil {
+				tile.Contribution = tile.Weight * *tile.ChangePct / 100
+				changed += tile.Size
+				weighted += tile.Size * *tile.ChangePct
+			}
+		}
+		if changed > 0 {
+			group.ChangePct = weighted / changed
+		}
+		if heatmap.Size > 0 {
+			group.Weight = group.Size / heatmap.Size * 100
+		}
+		totalChanged += changed
+		totalWeighted += weighted
+		heatmap.UnrealizedPnL += group.UnrealizedPnL
+
+		sort.Slice(group.Children, func(i, j int) bool { return group.Children[i].Size > group.Children[j].Size })
+		heatmap.Groups = append(heatmap.Groups, group)
+	}
+	if totalChanged > 0 {
+		heatmap.ChangePct = totalWeighted / totalChanged
+	}
+	sort.Slice(heatmap.Groups, func(i, j int) bool { return heatmap.Groups[i].Size > heatmap.Groups[j].Size })
+}
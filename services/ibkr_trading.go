@@ -0,0 +1,873 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"prophet-trader/config"
+	"prophet-trader/interfaces"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterBroker("ibkr", func(cfg *config.Config) (interfaces.TradingService, error) {
+		return NewIBKRTradingService(cfg.IBKRBaseURL, cfg.IBKRAccountID, cfg.IBKRInsecureTLS)
+	})
+}
+
+// ibkrTickleInterval keeps the Client Portal session alive between requests
+const ibkrTickleInterval = time.Minute
+
+// ibkrMaxReplies bounds how many order confirmation prompts are answered
+const ibkrMaxReplies = 5
+
+// IBKRTradingService implements TradingService against the Interactive
+// Brokers Client Portal Web API. It expects an authenticated Client Portal
+// Gateway (or IBeam) session at baseURL, e.g. https://localhost:5000/v1/api.
+type IBKRTradingService struct {
+	baseURL    string
+	accountID  string
+	httpClient *http.Client
+	conids     map[string]int
+	lastTickle time.Time
+	mu         sync.Mutex
+	logger     *logrus.Logger
+}
+
+// NewIBKRTradingService creates a Client Portal trading service. accountID may
+// be empty to use the gateway's selected account. insecureTLS accepts the
+// gateway's self-signed certificate.
+func NewIBKRTradingService(baseURL, accountID string, insecureTLS bool) (*IBKRTradingService, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("IBKR base URL not configured")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecureTLS {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &IBKRTradingService{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		accountID:  accountID,
+		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: transport},
+		conids:     make(map[string]int),
+		logger:     logger,
+	}, nil
+}
+
+// ibkrNumber decodes Client Portal fields that arrive as either JSON numbers
+// or strings (e.g. "1,234.50")
+type ibkrNumber float64
+
+func (n *ibkrNumber) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	s = strings.ReplaceAll(s, ",", "")
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q: %w", s, err)
+	}
+	*n = ibkrNumber(f)
+	return nil
+}
+
+func (n ibkrNumber) decimal() decimal.Decimal {
+	return decimal.NewFromFloat(float64(n))
+}
+
+// ibkrOrderTicket is one order in a place-order request
+type ibkrOrderTicket struct {
+	AcctID    string   `json:"acctId"`
+	ConID     int      `json:"conid"`
+	SecType   string   `json:"secType"`
+	OrderType string   `json:"orderType"`
+	Side      string   `json:"side"`
+	Quantity  float64  `json:"quantity"`
+	TIF       string   `json:"tif"`
+	Price     *float64 `json:"price,omitempty"`
+	AuxPrice  *float64 `json:"auxPrice,omitempty"`
+	COID      string   `json:"cOID,omitempty"`
+}
+
+// ibkrOrderReply is either a placed order or a confirmation prompt
+type ibkrOrderReply struct {
+	OrderID     json.Number `json:"order_id"`
+	OrderStatus string      `json:"order_status"`
+	ReplyID     string      `json:"id"`
+	Message     []string    `json:"message"`
+}
+
+// ibkrOrder is an order from the live orders endpoint
+type ibkrOrder struct {
+	OrderID        json.Number `json:"orderId"`
+	Ticker         string      `json:"ticker"`
+	Side           string      `json:"side"`
+	TotalSize      ibkrNumber  `json:"totalSize"`
+	FilledQuantity ibkrNumber  `json:"filledQuantity"`
+	Status         string      `json:"status"`
+	OrigOrderType  string      `json:"origOrderType"`
+	Price          ibkrNumber  `json:"price"`
+	AuxPrice       ibkrNumber  `json:"auxPrice"`
+	AvgPrice       ibkrNumber  `json:"avgPrice"`
+	TimeInForce    string      `json:"timeInForce"`
+	LastExecution  int64       `json:"lastExecutionTime_r"`
+	OrderRef       string      `json:"order_ref"`
+}
+
+// ibkrOrderStatus is the response of the single order status endpoint
+type ibkrOrderStatus struct {
+	OrderID     json.Number `json:"order_id"`
+	Symbol      string      `json:"symbol"`
+	Side        string      `json:"side"`
+	TotalSize   ibkrNumber  `json:"total_size"`
+	CumFill     ibkrNumber  `json:"cum_fill"`
+	OrderStatus string      `json:"order_status"`
+	OrderType   string      `json:"order_type"`
+	LimitPrice  ibkrNumber  `json:"limit_price"`
+	StopPrice   ibkrNumber  `json:"stop_price"`
+	AvgPrice    ibkrNumber  `json:"average_price"`
+	TIF         string      `json:"tif"`
+}
+
+// ibkrPosition is a portfolio position
+type ibkrPosition struct {
+	ConID         int        `json:"conid"`
+	ContractDesc  string     `json:"contractDesc"`
+	Ticker        string     `json:"ticker"`
+	AssetClass    string     `json:"assetClass"`
+	Position      ibkrNumber `json:"position"`
+	MktPrice      ibkrNumber `json:"mktPrice"`
+	MktValue      ibkrNumber `json:"mktValue"`
+	AvgCost       ibkrNumber `json:"avgCost"`
+	AvgPrice      ibkrNumber `json:"avgPrice"`
+	UnrealizedPnl ibkrNumber `json:"unrealizedPnl"`
+	Expiry        string     `json:"expiry"`
+	PutOrCall     string     `json:"putOrCall"`
+	Strike        ibkrNumber `json:"strike"`
+	UndSym        string     `json:"undSym"`
+}
+
+// PlaceOrder places a stock order
+func (s *IBKRTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	conid, err := s.stockConID(ctx, order.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place order: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"symbol": order.Symbol,
+		"conid":  conid,
+		"side":   order.Side,
+		"qty":    order.Qty.String(),
+		"type":   order.Type,
+	}).Info("Placing order")
+
+	result, err := s.submitOrder(ctx, conid, "STK", order.ClientOrderID, order.Side, order.Type, order.TimeInForce, order.Qty, order.LimitPrice, order.StopPrice)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to place order")
+		return nil, fmt.Errorf("failed to place order: %w", err)
+	}
+	result.Message = fmt.Sprintf("Order placed successfully: %s %s shares of %s", order.Side, order.Qty, order.Symbol)
+	return result, nil
+}
+
+// PlaceOptionsOrder places an options order. IBKR nets positions itself, so
+// the position intent is not sent.
+func (s *IBKRTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	conid, err := s.optionConID(ctx, order.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place options order: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"symbol": order.Symbol,
+		"conid":  conid,
+		"side":   order.Side,
+		"qty":    order.Qty.String(),
+		"type":   order.Type,
+	}).Info("Placing options order")
+
+	result, err := s.submitOrder(ctx, conid, "OPT", "", order.Side, order.Type, order.TimeInForce, order.Qty, order.LimitPrice, nil)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to place options order")
+		return nil, fmt.Errorf("failed to place options order: %w", err)
+	}
+	result.Message = fmt.Sprintf("Options order placed successfully: %s %s contracts of %s", order.Side, order.Qty, order.Symbol)
+	return result, nil
+}
+
+// submitOrder sends an order ticket, tagged with clientOrderID when set, and
+// answers any confirmation prompts.
+// Orders reaching the broker have already passed the risk manager, so
+// precautionary warnings are confirmed and logged.
+func (s *IBKRTradingService) submitOrder(ctx context.Context, conid int, secType, clientOrderID, side, orderType, tif string, qty decimal.Decimal, limitPrice, stopPrice *decimal.Decimal) (*interfaces.OrderResult, error) {
+	account, err := s.account(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket := ibkrOrderTicket{
+		AcctID:    account,
+		ConID:     conid,
+		SecType:   fmt.Sprintf("%d:%s", conid, secType),
+		Side:      strings.ToUpper(side),
+		Quantity:  qty.InexactFloat64(),
+		OrderType: ibkrOrderType(orderType),
+		TIF:       strings.ToUpper(tif),
+		COID:      clientOrderID,
+	}
+	if ticket.TIF == "" {
+		ticket.TIF = "DAY"
+	}
+	switch ticket.OrderType {
+	case "LMT":
+		ticket.Price = decimalPtrFloat(limitPrice)
+	case "STP":
+		ticket.Price = decimalPtrFloat(stopPrice)
+	case "STOP_LIMIT":
+		ticket.Price = decimalPtrFloat(limitPrice)
+		ticket.AuxPrice = decimalPtrFloat(stopPrice)
+	}
+
+	var replies []ibkrOrderReply
+	body := map[string][]ibkrOrderTicket{"orders": {ticket}}
+	if err := s.do(ctx, http.MethodPost, "/iserver/account/"+account+"/orders", body, &replies); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < ibkrMaxReplies; i++ {
+		if len(replies) == 0 {
+			return nil, fmt.Errorf("empty order response")
+		}
+		reply := replies[0]
+		if reply.OrderID != "" {
+			return &interfaces.OrderResult{
+				OrderID: reply.OrderID.String(),
+				Status:  ibkrStatus(reply.OrderStatus, 0),
+			}, nil
+		}
+		if reply.ReplyID == "" {
+			return nil, fmt.Errorf("unexpected order response")
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"reply_id": reply.ReplyID,
+			"message":  strings.Join(reply.Message, " "),
+		}).Warn("Confirming IBKR order prompt")
+
+		replies = nil
+		if err := s.do(ctx, http.MethodPost, "/iserver/reply/"+reply.ReplyID, map[string]bool{"confirmed": true}, &replies); err != nil {
+			return nil, fmt.Errorf("failed to confirm order: %w", err)
+		}
+	}
+
+	return nil, fmt.Errorf("order still awaiting confirmation after %d replies", ibkrMaxReplies)
+}
+
+// CancelOrder cancels an existing order
+func (s *IBKRTradingService) CancelOrder(ctx context.Context, orderID string) error {
+	s.logger.WithField("orderID", orderID).Info("Canceling order")
+
+	account, err := s.account(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	if err := s.do(ctx, http.MethodDelete, "/iserver/account/"+account+"/order/"+url.PathEscape(orderID), nil, nil); err != nil {
+		s.logger.WithError(err).Error("Failed to cancel order")
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	return nil
+}
+
+// GetOrder retrieves a specific order
+func (s *IBKRTradingService) GetOrder(ctx context.Context, orderID string) (*interfaces.Order, error) {
+	var status ibkrOrderStatus
+	if err := s.do(ctx, http.MethodGet, "/iserver/account/order/status/"+url.PathEscape(orderID), nil, &status); err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	order := &interfaces.Order{
+		ID:          orderID,
+		Symbol:      status.Symbol,
+		Qty:         status.TotalSize.decimal(),
+		Side:        ibkrSide(status.Side),
+		Type:        alpacaStyleOrderType(status.OrderType),
+		TimeInForce: strings.ToLower(status.TIF),
+		Status:      ibkrStatus(status.OrderStatus, float64(status.CumFill)),
+		FilledQty:   status.CumFill.decimal(),
+	}
+	if status.LimitPrice != 0 {
+		price := status.LimitPrice.decimal()
+		order.LimitPrice = &price
+	}
+	if status.StopPrice != 0 {
+		price := status.StopPrice.decimal()
+		order.StopPrice = &price
+	}
+	if status.AvgPrice != 0 {
+		price := status.AvgPrice.decimal()
+		order.FilledAvgPrice = &price
+	}
+	return order, nil
+}
+
+// ListOrders retrieves orders from the current session. status accepts the
+// Alpaca-style "open", "closed" or "all".
+func (s *IBKRTradingService) ListOrders(ctx context.Context, status string) ([]*interfaces.Order, error) {
+	var resp struct {
+		Orders []ibkrOrder `json:"orders"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/iserver/account/orders", nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+
+	orders := make([]*interfaces.Order, 0, len(resp.Orders))
+	for _, o := range resp.Orders {
+		order := &interfaces.Order{
+			ID:            o.OrderID.String(),
+			ClientOrderID: o.OrderRef,
+			Symbol:        o.Ticker,
+			Qty:           o.TotalSize.decimal(),
+			Side:          ibkrSide(o.Side),
+			Type:          alpacaStyleOrderType(o.OrigOrderType),
+			TimeInForce:   strings.ToLower(o.TimeInForce),
+			Status:        ibkrStatus(o.Status, float64(o.FilledQuantity)),
+			FilledQty:     o.FilledQuantity.decimal(),
+		}
+		if o.Price != 0 {
+			price := o.Price.decimal()
+			order.LimitPrice = &price
+		}
+		if o.AvgPrice != 0 {
+			price := o.AvgPrice.decimal()
+			order.FilledAvgPrice = &price
+		}
+		if o.LastExecution > 0 {
+			at := time.UnixMilli(o.LastExecution)
+			order.SubmittedAt = at
+			if order.Status == "filled" {
+				order.FilledAt = &at
+			}
+		}
+
+		open := isOpenOrderStatus(order.Status)
+		if (status == "open" && !open) || (status == "closed" && open) {
+			continue
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// GetPositions retrieves all positions; options use OCC symbols
+func (s *IBKRTradingService) GetPositions(ctx context.Context) ([]*interfaces.Position, error) {
+	raw, err := s.positions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	positions := make([]*interfaces.Position, 0, len(raw))
+	for _, p := range raw {
+		positions = append(positions, ibkrToPosition(p))
+	}
+	return positions, nil
+}
+
+// GetAccount retrieves account balances
+func (s *IBKRTradingService) GetAccount(ctx context.Context) (*interfaces.Account, error) {
+	account, err := s.account(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	var summary map[string]struct {
+		Amount ibkrNumber `json:"amount"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/portfolio/"+account+"/summary", nil, &summary); err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	return &interfaces.Account{
+		ID:                account,
+		Cash:              summary["totalcashvalue"].Amount.decimal(),
+		PortfolioValue:    summary["netliquidation"].Amount.decimal(),
+		BuyingPower:       summary["buyingpower"].Amount.decimal(),
+		Equity:            summary["netliquidation"].Amount.decimal(),
+		InitialMargin:     summary["initmarginreq"].Amount.decimal(),
+		MaintenanceMargin: summary["maintmarginreq"].Amount.decimal(),
+	}, nil
+}
+
+// GetPortfolioHistory retrieves daily net liquidation value from the
+// portfolio analyst performance endpoint
+func (s *IBKRTradingService) GetPortfolioHistory(ctx context.Context, start, end time.Time) ([]*interfaces.EquitySnapshot, error) {
+	account, err := s.account(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio history: %w", err)
+	}
+
+	period := "1Y"
+	if time.Since(start) <= 31*24*time.Hour {
+		period = "1M"
+	}
+
+	var resp struct {
+		NAV struct {
+			Dates []string `json:"dates"`
+			Data  []struct {
+				ID   string       `json:"id"`
+				NAVs []ibkrNumber `json:"navs"`
+			} `json:"data"`
+		} `json:"nav"`
+	}
+	body := map[string]interface{}{"acctIds": []string{account}, "period": period}
+	if err := s.do(ctx, http.MethodPost, "/pa/performance", body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get portfolio history: %w", err)
+	}
+	if len(resp.NAV.Data) == 0 {
+		return nil, fmt.Errorf("no portfolio history returned for %s", account)
+	}
+
+	navs := resp.NAV.Data[0].NAVs
+	snapshots := make([]*interfaces.EquitySnapshot, 0, len(resp.NAV.Dates))
+	for i, dateStr := range resp.NAV.Dates {
+		if i >= len(navs) {
+			break
+		}
+		date, err := time.Parse("20060102", dateStr)
+		if err != nil || date.Before(start) || date.After(end) || navs[i] == 0 {
+			continue
+		}
+		snapshots = append(snapshots, &interfaces.EquitySnapshot{
+			Timestamp: date,
+			Equity:    navs[i].decimal(),
+		})
+	}
+
+	return snapshots, nil
+}
+
+// GetOptionsChain is not implemented for IBKR; use the Alpaca options data service
+func (s *IBKRTradingService) GetOptionsChain(ctx context.Context, underlying string, expiration time.Time) ([]*interfaces.OptionContract, error) {
+	return nil, fmt.Errorf("options chain: %w", ErrNotSupported)
+}
+
+// GetOptionsQuote is not implemented for IBKR; use the Alpaca options data service
+func (s *IBKRTradingService) GetOptionsQuote(ctx context.Context, symbol string) (*interfaces.OptionsQuote, error) {
+	return nil, fmt.Errorf("options quote: %w", ErrNotSupported)
+}
+
+// GetOptionsPosition retrieves a specific options position by OCC symbol
+func (s *IBKRTradingService) GetOptionsPosition(ctx context.Context, symbol string) (*interfaces.OptionsPosition, error) {
+	positions, err := s.ListOptionsPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, pos := range positions {
+		if pos.Symbol == symbol {
+			return pos, nil
+		}
+	}
+	return nil, fmt.Errorf("options position not found: %s", symbol)
+}
+
+// ListOptionsPositions retrieves all options positions
+func (s *IBKRTradingService) ListOptionsPositions(ctx context.Context) ([]*interfaces.OptionsPosition, error) {
+	raw, err := s.positions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	optionsPositions := []*interfaces.OptionsPosition{}
+	for _, p := range raw {
+		if p.AssetClass != "OPT" {
+			continue
+		}
+		pos := ibkrToPosition(p)
+		option := &interfaces.OptionsPosition{
+			Symbol:         pos.Symbol,
+			Qty:            pos.Qty,
+			AvgEntryPrice:  pos.AvgEntryPrice,
+			MarketValue:    pos.MarketValue,
+			CostBasis:      pos.CostBasis,
+			UnrealizedPL:   pos.UnrealizedPL,
+			UnrealizedPLPC: pos.UnrealizedPLPC,
+			CurrentPrice:   pos.CurrentPrice,
+			Side:           pos.Side,
+		}
+		fillOptionDetails(option)
+		optionsPositions = append(optionsPositions, option)
+	}
+
+	return optionsPositions, nil
+}
+
+// positions pages through the portfolio positions endpoint
+func (s *IBKRTradingService) positions(ctx context.Context) ([]ibkrPosition, error) {
+	account, err := s.account(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]ibkrPosition, 0)
+	for page := 0; page < 50; page++ {
+		var batch []ibkrPosition
+		if err := s.do(ctx, http.MethodGet, fmt.Sprintf("/portfolio/%s/positions/%d", account, page), nil, &batch); err != nil {
+			return nil, err
+		}
+		all = append(all, batch...)
+		if len(batch) < 100 {
+			break
+		}
+	}
+	return all, nil
+}
+
+// ibkrToPosition converts a portfolio position, naming options by OCC symbol
+func ibkrToPosition(p ibkrPosition) *interfaces.Position {
+	symbol := p.Ticker
+	if symbol == "" {
+		symbol = strings.Fields(p.ContractDesc + " ")[0]
+	}
+
+	// avgCost includes the contract multiplier for options; avgPrice does not
+	multiplier := 1.0
+	if p.AssetClass == "OPT" {
+		multiplier = 100
+		underlying := p.UndSym
+		if underlying == "" {
+			underlying = symbol
+		}
+		if expiry, err := time.Parse("20060102", p.Expiry); err == nil {
+			optionType := "call"
+			if strings.HasPrefix(strings.ToUpper(p.PutOrCall), "P") {
+				optionType = "put"
+			}
+			symbol = OCCSymbol{Underlying: underlying, Expiration: expiry, Type: optionType, Strike: float64(p.Strike)}.String()
+		}
+	}
+
+	avgPrice := float64(p.AvgPrice)
+	if avgPrice == 0 {
+		avgPrice = float64(p.AvgCost) / multiplier
+	}
+	costBasis := avgPrice * float64(p.Position) * multiplier
+
+	side := "long"
+	if p.Position < 0 {
+		side = "short"
+	}
+
+	pos := &interfaces.Position{
+		Symbol:        symbol,
+		Qty:           p.Position.decimal(),
+		AvgEntryPrice: decimal.NewFromFloat(avgPrice),
+		MarketValue:   p.MktValue.decimal(),
+		CostBasis:     decimal.NewFromFloat(costBasis),
+		UnrealizedPL:  p.UnrealizedPnl.decimal(),
+		CurrentPrice:  p.MktPrice.decimal(),
+		Side:          side,
+	}
+	if costBasis != 0 {
+		pos.UnrealizedPLPC = decimal.NewFromFloat(float64(p.UnrealizedPnl) / math.Abs(costBasis))
+	}
+	return pos
+}
+
+// account returns the configured account, or the gateway's selected account
+func (s *IBKRTradingService) account(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	account := s.accountID
+	s.mu.Unlock()
+	if account != "" {
+		return account, nil
+	}
+
+	var resp struct {
+		Accounts        []string `json:"accounts"`
+		SelectedAccount string   `json:"selectedAccount"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/iserver/accounts", nil, &resp); err != nil {
+		return "", fmt.Errorf("failed to get IBKR accounts: %w", err)
+	}
+
+	account = resp.SelectedAccount
+	if account == "" && len(resp.Accounts) > 0 {
+		account = resp.Accounts[0]
+	}
+	if account == "" {
+		return "", fmt.Errorf("no IBKR account available; is the gateway session authenticated?")
+	}
+
+	s.mu.Lock()
+	s.accountID = account
+	s.mu.Unlock()
+	return account, nil
+}
+
+// stockConID resolves a stock symbol to its IBKR contract ID
+func (s *IBKRTradingService) stockConID(ctx context.Context, symbol string) (int, error) {
+	symbol = strings.ToUpper(symbol)
+	if conid, ok := s.cachedConID(symbol); ok {
+		return conid, nil
+	}
+
+	var results []struct {
+		ConID json.Number `json:"conid"`
+	}
+	query := url.Values{"symbol": {symbol}, "secType": {"STK"}}
+	if err := s.do(ctx, http.MethodGet, "/iserver/secdef/search?"+query.Encode(), nil, &results); err != nil {
+		return 0, fmt.Errorf("failed to look up contract for %s: %w", symbol, err)
+	}
+	if len(results) == 0 {
+		return 0, fmt.Errorf("no IBKR contract found for %s", symbol)
+	}
+
+	conid, err := strconv.Atoi(results[0].ConID.String())
+	if err != nil {
+		return 0, fmt.Errorf("invalid contract id for %s: %w", symbol, err)
+	}
+	s.cacheConID(symbol, conid)
+	return conid, nil
+}
+
+// optionConID resolves an OCC option symbol to its IBKR contract ID
+func (s *IBKRTradingService) optionConID(ctx context.Context, symbol string) (int, error) {
+	if conid, ok := s.cachedConID(symbol); ok {
+		return conid, nil
+	}
+
+	occ, err := ParseOCCSymbol(symbol)
+	if err != nil {
+		return 0, err
+	}
+	underlying, err := s.stockConID(ctx, occ.Underlying)
+	if err != nil {
+		return 0, err
+	}
+
+	right := "C"
+	if occ.Type == "put" {
+		right = "P"
+	}
+	query := url.Values{
+		"conid":   {strconv.Itoa(underlying)},
+		"sectype": {"OPT"},
+		"month":   {strings.ToUpper(occ.Expiration.Format("Jan06"))},
+		"strike":  {strconv.FormatFloat(occ.Strike, 'f', -1, 64)},
+		"right":   {right},
+	}
+	var contracts []struct {
+		ConID        int    `json:"conid"`
+		MaturityDate string `json:"maturityDate"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/iserver/secdef/info?"+query.Encode(), nil, &contracts); err != nil {
+		return 0, fmt.Errorf("failed to look up option contract %s: %w", symbol, err)
+	}
+
+	expiry := occ.Expiration.Format("20060102")
+	for _, contract := range contracts {
+		if contract.MaturityDate == expiry {
+			s.cacheConID(symbol, contract.ConID)
+			return contract.ConID, nil
+		}
+	}
+	return 0, fmt.Errorf("no IBKR contract found for option %s", symbol)
+}
+
+func (s *IBKRTradingService) cachedConID(symbol string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conid, ok := s.conids[symbol]
+	return conid, ok
+}
+
+func (s *IBKRTradingService) cacheConID(symbol string, conid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conids[symbol] = conid
+}
+
+// tickle keeps the gateway session from timing out
+func (s *IBKRTradingService) tickle(ctx context.Context) {
+	s.mu.Lock()
+	due := time.Since(s.lastTickle) >= ibkrTickleInterval
+	if due {
+		s.lastTickle = time.Now()
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+
+	if err := s.request(ctx, http.MethodPost, "/tickle", nil, nil); err != nil {
+		s.logger.WithError(err).Warn("IBKR session keep-alive failed")
+	}
+}
+
+// do keeps the session alive and performs a Client Portal API request
+func (s *IBKRTradingService) do(ctx context.Context, method, path string, body, out interface{}) error {
+	s.tickle(ctx)
+	return s.request(ctx, method, path, body, out)
+}
+
+// request performs a Client Portal API request and decodes the JSON response
+func (s *IBKRTradingService) request(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "prophet-trader")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("IBKR request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("IBKR gateway session is not authenticated (HTTP 401)")
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("IBKR API error (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	// Errors can also arrive with HTTP 200 as {"error": "..."}
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+		return fmt.Errorf("IBKR API error: %s", apiErr.Error)
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// ibkrOrderType maps Alpaca-style order types to Client Portal order types
+func ibkrOrderType(orderType string) string {
+	switch strings.ToLower(orderType) {
+	case "limit":
+		return "LMT"
+	case "stop":
+		return "STP"
+	case "stop_limit":
+		return "STOP_LIMIT"
+	case "trailing_stop":
+		return "TRAIL"
+	default:
+		return "MKT"
+	}
+}
+
+// alpacaStyleOrderType maps Client Portal order types back to the names used
+// throughout the bot
+func alpacaStyleOrderType(orderType string) string {
+	switch strings.ToUpper(strings.ReplaceAll(orderType, " ", "_")) {
+	case "LMT", "LIMIT":
+		return "limit"
+	case "STP", "STOP":
+		return "stop"
+	case "STOP_LIMIT", "STP_LMT":
+		return "stop_limit"
+	case "TRAIL", "TRAILING_STOP":
+		return "trailing_stop"
+	default:
+		return "market"
+	}
+}
+
+// ibkrSide normalizes "BUY"/"B"/"SELL"/"S" to "buy" or "sell"
+func ibkrSide(side string) string {
+	if strings.HasPrefix(strings.ToUpper(side), "S") {
+		return "sell"
+	}
+	return "buy"
+}
+
+// ibkrStatus maps Client Portal order statuses to the Alpaca-style statuses
+// the position manager and reconciler expect
+func ibkrStatus(status string, filled float64) string {
+	switch strings.ToLower(status) {
+	case "filled":
+		return "filled"
+	case "cancelled", "apicancelled":
+		return "canceled"
+	case "pendingcancel":
+		return "pending_cancel"
+	case "inactive":
+		return "rejected"
+	case "pendingsubmit", "presubmitted", "apipending":
+		return "pending_new"
+	case "submitted":
+		if filled > 0 {
+			return "partially_filled"
+		}
+		return "new"
+	default:
+		return strings.ToLower(status)
+	}
+}
+
+// isOpenOrderStatus reports whether an Alpaca-style status is still working
+func isOpenOrderStatus(status string) bool {
+	switch status {
+	case "new", "pending_new", "partially_filled", "accepted", "pending_cancel":
+		return true
+	}
+	return false
+}
+
+// decimalPtrFloat converts an optional decimal to an optional float
+func decimalPtrFloat(d *decimal.Decimal) *float64 {
+	if d == nil {
+		return nil
+	}
+	f := d.InexactFloat64()
+	return &f
+}
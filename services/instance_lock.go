@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"prophet-trader/config"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrStandby is returned for every order while another instance holds the
+// writer lock
+var ErrStandby = errors.New("this instance is on standby: another instance holds the writer lock")
+
+// InstanceLock is a lock at most one bot instance holds. The instance
+// holding it is the only one that trades and runs the background jobs that
+// write.
+type InstanceLock interface {
+	// TryLock takes the lock if it is free and reports whether it did
+	TryLock(ctx context.Context) (bool, error)
+	// Refresh reports whether the lock is still held, extending its lease
+	Refresh(ctx context.Context) (bool, error)
+	// Unlock releases the lock
+	Unlock(ctx context.Context) error
+	// Backend names the lock: "file", "postgres" or "redis"
+	Backend() string
+}
+
+// NewInstanceLock creates the lock named by kind: "file" locks
+// INSTANCE_LOCK_FILE on this host, "postgres" takes an advisory lock in the
+// database and "redis" a lease at REDIS_URL
+func NewInstanceLock(kind string, cfg *config.Config, storageService *database.LocalStorage) (InstanceLock, error) {
+	switch strings.ToLower(kind) {
+	case "file":
+		return NewFileLock(cfg.InstanceLockFile), nil
+	case "postgres":
+		if storageService.Dialect() != "postgres" {
+			return nil, fmt.Errorf("INSTANCE_LOCK=postgres needs DATABASE_URL")
+		}
+		db, err := storageService.SQLDB()
+		if err != nil {
+			return nil, err
+		}
+		return NewPostgresLock(db, cfg.InstanceLockKey), nil
+	case "redis":
+		if cfg.RedisURL == "" {
+			return nil, fmt.Errorf("INSTANCE_LOCK=redis needs REDIS_URL")
+		}
+		return NewRedisLock(cfg.RedisURL, cfg.InstanceLockKey)
+	}
+	return nil, fmt.Errorf("unknown instance lock %q: use file, postgres, redis or none", kind)
+}
+
+// PostgresLock is a session-level advisory lock held on a dedicated
+// connection. Postgres releases it when that connection drops.
+type PostgresLock struct {
+	db   *sql.DB
+	key  int64
+	conn *sql.Conn
+}
+
+// NewPostgresLock creates an advisory lock keyed by a hash of name
+func NewPostgresLock(db *sql.DB, name string) *PostgresLock {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return &PostgresLock{db: db, key: int64(h.Sum64())}
+}
+
+// Backend implements InstanceLock
+func (l *PostgresLock) Backend() string {
+	return "postgres"
+}
+
+// TryLock implements InstanceLock
+func (l *PostgresLock) TryLock(ctx context.Context) (bool, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get a lock connection: %w", err)
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&locked); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("failed to take advisory lock: %w", err)
+	}
+	if !locked {
+		conn.Close()
+		return false, nil
+	}
+	l.conn = conn
+	return true, nil
+}
+
+// Refresh implements InstanceLock. The lock lives as long as its
+// connection, so a working connection means it is still held.
+func (l *PostgresLock) Refresh(ctx context.Context) (bool, error) {
+	if l.conn == nil {
+		return false, nil
+	}
+	if err := l.conn.PingContext(ctx); err != nil {
+		l.conn.Close()
+		l.conn = nil
+		return false, fmt.Errorf("lock connection lost: %w", err)
+	}
+	return true, nil
+}
+
+// Unlock implements InstanceLock
+func (l *PostgresLock) Unlock(ctx context.Context) error {
+	if l.conn == nil {
+		return nil
+	}
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	l.conn.Close()
+	l.conn = nil
+	return err
+}
+
+// LeaderStatus describes this instance's hold on the writer lock
+type LeaderStatus struct {
+	Backend string     `json:"backend"`
+	Leader  bool       `json:"leader"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// LeaderElector keeps trying to take the writer lock and runs the writer
+// jobs while it holds it. Other instances stand by and serve reads.
+type LeaderElector struct {
+	lock   InstanceLock
+	logger *logrus.Logger
+
+	mu     sync.RWMutex
+	leader bool
+	since  time.Time
+}
+
+// NewLeaderElector creates an elector for lock
+func NewLeaderElector(lock InstanceLock) *LeaderElector {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &LeaderElector{
+		lock:   lock,
+		logger: logger,
+	}
+}
+
+// IsLeader reports whether this instance holds the writer lock. Without
+// an elector every instance writes.
+func (le *LeaderElector) IsLeader() bool {
+	if le == nil {
+		return true
+	}
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	return le.leader
+}
+
+// Status returns this instance's hold on the lock
+func (le *LeaderElector) Status() LeaderStatus {
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+
+	status := LeaderStatus{Backend: le.lock.Backend(), Leader: le.leader}
+	if le.leader {
+		since := le.since
+		status.Since = &since
+	}
+	return status
+}
+
+// Run tries to take the lock every interval and refreshes it while held.
+// On taking it, lead is called with a context that is cancelled when the
+// lock is lost or ctx ends; lead starts the writer jobs under it. The lock
+// is released when ctx ends.
+func (le *LeaderElector) Run(ctx context.Context, interval time.Duration, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	le.logger.WithField("backend", le.lock.Backend()).Info("Waiting for the writer lock")
+
+	var cancelLead context.CancelFunc
+	for {
+		if !le.IsLeader() {
+			locked, err := le.lock.TryLock(ctx)
+			if err != nil {
+				le.logger.WithError(err).Warn("Failed to take the writer lock")
+			} else if locked {
+				le.setLeader(true)
+				le.logger.Info("Writer lock taken: this instance trades and runs background jobs")
+				cancelLead = startLeading(ctx, lead)
+			}
+		} else if held, err := le.lock.Refresh(ctx); !held {
+			// Stop writing first; another instance may take over at once
+			le.setLeader(false)
+			cancelLead()
+			le.logger.WithError(err).Error("Writer lock lost: standing by")
+		}
+
+		select {
+		case <-ctx.Done():
+			if le.IsLeader() {
+				le.setLeader(false)
+				cancelLead()
+				if err := le.lock.Unlock(context.Background()); err != nil {
+					le.logger.WithError(err).Warn("Failed to release the writer lock")
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startLeading calls lead with a context of its own and returns its cancel
+func startLeading(ctx context.Context, lead func(ctx context.Context)) context.CancelFunc {
+	leadCtx, cancel := context.WithCancel(ctx)
+	lead(leadCtx)
+	return cancel
+}
+
+func (le *LeaderElector) setLeader(leader bool) {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	le.leader = leader
+	le.since = time.Now()
+}
+
+// Guard wraps trading so orders and cancellations are rejected while this
+// instance stands by. Reads pass through.
+func (le *LeaderElector) Guard(trading interfaces.TradingService) interfaces.TradingService {
+	return &standbyTradingService{TradingService: trading, elector: le}
+}
+
+// standbyTradingService rejects orders unless its elector holds the lock
+type standbyTradingService struct {
+	interfaces.TradingService
+	elector *LeaderElector
+}
+
+// PlaceOrder implements interfaces.TradingService
+func (s *standbyTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	if !s.elector.IsLeader() {
+		return nil, WithErrorCode(ErrCodeUnavailable, ErrStandby)
+	}
+	return s.TradingService.PlaceOrder(ctx, order)
+}
+
+// PlaceOptionsOrder implements interfaces.TradingService
+func (s *standbyTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	if !s.elector.IsLeader() {
+		return nil, WithErrorCode(ErrCodeUnavailable, ErrStandby)
+	}
+	return s.TradingService.PlaceOptionsOrder(ctx, order)
+}
+
+// CancelOrder implements interfaces.TradingService
+func (s *standbyTradingService) CancelOrder(ctx context.Context, orderID string) error {
+	if !s.elector.IsLeader() {
+		return WithErrorCode(ErrCodeUnavailable, ErrStandby)
+	}
+	return s.TradingService.CancelOrder(ctx, orderID)
+}
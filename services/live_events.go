@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"prophet-trader/interfaces"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// OrderUpdate is the payload of an order_update event
+type OrderUpdate struct {
+	OrderID        string           `json:"order_id"`
+	Symbol         string           `json:"symbol"`
+	Side           string           `json:"side,omitempty"`
+	Type           string           `json:"type,omitempty"`
+	Qty            decimal.Decimal  `json:"qty"`
+	FilledQty      decimal.Decimal  `json:"filled_qty"`
+	FilledAvgPrice *decimal.Decimal `json:"filled_avg_price,omitempty"`
+	Status         string           `json:"status"`
+	PreviousStatus string           `json:"previous_status,omitempty"`
+}
+
+// PositionPnL is one position in a position_pnl event
+type PositionPnL struct {
+	Symbol         string  `json:"symbol"`
+	Qty            float64 `json:"qty"`
+	CurrentPrice   float64 `json:"current_price"`
+	MarketValue    float64 `json:"market_value"`
+	UnrealizedPL   float64 `json:"unrealized_pl"`
+	UnrealizedPLPC float64 `json:"unrealized_pl_percent"`
+}
+
+// PnLTick is the payload of a position_pnl event
+type PnLTick struct {
+	Positions         []PositionPnL `json:"positions"`
+	TotalMarketValue  float64       `json:"total_market_value"`
+	TotalUnrealizedPL float64       `json:"total_unrealized_pl"`
+}
+
+// LiveEventMonitor polls the broker and publishes order status changes,
+// position P&L ticks and closed positions on the event bus
+type LiveEventMonitor struct {
+	tradingService interfaces.TradingService
+	bus            *EventBus
+	orders         map[string]*interfaces.Order    // last seen state of orders not yet final
+	lastPnL        map[string]string               // symbol -> rounded unrealized P&L
+	positions      map[string]*interfaces.Position // last seen positions, nil before the first poll
+	lastFill       map[string]decimal.Decimal      // "symbol:side" -> latest fill price
+	fills          *FillTracker                    // nil records no fills
+	mu             sync.Mutex
+	logger         *logrus.Logger
+}
+
+// NewLiveEventMonitor creates a monitor publishing to bus
+func NewLiveEventMonitor(tradingService interfaces.TradingService, bus *EventBus) *LiveEventMonitor {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &LiveEventMonitor{
+		tradingService: tradingService,
+		bus:            bus,
+		orders:         make(map[string]*interfaces.Order),
+		lastPnL:        make(map[string]string),
+		lastFill:       make(map[string]decimal.Decimal),
+		logger:         logger,
+	}
+}
+
+// SetFillTracker records the executions of tracked orders whose filled
+// quantity rose since they were last seen
+func (m *LiveEventMonitor) SetFillTracker(fills *FillTracker) {
+	m.fills = fills
+}
+
+// TrackOrder records an order's state and publishes an order_update when
+// its status changed since it was last seen. Final orders stop being polled.
+func (m *LiveEventMonitor) TrackOrder(order *interfaces.Order) {
+	m.mu.Lock()
+	previous, known := m.orders[order.ID]
+	if known && previous.Status == order.Status && previous.FilledQty.Equal(order.FilledQty) {
+		m.mu.Unlock()
+		return
+	}
+	if isFinalOrderStatus(order.Status) {
+		delete(m.orders, order.ID)
+	} else {
+		m.orders[order.ID] = order
+	}
+	if order.FilledAvgPrice != nil && order.FilledQty.IsPositive() {
+		m.lastFill[order.Symbol+":"+order.Side] = *order.FilledAvgPrice
+	}
+	m.mu.Unlock()
+
+	if m.fills != nil && (!known || !previous.FilledQty.Equal(order.FilledQty)) {
+		m.fills.ObserveOrder(order)
+	}
+
+	update := OrderUpdate{
+		OrderID:        order.ID,
+		Symbol:         order.Symbol,
+		Side:           order.Side,
+		Type:           order.Type,
+		Qty:            order.Qty,
+		FilledQty:      order.FilledQty,
+		FilledAvgPrice: order.FilledAvgPrice,
+		Status:         order.Status,
+	}
+	if known {
+		update.PreviousStatus = previous.Status
+	}
+	m.bus.Publish(EventOrderUpdate, update)
+}
+
+// Poll reports order status changes and position P&L since the last poll
+func (m *LiveEventMonitor) Poll(ctx context.Context) {
+	m.pollOrders(ctx)
+	m.pollPositions(ctx)
+}
+
+// pollOrders publishes changes of open orders and the final status of
+// tracked orders that are no longer open
+func (m *LiveEventMonitor) pollOrders(ctx context.Context) {
+	open, err := m.tradingService.ListOrders(ctx, "open")
+	if err != nil {
+		m.logger.WithError(err).Debug("Failed to list open orders")
+		return
+	}
+
+	stillOpen := make(map[string]bool, len(open))
+	for _, order := range open {
+		stillOpen[order.ID] = true
+		m.TrackOrder(order)
+	}
+
+	m.mu.Lock()
+	closed := make([]string, 0)
+	for id := range m.orders {
+		if !stillOpen[id] {
+			closed = append(closed, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, id := range closed {
+		order, err := m.tradingService.GetOrder(ctx, id)
+		if err != nil {
+			m.logger.WithError(err).WithField("order_id", id).Debug("Failed to get order")
+			continue
+		}
+		m.TrackOrder(order)
+	}
+}
+
+// pollPositions publishes a position_pnl tick when any position's P&L
+// moved by at least a cent or a position was opened or closed, and a
+// position_closed event for every position that is gone
+func (m *LiveEventMonitor) pollPositions(ctx context.Context) {
+	positions, err := m.tradingService.GetPositions(ctx)
+	if err != nil {
+		m.logger.WithError(err).Debug("Failed to get positions")
+		return
+	}
+
+	tick := PnLTick{Positions: make([]PositionPnL, 0, len(positions))}
+	current := make(map[string]string, len(positions))
+	for _, p := range positions {
+		tick.Positions = append(tick.Positions, PositionPnL{
+			Symbol:         p.Symbol,
+			Qty:            p.Qty.InexactFloat64(),
+			CurrentPrice:   p.CurrentPrice.InexactFloat64(),
+			MarketValue:    p.MarketValue.InexactFloat64(),
+			UnrealizedPL:   p.UnrealizedPL.InexactFloat64(),
+			UnrealizedPLPC: p.UnrealizedPLPC.Mul(decimal.NewFromInt(100)).InexactFloat64(),
+		})
+		tick.TotalMarketValue += p.MarketValue.InexactFloat64()
+		tick.TotalUnrealizedPL += p.UnrealizedPL.InexactFloat64()
+		current[p.Symbol] = p.UnrealizedPL.StringFixed(2)
+	}
+
+	m.mu.Lock()
+	changed := len(current) != len(m.lastPnL)
+	for symbol, pnl := range current {
+		if m.lastPnL[symbol] != pnl {
+			changed = true
+		}
+	}
+	m.lastPnL = current
+
+	closed := make([]PositionClosed, 0)
+	held := make(map[string]*interfaces.Position, len(positions))
+	for _, p := range positions {
+		held[p.Symbol] = p
+	}
+	for symbol, p := range m.positions {
+		if held[symbol] == nil {
+			closed = append(closed, m.closedPosition(p))
+		}
+	}
+	m.positions = held
+	m.mu.Unlock()
+
+	if changed {
+		m.bus.Publish(EventPositionPnL, tick)
+	}
+	for _, c := range closed {
+		m.bus.Publish(EventPositionClosed, c)
+	}
+}
+
+// closedPosition describes a position that is no longer held, preferring
+// the latest closing fill price over the last polled price. The caller
+// holds m.mu.
+func (m *LiveEventMonitor) closedPosition(p *interfaces.Position) PositionClosed {
+	qty := p.Qty.Abs()
+	closingFill := p.Symbol + ":sell"
+	if p.Side == "short" {
+		closingFill = p.Symbol + ":buy"
+	}
+	exit := p.CurrentPrice
+	if fill, ok := m.lastFill[closingFill]; ok {
+		exit = fill
+		delete(m.lastFill, closingFill)
+	}
+	pnl := exit.Sub(p.AvgEntryPrice).Mul(qty)
+	if p.Side == "short" {
+		pnl = pnl.Neg()
+	}
+	closed := PositionClosed{
+		Symbol:     p.Symbol,
+		Side:       p.Side,
+		Qty:        qty.InexactFloat64(),
+		EntryPrice: p.AvgEntryPrice.InexactFloat64(),
+		ExitPrice:  exit.InexactFloat64(),
+		PnL:        pnl.Round(2).InexactFloat64(),
+	}
+	if cost := p.AvgEntryPrice.Mul(qty); cost.IsPositive() {
+		closed.PnLPercent = pnl.Div(cost).Mul(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+	}
+	return closed
+}
+
+func isFinalOrderStatus(status string) bool {
+	switch status {
+	case "filled", "canceled", "expired", "rejected", "replaced", "done_for_day":
+		return true
+	default:
+		return false
+	}
+}
+
+// EventedTradingService wraps a TradingService and reports submitted and
+// canceled orders to the live event monitor straight away, so orders that
+// fill between polls still produce events
+type EventedTradingService struct {
+	interfaces.TradingService
+	monitor *LiveEventMonitor
+}
+
+// NewEventedTradingService wraps trading with live order events
+func NewEventedTradingService(trading interfaces.TradingService, monitor *LiveEventMonitor) *EventedTradingService {
+	return &EventedTradingService{
+		TradingService: trading,
+		monitor:        monitor,
+	}
+}
+
+// PlaceOrder submits an order and publishes an order_placed event and its
+// initial status
+func (s *EventedTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	result, err := s.TradingService.PlaceOrder(ctx, order)
+	if err == nil {
+		s.publishPlaced(ctx, result, order.Symbol, order.Side, order.Type, "us_equity", order.Qty, order.LimitPrice)
+		s.trackSubmitted(result, order.Symbol, order.Side, order.Type, order.Qty)
+	}
+	return result, err
+}
+
+// PlaceOptionsOrder submits an options order and publishes an order_placed
+// event and its initial status
+func (s *EventedTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	result, err := s.TradingService.PlaceOptionsOrder(ctx, order)
+	if err == nil {
+		s.publishPlaced(ctx, result, order.Symbol, order.Side, order.Type, "us_option", order.Qty, order.LimitPrice)
+		s.trackSubmitted(result, order.Symbol, order.Side, order.Type, order.Qty)
+	}
+	return result, err
+}
+
+// CancelOrder cancels an order and publishes its new status
+func (s *EventedTradingService) CancelOrder(ctx context.Context, orderID string) error {
+	if err := s.TradingService.CancelOrder(ctx, orderID); err != nil {
+		return err
+	}
+	if order, err := s.TradingService.GetOrder(ctx, orderID); err == nil {
+		s.monitor.TrackOrder(order)
+	}
+	return nil
+}
+
+func (s *EventedTradingService) publishPlaced(ctx context.Context, result *interfaces.OrderResult, symbol, side, orderType, assetClass string, qty decimal.Decimal, limitPrice *decimal.Decimal) {
+	if result == nil {
+		return
+	}
+	s.monitor.bus.Publish(EventOrderPlaced, OrderPlaced{
+		OrderID:    result.OrderID,
+		Symbol:     symbol,
+		Side:       side,
+		Type:       orderType,
+		Qty:        qty,
+		LimitPrice: limitPrice,
+		AssetClass: assetClass,
+		Status:     result.Status,
+		Source:     OrderSourceFrom(ctx),
+		Book:       BookFrom(ctx),
+	})
+}
+
+func (s *EventedTradingService) trackSubmitted(result *interfaces.OrderResult, symbol, side, orderType string, qty decimal.Decimal) {
+	if result == nil || result.OrderID == "" {
+		return
+	}
+	s.monitor.TrackOrder(&interfaces.Order{
+		ID:          result.OrderID,
+		Symbol:      symbol,
+		Side:        side,
+		Type:        orderType,
+		Qty:         qty,
+		Status:      result.Status,
+		SubmittedAt: time.Now(),
+	})
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LiveTradingRule guards the first days of trading a live account: orders
+// must be for allowlisted symbols and stay under a notional cap until the
+// restricted period ends. Orders that close or reduce an existing position
+// are exempt from the allowlist so exits are never blocked.
+type LiveTradingRule struct {
+	AllowedSymbols []string        // empty allows all symbols
+	MaxOrderValue  decimal.Decimal // zero disables the cap
+	Until          time.Time       // end of the restricted period
+}
+
+func (LiveTradingRule) Name() string { return "live_trading_limits" }
+
+func (r LiveTradingRule) Check(ctx context.Context, check *RiskCheck) error {
+	if !time.Now().Before(r.Until) {
+		return nil
+	}
+	restricted := fmt.Sprintf("during the live trading restricted period (until %s)", r.Until.Format("2006-01-02"))
+
+	if len(r.AllowedSymbols) > 0 && !reducesPosition(check) {
+		symbol := strings.ToUpper(check.Order.Symbol)
+		if occ, err := ParseOCCSymbol(symbol); err == nil {
+			symbol = strings.ToUpper(occ.Underlying)
+		}
+		allowed := false
+		for _, s := range r.AllowedSymbols {
+			if strings.EqualFold(s, symbol) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%s is not in LIVE_ALLOWED_SYMBOLS %s", symbol, restricted)
+		}
+	}
+
+	if r.MaxOrderValue.IsPositive() && check.Notional.GreaterThan(r.MaxOrderValue) {
+		return fmt.Errorf("order notional %s exceeds live limit %s %s",
+			check.Notional.StringFixed(2), r.MaxOrderValue.StringFixed(2), restricted)
+	}
+	return nil
+}
+
+// reducesPosition reports whether the order trades against an existing
+// position in the same symbol
+func reducesPosition(check *RiskCheck) bool {
+	for _, pos := range check.Positions {
+		if !strings.EqualFold(pos.Symbol, check.Order.Symbol) {
+			continue
+		}
+		long := pos.Side != "short" && !pos.Qty.IsNegative()
+		return (long && check.Order.Side == "sell") || (!long && check.Order.Side == "buy")
+	}
+	return false
+}
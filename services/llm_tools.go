@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LLM tool call outcomes recorded in the audit log
+const (
+	LLMToolCallOK      = "ok"
+	LLMToolCallError   = "error"
+	LLMToolCallLimited = "limited" // refused because the run used up its calls
+)
+
+// Limits on what a single tool call may return
+const (
+	llmToolMaxBarDays  = 365
+	llmToolMaxBars     = 120
+	llmToolMaxHeadline = 10
+)
+
+// llmToolSymbolPattern matches the symbols tools accept
+var llmToolSymbolPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{0,5}([./-][A-Z0-9]{1,4})?$`)
+
+// LLMToolCall is an audited function call made by the LLM
+type LLMToolCall struct {
+	Source     string    `json:"source"`
+	Tool       string    `json:"tool"`
+	Args       string    `json:"args"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	At         time.Time `json:"at"`
+}
+
+// llmTool is a read-only function the model may call
+type llmTool struct {
+	description string
+	parameters  map[string]interface{}
+	call        func(tb *LLMToolBridge, ctx context.Context, args map[string]interface{}) (interface{}, error)
+}
+
+// llmToolNames fixes the order tools are declared in
+var llmToolNames = []string{"get_quote", "get_bars", "get_positions", "get_news"}
+
+var llmTools = map[string]llmTool{
+	"get_quote": {
+		description: "Latest bid/ask quote and last trade price for a symbol",
+		parameters:  llmToolParams(map[string]interface{}{"symbol": llmToolString("Ticker, e.g. NVDA")}, "symbol"),
+		call: func(tb *LLMToolBridge, ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			symbol, err := llmToolSymbol(args)
+			if err != nil {
+				return nil, err
+			}
+			quote, err := tb.dataService.GetLatestQuote(ctx, symbol)
+			if err != nil {
+				return nil, err
+			}
+			result := map[string]interface{}{
+				"symbol":    symbol,
+				"bid":       quote.BidPrice,
+				"ask":       quote.AskPrice,
+				"timestamp": quote.Timestamp,
+			}
+			if trade, err := tb.dataService.GetLatestTrade(ctx, symbol); err == nil {
+				result["last"] = trade.Price
+			}
+			return result, nil
+		},
+	},
+	"get_bars": {
+		description: "Historical OHLCV bars for a symbol, oldest first, at most 120 bars",
+		parameters: llmToolParams(map[string]interface{}{
+			"symbol":    llmToolString("Ticker, e.g. NVDA"),
+			"timeframe": map[string]interface{}{"type": "STRING", "enum": []string{"5Min", "15Min", "1Hour", "1Day", "1Week"}},
+			"days":      map[string]interface{}{"type": "INTEGER", "description": "Calendar days of history, 1-365"},
+		}, "symbol", "timeframe", "days"),
+		call: func(tb *LLMToolBridge, ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			symbol, err := llmToolSymbol(args)
+			if err != nil {
+				return nil, err
+			}
+			timeframe, _ := args["timeframe"].(string)
+			switch timeframe {
+			case "5Min", "15Min", "1Hour", "1Day", "1Week":
+			default:
+				return nil, fmt.Errorf("unsupported timeframe %q", timeframe)
+			}
+			days, _ := args["days"].(float64)
+			if days < 1 || days > llmToolMaxBarDays {
+				return nil, fmt.Errorf("days must be between 1 and %d", llmToolMaxBarDays)
+			}
+
+			end := time.Now()
+			bars, err := tb.dataService.GetHistoricalBars(ctx, symbol, end.AddDate(0, 0, -int(days)), end, timeframe)
+			if err != nil {
+				return nil, err
+			}
+			if len(bars) > llmToolMaxBars {
+				bars = bars[len(bars)-llmToolMaxBars:]
+			}
+			rows := make([][]interface{}, 0, len(bars))
+			for _, bar := range bars {
+				rows = append(rows, []interface{}{bar.Timestamp.Format(time.RFC3339), bar.Open, bar.High, bar.Low, bar.Close, bar.Volume})
+			}
+			return map[string]interface{}{
+				"symbol":    symbol,
+				"timeframe": timeframe,
+				"columns":   []string{"time", "open", "high", "low", "close", "volume"},
+				"bars":      rows,
+			}, nil
+		},
+	},
+	"get_positions": {
+		description: "The account's open positions with entry price, market value and unrealized P/L",
+		parameters:  llmToolParams(map[string]interface{}{}),
+		call: func(tb *LLMToolBridge, ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			positions, err := tb.tradingService.GetPositions(ctx)
+			if err != nil {
+				return nil, err
+			}
+			rows := make([]map[string]interface{}, 0, len(positions))
+			for _, p := range positions {
+				rows = append(rows, map[string]interface{}{
+					"symbol":            p.Symbol,
+					"side":              p.Side,
+					"qty":               p.Qty.String(),
+					"avg_entry_price":   p.AvgEntryPrice.StringFixed(2),
+					"current_price":     p.CurrentPrice.StringFixed(2),
+					"market_value":      p.MarketValue.StringFixed(2),
+					"unrealized_pl":     p.UnrealizedPL.StringFixed(2),
+					"unrealized_pl_pct": p.UnrealizedPLPC.Shift(2).StringFixed(2),
+				})
+			}
+			return map[string]interface{}{"positions": rows}, nil
+		},
+	},
+	"get_news": {
+		description: "Recent news headlines about a symbol, newest first",
+		parameters: llmToolParams(map[string]interface{}{
+			"symbol": llmToolString("Ticker, e.g. NVDA"),
+			"limit":  map[string]interface{}{"type": "INTEGER", "description": "Headlines to return, 1-10"},
+		}, "symbol"),
+		call: func(tb *LLMToolBridge, ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			symbol, err := llmToolSymbol(args)
+			if err != nil {
+				return nil, err
+			}
+			limit := llmToolMaxHeadline
+			if l, ok := args["limit"].(float64); ok && l >= 1 && int(l) < limit {
+				limit = int(l)
+			}
+
+			started := time.Now()
+			news, err := tb.newsService.GetGoogleNewsSearch(symbol)
+			RecordUpstreamCall(ctx, UpstreamNews, started, err)
+			if err != nil {
+				return nil, err
+			}
+			rows := make([]map[string]interface{}, 0, limit)
+			for _, item := range news[:min(len(news), limit)] {
+				rows = append(rows, map[string]interface{}{
+					"title":     item.Title,
+					"source":    item.Source,
+					"published": item.PubDate,
+				})
+			}
+			return map[string]interface{}{"symbol": symbol, "headlines": rows}, nil
+		},
+	},
+}
+
+// LLMToolBridge exposes a read-only subset of the services to the model as
+// callable functions. Each answer may make a limited number of calls, and
+// every call is written to the audit log.
+type LLMToolBridge struct {
+	dataService    interfaces.DataService
+	tradingService interfaces.TradingService
+	newsService    *NewsService
+	storageService *database.LocalStorage
+	maxCalls       int
+	logger         *logrus.Logger
+}
+
+// NewLLMToolBridge creates a tool bridge allowing maxCalls calls per answer
+func NewLLMToolBridge(dataService interfaces.DataService, tradingService interfaces.TradingService, newsService *NewsService, storageService *database.LocalStorage, maxCalls int) *LLMToolBridge {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &LLMToolBridge{
+		dataService:    dataService,
+		tradingService: tradingService,
+		newsService:    newsService,
+		storageService: storageService,
+		maxCalls:       maxCalls,
+		logger:         logger,
+	}
+}
+
+// declarations lists the tools for the model
+func (tb *LLMToolBridge) declarations() []GeminiFunctionDeclaration {
+	declarations := make([]GeminiFunctionDeclaration, 0, len(llmToolNames))
+	for _, name := range llmToolNames {
+		tool := llmTools[name]
+		declarations = append(declarations, GeminiFunctionDeclaration{
+			Name:        name,
+			Description: tool.description,
+			Parameters:  tool.parameters,
+		})
+	}
+	return declarations
+}
+
+// RecentCalls returns the latest audited tool calls, newest first
+func (tb *LLMToolBridge) RecentCalls(limit int) ([]*LLMToolCall, error) {
+	saved, err := tb.storageService.GetLLMToolCalls(limit)
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]*LLMToolCall, 0, len(saved))
+	for _, call := range saved {
+		calls = append(calls, &LLMToolCall{
+			Source:     call.Source,
+			Tool:       call.Tool,
+			Args:       call.Args,
+			Status:     call.Status,
+			Error:      call.Error,
+			DurationMs: call.DurationMs,
+			At:         call.CreatedAt,
+		})
+	}
+	return calls, nil
+}
+
+// llmToolRun counts the tool calls made for one answer
+type llmToolRun struct {
+	bridge *LLMToolBridge
+	source string
+	calls  int
+}
+
+// newRun starts counting calls for an answer to a source prompt
+func (tb *LLMToolBridge) newRun(source string) *llmToolRun {
+	return &llmToolRun{bridge: tb, source: source}
+}
+
+// remaining is the number of calls the run may still make
+func (r *llmToolRun) remaining() int {
+	return maxInt(0, r.bridge.maxCalls-r.calls)
+}
+
+// invoke runs a tool call, records it, and returns the function response
+// for the model. Failures are returned to the model as an error field.
+func (r *llmToolRun) invoke(ctx context.Context, name string, args map[string]interface{}) map[string]interface{} {
+	started := time.Now()
+	status := LLMToolCallOK
+	var result interface{}
+	var err error
+
+	if r.remaining() == 0 {
+		status = LLMToolCallLimited
+		err = fmt.Errorf("tool call limit of %d reached, answer with the data you have", r.bridge.maxCalls)
+	} else {
+		r.calls++
+		tool, ok := llmTools[name]
+		if !ok {
+			err = fmt.Errorf("unknown tool %q", name)
+		} else {
+			result, err = tool.call(r.bridge, ctx, args)
+		}
+		if err != nil {
+			status = LLMToolCallError
+		}
+	}
+
+	encodedArgs, _ := json.Marshal(args)
+	call := &models.DBLLMToolCall{
+		Source:     r.source,
+		Tool:       name,
+		Args:       string(encodedArgs),
+		Status:     status,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	if saveErr := r.bridge.storageService.SaveLLMToolCall(call); saveErr != nil {
+		r.bridge.logger.WithError(saveErr).Warn("Failed to record LLM tool call")
+	}
+	r.bridge.logger.WithFields(logrus.Fields{
+		"source": r.source,
+		"tool":   name,
+		"args":   call.Args,
+		"status": status,
+	}).Info("LLM tool call")
+
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+	return map[string]interface{}{"result": result}
+}
+
+// llmToolSymbol reads and checks the symbol argument
+func llmToolSymbol(args map[string]interface{}) (string, error) {
+	symbol, _ := args["symbol"].(string)
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if !llmToolSymbolPattern.MatchString(symbol) {
+		return "", fmt.Errorf("invalid symbol %q", symbol)
+	}
+	return symbol, nil
+}
+
+// llmToolParams builds an object schema
+func llmToolParams(properties map[string]interface{}, required ...string) map[string]interface{} {
+	if len(properties) == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"type":       "OBJECT",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// llmToolString is a string parameter schema
+func llmToolString(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "STRING", "description": description}
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// marginAlertHysteresis is how many percentage points utilization must fall
+// below a crossed threshold before it is reported clear, so an account
+// hovering at a threshold doesn't alert on every check
+const marginAlertHysteresis = 2.0
+
+// FINRA minimum maintenance requirements, used to estimate the margin a new
+// equity order adds
+var (
+	longMaintenanceRate  = decimal.NewFromFloat(0.25)
+	shortMaintenanceRate = decimal.NewFromFloat(0.30)
+)
+
+// MarginStatus is the account's margin position at one point in time
+type MarginStatus struct {
+	Time              time.Time       `json:"time"`
+	Equity            decimal.Decimal `json:"equity"`
+	BuyingPower       decimal.Decimal `json:"buying_power"`
+	RegTBuyingPower   decimal.Decimal `json:"regt_buying_power"`
+	Multiplier        decimal.Decimal `json:"multiplier"`
+	InitialMargin     decimal.Decimal `json:"initial_margin"`
+	MaintenanceMargin decimal.Decimal `json:"maintenance_margin"`
+	UtilizationPct    float64         `json:"utilization_pct"` // maintenance margin as a percent of equity
+}
+
+// MarginReport is the current margin status with its recorded history
+type MarginReport struct {
+	Current      *MarginStatus   `json:"current"`
+	Thresholds   []float64       `json:"thresholds_pct"`
+	AlertLevel   float64         `json:"alert_level_pct"` // highest threshold crossed, 0 when none
+	History      []*MarginStatus `json:"history"`
+	HistoryStart time.Time       `json:"history_start"`
+	HistoryEnd   time.Time       `json:"history_end"`
+}
+
+// MarginUtilization is maintenance margin as a percent of equity, or 0 when
+// the broker doesn't report margin
+func MarginUtilization(account *interfaces.Account) float64 {
+	equity := accountEquity(account)
+	if !equity.IsPositive() || account.MaintenanceMargin.IsZero() {
+		return 0
+	}
+	return account.MaintenanceMargin.Div(equity).Mul(decimal.NewFromInt(100)).InexactFloat64()
+}
+
+// accountEquity is the account's equity, falling back to portfolio value for
+// brokers that only report that
+func accountEquity(account *interfaces.Account) decimal.Decimal {
+	if account.Equity.IsPositive() {
+		return account.Equity
+	}
+	return account.PortfolioValue
+}
+
+// NewMarginStatus reads the margin figures off an account
+func NewMarginStatus(account *interfaces.Account, at time.Time) *MarginStatus {
+	return &MarginStatus{
+		Time:              at,
+		Equity:            accountEquity(account),
+		BuyingPower:       account.BuyingPower,
+		RegTBuyingPower:   account.RegTBuyingPower,
+		Multiplier:        account.Multiplier,
+		InitialMargin:     account.InitialMargin,
+		MaintenanceMargin: account.MaintenanceMargin,
+		UtilizationPct:    MarginUtilization(account),
+	}
+}
+
+// MarginMonitor watches margin utilization and notifies when it crosses one
+// of the configured thresholds, and again when it falls back below
+type MarginMonitor struct {
+	tradingService interfaces.TradingService
+	storage        *database.LocalStorage
+	notifier       *Notifier
+	thresholds     []float64 // percent of equity, ascending
+
+	mu    sync.Mutex
+	level int // number of thresholds crossed
+	last  *MarginStatus
+
+	logger *logrus.Logger
+}
+
+// NewMarginMonitor creates a margin monitor alerting at the given
+// utilization thresholds, in percent of equity
+func NewMarginMonitor(tradingService interfaces.TradingService, storage *database.LocalStorage, notifier *Notifier, thresholds []float64) *MarginMonitor {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	sorted := make([]float64, 0, len(thresholds))
+	for _, t := range thresholds {
+		if t > 0 {
+			sorted = append(sorted, t)
+		}
+	}
+	sort.Float64s(sorted)
+
+	return &MarginMonitor{
+		tradingService: tradingService,
+		storage:        storage,
+		notifier:       notifier,
+		thresholds:     sorted,
+		logger:         logger,
+	}
+}
+
+// Check reads the account and alerts on threshold crossings
+func (m *MarginMonitor) Check(ctx context.Context) error {
+	account, err := m.tradingService.GetAccount(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get account: %w", err)
+	}
+	m.observe(ctx, NewMarginStatus(account, time.Now()))
+	return nil
+}
+
+// observe updates the alert level for a new status
+func (m *MarginMonitor) observe(ctx context.Context, status *MarginStatus) {
+	m.mu.Lock()
+	m.last = status
+	previous := m.level
+	level := previous
+	for level < len(m.thresholds) && status.UtilizationPct >= m.thresholds[level] {
+		level++
+	}
+	for level > 0 && status.UtilizationPct < m.thresholds[level-1]-marginAlertHysteresis {
+		level--
+	}
+	m.level = level
+	m.mu.Unlock()
+
+	if level == previous {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"utilization_pct":    fmt.Sprintf("%.1f", status.UtilizationPct),
+		"equity":             status.Equity.StringFixed(2),
+		"maintenance_margin": status.MaintenanceMargin.StringFixed(2),
+		"buying_power":       status.BuyingPower.StringFixed(2),
+	}
+	m.logger.WithFields(logrus.Fields(fields)).Warn("Margin utilization changed alert level")
+
+	if level > previous {
+		notifyLevel := NotifyWarning
+		if level == len(m.thresholds) {
+			notifyLevel = NotifyCritical
+		}
+		m.notifier.Notify(ctx, notifyLevel, "Margin utilization high",
+			fmt.Sprintf("Maintenance margin is %.1f%% of equity, above the %.0f%% threshold", status.UtilizationPct, m.thresholds[level-1]),
+			fields)
+		return
+	}
+
+	message := fmt.Sprintf("Maintenance margin is back down to %.1f%% of equity", status.UtilizationPct)
+	if level > 0 {
+		message += fmt.Sprintf(", still above the %.0f%% threshold", m.thresholds[level-1])
+	}
+	m.notifier.Notify(ctx, NotifyInfo, "Margin utilization eased", message, fields)
+}
+
+// Report returns the live margin status and the history recorded by account
+// snapshots between start and end
+func (m *MarginMonitor) Report(ctx context.Context, start, end time.Time) (*MarginReport, error) {
+	account, err := m.tradingService.GetAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	current := NewMarginStatus(account, time.Now())
+
+	snapshots, err := m.storage.GetAccountSnapshots(start, end)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]*MarginStatus, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		// Snapshots taken before margin was recorded carry no equity
+		if snapshot.Equity.IsZero() {
+			continue
+		}
+		history = append(history, NewMarginStatus(&interfaces.Account{
+			PortfolioValue:    snapshot.PortfolioValue,
+			BuyingPower:       snapshot.BuyingPower,
+			Equity:            snapshot.Equity,
+			RegTBuyingPower:   snapshot.RegTBuyingPower,
+			Multiplier:        snapshot.Multiplier,
+			InitialMargin:     snapshot.InitialMargin,
+			MaintenanceMargin: snapshot.MaintenanceMargin,
+		}, snapshot.SnapshotTime))
+	}
+
+	m.mu.Lock()
+	var level float64
+	if m.level > 0 {
+		level = m.thresholds[m.level-1]
+	}
+	m.mu.Unlock()
+
+	return &MarginReport{
+		Current:      current,
+		Thresholds:   m.thresholds,
+		AlertLevel:   level,
+		History:      history,
+		HistoryStart: start,
+		HistoryEnd:   end,
+	}, nil
+}
+
+// MarginRule flags orders that add margin while maintenance margin is, or
+// would be, above MaxUtilizationPct of equity. Orders that reduce a position
+// and option buys, which are paid in full, always pass. Accounts whose
+// broker doesn't report maintenance margin are not checked.
+type MarginRule struct {
+	MaxUtilizationPct float64
+	Block             bool
+}
+
+func (MarginRule) Name() string { return "margin_utilization" }
+
+func (r MarginRule) Check(ctx context.Context, check *RiskCheck) error {
+	if r.MaxUtilizationPct <= 0 || check.Account.MaintenanceMargin.IsZero() {
+		return nil
+	}
+	if check.AssetClass == "us_option" && check.Order.Side == "buy" {
+		return nil
+	}
+	equity := accountEquity(check.Account)
+	if !equity.IsPositive() {
+		return nil
+	}
+
+	// Only the part of the order that opens or adds to a position adds margin
+	var held decimal.Decimal
+	for _, p := range check.Positions {
+		if p.Symbol == check.Order.Symbol {
+			held = p.Qty
+			break
+		}
+	}
+	added := check.Order.Qty
+	rate := longMaintenanceRate
+	switch {
+	case check.Order.Side == "buy" && held.IsNegative():
+		added = added.Add(held)
+	case check.Order.Side == "sell" && held.IsPositive():
+		added = added.Sub(held)
+		rate = shortMaintenanceRate
+	case check.Order.Side == "sell":
+		rate = shortMaintenanceRate
+	}
+	if !added.IsPositive() {
+		return nil
+	}
+
+	current := MarginUtilization(check.Account)
+	projected := current
+	if check.AssetClass != "us_option" {
+		addedMargin := added.Mul(check.EstimatedPrice).Mul(check.Multiplier).Mul(rate)
+		projected = check.Account.MaintenanceMargin.Add(addedMargin).Div(equity).Mul(decimal.NewFromInt(100)).InexactFloat64()
+	}
+
+	var message string
+	switch {
+	case current >= r.MaxUtilizationPct:
+		message = fmt.Sprintf("margin utilization is %.1f%% of equity (cap %.1f%%)", current, r.MaxUtilizationPct)
+	case projected > r.MaxUtilizationPct:
+		message = fmt.Sprintf("margin utilization would be about %.1f%% of equity (cap %.1f%%)", projected, r.MaxUtilizationPct)
+	default:
+		return nil
+	}
+
+	if r.Block {
+		return fmt.Errorf("%s", message)
+	}
+	return &RiskWarning{Message: message}
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Market brief limits
+const (
+	briefWindow             = 24 * time.Hour // headlines behind the rollups
+	briefMarketHeadlines    = 40             // newest new headlines sent for the market summary
+	briefSymbolHeadlines    = 10             // newest new headlines sent per symbol
+	briefSymbolsPerUpdate   = 15             // symbols re-summarized per update, most new headlines first
+	briefHeadlinesPerSymbol = 5              // latest headlines listed per symbol
+)
+
+// SymbolBrief is the rolling news summary of one symbol
+type SymbolBrief struct {
+	Symbol    string     `json:"symbol"`
+	Summary   string     `json:"summary,omitempty"` // empty until the LLM has summarized the symbol
+	Sentiment float64    `json:"sentiment"`         // mean headline score, -1 to 1
+	Headlines int        `json:"headlines"`         // in the last 24 hours
+	Latest    []NewsItem `json:"latest"`            // newest first
+	UpdatedAt time.Time  `json:"updated_at,omitempty"`
+}
+
+// MarketBrief is the continuously maintained summary of the stored news
+type MarketBrief struct {
+	GeneratedAt time.Time      `json:"generated_at"` // last successful summary
+	Summary     string         `json:"summary"`
+	Headlines   int            `json:"headlines"` // deduplicated, in the last 24 hours
+	Symbols     []*SymbolBrief `json:"symbols"`   // most headlines first
+	Error       string         `json:"error,omitempty"`
+}
+
+// MarketBriefService ingests stored news as it arrives and periodically
+// asks the LLM to revise a market-wide summary and per-symbol rollups
+type MarketBriefService struct {
+	newsService   *NewsService
+	geminiService *GeminiService
+	eventBus      *EventBus
+	headlines     []NewsItem // deduplicated by link, oldest first
+	fresh         []NewsItem // not yet summarized
+	seen          map[string]bool
+	summary       NewsBriefSummary
+	symbolUpdated map[string]time.Time
+	generatedAt   time.Time
+	lastErr       string
+	mu            sync.Mutex
+	logger        *logrus.Logger
+}
+
+// NewMarketBriefService creates a market brief service
+func NewMarketBriefService(newsService *NewsService, geminiService *GeminiService) *MarketBriefService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &MarketBriefService{
+		newsService:   newsService,
+		geminiService: geminiService,
+		seen:          make(map[string]bool),
+		summary:       NewsBriefSummary{Symbols: make(map[string]string)},
+		symbolUpdated: make(map[string]time.Time),
+		logger:        logger,
+	}
+}
+
+// SetEventBus publishes every brief update as a market_brief event
+func (bs *MarketBriefService) SetEventBus(bus *EventBus) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.eventBus = bus
+}
+
+// Run ingests news until ctx is done and revises the brief every interval
+// when new headlines have arrived
+func (bs *MarketBriefService) Run(ctx context.Context, interval time.Duration) {
+	id, items := bs.newsService.Subscribe(500)
+	defer bs.newsService.Unsubscribe(id)
+
+	for _, item := range bs.newsService.StoredItems(time.Now().Add(-briefWindow)) {
+		bs.ingest(item)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	bs.logger.WithField("interval", interval).Info("Market brief started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item, ok := <-items:
+			if !ok {
+				return
+			}
+			bs.ingest(item)
+		case <-ticker.C:
+			bs.update(ctx)
+		}
+	}
+}
+
+// ingest adds a headline the brief has not seen
+func (bs *MarketBriefService) ingest(item NewsItem) {
+	if item.PublishedAt.IsZero() {
+		item.PublishedAt = time.Now()
+	}
+	if time.Since(item.PublishedAt) > briefWindow {
+		return
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if item.Link == "" || bs.seen[item.Link] {
+		return
+	}
+	bs.seen[item.Link] = true
+	bs.headlines = append(bs.headlines, item)
+	bs.fresh = append(bs.fresh, item)
+}
+
+// update sends the new headlines to the LLM and publishes the revised brief.
+// On failure the previous summaries are kept and the headlines are retried
+// on the next update.
+func (bs *MarketBriefService) update(ctx context.Context) {
+	bs.mu.Lock()
+	bs.prune()
+	if len(bs.fresh) == 0 {
+		bs.mu.Unlock()
+		return
+	}
+	fresh := append([]NewsItem(nil), bs.fresh...)
+	previous := NewsBriefSummary{Market: bs.summary.Market, Symbols: make(map[string]string, len(bs.summary.Symbols))}
+	for symbol, text := range bs.summary.Symbols {
+		previous.Symbols[symbol] = text
+	}
+	bs.mu.Unlock()
+
+	// Newest first, so the caps keep the latest news
+	sort.SliceStable(fresh, func(i, j int) bool { return fresh[i].PublishedAt.After(fresh[j].PublishedAt) })
+	bySymbol := groupBySymbol(fresh)
+	symbols := make([]string, 0, len(bySymbol))
+	for symbol := range bySymbol {
+		symbols = append(symbols, symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if len(bySymbol[symbols[i]]) != len(bySymbol[symbols[j]]) {
+			return len(bySymbol[symbols[i]]) > len(bySymbol[symbols[j]])
+		}
+		return symbols[i] < symbols[j]
+	})
+	selected := make(map[string][]NewsItem)
+	for _, symbol := range symbols[:min(len(symbols), briefSymbolsPerUpdate)] {
+		items := bySymbol[symbol]
+		selected[symbol] = items[:min(len(items), briefSymbolHeadlines)]
+	}
+
+	revised, err := bs.geminiService.SummarizeNewsBrief(ctx, &previous, fresh[:min(len(fresh), briefMarketHeadlines)], selected)
+
+	bs.mu.Lock()
+	if err != nil {
+		bs.lastErr = err.Error()
+		bs.mu.Unlock()
+		bs.logger.WithError(err).Warn("Failed to update market brief")
+		return
+	}
+
+	now := time.Now()
+	// Keep what arrived while the LLM was working
+	bs.fresh = bs.fresh[len(fresh):]
+	bs.lastErr = ""
+	bs.generatedAt = now
+	if revised.Market != "" {
+		bs.summary.Market = revised.Market
+	}
+	for symbol := range selected {
+		if text := revised.Symbols[symbol]; text != "" {
+			bs.summary.Symbols[symbol] = text
+			bs.symbolUpdated[symbol] = now
+		}
+	}
+	brief := bs.brief()
+	eventBus := bs.eventBus
+	bs.mu.Unlock()
+
+	eventBus.Publish(EventMarketBrief, brief)
+
+	bs.logger.WithFields(logrus.Fields{
+		"headlines": len(fresh),
+		"symbols":   len(selected),
+	}).Info("Market brief updated")
+}
+
+// prune drops headlines older than the window, and the summaries of
+// symbols no longer in the news. The caller holds bs.mu.
+func (bs *MarketBriefService) prune() {
+	cutoff := time.Now().Add(-briefWindow)
+	kept := bs.headlines[:0]
+	for _, item := range bs.headlines {
+		if item.PublishedAt.Before(cutoff) {
+			delete(bs.seen, item.Link)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	bs.headlines = kept
+
+	mentioned := groupBySymbol(bs.headlines)
+	for symbol := range bs.summary.Symbols {
+		if _, ok := mentioned[symbol]; !ok {
+			delete(bs.summary.Symbols, symbol)
+			delete(bs.symbolUpdated, symbol)
+		}
+	}
+}
+
+// Brief returns the current market brief
+func (bs *MarketBriefService) Brief() *MarketBrief {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.prune()
+	return bs.brief()
+}
+
+// brief builds the brief from the stored headlines. The caller holds bs.mu.
+func (bs *MarketBriefService) brief() *MarketBrief {
+	brief := &MarketBrief{
+		GeneratedAt: bs.generatedAt,
+		Summary:     bs.summary.Market,
+		Headlines:   len(bs.headlines),
+		Symbols:     make([]*SymbolBrief, 0),
+		Error:       bs.lastErr,
+	}
+
+	newest := make([]NewsItem, len(bs.headlines))
+	copy(newest, bs.headlines)
+	sort.SliceStable(newest, func(i, j int) bool { return newest[i].PublishedAt.After(newest[j].PublishedAt) })
+
+	for symbol, items := range groupBySymbol(newest) {
+		sb := &SymbolBrief{
+			Symbol:    symbol,
+			Summary:   bs.summary.Symbols[symbol],
+			Headlines: len(items),
+			Latest:    items[:min(len(items), briefHeadlinesPerSymbol)],
+			UpdatedAt: bs.symbolUpdated[symbol],
+		}
+		for _, item := range items {
+			sb.Sentiment += scoreSentiment(item.Title + " " + item.Description)
+		}
+		sb.Sentiment /= float64(len(items))
+		brief.Symbols = append(brief.Symbols, sb)
+	}
+	sort.Slice(brief.Symbols, func(i, j int) bool {
+		if brief.Symbols[i].Headlines != brief.Symbols[j].Headlines {
+			return brief.Symbols[i].Headlines > brief.Symbols[j].Headlines
+		}
+		return brief.Symbols[i].Symbol < brief.Symbols[j].Symbol
+	})
+	return brief
+}
+
+// groupBySymbol lists the headlines mentioning each ticker, keeping order
+func groupBySymbol(items []NewsItem) map[string][]NewsItem {
+	groups := make(map[string][]NewsItem)
+	for _, item := range items {
+		for _, symbol := range extractTickers(item.Title + " " + strings.TrimSpace(item.Description)) {
+			groups[symbol] = append(groups[symbol], item)
+		}
+	}
+	return groups
+}
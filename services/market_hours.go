@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Asset classes with their own trading hours
+const (
+	AssetClassEquity = "us_equity"
+	AssetClassOption = "us_option"
+	AssetClassCrypto = "crypto"
+)
+
+// Market sessions, in New York time
+const (
+	SessionClosed     = "closed"
+	SessionPreMarket  = "pre_market"  // 4:00 to the open on trading days, equities only
+	SessionRegular    = "regular"     // 9:30-16:00 on trading days, earlier on half-days
+	SessionAfterHours = "after_hours" // four hours after the close, equities only
+	SessionContinuous = "continuous"  // crypto trades around the clock
+)
+
+// Session boundaries in minutes after midnight ET
+const (
+	preMarketOpen    = 4 * 60
+	regularOpen      = 9*60 + 30
+	regularClose     = 16 * 60
+	afterHoursClose  = 20 * 60
+	marketClockTTL   = 30 * time.Second // how long the broker's open/closed answer is reused
+	marketTimeFormat = "15:04"
+	marketDateFormat = "2006-01-02"
+)
+
+// Market calendar caching
+const (
+	marketCalendarTTL      = 24 * time.Hour  // how long fetched trading days are reused
+	marketCalendarRetry    = 5 * time.Minute // wait after a failed fetch before asking again
+	marketCalendarPastDays = 7
+	marketCalendarDays     = 60 // trading days are fetched this far ahead
+)
+
+// MarketClock is implemented by brokers that know the exchange calendar,
+// including holidays and early closes
+type MarketClock interface {
+	IsMarketOpen(ctx context.Context) (bool, error)
+}
+
+// MarketCalendar is implemented by brokers that publish the exchange
+// calendar ahead of time, so holidays and early closes are known before
+// the day. It returns the trading days between start and end.
+type MarketCalendar interface {
+	GetMarketCalendar(ctx context.Context, start, end time.Time) ([]MarketDay, error)
+}
+
+// MarketDay is the regular session of one trading day
+type MarketDay struct {
+	Open  time.Time `json:"open"`
+	Close time.Time `json:"close"`
+}
+
+// ClosesEarlyBy returns how long before 16:00 ET the session closes, 0 on
+// a full day
+func (d MarketDay) ClosesEarlyBy() time.Duration {
+	full := marketTimeOn(d.Close, regularClose)
+	if !d.Close.Before(full) {
+		return 0
+	}
+	return full.Sub(d.Close)
+}
+
+// MarketHours is the one place that knows when each asset class trades.
+// The order queue, DCA and rebalance schedulers, the position manager and
+// the trading hours risk rule all ask it rather than checking the clock
+// themselves. Equities trade in the pre-market, regular and after-hours
+// sessions, options only in the regular session and crypto at all times.
+// With a broker calendar, holidays and early closes are known in advance;
+// with a broker clock, its open/closed answer overrides the session.
+// Otherwise weekdays are assumed to be full trading days.
+type MarketHours struct {
+	clock    MarketClock    // nil uses the weekday calendar
+	calendar MarketCalendar // nil uses the weekday calendar
+	location *time.Location
+	now      func() time.Time
+
+	mu        sync.Mutex
+	clockOpen bool
+	checkedAt time.Time // when clockOpen was asked, zero before the first answer
+
+	days             map[string]MarketDay // trading days by date, between daysFrom and daysTo
+	daysFrom, daysTo time.Time
+	daysAt           time.Time // when days were fetched, zero before the first fetch
+	daysFailedAt     time.Time
+
+	logger *logrus.Logger
+}
+
+// NewMarketHours creates market hours that follow the weekday calendar
+// until a broker clock is set
+func NewMarketHours() *MarketHours {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &MarketHours{
+		location: marketLocation,
+		now:      time.Now,
+		logger:   logger,
+	}
+}
+
+// SetMarketClock asks the broker whether the regular session is open, so
+// holidays and early closes are honoured
+func (h *MarketHours) SetMarketClock(clock MarketClock) {
+	h.clock = clock
+}
+
+// SetMarketCalendar fetches trading days from the broker, so holidays and
+// early closes are known before the day
+func (h *MarketHours) SetMarketCalendar(calendar MarketCalendar) {
+	h.calendar = calendar
+}
+
+// Location returns the exchange time zone
+func (h *MarketHours) Location() *time.Location {
+	return h.location
+}
+
+// Session returns the session assetClass is in now
+func (h *MarketHours) Session(ctx context.Context, assetClass string) string {
+	if assetClass == AssetClassCrypto {
+		return SessionContinuous
+	}
+	now := h.now()
+	session := SessionClosed
+	if day, ok := h.Day(ctx, now); ok {
+		session = sessionOn(now, day, assetClass)
+	}
+	if h.clock == nil {
+		return session
+	}
+
+	open, ok := h.clockSaysOpen(ctx)
+	switch {
+	case !ok:
+		return session
+	case open:
+		return SessionRegular
+	case session == SessionRegular:
+		// A holiday or an early close
+		return SessionClosed
+	}
+	return session
+}
+
+// Day returns the trading day on t's date in New York, or false on weekends
+// and holidays. Without a calendar, or for dates outside the fetched range,
+// weekdays are full 9:30-16:00 trading days.
+func (h *MarketHours) Day(ctx context.Context, t time.Time) (MarketDay, bool) {
+	if day, trading, known := h.calendarDay(ctx, t); known {
+		return day, trading
+	}
+	return weekdayMarketDay(t)
+}
+
+// calendarDay looks t's date up in the broker calendar, fetching it when
+// it is stale. known is false when the calendar has no answer for the date.
+func (h *MarketHours) calendarDay(ctx context.Context, t time.Time) (day MarketDay, trading, known bool) {
+	if h.calendar == nil {
+		return MarketDay{}, false, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stale := h.daysAt.IsZero() || time.Since(h.daysAt) >= marketCalendarTTL
+	if stale && (h.daysFailedAt.IsZero() || time.Since(h.daysFailedAt) >= marketCalendarRetry) {
+		h.fetchCalendar(ctx)
+	}
+	date := marketTimeOn(t, 0)
+	if h.daysAt.IsZero() || date.Before(h.daysFrom) || date.After(h.daysTo) {
+		return MarketDay{}, false, false
+	}
+	day, trading = h.days[date.Format(marketDateFormat)]
+	return day, trading, true
+}
+
+// fetchCalendar replaces the cached trading days with those around today.
+// On failure the previous days are kept. The caller holds h.mu.
+func (h *MarketHours) fetchCalendar(ctx context.Context) {
+	today := marketTimeOn(h.now(), 0)
+	from, to := today.AddDate(0, 0, -marketCalendarPastDays), today.AddDate(0, 0, marketCalendarDays)
+	days, err := h.calendar.GetMarketCalendar(ctx, from, to)
+	if err != nil {
+		h.logger.WithError(err).Warn("Market calendar unavailable, using weekday calendar")
+		h.daysFailedAt = time.Now()
+		return
+	}
+
+	h.days = make(map[string]MarketDay, len(days))
+	for _, day := range days {
+		h.days[day.Open.In(h.location).Format(marketDateFormat)] = day
+	}
+	h.daysFrom, h.daysTo = from, to
+	h.daysAt, h.daysFailedAt = time.Now(), time.Time{}
+}
+
+// IsOpen reports whether orders for assetClass execute now: it is in its
+// regular session, or trades around the clock. Orders are never flagged for
+// extended hours, so equities wait for the regular session too.
+func (h *MarketHours) IsOpen(ctx context.Context, assetClass string) bool {
+	session := h.Session(ctx, assetClass)
+	return session == SessionRegular || session == SessionContinuous
+}
+
+// clockSaysOpen asks the broker clock, reusing its answer for
+// marketClockTTL. ok is false when the clock failed and never answered.
+func (h *MarketHours) clockSaysOpen(ctx context.Context) (open, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.checkedAt.IsZero() && time.Since(h.checkedAt) < marketClockTTL {
+		return h.clockOpen, true
+	}
+	open, err := h.clock.IsMarketOpen(ctx)
+	if err != nil {
+		h.logger.WithError(err).Warn("Market clock unavailable, using regular session hours")
+		return false, false
+	}
+	h.clockOpen, h.checkedAt = open, time.Now()
+	return open, true
+}
+
+// MarketHoursStatus is the current session of each asset class
+type MarketHoursStatus struct {
+	Time     time.Time         `json:"time"`
+	Sessions map[string]string `json:"sessions"`        // asset class -> session
+	Today    *MarketDay        `json:"today,omitempty"` // today's regular session, nil on weekends and holidays
+	Clock    bool              `json:"broker_clock"`    // whether the broker's clock is consulted
+	Calendar bool              `json:"broker_calendar"` // whether holidays and early closes come from the broker
+}
+
+// Status returns the current session of every asset class
+func (h *MarketHours) Status(ctx context.Context) *MarketHoursStatus {
+	status := &MarketHoursStatus{
+		Time:     h.now().In(h.location),
+		Sessions: make(map[string]string),
+		Clock:    h.clock != nil,
+		Calendar: h.calendar != nil,
+	}
+	if day, ok := h.Day(ctx, status.Time); ok {
+		status.Today = &day
+	}
+	for _, assetClass := range []string{AssetClassEquity, AssetClassOption, AssetClassCrypto} {
+		status.Sessions[assetClass] = h.Session(ctx, assetClass)
+	}
+	return status
+}
+
+// SessionAt returns the session assetClass is in at t by the weekday
+// calendar, without holidays or early closes
+func SessionAt(t time.Time, assetClass string) string {
+	if assetClass == AssetClassCrypto {
+		return SessionContinuous
+	}
+	day, ok := weekdayMarketDay(t)
+	if !ok {
+		return SessionClosed
+	}
+	return sessionOn(t, day, assetClass)
+}
+
+// sessionOn returns the session assetClass is in at t on trading day day.
+// The pre-market starts at 4:00 and the after-hours session runs for four
+// hours after the close, so it ends at 17:00 on a 13:00 half-day.
+func sessionOn(t time.Time, day MarketDay, assetClass string) string {
+	switch {
+	case !t.Before(day.Open) && t.Before(day.Close):
+		return SessionRegular
+	case assetClass == AssetClassOption:
+		return SessionClosed
+	case !t.Before(marketTimeOn(day.Open, preMarketOpen)) && t.Before(day.Open):
+		return SessionPreMarket
+	case !t.Before(day.Close) && t.Before(day.Close.Add((afterHoursClose-regularClose)*time.Minute)):
+		return SessionAfterHours
+	}
+	return SessionClosed
+}
+
+// weekdayMarketDay returns a 9:30-16:00 session on t's date in New York,
+// or false at weekends
+func weekdayMarketDay(t time.Time) (MarketDay, bool) {
+	t = t.In(marketLocation)
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return MarketDay{}, false
+	}
+	return MarketDay{Open: marketTimeOn(t, regularOpen), Close: marketTimeOn(t, regularClose)}, true
+}
+
+// marketTimeOn returns minutes after midnight ET on t's date in New York
+func marketTimeOn(t time.Time, minutes int) time.Time {
+	t = t.In(marketLocation)
+	return time.Date(t.Year(), t.Month(), t.Day(), minutes/60, minutes%60, 0, 0, marketLocation)
+}
+
+// marketAssetClass tells crypto pairs, which trade around the clock, apart
+// from equities and options
+func marketAssetClass(symbol string) string {
+	if strings.Contains(symbol, "/") {
+		return AssetClassCrypto
+	}
+	return assetClassFor(symbol)
+}
+
+// inRegularSession reports whether t falls within 9:30-16:00 in loc, which
+// should be New York time, on a weekday
+func inRegularSession(t time.Time, loc *time.Location) bool {
+	return SessionAt(t.In(loc), AssetClassEquity) == SessionRegular
+}
+
+// inRegularHours reports whether t's time of day in New York falls within
+// 9:30-16:00, whatever the day
+func inRegularHours(t time.Time) bool {
+	t = t.In(marketLocation)
+	minutes := t.Hour()*60 + t.Minute()
+	return minutes >= regularOpen && minutes < regularClose
+}
+
+// TradingHoursRule flags orders that cannot execute in the current session
+// of their asset class, such as options outside regular hours. The broker
+// would only hold them until the open. With Block they are rejected rather
+// than warned about. Opening and closing auction orders are exempt, as they
+// are meant to be placed ahead of the session.
+type TradingHoursRule struct {
+	Hours *MarketHours
+	Block bool
+}
+
+func (TradingHoursRule) Name() string { return "trading_hours" }
+
+func (r TradingHoursRule) Check(ctx context.Context, check *RiskCheck) error {
+	if r.Hours == nil || check.Order.TimeInForce == "opg" || check.Order.TimeInForce == "cls" {
+		return nil
+	}
+	assetClass := check.AssetClass
+	if assetClass == AssetClassEquity {
+		assetClass = marketAssetClass(check.Order.Symbol)
+	}
+	if r.Hours.IsOpen(ctx, assetClass) {
+		return nil
+	}
+
+	session := r.Hours.Session(ctx, assetClass)
+	message := fmt.Sprintf("%s %s orders only execute in the regular session and it is now %s (%s ET)",
+		check.Order.Symbol, assetClass, session, r.Hours.now().In(r.Hours.location).Format(marketTimeFormat))
+	if r.Block {
+		return fmt.Errorf("%s", message)
+	}
+	return &RiskWarning{Message: message}
+}
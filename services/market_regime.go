@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Market regimes
+const (
+	RegimeRiskOn  = "risk_on"
+	RegimeNeutral = "neutral"
+	RegimeRiskOff = "risk_off"
+)
+
+// breadthIndexETFs are the major index ETFs; sector ETFs are added to the
+// breadth universe as well
+var breadthIndexETFs = []string{"SPY", "QQQ", "IWM", "DIA"}
+
+// regimeVolatilityETF tracks short-term VIX futures. Alpaca does not serve
+// the VIX index itself, so its trend stands in for the direction of VIX.
+const regimeVolatilityETF = "VIXY"
+
+// regimeCacheTTL bounds how often the regime is recomputed
+const regimeCacheTTL = 5 * time.Minute
+
+// BreadthReading summarizes advancing and declining index and sector ETFs
+type BreadthReading struct {
+	Symbols       int      `json:"symbols"`
+	Advancing     int      `json:"advancing"`
+	Declining     int      `json:"declining"`
+	AdvanceRatio  float64  `json:"advance_ratio"`         // advancing / (advancing + declining)
+	AboveSMA50Pct float64  `json:"above_sma50_percent"`   // share of ETFs above their 50-day SMA
+	Unavailable   []string `json:"unavailable,omitempty"` // ETFs without data
+}
+
+// VolatilityReading describes the volatility backdrop
+type VolatilityReading struct {
+	RealizedVol20d float64 `json:"realized_vol_20d"` // SPY annualized percent, on the VIX scale
+	Level          string  `json:"level"`            // "low", "normal", "elevated", "high"
+	VIXYPrice      float64 `json:"vixy_price,omitempty"`
+	VIXYvsSMA20Pct float64 `json:"vixy_vs_sma20_percent,omitempty"` // positive when volatility is rising
+}
+
+// TrendReading is the moving-average regime of SPY
+type TrendReading struct {
+	Price       float64 `json:"price"`
+	SMA50       float64 `json:"sma_50"`
+	SMA200      float64 `json:"sma_200"`
+	AboveSMA50  bool    `json:"above_sma_50"`
+	AboveSMA200 bool    `json:"above_sma_200"`
+	GoldenCross bool    `json:"golden_cross"` // SMA50 above SMA200
+}
+
+// MarketRegime is the combined regime classification
+type MarketRegime struct {
+	Regime         string            `json:"regime"` // risk_on, neutral, risk_off
+	Score          int               `json:"score"`  // positive leans risk-on
+	Trend          TrendReading      `json:"trend"`
+	Breadth        BreadthReading    `json:"breadth"`
+	Volatility     VolatilityReading `json:"volatility"`
+	Signals        []string          `json:"signals"`
+	SizeMultiplier float64           `json:"size_multiplier"` // scale applied to new position sizes
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// MarketRegimeService classifies the market as risk-on, neutral or risk-off
+// from SPY's moving averages, ETF breadth and volatility
+type MarketRegimeService struct {
+	dataService   interfaces.DataService
+	neutralSizing float64
+	riskOffSizing float64
+	cached        *MarketRegime
+	mu            sync.Mutex
+	logger        *logrus.Logger
+}
+
+// NewMarketRegimeService creates a regime service. neutralSizing and
+// riskOffSizing are the position size multipliers for those regimes (0-1).
+func NewMarketRegimeService(dataService interfaces.DataService, neutralSizing, riskOffSizing float64) *MarketRegimeService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &MarketRegimeService{
+		dataService:   dataService,
+		neutralSizing: neutralSizing,
+		riskOffSizing: riskOffSizing,
+		logger:        logger,
+	}
+}
+
+// Current returns the market regime, recomputing it at most every few minutes
+func (mrs *MarketRegimeService) Current(ctx context.Context) (*MarketRegime, error) {
+	mrs.mu.Lock()
+	defer mrs.mu.Unlock()
+
+	if mrs.cached != nil && time.Since(mrs.cached.UpdatedAt) < regimeCacheTTL {
+		return mrs.cached, nil
+	}
+
+	regime, err := mrs.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mrs.cached = regime
+
+	mrs.logger.WithFields(logrus.Fields{
+		"regime": regime.Regime,
+		"score":  regime.Score,
+	}).Info("Market regime updated")
+
+	return regime, nil
+}
+
+func (mrs *MarketRegimeService) compute(ctx context.Context) (*MarketRegime, error) {
+	end := time.Now()
+	start := end.AddDate(0, 0, -320) // 200 trading days plus holidays
+
+	spy, err := mrs.dataService.GetHistoricalBars(ctx, "SPY", start, end, "1Day")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SPY bars: %w", err)
+	}
+	if len(spy) < 200 {
+		return nil, fmt.Errorf("not enough SPY history for regime detection (got %d bars)", len(spy))
+	}
+
+	regime := &MarketRegime{
+		Signals:   make([]string, 0),
+		UpdatedAt: time.Now(),
+	}
+
+	// Trend: SPY against its 50 and 200-day moving averages
+	closes := barCloses(spy)
+	trend := TrendReading{
+		Price:  closes[len(closes)-1],
+		SMA50:  average(closes[len(closes)-50:]),
+		SMA200: average(closes[len(closes)-200:]),
+	}
+	trend.AboveSMA50 = trend.Price > trend.SMA50
+	trend.AboveSMA200 = trend.Price > trend.SMA200
+	trend.GoldenCross = trend.SMA50 > trend.SMA200
+	regime.Trend = trend
+
+	if trend.AboveSMA200 {
+		regime.Score++
+		regime.Signals = append(regime.Signals, "SPY above 200-day SMA")
+	} else {
+		regime.Score--
+		regime.Signals = append(regime.Signals, "SPY below 200-day SMA")
+	}
+	if trend.GoldenCross {
+		regime.Score++
+	} else {
+		regime.Score--
+		regime.Signals = append(regime.Signals, "SPY 50-day SMA below 200-day SMA")
+	}
+
+	// Breadth: advancing vs declining ETFs and share above their 50-day SMA
+	regime.Breadth = mrs.breadth(ctx, start, end)
+	switch {
+	case regime.Breadth.AdvanceRatio >= 0.65:
+		regime.Score++
+		regime.Signals = append(regime.Signals, "broad advance across index and sector ETFs")
+	case regime.Breadth.AdvanceRatio <= 0.35:
+		regime.Score--
+		regime.Signals = append(regime.Signals, "broad decline across index and sector ETFs")
+	}
+	switch {
+	case regime.Breadth.AboveSMA50Pct >= 60:
+		regime.Score++
+	case regime.Breadth.AboveSMA50Pct <= 40:
+		regime.Score--
+		regime.Signals = append(regime.Signals, fmt.Sprintf("only %.0f%% of ETFs above 50-day SMA", regime.Breadth.AboveSMA50Pct))
+	}
+
+	// Volatility: realized SPY volatility on the VIX scale, plus VIXY trend
+	regime.Volatility = mrs.volatility(ctx, closes, start, end)
+	switch regime.Volatility.Level {
+	case "low":
+		regime.Score++
+	case "elevated":
+		regime.Score--
+		regime.Signals = append(regime.Signals, fmt.Sprintf("elevated volatility (%.0f)", regime.Volatility.RealizedVol20d))
+	case "high":
+		regime.Score -= 2
+		regime.Signals = append(regime.Signals, fmt.Sprintf("high volatility (%.0f)", regime.Volatility.RealizedVol20d))
+	}
+	if regime.Volatility.VIXYvsSMA20Pct > 15 {
+		regime.Score--
+		regime.Signals = append(regime.Signals, "volatility futures spiking")
+	}
+
+	switch {
+	case regime.Score >= 2:
+		regime.Regime = RegimeRiskOn
+		regime.SizeMultiplier = 1
+	case regime.Score <= -2:
+		regime.Regime = RegimeRiskOff
+		regime.SizeMultiplier = mrs.riskOffSizing
+	default:
+		regime.Regime = RegimeNeutral
+		regime.SizeMultiplier = mrs.neutralSizing
+	}
+
+	return regime, nil
+}
+
+// breadth measures how many index and sector ETFs are up on the day and
+// above their 50-day SMA
+func (mrs *MarketRegimeService) breadth(ctx context.Context, start, end time.Time) BreadthReading {
+	symbols := append([]string{}, breadthIndexETFs...)
+	for _, etf := range sectorETFs {
+		symbols = append(symbols, etf)
+	}
+
+	reading := BreadthReading{}
+	above := 0
+	for _, symbol := range symbols {
+		bars, err := mrs.dataService.GetHistoricalBars(ctx, symbol, start, end, "1Day")
+		if err != nil || len(bars) < 50 {
+			reading.Unavailable = append(reading.Unavailable, symbol)
+			continue
+		}
+
+		closes := barCloses(bars)
+		last, prev := closes[len(closes)-1], closes[len(closes)-2]
+		reading.Symbols++
+		switch {
+		case last > prev:
+			reading.Advancing++
+		case last < prev:
+			reading.Declining++
+		}
+		if last > average(closes[len(closes)-50:]) {
+			above++
+		}
+	}
+
+	if moved := reading.Advancing + reading.Declining; moved > 0 {
+		reading.AdvanceRatio = float64(reading.Advancing) / float64(moved)
+	}
+	if reading.Symbols > 0 {
+		reading.AboveSMA50Pct = float64(above) / float64(reading.Symbols) * 100
+	}
+	return reading
+}
+
+// volatility computes SPY's 20-day realized volatility and the VIXY trend
+func (mrs *MarketRegimeService) volatility(ctx context.Context, spyCloses []float64, start, end time.Time) VolatilityReading {
+	returns := make([]float64, 0, 20)
+	for i := len(spyCloses) - 20; i < len(spyCloses); i++ {
+		returns = append(returns, spyCloses[i]/spyCloses[i-1]-1)
+	}
+
+	reading := VolatilityReading{
+		RealizedVol20d: stdDev(returns) * math.Sqrt(tradingDaysPerYear) * 100,
+	}
+	switch {
+	case reading.RealizedVol20d < 12:
+		reading.Level = "low"
+	case reading.RealizedVol20d < 20:
+		reading.Level = "normal"
+	case reading.RealizedVol20d < 30:
+		reading.Level = "elevated"
+	default:
+		reading.Level = "high"
+	}
+
+	bars, err := mrs.dataService.GetHistoricalBars(ctx, regimeVolatilityETF, start, end, "1Day")
+	if err != nil || len(bars) < 20 {
+		mrs.logger.WithError(err).Debug("VIXY bars unavailable for regime")
+		return reading
+	}
+	closes := barCloses(bars)
+	reading.VIXYPrice = closes[len(closes)-1]
+	if sma := average(closes[len(closes)-20:]); sma > 0 {
+		reading.VIXYvsSMA20Pct = (reading.VIXYPrice/sma - 1) * 100
+	}
+	return reading
+}
+
+// SizeMultiplier returns the position size scale for the current regime, or
+// 1 with a warning when the regime cannot be determined
+func (mrs *MarketRegimeService) SizeMultiplier(ctx context.Context) (float64, string) {
+	regime, err := mrs.Current(ctx)
+	if err != nil {
+		mrs.logger.WithError(err).Warn("Market regime unavailable, not scaling position size")
+		return 1, ""
+	}
+	return regime.SizeMultiplier, regime.Regime
+}
+
+// RegimeRule warns on, or blocks, new buys while the market is risk-off
+type RegimeRule struct {
+	Regime       *MarketRegimeService
+	BlockRiskOff bool
+}
+
+func (RegimeRule) Name() string { return "market_regime" }
+
+func (r RegimeRule) Check(ctx context.Context, check *RiskCheck) error {
+	if r.Regime == nil || check.Order.Side != "buy" {
+		return nil
+	}
+
+	regime, err := r.Regime.Current(ctx)
+	if err != nil {
+		return &RiskWarning{Message: fmt.Sprintf("market regime unavailable: %v", err)}
+	}
+	if regime.Regime != RegimeRiskOff {
+		return nil
+	}
+
+	message := fmt.Sprintf("market regime is risk-off (score %d)", regime.Score)
+	if r.BlockRiskOff {
+		return fmt.Errorf("%s, new entries are blocked", message)
+	}
+	return &RiskWarning{Message: message}
+}
+
+// SizeMultiplier lets the risk manager scale new positions by regime
+func (r RegimeRule) SizeMultiplier(ctx context.Context) (float64, string) {
+	if r.Regime == nil {
+		return 1, ""
+	}
+	return r.Regime.SizeMultiplier(ctx)
+}
@@ -0,0 +1,522 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// News rule actions
+const (
+	NewsActionAlert    = "alert"    // notify and publish a news_rule_matched event
+	NewsActionStrategy = "strategy" // also ask Strategy for a signal
+)
+
+// News rule sentiment directions
+const (
+	NewsBullish = "bullish"
+	NewsBearish = "bearish"
+	NewsAny     = "any"
+)
+
+// breakingNewsAge is how old a headline may be when it arrives and still
+// count as breaking; older items are backfill from a feed's first fetch
+const breakingNewsAge = 15 * time.Minute
+
+// NewsRule matches breaking headlines that mention a watched symbol, carry
+// enough sentiment in its direction and contain a keyword
+type NewsRule struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name,omitempty"`
+	Symbols         []string   `json:"symbols"`
+	Keywords        []string   `json:"keywords,omitempty"` // any one must appear; none matches every headline
+	Direction       string     `json:"direction"`
+	MinSentiment    float64    `json:"min_sentiment"` // 0-1, reached in Direction
+	Action          string     `json:"action"`
+	Strategy        string     `json:"strategy,omitempty"`
+	CooldownMinutes int        `json:"cooldown_minutes"`
+	DryRun          bool       `json:"dry_run"` // record matches without notifying or running the strategy
+	TriggerCount    int        `json:"trigger_count"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// NewsRuleRequest is the payload for creating or previewing a news rule
+type NewsRuleRequest struct {
+	Name            string   `json:"name"`
+	Symbols         []string `json:"symbols" binding:"required,min=1,max=50,dive,symbol"`
+	Keywords        []string `json:"keywords" binding:"omitempty,max=20,dive,min=1,max=64"`
+	Direction       string   `json:"direction" binding:"omitempty,oneof=bullish bearish any"`
+	MinSentiment    float64  `json:"min_sentiment" binding:"gte=0,lte=1"`
+	Action          string   `json:"action" binding:"omitempty,oneof=alert strategy"`
+	Strategy        string   `json:"strategy"`
+	CooldownMinutes int      `json:"cooldown_minutes" binding:"gte=0"`
+	DryRun          bool     `json:"dry_run"`
+}
+
+// NewsRuleMatch is one headline a rule matched
+type NewsRuleMatch struct {
+	RuleID         string       `json:"rule_id"`
+	Symbol         string       `json:"symbol"`
+	Title          string       `json:"title"`
+	Link           string       `json:"link"`
+	Source         string       `json:"source,omitempty"`
+	Sentiment      float64      `json:"sentiment"`
+	Keyword        string       `json:"keyword,omitempty"`
+	PublishedAt    time.Time    `json:"published_at"`
+	MatchedAt      time.Time    `json:"matched_at"`
+	LatencySeconds float64      `json:"latency_seconds"` // publication to match
+	Action         string       `json:"action"`
+	DryRun         bool         `json:"dry_run"`
+	Suppressed     string       `json:"suppressed,omitempty"` // why no action was taken, e.g. the cooldown
+	Signal         *AlertSignal `json:"signal,omitempty"`
+}
+
+// NewsPipeline evaluates news rules against every headline as the news
+// store first sees it
+type NewsPipeline struct {
+	newsService    *NewsService
+	alertEngine    *AlertEngine // strategy hooks
+	storageService *database.LocalStorage
+	notifier       *Notifier
+	eventBus       *EventBus
+	rules          map[string]*NewsRule
+	seen           map[string]time.Time // headlines already evaluated, by link
+	matches        []NewsRuleMatch
+	mu             sync.Mutex
+	logger         *logrus.Logger
+}
+
+// NewNewsPipeline creates a news pipeline and loads persisted rules
+func NewNewsPipeline(newsService *NewsService, alertEngine *AlertEngine, storageService *database.LocalStorage, notifier *Notifier) *NewsPipeline {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	np := &NewsPipeline{
+		newsService:    newsService,
+		alertEngine:    alertEngine,
+		storageService: storageService,
+		notifier:       notifier,
+		rules:          make(map[string]*NewsRule),
+		seen:           make(map[string]time.Time),
+		matches:        make([]NewsRuleMatch, 0),
+		logger:         logger,
+	}
+
+	if err := np.loadRulesFromDB(); err != nil {
+		logger.WithError(err).Error("Failed to load news rules from database")
+	}
+
+	return np
+}
+
+// SetEventBus publishes rule matches as news_rule_matched events
+func (np *NewsPipeline) SetEventBus(bus *EventBus) {
+	np.mu.Lock()
+	defer np.mu.Unlock()
+	np.eventBus = bus
+}
+
+// CreateRule validates and registers a news rule
+func (np *NewsPipeline) CreateRule(req *NewsRuleRequest) (*NewsRule, error) {
+	rule, err := np.buildRule(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := np.saveRuleToDB(rule); err != nil {
+		return nil, fmt.Errorf("failed to save news rule: %w", err)
+	}
+
+	np.mu.Lock()
+	np.rules[rule.ID] = rule
+	np.mu.Unlock()
+
+	np.logger.WithFields(logrus.Fields{
+		"rule_id": rule.ID,
+		"symbols": rule.Symbols,
+		"action":  rule.Action,
+		"dry_run": rule.DryRun,
+	}).Info("News rule created")
+
+	copied := *rule
+	return &copied, nil
+}
+
+// buildRule validates req and fills in defaults
+func (np *NewsPipeline) buildRule(req *NewsRuleRequest) (*NewsRule, error) {
+	rule := &NewsRule{
+		ID:              fmt.Sprintf("newsrule_%d", time.Now().UnixNano()),
+		Name:            req.Name,
+		Direction:       req.Direction,
+		MinSentiment:    req.MinSentiment,
+		Action:          req.Action,
+		Strategy:        req.Strategy,
+		CooldownMinutes: req.CooldownMinutes,
+		DryRun:          req.DryRun,
+		CreatedAt:       time.Now(),
+	}
+	for _, symbol := range req.Symbols {
+		rule.Symbols = append(rule.Symbols, strings.ToUpper(strings.TrimSpace(symbol)))
+	}
+	for _, keyword := range req.Keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			rule.Keywords = append(rule.Keywords, keyword)
+		}
+	}
+	if rule.Direction == "" {
+		rule.Direction = NewsAny
+	}
+	if rule.Action == "" {
+		rule.Action = NewsActionAlert
+	}
+	if rule.CooldownMinutes == 0 {
+		rule.CooldownMinutes = 15
+	}
+
+	if rule.Action == NewsActionStrategy {
+		if rule.Strategy == "" {
+			return nil, fmt.Errorf("strategy is required for the strategy action")
+		}
+		if np.alertEngine == nil || np.alertEngine.resolveStrategy == nil {
+			return nil, fmt.Errorf("strategy hooks are not enabled")
+		}
+		if _, err := np.alertEngine.resolveStrategy(rule.Strategy, nil); err != nil {
+			return nil, err
+		}
+	} else if rule.Strategy != "" {
+		return nil, fmt.Errorf("strategy is only used by the strategy action")
+	}
+
+	return rule, nil
+}
+
+// ListRules returns every rule, oldest first
+func (np *NewsPipeline) ListRules() []*NewsRule {
+	np.mu.Lock()
+	defer np.mu.Unlock()
+
+	rules := make([]*NewsRule, 0, len(np.rules))
+	for _, rule := range np.rules {
+		copied := *rule
+		rules = append(rules, &copied)
+	}
+	sortNewsRules(rules)
+	return rules
+}
+
+// DeleteRule removes a rule
+func (np *NewsPipeline) DeleteRule(id string) error {
+	np.mu.Lock()
+	_, ok := np.rules[id]
+	delete(np.rules, id)
+	np.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("news rule %s not found", id)
+	}
+	return np.storageService.DeleteNewsRule(id)
+}
+
+// RecentMatches returns up to limit matches, newest first
+func (np *NewsPipeline) RecentMatches(limit int) []NewsRuleMatch {
+	np.mu.Lock()
+	defer np.mu.Unlock()
+
+	matches := make([]NewsRuleMatch, 0, limit)
+	for i := len(np.matches) - 1; i >= 0 && len(matches) < limit; i-- {
+		matches = append(matches, np.matches[i])
+	}
+	return matches
+}
+
+// Preview runs req as a dry-run rule over the stored headlines of the last
+// lookback, oldest first, applying its cooldown between matches. Nothing is
+// saved, notified or sent to a strategy.
+func (np *NewsPipeline) Preview(req *NewsRuleRequest, lookback time.Duration) ([]NewsRuleMatch, error) {
+	rule, err := np.buildRule(req)
+	if err != nil {
+		return nil, err
+	}
+	rule.DryRun = true
+
+	items := np.newsService.StoredItems(time.Now().Add(-lookback))
+	matches := make([]NewsRuleMatch, 0)
+	for i := len(items) - 1; i >= 0; i-- {
+		match, ok := rule.match(&items[i])
+		if !ok {
+			continue
+		}
+		match.MatchedAt = items[i].PublishedAt
+		if rule.LastTriggeredAt != nil && match.MatchedAt.Sub(*rule.LastTriggeredAt) < time.Duration(rule.CooldownMinutes)*time.Minute {
+			match.Suppressed = "cooldown"
+		} else {
+			at := match.MatchedAt
+			rule.LastTriggeredAt = &at
+		}
+		matches = append(matches, *match)
+	}
+	return matches, nil
+}
+
+// Run evaluates every headline the news store receives until ctx is done
+func (np *NewsPipeline) Run(ctx context.Context) {
+	id, items := np.newsService.Subscribe(500)
+	defer np.newsService.Unsubscribe(id)
+
+	np.logger.Info("News pipeline started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			np.logger.Info("News pipeline stopped")
+			return
+		case item, ok := <-items:
+			if !ok {
+				return
+			}
+			np.evaluate(ctx, item)
+		}
+	}
+}
+
+// evaluate runs every rule against a breaking headline
+func (np *NewsPipeline) evaluate(ctx context.Context, item NewsItem) {
+	now := time.Now()
+	if item.PublishedAt.IsZero() || now.Sub(item.PublishedAt) > breakingNewsAge {
+		return
+	}
+
+	// The same story arrives from several feeds
+	np.mu.Lock()
+	if _, ok := np.seen[item.Link]; ok {
+		np.mu.Unlock()
+		return
+	}
+	np.seen[item.Link] = now
+	for link, at := range np.seen {
+		if now.Sub(at) > 2*breakingNewsAge {
+			delete(np.seen, link)
+		}
+	}
+	rules := make([]*NewsRule, 0, len(np.rules))
+	for _, rule := range np.rules {
+		copied := *rule
+		rules = append(rules, &copied)
+	}
+	np.mu.Unlock()
+	sortNewsRules(rules)
+
+	for _, rule := range rules {
+		match, ok := rule.match(&item)
+		if !ok {
+			continue
+		}
+		match.MatchedAt = now
+		match.LatencySeconds = now.Sub(item.PublishedAt).Seconds()
+		np.fire(ctx, rule.ID, match)
+	}
+}
+
+// fire applies the cooldown, acts on a match and records it
+func (np *NewsPipeline) fire(ctx context.Context, ruleID string, match *NewsRuleMatch) {
+	np.mu.Lock()
+	rule, ok := np.rules[ruleID]
+	if !ok {
+		np.mu.Unlock()
+		return
+	}
+	if rule.LastTriggeredAt != nil && match.MatchedAt.Sub(*rule.LastTriggeredAt) < time.Duration(rule.CooldownMinutes)*time.Minute {
+		match.Suppressed = "cooldown"
+	} else {
+		at := match.MatchedAt
+		rule.TriggerCount++
+		rule.LastTriggeredAt = &at
+	}
+	snapshot := *rule
+	np.mu.Unlock()
+
+	if match.Suppressed == "" {
+		if err := np.saveRuleToDB(&snapshot); err != nil {
+			np.logger.WithError(err).WithField("rule_id", snapshot.ID).Error("Failed to persist news rule")
+		}
+		if !snapshot.DryRun {
+			np.act(ctx, &snapshot, match)
+		}
+	}
+
+	np.mu.Lock()
+	np.matches = append(np.matches, *match)
+	if len(np.matches) > 200 {
+		np.matches = np.matches[len(np.matches)-200:]
+	}
+	eventBus := np.eventBus
+	np.mu.Unlock()
+
+	eventBus.Publish(EventNewsRuleMatched, *match)
+
+	np.logger.WithFields(logrus.Fields{
+		"rule_id":    snapshot.ID,
+		"symbol":     match.Symbol,
+		"sentiment":  match.Sentiment,
+		"latency":    match.LatencySeconds,
+		"dry_run":    match.DryRun,
+		"suppressed": match.Suppressed,
+	}).Info("News rule matched")
+}
+
+// act notifies about a match and runs the rule's strategy
+func (np *NewsPipeline) act(ctx context.Context, rule *NewsRule, match *NewsRuleMatch) {
+	if rule.Action == NewsActionStrategy {
+		reason := fmt.Sprintf("news rule %s: %s", rule.ID, match.Title)
+		match.Signal = np.alertEngine.RunStrategy(ctx, rule.Strategy, match.Symbol, nil, reason)
+	}
+
+	fields := map[string]interface{}{
+		"rule_id":   rule.ID,
+		"symbol":    match.Symbol,
+		"sentiment": match.Sentiment,
+		"link":      match.Link,
+	}
+	if match.Signal != nil {
+		fields["strategy"] = match.Signal.Strategy
+		fields["signal"] = match.Signal.Action
+	}
+	np.notifier.Notify(ctx, NotifyWarning, "News rule matched", fmt.Sprintf("%s: %s", match.Symbol, match.Title), fields)
+}
+
+// match checks one headline against the rule
+func (r *NewsRule) match(item *NewsItem) (*NewsRuleMatch, bool) {
+	text := item.Title + " " + item.Description
+
+	symbol := ""
+	for _, s := range r.Symbols {
+		if mentionsSymbol(text, s) {
+			symbol = s
+			break
+		}
+	}
+	if symbol == "" {
+		return nil, false
+	}
+
+	keyword := ""
+	if len(r.Keywords) > 0 {
+		lower := strings.ToLower(text)
+		for _, k := range r.Keywords {
+			if strings.Contains(lower, strings.ToLower(k)) {
+				keyword = k
+				break
+			}
+		}
+		if keyword == "" {
+			return nil, false
+		}
+	}
+
+	sentiment := scoreSentiment(text)
+	switch r.Direction {
+	case NewsBullish:
+		if sentiment <= 0 || sentiment < r.MinSentiment {
+			return nil, false
+		}
+	case NewsBearish:
+		if sentiment >= 0 || -sentiment < r.MinSentiment {
+			return nil, false
+		}
+	default:
+		if math.Abs(sentiment) < r.MinSentiment {
+			return nil, false
+		}
+	}
+
+	return &NewsRuleMatch{
+		RuleID:      r.ID,
+		Symbol:      symbol,
+		Title:       item.Title,
+		Link:        item.Link,
+		Source:      item.Source,
+		Sentiment:   sentiment,
+		Keyword:     keyword,
+		PublishedAt: item.PublishedAt,
+		Action:      r.Action,
+		DryRun:      r.DryRun,
+	}, true
+}
+
+// mentionsSymbol reports whether text names symbol as an upper-case word or
+// a cashtag
+func mentionsSymbol(text, symbol string) bool {
+	pattern := regexp.MustCompile(`(^|[^A-Za-z0-9])\$?` + regexp.QuoteMeta(symbol) + `($|[^A-Za-z0-9])`)
+	return pattern.MatchString(text)
+}
+
+func sortNewsRules(rules []*NewsRule) {
+	for i := 1; i < len(rules); i++ {
+		for j := i; j > 0 && rules[j].CreatedAt.Before(rules[j-1].CreatedAt); j-- {
+			rules[j], rules[j-1] = rules[j-1], rules[j]
+		}
+	}
+}
+
+// loadRulesFromDB restores rules on startup
+func (np *NewsPipeline) loadRulesFromDB() error {
+	dbRules, err := np.storageService.GetNewsRules()
+	if err != nil {
+		return err
+	}
+
+	for _, dbRule := range dbRules {
+		var keywords []string
+		if dbRule.Keywords != "" {
+			json.Unmarshal([]byte(dbRule.Keywords), &keywords)
+		}
+		np.rules[dbRule.RuleID] = &NewsRule{
+			ID:              dbRule.RuleID,
+			Name:            dbRule.Name,
+			Symbols:         strings.Split(dbRule.Symbols, ","),
+			Keywords:        keywords,
+			Direction:       dbRule.Direction,
+			MinSentiment:    dbRule.MinSentiment,
+			Action:          dbRule.Action,
+			Strategy:        dbRule.Strategy,
+			CooldownMinutes: dbRule.CooldownMinutes,
+			DryRun:          dbRule.DryRun,
+			TriggerCount:    dbRule.TriggerCount,
+			LastTriggeredAt: dbRule.LastTriggeredAt,
+			CreatedAt:       dbRule.CreatedAt,
+		}
+	}
+
+	np.logger.WithField("count", len(dbRules)).Info("Loaded news rules from database")
+	return nil
+}
+
+// saveRuleToDB persists a rule
+func (np *NewsPipeline) saveRuleToDB(rule *NewsRule) error {
+	keywords, _ := json.Marshal(rule.Keywords)
+	return np.storageService.SaveNewsRule(&models.DBNewsRule{
+		RuleID:          rule.ID,
+		Name:            rule.Name,
+		Symbols:         strings.Join(rule.Symbols, ","),
+		Keywords:        string(keywords),
+		Direction:       rule.Direction,
+		MinSentiment:    rule.MinSentiment,
+		Action:          rule.Action,
+		Strategy:        rule.Strategy,
+		CooldownMinutes: rule.CooldownMinutes,
+		DryRun:          rule.DryRun,
+		TriggerCount:    rule.TriggerCount,
+		LastTriggeredAt: rule.LastTriggeredAt,
+	})
+}
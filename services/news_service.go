@@ -3,12 +3,36 @@ package services
 import (
 	"encoding/xml"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Built-in feeds, polled in the background
+const (
+	googleNewsURL                  = "https://news.google.com/rss?hl=en-US&gl=US&ceid=US:en"
+	marketWatchTopStoriesURL       = "https://feeds.content.dowjones.io/public/rss/mw_topstories"
+	marketWatchRealtimeHeadlineURL = "https://feeds.content.dowjones.io/public/rss/mw_realtimeheadlines"
+	marketWatchBulletinsURL        = "https://feeds.content.dowjones.io/public/rss/mw_bulletins"
+	marketWatchMarketPulseURL      = "https://feeds.content.dowjones.io/public/rss/mw_marketpulse"
 )
 
+// googleNewsTopicURLs are the Google News feeds per topic
+var googleNewsTopicURLs = map[string]string{
+	"WORLD":         "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRGx1YlY4U0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
+	"NATION":        "https://news.google.com/rss/topics/CAAqIQgKIhtDQkFTRGdvSUwyMHZNRGxqTjNjd0VnSmxiaWdBUAE?hl=en-US&gl=US&ceid=US:en",
+	"BUSINESS":      "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRGx6TVdZU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
+	"TECHNOLOGY":    "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRGRqTVhZU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
+	"ENTERTAINMENT": "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNREpxYW5RU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
+	"SPORTS":        "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRFp1ZEdvU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
+	"SCIENCE":       "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRFp0Y1RjU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
+	"HEALTH":        "https://news.google.com/rss/topics/CAAqIQgKIhtDQkFTRGdvSUwyMHZNR3QwTlRFd0VnSmxiaWdBUAE?hl=en-US&gl=US&ceid=US:en",
+}
+
 // NewsItem represents a single news article from the RSS feed
 type NewsItem struct {
 	Title       string    `xml:"title" json:"title"`
@@ -18,6 +42,7 @@ type NewsItem struct {
 	Source      string    `xml:"source" json:"source,omitempty"`
 	GUID        string    `xml:"guid" json:"guid,omitempty"`
 	PublishedAt time.Time `json:"published_at,omitempty"`
+	Seq         uint64    `json:"seq"` // order the item was first stored in
 }
 
 // NewsItemCompact represents a compact news article with only essential fields
@@ -50,44 +75,57 @@ type RSSFeed struct {
 	Channel NewsChannel `xml:"channel"`
 }
 
-// NewsService handles fetching news from various sources
+// NewsService serves news from a local store of RSS feeds. Feeds are
+// refreshed with conditional requests, in the background while Run is
+// running and otherwise when a request finds them more than a minute old.
 type NewsService struct {
 	httpClient *http.Client
+	feeds      map[string]*newsFeed // by URL
+	maxAge     time.Duration
+	seq        atomic.Uint64
+	subs       map[int]chan NewsItem
+	nextSubID  int
+	mu         sync.Mutex
+	logger     *logrus.Logger
 }
 
 // NewNewsService creates a new news service
 func NewNewsService() *NewsService {
-	return &NewsService{
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	ns := &NewsService{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		feeds:  make(map[string]*newsFeed),
+		maxAge: newsDefaultAge,
+		subs:   make(map[int]chan NewsItem),
+		logger: logger,
+	}
+
+	pinned := []string{googleNewsURL, marketWatchTopStoriesURL, marketWatchRealtimeHeadlineURL, marketWatchBulletinsURL, marketWatchMarketPulseURL}
+	for _, feedURL := range googleNewsTopicURLs {
+		pinned = append(pinned, feedURL)
 	}
+	for _, feedURL := range pinned {
+		ns.feeds[feedURL] = &newsFeed{url: feedURL, pinned: true, seen: make(map[string]bool)}
+	}
+	return ns
 }
 
 // GetGoogleNews fetches the latest news from Google News RSS feed
 func (ns *NewsService) GetGoogleNews() ([]NewsItem, error) {
-	url := "https://news.google.com/rss?hl=en-US&gl=US&ceid=US:en"
-	return ns.fetchRSSFeed(url)
+	return ns.fetchRSSFeed(googleNewsURL)
 }
 
 // GetGoogleNewsByTopic fetches news for a specific topic
 // Topics: WORLD, NATION, BUSINESS, TECHNOLOGY, ENTERTAINMENT, SPORTS, SCIENCE, HEALTH
 func (ns *NewsService) GetGoogleNewsByTopic(topic string) ([]NewsItem, error) {
-	url := fmt.Sprintf("https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRGx6TVdZU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en")
-
-	// Topic-specific URLs
-	topicURLs := map[string]string{
-		"WORLD":         "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRGx1YlY4U0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
-		"NATION":        "https://news.google.com/rss/topics/CAAqIQgKIhtDQkFTRGdvSUwyMHZNRGxqTjNjd0VnSmxiaWdBUAE?hl=en-US&gl=US&ceid=US:en",
-		"BUSINESS":      "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRGx6TVdZU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
-		"TECHNOLOGY":    "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRGRqTVhZU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
-		"ENTERTAINMENT": "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNREpxYW5RU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
-		"SPORTS":        "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRFp1ZEdvU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
-		"SCIENCE":       "https://news.google.com/rss/topics/CAAqJggKIiBDQkFTRWdvSUwyMHZNRFp0Y1RjU0FtVnVHZ0pWVXlnQVAB?hl=en-US&gl=US&ceid=US:en",
-		"HEALTH":        "https://news.google.com/rss/topics/CAAqIQgKIhtDQkFTRGdvSUwyMHZNR3QwTlRFd0VnSmxiaWdBUAE?hl=en-US&gl=US&ceid=US:en",
-	}
-
-	if topicURL, ok := topicURLs[topic]; ok {
+	url := googleNewsTopicURLs["BUSINESS"]
+	if topicURL, ok := googleNewsTopicURLs[topic]; ok {
 		url = topicURL
 	}
 
@@ -104,26 +142,22 @@ func (ns *NewsService) GetGoogleNewsSearch(query string) ([]NewsItem, error) {
 
 // GetMarketWatchTopStories fetches top stories from MarketWatch
 func (ns *NewsService) GetMarketWatchTopStories() ([]NewsItem, error) {
-	url := "https://feeds.content.dowjones.io/public/rss/mw_topstories"
-	return ns.fetchRSSFeed(url)
+	return ns.fetchRSSFeed(marketWatchTopStoriesURL)
 }
 
 // GetMarketWatchRealtimeHeadlines fetches real-time headlines from MarketWatch
 func (ns *NewsService) GetMarketWatchRealtimeHeadlines() ([]NewsItem, error) {
-	url := "https://feeds.content.dowjones.io/public/rss/mw_realtimeheadlines"
-	return ns.fetchRSSFeed(url)
+	return ns.fetchRSSFeed(marketWatchRealtimeHeadlineURL)
 }
 
 // GetMarketWatchBulletins fetches breaking news bulletins from MarketWatch
 func (ns *NewsService) GetMarketWatchBulletins() ([]NewsItem, error) {
-	url := "https://feeds.content.dowjones.io/public/rss/mw_bulletins"
-	return ns.fetchRSSFeed(url)
+	return ns.fetchRSSFeed(marketWatchBulletinsURL)
 }
 
 // GetMarketWatchMarketPulse fetches market pulse updates from MarketWatch
 func (ns *NewsService) GetMarketWatchMarketPulse() ([]NewsItem, error) {
-	url := "https://feeds.content.dowjones.io/public/rss/mw_marketpulse"
-	return ns.fetchRSSFeed(url)
+	return ns.fetchRSSFeed(marketWatchMarketPulseURL)
 }
 
 // GetAllMarketWatchNews aggregates all MarketWatch feeds
@@ -149,44 +183,9 @@ func (ns *NewsService) GetAllMarketWatchNews() ([]NewsItem, error) {
 	return allNews, nil
 }
 
-// fetchRSSFeed is a helper method to fetch and parse any RSS feed
+// fetchRSSFeed returns a feed's items from the store
 func (ns *NewsService) fetchRSSFeed(url string) ([]NewsItem, error) {
-	// Make HTTP request
-	resp, err := ns.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch RSS feed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	// Parse XML
-	var feed RSSFeed
-	if err := xml.Unmarshal(body, &feed); err != nil {
-		return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
-	}
-
-	// Parse pub dates
-	for i := range feed.Channel.Items {
-		if feed.Channel.Items[i].PubDate != "" {
-			// Try to parse RFC1123 format (common in RSS)
-			if t, err := time.Parse(time.RFC1123, feed.Channel.Items[i].PubDate); err == nil {
-				feed.Channel.Items[i].PublishedAt = t
-			} else if t, err := time.Parse(time.RFC1123Z, feed.Channel.Items[i].PubDate); err == nil {
-				feed.Channel.Items[i].PublishedAt = t
-			}
-		}
-	}
-
-	return feed.Channel.Items, nil
+	return ns.feed(url)
 }
 
 // GetLatestNews returns the most recent N news items
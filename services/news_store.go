@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// News store limits
+const (
+	newsItemsPerFeed = 200         // newest items kept per feed
+	newsFeedIdleTTL  = time.Hour   // search feeds nobody asked for in this long stop being polled
+	newsDefaultAge   = time.Minute // stored feeds are served for this long without background polling
+	newsPollTimeout  = 2 * time.Minute
+)
+
+// newsFeed is the stored state of one RSS feed
+type newsFeed struct {
+	url          string
+	pinned       bool       // a built-in feed, always polled
+	items        []NewsItem // newest first
+	seen         map[string]bool
+	etag         string
+	lastModified string
+	fetchedAt    time.Time
+	requestedAt  time.Time
+	mu           sync.Mutex // held while the feed is fetched
+}
+
+// newsKey identifies an item across fetches
+func newsKey(item *NewsItem) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	return item.Link
+}
+
+// feed returns the stored items of url, fetching the feed first when it
+// has never been fetched or is older than maxAge. A failed refresh serves
+// the stored items when there are any.
+func (ns *NewsService) feed(url string) ([]NewsItem, error) {
+	ns.mu.Lock()
+	f, ok := ns.feeds[url]
+	if !ok {
+		f = &newsFeed{url: url, seen: make(map[string]bool)}
+		ns.feeds[url] = f
+	}
+	f.requestedAt = time.Now()
+	maxAge := ns.maxAge
+	ns.mu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Since(f.fetchedAt) > maxAge {
+		if err := ns.refresh(context.Background(), f); err != nil {
+			if f.fetchedAt.IsZero() {
+				return nil, err
+			}
+			ns.logger.WithError(err).WithField("url", f.url).Warn("Failed to refresh news feed, serving stored items")
+		}
+	}
+
+	items := make([]NewsItem, len(f.items))
+	copy(items, f.items)
+	return items, nil
+}
+
+// refresh fetches f with a conditional request and merges new items. The
+// caller holds f.mu.
+func (ns *NewsService) refresh(ctx context.Context, f *newsFeed) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build RSS request: %w", err)
+	}
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+	if f.lastModified != "" {
+		req.Header.Set("If-Modified-Since", f.lastModified)
+	}
+
+	resp, err := ns.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch RSS feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		f.fetchedAt = time.Now()
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	items, err := parseRSSFeed(body)
+	if err != nil {
+		return err
+	}
+
+	f.etag = resp.Header.Get("ETag")
+	f.lastModified = resp.Header.Get("Last-Modified")
+	f.fetchedAt = time.Now()
+	ns.merge(f, items)
+	return nil
+}
+
+// merge adds the items f has not seen, numbering them in arrival order, and
+// keeps the newest newsItemsPerFeed
+func (ns *NewsService) merge(f *newsFeed, items []NewsItem) {
+	added := make([]NewsItem, 0)
+	// Feeds list newest first; number the oldest new item first
+	for i := len(items) - 1; i >= 0; i-- {
+		key := newsKey(&items[i])
+		if key == "" || f.seen[key] {
+			continue
+		}
+		f.seen[key] = true
+		items[i].Seq = ns.seq.Add(1)
+		added = append(added, items[i])
+	}
+	if len(added) == 0 {
+		return
+	}
+	ns.publish(added)
+
+	f.items = append(added, f.items...)
+	sort.SliceStable(f.items, func(i, j int) bool {
+		return f.items[i].PublishedAt.After(f.items[j].PublishedAt)
+	})
+	if len(f.items) > newsItemsPerFeed {
+		for _, item := range f.items[newsItemsPerFeed:] {
+			delete(f.seen, newsKey(&item))
+		}
+		f.items = f.items[:newsItemsPerFeed]
+	}
+}
+
+// Subscribe returns a channel receiving every item as it is first stored,
+// oldest first. Slow subscribers miss items rather than blocking refreshes.
+func (ns *NewsService) Subscribe(buffer int) (int, <-chan NewsItem) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.nextSubID++
+	ch := make(chan NewsItem, buffer)
+	ns.subs[ns.nextSubID] = ch
+	return ns.nextSubID, ch
+}
+
+// Unsubscribe closes a subscription
+func (ns *NewsService) Unsubscribe(id int) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if ch, ok := ns.subs[id]; ok {
+		close(ch)
+		delete(ns.subs, id)
+	}
+}
+
+func (ns *NewsService) publish(items []NewsItem) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	for _, ch := range ns.subs {
+		for _, item := range items {
+			select {
+			case ch <- item:
+			default:
+				ns.logger.WithField("title", item.Title).Warn("News subscriber is behind, dropping item")
+			}
+		}
+	}
+}
+
+// StoredItems returns the items of every stored feed published since the
+// given time, newest first, without duplicates across feeds
+func (ns *NewsService) StoredItems(since time.Time) []NewsItem {
+	ns.mu.Lock()
+	feeds := make([]*newsFeed, 0, len(ns.feeds))
+	for _, f := range ns.feeds {
+		feeds = append(feeds, f)
+	}
+	ns.mu.Unlock()
+
+	seen := make(map[string]bool)
+	items := make([]NewsItem, 0)
+	for _, f := range feeds {
+		f.mu.Lock()
+		for _, item := range f.items {
+			if item.PublishedAt.Before(since) || seen[item.Link] {
+				continue
+			}
+			seen[item.Link] = true
+			items = append(items, item)
+		}
+		f.mu.Unlock()
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].PublishedAt.After(items[j].PublishedAt) })
+	return items
+}
+
+// SetPollInterval tells the service its feeds are polled every interval.
+// While they are, requests are served from the store.
+func (ns *NewsService) SetPollInterval(interval time.Duration) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.maxAge = 2 * interval
+}
+
+// Poll refreshes every stored feed once. Search feeds that have not been
+// requested for an hour are dropped.
+func (ns *NewsService) Poll(ctx context.Context) {
+	ns.mu.Lock()
+	feeds := make([]*newsFeed, 0, len(ns.feeds))
+	for url, f := range ns.feeds {
+		if !f.pinned && time.Since(f.requestedAt) > newsFeedIdleTTL {
+			delete(ns.feeds, url)
+			continue
+		}
+		feeds = append(feeds, f)
+	}
+	ns.mu.Unlock()
+
+	for _, f := range feeds {
+		if ctx.Err() != nil {
+			return
+		}
+		fetchCtx, cancel := context.WithTimeout(ctx, newsPollTimeout)
+		f.mu.Lock()
+		err := ns.refresh(fetchCtx, f)
+		f.mu.Unlock()
+		cancel()
+		if err != nil {
+			ns.logger.WithError(err).WithField("url", f.url).Warn("Failed to poll news feed")
+		}
+	}
+}
+
+// parseRSSFeed decodes an RSS document and parses the items' dates
+func parseRSSFeed(body []byte) ([]NewsItem, error) {
+	var feed RSSFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+	}
+
+	for i := range feed.Channel.Items {
+		if feed.Channel.Items[i].PubDate != "" {
+			// Try to parse RFC1123 format (common in RSS)
+			if t, err := time.Parse(time.RFC1123, feed.Channel.Items[i].PubDate); err == nil {
+				feed.Channel.Items[i].PublishedAt = t
+			} else if t, err := time.Parse(time.RFC1123Z, feed.Channel.Items[i].PubDate); err == nil {
+				feed.Channel.Items[i].PublishedAt = t
+			}
+		}
+	}
+
+	return feed.Channel.Items, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Notification levels
+const (
+	NotifyInfo     = "info"
+	NotifyWarning  = "warning"
+	NotifyCritical = "critical"
+)
+
+// Notification is a single message fanned out to every channel
+type Notification struct {
+	Level     string                 `json:"level"`
+	Title     string                 `json:"title"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// NotificationChannel delivers notifications to one destination
+type NotificationChannel interface {
+	Name() string
+	Send(ctx context.Context, n *Notification) error
+}
+
+// Notifier fans notifications out to all configured channels
+type Notifier struct {
+	channels []NotificationChannel
+	logger   *logrus.Logger
+}
+
+// NewNotifier creates a notifier with the given channels
+func NewNotifier(channels ...NotificationChannel) *Notifier {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &Notifier{
+		channels: channels,
+		logger:   logger,
+	}
+}
+
+// AddChannel registers another delivery channel
+func (n *Notifier) AddChannel(channel NotificationChannel) {
+	n.channels = append(n.channels, channel)
+}
+
+// Notify sends a notification to every channel. Delivery failures are logged
+// and never returned so callers are not blocked by a broken channel.
+func (n *Notifier) Notify(ctx context.Context, level, title, message string, fields map[string]interface{}) {
+	if n == nil {
+		return
+	}
+
+	notification := &Notification{
+		Level:     level,
+		Title:     title,
+		Message:   message,
+		Fields:    fields,
+		Timestamp: time.Now(),
+	}
+
+	for _, channel := range n.channels {
+		if err := channel.Send(ctx, notification); err != nil {
+			n.logger.WithError(err).WithField("channel", channel.Name()).Warn("Failed to deliver notification")
+		}
+	}
+}
+
+// LogChannel writes notifications to a logger
+type LogChannel struct {
+	logger *logrus.Logger
+}
+
+// NewLogChannel creates a channel that logs notifications
+func NewLogChannel() *LogChannel {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &LogChannel{logger: logger}
+}
+
+func (lc *LogChannel) Name() string { return "log" }
+
+func (lc *LogChannel) Send(ctx context.Context, n *Notification) error {
+	entry := lc.logger.WithFields(logrus.Fields(n.Fields)).WithField("title", n.Title)
+	switch n.Level {
+	case NotifyCritical:
+		entry.Error(n.Message)
+	case NotifyWarning:
+		entry.Warn(n.Message)
+	default:
+		entry.Info(n.Message)
+	}
+	return nil
+}
+
+// WebhookChannel POSTs notifications as JSON to a URL
+type WebhookChannel struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookChannel creates a channel that posts to url
+func NewWebhookChannel(url string) *WebhookChannel {
+	return &WebhookChannel{
+		url: url,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (wc *WebhookChannel) Name() string { return "webhook" }
+
+func (wc *WebhookChannel) Send(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", wc.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// notifyLevelRank orders notification levels by severity
+var notifyLevelRank = map[string]int{
+	NotifyInfo:     0,
+	NotifyWarning:  1,
+	NotifyCritical: 2,
+}
+
+// NotificationRules limit what one channel delivers and how often
+type NotificationRules struct {
+	MinLevel     string        // drop notifications below this level, empty delivers all
+	QuietStart   time.Duration // time of day quiet hours start, in market time
+	QuietEnd     time.Duration // time of day quiet hours end; equal to QuietStart disables them
+	MaxPerMinute int           // messages per rolling minute, 0 disables the cap
+	DigestWindow time.Duration // batch notifications with the same title, 0 disables
+}
+
+// ParseQuietHours parses "22:00-07:00" into times of day. An empty string
+// disables quiet hours.
+func ParseQuietHours(s string) (time.Duration, time.Duration, error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("quiet hours %q must look like 22:00-07:00", s)
+	}
+	start, err := parseTimeOfDay(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseTimeOfDay(strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, use HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Validate checks the rules' level and durations
+func (r NotificationRules) Validate() error {
+	if _, ok := notifyLevelRank[r.MinLevel]; r.MinLevel != "" && !ok {
+		return fmt.Errorf("invalid minimum level %q: use info, warning or critical", r.MinLevel)
+	}
+	if r.MaxPerMinute < 0 || r.DigestWindow < 0 {
+		return fmt.Errorf("rate cap and digest window must not be negative")
+	}
+	return nil
+}
+
+// RuledChannel applies NotificationRules in front of another channel.
+// Critical notifications skip quiet hours and digests but still count
+// against the rate cap.
+type RuledChannel struct {
+	channel    NotificationChannel
+	rules      NotificationRules
+	location   *time.Location
+	mu         sync.Mutex
+	sent       []time.Time                // delivery times within the last minute
+	suppressed int                        // dropped by the rate cap since the last delivery
+	digests    map[string][]*Notification // pending batches by title
+	logger     *logrus.Logger
+}
+
+// NewRuledChannel wraps channel with rules. Quiet hours are in market time.
+func NewRuledChannel(channel NotificationChannel, rules NotificationRules) *RuledChannel {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.UTC
+	}
+
+	return &RuledChannel{
+		channel:  channel,
+		rules:    rules,
+		location: loc,
+		digests:  make(map[string][]*Notification),
+		logger:   logger,
+	}
+}
+
+func (rc *RuledChannel) Name() string { return rc.channel.Name() }
+
+// Send delivers n if the rules allow it now, holds it for a digest, or
+// drops it
+func (rc *RuledChannel) Send(ctx context.Context, n *Notification) error {
+	if notifyLevelRank[n.Level] < notifyLevelRank[rc.rules.MinLevel] {
+		return nil
+	}
+	critical := n.Level == NotifyCritical
+	if !critical && rc.inQuietHours(time.Now()) {
+		return nil
+	}
+
+	if !critical && rc.rules.DigestWindow > 0 {
+		rc.mu.Lock()
+		pending := rc.digests[n.Title]
+		rc.digests[n.Title] = append(pending, n)
+		rc.mu.Unlock()
+		if len(pending) == 0 {
+			title := n.Title
+			time.AfterFunc(rc.rules.DigestWindow, func() { rc.flush(title) })
+		}
+		return nil
+	}
+
+	return rc.deliver(ctx, n)
+}
+
+// flush delivers the batch pending under title as one message
+func (rc *RuledChannel) flush(title string) {
+	rc.mu.Lock()
+	batch := rc.digests[title]
+	delete(rc.digests, title)
+	rc.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := rc.deliver(ctx, digestNotification(batch)); err != nil {
+		rc.logger.WithError(err).WithField("channel", rc.Name()).Warn("Failed to deliver notification digest")
+	}
+}
+
+// deliver sends n unless the rate cap is reached. The next delivery says how
+// many were dropped.
+func (rc *RuledChannel) deliver(ctx context.Context, n *Notification) error {
+	rc.mu.Lock()
+	if rc.rules.MaxPerMinute > 0 {
+		now := time.Now()
+		recent := rc.sent[:0]
+		for _, at := range rc.sent {
+			if now.Sub(at) < time.Minute {
+				recent = append(recent, at)
+			}
+		}
+		rc.sent = recent
+		if len(rc.sent) >= rc.rules.MaxPerMinute {
+			rc.suppressed++
+			rc.mu.Unlock()
+			return nil
+		}
+		rc.sent = append(rc.sent, now)
+	}
+	suppressed := rc.suppressed
+	rc.suppressed = 0
+	rc.mu.Unlock()
+
+	if suppressed > 0 {
+		copied := *n
+		copied.Fields = make(map[string]interface{}, len(n.Fields)+1)
+		for k, v := range n.Fields {
+			copied.Fields[k] = v
+		}
+		copied.Fields["suppressed"] = suppressed
+		n = &copied
+	}
+	return rc.channel.Send(ctx, n)
+}
+
+// inQuietHours reports whether t falls in the quiet hours, which may wrap
+// past midnight
+func (rc *RuledChannel) inQuietHours(t time.Time) bool {
+	start, end := rc.rules.QuietStart, rc.rules.QuietEnd
+	if start == end {
+		return false
+	}
+	t = t.In(rc.location)
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// digestNotification merges a batch of notifications with the same title.
+// A batch of one is delivered unchanged.
+func digestNotification(batch []*Notification) *Notification {
+	if len(batch) == 1 {
+		return batch[0]
+	}
+
+	level := NotifyInfo
+	lines := make([]string, 0, len(batch))
+	for _, n := range batch {
+		if notifyLevelRank[n.Level] > notifyLevelRank[level] {
+			level = n.Level
+		}
+		lines = append(lines, n.Timestamp.Format("15:04:05")+" "+n.Message)
+	}
+
+	return &Notification{
+		Level:   level,
+		Title:   fmt.Sprintf("%s (%d)", batch[0].Title, len(batch)),
+		Message: strings.Join(lines, "\n"),
+		Fields: map[string]interface{}{
+			"count": len(batch),
+			"first": batch[0].Timestamp,
+			"last":  batch[len(batch)-1].Timestamp,
+		},
+		Timestamp: batch[len(batch)-1].Timestamp,
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"prophet-trader/interfaces"
+)
+
+// ErrObserverMode is returned for every order while observer mode is on
+var ErrObserverMode = errors.New("trading is disabled in observer mode")
+
+// ReadOnlyTradingService rejects every order and cancellation while passing
+// account, position and market reads through. It backs observer mode, so
+// nothing reachable from the API or a background task can trade.
+type ReadOnlyTradingService struct {
+	interfaces.TradingService
+}
+
+// NewReadOnlyTradingService wraps trading, rejecting all orders
+func NewReadOnlyTradingService(trading interfaces.TradingService) *ReadOnlyTradingService {
+	return &ReadOnlyTradingService{TradingService: trading}
+}
+
+// PlaceOrder implements interfaces.TradingService
+func (s *ReadOnlyTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	return nil, WithErrorCode(ErrCodeForbidden, ErrObserverMode)
+}
+
+// PlaceOptionsOrder implements interfaces.TradingService
+func (s *ReadOnlyTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	return nil, WithErrorCode(ErrCodeForbidden, ErrObserverMode)
+}
+
+// CancelOrder implements interfaces.TradingService
+func (s *ReadOnlyTradingService) CancelOrder(ctx context.Context, orderID string) error {
+	return WithErrorCode(ErrCodeForbidden, ErrObserverMode)
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// occSymbolPattern matches OCC option symbols such as TSLA251219C00400000
+var occSymbolPattern = regexp.MustCompile(`^([A-Z]{1,6})(\d{6})([CP])(\d{8})$`)
+
+// OCCSymbol is a parsed OCC option symbol
+type OCCSymbol struct {
+	Underlying string
+	Expiration time.Time
+	Type       string // "call" or "put"
+	Strike     float64
+}
+
+// ParseOCCSymbol parses an OCC option symbol such as TSLA251219C00400000.
+// Padded symbols (e.g. "TSLA  251219C00400000") are accepted as well.
+func ParseOCCSymbol(symbol string) (*OCCSymbol, error) {
+	m := occSymbolPattern.FindStringSubmatch(strings.ReplaceAll(strings.ToUpper(symbol), " ", ""))
+	if m == nil {
+		return nil, fmt.Errorf("invalid OCC option symbol: %s", symbol)
+	}
+
+	expiration, err := time.Parse("060102", m[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration in option symbol %s: %w", symbol, err)
+	}
+	strike, err := strconv.ParseInt(m[4], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid strike in option symbol %s: %w", symbol, err)
+	}
+
+	optionType := "call"
+	if m[3] == "P" {
+		optionType = "put"
+	}
+
+	return &OCCSymbol{
+		Underlying: m[1],
+		Expiration: expiration,
+		Type:       optionType,
+		Strike:     float64(strike) / 1000,
+	}, nil
+}
+
+// String formats the symbol back into OCC form
+func (o OCCSymbol) String() string {
+	right := "C"
+	if o.Type == "put" {
+		right = "P"
+	}
+	return fmt.Sprintf("%s%s%s%08d", strings.ToUpper(o.Underlying), o.Expiration.Format("060102"), right, int64(math.Round(o.Strike*1000)))
+}
+
+// IsOCCSymbol reports whether symbol is an OCC option symbol
+func IsOCCSymbol(symbol string) bool {
+	return occSymbolPattern.MatchString(symbol)
+}
+
+// fillOptionDetails sets the underlying, expiration, strike and type of an
+// options position from its OCC symbol
+func fillOptionDetails(pos *interfaces.OptionsPosition) {
+	occ, err := ParseOCCSymbol(pos.Symbol)
+	if err != nil {
+		return
+	}
+	pos.Underlying = occ.Underlying
+	pos.Expiration = occ.Expiration
+	pos.Strike = occ.Strike
+	pos.OptionType = occ.Type
+}
@@ -0,0 +1,574 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"prophet-trader/interfaces"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Optimizer search methods and objectives
+const (
+	OptimizeGrid   = "grid"
+	OptimizeRandom = "random"
+
+	ObjectiveReturn       = "return"
+	ObjectiveReturnOverDD = "return_over_drawdown"
+	ObjectiveWinRate      = "win_rate"
+)
+
+const (
+	maxOptimizerBacktests      = 20000 // combinations x folds x 2 per request
+	overfitEfficiencyThreshold = 0.5
+)
+
+// ParameterRange is the set of values tried for one strategy parameter:
+// either explicit values, or min to max in steps. Random search draws
+// from the values, or uniformly between min and max (snapped to step when
+// set; use step 1 for whole-number parameters).
+type ParameterRange struct {
+	Values []float64 `json:"values,omitempty"`
+	Min    *float64  `json:"min,omitempty"`
+	Max    *float64  `json:"max,omitempty"`
+	Step   *float64  `json:"step,omitempty"`
+}
+
+// OptimizeRequest is the payload for a parameter sweep
+type OptimizeRequest struct {
+	Symbol         string                    `json:"symbol" binding:"required,symbol"`
+	Strategy       string                    `json:"strategy" binding:"required"`
+	Start          string                    `json:"start" binding:"required"` // YYYY-MM-DD
+	End            string                    `json:"end,omitempty"`            // YYYY-MM-DD, defaults to now
+	Timeframe      string                    `json:"timeframe,omitempty"`
+	InitialCapital float64                   `json:"initial_capital,omitempty" binding:"omitempty,gt=0"`
+	Lookback       int                       `json:"lookback,omitempty" binding:"omitempty,min=1"`
+	Parameters     map[string]ParameterRange `json:"parameters" binding:"required,min=1"`
+	Method         string                    `json:"method,omitempty" binding:"omitempty,oneof=grid random"`
+	Samples        int                       `json:"samples,omitempty" binding:"omitempty,min=1"` // random search draws, default 50
+	Objective      string                    `json:"objective,omitempty" binding:"omitempty,oneof=return return_over_drawdown win_rate"`
+	Folds          int                       `json:"folds,omitempty" binding:"omitempty,min=1,max=20"`    // walk-forward windows, default 4
+	TrainPct       float64                   `json:"train_pct,omitempty" binding:"omitempty,gt=0,lt=100"` // in-sample share of each window, default 70
+	MinTrades      *int                      `json:"min_trades,omitempty" binding:"omitempty,min=0"`      // in-sample trades needed to be selected, default 1
+	Seed           int64                     `json:"seed,omitempty"`                                      // random search seed, 0 picks one
+	Fills          FillModel                 `json:"fills"`                                               // slippage and commission for every backtest
+}
+
+// BacktestMetrics is the outcome of one backtest, or the mean of several
+type BacktestMetrics struct {
+	ReturnPct      float64 `json:"return_percent"`
+	MaxDrawdownPct float64 `json:"max_drawdown_percent"`
+	WinRate        float64 `json:"win_rate"`
+	Trades         float64 `json:"trades"`
+	Score          float64 `json:"score"`
+}
+
+// ParameterScore is one point of the parameter surface, averaged over the
+// walk-forward windows
+type ParameterScore struct {
+	Params      map[string]float64 `json:"params"`
+	InSample    BacktestMetrics    `json:"in_sample"`
+	OutOfSample BacktestMetrics    `json:"out_of_sample"`
+}
+
+// WalkForwardFold is one walk-forward window: the best in-sample
+// parameters and how they did on the following unseen bars
+type WalkForwardFold struct {
+	Fold        int                `json:"fold"`
+	TrainStart  time.Time          `json:"train_start"`
+	TrainEnd    time.Time          `json:"train_end"`
+	TestStart   time.Time          `json:"test_start"`
+	TestEnd     time.Time          `json:"test_end"`
+	Params      map[string]float64 `json:"params"`
+	InSample    BacktestMetrics    `json:"in_sample"`
+	OutOfSample BacktestMetrics    `json:"out_of_sample"`
+}
+
+// OptimizeResult reports the parameter surface and walk-forward validation
+type OptimizeResult struct {
+	Strategy     string            `json:"strategy"`
+	Symbol       string            `json:"symbol"`
+	Method       string            `json:"method"`
+	Objective    string            `json:"objective"`
+	Combinations int               `json:"combinations"`
+	Bars         int               `json:"bars"`
+	Best         *ParameterScore   `json:"best,omitempty"` // best mean in-sample score with enough trades
+	Folds        []WalkForwardFold `json:"walk_forward"`
+	// Out-of-sample return compounded across the test windows, trading the
+	// parameters re-selected on each training window
+	OutOfSampleReturnPct float64 `json:"out_of_sample_return_percent"`
+	// Mean out-of-sample score over mean in-sample score of the selected
+	// parameters; well below 1 means the in-sample fit did not carry over
+	Efficiency float64          `json:"walk_forward_efficiency"`
+	Overfit    bool             `json:"overfit"`
+	Warnings   []string         `json:"warnings,omitempty"`
+	Surface    []ParameterScore `json:"surface"` // sorted by in-sample score
+}
+
+// StrategyOptimizer sweeps strategy parameters through the backtest engine
+type StrategyOptimizer struct {
+	engine      *BacktestEngine
+	dataService interfaces.DataService
+	resolve     StrategyResolver
+	workers     int
+	logger      *logrus.Logger
+}
+
+// NewStrategyOptimizer creates an optimizer running up to workers backtests
+// in parallel; workers <= 0 uses one per CPU
+func NewStrategyOptimizer(dataService interfaces.DataService, resolve StrategyResolver, workers int) *StrategyOptimizer {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	// A sweep runs thousands of backtests; only log the summary
+	engine := NewBacktestEngine(dataService)
+	engine.logger.SetLevel(logrus.WarnLevel)
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return &StrategyOptimizer{
+		engine:      engine,
+		dataService: dataService,
+		resolve:     resolve,
+		workers:     workers,
+		logger:      logger,
+	}
+}
+
+// window is a walk-forward split of the bar series: bars[trainFrom:testFrom]
+// are in-sample and bars[testFrom:testTo] out-of-sample
+type window struct {
+	trainFrom, testFrom, testTo int
+}
+
+// Optimize runs the sweep described by req
+func (o *StrategyOptimizer) Optimize(ctx context.Context, req *OptimizeRequest) (*OptimizeResult, error) {
+	o.defaults(req)
+	timeframe, err := NormalizeBarSpec(req.Timeframe)
+	if err != nil {
+		return nil, err
+	}
+	req.Timeframe = timeframe
+	start, err := time.Parse("2006-01-02", req.Start)
+	if err != nil {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("invalid start date: %w", err))
+	}
+	end := time.Now()
+	if req.End != "" {
+		if end, err = time.Parse("2006-01-02", req.End); err != nil {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("invalid end date: %w", err))
+		}
+	}
+	if !end.After(start) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("end must be after start"))
+	}
+	if _, err := o.resolve(req.Strategy, nil); err != nil {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, err)
+	}
+	if err := req.Fills.Validate(); err != nil {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, err)
+	}
+
+	combos, err := parameterCombinations(req)
+	if err != nil {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, err)
+	}
+	if runs := len(combos) * req.Folds * 2; runs > maxOptimizerBacktests {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("%d combinations x %d folds needs %d backtests, more than the limit of %d; narrow the ranges or use random search",
+			len(combos), req.Folds, runs, maxOptimizerBacktests))
+	}
+	for _, params := range combos {
+		if _, err := o.resolve(req.Strategy, paramConfig(params)); err != nil {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("parameters %v: %w", params, err))
+		}
+	}
+
+	bars, err := o.dataService.GetHistoricalBars(ctx, req.Symbol, start, end, req.Timeframe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bars: %w", err)
+	}
+	windows := walkForwardWindows(len(bars), req.Folds, req.TrainPct, req.Lookback)
+	if windows == nil {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("%d bars are too few for %d walk-forward folds; widen the date range or use fewer folds", len(bars), req.Folds))
+	}
+
+	// in[c][f] and out[c][f] are combination c on fold f
+	in := make([][]BacktestMetrics, len(combos))
+	out := make([][]BacktestMetrics, len(combos))
+	for c := range combos {
+		in[c] = make([]BacktestMetrics, len(windows))
+		out[c] = make([]BacktestMetrics, len(windows))
+	}
+	type job struct {
+		combo, fold int
+		test        bool
+	}
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for w := 0; w < o.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				win := windows[j.fold]
+				metrics := o.run(ctx, req, combos[j.combo], bars, win, j.test)
+				if j.test {
+					out[j.combo][j.fold] = metrics
+				} else {
+					in[j.combo][j.fold] = metrics
+				}
+			}
+		}()
+	}
+send:
+	for c := range combos {
+		for f := range windows {
+			for _, test := range []bool{false, true} {
+				select {
+				case jobs <- job{combo: c, fold: f, test: test}:
+				case <-ctx.Done():
+					break send
+				}
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	minTrades := float64(*req.MinTrades)
+	result := &OptimizeResult{
+		Strategy:     req.Strategy,
+		Symbol:       req.Symbol,
+		Method:       req.Method,
+		Objective:    req.Objective,
+		Combinations: len(combos),
+		Bars:         len(bars),
+		Folds:        make([]WalkForwardFold, len(windows)),
+		Surface:      make([]ParameterScore, len(combos)),
+	}
+
+	// Walk forward: pick the best combination on each training window and
+	// trade it, unchanged, on the window after it
+	compounded := 1.0
+	var inScore, outScore float64
+	for f, win := range windows {
+		best := -1
+		for c := range combos {
+			if in[c][f].Trades < minTrades {
+				continue
+			}
+			if best < 0 || in[c][f].Score > in[best][f].Score {
+				best = c
+			}
+		}
+		fold := WalkForwardFold{
+			Fold:       f + 1,
+			TrainStart: bars[win.trainFrom].Timestamp,
+			TrainEnd:   bars[win.testFrom-1].Timestamp,
+			TestStart:  bars[win.testFrom].Timestamp,
+			TestEnd:    bars[win.testTo-1].Timestamp,
+		}
+		if best >= 0 {
+			fold.Params = combos[best]
+			fold.InSample = in[best][f]
+			fold.OutOfSample = out[best][f]
+			compounded *= 1 + fold.OutOfSample.ReturnPct/100
+			inScore += fold.InSample.Score
+			outScore += fold.OutOfSample.Score
+		} else {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("fold %d: no combination made %d trades in-sample", f+1, *req.MinTrades))
+		}
+		result.Folds[f] = fold
+	}
+	result.OutOfSampleReturnPct = (compounded - 1) * 100
+	if inScore > 0 {
+		result.Efficiency = outScore / inScore
+	}
+	result.Overfit = inScore > 0 && result.Efficiency < overfitEfficiencyThreshold
+	if result.Overfit {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("out-of-sample results are %.0f%% of in-sample; the parameters look overfit", result.Efficiency*100))
+	}
+
+	for c, params := range combos {
+		result.Surface[c] = ParameterScore{
+			Params:      params,
+			InSample:    meanMetrics(in[c]),
+			OutOfSample: meanMetrics(out[c]),
+		}
+	}
+	sort.SliceStable(result.Surface, func(i, j int) bool {
+		return result.Surface[i].InSample.Score > result.Surface[j].InSample.Score
+	})
+	for i := range result.Surface {
+		if result.Surface[i].InSample.Trades >= minTrades {
+			best := result.Surface[i]
+			result.Best = &best
+			break
+		}
+	}
+
+	o.logger.WithFields(logrus.Fields{
+		"strategy":     req.Strategy,
+		"symbol":       req.Symbol,
+		"combinations": len(combos),
+		"folds":        len(windows),
+		"oos_return":   result.OutOfSampleReturnPct,
+		"efficiency":   result.Efficiency,
+	}).Info("Optimization complete")
+
+	return result, nil
+}
+
+func (o *StrategyOptimizer) defaults(req *OptimizeRequest) {
+	req.Symbol = strings.ToUpper(req.Symbol)
+	if req.Timeframe == "" {
+		req.Timeframe = "1Day"
+	}
+	if req.InitialCapital <= 0 {
+		req.InitialCapital = 100000
+	}
+	if req.Lookback <= 0 {
+		req.Lookback = 50
+	}
+	if req.Method == "" {
+		req.Method = OptimizeGrid
+	}
+	if req.Samples <= 0 {
+		req.Samples = 50
+	}
+	if req.Objective == "" {
+		req.Objective = ObjectiveReturnOverDD
+	}
+	if req.Folds <= 0 {
+		req.Folds = 4
+	}
+	if req.TrainPct <= 0 {
+		req.TrainPct = 70
+	}
+	if req.MinTrades == nil {
+		minTrades := 1
+		req.MinTrades = &minTrades
+	}
+}
+
+// run backtests one combination on the training or test part of a window.
+// Test runs get the lookback bars before the window as untraded history.
+func (o *StrategyOptimizer) run(ctx context.Context, req *OptimizeRequest, params map[string]float64, bars []*interfaces.Bar, win window, test bool) BacktestMetrics {
+	strategy, err := o.resolve(req.Strategy, paramConfig(params))
+	if err != nil {
+		return BacktestMetrics{}
+	}
+
+	cfg := BacktestConfig{
+		Symbol:         req.Symbol,
+		Timeframe:      req.Timeframe,
+		InitialCapital: req.InitialCapital,
+		Lookback:       req.Lookback,
+		Fills:          req.Fills,
+	}
+	from, to := win.trainFrom, win.testFrom
+	if test {
+		from, to = win.testFrom-req.Lookback, win.testTo
+		if from < 0 {
+			from = 0
+		}
+		cfg.Warmup = win.testFrom - from
+	}
+	cfg.Start = bars[from].Timestamp
+	cfg.End = bars[to-1].Timestamp
+
+	result := o.engine.RunBars(ctx, strategy, cfg, bars[from:to])
+	metrics := BacktestMetrics{
+		ReturnPct:      result.TotalReturnPct,
+		MaxDrawdownPct: result.MaxDrawdownPct,
+		WinRate:        result.WinRate,
+		Trades:         float64(len(result.Trades)),
+	}
+	metrics.Score = objectiveScore(req.Objective, metrics)
+	return metrics
+}
+
+// objectiveScore ranks a backtest; higher is better
+func objectiveScore(objective string, m BacktestMetrics) float64 {
+	switch objective {
+	case ObjectiveReturn:
+		return m.ReturnPct
+	case ObjectiveWinRate:
+		return m.WinRate
+	default:
+		// Drawdowns under 1% are floored so flat runs don't blow up the ratio
+		return m.ReturnPct / math.Max(m.MaxDrawdownPct, 1)
+	}
+}
+
+// walkForwardWindows splits n bars into folds consecutive, non-overlapping
+// windows, each trained on its first trainPct percent and tested on the
+// rest. It returns nil when a window would be too short to be meaningful.
+func walkForwardWindows(n, folds int, trainPct float64, lookback int) []window {
+	size := n / folds
+	train := int(float64(size) * trainPct / 100)
+	if train < lookback || size-train < 2 || train < 2 {
+		return nil
+	}
+
+	windows := make([]window, folds)
+	for f := range windows {
+		from := f * size
+		windows[f] = window{trainFrom: from, testFrom: from + train, testTo: from + size}
+	}
+	// The last window absorbs the remainder
+	windows[folds-1].testTo = n
+	return windows
+}
+
+// parameterCombinations expands the ranges into the parameter sets to try
+func parameterCombinations(req *OptimizeRequest) ([]map[string]float64, error) {
+	names := make([]string, 0, len(req.Parameters))
+	for name := range req.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if req.Method == OptimizeRandom {
+		seed := req.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		rng := rand.New(rand.NewSource(seed))
+		combos := make([]map[string]float64, 0, req.Samples)
+		seen := make(map[string]bool)
+		// Give up on duplicates eventually: small discrete spaces may hold
+		// fewer distinct sets than samples
+		for attempts := 0; len(combos) < req.Samples && attempts < req.Samples*20; attempts++ {
+			params := make(map[string]float64, len(names))
+			for _, name := range names {
+				value, err := sampleRange(name, req.Parameters[name], rng)
+				if err != nil {
+					return nil, err
+				}
+				params[name] = value
+			}
+			if key := fmt.Sprint(params); !seen[key] {
+				seen[key] = true
+				combos = append(combos, params)
+			}
+		}
+		return combos, nil
+	}
+
+	combos := []map[string]float64{{}}
+	for _, name := range names {
+		values, err := rangeValues(name, req.Parameters[name])
+		if err != nil {
+			return nil, err
+		}
+		if len(combos)*len(values) > maxOptimizerBacktests {
+			return nil, fmt.Errorf("the parameter grid has more than %d combinations; narrow the ranges or use random search", maxOptimizerBacktests)
+		}
+		next := make([]map[string]float64, 0, len(combos)*len(values))
+		for _, combo := range combos {
+			for _, value := range values {
+				params := make(map[string]float64, len(combo)+1)
+				for k, v := range combo {
+					params[k] = v
+				}
+				params[name] = value
+				next = append(next, params)
+			}
+		}
+		combos = next
+	}
+	return combos, nil
+}
+
+// rangeValues lists the grid values of one parameter
+func rangeValues(name string, r ParameterRange) ([]float64, error) {
+	if len(r.Values) > 0 {
+		return r.Values, nil
+	}
+	if r.Min == nil || r.Max == nil || r.Step == nil {
+		return nil, fmt.Errorf("parameter %s: set values, or min, max and step", name)
+	}
+	if *r.Max < *r.Min {
+		return nil, fmt.Errorf("parameter %s: max is below min", name)
+	}
+	if *r.Step <= 0 {
+		return nil, fmt.Errorf("parameter %s: step must be positive", name)
+	}
+	count := int(math.Floor((*r.Max-*r.Min)/(*r.Step)+1e-9)) + 1
+	if count > maxOptimizerBacktests {
+		return nil, fmt.Errorf("parameter %s: too many steps", name)
+	}
+	values := make([]float64, count)
+	for i := range values {
+		// Round away float drift so 0.1 steps stay readable
+		values[i] = math.Round((*r.Min+float64(i)*(*r.Step))*1e9) / 1e9
+	}
+	return values, nil
+}
+
+// sampleRange draws one value of a parameter for random search
+func sampleRange(name string, r ParameterRange, rng *rand.Rand) (float64, error) {
+	if len(r.Values) > 0 {
+		return r.Values[rng.Intn(len(r.Values))], nil
+	}
+	if r.Min == nil || r.Max == nil {
+		return 0, fmt.Errorf("parameter %s: set values, or min and max", name)
+	}
+	if *r.Max < *r.Min {
+		return 0, fmt.Errorf("parameter %s: max is below min", name)
+	}
+	if r.Step != nil && *r.Step <= 0 {
+		return 0, fmt.Errorf("parameter %s: step must be positive", name)
+	}
+	if r.Step != nil {
+		steps := int(math.Floor((*r.Max-*r.Min)/(*r.Step) + 1e-9))
+		return math.Round((*r.Min+float64(rng.Intn(steps+1))*(*r.Step))*1e9) / 1e9, nil
+	}
+	return *r.Min + rng.Float64()*(*r.Max-*r.Min), nil
+}
+
+// paramConfig converts a parameter set to a strategy config
+func paramConfig(params map[string]float64) map[string]interface{} {
+	config := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		config[k] = v
+	}
+	return config
+}
+
+// meanMetrics averages metrics over the walk-forward windows
+func meanMetrics(runs []BacktestMetrics) BacktestMetrics {
+	var mean BacktestMetrics
+	if len(runs) == 0 {
+		return mean
+	}
+	for _, m := range runs {
+		mean.ReturnPct += m.ReturnPct
+		mean.MaxDrawdownPct += m.MaxDrawdownPct
+		mean.WinRate += m.WinRate
+		mean.Trades += m.Trades
+		mean.Score += m.Score
+	}
+	n := float64(len(runs))
+	mean.ReturnPct /= n
+	mean.MaxDrawdownPct /= n
+	mean.WinRate /= n
+	mean.Trades /= n
+	mean.Score /= n
+	return mean
+}
@@ -0,0 +1,466 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sources of the volatility an options analysis prices outcomes with
+const (
+	VolatilitySourceRequest  = "request"  // given in the request
+	VolatilitySourceStraddle = "straddle" // implied by the at-the-money straddle
+	VolatilitySourceLegs     = "legs"     // average implied volatility of the legs
+)
+
+// OptionsAnalysisLeg is one option of a proposed structure
+type OptionsAnalysisLeg struct {
+	Symbol string  `json:"symbol"`
+	Side   string  `json:"side"`            // "buy" or "sell"
+	Qty    float64 `json:"qty"`             // contracts, default 1
+	Price  float64 `json:"price,omitempty"` // per share, defaults to the quote midpoint
+}
+
+// OptionsAnalysisRequest is a proposed options structure. Shares are
+// underlying shares held with it, negative for a short, as in a covered
+// call.
+type OptionsAnalysisRequest struct {
+	Legs       []OptionsAnalysisLeg `json:"legs"`
+	Shares     float64              `json:"shares,omitempty"`
+	Volatility float64              `json:"volatility,omitempty"` // annualized, such as 0.3; defaults to the straddle's
+}
+
+// AnalyzedLeg is a leg with its quote and the chance it expires in the
+// money
+type AnalyzedLeg struct {
+	OptionsAnalysisLeg
+	Type              string    `json:"type"`
+	Strike            float64   `json:"strike"`
+	Expiration        time.Time `json:"expiration"`
+	Bid               float64   `json:"bid"`
+	Ask               float64   `json:"ask"`
+	ImpliedVolatility float64   `json:"implied_volatility"`
+	Delta             float64   `json:"delta"`
+	ProbITM           float64   `json:"prob_itm"`
+}
+
+// ExpectedMove is the move in the underlying priced into the at-the-money
+// straddle for an expiration
+type ExpectedMove struct {
+	Underlying        string    `json:"underlying"`
+	UnderlyingPrice   float64   `json:"underlying_price"`
+	Expiration        time.Time `json:"expiration"`
+	DaysToExpiry      float64   `json:"days_to_expiry"`
+	Strike            float64   `json:"strike"`
+	CallSymbol        string    `json:"call_symbol"`
+	PutSymbol         string    `json:"put_symbol"`
+	CallPrice         float64   `json:"call_price"`
+	PutPrice          float64   `json:"put_price"`
+	Move              float64   `json:"move"` // the straddle's price
+	MovePct           float64   `json:"move_percent"`
+	Low               float64   `json:"low"`
+	High              float64   `json:"high"`
+	ImpliedVolatility float64   `json:"implied_volatility"` // the volatility the straddle is priced at
+	OneSigmaMove      float64   `json:"one_sigma_move"`
+}
+
+// OptionsAnalysis is the outcome of a proposed structure at its first
+// expiration. Probabilities are from a lognormal distribution at
+// Volatility. MaxProfit and MaxLoss are nil when unbounded.
+type OptionsAnalysis struct {
+	Underlying          string         `json:"underlying"`
+	UnderlyingPrice     float64        `json:"underlying_price"`
+	Horizon             time.Time      `json:"horizon"`
+	DaysToExpiry        float64        `json:"days_to_expiry"`
+	Volatility          float64        `json:"volatility"`
+	VolatilitySource    string         `json:"volatility_source"`
+	Legs                []*AnalyzedLeg `json:"legs"`
+	Shares              float64        `json:"shares,omitempty"`
+	NetPremium          float64        `json:"net_premium"` // paid for the options, negative for a credit
+	ProbabilityOfProfit float64        `json:"probability_of_profit"`
+	ExpectedPnL         float64        `json:"expected_pnl"`
+	MaxProfit           *float64       `json:"max_profit"`
+	MaxLoss             *float64       `json:"max_loss"`
+	Breakevens          []float64      `json:"breakevens"`
+	ExpectedMove        *ExpectedMove  `json:"expected_move,omitempty"`
+	Warnings            []string       `json:"warnings,omitempty"`
+}
+
+// maxAnalysisLegs bounds the legs of one analyzed structure
+const maxAnalysisLegs = 8
+
+// OptionsAnalyzer prices proposed options structures from the live chain:
+// the expected move, probability of profit and chance each leg expires in
+// the money
+type OptionsAnalyzer struct {
+	tradingService interfaces.TradingService
+	dataService    interfaces.DataService
+	chains         *OptionsChainCache
+	logger         *logrus.Logger
+}
+
+// NewOptionsAnalyzer creates a new options analyzer. chains may be nil, in
+// which case chains are fetched from the broker on every request.
+func NewOptionsAnalyzer(trading interfaces.TradingService, data interfaces.DataService, chains *OptionsChainCache) *OptionsAnalyzer {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &OptionsAnalyzer{
+		tradingService: trading,
+		dataService:    data,
+		chains:         chains,
+		logger:         logger,
+	}
+}
+
+// ExpectedMove prices the at-the-money straddle for underlying's
+// expiration: the strike nearest the last trade with both a call and a put
+// quoted
+func (a *OptionsAnalyzer) ExpectedMove(ctx context.Context, underlying string, expiration time.Time) (*ExpectedMove, error) {
+	underlying = strings.ToUpper(underlying)
+	spot, err := a.spot(ctx, underlying)
+	if err != nil {
+		return nil, err
+	}
+	contracts, err := a.chain(ctx, underlying, expiration)
+	if err != nil {
+		return nil, err
+	}
+	return expectedMoveFromChain(underlying, spot, expiration, contracts, time.Now())
+}
+
+// Analyze prices a proposed structure at its first expiration. Legs
+// expiring later are valued with the model at their own volatility.
+func (a *OptionsAnalyzer) Analyze(ctx context.Context, req OptionsAnalysisRequest) (*OptionsAnalysis, error) {
+	if len(req.Legs) == 0 || len(req.Legs) > maxAnalysisLegs {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("a structure needs 1 to %d legs", maxAnalysisLegs))
+	}
+	if req.Volatility < 0 || req.Volatility > 5 {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("volatility must be between 0 and 5"))
+	}
+
+	analysis := &OptionsAnalysis{Shares: req.Shares, Legs: make([]*AnalyzedLeg, 0, len(req.Legs))}
+	for i, leg := range req.Legs {
+		occ, err := ParseOCCSymbol(leg.Symbol)
+		if err != nil {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, err)
+		}
+		leg.Symbol = occ.String()
+		leg.Side = strings.ToLower(leg.Side)
+		if leg.Side != "buy" && leg.Side != "sell" {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("leg %d: side must be buy or sell", i+1))
+		}
+		if leg.Qty == 0 {
+			leg.Qty = 1
+		}
+		if leg.Qty < 0 || leg.Price < 0 {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("leg %d: qty and price cannot be negative", i+1))
+		}
+		if analysis.Underlying == "" {
+			analysis.Underlying = occ.Underlying
+		} else if occ.Underlying != analysis.Underlying {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("every leg must be on %s", analysis.Underlying))
+		}
+		if analysis.Horizon.IsZero() || occ.Expiration.Before(analysis.Horizon) {
+			analysis.Horizon = occ.Expiration
+		}
+		analysis.Legs = append(analysis.Legs, &AnalyzedLeg{
+			OptionsAnalysisLeg: leg,
+			Type:               occ.Type,
+			Strike:             occ.Strike,
+			Expiration:         occ.Expiration,
+		})
+	}
+
+	now := time.Now()
+	if daysToExpiry(analysis.Horizon, now) < 0 {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("%s has expired", analysis.Horizon.Format("2006-01-02")))
+	}
+	spot, err := a.spot(ctx, analysis.Underlying)
+	if err != nil {
+		return nil, err
+	}
+	analysis.UnderlyingPrice = spot
+	years := yearsToExpiry(analysis.Horizon, now)
+	analysis.DaysToExpiry = years * 365
+
+	// Quote the legs, one chain per expiration
+	chains := make(map[string][]*interfaces.OptionContract)
+	for _, leg := range analysis.Legs {
+		key := leg.Expiration.Format("2006-01-02")
+		contracts, ok := chains[key]
+		if !ok {
+			if contracts, err = a.chain(ctx, analysis.Underlying, leg.Expiration); err != nil {
+				return nil, err
+			}
+			chains[key] = contracts
+		}
+		for _, contract := range contracts {
+			if contract.Symbol == leg.Symbol {
+				leg.Bid, leg.Ask = contract.Bid, contract.Ask
+				leg.ImpliedVolatility = contract.ImpliedVolatility
+				leg.Delta = contract.Delta
+				if leg.Price == 0 {
+					leg.Price = contractPrice(contract)
+				}
+				break
+			}
+		}
+		if leg.Price == 0 {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("no price for %s; give one", leg.Symbol))
+		}
+		if leg.ImpliedVolatility <= 0 {
+			leg.ImpliedVolatility = impliedVolatility(leg.Type == "call", leg.Price, spot, leg.Strike, yearsToExpiry(leg.Expiration, now), riskFreeRate)
+		}
+	}
+
+	move, err := expectedMoveFromChain(analysis.Underlying, spot, analysis.Horizon, chains[analysis.Horizon.Format("2006-01-02")], now)
+	if err != nil {
+		analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("no expected move: %v", err))
+	}
+	analysis.ExpectedMove = move
+
+	switch {
+	case req.Volatility > 0:
+		analysis.Volatility, analysis.VolatilitySource = req.Volatility, VolatilitySourceRequest
+	case move != nil && move.ImpliedVolatility > 0:
+		analysis.Volatility, analysis.VolatilitySource = move.ImpliedVolatility, VolatilitySourceStraddle
+	default:
+		var sum float64
+		var n int
+		for _, leg := range analysis.Legs {
+			if leg.ImpliedVolatility > 0 {
+				sum += leg.ImpliedVolatility
+				n++
+			}
+		}
+		if n == 0 {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("no implied volatility for %s; give a volatility", analysis.Underlying))
+		}
+		analysis.Volatility, analysis.VolatilitySource = sum/float64(n), VolatilitySourceLegs
+	}
+
+	for _, leg := range analysis.Legs {
+		legYears := yearsToExpiry(leg.Expiration, now)
+		vol := leg.ImpliedVolatility
+		if vol <= 0 {
+			vol = analysis.Volatility
+		}
+		_, d2 := blackScholesD(spot, leg.Strike, legYears, riskFreeRate, vol)
+		if leg.Type == "call" {
+			leg.ProbITM = normCDF(d2)
+		} else {
+			leg.ProbITM = normCDF(-d2)
+		}
+		if leg.Delta == 0 {
+			leg.Delta = blackScholesGreeks(leg.Type == "call", spot, leg.Strike, legYears, riskFreeRate, vol).Delta
+		}
+		analysis.NetPremium += legSign(leg) * leg.Qty * leg.Price * optionContractShares
+	}
+
+	pnl := func(price float64) float64 {
+		return structurePnL(analysis, price, now)
+	}
+
+	// Probability of profit and expected P&L over the lognormal
+	// distribution at the horizon, in steps of 0.01 standard deviations
+	vol := analysis.Volatility
+	drift := (riskFreeRate - vol*vol/2) * years
+	sigma := vol * math.Sqrt(years)
+	const steps = 1200
+	for i := 0; i <= steps; i++ {
+		z := -6 + 12*float64(i)/steps
+		weight := normPDF(z) * 12 / steps
+		value := pnl(spot * math.Exp(drift+sigma*z))
+		if value > 0.005 {
+			analysis.ProbabilityOfProfit += weight
+		}
+		analysis.ExpectedPnL += weight * value
+	}
+	analysis.ProbabilityOfProfit = math.Min(analysis.ProbabilityOfProfit, 1)
+
+	// Bounds and breakevens on a grid out to three times the highest
+	// price of interest, with every strike on it
+	upper := spot
+	for _, leg := range analysis.Legs {
+		upper = math.Max(upper, leg.Strike)
+	}
+	upper *= 3
+	prices := []float64{0}
+	for i := 1; i <= 3000; i++ {
+		prices = append(prices, upper*float64(i)/3000)
+	}
+	for _, leg := range analysis.Legs {
+		prices = append(prices, leg.Strike)
+	}
+	sort.Float64s(prices)
+
+	maxProfit, maxLoss := math.Inf(-1), math.Inf(1)
+	previous, previousPnL := 0.0, pnl(0)
+	for _, price := range prices {
+		value := pnl(price)
+		maxProfit = math.Max(maxProfit, value)
+		maxLoss = math.Min(maxLoss, value)
+		if (previousPnL < 0) != (value < 0) && price > previous {
+			breakeven := previous + (price-previous)*(-previousPnL)/(value-previousPnL)
+			analysis.Breakevens = append(analysis.Breakevens, math.Round(breakeven*100)/100)
+		}
+		previous, previousPnL = price, value
+	}
+	// Still rising or falling past the grid, the structure is unbounded
+	slope := pnl(upper*2) - pnl(upper)
+	if !(slope > 0.01) {
+		maxProfit = math.Round(maxProfit*100) / 100
+		analysis.MaxProfit = &maxProfit
+	}
+	if !(slope < -0.01) {
+		maxLoss = math.Round(math.Min(maxLoss, 0)*100) / 100
+		analysis.MaxLoss = &maxLoss
+	}
+
+	a.logger.WithFields(logrus.Fields{
+		"underlying": analysis.Underlying,
+		"legs":       len(analysis.Legs),
+		"pop":        analysis.ProbabilityOfProfit,
+	}).Debug("Analyzed options structure")
+
+	return analysis, nil
+}
+
+// structurePnL is the structure's P&L with the underlying at price on the
+// horizon. Legs expiring then are worth their intrinsic value.
+func structurePnL(analysis *OptionsAnalysis, price float64, now time.Time) float64 {
+	pnl := analysis.Shares * (price - analysis.UnderlyingPrice)
+	for _, leg := range analysis.Legs {
+		value := intrinsicValue(leg.Type, leg.Strike, price)
+		if leg.Expiration.After(analysis.Horizon) && price > 0 {
+			vol := leg.ImpliedVolatility
+			if vol <= 0 {
+				vol = analysis.Volatility
+			}
+			// Time left after the horizon, from the same moment now
+			years := yearsToExpiry(leg.Expiration, now) - yearsToExpiry(analysis.Horizon, now)
+			value = blackScholesPrice(leg.Type == "call", price, leg.Strike, years, riskFreeRate, vol)
+		}
+		pnl += legSign(leg) * leg.Qty * (value - leg.Price) * optionContractShares
+	}
+	return pnl
+}
+
+// legSign is 1 for a bought leg and -1 for a sold one
+func legSign(leg *AnalyzedLeg) float64 {
+	if leg.Side == "sell" {
+		return -1
+	}
+	return 1
+}
+
+// expectedMoveFromChain prices the straddle at the quoted strike nearest
+// spot
+func expectedMoveFromChain(underlying string, spot float64, expiration time.Time, contracts []*interfaces.OptionContract, now time.Time) (*ExpectedMove, error) {
+	if spot <= 0 {
+		return nil, fmt.Errorf("no price for %s", underlying)
+	}
+	calls := make(map[float64]*interfaces.OptionContract)
+	puts := make(map[float64]*interfaces.OptionContract)
+	for _, contract := range contracts {
+		if contractPrice(contract) <= 0 {
+			continue
+		}
+		if contract.ContractType == "put" {
+			puts[contract.StrikePrice] = contract
+		} else {
+			calls[contract.StrikePrice] = contract
+		}
+	}
+
+	var call, put *interfaces.OptionContract
+	for strike, c := range calls {
+		p, ok := puts[strike]
+		if ok && (call == nil || math.Abs(strike-spot) < math.Abs(call.StrikePrice-spot)) {
+			call, put = c, p
+		}
+	}
+	if call == nil {
+		return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("no quoted straddle for %s expiring %s", underlying, expiration.Format("2006-01-02")))
+	}
+
+	years := yearsToExpiry(expiration, now)
+	move := &ExpectedMove{
+		Underlying:      underlying,
+		UnderlyingPrice: spot,
+		Expiration:      expiration,
+		DaysToExpiry:    years * 365,
+		Strike:          call.StrikePrice,
+		CallSymbol:      call.Symbol,
+		PutSymbol:       put.Symbol,
+		CallPrice:       contractPrice(call),
+		PutPrice:        contractPrice(put),
+	}
+	move.Move = move.CallPrice + move.PutPrice
+	move.MovePct = move.Move / spot * 100
+	move.Low = math.Max(spot-move.Move, 0)
+	move.High = spot + move.Move
+	move.ImpliedVolatility = straddleVolatility(move.Move, spot, move.Strike, years)
+	move.OneSigmaMove = spot * move.ImpliedVolatility * math.Sqrt(years)
+	return move, nil
+}
+
+// straddleVolatility solves the model for the volatility that prices the
+// straddle at price, by bisection between 1% and 500%, or 0 when none does
+func straddleVolatility(price, spot, strike, years float64) float64 {
+	straddle := func(vol float64) float64 {
+		return blackScholesPrice(true, spot, strike, years, riskFreeRate, vol) + blackScholesPrice(false, spot, strike, years, riskFreeRate, vol)
+	}
+	low, high := 0.01, 5.0
+	if price < straddle(low) || price > straddle(high) {
+		return 0
+	}
+	for i := 0; i < 100 && high-low > 1e-6; i++ {
+		mid := (low + high) / 2
+		if straddle(mid) < price {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// contractPrice is a contract's quote midpoint, or its last trade without
+// a two-sided quote
+func contractPrice(contract *interfaces.OptionContract) float64 {
+	if contract.Bid > 0 && contract.Ask > 0 {
+		return (contract.Bid + contract.Ask) / 2
+	}
+	return contract.Premium
+}
+
+// chain returns the contracts for underlying's expiration, from the cache
+// when there is one
+func (a *OptionsAnalyzer) chain(ctx context.Context, underlying string, expiration time.Time) ([]*interfaces.OptionContract, error) {
+	if a.chains != nil {
+		chain, err := a.chains.Get(ctx, underlying, expiration)
+		if err != nil {
+			return nil, err
+		}
+		return chain.Contracts, nil
+	}
+	return a.tradingService.GetOptionsChain(ctx, underlying, expiration)
+}
+
+func (a *OptionsAnalyzer) spot(ctx context.Context, underlying string) (float64, error) {
+	trade, err := a.dataService.GetLatestTrade(ctx, underlying)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get price for %s: %w", underlying, err)
+	}
+	return trade.Price, nil
+}
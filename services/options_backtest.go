@@ -0,0 +1,454 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OptionBarsSource fetches historical bars for OCC option symbols
+type OptionBarsSource interface {
+	GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error)
+}
+
+// OptionBarsDataService sends historical bar requests for OCC option
+// symbols to an options source and everything else to the wrapped data
+// service. Wrapped in a BarCacheDataService, option bars fetched by the
+// download command are served from storage like any other symbol's.
+type OptionBarsDataService struct {
+	interfaces.DataService
+	options OptionBarsSource
+}
+
+// NewOptionBarsDataService wraps data, fetching option bars from options
+func NewOptionBarsDataService(data interfaces.DataService, options OptionBarsSource) *OptionBarsDataService {
+	return &OptionBarsDataService{DataService: data, options: options}
+}
+
+// GetHistoricalBars implements interfaces.DataService
+func (s *OptionBarsDataService) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
+	if IsOCCSymbol(symbol) {
+		return s.options.GetHistoricalBars(ctx, symbol, start, end, timeframe)
+	}
+	return s.DataService.GetHistoricalBars(ctx, symbol, start, end, timeframe)
+}
+
+// Option structures the options backtest can trade
+const (
+	StructureCoveredCall    = "covered_call"     // long 100 shares per contract, short an OTM call
+	StructureBullCallSpread = "bull_call_spread" // long a call, short a higher call, for a debit
+	StructureBearPutSpread  = "bear_put_spread"  // long a put, short a lower put, for a debit
+	StructureBullPutSpread  = "bull_put_spread"  // short a put, long a lower put, for a credit
+	StructureBearCallSpread = "bear_call_spread" // short a call, long a higher call, for a credit
+)
+
+// Ways an options backtest cycle ends
+const (
+	CycleExpired      = "expired"       // held to expiration and settled at intrinsic value
+	CycleAssigned     = "assigned"      // covered call expired in the money, shares called away
+	CycleProfitTarget = "profit_target" // closed at the profit target
+	CycleTimeExit     = "time_exit"     // closed days before expiration
+	CycleOpen         = "open"          // still open when the backtest ended, marked to market
+)
+
+// OptionsBacktestConfig describes an options backtest: the structure is
+// opened on the underlying, held until it expires or an exit rule fires,
+// then rolled into a new one at that bar's close
+type OptionsBacktestConfig struct {
+	Underlying      string    `json:"underlying"`
+	Structure       string    `json:"structure"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	InitialCapital  float64   `json:"initial_capital"`
+	Contracts       int       `json:"contracts"`         // per leg, default 1
+	DTE             int       `json:"dte"`               // target days to expiration, default 30; the first Friday on or after it is used
+	StrikeOffsetPct float64   `json:"strike_offset_pct"` // first leg's strike, percent out of the money (negative is in the money), default 5
+	Width           float64   `json:"width"`             // spread width in dollars, default 5
+	StrikeStep      float64   `json:"strike_step"`       // strikes are rounded to this, default 1
+	ProfitTakePct   float64   `json:"profit_take_pct"`   // close at this percent of the maximum option profit, 0 holds on
+	CloseDaysBefore int       `json:"close_days_before"` // close this many days before expiration, 0 holds to expiration
+	Fills           FillModel `json:"fills"`
+}
+
+// OptionsLeg is one option of a structure with its fills. Qty is in
+// contracts, negative for short legs.
+type OptionsLeg struct {
+	Symbol     string  `json:"symbol"`
+	Type       string  `json:"type"` // "call" or "put"
+	Strike     float64 `json:"strike"`
+	Qty        float64 `json:"qty"`
+	EntryPrice float64 `json:"entry_price"`
+	ExitPrice  float64 `json:"exit_price"`
+
+	prices map[string]float64 // close by day
+	last   float64
+}
+
+// OptionsBacktestCycle is one opening and closing of the structure. For a
+// covered call, SharePnL is the change in the shares' value over the
+// cycle; when they are called away, the call's loss past the strike is
+// in OptionPnL.
+type OptionsBacktestCycle struct {
+	EntryTime       time.Time    `json:"entry_time"`
+	ExitTime        time.Time    `json:"exit_time"`
+	Expiration      time.Time    `json:"expiration"`
+	UnderlyingEntry float64      `json:"underlying_entry"`
+	UnderlyingExit  float64      `json:"underlying_exit"`
+	Legs            []OptionsLeg `json:"legs"`
+	Shares          float64      `json:"shares,omitempty"`
+	OptionPnL       float64      `json:"option_pnl"`
+	SharePnL        float64      `json:"share_pnl,omitempty"`
+	Fees            float64      `json:"fees"`
+	PnL             float64      `json:"pnl"` // after fees
+	Outcome         string       `json:"outcome"`
+}
+
+// OptionsBacktestResult summarizes an options backtest
+type OptionsBacktestResult struct {
+	Config         OptionsBacktestConfig  `json:"config"`
+	BarsProcessed  int                    `json:"bars_processed"`
+	FinalEquity    float64                `json:"final_equity"`
+	TotalReturnPct float64                `json:"total_return_percent"`
+	MaxDrawdownPct float64                `json:"max_drawdown_percent"`
+	WinRate        float64                `json:"win_rate"`
+	TotalFees      float64                `json:"total_fees"`
+	Cycles         []OptionsBacktestCycle `json:"cycles"`
+	Skipped        int                    `json:"skipped"` // entries skipped for lack of contract bars or cash
+	EquityCurve    []EquityPoint          `json:"equity_curve"`
+	Warnings       []string               `json:"warnings,omitempty"`
+}
+
+// optionsCycle is the state of the open cycle
+type optionsCycle struct {
+	OptionsBacktestCycle
+	expiry     time.Time
+	shareBasis float64 // value of the shares at the start of the cycle
+	maxProfit  float64 // most the options can make, per structure
+	entryValue float64 // signed value of the options at entry
+}
+
+// RunOptions runs an options structure over the underlying's daily bars.
+// Option prices are the daily closes of each contract, so the data service
+// must serve OCC symbols, such as an OptionBarsDataService. A leg without a
+// bar on a day keeps its last close; at expiration the legs settle at
+// intrinsic value from the underlying's close.
+func (be *BacktestEngine) RunOptions(ctx context.Context, cfg OptionsBacktestConfig) (*OptionsBacktestResult, error) {
+	cfg.Underlying = strings.ToUpper(cfg.Underlying)
+	if cfg.InitialCapital <= 0 {
+		cfg.InitialCapital = 100000
+	}
+	if cfg.Contracts <= 0 {
+		cfg.Contracts = 1
+	}
+	if cfg.DTE <= 0 {
+		cfg.DTE = 30
+	}
+	if cfg.StrikeOffsetPct == 0 {
+		cfg.StrikeOffsetPct = 5
+	}
+	if cfg.Width <= 0 {
+		cfg.Width = 5
+	}
+	if cfg.StrikeStep <= 0 {
+		cfg.StrikeStep = 1
+	}
+	switch cfg.Structure {
+	case StructureCoveredCall, StructureBullCallSpread, StructureBearPutSpread, StructureBullPutSpread, StructureBearCallSpread:
+	default:
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("structure must be %s, %s, %s, %s or %s",
+			StructureCoveredCall, StructureBullCallSpread, StructureBearPutSpread, StructureBullPutSpread, StructureBearCallSpread))
+	}
+	if err := cfg.Fills.Validate(); err != nil {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, err)
+	}
+
+	bars, err := be.dataService.GetHistoricalBars(ctx, cfg.Underlying, cfg.Start, cfg.End, "1Day")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bars: %w", err)
+	}
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no bars available for %s", cfg.Underlying)
+	}
+
+	result := &OptionsBacktestResult{
+		Config:      cfg,
+		Cycles:      make([]OptionsBacktestCycle, 0),
+		EquityCurve: make([]EquityPoint, 0, len(bars)),
+	}
+	cash := cfg.InitialCapital
+	shares := 0.0
+	peak := cfg.InitialCapital
+	wins := 0
+	var cycle *optionsCycle
+	var retryAfter time.Time
+
+	closeCycle := func(bar *interfaces.Bar, outcome string) {
+		expired := outcome == CycleExpired || outcome == CycleAssigned
+		optionValue := 0.0
+		for i := range cycle.Legs {
+			leg := &cycle.Legs[i]
+			switch {
+			case expired:
+				leg.ExitPrice = intrinsicValue(leg.Type, leg.Strike, bar.Close)
+			case outcome == CycleOpen:
+				leg.ExitPrice = leg.last
+			default:
+				side := "sell"
+				if leg.Qty < 0 {
+					side = "buy"
+				}
+				leg.ExitPrice = cfg.Fills.FillPrice(side, leg.last, nil)
+				fees := cfg.Fills.Fees(leg.Symbol, math.Abs(leg.Qty))
+				cycle.Fees += fees
+				cash -= fees
+			}
+			value := leg.Qty * leg.ExitPrice * optionContractShares
+			optionValue += value
+			if outcome != CycleOpen {
+				cash += value
+			}
+		}
+		cycle.OptionPnL = optionValue - cycle.entryValue
+
+		if cycle.Shares > 0 {
+			shareValue := shares * bar.Close
+			if outcome == CycleAssigned {
+				// Delivering at the strike is the call settling at its
+				// intrinsic value and the shares going at the close
+				fees := cfg.Fills.Fees(cfg.Underlying, shares)
+				cycle.Fees += fees
+				cash += shareValue - fees
+				shares = 0
+			}
+			cycle.SharePnL = shareValue - cycle.shareBasis
+		}
+
+		cycle.ExitTime = bar.Timestamp
+		cycle.UnderlyingExit = bar.Close
+		cycle.Outcome = outcome
+		cycle.PnL = cycle.OptionPnL + cycle.SharePnL - cycle.Fees
+		if cycle.PnL > 0 {
+			wins++
+		}
+		result.TotalFees += cycle.Fees
+		result.Cycles = append(result.Cycles, cycle.OptionsBacktestCycle)
+		cycle = nil
+	}
+
+	for _, bar := range bars {
+		if ctx.Err() != nil {
+			break
+		}
+		day := bar.Timestamp.Format("2006-01-02")
+
+		if cycle != nil {
+			for i := range cycle.Legs {
+				if price, ok := cycle.Legs[i].prices[day]; ok {
+					cycle.Legs[i].last = price
+				}
+			}
+			switch {
+			case !bar.Timestamp.Before(cycle.expiry):
+				outcome := CycleExpired
+				if cycle.Shares > 0 && bar.Close > cycle.Legs[0].Strike {
+					outcome = CycleAssigned
+				}
+				closeCycle(bar, outcome)
+			case cfg.CloseDaysBefore > 0 && !bar.Timestamp.Before(cycle.expiry.AddDate(0, 0, -cfg.CloseDaysBefore)):
+				closeCycle(bar, CycleTimeExit)
+			case cfg.ProfitTakePct > 0 && cycle.maxProfit > 0 && cycle.markedOptionPnL() >= cycle.maxProfit*cfg.ProfitTakePct/100:
+				closeCycle(bar, CycleProfitTarget)
+			}
+		}
+
+		// A cycle that ended on this bar rolls into the next at its close
+		if cycle == nil && !bar.Timestamp.Before(retryAfter) {
+			opened, err := be.openCycle(ctx, cfg, bar, &cash, &shares)
+			if err != nil {
+				result.Skipped++
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %v", day, err))
+				// Weekly expirations: try again with the next week's contracts
+				retryAfter = bar.Timestamp.AddDate(0, 0, 7)
+			}
+			cycle = opened
+		}
+
+		equity := cash + shares*bar.Close
+		if cycle != nil {
+			for _, leg := range cycle.Legs {
+				equity += leg.Qty * leg.last * optionContractShares
+			}
+		}
+		result.EquityCurve = append(result.EquityCurve, EquityPoint{Timestamp: bar.Timestamp, Equity: equity})
+		if equity > peak {
+			peak = equity
+		}
+		if drawdown := (peak - equity) / peak * 100; drawdown > result.MaxDrawdownPct {
+			result.MaxDrawdownPct = drawdown
+		}
+		result.BarsProcessed++
+	}
+
+	if cycle != nil && result.BarsProcessed > 0 {
+		closeCycle(bars[result.BarsProcessed-1], CycleOpen)
+	}
+	if result.BarsProcessed > 0 {
+		result.FinalEquity = result.EquityCurve[len(result.EquityCurve)-1].Equity
+	} else {
+		result.FinalEquity = cfg.InitialCapital
+	}
+	result.TotalReturnPct = (result.FinalEquity - cfg.InitialCapital) / cfg.InitialCapital * 100
+	if len(result.Cycles) > 0 {
+		result.WinRate = float64(wins) / float64(len(result.Cycles)) * 100
+	}
+
+	be.logger.WithFields(logrus.Fields{
+		"structure":    cfg.Structure,
+		"underlying":   cfg.Underlying,
+		"bars":         result.BarsProcessed,
+		"cycles":       len(result.Cycles),
+		"skipped":      result.Skipped,
+		"return_pct":   result.TotalReturnPct,
+		"max_drawdown": result.MaxDrawdownPct,
+	}).Info("Options backtest complete")
+
+	return result, nil
+}
+
+// openCycle picks the contracts for a new cycle, loads their bars and
+// fills the entry. Covered calls buy the shares unless they are still held
+// from the last cycle.
+func (be *BacktestEngine) openCycle(ctx context.Context, cfg OptionsBacktestConfig, bar *interfaces.Bar, cash, shares *float64) (*optionsCycle, error) {
+	expiry := nextFriday(bar.Timestamp.AddDate(0, 0, cfg.DTE))
+	// Options stop trading at the close on their expiration date
+	expiryClose := time.Date(expiry.Year(), expiry.Month(), expiry.Day(), 0, 0, 0, 0, bar.Timestamp.Location())
+	contracts := float64(cfg.Contracts)
+
+	offset := bar.Close * cfg.StrikeOffsetPct / 100
+	roundStrike := func(price float64) float64 {
+		return math.Round(price/cfg.StrikeStep) * cfg.StrikeStep
+	}
+	var legs []OptionsLeg
+	switch cfg.Structure {
+	case StructureCoveredCall:
+		legs = []OptionsLeg{{Type: "call", Strike: roundStrike(bar.Close + offset), Qty: -contracts}}
+	case StructureBullCallSpread:
+		strike := roundStrike(bar.Close + offset)
+		legs = []OptionsLeg{{Type: "call", Strike: strike, Qty: contracts}, {Type: "call", Strike: strike + cfg.Width, Qty: -contracts}}
+	case StructureBearCallSpread:
+		strike := roundStrike(bar.Close + offset)
+		legs = []OptionsLeg{{Type: "call", Strike: strike, Qty: -contracts}, {Type: "call", Strike: strike + cfg.Width, Qty: contracts}}
+	case StructureBearPutSpread:
+		strike := roundStrike(bar.Close - offset)
+		legs = []OptionsLeg{{Type: "put", Strike: strike, Qty: contracts}, {Type: "put", Strike: strike - cfg.Width, Qty: -contracts}}
+	case StructureBullPutSpread:
+		strike := roundStrike(bar.Close - offset)
+		legs = []OptionsLeg{{Type: "put", Strike: strike, Qty: -contracts}, {Type: "put", Strike: strike - cfg.Width, Qty: contracts}}
+	}
+
+	day := bar.Timestamp.Format("2006-01-02")
+	for i := range legs {
+		leg := &legs[i]
+		leg.Symbol = OCCSymbol{Underlying: cfg.Underlying, Expiration: expiry, Type: leg.Type, Strike: leg.Strike}.String()
+		optionBars, err := be.dataService.GetHistoricalBars(ctx, leg.Symbol, bar.Timestamp, expiryClose.AddDate(0, 0, 1), "1Day")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load bars for %s: %w", leg.Symbol, err)
+		}
+		leg.prices = make(map[string]float64, len(optionBars))
+		for _, optionBar := range optionBars {
+			leg.prices[optionBar.Timestamp.Format("2006-01-02")] = optionBar.Close
+		}
+		price, ok := leg.prices[day]
+		if !ok {
+			return nil, fmt.Errorf("%s did not trade on the entry day", leg.Symbol)
+		}
+		leg.last = price
+	}
+
+	cycle := &optionsCycle{expiry: expiryClose}
+	cycle.EntryTime = bar.Timestamp
+	cycle.Expiration = expiry
+	cycle.UnderlyingEntry = bar.Close
+
+	// Cash needed: the shares of a covered call, the debit of a debit
+	// spread and the width less the credit of a credit spread
+	required := 0.0
+	buyShares := 0.0
+	if cfg.Structure == StructureCoveredCall {
+		cycle.Shares = contracts * optionContractShares
+		if *shares < cycle.Shares {
+			buyShares = cycle.Shares - *shares
+			required += buyShares*cfg.Fills.FillPrice("buy", bar.Close, nil) + cfg.Fills.Fees(cfg.Underlying, buyShares)
+		}
+	}
+	for i := range legs {
+		leg := &legs[i]
+		side := "buy"
+		if leg.Qty < 0 {
+			side = "sell"
+		}
+		leg.EntryPrice = cfg.Fills.FillPrice(side, leg.last, nil)
+		cycle.entryValue += leg.Qty * leg.EntryPrice * optionContractShares
+		cycle.Fees += cfg.Fills.Fees(leg.Symbol, math.Abs(leg.Qty))
+	}
+	switch {
+	case cfg.Structure == StructureCoveredCall:
+		cycle.maxProfit = -cycle.entryValue
+	case cycle.entryValue > 0:
+		// Debit spread
+		required += cycle.entryValue
+		cycle.maxProfit = cfg.Width*contracts*optionContractShares - cycle.entryValue
+	default:
+		// Credit spread: the width is held as collateral
+		required += cfg.Width*contracts*optionContractShares + cycle.entryValue
+		cycle.maxProfit = -cycle.entryValue
+	}
+	required += cycle.Fees
+	if required > *cash {
+		return nil, fmt.Errorf("%.2f needed to open %s but %.2f is available", required, cfg.Structure, *cash)
+	}
+
+	if buyShares > 0 {
+		price := cfg.Fills.FillPrice("buy", bar.Close, nil)
+		fees := cfg.Fills.Fees(cfg.Underlying, buyShares)
+		*cash -= buyShares*price + fees
+		cycle.Fees += fees
+		// Shares bought now are worth what was paid for them
+		cycle.shareBasis = buyShares * price
+		*shares += buyShares
+	}
+	cycle.shareBasis += (*shares - buyShares) * bar.Close
+	*cash -= cycle.entryValue + cycle.Fees - cfg.Fills.Fees(cfg.Underlying, buyShares)
+	cycle.Legs = legs
+
+	return cycle, nil
+}
+
+// markedOptionPnL is the options' P&L at their last closes
+func (c *optionsCycle) markedOptionPnL() float64 {
+	value := 0.0
+	for _, leg := range c.Legs {
+		value += leg.Qty * leg.last * optionContractShares
+	}
+	return value - c.entryValue
+}
+
+// intrinsicValue is what an option is worth at expiration
+func intrinsicValue(optionType string, strike, underlying float64) float64 {
+	if optionType == "put" {
+		return math.Max(strike-underlying, 0)
+	}
+	return math.Max(underlying-strike, 0)
+}
+
+// nextFriday returns the first Friday on or after t, as a date
+func nextFriday(t time.Time) time.Time {
+	days := (int(time.Friday) - int(t.Weekday()) + 7) % 7
+	d := t.AddDate(0, 0, days)
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+}
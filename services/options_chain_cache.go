@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// optionsQuoteBatchSize is how many contracts one options quote request asks for
+const optionsQuoteBatchSize = 100
+
+// OptionsQuoteSource fetches latest quotes for many option contracts at
+// once. Brokers implementing it let cached chains refresh just their quotes.
+type OptionsQuoteSource interface {
+	GetOptionsQuotes(ctx context.Context, symbols []string) (map[string]*interfaces.OptionsQuote, error)
+}
+
+// OptionsChain is a cached options chain for one underlying and expiration
+type OptionsChain struct {
+	Underlying string
+	Expiration time.Time
+	Contracts  []*interfaces.OptionContract // sorted by symbol
+	FetchedAt  time.Time                    // last full fetch, greeks included
+	QuotedAt   time.Time                    // last quote refresh
+	ModifiedAt time.Time                    // last time any field changed
+	Version    string                       // changes whenever the contracts do
+}
+
+// OptionsChainCache keeps parsed options chains per underlying and
+// expiration. Within chainTTL only bid, ask, last and volume are refreshed,
+// and no more often than quoteTTL. Brokers that cannot batch options quotes
+// refetch the whole chain once quoteTTL has passed.
+type OptionsChainCache struct {
+	trading  interfaces.TradingService
+	quotes   OptionsQuoteSource // nil when the broker cannot batch quotes
+	chainTTL time.Duration
+	flights  *ttlCache[*OptionsChain]
+	chains   map[string]*OptionsChain // latest chain per key, the base for quote refreshes
+	mu       sync.Mutex
+}
+
+// NewOptionsChainCache creates a chain cache over trading. quotes may be nil.
+func NewOptionsChainCache(trading interfaces.TradingService, quotes OptionsQuoteSource, chainTTL, quoteTTL time.Duration) *OptionsChainCache {
+	return &OptionsChainCache{
+		trading:  trading,
+		quotes:   quotes,
+		chainTTL: chainTTL,
+		flights:  newTTLCache[*OptionsChain](quoteTTL),
+		chains:   make(map[string]*OptionsChain),
+	}
+}
+
+// Get returns the chain for underlying and expiration, fetching or
+// refreshing it when it is stale. The result must not be modified.
+func (c *OptionsChainCache) Get(ctx context.Context, underlying string, expiration time.Time) (*OptionsChain, error) {
+	underlying = strings.ToUpper(underlying)
+	key := underlying + "|" + expiration.Format("2006-01-02")
+
+	return c.flights.get(ctx, key, func() (*OptionsChain, error) {
+		c.mu.Lock()
+		previous := c.chains[key]
+		c.mu.Unlock()
+
+		var chain *OptionsChain
+		var err error
+		if previous != nil && c.quotes != nil && time.Since(previous.FetchedAt) < c.chainTTL {
+			chain, err = c.refreshQuotes(ctx, previous)
+		} else {
+			chain, err = c.fetch(ctx, underlying, expiration, previous)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.chains[key] = chain
+		// Expired chains are never asked for again
+		for k, cached := range c.chains {
+			if time.Since(cached.Expiration) > 24*time.Hour {
+				delete(c.chains, k)
+			}
+		}
+		c.mu.Unlock()
+		return chain, nil
+	})
+}
+
+// fetch loads the whole chain from the broker
+func (c *OptionsChainCache) fetch(ctx context.Context, underlying string, expiration time.Time, previous *OptionsChain) (*OptionsChain, error) {
+	contracts, err := c.trading.GetOptionsChain(ctx, underlying, expiration)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].Symbol < contracts[j].Symbol })
+
+	now := time.Now()
+	chain := &OptionsChain{
+		Underlying: underlying,
+		Expiration: expiration,
+		Contracts:  contracts,
+		FetchedAt:  now,
+		QuotedAt:   now,
+		ModifiedAt: now,
+		Version:    chainVersion(contracts),
+	}
+	if previous != nil && previous.Version == chain.Version {
+		chain.ModifiedAt = previous.ModifiedAt
+	}
+	return chain, nil
+}
+
+// refreshQuotes copies previous with the latest quotes applied
+func (c *OptionsChainCache) refreshQuotes(ctx context.Context, previous *OptionsChain) (*OptionsChain, error) {
+	symbols := make([]string, len(previous.Contracts))
+	for i, contract := range previous.Contracts {
+		symbols[i] = contract.Symbol
+	}
+	quotes, err := c.quotes.GetOptionsQuotes(ctx, symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := *previous
+	chain.QuotedAt = time.Now()
+	chain.Contracts = make([]*interfaces.OptionContract, len(previous.Contracts))
+	for i, contract := range previous.Contracts {
+		updated := *contract
+		if quote, ok := quotes[contract.Symbol]; ok {
+			updated.Bid = quote.BidPrice
+			updated.Ask = quote.AskPrice
+			if quote.LastPrice > 0 {
+				updated.Premium = quote.LastPrice
+			}
+			if quote.Volume > 0 {
+				updated.Volume = quote.Volume
+			}
+		}
+		chain.Contracts[i] = &updated
+	}
+	chain.Version = chainVersion(chain.Contracts)
+	if chain.Version != previous.Version {
+		chain.ModifiedAt = chain.QuotedAt
+	}
+	return &chain, nil
+}
+
+// chainVersion hashes the fields clients see, so an unchanged chain keeps
+// its version across refreshes
+func chainVersion(contracts []*interfaces.OptionContract) string {
+	hash := sha256.New()
+	for _, c := range contracts {
+		fmt.Fprintf(hash, "%s|%s|%g|%d|%g|%g|%g|%d|%d|%g|%g|%g|%g|%g|%d\n",
+			c.Symbol, c.ContractType, c.StrikePrice, c.ExpirationDate.Unix(), c.Premium, c.Bid, c.Ask,
+			c.Volume, c.OpenInterest, c.ImpliedVolatility, c.Delta, c.Gamma, c.Theta, c.Vega, c.DTE)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// riskFreeRate is the annual rate the local option model discounts at
+const riskFreeRate = 0.045
+
+// Where a position's greeks came from
+const (
+	GreeksSourceQuote       = "quote"       // the broker's option snapshot
+	GreeksSourceModel       = "model"       // Black-Scholes from the position's mark
+	GreeksSourceUnavailable = "unavailable" // neither worked
+)
+
+// OptionGreeks are the greeks of one contract. Theta is per calendar day
+// and vega per volatility point, as quoted by Alpaca.
+type OptionGreeks struct {
+	Delta             float64 `json:"delta"`
+	Gamma             float64 `json:"gamma"`
+	Theta             float64 `json:"theta"`
+	Vega              float64 `json:"vega"`
+	ImpliedVolatility float64 `json:"implied_volatility"`
+	Source            string  `json:"source"`
+}
+
+// GreekExposure is net greeks in shares of the underlying: per-contract
+// greeks times contracts times 100, negative for shorts. DeltaDollars is
+// delta times the underlying price.
+type GreekExposure struct {
+	Delta        float64 `json:"delta"`
+	Gamma        float64 `json:"gamma"`
+	Theta        float64 `json:"theta"`
+	Vega         float64 `json:"vega"`
+	DeltaDollars float64 `json:"delta_dollars"`
+}
+
+func (e *GreekExposure) add(other GreekExposure) {
+	e.Delta += other.Delta
+	e.Gamma += other.Gamma
+	e.Theta += other.Theta
+	e.Vega += other.Vega
+	e.DeltaDollars += other.DeltaDollars
+}
+
+// OptionsPositionGreeks is an options position with its current greeks and
+// the exposure they give it
+type OptionsPositionGreeks struct {
+	*interfaces.OptionsPosition
+	UnderlyingPrice float64       `json:"underlying_price"`
+	DaysToExpiry    float64       `json:"days_to_expiry"`
+	Greeks          OptionGreeks  `json:"greeks"`
+	Exposure        GreekExposure `json:"exposure"`
+}
+
+// UnderlyingExposure is the net exposure of every option on one underlying
+type UnderlyingExposure struct {
+	Underlying      string  `json:"underlying"`
+	UnderlyingPrice float64 `json:"underlying_price"`
+	Positions       int     `json:"positions"`
+	Contracts       float64 `json:"contracts"` // signed, negative for net short
+	MarketValue     float64 `json:"market_value"`
+	UnrealizedPL    float64 `json:"unrealized_pl"`
+	GreekExposure
+}
+
+// OptionsExposure is the greek exposure of the options book per underlying
+// and in total
+type OptionsExposure struct {
+	GeneratedAt  time.Time             `json:"generated_at"`
+	Positions    int                   `json:"positions"`
+	MarketValue  float64               `json:"market_value"`
+	UnrealizedPL float64               `json:"unrealized_pl"`
+	Total        GreekExposure         `json:"total"`
+	Underlyings  []*UnderlyingExposure `json:"underlyings"`
+	Warnings     []string              `json:"warnings,omitempty"`
+}
+
+// OptionsExposureService enriches options positions with greeks, from the
+// option's snapshot when available and otherwise from a Black-Scholes model
+// fitted to the position's mark
+type OptionsExposureService struct {
+	tradingService interfaces.TradingService
+	dataService    interfaces.DataService
+	optionsData    *AlpacaOptionsDataService
+	logger         *logrus.Logger
+}
+
+// NewOptionsExposureService creates a new options exposure service.
+// optionsData may be nil, in which case every position is modelled.
+func NewOptionsExposureService(
+	tradingService interfaces.TradingService,
+	dataService interfaces.DataService,
+	optionsData *AlpacaOptionsDataService,
+) *OptionsExposureService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &OptionsExposureService{
+		tradingService: tradingService,
+		dataService:    dataService,
+		optionsData:    optionsData,
+		logger:         logger,
+	}
+}
+
+// ListPositions returns the open options positions with their greeks
+func (s *OptionsExposureService) ListPositions(ctx context.Context) ([]*OptionsPositionGreeks, error) {
+	positions, err := s.tradingService.ListOptionsPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	prices := make(map[string]float64)
+	result := make([]*OptionsPositionGreeks, 0, len(positions))
+	for _, p := range positions {
+		if p.Underlying == "" {
+			fillOptionDetails(p)
+		}
+		spot, ok := prices[p.Underlying]
+		if !ok {
+			if trade, err := s.dataService.GetLatestTrade(ctx, p.Underlying); err != nil {
+				s.logger.WithError(err).WithField("symbol", p.Underlying).Warn("Failed to get underlying price for option greeks")
+			} else {
+				spot = trade.Price
+			}
+			prices[p.Underlying] = spot
+		}
+
+		pg := &OptionsPositionGreeks{
+			OptionsPosition: p,
+			UnderlyingPrice: spot,
+			DaysToExpiry:    yearsToExpiry(p.Expiration, now) * 365,
+		}
+		pg.Greeks = s.greeks(ctx, p, spot, pg.DaysToExpiry/365)
+		if pg.Greeks.Source != GreeksSourceUnavailable {
+			contracts := signedContracts(p)
+			pg.Exposure = GreekExposure{
+				Delta:        pg.Greeks.Delta * contracts * 100,
+				Gamma:        pg.Greeks.Gamma * contracts * 100,
+				Theta:        pg.Greeks.Theta * contracts * 100,
+				Vega:         pg.Greeks.Vega * contracts * 100,
+				DeltaDollars: pg.Greeks.Delta * contracts * 100 * spot,
+			}
+		}
+		result = append(result, pg)
+	}
+
+	return result, nil
+}
+
+// Exposure aggregates net delta, gamma, theta and vega per underlying and
+// across the options book. Positions without greeks are counted but add
+// no exposure, and are listed in the warnings.
+func (s *OptionsExposureService) Exposure(ctx context.Context) (*OptionsExposure, error) {
+	positions, err := s.ListPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exposure := &OptionsExposure{
+		GeneratedAt: time.Now(),
+		Positions:   len(positions),
+		Underlyings: make([]*UnderlyingExposure, 0),
+	}
+	byUnderlying := make(map[string]*UnderlyingExposure)
+	for _, p := range positions {
+		u, ok := byUnderlying[p.Underlying]
+		if !ok {
+			u = &UnderlyingExposure{Underlying: p.Underlying, UnderlyingPrice: p.UnderlyingPrice}
+			byUnderlying[p.Underlying] = u
+			exposure.Underlyings = append(exposure.Underlyings, u)
+		}
+		u.Positions++
+		u.Contracts += signedContracts(p.OptionsPosition)
+		u.MarketValue += p.MarketValue.InexactFloat64()
+		u.UnrealizedPL += p.UnrealizedPL.InexactFloat64()
+		u.add(p.Exposure)
+
+		if p.Greeks.Source == GreeksSourceUnavailable {
+			exposure.Warnings = append(exposure.Warnings, fmt.Sprintf("no greeks for %s", p.Symbol))
+		}
+	}
+
+	for _, u := range exposure.Underlyings {
+		exposure.MarketValue += u.MarketValue
+		exposure.UnrealizedPL += u.UnrealizedPL
+		exposure.Total.add(u.GreekExposure)
+	}
+
+	// Largest dollar delta first
+	sort.Slice(exposure.Underlyings, func(i, j int) bool {
+		return math.Abs(exposure.Underlyings[i].DeltaDollars) > math.Abs(exposure.Underlyings[j].DeltaDollars)
+	})
+
+	return exposure, nil
+}
+
+// greeks takes a position's greeks from its snapshot, falling back to the
+// model when the snapshot fails or carries none
+func (s *OptionsExposureService) greeks(ctx context.Context, p *interfaces.OptionsPosition, spot, years float64) OptionGreeks {
+	var iv float64
+	if s.optionsData != nil {
+		contract, err := s.optionsData.GetOptionSnapshot(ctx, p.Symbol)
+		if err != nil {
+			s.logger.WithError(err).WithField("symbol", p.Symbol).Warn("Failed to get option greeks, using the model")
+		} else if contract.Delta != 0 || contract.Gamma != 0 || contract.Vega != 0 {
+			return OptionGreeks{
+				Delta:             contract.Delta,
+				Gamma:             contract.Gamma,
+				Theta:             contract.Theta,
+				Vega:              contract.Vega,
+				ImpliedVolatility: contract.ImpliedVolatility,
+				Source:            GreeksSourceQuote,
+			}
+		} else {
+			iv = contract.ImpliedVolatility
+		}
+	}
+
+	if spot <= 0 || p.Strike <= 0 || years <= 0 {
+		return OptionGreeks{Source: GreeksSourceUnavailable}
+	}
+	call := p.OptionType != "put"
+	if iv <= 0 {
+		iv = impliedVolatility(call, p.CurrentPrice.InexactFloat64(), spot, p.Strike, years, riskFreeRate)
+	}
+	if iv <= 0 {
+		return OptionGreeks{Source: GreeksSourceUnavailable}
+	}
+
+	greeks := blackScholesGreeks(call, spot, p.Strike, years, riskFreeRate, iv)
+	greeks.Source = GreeksSourceModel
+	return greeks
+}
+
+// yearsToExpiry is the time left until the 4pm ET close on the expiration
+// date, at least one hour
+func yearsToExpiry(expiration, now time.Time) float64 {
+	if expiration.IsZero() {
+		return 0
+	}
+	expiry := time.Date(expiration.Year(), expiration.Month(), expiration.Day(), 16, 0, 0, 0, marketLocation)
+	left := expiry.Sub(now)
+	if left < time.Hour {
+		left = time.Hour
+	}
+	return left.Hours() / 24 / 365
+}
+
+// signedContracts is a position's contract count, negative for shorts
+func signedContracts(p *interfaces.OptionsPosition) float64 {
+	qty := p.Qty.Abs().InexactFloat64()
+	if p.Side == "short" || p.Qty.IsNegative() {
+		return -qty
+	}
+	return qty
+}
+
+// blackScholesPrice prices a European option on a non-dividend stock
+func blackScholesPrice(call bool, spot, strike, years, rate, vol float64) float64 {
+	d1, d2 := blackScholesD(spot, strike, years, rate, vol)
+	discount := strike * math.Exp(-rate*years)
+	if call {
+		return spot*normCDF(d1) - discount*normCDF(d2)
+	}
+	return discount*normCDF(-d2) - spot*normCDF(-d1)
+}
+
+// blackScholesGreeks returns the model greeks, theta per calendar day and
+// vega per volatility point
+func blackScholesGreeks(call bool, spot, strike, years, rate, vol float64) OptionGreeks {
+	d1, d2 := blackScholesD(spot, strike, years, rate, vol)
+	sqrtT := math.Sqrt(years)
+	discount := strike * math.Exp(-rate*years)
+	decay := -spot * normPDF(d1) * vol / (2 * sqrtT)
+
+	greeks := OptionGreeks{
+		Gamma:             normPDF(d1) / (spot * vol * sqrtT),
+		Vega:              spot * normPDF(d1) * sqrtT / 100,
+		ImpliedVolatility: vol,
+	}
+	if call {
+		greeks.Delta = normCDF(d1)
+		greeks.Theta = (decay - rate*discount*normCDF(d2)) / 365
+	} else {
+		greeks.Delta = normCDF(d1) - 1
+		greeks.Theta = (decay + rate*discount*normCDF(-d2)) / 365
+	}
+	return greeks
+}
+
+func blackScholesD(spot, strike, years, rate, vol float64) (float64, float64) {
+	volT := vol * math.Sqrt(years)
+	d1 := (math.Log(spot/strike) + (rate+vol*vol/2)*years) / volT
+	return d1, d1 - volT
+}
+
+// impliedVolatility solves the model for the volatility that gives price,
+// by bisection between 1% and 500%. It returns 0 when no volatility in
+// that range does, such as for a price below intrinsic value.
+func impliedVolatility(call bool, price, spot, strike, years, rate float64) float64 {
+	low, high := 0.01, 5.0
+	if price <= 0 || price < blackScholesPrice(call, spot, strike, years, rate, low) || price > blackScholesPrice(call, spot, strike, years, rate, high) {
+		return 0
+	}
+	for i := 0; i < 100 && high-low > 1e-6; i++ {
+		mid := (low + high) / 2
+		if blackScholesPrice(call, spot, strike, years, rate, mid) < price {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
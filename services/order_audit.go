@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Order sources recorded in the audit trail
+const (
+	OrderSourceManual          = "manual"
+	OrderSourceWebhook         = "webhook"
+	OrderSourceStrategy        = "strategy"
+	OrderSourceAI              = "ai"
+	OrderSourcePositionManager = "position_manager"
+	OrderSourceDCA             = "dca"
+	OrderSourceRebalance       = "rebalance"
+	OrderSourceGrid            = "grid"
+	OrderSourceFlatten         = "flatten"
+	OrderSourceAlgo            = "algo"
+	OrderSourceConditional     = "conditional"
+)
+
+// Order audit outcomes
+const (
+	AuditOutcomeSubmitted = "submitted"
+	AuditOutcomeFailed    = "failed"
+	AuditOutcomeRejected  = "rejected"
+	AuditOutcomeDryRun    = "dry_run"
+	AuditOutcomeQueued    = "queued" // held in the local order queue until the open
+	AuditOutcomeShadow    = "shadow" // simulated in shadow mode, never sent to the broker
+)
+
+type orderSourceKey struct{}
+type riskDecisionKey struct{}
+
+// WithOrderSource tags orders placed with ctx with their originating source
+func WithOrderSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, orderSourceKey{}, source)
+}
+
+// OrderSourceFrom returns the order source carried by ctx, defaulting to manual
+func OrderSourceFrom(ctx context.Context) string {
+	if source, ok := ctx.Value(orderSourceKey{}).(string); ok && source != "" {
+		return source
+	}
+	return OrderSourceManual
+}
+
+// WithRiskDecision attaches the risk outcome so it is captured with the order
+func WithRiskDecision(ctx context.Context, decision *RiskDecision) context.Context {
+	return context.WithValue(ctx, riskDecisionKey{}, decision)
+}
+
+func riskDecisionFrom(ctx context.Context) *RiskDecision {
+	decision, _ := ctx.Value(riskDecisionKey{}).(*RiskDecision)
+	return decision
+}
+
+// OrderAttempt describes an order attempt to record
+type OrderAttempt struct {
+	OrderID    string
+	Symbol     string
+	Side       string
+	AssetClass string
+	Outcome    string
+	Request    interface{}
+	Response   interface{}
+	Risk       *RiskDecision
+	Err        error
+	Latency    time.Duration
+	Tags       []string
+	Metadata   map[string]string
+}
+
+// OrderAuditor persists order attempts
+type OrderAuditor struct {
+	storage *database.LocalStorage
+	logger  *logrus.Logger
+}
+
+// NewOrderAuditor creates a new order auditor
+func NewOrderAuditor(storage *database.LocalStorage) *OrderAuditor {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &OrderAuditor{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// Record stores an order attempt. Failures are logged, never returned, so
+// auditing cannot block trading.
+func (oa *OrderAuditor) Record(ctx context.Context, attempt OrderAttempt) {
+	if oa == nil {
+		return
+	}
+
+	audit := &models.DBOrderAudit{
+		OrderID:         attempt.OrderID,
+		Source:          OrderSourceFrom(ctx),
+		Book:            BookFrom(ctx),
+		Symbol:          attempt.Symbol,
+		Side:            attempt.Side,
+		AssetClass:      attempt.AssetClass,
+		Outcome:         attempt.Outcome,
+		RequestPayload:  marshalAudit(attempt.Request),
+		ResponsePayload: marshalAudit(attempt.Response),
+		LatencyMs:       attempt.Latency.Milliseconds(),
+		Tags:            database.EncodeOrderTags(attempt.Tags),
+		Metadata:        database.EncodeOrderMetadata(attempt.Metadata),
+	}
+	if attempt.Risk != nil {
+		audit.RiskDecision = marshalAudit(attempt.Risk)
+	}
+	if attempt.Err != nil {
+		audit.Error = attempt.Err.Error()
+	}
+
+	if err := oa.storage.SaveOrderAudit(audit); err != nil {
+		oa.logger.WithError(err).WithField("symbol", attempt.Symbol).Error("Failed to save order audit")
+	}
+}
+
+// RecordUnsubmitted stores an equity order that never reached the broker
+// because it was rejected by risk checks or placed in dry-run mode
+func (oa *OrderAuditor) RecordUnsubmitted(ctx context.Context, order *interfaces.Order, outcome string, decision *RiskDecision, err error) {
+	oa.Record(ctx, OrderAttempt{
+		Symbol:     order.Symbol,
+		Side:       order.Side,
+		AssetClass: "us_equity",
+		Outcome:    outcome,
+		Request:    BuildOrderRequest(order),
+		Risk:       decision,
+		Err:        err,
+		Tags:       order.Tags,
+		Metadata:   order.Metadata,
+	})
+}
+
+// GetOrderAudits returns the audit trail for an order
+func (oa *OrderAuditor) GetOrderAudits(orderID string) ([]*models.DBOrderAudit, error) {
+	return oa.storage.GetOrderAudits(orderID)
+}
+
+func marshalAudit(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// AuditedTradingService wraps a TradingService and records every order
+// submission with its request, response, latency and risk outcome
+type AuditedTradingService struct {
+	interfaces.TradingService
+	auditor *OrderAuditor
+}
+
+// NewAuditedTradingService wraps trading with order auditing
+func NewAuditedTradingService(trading interfaces.TradingService, auditor *OrderAuditor) *AuditedTradingService {
+	return &AuditedTradingService{
+		TradingService: trading,
+		auditor:        auditor,
+	}
+}
+
+// PlaceOrder submits an order and records the attempt
+func (s *AuditedTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	start := time.Now()
+	result, err := s.TradingService.PlaceOrder(ctx, order)
+
+	attempt := OrderAttempt{
+		Symbol:     order.Symbol,
+		Side:       order.Side,
+		AssetClass: "us_equity",
+		Outcome:    AuditOutcomeSubmitted,
+		Request:    BuildOrderRequest(order),
+		Risk:       riskDecisionFrom(ctx),
+		Err:        err,
+		Latency:    time.Since(start),
+		Tags:       order.Tags,
+		Metadata:   order.Metadata,
+	}
+	if err != nil {
+		attempt.Outcome = AuditOutcomeFailed
+	} else {
+		attempt.OrderID = result.OrderID
+		attempt.Response = result
+	}
+	s.auditor.Record(ctx, attempt)
+
+	return result, err
+}
+
+// PlaceOptionsOrder submits an options order and records the attempt
+func (s *AuditedTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	start := time.Now()
+	result, err := s.TradingService.PlaceOptionsOrder(ctx, order)
+
+	attempt := OrderAttempt{
+		Symbol:     order.Symbol,
+		Side:       order.Side,
+		AssetClass: "us_option",
+		Outcome:    AuditOutcomeSubmitted,
+		Request:    BuildOptionsOrderRequest(order),
+		Risk:       riskDecisionFrom(ctx),
+		Err:        err,
+		Latency:    time.Since(start),
+		Tags:       order.Tags,
+		Metadata:   order.Metadata,
+	}
+	if err != nil {
+		attempt.Outcome = AuditOutcomeFailed
+	} else {
+		attempt.OrderID = result.OrderID
+		attempt.Response = result
+	}
+	s.auditor.Record(ctx, attempt)
+
+	return result, err
+}
@@ -0,0 +1,476 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Prices a pegged limit follows
+const (
+	PegMid = "mid"
+	PegBid = "bid"
+	PegAsk = "ask"
+)
+
+// What a chase does once its timeout passes
+const (
+	PegFallbackMarket = "market" // cancel the limit and send the rest at market
+	PegFallbackCancel = "cancel" // cancel the limit and leave the rest unfilled
+)
+
+// Chase statuses
+const (
+	ChaseWorking  = "CHASING"
+	ChaseFilled   = "FILLED"
+	ChaseMarket   = "MARKET"   // the timeout passed and the rest went out at market
+	ChaseCanceled = "CANCELED" // canceled by request, by the broker or at the timeout
+	ChaseFailed   = "FAILED"   // a replacement or the market fallback was refused
+)
+
+// Steps a chase waits on the broker to cancel its order for
+const (
+	chaseStepRepeg  = "repeg"
+	chaseStepMarket = "market"
+	chaseStepCancel = "cancel"
+)
+
+const (
+	pegDefaultTimeout = time.Minute
+	chaseHistory      = 100 // finished chases kept for listing
+)
+
+var (
+	pegTick      = decimal.New(1, -2) // price increment at $1 and above
+	pegTickPenny = decimal.New(1, -4) // price increment below $1
+)
+
+// PegRequest asks for a limit order that follows the quote until it fills
+type PegRequest struct {
+	Reference      string          `json:"reference,omitempty" binding:"omitempty,oneof=mid bid ask"`    // price the limit follows, mid by default
+	Offset         decimal.Decimal `json:"offset"`                                                       // dollars added to a buy's reference and taken from a sell's
+	MaxChase       decimal.Decimal `json:"max_chase" binding:"gte=0"`                                    // furthest the limit may move from its first price, in dollars, zero for no limit
+	TimeoutSeconds int             `json:"timeout_seconds,omitempty" binding:"omitempty,min=1,max=3600"` // when the fallback happens, 60 by default
+	Fallback       string          `json:"fallback,omitempty" binding:"omitempty,oneof=market cancel"`   // market by default
+}
+
+// OrderChase is a pegged limit order being re-priced as the quote moves
+type OrderChase struct {
+	ID            string           `json:"id"`       // the first order's ID
+	OrderID       string           `json:"order_id"` // the order working now, or the last one
+	MarketOrderID string           `json:"market_order_id,omitempty"`
+	Symbol        string           `json:"symbol"`
+	Side          string           `json:"side"`
+	Qty           decimal.Decimal  `json:"qty"`
+	Book          string           `json:"book"`
+	Peg           PegRequest       `json:"peg"`
+	StartPrice    decimal.Decimal  `json:"start_price"`
+	LimitPrice    decimal.Decimal  `json:"limit_price"`
+	Bound         *decimal.Decimal `json:"bound,omitempty"` // the highest a buy or lowest a sell may be priced
+	Repegs        int              `json:"repegs"`
+	FilledQty     decimal.Decimal  `json:"filled_qty"` // filled at a limit, across every re-pegged order
+	Status        string           `json:"status"`
+	Reason        string           `json:"reason,omitempty"`
+	StartedAt     time.Time        `json:"started_at"`
+	Deadline      time.Time        `json:"deadline"`
+	EndedAt       *time.Time       `json:"ended_at,omitempty"`
+
+	ctx         context.Context   // carries the book, source and risk decision of the first order
+	order       *interfaces.Order // the order working now
+	priorFilled decimal.Decimal   // filled by orders canceled for a re-peg
+	step        string            // waiting for the broker to cancel before this step
+}
+
+// OrderChaser works pegged limit orders. Each limit starts at the quote's
+// mid, bid or ask plus an offset and is re-priced when the quote moves,
+// in place when the broker can replace orders and otherwise by canceling
+// and placing the rest again. The limit never goes past the max chase
+// distance. Once the timeout passes the rest is sent at market or left
+// unfilled. Chases are kept in memory: after a restart a chased order
+// rests as a plain limit order.
+type OrderChaser struct {
+	trading     interfaces.TradingService
+	dataService interfaces.DataService
+	storage     *database.LocalStorage
+	replacer    OrderReplacer // nil re-pegs by canceling and placing again
+
+	mu       sync.Mutex
+	active   []*OrderChase
+	finished []*OrderChase
+	logger   *logrus.Logger
+}
+
+// OrderReplacer is implemented by brokers that can move an open order's
+// limit price, such as Alpaca. The replacement may have a new order ID.
+type OrderReplacer interface {
+	ReplaceOrder(ctx context.Context, order *interfaces.Order, limitPrice decimal.Decimal) (*interfaces.Order, error)
+}
+
+// NewOrderChaser creates an order chaser placing orders through trading
+func NewOrderChaser(trading interfaces.TradingService, dataService interfaces.DataService, storage *database.LocalStorage) *OrderChaser {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &OrderChaser{
+		trading:     trading,
+		dataService: dataService,
+		storage:     storage,
+		logger:      logger,
+	}
+}
+
+// SetOrderReplacer re-pegs orders in place instead of canceling them and
+// placing the rest again
+func (c *OrderChaser) SetOrderReplacer(replacer OrderReplacer) {
+	c.replacer = replacer
+}
+
+// PegPrice returns the limit price peg gives a side of symbol now, rounded
+// to the price increment in the order's favour
+func (c *OrderChaser) PegPrice(ctx context.Context, symbol, side string, peg *PegRequest) (decimal.Decimal, error) {
+	quote, err := c.dataService.GetLatestQuote(ctx, symbol)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("no quote for %s: %w", symbol, err)
+	}
+	if quote.BidPrice <= 0 || quote.AskPrice <= 0 {
+		return decimal.Zero, fmt.Errorf("no two-sided quote for %s", symbol)
+	}
+
+	bid := decimal.NewFromFloat(quote.BidPrice)
+	ask := decimal.NewFromFloat(quote.AskPrice)
+	var price decimal.Decimal
+	switch peg.Reference {
+	case PegBid:
+		price = bid
+	case PegAsk:
+		price = ask
+	default:
+		price = bid.Add(ask).Div(decimal.NewFromInt(2))
+	}
+
+	tick := pegTick
+	if price.LessThan(decimal.NewFromInt(1)) {
+		tick = pegTickPenny
+	}
+	if side == "buy" {
+		price = price.Add(peg.Offset).Div(tick).Floor().Mul(tick)
+	} else {
+		price = price.Sub(peg.Offset).Div(tick).Ceil().Mul(tick)
+	}
+	if !price.IsPositive() {
+		return decimal.Zero, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("peg offset %s leaves no positive price for %s", peg.Offset.String(), symbol))
+	}
+	return price, nil
+}
+
+// Chase starts re-pegging an order placed at its first pegged price. ctx
+// should carry the risk decision the order was placed with.
+func (c *OrderChaser) Chase(ctx context.Context, order *interfaces.Order, peg *PegRequest) *OrderChase {
+	now := time.Now()
+	settings := pegDefaults(peg)
+	chase := &OrderChase{
+		ID:         order.ID,
+		OrderID:    order.ID,
+		Symbol:     order.Symbol,
+		Side:       order.Side,
+		Qty:        order.Qty,
+		Book:       bookOrDefault(order.Book),
+		Peg:        settings,
+		StartPrice: *order.LimitPrice,
+		LimitPrice: *order.LimitPrice,
+		Status:     ChaseWorking,
+		StartedAt:  now,
+		Deadline:   now.Add(time.Duration(settings.TimeoutSeconds) * time.Second),
+		ctx:        context.WithoutCancel(ctx),
+		order:      order,
+	}
+	if settings.MaxChase.IsPositive() {
+		bound := chase.StartPrice.Add(settings.MaxChase)
+		if order.Side == "sell" {
+			bound = chase.StartPrice.Sub(settings.MaxChase)
+		}
+		chase.Bound = &bound
+	}
+
+	c.mu.Lock()
+	c.active = append(c.active, chase)
+	view := *chase
+	c.mu.Unlock()
+
+	c.logger.WithFields(logrus.Fields{
+		"order_id": order.ID,
+		"symbol":   order.Symbol,
+		"side":     order.Side,
+		"limit":    chase.LimitPrice.String(),
+		"deadline": chase.Deadline,
+	}).Info("Chasing pegged order")
+	return &view
+}
+
+// Chases returns the chases in progress followed by the most recent
+// finished ones, newest first
+func (c *OrderChaser) Chases() []*OrderChase {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chases := make([]*OrderChase, 0, len(c.active)+len(c.finished))
+	for i := len(c.active) - 1; i >= 0; i-- {
+		view := *c.active[i]
+		chases = append(chases, &view)
+	}
+	for i := len(c.finished) - 1; i >= 0; i-- {
+		view := *c.finished[i]
+		chases = append(chases, &view)
+	}
+	return chases
+}
+
+// Run re-pegs every chased order whose quote has moved, finishes the ones
+// that filled or were canceled, and falls back on the ones past their
+// timeout
+func (c *OrderChaser) Run(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	active := c.active[:0]
+	for _, chase := range c.active {
+		c.advance(chase, now)
+		if chase.Status == ChaseWorking {
+			active = append(active, chase)
+			continue
+		}
+		c.finished = append(c.finished, chase)
+	}
+	c.active = active
+	if len(c.finished) > chaseHistory {
+		c.finished = c.finished[len(c.finished)-chaseHistory:]
+	}
+	return nil
+}
+
+// advance moves one chase along. The caller holds c.mu.
+func (c *OrderChaser) advance(chase *OrderChase, now time.Time) {
+	ctx := chase.ctx
+	logger := c.logger.WithFields(logrus.Fields{"id": chase.ID, "order_id": chase.OrderID, "symbol": chase.Symbol})
+
+	order, err := c.trading.GetOrder(ctx, chase.OrderID)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to get chased order")
+		return
+	}
+	chase.FilledQty = chase.priorFilled.Add(order.FilledQty)
+	remaining := chase.Qty.Sub(chase.FilledQty)
+
+	status := strings.ToLower(order.Status)
+	switch status {
+	case "filled":
+		c.finish(chase, ChaseFilled, "")
+		return
+	case "canceled", "expired", "rejected", "done_for_day", "replaced":
+		step := chase.step
+		chase.step = ""
+		chase.priorFilled = chase.FilledQty
+		switch {
+		case !remaining.IsPositive():
+			c.finish(chase, ChaseFilled, "")
+		case step == chaseStepRepeg:
+			c.repegByPlacing(chase, remaining)
+		case step == chaseStepMarket:
+			c.placeMarket(chase, remaining)
+		case step == chaseStepCancel:
+			c.finish(chase, ChaseCanceled, fmt.Sprintf("timed out with %s of %s filled", chase.FilledQty.String(), chase.Qty.String()))
+		default:
+			c.finish(chase, ChaseCanceled, "order "+status)
+		}
+		return
+	}
+
+	chase.order = order
+	if chase.step != "" {
+		return // waiting for the broker to cancel
+	}
+
+	if !now.Before(chase.Deadline) {
+		chase.step = chaseStepMarket
+		if chase.Peg.Fallback == PegFallbackCancel {
+			chase.step = chaseStepCancel
+		}
+		if err := c.trading.CancelOrder(ctx, chase.OrderID); err != nil {
+			logger.WithError(err).Warn("Failed to cancel chased order at its timeout")
+			chase.step = ""
+		}
+		return
+	}
+
+	target, err := c.target(ctx, chase)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to price chased order")
+		return
+	}
+	if target.Equal(chase.LimitPrice) {
+		return
+	}
+
+	// A partly filled order is canceled and its rest placed again, since
+	// replacing it would leave what the replacement covers unclear
+	if c.replacer == nil || order.FilledQty.IsPositive() {
+		chase.step = chaseStepRepeg
+		if err := c.trading.CancelOrder(ctx, chase.OrderID); err != nil {
+			logger.WithError(err).Warn("Failed to cancel chased order for a re-peg")
+			chase.step = ""
+		}
+		return
+	}
+
+	replaced, err := c.replacer.ReplaceOrder(ctx, order, target)
+	if err != nil {
+		// Most often the order filled in the meantime, which the next run sees
+		logger.WithError(err).Warn("Failed to re-peg chased order")
+		return
+	}
+	c.repegged(chase, replaced, target)
+}
+
+// target is the chase's pegged price now, held within its bound
+func (c *OrderChaser) target(ctx context.Context, chase *OrderChase) (decimal.Decimal, error) {
+	price, err := c.PegPrice(ctx, chase.Symbol, chase.Side, &chase.Peg)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if chase.Bound != nil {
+		if chase.Side == "buy" {
+			price = decimal.Min(price, *chase.Bound)
+		} else {
+			price = decimal.Max(price, *chase.Bound)
+		}
+	}
+	return price, nil
+}
+
+// repegByPlacing places the rest of a chase at its current pegged price
+// once the previous order is canceled
+func (c *OrderChaser) repegByPlacing(chase *OrderChase, remaining decimal.Decimal) {
+	target, err := c.target(chase.ctx, chase)
+	if err != nil {
+		// Without a price the rest goes straight to the fallback
+		c.logger.WithError(err).WithField("id", chase.ID).Warn("Failed to price chased order")
+		if chase.Peg.Fallback == PegFallbackMarket {
+			c.placeMarket(chase, remaining)
+		} else {
+			c.finish(chase, ChaseCanceled, err.Error())
+		}
+		return
+	}
+
+	next := c.childOrder(chase, remaining)
+	next.Type = "limit"
+	next.LimitPrice = &target
+	result, err := c.trading.PlaceOrder(chase.ctx, next)
+	if err != nil {
+		c.finish(chase, ChaseFailed, "re-peg refused: "+err.Error())
+		return
+	}
+	next.ID = result.OrderID
+	next.Status = result.Status
+	c.repegged(chase, next, target)
+}
+
+// repegged records the order now working for a chase
+func (c *OrderChaser) repegged(chase *OrderChase, order *interfaces.Order, price decimal.Decimal) {
+	order.Strategy = chase.order.Strategy
+	order.Book = chase.order.Book
+	order.Tags = chase.order.Tags
+	order.Metadata = chase.order.Metadata
+	if err := c.storage.SaveOrder(order); err != nil {
+		c.logger.WithError(err).Warn("Failed to save order to database")
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"id":       chase.ID,
+		"order_id": order.ID,
+		"from":     chase.LimitPrice.String(),
+		"to":       price.String(),
+	}).Info("Re-pegged chased order")
+
+	chase.order = order
+	chase.OrderID = order.ID
+	chase.LimitPrice = price
+	chase.Repegs++
+}
+
+// placeMarket sends the rest of a chase at market
+func (c *OrderChaser) placeMarket(chase *OrderChase, remaining decimal.Decimal) {
+	market := c.childOrder(chase, remaining)
+	market.Type = "market"
+	result, err := c.trading.PlaceOrder(chase.ctx, market)
+	if err != nil {
+		c.finish(chase, ChaseFailed, "market fallback refused: "+err.Error())
+		return
+	}
+
+	market.ID = result.OrderID
+	market.Status = result.Status
+	if err := c.storage.SaveOrder(market); err != nil {
+		c.logger.WithError(err).Warn("Failed to save order to database")
+	}
+	chase.MarketOrderID = result.OrderID
+	c.finish(chase, ChaseMarket, fmt.Sprintf("timed out with %s of %s filled, sent %s at market", chase.FilledQty.String(), chase.Qty.String(), remaining.String()))
+}
+
+// childOrder copies a chase's order for the quantity left
+func (c *OrderChaser) childOrder(chase *OrderChase, qty decimal.Decimal) *interfaces.Order {
+	return &interfaces.Order{
+		Symbol:      chase.Symbol,
+		Qty:         qty,
+		Side:        chase.Side,
+		TimeInForce: chase.order.TimeInForce,
+		Status:      "pending",
+		SubmittedAt: time.Now(),
+		Strategy:    chase.order.Strategy,
+		Book:        chase.order.Book,
+		Tags:        chase.order.Tags,
+		Metadata:    chase.order.Metadata,
+	}
+}
+
+// finish ends a chase
+func (c *OrderChaser) finish(chase *OrderChase, status, reason string) {
+	now := time.Now()
+	chase.Status = status
+	chase.Reason = reason
+	chase.EndedAt = &now
+
+	c.logger.WithFields(logrus.Fields{
+		"id":         chase.ID,
+		"symbol":     chase.Symbol,
+		"status":     status,
+		"filled_qty": chase.FilledQty.String(),
+		"repegs":     chase.Repegs,
+		"reason":     reason,
+	}).Info("Chase finished")
+}
+
+// pegDefaults fills in the defaults of a peg request
+func pegDefaults(peg *PegRequest) PegRequest {
+	settings := *peg
+	if settings.Reference == "" {
+		settings.Reference = PegMid
+	}
+	if settings.Fallback == "" {
+		settings.Fallback = PegFallbackMarket
+	}
+	if settings.TimeoutSeconds == 0 {
+		settings.TimeoutSeconds = int(pegDefaultTimeout / time.Second)
+	}
+	return settings
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Trade update events that carry an execution
+const (
+	FillEventPartial = "partial_fill"
+	FillEventFill    = "fill"
+)
+
+// Reconnect delays of the trade updates stream
+const (
+	tradeUpdatesMinBackoff = time.Second
+	tradeUpdatesMaxBackoff = time.Minute
+)
+
+// TradeUpdate is one event from a broker's order updates stream
+type TradeUpdate struct {
+	Event       string            // "new", "partial_fill", "fill", "canceled", ...
+	Order       *interfaces.Order // the order as of this event
+	ExecutionID string            // set on fills
+	Price       decimal.Decimal   // execution price, set on fills
+	Qty         decimal.Decimal   // execution quantity, set on fills
+	At          time.Time
+}
+
+// TradeUpdateStream is implemented by brokers that push order updates as
+// they happen, such as Alpaca's trade_updates stream. StreamTradeUpdates
+// replays events after since and blocks until ctx is cancelled or the
+// connection drops.
+type TradeUpdateStream interface {
+	StreamTradeUpdates(ctx context.Context, since time.Time, handle func(TradeUpdate)) error
+}
+
+// FillTracker records each execution of an order and keeps the stored
+// order's filled quantity, average price and status current. With a trade
+// updates stream fills are recorded as they happen; without one, or while
+// it is disconnected, they are inferred from the rise in an order's filled
+// quantity each time it is polled.
+type FillTracker struct {
+	storage *database.LocalStorage
+	monitor *LiveEventMonitor // nil publishes no order updates from the stream
+
+	mu        sync.Mutex
+	streaming bool // the stream is connected, so polled orders aren't used
+	logger    *logrus.Logger
+}
+
+// NewFillTracker creates a fill tracker storing fills in storage
+func NewFillTracker(storage *database.LocalStorage) *FillTracker {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &FillTracker{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// SetLiveEvents publishes streamed order updates as order_update events
+// without waiting for the next poll
+func (t *FillTracker) SetLiveEvents(monitor *LiveEventMonitor) {
+	t.monitor = monitor
+}
+
+// Run streams trade updates until ctx is cancelled, reconnecting with
+// backoff and resuming after the last event received
+func (t *FillTracker) Run(ctx context.Context, stream TradeUpdateStream) {
+	var since time.Time
+	backoff := tradeUpdatesMinBackoff
+	for {
+		t.setStreaming(true)
+		err := stream.StreamTradeUpdates(ctx, since, func(update TradeUpdate) {
+			since = update.At
+			backoff = tradeUpdatesMinBackoff
+			t.HandleTradeUpdate(update)
+		})
+		t.setStreaming(false)
+		if ctx.Err() != nil {
+			return
+		}
+		t.logger.WithError(err).WithField("retry_in", backoff).Warn("Trade updates stream disconnected")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > tradeUpdatesMaxBackoff {
+			backoff = tradeUpdatesMaxBackoff
+		}
+	}
+}
+
+func (t *FillTracker) setStreaming(streaming bool) {
+	t.mu.Lock()
+	t.streaming = streaming
+	t.mu.Unlock()
+}
+
+// HandleTradeUpdate records the execution carried by a fill or partial fill
+// and publishes the order's new state
+func (t *FillTracker) HandleTradeUpdate(update TradeUpdate) {
+	if update.Order == nil {
+		return
+	}
+	if update.Event == FillEventPartial || update.Event == FillEventFill {
+		t.mu.Lock()
+		t.record(update.Order, update.Event, update.ExecutionID, update.Qty, update.Price, update.At, false)
+		t.mu.Unlock()
+	}
+	if t.monitor != nil {
+		t.monitor.TrackOrder(update.Order)
+	}
+}
+
+// ObserveOrder infers an execution when a polled order has filled more
+// than the fills recorded for it. The execution's price is whatever moves
+// the recorded average to the broker's. Nothing is inferred while the
+// stream is connected, since it reports the executions themselves.
+func (t *FillTracker) ObserveOrder(order *interfaces.Order) {
+	if !order.FilledQty.IsPositive() || order.FilledAvgPrice == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.streaming {
+		return
+	}
+
+	fills, err := t.storage.GetOrderFills(order.ID)
+	if err != nil {
+		t.logger.WithError(err).WithField("order_id", order.ID).Warn("Failed to get order fills")
+		return
+	}
+	recordedQty, recordedNotional := fillTotals(fills)
+	qty := order.FilledQty.Sub(recordedQty)
+	if !qty.IsPositive() {
+		return
+	}
+	price := order.FilledAvgPrice.Mul(order.FilledQty).Sub(recordedNotional).Div(qty)
+
+	event := FillEventPartial
+	if order.Status == "filled" {
+		event = FillEventFill
+	}
+	at := time.Now()
+	if order.FilledAt != nil {
+		at = *order.FilledAt
+	}
+	executionID := fmt.Sprintf("%s:%s", order.ID, order.FilledQty.String())
+	t.record(order, event, executionID, qty, price, at, true)
+}
+
+// record saves one execution and updates the stored order's running
+// quantity and average price. Executions the recorded fills already cover
+// are skipped, so a fill inferred while the stream was down isn't counted
+// again when the stream replays it. The caller holds t.mu.
+func (t *FillTracker) record(order *interfaces.Order, event, executionID string, qty, price decimal.Decimal, at time.Time, inferred bool) {
+	if !qty.IsPositive() || executionID == "" {
+		return
+	}
+	logger := t.logger.WithFields(logrus.Fields{"order_id": order.ID, "symbol": order.Symbol})
+
+	fills, err := t.storage.GetOrderFills(order.ID)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to get order fills")
+		return
+	}
+	filledQty, notional := fillTotals(fills)
+	if order.FilledQty.IsPositive() && !filledQty.LessThan(order.FilledQty) {
+		return
+	}
+	filledQty = filledQty.Add(qty)
+	avgPrice := notional.Add(qty.Mul(price)).Div(filledQty)
+
+	saved, err := t.storage.SaveOrderFill(&models.DBOrderFill{
+		OrderID:        order.ID,
+		ExecutionID:    executionID,
+		Symbol:         order.Symbol,
+		Side:           order.Side,
+		Event:          event,
+		Qty:            qty,
+		Price:          price,
+		FilledQty:      filledQty,
+		FilledAvgPrice: avgPrice,
+		FilledAt:       at,
+		Inferred:       inferred,
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to save order fill")
+		return
+	}
+	if !saved {
+		return
+	}
+
+	var filledAt *time.Time
+	if event == FillEventFill {
+		filledAt = &at
+	}
+	if err := t.storage.UpdateOrderFill(order.ID, order.Status, filledQty, avgPrice, filledAt); err != nil {
+		logger.WithError(err).Error("Failed to update order fill")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"event":      event,
+		"qty":        qty,
+		"price":      price,
+		"filled_qty": filledQty,
+		"avg_price":  avgPrice.StringFixed(4),
+	}).Info("Order fill recorded")
+}
+
+// Fills returns the executions of an order, oldest first
+func (t *FillTracker) Fills(orderID string) ([]*models.DBOrderFill, error) {
+	return t.storage.GetOrderFills(orderID)
+}
+
+// fillTotals sums the quantity and notional of recorded fills
+func fillTotals(fills []*models.DBOrderFill) (qty, notional decimal.Decimal) {
+	for _, fill := range fills {
+		qty = qty.Add(fill.Qty)
+		notional = notional.Add(fill.Qty.Mul(fill.Price))
+	}
+	return qty, notional
+}
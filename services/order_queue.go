@@ -0,0 +1,451 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Queued order statuses
+const (
+	QueuedOrderQueued    = "QUEUED"
+	QueuedOrderSubmitted = "SUBMITTED"
+	QueuedOrderRejected  = "REJECTED" // failed risk checks at submission time
+	QueuedOrderFailed    = "FAILED"   // the broker refused the order
+	QueuedOrderCanceled  = "CANCELED"
+	QueuedOrderExpired   = "EXPIRED" // reached its good-till-date before it was submitted
+)
+
+// JobQueue carries work from the instances that accept it to the one that
+// runs it, such as a Redis list
+type JobQueue interface {
+	// Push adds a job at the back
+	Push(ctx context.Context, job []byte) error
+	// Pop removes the job at the front, returning nil when there is none
+	Pop(ctx context.Context) ([]byte, error)
+	// Requeue puts a popped job back at the front
+	Requeue(ctx context.Context, job []byte) error
+}
+
+// QueuedOrder is an order held locally until the market opens or its
+// scheduled time arrives
+type QueuedOrder struct {
+	ID          string            `json:"id"`
+	Symbol      string            `json:"symbol"`
+	Qty         decimal.Decimal   `json:"qty"`
+	Side        string            `json:"side"`
+	Type        string            `json:"type"`
+	TimeInForce string            `json:"time_in_force"`
+	LimitPrice  *decimal.Decimal  `json:"limit_price,omitempty"`
+	StopPrice   *decimal.Decimal  `json:"stop_price,omitempty"`
+	Strategy    string            `json:"strategy,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Source      string            `json:"source"`
+	Book        string            `json:"book"`
+	SubmitAt    *time.Time        `json:"submit_at,omitempty"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	ExpiresIn   *int64            `json:"expires_in_seconds,omitempty"` // remaining lifetime while queued
+	Status      string            `json:"status"`
+	OrderID     string            `json:"order_id,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ProcessedAt *time.Time        `json:"processed_at,omitempty"`
+}
+
+// OrderQueue holds orders accepted while the market is closed. Risk checks
+// run when an order is due, against the account and prices at the open, and
+// approved orders are then submitted to the broker.
+type OrderQueue struct {
+	storage     *database.LocalStorage
+	trading     interfaces.TradingService
+	riskManager *RiskManager
+	auditor     *OrderAuditor
+	notifier    *Notifier
+	hours       *MarketHours
+	jobs        JobQueue   // nil stores orders straight in this instance's database
+	mu          sync.Mutex // keeps cancellation from racing submission
+	logger      *logrus.Logger
+}
+
+// NewOrderQueue creates an order queue that submits through trading
+func NewOrderQueue(storage *database.LocalStorage, trading interfaces.TradingService, riskManager *RiskManager, auditor *OrderAuditor, notifier *Notifier) *OrderQueue {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &OrderQueue{
+		storage:     storage,
+		trading:     trading,
+		riskManager: riskManager,
+		auditor:     auditor,
+		notifier:    notifier,
+		hours:       NewMarketHours(),
+		logger:      logger,
+	}
+}
+
+// SetMarketHours decides when the market is open, by default the weekday
+// calendar
+func (q *OrderQueue) SetMarketHours(hours *MarketHours) {
+	q.hours = hours
+}
+
+// SetJobQueue hands queued orders to the trading instance through jobs
+// instead of this instance's database. The instance running the queue moves
+// them into its database on every pass.
+func (q *OrderQueue) SetJobQueue(jobs JobQueue) {
+	q.jobs = jobs
+}
+
+// MarketOpen reports whether the equity market is open now
+func (q *OrderQueue) MarketOpen(ctx context.Context) bool {
+	return q.hours.IsOpen(ctx, AssetClassEquity)
+}
+
+// Enqueue persists an order for submission at the next open, or at the
+// first open moment after submitAt when it is set. An order still queued
+// at expiresAt expires instead of being submitted.
+func (q *OrderQueue) Enqueue(ctx context.Context, order *interfaces.Order, submitAt, expiresAt *time.Time) (*QueuedOrder, error) {
+	if submitAt != nil && !submitAt.After(time.Now()) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("submit_at must be in the future"))
+	}
+	if err := ValidateExpiry(expiresAt, submitAt); err != nil {
+		return nil, err
+	}
+
+	dbOrder := &models.DBQueuedOrder{
+		QueueID:     fmt.Sprintf("queued_%d", time.Now().UnixNano()),
+		Symbol:      order.Symbol,
+		Qty:         order.Qty,
+		Side:        order.Side,
+		Type:        order.Type,
+		TimeInForce: order.TimeInForce,
+		LimitPrice:  order.LimitPrice,
+		StopPrice:   order.StopPrice,
+		Strategy:    order.Strategy,
+		Tags:        database.EncodeOrderTags(order.Tags),
+		Metadata:    database.EncodeOrderMetadata(order.Metadata),
+		Source:      OrderSourceFrom(ctx),
+		Book:        order.Book,
+		SubmitAt:    submitAt,
+		ExpiresAt:   expiresAt,
+		Status:      QueuedOrderQueued,
+	}
+	if q.jobs != nil {
+		dbOrder.CreatedAt = time.Now()
+		job, err := json.Marshal(dbOrder)
+		if err != nil {
+			return nil, err
+		}
+		if err := q.jobs.Push(ctx, job); err != nil {
+			return nil, WithErrorCode(ErrCodeUnavailable, fmt.Errorf("failed to hand order to the trading instance: %w", err))
+		}
+	} else if err := q.storage.SaveQueuedOrder(dbOrder); err != nil {
+		return nil, err
+	}
+	q.auditor.RecordUnsubmitted(ctx, order, AuditOutcomeQueued, nil, nil)
+
+	q.logger.WithFields(logrus.Fields{
+		"id":         dbOrder.QueueID,
+		"symbol":     order.Symbol,
+		"side":       order.Side,
+		"qty":        order.Qty.String(),
+		"submit_at":  submitAt,
+		"expires_at": expiresAt,
+	}).Info("Order queued")
+
+	return queuedOrderFromDB(dbOrder), nil
+}
+
+// List returns queued orders, optionally filtered by status, oldest first
+func (q *OrderQueue) List(status string) ([]*QueuedOrder, error) {
+	dbOrders, err := q.storage.GetQueuedOrders(status)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]*QueuedOrder, len(dbOrders))
+	for i, dbOrder := range dbOrders {
+		orders[i] = queuedOrderFromDB(dbOrder)
+	}
+	return orders, nil
+}
+
+// Cancel withdraws an order that has not been submitted yet
+func (q *OrderQueue) Cancel(id string) (*QueuedOrder, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	dbOrder, err := q.storage.GetQueuedOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if dbOrder.Status != QueuedOrderQueued {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("queued order %s is already %s", id, dbOrder.Status))
+	}
+
+	now := time.Now()
+	dbOrder.Status = QueuedOrderCanceled
+	dbOrder.ProcessedAt = &now
+	if err := q.storage.SaveQueuedOrder(dbOrder); err != nil {
+		return nil, err
+	}
+
+	q.logger.WithField("id", id).Info("Queued order canceled")
+	return queuedOrderFromDB(dbOrder), nil
+}
+
+// receive moves the orders other instances handed over into storage
+func (q *OrderQueue) receive(ctx context.Context) error {
+	if q.jobs == nil {
+		return nil
+	}
+
+	for {
+		job, err := q.jobs.Pop(ctx)
+		if err != nil || job == nil {
+			return err
+		}
+		var dbOrder models.DBQueuedOrder
+		if err := json.Unmarshal(job, &dbOrder); err != nil {
+			q.logger.WithError(err).Error("Dropping unreadable queued order job")
+			continue
+		}
+		dbOrder.ID = 0
+		if err := q.storage.SaveQueuedOrder(&dbOrder); err != nil {
+			if requeueErr := q.jobs.Requeue(ctx, job); requeueErr != nil {
+				q.logger.WithError(requeueErr).WithField("id", dbOrder.QueueID).Error("Failed to requeue order job, order lost")
+			}
+			return err
+		}
+		q.logger.WithFields(logrus.Fields{
+			"id":     dbOrder.QueueID,
+			"symbol": dbOrder.Symbol,
+		}).Info("Received queued order")
+	}
+}
+
+// ProcessDue risk-checks and submits every due order if the market is open.
+// It returns how many orders left the queue.
+func (q *OrderQueue) ProcessDue(ctx context.Context) (int, error) {
+	if err := q.receive(ctx); err != nil {
+		q.logger.WithError(err).Error("Failed to receive queued orders")
+	}
+	if err := q.expire(ctx); err != nil {
+		q.logger.WithError(err).Error("Failed to expire queued orders")
+	}
+	if !q.MarketOpen(ctx) {
+		return 0, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending, err := q.storage.GetQueuedOrders(QueuedOrderQueued)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	processed := 0
+	for _, dbOrder := range pending {
+		if dbOrder.SubmitAt != nil && dbOrder.SubmitAt.After(now) {
+			continue
+		}
+		if q.process(ctx, dbOrder) {
+			processed++
+		}
+	}
+	return processed, nil
+}
+
+// expire ends the queued orders past their good-till-date, whether or not
+// the market is open
+func (q *OrderQueue) expire(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending, err := q.storage.GetQueuedOrders(QueuedOrderQueued)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, dbOrder := range pending {
+		if dbOrder.ExpiresAt == nil || now.Before(*dbOrder.ExpiresAt) {
+			continue
+		}
+		dbOrder.Status = QueuedOrderExpired
+		dbOrder.ProcessedAt = &now
+		if err := q.storage.SaveQueuedOrder(dbOrder); err != nil {
+			q.logger.WithError(err).WithField("id", dbOrder.QueueID).Error("Failed to update queued order")
+			continue
+		}
+
+		fields := logrus.Fields{
+			"id":         dbOrder.QueueID,
+			"symbol":     dbOrder.Symbol,
+			"side":       dbOrder.Side,
+			"qty":        dbOrder.Qty.String(),
+			"expires_at": dbOrder.ExpiresAt,
+		}
+		q.logger.WithFields(fields).Info("Queued order expired")
+		q.notifier.Notify(ctx, NotifyInfo, "Queued order expired",
+			fmt.Sprintf("%s %s %s expired at %s without being submitted", dbOrder.Side, dbOrder.Qty.String(), dbOrder.Symbol,
+				dbOrder.ExpiresAt.Format(time.RFC3339)), fields)
+	}
+	return nil
+}
+
+// process submits one due order. It returns false when the order stays
+// queued because the risk checks could not be run.
+func (q *OrderQueue) process(ctx context.Context, dbOrder *models.DBQueuedOrder) bool {
+	book := bookOrDefault(dbOrder.Book)
+	ctx = WithBook(WithOrderSource(ctx, dbOrder.Source), book)
+	order := &interfaces.Order{
+		Symbol:      dbOrder.Symbol,
+		Qty:         dbOrder.Qty,
+		Side:        dbOrder.Side,
+		Type:        dbOrder.Type,
+		TimeInForce: dbOrder.TimeInForce,
+		LimitPrice:  dbOrder.LimitPrice,
+		StopPrice:   dbOrder.StopPrice,
+		Status:      "pending",
+		SubmittedAt: time.Now(),
+		Strategy:    dbOrder.Strategy,
+		Book:        book,
+		Tags:        database.DecodeOrderTags(dbOrder.Tags),
+		Metadata:    database.DecodeOrderMetadata(dbOrder.Metadata),
+	}
+
+	decision, err := q.riskManager.Evaluate(ctx, order)
+	if err != nil {
+		q.logger.WithError(err).WithField("id", dbOrder.QueueID).Warn("Risk checks failed to run, order stays queued")
+		return false
+	}
+
+	if !decision.Approved {
+		rejected := &RiskRejectedError{Decision: decision}
+		q.auditor.RecordUnsubmitted(ctx, order, AuditOutcomeRejected, decision, rejected)
+		q.finish(ctx, dbOrder, QueuedOrderRejected, "", rejected)
+		return true
+	}
+
+	result, err := q.trading.PlaceOrder(WithRiskDecision(ctx, decision), order)
+	if err != nil {
+		q.finish(ctx, dbOrder, QueuedOrderFailed, "", err)
+		return true
+	}
+
+	order.ID = result.OrderID
+	order.Status = result.Status
+	if err := q.storage.SaveOrder(order); err != nil {
+		q.logger.WithError(err).Warn("Failed to save order to database")
+	}
+	q.finish(ctx, dbOrder, QueuedOrderSubmitted, result.OrderID, nil)
+	return true
+}
+
+// finish records the outcome of a queued order and reports failures
+func (q *OrderQueue) finish(ctx context.Context, dbOrder *models.DBQueuedOrder, status, orderID string, err error) {
+	now := time.Now()
+	dbOrder.Status = status
+	dbOrder.OrderID = orderID
+	dbOrder.ProcessedAt = &now
+	if err != nil {
+		dbOrder.Error = err.Error()
+	}
+	if saveErr := q.storage.SaveQueuedOrder(dbOrder); saveErr != nil {
+		q.logger.WithError(saveErr).WithField("id", dbOrder.QueueID).Error("Failed to update queued order")
+	}
+
+	fields := logrus.Fields{
+		"id":       dbOrder.QueueID,
+		"symbol":   dbOrder.Symbol,
+		"side":     dbOrder.Side,
+		"qty":      dbOrder.Qty.String(),
+		"status":   status,
+		"order_id": orderID,
+	}
+	if err == nil {
+		q.logger.WithFields(fields).Info("Queued order submitted")
+		return
+	}
+
+	q.logger.WithFields(fields).WithError(err).Warn("Queued order not submitted")
+	var rejected *RiskRejectedError
+	title := "Queued order failed"
+	if errors.As(err, &rejected) {
+		title = "Queued order rejected by risk checks"
+	}
+	q.notifier.Notify(ctx, NotifyWarning, title,
+		fmt.Sprintf("%s %s %s: %s", dbOrder.Side, dbOrder.Qty.String(), dbOrder.Symbol, err.Error()), fields)
+}
+
+func queuedOrderFromDB(dbOrder *models.DBQueuedOrder) *QueuedOrder {
+	order := &QueuedOrder{
+		ID:          dbOrder.QueueID,
+		Symbol:      dbOrder.Symbol,
+		Qty:         dbOrder.Qty,
+		Side:        dbOrder.Side,
+		Type:        dbOrder.Type,
+		TimeInForce: dbOrder.TimeInForce,
+		LimitPrice:  dbOrder.LimitPrice,
+		StopPrice:   dbOrder.StopPrice,
+		Strategy:    dbOrder.Strategy,
+		Tags:        database.DecodeOrderTags(dbOrder.Tags),
+		Metadata:    database.DecodeOrderMetadata(dbOrder.Metadata),
+		Source:      dbOrder.Source,
+		Book:        bookOrDefault(dbOrder.Book),
+		SubmitAt:    dbOrder.SubmitAt,
+		ExpiresAt:   dbOrder.ExpiresAt,
+		Status:      dbOrder.Status,
+		OrderID:     dbOrder.OrderID,
+		Error:       dbOrder.Error,
+		CreatedAt:   dbOrder.CreatedAt,
+		ProcessedAt: dbOrder.ProcessedAt,
+	}
+	if dbOrder.Status == QueuedOrderQueued {
+		order.ExpiresIn = RemainingLifetime(dbOrder.ExpiresAt)
+	}
+	return order
+}
+
+// ValidateExpiry checks a good-till-date for a locally held order: it must
+// be in the future and after the order's earliest submission time
+func ValidateExpiry(expiresAt, submitAt *time.Time) error {
+	if expiresAt == nil {
+		return nil
+	}
+	if !expiresAt.After(time.Now()) {
+		return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("expires_at must be in the future"))
+	}
+	if submitAt != nil && !expiresAt.After(*submitAt) {
+		return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("expires_at must be after submit_at"))
+	}
+	return nil
+}
+
+// RemainingLifetime returns the whole seconds left until expiresAt, zero
+// once it has passed, or nil when there is no expiry
+func RemainingLifetime(expiresAt *time.Time) *int64 {
+	if expiresAt == nil {
+		return nil
+	}
+	remaining := int64(time.Until(*expiresAt) / time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// A minimal Parquet writer for dataset exports: one row group, one
+// uncompressed PLAIN-encoded page per column and only required columns.
+// pandas, pyarrow, polars and duckdb all read it.
+
+const parquetMagic = "PAR1"
+
+// Parquet physical types, converted types and enums used by the writer
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMillis = 9
+
+	parquetRequired     = 0
+	parquetPlain        = 0
+	parquetRLE          = 3
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+// Thrift compact protocol field types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// writeParquet writes table as a Parquet file
+func writeParquet(w io.Writer, table *datasetTable) error {
+	var out bytes.Buffer
+	out.WriteString(parquetMagic)
+
+	columns := make([]*thriftWriter, len(table.columns))
+	var totalSize int64
+	for i, column := range table.columns {
+		page, err := parquetPlainValues(column, table.rows, i)
+		if err != nil {
+			return err
+		}
+
+		header := &thriftWriter{}
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.beginStruct(5)
+		header.i32(1, int32(len(table.rows)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.stop()
+
+		offset := int64(out.Len())
+		out.Write(header.buf.Bytes())
+		out.Write(page)
+		size := int64(header.buf.Len() + len(page))
+		totalSize += size
+
+		chunk := &thriftWriter{}
+		chunk.i64(2, offset)
+		chunk.beginStruct(3)
+		chunk.i32(1, column.parquetType())
+		chunk.listHeader(2, thriftI32, 1)
+		chunk.varint(zigzag(parquetPlain))
+		chunk.listHeader(3, thriftBinary, 1)
+		chunk.bytes([]byte(column.name))
+		chunk.i32(4, parquetUncompressed)
+		chunk.i64(5, int64(len(table.rows)))
+		chunk.i64(6, size)
+		chunk.i64(7, size)
+		chunk.i64(9, offset)
+		chunk.endStruct()
+		chunk.stop()
+		columns[i] = chunk
+	}
+
+	meta := &thriftWriter{}
+	meta.i32(1, 1)
+	meta.listHeader(2, thriftStruct, len(table.columns)+1)
+	root := &thriftWriter{}
+	root.binary(4, "schema")
+	root.i32(5, int32(len(table.columns)))
+	root.stop()
+	meta.buf.Write(root.buf.Bytes())
+	for _, column := range table.columns {
+		element := &thriftWriter{}
+		element.i32(1, column.parquetType())
+		element.i32(3, parquetRequired)
+		element.binary(4, column.name)
+		if converted, ok := column.convertedType(); ok {
+			element.i32(6, converted)
+		}
+		element.stop()
+		meta.buf.Write(element.buf.Bytes())
+	}
+	meta.i64(3, int64(len(table.rows)))
+	meta.listHeader(4, thriftStruct, 1)
+	group := &thriftWriter{}
+	group.listHeader(1, thriftStruct, len(columns))
+	for _, chunk := range columns {
+		group.buf.Write(chunk.buf.Bytes())
+	}
+	group.i64(2, totalSize)
+	group.i64(3, int64(len(table.rows)))
+	group.stop()
+	meta.buf.Write(group.buf.Bytes())
+	meta.binary(6, "prophet-trader")
+	meta.stop()
+
+	out.Write(meta.buf.Bytes())
+	binary.Write(&out, binary.LittleEndian, uint32(meta.buf.Len()))
+	out.WriteString(parquetMagic)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// parquetPlainValues PLAIN-encodes column i of rows
+func parquetPlainValues(column datasetColumn, rows [][]interface{}, i int) ([]byte, error) {
+	var buf bytes.Buffer
+	var scratch [8]byte
+	for _, row := range rows {
+		switch value := row[i].(type) {
+		case string:
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(len(value)))
+			buf.Write(scratch[:4])
+			buf.WriteString(value)
+		case int64:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(value))
+			buf.Write(scratch[:])
+		case float64:
+			binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(value))
+			buf.Write(scratch[:])
+		case time.Time:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(value.UnixMilli()))
+			buf.Write(scratch[:])
+		default:
+			return nil, fmt.Errorf("unsupported value %T in column %s", value, column.name)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (c datasetColumn) parquetType() int32 {
+	switch c.kind {
+	case columnString:
+		return parquetByteArray
+	case columnFloat:
+		return parquetDouble
+	default:
+		return parquetInt64
+	}
+}
+
+func (c datasetColumn) convertedType() (int32, bool) {
+	switch c.kind {
+	case columnString:
+		return parquetConvertedUTF8, true
+	case columnTime:
+		return parquetConvertedTimestampMillis, true
+	}
+	return 0, false
+}
+
+// thriftWriter writes Thrift compact protocol structs. Fields must be
+// written in ascending id order.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastID  int16
+	parents []int16
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], v)
+	t.buf.Write(scratch[:n])
+}
+
+func (t *thriftWriter) field(id int16, fieldType byte) {
+	delta := id - t.lastID
+	if delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		t.buf.WriteByte(fieldType)
+		t.varint(zigzag(int64(id)))
+	}
+	t.lastID = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) bytes(v []byte) {
+	t.varint(uint64(len(v)))
+	t.buf.Write(v)
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.bytes([]byte(v))
+}
+
+// listHeader starts a list field of size elements, which the caller writes
+func (t *thriftWriter) listHeader(id int16, elemType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.varint(uint64(size))
+	}
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.parents = append(t.parents, t.lastID)
+	t.lastID = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.stop()
+	t.lastID = t.parents[len(t.parents)-1]
+	t.parents = t.parents[:len(t.parents)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
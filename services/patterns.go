@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"sort"
+	"time"
+)
+
+// pivotWindow is the number of bars on each side a swing high/low must dominate
+const pivotWindow = 3
+
+// CandlestickPattern is a single- or two-bar candlestick formation
+type CandlestickPattern struct {
+	Name      string    `json:"name"`      // "bullish_engulfing", "hammer", "doji", ...
+	Direction string    `json:"direction"` // "bullish", "bearish", "neutral"
+	Timestamp time.Time `json:"timestamp"`
+	BarsAgo   int       `json:"bars_ago"`
+}
+
+// ChartPattern is a multi-bar price formation
+type ChartPattern struct {
+	Name        string    `json:"name"` // "breakout", "breakdown", "double_top", "double_bottom"
+	Direction   string    `json:"direction"`
+	Level       float64   `json:"level"` // breakout level or neckline
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Confirmed   bool      `json:"confirmed"`
+	Description string    `json:"description"`
+}
+
+// PriceLevel is a support or resistance level built from clustered swing points
+type PriceLevel struct {
+	Type        string    `json:"type"` // "support" or "resistance"
+	Price       float64   `json:"price"`
+	Touches     int       `json:"touches"`
+	LastTouched time.Time `json:"last_touched"`
+	DistancePct float64   `json:"distance_percent"` // from the current price
+}
+
+// PatternAnalysis contains all patterns detected over a bar series
+type PatternAnalysis struct {
+	Symbol        string               `json:"symbol"`
+	Timeframe     string               `json:"timeframe"`
+	Bars          int                  `json:"bars"`
+	CurrentPrice  float64              `json:"current_price"`
+	Candlesticks  []CandlestickPattern `json:"candlesticks"`
+	ChartPatterns []ChartPattern       `json:"chart_patterns"`
+	Levels        []PriceLevel         `json:"levels"`
+}
+
+// Patterns fetches bars and runs candlestick, chart pattern and
+// support/resistance detection
+func (tas *TechnicalAnalysisService) Patterns(ctx context.Context, symbol, timeframe string, days int) (*PatternAnalysis, error) {
+	if timeframe == "" {
+		timeframe = "1Day"
+	}
+	timeframe, err := NormalizeBarSpec(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	if days <= 0 {
+		days = 180
+	}
+
+	end := time.Now()
+	bars, err := tas.dataService.GetHistoricalBars(ctx, symbol, end.AddDate(0, 0, -days), end, timeframe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bars: %w", err)
+	}
+	if len(bars) < 2*pivotWindow+2 {
+		return nil, fmt.Errorf("not enough bars for pattern detection (got %d)", len(bars))
+	}
+
+	analysis := DetectPatterns(bars)
+	analysis.Symbol = symbol
+	analysis.Timeframe = timeframe
+	return analysis, nil
+}
+
+// DetectPatterns runs all detectors over bars in ascending time order
+func DetectPatterns(bars []*interfaces.Bar) *PatternAnalysis {
+	analysis := &PatternAnalysis{
+		Bars:          len(bars),
+		Candlesticks:  make([]CandlestickPattern, 0),
+		ChartPatterns: make([]ChartPattern, 0),
+		Levels:        make([]PriceLevel, 0),
+	}
+	if len(bars) == 0 {
+		return analysis
+	}
+
+	analysis.CurrentPrice = bars[len(bars)-1].Close
+	analysis.Candlesticks = DetectCandlestickPatterns(bars, 10)
+	analysis.Levels = FindSupportResistance(bars, 1.5)
+	if p := detectConsolidationBreak(bars, 20); p != nil {
+		analysis.ChartPatterns = append(analysis.ChartPatterns, *p)
+	}
+	analysis.ChartPatterns = append(analysis.ChartPatterns, detectDoubleTopsBottoms(bars)...)
+
+	return analysis
+}
+
+// PatternIndicators summarizes detected patterns on the latest bar as
+// numeric inputs for MarketData.Indicators: 1 when a pattern is present
+// (signed +1/-1 for breakouts), plus the nearest support and resistance
+func PatternIndicators(bars []*interfaces.Bar) map[string]float64 {
+	indicators := make(map[string]float64)
+	if len(bars) < 2 {
+		return indicators
+	}
+
+	for _, p := range DetectCandlestickPatterns(bars, 1) {
+		indicators["pattern_"+p.Name] = 1
+	}
+
+	analysis := DetectPatterns(bars)
+	for _, p := range analysis.ChartPatterns {
+		if !p.Confirmed {
+			continue
+		}
+		switch p.Name {
+		case "breakout":
+			indicators["pattern_breakout"] = 1
+		case "breakdown":
+			indicators["pattern_breakout"] = -1
+		default:
+			indicators["pattern_"+p.Name] = 1
+		}
+	}
+	for _, level := range analysis.Levels {
+		key := level.Type
+		if _, ok := indicators[key]; !ok {
+			indicators[key] = level.Price
+		}
+	}
+
+	return indicators
+}
+
+// PatternSummary returns short labels for patterns on the most recent bars,
+// compact enough for AI prompts
+func PatternSummary(bars []*interfaces.Bar) []string {
+	summary := make([]string, 0)
+	if len(bars) < 2 {
+		return summary
+	}
+
+	analysis := DetectPatterns(bars)
+	for _, p := range analysis.Candlesticks {
+		if p.BarsAgo <= 3 {
+			summary = append(summary, fmt.Sprintf("%s (%d bars ago)", p.Name, p.BarsAgo))
+		}
+	}
+	for _, p := range analysis.ChartPatterns {
+		status := "forming"
+		if p.Confirmed {
+			status = "confirmed"
+		}
+		summary = append(summary, fmt.Sprintf("%s at %.2f (%s)", p.Name, p.Level, status))
+	}
+	return summary
+}
+
+// DetectCandlestickPatterns scans the last lookback bars for engulfing,
+// hammer, shooting star and doji formations
+func DetectCandlestickPatterns(bars []*interfaces.Bar, lookback int) []CandlestickPattern {
+	patterns := make([]CandlestickPattern, 0)
+	start := len(bars) - lookback
+	if start < 1 {
+		start = 1
+	}
+
+	for i := start; i < len(bars); i++ {
+		bar, prev := bars[i], bars[i-1]
+		barsAgo := len(bars) - 1 - i
+		add := func(name, direction string) {
+			patterns = append(patterns, CandlestickPattern{Name: name, Direction: direction, Timestamp: bar.Timestamp, BarsAgo: barsAgo})
+		}
+
+		rng := bar.High - bar.Low
+		if rng <= 0 {
+			continue
+		}
+		body := math.Abs(bar.Close - bar.Open)
+		upper := bar.High - math.Max(bar.Open, bar.Close)
+		lower := math.Min(bar.Open, bar.Close) - bar.Low
+		downtrend, uptrend := priorTrend(bars, i, 5)
+
+		// Engulfing: body fully covers the previous opposite-colored body
+		prevBody := math.Abs(prev.Close - prev.Open)
+		if prev.Close < prev.Open && bar.Close > bar.Open && bar.Open <= prev.Close && bar.Close >= prev.Open && body > prevBody {
+			add("bullish_engulfing", "bullish")
+		} else if prev.Close > prev.Open && bar.Close < bar.Open && bar.Open >= prev.Close && bar.Close <= prev.Open && body > prevBody {
+			add("bearish_engulfing", "bearish")
+		}
+
+		switch {
+		case body <= rng*0.1:
+			add("doji", "neutral")
+		case lower >= 2*body && upper <= rng*0.15:
+			if downtrend {
+				add("hammer", "bullish")
+			} else if uptrend {
+				add("hanging_man", "bearish")
+			}
+		case upper >= 2*body && lower <= rng*0.15:
+			if uptrend {
+				add("shooting_star", "bearish")
+			} else if downtrend {
+				add("inverted_hammer", "bullish")
+			}
+		}
+	}
+
+	return patterns
+}
+
+// priorTrend reports whether the closes before bar i were falling or rising
+func priorTrend(bars []*interfaces.Bar, i, period int) (down, up bool) {
+	if i < period {
+		return false, false
+	}
+	first, last := bars[i-period].Close, bars[i-1].Close
+	return last < first, last > first
+}
+
+// swingPoint is a local high or low
+type swingPoint struct {
+	index int
+	price float64
+}
+
+// findSwings returns pivot highs and lows that dominate pivotWindow bars on each side
+func findSwings(bars []*interfaces.Bar) (highs, lows []swingPoint) {
+	for i := pivotWindow; i < len(bars)-pivotWindow; i++ {
+		isHigh, isLow := true, true
+		for j := i - pivotWindow; j <= i+pivotWindow; j++ {
+			if j == i {
+				continue
+			}
+			if bars[j].High >= bars[i].High {
+				isHigh = false
+			}
+			if bars[j].Low <= bars[i].Low {
+				isLow = false
+			}
+		}
+		if isHigh {
+			highs = append(highs, swingPoint{index: i, price: bars[i].High})
+		}
+		if isLow {
+			lows = append(lows, swingPoint{index: i, price: bars[i].Low})
+		}
+	}
+	return highs, lows
+}
+
+// FindSupportResistance clusters swing highs and lows within tolerancePct of
+// each other into levels, keeping those touched at least twice
+func FindSupportResistance(bars []*interfaces.Bar, tolerancePct float64) []PriceLevel {
+	highs, lows := findSwings(bars)
+	points := append(append([]swingPoint{}, highs...), lows...)
+	sort.Slice(points, func(i, j int) bool { return points[i].price < points[j].price })
+
+	current := bars[len(bars)-1].Close
+	levels := make([]PriceLevel, 0)
+
+	for i := 0; i < len(points); {
+		j := i
+		sum, last := 0.0, 0
+		for j < len(points) && points[j].price <= points[i].price*(1+tolerancePct/100) {
+			sum += points[j].price
+			if points[j].index > last {
+				last = points[j].index
+			}
+			j++
+		}
+
+		if touches := j - i; touches >= 2 {
+			price := sum / float64(touches)
+			level := PriceLevel{
+				Type:        "support",
+				Price:       price,
+				Touches:     touches,
+				LastTouched: bars[last].Timestamp,
+				DistancePct: (price - current) / current * 100,
+			}
+			if price > current {
+				level.Type = "resistance"
+			}
+			levels = append(levels, level)
+		}
+		i = j
+	}
+
+	// Nearest levels first
+	sort.Slice(levels, func(i, j int) bool {
+		return math.Abs(levels[i].DistancePct) < math.Abs(levels[j].DistancePct)
+	})
+	return levels
+}
+
+// detectConsolidationBreak checks whether the latest bar closed outside a
+// tight range formed by the preceding window bars
+func detectConsolidationBreak(bars []*interfaces.Bar, window int) *ChartPattern {
+	if len(bars) < window+1 {
+		return nil
+	}
+
+	base := bars[len(bars)-1-window : len(bars)-1]
+	high, low := math.Inf(-1), math.Inf(1)
+	volume := 0.0
+	for _, bar := range base {
+		high = math.Max(high, bar.High)
+		low = math.Min(low, bar.Low)
+		volume += float64(bar.Volume)
+	}
+	mid := (high + low) / 2
+	if mid <= 0 || (high-low)/mid > 0.08 {
+		return nil
+	}
+
+	last := bars[len(bars)-1]
+	avgVolume := volume / float64(len(base))
+	volumeConfirmed := avgVolume > 0 && float64(last.Volume) >= 1.5*avgVolume
+	widthPct := (high - low) / mid * 100
+
+	pattern := &ChartPattern{
+		Start:     base[0].Timestamp,
+		End:       last.Timestamp,
+		Confirmed: volumeConfirmed,
+	}
+	switch {
+	case last.Close > high:
+		pattern.Name, pattern.Direction, pattern.Level = "breakout", "bullish", high
+		pattern.Description = fmt.Sprintf("closed above a %d-bar %.1f%% range", window, widthPct)
+	case last.Close < low:
+		pattern.Name, pattern.Direction, pattern.Level = "breakdown", "bearish", low
+		pattern.Description = fmt.Sprintf("closed below a %d-bar %.1f%% range", window, widthPct)
+	default:
+		return nil
+	}
+	if !volumeConfirmed {
+		pattern.Description += " on below-average volume"
+	}
+	return pattern
+}
+
+// detectDoubleTopsBottoms looks at the last two swing highs and lows for
+// matching peaks or troughs separated by a meaningful pullback
+func detectDoubleTopsBottoms(bars []*interfaces.Bar) []ChartPattern {
+	patterns := make([]ChartPattern, 0)
+	highs, lows := findSwings(bars)
+	last := bars[len(bars)-1]
+
+	if len(highs) >= 2 {
+		a, b := highs[len(highs)-2], highs[len(highs)-1]
+		if b.index-a.index >= 5 && math.Abs(a.price-b.price)/math.Max(a.price, b.price) <= 0.03 {
+			trough := math.Inf(1)
+			for _, bar := range bars[a.index : b.index+1] {
+				trough = math.Min(trough, bar.Low)
+			}
+			if trough <= math.Min(a.price, b.price)*0.97 {
+				patterns = append(patterns, ChartPattern{
+					Name:        "double_top",
+					Direction:   "bearish",
+					Level:       trough,
+					Start:       bars[a.index].Timestamp,
+					End:         bars[b.index].Timestamp,
+					Confirmed:   last.Close < trough,
+					Description: fmt.Sprintf("peaks at %.2f and %.2f with neckline %.2f", a.price, b.price, trough),
+				})
+			}
+		}
+	}
+
+	if len(lows) >= 2 {
+		a, b := lows[len(lows)-2], lows[len(lows)-1]
+		if b.index-a.index >= 5 && math.Abs(a.price-b.price)/math.Max(a.price, b.price) <= 0.03 {
+			peak := math.Inf(-1)
+			for _, bar := range bars[a.index : b.index+1] {
+				peak = math.Max(peak, bar.High)
+			}
+			if peak >= math.Max(a.price, b.price)*1.03 {
+				patterns = append(patterns, ChartPattern{
+					Name:        "double_bottom",
+					Direction:   "bullish",
+					Level:       peak,
+					Start:       bars[a.index].Timestamp,
+					End:         bars[b.index].Timestamp,
+					Confirmed:   last.Close > peak,
+					Description: fmt.Sprintf("troughs at %.2f and %.2f with neckline %.2f", a.price, b.price, peak),
+				})
+			}
+		}
+	}
+
+	return patterns
+}
@@ -0,0 +1,548 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"prophet-trader/config"
+	"prophet-trader/interfaces"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+func init() {
+	RegisterDataProvider("polygon", func(cfg *config.Config) (interfaces.DataService, error) {
+		return NewPolygonDataService(cfg.PolygonAPIKey, cfg.PolygonDelayed)
+	})
+}
+
+// Polygon API roots
+const (
+	polygonRESTURL            = "https://api.polygon.io"
+	polygonRealtimeSocketURL  = "wss://socket.polygon.io/stocks"
+	polygonDelayedSocketURL   = "wss://delayed.polygon.io/stocks"
+	polygonReconnectDelay     = 5 * time.Second
+	polygonMaxReconnectDelay  = 2 * time.Minute
+	polygonAggregatesPageSize = 50000
+)
+
+// PolygonDataService implements DataService using the Polygon.io REST and
+// websocket APIs
+type PolygonDataService struct {
+	apiKey     string
+	baseURL    string
+	socketURL  string
+	httpClient *http.Client
+	logger     *logrus.Logger
+}
+
+// NewPolygonDataService creates a Polygon market data service. delayed
+// streams from the 15-minute delayed cluster, which is what lower plans
+// are entitled to.
+func NewPolygonDataService(apiKey string, delayed bool) (*PolygonDataService, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("Polygon API key not configured")
+	}
+
+	socketURL := polygonRealtimeSocketURL
+	if delayed {
+		socketURL = polygonDelayedSocketURL
+	}
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &PolygonDataService{
+		apiKey:     apiKey,
+		baseURL:    polygonRESTURL,
+		socketURL:  socketURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		logger:     logger,
+	}, nil
+}
+
+// polygonAggregate is one bar from the aggregates endpoint
+type polygonAggregate struct {
+	Open      float64 `json:"o"`
+	High      float64 `json:"h"`
+	Low       float64 `json:"l"`
+	Close     float64 `json:"c"`
+	Volume    float64 `json:"v"`
+	VWAP      float64 `json:"vw"`
+	Timestamp int64   `json:"t"` // epoch milliseconds of the bar start
+}
+
+// get requests an API path (or a next_url) and decodes the JSON response
+func (s *PolygonDataService) get(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	if !strings.HasPrefix(endpoint, "http") {
+		endpoint = s.baseURL + endpoint
+	}
+	if len(params) > 0 {
+		sep := "?"
+		if strings.Contains(endpoint, "?") {
+			sep = "&"
+		}
+		endpoint += sep + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	// The key goes in a header rather than the query, where transport errors
+	// would carry it into logs
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Polygon request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Polygon API error (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// polygonTimespan maps a timeframe string to a Polygon multiplier and timespan
+func polygonTimespan(timeframe string) (int, string, error) {
+	tf, err := ParseTimeframe(timeframe)
+	if err != nil {
+		return 0, "", err
+	}
+	timespan := map[TimeframeUnit]string{
+		TimeframeMinute: "minute",
+		TimeframeHour:   "hour",
+		TimeframeDay:    "day",
+		TimeframeWeek:   "week",
+		TimeframeMonth:  "month",
+	}[tf.Unit]
+	return tf.Amount, timespan, nil
+}
+
+// GetHistoricalBars retrieves aggregates, following pagination. They are
+// split-adjusted unless the context asks for raw prices; Polygon offers no
+// dividend adjustment or feed selection.
+func (s *PolygonDataService) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
+	s.logger.WithFields(logrus.Fields{
+		"symbol":    symbol,
+		"start":     start,
+		"end":       end,
+		"timeframe": timeframe,
+	}).Debug("Fetching historical bars")
+
+	opts := barOptionsFrom(ctx)
+	if opts.Feed != "" {
+		return nil, fmt.Errorf("%s feed: %w", opts.Feed, ErrNotSupported)
+	}
+	adjusted := "true"
+	switch opts.Adjustment {
+	case BarAdjustmentRaw:
+		adjusted = "false"
+	case BarAdjustmentDividend, BarAdjustmentAll:
+		return nil, fmt.Errorf("%s adjustment: %w", opts.Adjustment, ErrNotSupported)
+	}
+
+	bars, err := s.aggregates(ctx, symbol, start, end, timeframe, adjusted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get historical bars: %w", err)
+	}
+	return bars, nil
+}
+
+func (s *PolygonDataService) aggregates(ctx context.Context, symbol string, start, end time.Time, timeframe, adjusted string) ([]*interfaces.Bar, error) {
+	multiplier, timespan, err := polygonTimespan(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("/v2/aggs/ticker/%s/range/%d/%s/%d/%d",
+		url.PathEscape(strings.ToUpper(symbol)), multiplier, timespan, start.UnixMilli(), end.UnixMilli())
+	params := url.Values{
+		"adjusted": {adjusted},
+		"sort":     {"asc"},
+		"limit":    {fmt.Sprintf("%d", polygonAggregatesPageSize)},
+	}
+
+	bars := make([]*interfaces.Bar, 0)
+	for endpoint != "" {
+		var resp struct {
+			Results []polygonAggregate `json:"results"`
+			NextURL string             `json:"next_url"`
+		}
+		if err := s.get(ctx, endpoint, params, &resp); err != nil {
+			return nil, err
+		}
+		for _, agg := range resp.Results {
+			bars = append(bars, polygonBar(symbol, agg))
+		}
+		// next_url carries the original query
+		endpoint, params = resp.NextURL, nil
+	}
+	return bars, nil
+}
+
+func polygonBar(symbol string, agg polygonAggregate) *interfaces.Bar {
+	return &interfaces.Bar{
+		Symbol:    symbol,
+		Timestamp: time.UnixMilli(agg.Timestamp),
+		Open:      agg.Open,
+		High:      agg.High,
+		Low:       agg.Low,
+		Close:     agg.Close,
+		Volume:    int64(agg.Volume),
+		VWAP:      agg.VWAP,
+	}
+}
+
+// GetLatestBar retrieves the most recent minute bar, falling back to the
+// previous session's daily bar outside market hours
+func (s *PolygonDataService) GetLatestBar(ctx context.Context, symbol string) (*interfaces.Bar, error) {
+	end := time.Now()
+	bars, err := s.aggregates(ctx, symbol, end.Add(-24*time.Hour), end, "1Min", "true")
+	if err == nil && len(bars) > 0 {
+		return bars[len(bars)-1], nil
+	}
+
+	var resp struct {
+		Results []polygonAggregate `json:"results"`
+	}
+	if err := s.get(ctx, "/v2/aggs/ticker/"+url.PathEscape(strings.ToUpper(symbol))+"/prev", url.Values{"adjusted": {"true"}}, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get latest bar: %w", err)
+	}
+	if len(resp.Results) == 0 {
+		return nil, fmt.Errorf("no bar data found for symbol: %s", symbol)
+	}
+	return polygonBar(symbol, resp.Results[0]), nil
+}
+
+// GetLatestQuote retrieves the latest NBBO quote
+func (s *PolygonDataService) GetLatestQuote(ctx context.Context, symbol string) (*interfaces.Quote, error) {
+	var resp struct {
+		Results struct {
+			BidPrice  float64 `json:"p"`
+			BidSize   int64   `json:"s"`
+			AskPrice  float64 `json:"P"`
+			AskSize   int64   `json:"S"`
+			Timestamp int64   `json:"t"` // SIP timestamp, nanoseconds
+		} `json:"results"`
+	}
+	if err := s.get(ctx, "/v2/last/nbbo/"+url.PathEscape(strings.ToUpper(symbol)), nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get latest quote: %w", err)
+	}
+
+	return &interfaces.Quote{
+		Symbol:    symbol,
+		BidPrice:  resp.Results.BidPrice,
+		BidSize:   resp.Results.BidSize,
+		AskPrice:  resp.Results.AskPrice,
+		AskSize:   resp.Results.AskSize,
+		Timestamp: time.Unix(0, resp.Results.Timestamp),
+	}, nil
+}
+
+// GetLatestTrade retrieves the latest trade
+func (s *PolygonDataService) GetLatestTrade(ctx context.Context, symbol string) (*interfaces.Trade, error) {
+	var resp struct {
+		Results struct {
+			Price     float64 `json:"p"`
+			Size      int64   `json:"s"`
+			Timestamp int64   `json:"t"` // SIP timestamp, nanoseconds
+		} `json:"results"`
+	}
+	if err := s.get(ctx, "/v2/last/trade/"+url.PathEscape(strings.ToUpper(symbol)), nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get latest trade: %w", err)
+	}
+
+	return &interfaces.Trade{
+		Symbol:    symbol,
+		Price:     resp.Results.Price,
+		Size:      resp.Results.Size,
+		Timestamp: time.Unix(0, resp.Results.Timestamp),
+	}, nil
+}
+
+// polygonSocketEvent is a websocket message; status messages, minute
+// aggregates ("AM") and trades ("T") share the envelope
+type polygonSocketEvent struct {
+	Event   string  `json:"ev"`
+	Status  string  `json:"status"`
+	Message string  `json:"message"`
+	Symbol  string  `json:"sym"`
+	Open    float64 `json:"o"`
+	High    float64 `json:"h"`
+	Low     float64 `json:"l"`
+	Close   float64 `json:"c"`
+	Volume  float64 `json:"v"`
+	VWAP    float64 `json:"vw"`
+	Start   int64   `json:"s"` // aggregate start in epoch milliseconds, or trade size
+	Price   float64 `json:"p"` // trade price
+	Time    int64   `json:"t"` // trade time in epoch milliseconds
+}
+
+// StreamBars streams minute bars over the Polygon websocket. The connection
+// is re-established with backoff until ctx is cancelled, which also closes
+// the returned channel.
+func (s *PolygonDataService) StreamBars(ctx context.Context, symbols []string) (<-chan *interfaces.Bar, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("no symbols to stream")
+	}
+
+	// Connect once up front so bad keys fail the call instead of the stream
+	ws, err := s.connect(symbols, "AM")
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream bars: %w", err)
+	}
+
+	barChan := make(chan *interfaces.Bar, 100)
+	go func() {
+		defer close(barChan)
+		s.keepStreaming(ctx, ws, symbols, "AM", func(ws *websocket.Conn) error {
+			return s.readBars(ctx, ws, barChan)
+		})
+	}()
+
+	return barChan, nil
+}
+
+// StreamTrades streams trades over the Polygon websocket, reconnecting as
+// StreamBars does
+func (s *PolygonDataService) StreamTrades(ctx context.Context, symbols []string) (<-chan *interfaces.Trade, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("no symbols to stream")
+	}
+
+	ws, err := s.connect(symbols, "T")
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream trades: %w", err)
+	}
+
+	tradeChan := make(chan *interfaces.Trade, 1000)
+	go func() {
+		defer close(tradeChan)
+		s.keepStreaming(ctx, ws, symbols, "T", func(ws *websocket.Conn) error {
+			return s.readTrades(ctx, ws, tradeChan)
+		})
+	}()
+
+	return tradeChan, nil
+}
+
+// keepStreaming runs read on ws, reconnecting to channel with backoff
+// whenever it fails, until ctx ends
+func (s *PolygonDataService) keepStreaming(ctx context.Context, ws *websocket.Conn, symbols []string, channel string, read func(*websocket.Conn) error) {
+	delay := polygonReconnectDelay
+	for {
+		// A malformed payload that panics is handled like a dropped
+		// connection
+		if err := callSafely(func() error { return read(ws) }); err != nil && ctx.Err() == nil {
+			s.logger.WithError(err).Warn("Polygon stream disconnected")
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			var err error
+			if ws, err = s.connect(symbols, channel); err == nil {
+				delay = polygonReconnectDelay
+				break
+			}
+			s.logger.WithError(err).Warn("Polygon stream reconnect failed")
+			if delay *= 2; delay > polygonMaxReconnectDelay {
+				delay = polygonMaxReconnectDelay
+			}
+		}
+	}
+}
+
+// connect opens an authenticated websocket subscribed to channel, "AM" for
+// minute aggregates or "T" for trades
+func (s *PolygonDataService) connect(symbols []string, channel string) (*websocket.Conn, error) {
+	ws, err := websocket.Dial(s.socketURL, "", "https://localhost/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	// The server greets with "connected", then answers auth
+	if err := websocket.JSON.Send(ws, map[string]string{"action": "auth", "params": s.apiKey}); err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	ws.SetReadDeadline(time.Now().Add(30 * time.Second))
+	for authed := false; !authed; {
+		var events []polygonSocketEvent
+		if err := websocket.JSON.Receive(ws, &events); err != nil {
+			ws.Close()
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+		for _, event := range events {
+			switch event.Status {
+			case "auth_success":
+				authed = true
+			case "auth_failed":
+				ws.Close()
+				return nil, fmt.Errorf("authentication failed: %s", event.Message)
+			}
+		}
+	}
+	ws.SetReadDeadline(time.Time{})
+
+	channels := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		channels[i] = channel + "." + strings.ToUpper(symbol)
+	}
+	if err := websocket.JSON.Send(ws, map[string]string{"action": "subscribe", "params": strings.Join(channels, ",")}); err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"symbols": symbols,
+		"channel": channel,
+	}).Info("Streaming from Polygon")
+	return ws, nil
+}
+
+// readBars forwards minute aggregates until the connection fails or ctx ends
+func (s *PolygonDataService) readBars(ctx context.Context, ws *websocket.Conn, barChan chan<- *interfaces.Bar) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		ws.Close()
+	}()
+
+	for {
+		var events []polygonSocketEvent
+		if err := websocket.JSON.Receive(ws, &events); err != nil {
+			return err
+		}
+		for _, event := range events {
+			if event.Event != "AM" {
+				continue
+			}
+			bar := &interfaces.Bar{
+				Symbol:    event.Symbol,
+				Timestamp: time.UnixMilli(event.Start),
+				Open:      event.Open,
+				High:      event.High,
+				Low:       event.Low,
+				Close:     event.Close,
+				Volume:    int64(event.Volume),
+				VWAP:      event.VWAP,
+			}
+			select {
+			case barChan <- bar:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// readTrades forwards trades until the connection fails or ctx ends
+func (s *PolygonDataService) readTrades(ctx context.Context, ws *websocket.Conn, tradeChan chan<- *interfaces.Trade) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		ws.Close()
+	}()
+
+	for {
+		var events []polygonSocketEvent
+		if err := websocket.JSON.Receive(ws, &events); err != nil {
+			return err
+		}
+		for _, event := range events {
+			if event.Event != "T" {
+				continue
+			}
+			trade := &interfaces.Trade{
+				Symbol:    event.Symbol,
+				Price:     event.Price,
+				Size:      event.Start,
+				Timestamp: time.UnixMilli(event.Time),
+			}
+			select {
+			case tradeChan <- trade:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// GetHistoricalTrades retrieves up to limit trades, following pagination
+func (s *PolygonDataService) GetHistoricalTrades(ctx context.Context, symbol string, start, end time.Time, limit int) ([]*interfaces.Trade, error) {
+	s.logger.WithFields(logrus.Fields{
+		"symbol": symbol,
+		"start":  start,
+		"end":    end,
+	}).Debug("Fetching historical trades")
+	if feed := barOptionsFrom(ctx).Feed; feed != "" {
+		return nil, fmt.Errorf("%s feed: %w", feed, ErrNotSupported)
+	}
+
+	endpoint := "/v3/trades/" + url.PathEscape(strings.ToUpper(symbol))
+	params := url.Values{
+		"timestamp.gte": {fmt.Sprintf("%d", start.UnixNano())},
+		"timestamp.lt":  {fmt.Sprintf("%d", end.UnixNano())},
+		"order":         {"asc"},
+		"sort":          {"timestamp"},
+		"limit":         {fmt.Sprintf("%d", polygonAggregatesPageSize)},
+	}
+
+	trades := make([]*interfaces.Trade, 0)
+	for endpoint != "" && len(trades) < limit {
+		var resp struct {
+			Results []struct {
+				Price     float64 `json:"price"`
+				Size      float64 `json:"size"`
+				Timestamp int64   `json:"sip_timestamp"` // nanoseconds
+			} `json:"results"`
+			NextURL string `json:"next_url"`
+		}
+		if err := s.get(ctx, endpoint, params, &resp); err != nil {
+			return nil, fmt.Errorf("failed to get historical trades: %w", err)
+		}
+		for _, result := range resp.Results {
+			trades = append(trades, &interfaces.Trade{
+				Symbol:    symbol,
+				Price:     result.Price,
+				Size:      int64(result.Size),
+				Timestamp: time.Unix(0, result.Timestamp),
+			})
+		}
+		// next_url carries the original query
+		endpoint, params = resp.NextURL, nil
+	}
+	if len(trades) > limit {
+		trades = trades[:limit]
+	}
+	return trades, nil
+}
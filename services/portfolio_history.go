@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Portfolio history sources
+const (
+	HistorySourceBroker   = "broker"
+	HistorySourceSnapshot = "snapshot" // filled in from a local account snapshot
+)
+
+// portfolioHistoryTimeframes are the supported point spacings
+var portfolioHistoryTimeframes = map[string]time.Duration{
+	"1Min":  time.Minute,
+	"5Min":  5 * time.Minute,
+	"15Min": 15 * time.Minute,
+	"1H":    time.Hour,
+	"1D":    24 * time.Hour,
+}
+
+// maxIntradayHistoryDays is how far back intraday history can be asked for
+const maxIntradayHistoryDays = 30
+
+// IntradayHistorySource is implemented by brokers that report account
+// equity at intraday timeframes: 1Min, 5Min, 15Min or 1H
+type IntradayHistorySource interface {
+	GetIntradayPortfolioHistory(ctx context.Context, start, end time.Time, timeframe string) ([]*interfaces.EquitySnapshot, error)
+}
+
+// PortfolioHistoryPoint is the account equity at one point of the history
+type PortfolioHistoryPoint struct {
+	Time          time.Time       `json:"time"`
+	Equity        decimal.Decimal `json:"equity"`
+	ProfitLoss    decimal.Decimal `json:"profit_loss"` // since the first point
+	ProfitLossPct float64         `json:"profit_loss_pct"`
+	Source        string          `json:"source"`
+}
+
+// TradeMarker is a filled order placed on the equity curve
+type TradeMarker struct {
+	Time       time.Time       `json:"time"`
+	OrderID    string          `json:"order_id"`
+	Symbol     string          `json:"symbol"`
+	Side       string          `json:"side"`
+	Qty        decimal.Decimal `json:"qty"`
+	Price      decimal.Decimal `json:"price"`
+	Strategy   string          `json:"strategy,omitempty"`
+	Book       string          `json:"book,omitempty"`
+	PointIndex int             `json:"point_index"` // last point at or before the fill, -1 when before them all
+}
+
+// PortfolioHistory is the account equity curve with the trades made along it
+type PortfolioHistory struct {
+	Start     time.Time                `json:"start"`
+	End       time.Time                `json:"end"`
+	Timeframe string                   `json:"timeframe"`
+	BaseValue decimal.Decimal          `json:"base_value"`
+	Points    []*PortfolioHistoryPoint `json:"points"`
+	Trades    []*TradeMarker           `json:"trades"`
+	Filled    int                      `json:"filled_from_snapshots"`
+	Warnings  []string                 `json:"warnings,omitempty"`
+}
+
+// PortfolioHistoryService builds the equity curve from the broker's
+// portfolio history, filling the gaps from local account snapshots and
+// marking the orders that filled along the way
+type PortfolioHistoryService struct {
+	tradingService interfaces.TradingService
+	intraday       IntradayHistorySource // nil when the broker only has daily history
+	storage        *database.LocalStorage
+	logger         *logrus.Logger
+}
+
+// NewPortfolioHistoryService creates a portfolio history service
+func NewPortfolioHistoryService(tradingService interfaces.TradingService, storage *database.LocalStorage) *PortfolioHistoryService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &PortfolioHistoryService{
+		tradingService: tradingService,
+		storage:        storage,
+		logger:         logger,
+	}
+}
+
+// SetIntradaySource serves intraday timeframes from the broker. Without it
+// they come from local snapshots only.
+func (s *PortfolioHistoryService) SetIntradaySource(source IntradayHistorySource) {
+	s.intraday = source
+}
+
+// NormalizeHistoryTimeframe returns the canonical spelling of a timeframe,
+// 1D when empty
+func NormalizeHistoryTimeframe(timeframe string) (string, error) {
+	if timeframe == "" {
+		return "1D", nil
+	}
+	for name := range portfolioHistoryTimeframes {
+		if strings.EqualFold(name, timeframe) {
+			return name, nil
+		}
+	}
+	return "", WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("timeframe must be 1Min, 5Min, 15Min, 1H or 1D, got %q", timeframe))
+}
+
+// History returns the equity curve between start and end at timeframe.
+// Points the broker doesn't report, such as today's, or all of them when
+// the broker has no history, come from the last account snapshot in each
+// period. A broker failure is reported as a warning rather than an error.
+func (s *PortfolioHistoryService) History(ctx context.Context, start, end time.Time, timeframe string) (*PortfolioHistory, error) {
+	timeframe, err := NormalizeHistoryTimeframe(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	daily := timeframe == "1D"
+	if !daily && end.Sub(start) > maxIntradayHistoryDays*24*time.Hour {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("intraday history covers at most %d days", maxIntradayHistoryDays))
+	}
+
+	history := &PortfolioHistory{
+		Start:     start,
+		End:       end,
+		Timeframe: timeframe,
+		Points:    make([]*PortfolioHistoryPoint, 0),
+		Trades:    make([]*TradeMarker, 0),
+	}
+
+	bucket := func(t time.Time) string {
+		if daily {
+			return t.In(marketLocation).Format(marketDateFormat)
+		}
+		return t.Truncate(portfolioHistoryTimeframes[timeframe]).UTC().Format(time.RFC3339)
+	}
+
+	// Broker points come first; a snapshot only fills a period they miss
+	points := make(map[string]*PortfolioHistoryPoint)
+	broker, err := s.brokerHistory(ctx, start, end, timeframe)
+	switch {
+	case errors.Is(err, ErrNotSupported):
+		history.Warnings = append(history.Warnings, "the broker has no "+timeframe+" portfolio history; points come from local snapshots")
+	case err != nil:
+		s.logger.WithError(err).Warn("Failed to get portfolio history from the broker")
+		history.Warnings = append(history.Warnings, "broker portfolio history unavailable: "+err.Error())
+	}
+	for _, snapshot := range broker {
+		points[bucket(snapshot.Timestamp)] = &PortfolioHistoryPoint{
+			Time:   snapshot.Timestamp,
+			Equity: snapshot.Equity,
+			Source: HistorySourceBroker,
+		}
+	}
+
+	snapshots, err := s.storage.GetAccountSnapshots(start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		equity := snapshot.Equity
+		if !equity.IsPositive() {
+			equity = snapshot.PortfolioValue
+		}
+		if !equity.IsPositive() {
+			continue
+		}
+		key := bucket(snapshot.SnapshotTime)
+		if existing, ok := points[key]; ok && existing.Source == HistorySourceBroker {
+			continue
+		}
+		// Snapshots come oldest first, so the last one in a period wins
+		points[key] = &PortfolioHistoryPoint{
+			Time:   snapshot.SnapshotTime,
+			Equity: equity,
+			Source: HistorySourceSnapshot,
+		}
+	}
+
+	for _, point := range points {
+		history.Points = append(history.Points, point)
+		if point.Source == HistorySourceSnapshot {
+			history.Filled++
+		}
+	}
+	sort.Slice(history.Points, func(i, j int) bool {
+		return history.Points[i].Time.Before(history.Points[j].Time)
+	})
+
+	if len(history.Points) > 0 {
+		history.BaseValue = history.Points[0].Equity
+		for _, point := range history.Points {
+			point.ProfitLoss = point.Equity.Sub(history.BaseValue)
+			if history.BaseValue.IsPositive() {
+				point.ProfitLossPct = point.ProfitLoss.Div(history.BaseValue).Mul(decimal.NewFromInt(100)).InexactFloat64()
+			}
+		}
+	}
+
+	orders, err := s.storage.GetFilledOrders(start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		if order.FilledAt == nil || !order.FilledQty.IsPositive() {
+			continue
+		}
+		marker := &TradeMarker{
+			Time:     *order.FilledAt,
+			OrderID:  order.ID,
+			Symbol:   order.Symbol,
+			Side:     order.Side,
+			Qty:      order.FilledQty,
+			Strategy: order.Strategy,
+			Book:     order.Book,
+		}
+		if order.FilledAvgPrice != nil {
+			marker.Price = *order.FilledAvgPrice
+		}
+		marker.PointIndex = historyPointIndex(history.Points, marker.Time, bucket)
+		history.Trades = append(history.Trades, marker)
+	}
+
+	return history, nil
+}
+
+// brokerHistory fetches the broker's points for timeframe
+func (s *PortfolioHistoryService) brokerHistory(ctx context.Context, start, end time.Time, timeframe string) ([]*interfaces.EquitySnapshot, error) {
+	if timeframe == "1D" {
+		return s.tradingService.GetPortfolioHistory(ctx, start, end)
+	}
+	if s.intraday == nil {
+		return nil, ErrNotSupported
+	}
+	return s.intraday.GetIntradayPortfolioHistory(ctx, start, end, timeframe)
+}
+
+// historyPointIndex finds the point whose period holds t, or failing that the last
+// point before t
+func historyPointIndex(points []*PortfolioHistoryPoint, t time.Time, bucket func(time.Time) string) int {
+	key := bucket(t)
+	i := sort.Search(len(points), func(i int) bool {
+		return points[i].Time.After(t)
+	})
+	if i < len(points) && bucket(points[i].Time) == key {
+		return i
+	}
+	return i - 1
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// BenchmarkSymbol is the market proxy used for beta calculations
+const BenchmarkSymbol = "SPY"
+
+// PositionWeight is one holding's share of gross exposure
+type PositionWeight struct {
+	Symbol      string  `json:"symbol"`
+	Underlying  string  `json:"underlying"`
+	AssetClass  string  `json:"asset_class"`
+	Sector      string  `json:"sector"`
+	MarketValue float64 `json:"market_value"`
+	Weight      float64 `json:"weight"` // percent of gross exposure, negative for shorts
+	Beta        float64 `json:"beta"`
+}
+
+// CorrelationPair is the return correlation between two held underlyings
+type CorrelationPair struct {
+	SymbolA     string  `json:"symbol_a"`
+	SymbolB     string  `json:"symbol_b"`
+	Correlation float64 `json:"correlation"`
+}
+
+// PortfolioRiskReport describes correlation and concentration of current holdings
+type PortfolioRiskReport struct {
+	GeneratedAt          time.Time          `json:"generated_at"`
+	LookbackDays         int                `json:"lookback_days"`
+	GrossExposure        float64            `json:"gross_exposure"`
+	NetExposure          float64            `json:"net_exposure"`
+	Positions            []PositionWeight   `json:"positions"`
+	SectorWeights        map[string]float64 `json:"sector_weights"`
+	AssetClassWeights    map[string]float64 `json:"asset_class_weights"`
+	Correlations         []CorrelationPair  `json:"correlations"`
+	AverageCorrelation   float64            `json:"average_correlation"`
+	PortfolioBeta        float64            `json:"portfolio_beta"`
+	HerfindahlIndex      float64            `json:"herfindahl_index"`
+	DiversificationScore float64            `json:"diversification_score"` // 0 (concentrated) - 100 (diversified)
+	Warnings             []string           `json:"warnings,omitempty"`
+}
+
+// PortfolioRiskService analyzes correlation and concentration of holdings
+type PortfolioRiskService struct {
+	tradingService     interfaces.TradingService
+	dataService        interfaces.DataService
+	maxSymbolWeightPct float64
+	maxSectorWeightPct float64
+	logger             *logrus.Logger
+}
+
+// NewPortfolioRiskService creates a new portfolio risk service. Thresholds are
+// percentages of gross exposure; zero disables the corresponding warning.
+func NewPortfolioRiskService(tradingService interfaces.TradingService, dataService interfaces.DataService, maxSymbolWeightPct, maxSectorWeightPct float64) *PortfolioRiskService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &PortfolioRiskService{
+		tradingService:     tradingService,
+		dataService:        dataService,
+		maxSymbolWeightPct: maxSymbolWeightPct,
+		maxSectorWeightPct: maxSectorWeightPct,
+		logger:             logger,
+	}
+}
+
+// Analyze builds a portfolio risk report from current positions and daily
+// bars over the lookback window
+func (prs *PortfolioRiskService) Analyze(ctx context.Context, lookbackDays int) (*PortfolioRiskReport, error) {
+	if lookbackDays <= 0 {
+		lookbackDays = 90
+	}
+
+	positions, err := prs.tradingService.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	report := &PortfolioRiskReport{
+		GeneratedAt:       time.Now(),
+		LookbackDays:      lookbackDays,
+		Positions:         make([]PositionWeight, 0, len(positions)),
+		SectorWeights:     make(map[string]float64),
+		AssetClassWeights: make(map[string]float64),
+		Correlations:      make([]CorrelationPair, 0),
+	}
+
+	for _, p := range positions {
+		mv := p.MarketValue.InexactFloat64()
+		if p.Side == "short" && mv > 0 {
+			mv = -mv
+		}
+		report.GrossExposure += math.Abs(mv)
+		report.NetExposure += mv
+		report.Positions = append(report.Positions, PositionWeight{
+			Symbol:      p.Symbol,
+			Underlying:  underlyingSymbol(p.Symbol),
+			AssetClass:  assetClassFor(p.Symbol),
+			Sector:      SectorFor(p.Symbol),
+			MarketValue: mv,
+		})
+	}
+	if report.GrossExposure == 0 {
+		return report, nil
+	}
+
+	// Weights and concentration
+	for i := range report.Positions {
+		pw := &report.Positions[i]
+		pw.Weight = pw.MarketValue / report.GrossExposure * 100
+		absWeight := math.Abs(pw.Weight)
+		report.SectorWeights[pw.Sector] += absWeight
+		report.AssetClassWeights[pw.AssetClass] += absWeight
+		report.HerfindahlIndex += (absWeight / 100) * (absWeight / 100)
+	}
+
+	// Daily returns per underlying plus the benchmark
+	end := time.Now()
+	start := end.AddDate(0, 0, -lookbackDays)
+	returns := make(map[string]map[string]float64)
+	underlyings := make([]string, 0)
+	for _, pw := range report.Positions {
+		if _, ok := returns[pw.Underlying]; ok {
+			continue
+		}
+		r, err := prs.dailyReturns(ctx, pw.Underlying, start, end)
+		if err != nil {
+			prs.logger.WithError(err).WithField("symbol", pw.Underlying).Warn("Skipping symbol in correlation analysis")
+			returns[pw.Underlying] = nil
+			continue
+		}
+		returns[pw.Underlying] = r
+		underlyings = append(underlyings, pw.Underlying)
+	}
+	sort.Strings(underlyings)
+
+	benchmark, err := prs.dailyReturns(ctx, BenchmarkSymbol, start, end)
+	if err != nil {
+		prs.logger.WithError(err).Warn("Failed to load benchmark returns, beta unavailable")
+	}
+
+	// Beta per position and for the portfolio
+	for i := range report.Positions {
+		pw := &report.Positions[i]
+		if r := returns[pw.Underlying]; r != nil && benchmark != nil {
+			pw.Beta = beta(r, benchmark)
+			report.PortfolioBeta += pw.Weight / 100 * pw.Beta
+		}
+	}
+
+	// Pairwise correlations
+	sum := 0.0
+	for i := 0; i < len(underlyings); i++ {
+		for j := i + 1; j < len(underlyings); j++ {
+			a, b := underlyings[i], underlyings[j]
+			corr := correlation(returns[a], returns[b])
+			report.Correlations = append(report.Correlations, CorrelationPair{SymbolA: a, SymbolB: b, Correlation: corr})
+			sum += corr
+		}
+	}
+	if len(report.Correlations) > 0 {
+		report.AverageCorrelation = sum / float64(len(report.Correlations))
+	}
+	sort.Slice(report.Correlations, func(i, j int) bool {
+		return report.Correlations[i].Correlation > report.Correlations[j].Correlation
+	})
+
+	// Score rewards spreading exposure and penalizes positively correlated holdings
+	report.DiversificationScore = 100 * (1 - report.HerfindahlIndex) * (1 - math.Max(report.AverageCorrelation, 0))
+
+	report.Warnings = prs.concentrationWarnings(report)
+
+	return report, nil
+}
+
+// concentrationWarnings flags symbols and sectors above the configured thresholds
+func (prs *PortfolioRiskService) concentrationWarnings(report *PortfolioRiskReport) []string {
+	warnings := make([]string, 0)
+
+	if prs.maxSymbolWeightPct > 0 {
+		for _, pw := range report.Positions {
+			if math.Abs(pw.Weight) > prs.maxSymbolWeightPct {
+				warnings = append(warnings, fmt.Sprintf("%s is %.1f%% of gross exposure (limit %.1f%%)", pw.Symbol, math.Abs(pw.Weight), prs.maxSymbolWeightPct))
+			}
+		}
+	}
+	if prs.maxSectorWeightPct > 0 {
+		for sector, weight := range report.SectorWeights {
+			if weight > prs.maxSectorWeightPct {
+				warnings = append(warnings, fmt.Sprintf("%s sector is %.1f%% of gross exposure (limit %.1f%%)", sector, weight, prs.maxSectorWeightPct))
+			}
+		}
+	}
+	for _, pair := range report.Correlations {
+		if pair.Correlation > 0.8 {
+			warnings = append(warnings, fmt.Sprintf("%s and %s are highly correlated (%.2f)", pair.SymbolA, pair.SymbolB, pair.Correlation))
+		}
+	}
+
+	return warnings
+}
+
+// dailyReturns returns close-to-close returns keyed by date
+func (prs *PortfolioRiskService) dailyReturns(ctx context.Context, symbol string, start, end time.Time) (map[string]float64, error) {
+	bars, err := prs.dataService.GetHistoricalBars(ctx, symbol, start, end, "1Day")
+	if err != nil {
+		return nil, err
+	}
+	if len(bars) < 2 {
+		return nil, fmt.Errorf("not enough bars for %s", symbol)
+	}
+
+	returns := make(map[string]float64, len(bars)-1)
+	for i := 1; i < len(bars); i++ {
+		if bars[i-1].Close == 0 {
+			continue
+		}
+		returns[bars[i].Timestamp.Format("2006-01-02")] = bars[i].Close/bars[i-1].Close - 1
+	}
+	return returns, nil
+}
+
+// alignedReturns returns the two series restricted to their common dates
+func alignedReturns(a, b map[string]float64) ([]float64, []float64) {
+	xs := make([]float64, 0, len(a))
+	ys := make([]float64, 0, len(a))
+	for date, x := range a {
+		if y, ok := b[date]; ok {
+			xs = append(xs, x)
+			ys = append(ys, y)
+		}
+	}
+	return xs, ys
+}
+
+// correlation computes the Pearson correlation of two return series
+func correlation(a, b map[string]float64) float64 {
+	xs, ys := alignedReturns(a, b)
+	if len(xs) < 2 {
+		return 0
+	}
+
+	meanX, meanY := average(xs), average(ys)
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// beta computes the beta of a return series against a benchmark
+func beta(returns, benchmark map[string]float64) float64 {
+	xs, ys := alignedReturns(returns, benchmark)
+	if len(xs) < 2 {
+		return 0
+	}
+
+	meanX, meanY := average(xs), average(ys)
+	var cov, varY float64
+	for i := range xs {
+		cov += (xs[i] - meanX) * (ys[i] - meanY)
+		varY += (ys[i] - meanY) * (ys[i] - meanY)
+	}
+	if varY == 0 {
+		return 0
+	}
+	return cov / varY
+}
+
+// ConcentrationRule flags orders that would push a symbol or sector above a
+// share of portfolio value. In block mode it rejects them instead.
+type ConcentrationRule struct {
+	MaxSymbolPct float64
+	MaxSectorPct float64
+	Block        bool
+}
+
+func (ConcentrationRule) Name() string { return "concentration" }
+
+func (r ConcentrationRule) Check(ctx context.Context, check *RiskCheck) error {
+	if check.Order.Side != "buy" || check.Notional.IsZero() || !check.Account.PortfolioValue.IsPositive() {
+		return nil
+	}
+
+	symbol := underlyingSymbol(check.Order.Symbol)
+	sector := SectorFor(check.Order.Symbol)
+	symbolValue := check.Notional
+	sectorValue := check.Notional
+	for _, p := range check.Positions {
+		mv := p.MarketValue.Abs()
+		if underlyingSymbol(p.Symbol) == symbol {
+			symbolValue = symbolValue.Add(mv)
+		}
+		if sector != "Unknown" && SectorFor(p.Symbol) == sector {
+			sectorValue = sectorValue.Add(mv)
+		}
+	}
+
+	hundred := decimal.NewFromInt(100)
+	symbolPct := symbolValue.Div(check.Account.PortfolioValue).Mul(hundred).InexactFloat64()
+	sectorPct := sectorValue.Div(check.Account.PortfolioValue).Mul(hundred).InexactFloat64()
+
+	var message string
+	switch {
+	case r.MaxSymbolPct > 0 && symbolPct > r.MaxSymbolPct:
+		message = fmt.Sprintf("%s would be %.1f%% of portfolio (limit %.1f%%)", symbol, symbolPct, r.MaxSymbolPct)
+	case r.MaxSectorPct > 0 && sector != "Unknown" && sectorPct > r.MaxSectorPct:
+		message = fmt.Sprintf("%s sector would be %.1f%% of portfolio (limit %.1f%%)", sector, sectorPct, r.MaxSectorPct)
+	default:
+		return nil
+	}
+
+	if r.Block {
+		return fmt.Errorf("%s", message)
+	}
+	return &RiskWarning{Message: message}
+}
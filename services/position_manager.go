@@ -11,6 +11,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,18 +22,22 @@ type ManagedPosition struct {
 	Symbol            string                 `json:"symbol"`
 	Side              string                 `json:"side"` // "buy" or "sell"
 	Strategy          string                 `json:"strategy"` // "SWING_TRADE", "LONG_TERM", "DAY_TRADE"
+	Book              string                 `json:"book"`
+	Shadow            bool                   `json:"shadow,omitempty"` // opened in shadow mode, never sent to the broker
 
 	// Entry details
 	Quantity          float64                `json:"quantity"`
 	EntryPrice        float64                `json:"entry_price"`
 	EntryOrderID      string                 `json:"entry_order_id"`
 	EntryOrderType    string                 `json:"entry_order_type"` // "market", "limit"
+	EntryFilledQty    float64                `json:"entry_filled_qty,omitempty"` // below Quantity while the entry is still filling
 	AllocationDollars float64                `json:"allocation_dollars"`
 
 	// Risk management
 	StopLossPrice     float64                `json:"stop_loss_price"`
 	StopLossPercent   float64                `json:"stop_loss_percent"`
 	StopLossOrderID   string                 `json:"stop_loss_order_id,omitempty"`
+	InitialRisk       float64                `json:"initial_risk,omitempty"` // per share, entry to the stop at entry (1R)
 	TrailingStop      bool                   `json:"trailing_stop"`
 	TrailingPercent   float64                `json:"trailing_percent,omitempty"`
 
@@ -62,31 +68,31 @@ type ManagedPosition struct {
 // PartialExitConfig defines partial profit taking strategy
 type PartialExitConfig struct {
 	Enabled       bool    `json:"enabled"`
-	Percent       float64 `json:"percent"`        // % of position to exit
-	TargetPercent float64 `json:"target_percent"` // % gain to trigger partial exit
+	Percent       float64 `json:"percent" binding:"omitempty,gt=0,lte=100"` // % of position to exit
+	TargetPercent float64 `json:"target_percent" binding:"gte=0"`          // % gain to trigger partial exit
 	TargetPrice   float64 `json:"target_price"`   // Calculated target price
 }
 
 // PlaceManagedPositionRequest represents request to open a managed position
 type PlaceManagedPositionRequest struct {
-	Symbol            string              `json:"symbol" binding:"required"`
-	Side              string              `json:"side" binding:"required"` // "buy" or "sell"
+	Symbol            string              `json:"symbol" binding:"required,symbol"`
+	Side              string              `json:"side" binding:"required,oneof=buy sell"`
 	Strategy          string              `json:"strategy"` // "SWING_TRADE", "LONG_TERM", "DAY_TRADE"
 	AllocationDollars float64             `json:"allocation_dollars" binding:"required,gt=0"`
 
 	// Entry configuration
-	EntryStrategy     string              `json:"entry_strategy"` // "market", "limit"
-	EntryPrice        *float64            `json:"entry_price,omitempty"` // Required for limit orders
+	EntryStrategy     string              `json:"entry_strategy" binding:"omitempty,oneof=market limit"`
+	EntryPrice        *float64            `json:"entry_price,omitempty" binding:"required_if=EntryStrategy limit,omitempty,gt=0"`
 
 	// Risk management (one of these required)
-	StopLossPrice     *float64            `json:"stop_loss_price,omitempty"`
-	StopLossPercent   *float64            `json:"stop_loss_percent,omitempty"`
+	StopLossPrice     *float64            `json:"stop_loss_price,omitempty" binding:"required_without=StopLossPercent,omitempty,gt=0"`
+	StopLossPercent   *float64            `json:"stop_loss_percent,omitempty" binding:"omitempty,gt=0,lt=100"`
 	TrailingStop      bool                `json:"trailing_stop"`
-	TrailingPercent   float64             `json:"trailing_percent,omitempty"`
+	TrailingPercent   float64             `json:"trailing_percent,omitempty" binding:"required_if=TrailingStop true,omitempty,gt=0,lt=100"`
 
 	// Profit targets (one of these required)
-	TakeProfitPrice   *float64            `json:"take_profit_price,omitempty"`
-	TakeProfitPercent *float64            `json:"take_profit_percent,omitempty"`
+	TakeProfitPrice   *float64            `json:"take_profit_price,omitempty" binding:"required_without=TakeProfitPercent,omitempty,gt=0"`
+	TakeProfitPercent *float64            `json:"take_profit_percent,omitempty" binding:"omitempty,gt=0"`
 
 	// Partial exit (optional)
 	PartialExit       *PartialExitConfig  `json:"partial_exit,omitempty"`
@@ -94,6 +100,17 @@ type PlaceManagedPositionRequest struct {
 	// Metadata
 	Notes             string              `json:"notes,omitempty"`
 	Tags              []string            `json:"tags,omitempty"`
+
+	// Validate, size and risk-check without placing any orders
+	DryRun            bool                `json:"dry_run"`
+}
+
+// ManagedPositionPreview is returned for dry-run managed positions
+type ManagedPositionPreview struct {
+	DryRun       bool                     `json:"dry_run"`
+	Position     *ManagedPosition         `json:"position"`
+	EntryRequest alpaca.PlaceOrderRequest `json:"entry_request"`
+	Risk         *RiskDecision            `json:"risk,omitempty"`
 }
 
 // PositionManager handles automated position management
@@ -101,8 +118,15 @@ type PositionManager struct {
 	tradingService interfaces.TradingService
 	dataService    interfaces.DataService
 	storageService *database.LocalStorage
+	riskManager    *RiskManager
+	auditor        *OrderAuditor
+	dryRun         bool
+
+	expiryExit     time.Duration // close same-day option expiries this long before the close, 0 disables
+	hours          *MarketHours  // nil manages positions at all hours
 
 	positions      map[string]*ManagedPosition // position_id -> position
+	positionLocks  map[string]*sync.Mutex      // position_id -> lock held while a position is checked or repaired
 	mu             sync.RWMutex
 	logger         *logrus.Logger
 
@@ -115,6 +139,9 @@ func NewPositionManager(
 	tradingService interfaces.TradingService,
 	dataService interfaces.DataService,
 	storageService *database.LocalStorage,
+	riskManager *RiskManager,
+	auditor *OrderAuditor,
+	dryRun bool,
 ) *PositionManager {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.TextFormatter{
@@ -127,7 +154,11 @@ func NewPositionManager(
 		tradingService: tradingService,
 		dataService:    dataService,
 		storageService: storageService,
+		riskManager:    riskManager,
+		auditor:        auditor,
+		dryRun:         dryRun,
 		positions:      make(map[string]*ManagedPosition),
+		positionLocks:  make(map[string]*sync.Mutex),
 		logger:         logger,
 		ctx:            ctx,
 		cancel:         cancel,
@@ -141,6 +172,25 @@ func NewPositionManager(
 	return pm
 }
 
+// SetExpiryExit closes managed positions in options expiring today this
+// long before the 4pm ET close, so they are never carried into expiry
+func (pm *PositionManager) SetExpiryExit(before time.Duration) {
+	pm.expiryExit = before
+}
+
+// SetMarketHours holds trailing stops still while a position's market is
+// closed, since quotes outside the session would move them on prices the
+// stop order cannot trade at. Same-day expiries are then also closed ahead
+// of early closes rather than the usual 16:00.
+func (pm *PositionManager) SetMarketHours(hours *MarketHours) {
+	pm.hours = hours
+}
+
+// IsDryRun reports whether the manager is globally in dry-run mode
+func (pm *PositionManager) IsDryRun() bool {
+	return pm.dryRun
+}
+
 // PlaceManagedPosition opens a new managed position with automated risk management
 func (pm *PositionManager) PlaceManagedPosition(ctx context.Context, req *PlaceManagedPositionRequest) (*ManagedPosition, error) {
 	pm.logger.WithFields(logrus.Fields{
@@ -149,15 +199,88 @@ func (pm *PositionManager) PlaceManagedPosition(ctx context.Context, req *PlaceM
 		"allocation": req.AllocationDollars,
 	}).Info("Placing managed position")
 
+	if pm.dryRun || req.DryRun {
+		return nil, fmt.Errorf("dry run enabled: use PreviewManagedPosition")
+	}
+
+	position, order, decision, err := pm.buildManagedPosition(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the position before its entry order goes out, so a restart in
+	// between finds the order by its client order ID
+	pm.mu.Lock()
+	pm.positions[position.ID] = position
+	pm.mu.Unlock()
+	if err := pm.savePositionToDB(position); err != nil {
+		pm.logger.WithError(err).Error("Failed to save position to database")
+	}
+
+	// Place entry order
+	if err := pm.placeEntryOrder(WithRiskDecision(ctx, decision), position, order); err != nil {
+		pm.mu.Lock()
+		delete(pm.positions, position.ID)
+		pm.mu.Unlock()
+		if err := pm.storageService.DeleteManagedPosition(position.ID); err != nil {
+			pm.logger.WithError(err).Error("Failed to delete position from database")
+		}
+		return nil, fmt.Errorf("failed to place entry order: %w", err)
+	}
+
+	// Save to database
+	if err := pm.savePositionToDB(position); err != nil {
+		pm.logger.WithError(err).Error("Failed to save position to database")
+	}
+
+	pm.logger.WithFields(logrus.Fields{
+		"position_id":       position.ID,
+		"entry_order_id":    position.EntryOrderID,
+		"quantity":          position.Quantity,
+		"entry_price":       position.EntryPrice,
+		"stop_loss":         position.StopLossPrice,
+		"take_profit":       position.TakeProfitPrice,
+		"risk_reward_ratio": position.TakeProfitPercent / position.StopLossPercent,
+	}).Info("Managed position created")
+
+	return position, nil
+}
+
+// PreviewManagedPosition runs validation, sizing and risk checks for a managed
+// position and returns the entry request without submitting anything
+func (pm *PositionManager) PreviewManagedPosition(ctx context.Context, req *PlaceManagedPositionRequest) (*ManagedPositionPreview, error) {
+	position, order, decision, err := pm.buildManagedPosition(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	position.Status = "DRY_RUN"
+	pm.auditor.RecordUnsubmitted(ctx, order, AuditOutcomeDryRun, decision, nil)
+
+	pm.logger.WithFields(logrus.Fields{
+		"symbol":   position.Symbol,
+		"quantity": position.Quantity,
+	}).Info("Dry run: managed position not submitted")
+
+	return &ManagedPositionPreview{
+		DryRun:       true,
+		Position:     position,
+		EntryRequest: BuildOrderRequest(order),
+		Risk:         decision,
+	}, nil
+}
+
+// buildManagedPosition validates and sizes a request, builds its entry order
+// and runs it through the risk manager
+func (pm *PositionManager) buildManagedPosition(ctx context.Context, req *PlaceManagedPositionRequest) (*ManagedPosition, *interfaces.Order, *RiskDecision, error) {
 	// Validate request
 	if err := pm.validateRequest(req); err != nil {
-		return nil, fmt.Errorf("invalid request: %w", err)
+		return nil, nil, nil, fmt.Errorf("invalid request: %w", err)
 	}
 
 	// Get current price for calculations
 	currentPrice, err := pm.getCurrentPrice(ctx, req.Symbol)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current price: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to get current price: %w", err)
 	}
 
 	// Calculate position parameters
@@ -166,10 +289,44 @@ func (pm *PositionManager) PlaceManagedPosition(ctx context.Context, req *PlaceM
 		entryPrice = *req.EntryPrice
 	}
 
-	quantity := pm.calculateQuantity(req.AllocationDollars, entryPrice)
+	// Scale the allocation down when sizing rules (e.g. market regime) ask for it
+	allocation := req.AllocationDollars
+	if pm.riskManager != nil {
+		if scale, reasons := pm.riskManager.PositionSizeMultiplier(ctx); scale < 1 {
+			allocation *= scale
+			pm.logger.WithFields(logrus.Fields{
+				"symbol":     req.Symbol,
+				"requested":  req.AllocationDollars,
+				"allocation": allocation,
+				"reasons":    reasons,
+			}).Info("Position size scaled by risk rules")
+		}
+	}
+	if allocation <= 0 {
+		return nil, nil, nil, fmt.Errorf("position size scaled to zero by risk rules")
+	}
+
+	quantity := pm.calculateQuantity(allocation, entryPrice)
 
 	// Calculate stop loss
 	stopLossPrice := pm.calculateStopLoss(entryPrice, req.StopLossPrice, req.StopLossPercent, req.Side)
+
+	// Widen the stop when stop rules (e.g. a macro event nearby) ask for it
+	if pm.riskManager != nil {
+		if scale, reasons := pm.riskManager.StopDistanceMultiplier(ctx); scale > 1 {
+			requested := stopLossPrice
+			stopLossPrice = entryPrice - (entryPrice-stopLossPrice)*scale
+			if stopLossPrice < 0 {
+				stopLossPrice = 0
+			}
+			pm.logger.WithFields(logrus.Fields{
+				"symbol":    req.Symbol,
+				"requested": requested,
+				"stop_loss": stopLossPrice,
+				"reasons":   reasons,
+			}).Info("Stop loss widened by risk rules")
+		}
+	}
 	stopLossPercent := math.Abs((stopLossPrice - entryPrice) / entryPrice * 100)
 
 	// Calculate take profit
@@ -181,18 +338,26 @@ func (pm *PositionManager) PlaceManagedPosition(ctx context.Context, req *PlaceM
 		req.PartialExit.TargetPrice = pm.calculatePartialExitPrice(entryPrice, req.PartialExit.TargetPercent, req.Side)
 	}
 
+	// Untagged positions are attributed to their order source
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = OrderSourceFrom(ctx)
+	}
+
 	// Create managed position
 	position := &ManagedPosition{
 		ID:                pm.generatePositionID(),
 		Symbol:            req.Symbol,
 		Side:              req.Side,
-		Strategy:          req.Strategy,
+		Strategy:          strategy,
+		Book:              BookFrom(ctx),
 		Quantity:          quantity,
 		EntryPrice:        entryPrice,
 		EntryOrderType:    req.EntryStrategy,
-		AllocationDollars: req.AllocationDollars,
+		AllocationDollars: allocation,
 		StopLossPrice:     stopLossPrice,
 		StopLossPercent:   stopLossPercent,
+		InitialRisk:       initialRisk(entryPrice, stopLossPrice),
 		TrailingStop:      req.TrailingStop,
 		TrailingPercent:   req.TrailingPercent,
 		TakeProfitPrice:   takeProfitPrice,
@@ -207,123 +372,146 @@ func (pm *PositionManager) PlaceManagedPosition(ctx context.Context, req *PlaceM
 		Tags:              req.Tags,
 	}
 
-	// Place entry order
-	if err := pm.placeEntryOrder(ctx, position); err != nil {
-		return nil, fmt.Errorf("failed to place entry order: %w", err)
-	}
-
-	// Store position
-	pm.mu.Lock()
-	pm.positions[position.ID] = position
-	pm.mu.Unlock()
+	order := pm.buildEntryOrder(position)
 
-	// Save to database
-	if err := pm.savePositionToDB(position); err != nil {
-		pm.logger.WithError(err).Error("Failed to save position to database")
+	var decision *RiskDecision
+	if pm.riskManager != nil {
+		decision, err = pm.riskManager.Evaluate(ctx, order)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !decision.Approved {
+			rejected := &RiskRejectedError{Decision: decision}
+			pm.auditor.RecordUnsubmitted(ctx, order, AuditOutcomeRejected, decision, rejected)
+			return nil, nil, nil, rejected
+		}
 	}
 
-	pm.logger.WithFields(logrus.Fields{
-		"position_id":       position.ID,
-		"entry_order_id":    position.EntryOrderID,
-		"quantity":          quantity,
-		"entry_price":       entryPrice,
-		"stop_loss":         stopLossPrice,
-		"take_profit":       takeProfitPrice,
-		"risk_reward_ratio": takeProfitPercent / stopLossPercent,
-	}).Info("Managed position created")
-
-	return position, nil
+	return position, order, decision, nil
 }
 
-// placeEntryOrder places the initial entry order
-func (pm *PositionManager) placeEntryOrder(ctx context.Context, position *ManagedPosition) error {
+// buildEntryOrder creates the initial entry order for a position
+func (pm *PositionManager) buildEntryOrder(position *ManagedPosition) *interfaces.Order {
 	orderType := "market"
 	if position.EntryOrderType == "limit" {
 		orderType = "limit"
 	}
 
 	order := &interfaces.Order{
-		Symbol:      position.Symbol,
-		Qty:         position.Quantity,
-		Side:        position.Side,
-		Type:        orderType,
-		TimeInForce: "gtc",
-		Status:      "pending",
-		SubmittedAt: time.Now(),
+		ClientOrderID: managedClientOrderID(position.ID, orderRoleEntry),
+		Symbol:        position.Symbol,
+		Qty:           decimal.NewFromFloat(position.Quantity),
+		Side:          position.Side,
+		Type:          orderType,
+		TimeInForce:   "gtc",
+		Status:        "pending",
+		SubmittedAt:   time.Now(),
+		Strategy:      position.Strategy,
+		Book:          position.Book,
 	}
 
 	if orderType == "limit" {
-		order.LimitPrice = &position.EntryPrice
+		order.LimitPrice = decimalPtr(position.EntryPrice)
 	}
 
+	return order
+}
+
+// placeEntryOrder places the initial entry order
+func (pm *PositionManager) placeEntryOrder(ctx context.Context, position *ManagedPosition, order *interfaces.Order) error {
 	result, err := pm.tradingService.PlaceOrder(ctx, order)
 	if err != nil {
 		return err
 	}
 
 	position.EntryOrderID = result.OrderID
+	position.Shadow = IsShadowOrderID(result.OrderID)
 	position.Status = "PENDING"
 
 	return nil
 }
 
-// MonitorPositions monitors all active positions and manages risk
-func (pm *PositionManager) MonitorPositions(ctx context.Context) {
-	ticker := time.NewTicker(10 * time.Second) // Check every 10 seconds
-	defer ticker.Stop()
-
-	pm.logger.Info("Position monitoring started")
-
-	for {
-		select {
-		case <-ctx.Done():
-			pm.logger.Info("Position monitoring stopped")
-			return
-		case <-ticker.C:
-			pm.checkPositions(ctx)
-		}
+// positionContext tags ctx for the orders placed for a position: its book,
+// and shadow mode when the position was opened in shadow
+func positionContext(ctx context.Context, position *ManagedPosition) context.Context {
+	ctx = WithBook(ctx, position.Book)
+	if position.Shadow {
+		ctx = WithShadow(ctx)
 	}
+	return ctx
+}
+
+// CheckPositions runs one monitoring pass, for callers that drive the
+// manager on their own schedule such as a market replay
+func (pm *PositionManager) CheckPositions(ctx context.Context) {
+	pm.checkPositions(ctx)
 }
 
 // checkPositions checks all positions and manages their risk orders
 func (pm *PositionManager) checkPositions(ctx context.Context) {
-	pm.mu.RLock()
-	positions := make([]*ManagedPosition, 0, len(pm.positions))
-	for _, pos := range pm.positions {
-		positions = append(positions, pos)
+	for _, position := range pm.allPositions() {
+		pm.checkPosition(ctx, position)
 	}
-	pm.mu.RUnlock()
+}
 
-	for _, position := range positions {
-		if position.Status == "CLOSED" || position.Status == "STOPPED_OUT" {
-			continue
-		}
+// checkPosition checks one position and manages its risk orders. The
+// position is locked against the reconciler's repairs meanwhile, so a fill
+// seen by both never gets two sets of risk orders.
+func (pm *PositionManager) checkPosition(ctx context.Context, position *ManagedPosition) {
+	unlock := pm.lockPosition(position.ID)
+	defer unlock()
 
-		// Check if entry order filled
+	if position.Status == "CLOSED" || position.Status == "STOPPED_OUT" {
+		return
+	}
+	ctx = positionContext(ctx, position)
+
+	// Same-day expiries are closed ahead of the bell
+	if pm.expiresBeforeExit(ctx, position, time.Now()) {
+		pm.closeBeforeExpiry(ctx, position)
+		return
+	}
+
+	// Check if entry order filled. A position without an entry order
+	// ID is still being opened.
+	if position.Status == "PENDING" {
+		if position.EntryOrderID == "" {
+			return
+		}
+		pm.checkEntryOrder(ctx, position)
 		if position.Status == "PENDING" {
-			pm.checkEntryOrder(ctx, position)
-			continue
+			return
 		}
+	} else if entryFilling(position) {
+		pm.checkEntryOrder(ctx, position)
+	}
 
-		// Update current price and P&L
-		if err := pm.updatePositionPrice(ctx, position); err != nil {
-			pm.logger.WithError(err).WithField("symbol", position.Symbol).Error("Failed to update position price")
-			continue
-		}
+	// Update current price and P&L
+	if err := pm.updatePositionPrice(ctx, position); err != nil {
+		pm.logger.WithError(err).WithField("symbol", position.Symbol).Error("Failed to update position price")
+		return
+	}
 
-		// Check if we need to place/update risk orders
-		if position.Status == "ACTIVE" {
-			pm.manageRiskOrders(ctx, position)
-		}
+	// Check if we need to place/update risk orders
+	if position.Status == "ACTIVE" {
+		pm.manageRiskOrders(ctx, position)
+	}
 
-		// Check trailing stop
-		if position.TrailingStop {
-			pm.updateTrailingStop(ctx, position)
-		}
+	// Check trailing stop
+	if position.TrailingStop && pm.marketOpen(ctx, position.Symbol) {
+		pm.updateTrailingStop(ctx, position)
 	}
 }
 
-// checkEntryOrder checks if entry order has filled
+// marketOpen reports whether symbol's market is open, or true without
+// market hours
+func (pm *PositionManager) marketOpen(ctx context.Context, symbol string) bool {
+	return pm.hours == nil || pm.hours.IsOpen(ctx, marketAssetClass(symbol))
+}
+
+// checkEntryOrder checks the entry order's fills. The position becomes
+// active on the first partial fill, protecting what has filled so far, and
+// its risk orders grow with each later fill.
 func (pm *PositionManager) checkEntryOrder(ctx context.Context, position *ManagedPosition) {
 	order, err := pm.tradingService.GetOrder(ctx, position.EntryOrderID)
 	if err != nil {
@@ -331,39 +519,123 @@ func (pm *PositionManager) checkEntryOrder(ctx context.Context, position *Manage
 		return
 	}
 
-	if order.Status == "filled" {
-		position.Status = "ACTIVE"
-		position.EntryPrice = *order.FilledAvgPrice
+	filled := order.FilledQty.InexactFloat64()
+	switch {
+	case filled > position.EntryFilledQty && order.FilledAvgPrice != nil:
+		pm.applyEntryFill(ctx, position, order)
+	case position.Status != "PENDING" && isFinalOrderStatus(order.Status):
+		// The rest of the entry was canceled or expired, so the position
+		// is whatever filled
+		position.Quantity = position.EntryFilledQty
 		position.UpdatedAt = time.Now()
-
 		pm.logger.WithFields(logrus.Fields{
 			"position_id": position.ID,
-			"symbol":      position.Symbol,
-			"fill_price":  position.EntryPrice,
-		}).Info("Entry order filled - position now active")
-
-		// Place risk management orders
+			"status":      order.Status,
+			"quantity":    position.Quantity,
+		}).Info("Entry order ended partially filled")
 		pm.placeRiskOrders(ctx, position)
-
-		// Save to database
 		pm.savePositionToDB(position)
 	}
 }
 
-// placeRiskOrders places stop loss and take profit orders
+// applyEntryFill takes the entry order's filled quantity and average price
+// into the position
+func (pm *PositionManager) applyEntryFill(ctx context.Context, position *ManagedPosition, order *interfaces.Order) {
+	filled := order.FilledQty.InexactFloat64()
+	activating := position.Status == "PENDING"
+	if activating {
+		position.RemainingQty = filled
+	} else {
+		position.RemainingQty += filled - position.EntryFilledQty
+	}
+	position.EntryFilledQty = filled
+	if order.Status == "filled" {
+		position.Quantity = filled
+	}
+	position.Status = "ACTIVE"
+	position.EntryPrice = order.FilledAvgPrice.InexactFloat64()
+	position.InitialRisk = initialRisk(position.EntryPrice, position.StopLossPrice)
+	position.UpdatedAt = time.Now()
+
+	fields := logrus.Fields{
+		"position_id": position.ID,
+		"symbol":      position.Symbol,
+		"fill_price":  position.EntryPrice,
+		"filled_qty":  filled,
+		"quantity":    position.Quantity,
+	}
+	switch {
+	case activating && order.Status == "filled":
+		pm.logger.WithFields(fields).Info("Entry order filled - position now active")
+	case activating:
+		pm.logger.WithFields(fields).Info("Entry order partially filled - position now active")
+	default:
+		pm.logger.WithFields(fields).Info("Entry order filled further")
+	}
+
+	// Place risk management orders, resized to the new quantity
+	if !activating {
+		pm.cancelSizedRiskOrders(ctx, position)
+	}
+	pm.placeRiskOrders(ctx, position)
+
+	// Save to database
+	pm.savePositionToDB(position)
+}
+
+// stopEntry cancels an entry that is still filling when its position
+// closes, and sizes the position to what filled
+func (pm *PositionManager) stopEntry(ctx context.Context, position *ManagedPosition) {
+	if !entryFilling(position) {
+		return
+	}
+	if err := pm.tradingService.CancelOrder(ctx, position.EntryOrderID); err != nil {
+		pm.logger.WithError(err).WithField("order_id", position.EntryOrderID).Warn("Failed to cancel entry order")
+	}
+	position.Quantity = position.EntryFilledQty
+}
+
+// entryFilling reports whether an active position's entry order is still
+// working after a partial fill
+func entryFilling(position *ManagedPosition) bool {
+	return position.Status == "ACTIVE" && position.EntryFilledQty > 0 && position.EntryFilledQty < position.Quantity
+}
+
+// cancelSizedRiskOrders cancels the stop loss and take profit orders so
+// they can be placed again for the position's new quantity. An order that
+// can't be canceled, for example because it just filled, is kept.
+func (pm *PositionManager) cancelSizedRiskOrders(ctx context.Context, position *ManagedPosition) {
+	for _, orderID := range []*string{&position.StopLossOrderID, &position.TakeProfitOrderID} {
+		if *orderID == "" {
+			continue
+		}
+		if err := pm.tradingService.CancelOrder(ctx, *orderID); err != nil {
+			pm.logger.WithError(err).WithField("order_id", *orderID).Warn("Failed to cancel risk order for resizing")
+			continue
+		}
+		*orderID = ""
+	}
+}
+
+// placeRiskOrders places the stop loss and take profit orders a position
+// does not have yet. Orders recovered after a restart are kept.
 func (pm *PositionManager) placeRiskOrders(ctx context.Context, position *ManagedPosition) {
 	// Place stop loss order
-	if err := pm.placeStopLossOrder(ctx, position); err != nil {
-		pm.logger.WithError(err).Error("Failed to place stop loss order")
+	if position.StopLossOrderID == "" {
+		if err := pm.placeStopLossOrder(ctx, position); err != nil {
+			pm.logger.WithError(err).Error("Failed to place stop loss order")
+		}
 	}
 
 	// Place take profit order
-	if err := pm.placeTakeProfitOrder(ctx, position); err != nil {
-		pm.logger.WithError(err).Error("Failed to place take profit order")
+	if position.TakeProfitOrderID == "" {
+		if err := pm.placeTakeProfitOrder(ctx, position); err != nil {
+			pm.logger.WithError(err).Error("Failed to place take profit order")
+		}
 	}
 
-	// Place partial exit order if configured
-	if position.PartialExit != nil && position.PartialExit.Enabled {
+	// Place partial exit order if configured, once the entry is complete
+	if position.PartialExit != nil && position.PartialExit.Enabled && len(position.PartialExitOrders) == 0 && !entryFilling(position) {
 		if err := pm.placePartialExitOrder(ctx, position); err != nil {
 			pm.logger.WithError(err).Error("Failed to place partial exit order")
 		}
@@ -378,14 +650,16 @@ func (pm *PositionManager) placeStopLossOrder(ctx context.Context, position *Man
 	}
 
 	order := &interfaces.Order{
-		Symbol:      position.Symbol,
-		Qty:         position.RemainingQty,
-		Side:        exitSide,
-		Type:        "stop",
-		TimeInForce: "gtc",
-		StopPrice:   &position.StopLossPrice,
-		Status:      "pending",
-		SubmittedAt: time.Now(),
+		ClientOrderID: managedClientOrderID(position.ID, orderRoleStopLoss),
+		Symbol:        position.Symbol,
+		Qty:           decimal.NewFromFloat(position.RemainingQty),
+		Side:          exitSide,
+		Type:          "stop",
+		TimeInForce:   "gtc",
+		StopPrice:     decimalPtr(position.StopLossPrice),
+		Status:        "pending",
+		SubmittedAt:   time.Now(),
+		Book:          position.Book,
 	}
 
 	result, err := pm.tradingService.PlaceOrder(ctx, order)
@@ -411,14 +685,16 @@ func (pm *PositionManager) placeTakeProfitOrder(ctx context.Context, position *M
 	}
 
 	order := &interfaces.Order{
-		Symbol:      position.Symbol,
-		Qty:         position.RemainingQty,
-		Side:        exitSide,
-		Type:        "limit",
-		TimeInForce: "gtc",
-		LimitPrice:  &position.TakeProfitPrice,
-		Status:      "pending",
-		SubmittedAt: time.Now(),
+		ClientOrderID: managedClientOrderID(position.ID, orderRoleTakeProfit),
+		Symbol:        position.Symbol,
+		Qty:           decimal.NewFromFloat(position.RemainingQty),
+		Side:          exitSide,
+		Type:          "limit",
+		TimeInForce:   "gtc",
+		LimitPrice:    decimalPtr(position.TakeProfitPrice),
+		Status:        "pending",
+		SubmittedAt:   time.Now(),
+		Book:          position.Book,
 	}
 
 	result, err := pm.tradingService.PlaceOrder(ctx, order)
@@ -446,14 +722,16 @@ func (pm *PositionManager) placePartialExitOrder(ctx context.Context, position *
 	partialQty := position.Quantity * (position.PartialExit.Percent / 100.0)
 
 	order := &interfaces.Order{
-		Symbol:      position.Symbol,
-		Qty:         partialQty,
-		Side:        exitSide,
-		Type:        "limit",
-		TimeInForce: "gtc",
-		LimitPrice:  &position.PartialExit.TargetPrice,
-		Status:      "pending",
-		SubmittedAt: time.Now(),
+		ClientOrderID: managedClientOrderID(position.ID, orderRolePartialExit),
+		Symbol:        position.Symbol,
+		Qty:           decimal.NewFromFloat(partialQty),
+		Side:          exitSide,
+		Type:          "limit",
+		TimeInForce:   "gtc",
+		LimitPrice:    decimalPtr(position.PartialExit.TargetPrice),
+		Status:        "pending",
+		SubmittedAt:   time.Now(),
+		Book:          position.Book,
 	}
 
 	result, err := pm.tradingService.PlaceOrder(ctx, order)
@@ -478,11 +756,13 @@ func (pm *PositionManager) manageRiskOrders(ctx context.Context, position *Manag
 	if position.StopLossOrderID != "" {
 		order, err := pm.tradingService.GetOrder(ctx, position.StopLossOrderID)
 		if err == nil && order.Status == "filled" {
+			pm.stopEntry(ctx, position)
 			position.Status = "STOPPED_OUT"
 			now := time.Now()
 			position.ClosedAt = &now
 			pm.logger.WithField("position_id", position.ID).Info("Position stopped out")
 			pm.savePositionToDB(position)
+			pm.recordTrade(position, exitFillPrice(order, position.StopLossPrice))
 			return
 		}
 	}
@@ -491,11 +771,13 @@ func (pm *PositionManager) manageRiskOrders(ctx context.Context, position *Manag
 	if position.TakeProfitOrderID != "" {
 		order, err := pm.tradingService.GetOrder(ctx, position.TakeProfitOrderID)
 		if err == nil && order.Status == "filled" {
+			pm.stopEntry(ctx, position)
 			position.Status = "CLOSED"
 			now := time.Now()
 			position.ClosedAt = &now
 			pm.logger.WithField("position_id", position.ID).Info("Position closed at profit target")
 			pm.savePositionToDB(position)
+			pm.recordTrade(position, exitFillPrice(order, position.TakeProfitPrice))
 			return
 		}
 	}
@@ -505,7 +787,7 @@ func (pm *PositionManager) manageRiskOrders(ctx context.Context, position *Manag
 		order, err := pm.tradingService.GetOrder(ctx, orderID)
 		if err == nil && order.Status == "filled" {
 			position.Status = "PARTIAL"
-			position.RemainingQty -= order.FilledQty
+			position.RemainingQty -= order.FilledQty.InexactFloat64()
 			pm.logger.WithFields(logrus.Fields{
 				"position_id":   position.ID,
 				"filled_qty":    order.FilledQty,
@@ -530,6 +812,7 @@ func (pm *PositionManager) updateTrailingStop(ctx context.Context, position *Man
 			// Update stop price and place new order
 			position.StopLossPrice = newStopPrice
 			pm.placeStopLossOrder(ctx, position)
+			pm.savePositionToDB(position)
 
 			pm.logger.WithFields(logrus.Fields{
 				"position_id":    position.ID,
@@ -546,6 +829,7 @@ func (pm *PositionManager) updateTrailingStop(ctx context.Context, position *Man
 
 			position.StopLossPrice = newStopPrice
 			pm.placeStopLossOrder(ctx, position)
+			pm.savePositionToDB(position)
 
 			pm.logger.WithFields(logrus.Fields{
 				"position_id":    position.ID,
@@ -577,6 +861,66 @@ func (pm *PositionManager) updatePositionPrice(ctx context.Context, position *Ma
 	return nil
 }
 
+// allPositions returns every position held in memory
+func (pm *PositionManager) allPositions() []*ManagedPosition {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	positions := make([]*ManagedPosition, 0, len(pm.positions))
+	for _, pos := range pm.positions {
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+// lockPosition locks one position against concurrent checks and repairs,
+// returning the unlock function
+func (pm *PositionManager) lockPosition(positionID string) func() {
+	pm.mu.Lock()
+	lock, ok := pm.positionLocks[positionID]
+	if !ok {
+		lock = &sync.Mutex{}
+		pm.positionLocks[positionID] = lock
+	}
+	pm.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// positionSnapshot returns a copy of a position taken while it is not
+// being checked, for callers that read it from another goroutine
+func (pm *PositionManager) positionSnapshot(position *ManagedPosition) *ManagedPosition {
+	unlock := pm.lockPosition(position.ID)
+	defer unlock()
+
+	snapshot := *position
+	snapshot.PartialExitOrders = append([]string(nil), position.PartialExitOrders...)
+	if position.PartialExit != nil {
+		partialExit := *position.PartialExit
+		snapshot.PartialExit = &partialExit
+	}
+	return &snapshot
+}
+
+// repairPosition runs repair on the live position while the monitor is not
+// checking it. The caller's view may be stale by then, so repair only runs
+// when stillNeeded holds for the live position; it reports whether it ran.
+func (pm *PositionManager) repairPosition(positionID string, stillNeeded func(position *ManagedPosition) bool, repair func(position *ManagedPosition)) bool {
+	position, err := pm.GetManagedPosition(positionID)
+	if err != nil {
+		return false
+	}
+
+	unlock := pm.lockPosition(positionID)
+	defer unlock()
+	if !stillNeeded(position) {
+		return false
+	}
+	repair(position)
+	return true
+}
+
 // GetManagedPosition retrieves a managed position by ID
 func (pm *PositionManager) GetManagedPosition(positionID string) (*ManagedPosition, error) {
 	pm.mu.RLock()
@@ -630,6 +974,7 @@ func (pm *PositionManager) CloseManagedPosition(ctx context.Context, positionID
 	if !exists {
 		return fmt.Errorf("position not found: %s", positionID)
 	}
+	ctx = positionContext(ctx, position)
 
 	// Cancel all open orders (ignore errors - orders may already be cancelled or market closed)
 
@@ -664,6 +1009,11 @@ func (pm *PositionManager) CloseManagedPosition(ctx context.Context, positionID
 		}
 	}
 
+	// A partly filled entry leaves a position of what filled
+	if entryFilling(position) {
+		position.Quantity = position.EntryFilledQty
+	}
+
 	// Place market order to close remaining position (ONLY if position is ACTIVE/PARTIAL - i.e., entry was filled)
 	if position.Status == "ACTIVE" || position.Status == "PARTIAL" {
 		if position.RemainingQty > 0 {
@@ -673,13 +1023,15 @@ func (pm *PositionManager) CloseManagedPosition(ctx context.Context, positionID
 			}
 
 			order := &interfaces.Order{
-				Symbol:      position.Symbol,
-				Qty:         position.RemainingQty,
-				Side:        exitSide,
-				Type:        "market",
-				TimeInForce: "day",
-				Status:      "pending",
-				SubmittedAt: time.Now(),
+				ClientOrderID: managedClientOrderID(position.ID, orderRoleExit),
+				Symbol:        position.Symbol,
+				Qty:           decimal.NewFromFloat(position.RemainingQty),
+				Side:          exitSide,
+				Type:          "market",
+				TimeInForce:   "day",
+				Status:        "pending",
+				SubmittedAt:   time.Now(),
+				Book:          position.Book,
 			}
 
 			_, err := pm.tradingService.PlaceOrder(ctx, order)
@@ -696,6 +1048,7 @@ func (pm *PositionManager) CloseManagedPosition(ctx context.Context, positionID
 		pm.logger.WithField("position_id", position.ID).Info("Closed pending position (entry order was never filled)")
 	}
 
+	wasFilled := position.Status == "ACTIVE" || position.Status == "PARTIAL"
 	position.Status = "CLOSED"
 	now := time.Now()
 	position.ClosedAt = &now
@@ -703,11 +1056,66 @@ func (pm *PositionManager) CloseManagedPosition(ctx context.Context, positionID
 	// Save to database
 	pm.savePositionToDB(position)
 
+	// Only filled positions produce a trade
+	if wasFilled {
+		pm.recordTrade(position, position.CurrentPrice)
+	}
+
 	pm.logger.WithField("position_id", positionID).Info("Position manually closed")
 
 	return nil
 }
 
+// ReleaseManagedPositions marks the open managed positions whose symbol
+// matches CLOSED without touching their orders, for callers closing the
+// broker positions themselves. Shadow positions are left alone. Each
+// position is released under its lock, so the monitor can't be placing its
+// stops meanwhile. It returns the released position IDs.
+func (pm *PositionManager) ReleaseManagedPositions(match func(symbol string) bool, note string) []string {
+	pm.mu.RLock()
+	candidates := make([]string, 0)
+	for _, position := range pm.positions {
+		if !position.Shadow {
+			candidates = append(candidates, position.ID)
+		}
+	}
+	pm.mu.RUnlock()
+
+	open := func(position *ManagedPosition) bool {
+		if !match(position.Symbol) {
+			return false
+		}
+		return position.Status == "PENDING" || position.Status == "ACTIVE" || position.Status == "PARTIAL"
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, positionID := range candidates {
+		released := pm.repairPosition(positionID, open, func(position *ManagedPosition) {
+			wasFilled := position.Status == "ACTIVE" || position.Status == "PARTIAL"
+			now := time.Now()
+			position.Status = "CLOSED"
+			position.ClosedAt = &now
+			position.UpdatedAt = now
+			if position.Notes != "" {
+				position.Notes += "; "
+			}
+			position.Notes += note
+
+			if err := pm.savePositionToDB(position); err != nil {
+				pm.logger.WithError(err).WithField("position_id", position.ID).Error("Failed to save released position")
+			}
+			if wasFilled {
+				pm.recordTrade(position, position.CurrentPrice)
+			}
+		})
+		if released {
+			ids = append(ids, positionID)
+		}
+	}
+
+	return ids
+}
+
 // Helper functions
 
 func (pm *PositionManager) validateRequest(req *PlaceManagedPositionRequest) error {
@@ -759,6 +1167,15 @@ func (pm *PositionManager) calculateStopLoss(entryPrice float64, stopPrice *floa
 	return entryPrice * (1 + *stopPercent/100.0)
 }
 
+// initialRisk is the per-share loss at the stop, 1R. It is zero without a
+// stop.
+func initialRisk(entryPrice, stopLossPrice float64) float64 {
+	if stopLossPrice <= 0 || entryPrice <= 0 {
+		return 0
+	}
+	return math.Abs(entryPrice - stopLossPrice)
+}
+
 func (pm *PositionManager) calculateTakeProfit(entryPrice float64, profitPrice *float64, profitPercent *float64, side string) float64 {
 	if profitPrice != nil {
 		return *profitPrice
@@ -779,6 +1196,12 @@ func (pm *PositionManager) calculatePartialExitPrice(entryPrice, targetPercent f
 	return entryPrice * (1 - targetPercent/100.0)
 }
 
+// decimalPtr converts a calculated price to the decimal form used on orders
+func decimalPtr(v float64) *decimal.Decimal {
+	d := decimal.NewFromFloat(v)
+	return &d
+}
+
 func (pm *PositionManager) generatePositionID() string {
 	return fmt.Sprintf("pos_%d", time.Now().UnixNano())
 }
@@ -796,7 +1219,7 @@ func (pm *PositionManager) loadPositionsFromDB() error {
 		return err
 	}
 
-	loaded := 0
+	positions := make(map[string]*ManagedPosition)
 	for _, dbPos := range dbPositions {
 		// Skip closed positions
 		if dbPos.Status == "CLOSED" || dbPos.Status == "STOPPED_OUT" {
@@ -805,16 +1228,78 @@ func (pm *PositionManager) loadPositionsFromDB() error {
 
 		// Convert DB position to managed position
 		position := pm.dbToManagedPosition(dbPos)
-
-		// Store in memory
-		pm.positions[position.ID] = position
-		loaded++
+		positions[position.ID] = position
 	}
 
-	pm.logger.WithField("count", loaded).Info("Loaded managed positions from database")
+	// Store in memory
+	pm.mu.Lock()
+	pm.positions = positions
+	pm.mu.Unlock()
+
+	pm.logger.WithField("count", len(positions)).Info("Loaded managed positions from database")
 	return nil
 }
 
+// Reload replaces the positions in memory with the stored ones, for an
+// instance taking over from another writer
+func (pm *PositionManager) Reload() error {
+	return pm.loadPositionsFromDB()
+}
+
+// recordTrade writes a closed managed position to the trade ledger. Shadow
+// positions stay out of it; their fills are in the shadow orders.
+func (pm *PositionManager) recordTrade(position *ManagedPosition, exitPrice float64) {
+	if position.Shadow {
+		return
+	}
+	exitTime := time.Now()
+	if position.ClosedAt != nil {
+		exitTime = *position.ClosedAt
+	}
+
+	pnlPerShare := exitPrice - position.EntryPrice
+	if position.Side == "sell" {
+		pnlPerShare = -pnlPerShare
+	}
+	pnlPercent := 0.0
+	if position.EntryPrice > 0 {
+		pnlPercent = pnlPerShare / position.EntryPrice * 100
+	}
+
+	trade := &models.DBTrade{
+		Symbol:       position.Symbol,
+		EntryPrice:   decimal.NewFromFloat(position.EntryPrice),
+		ExitPrice:    decimal.NewFromFloat(exitPrice),
+		Qty:          decimal.NewFromFloat(position.Quantity),
+		Side:         position.Side,
+		PnL:          decimal.NewFromFloat(pnlPerShare * position.Quantity),
+		PnLPercent:   pnlPercent,
+		EntryTime:    position.CreatedAt,
+		ExitTime:     exitTime,
+		Duration:     int64(exitTime.Sub(position.CreatedAt).Seconds()),
+		StrategyName: position.Strategy,
+		PositionID:   position.ID,
+		Book:         position.Book,
+	}
+	if position.InitialRisk > 0 {
+		r := pnlPerShare / position.InitialRisk
+		trade.InitialRisk = decimal.NewFromFloat(position.InitialRisk * position.Quantity)
+		trade.RMultiple = &r
+	}
+
+	if err := pm.storageService.SaveTrade(trade); err != nil {
+		pm.logger.WithError(err).WithField("position_id", position.ID).Error("Failed to record trade")
+	}
+}
+
+// exitFillPrice returns the average fill price of an exit order, or fallback
+func exitFillPrice(order *interfaces.Order, fallback float64) float64 {
+	if order != nil && order.FilledAvgPrice != nil && order.FilledAvgPrice.IsPositive() {
+		return order.FilledAvgPrice.InexactFloat64()
+	}
+	return fallback
+}
+
 // savePositionToDB saves a managed position to database
 func (pm *PositionManager) savePositionToDB(position *ManagedPosition) error {
 	dbPosition := pm.managedPositionToDB(position)
@@ -834,14 +1319,17 @@ func (pm *PositionManager) managedPositionToDB(pos *ManagedPosition) *models.DBM
 		Symbol:            pos.Symbol,
 		Side:              pos.Side,
 		Strategy:          pos.Strategy,
+		Book:              pos.Book,
 		Quantity:          pos.Quantity,
 		EntryPrice:        pos.EntryPrice,
 		EntryOrderID:      pos.EntryOrderID,
 		EntryOrderType:    pos.EntryOrderType,
+		EntryFilledQty:    pos.EntryFilledQty,
 		AllocationDollars: pos.AllocationDollars,
 		StopLossPrice:     pos.StopLossPrice,
 		StopLossPercent:   pos.StopLossPercent,
 		StopLossOrderID:   pos.StopLossOrderID,
+		InitialRisk:       pos.InitialRisk,
 		TrailingStop:      pos.TrailingStop,
 		TrailingPercent:   pos.TrailingPercent,
 		TakeProfitPrice:   pos.TakeProfitPrice,
@@ -887,14 +1375,18 @@ func (pm *PositionManager) dbToManagedPosition(dbPos *models.DBManagedPosition)
 		Symbol:            dbPos.Symbol,
 		Side:              dbPos.Side,
 		Strategy:          dbPos.Strategy,
+		Book:              bookOrDefault(dbPos.Book),
+		Shadow:            IsShadowOrderID(dbPos.EntryOrderID),
 		Quantity:          dbPos.Quantity,
 		EntryPrice:        dbPos.EntryPrice,
 		EntryOrderID:      dbPos.EntryOrderID,
 		EntryOrderType:    dbPos.EntryOrderType,
+		EntryFilledQty:    dbPos.EntryFilledQty,
 		AllocationDollars: dbPos.AllocationDollars,
 		StopLossPrice:     dbPos.StopLossPrice,
 		StopLossPercent:   dbPos.StopLossPercent,
 		StopLossOrderID:   dbPos.StopLossOrderID,
+		InitialRisk:       dbPos.InitialRisk,
 		TrailingStop:      dbPos.TrailingStop,
 		TrailingPercent:   dbPos.TrailingPercent,
 		TakeProfitPrice:   dbPos.TakeProfitPrice,
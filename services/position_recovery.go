@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/interfaces"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// managedOrderPrefix starts the client order IDs of managed position orders
+const managedOrderPrefix = "pm-"
+
+// Roles of the orders a managed position places
+const (
+	orderRoleEntry       = "entry"
+	orderRoleStopLoss    = "stop"
+	orderRoleTakeProfit  = "target"
+	orderRolePartialExit = "partial"
+	orderRoleExit        = "exit"
+)
+
+// managedClientOrderID builds the client order ID of an order placed for a
+// position: pm-<position>-<role>-<nonce>. It only uses letters, digits and
+// dashes, which every broker accepts, and stays unique when an order is
+// re-placed such as a trailing stop.
+func managedClientOrderID(positionID, role string) string {
+	return fmt.Sprintf("%s%s-%s-%s", managedOrderPrefix, strings.TrimPrefix(positionID, "pos_"), role, strconv.FormatInt(time.Now().UnixNano(), 36))
+}
+
+// parseManagedClientOrderID returns the position ID and role of a managed
+// position order
+func parseManagedClientOrderID(clientOrderID string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(clientOrderID, managedOrderPrefix)
+	if !ok {
+		return "", "", false
+	}
+	parts := strings.Split(rest, "-")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", "", false
+	}
+	switch parts[1] {
+	case orderRoleEntry, orderRoleStopLoss, orderRoleTakeProfit, orderRolePartialExit, orderRoleExit:
+		return "pos_" + parts[0], parts[1], true
+	}
+	return "", "", false
+}
+
+// RecoveryReport is the result of the startup recovery of managed positions.
+// Discrepancies that are not healed could not be restored.
+type RecoveryReport struct {
+	StartedAt         time.Time     `json:"started_at"`
+	CompletedAt       time.Time     `json:"completed_at"`
+	RestoredPositions int           `json:"restored_positions"`
+	BrokerOrders      int           `json:"broker_orders"`
+	Unrestored        int           `json:"unrestored"`
+	Discrepancies     []Discrepancy `json:"discrepancies"`
+	Error             string        `json:"error,omitempty"`
+}
+
+// LastRecovery returns the startup recovery report, or nil before it ran
+func (r *Reconciler) LastRecovery() *RecoveryReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastRecovery
+}
+
+// Recover re-associates the managed positions loaded from storage with
+// their broker orders, found by client order ID. It restores what a crash
+// left unsaved: entry orders placed before their ID was stored, stops
+// re-placed by the trailing logic, risk orders placed after an entry fill
+// and manual closes whose exit order went out. Run it once at startup,
+// before the position monitor.
+func (r *Reconciler) Recover(ctx context.Context) (*RecoveryReport, error) {
+	report := &RecoveryReport{
+		StartedAt:     time.Now(),
+		Discrepancies: make([]Discrepancy, 0),
+	}
+
+	err := r.recover(ctx, report)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	for _, d := range report.Discrepancies {
+		if !d.Healed {
+			report.Unrestored++
+		}
+	}
+	report.CompletedAt = time.Now()
+
+	r.mu.Lock()
+	r.lastRecovery = report
+	r.mu.Unlock()
+
+	r.notify(ctx, "Recovery", report.Error, report.Discrepancies)
+
+	r.logger.WithFields(logrus.Fields{
+		"restored_positions": report.RestoredPositions,
+		"discrepancies":      len(report.Discrepancies),
+		"unrestored":         report.Unrestored,
+	}).Info("Managed position recovery complete")
+
+	return report, err
+}
+
+func (r *Reconciler) recover(ctx context.Context, report *RecoveryReport) error {
+	brokerOrders, err := r.tradingService.ListOrders(ctx, "all")
+	if err != nil {
+		return fmt.Errorf("failed to get broker orders: %w", err)
+	}
+	report.BrokerOrders = len(brokerOrders)
+
+	ordersByPosition := make(map[string][]*interfaces.Order)
+	for _, order := range brokerOrders {
+		if positionID, _, ok := parseManagedClientOrderID(order.ClientOrderID); ok {
+			ordersByPosition[positionID] = append(ordersByPosition[positionID], order)
+		}
+	}
+
+	for _, position := range r.positionManager.allPositions() {
+		if position.Shadow || (position.Status != "PENDING" && position.Status != "ACTIVE" && position.Status != "PARTIAL") {
+			continue
+		}
+		report.RestoredPositions++
+		r.recoverPosition(report, position, ordersByPosition[position.ID])
+		delete(ordersByPosition, position.ID)
+	}
+
+	// Whatever is left belongs to positions that are closed or were never
+	// stored
+	for positionID, orders := range ordersByPosition {
+		closed := false
+		if dbPos, err := r.storageService.GetManagedPosition(positionID); err == nil {
+			closed = dbPos.Status == "CLOSED" || dbPos.Status == "STOPPED_OUT"
+		}
+		for _, order := range orders {
+			if !isOpenStatus(order.Status) && (closed || order.FilledQty.IsZero()) {
+				continue
+			}
+			message := fmt.Sprintf("order is %s but its managed position was never stored", order.Status)
+			if closed {
+				message = fmt.Sprintf("order is still %s but its managed position is closed", order.Status)
+			}
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Type:       "orphaned_order",
+				Severity:   SeverityWarning,
+				Symbol:     order.Symbol,
+				PositionID: positionID,
+				OrderID:    order.ID,
+				Message:    message,
+			})
+		}
+	}
+
+	return nil
+}
+
+// recoverPosition matches one open position with its broker orders
+func (r *Reconciler) recoverPosition(report *RecoveryReport, position *ManagedPosition, orders []*interfaces.Order) {
+	byRole := make(map[string][]*interfaces.Order)
+	for _, order := range orders {
+		_, role, _ := parseManagedClientOrderID(order.ClientOrderID)
+		byRole[role] = append(byRole[role], order)
+	}
+	changed := false
+	discrepancy := func(kind, severity, orderID, message string) *Discrepancy {
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Type:       kind,
+			Severity:   severity,
+			Symbol:     position.Symbol,
+			PositionID: position.ID,
+			OrderID:    orderID,
+			Message:    message,
+		})
+		return &report.Discrepancies[len(report.Discrepancies)-1]
+	}
+
+	if position.EntryOrderID == "" {
+		entry := workingOrder(byRole[orderRoleEntry])
+		if entry == nil {
+			d := discrepancy("entry_order_missing", SeverityWarning, "", "managed position was stored but its entry order never reached the broker")
+			if r.autoHeal {
+				r.closeLocally(position, "closed by recovery: entry order never placed")
+				d.Healed = true
+				d.HealAction = "marked managed position CLOSED"
+			}
+			return
+		}
+		d := discrepancy("entry_order_recovered", SeverityWarning, entry.ID, "entry order was placed but its ID was never stored")
+		if r.autoHeal {
+			position.EntryOrderID = entry.ID
+			changed = true
+			d.Healed = true
+			d.HealAction = "re-associated entry order"
+		}
+	}
+
+	// A manual close that placed its exit order and stopped before saving
+	if exit := workingOrder(byRole[orderRoleExit]); exit != nil && position.Status != "PENDING" {
+		d := discrepancy("unsaved_close", SeverityWarning, exit.ID, fmt.Sprintf("exit order is %s but the managed position is still %s", exit.Status, position.Status))
+		if r.autoHeal {
+			now := time.Now()
+			position.Status = "CLOSED"
+			position.ClosedAt = &now
+			position.UpdatedAt = now
+			r.positionManager.savePositionToDB(position)
+			r.positionManager.recordTrade(position, exitFillPrice(exit, position.CurrentPrice))
+			d.Healed = true
+			d.HealAction = "marked managed position CLOSED and recorded the trade"
+		}
+		return
+	}
+
+	for _, risk := range []struct {
+		role  string
+		name  string
+		field *string
+	}{
+		{orderRoleStopLoss, "stop loss", &position.StopLossOrderID},
+		{orderRoleTakeProfit, "take profit", &position.TakeProfitOrderID},
+	} {
+		order := workingOrder(byRole[risk.role])
+		if order == nil || order.ID == *risk.field {
+			continue
+		}
+		previous := *risk.field
+		if previous == "" {
+			previous = "none"
+		}
+		d := discrepancy("order_reassociated", SeverityWarning, order.ID, fmt.Sprintf("%s order at the broker is %s but the stored one is %s", risk.name, order.ID, previous))
+		if r.autoHeal {
+			*risk.field = order.ID
+			changed = true
+			d.Healed = true
+			d.HealAction = "re-associated " + risk.name + " order"
+		}
+		for _, other := range byRole[risk.role] {
+			if other.ID != order.ID && isOpenStatus(other.Status) {
+				discrepancy("duplicate_order", SeverityWarning, other.ID, fmt.Sprintf("another %s order is still open at the broker", risk.name))
+			}
+		}
+	}
+
+	known := make(map[string]bool, len(position.PartialExitOrders))
+	for _, id := range position.PartialExitOrders {
+		known[id] = true
+	}
+	for _, order := range byRole[orderRolePartialExit] {
+		if known[order.ID] || isInactiveStatus(order.Status) {
+			continue
+		}
+		d := discrepancy("order_reassociated", SeverityWarning, order.ID, "partial exit order at the broker was never stored")
+		if r.autoHeal {
+			position.PartialExitOrders = append(position.PartialExitOrders, order.ID)
+			changed = true
+			d.Healed = true
+			d.HealAction = "re-associated partial exit order"
+		}
+	}
+
+	if changed {
+		position.UpdatedAt = time.Now()
+		if err := r.positionManager.savePositionToDB(position); err != nil {
+			r.logger.WithError(err).WithField("position_id", position.ID).Error("Failed to save recovered position")
+		}
+	}
+}
+
+// workingOrder picks the order that is in force among orders of one role:
+// the latest open one, else the latest that filled
+func workingOrder(orders []*interfaces.Order) *interfaces.Order {
+	var open, filled *interfaces.Order
+	for _, order := range orders {
+		switch {
+		case isOpenStatus(order.Status):
+			if open == nil || order.SubmittedAt.After(open.SubmittedAt) {
+				open = order
+			}
+		case !order.FilledQty.IsZero():
+			if filled == nil || order.SubmittedAt.After(filled.SubmittedAt) {
+				filled = order
+			}
+		}
+	}
+	if open != nil {
+		return open
+	}
+	return filled
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Pre-market scan orderings
+const (
+	PremarketSortScore  = "score"  // composite rank, highest first
+	PremarketSortGap    = "gap"    // absolute gap, largest first
+	PremarketSortVolume = "volume" // pre-market volume, highest first
+	PremarketSortNews   = "news"   // overnight headlines, most first
+)
+
+// premarketHeadlines is how many overnight headlines are listed per symbol
+const premarketHeadlines = 3
+
+// PremarketMover is one symbol of a pre-market scan
+type PremarketMover struct {
+	Symbol          string            `json:"symbol"`
+	Sector          string            `json:"sector"`
+	PreviousClose   float64           `json:"previous_close"`
+	Price           float64           `json:"price"`
+	GapPct          float64           `json:"gap_percent"`
+	Volume          int64             `json:"premarket_volume"` // shares traded from 4:00 ET to the open
+	Headlines       int               `json:"headlines"`        // since the previous close
+	LatestHeadlines []NewsItemCompact `json:"latest_headlines,omitempty"`
+	Score           float64           `json:"score"` // 0-100, the mean percentile of gap, volume and headlines
+}
+
+// PremarketScan ranks a watchlist by its gaps before the open
+type PremarketScan struct {
+	Date        string            `json:"date"` // trading date, New York
+	GeneratedAt time.Time         `json:"generated_at"`
+	NewsSince   time.Time         `json:"news_since"` // the previous session's close
+	Published   bool              `json:"published"`  // true for the scheduled scan before the open
+	Movers      []*PremarketMover `json:"movers"`     // highest score first
+	Errors      []string          `json:"errors,omitempty"`
+}
+
+// PremarketScanner ranks the watchlist by gap from the previous close,
+// pre-market volume and overnight headlines, and publishes the scan a set
+// time before each open to the API and the notification channels
+type PremarketScanner struct {
+	dataService interfaces.DataService
+	newsService *NewsService
+	marketHours *MarketHours
+	notifier    *Notifier
+	symbols     []string
+	lead        time.Duration // how long before the open the scan is published
+	minGapPct   float64       // smallest absolute gap named in the notification
+	topN        int           // movers named in the notification
+
+	mu            sync.Mutex
+	latest        *PremarketScan
+	publishedDate string
+
+	logger *logrus.Logger
+}
+
+// NewPremarketScanner creates a pre-market scanner for symbols that
+// publishes lead before the open
+func NewPremarketScanner(dataService interfaces.DataService, newsService *NewsService, marketHours *MarketHours, notifier *Notifier, symbols []string, lead time.Duration, minGapPct float64, topN int) *PremarketScanner {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &PremarketScanner{
+		dataService: dataService,
+		newsService: newsService,
+		marketHours: marketHours,
+		notifier:    notifier,
+		symbols:     symbols,
+		lead:        lead,
+		minGapPct:   minGapPct,
+		topN:        topN,
+		logger:      logger,
+	}
+}
+
+// Latest returns the last published scan, or nil before the first one
+func (ps *PremarketScanner) Latest() *PremarketScan {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.latest
+}
+
+// Tick publishes the scan once per trading day, from lead before the open
+// until the open. A scan that is missed, because the bot was down, is not
+// published late.
+func (ps *PremarketScanner) Tick(ctx context.Context) error {
+	now := time.Now()
+	day, ok := ps.marketHours.Day(ctx, now)
+	if !ok || now.Before(day.Open.Add(-ps.lead)) || !now.Before(day.Open) {
+		return nil
+	}
+	date := now.In(marketLocation).Format(marketDateFormat)
+	ps.mu.Lock()
+	published := ps.publishedDate == date
+	ps.mu.Unlock()
+	if published {
+		return nil
+	}
+
+	scan, err := ps.Scan(ctx, nil)
+	if err != nil {
+		return err
+	}
+	scan.Published = true
+
+	ps.mu.Lock()
+	ps.latest = scan
+	ps.publishedDate = date
+	ps.mu.Unlock()
+
+	ps.logger.WithFields(logrus.Fields{
+		"date":    date,
+		"symbols": len(scan.Movers),
+		"errors":  len(scan.Errors),
+	}).Info("Published pre-market scan")
+	ps.notify(ctx, scan)
+	return nil
+}
+
+// Scan ranks symbols, or the watchlist when none are given, by their gap,
+// pre-market volume and overnight headlines. After the open the gap is
+// measured at the latest price.
+func (ps *PremarketScanner) Scan(ctx context.Context, symbols []string) (*PremarketScan, error) {
+	if len(symbols) == 0 {
+		symbols = ps.symbols
+	}
+	now := time.Now()
+	scan := &PremarketScan{
+		Date:        now.In(marketLocation).Format(marketDateFormat),
+		GeneratedAt: now,
+		NewsSince:   ps.previousSessionClose(ctx, now),
+		Movers:      make([]*PremarketMover, 0, len(symbols)),
+	}
+
+	var news []NewsItem
+	if ps.newsService != nil {
+		news = ps.newsService.StoredItems(scan.NewsSince)
+	}
+
+	seen := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+
+		mover, err := ps.scanSymbol(ctx, symbol, now, scan.NewsSince)
+		if err != nil {
+			scan.Errors = append(scan.Errors, fmt.Sprintf("%s: %v", symbol, err))
+			continue
+		}
+		for _, item := range news {
+			if !mentionsSymbol(item.Title+" "+item.Description, symbol) {
+				continue
+			}
+			mover.Headlines++
+			if len(mover.LatestHeadlines) < premarketHeadlines {
+				mover.LatestHeadlines = append(mover.LatestHeadlines, item.ToCompact())
+			}
+		}
+		scan.Movers = append(scan.Movers, mover)
+	}
+	if len(scan.Movers) == 0 && len(scan.Errors) > 0 {
+		return nil, fmt.Errorf("no market data for the watchlist: %s", strings.Join(scan.Errors, "; "))
+	}
+
+	scorePremarketMovers(scan.Movers)
+	SortPremarketMovers(scan.Movers, PremarketSortScore)
+	return scan, nil
+}
+
+// scanSymbol measures one symbol's gap and pre-market volume
+func (ps *PremarketScanner) scanSymbol(ctx context.Context, symbol string, now, sessionClose time.Time) (*PremarketMover, error) {
+	bars, err := ps.dataService.GetHistoricalBars(ctx, symbol, now.AddDate(0, 0, -10), now, "1Day")
+	if err != nil {
+		return nil, err
+	}
+	// The last daily bar before today; a bar for today may already have
+	// formed from pre-market trades
+	today := now.In(marketLocation).Format(marketDateFormat)
+	var prevClose float64
+	for i := len(bars) - 1; i >= 0; i-- {
+		if bars[i].Timestamp.In(marketLocation).Format(marketDateFormat) < today {
+			prevClose = bars[i].Close
+			break
+		}
+	}
+	if prevClose <= 0 {
+		return nil, fmt.Errorf("no previous close")
+	}
+
+	// Prefer a trade since the close; before the first pre-market print,
+	// the quote midpoint shows where the stock is indicated
+	var price float64
+	trade, tradeErr := ps.dataService.GetLatestTrade(ctx, symbol)
+	if tradeErr == nil && trade.Price > 0 && trade.Timestamp.After(sessionClose) {
+		price = trade.Price
+	} else if quote, err := ps.dataService.GetLatestQuote(ctx, symbol); err == nil && quote.BidPrice > 0 && quote.AskPrice >= quote.BidPrice {
+		price = (quote.BidPrice + quote.AskPrice) / 2
+	} else if tradeErr == nil && trade.Price > 0 {
+		price = trade.Price
+	} else {
+		return nil, fmt.Errorf("no latest price")
+	}
+
+	mover := &PremarketMover{
+		Symbol:        symbol,
+		Sector:        SectorFor(symbol),
+		PreviousClose: prevClose,
+		Price:         price,
+		GapPct:        (price/prevClose - 1) * 100,
+	}
+
+	// Volume from 4:00 ET until now or the open, whichever is first
+	start := marketTimeOn(now, preMarketOpen)
+	end := now
+	if day, ok := ps.marketHours.Day(ctx, now); ok && day.Open.Before(end) {
+		end = day.Open
+	}
+	if end.After(start) {
+		minutes, err := ps.dataService.GetHistoricalBars(ctx, symbol, start, end, "1Min")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pre-market bars: %w", err)
+		}
+		for _, bar := range minutes {
+			if bar.Timestamp.Before(end) {
+				mover.Volume += bar.Volume
+			}
+		}
+	}
+	return mover, nil
+}
+
+// previousSessionClose returns the close of the last trading day before
+// now's date, so overnight news covers weekends and holidays
+func (ps *PremarketScanner) previousSessionClose(ctx context.Context, now time.Time) time.Time {
+	for i := 1; i <= 10; i++ {
+		if day, ok := ps.marketHours.Day(ctx, now.AddDate(0, 0, -i)); ok {
+			return day.Close
+		}
+	}
+	return marketTimeOn(now.AddDate(0, 0, -1), regularClose)
+}
+
+// notify sends the top movers whose gap reaches the minimum
+func (ps *PremarketScanner) notify(ctx context.Context, scan *PremarketScan) {
+	lines := make([]string, 0, ps.topN)
+	symbols := make([]string, 0, ps.topN)
+	for _, mover := range scan.Movers {
+		if len(lines) >= ps.topN {
+			break
+		}
+		if math.Abs(mover.GapPct) < ps.minGapPct {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s %+.2f%% at %.2f (%d shares, %d headlines)", mover.Symbol, mover.GapPct, mover.Price, mover.Volume, mover.Headlines))
+		symbols = append(symbols, mover.Symbol)
+	}
+
+	message := fmt.Sprintf("No gaps of %.1f%% or more across %d symbols", ps.minGapPct, len(scan.Movers))
+	if len(lines) > 0 {
+		message = strings.Join(lines, "\n")
+	}
+	ps.notifier.Notify(ctx, NotifyInfo, "Pre-market gaps", message, map[string]interface{}{
+		"date":    scan.Date,
+		"scanned": len(scan.Movers),
+		"movers":  strings.Join(symbols, ","),
+	})
+}
+
+// scorePremarketMovers sets each mover's score to the mean of its
+// percentile ranks by absolute gap, volume and headlines
+func scorePremarketMovers(movers []*PremarketMover) {
+	gaps := make([]float64, len(movers))
+	volumes := make([]float64, len(movers))
+	headlines := make([]float64, len(movers))
+	for i, mover := range movers {
+		gaps[i] = math.Abs(mover.GapPct)
+		volumes[i] = float64(mover.Volume)
+		headlines[i] = float64(mover.Headlines)
+	}
+	for i, mover := range movers {
+		mover.Score = (percentileRank(gaps, i) + percentileRank(volumes, i) + percentileRank(headlines, i)) / 3 * 100
+	}
+}
+
+// percentileRank is the share of the other values below values[i], with
+// ties counted as half
+func percentileRank(values []float64, i int) float64 {
+	if len(values) < 2 {
+		return 1
+	}
+	var below float64
+	for j, v := range values {
+		switch {
+		case j == i:
+		case v < values[i]:
+			below++
+		case v == values[i]:
+			below += 0.5
+		}
+	}
+	return below / float64(len(values)-1)
+}
+
+// SortPremarketMovers orders movers by score, gap, volume or news, with
+// ties broken by symbol
+func SortPremarketMovers(movers []*PremarketMover, by string) {
+	key := func(m *PremarketMover) float64 {
+		switch by {
+		case PremarketSortGap:
+			return math.Abs(m.GapPct)
+		case PremarketSortVolume:
+			return float64(m.Volume)
+		case PremarketSortNews:
+			return float64(m.Headlines)
+		}
+		return m.Score
+	}
+	sort.SliceStable(movers, func(i, j int) bool {
+		if ki, kj := key(movers[i]), key(movers[j]); ki != kj {
+			return ki > kj
+		}
+		return movers[i].Symbol < movers[j].Symbol
+	})
+}
@@ -0,0 +1,451 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Prompt template names
+const (
+	PromptCleanedNews   = "cleaned_news"   // news cleaning for the intelligence endpoints
+	PromptFilingSummary = "filing_summary" // SEC filing summaries
+	PromptMarketBrief   = "market_brief"   // market brief updates
+	PromptChat          = "chat"           // intelligence chat turns
+)
+
+// Where the template in use for a prompt comes from
+const (
+	PromptSourceBuiltin = "builtin"
+	PromptSourceFile    = "file"
+	PromptSourceDB      = "db"
+)
+
+// promptEndpointPattern limits the endpoint part of "name@endpoint"
+var promptEndpointPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// promptDefinition is a built-in prompt with sample values for every
+// variable it may use; edits are rendered with the samples to catch typos
+type promptDefinition struct {
+	description string
+	samples     map[string]interface{}
+	text        string
+}
+
+var promptDefinitions = map[string]promptDefinition{
+	PromptCleanedNews: {
+		description: "Turns a batch of headlines into a trading intelligence report (cleaned-news, quick-market)",
+		samples: map[string]interface{}{
+			"Count":    1,
+			"Articles": "[1] Stocks rise\n   Source: Reuters | Published: Mon, 02 Jan 2006 15:04:05 MST\n",
+			"History":  "- 2006-01-02 news: Stocks fell on rate fears\n",
+		},
+		text: `You are a financial analyst AI. Analyze the following {{.Count}} news articles and create a CONCISE trading intelligence report.
+
+NEWS ARTICLES:
+{{.Articles}}
+{{if .History}}
+RELATED EARLIER NEWS AND ANALYSES (background only, not part of the report):
+{{.History}}
+{{end}}
+Provide a JSON response with this EXACT structure:
+{
+  "market_sentiment": "BULLISH|BEARISH|NEUTRAL",
+  "key_themes": ["theme1", "theme2", "theme3"],
+  "stock_mentions": {
+    "SYMBOL": "POSITIVE|NEGATIVE|NEUTRAL with 1-sentence reason"
+  },
+  "actionable_items": ["brief actionable insight 1", "brief actionable insight 2"],
+  "executive_summary": "2-3 sentence summary of the market situation"
+}
+
+Focus on:
+- Stock symbols and their sentiment
+- Market-moving themes
+- Actionable trading insights
+- Overall market direction
+
+Keep it BRIEF and DENSE. Maximum 200 tokens total.`,
+	},
+	PromptFilingSummary: {
+		description: "Summarizes an SEC filing for traders",
+		samples: map[string]interface{}{
+			"Symbol": "AAPL",
+			"Form":   "8-K",
+			"Text":   "Item 2.02 Results of Operations and Financial Condition",
+		},
+		text: `You are a financial analyst AI. Summarize the following SEC {{.Form}} filing for {{.Symbol}} in 2-3 sentences.
+
+FILING TEXT:
+{{.Text}}
+
+Focus on:
+- What happened (earnings, deal, executive change, insider trade, guidance)
+- Concrete numbers (amounts, share counts, prices, dates)
+- Anything a trader holding the stock must know
+
+Be factual. No recommendations. Maximum 80 words.`,
+	},
+	PromptMarketBrief: {
+		description: "Revises the rolling market brief with new headlines; must answer with the market/symbols JSON",
+		samples: map[string]interface{}{
+			"Context": "PREVIOUS MARKET SUMMARY:\n(none)\n\nNEW MARKET HEADLINES:\n- Stocks rise (Reuters)\n",
+		},
+		text: `You are a financial analyst AI maintaining a rolling market brief. Update the previous summaries with the new headlines. Drop points that are stale or contradicted by newer news.
+
+{{.Context}}
+Provide a JSON response with this EXACT structure:
+{
+  "market": "2-3 sentence market-wide summary",
+  "symbols": {
+    "SYMBOL": "1-2 sentence summary of what is driving this stock"
+  }
+}
+
+Only include the symbols listed above. Be factual. No recommendations. Maximum 40 words per symbol.`,
+	},
+	PromptChat: {
+		description: "Answers the latest chat question with the current portfolio, open orders and recent analyses",
+		samples: map[string]interface{}{
+			"Context":  "ACCOUNT:\nPortfolio value $100000.00 | Cash $50000.00\n",
+			"Question": "Why was NVDA rated a hold yesterday?",
+		},
+		text: `You are the trading assistant of this account. Answer the question using the conversation so far and the current data below. The analyses and signals are the ones this system produced; when asked why something was rated a certain way, explain it from their scores and notes. If the data does not answer the question, say so instead of guessing.
+
+{{.Context}}
+QUESTION:
+{{.Question}}
+
+Be concise and factual. Quote numbers from the data where they matter.`,
+	},
+}
+
+type promptEndpointKey struct{}
+
+// WithPromptEndpoint selects the "name@endpoint" overrides of prompts
+// rendered with ctx
+func WithPromptEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, promptEndpointKey{}, endpoint)
+}
+
+// PromptTemplateVersion is one saved version of a prompt template
+type PromptTemplateVersion struct {
+	Version   int       `json:"version"`
+	Template  string    `json:"template"`
+	Note      string    `json:"note,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PromptTemplate is the template in use for a prompt name
+type PromptTemplate struct {
+	Name        string                   `json:"name"` // "name" or "name@endpoint"
+	Description string                   `json:"description,omitempty"`
+	Variables   []string                 `json:"variables"`
+	Source      string                   `json:"source"`             // builtin, file or db
+	Version     int                      `json:"version,omitempty"`  // active saved version when Source is db
+	Inherits    string                   `json:"inherits,omitempty"` // base prompt used by an override that is not set
+	Template    string                   `json:"template"`
+	Versions    []*PromptTemplateVersion `json:"versions,omitempty"` // newest first, from GET by name only
+}
+
+// PromptStore resolves LLM prompts. Saved versions in the database win over
+// <name>.tmpl files in dir, which win over the built-in text. Templates use
+// Go text/template syntax, e.g. {{.Symbol}}. An endpoint selected with
+// WithPromptEndpoint uses "name@endpoint" when one exists.
+type PromptStore struct {
+	storageService *database.LocalStorage
+	dir            string
+	active         map[string]*models.DBPromptTemplate
+	mu             sync.RWMutex
+	logger         *logrus.Logger
+}
+
+// NewPromptStore creates a prompt store and loads the active saved
+// versions. dir may be empty.
+func NewPromptStore(storageService *database.LocalStorage, dir string) *PromptStore {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	ps := &PromptStore{
+		storageService: storageService,
+		dir:            dir,
+		active:         make(map[string]*models.DBPromptTemplate),
+		logger:         logger,
+	}
+
+	if err := ps.loadActive(); err != nil {
+		logger.WithError(err).Error("Failed to load prompt templates from database")
+	}
+
+	return ps
+}
+
+// Render fills in the prompt name with vars. A nil store renders the
+// built-in text.
+func (ps *PromptStore) Render(ctx context.Context, name string, vars map[string]interface{}) (string, error) {
+	text := promptDefinitions[name].text
+	if ps != nil {
+		text, _, _, _ = ps.resolve(name)
+		if endpoint, ok := ctx.Value(promptEndpointKey{}).(string); ok && endpoint != "" {
+			if override, _, _, ok := ps.resolve(name + "@" + endpoint); ok {
+				text = override
+			}
+		}
+	}
+
+	out, err := executePrompt(name, text, vars)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %w", name, err)
+	}
+	return out, nil
+}
+
+// resolve returns the text, source and saved version of a prompt name, and
+// whether anything besides the built-in text applies to an override
+func (ps *PromptStore) resolve(name string) (string, string, int, bool) {
+	ps.mu.RLock()
+	saved, ok := ps.active[name]
+	ps.mu.RUnlock()
+	if ok {
+		return saved.Template, PromptSourceDB, saved.Version, true
+	}
+
+	if ps.dir != "" {
+		data, err := os.ReadFile(filepath.Join(ps.dir, name+".tmpl"))
+		if err == nil {
+			return string(data), PromptSourceFile, 0, true
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			ps.logger.WithError(err).WithField("prompt", name).Warn("Failed to read prompt template file")
+		}
+	}
+
+	base, _, override := strings.Cut(name, "@")
+	return promptDefinitions[base].text, PromptSourceBuiltin, 0, !override
+}
+
+// List returns every prompt and override in use, sorted by name
+func (ps *PromptStore) List() []*PromptTemplate {
+	names := make(map[string]bool)
+	for name := range promptDefinitions {
+		names[name] = true
+	}
+	ps.mu.RLock()
+	for name := range ps.active {
+		names[name] = true
+	}
+	ps.mu.RUnlock()
+	if ps.dir != "" {
+		files, _ := filepath.Glob(filepath.Join(ps.dir, "*.tmpl"))
+		for _, file := range files {
+			name := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+			if validatePromptName(name) == nil {
+				names[name] = true
+			}
+		}
+	}
+
+	templates := make([]*PromptTemplate, 0, len(names))
+	for name := range names {
+		templates = append(templates, ps.describe(name))
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// Get returns the template in use for name with its saved versions
+func (ps *PromptStore) Get(name string) (*PromptTemplate, error) {
+	if err := validatePromptName(name); err != nil {
+		return nil, err
+	}
+
+	tmpl := ps.describe(name)
+	versions, err := ps.storageService.GetPromptTemplateVersions(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		tmpl.Versions = append(tmpl.Versions, &PromptTemplateVersion{
+			Version:   v.Version,
+			Template:  v.Template,
+			Note:      v.Note,
+			Author:    v.Author,
+			Active:    v.Active,
+			CreatedAt: v.CreatedAt,
+		})
+	}
+	return tmpl, nil
+}
+
+// Save validates text and stores it as the next active version of name
+func (ps *PromptStore) Save(name, text, note, author string) (*PromptTemplate, error) {
+	if err := validatePromptName(name); err != nil {
+		return nil, err
+	}
+	base, _, _ := strings.Cut(name, "@")
+	if _, err := executePrompt(name, text, promptDefinitions[base].samples); err != nil {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, err)
+	}
+
+	saved, err := ps.storageService.CreatePromptTemplateVersion(name, text, note, author)
+	if err != nil {
+		return nil, err
+	}
+
+	ps.mu.Lock()
+	ps.active[name] = saved
+	ps.mu.Unlock()
+
+	ps.logger.WithFields(logrus.Fields{
+		"prompt":  name,
+		"version": saved.Version,
+		"author":  author,
+	}).Info("Prompt template saved")
+
+	return ps.Get(name)
+}
+
+// Rollback activates a saved version of name. Without a version it steps
+// back to the version before the active one; version 0 returns to the file
+// or built-in template.
+func (ps *PromptStore) Rollback(name string, version *int) (*PromptTemplate, error) {
+	if err := validatePromptName(name); err != nil {
+		return nil, err
+	}
+
+	versions, err := ps.storageService.GetPromptTemplateVersions(name)
+	if err != nil {
+		return nil, err
+	}
+
+	target := 0
+	if version != nil {
+		target = *version
+	} else {
+		// Versions are newest first; pick the one after the active one
+		for i, v := range versions {
+			if v.Active && i+1 < len(versions) {
+				target = versions[i+1].Version
+				break
+			}
+		}
+	}
+
+	var selected *models.DBPromptTemplate
+	for _, v := range versions {
+		if v.Version == target {
+			selected = v
+		}
+	}
+	if target != 0 && selected == nil {
+		return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("version %d of %s not found", target, name))
+	}
+
+	if err := ps.storageService.ActivatePromptTemplateVersion(name, target); err != nil {
+		return nil, err
+	}
+
+	ps.mu.Lock()
+	if selected != nil {
+		selected.Active = true
+		ps.active[name] = selected
+	} else {
+		delete(ps.active, name)
+	}
+	ps.mu.Unlock()
+
+	ps.logger.WithFields(logrus.Fields{
+		"prompt":  name,
+		"version": target,
+	}).Info("Prompt template rolled back")
+
+	return ps.Get(name)
+}
+
+// describe reports the template in use for name
+func (ps *PromptStore) describe(name string) *PromptTemplate {
+	base, _, _ := strings.Cut(name, "@")
+	def := promptDefinitions[base]
+	text, source, version, ok := ps.resolve(name)
+	inherits := ""
+	if !ok {
+		// An override that is not set falls back to its base prompt
+		inherits = base
+		text, source, version, _ = ps.resolve(base)
+	}
+
+	variables := make([]string, 0, len(def.samples))
+	for v := range def.samples {
+		variables = append(variables, v)
+	}
+	sort.Strings(variables)
+
+	return &PromptTemplate{
+		Name:        name,
+		Description: def.description,
+		Variables:   variables,
+		Source:      source,
+		Version:     version,
+		Inherits:    inherits,
+		Template:    text,
+	}
+}
+
+// loadActive caches the active saved versions
+func (ps *PromptStore) loadActive() error {
+	saved, err := ps.storageService.GetActivePromptTemplates()
+	if err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, v := range saved {
+		ps.active[v.Name] = v
+	}
+
+	ps.logger.WithField("count", len(saved)).Info("Loaded prompt templates from database")
+	return nil
+}
+
+// validatePromptName checks for a known prompt with an optional endpoint
+func validatePromptName(name string) error {
+	base, endpoint, override := strings.Cut(name, "@")
+	if _, ok := promptDefinitions[base]; !ok {
+		return WithErrorCode(ErrCodeNotFound, fmt.Errorf("unknown prompt %q", base))
+	}
+	if override && !promptEndpointPattern.MatchString(endpoint) {
+		return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("invalid endpoint %q: use up to 32 lowercase letters, digits or '-'", endpoint))
+	}
+	return nil
+}
+
+// executePrompt renders text, failing on variables that were not supplied
+func executePrompt(name, text string, vars map[string]interface{}) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often idle, refilled buckets are dropped
+const rateLimitSweepInterval = 10 * time.Minute
+
+// RateLimit is a per-minute request allowance. Requests may burst up to the
+// full allowance and then refill evenly over the minute.
+type RateLimit struct {
+	PerMinute int
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimiter keeps token buckets per named limit and caller key
+type RateLimiter struct {
+	limits    map[string]RateLimit
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// NewRateLimiter creates a rate limiter with no limits configured
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		limits:    make(map[string]RateLimit),
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// SetLimit configures the named limit. A zero or negative allowance disables it.
+func (rl *RateLimiter) SetLimit(name string, limit RateLimit) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.limits[name] = limit
+}
+
+// Limit returns the named limit and whether it is enabled
+func (rl *RateLimiter) Limit(name string) (RateLimit, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	limit, ok := rl.limits[name]
+	return limit, ok && limit.PerMinute > 0
+}
+
+// Allow takes one request from the caller's bucket for the named limit. It
+// returns the requests left and, when denied, how long until one is allowed.
+func (rl *RateLimiter) Allow(name, caller string) (bool, int, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	limit, ok := rl.limits[name]
+	if !ok || limit.PerMinute <= 0 {
+		return true, 0, 0
+	}
+
+	now := time.Now()
+	capacity := float64(limit.PerMinute)
+	perSecond := capacity / 60
+
+	key := name + "|" + caller
+	bucket, ok := rl.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		rl.buckets[key] = bucket
+	} else {
+		bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond)
+		bucket.updated = now
+	}
+
+	rl.sweep(now)
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / perSecond * float64(time.Second))
+		return false, 0, wait
+	}
+	bucket.tokens--
+	return true, int(bucket.tokens), 0
+}
+
+// sweep drops buckets that have been idle long enough to be full again.
+// Callers must hold rl.mu.
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	rl.lastSweep = now
+
+	for key, bucket := range rl.buckets {
+		if now.Sub(bucket.updated) > time.Minute {
+			delete(rl.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,605 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// CashSymbol is the target allocation entry for uninvested cash
+const CashSymbol = "CASH"
+
+// Rebalance order statuses
+const (
+	RebalancePlanned   = "planned" // dry run, nothing was placed
+	RebalanceSubmitted = "submitted"
+	RebalanceRejected  = "rejected" // failed the risk checks
+	RebalanceFailed    = "failed"   // the broker refused the order
+)
+
+// RebalanceRequest is the payload for a rebalance
+type RebalanceRequest struct {
+	Targets      map[string]float64 `json:"targets,omitempty" binding:"omitempty,dive,keys,symbol,endkeys,gte=0,lte=100"` // percent weights, defaults to the saved targets
+	TolerancePct *float64           `json:"tolerance_pct,omitempty" binding:"omitempty,gte=0,lte=100"`                    // drift band in percentage points, defaults to REBALANCE_TOLERANCE_PCT
+	TaxAware     bool               `json:"tax_aware,omitempty"`                                                          // sell losing and long-term lots first and defer short-term gains
+	WholeShares  bool               `json:"whole_shares,omitempty"`                                                       // round quantities down to whole shares
+	DryRun       bool               `json:"dry_run"`
+}
+
+// AllocationDrift compares one symbol's weight with its target
+type AllocationDrift struct {
+	Symbol      string          `json:"symbol"`
+	TargetPct   float64         `json:"target_pct"`
+	ActualPct   float64         `json:"actual_pct"`
+	DriftPct    float64         `json:"drift_pct"` // actual minus target, in percentage points
+	Value       decimal.Decimal `json:"value"`
+	TargetValue decimal.Decimal `json:"target_value"`
+	InBand      bool            `json:"in_band"` // within the tolerance, so not traded
+}
+
+// TaxLot is a block of shares bought together
+type TaxLot struct {
+	Qty        decimal.Decimal `json:"qty"`
+	CostBasis  decimal.Decimal `json:"cost_basis"`            // per share
+	AcquiredAt *time.Time      `json:"acquired_at,omitempty"` // nil when the purchase is older than the order history
+	LongTerm   bool            `json:"long_term"`
+	Gain       decimal.Decimal `json:"gain"` // estimated gain on Qty at the sale price
+}
+
+// RebalanceOrder is one trade of a rebalance
+type RebalanceOrder struct {
+	Symbol         string          `json:"symbol"`
+	Side           string          `json:"side"`
+	Qty            decimal.Decimal `json:"qty"`
+	EstimatedPrice decimal.Decimal `json:"estimated_price"`
+	Value          decimal.Decimal `json:"value"`
+	Lots           []*TaxLot       `json:"lots,omitempty"`
+	ShortTermGain  decimal.Decimal `json:"short_term_gain"`
+	LongTermGain   decimal.Decimal `json:"long_term_gain"`
+	DeferredQty    decimal.Decimal `json:"deferred_qty"` // not sold to avoid realizing short-term gains
+	Status         string          `json:"status"`
+	OrderID        string          `json:"order_id,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	Risk           *RiskDecision   `json:"risk,omitempty"`
+}
+
+// RebalancePlan is the drift of the portfolio and the trades that bring it
+// back to its targets
+type RebalancePlan struct {
+	Equity       decimal.Decimal    `json:"equity"`
+	Cash         decimal.Decimal    `json:"cash"`
+	TolerancePct float64            `json:"tolerance_pct"`
+	TaxAware     bool               `json:"tax_aware"`
+	DryRun       bool               `json:"dry_run"`
+	Allocations  []*AllocationDrift `json:"allocations"`
+	Orders       []*RebalanceOrder  `json:"orders"`
+	Unmanaged    []string           `json:"unmanaged,omitempty"` // held symbols without a target, left alone
+	Notes        []string           `json:"notes,omitempty"`
+	Timestamp    time.Time          `json:"timestamp"`
+}
+
+// RebalanceService keeps the portfolio at target weights. Only symbols that
+// drift outside the tolerance band are traded, straight back to their target,
+// so a rebalance places the fewest orders that restore the allocation.
+type RebalanceService struct {
+	storage       *database.LocalStorage
+	trading       interfaces.TradingService
+	dataService   interfaces.DataService
+	riskManager   *RiskManager
+	hours         *MarketHours
+	tolerancePct  float64
+	minTradeValue decimal.Decimal
+	dryRun        bool
+	mu            sync.Mutex // one rebalance at a time
+	logger        *logrus.Logger
+}
+
+// NewRebalanceService creates a rebalancing service. tolerancePct is the
+// default drift band in percentage points and trades worth less than
+// minTradeValue are not placed.
+func NewRebalanceService(storage *database.LocalStorage, trading interfaces.TradingService, dataService interfaces.DataService, riskManager *RiskManager, tolerancePct, minTradeValue float64, dryRun bool) *RebalanceService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &RebalanceService{
+		storage:       storage,
+		trading:       trading,
+		dataService:   dataService,
+		riskManager:   riskManager,
+		hours:         NewMarketHours(),
+		tolerancePct:  tolerancePct,
+		minTradeValue: decimal.NewFromFloat(minTradeValue),
+		dryRun:        dryRun,
+		logger:        logger,
+	}
+}
+
+// SetMarketHours decides when the market is open, by default the weekday
+// calendar
+func (s *RebalanceService) SetMarketHours(hours *MarketHours) {
+	s.hours = hours
+}
+
+// Targets returns the saved target allocation, including CASH
+func (s *RebalanceService) Targets() (map[string]float64, error) {
+	dbTargets, err := s.storage.GetTargetAllocations()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]float64, len(dbTargets))
+	for _, target := range dbTargets {
+		targets[target.Symbol] = target.Weight
+	}
+	return targets, nil
+}
+
+// SetTargets validates and saves the target allocation
+func (s *RebalanceService) SetTargets(targets map[string]float64) (map[string]float64, error) {
+	targets, err := normalizeTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	dbTargets := make([]*models.DBTargetAllocation, 0, len(targets))
+	for symbol, weight := range targets {
+		dbTargets = append(dbTargets, &models.DBTargetAllocation{Symbol: symbol, Weight: weight})
+	}
+	if err := s.storage.SaveTargetAllocations(dbTargets); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithField("targets", targets).Info("Target allocation updated")
+	return targets, nil
+}
+
+// normalizeTargets uppercases symbols and fills in the cash weight. Symbol
+// weights may not exceed 100%; when CASH is given the total must be 100%.
+func normalizeTargets(targets map[string]float64) (map[string]float64, error) {
+	if len(targets) == 0 {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("at least one target is required"))
+	}
+
+	normalized := make(map[string]float64, len(targets)+1)
+	invested := 0.0
+	for symbol, weight := range targets {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if weight < 0 || weight > 100 {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("weight for %s must be between 0 and 100", symbol))
+		}
+		if IsOCCSymbol(symbol) {
+			return nil, WithErrorCode(ErrCodeNotSupported, fmt.Errorf("options cannot be rebalance targets: %s", symbol))
+		}
+		if _, dup := normalized[symbol]; dup {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("%s is listed twice", symbol))
+		}
+		normalized[symbol] = weight
+		if symbol != CashSymbol {
+			invested += weight
+		}
+	}
+
+	if invested > 100.005 {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("target weights add up to %.2f%%, more than 100%%", invested))
+	}
+	if cash, ok := normalized[CashSymbol]; ok {
+		if math.Abs(invested+cash-100) > 0.005 {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("target weights including CASH add up to %.2f%%, not 100%%", invested+cash))
+		}
+	} else {
+		normalized[CashSymbol] = math.Round((100-invested)*1000) / 1000
+	}
+	return normalized, nil
+}
+
+// Rebalance computes the drift from the target allocation and, unless this
+// is a dry run, places the orders that restore it. Sells go first so their
+// proceeds fund the buys.
+func (s *RebalanceService) Rebalance(ctx context.Context, req *RebalanceRequest) (*RebalancePlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targets := req.Targets
+	if len(targets) == 0 {
+		saved, err := s.Targets()
+		if err != nil {
+			return nil, err
+		}
+		if len(saved) == 0 {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("no target allocation: set one with PUT /api/v1/rebalance/targets or pass targets"))
+		}
+		targets = saved
+	}
+	targets, err := normalizeTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	dryRun := req.DryRun || s.dryRun
+	if !dryRun && !s.hours.IsOpen(ctx, AssetClassEquity) {
+		return nil, WithErrorCode(ErrCodeMarketClosed, fmt.Errorf("market is closed; preview the rebalance with dry_run"))
+	}
+
+	plan, err := s.plan(ctx, targets, req)
+	if err != nil {
+		return nil, err
+	}
+	plan.DryRun = dryRun
+	if dryRun {
+		return plan, nil
+	}
+
+	ctx = WithOrderSource(ctx, OrderSourceRebalance)
+	for _, order := range plan.Orders {
+		s.place(ctx, order)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"orders": len(plan.Orders),
+		"equity": plan.Equity.StringFixed(2),
+	}).Info("Portfolio rebalanced")
+	return plan, nil
+}
+
+// plan works out the drift and the trades without placing anything
+func (s *RebalanceService) plan(ctx context.Context, targets map[string]float64, req *RebalanceRequest) (*RebalancePlan, error) {
+	account, err := s.trading.GetAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	positions, err := s.trading.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+	if !account.PortfolioValue.IsPositive() {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("account has no equity to rebalance"))
+	}
+
+	tolerance := s.tolerancePct
+	if req.TolerancePct != nil {
+		tolerance = *req.TolerancePct
+	}
+
+	plan := &RebalancePlan{
+		Equity:       account.PortfolioValue,
+		Cash:         account.Cash,
+		TolerancePct: tolerance,
+		TaxAware:     req.TaxAware,
+		Allocations:  make([]*AllocationDrift, 0, len(targets)),
+		Orders:       make([]*RebalanceOrder, 0),
+		Timestamp:    time.Now(),
+	}
+	equity := account.PortfolioValue
+	hundred := decimal.NewFromInt(100)
+
+	held := make(map[string]*interfaces.Position)
+	for _, position := range positions {
+		symbol := strings.ToUpper(position.Symbol)
+		_, targeted := targets[symbol]
+		if !targeted || IsOCCSymbol(symbol) || position.Side == "short" || position.Qty.IsNegative() {
+			plan.Unmanaged = append(plan.Unmanaged, symbol)
+			continue
+		}
+		held[symbol] = position
+	}
+	sort.Strings(plan.Unmanaged)
+
+	symbols := make([]string, 0, len(targets))
+	for symbol := range targets {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	var lots map[string][]*TaxLot
+	if req.TaxAware {
+		lots, err = s.openLots(ctx, held)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var sells, buys []*RebalanceOrder
+	for _, symbol := range symbols {
+		weight := targets[symbol]
+		drift := &AllocationDrift{
+			Symbol:      symbol,
+			TargetPct:   weight,
+			TargetValue: equity.Mul(decimal.NewFromFloat(weight)).Div(hundred).Round(2),
+		}
+		if symbol == CashSymbol {
+			drift.Value = account.Cash
+		} else if position, ok := held[symbol]; ok {
+			drift.Value = position.MarketValue
+		}
+		drift.ActualPct = drift.Value.Div(equity).Mul(hundred).Round(2).InexactFloat64()
+		drift.DriftPct = math.Round((drift.ActualPct-weight)*100) / 100
+		drift.InBand = math.Abs(drift.DriftPct) <= tolerance
+		plan.Allocations = append(plan.Allocations, drift)
+
+		// Cash is whatever the trades leave behind
+		if symbol == CashSymbol || drift.InBand {
+			continue
+		}
+
+		order, note := s.tradeFor(ctx, drift, held[symbol], req.WholeShares)
+		if note != "" {
+			plan.Notes = append(plan.Notes, note)
+		}
+		if order == nil {
+			continue
+		}
+		if order.Side == "sell" {
+			if req.TaxAware {
+				selectLots(order, lots[symbol], plan.Timestamp)
+				if order.DeferredQty.IsPositive() {
+					plan.Notes = append(plan.Notes, fmt.Sprintf("%s: %s shares not sold to avoid short-term gains", symbol, order.DeferredQty.String()))
+				}
+				if !order.Qty.IsPositive() {
+					continue
+				}
+			}
+			sells = append(sells, order)
+		} else {
+			buys = append(buys, order)
+		}
+	}
+
+	// Buys are funded by cash above its target plus the sale proceeds
+	available := account.Cash.Sub(equity.Mul(decimal.NewFromFloat(targets[CashSymbol])).Div(hundred))
+	for _, sell := range sells {
+		available = available.Add(sell.Value)
+	}
+	needed := decimal.Zero
+	for _, buy := range buys {
+		needed = needed.Add(buy.Value)
+	}
+	if needed.GreaterThan(available) && needed.IsPositive() {
+		scale := decimal.Max(available, decimal.Zero).Div(needed)
+		plan.Notes = append(plan.Notes, fmt.Sprintf("buys scaled to %s%% to stay within available cash", scale.Mul(hundred).StringFixed(1)))
+		scaled := buys[:0]
+		for _, buy := range buys {
+			buy.Qty = roundShares(buy.Qty.Mul(scale), req.WholeShares)
+			buy.Value = buy.Qty.Mul(buy.EstimatedPrice).Round(2)
+			if buy.Qty.IsPositive() && !buy.Value.LessThan(s.minTradeValue) {
+				scaled = append(scaled, buy)
+			}
+		}
+		buys = scaled
+	}
+
+	plan.Orders = append(sells, buys...)
+	return plan, nil
+}
+
+// tradeFor sizes the order that takes one out-of-band symbol back to its
+// target. It returns a note instead when no order can be placed.
+func (s *RebalanceService) tradeFor(ctx context.Context, drift *AllocationDrift, position *interfaces.Position, wholeShares bool) (*RebalanceOrder, string) {
+	delta := drift.TargetValue.Sub(drift.Value)
+	if delta.Abs().LessThan(s.minTradeValue) {
+		return nil, ""
+	}
+
+	order := &RebalanceOrder{Symbol: drift.Symbol, Side: "buy", Status: RebalancePlanned}
+	if delta.IsNegative() {
+		order.Side = "sell"
+	}
+
+	if position != nil && position.CurrentPrice.IsPositive() {
+		order.EstimatedPrice = position.CurrentPrice
+	} else {
+		quote, err := s.dataService.GetLatestQuote(ctx, drift.Symbol)
+		if err != nil {
+			return nil, fmt.Sprintf("%s: no price, not traded (%v)", drift.Symbol, err)
+		}
+		price := quote.AskPrice
+		if order.Side == "sell" || price <= 0 {
+			price = quote.BidPrice
+		}
+		if price <= 0 {
+			return nil, fmt.Sprintf("%s: no price, not traded", drift.Symbol)
+		}
+		order.EstimatedPrice = decimal.NewFromFloat(price)
+	}
+
+	order.Qty = roundShares(delta.Abs().Div(order.EstimatedPrice), wholeShares)
+	if order.Side == "sell" && (drift.TargetPct == 0 || order.Qty.GreaterThan(position.Qty)) {
+		order.Qty = position.Qty
+	}
+	if !order.Qty.IsPositive() {
+		return nil, fmt.Sprintf("%s: drift is less than one share", drift.Symbol)
+	}
+	order.Value = order.Qty.Mul(order.EstimatedPrice).Round(2)
+	return order, ""
+}
+
+// place risk-checks and submits one order of the plan
+func (s *RebalanceService) place(ctx context.Context, order *RebalanceOrder) {
+	brokerOrder := &interfaces.Order{
+		Symbol:      order.Symbol,
+		Qty:         order.Qty,
+		Side:        order.Side,
+		Type:        "market",
+		TimeInForce: "day",
+		Status:      "pending",
+		SubmittedAt: time.Now(),
+		Strategy:    OrderSourceRebalance,
+		Book:        BookFrom(ctx),
+	}
+
+	decision, err := s.riskManager.Evaluate(ctx, brokerOrder)
+	if err != nil {
+		order.Status = RebalanceFailed
+		order.Error = err.Error()
+		return
+	}
+	order.Risk = decision
+	if !decision.Approved {
+		order.Status = RebalanceRejected
+		order.Error = (&RiskRejectedError{Decision: decision}).Error()
+		return
+	}
+
+	result, err := s.trading.PlaceOrder(WithRiskDecision(ctx, decision), brokerOrder)
+	if err != nil {
+		order.Status = RebalanceFailed
+		order.Error = err.Error()
+		s.logger.WithError(err).WithField("symbol", order.Symbol).Warn("Rebalance order failed")
+		return
+	}
+
+	brokerOrder.ID = result.OrderID
+	brokerOrder.Status = result.Status
+	if err := s.storage.SaveOrder(brokerOrder); err != nil {
+		s.logger.WithError(err).Warn("Failed to save order to database")
+	}
+	order.Status = RebalanceSubmitted
+	order.OrderID = result.OrderID
+}
+
+// openLots rebuilds the open tax lots of the held symbols from the broker's
+// filled orders, matching sells against the oldest lots first
+func (s *RebalanceService) openLots(ctx context.Context, held map[string]*interfaces.Position) (map[string][]*TaxLot, error) {
+	orders, err := s.trading.ListOrders(ctx, "closed")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order history for tax lots: %w", err)
+	}
+
+	fills := make([]*interfaces.Order, 0, len(orders))
+	for _, order := range orders {
+		if _, ok := held[strings.ToUpper(order.Symbol)]; ok && order.FilledQty.IsPositive() && order.FilledAvgPrice != nil && order.FilledAt != nil {
+			fills = append(fills, order)
+		}
+	}
+	sort.Slice(fills, func(i, j int) bool { return fills[i].FilledAt.Before(*fills[j].FilledAt) })
+
+	lots := make(map[string][]*TaxLot)
+	for _, fill := range fills {
+		symbol := strings.ToUpper(fill.Symbol)
+		if fill.Side == "buy" {
+			acquired := *fill.FilledAt
+			lots[symbol] = append(lots[symbol], &TaxLot{Qty: fill.FilledQty, CostBasis: *fill.FilledAvgPrice, AcquiredAt: &acquired})
+			continue
+		}
+		remaining := fill.FilledQty
+		for len(lots[symbol]) > 0 && remaining.IsPositive() {
+			lot := lots[symbol][0]
+			used := decimal.Min(lot.Qty, remaining)
+			lot.Qty = lot.Qty.Sub(used)
+			remaining = remaining.Sub(used)
+			if !lot.Qty.IsPositive() {
+				lots[symbol] = lots[symbol][1:]
+			}
+		}
+	}
+
+	// The order history may not reach back to every purchase. Trim lots the
+	// position no longer holds, oldest first, and cover shares bought before
+	// the history starts with an undated lot at the remaining cost basis.
+	for symbol, position := range held {
+		total := decimal.Zero
+		for _, lot := range lots[symbol] {
+			total = total.Add(lot.Qty)
+		}
+		for total.GreaterThan(position.Qty) && len(lots[symbol]) > 0 {
+			lot := lots[symbol][0]
+			excess := decimal.Min(lot.Qty, total.Sub(position.Qty))
+			lot.Qty = lot.Qty.Sub(excess)
+			total = total.Sub(excess)
+			if !lot.Qty.IsPositive() {
+				lots[symbol] = lots[symbol][1:]
+			}
+		}
+		if missing := position.Qty.Sub(total); missing.IsPositive() {
+			known := decimal.Zero
+			for _, lot := range lots[symbol] {
+				known = known.Add(lot.Qty.Mul(lot.CostBasis))
+			}
+			basis := position.AvgEntryPrice
+			if remainingCost := position.CostBasis.Sub(known); remainingCost.IsPositive() {
+				basis = remainingCost.Div(missing)
+			}
+			lots[symbol] = append([]*TaxLot{{Qty: missing, CostBasis: basis}}, lots[symbol]...)
+		}
+	}
+	return lots, nil
+}
+
+// selectLots picks the lots a tax-aware sell relieves: losses first, then
+// long-term gains, each highest cost first. Lots that would realize a
+// short-term gain are not sold and their shares are reported as deferred.
+// Undated lots count as short-term since their holding period is unknown.
+func selectLots(order *RebalanceOrder, lots []*TaxLot, now time.Time) {
+	candidates := make([]*TaxLot, len(lots))
+	for i, lot := range lots {
+		candidate := *lot
+		candidate.LongTerm = lot.AcquiredAt != nil && lot.AcquiredAt.AddDate(1, 0, 0).Before(now)
+		candidates[i] = &candidate
+	}
+
+	rank := func(lot *TaxLot) int {
+		switch {
+		case !lot.CostBasis.LessThan(order.EstimatedPrice):
+			return 0 // loss or break-even
+		case lot.LongTerm:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ri, rj := rank(candidates[i]), rank(candidates[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return candidates[i].CostBasis.GreaterThan(candidates[j].CostBasis)
+	})
+
+	wanted := order.Qty
+	sold := decimal.Zero
+	for _, lot := range candidates {
+		if !wanted.IsPositive() || rank(lot) == 2 {
+			break
+		}
+		qty := decimal.Min(lot.Qty, wanted)
+		gain := order.EstimatedPrice.Sub(lot.CostBasis).Mul(qty).Round(2)
+		order.Lots = append(order.Lots, &TaxLot{
+			Qty:        qty,
+			CostBasis:  lot.CostBasis,
+			AcquiredAt: lot.AcquiredAt,
+			LongTerm:   lot.LongTerm,
+			Gain:       gain,
+		})
+		if lot.LongTerm {
+			order.LongTermGain = order.LongTermGain.Add(gain)
+		} else {
+			order.ShortTermGain = order.ShortTermGain.Add(gain)
+		}
+		sold = sold.Add(qty)
+		wanted = wanted.Sub(qty)
+	}
+
+	order.DeferredQty = order.Qty.Sub(sold)
+	order.Qty = sold
+	order.Value = sold.Mul(order.EstimatedPrice).Round(2)
+}
+
+// roundShares truncates a quantity to whole shares, or to the six decimals
+// fractional orders accept
+func roundShares(qty decimal.Decimal, wholeShares bool) decimal.Decimal {
+	if wholeShares {
+		return qty.Floor()
+	}
+	return qty.Truncate(6)
+}
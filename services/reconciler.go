@@ -0,0 +1,525 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Discrepancy severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Discrepancy is a single divergence between local and broker state
+type Discrepancy struct {
+	Type       string `json:"type"`
+	Severity   string `json:"severity"`
+	Symbol     string `json:"symbol,omitempty"`
+	PositionID string `json:"position_id,omitempty"`
+	OrderID    string `json:"order_id,omitempty"`
+	Message    string `json:"message"`
+	Healed     bool   `json:"healed"`
+	HealAction string `json:"heal_action,omitempty"`
+}
+
+// ReconciliationReport is the result of one reconciliation pass
+type ReconciliationReport struct {
+	StartedAt        time.Time     `json:"started_at"`
+	CompletedAt      time.Time     `json:"completed_at"`
+	BrokerPositions  int           `json:"broker_positions"`
+	BrokerOpenOrders int           `json:"broker_open_orders"`
+	ManagedPositions int           `json:"managed_positions"`
+	LocalOpenOrders  int           `json:"local_open_orders"`
+	Discrepancies    []Discrepancy `json:"discrepancies"`
+	Error            string        `json:"error,omitempty"`
+}
+
+// Reconciler compares local state (managed positions, stored orders, trade
+// ledger) against the broker and heals divergences where it is safe to do so
+type Reconciler struct {
+	tradingService  interfaces.TradingService
+	storageService  *database.LocalStorage
+	positionManager *PositionManager
+	notifier        *Notifier
+	autoHeal        bool
+	ledgerLookback  time.Duration
+
+	mu           sync.RWMutex
+	lastReport   *ReconciliationReport
+	lastRecovery *RecoveryReport
+	logger       *logrus.Logger
+}
+
+// NewReconciler creates a new reconciler
+func NewReconciler(
+	tradingService interfaces.TradingService,
+	storageService *database.LocalStorage,
+	positionManager *PositionManager,
+	notifier *Notifier,
+	autoHeal bool,
+) *Reconciler {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &Reconciler{
+		tradingService:  tradingService,
+		storageService:  storageService,
+		positionManager: positionManager,
+		notifier:        notifier,
+		autoHeal:        autoHeal,
+		ledgerLookback:  7 * 24 * time.Hour,
+		logger:          logger,
+	}
+}
+
+// LastReport returns the most recent reconciliation report, or nil
+func (r *Reconciler) LastReport() *ReconciliationReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastReport
+}
+
+// Reconcile runs a single reconciliation pass
+func (r *Reconciler) Reconcile(ctx context.Context) (*ReconciliationReport, error) {
+	report := &ReconciliationReport{
+		StartedAt:     time.Now(),
+		Discrepancies: make([]Discrepancy, 0),
+	}
+
+	err := r.reconcile(ctx, report)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	report.CompletedAt = time.Now()
+
+	r.mu.Lock()
+	r.lastReport = report
+	r.mu.Unlock()
+
+	r.notify(ctx, "Reconciliation", report.Error, report.Discrepancies)
+
+	r.logger.WithFields(logrus.Fields{
+		"discrepancies": len(report.Discrepancies),
+		"duration_ms":   report.CompletedAt.Sub(report.StartedAt).Milliseconds(),
+	}).Info("Reconciliation complete")
+
+	return report, err
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, report *ReconciliationReport) error {
+	brokerPositions, err := r.tradingService.GetPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get broker positions: %w", err)
+	}
+	brokerOrders, err := r.tradingService.ListOrders(ctx, "open")
+	if err != nil {
+		return fmt.Errorf("failed to get broker orders: %w", err)
+	}
+	report.BrokerPositions = len(brokerPositions)
+	report.BrokerOpenOrders = len(brokerOrders)
+
+	positionsBySymbol := make(map[string]*interfaces.Position, len(brokerPositions))
+	for _, p := range brokerPositions {
+		positionsBySymbol[p.Symbol] = p
+	}
+	openOrderIDs := make(map[string]bool, len(brokerOrders))
+	for _, o := range brokerOrders {
+		openOrderIDs[o.ID] = true
+	}
+
+	managed := r.openManagedPositions()
+	report.ManagedPositions = len(managed)
+
+	r.checkManagedPositions(ctx, report, managed, positionsBySymbol, openOrderIDs)
+	r.checkUntracked(report, managed, positionsBySymbol, brokerOrders)
+	if err := r.checkLocalOrders(ctx, report, openOrderIDs); err != nil {
+		return err
+	}
+	return r.checkLedger(ctx, report)
+}
+
+// openManagedPositions returns snapshots of the managed positions that are
+// not closed. Repairs go through the position manager, which applies them
+// to the live position only if it has not moved on since.
+func (r *Reconciler) openManagedPositions() []*ManagedPosition {
+	all := r.positionManager.ListManagedPositions("")
+	open := make([]*ManagedPosition, 0, len(all))
+	for _, live := range all {
+		p := r.positionManager.positionSnapshot(live)
+		// Shadow positions have nothing at the broker to reconcile
+		if p.Shadow {
+			continue
+		}
+		if p.Status == "PENDING" || p.Status == "ACTIVE" || p.Status == "PARTIAL" {
+			open = append(open, p)
+		}
+	}
+	return open
+}
+
+// checkManagedPositions verifies each open managed position against the broker
+func (r *Reconciler) checkManagedPositions(ctx context.Context, report *ReconciliationReport, managed []*ManagedPosition, positionsBySymbol map[string]*interfaces.Position, openOrderIDs map[string]bool) {
+	expectedQty := make(map[string]float64)
+
+	for _, position := range managed {
+		if position.Status == "PENDING" {
+			r.checkPendingEntry(ctx, report, position)
+			continue
+		}
+
+		expectedQty[position.Symbol] += position.RemainingQty
+
+		if _, ok := positionsBySymbol[position.Symbol]; !ok {
+			d := Discrepancy{
+				Type:       "missing_broker_position",
+				Severity:   SeverityCritical,
+				Symbol:     position.Symbol,
+				PositionID: position.ID,
+				Message:    "managed position is open locally but the broker holds no position",
+			}
+			// Safe to close locally only when no exit order is still working
+			if r.autoHeal && !r.hasWorkingExit(position, openOrderIDs) {
+				d.Healed = r.closeLocally(position, "closed by reconciler: no broker position")
+				if d.Healed {
+					d.HealAction = "marked managed position CLOSED"
+				}
+			}
+			report.Discrepancies = append(report.Discrepancies, d)
+			continue
+		}
+
+		if position.StopLossOrderID != "" && !openOrderIDs[position.StopLossOrderID] {
+			order, err := r.tradingService.GetOrder(ctx, position.StopLossOrderID)
+			if err == nil && isInactiveStatus(order.Status) {
+				d := Discrepancy{
+					Type:       "stop_order_inactive",
+					Severity:   SeverityCritical,
+					Symbol:     position.Symbol,
+					PositionID: position.ID,
+					OrderID:    position.StopLossOrderID,
+					Message:    fmt.Sprintf("stop loss order is %s at the broker", order.Status),
+				}
+				if r.autoHeal {
+					// The monitor may have replaced the stop since the
+					// snapshot; only the inactive order is replaced
+					inactiveID := position.StopLossOrderID
+					r.positionManager.repairPosition(position.ID, func(live *ManagedPosition) bool {
+						return live.Status == position.Status && live.StopLossOrderID == inactiveID
+					}, func(live *ManagedPosition) {
+						if err := r.positionManager.placeStopLossOrder(ctx, live); err != nil {
+							d.HealAction = "failed to re-place stop: " + err.Error()
+							return
+						}
+						r.positionManager.savePositionToDB(live)
+						d.Healed = true
+						d.HealAction = "re-placed stop loss order " + live.StopLossOrderID
+					})
+				}
+				report.Discrepancies = append(report.Discrepancies, d)
+			}
+		}
+	}
+
+	for symbol, qty := range expectedQty {
+		brokerPosition, ok := positionsBySymbol[symbol]
+		if !ok {
+			continue
+		}
+		if brokerQty := brokerPosition.Qty.Abs().InexactFloat64(); brokerQty < qty {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Type:     "quantity_mismatch",
+				Severity: SeverityWarning,
+				Symbol:   symbol,
+				Message:  fmt.Sprintf("managed positions expect %g shares but broker holds %g", qty, brokerQty),
+			})
+		}
+	}
+}
+
+// checkPendingEntry detects entry fills the position monitor has not seen yet
+func (r *Reconciler) checkPendingEntry(ctx context.Context, report *ReconciliationReport, position *ManagedPosition) {
+	if position.EntryOrderID == "" {
+		return
+	}
+
+	order, err := r.tradingService.GetOrder(ctx, position.EntryOrderID)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case order.Status == "filled":
+		d := Discrepancy{
+			Type:       "missed_entry_fill",
+			Severity:   SeverityWarning,
+			Symbol:     position.Symbol,
+			PositionID: position.ID,
+			OrderID:    position.EntryOrderID,
+			Message:    "entry order filled but managed position is still PENDING",
+		}
+		if r.autoHeal {
+			// Skipped when the monitor has activated it in the meantime
+			r.positionManager.repairPosition(position.ID, func(live *ManagedPosition) bool {
+				return live.Status == "PENDING" && live.EntryOrderID == position.EntryOrderID
+			}, func(live *ManagedPosition) {
+				r.positionManager.checkEntryOrder(ctx, live)
+				d.Healed = live.Status == "ACTIVE"
+				d.HealAction = "activated position and placed risk orders"
+			})
+		}
+		report.Discrepancies = append(report.Discrepancies, d)
+	case isInactiveStatus(order.Status):
+		d := Discrepancy{
+			Type:       "entry_order_inactive",
+			Severity:   SeverityWarning,
+			Symbol:     position.Symbol,
+			PositionID: position.ID,
+			OrderID:    position.EntryOrderID,
+			Message:    fmt.Sprintf("entry order is %s but managed position is still PENDING", order.Status),
+		}
+		if r.autoHeal {
+			d.Healed = r.closeLocally(position, "closed by reconciler: entry order "+order.Status)
+			if d.Healed {
+				d.HealAction = "marked managed position CLOSED"
+			}
+		}
+		report.Discrepancies = append(report.Discrepancies, d)
+	}
+}
+
+// checkUntracked reports broker state that no managed position accounts for
+func (r *Reconciler) checkUntracked(report *ReconciliationReport, managed []*ManagedPosition, positionsBySymbol map[string]*interfaces.Position, brokerOrders []*interfaces.Order) {
+	managedSymbols := make(map[string]bool)
+	managedOrders := make(map[string]bool)
+	for _, p := range managed {
+		managedSymbols[p.Symbol] = true
+		managedOrders[p.EntryOrderID] = true
+		managedOrders[p.StopLossOrderID] = true
+		managedOrders[p.TakeProfitOrderID] = true
+		for _, id := range p.PartialExitOrders {
+			managedOrders[id] = true
+		}
+	}
+
+	for symbol := range positionsBySymbol {
+		if !managedSymbols[symbol] {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Type:     "untracked_position",
+				Severity: SeverityInfo,
+				Symbol:   symbol,
+				Message:  "broker position is not covered by a managed position",
+			})
+		}
+	}
+
+	for _, order := range brokerOrders {
+		if managedOrders[order.ID] {
+			continue
+		}
+		if _, err := r.storageService.GetOrder(order.ID); err != nil {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Type:     "untracked_order",
+				Severity: SeverityInfo,
+				Symbol:   order.Symbol,
+				OrderID:  order.ID,
+				Message:  "open broker order has no local record",
+			})
+		}
+	}
+}
+
+// checkLocalOrders syncs locally stored open orders that the broker no longer has open
+func (r *Reconciler) checkLocalOrders(ctx context.Context, report *ReconciliationReport, openOrderIDs map[string]bool) error {
+	localOrders, err := r.storageService.GetOrders("")
+	if err != nil {
+		return fmt.Errorf("failed to get local orders: %w", err)
+	}
+
+	for _, local := range localOrders {
+		if !isOpenStatus(local.Status) || IsShadowOrderID(local.ID) {
+			continue
+		}
+		report.LocalOpenOrders++
+		if openOrderIDs[local.ID] {
+			continue
+		}
+
+		brokerOrder, err := r.tradingService.GetOrder(ctx, local.ID)
+		if err != nil {
+			continue
+		}
+
+		d := Discrepancy{
+			Type:     "stale_local_order",
+			Severity: SeverityWarning,
+			Symbol:   local.Symbol,
+			OrderID:  local.ID,
+			Message:  fmt.Sprintf("local order is %s but broker reports %s", local.Status, brokerOrder.Status),
+		}
+		if brokerOrder.Status == "filled" {
+			d.Type = "missed_fill"
+		}
+		if r.autoHeal {
+			if err := r.storageService.SaveOrder(brokerOrder); err == nil {
+				d.Healed = true
+				d.HealAction = "updated local order from broker"
+			}
+		}
+		report.Discrepancies = append(report.Discrepancies, d)
+	}
+
+	return nil
+}
+
+// checkLedger makes sure recently closed managed positions are in the trade ledger
+func (r *Reconciler) checkLedger(ctx context.Context, report *ReconciliationReport) error {
+	cutoff := time.Now().Add(-r.ledgerLookback)
+
+	for _, status := range []string{"CLOSED", "STOPPED_OUT"} {
+		dbPositions, err := r.storageService.GetAllManagedPositions(status)
+		if err != nil {
+			return err
+		}
+
+		for _, dbPos := range dbPositions {
+			if dbPos.ClosedAt == nil || dbPos.ClosedAt.Before(cutoff) || dbPos.EntryOrderID == "" || IsShadowOrderID(dbPos.EntryOrderID) {
+				continue
+			}
+
+			// Positions closed before their entry filled never traded
+			entry, err := r.tradingService.GetOrder(ctx, dbPos.EntryOrderID)
+			if err != nil || entry.FilledQty.IsZero() {
+				continue
+			}
+
+			recorded, err := r.storageService.HasTradeForPosition(dbPos.PositionID)
+			if err != nil {
+				return err
+			}
+			if recorded {
+				continue
+			}
+
+			d := Discrepancy{
+				Type:       "missing_ledger_trade",
+				Severity:   SeverityWarning,
+				Symbol:     dbPos.Symbol,
+				PositionID: dbPos.PositionID,
+				Message:    "closed managed position has no trade ledger entry",
+			}
+			if r.autoHeal {
+				position := r.positionManager.dbToManagedPosition(dbPos)
+				exitPrice := position.CurrentPrice
+				exitOrderID := position.TakeProfitOrderID
+				if status == "STOPPED_OUT" {
+					exitOrderID = position.StopLossOrderID
+				}
+				if exitOrderID != "" {
+					if order, err := r.tradingService.GetOrder(ctx, exitOrderID); err == nil {
+						exitPrice = exitFillPrice(order, exitPrice)
+					}
+				}
+				r.positionManager.recordTrade(position, exitPrice)
+				d.Healed = true
+				d.HealAction = "recorded trade from broker fills"
+			}
+			report.Discrepancies = append(report.Discrepancies, d)
+		}
+	}
+
+	return nil
+}
+
+// hasWorkingExit reports whether any exit order of a position is still open
+func (r *Reconciler) hasWorkingExit(position *ManagedPosition, openOrderIDs map[string]bool) bool {
+	if openOrderIDs[position.StopLossOrderID] || openOrderIDs[position.TakeProfitOrderID] {
+		return true
+	}
+	for _, id := range position.PartialExitOrders {
+		if openOrderIDs[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// closeLocally marks a managed position closed without touching the
+// broker. It is left open, and false returned, when the monitor has changed
+// its status or orders since the snapshot was taken.
+func (r *Reconciler) closeLocally(snapshot *ManagedPosition, note string) bool {
+	return r.positionManager.repairPosition(snapshot.ID, func(live *ManagedPosition) bool {
+		return live.Status == snapshot.Status &&
+			live.EntryOrderID == snapshot.EntryOrderID &&
+			live.StopLossOrderID == snapshot.StopLossOrderID &&
+			live.TakeProfitOrderID == snapshot.TakeProfitOrderID &&
+			len(live.PartialExitOrders) == len(snapshot.PartialExitOrders)
+	}, func(live *ManagedPosition) {
+		now := time.Now()
+		live.Status = "CLOSED"
+		live.ClosedAt = &now
+		live.UpdatedAt = now
+		if live.Notes != "" {
+			live.Notes += "; "
+		}
+		live.Notes += note
+
+		if err := r.positionManager.savePositionToDB(live); err != nil {
+			r.logger.WithError(err).WithField("position_id", live.ID).Error("Failed to save reconciled position")
+		}
+	})
+}
+
+// notify sends the error of a pass and one notification per warning or
+// critical discrepancy, titled after the pass
+func (r *Reconciler) notify(ctx context.Context, pass, passErr string, discrepancies []Discrepancy) {
+	if passErr != "" {
+		r.notifier.Notify(ctx, NotifyWarning, pass+" failed", passErr, nil)
+	}
+
+	for _, d := range discrepancies {
+		if d.Severity == SeverityInfo {
+			continue
+		}
+
+		level := NotifyWarning
+		if d.Severity == SeverityCritical && !d.Healed {
+			level = NotifyCritical
+		}
+
+		r.notifier.Notify(ctx, level, pass+": "+d.Type, d.Message, map[string]interface{}{
+			"symbol":      d.Symbol,
+			"position_id": d.PositionID,
+			"order_id":    d.OrderID,
+			"healed":      d.Healed,
+			"heal_action": d.HealAction,
+		})
+	}
+}
+
+// isOpenStatus reports whether an order status means the order is still working
+func isOpenStatus(status string) bool {
+	switch status {
+	case "new", "accepted", "pending", "pending_new", "partially_filled", "accepted_for_bidding", "held":
+		return true
+	}
+	return false
+}
+
+// isInactiveStatus reports whether an order ended without filling
+func isInactiveStatus(status string) bool {
+	switch status {
+	case "canceled", "expired", "rejected", "done_for_day":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+)
+
+// reconcilerHarness is a position manager and reconciler sharing a
+// simulated broker, with one PENDING managed AAPL position whose entry of
+// 10 shares has filled at the broker
+type reconcilerHarness struct {
+	broker     *SimulatedBroker
+	pm         *PositionManager
+	reconciler *Reconciler
+	position   *ManagedPosition
+}
+
+func newReconcilerHarness(t *testing.T) *reconcilerHarness {
+	t.Helper()
+	storage, err := database.NewLocalStorage(filepath.Join(t.TempDir(), "prophet.db"))
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	broker := newTestBroker(newTestMarket(map[string]float64{"AAPL": 100}))
+	pm := NewPositionManager(broker, broker.dataService, storage, nil, nil, false)
+	t.Cleanup(pm.Stop)
+
+	position := &ManagedPosition{
+		ID:              "pos_test",
+		Symbol:          "AAPL",
+		Side:            "buy",
+		Strategy:        "SWING_TRADE",
+		Quantity:        10,
+		EntryOrderID:    buyShares(t, broker, "AAPL", 10),
+		EntryOrderType:  "market",
+		StopLossPrice:   95,
+		TakeProfitPrice: 110,
+		Status:          "PENDING",
+		CreatedAt:       time.Now(), // older PENDING positions are skipped as stale
+		UpdatedAt:       time.Now(),
+	}
+	pm.mu.Lock()
+	pm.positions[position.ID] = position
+	pm.mu.Unlock()
+
+	return &reconcilerHarness{
+		broker:     broker,
+		pm:         pm,
+		reconciler: NewReconciler(broker, storage, pm, nil, true),
+		position:   position,
+	}
+}
+
+// activate runs the monitor over the pending position, which sees the entry
+// fill and places the stop and target
+func (h *reconcilerHarness) activate(t *testing.T) {
+	t.Helper()
+	h.pm.CheckPositions(context.Background())
+	if h.position.Status != "ACTIVE" {
+		t.Fatalf("position status %s after the monitor ran, want ACTIVE", h.position.Status)
+	}
+}
+
+// snapshot is what a reconciliation pass starting now sees
+func (h *reconcilerHarness) snapshot(t *testing.T) []*ManagedPosition {
+	t.Helper()
+	return h.reconciler.openManagedPositions()
+}
+
+// reconcile finishes a pass over an earlier snapshot of the managed positions
+func (h *reconcilerHarness) reconcile(t *testing.T, managed []*ManagedPosition) *ReconciliationReport {
+	t.Helper()
+	positionsBySymbol, openOrderIDs := h.brokerState(t)
+	report := &ReconciliationReport{Discrepancies: make([]Discrepancy, 0)}
+	h.reconciler.checkManagedPositions(context.Background(), report, managed, positionsBySymbol, openOrderIDs)
+	return report
+}
+
+// brokerState is the broker's positions by symbol and open order IDs, as a
+// reconciliation pass reads them
+func (h *reconcilerHarness) brokerState(t *testing.T) (map[string]*interfaces.Position, map[string]bool) {
+	t.Helper()
+	ctx := context.Background()
+	positions, err := h.broker.GetPositions(ctx)
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	orders, err := h.broker.ListOrders(ctx, "open")
+	if err != nil {
+		t.Fatalf("ListOrders: %v", err)
+	}
+	positionsBySymbol := make(map[string]*interfaces.Position)
+	for _, p := range positions {
+		positionsBySymbol[p.Symbol] = p
+	}
+	openOrderIDs := make(map[string]bool)
+	for _, o := range orders {
+		openOrderIDs[o.ID] = true
+	}
+
+	return positionsBySymbol, openOrderIDs
+}
+
+// openExits counts the working stop and limit sells at the broker
+func (h *reconcilerHarness) openExits(t *testing.T) (stops, limits int) {
+	t.Helper()
+	orders, err := h.broker.ListOrders(context.Background(), "open")
+	if err != nil {
+		t.Fatalf("ListOrders: %v", err)
+	}
+	for _, o := range orders {
+		if o.Side != "sell" {
+			continue
+		}
+		switch o.Type {
+		case "stop":
+			stops++
+		case "limit":
+			limits++
+		}
+	}
+	return stops, limits
+}
+
+func findDiscrepancy(report *ReconciliationReport, kind string) *Discrepancy {
+	for i := range report.Discrepancies {
+		if report.Discrepancies[i].Type == kind {
+			return &report.Discrepancies[i]
+		}
+	}
+	return nil
+}
+
+// The monitor and the reconciler both see an entry fill or a dead stop;
+// whichever acts second must find the work done and leave the position
+// with exactly one stop and one target
+func TestReconcilerRepairsDoNotDuplicateRiskOrders(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(h *reconcilerHarness, t *testing.T)
+		race        func(h *reconcilerHarness, t *testing.T) // runs between the snapshot and the repairs
+		discrepancy string
+		healed      bool
+	}{
+		{
+			name:        "monitor activates the entry after the snapshot",
+			race:        (*reconcilerHarness).activate,
+			discrepancy: "missed_entry_fill",
+			healed:      false,
+		},
+		{
+			name:        "reconciler activates the entry before the monitor",
+			discrepancy: "missed_entry_fill",
+			healed:      true,
+		},
+		{
+			name: "monitor replaces the canceled stop after the snapshot",
+			setup: func(h *reconcilerHarness, t *testing.T) {
+				h.activate(t)
+				if err := h.broker.CancelOrder(context.Background(), h.position.StopLossOrderID); err != nil {
+					t.Fatalf("cancel stop: %v", err)
+				}
+			},
+			race: func(h *reconcilerHarness, t *testing.T) {
+				unlock := h.pm.lockPosition(h.position.ID)
+				defer unlock()
+				if err := h.pm.placeStopLossOrder(context.Background(), h.position); err != nil {
+					t.Fatalf("replace stop: %v", err)
+				}
+			},
+			discrepancy: "stop_order_inactive",
+			healed:      false,
+		},
+		{
+			name: "reconciler replaces the canceled stop",
+			setup: func(h *reconcilerHarness, t *testing.T) {
+				h.activate(t)
+				if err := h.broker.CancelOrder(context.Background(), h.position.StopLossOrderID); err != nil {
+					t.Fatalf("cancel stop: %v", err)
+				}
+			},
+			discrepancy: "stop_order_inactive",
+			healed:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newReconcilerHarness(t)
+			if tt.setup != nil {
+				tt.setup(h, t)
+			}
+
+			managed := h.snapshot(t)
+			if tt.race != nil {
+				tt.race(h, t)
+			}
+			report := h.reconcile(t, managed)
+			// The monitor's next pass must not add to what is there
+			h.pm.CheckPositions(context.Background())
+
+			d := findDiscrepancy(report, tt.discrepancy)
+			if d == nil {
+				t.Fatalf("no %s discrepancy in %+v", tt.discrepancy, report.Discrepancies)
+			}
+			if d.Healed != tt.healed {
+				t.Errorf("%s healed = %v, want %v (%s)", tt.discrepancy, d.Healed, tt.healed, d.HealAction)
+			}
+			if h.position.Status != "ACTIVE" {
+				t.Errorf("position status = %s, want ACTIVE", h.position.Status)
+			}
+			if stops, limits := h.openExits(t); stops != 1 || limits != 1 {
+				t.Errorf("working exits = %d stops and %d limits, want 1 of each", stops, limits)
+			}
+		})
+	}
+}
+
+// Run the monitor and the reconciler over the same missed entry fill at
+// once; under -race this also checks they share the position safely
+func TestReconcilerAndMonitorConcurrentEntryFill(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		h := newReconcilerHarness(t)
+		managed := h.snapshot(t)
+		positionsBySymbol, openOrderIDs := h.brokerState(t)
+		report := &ReconciliationReport{Discrepancies: make([]Discrepancy, 0)}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h.pm.CheckPositions(context.Background())
+		}()
+		go func() {
+			defer wg.Done()
+			h.reconciler.checkManagedPositions(context.Background(), report, managed, positionsBySymbol, openOrderIDs)
+		}()
+		wg.Wait()
+
+		if stops, limits := h.openExits(t); stops != 1 || limits != 1 {
+			t.Fatalf("run %d: working exits = %d stops and %d limits, want 1 of each", i, stops, limits)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisClient is a minimal Redis client. Commands dial a fresh connection,
+// which suits the few calls a second the bot makes; subscriptions keep
+// theirs open.
+type redisClient struct {
+	addr     string
+	useTLS   bool
+	username string
+	password string
+	db       int
+}
+
+// newRedisClient creates a client for redis://[user:password@]host:port[/db],
+// or rediss:// for TLS
+func newRedisClient(redisURL string) (*redisClient, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return nil, fmt.Errorf("invalid REDIS_URL: want redis://[:password@]host:port[/db]")
+	}
+
+	c := &redisClient{
+		addr:   u.Host,
+		useTLS: u.Scheme == "rediss",
+	}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid REDIS_URL database %q", db)
+		}
+	}
+	return c, nil
+}
+
+// do runs one command on a fresh connection
+func (c *redisClient) do(ctx context.Context, args ...string) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	conn, reader, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return redisCommand(conn, reader, args...)
+}
+
+// subscribe delivers the messages published on channel to handle until ctx
+// ends or the connection fails
+func (c *redisClient) subscribe(ctx context.Context, channel string, handle func([]byte)) error {
+	dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	conn, reader, err := c.connect(dialCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock the read below when ctx ends
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if _, err := redisCommand(conn, reader, "SUBSCRIBE", channel); err != nil {
+		return err
+	}
+	for {
+		reply, err := redisRead(reader)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		// Messages arrive as ["message", channel, payload]
+		if msg, ok := reply.([]interface{}); ok && len(msg) == 3 && msg[0] == "message" {
+			if payload, ok := msg[2].(string); ok {
+				handle([]byte(payload))
+			}
+		}
+	}
+}
+
+// connect dials the server, authenticates and selects the database
+func (c *redisClient) connect(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if c.useTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", c.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	reader := bufio.NewReader(conn)
+	if c.password != "" {
+		auth := []string{"AUTH", c.password}
+		if c.username != "" {
+			auth = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := redisCommand(conn, reader, auth...); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := redisCommand(conn, reader, "SELECT", strconv.Itoa(c.db)); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, reader, nil
+}
+
+// redisCommand writes a command in RESP and reads its reply
+func redisCommand(w io.Writer, r *bufio.Reader, args ...string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return nil, fmt.Errorf("redis write failed: %w", err)
+	}
+	return redisRead(r)
+}
+
+// redisRead reads one RESP reply: a string, an int64, a []interface{}, nil
+// for a null reply or an error
+func redisRead(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis read failed: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("bad redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("redis read failed: %w", err)
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("bad redis reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = redisRead(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected redis reply %q", line)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// redisLockTTL is how long a Redis lease lasts without a refresh. The
+// elector refreshes well within it.
+const redisLockTTL = 30 * time.Second
+
+// Lua scripts that only touch the key while it still holds our token
+const (
+	redisRefreshScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+	redisReleaseScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+)
+
+// RedisLock is a lease on a Redis key holding a random token. A holder that
+// stops refreshing loses it after redisLockTTL.
+type RedisLock struct {
+	client *redisClient
+	key    string
+	token  string
+}
+
+// NewRedisLock creates a lease on key at the Redis server at redisURL
+func NewRedisLock(redisURL, key string) (*RedisLock, error) {
+	client, err := newRedisClient(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisLock{client: client, key: key}, nil
+}
+
+// Backend implements InstanceLock
+func (l *RedisLock) Backend() string {
+	return "redis"
+}
+
+// TryLock implements InstanceLock
+func (l *RedisLock) TryLock(ctx context.Context) (bool, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return false, err
+	}
+	reply, err := l.client.do(ctx, "SET", l.key, hex.EncodeToString(token), "NX", "PX", strconv.FormatInt(redisLockTTL.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	if reply == nil {
+		return false, nil
+	}
+	l.token = hex.EncodeToString(token)
+	return true, nil
+}
+
+// Refresh implements InstanceLock
+func (l *RedisLock) Refresh(ctx context.Context) (bool, error) {
+	if l.token == "" {
+		return false, nil
+	}
+	reply, err := l.client.do(ctx, "EVAL", redisRefreshScript, "1", l.key, l.token, strconv.FormatInt(redisLockTTL.Milliseconds(), 10))
+	if err != nil {
+		// The lease may still be ours, but not for longer than its TTL
+		// without a refresh, so stop writing now
+		l.token = ""
+		return false, err
+	}
+	if reply != int64(1) {
+		l.token = ""
+		return false, nil
+	}
+	return true, nil
+}
+
+// Unlock implements InstanceLock
+func (l *RedisLock) Unlock(ctx context.Context) error {
+	if l.token == "" {
+		return nil
+	}
+	_, err := l.client.do(ctx, "EVAL", redisReleaseScript, "1", l.key, l.token)
+	l.token = ""
+	return err
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// RedisCache is a SharedCache in Redis, under keys starting with prefix
+type RedisCache struct {
+	client *redisClient
+	prefix string
+}
+
+// NewRedisCache creates a cache at the Redis server at redisURL
+func NewRedisCache(redisURL, prefix string) (*RedisCache, error) {
+	client, err := newRedisClient(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisCache{client: client, prefix: prefix}, nil
+}
+
+// Get implements SharedCache
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := c.client.do(ctx, "GET", c.prefix+key)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	value, _ := reply.(string)
+	return []byte(value), nil
+}
+
+// Set implements SharedCache
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := c.client.do(ctx, "SET", c.prefix+key, string(value), "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	return err
+}
+
+// RedisEventRelay is an EventRelay over a Redis pub/sub channel
+type RedisEventRelay struct {
+	client  *redisClient
+	channel string
+}
+
+// NewRedisEventRelay creates a relay on the channel prefix+"events"
+func NewRedisEventRelay(redisURL, prefix string) (*RedisEventRelay, error) {
+	client, err := newRedisClient(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisEventRelay{client: client, channel: prefix + "events"}, nil
+}
+
+// Send implements EventRelay
+func (r *RedisEventRelay) Send(ctx context.Context, data []byte) error {
+	_, err := r.client.do(ctx, "PUBLISH", r.channel, string(data))
+	return err
+}
+
+// Receive implements EventRelay
+func (r *RedisEventRelay) Receive(ctx context.Context, handle func([]byte)) error {
+	return r.client.subscribe(ctx, r.channel, handle)
+}
+
+// RedisJobQueue is a JobQueue in a Redis list. Jobs are pushed on the left
+// and popped from the right.
+type RedisJobQueue struct {
+	client *redisClient
+	key    string
+}
+
+// NewRedisJobQueue creates a job queue in the list prefix+name
+func NewRedisJobQueue(redisURL, prefix, name string) (*RedisJobQueue, error) {
+	client, err := newRedisClient(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisJobQueue{client: client, key: prefix + name}, nil
+}
+
+// Push implements JobQueue
+func (q *RedisJobQueue) Push(ctx context.Context, job []byte) error {
+	_, err := q.client.do(ctx, "LPUSH", q.key, string(job))
+	return err
+}
+
+// Pop implements JobQueue
+func (q *RedisJobQueue) Pop(ctx context.Context) ([]byte, error) {
+	reply, err := q.client.do(ctx, "RPOP", q.key)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	job, _ := reply.(string)
+	return []byte(job), nil
+}
+
+// Requeue implements JobQueue
+func (q *RedisJobQueue) Requeue(ctx context.Context, job []byte) error {
+	_, err := q.client.do(ctx, "RPUSH", q.key, string(job))
+	return err
+}
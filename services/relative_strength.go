@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+	"time"
+)
+
+// relativeStrengthMarket is the broad market benchmark for relative strength
+const relativeStrengthMarket = "SPY"
+
+// RelativeStrengthLookbacks are the trading-day windows reported: 1, 3, 6 and 12 months
+var RelativeStrengthLookbacks = []int{21, 63, 126, 252}
+
+// momentumWeights blend the lookbacks into one score, favouring 3 months
+var momentumWeights = map[int]float64{21: 0.2, 63: 0.4, 126: 0.2, 252: 0.2}
+
+// StrengthPeriod is the performance of a symbol over one lookback
+type StrengthPeriod struct {
+	Days            int      `json:"days"`
+	ReturnPct       float64  `json:"return_percent"`
+	MarketReturnPct float64  `json:"market_return_percent"`
+	VsMarketPct     float64  `json:"vs_market_percent"` // excess return over SPY
+	SectorReturnPct *float64 `json:"sector_return_percent,omitempty"`
+	VsSectorPct     *float64 `json:"vs_sector_percent,omitempty"` // excess return over the sector ETF
+}
+
+// RelativeStrength compares a symbol against its sector ETF and SPY
+type RelativeStrength struct {
+	Symbol        string           `json:"symbol"`
+	Sector        string           `json:"sector"`
+	SectorETF     string           `json:"sector_etf,omitempty"`
+	Price         float64          `json:"price"`
+	Periods       []StrengthPeriod `json:"periods"`
+	MomentumScore float64          `json:"momentum_score"` // weighted excess return over SPY
+}
+
+// SectorMomentum is one row of the sector rotation dashboard
+type SectorMomentum struct {
+	Rank          int              `json:"rank"`
+	Sector        string           `json:"sector"`
+	ETF           string           `json:"etf"`
+	Price         float64          `json:"price"`
+	Periods       []StrengthPeriod `json:"periods"`
+	MomentumScore float64          `json:"momentum_score"`
+	Quadrant      string           `json:"quadrant"` // "leading", "weakening", "lagging", "improving"
+}
+
+// SectorRotation ranks sector ETFs by momentum relative to SPY
+type SectorRotation struct {
+	Benchmark   string           `json:"benchmark"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Sectors     []SectorMomentum `json:"sectors"`
+	Skipped     []string         `json:"skipped,omitempty"` // ETFs without data
+}
+
+// RelativeStrengthFilter gates buy signals on relative strength. Nil
+// thresholds and a zero TopSectors disable the corresponding check.
+type RelativeStrengthFilter struct {
+	LookbackDays   int      // one of RelativeStrengthLookbacks, default 63
+	MinVsMarketPct *float64 // minimum excess return over SPY
+	MinVsSectorPct *float64 // minimum excess return over the sector ETF
+	TopSectors     int      // require the symbol's sector to rank in the top N
+}
+
+// RelativeStrength computes a symbol's returns against its sector ETF and SPY
+func (tas *TechnicalAnalysisService) RelativeStrength(ctx context.Context, symbol string) (*RelativeStrength, error) {
+	symbol = strings.ToUpper(symbol)
+	closes, err := tas.fetchCloses(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	market, err := tas.fetchCloses(ctx, relativeStrengthMarket)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RelativeStrength{
+		Symbol:    symbol,
+		Sector:    SectorFor(symbol),
+		SectorETF: SectorETF(symbol),
+		Price:     closes[len(closes)-1],
+	}
+
+	// Sector comparison is best effort; periods omit it when bars are missing
+	var sector []float64
+	if result.SectorETF != "" && result.SectorETF != symbol {
+		sector, _ = tas.fetchCloses(ctx, result.SectorETF)
+	}
+
+	result.Periods = strengthPeriods(closes, market, sector)
+	result.MomentumScore = momentumScore(result.Periods)
+	return result, nil
+}
+
+// SectorRotation ranks the sector ETFs by momentum relative to SPY
+func (tas *TechnicalAnalysisService) SectorRotation(ctx context.Context) (*SectorRotation, error) {
+	market, err := tas.fetchCloses(ctx, relativeStrengthMarket)
+	if err != nil {
+		return nil, err
+	}
+
+	rotation := &SectorRotation{
+		Benchmark:   relativeStrengthMarket,
+		GeneratedAt: time.Now(),
+		Sectors:     make([]SectorMomentum, 0, len(sectorETFs)),
+	}
+
+	for sector, etf := range sectorETFs {
+		closes, err := tas.fetchCloses(ctx, etf)
+		if err != nil {
+			rotation.Skipped = append(rotation.Skipped, etf)
+			continue
+		}
+
+		periods := strengthPeriods(closes, market, nil)
+		rotation.Sectors = append(rotation.Sectors, SectorMomentum{
+			Sector:        sector,
+			ETF:           etf,
+			Price:         closes[len(closes)-1],
+			Periods:       periods,
+			MomentumScore: momentumScore(periods),
+			Quadrant:      rotationQuadrant(periods),
+		})
+	}
+	if len(rotation.Sectors) == 0 {
+		return nil, fmt.Errorf("no sector data available")
+	}
+
+	sort.Slice(rotation.Sectors, func(i, j int) bool {
+		return rotation.Sectors[i].MomentumScore > rotation.Sectors[j].MomentumScore
+	})
+	for i := range rotation.Sectors {
+		rotation.Sectors[i].Rank = i + 1
+	}
+
+	return rotation, nil
+}
+
+// CheckRelativeStrength reports whether a symbol passes the filter, with a
+// reason when it does not
+func (tas *TechnicalAnalysisService) CheckRelativeStrength(ctx context.Context, symbol string, filter RelativeStrengthFilter) (bool, string, error) {
+	if filter.LookbackDays <= 0 {
+		filter.LookbackDays = 63
+	}
+
+	rs, err := tas.RelativeStrength(ctx, symbol)
+	if err != nil {
+		return false, "", err
+	}
+
+	var period *StrengthPeriod
+	for i := range rs.Periods {
+		if rs.Periods[i].Days == filter.LookbackDays {
+			period = &rs.Periods[i]
+		}
+	}
+	if period == nil {
+		return false, "", fmt.Errorf("not enough history for a %d-day lookback", filter.LookbackDays)
+	}
+
+	if filter.MinVsMarketPct != nil && period.VsMarketPct < *filter.MinVsMarketPct {
+		return false, fmt.Sprintf("%s %dd return vs %s %.1f%% < %.1f%%", rs.Symbol, period.Days, relativeStrengthMarket, period.VsMarketPct, *filter.MinVsMarketPct), nil
+	}
+	if filter.MinVsSectorPct != nil && period.VsSectorPct != nil && *period.VsSectorPct < *filter.MinVsSectorPct {
+		return false, fmt.Sprintf("%s %dd return vs %s %.1f%% < %.1f%%", rs.Symbol, period.Days, rs.SectorETF, *period.VsSectorPct, *filter.MinVsSectorPct), nil
+	}
+
+	if filter.TopSectors > 0 && rs.SectorETF != "" {
+		rotation, err := tas.SectorRotation(ctx)
+		if err != nil {
+			return false, "", err
+		}
+		for _, sector := range rotation.Sectors {
+			if sector.Sector == rs.Sector && sector.Rank > filter.TopSectors {
+				return false, fmt.Sprintf("sector %s ranks %d of %d", rs.Sector, sector.Rank, len(rotation.Sectors)), nil
+			}
+		}
+	}
+
+	return true, "", nil
+}
+
+// fetchCloses returns about 13 months of daily closes, enough for every lookback
+func (tas *TechnicalAnalysisService) fetchCloses(ctx context.Context, symbol string) ([]float64, error) {
+	end := time.Now()
+	bars, err := tas.dataService.GetHistoricalBars(ctx, symbol, end.AddDate(0, 0, -400), end, "1Day")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bars for %s: %w", symbol, err)
+	}
+	if len(bars) < 2 {
+		return nil, fmt.Errorf("not enough bars for %s", symbol)
+	}
+	return barCloses(bars), nil
+}
+
+// barCloses extracts closing prices
+func barCloses(bars []*interfaces.Bar) []float64 {
+	closes := make([]float64, len(bars))
+	for i, bar := range bars {
+		closes[i] = bar.Close
+	}
+	return closes
+}
+
+// periodReturnPct is the percent return over the last days bars
+func periodReturnPct(closes []float64, days int) (float64, bool) {
+	if len(closes) <= days || closes[len(closes)-1-days] == 0 {
+		return 0, false
+	}
+	return (closes[len(closes)-1]/closes[len(closes)-1-days] - 1) * 100, true
+}
+
+// strengthPeriods computes returns for every lookback the series cover.
+// sector may be nil when there is no sector ETF.
+func strengthPeriods(closes, market, sector []float64) []StrengthPeriod {
+	periods := make([]StrengthPeriod, 0, len(RelativeStrengthLookbacks))
+	for _, days := range RelativeStrengthLookbacks {
+		ret, ok := periodReturnPct(closes, days)
+		if !ok {
+			continue
+		}
+		marketRet, ok := periodReturnPct(market, days)
+		if !ok {
+			continue
+		}
+
+		period := StrengthPeriod{
+			Days:            days,
+			ReturnPct:       ret,
+			MarketReturnPct: marketRet,
+			VsMarketPct:     ret - marketRet,
+		}
+		if sectorRet, ok := periodReturnPct(sector, days); ok {
+			vsSector := ret - sectorRet
+			period.SectorReturnPct = &sectorRet
+			period.VsSectorPct = &vsSector
+		}
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// momentumScore blends excess returns over the market across lookbacks,
+// renormalizing when the longer windows are unavailable
+func momentumScore(periods []StrengthPeriod) float64 {
+	score, weights := 0.0, 0.0
+	for _, period := range periods {
+		score += momentumWeights[period.Days] * period.VsMarketPct
+		weights += momentumWeights[period.Days]
+	}
+	if weights == 0 {
+		return 0
+	}
+	return score / weights
+}
+
+// rotationQuadrant places a sector on a relative rotation graph using its
+// 6-month relative trend and 1-month relative momentum
+func rotationQuadrant(periods []StrengthPeriod) string {
+	var short, long float64
+	for _, period := range periods {
+		switch period.Days {
+		case 21:
+			short = period.VsMarketPct
+		case 126:
+			long = period.VsMarketPct
+		}
+	}
+
+	switch {
+	case long > 0 && short > 0:
+		return "leading"
+	case long > 0:
+		return "weakening"
+	case short > 0:
+		return "improving"
+	default:
+		return "lagging"
+	}
+}
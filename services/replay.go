@@ -0,0 +1,603 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Replay speed limits, as multiples of real time
+const (
+	MinReplaySpeed = 1
+	MaxReplaySpeed = 100
+)
+
+// ReplayClock is the virtual time of a market replay
+type ReplayClock struct {
+	now time.Time
+	mu  sync.RWMutex
+}
+
+// Now returns the virtual time
+func (c *ReplayClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+// Set moves the virtual time
+func (c *ReplayClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// ReplayDataService serves stored minute bars as if the replay clock were
+// the current time: the latest bar is the last one completed by then, and
+// nothing after it is ever returned.
+type ReplayDataService struct {
+	fallback interfaces.DataService
+	clock    *ReplayClock
+	interval time.Duration
+	bars     map[string][]*interfaces.Bar
+	mu       sync.RWMutex
+	logger   *logrus.Logger
+}
+
+// NewReplayDataService creates a replay data service. fallback loads bars
+// that are not stored yet and answers requests for other symbols and
+// timeframes, cut off at the replay clock.
+func NewReplayDataService(fallback interfaces.DataService, clock *ReplayClock) *ReplayDataService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &ReplayDataService{
+		fallback: fallback,
+		clock:    clock,
+		interval: time.Minute,
+		bars:     make(map[string][]*interfaces.Bar),
+		logger:   logger,
+	}
+}
+
+// Load reads minute bars for symbols between start and end from storage.
+// Symbols with nothing stored are fetched from the fallback data service
+// and stored, so later replays of the same day run offline.
+func (d *ReplayDataService) Load(ctx context.Context, storage *database.LocalStorage, symbols []string, start, end time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(symbol)
+		bars, err := storage.GetTimeframeBars(symbol, "1Min", start, end)
+		if err != nil {
+			return err
+		}
+		if len(bars) == 0 {
+			bars, err = d.fallback.GetHistoricalBars(ctx, symbol, start, end, "1Min")
+			if err != nil {
+				return fmt.Errorf("failed to load %s bars: %w", symbol, err)
+			}
+			for _, bar := range bars {
+				bar.Symbol = symbol
+			}
+			if err := storage.ReplaceBars(symbol, "1Min", start, end, bars); err != nil {
+				d.logger.WithError(err).WithField("symbol", symbol).Warn("Failed to store replay bars")
+			}
+		}
+		if len(bars) == 0 {
+			return WithErrorCode(ErrCodeNotFound, fmt.Errorf("no bars for %s between %s and %s", symbol, start.Format(time.RFC3339), end.Format(time.RFC3339)))
+		}
+		sort.Slice(bars, func(i, j int) bool { return bars[i].Timestamp.Before(bars[j].Timestamp) })
+		d.bars[symbol] = bars
+
+		d.logger.WithFields(logrus.Fields{
+			"symbol": symbol,
+			"bars":   len(bars),
+		}).Info("Replay bars loaded")
+	}
+	return nil
+}
+
+// Symbols returns the loaded symbols in sorted order
+func (d *ReplayDataService) Symbols() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	symbols := make([]string, 0, len(d.bars))
+	for symbol := range d.bars {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Steps returns the times at which a loaded bar completes between from and
+// to, in order. The replay clock visits each of them.
+func (d *ReplayDataService) Steps(from, to time.Time) []time.Time {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	seen := make(map[int64]bool)
+	steps := make([]time.Time, 0)
+	for _, bars := range d.bars {
+		for _, bar := range bars {
+			t := bar.Timestamp.Add(d.interval)
+			if t.Before(from) || t.After(to) || seen[t.UnixNano()] {
+				continue
+			}
+			seen[t.UnixNano()] = true
+			steps = append(steps, t)
+		}
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Before(steps[j]) })
+	return steps
+}
+
+// completed returns the symbol's bars completed at the replay clock
+func (d *ReplayDataService) completed(symbol string) ([]*interfaces.Bar, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	bars, ok := d.bars[strings.ToUpper(symbol)]
+	if !ok {
+		return nil, false
+	}
+	now := d.clock.Now()
+	n := sort.Search(len(bars), func(i int) bool { return bars[i].Timestamp.Add(d.interval).After(now) })
+	return bars[:n], true
+}
+
+// RecentBars returns up to n of the symbol's latest completed bars
+func (d *ReplayDataService) RecentBars(symbol string, n int) []*interfaces.Bar {
+	bars, _ := d.completed(symbol)
+	if len(bars) > n {
+		bars = bars[len(bars)-n:]
+	}
+	return bars
+}
+
+// GetHistoricalBars implements interfaces.DataService, never returning
+// bars after the replay clock
+func (d *ReplayDataService) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
+	if now := d.clock.Now(); end.After(now) {
+		end = now
+	}
+	if bars, ok := d.completed(symbol); ok && len(bars) > 0 && timeframe == "1Min" && !start.Before(bars[0].Timestamp) {
+		result := make([]*interfaces.Bar, 0)
+		for _, bar := range bars {
+			if !bar.Timestamp.Before(start) && !bar.Timestamp.After(end) {
+				result = append(result, bar)
+			}
+		}
+		return result, nil
+	}
+	if !end.After(start) {
+		return []*interfaces.Bar{}, nil
+	}
+	return d.fallback.GetHistoricalBars(ctx, symbol, start, end, timeframe)
+}
+
+// GetLatestBar implements interfaces.DataService
+func (d *ReplayDataService) GetLatestBar(ctx context.Context, symbol string) (*interfaces.Bar, error) {
+	bars, ok := d.completed(symbol)
+	if !ok {
+		return nil, WithErrorCode(ErrCodeNotSupported, fmt.Errorf("%s is not part of the replay", symbol))
+	}
+	if len(bars) == 0 {
+		return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("no %s bars yet at %s", symbol, d.clock.Now().Format(time.RFC3339)))
+	}
+	return bars[len(bars)-1], nil
+}
+
+// GetLatestQuote implements interfaces.DataService with a zero-spread quote
+// at the latest close
+func (d *ReplayDataService) GetLatestQuote(ctx context.Context, symbol string) (*interfaces.Quote, error) {
+	bar, err := d.GetLatestBar(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &interfaces.Quote{
+		Symbol:    bar.Symbol,
+		BidPrice:  bar.Close,
+		AskPrice:  bar.Close,
+		Timestamp: d.clock.Now(),
+	}, nil
+}
+
+// GetLatestTrade implements interfaces.DataService with the latest close
+func (d *ReplayDataService) GetLatestTrade(ctx context.Context, symbol string) (*interfaces.Trade, error) {
+	bar, err := d.GetLatestBar(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &interfaces.Trade{
+		Symbol:    bar.Symbol,
+		Price:     bar.Close,
+		Size:      bar.Volume,
+		Timestamp: d.clock.Now(),
+	}, nil
+}
+
+// StreamBars is not available during a replay; subscribe to the stream hub
+func (d *ReplayDataService) StreamBars(ctx context.Context, symbols []string) (<-chan *interfaces.Bar, error) {
+	return nil, ErrNotSupported
+}
+
+// ReplaySignal is a strategy decision made during a replay
+type ReplaySignal struct {
+	Time    time.Time `json:"time"`
+	Symbol  string    `json:"symbol"`
+	Action  string    `json:"action"` // "buy" or "sell"
+	Price   float64   `json:"price"`
+	OrderID string    `json:"order_id,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// ReplayFill is an order filled by the simulated broker
+type ReplayFill struct {
+	Time       time.Time       `json:"time"`
+	OrderID    string          `json:"order_id"`
+	Symbol     string          `json:"symbol"`
+	Side       string          `json:"side"`
+	Type       string          `json:"type"`
+	Qty        decimal.Decimal `json:"qty"`
+	Price      decimal.Decimal `json:"price"`
+	Commission decimal.Decimal `json:"commission"`
+}
+
+// ReplaySummary is the outcome of a market replay
+type ReplaySummary struct {
+	From         time.Time          `json:"from"`
+	To           time.Time          `json:"to"`
+	Symbols      []string           `json:"symbols"`
+	Steps        int                `json:"steps"`
+	Strategy     string             `json:"strategy,omitempty"`
+	StartingCash decimal.Decimal    `json:"starting_cash"`
+	FinalEquity  decimal.Decimal    `json:"final_equity"`
+	ReturnPct    float64            `json:"return_percent"`
+	Commissions  decimal.Decimal    `json:"commissions"`
+	Signals      []ReplaySignal     `json:"signals"`
+	Fills        []ReplayFill       `json:"fills"`
+	Managed      []*ManagedPosition `json:"managed_positions,omitempty"`
+	Positions    []ReplayPosition   `json:"positions"`
+}
+
+// ReplayPosition is a holding left at the end of a replay
+type ReplayPosition struct {
+	Symbol       string          `json:"symbol"`
+	Qty          decimal.Decimal `json:"qty"`
+	AvgPrice     decimal.Decimal `json:"avg_price"`
+	CurrentPrice decimal.Decimal `json:"current_price"`
+	UnrealizedPL decimal.Decimal `json:"unrealized_pl"`
+}
+
+// MarketReplay steps a replay clock through stored bars at a multiple of
+// real time. On every bar it fills resting simulated orders, publishes
+// ticks through the stream hub, runs the strategy on them and gives the
+// position manager a monitoring pass, in that order.
+type MarketReplay struct {
+	clock       *ReplayClock
+	data        *ReplayDataService
+	broker      *SimulatedBroker
+	hub         *StreamHub
+	riskManager *RiskManager
+	logger      *logrus.Logger
+
+	strategy   interfaces.StrategyExecutor
+	orderValue decimal.Decimal
+	lookback   int
+
+	positionManager *PositionManager
+	stopLossPct     float64
+	takeProfitPct   float64
+	managed         map[string]string // symbol -> managed position ID
+	signals         []ReplaySignal
+}
+
+// NewMarketReplay creates a replay over data, trading through broker
+func NewMarketReplay(clock *ReplayClock, data *ReplayDataService, broker *SimulatedBroker, hub *StreamHub, riskManager *RiskManager) *MarketReplay {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &MarketReplay{
+		clock:       clock,
+		data:        data,
+		broker:      broker,
+		hub:         hub,
+		riskManager: riskManager,
+		logger:      logger,
+		managed:     make(map[string]string),
+	}
+}
+
+// SetStrategy runs strategy on every replayed bar. Entries are sized at
+// orderValue dollars; lookback bars of history are passed to the strategy.
+func (r *MarketReplay) SetStrategy(strategy interfaces.StrategyExecutor, orderValue decimal.Decimal, lookback int) {
+	r.strategy = strategy
+	r.orderValue = orderValue
+	r.lookback = lookback
+}
+
+// SetPositionManager makes strategy entries managed positions with the
+// given stop loss and take profit, and monitors them on every bar
+func (r *MarketReplay) SetPositionManager(pm *PositionManager, stopLossPct, takeProfitPct float64) {
+	r.positionManager = pm
+	r.stopLossPct = stopLossPct
+	r.takeProfitPct = takeProfitPct
+}
+
+// Run replays every bar completing between from and to at speed times
+// real time and returns the outcome. Cancelling ctx stops the replay early
+// and still returns a summary.
+func (r *MarketReplay) Run(ctx context.Context, from, to time.Time, speed float64) (*ReplaySummary, error) {
+	if speed < MinReplaySpeed || speed > MaxReplaySpeed {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("speed must be between %dx and %dx", MinReplaySpeed, MaxReplaySpeed))
+	}
+	steps := r.data.Steps(from, to)
+	if len(steps) == 0 {
+		return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("no bars complete between %s and %s", from.Format(time.RFC3339), to.Format(time.RFC3339)))
+	}
+
+	symbols := r.data.Symbols()
+	r.hub.Track(symbols...)
+	defer r.hub.Untrack(symbols...)
+	subID, ticks := r.hub.Subscribe(len(symbols) * 2)
+	defer r.hub.Unsubscribe(subID)
+
+	r.clock.Set(steps[0])
+	startAccount, err := r.broker.GetAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"from":    steps[0].Format(time.RFC3339),
+		"to":      steps[len(steps)-1].Format(time.RFC3339),
+		"steps":   len(steps),
+		"symbols": strings.Join(symbols, ","),
+		"speed":   speed,
+	}).Info("Replay started")
+
+	completed := 0
+	var previous time.Time
+replay:
+	for _, step := range steps {
+		// Sleep for the virtual time between bars, scaled by speed
+		if !previous.IsZero() {
+			select {
+			case <-ctx.Done():
+				break replay
+			case <-time.After(time.Duration(float64(step.Sub(previous)) / speed)):
+			}
+		}
+		previous = step
+
+		r.clock.Set(step)
+		r.broker.Match(ctx)
+		r.hub.Poll(ctx)
+		for drained := false; !drained; {
+			select {
+			case tick := <-ticks:
+				r.onTick(ctx, tick)
+			default:
+				drained = true
+			}
+		}
+		if r.positionManager != nil {
+			r.positionManager.CheckPositions(ctx)
+		}
+		completed++
+	}
+
+	return r.summary(ctx, startAccount.PortfolioValue, completed, from, to)
+}
+
+// onTick runs the strategy on a tick carrying a bar completed at this step
+func (r *MarketReplay) onTick(ctx context.Context, tick *MarketTick) {
+	if r.strategy == nil || tick.Bar == nil || !tick.Bar.Timestamp.Add(r.data.interval).Equal(r.clock.Now()) {
+		return
+	}
+
+	data := &interfaces.MarketData{
+		Symbol:     tick.Symbol,
+		CurrentBar: tick.Bar,
+		RecentBars: r.data.RecentBars(tick.Symbol, r.lookback),
+	}
+	data.Indicators = PatternIndicators(data.RecentBars)
+	if quote, err := r.data.GetLatestQuote(ctx, tick.Symbol); err == nil {
+		data.LatestQuote = quote
+	}
+	r.strategy.OnMarketData(data)
+
+	ctx = WithOrderSource(ctx, OrderSourceStrategy)
+	if ok, req := r.strategy.ShouldBuy(ctx, tick.Symbol, data); ok {
+		r.signal(ctx, tick, "buy", req)
+	} else if ok, req := r.strategy.ShouldSell(ctx, tick.Symbol, data); ok {
+		r.signal(ctx, tick, "sell", req)
+	}
+}
+
+// signal acts on a strategy decision and reports any fill back to it
+func (r *MarketReplay) signal(ctx context.Context, tick *MarketTick, action string, req *interfaces.OrderRequest) {
+	signal := ReplaySignal{Time: r.clock.Now(), Symbol: tick.Symbol, Action: action, Price: tick.Price}
+	orderID, err := r.act(ctx, tick, action, req)
+	signal.OrderID = orderID
+	if err != nil {
+		signal.Error = err.Error()
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"symbol": tick.Symbol,
+			"action": action,
+		}).Warn("Replay signal not acted on")
+	}
+	r.signals = append(r.signals, signal)
+
+	if orderID != "" {
+		if order, err := r.broker.GetOrder(ctx, orderID); err == nil && order.Status == "filled" {
+			r.strategy.OnOrderFilled(order)
+		}
+	}
+}
+
+// act places the order for a strategy decision, through the position
+// manager when one is set
+func (r *MarketReplay) act(ctx context.Context, tick *MarketTick, action string, req *interfaces.OrderRequest) (string, error) {
+	if r.positionManager != nil {
+		if action == "buy" {
+			position, err := r.positionManager.PlaceManagedPosition(ctx, &PlaceManagedPositionRequest{
+				Symbol:            tick.Symbol,
+				Side:              "buy",
+				Strategy:          r.strategy.GetName(),
+				AllocationDollars: r.orderValue.InexactFloat64(),
+				EntryStrategy:     "market",
+				StopLossPercent:   &r.stopLossPct,
+				TakeProfitPercent: &r.takeProfitPct,
+			})
+			if err != nil {
+				return "", err
+			}
+			r.managed[tick.Symbol] = position.ID
+			return position.EntryOrderID, nil
+		}
+		if id, ok := r.managed[tick.Symbol]; ok {
+			delete(r.managed, tick.Symbol)
+			if position, err := r.positionManager.GetManagedPosition(id); err == nil && (position.Status == "ACTIVE" || position.Status == "PARTIAL") {
+				return "", r.positionManager.CloseManagedPosition(ctx, id)
+			}
+		}
+	}
+
+	qty := decimal.Zero
+	if req != nil && req.Qty.IsPositive() {
+		qty = req.Qty
+	} else if action == "buy" {
+		qty = r.orderValue.Div(decimal.NewFromFloat(tick.Price)).Floor()
+	} else {
+		positions, err := r.broker.GetPositions(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, position := range positions {
+			if position.Symbol == tick.Symbol {
+				qty = position.Qty
+			}
+		}
+	}
+	if !qty.IsPositive() {
+		return "", fmt.Errorf("nothing to %s", action)
+	}
+
+	order := &interfaces.Order{
+		Symbol:      tick.Symbol,
+		Qty:         qty,
+		Side:        action,
+		Type:        "market",
+		TimeInForce: "day",
+		Status:      "pending",
+		SubmittedAt: r.clock.Now(),
+		Strategy:    r.strategy.GetName(),
+	}
+	if req != nil && req.Type == "limit" && req.LimitPrice != nil {
+		order.Type = "limit"
+		order.LimitPrice = req.LimitPrice
+	}
+
+	decision, err := r.riskManager.Evaluate(ctx, order)
+	if err != nil {
+		return "", err
+	}
+	if !decision.Approved {
+		return "", &RiskRejectedError{Decision: decision}
+	}
+	result, err := r.broker.PlaceOrder(WithRiskDecision(ctx, decision), order)
+	if err != nil {
+		return "", err
+	}
+	return result.OrderID, nil
+}
+
+func (r *MarketReplay) summary(ctx context.Context, startingCash decimal.Decimal, steps int, from, to time.Time) (*ReplaySummary, error) {
+	account, err := r.broker.GetAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary := &ReplaySummary{
+		From:         from,
+		To:           to,
+		Symbols:      r.data.Symbols(),
+		Steps:        steps,
+		StartingCash: startingCash,
+		FinalEquity:  account.PortfolioValue,
+		Commissions:  r.broker.TotalCommissions(),
+		Signals:      r.signals,
+		Fills:        make([]ReplayFill, 0),
+		Positions:    make([]ReplayPosition, 0),
+	}
+	if summary.Signals == nil {
+		summary.Signals = []ReplaySignal{}
+	}
+	if r.strategy != nil {
+		summary.Strategy = r.strategy.GetName()
+	}
+	if startingCash.IsPositive() {
+		summary.ReturnPct = account.PortfolioValue.Sub(startingCash).Div(startingCash).Mul(decimal.NewFromInt(100)).InexactFloat64()
+	}
+
+	orders, err := r.broker.ListOrders(ctx, "all")
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		if !order.FilledQty.IsPositive() {
+			continue
+		}
+		summary.Fills = append(summary.Fills, ReplayFill{
+			Time:       *order.FilledAt,
+			OrderID:    order.ID,
+			Symbol:     order.Symbol,
+			Side:       order.Side,
+			Type:       order.Type,
+			Qty:        order.FilledQty,
+			Price:      *order.FilledAvgPrice,
+			Commission: r.broker.Commission(order.ID),
+		})
+	}
+
+	positions, err := r.broker.GetPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, position := range positions {
+		summary.Positions = append(summary.Positions, ReplayPosition{
+			Symbol:       position.Symbol,
+			Qty:          position.Qty,
+			AvgPrice:     position.AvgEntryPrice,
+			CurrentPrice: position.CurrentPrice,
+			UnrealizedPL: position.UnrealizedPL,
+		})
+	}
+	if r.positionManager != nil {
+		summary.Managed = r.positionManager.ListManagedPositions("")
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"steps":        steps,
+		"fills":        len(summary.Fills),
+		"final_equity": summary.FinalEquity.StringFixed(2),
+		"return_pct":   summary.ReturnPct,
+	}).Info("Replay complete")
+
+	return summary, nil
+}
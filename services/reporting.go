@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// UntaggedStrategy labels activity with no strategy or source tag
+const UntaggedStrategy = "untagged"
+
+// rollingExpectancyTrades is how many of the latest trades the rolling
+// expectancy averages
+const rollingExpectancyTrades = 20
+
+// rBucketEdges bound the R-multiple histogram buckets; the outer buckets are
+// open ended
+var rBucketEdges = []float64{-2, -1, 0, 1, 2, 3}
+
+// RBucket counts closed trades whose R-multiple falls in [Min, Max). Min is
+// nil for the lowest bucket and Max for the highest.
+type RBucket struct {
+	Label  string   `json:"label"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+	Trades int      `json:"trades"`
+}
+
+// PerformanceStats aggregates results for one strategy tag or book
+type PerformanceStats struct {
+	Trades        int     `json:"trades"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	WinRate       float64 `json:"win_rate"`
+	RealizedPnL   float64 `json:"realized_pnl"`
+	AvgWin        float64 `json:"avg_win"`
+	AvgLoss       float64 `json:"avg_loss"`
+	ProfitFactor  float64 `json:"profit_factor"`
+	Orders        int     `json:"orders"`
+	OpenPositions int     `json:"open_positions"`
+	Exposure      float64 `json:"exposure"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+	Dividends     float64 `json:"dividends"` // from account activities, credited to the symbol's latest buy
+	Fees          float64 `json:"fees"`      // negative
+	NetPnL        float64 `json:"net_pnl"`   // realized P&L plus dividends and fees
+
+	// R-multiples of the trades that had a stop at entry
+	RTrades            int       `json:"r_trades"`
+	ExpectancyR        float64   `json:"expectancy_r"`         // mean R-multiple
+	RollingExpectancyR float64   `json:"rolling_expectancy_r"` // mean of the latest rollingExpectancyTrades
+	RDistribution      []RBucket `json:"r_distribution,omitempty"`
+
+	grossWin   float64
+	grossLoss  float64
+	rMultiples []float64 // in exit order
+}
+
+// StrategyPerformance aggregates results for one strategy or source tag
+type StrategyPerformance struct {
+	Strategy string `json:"strategy"`
+	PerformanceStats
+}
+
+// StrategyReport is the per-strategy attribution for a time window
+type StrategyReport struct {
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	Book       string                 `json:"book,omitempty"` // set when the report covers one book
+	Strategies []*StrategyPerformance `json:"strategies"`
+}
+
+// BookPerformance aggregates results for one book
+type BookPerformance struct {
+	Book string `json:"book"`
+	PerformanceStats
+}
+
+// BookReport is the per-book attribution for a time window
+type BookReport struct {
+	Start time.Time          `json:"start"`
+	End   time.Time          `json:"end"`
+	Books []*BookPerformance `json:"books"`
+}
+
+// ReportingService builds performance reports from the trade ledger,
+// stored orders, managed positions and live broker positions
+type ReportingService struct {
+	tradingService  interfaces.TradingService
+	dataService     interfaces.DataService
+	storageService  *database.LocalStorage
+	positionManager *PositionManager
+	activities      *AccountActivityService // nil leaves cash flows, dividends and fees out
+	logger          *logrus.Logger
+}
+
+// NewReportingService creates a new reporting service
+func NewReportingService(
+	tradingService interfaces.TradingService,
+	dataService interfaces.DataService,
+	storageService *database.LocalStorage,
+	positionManager *PositionManager,
+) *ReportingService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &ReportingService{
+		tradingService:  tradingService,
+		dataService:     dataService,
+		storageService:  storageService,
+		positionManager: positionManager,
+		logger:          logger,
+	}
+}
+
+// SetAccountActivities takes deposits and withdrawals out of benchmark
+// returns and adds dividends and fees to performance attribution
+func (rs *ReportingService) SetAccountActivities(activities *AccountActivityService) {
+	rs.activities = activities
+}
+
+// StrategyPerformance attributes realized P&L (trades closed in the window),
+// order counts and current exposure to each strategy tag. When ctx selects a
+// book only that book's activity is counted.
+func (rs *ReportingService) StrategyPerformance(ctx context.Context, start, end time.Time) (*StrategyReport, error) {
+	selected, hasBook := SelectedBook(ctx)
+	stats, err := rs.attribute(ctx, start, end, func(strategy, book string) (string, bool) {
+		if hasBook && bookOrDefault(book) != selected {
+			return "", false
+		}
+		if strategy == "" {
+			strategy = UntaggedStrategy
+		}
+		return strategy, true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StrategyReport{
+		Start:      start,
+		End:        end,
+		Book:       selected,
+		Strategies: make([]*StrategyPerformance, 0, len(stats)),
+	}
+	for tag, s := range stats {
+		report.Strategies = append(report.Strategies, &StrategyPerformance{Strategy: tag, PerformanceStats: *s})
+	}
+
+	// Best performer first
+	sort.Slice(report.Strategies, func(i, j int) bool {
+		return report.Strategies[i].RealizedPnL > report.Strategies[j].RealizedPnL
+	})
+
+	return report, nil
+}
+
+// BookPerformance attributes realized P&L, order counts and current
+// exposure to each book
+func (rs *ReportingService) BookPerformance(ctx context.Context, start, end time.Time) (*BookReport, error) {
+	stats, err := rs.attribute(ctx, start, end, func(strategy, book string) (string, bool) {
+		return bookOrDefault(book), true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BookReport{
+		Start: start,
+		End:   end,
+		Books: make([]*BookPerformance, 0, len(stats)),
+	}
+	for book, s := range stats {
+		report.Books = append(report.Books, &BookPerformance{Book: book, PerformanceStats: *s})
+	}
+
+	sort.Slice(report.Books, func(i, j int) bool {
+		return report.Books[i].RealizedPnL > report.Books[j].RealizedPnL
+	})
+
+	return report, nil
+}
+
+// attribute aggregates trades, orders and open positions into the groups
+// returned by group, which skips an item by returning false
+func (rs *ReportingService) attribute(ctx context.Context, start, end time.Time, group func(strategy, book string) (string, bool)) (map[string]*PerformanceStats, error) {
+	stats := make(map[string]*PerformanceStats)
+	get := func(strategy, book string) *PerformanceStats {
+		key, ok := group(strategy, book)
+		if !ok {
+			return nil
+		}
+		if _, ok := stats[key]; !ok {
+			stats[key] = &PerformanceStats{}
+		}
+		return stats[key]
+	}
+
+	// Realized P&L from the trade ledger
+	trades, err := rs.storageService.GetTrades(start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, trade := range trades {
+		s := get(trade.StrategyName, trade.Book)
+		if s == nil {
+			continue
+		}
+		pnl := trade.PnL.InexactFloat64()
+		s.Trades++
+		s.RealizedPnL += pnl
+		if pnl > 0 {
+			s.Wins++
+			s.grossWin += pnl
+		} else if pnl < 0 {
+			s.Losses++
+			s.grossLoss += -pnl
+		}
+		if trade.RMultiple != nil {
+			s.rMultiples = append(s.rMultiples, *trade.RMultiple)
+		}
+	}
+
+	// Order counts, and the latest buy per symbol for attributing broker
+	// positions that are not managed
+	orders, err := rs.storageService.GetOrders("")
+	if err != nil {
+		return nil, err
+	}
+	symbolBuys := make(map[string]*interfaces.Order)
+	for _, order := range orders {
+		if IsShadowOrderID(order.ID) {
+			continue
+		}
+		// Orders are returned newest first
+		if order.Side == "buy" {
+			if _, ok := symbolBuys[order.Symbol]; !ok {
+				symbolBuys[order.Symbol] = order
+			}
+		}
+		if order.SubmittedAt.Before(start) || order.SubmittedAt.After(end) {
+			continue
+		}
+		if s := get(order.Strategy, order.Book); s != nil {
+			s.Orders++
+		}
+	}
+
+	// Dividends and fees, credited like the positions they came from
+	if rs.activities != nil {
+		activities, err := rs.activities.Between(start, end)
+		if err != nil {
+			return nil, err
+		}
+		for _, activity := range activities {
+			if activity.Category != ActivityDividend && activity.Category != ActivityFee {
+				continue
+			}
+			var s *PerformanceStats
+			if buy, ok := symbolBuys[activity.Symbol]; ok && activity.Symbol != "" {
+				s = get(buy.Strategy, buy.Book)
+			} else {
+				s = get("", "")
+			}
+			if s == nil {
+				continue
+			}
+			if activity.Category == ActivityDividend {
+				s.Dividends += activity.NetAmount.InexactFloat64()
+			} else {
+				s.Fees += activity.NetAmount.InexactFloat64()
+			}
+		}
+	}
+
+	// Exposure from open managed positions
+	managedSymbols := make(map[string]bool)
+	for _, position := range rs.positionManager.ListManagedPositions("") {
+		if position.Shadow || (position.Status != "ACTIVE" && position.Status != "PARTIAL") {
+			continue
+		}
+		managedSymbols[position.Symbol] = true
+		s := get(position.Strategy, position.Book)
+		if s == nil {
+			continue
+		}
+		s.OpenPositions++
+		s.Exposure += position.RemainingQty * position.CurrentPrice
+		s.UnrealizedPnL += position.UnrealizedPL
+	}
+
+	// Exposure from the remaining broker positions
+	positions, err := rs.tradingService.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+	for _, position := range positions {
+		if managedSymbols[position.Symbol] {
+			continue
+		}
+		var s *PerformanceStats
+		if buy, ok := symbolBuys[position.Symbol]; ok {
+			s = get(buy.Strategy, buy.Book)
+		} else {
+			s = get("", "")
+		}
+		if s == nil {
+			continue
+		}
+		s.OpenPositions++
+		s.Exposure += position.MarketValue.Abs().InexactFloat64()
+		s.UnrealizedPnL += position.UnrealizedPL.InexactFloat64()
+	}
+
+	for _, s := range stats {
+		s.NetPnL = s.RealizedPnL + s.Dividends + s.Fees
+		if s.Trades > 0 {
+			s.WinRate = float64(s.Wins) / float64(s.Trades) * 100
+		}
+		if s.Wins > 0 {
+			s.AvgWin = s.grossWin / float64(s.Wins)
+		}
+		if s.Losses > 0 {
+			s.AvgLoss = -s.grossLoss / float64(s.Losses)
+		}
+		if s.grossLoss > 0 {
+			s.ProfitFactor = s.grossWin / s.grossLoss
+		}
+		if s.RTrades = len(s.rMultiples); s.RTrades > 0 {
+			s.ExpectancyR = meanR(s.rMultiples)
+			s.RollingExpectancyR = meanR(s.rMultiples[max(0, s.RTrades-rollingExpectancyTrades):])
+			s.RDistribution = rDistribution(s.rMultiples)
+		}
+	}
+
+	return stats, nil
+}
+
+func meanR(rs []float64) float64 {
+	var sum float64
+	for _, r := range rs {
+		sum += r
+	}
+	return sum / float64(len(rs))
+}
+
+// rDistribution buckets R-multiples by rBucketEdges
+func rDistribution(rs []float64) []RBucket {
+	buckets := make([]RBucket, len(rBucketEdges)+1)
+	for i := range buckets {
+		if i > 0 {
+			low := rBucketEdges[i-1]
+			buckets[i].Min = &low
+		}
+		if i < len(rBucketEdges) {
+			high := rBucketEdges[i]
+			buckets[i].Max = &high
+		}
+		switch {
+		case buckets[i].Min == nil:
+			buckets[i].Label = fmt.Sprintf("< %gR", *buckets[i].Max)
+		case buckets[i].Max == nil:
+			buckets[i].Label = fmt.Sprintf(">= %gR", *buckets[i].Min)
+		default:
+			buckets[i].Label = fmt.Sprintf("%gR to %gR", *buckets[i].Min, *buckets[i].Max)
+		}
+	}
+	for _, r := range rs {
+		i := sort.SearchFloat64s(rBucketEdges, r)
+		if i < len(rBucketEdges) && rBucketEdges[i] == r {
+			i++ // an edge belongs to the bucket above it
+		}
+		buckets[i].Trades++
+	}
+	return buckets
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"prophet-trader/interfaces"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ttlCache keeps values for a short time and shares one fetch between
+// concurrent callers asking for the same key. Errors are shared with the
+// callers already waiting but never cached.
+type ttlCache[T any] struct {
+	ttl     time.Duration
+	entries map[string]*cacheEntry[T]
+	mu      sync.Mutex
+}
+
+type cacheEntry[T any] struct {
+	value   T
+	err     error
+	fetched time.Time
+	done    chan struct{} // closed once the fetch has finished
+}
+
+func newTTLCache[T any](ttl time.Duration) *ttlCache[T] {
+	return &ttlCache[T]{
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry[T]),
+	}
+}
+
+// get returns the cached value for key, calling fetch when there is none
+// younger than the TTL and no fetch already in flight
+func (c *ttlCache[T]) get(ctx context.Context, key string, fetch func() (T, error)) (T, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		select {
+		case <-entry.done:
+			if entry.err == nil && time.Since(entry.fetched) < c.ttl {
+				c.mu.Unlock()
+				return entry.value, nil
+			}
+			ok = false
+		default:
+		}
+	}
+	if ok {
+		// Another caller is fetching; wait for it
+		c.mu.Unlock()
+		select {
+		case <-entry.done:
+			return entry.value, entry.err
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+
+	entry = &cacheEntry[T]{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	entry.value, entry.err = fetch()
+	entry.fetched = time.Now()
+	if entry.err != nil {
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.value, entry.err
+}
+
+// clear drops every entry. Fetches in flight still answer the callers
+// waiting on them but are not cached.
+func (c *ttlCache[T]) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry[T])
+}
+
+// SharedCache is a cache several instances use together, such as Redis
+type SharedCache interface {
+	// Get returns the value stored at key, or nil when there is none
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value at key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CachedDataService serves latest quotes from a short-lived cache so bursts
+// of dashboard refreshes and monitoring loops share upstream calls
+type CachedDataService struct {
+	interfaces.DataService
+	quotes   *ttlCache[*interfaces.Quote]
+	quoteTTL time.Duration
+	shared   SharedCache
+}
+
+// NewCachedDataService wraps data, caching latest quotes for quoteTTL. A
+// TTL of zero still merges concurrent requests for the same symbol.
+func NewCachedDataService(data interfaces.DataService, quoteTTL time.Duration) *CachedDataService {
+	return &CachedDataService{
+		DataService: data,
+		quotes:      newTTLCache[*interfaces.Quote](quoteTTL),
+		quoteTTL:    quoteTTL,
+	}
+}
+
+// SetSharedCache shares quotes with other instances through shared. The
+// in-process cache still answers first; shared is asked before upstream.
+func (s *CachedDataService) SetSharedCache(shared SharedCache) {
+	s.shared = shared
+}
+
+// GetLatestQuote implements interfaces.DataService
+func (s *CachedDataService) GetLatestQuote(ctx context.Context, symbol string) (*interfaces.Quote, error) {
+	quote, err := s.quotes.get(ctx, strings.ToUpper(symbol), func() (*interfaces.Quote, error) {
+		return s.fetchQuote(ctx, symbol)
+	})
+	if err != nil {
+		return nil, err
+	}
+	copied := *quote
+	return &copied, nil
+}
+
+// fetchQuote gets a quote from the shared cache, else from upstream. The
+// shared cache is an optimisation, so its failures fall through to upstream.
+func (s *CachedDataService) fetchQuote(ctx context.Context, symbol string) (*interfaces.Quote, error) {
+	if s.shared == nil || s.quoteTTL <= 0 {
+		return s.DataService.GetLatestQuote(ctx, symbol)
+	}
+
+	key := "quote:" + strings.ToUpper(symbol)
+	if data, err := s.shared.Get(ctx, key); err == nil && data != nil {
+		var quote interfaces.Quote
+		if json.Unmarshal(data, &quote) == nil {
+			return &quote, nil
+		}
+	}
+
+	quote, err := s.DataService.GetLatestQuote(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(quote); err == nil {
+		s.shared.Set(ctx, key, data, s.quoteTTL)
+	}
+	return quote, nil
+}
+
+// CachedTradingService serves positions from a short-lived cache. Placing
+// or cancelling an order clears it, but a fill between those calls can
+// take up to the TTL to show.
+type CachedTradingService struct {
+	interfaces.TradingService
+	positions *ttlCache[[]*interfaces.Position]
+}
+
+// NewCachedTradingService wraps trading, caching positions for positionTTL
+func NewCachedTradingService(trading interfaces.TradingService, positionTTL time.Duration) *CachedTradingService {
+	return &CachedTradingService{
+		TradingService: trading,
+		positions:      newTTLCache[[]*interfaces.Position](positionTTL),
+	}
+}
+
+// GetPositions implements interfaces.TradingService
+func (s *CachedTradingService) GetPositions(ctx context.Context) ([]*interfaces.Position, error) {
+	positions, err := s.positions.get(ctx, "", func() ([]*interfaces.Position, error) {
+		return s.TradingService.GetPositions(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	copied := make([]*interfaces.Position, len(positions))
+	for i, position := range positions {
+		p := *position
+		copied[i] = &p
+	}
+	return copied, nil
+}
+
+// PlaceOrder implements interfaces.TradingService
+func (s *CachedTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	defer s.positions.clear()
+	return s.TradingService.PlaceOrder(ctx, order)
+}
+
+// PlaceOptionsOrder implements interfaces.TradingService
+func (s *CachedTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	defer s.positions.clear()
+	return s.TradingService.PlaceOptionsOrder(ctx, order)
+}
+
+// CancelOrder implements interfaces.TradingService
+func (s *CachedTradingService) CancelOrder(ctx context.Context, orderID string) error {
+	defer s.positions.clear()
+	return s.TradingService.CancelOrder(ctx, orderID)
+}
@@ -0,0 +1,424 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prophet-trader/interfaces"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// RiskCheck is the pre-trade context handed to every rule
+type RiskCheck struct {
+	Order          *interfaces.Order
+	AssetClass     string          // "us_equity" or "us_option"
+	Multiplier     decimal.Decimal // 1 for equities, 100 for options
+	EstimatedPrice decimal.Decimal // zero when no price could be determined
+	Notional       decimal.Decimal
+	Account        *interfaces.Account
+	Positions      []*interfaces.Position
+	OpenOrders     []*interfaces.Order // working at the broker, not yet filled in full
+}
+
+// RiskRule is a single pre-trade check. Returning an error rejects the order,
+// unless the error is a *RiskWarning, which is reported but lets it through.
+type RiskRule interface {
+	Name() string
+	Check(ctx context.Context, check *RiskCheck) error
+}
+
+// SizingRule is implemented by rules that also scale new position sizes.
+// The returned reason is empty when no scaling applies.
+type SizingRule interface {
+	SizeMultiplier(ctx context.Context) (float64, string)
+}
+
+// StopRule is implemented by rules that widen the stops of new positions.
+// The returned reason is empty when no widening applies.
+type StopRule interface {
+	StopMultiplier(ctx context.Context) (float64, string)
+}
+
+// RiskViolation describes a rule that rejected an order
+type RiskViolation struct {
+	Rule    string `json:"rule"`
+	Code    string `json:"code,omitempty"` // why, for rules that say
+	Message string `json:"message"`
+}
+
+// Rejection codes reported by rules that return a *RiskRejection
+const (
+	RiskCodeSymbolBlocked       = "SYMBOL_BLOCKED"
+	RiskCodeMaxPositionExceeded = "MAX_POSITION_EXCEEDED"
+	RiskCodeOrderTypeNotAllowed = "ORDER_TYPE_NOT_ALLOWED"
+	RiskCodePriceBelowMinimum   = "PRICE_BELOW_MINIMUM"
+	RiskCodeShortNotAllowed     = "SHORT_NOT_ALLOWED"
+	RiskCodePriceUnavailable    = "PRICE_UNAVAILABLE"
+)
+
+// RiskRejection is returned by rules that reject an order with a code
+// clients can act on
+type RiskRejection struct {
+	Code    string
+	Message string
+}
+
+func (r *RiskRejection) Error() string {
+	return r.Message
+}
+
+// RiskWarning is returned by rules that flag an order without blocking it
+type RiskWarning struct {
+	Message string
+}
+
+func (w *RiskWarning) Error() string {
+	return w.Message
+}
+
+// RiskDecision is the outcome of evaluating an order against all rules
+type RiskDecision struct {
+	Approved       bool            `json:"approved"`
+	EstimatedPrice decimal.Decimal `json:"estimated_price"`
+	Notional       decimal.Decimal `json:"notional"`
+	Violations     []RiskViolation `json:"violations,omitempty"`
+	Warnings       []RiskViolation `json:"warnings,omitempty"`
+}
+
+// RiskRejectedError is returned when an order fails one or more risk rules
+type RiskRejectedError struct {
+	Decision *RiskDecision
+}
+
+func (e *RiskRejectedError) Error() string {
+	messages := make([]string, len(e.Decision.Violations))
+	for i, v := range e.Decision.Violations {
+		messages[i] = fmt.Sprintf("%s: %s", v.Rule, v.Message)
+	}
+	return "order rejected by risk checks: " + strings.Join(messages, "; ")
+}
+
+// RiskManager runs pre-trade risk rules against orders
+type RiskManager struct {
+	tradingService interfaces.TradingService
+	dataService    interfaces.DataService
+	rules          []RiskRule
+	eventBus       *EventBus
+	logger         *logrus.Logger
+}
+
+// NewRiskManager creates a risk manager with the given rules
+func NewRiskManager(tradingService interfaces.TradingService, dataService interfaces.DataService, rules ...RiskRule) *RiskManager {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &RiskManager{
+		tradingService: tradingService,
+		dataService:    dataService,
+		rules:          rules,
+		logger:         logger,
+	}
+}
+
+// SetEventBus publishes rejected orders as risk_breached events
+func (rm *RiskManager) SetEventBus(bus *EventBus) {
+	rm.eventBus = bus
+}
+
+// AddRule appends a rule to the chain
+func (rm *RiskManager) AddRule(rule RiskRule) {
+	rm.rules = append(rm.rules, rule)
+}
+
+// PositionSizeMultiplier combines the scale factors of all sizing rules,
+// returning 1 when none apply
+func (rm *RiskManager) PositionSizeMultiplier(ctx context.Context) (float64, []string) {
+	multiplier := 1.0
+	reasons := make([]string, 0)
+	for _, rule := range rm.rules {
+		sizer, ok := rule.(SizingRule)
+		if !ok {
+			continue
+		}
+		scale, reason := sizer.SizeMultiplier(ctx)
+		if scale < 0 {
+			scale = 0
+		}
+		if scale != 1 && reason != "" {
+			reasons = append(reasons, fmt.Sprintf("%s: x%.2f (%s)", rule.Name(), scale, reason))
+		}
+		multiplier *= scale
+	}
+	return multiplier, reasons
+}
+
+// StopDistanceMultiplier returns the largest stop widening asked for by any
+// stop rule, or 1 when none apply
+func (rm *RiskManager) StopDistanceMultiplier(ctx context.Context) (float64, []string) {
+	multiplier := 1.0
+	reasons := make([]string, 0)
+	for _, rule := range rm.rules {
+		stopper, ok := rule.(StopRule)
+		if !ok {
+			continue
+		}
+		scale, reason := stopper.StopMultiplier(ctx)
+		if scale <= 1 {
+			continue
+		}
+		if reason != "" {
+			reasons = append(reasons, fmt.Sprintf("%s: x%.2f (%s)", rule.Name(), scale, reason))
+		}
+		if scale > multiplier {
+			multiplier = scale
+		}
+	}
+	return multiplier, reasons
+}
+
+// Evaluate runs all rules against an equity order
+func (rm *RiskManager) Evaluate(ctx context.Context, order *interfaces.Order) (*RiskDecision, error) {
+	check := &RiskCheck{
+		Order:          order,
+		AssetClass:     "us_equity",
+		Multiplier:     decimal.NewFromInt(1),
+		EstimatedPrice: rm.estimatePrice(ctx, order),
+	}
+	return rm.evaluate(ctx, check)
+}
+
+// EvaluateOptions runs all rules against an options order. Market orders
+// are priced from the contract's quote.
+func (rm *RiskManager) EvaluateOptions(ctx context.Context, order *interfaces.OptionsOrder) (*RiskDecision, error) {
+	check := &RiskCheck{
+		Order: &interfaces.Order{
+			Symbol:      order.Symbol,
+			Qty:         order.Qty,
+			Side:        order.Side,
+			Type:        order.Type,
+			TimeInForce: order.TimeInForce,
+			LimitPrice:  order.LimitPrice,
+		},
+		AssetClass:     "us_option",
+		Multiplier:     decimal.NewFromInt(100),
+		EstimatedPrice: rm.estimateOptionsPrice(ctx, order),
+	}
+	return rm.evaluate(ctx, check)
+}
+
+func (rm *RiskManager) evaluate(ctx context.Context, check *RiskCheck) (*RiskDecision, error) {
+	account, err := rm.tradingService.GetAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account for risk checks: %w", err)
+	}
+	positions, err := rm.tradingService.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions for risk checks: %w", err)
+	}
+	openOrders, err := rm.tradingService.ListOrders(ctx, "open")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open orders for risk checks: %w", err)
+	}
+
+	check.Account = account
+	check.Positions = positions
+	check.OpenOrders = openOrders
+	check.Notional = check.EstimatedPrice.Mul(check.Order.Qty).Mul(check.Multiplier)
+
+	decision := &RiskDecision{
+		Approved:       true,
+		EstimatedPrice: check.EstimatedPrice,
+		Notional:       check.Notional,
+	}
+
+	// Without a price the notional limits can't be checked, so only orders
+	// that reduce a position, such as exits while quotes are down, pass
+	if !check.EstimatedPrice.IsPositive() && !onlyReducesPosition(check) {
+		decision.Approved = false
+		decision.Violations = append(decision.Violations, RiskViolation{
+			Rule:    "price_estimate",
+			Code:    RiskCodePriceUnavailable,
+			Message: fmt.Sprintf("no price available to estimate the notional of %s: set a limit price or retry when quotes are available", check.Order.Symbol),
+		})
+	}
+
+	for _, rule := range rm.rules {
+		if err := rule.Check(ctx, check); err != nil {
+			var warning *RiskWarning
+			if errors.As(err, &warning) {
+				decision.Warnings = append(decision.Warnings, RiskViolation{
+					Rule:    rule.Name(),
+					Message: warning.Message,
+				})
+				continue
+			}
+			violation := RiskViolation{
+				Rule:    rule.Name(),
+				Message: err.Error(),
+			}
+			var rejection *RiskRejection
+			if errors.As(err, &rejection) {
+				violation.Code = rejection.Code
+			}
+			decision.Approved = false
+			decision.Violations = append(decision.Violations, violation)
+		}
+	}
+
+	if !decision.Approved {
+		rm.logger.WithFields(logrus.Fields{
+			"symbol":     check.Order.Symbol,
+			"side":       check.Order.Side,
+			"qty":        check.Order.Qty.String(),
+			"violations": len(decision.Violations),
+		}).Warn("Order rejected by risk checks")
+
+		rm.eventBus.Publish(EventRiskBreached, RiskBreached{
+			Symbol:     check.Order.Symbol,
+			Side:       check.Order.Side,
+			Qty:        check.Order.Qty,
+			AssetClass: check.AssetClass,
+			Notional:   check.Notional,
+			Violations: decision.Violations,
+			Source:     OrderSourceFrom(ctx),
+			Book:       BookFrom(ctx),
+		})
+	}
+
+	return decision, nil
+}
+
+// estimatePrice picks the price an equity order is expected to fill at
+func (rm *RiskManager) estimatePrice(ctx context.Context, order *interfaces.Order) decimal.Decimal {
+	if order.LimitPrice != nil {
+		return *order.LimitPrice
+	}
+	if order.StopPrice != nil {
+		return *order.StopPrice
+	}
+
+	quote, err := rm.dataService.GetLatestQuote(ctx, order.Symbol)
+	if err != nil {
+		rm.logger.WithError(err).WithField("symbol", order.Symbol).Warn("Failed to get quote for risk checks")
+		return decimal.Zero
+	}
+
+	price := quote.AskPrice
+	if order.Side == "sell" || price <= 0 {
+		price = quote.BidPrice
+	}
+	return decimal.NewFromFloat(price)
+}
+
+// estimateOptionsPrice picks the price an options order is expected to
+// fill at: the limit, or for market orders the ask when buying and the
+// midpoint when selling
+func (rm *RiskManager) estimateOptionsPrice(ctx context.Context, order *interfaces.OptionsOrder) decimal.Decimal {
+	if order.LimitPrice != nil {
+		return *order.LimitPrice
+	}
+
+	quote, err := rm.tradingService.GetOptionsQuote(ctx, order.Symbol)
+	if err != nil {
+		rm.logger.WithError(err).WithField("symbol", order.Symbol).Warn("Failed to get options quote for risk checks")
+		return decimal.Zero
+	}
+
+	var price float64
+	switch {
+	case order.Side == "buy" && quote.AskPrice > 0:
+		price = quote.AskPrice
+	case quote.BidPrice > 0 && quote.AskPrice > 0:
+		price = (quote.BidPrice + quote.AskPrice) / 2
+	case quote.LastPrice > 0:
+		price = quote.LastPrice
+	default:
+		// A one-sided quote with no last trade
+		price = max(quote.AskPrice, quote.BidPrice)
+	}
+	return decimal.NewFromFloat(price)
+}
+
+// onlyReducesPosition reports whether the order shrinks the position held
+// in its symbol without reversing it
+func onlyReducesPosition(check *RiskCheck) bool {
+	held := heldQty(check)
+	after := held.Add(check.Order.Qty)
+	if check.Order.Side == "sell" {
+		after = held.Sub(check.Order.Qty)
+	}
+	return after.Abs().LessThan(held.Abs()) && after.Sign()*held.Sign() >= 0
+}
+
+// heldQty is the position in the order's symbol, negative when short
+func heldQty(check *RiskCheck) decimal.Decimal {
+	for _, p := range check.Positions {
+		if strings.EqualFold(p.Symbol, check.Order.Symbol) {
+			if p.Side == "short" && p.Qty.IsPositive() {
+				return p.Qty.Neg()
+			}
+			return p.Qty
+		}
+	}
+	return decimal.Zero
+}
+
+// workingQty is the unfilled quantity of the open orders in the order's
+// symbol on the same side as it, negative for sells
+func workingQty(check *RiskCheck) decimal.Decimal {
+	working := decimal.Zero
+	for _, o := range check.OpenOrders {
+		if o == nil || !strings.EqualFold(o.Symbol, check.Order.Symbol) || o.Side != check.Order.Side {
+			continue
+		}
+		if check.Order.ID != "" && o.ID == check.Order.ID {
+			continue
+		}
+		if remaining := o.Qty.Sub(o.FilledQty); remaining.IsPositive() {
+			working = working.Add(remaining)
+		}
+	}
+	if check.Order.Side == "sell" {
+		return working.Neg()
+	}
+	return working
+}
+
+// BuyingPowerRule rejects buys whose notional exceeds available buying power
+type BuyingPowerRule struct{}
+
+func (BuyingPowerRule) Name() string { return "buying_power" }
+
+func (BuyingPowerRule) Check(ctx context.Context, check *RiskCheck) error {
+	if check.Order.Side != "buy" || check.Notional.IsZero() {
+		return nil
+	}
+	if check.Notional.GreaterThan(check.Account.BuyingPower) {
+		return fmt.Errorf("order notional %s exceeds buying power %s",
+			check.Notional.StringFixed(2), check.Account.BuyingPower.StringFixed(2))
+	}
+	return nil
+}
+
+// MaxOrderValueRule rejects orders above a fixed notional limit
+type MaxOrderValueRule struct {
+	Max decimal.Decimal
+}
+
+func (MaxOrderValueRule) Name() string { return "max_order_value" }
+
+func (r MaxOrderValueRule) Check(ctx context.Context, check *RiskCheck) error {
+	if !r.Max.IsPositive() {
+		return nil
+	}
+	if check.Notional.GreaterThan(r.Max) {
+		return fmt.Errorf("order notional %s exceeds limit %s",
+			check.Notional.StringFixed(2), r.Max.StringFixed(2))
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"prophet-trader/interfaces"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRiskManagerEvaluate(t *testing.T) {
+	limit := decimal.NewFromInt(40)
+	stop := decimal.NewFromInt(45)
+
+	tests := []struct {
+		name      string
+		order     *interfaces.Order
+		rules     []RiskRule
+		approved  bool
+		code      string // the first violation's code, empty when approved
+		estimated string // the estimated price, empty to skip the check
+	}{
+		{
+			name:      "market buy priced at the ask",
+			order:     &interfaces.Order{Symbol: "AAPL", Qty: decimal.NewFromInt(10), Side: "buy", Type: "market"},
+			approved:  true,
+			estimated: "100.01",
+		},
+		{
+			name:      "market sell priced at the bid",
+			order:     &interfaces.Order{Symbol: "AAPL", Qty: decimal.NewFromInt(10), Side: "sell", Type: "market"},
+			approved:  true,
+			estimated: "99.99",
+		},
+		{
+			name:     "market buy without a quote",
+			order:    &interfaces.Order{Symbol: "HALTED", Qty: decimal.NewFromInt(10), Side: "buy", Type: "market"},
+			approved: false,
+			code:     RiskCodePriceUnavailable,
+		},
+		{
+			name:      "limit buy without a quote priced at its limit",
+			order:     &interfaces.Order{Symbol: "HALTED", Qty: decimal.NewFromInt(10), Side: "buy", Type: "limit", LimitPrice: &limit},
+			approved:  true,
+			estimated: "40",
+		},
+		{
+			name:      "stop sell without a quote priced at its stop",
+			order:     &interfaces.Order{Symbol: "HALTED", Qty: decimal.NewFromInt(50), Side: "sell", Type: "stop", StopPrice: &stop},
+			approved:  true,
+			estimated: "45",
+		},
+		{
+			name:      "market sell reducing a position without a quote",
+			order:     &interfaces.Order{Symbol: "HALTED", Qty: decimal.NewFromInt(50), Side: "sell", Type: "market"},
+			approved:  true,
+			estimated: "0",
+		},
+		{
+			name:     "market sell closing and reversing a position without a quote",
+			order:    &interfaces.Order{Symbol: "HALTED", Qty: decimal.NewFromInt(150), Side: "sell", Type: "market"},
+			approved: false,
+			code:     RiskCodePriceUnavailable,
+		},
+		{
+			name:     "market buy adding to a position without a quote",
+			order:    &interfaces.Order{Symbol: "HALTED", Qty: decimal.NewFromInt(1), Side: "buy", Type: "market"},
+			approved: false,
+			code:     RiskCodePriceUnavailable,
+		},
+		{
+			name:     "rule rejection carries its code",
+			order:    &interfaces.Order{Symbol: "AAPL", Qty: decimal.NewFromInt(10), Side: "buy", Type: "market"},
+			rules:    []RiskRule{SymbolRulesRule{Rules: newTestSymbolRules(nil, []string{"AAPL"}, 0)}},
+			approved: false,
+			code:     RiskCodeSymbolBlocked,
+		},
+		{
+			name:     "buying power rule rejects without a code",
+			order:    &interfaces.Order{Symbol: "AAPL", Qty: decimal.NewFromInt(10000), Side: "buy", Type: "market"},
+			rules:    []RiskRule{BuyingPowerRule{}},
+			approved: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 100 HALTED shares were bought before its quotes went away
+			market := newTestMarket(map[string]float64{"AAPL": 100, "HALTED": 50})
+			broker := newTestBroker(market)
+			buyShares(t, broker, "HALTED", 100)
+			market.set("HALTED", 0)
+
+			rm := NewRiskManager(broker, market, tt.rules...)
+			decision, err := rm.Evaluate(context.Background(), tt.order)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+
+			if decision.Approved != tt.approved {
+				t.Errorf("approved = %v, want %v (violations %+v)", decision.Approved, tt.approved, decision.Violations)
+			}
+			if !tt.approved {
+				if len(decision.Violations) == 0 {
+					t.Fatalf("rejected without violations")
+				}
+				if got := decision.Violations[0].Code; got != tt.code {
+					t.Errorf("violation code = %q, want %q (%s)", got, tt.code, decision.Violations[0].Message)
+				}
+			}
+			if tt.estimated != "" && !decision.EstimatedPrice.Equal(decimal.RequireFromString(tt.estimated)) {
+				t.Errorf("estimated price = %s, want %s", decision.EstimatedPrice, tt.estimated)
+			}
+		})
+	}
+}
+
+// The simulated broker has no options quotes, so only a limit prices an
+// options order
+func TestRiskManagerEvaluateOptionsWithoutQuote(t *testing.T) {
+	market := newTestMarket(map[string]float64{"AAPL": 100})
+	rm := NewRiskManager(newTestBroker(market), market)
+	limit := decimal.RequireFromString("2.50")
+
+	tests := []struct {
+		name     string
+		order    *interfaces.OptionsOrder
+		approved bool
+		notional string
+	}{
+		{
+			name:     "market buy",
+			order:    &interfaces.OptionsOrder{Symbol: "AAPL261120C00100000", Qty: decimal.NewFromInt(1), Side: "buy", Type: "market"},
+			approved: false,
+		},
+		{
+			name:     "limit buy",
+			order:    &interfaces.OptionsOrder{Symbol: "AAPL261120C00100000", Qty: decimal.NewFromInt(2), Side: "buy", Type: "limit", LimitPrice: &limit},
+			approved: true,
+			notional: "500",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := rm.EvaluateOptions(context.Background(), tt.order)
+			if err != nil {
+				t.Fatalf("EvaluateOptions: %v", err)
+			}
+			if decision.Approved != tt.approved {
+				t.Fatalf("approved = %v, want %v (violations %+v)", decision.Approved, tt.approved, decision.Violations)
+			}
+			if !tt.approved && decision.Violations[0].Code != RiskCodePriceUnavailable {
+				t.Errorf("violation code = %q, want %q", decision.Violations[0].Code, RiskCodePriceUnavailable)
+			}
+			if tt.notional != "" && !decision.Notional.Equal(decimal.RequireFromString(tt.notional)) {
+				t.Errorf("notional = %s, want %s", decision.Notional, tt.notional)
+			}
+		})
+	}
+}
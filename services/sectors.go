@@ -0,0 +1,106 @@
+package services
+
+import (
+	"strings"
+)
+
+// underlyingSymbol returns the underlying ticker for an option symbol, or
+// the symbol itself for equities
+func underlyingSymbol(symbol string) string {
+	if m := occSymbolPattern.FindStringSubmatch(symbol); m != nil {
+		return m[1]
+	}
+	return symbol
+}
+
+// assetClassFor classifies a symbol as an equity or an option
+func assetClassFor(symbol string) string {
+	if IsOCCSymbol(symbol) {
+		return "us_option"
+	}
+	return "us_equity"
+}
+
+// symbolSectors maps common tickers and ETFs to a GICS-style sector.
+// Symbols not listed are reported as "Unknown".
+var symbolSectors = map[string]string{
+	// Broad market ETFs
+	"SPY": "Broad Market", "VOO": "Broad Market", "IVV": "Broad Market", "VTI": "Broad Market",
+	"QQQ": "Broad Market", "IWM": "Broad Market", "DIA": "Broad Market",
+
+	// Technology
+	"AAPL": "Technology", "MSFT": "Technology", "NVDA": "Technology", "AMD": "Technology",
+	"AVGO": "Technology", "ORCL": "Technology", "CRM": "Technology", "ADBE": "Technology",
+	"INTC": "Technology", "CSCO": "Technology", "QCOM": "Technology", "TXN": "Technology",
+	"MU": "Technology", "PLTR": "Technology", "SMCI": "Technology", "ARM": "Technology",
+	"TSM": "Technology", "ASML": "Technology", "NOW": "Technology", "SNOW": "Technology",
+	"XLK": "Technology", "SMH": "Technology", "SOXX": "Technology",
+
+	// Communication services
+	"GOOGL": "Communication Services", "GOOG": "Communication Services", "META": "Communication Services",
+	"NFLX": "Communication Services", "DIS": "Communication Services", "T": "Communication Services",
+	"VZ": "Communication Services", "TMUS": "Communication Services", "XLC": "Communication Services",
+
+	// Consumer discretionary
+	"AMZN": "Consumer Discretionary", "TSLA": "Consumer Discretionary", "HD": "Consumer Discretionary",
+	"MCD": "Consumer Discretionary", "NKE": "Consumer Discretionary", "SBUX": "Consumer Discretionary",
+	"LOW": "Consumer Discretionary", "BKNG": "Consumer Discretionary", "XLY": "Consumer Discretionary",
+
+	// Consumer staples
+	"WMT": "Consumer Staples", "COST": "Consumer Staples", "PG": "Consumer Staples",
+	"KO": "Consumer Staples", "PEP": "Consumer Staples", "XLP": "Consumer Staples",
+
+	// Financials
+	"JPM": "Financials", "BAC": "Financials", "WFC": "Financials", "GS": "Financials",
+	"MS": "Financials", "C": "Financials", "V": "Financials", "MA": "Financials",
+	"BRK.B": "Financials", "COIN": "Financials", "XLF": "Financials",
+
+	// Health care
+	"UNH": "Health Care", "JNJ": "Health Care", "LLY": "Health Care", "PFE": "Health Care",
+	"MRK": "Health Care", "ABBV": "Health Care", "TMO": "Health Care", "XLV": "Health Care",
+
+	// Energy
+	"XOM": "Energy", "CVX": "Energy", "COP": "Energy", "OXY": "Energy", "XLE": "Energy",
+
+	// Industrials
+	"BA": "Industrials", "CAT": "Industrials", "GE": "Industrials", "HON": "Industrials",
+	"UPS": "Industrials", "LMT": "Industrials", "XLI": "Industrials",
+
+	// Materials, utilities, real estate
+	"LIN": "Materials", "XLB": "Materials",
+	"NEE": "Utilities", "XLU": "Utilities",
+	"PLD": "Real Estate", "AMT": "Real Estate", "XLRE": "Real Estate",
+
+	// Other asset classes
+	"GLD": "Commodities", "SLV": "Commodities", "USO": "Commodities",
+	"TLT": "Fixed Income", "IEF": "Fixed Income", "SHY": "Fixed Income", "HYG": "Fixed Income",
+	"UVXY": "Volatility", "VXX": "Volatility",
+}
+
+// SectorFor returns the sector of a symbol (options map to their underlying)
+func SectorFor(symbol string) string {
+	if sector, ok := symbolSectors[strings.ToUpper(underlyingSymbol(symbol))]; ok {
+		return sector
+	}
+	return "Unknown"
+}
+
+// sectorETFs maps each sector to its SPDR sector ETF
+var sectorETFs = map[string]string{
+	"Technology":             "XLK",
+	"Communication Services": "XLC",
+	"Consumer Discretionary": "XLY",
+	"Consumer Staples":       "XLP",
+	"Financials":             "XLF",
+	"Health Care":            "XLV",
+	"Energy":                 "XLE",
+	"Industrials":            "XLI",
+	"Materials":              "XLB",
+	"Utilities":              "XLU",
+	"Real Estate":            "XLRE",
+}
+
+// SectorETF returns the sector ETF for a symbol, or "" when its sector has none
+func SectorETF(symbol string) string {
+	return sectorETFs[SectorFor(symbol)]
+}
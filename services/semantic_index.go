@@ -0,0 +1,424 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"math"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Semantic index limits
+const (
+	semanticRetention  = 90 * 24 * time.Hour // items published earlier are dropped
+	semanticMaxEntries = 20000               // oldest entries are dropped beyond this
+	semanticTextLength = 1000                // bytes of each item that are embedded and stored
+	semanticMaxResults = 50
+	semanticFlushBatch = 500 // items embedded per flush
+)
+
+// Kinds of items in the semantic index
+const (
+	SemanticKindNews     = "news"
+	SemanticKindAnalysis = "analysis"
+)
+
+// SemanticMatch is an indexed item similar to a query
+type SemanticMatch struct {
+	Kind        string    `json:"kind"`
+	Title       string    `json:"title"`
+	Text        string    `json:"text,omitempty"`
+	Link        string    `json:"link,omitempty"`
+	Symbols     []string  `json:"symbols,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	Score       float64   `json:"score"` // cosine similarity, 1 is identical
+}
+
+// SemanticQuery searches the semantic index
+type SemanticQuery struct {
+	Text     string
+	Kind     string    // empty searches every kind
+	Symbol   string    // only items tagged with or naming the symbol
+	Before   time.Time // only items published before this, zero for any
+	Exclude  []string  // links to leave out
+	Limit    int
+	MinScore float64
+}
+
+// semanticEntry is one indexed item
+type semanticEntry struct {
+	kind        string
+	key         string
+	title       string
+	text        string
+	link        string
+	symbols     []string
+	publishedAt time.Time
+	vector      []float32 // unit length
+}
+
+// SemanticIndex embeds stored news and stock analyses as they arrive and
+// finds the ones most similar to a query. Vectors are persisted so they are
+// computed once per item and provider.
+type SemanticIndex struct {
+	provider       EmbeddingProvider
+	storageService *database.LocalStorage
+	newsService    *NewsService
+	entries        []*semanticEntry // oldest first
+	keys           map[string]bool  // kind:key of entries and pending items
+	pending        []*semanticEntry // waiting to be embedded
+	mu             sync.Mutex
+	logger         *logrus.Logger
+}
+
+// NewSemanticIndex creates a semantic index and loads the provider's stored
+// vectors
+func NewSemanticIndex(provider EmbeddingProvider, storageService *database.LocalStorage, newsService *NewsService) *SemanticIndex {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	si := &SemanticIndex{
+		provider:       provider,
+		storageService: storageService,
+		newsService:    newsService,
+		keys:           make(map[string]bool),
+		logger:         logger,
+	}
+
+	if err := si.loadFromDB(); err != nil {
+		logger.WithError(err).Error("Failed to load embeddings from database")
+	}
+
+	return si
+}
+
+// Run indexes stored news until ctx is done, embedding new items every
+// interval
+func (si *SemanticIndex) Run(ctx context.Context, interval time.Duration) {
+	id, items := si.newsService.Subscribe(500)
+	defer si.newsService.Unsubscribe(id)
+
+	for _, item := range si.newsService.StoredItems(time.Now().Add(-semanticRetention)) {
+		si.AddNews(item)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	pruneTicker := time.NewTicker(24 * time.Hour)
+	defer pruneTicker.Stop()
+
+	si.logger.WithFields(logrus.Fields{
+		"provider": si.provider.Name(),
+		"interval": interval,
+	}).Info("Semantic index started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item, ok := <-items:
+			if !ok {
+				return
+			}
+			si.AddNews(item)
+		case <-ticker.C:
+			si.flush(ctx)
+		case <-pruneTicker.C:
+			si.prune()
+		}
+	}
+}
+
+// AddNews queues a news item for indexing
+func (si *SemanticIndex) AddNews(item NewsItem) {
+	if item.PublishedAt.IsZero() {
+		item.PublishedAt = time.Now()
+	}
+	description := strings.TrimSpace(whitespacePattern.ReplaceAllString(html.UnescapeString(htmlTagPattern.ReplaceAllString(item.Description, " ")), " "))
+	si.queue(&semanticEntry{
+		kind:        SemanticKindNews,
+		key:         newsKey(&item),
+		title:       item.Title,
+		text:        clipText(strings.TrimSpace(item.Title+". "+description), semanticTextLength),
+		link:        item.Link,
+		symbols:     extractTickers(item.Title + " " + description),
+		publishedAt: item.PublishedAt,
+	})
+}
+
+// AddAnalysis queues a stored stock analysis for indexing
+func (si *SemanticIndex) AddAnalysis(id uint, analysis *StockAnalysis) {
+	setup := analysis.TradeSetup
+	title := fmt.Sprintf("%s analysis: composite %d/10, trend %s", analysis.Symbol, setup.CompositeScore, analysis.Technical.Trend)
+	text := title + ". " + setup.Notes
+	if analysis.NewsSummary != "" {
+		text += ". News: " + analysis.NewsSummary
+	}
+	if len(setup.RecentNews) > 0 {
+		text += ". Headlines: " + strings.Join(setup.RecentNews, "; ")
+	}
+	si.queue(&semanticEntry{
+		kind:        SemanticKindAnalysis,
+		key:         fmt.Sprintf("%d", id),
+		title:       title,
+		text:        clipText(text, semanticTextLength),
+		symbols:     []string{analysis.Symbol},
+		publishedAt: analysis.Timestamp,
+	})
+}
+
+// queue adds an entry the index has not seen
+func (si *SemanticIndex) queue(entry *semanticEntry) {
+	if entry.key == "" || time.Since(entry.publishedAt) > semanticRetention {
+		return
+	}
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	id := entry.kind + ":" + entry.key
+	if si.keys[id] {
+		return
+	}
+	si.keys[id] = true
+	si.pending = append(si.pending, entry)
+}
+
+// flush embeds and stores the queued items. On failure they are retried on
+// the next flush.
+func (si *SemanticIndex) flush(ctx context.Context) {
+	si.mu.Lock()
+	n := min(len(si.pending), semanticFlushBatch)
+	pending := si.pending[:n:n]
+	si.pending = si.pending[n:]
+	si.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	texts := make([]string, len(pending))
+	for i, entry := range pending {
+		texts[i] = entry.text
+	}
+	vectors, err := si.provider.Embed(ctx, texts)
+	if err != nil {
+		si.mu.Lock()
+		si.pending = append(pending, si.pending...)
+		si.mu.Unlock()
+		si.logger.WithError(err).WithField("items", len(pending)).Warn("Failed to embed items")
+		return
+	}
+
+	rows := make([]*models.DBEmbedding, len(pending))
+	for i, entry := range pending {
+		entry.vector = normalizeVector(vectors[i])
+		rows[i] = &models.DBEmbedding{
+			Provider:    si.provider.Name(),
+			Kind:        entry.kind,
+			ItemKey:     entry.key,
+			Symbols:     strings.Join(entry.symbols, ","),
+			Title:       entry.title,
+			Text:        entry.text,
+			Link:        entry.link,
+			PublishedAt: entry.publishedAt,
+			Vector:      encodeVector(entry.vector),
+		}
+	}
+	if err := si.storageService.SaveEmbeddings(rows); err != nil {
+		si.logger.WithError(err).Warn("Failed to store embeddings")
+	}
+
+	si.mu.Lock()
+	si.entries = append(si.entries, pending...)
+	sort.SliceStable(si.entries, func(i, j int) bool { return si.entries[i].publishedAt.Before(si.entries[j].publishedAt) })
+	si.trim()
+	si.mu.Unlock()
+
+	si.logger.WithField("items", len(pending)).Debug("Indexed items")
+}
+
+// Search returns the indexed items most similar to the query text, best
+// first
+func (si *SemanticIndex) Search(ctx context.Context, query SemanticQuery) ([]*SemanticMatch, error) {
+	if strings.TrimSpace(query.Text) == "" {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("query text is required"))
+	}
+	if query.Limit <= 0 || query.Limit > semanticMaxResults {
+		query.Limit = 10
+	}
+
+	vectors, err := si.provider.Embed(ctx, []string{clipText(query.Text, semanticTextLength)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	vector := normalizeVector(vectors[0])
+
+	excluded := make(map[string]bool, len(query.Exclude))
+	for _, link := range query.Exclude {
+		excluded[link] = true
+	}
+
+	si.mu.Lock()
+	matches := make([]*SemanticMatch, 0)
+	for _, entry := range si.entries {
+		if query.Kind != "" && entry.kind != query.Kind {
+			continue
+		}
+		if !query.Before.IsZero() && !entry.publishedAt.Before(query.Before) {
+			continue
+		}
+		if entry.link != "" && excluded[entry.link] {
+			continue
+		}
+		if query.Symbol != "" && !entry.tagged(query.Symbol) {
+			continue
+		}
+		score := dotProduct(vector, entry.vector)
+		if score < query.MinScore {
+			continue
+		}
+		matches = append(matches, &SemanticMatch{
+			Kind:        entry.kind,
+			Title:       entry.title,
+			Text:        entry.text,
+			Link:        entry.link,
+			Symbols:     entry.symbols,
+			PublishedAt: entry.publishedAt,
+			Score:       math.Round(score*1000) / 1000,
+		})
+	}
+	si.mu.Unlock()
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > query.Limit {
+		matches = matches[:query.Limit]
+	}
+	return matches, nil
+}
+
+// Size returns the number of indexed items
+func (si *SemanticIndex) Size() int {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	return len(si.entries)
+}
+
+// Provider names the vector space the index searches
+func (si *SemanticIndex) Provider() string {
+	return si.provider.Name()
+}
+
+// formatRelatedHistory lists matches for a prompt, one per line
+func formatRelatedHistory(matches []*SemanticMatch) string {
+	var b strings.Builder
+	for _, m := range matches {
+		fmt.Fprintf(&b, "- %s %s: %s\n", m.PublishedAt.Format("2006-01-02"), m.Kind, clipText(m.Text, chatContextNotesLength))
+	}
+	return b.String()
+}
+
+// tagged reports whether the entry is about symbol
+func (e *semanticEntry) tagged(symbol string) bool {
+	for _, s := range e.symbols {
+		if s == symbol {
+			return true
+		}
+	}
+	return mentionsSymbol(e.text, symbol)
+}
+
+// prune drops items past the retention period, in memory and in storage
+func (si *SemanticIndex) prune() {
+	cutoff := time.Now().Add(-semanticRetention)
+
+	si.mu.Lock()
+	kept := si.entries[:0]
+	for _, entry := range si.entries {
+		if entry.publishedAt.Before(cutoff) {
+			delete(si.keys, entry.kind+":"+entry.key)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	si.entries = kept
+	si.mu.Unlock()
+
+	if deleted, err := si.storageService.DeleteEmbeddingsBefore(cutoff); err != nil {
+		si.logger.WithError(err).Warn("Failed to prune embeddings")
+	} else if deleted > 0 {
+		si.logger.WithField("deleted", deleted).Info("Pruned old embeddings")
+	}
+}
+
+// trim drops the oldest entries beyond the size limit. The caller holds
+// si.mu.
+func (si *SemanticIndex) trim() {
+	if excess := len(si.entries) - semanticMaxEntries; excess > 0 {
+		for _, entry := range si.entries[:excess] {
+			delete(si.keys, entry.kind+":"+entry.key)
+		}
+		si.entries = append([]*semanticEntry(nil), si.entries[excess:]...)
+	}
+}
+
+// loadFromDB restores the provider's vectors on startup
+func (si *SemanticIndex) loadFromDB() error {
+	rows, err := si.storageService.GetEmbeddings(si.provider.Name(), time.Now().Add(-semanticRetention))
+	if err != nil {
+		return err
+	}
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	for _, row := range rows {
+		entry := &semanticEntry{
+			kind:        row.Kind,
+			key:         row.ItemKey,
+			title:       row.Title,
+			text:        row.Text,
+			link:        row.Link,
+			publishedAt: row.PublishedAt,
+			vector:      decodeVector(row.Vector),
+		}
+		if row.Symbols != "" {
+			entry.symbols = strings.Split(row.Symbols, ",")
+		}
+		si.entries = append(si.entries, entry)
+		si.keys[entry.kind+":"+entry.key] = true
+	}
+	si.trim()
+
+	si.logger.WithFields(logrus.Fields{
+		"provider": si.provider.Name(),
+		"count":    len(si.entries),
+	}).Info("Loaded embeddings from database")
+	return nil
+}
+
+// encodeVector packs a vector as little-endian float32s
+func encodeVector(v []float32) []byte {
+	data := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(x))
+	}
+	return data
+}
+
+// decodeVector unpacks a vector written by encodeVector
+func decodeVector(data []byte) []float32 {
+	v := make([]float32, len(data)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return v
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// Shadow order statuses
+const (
+	ShadowOrderOpen     = "OPEN"
+	ShadowOrderFilled   = "FILLED"
+	ShadowOrderCanceled = "CANCELED"
+)
+
+// shadowOrderPrefix marks the IDs of simulated orders, so they are never
+// looked up at the broker
+const shadowOrderPrefix = "shadow-"
+
+type shadowKey struct{}
+
+// WithShadow marks orders placed with ctx as shadow orders whatever their
+// source. Exits of a managed position opened in shadow carry it.
+func WithShadow(ctx context.Context) context.Context {
+	return context.WithValue(ctx, shadowKey{}, true)
+}
+
+func shadowFrom(ctx context.Context) bool {
+	shadow, _ := ctx.Value(shadowKey{}).(bool)
+	return shadow
+}
+
+// IsShadowOrderID reports whether id belongs to a simulated order
+func IsShadowOrderID(id string) bool {
+	return strings.HasPrefix(id, shadowOrderPrefix)
+}
+
+// ShadowTradingService records and simulates the orders of shadowed sources
+// instead of sending them, while every other order reaches the broker. Fills
+// are simulated against live quotes: market orders fill at the ask or bid
+// when placed and resting orders fill once Match sees the quote cross them.
+type ShadowTradingService struct {
+	interfaces.TradingService
+	data    interfaces.DataService
+	storage *database.LocalStorage
+	auditor *OrderAuditor
+	sources map[string]bool
+	mu      sync.Mutex
+	logger  *logrus.Logger
+}
+
+// NewShadowTradingService wraps trading, shadowing orders from sources
+func NewShadowTradingService(trading interfaces.TradingService, data interfaces.DataService, storage *database.LocalStorage, auditor *OrderAuditor, sources []string) *ShadowTradingService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	shadowed := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		shadowed[strings.ToLower(strings.TrimSpace(source))] = true
+	}
+
+	return &ShadowTradingService{
+		TradingService: trading,
+		data:           data,
+		storage:        storage,
+		auditor:        auditor,
+		sources:        shadowed,
+		logger:         logger,
+	}
+}
+
+// shadows reports whether orders placed with ctx are simulated
+func (s *ShadowTradingService) shadows(ctx context.Context) bool {
+	return shadowFrom(ctx) || s.sources[OrderSourceFrom(ctx)]
+}
+
+// PlaceOrder implements interfaces.TradingService
+func (s *ShadowTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	if !s.shadows(ctx) {
+		return s.TradingService.PlaceOrder(ctx, order)
+	}
+
+	shadow := &models.DBShadowOrder{
+		Symbol:     strings.ToUpper(order.Symbol),
+		AssetClass: "us_equity",
+		Side:       order.Side,
+		Type:       order.Type,
+		Qty:        order.Qty,
+		LimitPrice: order.LimitPrice,
+		StopPrice:  order.StopPrice,
+		Strategy:   order.Strategy,
+	}
+	return s.place(ctx, shadow, BuildOrderRequest(order))
+}
+
+// PlaceOptionsOrder implements interfaces.TradingService
+func (s *ShadowTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	if !s.shadows(ctx) {
+		return s.TradingService.PlaceOptionsOrder(ctx, order)
+	}
+
+	shadow := &models.DBShadowOrder{
+		Symbol:     strings.ToUpper(order.Symbol),
+		AssetClass: "us_option",
+		Side:       order.Side,
+		Type:       order.Type,
+		Qty:        order.Qty,
+		LimitPrice: order.LimitPrice,
+	}
+	return s.place(ctx, shadow, order)
+}
+
+// place records a shadow order and fills it right away when the current
+// quote allows
+func (s *ShadowTradingService) place(ctx context.Context, shadow *models.DBShadowOrder, request interface{}) (*interfaces.OrderResult, error) {
+	start := time.Now()
+	shadow.ShadowID = fmt.Sprintf("%s%d", shadowOrderPrefix, time.Now().UnixNano())
+	shadow.Source = OrderSourceFrom(ctx)
+	shadow.Book = BookFrom(ctx)
+	shadow.Status = ShadowOrderOpen
+
+	bid, ask, err := s.quote(ctx, shadow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote shadow order: %w", err)
+	}
+	if price := shadowQuotePrice(shadow.Side, bid, ask); price > 0 {
+		shadow.QuotePrice = decimal.NewFromFloat(price)
+	}
+	shadowFill(shadow, bid, ask, time.Now())
+
+	s.mu.Lock()
+	err = s.storage.SaveShadowOrder(shadow)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &interfaces.OrderResult{
+		OrderID: shadow.ShadowID,
+		Status:  shadowOrderStatus(shadow.Status),
+		Message: "Shadow order: simulated, not sent to the broker",
+	}
+	s.auditor.Record(ctx, OrderAttempt{
+		OrderID:    shadow.ShadowID,
+		Symbol:     shadow.Symbol,
+		Side:       shadow.Side,
+		AssetClass: shadow.AssetClass,
+		Outcome:    AuditOutcomeShadow,
+		Request:    request,
+		Response:   result,
+		Risk:       riskDecisionFrom(ctx),
+		Latency:    time.Since(start),
+	})
+
+	s.logger.WithFields(logrus.Fields{
+		"id":     shadow.ShadowID,
+		"symbol": shadow.Symbol,
+		"side":   shadow.Side,
+		"qty":    shadow.Qty.String(),
+		"source": shadow.Source,
+		"status": shadow.Status,
+	}).Info("Shadow order recorded")
+
+	return result, nil
+}
+
+// GetOrder implements interfaces.TradingService, answering for shadow orders
+func (s *ShadowTradingService) GetOrder(ctx context.Context, orderID string) (*interfaces.Order, error) {
+	if !IsShadowOrderID(orderID) {
+		return s.TradingService.GetOrder(ctx, orderID)
+	}
+	shadow, err := s.storage.GetShadowOrder(orderID)
+	if err != nil {
+		return nil, WithErrorCode(ErrCodeNotFound, err)
+	}
+	return shadowToOrder(shadow), nil
+}
+
+// CancelOrder implements interfaces.TradingService, canceling shadow orders
+// locally
+func (s *ShadowTradingService) CancelOrder(ctx context.Context, orderID string) error {
+	if !IsShadowOrderID(orderID) {
+		return s.TradingService.CancelOrder(ctx, orderID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shadow, err := s.storage.GetShadowOrder(orderID)
+	if err != nil {
+		return WithErrorCode(ErrCodeNotFound, err)
+	}
+	if shadow.Status != ShadowOrderOpen {
+		return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("shadow order %s is already %s", orderID, strings.ToLower(shadow.Status)))
+	}
+	shadow.Status = ShadowOrderCanceled
+	return s.storage.SaveShadowOrder(shadow)
+}
+
+// Match fills the open shadow orders the current quotes cross. It returns
+// how many filled.
+func (s *ShadowTradingService) Match(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	open, err := s.storage.GetShadowOrders(ShadowOrderOpen, time.Time{}, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	filled := 0
+	for _, shadow := range open {
+		bid, ask, err := s.quote(ctx, shadow)
+		if err != nil {
+			s.logger.WithError(err).WithField("id", shadow.ShadowID).Warn("Failed to quote shadow order")
+			continue
+		}
+		if !shadowFill(shadow, bid, ask, time.Now()) {
+			continue
+		}
+		if err := s.storage.SaveShadowOrder(shadow); err != nil {
+			return filled, err
+		}
+		filled++
+	}
+	return filled, nil
+}
+
+// quote returns the current bid and ask of the order's symbol
+func (s *ShadowTradingService) quote(ctx context.Context, shadow *models.DBShadowOrder) (float64, float64, error) {
+	if shadow.AssetClass == "us_option" {
+		quote, err := s.TradingService.GetOptionsQuote(ctx, shadow.Symbol)
+		if err != nil {
+			return 0, 0, err
+		}
+		return quote.BidPrice, quote.AskPrice, nil
+	}
+	quote, err := s.data.GetLatestQuote(ctx, shadow.Symbol)
+	if err != nil {
+		return 0, 0, err
+	}
+	return quote.BidPrice, quote.AskPrice, nil
+}
+
+// shadowQuotePrice is the price side trades at: the ask for buys and the
+// bid for sells, falling back to the other side of a one-sided quote
+func shadowQuotePrice(side string, bid, ask float64) float64 {
+	if side == "buy" {
+		if ask > 0 {
+			return ask
+		}
+		return bid
+	}
+	if bid > 0 {
+		return bid
+	}
+	return ask
+}
+
+// shadowFill fills an open order if the quote reaches its limit or stop
+// and reports whether it did
+func shadowFill(shadow *models.DBShadowOrder, bid, ask float64, now time.Time) bool {
+	// Trailing stops have no fixed trigger to simulate
+	if shadow.Status != ShadowOrderOpen || shadow.Type == "trailing_stop" {
+		return false
+	}
+	price := shadowQuotePrice(shadow.Side, bid, ask)
+	if price <= 0 {
+		return false
+	}
+	buy := shadow.Side == "buy"
+
+	if shadow.StopPrice != nil {
+		stop := shadow.StopPrice.InexactFloat64()
+		if (buy && price < stop) || (!buy && price > stop) {
+			return false
+		}
+	}
+	if shadow.LimitPrice != nil {
+		limit := shadow.LimitPrice.InexactFloat64()
+		if (buy && price > limit) || (!buy && price < limit) {
+			return false
+		}
+	}
+	filled := decimal.NewFromFloat(price)
+	shadow.FilledPrice = &filled
+	shadow.FilledAt = &now
+	shadow.Status = ShadowOrderFilled
+	return true
+}
+
+// shadowOrderStatus maps a shadow status to the broker's order statuses
+func shadowOrderStatus(status string) string {
+	switch status {
+	case ShadowOrderFilled:
+		return "filled"
+	case ShadowOrderCanceled:
+		return "canceled"
+	}
+	return "new"
+}
+
+func shadowToOrder(shadow *models.DBShadowOrder) *interfaces.Order {
+	order := &interfaces.Order{
+		ID:             shadow.ShadowID,
+		Symbol:         shadow.Symbol,
+		Qty:            shadow.Qty,
+		Side:           shadow.Side,
+		Type:           shadow.Type,
+		LimitPrice:     shadow.LimitPrice,
+		StopPrice:      shadow.StopPrice,
+		Status:         shadowOrderStatus(shadow.Status),
+		FilledAvgPrice: shadow.FilledPrice,
+		SubmittedAt:    shadow.CreatedAt,
+		FilledAt:       shadow.FilledAt,
+		Strategy:       shadow.Strategy,
+		Book:           bookOrDefault(shadow.Book),
+	}
+	if shadow.Status == ShadowOrderFilled {
+		order.FilledQty = shadow.Qty
+	}
+	if shadow.Status == ShadowOrderCanceled {
+		updated := shadow.UpdatedAt
+		order.CanceledAt = &updated
+	}
+	return order
+}
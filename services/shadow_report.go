@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Outcomes of comparing shadow orders with actual fills
+const (
+	ShadowMatched    = "matched"     // the bot and the account traded the same way
+	ShadowBotOnly    = "bot_only"    // the bot would have traded, nobody did
+	ShadowManualOnly = "manual_only" // the account traded without the bot
+)
+
+// ShadowDecision pairs a shadow order with the actual fill on the same
+// symbol and side, when there was one
+type ShadowDecision struct {
+	Symbol        string     `json:"symbol"`
+	Side          string     `json:"side"`
+	Outcome       string     `json:"outcome"`
+	ShadowOrderID string     `json:"shadow_order_id,omitempty"`
+	ShadowSource  string     `json:"shadow_source,omitempty"`
+	ShadowQty     float64    `json:"shadow_qty,omitempty"`
+	ShadowPrice   float64    `json:"shadow_price,omitempty"`
+	ShadowTime    *time.Time `json:"shadow_time,omitempty"`
+	ActualOrderID string     `json:"actual_order_id,omitempty"`
+	ActualQty     float64    `json:"actual_qty,omitempty"`
+	ActualPrice   float64    `json:"actual_price,omitempty"`
+	ActualTime    *time.Time `json:"actual_time,omitempty"`
+	// PriceDifference is what the actual fill paid per share over the shadow
+	// fill; negative means the account got the better price
+	PriceDifference float64 `json:"price_difference,omitempty"`
+}
+
+// ShadowSymbolComparison compares the bot's and the account's results on
+// one symbol, both starting flat at the start of the window
+type ShadowSymbolComparison struct {
+	Symbol       string  `json:"symbol"`
+	CurrentPrice float64 `json:"current_price"`
+	ShadowOrders int     `json:"shadow_orders"`
+	ShadowNetQty float64 `json:"shadow_net_qty"`
+	ShadowPnL    float64 `json:"shadow_pnl"` // realized plus unrealized at the current price
+	ActualOrders int     `json:"actual_orders"`
+	ActualNetQty float64 `json:"actual_net_qty"`
+	ActualPnL    float64 `json:"actual_pnl"`
+}
+
+// ShadowReport compares what the shadowed sources would have done with
+// what the account actually did
+type ShadowReport struct {
+	Start              time.Time                 `json:"start"`
+	End                time.Time                 `json:"end"`
+	Book               string                    `json:"book,omitempty"`
+	MatchWindowMinutes int                       `json:"match_window_minutes"`
+	ShadowOrders       int                       `json:"shadow_orders"` // filled
+	OpenShadowOrders   int                       `json:"open_shadow_orders"`
+	ActualOrders       int                       `json:"actual_orders"` // filled
+	Matched            int                       `json:"matched"`
+	BotOnly            int                       `json:"bot_only"`
+	ManualOnly         int                       `json:"manual_only"`
+	AgreementRate      float64                   `json:"agreement_rate"` // percent of decisions both made
+	ShadowPnL          float64                   `json:"shadow_pnl"`
+	ActualPnL          float64                   `json:"actual_pnl"`
+	Symbols            []*ShadowSymbolComparison `json:"symbols"`
+	Decisions          []*ShadowDecision         `json:"decisions"`
+}
+
+// comparedFill is one filled order on either side of the comparison
+type comparedFill struct {
+	id     string
+	source string
+	symbol string
+	side   string
+	qty    float64
+	price  float64
+	at     time.Time
+	paired bool
+}
+
+// ShadowComparison compares the shadow orders filled in the window with the
+// account's actual fills. A shadow fill is matched with the nearest unpaired
+// actual fill on the same symbol and side within matchWindow.
+func (rs *ReportingService) ShadowComparison(ctx context.Context, start, end time.Time, matchWindow time.Duration) (*ShadowReport, error) {
+	book, hasBook := SelectedBook(ctx)
+	report := &ShadowReport{
+		Start:              start,
+		End:                end,
+		Book:               book,
+		MatchWindowMinutes: int(matchWindow.Minutes()),
+		Symbols:            make([]*ShadowSymbolComparison, 0),
+		Decisions:          make([]*ShadowDecision, 0),
+	}
+
+	shadowOrders, err := rs.storageService.GetShadowOrders("", start, end)
+	if err != nil {
+		return nil, err
+	}
+	var shadows []*comparedFill
+	for _, order := range shadowOrders {
+		if hasBook && bookOrDefault(order.Book) != book {
+			continue
+		}
+		if order.Status == ShadowOrderOpen {
+			report.OpenShadowOrders++
+		}
+		if order.Status != ShadowOrderFilled || order.FilledPrice == nil || order.FilledAt == nil {
+			continue
+		}
+		shadows = append(shadows, &comparedFill{
+			id:     order.ShadowID,
+			source: order.Source,
+			symbol: order.Symbol,
+			side:   order.Side,
+			qty:    order.Qty.InexactFloat64(),
+			price:  order.FilledPrice.InexactFloat64(),
+			at:     *order.FilledAt,
+		})
+	}
+
+	// Actual fills come from the broker; the stored orders give their books
+	brokerOrders, err := rs.tradingService.ListOrders(ctx, "closed")
+	if err != nil {
+		return nil, err
+	}
+	var books map[string]string
+	if hasBook {
+		stored, err := rs.storageService.GetOrders("")
+		if err != nil {
+			return nil, err
+		}
+		books = make(map[string]string, len(stored))
+		for _, order := range stored {
+			books[order.ID] = bookOrDefault(order.Book)
+		}
+	}
+	var actuals []*comparedFill
+	for _, order := range brokerOrders {
+		if order.FilledAt == nil || order.FilledAvgPrice == nil || !order.FilledQty.IsPositive() {
+			continue
+		}
+		if order.FilledAt.Before(start) || order.FilledAt.After(end) {
+			continue
+		}
+		if hasBook && bookOrDefault(books[order.ID]) != book {
+			continue
+		}
+		actuals = append(actuals, &comparedFill{
+			id:     order.ID,
+			symbol: strings.ToUpper(order.Symbol),
+			side:   order.Side,
+			qty:    order.FilledQty.InexactFloat64(),
+			price:  order.FilledAvgPrice.InexactFloat64(),
+			at:     *order.FilledAt,
+		})
+	}
+	sort.Slice(shadows, func(i, j int) bool { return shadows[i].at.Before(shadows[j].at) })
+	sort.Slice(actuals, func(i, j int) bool { return actuals[i].at.Before(actuals[j].at) })
+	report.ShadowOrders = len(shadows)
+	report.ActualOrders = len(actuals)
+
+	// Pair every shadow fill with the closest actual fill
+	for _, shadow := range shadows {
+		var best *comparedFill
+		for _, actual := range actuals {
+			if actual.paired || actual.symbol != shadow.symbol || actual.side != shadow.side {
+				continue
+			}
+			gap := actual.at.Sub(shadow.at)
+			if gap < -matchWindow || gap > matchWindow {
+				continue
+			}
+			if best == nil || math.Abs(float64(gap)) < math.Abs(float64(best.at.Sub(shadow.at))) {
+				best = actual
+			}
+		}
+
+		shadowTime := shadow.at
+		decision := &ShadowDecision{
+			Symbol:        shadow.symbol,
+			Side:          shadow.side,
+			Outcome:       ShadowBotOnly,
+			ShadowOrderID: shadow.id,
+			ShadowSource:  shadow.source,
+			ShadowQty:     shadow.qty,
+			ShadowPrice:   shadow.price,
+			ShadowTime:    &shadowTime,
+		}
+		if best != nil {
+			best.paired = true
+			actualTime := best.at
+			decision.Outcome = ShadowMatched
+			decision.ActualOrderID = best.id
+			decision.ActualQty = best.qty
+			decision.ActualPrice = best.price
+			decision.ActualTime = &actualTime
+			decision.PriceDifference = best.price - shadow.price
+			if shadow.side == "sell" {
+				decision.PriceDifference = -decision.PriceDifference
+			}
+			report.Matched++
+		} else {
+			report.BotOnly++
+		}
+		report.Decisions = append(report.Decisions, decision)
+	}
+	for _, actual := range actuals {
+		if actual.paired {
+			continue
+		}
+		actualTime := actual.at
+		report.Decisions = append(report.Decisions, &ShadowDecision{
+			Symbol:        actual.symbol,
+			Side:          actual.side,
+			Outcome:       ShadowManualOnly,
+			ActualOrderID: actual.id,
+			ActualQty:     actual.qty,
+			ActualPrice:   actual.price,
+			ActualTime:    &actualTime,
+		})
+		report.ManualOnly++
+	}
+	if decisions := report.Matched + report.BotOnly + report.ManualOnly; decisions > 0 {
+		report.AgreementRate = float64(report.Matched) / float64(decisions) * 100
+	}
+
+	// Results per symbol, marked to the current price
+	bySymbol := make(map[string]*ShadowSymbolComparison)
+	shadowFills := make(map[string][]*comparedFill)
+	actualFills := make(map[string][]*comparedFill)
+	for _, fill := range shadows {
+		shadowFills[fill.symbol] = append(shadowFills[fill.symbol], fill)
+		bySymbol[fill.symbol] = &ShadowSymbolComparison{Symbol: fill.symbol}
+	}
+	for _, fill := range actuals {
+		actualFills[fill.symbol] = append(actualFills[fill.symbol], fill)
+		bySymbol[fill.symbol] = &ShadowSymbolComparison{Symbol: fill.symbol}
+	}
+	for symbol, comparison := range bySymbol {
+		comparison.CurrentPrice = rs.currentPrice(ctx, symbol)
+		multiplier := contractMultiplier(symbol)
+		comparison.ShadowOrders = len(shadowFills[symbol])
+		comparison.ShadowNetQty, comparison.ShadowPnL = fillsPnL(shadowFills[symbol], comparison.CurrentPrice, multiplier)
+		comparison.ActualOrders = len(actualFills[symbol])
+		comparison.ActualNetQty, comparison.ActualPnL = fillsPnL(actualFills[symbol], comparison.CurrentPrice, multiplier)
+		report.ShadowPnL += comparison.ShadowPnL
+		report.ActualPnL += comparison.ActualPnL
+		report.Symbols = append(report.Symbols, comparison)
+	}
+	sort.Slice(report.Symbols, func(i, j int) bool { return report.Symbols[i].Symbol < report.Symbols[j].Symbol })
+	sort.SliceStable(report.Decisions, func(i, j int) bool {
+		return decisionTime(report.Decisions[i]).Before(decisionTime(report.Decisions[j]))
+	})
+
+	return report, nil
+}
+
+// currentPrice returns the midpoint of the latest quote, or zero when there
+// is none
+func (rs *ReportingService) currentPrice(ctx context.Context, symbol string) float64 {
+	var bid, ask float64
+	if IsOCCSymbol(symbol) {
+		quote, err := rs.tradingService.GetOptionsQuote(ctx, symbol)
+		if err != nil {
+			return 0
+		}
+		if quote.BidPrice <= 0 || quote.AskPrice <= 0 {
+			return quote.LastPrice
+		}
+		bid, ask = quote.BidPrice, quote.AskPrice
+	} else {
+		quote, err := rs.dataService.GetLatestQuote(ctx, symbol)
+		if err != nil {
+			return 0
+		}
+		bid, ask = quote.BidPrice, quote.AskPrice
+	}
+	if bid <= 0 || ask <= 0 {
+		return math.Max(bid, ask)
+	}
+	return (bid + ask) / 2
+}
+
+// fillsPnL replays fills in order at average cost and returns the net
+// quantity and the realized plus unrealized P&L at current. Unrealized P&L
+// is left out when there is no current price.
+func fillsPnL(fills []*comparedFill, current, multiplier float64) (float64, float64) {
+	var qty, avg, realized float64
+	for _, fill := range fills {
+		signed := fill.qty
+		if fill.side == "sell" {
+			signed = -signed
+		}
+		if qty == 0 || (qty > 0) == (signed > 0) {
+			avg = (avg*math.Abs(qty) + fill.price*fill.qty) / (math.Abs(qty) + fill.qty)
+			qty += signed
+			continue
+		}
+		closing := math.Min(math.Abs(signed), math.Abs(qty))
+		if qty > 0 {
+			realized += closing * (fill.price - avg)
+		} else {
+			realized += closing * (avg - fill.price)
+		}
+		qty += signed
+		if math.Abs(qty) < 1e-9 {
+			qty, avg = 0, 0
+		} else if (qty > 0) == (signed > 0) {
+			// The fill flipped the position; the rest opened at its price
+			avg = fill.price
+		}
+	}
+
+	pnl := realized
+	if current > 0 {
+		pnl += qty * (current - avg)
+	}
+	return qty, pnl * multiplier
+}
+
+func decisionTime(d *ShadowDecision) time.Time {
+	if d.ShadowTime != nil {
+		return *d.ShadowTime
+	}
+	return *d.ActualTime
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// marketLocation is the exchange time zone option expirations are in
+var marketLocation = func() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}()
+
+// daysToExpiry counts the calendar days from now to an option's expiration,
+// in exchange time: 0 on the expiration date itself
+func daysToExpiry(expiration, now time.Time) int {
+	now = now.In(marketLocation)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	expiry := time.Date(expiration.Year(), expiration.Month(), expiration.Day(), 0, 0, 0, 0, time.UTC)
+	return int(expiry.Sub(today).Hours() / 24)
+}
+
+// ShortDTERule guards entries into short-dated options, which can lose
+// their whole value within hours. Entries expiring within MaxDays are held
+// to tighter per-trade caps, and same-day expiries are blocked, or warned
+// on, after the cutoff. Orders that close or reduce a position are exempt.
+type ShortDTERule struct {
+	MaxDays          int             // options expiring within this many days are short-dated
+	MaxOrderValue    decimal.Decimal // per-trade notional limit, zero disables
+	MaxRiskPct       float64         // per-trade notional limit as a percent of portfolio value, 0 disables
+	EntryCutoff      time.Duration   // time after midnight ET after which same-day entries stop, 0 disables
+	BlockAfterCutoff bool
+	Hours            *MarketHours // moves the cutoff earlier on half-days, nil assumes a 16:00 close
+}
+
+func (ShortDTERule) Name() string { return "short_dte" }
+
+func (r ShortDTERule) Check(ctx context.Context, check *RiskCheck) error {
+	if check.AssetClass != "us_option" || reducesPosition(check) {
+		return nil
+	}
+	occ, err := ParseOCCSymbol(check.Order.Symbol)
+	if err != nil {
+		return nil
+	}
+	now := time.Now().In(marketLocation)
+	dte := daysToExpiry(occ.Expiration, now)
+	if dte > r.MaxDays {
+		return nil
+	}
+
+	if r.MaxOrderValue.IsPositive() && check.Notional.GreaterThan(r.MaxOrderValue) {
+		return fmt.Errorf("order notional %s exceeds the %d DTE limit %s",
+			check.Notional.StringFixed(2), dte, r.MaxOrderValue.StringFixed(2))
+	}
+	if r.MaxRiskPct > 0 && check.Account != nil && check.Account.PortfolioValue.IsPositive() {
+		limit := check.Account.PortfolioValue.Mul(decimal.NewFromFloat(r.MaxRiskPct / 100))
+		if check.Notional.GreaterThan(limit) {
+			return fmt.Errorf("order notional %s exceeds %g%% of portfolio value (%s) for a %d DTE option",
+				check.Notional.StringFixed(2), r.MaxRiskPct, limit.StringFixed(2), dte)
+		}
+	}
+
+	if dte == 0 && r.EntryCutoff > 0 {
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, marketLocation)
+		cutoff := midnight.Add(r.EntryCutoff)
+		if r.Hours != nil {
+			if day, ok := r.Hours.Day(ctx, now); ok {
+				cutoff = cutoff.Add(-day.ClosesEarlyBy())
+			}
+		}
+		if !now.Before(cutoff) {
+			message := fmt.Sprintf("%s expires today and the 0DTE entry cutoff %s ET has passed", check.Order.Symbol, cutoff.Format("15:04"))
+			if r.BlockAfterCutoff {
+				return errors.New(message)
+			}
+			return &RiskWarning{Message: message}
+		}
+	}
+	return nil
+}
+
+// expiresBeforeExit reports whether a position is in an option expiring
+// today and the expiry exit window before the close has begun. With market
+// hours the window ends at the day's actual close, which is earlier on
+// half-days.
+func (pm *PositionManager) expiresBeforeExit(ctx context.Context, position *ManagedPosition, now time.Time) bool {
+	if pm.expiryExit <= 0 {
+		return false
+	}
+	occ, err := ParseOCCSymbol(position.Symbol)
+	if err != nil || daysToExpiry(occ.Expiration, now) != 0 {
+		return false
+	}
+	day, ok := weekdayMarketDay(now)
+	if pm.hours != nil {
+		day, ok = pm.hours.Day(ctx, now)
+	}
+	return ok && !now.Before(day.Close.Add(-pm.expiryExit))
+}
+
+// closeBeforeExpiry cancels a same-day expiry's orders and closes it at
+// market
+func (pm *PositionManager) closeBeforeExpiry(ctx context.Context, position *ManagedPosition) {
+	if position.Notes != "" {
+		position.Notes += "; "
+	}
+	position.Notes += fmt.Sprintf("closed %s before expiry", pm.expiryExit)
+
+	if err := pm.CloseManagedPosition(ctx, position.ID); err != nil {
+		pm.logger.WithError(err).WithField("position_id", position.ID).Error("Failed to close position before expiry")
+		return
+	}
+	pm.logger.WithFields(logrus.Fields{
+		"position_id": position.ID,
+		"symbol":      position.Symbol,
+	}).Warn("Closed same-day expiry before the close")
+}
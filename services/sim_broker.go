@@ -0,0 +1,450 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// SimulatedBroker is an in-memory, long-only cash account that fills orders
+// against a DataService. Market orders fill at the price the fill model
+// gives when the market is open; limit and stop orders rest until Match sees
+// the price cross them. Orders capped by bar volume fill over several bars.
+// Sells are limited to the shares held.
+type SimulatedBroker struct {
+	dataService interfaces.DataService
+	now         func() time.Time
+	location    *time.Location
+	fills       FillModel
+	cash        decimal.Decimal
+	positions   map[string]*simPosition
+	orders      map[string]*interfaces.Order
+	commissions map[string]decimal.Decimal // order ID -> commission paid
+	volume      map[string]simVolume       // symbol -> shares filled in its latest bar
+	sequence    []string                   // order IDs in submission order
+	nextID      int
+	mu          sync.Mutex
+	logger      *logrus.Logger
+}
+
+type simPosition struct {
+	qty  decimal.Decimal
+	cost decimal.Decimal // total cost basis
+}
+
+type simVolume struct {
+	bar time.Time
+	qty decimal.Decimal
+}
+
+// NewSimulatedBroker creates a simulated account holding cash. now supplies
+// the broker's notion of the current time, so a replay clock can drive it.
+func NewSimulatedBroker(dataService interfaces.DataService, cash decimal.Decimal, now func() time.Time) *SimulatedBroker {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.UTC
+	}
+
+	return &SimulatedBroker{
+		dataService: dataService,
+		now:         now,
+		location:    loc,
+		cash:        cash,
+		positions:   make(map[string]*simPosition),
+		orders:      make(map[string]*interfaces.Order),
+		commissions: make(map[string]decimal.Decimal),
+		volume:      make(map[string]simVolume),
+		logger:      logger,
+	}
+}
+
+// SetFillModel sets how orders fill from now on
+func (b *SimulatedBroker) SetFillModel(model FillModel) error {
+	if err := model.Validate(); err != nil {
+		return WithErrorCode(ErrCodeInvalidRequest, err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fills = model
+	return nil
+}
+
+// Commission returns the commission paid on an order so far
+func (b *SimulatedBroker) Commission(orderID string) decimal.Decimal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.commissions[orderID]
+}
+
+// TotalCommissions returns the commission paid on all orders
+func (b *SimulatedBroker) TotalCommissions() decimal.Decimal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	total := decimal.Zero
+	for _, fee := range b.commissions {
+		total = total.Add(fee)
+	}
+	return total
+}
+
+// IsMarketOpen implements MarketClock with regular session hours
+func (b *SimulatedBroker) IsMarketOpen(ctx context.Context) (bool, error) {
+	return inRegularSession(b.now(), b.location), nil
+}
+
+// PlaceOrder accepts an order and fills it at once if it is marketable
+func (b *SimulatedBroker) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	if !order.Qty.IsPositive() {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("quantity must be positive"))
+	}
+	if order.Side != "buy" && order.Side != "sell" {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("invalid side: %s", order.Side))
+	}
+	switch order.Type {
+	case "market":
+	case "limit":
+		if order.LimitPrice == nil {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("limit orders need a limit price"))
+		}
+	case "stop":
+		if order.StopPrice == nil {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("stop orders need a stop price"))
+		}
+	default:
+		return nil, WithErrorCode(ErrCodeNotSupported, fmt.Errorf("the simulated broker does not support %s orders", order.Type))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	sim := *order
+	sim.ID = fmt.Sprintf("sim_%d", b.nextID)
+	sim.Symbol = strings.ToUpper(order.Symbol)
+	sim.Status = "new"
+	sim.SubmittedAt = b.now()
+	b.orders[sim.ID] = &sim
+	b.sequence = append(b.sequence, sim.ID)
+
+	b.match(ctx, &sim)
+	return &interfaces.OrderResult{OrderID: sim.ID, Status: sim.Status}, nil
+}
+
+// Match fills every resting order the latest prices have reached
+func (b *SimulatedBroker) Match(ctx context.Context) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, id := range b.sequence {
+		if order := b.orders[id]; simOrderOpen(order) {
+			b.match(ctx, order)
+		}
+	}
+}
+
+// match fills one open order if the market is open and its price condition
+// holds at the fill model's price. The caller holds mu.
+func (b *SimulatedBroker) match(ctx context.Context, order *interfaces.Order) {
+	if !inRegularSession(b.now(), b.location) {
+		return
+	}
+	trade, err := b.dataService.GetLatestTrade(ctx, order.Symbol)
+	if err != nil || trade.Price <= 0 {
+		return
+	}
+	var quote *interfaces.Quote
+	if b.fills.Price == FillAtMidpoint || b.fills.Price == FillCrossSpread {
+		quote, _ = b.dataService.GetLatestQuote(ctx, order.Symbol)
+	}
+	quoted := b.fills.QuotedPrice(order.Side, trade.Price, quote)
+	price := decimal.NewFromFloat(b.fills.FillPrice(order.Side, trade.Price, quote))
+
+	switch order.Type {
+	case "limit":
+		limit := *order.LimitPrice
+		if (order.Side == "buy" && quoted > limit.InexactFloat64()) || (order.Side == "sell" && quoted < limit.InexactFloat64()) {
+			return
+		}
+		// A limit that was marketable on arrival fills at the market price,
+		// otherwise at its limit; slippage never goes through the limit
+		if order.Side == "buy" {
+			price = decimal.Min(price, limit)
+		} else {
+			price = decimal.Max(price, limit)
+		}
+	case "stop":
+		last := decimal.NewFromFloat(trade.Price)
+		if (order.Side == "buy" && last.LessThan(*order.StopPrice)) || (order.Side == "sell" && last.GreaterThan(*order.StopPrice)) {
+			return
+		}
+	}
+
+	qty := order.Qty.Sub(order.FilledQty)
+	if limit, ok := b.volumeAvailable(ctx, order.Symbol); ok {
+		qty = decimal.Min(qty, limit)
+		if !qty.IsPositive() {
+			return
+		}
+	}
+
+	multiplier := decimal.NewFromFloat(contractMultiplier(order.Symbol))
+	filledBefore := order.FilledQty.InexactFloat64()
+	fee := decimal.NewFromFloat(b.fills.Fees(order.Symbol, filledBefore+qty.InexactFloat64()) - b.fills.Fees(order.Symbol, filledBefore))
+
+	position := b.positions[order.Symbol]
+	if order.Side == "buy" {
+		cost := price.Mul(qty).Mul(multiplier)
+		if cost.Add(fee).GreaterThan(b.cash) {
+			b.reject(order, "insufficient buying power")
+			return
+		}
+		if position == nil {
+			position = &simPosition{}
+			b.positions[order.Symbol] = position
+		}
+		b.cash = b.cash.Sub(cost).Sub(fee)
+		position.qty = position.qty.Add(qty)
+		position.cost = position.cost.Add(cost)
+	} else {
+		if position == nil || position.qty.LessThan(qty) {
+			b.reject(order, "insufficient qty available for order")
+			return
+		}
+		// Relieve cost basis at the average cost
+		relieved := position.cost.Mul(qty).Div(position.qty)
+		b.cash = b.cash.Add(price.Mul(qty).Mul(multiplier)).Sub(fee)
+		position.qty = position.qty.Sub(qty)
+		position.cost = position.cost.Sub(relieved)
+		if !position.qty.IsPositive() {
+			delete(b.positions, order.Symbol)
+		}
+	}
+	b.useVolume(order.Symbol, qty)
+	b.commissions[order.ID] = b.commissions[order.ID].Add(fee)
+
+	filledAt := b.now()
+	avg := price
+	if order.FilledAvgPrice != nil {
+		avg = order.FilledAvgPrice.Mul(order.FilledQty).Add(price.Mul(qty)).Div(order.FilledQty.Add(qty))
+	}
+	order.FilledQty = order.FilledQty.Add(qty)
+	order.FilledAvgPrice = &avg
+	order.FilledAt = &filledAt
+	order.Status = "filled"
+	if order.FilledQty.LessThan(order.Qty) {
+		order.Status = "partially_filled"
+	}
+
+	b.logger.WithFields(logrus.Fields{
+		"order_id":   order.ID,
+		"symbol":     order.Symbol,
+		"side":       order.Side,
+		"qty":        qty.String(),
+		"price":      price.String(),
+		"commission": fee.StringFixed(2),
+		"status":     order.Status,
+		"bar_time":   filledAt.In(b.location).Format("15:04"),
+	}).Info("Simulated fill")
+}
+
+// volumeAvailable returns the shares left for symbol in the latest bar
+// under the fill model's volume cap, and false when there is no cap. The
+// caller holds mu.
+func (b *SimulatedBroker) volumeAvailable(ctx context.Context, symbol string) (decimal.Decimal, bool) {
+	if _, capped := b.fills.VolumeCap(0); !capped {
+		return decimal.Zero, false
+	}
+	bar, err := b.dataService.GetLatestBar(ctx, symbol)
+	if err != nil {
+		return decimal.Zero, true
+	}
+	limit, _ := b.fills.VolumeCap(bar.Volume)
+	used := b.volume[symbol]
+	if !used.bar.Equal(bar.Timestamp) {
+		used = simVolume{bar: bar.Timestamp}
+		b.volume[symbol] = used
+	}
+	return decimal.NewFromFloat(limit).Sub(used.qty), true
+}
+
+// useVolume counts a fill against the latest bar's volume. The caller
+// holds mu.
+func (b *SimulatedBroker) useVolume(symbol string, qty decimal.Decimal) {
+	if used, ok := b.volume[symbol]; ok {
+		used.qty = used.qty.Add(qty)
+		b.volume[symbol] = used
+	}
+}
+
+func (b *SimulatedBroker) reject(order *interfaces.Order, reason string) {
+	order.Status = "rejected"
+	b.logger.WithFields(logrus.Fields{
+		"order_id": order.ID,
+		"symbol":   order.Symbol,
+		"side":     order.Side,
+		"qty":      order.Qty.String(),
+	}).Warnf("Simulated order rejected: %s", reason)
+}
+
+// simOrderOpen reports whether an order can still fill
+func simOrderOpen(order *interfaces.Order) bool {
+	return order.Status == "new" || order.Status == "partially_filled"
+}
+
+// CancelOrder cancels an order that has not filled, keeping any partial fill
+func (b *SimulatedBroker) CancelOrder(ctx context.Context, orderID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	order, ok := b.orders[orderID]
+	if !ok {
+		return WithErrorCode(ErrCodeNotFound, fmt.Errorf("order not found: %s", orderID))
+	}
+	if !simOrderOpen(order) {
+		return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("order %s is already %s", orderID, order.Status))
+	}
+	now := b.now()
+	order.Status = "canceled"
+	order.CanceledAt = &now
+	return nil
+}
+
+// GetOrder returns a copy of an order
+func (b *SimulatedBroker) GetOrder(ctx context.Context, orderID string) (*interfaces.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	order, ok := b.orders[orderID]
+	if !ok {
+		return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("order not found: %s", orderID))
+	}
+	copied := *order
+	return &copied, nil
+}
+
+// ListOrders returns orders by status: "open", "closed" or "all"
+func (b *SimulatedBroker) ListOrders(ctx context.Context, status string) ([]*interfaces.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	orders := make([]*interfaces.Order, 0, len(b.sequence))
+	for _, id := range b.sequence {
+		order := b.orders[id]
+		open := simOrderOpen(order)
+		if (status == "open" && !open) || (status == "closed" && open) {
+			continue
+		}
+		copied := *order
+		orders = append(orders, &copied)
+	}
+	return orders, nil
+}
+
+// GetPositions returns the open positions marked at the latest trade
+func (b *SimulatedBroker) GetPositions(ctx context.Context) ([]*interfaces.Position, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	symbols := make([]string, 0, len(b.positions))
+	for symbol := range b.positions {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	positions := make([]*interfaces.Position, 0, len(symbols))
+	for _, symbol := range symbols {
+		positions = append(positions, b.position(ctx, symbol))
+	}
+	return positions, nil
+}
+
+// position marks one holding to market. The caller holds mu.
+func (b *SimulatedBroker) position(ctx context.Context, symbol string) *interfaces.Position {
+	held := b.positions[symbol]
+	multiplier := decimal.NewFromFloat(contractMultiplier(symbol))
+	avg := held.cost.Div(held.qty).Div(multiplier)
+	current := avg
+	if trade, err := b.dataService.GetLatestTrade(ctx, symbol); err == nil && trade.Price > 0 {
+		current = decimal.NewFromFloat(trade.Price)
+	}
+	value := current.Mul(held.qty).Mul(multiplier)
+	pl := value.Sub(held.cost)
+	plpc := decimal.Zero
+	if held.cost.IsPositive() {
+		plpc = pl.Div(held.cost)
+	}
+	return &interfaces.Position{
+		Symbol:         symbol,
+		Qty:            held.qty,
+		AvgEntryPrice:  avg,
+		MarketValue:    value,
+		CostBasis:      held.cost,
+		UnrealizedPL:   pl,
+		UnrealizedPLPC: plpc,
+		CurrentPrice:   current,
+		Side:           "long",
+	}
+}
+
+// GetAccount returns the simulated cash and equity
+func (b *SimulatedBroker) GetAccount(ctx context.Context) (*interfaces.Account, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	equity := b.cash
+	for symbol := range b.positions {
+		equity = equity.Add(b.position(ctx, symbol).MarketValue)
+	}
+	return &interfaces.Account{
+		ID:             "simulated",
+		Cash:           b.cash,
+		PortfolioValue: equity,
+		BuyingPower:    b.cash,
+		Equity:         equity,
+		Multiplier:     decimal.NewFromInt(1),
+	}, nil
+}
+
+// GetPortfolioHistory is not recorded by the simulated broker
+func (b *SimulatedBroker) GetPortfolioHistory(ctx context.Context, start, end time.Time) ([]*interfaces.EquitySnapshot, error) {
+	return nil, ErrNotSupported
+}
+
+// PlaceOptionsOrder is not supported by the simulated broker
+func (b *SimulatedBroker) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	return nil, ErrNotSupported
+}
+
+// GetOptionsChain is not supported by the simulated broker
+func (b *SimulatedBroker) GetOptionsChain(ctx context.Context, underlying string, expiration time.Time) ([]*interfaces.OptionContract, error) {
+	return nil, ErrNotSupported
+}
+
+// GetOptionsQuote is not supported by the simulated broker
+func (b *SimulatedBroker) GetOptionsQuote(ctx context.Context, symbol string) (*interfaces.OptionsQuote, error) {
+	return nil, ErrNotSupported
+}
+
+// GetOptionsPosition is not supported by the simulated broker
+func (b *SimulatedBroker) GetOptionsPosition(ctx context.Context, symbol string) (*interfaces.OptionsPosition, error) {
+	return nil, ErrNotSupported
+}
+
+// ListOptionsPositions returns no positions; the simulated broker holds
+// stock only
+func (b *SimulatedBroker) ListOptionsPositions(ctx context.Context) ([]*interfaces.OptionsPosition, error) {
+	return []*interfaces.OptionsPosition{}, nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"prophet-trader/interfaces"
+
+	"github.com/shopspring/decimal"
+)
+
+// testSession is a Wednesday morning in regular trading hours, so the
+// simulated broker fills marketable orders at once
+var testSession = time.Date(2026, 10, 14, 11, 0, 0, 0, marketLocation)
+
+// testMarket is a DataService quoting fixed prices, a cent either side of
+// the last trade. A symbol without a price has no trade or quote.
+type testMarket struct {
+	mu     sync.Mutex
+	prices map[string]float64
+}
+
+func newTestMarket(prices map[string]float64) *testMarket {
+	m := &testMarket{prices: make(map[string]float64)}
+	for symbol, price := range prices {
+		m.prices[symbol] = price
+	}
+	return m
+}
+
+// set changes symbol's price, removing it when price is zero
+func (m *testMarket) set(symbol string, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if price <= 0 {
+		delete(m.prices, symbol)
+		return
+	}
+	m.prices[symbol] = price
+}
+
+func (m *testMarket) price(symbol string) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	price, ok := m.prices[symbol]
+	if !ok {
+		return 0, fmt.Errorf("no market data for %s", symbol)
+	}
+	return price, nil
+}
+
+func (m *testMarket) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
+	return []*interfaces.Bar{}, nil
+}
+
+func (m *testMarket) GetLatestBar(ctx context.Context, symbol string) (*interfaces.Bar, error) {
+	price, err := m.price(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &interfaces.Bar{Symbol: symbol, Timestamp: testSession, Open: price, High: price, Low: price, Close: price, Volume: 1000000}, nil
+}
+
+func (m *testMarket) GetLatestQuote(ctx context.Context, symbol string) (*interfaces.Quote, error) {
+	price, err := m.price(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &interfaces.Quote{Symbol: symbol, BidPrice: price - 0.01, AskPrice: price + 0.01, Timestamp: testSession}, nil
+}
+
+func (m *testMarket) GetLatestTrade(ctx context.Context, symbol string) (*interfaces.Trade, error) {
+	price, err := m.price(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &interfaces.Trade{Symbol: symbol, Price: price, Timestamp: testSession}, nil
+}
+
+func (m *testMarket) StreamBars(ctx context.Context, symbols []string) (<-chan *interfaces.Bar, error) {
+	return nil, ErrNotSupported
+}
+
+// newTestBroker returns a simulated broker with $100,000 in cash, trading
+// in testSession on market's prices
+func newTestBroker(market *testMarket) *SimulatedBroker {
+	return NewSimulatedBroker(market, decimal.NewFromInt(100000), func() time.Time { return testSession })
+}
+
+// buyShares fills a market buy of qty shares of symbol at the broker
+func buyShares(t *testing.T, broker *SimulatedBroker, symbol string, qty int64) string {
+	t.Helper()
+	result, err := broker.PlaceOrder(context.Background(), &interfaces.Order{
+		Symbol:      symbol,
+		Qty:         decimal.NewFromInt(qty),
+		Side:        "buy",
+		Type:        "market",
+		TimeInForce: "day",
+	})
+	if err != nil {
+		t.Fatalf("buy %d %s: %v", qty, symbol, err)
+	}
+	if result.Status != "filled" {
+		t.Fatalf("buy %d %s: status %s, want filled", qty, symbol, result.Status)
+	}
+	return result.OrderID
+}
@@ -0,0 +1,463 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SocialPost is one post from a social source
+type SocialPost struct {
+	Source     string // "reddit" or "x"
+	ID         string
+	Channel    string // subreddit or list ID
+	Text       string
+	URL        string
+	Engagement int // upvotes + comments, or likes + reposts + replies
+	CreatedAt  time.Time
+}
+
+// SocialSource fetches recent posts from one social platform
+type SocialSource interface {
+	Name() string
+	FetchPosts(ctx context.Context) ([]SocialPost, error)
+}
+
+// Social sentiment windows
+var socialWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// socialRetention bounds how long mentions are kept
+const socialRetention = 30 * 24 * time.Hour
+
+var cashtagPattern = regexp.MustCompile(`\$([A-Za-z]{1,5})\b`)
+var bareTickerPattern = regexp.MustCompile(`\b[A-Z]{2,5}\b`)
+
+// ambiguousTickers are known tickers that are also common words or slang
+// and only count when written as a cashtag
+var ambiguousTickers = map[string]bool{
+	"NOW": true, "ARM": true, "ALL": true, "ON": true, "IT": true, "ARE": true,
+	"DD": true, "CEO": true, "USA": true, "GDP": true, "IPO": true, "ATH": true,
+	"EPS": true, "ETF": true, "YOLO": true, "FOMO": true, "IMO": true, "LOW": true,
+	"KEY": true, "CAT": true, "MA": true, "SO": true, "BE": true, "OR": true,
+}
+
+// Sentiment lexicon tuned for retail trading chatter
+var (
+	bullishTerms = []string{
+		"bull", "bullish", "calls", "call", "long", "buy", "buying", "bought", "moon", "mooning",
+		"rocket", "squeeze", "breakout", "undervalued", "beat", "beats", "upgrade", "rally",
+		"ripping", "green", "tendies", "diamond hands", "all in", "ath", "strong",
+	}
+	bearishTerms = []string{
+		"bear", "bearish", "puts", "put", "short", "shorting", "sell", "selling", "sold", "dump",
+		"dumping", "crash", "overvalued", "miss", "missed", "downgrade", "red", "bagholder",
+		"bagholding", "rug", "bankrupt", "dead", "weak", "fraud", "tank", "tanking",
+	}
+)
+
+// SocialWindow is mention and sentiment activity over one rolling window
+type SocialWindow struct {
+	Window     string  `json:"window"` // "1h", "24h", "7d"
+	Mentions   int     `json:"mentions"`
+	Bullish    int     `json:"bullish"`
+	Bearish    int     `json:"bearish"`
+	Sentiment  float64 `json:"sentiment"` // engagement-weighted, -1 (bearish) to 1 (bullish)
+	Engagement int     `json:"engagement"`
+}
+
+// SocialSentiment is the social activity for one symbol
+type SocialSentiment struct {
+	Symbol          string          `json:"symbol"`
+	Windows         []*SocialWindow `json:"windows"`
+	MentionVelocity float64         `json:"mention_velocity"` // last 24h mentions vs the 7-day daily average
+	Label           string          `json:"label"`            // "BULLISH", "BEARISH", "NEUTRAL", "QUIET"
+	Sources         map[string]int  `json:"sources"`          // 7-day mentions per source
+	LastCollected   *time.Time      `json:"last_collected,omitempty"`
+	Timestamp       time.Time       `json:"timestamp"`
+}
+
+// Summary condenses the sentiment into one line for AI prompts
+func (s *SocialSentiment) Summary() string {
+	day := s.Windows[1]
+	return fmt.Sprintf("Social: %d mentions/24h (%.1fx avg), sentiment %+.2f (%s)",
+		day.Mentions, s.MentionVelocity, day.Sentiment, s.Label)
+}
+
+// SocialSentimentService collects ticker mentions from social sources and
+// scores sentiment over rolling windows
+type SocialSentimentService struct {
+	sources        []SocialSource
+	storageService *database.LocalStorage
+	lastCollected  time.Time
+	mu             sync.Mutex
+	logger         *logrus.Logger
+}
+
+// NewSocialSentimentService creates a social sentiment service
+func NewSocialSentimentService(storageService *database.LocalStorage, sources ...SocialSource) *SocialSentimentService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &SocialSentimentService{
+		sources:        sources,
+		storageService: storageService,
+		logger:         logger,
+	}
+}
+
+// Collect fetches posts from every source and stores their ticker mentions
+func (s *SocialSentimentService) Collect(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := 0
+	for _, source := range s.sources {
+		posts, err := source.FetchPosts(ctx)
+		if err != nil {
+			s.logger.WithError(err).WithField("source", source.Name()).Warn("Failed to fetch social posts")
+			continue
+		}
+
+		for _, post := range posts {
+			sentiment := scoreSentiment(post.Text)
+			for _, symbol := range extractTickers(post.Text) {
+				mention := &models.DBSocialMention{
+					Source:     post.Source,
+					PostID:     post.ID,
+					Channel:    post.Channel,
+					Symbol:     symbol,
+					Sentiment:  sentiment,
+					Engagement: post.Engagement,
+					URL:        post.URL,
+					PostedAt:   post.CreatedAt,
+				}
+				if err := s.storageService.SaveSocialMention(mention); err != nil {
+					s.logger.WithError(err).Warn("Failed to save social mention")
+					continue
+				}
+				stored++
+			}
+		}
+	}
+
+	if err := s.storageService.CleanupSocialMentions(time.Now().Add(-socialRetention)); err != nil {
+		s.logger.WithError(err).Warn("Failed to clean up social mentions")
+	}
+
+	s.lastCollected = time.Now()
+	s.logger.WithField("mentions", stored).Debug("Collected social mentions")
+}
+
+// Get returns rolling-window sentiment for symbol. Posts are collected
+// first when nothing has been collected yet.
+func (s *SocialSentimentService) Get(ctx context.Context, symbol string) (*SocialSentiment, error) {
+	symbol = strings.ToUpper(symbol)
+
+	s.mu.Lock()
+	collected := s.lastCollected
+	s.mu.Unlock()
+	if collected.IsZero() {
+		s.Collect(ctx)
+		s.mu.Lock()
+		collected = s.lastCollected
+		s.mu.Unlock()
+	}
+
+	now := time.Now()
+	mentions, err := s.storageService.GetSocialMentions(symbol, now.Add(-socialWindows[len(socialWindows)-1].duration))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get social mentions: %w", err)
+	}
+
+	result := &SocialSentiment{
+		Symbol:        symbol,
+		Sources:       make(map[string]int),
+		LastCollected: &collected,
+		Timestamp:     now,
+	}
+	for _, w := range socialWindows {
+		result.Windows = append(result.Windows, aggregateMentions(w.name, mentions, now.Add(-w.duration)))
+	}
+	for _, m := range mentions {
+		result.Sources[m.Source]++
+	}
+
+	day, week := result.Windows[1], result.Windows[2]
+	if dailyAvg := float64(week.Mentions) / 7; dailyAvg > 0 {
+		result.MentionVelocity = float64(day.Mentions) / dailyAvg
+	}
+
+	switch {
+	case day.Mentions < 3:
+		result.Label = "QUIET"
+	case day.Sentiment >= 0.2:
+		result.Label = "BULLISH"
+	case day.Sentiment <= -0.2:
+		result.Label = "BEARISH"
+	default:
+		result.Label = "NEUTRAL"
+	}
+
+	return result, nil
+}
+
+// aggregateMentions summarizes the mentions posted at or after since.
+// Sentiment is weighted by log-scaled engagement so viral posts count more
+// without drowning out everything else.
+func aggregateMentions(name string, mentions []*models.DBSocialMention, since time.Time) *SocialWindow {
+	window := &SocialWindow{Window: name}
+	var weighted, weights float64
+
+	for _, m := range mentions {
+		if m.PostedAt.Before(since) {
+			continue
+		}
+		window.Mentions++
+		window.Engagement += m.Engagement
+		switch {
+		case m.Sentiment > 0:
+			window.Bullish++
+		case m.Sentiment < 0:
+			window.Bearish++
+		}
+
+		weight := 1 + logEngagement(m.Engagement)
+		weighted += m.Sentiment * weight
+		weights += weight
+	}
+
+	if weights > 0 {
+		window.Sentiment = weighted / weights
+	}
+	return window
+}
+
+func logEngagement(engagement int) float64 {
+	weight := 0.0
+	for e := engagement; e >= 10; e /= 10 {
+		weight++
+	}
+	return weight
+}
+
+// extractTickers returns the symbols mentioned in text: every cashtag, plus
+// bare upper-case known tickers that are not also common words
+func extractTickers(text string) []string {
+	seen := make(map[string]bool)
+	symbols := []string{}
+
+	for _, m := range cashtagPattern.FindAllStringSubmatch(text, -1) {
+		symbol := strings.ToUpper(m[1])
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	for _, word := range bareTickerPattern.FindAllString(text, -1) {
+		if _, known := symbolSectors[word]; known && !ambiguousTickers[word] && !seen[word] {
+			seen[word] = true
+			symbols = append(symbols, word)
+		}
+	}
+	return symbols
+}
+
+// scoreSentiment scores text from -1 (bearish) to 1 (bullish) by counting
+// lexicon terms; text without any terms scores 0
+func scoreSentiment(text string) float64 {
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z')
+	}), " ") + " "
+
+	count := func(terms []string) int {
+		n := 0
+		for _, term := range terms {
+			n += strings.Count(words, " "+term+" ")
+		}
+		return n
+	}
+
+	bullish, bearish := count(bullishTerms), count(bearishTerms)
+	if bullish+bearish == 0 {
+		return 0
+	}
+	return float64(bullish-bearish) / float64(bullish+bearish)
+}
+
+// socialHTTPGet fetches url with optional headers and decodes the JSON response
+func socialHTTPGet(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// RedditSource reads the newest posts of a set of subreddits through
+// Reddit's public JSON listings
+type RedditSource struct {
+	subreddits []string
+	httpClient *http.Client
+}
+
+// NewRedditSource creates a Reddit source for the given subreddits
+func NewRedditSource(subreddits []string) *RedditSource {
+	return &RedditSource{
+		subreddits: subreddits,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the source name
+func (rs *RedditSource) Name() string { return "reddit" }
+
+// FetchPosts returns the newest posts of every subreddit
+func (rs *RedditSource) FetchPosts(ctx context.Context) ([]SocialPost, error) {
+	posts := []SocialPost{}
+	var lastErr error
+
+	for _, subreddit := range rs.subreddits {
+		var listing struct {
+			Data struct {
+				Children []struct {
+					Data struct {
+						ID          string  `json:"id"`
+						Title       string  `json:"title"`
+						Selftext    string  `json:"selftext"`
+						Permalink   string  `json:"permalink"`
+						Score       int     `json:"score"`
+						NumComments int     `json:"num_comments"`
+						CreatedUTC  float64 `json:"created_utc"`
+					} `json:"data"`
+				} `json:"children"`
+			} `json:"data"`
+		}
+
+		endpoint := fmt.Sprintf("https://www.reddit.com/r/%s/new.json?limit=100", url.PathEscape(subreddit))
+		if err := socialHTTPGet(ctx, rs.httpClient, endpoint, map[string]string{"User-Agent": "prophet-trader/1.0"}, &listing); err != nil {
+			lastErr = fmt.Errorf("r/%s: %w", subreddit, err)
+			continue
+		}
+
+		for _, child := range listing.Data.Children {
+			p := child.Data
+			posts = append(posts, SocialPost{
+				Source:     "reddit",
+				ID:         p.ID,
+				Channel:    subreddit,
+				Text:       p.Title + "\n" + p.Selftext,
+				URL:        "https://www.reddit.com" + p.Permalink,
+				Engagement: p.Score + p.NumComments,
+				CreatedAt:  time.Unix(int64(p.CreatedUTC), 0),
+			})
+		}
+	}
+
+	if len(posts) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return posts, nil
+}
+
+// XSource reads recent posts from X lists through the v2 API
+type XSource struct {
+	listIDs     []string
+	bearerToken string
+	httpClient  *http.Client
+}
+
+// NewXSource creates an X source for the given list IDs
+func NewXSource(bearerToken string, listIDs []string) *XSource {
+	return &XSource{
+		listIDs:     listIDs,
+		bearerToken: bearerToken,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the source name
+func (xs *XSource) Name() string { return "x" }
+
+// FetchPosts returns the latest posts of every list
+func (xs *XSource) FetchPosts(ctx context.Context) ([]SocialPost, error) {
+	posts := []SocialPost{}
+	var lastErr error
+
+	for _, listID := range xs.listIDs {
+		var resp struct {
+			Data []struct {
+				ID            string    `json:"id"`
+				Text          string    `json:"text"`
+				CreatedAt     time.Time `json:"created_at"`
+				PublicMetrics struct {
+					LikeCount    int `json:"like_count"`
+					RetweetCount int `json:"retweet_count"`
+					ReplyCount   int `json:"reply_count"`
+				} `json:"public_metrics"`
+			} `json:"data"`
+		}
+
+		endpoint := fmt.Sprintf("https://api.twitter.com/2/lists/%s/tweets?max_results=100&tweet.fields=created_at,public_metrics", url.PathEscape(listID))
+		if err := socialHTTPGet(ctx, xs.httpClient, endpoint, map[string]string{"Authorization": "Bearer " + xs.bearerToken}, &resp); err != nil {
+			lastErr = fmt.Errorf("list %s: %w", listID, err)
+			continue
+		}
+
+		for _, tweet := range resp.Data {
+			posts = append(posts, SocialPost{
+				Source:     "x",
+				ID:         tweet.ID,
+				Channel:    listID,
+				Text:       tweet.Text,
+				URL:        "https://x.com/i/web/status/" + tweet.ID,
+				Engagement: tweet.PublicMetrics.LikeCount + tweet.PublicMetrics.RetweetCount + tweet.PublicMetrics.ReplyCount,
+				CreatedAt:  tweet.CreatedAt,
+			})
+		}
+	}
+
+	if len(posts) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return posts, nil
+}
@@ -0,0 +1,474 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// splitLookbackDays is how far back a split check looks for ex-dates, so a
+// bot that was down over a split still catches it
+const splitLookbackDays = 7
+
+// StockSplit is a forward or reverse split. Each OldRate shares become
+// NewRate shares on ExDate.
+type StockSplit struct {
+	Symbol  string    `json:"symbol"`
+	ExDate  time.Time `json:"ex_date"` // midnight ET
+	OldRate float64   `json:"old_rate"`
+	NewRate float64   `json:"new_rate"`
+}
+
+// Ratio is the number of shares each share became: 4 for a 4-for-1 split,
+// 0.1 for a 1-for-10 reverse split
+func (s *StockSplit) Ratio() decimal.Decimal {
+	if s.OldRate <= 0 {
+		return decimal.NewFromInt(1)
+	}
+	return decimal.NewFromFloat(s.NewRate).Div(decimal.NewFromFloat(s.OldRate))
+}
+
+// SplitSource is implemented by data providers that report stock splits
+type SplitSource interface {
+	GetSplits(ctx context.Context, symbols []string, start, end time.Time) ([]*StockSplit, error)
+}
+
+// SplitFieldChange is one rescaled value
+type SplitFieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// Split change kinds
+const (
+	SplitChangePositionSnapshot = "position_snapshot"
+	SplitChangeManagedPosition  = "managed_position"
+	SplitChangeTrade            = "trade"
+)
+
+// SplitChange lists the values rescaled on one stored record
+type SplitChange struct {
+	Kind   string             `json:"kind"`
+	ID     string             `json:"id"`
+	Fields []SplitFieldChange `json:"fields"`
+	Note   string             `json:"note,omitempty"`
+}
+
+// SplitAdjustment is the audit entry for a split applied to stored records
+type SplitAdjustment struct {
+	Symbol    string        `json:"symbol"`
+	ExDate    string        `json:"ex_date"`
+	OldRate   float64       `json:"old_rate"`
+	NewRate   float64       `json:"new_rate"`
+	Ratio     float64       `json:"ratio"`
+	Source    string        `json:"source"` // "detected" or "manual"
+	Changes   []SplitChange `json:"changes"`
+	AppliedAt time.Time     `json:"applied_at"`
+}
+
+// SplitAdjuster rescales stored position snapshots, managed positions and
+// the trade ledger when a held symbol splits, so quantities and prices stay
+// comparable with the broker's split-adjusted positions and bars
+type SplitAdjuster struct {
+	source          SplitSource // nil when no data provider reports splits
+	tradingService  interfaces.TradingService
+	storage         *database.LocalStorage
+	positionManager *PositionManager
+	notifier        *Notifier
+	logger          *logrus.Logger
+}
+
+// NewSplitAdjuster creates a split adjuster. source may be nil, in which
+// case splits are only applied through Apply.
+func NewSplitAdjuster(source SplitSource, tradingService interfaces.TradingService, storage *database.LocalStorage, positionManager *PositionManager, notifier *Notifier) *SplitAdjuster {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &SplitAdjuster{
+		source:          source,
+		tradingService:  tradingService,
+		storage:         storage,
+		positionManager: positionManager,
+		notifier:        notifier,
+		logger:          logger,
+	}
+}
+
+// Check looks up recent splits of held symbols and applies those not
+// applied before
+func (a *SplitAdjuster) Check(ctx context.Context) error {
+	if a.source == nil {
+		return WithErrorCode(ErrCodeNotSupported, fmt.Errorf("the data provider doesn't report splits"))
+	}
+
+	symbols, err := a.heldSymbols(ctx)
+	if err != nil {
+		return err
+	}
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	now := time.Now().In(marketLocation)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, marketLocation)
+	splits, err := a.source.GetSplits(ctx, symbols, today.AddDate(0, 0, -splitLookbackDays), today)
+	if err != nil {
+		return fmt.Errorf("failed to get splits: %w", err)
+	}
+
+	for _, split := range splits {
+		if split.ExDate.After(today) {
+			continue
+		}
+		applied, err := a.storage.HasSplitAdjustment(split.Symbol, split.ExDate.Format(marketDateFormat))
+		if err != nil {
+			return err
+		}
+		if applied {
+			continue
+		}
+		if _, err := a.apply(ctx, split, "detected"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SplitRequest describes a split to apply by hand
+type SplitRequest struct {
+	Symbol  string  `json:"symbol" binding:"required,symbol"`
+	ExDate  string  `json:"ex_date" binding:"required"` // YYYY-MM-DD
+	OldRate float64 `json:"old_rate" binding:"required,gt=0"`
+	NewRate float64 `json:"new_rate" binding:"required,gt=0"`
+}
+
+// Apply applies a split by hand, for data providers that don't report
+// them. A split already applied is rejected.
+func (a *SplitAdjuster) Apply(ctx context.Context, req *SplitRequest) (*SplitAdjustment, error) {
+	exDate, err := time.ParseInLocation(marketDateFormat, req.ExDate, marketLocation)
+	if err != nil {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("ex_date must be YYYY-MM-DD: %w", err))
+	}
+	if exDate.After(time.Now()) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("the split's ex-date hasn't come yet"))
+	}
+	if req.OldRate <= 0 || req.NewRate <= 0 || req.OldRate == req.NewRate {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("old_rate and new_rate must be positive and differ"))
+	}
+	split := &StockSplit{
+		Symbol:  strings.ToUpper(req.Symbol),
+		ExDate:  exDate,
+		OldRate: req.OldRate,
+		NewRate: req.NewRate,
+	}
+
+	applied, err := a.storage.HasSplitAdjustment(split.Symbol, req.ExDate)
+	if err != nil {
+		return nil, err
+	}
+	if applied {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("the %s split on %s was already applied", split.Symbol, req.ExDate))
+	}
+	return a.apply(ctx, split, "manual")
+}
+
+// List returns the applied splits, newest first, optionally for one symbol
+func (a *SplitAdjuster) List(symbol string) ([]*SplitAdjustment, error) {
+	dbAdjustments, err := a.storage.GetSplitAdjustments(strings.ToUpper(symbol))
+	if err != nil {
+		return nil, err
+	}
+
+	adjustments := make([]*SplitAdjustment, len(dbAdjustments))
+	for i, dbAdjustment := range dbAdjustments {
+		adjustments[i] = splitAdjustmentFromDB(dbAdjustment)
+	}
+	return adjustments, nil
+}
+
+// apply rescales every record dated before the ex-date. Records written
+// since already reflect the split: position snapshots are refreshed from
+// the broker, and trades closed after the split have a post-split exit.
+func (a *SplitAdjuster) apply(ctx context.Context, split *StockSplit, source string) (*SplitAdjustment, error) {
+	ratio := split.Ratio()
+	exDate := split.ExDate.Format(marketDateFormat)
+	changes := make([]SplitChange, 0)
+
+	snapshots, err := a.storage.GetPositionSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	rescaledSnapshots := make([]*models.DBPosition, 0)
+	for _, snapshot := range snapshots {
+		if snapshot.Symbol != split.Symbol || !snapshot.SnapshotTime.Before(split.ExDate) {
+			continue
+		}
+		change := SplitChange{Kind: SplitChangePositionSnapshot, ID: snapshot.Symbol}
+		snapshot.Qty = rescaleQty(&change, "qty", snapshot.Qty, ratio)
+		snapshot.AvgEntryPrice = rescalePrice(&change, "avg_entry_price", snapshot.AvgEntryPrice, ratio)
+		snapshot.CurrentPrice = rescalePrice(&change, "current_price", snapshot.CurrentPrice, ratio)
+		rescaledSnapshots = append(rescaledSnapshots, snapshot)
+		changes = append(changes, change)
+	}
+
+	trades, err := a.storage.GetSymbolTrades(split.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	rescaledTrades := make([]*models.DBTrade, 0)
+	for _, trade := range trades {
+		if !trade.EntryTime.Before(split.ExDate) {
+			continue
+		}
+		change := SplitChange{Kind: SplitChangeTrade, ID: strconv.FormatUint(uint64(trade.ID), 10)}
+		trade.Qty = rescaleQty(&change, "qty", trade.Qty, ratio)
+		trade.EntryPrice = rescalePrice(&change, "entry_price", trade.EntryPrice, ratio)
+		if trade.ExitTime.Before(split.ExDate) {
+			// Entirely before the split: P&L is unchanged
+			trade.ExitPrice = rescalePrice(&change, "exit_price", trade.ExitPrice, ratio)
+		} else {
+			// Opened before and closed after: the exit was at a post-split
+			// price against a pre-split entry, so P&L is recomputed
+			change.Note = "closed after the split; P&L recomputed"
+			recomputeTradePnL(&change, trade)
+		}
+		rescaledTrades = append(rescaledTrades, trade)
+		changes = append(changes, change)
+	}
+
+	adjustment := &SplitAdjustment{
+		Symbol:    split.Symbol,
+		ExDate:    exDate,
+		OldRate:   split.OldRate,
+		NewRate:   split.NewRate,
+		Ratio:     ratio.InexactFloat64(),
+		Source:    source,
+		AppliedAt: time.Now(),
+	}
+	adjustment.Changes = changes
+	dbAdjustment := splitAdjustmentToDB(adjustment)
+	if err := a.storage.ApplySplitAdjustment(dbAdjustment, rescaledSnapshots, rescaledTrades); err != nil {
+		return nil, err
+	}
+
+	// Managed positions live in the position manager, so they are rescaled
+	// there once the split is recorded, and the audit entry updated
+	if a.positionManager != nil {
+		managed := a.positionManager.ApplySplit(ctx, split.Symbol, ratio.InexactFloat64(), split.ExDate)
+		if len(managed) > 0 {
+			adjustment.Changes = append(adjustment.Changes, managed...)
+			dbAdjustment.Changes = encodeSplitChanges(adjustment.Changes)
+			if err := a.storage.SaveSplitAdjustment(dbAdjustment); err != nil {
+				a.logger.WithError(err).WithField("symbol", split.Symbol).Error("Failed to record managed position split changes")
+			}
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, change := range adjustment.Changes {
+		counts[change.Kind]++
+	}
+	fields := map[string]interface{}{
+		"symbol":             split.Symbol,
+		"ex_date":            exDate,
+		"ratio":              ratio.String(),
+		"position_snapshots": counts[SplitChangePositionSnapshot],
+		"managed_positions":  counts[SplitChangeManagedPosition],
+		"trades":             counts[SplitChangeTrade],
+	}
+	a.logger.WithFields(logrus.Fields(fields)).Info("Applied stock split to stored records")
+	a.notifier.Notify(ctx, NotifyInfo, "Stock split applied",
+		fmt.Sprintf("%s split %g-for-%g on %s: rescaled %d position snapshot(s), %d managed position(s) and %d trade(s)",
+			split.Symbol, split.NewRate, split.OldRate, exDate,
+			counts[SplitChangePositionSnapshot], counts[SplitChangeManagedPosition], counts[SplitChangeTrade]),
+		fields)
+
+	return adjustment, nil
+}
+
+// heldSymbols returns the equity symbols held at the broker or by open
+// managed positions
+func (a *SplitAdjuster) heldSymbols(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	symbols := make([]string, 0)
+	add := func(symbol string) {
+		if IsOCCSymbol(symbol) || strings.Contains(symbol, "/") || seen[symbol] {
+			return
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+
+	positions, err := a.tradingService.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+	for _, position := range positions {
+		add(position.Symbol)
+	}
+	if a.positionManager != nil {
+		for _, position := range a.positionManager.allPositions() {
+			if position.Status == "ACTIVE" || position.Status == "PARTIAL" {
+				add(position.Symbol)
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// ApplySplit rescales the filled, open managed positions in symbol opened
+// before exDate: quantities are multiplied by ratio and prices divided by
+// it. Their stop loss and take profit orders are replaced at the new size
+// and prices. Pending entries are left to the broker's adjustment of the
+// entry order.
+func (pm *PositionManager) ApplySplit(ctx context.Context, symbol string, ratio float64, exDate time.Time) []SplitChange {
+	changes := make([]SplitChange, 0)
+	if ratio <= 0 || ratio == 1 {
+		return changes
+	}
+
+	for _, position := range pm.allPositions() {
+		// Locked so the monitor doesn't place risk orders at the old size
+		// while they are replaced
+		unlock := pm.lockPosition(position.ID)
+		change, ok := pm.applySplit(ctx, position, symbol, ratio, exDate)
+		unlock()
+		if ok {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// applySplit rescales one position if the split applies to it
+func (pm *PositionManager) applySplit(ctx context.Context, position *ManagedPosition, symbol string, ratio float64, exDate time.Time) (SplitChange, bool) {
+	if position.Symbol != symbol || position.Shadow || !position.CreatedAt.Before(exDate) {
+		return SplitChange{}, false
+	}
+	if position.Status != "ACTIVE" && position.Status != "PARTIAL" {
+		return SplitChange{}, false
+	}
+
+	change := SplitChange{Kind: SplitChangeManagedPosition, ID: position.ID}
+	scale := func(field string, value *float64, factor float64) {
+		if *value == 0 {
+			return
+		}
+		before := *value
+		*value *= factor
+		change.Fields = append(change.Fields, SplitFieldChange{
+			Field:  field,
+			Before: strconv.FormatFloat(before, 'f', -1, 64),
+			After:  strconv.FormatFloat(*value, 'f', -1, 64),
+		})
+	}
+	scale("quantity", &position.Quantity, ratio)
+	scale("remaining_qty", &position.RemainingQty, ratio)
+	scale("entry_filled_qty", &position.EntryFilledQty, ratio)
+	scale("entry_price", &position.EntryPrice, 1/ratio)
+	scale("stop_loss_price", &position.StopLossPrice, 1/ratio)
+	scale("take_profit_price", &position.TakeProfitPrice, 1/ratio)
+	scale("initial_risk", &position.InitialRisk, 1/ratio)
+	scale("current_price", &position.CurrentPrice, 1/ratio)
+	if position.PartialExit != nil {
+		scale("partial_exit_target_price", &position.PartialExit.TargetPrice, 1/ratio)
+	}
+	position.UpdatedAt = time.Now()
+	if position.Notes != "" {
+		position.Notes += "; "
+	}
+	position.Notes += fmt.Sprintf("adjusted for the %s split on %s", symbol, exDate.Format(marketDateFormat))
+
+	pm.cancelSizedRiskOrders(ctx, position)
+	pm.placeRiskOrders(ctx, position)
+	if err := pm.savePositionToDB(position); err != nil {
+		pm.logger.WithError(err).WithField("position_id", position.ID).Error("Failed to save split-adjusted position")
+	}
+	return change, true
+}
+
+// rescaleQty multiplies a quantity by ratio, recording the change
+func rescaleQty(change *SplitChange, field string, qty, ratio decimal.Decimal) decimal.Decimal {
+	if qty.IsZero() {
+		return qty
+	}
+	after := qty.Mul(ratio)
+	change.Fields = append(change.Fields, SplitFieldChange{Field: field, Before: qty.String(), After: after.String()})
+	return after
+}
+
+// rescalePrice divides a price by ratio, recording the change
+func rescalePrice(change *SplitChange, field string, price, ratio decimal.Decimal) decimal.Decimal {
+	if price.IsZero() {
+		return price
+	}
+	after := price.DivRound(ratio, 8)
+	change.Fields = append(change.Fields, SplitFieldChange{Field: field, Before: price.String(), After: after.String()})
+	return after
+}
+
+// recomputeTradePnL recalculates a trade's P&L, percent and R multiple from
+// its entry, exit and quantity
+func recomputeTradePnL(change *SplitChange, trade *models.DBTrade) {
+	perShare := trade.ExitPrice.Sub(trade.EntryPrice)
+	if trade.Side == "sell" {
+		perShare = perShare.Neg()
+	}
+	pnl := perShare.Mul(trade.Qty)
+	change.Fields = append(change.Fields, SplitFieldChange{Field: "pnl", Before: trade.PnL.String(), After: pnl.String()})
+	trade.PnL = pnl
+	if trade.EntryPrice.IsPositive() {
+		trade.PnLPercent = perShare.Div(trade.EntryPrice).Mul(decimal.NewFromInt(100)).InexactFloat64()
+	}
+	if trade.InitialRisk.IsPositive() {
+		r := pnl.Div(trade.InitialRisk).InexactFloat64()
+		trade.RMultiple = &r
+	}
+}
+
+func encodeSplitChanges(changes []SplitChange) string {
+	data, _ := json.Marshal(changes)
+	return string(data)
+}
+
+func splitAdjustmentToDB(adjustment *SplitAdjustment) *models.DBSplitAdjustment {
+	return &models.DBSplitAdjustment{
+		Symbol:    adjustment.Symbol,
+		ExDate:    adjustment.ExDate,
+		OldRate:   adjustment.OldRate,
+		NewRate:   adjustment.NewRate,
+		Ratio:     adjustment.Ratio,
+		Source:    adjustment.Source,
+		Changes:   encodeSplitChanges(adjustment.Changes),
+		AppliedAt: adjustment.AppliedAt,
+	}
+}
+
+func splitAdjustmentFromDB(dbAdjustment *models.DBSplitAdjustment) *SplitAdjustment {
+	adjustment := &SplitAdjustment{
+		Symbol:    dbAdjustment.Symbol,
+		ExDate:    dbAdjustment.ExDate,
+		OldRate:   dbAdjustment.OldRate,
+		NewRate:   dbAdjustment.NewRate,
+		Ratio:     dbAdjustment.Ratio,
+		Source:    dbAdjustment.Source,
+		Changes:   make([]SplitChange, 0),
+		AppliedAt: dbAdjustment.AppliedAt,
+	}
+	if dbAdjustment.Changes != "" {
+		_ = json.Unmarshal([]byte(dbAdjustment.Changes), &adjustment.Changes)
+	}
+	return adjustment
+}
@@ -2,9 +2,13 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
+	"prophet-trader/database"
 	"prophet-trader/interfaces"
+	"prophet-trader/models"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -12,33 +16,106 @@ import (
 
 // StockAnalysisService provides comprehensive stock analysis
 type StockAnalysisService struct {
-	dataService   interfaces.DataService
-	newsService   *NewsService
-	geminiService *GeminiService
-	logger        *logrus.Logger
+	dataService         interfaces.DataService
+	newsService         *NewsService
+	geminiService       *GeminiService
+	fundamentalsService *FundamentalsService
+	socialService       *SocialSentimentService
+	profiles            *AnalysisProfileService
+	storageService      *database.LocalStorage
+	semanticIndex       *SemanticIndex
+	eventBus            *EventBus
+	logger              *logrus.Logger
 }
 
-// NewStockAnalysisService creates a new stock analysis service
-func NewStockAnalysisService(dataService interfaces.DataService, newsService *NewsService, geminiService *GeminiService) *StockAnalysisService {
+// NewStockAnalysisService creates a new stock analysis service.
+// fundamentalsService may be nil, in which case market cap is estimated.
+func NewStockAnalysisService(dataService interfaces.DataService, newsService *NewsService, geminiService *GeminiService, fundamentalsService *FundamentalsService) *StockAnalysisService {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
 	})
 
 	return &StockAnalysisService{
-		dataService:   dataService,
-		newsService:   newsService,
-		geminiService: geminiService,
-		logger:        logger,
+		dataService:         dataService,
+		newsService:         newsService,
+		geminiService:       geminiService,
+		fundamentalsService: fundamentalsService,
+		logger:              logger,
 	}
 }
 
+// SetSocialSentiment includes social media sentiment in analyses
+func (sas *StockAnalysisService) SetSocialSentiment(socialService *SocialSentimentService) {
+	sas.socialService = socialService
+}
+
+// SetProfiles analyses each symbol with the profile that matches it instead
+// of the built-in defaults
+func (sas *StockAnalysisService) SetProfiles(profiles *AnalysisProfileService) {
+	sas.profiles = profiles
+}
+
+// SetStorage records every analysis, so later questions can refer to it
+func (sas *StockAnalysisService) SetStorage(storageService *database.LocalStorage) {
+	sas.storageService = storageService
+}
+
+// SetSemanticIndex indexes recorded analyses and adds related earlier news
+// and analyses to each analysis
+func (sas *StockAnalysisService) SetSemanticIndex(semanticIndex *SemanticIndex) {
+	sas.semanticIndex = semanticIndex
+}
+
+// SetEventBus publishes every analysis as an analysis_completed event
+func (sas *StockAnalysisService) SetEventBus(bus *EventBus) {
+	sas.eventBus = bus
+}
+
+// relatedHistory finds indexed news and analyses from before the lookback
+// cutoff that resemble the current headlines
+func (sas *StockAnalysisService) relatedHistory(ctx context.Context, symbol string, news []NewsItem, catalysts []string, cutoff time.Time) []*SemanticMatch {
+	if sas.semanticIndex == nil {
+		return nil
+	}
+
+	exclude := make([]string, 0, len(news))
+	for _, item := range news {
+		exclude = append(exclude, item.Link)
+	}
+	related, err := sas.semanticIndex.Search(ctx, SemanticQuery{
+		Text:    symbol + ": " + strings.Join(catalysts, "; "),
+		Symbol:  symbol,
+		Before:  cutoff,
+		Exclude: exclude,
+		Limit:   3,
+	})
+	if err != nil {
+		sas.logger.WithError(err).WithField("symbol", symbol).Warn("Failed to search related history")
+		return nil
+	}
+	return related
+}
+
+// profileFor returns the analysis profile for symbol
+func (sas *StockAnalysisService) profileFor(symbol string) *AnalysisProfile {
+	if sas.profiles == nil {
+		return DefaultAnalysisProfile()
+	}
+	return sas.profiles.ProfileFor(symbol)
+}
+
 // StockAnalysis represents comprehensive analysis of a stock
 type StockAnalysis struct {
 	Symbol          string                 `json:"symbol"`
+	Profile         string                 `json:"profile"` // analysis profile applied
 	CurrentPrice    float64                `json:"current_price"`
 	MarketCap       string                 `json:"market_cap_estimate"`
 	Technical       TechnicalAnalysis      `json:"technical"`
+	Timeframes      []*TimeframeTechnicals `json:"timeframes,omitempty"` // the profile's other timeframes
+	RelatedHistory  []*SemanticMatch       `json:"related_history,omitempty"` // earlier news and analyses like the current news
+	Fundamentals    *Fundamentals          `json:"fundamentals,omitempty"`
+	Social          *SocialSentiment       `json:"social,omitempty"`
 	NewsSummary     string                 `json:"news_summary"` // Just summary, not full articles
 	TradeSetup      TradeSetup             `json:"trade_setup"`
 	Timestamp       time.Time              `json:"timestamp"`
@@ -57,14 +134,24 @@ type TechnicalAnalysis struct {
 	Volatility    float64  `json:"volatility_30d"`
 	RSI           float64  `json:"rsi_14"` // 0-100
 	PriceStrength string   `json:"price_strength"` // "OVERSOLD", "NEUTRAL", "OVERBOUGHT"
+	Patterns      []string `json:"patterns,omitempty"` // Recent candlestick/chart patterns
+}
+
+// TimeframeTechnicals summarizes one additional timeframe of a profile
+type TimeframeTechnicals struct {
+	Timeframe string  `json:"timeframe"`
+	Trend     string  `json:"trend"`
+	RSI       float64 `json:"rsi_14"`
+	ChangePct float64 `json:"change_percent"` // over the bars fetched
+	Bars      int     `json:"bars"`
 }
 
 // TradeSetup provides neutral trading data for AI interpretation
 type TradeSetup struct {
 	// Price Levels (NEUTRAL - just data)
 	Entry          float64  `json:"entry"`          // Current price
-	StopLoss       float64  `json:"stop_loss"`      // Suggested -15% stop
-	TakeProfit     float64  `json:"take_profit"`    // Suggested +30% target
+	StopLoss       float64  `json:"stop_loss"`      // Profile stop, -15% by default
+	TakeProfit     float64  `json:"take_profit"`    // Profile target, +30% by default
 	RiskReward     float64  `json:"risk_reward"`    // Ratio
 
 	// Catalysts (NEUTRAL - just facts)
@@ -78,6 +165,7 @@ type TradeSetup struct {
 
 	// Overall (NEUTRAL - composite)
 	CompositeScore int      `json:"composite_score"` // 0-10 (avg of above)
+	BelowThreshold bool     `json:"below_threshold,omitempty"` // composite below the profile's min_confidence
 
 	// Notes (FACTUAL - no recommendation)
 	Notes          string   `json:"notes"`           // Factual observations only
@@ -103,8 +191,10 @@ func (sas *StockAnalysisService) AnalyzeStocks(ctx context.Context, symbols []st
 
 // AnalyzeStock provides comprehensive analysis for a single stock
 func (sas *StockAnalysisService) AnalyzeStock(ctx context.Context, symbol string) (*StockAnalysis, error) {
+	profile := sas.profileFor(symbol)
 	analysis := &StockAnalysis{
 		Symbol:    symbol,
+		Profile:   profile.Name,
 		Timestamp: time.Now(),
 	}
 
@@ -124,13 +214,19 @@ func (sas *StockAnalysisService) AnalyzeStock(ctx context.Context, symbol string
 		analysis.Technical.Price = quote.BidPrice
 	}
 
-	// Get historical data for technical analysis (30 days)
+	// Get historical data for technical analysis over the profile's primary timeframe
 	endTime := time.Now()
-	startTime := endTime.AddDate(0, 0, -30)
+	startTime := endTime.AddDate(0, 0, -profile.LookbackDays)
 
-	bars, err := sas.dataService.GetHistoricalBars(ctx, symbol, startTime, endTime, "1Day")
+	bars, err := sas.dataService.GetHistoricalBars(ctx, symbol, startTime, endTime, profile.Timeframes[0])
 	if err == nil && len(bars) > 0 {
 		analysis.Technical = sas.calculateTechnicalIndicators(bars)
+		if !profile.uses(IndicatorLevels) {
+			analysis.Technical.Support, analysis.Technical.Resistance = 0, 0
+		}
+		if !profile.uses(IndicatorPatterns) {
+			analysis.Technical.Patterns = nil
+		}
 	} else {
 		// Minimal analysis without historical data
 		analysis.Technical.Price = quote.BidPrice
@@ -138,34 +234,124 @@ func (sas *StockAnalysisService) AnalyzeStock(ctx context.Context, symbol string
 		analysis.Technical.PriceStrength = "UNKNOWN"
 	}
 
-	// Estimate market cap range
-	analysis.MarketCap = sas.estimateMarketCap(analysis.Technical.Price, symbol)
+	for _, timeframe := range profile.Timeframes[1:] {
+		tfBars, err := sas.dataService.GetHistoricalBars(ctx, symbol, endTime.Add(-analysisTimeframeWindows[timeframe]), endTime, timeframe)
+		if err != nil || len(tfBars) == 0 {
+			sas.logger.WithError(err).WithFields(logrus.Fields{"symbol": symbol, "timeframe": timeframe}).Debug("No bars for profile timeframe")
+			continue
+		}
+		tfTech := sas.calculateTechnicalIndicators(tfBars)
+		analysis.Timeframes = append(analysis.Timeframes, &TimeframeTechnicals{
+			Timeframe: timeframe,
+			Trend:     tfTech.Trend,
+			RSI:       tfTech.RSI,
+			ChangePct: (tfBars[len(tfBars)-1].Close - tfBars[0].Close) / tfBars[0].Close * 100,
+			Bars:      len(tfBars),
+		})
+	}
+
+	// Use reported fundamentals when available, otherwise estimate the market cap range
+	if sas.fundamentalsService != nil {
+		if fundamentals, err := sas.fundamentalsService.Get(ctx, symbol); err == nil {
+			analysis.Fundamentals = fundamentals
+			analysis.MarketCap = fundamentals.MarketCapLabel()
+		}
+	}
+	if analysis.MarketCap == "" {
+		analysis.MarketCap = sas.estimateMarketCap(analysis.Technical.Price, symbol)
+	}
 
 	// Get recent news (summarize to save tokens)
 	newsSummary := ""
 	catalysts := []string{}
+	started := time.Now()
 	news, err := sas.newsService.GetGoogleNewsSearch(symbol)
-	if err == nil && len(news) > 0 {
-		// Get top 3 most recent headlines only
-		limit := 3
-		if len(news) < limit {
-			limit = len(news)
+	RecordUpstreamCall(ctx, UpstreamNews, started, err)
+	// Keep headlines inside the profile's lookback; undated ones are kept
+	cutoff := time.Now().Add(-time.Duration(profile.NewsLookbackHours) * time.Hour)
+	if err == nil {
+		recent := news[:0]
+		for _, item := range news {
+			if item.PublishedAt.IsZero() || item.PublishedAt.After(cutoff) {
+				recent = append(recent, item)
+			}
 		}
+		news = recent
+	}
+	if err == nil && len(news) > 0 {
+		// Get the most recent headlines only
+		limit := minInt(profile.MaxHeadlines, len(news))
 		for i := 0; i < limit; i++ {
 			catalysts = append(catalysts, news[i].Title)
 		}
-		if len(news) > 0 {
-			newsSummary = fmt.Sprintf("%d recent articles (past 48h)", len(news))
+		newsSummary = fmt.Sprintf("%d recent articles (past %dh)", len(news), profile.NewsLookbackHours)
+		analysis.RelatedHistory = sas.relatedHistory(ctx, symbol, news, catalysts, cutoff)
+
+		if profile.NewsPrompt != "" && sas.geminiService != nil {
+			cleaned, err := sas.geminiService.CleanNewsWithHistory(WithPromptEndpoint(ctx, profile.NewsPrompt), news[:limit], analysis.RelatedHistory)
+			if err != nil {
+				sas.logger.WithError(err).WithField("symbol", symbol).Warn("Failed to summarize news with profile prompt")
+			} else if cleaned.ExecutiveSummary != "" {
+				newsSummary = cleaned.ExecutiveSummary
+			}
 		}
 	}
 	analysis.NewsSummary = newsSummary
 
+	if sas.socialService != nil {
+		if social, err := sas.socialService.Get(ctx, symbol); err == nil {
+			analysis.Social = social
+		}
+	}
+
 	// Generate NEUTRAL trade setup (no recommendations, just data)
-	analysis.TradeSetup = sas.generateTradeSetup(analysis.Technical, catalysts, analysis.CurrentPrice)
+	analysis.TradeSetup = sas.generateTradeSetup(analysis.Technical, catalysts, analysis.CurrentPrice, profile)
+	if analysis.Fundamentals != nil {
+		if summary := analysis.Fundamentals.Summary(); summary != "" {
+			analysis.TradeSetup.Notes += " | " + summary
+		}
+	}
+	if analysis.Social != nil {
+		analysis.TradeSetup.Notes += " | " + analysis.Social.Summary()
+	}
+
+	sas.record(analysis)
+	sas.eventBus.Publish(EventAnalysisCompleted, AnalysisCompleted{
+		Symbol:         analysis.Symbol,
+		Profile:        analysis.Profile,
+		Price:          analysis.CurrentPrice,
+		CompositeScore: analysis.TradeSetup.CompositeScore,
+		Trend:          analysis.Technical.Trend,
+		Notes:          analysis.TradeSetup.Notes,
+	})
 
 	return analysis, nil
 }
 
+// record stores an analysis when storage is set
+func (sas *StockAnalysisService) record(analysis *StockAnalysis) {
+	if sas.storageService == nil {
+		return
+	}
+	data, err := json.Marshal(analysis)
+	stored := &models.DBStockAnalysis{
+		Symbol:         analysis.Symbol,
+		Profile:        analysis.Profile,
+		CompositeScore: analysis.TradeSetup.CompositeScore,
+		Analysis:       string(data),
+	}
+	if err == nil {
+		err = sas.storageService.SaveStockAnalysis(stored)
+	}
+	if err != nil {
+		sas.logger.WithError(err).WithField("symbol", analysis.Symbol).Warn("Failed to record stock analysis")
+		return
+	}
+	if sas.semanticIndex != nil {
+		sas.semanticIndex.AddAnalysis(stored.ID, analysis)
+	}
+}
+
 // calculateTechnicalIndicators calculates technical indicators from historical bars
 func (sas *StockAnalysisService) calculateTechnicalIndicators(bars []*interfaces.Bar) TechnicalAnalysis {
 	if len(bars) == 0 {
@@ -250,6 +436,8 @@ func (sas *StockAnalysisService) calculateTechnicalIndicators(bars []*interfaces
 		}
 	}
 
+	tech.Patterns = PatternSummary(bars)
+
 	return tech
 }
 
@@ -326,13 +514,14 @@ func (sas *StockAnalysisService) estimateMarketCap(price float64, symbol string)
 	}
 }
 
-// generateTradeSetup creates neutral trade setup data for AI interpretation
-func (sas *StockAnalysisService) generateTradeSetup(tech TechnicalAnalysis, catalysts []string, currentPrice float64) TradeSetup {
+// generateTradeSetup creates neutral trade setup data for AI interpretation,
+// scoring only the indicators the profile includes
+func (sas *StockAnalysisService) generateTradeSetup(tech TechnicalAnalysis, catalysts []string, currentPrice float64, profile *AnalysisProfile) TradeSetup {
 	setup := TradeSetup{
 		Entry:        currentPrice,
-		StopLoss:     currentPrice * 0.85,  // Default 15% stop
-		TakeProfit:   currentPrice * 1.30,  // Default 30% target
-		RiskReward:   2.0,
+		StopLoss:     currentPrice * (1 - profile.StopLossPct/100),
+		TakeProfit:   currentPrice * (1 + profile.TakeProfitPct/100),
+		RiskReward:   profile.TakeProfitPct / profile.StopLossPct,
 		RecentNews:   catalysts,
 		KeyCatalysts: catalysts,
 	}
@@ -341,21 +530,25 @@ func (sas *StockAnalysisService) generateTradeSetup(tech TechnicalAnalysis, cata
 
 	// Technical Score (0-10)
 	technicalScore := 5 // Start neutral
-	if tech.Trend == "BULLISH" {
-		technicalScore += 2
-	} else if tech.Trend == "BEARISH" {
-		technicalScore -= 2
-	}
-	if tech.RSI > 30 && tech.RSI < 70 {
-		technicalScore += 1 // Healthy RSI range
-	}
-	if tech.RSI < 30 {
-		technicalScore += 2 // Oversold opportunity
+	if profile.uses(IndicatorTrend) {
+		if tech.Trend == "BULLISH" {
+			technicalScore += 2
+		} else if tech.Trend == "BEARISH" {
+			technicalScore -= 2
+		}
 	}
-	if tech.RSI > 75 {
-		technicalScore -= 2 // Overbought risk
+	if profile.uses(IndicatorRSI) {
+		if tech.RSI > 30 && tech.RSI < 70 {
+			technicalScore += 1 // Healthy RSI range
+		}
+		if tech.RSI < 30 {
+			technicalScore += 2 // Oversold opportunity
+		}
+		if tech.RSI > 75 {
+			technicalScore -= 2 // Overbought risk
+		}
 	}
-	if tech.Volatility > 15 {
+	if profile.uses(IndicatorVolatility) && tech.Volatility > 15 {
 		technicalScore += 1 // High volatility = opportunity for small-caps
 	}
 	setup.TechnicalScore = maxInt(0, minInt(10, technicalScore))
@@ -388,13 +581,36 @@ func (sas *StockAnalysisService) generateTradeSetup(tech TechnicalAnalysis, cata
 	}
 	setup.CatalystScore = catalystScore
 
-	// Composite Score (simple average)
-	setup.CompositeScore = (setup.TechnicalScore + setup.VolumeScore + setup.CatalystScore) / 3
+	// Composite Score (simple average, leaving out volume when the profile does)
+	if profile.uses(IndicatorVolume) {
+		setup.CompositeScore = (setup.TechnicalScore + setup.VolumeScore + setup.CatalystScore) / 3
+	} else {
+		setup.VolumeScore = 0
+		setup.CompositeScore = (setup.TechnicalScore + setup.CatalystScore) / 2
+	}
 
 	// Factual notes only
-	notes := fmt.Sprintf("Trend: %s | RSI: %.0f (%s) | Vol: %.1fx avg | Volatility: %.1f%%",
-		tech.Trend, tech.RSI, tech.PriceStrength, tech.VolumeRatio, tech.Volatility)
-	setup.Notes = notes
+	var notes []string
+	if profile.uses(IndicatorTrend) {
+		notes = append(notes, "Trend: "+tech.Trend)
+	}
+	if profile.uses(IndicatorRSI) {
+		notes = append(notes, fmt.Sprintf("RSI: %.0f (%s)", tech.RSI, tech.PriceStrength))
+	}
+	if profile.uses(IndicatorVolume) {
+		notes = append(notes, fmt.Sprintf("Vol: %.1fx avg", tech.VolumeRatio))
+	}
+	if profile.uses(IndicatorVolatility) {
+		notes = append(notes, fmt.Sprintf("Volatility: %.1f%%", tech.Volatility))
+	}
+	if len(tech.Patterns) > 0 {
+		notes = append(notes, "Patterns: "+strings.Join(tech.Patterns, ", "))
+	}
+	if setup.CompositeScore < profile.MinConfidence {
+		setup.BelowThreshold = true
+		notes = append(notes, fmt.Sprintf("Composite %d below profile %s minimum %d", setup.CompositeScore, profile.Name, profile.MinConfidence))
+	}
+	setup.Notes = strings.Join(notes, " | ")
 
 	return setup
 }
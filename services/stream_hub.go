@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"prophet-trader/interfaces"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MarketTick is the latest market state for one symbol
+type MarketTick struct {
+	Symbol    string
+	Price     float64
+	Bar       *interfaces.Bar // latest minute bar, nil if unavailable
+	Timestamp time.Time
+}
+
+// StreamHub fans out market updates for tracked symbols to subscribers.
+// Updates are produced by polling the data service, since StreamBars is
+// not backed by a websocket yet.
+type StreamHub struct {
+	dataService interfaces.DataService
+	symbols     map[string]int // reference counts
+	subscribers map[int]chan *MarketTick
+	nextID      int
+	mu          sync.RWMutex
+	logger      *logrus.Logger
+}
+
+// NewStreamHub creates a new stream hub
+func NewStreamHub(dataService interfaces.DataService) *StreamHub {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &StreamHub{
+		dataService: dataService,
+		symbols:     make(map[string]int),
+		subscribers: make(map[int]chan *MarketTick),
+		logger:      logger,
+	}
+}
+
+// Track adds symbols to the polled set
+func (h *StreamHub) Track(symbols ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, symbol := range symbols {
+		h.symbols[strings.ToUpper(symbol)]++
+	}
+}
+
+// Untrack releases symbols added with Track
+func (h *StreamHub) Untrack(symbols ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(symbol)
+		if h.symbols[symbol] <= 1 {
+			delete(h.symbols, symbol)
+		} else {
+			h.symbols[symbol]--
+		}
+	}
+}
+
+// Subscribe returns a channel receiving ticks for every tracked symbol.
+// Slow subscribers miss ticks rather than blocking the hub.
+func (h *StreamHub) Subscribe(buffer int) (int, <-chan *MarketTick) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	ch := make(chan *MarketTick, buffer)
+	h.subscribers[h.nextID] = ch
+	return h.nextID, ch
+}
+
+// Unsubscribe closes a subscription
+func (h *StreamHub) Unsubscribe(id int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ch, ok := h.subscribers[id]; ok {
+		close(ch)
+		delete(h.subscribers, id)
+	}
+}
+
+// Poll fetches the latest trade of every tracked symbol and delivers the
+// ticks to subscribers
+func (h *StreamHub) Poll(ctx context.Context) {
+	h.mu.RLock()
+	symbols := make([]string, 0, len(h.symbols))
+	for symbol := range h.symbols {
+		symbols = append(symbols, symbol)
+	}
+	h.mu.RUnlock()
+
+	for _, symbol := range symbols {
+		trade, err := h.dataService.GetLatestTrade(ctx, symbol)
+		if err != nil {
+			h.logger.WithError(err).WithField("symbol", symbol).Debug("Failed to poll latest trade")
+			continue
+		}
+
+		tick := &MarketTick{
+			Symbol:    symbol,
+			Price:     trade.Price,
+			Timestamp: trade.Timestamp,
+		}
+		if bar, err := h.dataService.GetLatestBar(ctx, symbol); err == nil {
+			tick.Bar = bar
+		}
+
+		h.publish(tick)
+	}
+}
+
+func (h *StreamHub) publish(tick *MarketTick) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, ch := range h.subscribers {
+		select {
+		case ch <- tick:
+		default:
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/database"
+	"prophet-trader/interfaces"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StressScenario is a hypothetical shock applied to every open position
+type StressScenario struct {
+	Name           string             `json:"name" binding:"required,max=64"`
+	MarketShockPct float64            `json:"market_shock_percent" binding:"gte=-100"`                                               // underlying price move, e.g. -5
+	IVShockPct     float64            `json:"iv_shock_percent" binding:"gte=-100"`                                                   // relative implied volatility change, e.g. 50
+	SymbolShocks   map[string]float64 `json:"symbol_shocks_percent,omitempty" binding:"omitempty,dive,keys,symbol,endkeys,gte=-100"` // per-underlying overrides of MarketShockPct
+}
+
+// DefaultStressScenarios are used when a request does not supply its own
+var DefaultStressScenarios = []StressScenario{
+	{Name: "market_gap_down_5", MarketShockPct: -5},
+	{Name: "market_gap_up_5", MarketShockPct: 5},
+	{Name: "market_crash_10", MarketShockPct: -10},
+	{Name: "iv_spike_50", IVShockPct: 50},
+	{Name: "gap_down_iv_spike", MarketShockPct: -5, IVShockPct: 50},
+}
+
+// StressRequest configures a stress test run
+type StressRequest struct {
+	Scenarios    []StressScenario `json:"scenarios" binding:"omitempty,max=50,dive"`
+	Confidence   float64          `json:"confidence" binding:"omitempty,gt=0,lt=1"`          // VaR confidence level, default 0.95
+	LookbackDays int              `json:"lookback_days" binding:"omitempty,min=20,max=2520"` // history for VaR, default 252
+}
+
+// PositionStress is the estimated P&L of one position under a scenario
+type PositionStress struct {
+	Symbol      string  `json:"symbol"`
+	Underlying  string  `json:"underlying"`
+	AssetClass  string  `json:"asset_class"`
+	MarketValue float64 `json:"market_value"`
+	PriceShock  float64 `json:"price_shock_percent"`
+	PnL         float64 `json:"pnl"`
+	Method      string  `json:"method"` // "linear", "greeks" or "unavailable"
+}
+
+// ScenarioResult is the portfolio impact of one scenario
+type ScenarioResult struct {
+	Scenario  StressScenario   `json:"scenario"`
+	PnL       float64          `json:"pnl"`
+	PnLPct    float64          `json:"pnl_percent"` // of portfolio value
+	Positions []PositionStress `json:"positions"`
+}
+
+// HistoricalVaR is the one-day value at risk from replaying past returns
+type HistoricalVaR struct {
+	Confidence        float64 `json:"confidence"`
+	LookbackDays      int     `json:"lookback_days"`
+	Observations      int     `json:"observations"`
+	VaR               float64 `json:"var"`                // loss not exceeded at the confidence level
+	VaRPct            float64 `json:"var_percent"`        // of portfolio value
+	ExpectedShortfall float64 `json:"expected_shortfall"` // average loss beyond VaR
+	WorstDay          float64 `json:"worst_day"`
+}
+
+// StressReport is the result of a stress test
+type StressReport struct {
+	GeneratedAt           time.Time        `json:"generated_at"`
+	PortfolioValue        float64          `json:"portfolio_value"`
+	GrossExposure         float64          `json:"gross_exposure"`
+	NetExposure           float64          `json:"net_exposure"`
+	DeltaAdjustedExposure float64          `json:"delta_adjusted_exposure"`
+	Scenarios             []ScenarioResult `json:"scenarios"`
+	WorstScenario         string           `json:"worst_scenario"`
+	VaR                   *HistoricalVaR   `json:"var,omitempty"`
+	Warnings              []string         `json:"warnings,omitempty"`
+}
+
+// stressPosition is a position with the inputs needed to reprice it
+type stressPosition struct {
+	symbol      string
+	underlying  string
+	assetClass  string
+	qty         float64 // signed, negative for shorts
+	marketValue float64
+	spot        float64 // underlying price
+	contract    *interfaces.OptionContract
+}
+
+// StressTestService estimates portfolio losses under shock scenarios and
+// from historical returns
+type StressTestService struct {
+	tradingService interfaces.TradingService
+	dataService    interfaces.DataService
+	optionsData    *AlpacaOptionsDataService
+	storageService *database.LocalStorage
+	logger         *logrus.Logger
+}
+
+// NewStressTestService creates a new stress test service
+func NewStressTestService(
+	tradingService interfaces.TradingService,
+	dataService interfaces.DataService,
+	optionsData *AlpacaOptionsDataService,
+	storageService *database.LocalStorage,
+) *StressTestService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &StressTestService{
+		tradingService: tradingService,
+		dataService:    dataService,
+		optionsData:    optionsData,
+		storageService: storageService,
+		logger:         logger,
+	}
+}
+
+// Run applies every scenario to the current positions and computes historical VaR
+func (s *StressTestService) Run(ctx context.Context, req *StressRequest) (*StressReport, error) {
+	scenarios := req.Scenarios
+	if len(scenarios) == 0 {
+		scenarios = DefaultStressScenarios
+	}
+	confidence := req.Confidence
+	if confidence <= 0 || confidence >= 1 {
+		confidence = 0.95
+	}
+	lookback := req.LookbackDays
+	if lookback <= 0 {
+		lookback = 252
+	}
+
+	account, err := s.tradingService.GetAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	positions, err := s.loadPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StressReport{
+		GeneratedAt:    time.Now(),
+		PortfolioValue: account.PortfolioValue.InexactFloat64(),
+		Scenarios:      make([]ScenarioResult, 0, len(scenarios)),
+	}
+	for _, p := range positions {
+		report.GrossExposure += math.Abs(p.marketValue)
+		report.NetExposure += p.marketValue
+		report.DeltaAdjustedExposure += deltaExposure(p)
+		if p.assetClass == "us_option" && p.contract == nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("no greeks for %s, option excluded from scenarios", p.symbol))
+		}
+	}
+
+	worst := math.Inf(1)
+	for i, scenario := range scenarios {
+		if scenario.Name == "" {
+			scenario.Name = fmt.Sprintf("scenario_%d", i+1)
+		}
+		result := applyScenario(scenario, positions, report.PortfolioValue)
+		if result.PnL < worst {
+			worst = result.PnL
+			report.WorstScenario = scenario.Name
+		}
+		report.Scenarios = append(report.Scenarios, result)
+	}
+
+	if len(positions) > 0 {
+		v, err := s.historicalVaR(ctx, positions, confidence, lookback, report.PortfolioValue)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("historical VaR unavailable: %v", err))
+		} else {
+			report.VaR = v
+		}
+	}
+
+	return report, nil
+}
+
+// loadPositions fetches positions along with underlying prices and option greeks
+func (s *StressTestService) loadPositions(ctx context.Context) ([]*stressPosition, error) {
+	positions, err := s.tradingService.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	result := make([]*stressPosition, 0, len(positions))
+	for _, p := range positions {
+		qty := p.Qty.Abs().InexactFloat64()
+		mv := p.MarketValue.Abs().InexactFloat64()
+		if p.Side == "short" {
+			qty, mv = -qty, -mv
+		}
+
+		sp := &stressPosition{
+			symbol:      p.Symbol,
+			underlying:  underlyingSymbol(p.Symbol),
+			assetClass:  assetClassFor(p.Symbol),
+			qty:         qty,
+			marketValue: mv,
+			spot:        p.CurrentPrice.InexactFloat64(),
+		}
+
+		if sp.assetClass == "us_option" {
+			if trade, err := s.dataService.GetLatestTrade(ctx, sp.underlying); err == nil {
+				sp.spot = trade.Price
+			}
+			if s.optionsData != nil {
+				contract, err := s.optionsData.GetOptionSnapshot(ctx, p.Symbol)
+				if err != nil {
+					s.logger.WithError(err).WithField("symbol", p.Symbol).Warn("Failed to get option greeks for stress test")
+				} else {
+					sp.contract = contract
+				}
+			}
+		}
+
+		result = append(result, sp)
+	}
+
+	return result, nil
+}
+
+// applyScenario reprices every position under a scenario. Equities move
+// linearly with the shock; options use a delta-gamma-vega approximation.
+func applyScenario(scenario StressScenario, positions []*stressPosition, portfolioValue float64) ScenarioResult {
+	result := ScenarioResult{
+		Scenario:  scenario,
+		Positions: make([]PositionStress, 0, len(positions)),
+	}
+
+	for _, p := range positions {
+		shock := scenario.MarketShockPct
+		if override, ok := scenario.SymbolShocks[strings.ToUpper(p.underlying)]; ok {
+			shock = override
+		}
+
+		ps := PositionStress{
+			Symbol:      p.symbol,
+			Underlying:  p.underlying,
+			AssetClass:  p.assetClass,
+			MarketValue: p.marketValue,
+			PriceShock:  shock,
+		}
+
+		switch {
+		case p.assetClass != "us_option":
+			ps.PnL = p.marketValue * shock / 100
+			ps.Method = "linear"
+		case p.contract != nil:
+			dS := p.spot * shock / 100
+			// Vega is per one volatility point; the IV shock is relative
+			dVolPoints := p.contract.ImpliedVolatility * scenario.IVShockPct
+			perContract := p.contract.Delta*dS + 0.5*p.contract.Gamma*dS*dS + p.contract.Vega*dVolPoints
+			ps.PnL = perContract * 100 * p.qty
+			ps.Method = "greeks"
+		default:
+			ps.Method = "unavailable"
+		}
+
+		result.PnL += ps.PnL
+		result.Positions = append(result.Positions, ps)
+	}
+
+	if portfolioValue > 0 {
+		result.PnLPct = result.PnL / portfolioValue * 100
+	}
+
+	// Largest loss first
+	sort.Slice(result.Positions, func(i, j int) bool {
+		return result.Positions[i].PnL < result.Positions[j].PnL
+	})
+
+	return result
+}
+
+// deltaExposure is the dollar exposure to a 100% move in the underlying
+func deltaExposure(p *stressPosition) float64 {
+	if p.assetClass != "us_option" {
+		return p.marketValue
+	}
+	if p.contract == nil {
+		return 0
+	}
+	return p.contract.Delta * 100 * p.qty * p.spot
+}
+
+// historicalVaR replays daily underlying returns against today's delta
+// exposures and takes the loss percentile
+func (s *StressTestService) historicalVaR(ctx context.Context, positions []*stressPosition, confidence float64, lookback int, portfolioValue float64) (*HistoricalVaR, error) {
+	end := time.Now()
+	// Calendar days to cover the requested number of trading days
+	start := end.AddDate(0, 0, -lookback*365/252-7)
+
+	exposures := make(map[string]float64)
+	for _, p := range positions {
+		exposures[p.underlying] += deltaExposure(p)
+	}
+
+	returns := make(map[string]map[string]float64, len(exposures))
+	for underlying := range exposures {
+		closes, err := s.dailyCloses(ctx, underlying, start, end)
+		if err != nil {
+			return nil, err
+		}
+		returns[underlying] = returnsByDate(closes, sortedDates(closes))
+	}
+
+	// Only days every underlying traded contribute a scenario, most recent first
+	var reference map[string]float64
+	for _, r := range returns {
+		reference = r
+		break
+	}
+	dates := sortedDates(reference)
+	pnls := make([]float64, 0, lookback)
+	for i := len(dates) - 1; i >= 0 && len(pnls) < lookback; i-- {
+		pnl := 0.0
+		complete := true
+		for underlying, exposure := range exposures {
+			r, ok := returns[underlying][dates[i]]
+			if !ok {
+				complete = false
+				break
+			}
+			pnl += exposure * r
+		}
+		if complete {
+			pnls = append(pnls, pnl)
+		}
+	}
+	if len(pnls) < 20 {
+		return nil, fmt.Errorf("only %d days of overlapping history", len(pnls))
+	}
+	sort.Float64s(pnls)
+
+	index := int(math.Floor((1 - confidence) * float64(len(pnls))))
+	if index >= len(pnls) {
+		index = len(pnls) - 1
+	}
+
+	v := &HistoricalVaR{
+		Confidence:   confidence,
+		LookbackDays: lookback,
+		Observations: len(pnls),
+		VaR:          math.Max(-pnls[index], 0),
+		WorstDay:     pnls[0],
+	}
+	tail := pnls[:index+1]
+	v.ExpectedShortfall = math.Max(-average(tail), 0)
+	if portfolioValue > 0 {
+		v.VaRPct = v.VaR / portfolioValue * 100
+	}
+
+	return v, nil
+}
+
+// dailyCloses returns closing prices keyed by date, preferring bars already
+// stored locally and falling back to the data service when they are sparse
+func (s *StressTestService) dailyCloses(ctx context.Context, symbol string, start, end time.Time) (map[string]float64, error) {
+	closes := make(map[string]float64)
+
+	if s.storageService != nil {
+		bars, err := s.storageService.GetBars(symbol, start, end)
+		if err != nil {
+			s.logger.WithError(err).WithField("symbol", symbol).Warn("Failed to read stored bars")
+		}
+		// Bars are ascending, so the last bar of each day wins
+		for _, bar := range bars {
+			closes[bar.Timestamp.Format("2006-01-02")] = bar.Close
+		}
+	}
+
+	expectedDays := int(end.Sub(start).Hours() / 24 * 252 / 365)
+	if len(closes) >= expectedDays*8/10 {
+		return closes, nil
+	}
+
+	bars, err := s.dataService.GetHistoricalBars(ctx, symbol, start, end, "1Day")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bars for %s: %w", symbol, err)
+	}
+	for _, bar := range bars {
+		closes[bar.Timestamp.Format("2006-01-02")] = bar.Close
+	}
+
+	return closes, nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SymbolAlias maps a ticker that changed to the symbol it trades under now
+type SymbolAlias struct {
+	OldSymbol     string           `json:"old_symbol"`
+	NewSymbol     string           `json:"new_symbol"`
+	EffectiveDate string           `json:"effective_date,omitempty"`
+	Note          string           `json:"note,omitempty"`
+	Migrated      map[string]int64 `json:"migrated"`                    // rows moved to NewSymbol, by table
+	Positions     []string         `json:"managed_positions,omitempty"` // open managed positions renamed
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// SymbolAliasRequest records a ticker change
+type SymbolAliasRequest struct {
+	OldSymbol     string `json:"old_symbol" binding:"required,symbol"`
+	NewSymbol     string `json:"new_symbol" binding:"required,symbol"`
+	EffectiveDate string `json:"effective_date"` // YYYY-MM-DD
+	Note          string `json:"note"`
+}
+
+// SymbolAliasService keeps the map of tickers that changed, through a
+// rename such as FB to META or a merger, and moves stored history and
+// managed positions over to the new symbol when one is added
+type SymbolAliasService struct {
+	storage         *database.LocalStorage
+	positionManager *PositionManager
+	notifier        *Notifier
+
+	mu      sync.RWMutex
+	aliases map[string]string // old symbol to new symbol
+
+	logger *logrus.Logger
+}
+
+// NewSymbolAliasService creates a symbol alias service with the aliases
+// stored so far
+func NewSymbolAliasService(storage *database.LocalStorage, positionManager *PositionManager, notifier *Notifier) *SymbolAliasService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	s := &SymbolAliasService{
+		storage:         storage,
+		positionManager: positionManager,
+		notifier:        notifier,
+		aliases:         make(map[string]string),
+		logger:          logger,
+	}
+
+	aliases, err := storage.GetSymbolAliases()
+	if err != nil {
+		logger.WithError(err).Error("Failed to load symbol aliases")
+	}
+	for _, alias := range aliases {
+		s.aliases[alias.OldSymbol] = alias.NewSymbol
+	}
+
+	return s
+}
+
+// Resolve returns the symbol a ticker trades under now, following chained
+// changes, and whether it was an alias
+func (s *SymbolAliasService) Resolve(symbol string) (string, bool) {
+	if s == nil {
+		return symbol, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.resolve(symbol)
+}
+
+// resolve follows symbol's aliases. The caller holds s.mu.
+func (s *SymbolAliasService) resolve(symbol string) (string, bool) {
+	resolved := strings.ToUpper(symbol)
+	// Adding an alias refuses cycles, so the bound only guards bad rows
+	for i := 0; i <= len(s.aliases); i++ {
+		next, ok := s.aliases[resolved]
+		if !ok {
+			break
+		}
+		resolved = next
+	}
+	if resolved == strings.ToUpper(symbol) {
+		return symbol, false
+	}
+	return resolved, true
+}
+
+// Add records a ticker change and moves the old symbol's stored orders,
+// fills, trades, position snapshots, bars, signals, analyses, filings and
+// account activities to the new one, along with its managed positions.
+// Alerts, DCA plans, conditional and algo orders, grids and target
+// allocations are left on the old symbol.
+func (s *SymbolAliasService) Add(ctx context.Context, req *SymbolAliasRequest) (*SymbolAlias, error) {
+	oldSymbol, newSymbol := strings.ToUpper(req.OldSymbol), strings.ToUpper(req.NewSymbol)
+	if req.EffectiveDate != "" {
+		if _, err := time.Parse(marketDateFormat, req.EffectiveDate); err != nil {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("effective_date must be YYYY-MM-DD: %w", err))
+		}
+	}
+	if oldSymbol == newSymbol {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("old_symbol and new_symbol must differ"))
+	}
+
+	// Held throughout, so two changes can't both pass the cycle checks
+	s.mu.Lock()
+	alias, err := s.add(oldSymbol, newSymbol, req)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	positions := alias.Positions
+	moved := alias.Migrated
+
+	fields := map[string]interface{}{
+		"old_symbol":        oldSymbol,
+		"new_symbol":        newSymbol,
+		"managed_positions": len(positions),
+	}
+	for table, rows := range moved {
+		fields[table] = rows
+	}
+	s.logger.WithFields(logrus.Fields(fields)).Info("Migrated symbol history")
+	s.notifier.Notify(ctx, NotifyInfo, "Symbol changed",
+		fmt.Sprintf("%s now trades as %s: moved its stored history and %d managed position(s)", oldSymbol, newSymbol, len(positions)),
+		fields)
+
+	return alias, nil
+}
+
+// add checks a ticker change against the stored aliases, migrates the
+// stored history and then renames the managed positions. The caller holds
+// s.mu.
+func (s *SymbolAliasService) add(oldSymbol, newSymbol string, req *SymbolAliasRequest) (*SymbolAlias, error) {
+	if resolved, ok := s.resolve(oldSymbol); ok {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("%s is already an alias of %s", oldSymbol, resolved))
+	}
+	if resolved, _ := s.resolve(newSymbol); resolved == oldSymbol {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("%s already resolves to %s", newSymbol, oldSymbol))
+	}
+
+	dbAlias := &models.DBSymbolAlias{
+		OldSymbol:     oldSymbol,
+		NewSymbol:     newSymbol,
+		EffectiveDate: req.EffectiveDate,
+		Note:          req.Note,
+	}
+	moved, err := s.storage.MigrateSymbol(dbAlias)
+	if err != nil {
+		return nil, err
+	}
+	s.aliases[oldSymbol] = newSymbol
+
+	// Managed positions are renamed only once the migration has committed.
+	// Each one is saved again, so a save by the monitor in between doesn't
+	// leave the old symbol stored.
+	alias := symbolAliasFromDB(dbAlias)
+	alias.Migrated = moved
+	if s.positionManager != nil {
+		alias.Positions = s.positionManager.RenameSymbol(oldSymbol, newSymbol)
+	}
+	return alias, nil
+}
+
+// List returns every alias, oldest first
+func (s *SymbolAliasService) List() ([]*SymbolAlias, error) {
+	dbAliases, err := s.storage.GetSymbolAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := make([]*SymbolAlias, len(dbAliases))
+	for i, dbAlias := range dbAliases {
+		aliases[i] = symbolAliasFromDB(dbAlias)
+	}
+	return aliases, nil
+}
+
+func symbolAliasFromDB(dbAlias *models.DBSymbolAlias) *SymbolAlias {
+	alias := &SymbolAlias{
+		OldSymbol:     dbAlias.OldSymbol,
+		NewSymbol:     dbAlias.NewSymbol,
+		EffectiveDate: dbAlias.EffectiveDate,
+		Note:          dbAlias.Note,
+		Migrated:      make(map[string]int64),
+		CreatedAt:     dbAlias.CreatedAt,
+	}
+	if dbAlias.Migrated != "" {
+		_ = json.Unmarshal([]byte(dbAlias.Migrated), &alias.Migrated)
+	}
+	return alias
+}
+
+// RenameSymbol moves the managed positions held in memory from oldSymbol to
+// newSymbol, each under its lock so the monitor never sees the change half
+// made. Their broker orders keep their IDs through a ticker change, so they
+// are left in place. It returns the renamed position IDs.
+func (pm *PositionManager) RenameSymbol(oldSymbol, newSymbol string) []string {
+	renamed := make([]string, 0)
+	for _, position := range pm.allPositions() {
+		held := func(position *ManagedPosition) bool {
+			return position.Symbol == oldSymbol
+		}
+		ok := pm.repairPosition(position.ID, held, func(position *ManagedPosition) {
+			position.Symbol = newSymbol
+			position.UpdatedAt = time.Now()
+			if position.Notes != "" {
+				position.Notes += "; "
+			}
+			position.Notes += fmt.Sprintf("renamed from %s", oldSymbol)
+
+			if err := pm.savePositionToDB(position); err != nil {
+				pm.logger.WithError(err).WithField("position_id", position.ID).Error("Failed to save renamed position")
+			}
+		})
+		if ok {
+			renamed = append(renamed, position.ID)
+		}
+	}
+	return renamed
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/database"
+	"prophet-trader/models"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// symbolOrderTypes are the order types a symbol rule can allow
+var symbolOrderTypes = map[string]bool{
+	"market":        true,
+	"limit":         true,
+	"stop":          true,
+	"stop_limit":    true,
+	"trailing_stop": true,
+}
+
+// SymbolRule is the set of trading restrictions for one symbol. Options
+// follow the rule of their underlying, except for the position and price
+// limits, which apply to shares only.
+type SymbolRule struct {
+	Symbol            string          `json:"symbol"`
+	Blocked           bool            `json:"blocked"`                       // never open or add to a position
+	MaxPositionQty    decimal.Decimal `json:"max_position_qty"`              // shares, zero for no limit
+	MaxPositionValue  decimal.Decimal `json:"max_position_value"`            // dollars, zero for no limit
+	AllowedOrderTypes []string        `json:"allowed_order_types,omitempty"` // empty allows all
+	MinPrice          decimal.Decimal `json:"min_price"`                     // zero uses the configured default
+	LongOnly          bool            `json:"long_only"`
+	Note              string          `json:"note,omitempty"`
+}
+
+// EffectiveSymbolRule is a symbol's stored rule combined with the
+// configured blocklist and minimum price
+type EffectiveSymbolRule struct {
+	SymbolRule
+	Stored        bool   `json:"stored"` // false when only the configuration applies
+	BlockedReason string `json:"blocked_reason,omitempty"`
+}
+
+// SymbolRuleService keeps the per-symbol trading rules. The configured
+// blocklist and minimum price apply to every symbol; stored rules add to
+// them, and a stored minimum price overrides the configured one.
+type SymbolRuleService struct {
+	storage   *database.LocalStorage
+	blocklist map[string]bool
+	minPrice  decimal.Decimal
+
+	mu    sync.RWMutex
+	rules map[string]*SymbolRule
+
+	logger *logrus.Logger
+}
+
+// NewSymbolRuleService creates a symbol rule service with the stored rules
+// and the configured defaults
+func NewSymbolRuleService(storage *database.LocalStorage, blocklist []string, minPrice float64) *SymbolRuleService {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	s := &SymbolRuleService{
+		storage:   storage,
+		blocklist: make(map[string]bool),
+		minPrice:  decimal.NewFromFloat(minPrice),
+		rules:     make(map[string]*SymbolRule),
+		logger:    logger,
+	}
+	for _, symbol := range blocklist {
+		s.blocklist[strings.ToUpper(symbol)] = true
+	}
+
+	rules, err := storage.GetSymbolRules()
+	if err != nil {
+		logger.WithError(err).Error("Failed to load symbol rules")
+	}
+	for _, rule := range rules {
+		s.rules[rule.Symbol] = symbolRuleFromDB(rule)
+	}
+
+	return s
+}
+
+// Effective returns the rule that applies to symbol
+func (s *SymbolRuleService) Effective(symbol string) *EffectiveSymbolRule {
+	symbol = strings.ToUpper(symbol)
+	effective := &EffectiveSymbolRule{SymbolRule: SymbolRule{Symbol: symbol}}
+
+	s.mu.RLock()
+	if rule, ok := s.rules[symbol]; ok {
+		effective.SymbolRule = *rule
+		effective.Stored = true
+	}
+	s.mu.RUnlock()
+
+	if effective.Blocked {
+		effective.BlockedReason = "blocked by stored symbol rule"
+	}
+	if s.blocklist[symbol] {
+		effective.Blocked = true
+		effective.BlockedReason = "on the configured blocklist"
+	}
+	if !effective.MinPrice.IsPositive() {
+		effective.MinPrice = s.minPrice
+	}
+	return effective
+}
+
+// List returns the stored rules and the configured blocklist, by symbol
+func (s *SymbolRuleService) List() []*EffectiveSymbolRule {
+	s.mu.RLock()
+	symbols := make([]string, 0, len(s.rules)+len(s.blocklist))
+	for symbol := range s.rules {
+		symbols = append(symbols, symbol)
+	}
+	for symbol := range s.blocklist {
+		if _, ok := s.rules[symbol]; !ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Strings(symbols)
+
+	rules := make([]*EffectiveSymbolRule, len(symbols))
+	for i, symbol := range symbols {
+		rules[i] = s.Effective(symbol)
+	}
+	return rules
+}
+
+// Save creates or replaces the stored rule of a symbol
+func (s *SymbolRuleService) Save(rule *SymbolRule) (*EffectiveSymbolRule, error) {
+	rule.Symbol = strings.ToUpper(rule.Symbol)
+	if rule.Symbol == "" || IsOCCSymbol(rule.Symbol) {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("symbol rules are set on the underlying symbol"))
+	}
+	if rule.MaxPositionQty.IsNegative() || rule.MaxPositionValue.IsNegative() || rule.MinPrice.IsNegative() {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("limits must not be negative"))
+	}
+	for i, orderType := range rule.AllowedOrderTypes {
+		orderType = strings.ToLower(strings.TrimSpace(orderType))
+		if !symbolOrderTypes[orderType] {
+			return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("unknown order type %q: use market, limit, stop, stop_limit or trailing_stop", orderType))
+		}
+		rule.AllowedOrderTypes[i] = orderType
+	}
+
+	if err := s.storage.SaveSymbolRule(symbolRuleToDB(rule)); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.rules[rule.Symbol] = rule
+	s.mu.Unlock()
+
+	s.logger.WithField("symbol", rule.Symbol).Info("Saved symbol rule")
+	return s.Effective(rule.Symbol), nil
+}
+
+// Delete removes the stored rule of a symbol. The configured blocklist and
+// minimum price still apply.
+func (s *SymbolRuleService) Delete(symbol string) error {
+	symbol = strings.ToUpper(symbol)
+	s.mu.RLock()
+	_, ok := s.rules[symbol]
+	s.mu.RUnlock()
+	if !ok {
+		return WithErrorCode(ErrCodeNotFound, fmt.Errorf("no rule stored for %s", symbol))
+	}
+
+	if err := s.storage.DeleteSymbolRule(symbol); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.rules, symbol)
+	s.mu.Unlock()
+	return nil
+}
+
+func symbolRuleToDB(rule *SymbolRule) *models.DBSymbolRule {
+	return &models.DBSymbolRule{
+		Symbol:            rule.Symbol,
+		Blocked:           rule.Blocked,
+		MaxPositionQty:    rule.MaxPositionQty,
+		MaxPositionValue:  rule.MaxPositionValue,
+		AllowedOrderTypes: strings.Join(rule.AllowedOrderTypes, ","),
+		MinPrice:          rule.MinPrice,
+		LongOnly:          rule.LongOnly,
+		Note:              rule.Note,
+	}
+}
+
+func symbolRuleFromDB(dbRule *models.DBSymbolRule) *SymbolRule {
+	rule := &SymbolRule{
+		Symbol:           dbRule.Symbol,
+		Blocked:          dbRule.Blocked,
+		MaxPositionQty:   dbRule.MaxPositionQty,
+		MaxPositionValue: dbRule.MaxPositionValue,
+		MinPrice:         dbRule.MinPrice,
+		LongOnly:         dbRule.LongOnly,
+		Note:             dbRule.Note,
+	}
+	if dbRule.AllowedOrderTypes != "" {
+		rule.AllowedOrderTypes = strings.Split(dbRule.AllowedOrderTypes, ",")
+	}
+	return rule
+}
+
+// SymbolRulesRule enforces the per-symbol rules on orders that open or add
+// to a position. Orders that only reduce one always pass, so stops and exits
+// are never blocked by a rule added after the position was opened.
+type SymbolRulesRule struct {
+	Rules *SymbolRuleService
+}
+
+func (SymbolRulesRule) Name() string { return "symbol_rules" }
+
+func (r SymbolRulesRule) Check(ctx context.Context, check *RiskCheck) error {
+	symbol := strings.ToUpper(check.Order.Symbol)
+	if occ, err := ParseOCCSymbol(symbol); err == nil {
+		symbol = strings.ToUpper(occ.Underlying)
+	}
+	rule := r.Rules.Effective(symbol)
+
+	// The position in the traded instrument before and after the order,
+	// negative when short
+	held := heldQty(check)
+	after := held.Add(check.Order.Qty)
+	if check.Order.Side == "sell" {
+		after = held.Sub(check.Order.Qty)
+	}
+	if after.Abs().LessThanOrEqual(held.Abs()) && after.Sign()*held.Sign() >= 0 {
+		return nil
+	}
+	// Working orders on the same side count as if filled, so orders split
+	// in pieces, basket legs, grid levels and child orders can't each pass
+	// the limits alone
+	after = after.Add(workingQty(check))
+
+	if rule.Blocked {
+		return &RiskRejection{
+			Code:    RiskCodeSymbolBlocked,
+			Message: fmt.Sprintf("%s is %s and can't be traded", symbol, rule.BlockedReason),
+		}
+	}
+	if len(rule.AllowedOrderTypes) > 0 {
+		allowed := false
+		for _, orderType := range rule.AllowedOrderTypes {
+			if strings.EqualFold(orderType, check.Order.Type) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return &RiskRejection{
+				Code:    RiskCodeOrderTypeNotAllowed,
+				Message: fmt.Sprintf("%s orders are not allowed for %s (allowed: %s)", check.Order.Type, symbol, strings.Join(rule.AllowedOrderTypes, ", ")),
+			}
+		}
+	}
+
+	if rule.LongOnly && after.IsNegative() {
+		return &RiskRejection{
+			Code:    RiskCodeShortNotAllowed,
+			Message: fmt.Sprintf("%s is long only; the order would leave a short position of %s", symbol, after.Abs().String()),
+		}
+	}
+	if check.AssetClass == "us_option" {
+		return nil
+	}
+
+	if rule.MinPrice.IsPositive() && check.EstimatedPrice.IsPositive() && check.EstimatedPrice.LessThan(rule.MinPrice) {
+		return &RiskRejection{
+			Code:    RiskCodePriceBelowMinimum,
+			Message: fmt.Sprintf("%s at %s is below the minimum price %s", symbol, check.EstimatedPrice.StringFixed(2), rule.MinPrice.StringFixed(2)),
+		}
+	}
+	if rule.MaxPositionQty.IsPositive() && after.Abs().GreaterThan(rule.MaxPositionQty) {
+		return &RiskRejection{
+			Code:    RiskCodeMaxPositionExceeded,
+			Message: fmt.Sprintf("position in %s would be %s shares, above the limit of %s", symbol, after.Abs().String(), rule.MaxPositionQty.String()),
+		}
+	}
+	if rule.MaxPositionValue.IsPositive() && check.EstimatedPrice.IsPositive() {
+		value := after.Abs().Mul(check.EstimatedPrice)
+		if value.GreaterThan(rule.MaxPositionValue) {
+			return &RiskRejection{
+				Code:    RiskCodeMaxPositionExceeded,
+				Message: fmt.Sprintf("position in %s would be worth %s, above the limit of %s", symbol, value.StringFixed(2), rule.MaxPositionValue.StringFixed(2)),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"prophet-trader/interfaces"
+
+	"github.com/shopspring/decimal"
+)
+
+// newTestSymbolRules returns a symbol rule service holding rules in memory
+func newTestSymbolRules(rules []*SymbolRule, blocklist []string, minPrice float64) *SymbolRuleService {
+	s := &SymbolRuleService{
+		blocklist: make(map[string]bool),
+		minPrice:  decimal.NewFromFloat(minPrice),
+		rules:     make(map[string]*SymbolRule),
+	}
+	for _, symbol := range blocklist {
+		s.blocklist[symbol] = true
+	}
+	for _, rule := range rules {
+		s.rules[rule.Symbol] = rule
+	}
+	return s
+}
+
+func TestSymbolRulesRuleCheck(t *testing.T) {
+	rules := newTestSymbolRules([]*SymbolRule{
+		{Symbol: "GME", Blocked: true},
+		{Symbol: "TSLA", MaxPositionQty: decimal.NewFromInt(100), MaxPositionValue: decimal.NewFromInt(25000)},
+		{Symbol: "NVDA", AllowedOrderTypes: []string{"limit"}},
+		{Symbol: "AAPL", LongOnly: true},
+		{Symbol: "SIRI", MinPrice: decimal.NewFromInt(5)},
+	}, []string{"AMC"}, 1)
+
+	long := func(symbol string, qty int64) *interfaces.Position {
+		return &interfaces.Position{Symbol: symbol, Qty: decimal.NewFromInt(qty), Side: "long"}
+	}
+	working := func(symbol, side string, qty, filled int64) *interfaces.Order {
+		return &interfaces.Order{ID: "open_" + symbol + side, Symbol: symbol, Side: side, Qty: decimal.NewFromInt(qty), FilledQty: decimal.NewFromInt(filled), Status: "new"}
+	}
+
+	tests := []struct {
+		name       string
+		symbol     string
+		side       string
+		qty        int64
+		orderType  string
+		price      float64
+		assetClass string
+		positions  []*interfaces.Position
+		openOrders []*interfaces.Order
+		code       string // empty when the order passes
+		message    string // a part of the rejection message
+	}{
+		{name: "no rule", symbol: "MSFT", side: "buy", qty: 10, price: 400},
+		{name: "stored block", symbol: "GME", side: "buy", qty: 10, price: 20, code: RiskCodeSymbolBlocked, message: "GME is blocked by stored symbol rule"},
+		{name: "configured blocklist", symbol: "AMC", side: "buy", qty: 10, price: 5, code: RiskCodeSymbolBlocked, message: "AMC is on the configured blocklist"},
+		{name: "blocked symbol can still be sold down", symbol: "GME", side: "sell", qty: 10, price: 20, positions: []*interfaces.Position{long("GME", 30)}},
+		{name: "blocked symbol can't be reversed", symbol: "GME", side: "sell", qty: 40, price: 20, positions: []*interfaces.Position{long("GME", 30)}, code: RiskCodeSymbolBlocked},
+		{name: "option on a blocked underlying", symbol: "GME261120C00030000", side: "buy", qty: 1, price: 1, assetClass: "us_option", code: RiskCodeSymbolBlocked},
+		{name: "order type not allowed", symbol: "NVDA", side: "buy", qty: 10, orderType: "market", price: 100, code: RiskCodeOrderTypeNotAllowed},
+		{name: "allowed order type", symbol: "NVDA", side: "buy", qty: 10, orderType: "limit", price: 100},
+		{name: "long only sell within the position", symbol: "AAPL", side: "sell", qty: 10, price: 100, positions: []*interfaces.Position{long("AAPL", 10)}},
+		{name: "long only sell past the position", symbol: "AAPL", side: "sell", qty: 15, price: 100, positions: []*interfaces.Position{long("AAPL", 10)}, code: RiskCodeShortNotAllowed},
+		{name: "long only target beside a working stop", symbol: "AAPL", side: "sell", qty: 10, orderType: "limit", price: 110, positions: []*interfaces.Position{long("AAPL", 10)}, openOrders: []*interfaces.Order{working("AAPL", "sell", 10, 0)}},
+		{name: "long only sell past the position with a working stop", symbol: "AAPL", side: "sell", qty: 15, price: 100, positions: []*interfaces.Position{long("AAPL", 10)}, openOrders: []*interfaces.Order{working("AAPL", "sell", 10, 0)}, code: RiskCodeShortNotAllowed, message: "short position of 15"},
+		{name: "long only writing a call", symbol: "AAPL261120C00250000", side: "sell", qty: 1, price: 2, assetClass: "us_option", code: RiskCodeShortNotAllowed},
+		{name: "stored minimum price", symbol: "SIRI", side: "buy", qty: 100, price: 4.5, code: RiskCodePriceBelowMinimum},
+		{name: "configured minimum price", symbol: "PENNY", side: "buy", qty: 100, price: 0.5, code: RiskCodePriceBelowMinimum},
+		{name: "options skip the minimum price", symbol: "PENNY261120C00001000", side: "buy", qty: 1, price: 0.05, assetClass: "us_option"},
+		{name: "within the share limit", symbol: "TSLA", side: "buy", qty: 40, price: 200, positions: []*interfaces.Position{long("TSLA", 60)}},
+		{name: "over the share limit", symbol: "TSLA", side: "buy", qty: 41, price: 200, positions: []*interfaces.Position{long("TSLA", 60)}, code: RiskCodeMaxPositionExceeded, message: "would be 101 shares"},
+		{name: "working buys count toward the share limit", symbol: "TSLA", side: "buy", qty: 60, price: 200, openOrders: []*interfaces.Order{working("TSLA", "buy", 60, 0)}, code: RiskCodeMaxPositionExceeded, message: "would be 120 shares"},
+		{name: "filled part of a working buy counted once", symbol: "TSLA", side: "buy", qty: 40, price: 200, positions: []*interfaces.Position{long("TSLA", 30)}, openOrders: []*interfaces.Order{working("TSLA", "buy", 60, 30)}},
+		{name: "working sells don't count toward a buy", symbol: "TSLA", side: "buy", qty: 60, price: 200, openOrders: []*interfaces.Order{working("TSLA", "sell", 60, 0)}},
+		{name: "over the value limit", symbol: "TSLA", side: "buy", qty: 90, price: 300, code: RiskCodeMaxPositionExceeded, message: "would be worth 27000.00"},
+		{name: "options skip the position limits", symbol: "TSLA261120C00400000", side: "buy", qty: 500, price: 10, assetClass: "us_option"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderType, assetClass := tt.orderType, tt.assetClass
+			if orderType == "" {
+				orderType = "market"
+			}
+			if assetClass == "" {
+				assetClass = "us_equity"
+			}
+			check := &RiskCheck{
+				Order: &interfaces.Order{
+					Symbol: tt.symbol,
+					Qty:    decimal.NewFromInt(tt.qty),
+					Side:   tt.side,
+					Type:   orderType,
+				},
+				AssetClass:     assetClass,
+				EstimatedPrice: decimal.NewFromFloat(tt.price),
+				Positions:      tt.positions,
+				OpenOrders:     tt.openOrders,
+			}
+
+			err := SymbolRulesRule{Rules: rules}.Check(context.Background(), check)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("Check rejected the order: %v", err)
+				}
+				return
+			}
+
+			var rejection *RiskRejection
+			if !errors.As(err, &rejection) {
+				t.Fatalf("Check = %v, want a %s rejection", err, tt.code)
+			}
+			if rejection.Code != tt.code {
+				t.Errorf("code = %q, want %q (%s)", rejection.Code, tt.code, rejection.Message)
+			}
+			if !strings.Contains(rejection.Message, tt.message) {
+				t.Errorf("message %q doesn't mention %q", rejection.Message, tt.message)
+			}
+		})
+	}
+}
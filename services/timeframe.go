@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeframeUnit is the unit of a bar timeframe
+type TimeframeUnit string
+
+// Timeframe units, named as in canonical timeframes such as "5Min"
+const (
+	TimeframeMinute TimeframeUnit = "Min"
+	TimeframeHour   TimeframeUnit = "Hour"
+	TimeframeDay    TimeframeUnit = "Day"
+	TimeframeWeek   TimeframeUnit = "Week"
+	TimeframeMonth  TimeframeUnit = "Month"
+)
+
+// Timeframe is a bar size: Amount of Unit
+type Timeframe struct {
+	Amount int
+	Unit   TimeframeUnit
+}
+
+// String formats the timeframe canonically, such as "5Min" or "1Day"
+func (t Timeframe) String() string {
+	return strconv.Itoa(t.Amount) + string(t.Unit)
+}
+
+// Duration is the length of one bar, taking a month as 30 days
+func (t Timeframe) Duration() time.Duration {
+	unit := map[TimeframeUnit]time.Duration{
+		TimeframeMinute: time.Minute,
+		TimeframeHour:   time.Hour,
+		TimeframeDay:    24 * time.Hour,
+		TimeframeWeek:   7 * 24 * time.Hour,
+		TimeframeMonth:  30 * 24 * time.Hour,
+	}[t.Unit]
+	return time.Duration(t.Amount) * unit
+}
+
+// TimeframeUnitInfo describes how a unit may be written and the amounts
+// the data providers accept for it
+type TimeframeUnitInfo struct {
+	Unit    TimeframeUnit `json:"unit"`
+	Amounts []int         `json:"amounts"`
+	Aliases []string      `json:"aliases"`
+}
+
+// timeframeUnits lists the units in order. Aliases match in any case,
+// except that "m" is minutes and "M" months.
+var timeframeUnits = []TimeframeUnitInfo{
+	{TimeframeMinute, timeframeRange(1, 59), []string{"Min", "m", "min", "mins", "minute", "minutes"}},
+	{TimeframeHour, timeframeRange(1, 23), []string{"Hour", "h", "hr", "hrs", "hour", "hours"}},
+	{TimeframeDay, []int{1}, []string{"Day", "D", "d", "day", "days"}},
+	{TimeframeWeek, []int{1}, []string{"Week", "W", "w", "wk", "week", "weeks"}},
+	{TimeframeMonth, []int{1, 2, 3, 4, 6, 12}, []string{"Month", "M", "mo", "mon", "month", "months"}},
+}
+
+// CommonTimeframes are the timeframes used across the bot, which every
+// data provider serves
+var CommonTimeframes = []string{"1Min", "5Min", "15Min", "30Min", "1Hour", "4Hour", "1Day", "1Week", "1Month"}
+
+var timeframePattern = regexp.MustCompile(`^(\d*)\s*([A-Za-z]+)$`)
+
+// TimeframeUnits describes every unit with its amounts and aliases
+func TimeframeUnits() []TimeframeUnitInfo {
+	return timeframeUnits
+}
+
+// ParseTimeframe parses a timeframe such as "5Min", "1h", "1D" or "W". The
+// amount defaults to 1. Unknown units and amounts a provider would reject
+// are an invalid request error.
+func ParseTimeframe(s string) (Timeframe, error) {
+	m := timeframePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Timeframe{}, invalidTimeframe(s)
+	}
+	amount := 1
+	if m[1] != "" {
+		var err error
+		if amount, err = strconv.Atoi(m[1]); err != nil {
+			return Timeframe{}, invalidTimeframe(s)
+		}
+	}
+
+	alias := m[2]
+	if alias != "m" && alias != "M" {
+		alias = strings.ToLower(alias)
+	}
+	for _, info := range timeframeUnits {
+		for _, a := range info.Aliases {
+			if a != alias && !(len(a) > 1 && strings.ToLower(a) == alias) {
+				continue
+			}
+			for _, allowed := range info.Amounts {
+				if amount == allowed {
+					return Timeframe{Amount: amount, Unit: info.Unit}, nil
+				}
+			}
+			return Timeframe{}, invalidTimeframe(s)
+		}
+	}
+	return Timeframe{}, invalidTimeframe(s)
+}
+
+// NormalizeTimeframe parses a timeframe and formats it canonically
+func NormalizeTimeframe(s string) (string, error) {
+	tf, err := ParseTimeframe(s)
+	if err != nil {
+		return "", err
+	}
+	return tf.String(), nil
+}
+
+func invalidTimeframe(s string) error {
+	return WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf(
+		"invalid timeframe %q: use 1-59Min, 1-23Hour, 1Day, 1Week or 1, 2, 3, 4, 6 or 12Month (GET /api/v1/market/timeframes lists the aliases)", s))
+}
+
+func timeframeRange(from, to int) []int {
+	amounts := make([]int, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		amounts = append(amounts, i)
+	}
+	return amounts
+}
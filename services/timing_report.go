@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Trade times used to bucket the timing report
+const (
+	TimingByEntry = "entry"
+	TimingByExit  = "exit"
+)
+
+// TimingBucket aggregates the closed trades that fall in one time slot
+type TimingBucket struct {
+	Label       string  `json:"label"` // "09:30" or "Monday"
+	Trades      int     `json:"trades"`
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	WinRate     float64 `json:"win_rate"`
+	RealizedPnL float64 `json:"realized_pnl"`
+	AvgPnL      float64 `json:"avg_pnl"`
+
+	order int // sort position: minute of day or weekday
+}
+
+// TimingReport buckets realized P&L by time of day and day of week
+type TimingReport struct {
+	Start         time.Time       `json:"start"`
+	End           time.Time       `json:"end"`
+	Book          string          `json:"book,omitempty"`
+	By            string          `json:"by"` // "entry" or "exit"
+	Timezone      string          `json:"timezone"`
+	BucketMinutes int             `json:"bucket_minutes"`
+	Trades        int             `json:"trades"`
+	TimeOfDay     []*TimingBucket `json:"time_of_day"`
+	DayOfWeek     []*TimingBucket `json:"day_of_week"`
+}
+
+// TimingPerformance buckets the trades closed in the window by the market
+// time of their entry or exit. Only slots with trades are listed. When ctx
+// selects a book only that book's trades are counted.
+func (rs *ReportingService) TimingPerformance(ctx context.Context, start, end time.Time, by string, bucketMinutes int) (*TimingReport, error) {
+	if by != TimingByEntry && by != TimingByExit {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("by must be %q or %q", TimingByEntry, TimingByExit))
+	}
+	if bucketMinutes <= 0 || 24*60%bucketMinutes != 0 {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("bucket_minutes must divide a day evenly, e.g. 15, 30 or 60"))
+	}
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.UTC
+	}
+
+	trades, err := rs.storageService.GetTrades(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	book, hasBook := SelectedBook(ctx)
+	report := &TimingReport{
+		Start:         start,
+		End:           end,
+		Book:          book,
+		By:            by,
+		Timezone:      loc.String(),
+		BucketMinutes: bucketMinutes,
+		TimeOfDay:     make([]*TimingBucket, 0),
+		DayOfWeek:     make([]*TimingBucket, 0),
+	}
+
+	slots := make(map[int]*TimingBucket)
+	days := make(map[time.Weekday]*TimingBucket)
+	for _, trade := range trades {
+		if hasBook && bookOrDefault(trade.Book) != book {
+			continue
+		}
+
+		at := trade.EntryTime
+		if by == TimingByExit {
+			at = trade.ExitTime
+		}
+		if at.IsZero() {
+			continue
+		}
+		at = at.In(loc)
+		pnl := trade.PnL.InexactFloat64()
+
+		minute := (at.Hour()*60 + at.Minute()) / bucketMinutes * bucketMinutes
+		slot, ok := slots[minute]
+		if !ok {
+			slot = &TimingBucket{Label: fmt.Sprintf("%02d:%02d", minute/60, minute%60), order: minute}
+			slots[minute] = slot
+		}
+		slot.add(pnl)
+
+		day, ok := days[at.Weekday()]
+		if !ok {
+			// Monday first, Sunday last
+			day = &TimingBucket{Label: at.Weekday().String(), order: (int(at.Weekday()) + 6) % 7}
+			days[at.Weekday()] = day
+		}
+		day.add(pnl)
+
+		report.Trades++
+	}
+
+	for _, slot := range slots {
+		report.TimeOfDay = append(report.TimeOfDay, slot.finish())
+	}
+	for _, day := range days {
+		report.DayOfWeek = append(report.DayOfWeek, day.finish())
+	}
+	sort.Slice(report.TimeOfDay, func(i, j int) bool { return report.TimeOfDay[i].order < report.TimeOfDay[j].order })
+	sort.Slice(report.DayOfWeek, func(i, j int) bool { return report.DayOfWeek[i].order < report.DayOfWeek[j].order })
+
+	return report, nil
+}
+
+func (b *TimingBucket) add(pnl float64) {
+	b.Trades++
+	b.RealizedPnL += pnl
+	if pnl > 0 {
+		b.Wins++
+	} else if pnl < 0 {
+		b.Losses++
+	}
+}
+
+func (b *TimingBucket) finish() *TimingBucket {
+	if b.Trades > 0 {
+		b.WinRate = float64(b.Wins) / float64(b.Trades) * 100
+		b.AvgPnL = b.RealizedPnL / float64(b.Trades)
+	}
+	return b
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Tradier API roots
+const (
+	tradierLiveURL    = "https://api.tradier.com/v1"
+	tradierSandboxURL = "https://sandbox.tradier.com/v1"
+)
+
+// tradierClient is the REST client shared by the Tradier trading and data services
+type tradierClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	logger     *logrus.Logger
+}
+
+func newTradierClient(token string, sandbox bool) (*tradierClient, error) {
+	if token == "" {
+		return nil, fmt.Errorf("Tradier access token not configured")
+	}
+
+	baseURL := tradierLiveURL
+	if sandbox {
+		baseURL = tradierSandboxURL
+	}
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &tradierClient{
+		baseURL:    baseURL,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		logger:     logger,
+	}, nil
+}
+
+// do sends a request with form-encoded parameters (query string for GET and
+// DELETE) and decodes the JSON response into out
+func (c *tradierClient) do(ctx context.Context, method, path string, params url.Values, out interface{}) error {
+	endpoint := c.baseURL + path
+	var body io.Reader
+	if method == http.MethodGet || method == http.MethodDelete {
+		if len(params) > 0 {
+			endpoint += "?" + params.Encode()
+		}
+	} else {
+		body = bytes.NewBufferString(params.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Tradier request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Tradier API error (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	// Validation errors arrive with HTTP 200 as {"errors": {"error": [...]}}
+	var apiErr struct {
+		Errors struct {
+			Error tradierList[string] `json:"error"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(data, &apiErr) == nil && len(apiErr.Errors.Error) > 0 {
+		return fmt.Errorf("Tradier API error: %s", strings.Join(apiErr.Errors.Error, "; "))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// tradierList decodes Tradier collections, which are an array for several
+// items, a bare object for one item and the string "null" for none
+type tradierList[T any] []T
+
+func (l *tradierList[T]) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case isTradierNull(trimmed):
+		*l = nil
+		return nil
+	case trimmed[0] == '[':
+		var items []T
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	default:
+		var item T
+		if err := json.Unmarshal(trimmed, &item); err != nil {
+			return err
+		}
+		*l = tradierList[T]{item}
+		return nil
+	}
+}
+
+// isTradierNull reports whether data is an empty Tradier value, which is
+// null or the string "null"
+func isTradierNull(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) || bytes.Equal(trimmed, []byte(`"null"`))
+}
+
+// tradierQuote is an equity or option quote
+type tradierQuote struct {
+	Symbol         string  `json:"symbol"`
+	Last           float64 `json:"last"`
+	Open           float64 `json:"open"`
+	High           float64 `json:"high"`
+	Low            float64 `json:"low"`
+	Close          float64 `json:"close"`
+	Volume         int64   `json:"volume"`
+	Bid            float64 `json:"bid"`
+	BidSize        int64   `json:"bidsize"`
+	Ask            float64 `json:"ask"`
+	AskSize        int64   `json:"asksize"`
+	LastVolume     int64   `json:"last_volume"`
+	TradeDate      int64   `json:"trade_date"` // epoch milliseconds
+	BidDate        int64   `json:"bid_date"`
+	OpenInterest   int64   `json:"open_interest"`
+	Underlying     string  `json:"underlying"`
+	Strike         float64 `json:"strike"`
+	OptionType     string  `json:"option_type"`
+	ExpirationDate string  `json:"expiration_date"`
+	Greeks         *struct {
+		Delta float64 `json:"delta"`
+		Gamma float64 `json:"gamma"`
+		Theta float64 `json:"theta"`
+		Vega  float64 `json:"vega"`
+		MidIV float64 `json:"mid_iv"`
+	} `json:"greeks"`
+}
+
+// quotes fetches quotes for symbols (equities or OCC option symbols)
+func (c *tradierClient) quotes(ctx context.Context, symbols []string, greeks bool) (map[string]tradierQuote, error) {
+	var resp struct {
+		Quotes struct {
+			Quote tradierList[tradierQuote] `json:"quote"`
+		} `json:"quotes"`
+	}
+	params := url.Values{"symbols": {strings.Join(symbols, ",")}}
+	if greeks {
+		params.Set("greeks", "true")
+	}
+	if err := c.do(ctx, http.MethodGet, "/markets/quotes", params, &resp); err != nil {
+		return nil, err
+	}
+
+	quotes := make(map[string]tradierQuote, len(resp.Quotes.Quote))
+	for _, q := range resp.Quotes.Quote {
+		quotes[q.Symbol] = q
+	}
+	return quotes, nil
+}
+
+// quote fetches a single quote
+func (c *tradierClient) quote(ctx context.Context, symbol string) (*tradierQuote, error) {
+	quotes, err := c.quotes(ctx, []string{symbol}, false)
+	if err != nil {
+		return nil, err
+	}
+	q, ok := quotes[symbol]
+	if !ok {
+		return nil, fmt.Errorf("no quote for %s", symbol)
+	}
+	return &q, nil
+}
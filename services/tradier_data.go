@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"prophet-trader/config"
+	"prophet-trader/interfaces"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterDataProvider("tradier", func(cfg *config.Config) (interfaces.DataService, error) {
+		return NewTradierDataService(cfg.TradierAccessToken, cfg.TradierSandbox)
+	})
+}
+
+// TradierDataService implements DataService using Tradier market data
+type TradierDataService struct {
+	client *tradierClient
+	logger *logrus.Logger
+}
+
+// NewTradierDataService creates a Tradier market data service
+func NewTradierDataService(token string, sandbox bool) (*TradierDataService, error) {
+	client, err := newTradierClient(token, sandbox)
+	if err != nil {
+		return nil, err
+	}
+	return &TradierDataService{client: client, logger: client.logger}, nil
+}
+
+// tradierTimesales picks the native time and sales interval for an
+// intraday timeframe, the longest that divides it, and the bucket size bars
+// are aggregated into when Tradier has no such interval
+func tradierTimesales(tf Timeframe) (string, time.Duration) {
+	size := tf.Duration()
+	for _, native := range []time.Duration{15 * time.Minute, 5 * time.Minute, time.Minute} {
+		if size%native != 0 {
+			continue
+		}
+		interval := fmt.Sprintf("%dmin", int(native.Minutes()))
+		if size == native {
+			return interval, 0
+		}
+		return interval, size
+	}
+	return "1min", size
+}
+
+// GetHistoricalBars retrieves bars from the history endpoint (daily and
+// longer) or the time and sales endpoint (intraday). Tradier only serves
+// split-adjusted prices from its own feed.
+func (s *TradierDataService) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
+	s.logger.WithFields(logrus.Fields{
+		"symbol":    symbol,
+		"start":     start,
+		"end":       end,
+		"timeframe": timeframe,
+	}).Debug("Fetching historical bars")
+
+	tf, err := ParseTimeframe(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	if opts := barOptionsFrom(ctx); opts.Feed != "" {
+		return nil, fmt.Errorf("%s feed: %w", opts.Feed, ErrNotSupported)
+	} else if opts.Adjustment != "" && opts.Adjustment != BarAdjustmentSplit {
+		return nil, fmt.Errorf("%s adjustment: %w", opts.Adjustment, ErrNotSupported)
+	}
+	if tf.Unit == TimeframeMinute || tf.Unit == TimeframeHour {
+		interval, bucket := tradierTimesales(tf)
+		bars, err := s.timesales(ctx, symbol, start, end, interval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bars: %w", err)
+		}
+		if bucket > 0 {
+			bars = aggregateBars(bars, bucket)
+		}
+		return bars, nil
+	}
+
+	interval := "daily"
+	switch tf.Unit {
+	case TimeframeWeek:
+		interval = "weekly"
+	case TimeframeMonth:
+		if tf.Amount != 1 {
+			return nil, fmt.Errorf("%s bars: %w", tf, ErrNotSupported)
+		}
+		interval = "monthly"
+	}
+	bars, err := s.history(ctx, symbol, start, end, interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bars: %w", err)
+	}
+	return bars, nil
+}
+
+func (s *TradierDataService) history(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*interfaces.Bar, error) {
+	var resp struct {
+		History struct {
+			Day tradierList[struct {
+				Date   string  `json:"date"`
+				Open   float64 `json:"open"`
+				High   float64 `json:"high"`
+				Low    float64 `json:"low"`
+				Close  float64 `json:"close"`
+				Volume int64   `json:"volume"`
+			}] `json:"day"`
+		} `json:"history"`
+	}
+	params := url.Values{
+		"symbol":   {strings.ToUpper(symbol)},
+		"interval": {interval},
+		"start":    {start.Format("2006-01-02")},
+		"end":      {end.Format("2006-01-02")},
+	}
+	if err := s.client.do(ctx, http.MethodGet, "/markets/history", params, &resp); err != nil {
+		return nil, err
+	}
+
+	// Daily bars are stamped at midnight New York time, as Alpaca does
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.UTC
+	}
+
+	bars := make([]*interfaces.Bar, 0, len(resp.History.Day))
+	for _, day := range resp.History.Day {
+		date, err := time.ParseInLocation("2006-01-02", day.Date, loc)
+		if err != nil {
+			continue
+		}
+		bars = append(bars, &interfaces.Bar{
+			Symbol:    symbol,
+			Timestamp: date,
+			Open:      day.Open,
+			High:      day.High,
+			Low:       day.Low,
+			Close:     day.Close,
+			Volume:    day.Volume,
+		})
+	}
+	return bars, nil
+}
+
+func (s *TradierDataService) timesales(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*interfaces.Bar, error) {
+	var resp struct {
+		Series struct {
+			Data tradierList[struct {
+				Timestamp int64   `json:"timestamp"`
+				Open      float64 `json:"open"`
+				High      float64 `json:"high"`
+				Low       float64 `json:"low"`
+				Close     float64 `json:"close"`
+				Volume    int64   `json:"volume"`
+				VWAP      float64 `json:"vwap"`
+			}] `json:"data"`
+		} `json:"series"`
+	}
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.UTC
+	}
+	params := url.Values{
+		"symbol":         {strings.ToUpper(symbol)},
+		"interval":       {interval},
+		"start":          {start.In(loc).Format("2006-01-02 15:04")},
+		"end":            {end.In(loc).Format("2006-01-02 15:04")},
+		"session_filter": {"all"},
+	}
+	if err := s.client.do(ctx, http.MethodGet, "/markets/timesales", params, &resp); err != nil {
+		return nil, err
+	}
+
+	bars := make([]*interfaces.Bar, 0, len(resp.Series.Data))
+	for _, d := range resp.Series.Data {
+		bars = append(bars, &interfaces.Bar{
+			Symbol:    symbol,
+			Timestamp: time.Unix(d.Timestamp, 0),
+			Open:      d.Open,
+			High:      d.High,
+			Low:       d.Low,
+			Close:     d.Close,
+			Volume:    d.Volume,
+			VWAP:      d.VWAP,
+		})
+	}
+	return bars, nil
+}
+
+// aggregateBars merges bars into clock-aligned buckets of the given size
+func aggregateBars(bars []*interfaces.Bar, bucket time.Duration) []*interfaces.Bar {
+	aggregated := make([]*interfaces.Bar, 0, len(bars))
+	var current *interfaces.Bar
+	var notional float64
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if current.Volume > 0 {
+			current.VWAP = notional / float64(current.Volume)
+		}
+		aggregated = append(aggregated, current)
+	}
+
+	for _, bar := range bars {
+		start := bar.Timestamp.Truncate(bucket)
+		if current == nil || !current.Timestamp.Equal(start) {
+			flush()
+			current = &interfaces.Bar{
+				Symbol:    bar.Symbol,
+				Timestamp: start,
+				Open:      bar.Open,
+				High:      bar.High,
+				Low:       bar.Low,
+			}
+			notional = 0
+		}
+		if bar.High > current.High {
+			current.High = bar.High
+		}
+		if bar.Low < current.Low {
+			current.Low = bar.Low
+		}
+		current.Close = bar.Close
+		current.Volume += bar.Volume
+		price := bar.VWAP
+		if price == 0 {
+			price = bar.Close
+		}
+		notional += price * float64(bar.Volume)
+	}
+	flush()
+
+	return aggregated
+}
+
+// GetLatestBar retrieves the most recent minute bar, falling back to the
+// last daily bar outside market hours
+func (s *TradierDataService) GetLatestBar(ctx context.Context, symbol string) (*interfaces.Bar, error) {
+	end := time.Now()
+	bars, err := s.timesales(ctx, symbol, end.Add(-24*time.Hour), end, "1min")
+	if err != nil || len(bars) == 0 {
+		bars, err = s.history(ctx, symbol, end.AddDate(0, 0, -7), end, "daily")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest bar: %w", err)
+	}
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no bars for %s", symbol)
+	}
+	return bars[len(bars)-1], nil
+}
+
+// GetLatestQuote retrieves the latest quote
+func (s *TradierDataService) GetLatestQuote(ctx context.Context, symbol string) (*interfaces.Quote, error) {
+	q, err := s.client.quote(ctx, strings.ToUpper(symbol))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest quote: %w", err)
+	}
+
+	return &interfaces.Quote{
+		Symbol:    symbol,
+		BidPrice:  q.Bid,
+		BidSize:   q.BidSize,
+		AskPrice:  q.Ask,
+		AskSize:   q.AskSize,
+		Timestamp: time.UnixMilli(q.BidDate),
+	}, nil
+}
+
+// GetLatestTrade retrieves the latest trade
+func (s *TradierDataService) GetLatestTrade(ctx context.Context, symbol string) (*interfaces.Trade, error) {
+	q, err := s.client.quote(ctx, strings.ToUpper(symbol))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest trade: %w", err)
+	}
+
+	return &interfaces.Trade{
+		Symbol:    symbol,
+		Price:     q.Last,
+		Size:      q.LastVolume,
+		Timestamp: time.UnixMilli(q.TradeDate),
+	}, nil
+}
+
+// StreamBars is not implemented for Tradier; the stream hub polls instead
+func (s *TradierDataService) StreamBars(ctx context.Context, symbols []string) (<-chan *interfaces.Bar, error) {
+	return nil, fmt.Errorf("bar streaming: %w", ErrNotSupported)
+}
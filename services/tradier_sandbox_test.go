@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"prophet-trader/interfaces"
+
+	"github.com/shopspring/decimal"
+)
+
+// Integration tests against Tradier's sandbox. They run only when
+// TRADIER_ACCESS_TOKEN and TRADIER_ACCOUNT_ID are set, always against the
+// sandbox whatever TRADIER_SANDBOX says, and place only limit orders far
+// from the market that are canceled before the test ends.
+
+const tradierSandboxUnderlying = "SPY"
+
+// tradierSandbox returns trading and data services on the sandbox, or skips
+func tradierSandbox(t *testing.T) (*TradierTradingService, *TradierDataService) {
+	t.Helper()
+	token, accountID := os.Getenv("TRADIER_ACCESS_TOKEN"), os.Getenv("TRADIER_ACCOUNT_ID")
+	if token == "" || accountID == "" {
+		t.Skip("TRADIER_ACCESS_TOKEN and TRADIER_ACCOUNT_ID not set")
+	}
+	if testing.Short() {
+		t.Skip("sandbox integration test skipped in short mode")
+	}
+
+	trading, err := NewTradierTradingService(token, accountID, true)
+	if err != nil {
+		t.Fatalf("NewTradierTradingService: %v", err)
+	}
+	data, err := NewTradierDataService(token, true)
+	if err != nil {
+		t.Fatalf("NewTradierDataService: %v", err)
+	}
+	return trading, data
+}
+
+func tradierSandboxContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// nearestTradierExpiration returns the first listed expiration of underlying
+func nearestTradierExpiration(ctx context.Context, t *testing.T, s *TradierTradingService, underlying string) time.Time {
+	t.Helper()
+	var resp struct {
+		Expirations struct {
+			Date tradierList[string] `json:"date"`
+		} `json:"expirations"`
+	}
+	if err := s.client.do(ctx, http.MethodGet, "/markets/options/expirations", url.Values{"symbol": {underlying}}, &resp); err != nil {
+		t.Fatalf("list expirations: %v", err)
+	}
+	if len(resp.Expirations.Date) == 0 {
+		t.Fatalf("no expirations listed for %s", underlying)
+	}
+	expiration, err := time.Parse("2006-01-02", resp.Expirations.Date[0])
+	if err != nil {
+		t.Fatalf("parse expiration %q: %v", resp.Expirations.Date[0], err)
+	}
+	return expiration
+}
+
+// cancelTradierOrder cancels orderID and waits for the sandbox to confirm
+func cancelTradierOrder(ctx context.Context, t *testing.T, s *TradierTradingService, orderID string) {
+	t.Helper()
+	if err := s.CancelOrder(ctx, orderID); err != nil {
+		t.Fatalf("CancelOrder(%s): %v", orderID, err)
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		order, err := s.GetOrder(ctx, orderID)
+		if err != nil {
+			t.Fatalf("GetOrder(%s) after cancel: %v", orderID, err)
+		}
+		if order.Status == "canceled" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("order %s still %s 30s after cancel", orderID, order.Status)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func TestTradierSandboxAccount(t *testing.T) {
+	trading, _ := tradierSandbox(t)
+	ctx := tradierSandboxContext(t)
+
+	account, err := trading.GetAccount(ctx)
+	if err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	if account.ID != trading.accountID {
+		t.Errorf("account ID = %q, want %q", account.ID, trading.accountID)
+	}
+	if account.Equity.IsNegative() || account.BuyingPower.IsNegative() {
+		t.Errorf("equity %s and buying power %s should not be negative", account.Equity, account.BuyingPower)
+	}
+
+	if _, err := trading.GetPositions(ctx); err != nil {
+		t.Errorf("GetPositions: %v", err)
+	}
+}
+
+func TestTradierSandboxQuote(t *testing.T) {
+	_, data := tradierSandbox(t)
+	ctx := tradierSandboxContext(t)
+
+	quote, err := data.GetLatestQuote(ctx, tradierSandboxUnderlying)
+	if err != nil {
+		t.Fatalf("GetLatestQuote: %v", err)
+	}
+	if quote.Symbol != tradierSandboxUnderlying {
+		t.Errorf("quote symbol = %q, want %q", quote.Symbol, tradierSandboxUnderlying)
+	}
+	if quote.BidPrice <= 0 && quote.AskPrice <= 0 {
+		t.Errorf("quote has no bid or ask: %+v", quote)
+	}
+	if quote.BidPrice > 0 && quote.AskPrice > 0 && quote.AskPrice < quote.BidPrice {
+		t.Errorf("ask %v below bid %v", quote.AskPrice, quote.BidPrice)
+	}
+}
+
+func TestTradierSandboxOptionsChain(t *testing.T) {
+	trading, _ := tradierSandbox(t)
+	ctx := tradierSandboxContext(t)
+
+	expiration := nearestTradierExpiration(ctx, t, trading, tradierSandboxUnderlying)
+	chain, err := trading.GetOptionsChain(ctx, tradierSandboxUnderlying, expiration)
+	if err != nil {
+		t.Fatalf("GetOptionsChain: %v", err)
+	}
+	if len(chain) == 0 {
+		t.Fatalf("empty chain for %s %s", tradierSandboxUnderlying, expiration.Format("2006-01-02"))
+	}
+
+	// Every contract symbol round-trips through the shared OCC parser and
+	// agrees with the chain's own fields
+	for _, contract := range chain {
+		occ, err := ParseOCCSymbol(contract.Symbol)
+		if err != nil {
+			t.Errorf("ParseOCCSymbol(%q): %v", contract.Symbol, err)
+			continue
+		}
+		if got := occ.String(); got != contract.Symbol {
+			t.Errorf("OCC round trip of %q gave %q", contract.Symbol, got)
+		}
+		if !occ.Expiration.Equal(expiration) {
+			t.Errorf("%s: parsed expiration %s, chain expiration %s", contract.Symbol, occ.Expiration.Format("2006-01-02"), expiration.Format("2006-01-02"))
+		}
+		if occ.Type != contract.ContractType {
+			t.Errorf("%s: parsed type %q, chain type %q", contract.Symbol, occ.Type, contract.ContractType)
+		}
+		if occ.Strike != contract.StrikePrice {
+			t.Errorf("%s: parsed strike %v, chain strike %v", contract.Symbol, occ.Strike, contract.StrikePrice)
+		}
+	}
+
+	quote, err := trading.GetOptionsQuote(ctx, chain[0].Symbol)
+	if err != nil {
+		t.Fatalf("GetOptionsQuote(%s): %v", chain[0].Symbol, err)
+	}
+	if quote.Symbol != chain[0].Symbol {
+		t.Errorf("options quote symbol = %q, want %q", quote.Symbol, chain[0].Symbol)
+	}
+}
+
+func TestTradierSandboxEquityOrderLifecycle(t *testing.T) {
+	trading, _ := tradierSandbox(t)
+	ctx := tradierSandboxContext(t)
+
+	limit := decimal.NewFromInt(1)
+	result, err := trading.PlaceOrder(ctx, &interfaces.Order{
+		Symbol:      tradierSandboxUnderlying,
+		Qty:         decimal.NewFromInt(1),
+		Side:        "buy",
+		Type:        "limit",
+		TimeInForce: "day",
+		LimitPrice:  &limit,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	if result.OrderID == "" || result.OrderID == "0" {
+		t.Fatalf("PlaceOrder returned no order ID: %+v", result)
+	}
+
+	order, err := trading.GetOrder(ctx, result.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if order.Symbol != tradierSandboxUnderlying || order.Side != "buy" || order.Type != "limit" {
+		t.Errorf("order = %s %s %s, want %s buy limit", order.Symbol, order.Side, order.Type, tradierSandboxUnderlying)
+	}
+	if !order.Qty.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("order qty = %s, want 1", order.Qty)
+	}
+	if order.LimitPrice == nil || !order.LimitPrice.Equal(limit) {
+		t.Errorf("order limit price = %v, want %s", order.LimitPrice, limit)
+	}
+	switch order.Status {
+	case "new", "pending_new", "accepted":
+	default:
+		t.Errorf("order status = %q, want a working status", order.Status)
+	}
+
+	open, err := trading.ListOrders(ctx, "open")
+	if err != nil {
+		t.Fatalf("ListOrders: %v", err)
+	}
+	found := false
+	for _, o := range open {
+		found = found || o.ID == result.OrderID
+	}
+	if !found {
+		t.Errorf("order %s missing from open orders", result.OrderID)
+	}
+
+	cancelTradierOrder(ctx, t, trading, result.OrderID)
+}
+
+func TestTradierSandboxOptionsOrderLifecycle(t *testing.T) {
+	trading, _ := tradierSandbox(t)
+	ctx := tradierSandboxContext(t)
+
+	expiration := nearestTradierExpiration(ctx, t, trading, tradierSandboxUnderlying)
+	chain, err := trading.GetOptionsChain(ctx, tradierSandboxUnderlying, expiration)
+	if err != nil {
+		t.Fatalf("GetOptionsChain: %v", err)
+	}
+	// The lowest put strike, bid at a penny, won't fill
+	var contract *interfaces.OptionContract
+	for _, c := range chain {
+		if c.ContractType == "put" && (contract == nil || c.StrikePrice < contract.StrikePrice) {
+			contract = c
+		}
+	}
+	if contract == nil {
+		t.Fatalf("no puts in the %s chain", tradierSandboxUnderlying)
+	}
+
+	limit := decimal.RequireFromString("0.01")
+	result, err := trading.PlaceOptionsOrder(ctx, &interfaces.OptionsOrder{
+		Symbol:      contract.Symbol,
+		Underlying:  tradierSandboxUnderlying,
+		Qty:         decimal.NewFromInt(1),
+		Side:        "buy",
+		Type:        "limit",
+		TimeInForce: "day",
+		LimitPrice:  &limit,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOptionsOrder(%s): %v", contract.Symbol, err)
+	}
+
+	order, err := trading.GetOrder(ctx, result.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	// The order comes back under the OCC symbol it was placed with
+	if order.Symbol != contract.Symbol {
+		t.Errorf("order symbol = %q, want %q", order.Symbol, contract.Symbol)
+	}
+	occ, err := ParseOCCSymbol(order.Symbol)
+	if err != nil {
+		t.Fatalf("ParseOCCSymbol(%q): %v", order.Symbol, err)
+	}
+	if occ.Underlying != tradierSandboxUnderlying || occ.Type != "put" || occ.Strike != contract.StrikePrice {
+		t.Errorf("parsed %+v from %q", occ, order.Symbol)
+	}
+	if order.Side != "buy" {
+		t.Errorf("order side = %q, want buy", order.Side)
+	}
+
+	cancelTradierOrder(ctx, t, trading, result.OrderID)
+}
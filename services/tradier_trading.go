@@ -0,0 +1,598 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"prophet-trader/config"
+	"prophet-trader/interfaces"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterBroker("tradier", func(cfg *config.Config) (interfaces.TradingService, error) {
+		return NewTradierTradingService(cfg.TradierAccessToken, cfg.TradierAccountID, cfg.TradierSandbox)
+	})
+}
+
+// TradierTradingService implements TradingService using the Tradier brokerage API
+type TradierTradingService struct {
+	client    *tradierClient
+	accountID string
+	logger    *logrus.Logger
+}
+
+// NewTradierTradingService creates a Tradier trading service. sandbox selects
+// the paper trading environment.
+func NewTradierTradingService(token, accountID string, sandbox bool) (*TradierTradingService, error) {
+	if accountID == "" {
+		return nil, fmt.Errorf("Tradier account ID not configured")
+	}
+	client, err := newTradierClient(token, sandbox)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TradierTradingService{
+		client:    client,
+		accountID: accountID,
+		logger:    client.logger,
+	}, nil
+}
+
+// tradierOrder is an order as returned by the accounts API
+type tradierOrder struct {
+	ID                int64   `json:"id"`
+	Type              string  `json:"type"`
+	Symbol            string  `json:"symbol"`
+	OptionSymbol      string  `json:"option_symbol"`
+	Side              string  `json:"side"`
+	Quantity          float64 `json:"quantity"`
+	Status            string  `json:"status"`
+	Duration          string  `json:"duration"`
+	Price             float64 `json:"price"`
+	StopPrice         float64 `json:"stop_price"`
+	AvgFillPrice      float64 `json:"avg_fill_price"`
+	ExecQuantity      float64 `json:"exec_quantity"`
+	CreateDate        string  `json:"create_date"`
+	TransactionDate   string  `json:"transaction_date"`
+	Class             string  `json:"class"`
+	ReasonDescription string  `json:"reason_description"`
+	Tag               string  `json:"tag"`
+}
+
+// tradierPosition is a position as returned by the accounts API
+type tradierPosition struct {
+	Symbol    string  `json:"symbol"`
+	Quantity  float64 `json:"quantity"`
+	CostBasis float64 `json:"cost_basis"`
+}
+
+// tradierOrders is an account's orders. An account without any is sent as
+// the string "null" in place of the whole object.
+type tradierOrders struct {
+	Order tradierList[tradierOrder] `json:"order"`
+}
+
+func (o *tradierOrders) UnmarshalJSON(data []byte) error {
+	*o = tradierOrders{}
+	if isTradierNull(data) {
+		return nil
+	}
+	type plain tradierOrders
+	return json.Unmarshal(data, (*plain)(o))
+}
+
+// tradierPositions is an account's positions, sent as the string "null"
+// like tradierOrders when there are none
+type tradierPositions struct {
+	Position tradierList[tradierPosition] `json:"position"`
+}
+
+func (p *tradierPositions) UnmarshalJSON(data []byte) error {
+	*p = tradierPositions{}
+	if isTradierNull(data) {
+		return nil
+	}
+	type plain tradierPositions
+	return json.Unmarshal(data, (*plain)(p))
+}
+
+// PlaceOrder places an equity order
+func (s *TradierTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	params := tradierOrderParams(order.Type, order.TimeInForce, order.Qty, order.LimitPrice, order.StopPrice)
+	params.Set("class", "equity")
+	params.Set("symbol", strings.ToUpper(order.Symbol))
+	params.Set("side", order.Side)
+	if order.ClientOrderID != "" {
+		params.Set("tag", order.ClientOrderID)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"symbol": order.Symbol,
+		"side":   order.Side,
+		"qty":    order.Qty.String(),
+		"type":   order.Type,
+	}).Info("Placing order")
+
+	result, err := s.submit(ctx, params)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to place order")
+		return nil, fmt.Errorf("failed to place order: %w", err)
+	}
+	result.Message = fmt.Sprintf("Order placed successfully: %s %s shares of %s", order.Side, order.Qty, order.Symbol)
+	return result, nil
+}
+
+// PlaceOptionsOrder places a single-leg options order. Without an explicit
+// position intent, buys open and sells close.
+func (s *TradierTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	occ, err := ParseOCCSymbol(order.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place options order: %w", err)
+	}
+
+	side := order.PositionIntent
+	if side == "" {
+		side = "sell_to_close"
+		if order.Side == "buy" {
+			side = "buy_to_open"
+		}
+	}
+
+	params := tradierOrderParams(order.Type, order.TimeInForce, order.Qty, order.LimitPrice, nil)
+	params.Set("class", "option")
+	params.Set("symbol", occ.Underlying)
+	params.Set("option_symbol", occ.String())
+	params.Set("side", side)
+
+	s.logger.WithFields(logrus.Fields{
+		"symbol": order.Symbol,
+		"side":   side,
+		"qty":    order.Qty.String(),
+		"type":   order.Type,
+	}).Info("Placing options order")
+
+	result, err := s.submit(ctx, params)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to place options order")
+		return nil, fmt.Errorf("failed to place options order: %w", err)
+	}
+	result.Message = fmt.Sprintf("Options order placed successfully: %s %s contracts of %s", order.Side, order.Qty, order.Symbol)
+	return result, nil
+}
+
+// tradierOrderParams builds the parameters shared by equity and option orders
+func tradierOrderParams(orderType, tif string, qty decimal.Decimal, limitPrice, stopPrice *decimal.Decimal) url.Values {
+	if orderType == "" {
+		orderType = "market"
+	}
+	duration := strings.ToLower(tif)
+	if duration != "gtc" {
+		duration = "day"
+	}
+
+	params := url.Values{
+		"type":     {orderType},
+		"duration": {duration},
+		"quantity": {qty.String()},
+	}
+	if limitPrice != nil {
+		params.Set("price", limitPrice.String())
+	}
+	if stopPrice != nil {
+		params.Set("stop", stopPrice.String())
+	}
+	return params
+}
+
+func (s *TradierTradingService) submit(ctx context.Context, params url.Values) (*interfaces.OrderResult, error) {
+	var resp struct {
+		Order struct {
+			ID     int64  `json:"id"`
+			Status string `json:"status"`
+		} `json:"order"`
+	}
+	if err := s.client.do(ctx, http.MethodPost, "/accounts/"+s.accountID+"/orders", params, &resp); err != nil {
+		return nil, err
+	}
+
+	// Tradier acknowledges accepted orders with status "ok"
+	status := resp.Order.Status
+	if status == "ok" {
+		status = "pending_new"
+	}
+	return &interfaces.OrderResult{
+		OrderID: strconv.FormatInt(resp.Order.ID, 10),
+		Status:  status,
+	}, nil
+}
+
+// CancelOrder cancels an existing order
+func (s *TradierTradingService) CancelOrder(ctx context.Context, orderID string) error {
+	s.logger.WithField("orderID", orderID).Info("Canceling order")
+
+	if err := s.client.do(ctx, http.MethodDelete, "/accounts/"+s.accountID+"/orders/"+url.PathEscape(orderID), nil, nil); err != nil {
+		s.logger.WithError(err).Error("Failed to cancel order")
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	return nil
+}
+
+// GetOrder retrieves a specific order
+func (s *TradierTradingService) GetOrder(ctx context.Context, orderID string) (*interfaces.Order, error) {
+	var resp struct {
+		Order tradierOrder `json:"order"`
+	}
+	if err := s.client.do(ctx, http.MethodGet, "/accounts/"+s.accountID+"/orders/"+url.PathEscape(orderID), nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	return convertTradierOrder(&resp.Order), nil
+}
+
+// ListOrders retrieves orders. status accepts the Alpaca-style "open",
+// "closed" or "all".
+func (s *TradierTradingService) ListOrders(ctx context.Context, status string) ([]*interfaces.Order, error) {
+	var resp struct {
+		Orders tradierOrders `json:"orders"`
+	}
+	if err := s.client.do(ctx, http.MethodGet, "/accounts/"+s.accountID+"/orders", nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+
+	orders := make([]*interfaces.Order, 0, len(resp.Orders.Order))
+	for i := range resp.Orders.Order {
+		order := convertTradierOrder(&resp.Orders.Order[i])
+		open := isOpenOrderStatus(order.Status)
+		if (status == "open" && !open) || (status == "closed" && open) {
+			continue
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// convertTradierOrder maps a Tradier order onto the interface type, using the
+// OCC symbol for options and Alpaca-style sides and statuses
+func convertTradierOrder(to *tradierOrder) *interfaces.Order {
+	symbol := to.Symbol
+	if to.OptionSymbol != "" {
+		symbol = to.OptionSymbol
+	}
+
+	side := "buy"
+	if strings.HasPrefix(to.Side, "sell") {
+		side = "sell"
+	}
+
+	order := &interfaces.Order{
+		ID:            strconv.FormatInt(to.ID, 10),
+		ClientOrderID: to.Tag,
+		Symbol:        symbol,
+		Qty:           decimal.NewFromFloat(to.Quantity),
+		Side:          side,
+		Type:          to.Type,
+		TimeInForce:   to.Duration,
+		Status:        tradierStatus(to.Status),
+		FilledQty:     decimal.NewFromFloat(to.ExecQuantity),
+	}
+	if to.Price > 0 {
+		price := decimal.NewFromFloat(to.Price)
+		order.LimitPrice = &price
+	}
+	if to.StopPrice > 0 {
+		price := decimal.NewFromFloat(to.StopPrice)
+		order.StopPrice = &price
+	}
+	if to.AvgFillPrice > 0 {
+		price := decimal.NewFromFloat(to.AvgFillPrice)
+		order.FilledAvgPrice = &price
+	}
+	if created, err := time.Parse(time.RFC3339, to.CreateDate); err == nil {
+		order.SubmittedAt = created
+	}
+	if updated, err := time.Parse(time.RFC3339, to.TransactionDate); err == nil {
+		switch order.Status {
+		case "filled":
+			order.FilledAt = &updated
+		case "canceled":
+			order.CanceledAt = &updated
+		}
+	}
+	return order
+}
+
+// tradierStatus maps Tradier order statuses to the Alpaca-style statuses
+// the position manager and reconciler expect
+func tradierStatus(status string) string {
+	switch status {
+	case "open":
+		return "new"
+	case "pending":
+		return "pending_new"
+	case "error":
+		return "rejected"
+	default:
+		// filled, partially_filled, canceled, expired, rejected match already
+		return status
+	}
+}
+
+// GetPositions retrieves all positions priced with current quotes
+func (s *TradierTradingService) GetPositions(ctx context.Context) ([]*interfaces.Position, error) {
+	positions, err := s.positions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+	return positions, nil
+}
+
+func (s *TradierTradingService) positions(ctx context.Context) ([]*interfaces.Position, error) {
+	var resp struct {
+		Positions tradierPositions `json:"positions"`
+	}
+	if err := s.client.do(ctx, http.MethodGet, "/accounts/"+s.accountID+"/positions", nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Positions.Position) == 0 {
+		return []*interfaces.Position{}, nil
+	}
+
+	symbols := make([]string, len(resp.Positions.Position))
+	for i, p := range resp.Positions.Position {
+		symbols[i] = p.Symbol
+	}
+	quotes, err := s.client.quotes(ctx, symbols, false)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to price positions, reporting cost basis only")
+		quotes = map[string]tradierQuote{}
+	}
+
+	positions := make([]*interfaces.Position, 0, len(resp.Positions.Position))
+	for _, p := range resp.Positions.Position {
+		multiplier := 1.0
+		if IsOCCSymbol(p.Symbol) {
+			multiplier = 100
+		}
+
+		price := quotes[p.Symbol].Last
+		marketValue := price * p.Quantity * multiplier
+		unrealized := marketValue - p.CostBasis
+		avgEntry := 0.0
+		if p.Quantity != 0 {
+			avgEntry = p.CostBasis / (p.Quantity * multiplier)
+		}
+
+		side := "long"
+		if p.Quantity < 0 {
+			side = "short"
+		}
+
+		position := &interfaces.Position{
+			Symbol:        p.Symbol,
+			Qty:           decimal.NewFromFloat(p.Quantity),
+			AvgEntryPrice: decimal.NewFromFloat(avgEntry),
+			MarketValue:   decimal.NewFromFloat(marketValue),
+			CostBasis:     decimal.NewFromFloat(p.CostBasis),
+			UnrealizedPL:  decimal.NewFromFloat(unrealized),
+			CurrentPrice:  decimal.NewFromFloat(price),
+			Side:          side,
+		}
+		if p.CostBasis != 0 && price > 0 {
+			position.UnrealizedPLPC = decimal.NewFromFloat(unrealized / math.Abs(p.CostBasis))
+		}
+		positions = append(positions, position)
+	}
+
+	return positions, nil
+}
+
+// GetAccount retrieves account balances
+func (s *TradierTradingService) GetAccount(ctx context.Context) (*interfaces.Account, error) {
+	var resp struct {
+		Balances struct {
+			AccountNumber string  `json:"account_number"`
+			AccountType   string  `json:"account_type"` // "cash", "margin" or "pdt"
+			TotalEquity   float64 `json:"total_equity"`
+			TotalCash     float64 `json:"total_cash"`
+			Margin        *struct {
+				StockBuyingPower float64 `json:"stock_buying_power"`
+			} `json:"margin"`
+			PDT *struct {
+				StockBuyingPower float64 `json:"stock_buying_power"`
+			} `json:"pdt"`
+			Cash *struct {
+				CashAvailable float64 `json:"cash_available"`
+			} `json:"cash"`
+		} `json:"balances"`
+	}
+	if err := s.client.do(ctx, http.MethodGet, "/accounts/"+s.accountID+"/balances", nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	balances := resp.Balances
+	buyingPower := balances.TotalCash
+	switch {
+	case balances.Margin != nil:
+		buyingPower = balances.Margin.StockBuyingPower
+	case balances.PDT != nil:
+		buyingPower = balances.PDT.StockBuyingPower
+	case balances.Cash != nil:
+		buyingPower = balances.Cash.CashAvailable
+	}
+
+	multiplier := decimal.NewFromInt(1)
+	switch balances.AccountType {
+	case "margin":
+		multiplier = decimal.NewFromInt(2)
+	case "pdt":
+		multiplier = decimal.NewFromInt(4)
+	}
+
+	return &interfaces.Account{
+		ID:               s.accountID,
+		Cash:             decimal.NewFromFloat(balances.TotalCash),
+		PortfolioValue:   decimal.NewFromFloat(balances.TotalEquity),
+		BuyingPower:      decimal.NewFromFloat(buyingPower),
+		PatternDayTrader: balances.AccountType == "pdt",
+		Equity:           decimal.NewFromFloat(balances.TotalEquity),
+		Multiplier:       multiplier,
+	}, nil
+}
+
+// GetPortfolioHistory is not offered by Tradier's API
+func (s *TradierTradingService) GetPortfolioHistory(ctx context.Context, start, end time.Time) ([]*interfaces.EquitySnapshot, error) {
+	return nil, fmt.Errorf("portfolio history: %w", ErrNotSupported)
+}
+
+// GetOptionsChain retrieves the options chain with greeks for one expiration
+func (s *TradierTradingService) GetOptionsChain(ctx context.Context, underlying string, expiration time.Time) ([]*interfaces.OptionContract, error) {
+	s.logger.WithFields(logrus.Fields{
+		"underlying": underlying,
+		"expiration": expiration,
+	}).Info("Getting options chain")
+
+	var resp struct {
+		Options struct {
+			Option tradierList[tradierQuote] `json:"option"`
+		} `json:"options"`
+	}
+	params := url.Values{
+		"symbol":     {strings.ToUpper(underlying)},
+		"expiration": {expiration.Format("2006-01-02")},
+		"greeks":     {"true"},
+	}
+	if err := s.client.do(ctx, http.MethodGet, "/markets/options/chains", params, &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch options chain: %w", err)
+	}
+
+	contracts := make([]*interfaces.OptionContract, 0, len(resp.Options.Option))
+	for _, q := range resp.Options.Option {
+		contracts = append(contracts, tradierOptionContract(q))
+	}
+
+	s.logger.WithField("count", len(contracts)).Info("Fetched options chain")
+	return contracts, nil
+}
+
+// tradierOptionContract converts an option quote into the shared contract type
+func tradierOptionContract(q tradierQuote) *interfaces.OptionContract {
+	contract := &interfaces.OptionContract{
+		Symbol:           q.Symbol,
+		UnderlyingSymbol: q.Underlying,
+		ContractType:     q.OptionType,
+		StrikePrice:      q.Strike,
+		Premium:          q.Last,
+		Bid:              q.Bid,
+		Ask:              q.Ask,
+		Volume:           q.Volume,
+		OpenInterest:     q.OpenInterest,
+	}
+	if expiration, err := time.Parse("2006-01-02", q.ExpirationDate); err == nil {
+		contract.ExpirationDate = expiration
+		contract.DTE = int(time.Until(expiration).Hours() / 24)
+	}
+	if q.Greeks != nil {
+		contract.ImpliedVolatility = q.Greeks.MidIV
+		contract.Delta = q.Greeks.Delta
+		contract.Gamma = q.Greeks.Gamma
+		contract.Theta = q.Greeks.Theta
+		contract.Vega = q.Greeks.Vega
+	}
+	return contract
+}
+
+// GetOptionsQuote retrieves a quote for a specific options contract
+func (s *TradierTradingService) GetOptionsQuote(ctx context.Context, symbol string) (*interfaces.OptionsQuote, error) {
+	q, err := s.client.quote(ctx, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get options quote: %w", err)
+	}
+
+	return &interfaces.OptionsQuote{
+		Symbol:    q.Symbol,
+		BidPrice:  q.Bid,
+		BidSize:   q.BidSize,
+		AskPrice:  q.Ask,
+		AskSize:   q.AskSize,
+		LastPrice: q.Last,
+		Volume:    q.Volume,
+		Timestamp: time.UnixMilli(q.TradeDate),
+	}, nil
+}
+
+// GetOptionsQuotes implements OptionsQuoteSource, 100 contracts per request
+func (s *TradierTradingService) GetOptionsQuotes(ctx context.Context, symbols []string) (map[string]*interfaces.OptionsQuote, error) {
+	quotes := make(map[string]*interfaces.OptionsQuote, len(symbols))
+	for start := 0; start < len(symbols); start += optionsQuoteBatchSize {
+		batch, err := s.client.quotes(ctx, symbols[start:min(start+optionsQuoteBatchSize, len(symbols))], false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get options quotes: %w", err)
+		}
+		for symbol, q := range batch {
+			quotes[symbol] = &interfaces.OptionsQuote{
+				Symbol:    q.Symbol,
+				BidPrice:  q.Bid,
+				BidSize:   q.BidSize,
+				AskPrice:  q.Ask,
+				AskSize:   q.AskSize,
+				LastPrice: q.Last,
+				Volume:    q.Volume,
+				Timestamp: time.UnixMilli(q.TradeDate),
+			}
+		}
+	}
+	return quotes, nil
+}
+
+// GetOptionsPosition retrieves a specific options position
+func (s *TradierTradingService) GetOptionsPosition(ctx context.Context, symbol string) (*interfaces.OptionsPosition, error) {
+	positions, err := s.ListOptionsPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, pos := range positions {
+		if pos.Symbol == symbol {
+			return pos, nil
+		}
+	}
+	return nil, fmt.Errorf("options position not found: %s", symbol)
+}
+
+// ListOptionsPositions retrieves all options positions
+func (s *TradierTradingService) ListOptionsPositions(ctx context.Context) ([]*interfaces.OptionsPosition, error) {
+	positions, err := s.positions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	optionsPositions := []*interfaces.OptionsPosition{}
+	for _, pos := range positions {
+		if !IsOCCSymbol(pos.Symbol) {
+			continue
+		}
+		option := &interfaces.OptionsPosition{
+			Symbol:         pos.Symbol,
+			Qty:            pos.Qty,
+			AvgEntryPrice:  pos.AvgEntryPrice,
+			MarketValue:    pos.MarketValue,
+			CostBasis:      pos.CostBasis,
+			UnrealizedPL:   pos.UnrealizedPL,
+			UnrealizedPLPC: pos.UnrealizedPLPC,
+			CurrentPrice:   pos.CurrentPrice,
+			Side:           pos.Side,
+		}
+		fillOptionDetails(option)
+		optionsPositions = append(optionsPositions, option)
+	}
+
+	return optionsPositions, nil
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"prophet-trader/interfaces"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Upstreams recorded outside the broker and data providers, which are
+// recorded under their registered names
+const (
+	UpstreamGemini       = "gemini"
+	UpstreamNews         = "news"
+	UpstreamFundamentals = "fundamentals"
+)
+
+// UpstreamStat counts calls to one upstream and the time spent in them.
+// Latency is summed, so calls made in parallel can add up to more than the
+// wall-clock time of the request.
+type UpstreamStat struct {
+	Calls     int     `json:"calls"`
+	Errors    int     `json:"errors"`
+	LatencyMs float64 `json:"latency_ms"`
+	MaxMs     float64 `json:"max_ms"`
+}
+
+func (s *UpstreamStat) add(latency time.Duration, err error) {
+	ms := float64(latency) / float64(time.Millisecond)
+	s.Calls++
+	if err != nil {
+		s.Errors++
+	}
+	s.LatencyMs += ms
+	if ms > s.MaxMs {
+		s.MaxMs = ms
+	}
+}
+
+func (s *UpstreamStat) merge(other UpstreamStat) {
+	s.Calls += other.Calls
+	s.Errors += other.Errors
+	s.LatencyMs += other.LatencyMs
+	if other.MaxMs > s.MaxMs {
+		s.MaxMs = other.MaxMs
+	}
+}
+
+// UpstreamUsage collects the upstream calls made on behalf of one request
+type UpstreamUsage struct {
+	stats map[string]*UpstreamStat
+	mu    sync.Mutex
+}
+
+// NewUpstreamUsage creates an empty usage record
+func NewUpstreamUsage() *UpstreamUsage {
+	return &UpstreamUsage{stats: make(map[string]*UpstreamStat)}
+}
+
+type upstreamUsageKey struct{}
+
+// WithUpstreamUsage returns a context whose upstream calls are recorded in
+// usage
+func WithUpstreamUsage(ctx context.Context, usage *UpstreamUsage) context.Context {
+	return context.WithValue(ctx, upstreamUsageKey{}, usage)
+}
+
+// UpstreamUsageFrom returns the usage record attached to ctx, if any
+func UpstreamUsageFrom(ctx context.Context) (*UpstreamUsage, bool) {
+	usage, ok := ctx.Value(upstreamUsageKey{}).(*UpstreamUsage)
+	return usage, ok
+}
+
+func (u *UpstreamUsage) record(upstream string, latency time.Duration, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	stat, ok := u.stats[upstream]
+	if !ok {
+		stat = &UpstreamStat{}
+		u.stats[upstream] = stat
+	}
+	stat.add(latency, err)
+}
+
+// Snapshot returns the calls recorded so far by upstream
+func (u *UpstreamUsage) Snapshot() map[string]UpstreamStat {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	snapshot := make(map[string]UpstreamStat, len(u.stats))
+	for upstream, stat := range u.stats {
+		snapshot[upstream] = *stat
+	}
+	return snapshot
+}
+
+// RecordUpstreamCall records a call to upstream that began at started,
+// against the request in ctx and the process-wide metrics
+func RecordUpstreamCall(ctx context.Context, upstream string, started time.Time, err error) {
+	latency := time.Since(started)
+	if usage, ok := UpstreamUsageFrom(ctx); ok {
+		usage.record(upstream, latency, err)
+	}
+	DefaultUpstreamMetrics.recordCall(upstream, latency, err)
+}
+
+// RouteUpstreamMetrics aggregates the upstream usage of one HTTP route
+type RouteUpstreamMetrics struct {
+	Method        string                  `json:"method"`
+	Route         string                  `json:"route"`
+	Requests      int                     `json:"requests"`
+	TotalMs       float64                 `json:"total_ms"` // handler wall-clock time
+	MaxMs         float64                 `json:"max_ms"`
+	UpstreamCalls int                     `json:"upstream_calls"`
+	UpstreamMs    float64                 `json:"upstream_ms"`
+	Upstreams     map[string]UpstreamStat `json:"upstreams"`
+}
+
+// UpstreamMetricsSnapshot is the process-wide view of upstream usage
+type UpstreamMetricsSnapshot struct {
+	Since     time.Time               `json:"since"`
+	Upstreams map[string]UpstreamStat `json:"upstreams"` // every call, including background work
+	Routes    []RouteUpstreamMetrics  `json:"routes"`    // sorted by upstream time, highest first
+}
+
+// UpstreamMetrics aggregates upstream calls by upstream and by HTTP route
+// since the process started
+type UpstreamMetrics struct {
+	since     time.Time
+	upstreams map[string]*UpstreamStat
+	routes    map[string]*RouteUpstreamMetrics
+	mu        sync.Mutex
+}
+
+// DefaultUpstreamMetrics receives every call passed to RecordUpstreamCall
+var DefaultUpstreamMetrics = NewUpstreamMetrics()
+
+// NewUpstreamMetrics creates empty metrics
+func NewUpstreamMetrics() *UpstreamMetrics {
+	return &UpstreamMetrics{
+		since:     time.Now(),
+		upstreams: make(map[string]*UpstreamStat),
+		routes:    make(map[string]*RouteUpstreamMetrics),
+	}
+}
+
+func (m *UpstreamMetrics) recordCall(upstream string, latency time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stat, ok := m.upstreams[upstream]
+	if !ok {
+		stat = &UpstreamStat{}
+		m.upstreams[upstream] = stat
+	}
+	stat.add(latency, err)
+}
+
+// RecordRequest adds one finished request on route and its upstream usage
+func (m *UpstreamMetrics) RecordRequest(method, route string, elapsed time.Duration, usage map[string]UpstreamStat) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := method + " " + route
+	metrics, ok := m.routes[key]
+	if !ok {
+		metrics = &RouteUpstreamMetrics{Method: method, Route: route, Upstreams: make(map[string]UpstreamStat)}
+		m.routes[key] = metrics
+	}
+	ms := float64(elapsed) / float64(time.Millisecond)
+	metrics.Requests++
+	metrics.TotalMs += ms
+	if ms > metrics.MaxMs {
+		metrics.MaxMs = ms
+	}
+	for upstream, stat := range usage {
+		total := metrics.Upstreams[upstream]
+		total.merge(stat)
+		metrics.Upstreams[upstream] = total
+		metrics.UpstreamCalls += stat.Calls
+		metrics.UpstreamMs += stat.LatencyMs
+	}
+}
+
+// Snapshot returns a copy of the metrics
+func (m *UpstreamMetrics) Snapshot() *UpstreamMetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := &UpstreamMetricsSnapshot{
+		Since:     m.since,
+		Upstreams: make(map[string]UpstreamStat, len(m.upstreams)),
+		Routes:    make([]RouteUpstreamMetrics, 0, len(m.routes)),
+	}
+	for upstream, stat := range m.upstreams {
+		snapshot.Upstreams[upstream] = *stat
+	}
+	for _, route := range m.routes {
+		copied := *route
+		copied.Upstreams = make(map[string]UpstreamStat, len(route.Upstreams))
+		for upstream, stat := range route.Upstreams {
+			copied.Upstreams[upstream] = stat
+		}
+		snapshot.Routes = append(snapshot.Routes, copied)
+	}
+	sort.Slice(snapshot.Routes, func(i, j int) bool {
+		return snapshot.Routes[i].UpstreamMs > snapshot.Routes[j].UpstreamMs
+	})
+	return snapshot
+}
+
+// InstrumentedTradingService records every broker call as an upstream call
+type InstrumentedTradingService struct {
+	interfaces.TradingService
+	upstream string
+}
+
+// NewInstrumentedTradingService wraps trading, recording its calls under
+// upstream
+func NewInstrumentedTradingService(trading interfaces.TradingService, upstream string) *InstrumentedTradingService {
+	return &InstrumentedTradingService{TradingService: trading, upstream: upstream}
+}
+
+// PlaceOrder implements interfaces.TradingService
+func (s *InstrumentedTradingService) PlaceOrder(ctx context.Context, order *interfaces.Order) (*interfaces.OrderResult, error) {
+	started := time.Now()
+	result, err := s.TradingService.PlaceOrder(ctx, order)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return result, err
+}
+
+// CancelOrder implements interfaces.TradingService
+func (s *InstrumentedTradingService) CancelOrder(ctx context.Context, orderID string) error {
+	started := time.Now()
+	err := s.TradingService.CancelOrder(ctx, orderID)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return err
+}
+
+// GetOrder implements interfaces.TradingService
+func (s *InstrumentedTradingService) GetOrder(ctx context.Context, orderID string) (*interfaces.Order, error) {
+	started := time.Now()
+	order, err := s.TradingService.GetOrder(ctx, orderID)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return order, err
+}
+
+// ListOrders implements interfaces.TradingService
+func (s *InstrumentedTradingService) ListOrders(ctx context.Context, status string) ([]*interfaces.Order, error) {
+	started := time.Now()
+	orders, err := s.TradingService.ListOrders(ctx, status)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return orders, err
+}
+
+// GetPositions implements interfaces.TradingService
+func (s *InstrumentedTradingService) GetPositions(ctx context.Context) ([]*interfaces.Position, error) {
+	started := time.Now()
+	positions, err := s.TradingService.GetPositions(ctx)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return positions, err
+}
+
+// GetAccount implements interfaces.TradingService
+func (s *InstrumentedTradingService) GetAccount(ctx context.Context) (*interfaces.Account, error) {
+	started := time.Now()
+	account, err := s.TradingService.GetAccount(ctx)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return account, err
+}
+
+// GetPortfolioHistory implements interfaces.TradingService
+func (s *InstrumentedTradingService) GetPortfolioHistory(ctx context.Context, start, end time.Time) ([]*interfaces.EquitySnapshot, error) {
+	started := time.Now()
+	history, err := s.TradingService.GetPortfolioHistory(ctx, start, end)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return history, err
+}
+
+// PlaceOptionsOrder implements interfaces.TradingService
+func (s *InstrumentedTradingService) PlaceOptionsOrder(ctx context.Context, order *interfaces.OptionsOrder) (*interfaces.OrderResult, error) {
+	started := time.Now()
+	result, err := s.TradingService.PlaceOptionsOrder(ctx, order)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return result, err
+}
+
+// GetOptionsChain implements interfaces.TradingService
+func (s *InstrumentedTradingService) GetOptionsChain(ctx context.Context, underlying string, expiration time.Time) ([]*interfaces.OptionContract, error) {
+	started := time.Now()
+	chain, err := s.TradingService.GetOptionsChain(ctx, underlying, expiration)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return chain, err
+}
+
+// GetOptionsQuote implements interfaces.TradingService
+func (s *InstrumentedTradingService) GetOptionsQuote(ctx context.Context, symbol string) (*interfaces.OptionsQuote, error) {
+	started := time.Now()
+	quote, err := s.TradingService.GetOptionsQuote(ctx, symbol)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return quote, err
+}
+
+// GetOptionsPosition implements interfaces.TradingService
+func (s *InstrumentedTradingService) GetOptionsPosition(ctx context.Context, symbol string) (*interfaces.OptionsPosition, error) {
+	started := time.Now()
+	position, err := s.TradingService.GetOptionsPosition(ctx, symbol)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return position, err
+}
+
+// ListOptionsPositions implements interfaces.TradingService
+func (s *InstrumentedTradingService) ListOptionsPositions(ctx context.Context) ([]*interfaces.OptionsPosition, error) {
+	started := time.Now()
+	positions, err := s.TradingService.ListOptionsPositions(ctx)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return positions, err
+}
+
+// InstrumentedDataService records every market data call as an upstream
+// call. Streams are long-lived and not counted.
+type InstrumentedDataService struct {
+	interfaces.DataService
+	upstream string
+}
+
+// NewInstrumentedDataService wraps data, recording its calls under upstream
+func NewInstrumentedDataService(data interfaces.DataService, upstream string) *InstrumentedDataService {
+	return &InstrumentedDataService{DataService: data, upstream: upstream}
+}
+
+// GetHistoricalBars implements interfaces.DataService
+func (s *InstrumentedDataService) GetHistoricalBars(ctx context.Context, symbol string, start, end time.Time, timeframe string) ([]*interfaces.Bar, error) {
+	started := time.Now()
+	bars, err := s.DataService.GetHistoricalBars(ctx, symbol, start, end, timeframe)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return bars, err
+}
+
+// GetLatestBar implements interfaces.DataService
+func (s *InstrumentedDataService) GetLatestBar(ctx context.Context, symbol string) (*interfaces.Bar, error) {
+	started := time.Now()
+	bar, err := s.DataService.GetLatestBar(ctx, symbol)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return bar, err
+}
+
+// GetLatestQuote implements interfaces.DataService
+func (s *InstrumentedDataService) GetLatestQuote(ctx context.Context, symbol string) (*interfaces.Quote, error) {
+	started := time.Now()
+	quote, err := s.DataService.GetLatestQuote(ctx, symbol)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return quote, err
+}
+
+// GetLatestTrade implements interfaces.DataService
+func (s *InstrumentedDataService) GetLatestTrade(ctx context.Context, symbol string) (*interfaces.Trade, error) {
+	started := time.Now()
+	trade, err := s.DataService.GetLatestTrade(ctx, symbol)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return trade, err
+}
+
+// InstrumentedOptionsQuotes records batched options quote calls as upstream
+// calls
+type InstrumentedOptionsQuotes struct {
+	source   OptionsQuoteSource
+	upstream string
+}
+
+// NewInstrumentedOptionsQuotes wraps source, recording its calls under
+// upstream
+func NewInstrumentedOptionsQuotes(source OptionsQuoteSource, upstream string) *InstrumentedOptionsQuotes {
+	return &InstrumentedOptionsQuotes{source: source, upstream: upstream}
+}
+
+// GetOptionsQuotes implements OptionsQuoteSource
+func (s *InstrumentedOptionsQuotes) GetOptionsQuotes(ctx context.Context, symbols []string) (map[string]*interfaces.OptionsQuote, error) {
+	started := time.Now()
+	quotes, err := s.source.GetOptionsQuotes(ctx, symbols)
+	RecordUpstreamCall(ctx, s.upstream, started, err)
+	return quotes, err
+}
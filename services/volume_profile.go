@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"prophet-trader/interfaces"
+	"time"
+)
+
+// VolumeProfileOptions controls the session and resolution of a volume profile
+type VolumeProfileOptions struct {
+	Date      time.Time // session date; zero uses the most recent session
+	Timeframe string    // intraday bar size, default "5Min"
+	Bins      int       // price levels in the profile, default 24
+	ValueArea float64   // share of volume in the value area, default 0.70
+	Extended  bool      // include pre- and post-market bars
+}
+
+// VolumeLevel is the traded volume within one price bin
+type VolumeLevel struct {
+	PriceLow  float64 `json:"price_low"`
+	PriceHigh float64 `json:"price_high"`
+	Volume    float64 `json:"volume"`
+	Percent   float64 `json:"percent"`
+	InValue   bool    `json:"in_value_area"`
+}
+
+// VWAPPoint is the session VWAP and its bands at one bar
+type VWAPPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Close     float64   `json:"close"`
+	VWAP      float64   `json:"vwap"`
+	StdDev    float64   `json:"std_dev"`
+	Upper1    float64   `json:"upper_1"`
+	Lower1    float64   `json:"lower_1"`
+	Upper2    float64   `json:"upper_2"`
+	Lower2    float64   `json:"lower_2"`
+	Upper3    float64   `json:"upper_3"`
+	Lower3    float64   `json:"lower_3"`
+}
+
+// VolumeProfileResult contains the session volume profile and VWAP bands
+type VolumeProfileResult struct {
+	Symbol        string        `json:"symbol"`
+	Session       string        `json:"session"`
+	Timeframe     string        `json:"timeframe"`
+	Bars          int           `json:"bars"`
+	TotalVolume   float64       `json:"total_volume"`
+	CurrentPrice  float64       `json:"current_price"`
+	POC           float64       `json:"poc"` // point of control: price with the most volume
+	ValueAreaHigh float64       `json:"value_area_high"`
+	ValueAreaLow  float64       `json:"value_area_low"`
+	ValueAreaPct  float64       `json:"value_area_percent"`
+	VWAP          *VWAPPoint    `json:"vwap"`
+	PricePosition string        `json:"price_position"` // "above_value", "in_value", "below_value"
+	VWAPPosition  string        `json:"vwap_position"`  // which band the current price sits in
+	Levels        []VolumeLevel `json:"levels"`
+	VWAPSeries    []VWAPPoint   `json:"vwap_series"`
+}
+
+// VolumeProfile builds an intraday volume profile and session VWAP with
+// standard deviation bands
+func (tas *TechnicalAnalysisService) VolumeProfile(ctx context.Context, symbol string, opts VolumeProfileOptions) (*VolumeProfileResult, error) {
+	if opts.Timeframe == "" {
+		opts.Timeframe = "5Min"
+	}
+	timeframe, err := NormalizeBarSpec(opts.Timeframe)
+	if err != nil {
+		return nil, err
+	}
+	opts.Timeframe = timeframe
+	if opts.Bins <= 0 {
+		opts.Bins = 24
+	}
+	if opts.ValueArea <= 0 || opts.ValueArea >= 1 {
+		opts.ValueArea = 0.70
+	}
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.UTC
+	}
+
+	// Without a date, look back far enough to cover weekends and holidays
+	end := time.Now()
+	start := end.AddDate(0, 0, -5)
+	if !opts.Date.IsZero() {
+		day := time.Date(opts.Date.Year(), opts.Date.Month(), opts.Date.Day(), 0, 0, 0, 0, loc)
+		start = day
+		end = day.Add(24 * time.Hour)
+		if end.After(time.Now()) {
+			end = time.Now()
+		}
+	}
+
+	bars, err := tas.dataService.GetHistoricalBars(ctx, symbol, start, end, opts.Timeframe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get intraday bars: %w", err)
+	}
+
+	session := sessionBars(bars, loc, !opts.Extended)
+	if len(session) == 0 {
+		return nil, fmt.Errorf("no intraday bars for %s", symbol)
+	}
+
+	result := &VolumeProfileResult{
+		Symbol:       symbol,
+		Session:      session[0].Timestamp.In(loc).Format("2006-01-02"),
+		Timeframe:    opts.Timeframe,
+		Bars:         len(session),
+		CurrentPrice: session[len(session)-1].Close,
+	}
+
+	result.Levels = buildVolumeLevels(session, opts.Bins)
+	for _, level := range result.Levels {
+		result.TotalVolume += level.Volume
+	}
+	if result.TotalVolume == 0 {
+		return nil, fmt.Errorf("no volume traded for %s in session %s", symbol, result.Session)
+	}
+	applyValueArea(result, opts.ValueArea)
+
+	result.VWAPSeries = calculateVWAPBands(session)
+	result.VWAP = &result.VWAPSeries[len(result.VWAPSeries)-1]
+
+	switch {
+	case result.CurrentPrice > result.ValueAreaHigh:
+		result.PricePosition = "above_value"
+	case result.CurrentPrice < result.ValueAreaLow:
+		result.PricePosition = "below_value"
+	default:
+		result.PricePosition = "in_value"
+	}
+	result.VWAPPosition = vwapPosition(result.CurrentPrice, result.VWAP)
+
+	return result, nil
+}
+
+// sessionBars returns the bars of the most recent trading day
+func sessionBars(bars []*interfaces.Bar, loc *time.Location, regularHours bool) []*interfaces.Bar {
+	filtered := make([]*interfaces.Bar, 0, len(bars))
+	for _, bar := range bars {
+		if regularHours && !inRegularHours(bar.Timestamp) {
+			continue
+		}
+		filtered = append(filtered, bar)
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+
+	lastDay := filtered[len(filtered)-1].Timestamp.In(loc).Format("2006-01-02")
+	session := make([]*interfaces.Bar, 0)
+	for _, bar := range filtered {
+		if bar.Timestamp.In(loc).Format("2006-01-02") == lastDay {
+			session = append(session, bar)
+		}
+	}
+	return session
+}
+
+// buildVolumeLevels spreads each bar's volume evenly across the price bins
+// its range covers
+func buildVolumeLevels(bars []*interfaces.Bar, bins int) []VolumeLevel {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, bar := range bars {
+		low = math.Min(low, bar.Low)
+		high = math.Max(high, bar.High)
+	}
+	if high <= low {
+		high = low + 0.01
+	}
+	step := (high - low) / float64(bins)
+
+	levels := make([]VolumeLevel, bins)
+	for i := range levels {
+		levels[i].PriceLow = low + float64(i)*step
+		levels[i].PriceHigh = low + float64(i+1)*step
+	}
+
+	binFor := func(price float64) int {
+		i := int((price - low) / step)
+		if i >= bins {
+			i = bins - 1
+		}
+		if i < 0 {
+			i = 0
+		}
+		return i
+	}
+
+	for _, bar := range bars {
+		first, last := binFor(bar.Low), binFor(bar.High)
+		share := float64(bar.Volume) / float64(last-first+1)
+		for i := first; i <= last; i++ {
+			levels[i].Volume += share
+		}
+	}
+
+	return levels
+}
+
+// applyValueArea finds the POC and grows the value area outward from it,
+// adding the heavier neighbouring bin each step
+func applyValueArea(result *VolumeProfileResult, share float64) {
+	levels := result.Levels
+	poc := 0
+	for i, level := range levels {
+		levels[i].Percent = level.Volume / result.TotalVolume * 100
+		if level.Volume > levels[poc].Volume {
+			poc = i
+		}
+	}
+	result.POC = (levels[poc].PriceLow + levels[poc].PriceHigh) / 2
+
+	lo, hi := poc, poc
+	covered := levels[poc].Volume
+	target := result.TotalVolume * share
+	for covered < target && (lo > 0 || hi < len(levels)-1) {
+		below, above := -1.0, -1.0
+		if lo > 0 {
+			below = levels[lo-1].Volume
+		}
+		if hi < len(levels)-1 {
+			above = levels[hi+1].Volume
+		}
+		if above >= below {
+			hi++
+			covered += above
+		} else {
+			lo--
+			covered += below
+		}
+	}
+
+	for i := lo; i <= hi; i++ {
+		levels[i].InValue = true
+	}
+	result.ValueAreaLow = levels[lo].PriceLow
+	result.ValueAreaHigh = levels[hi].PriceHigh
+	result.ValueAreaPct = covered / result.TotalVolume * 100
+}
+
+// calculateVWAPBands computes the cumulative session VWAP and volume-weighted
+// standard deviation bands at every bar
+func calculateVWAPBands(bars []*interfaces.Bar) []VWAPPoint {
+	series := make([]VWAPPoint, 0, len(bars))
+	var cumVolume, cumPV, cumPV2 float64
+
+	for _, bar := range bars {
+		typical := (bar.High + bar.Low + bar.Close) / 3
+		volume := float64(bar.Volume)
+		cumVolume += volume
+		cumPV += typical * volume
+		cumPV2 += typical * typical * volume
+
+		point := VWAPPoint{Timestamp: bar.Timestamp, Close: bar.Close, VWAP: typical}
+		if cumVolume > 0 {
+			point.VWAP = cumPV / cumVolume
+			point.StdDev = math.Sqrt(math.Max(cumPV2/cumVolume-point.VWAP*point.VWAP, 0))
+		}
+		point.Upper1, point.Lower1 = point.VWAP+point.StdDev, point.VWAP-point.StdDev
+		point.Upper2, point.Lower2 = point.VWAP+2*point.StdDev, point.VWAP-2*point.StdDev
+		point.Upper3, point.Lower3 = point.VWAP+3*point.StdDev, point.VWAP-3*point.StdDev
+
+		series = append(series, point)
+	}
+
+	return series
+}
+
+// vwapPosition labels where a price sits relative to the VWAP bands
+func vwapPosition(price float64, v *VWAPPoint) string {
+	switch {
+	case price >= v.Upper3:
+		return "above_upper_3"
+	case price >= v.Upper2:
+		return "above_upper_2"
+	case price >= v.Upper1:
+		return "above_upper_1"
+	case price >= v.VWAP:
+		return "above_vwap"
+	case price > v.Lower1:
+		return "below_vwap"
+	case price > v.Lower2:
+		return "below_lower_1"
+	case price > v.Lower3:
+		return "below_lower_2"
+	default:
+		return "below_lower_3"
+	}
+}
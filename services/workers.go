@@ -0,0 +1,474 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Worker kinds
+const (
+	WorkerPeriodic = "periodic" // runs one pass every interval
+	WorkerStream   = "stream"   // runs until stopped, such as a subscription
+)
+
+// Worker states
+const (
+	WorkerRunning = "running"
+	WorkerPaused  = "paused"
+	WorkerStopped = "stopped" // its context ended, e.g. the writer lock was lost
+	WorkerFailed  = "failed"  // it panicked and waits to be restarted
+)
+
+// A worker that panics is restarted after a backoff that doubles with each
+// panic in a row, from workerBackoffMin up to workerBackoffMax
+const (
+	workerBackoffMin   = time.Second
+	workerBackoffMax   = 5 * time.Minute
+	workerHealthyAfter = time.Minute // a stream running this long resets its backoff
+)
+
+// PanicError is a panic recovered from a worker or stream consumer
+type PanicError struct {
+	Value interface{}
+	Stack string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// callSafely calls fn, returning a panic as a *PanicError
+func callSafely(fn func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &PanicError{Value: p, Stack: string(debug.Stack())}
+		}
+	}()
+	return fn()
+}
+
+// workerBackoff is the restart delay after panics panics in a row
+func workerBackoff(panics int) time.Duration {
+	delay := workerBackoffMin
+	for i := 1; i < panics && delay < workerBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > workerBackoffMax {
+		return workerBackoffMax
+	}
+	return delay
+}
+
+// WorkerStatus describes one background worker
+type WorkerStatus struct {
+	Name            string     `json:"name"`
+	Kind            string     `json:"kind"`
+	State           string     `json:"state"`
+	IntervalSeconds float64    `json:"interval_seconds,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	LastTickAt      *time.Time `json:"last_tick_at,omitempty"`
+	LastDurationMs  int64      `json:"last_duration_ms,omitempty"`
+	NextTickAt      *time.Time `json:"next_tick_at,omitempty"`
+	RetryAt         *time.Time `json:"retry_at,omitempty"` // when a worker that panicked runs again
+	Ticks           int64      `json:"ticks"`
+	Errors          int64      `json:"errors"`
+	Panics          int64      `json:"panics"`
+	Restarts        int64      `json:"restarts"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+}
+
+// worker is one registered background goroutine. Its fields are guarded by
+// the registry's lock.
+type worker struct {
+	status  WorkerStatus
+	gen     int // bumped on every start so a finished run can't mark a newer one stopped
+	paused  bool
+	streak  int // panics in a row
+	run     func(ctx context.Context)
+	trigger chan struct{}
+	parent  context.Context
+	cancel  context.CancelFunc
+}
+
+// WorkerRegistry runs the bot's background goroutines and keeps their
+// status, so a loop that stops or keeps failing shows up instead of dying
+// silently. Panics are recovered, logged with their stack and notified, and
+// the worker is restarted with backoff. Workers can be paused, resumed and,
+// when periodic, run at once.
+type WorkerRegistry struct {
+	workers  map[string]*worker
+	notifier *Notifier
+	mu       sync.Mutex
+	logger   *logrus.Logger
+}
+
+// NewWorkerRegistry creates an empty registry
+func NewWorkerRegistry() *WorkerRegistry {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+
+	return &WorkerRegistry{
+		workers: make(map[string]*worker),
+		logger:  logger,
+	}
+}
+
+// SetNotifier sends a critical notification when a worker starts panicking
+func (r *WorkerRegistry) SetNotifier(notifier *Notifier) {
+	r.notifier = notifier
+}
+
+// Every starts a worker calling tick every interval until ctx is done. A
+// tick that fails is counted and the worker carries on; after a panic its
+// ticks are skipped until the backoff has passed. Starting a name again,
+// such as after the writer lock is retaken, keeps its counts.
+func (r *WorkerRegistry) Every(ctx context.Context, name string, interval time.Duration, tick func(ctx context.Context) error) {
+	if interval <= 0 {
+		r.logger.WithField("worker", name).Error("Worker interval must be positive, not starting it")
+		return
+	}
+
+	r.mu.Lock()
+	w := r.register(name, WorkerPeriodic)
+	w.status.IntervalSeconds = interval.Seconds()
+	w.gen++
+	gen := w.gen
+	r.mu.Unlock()
+
+	r.logger.WithFields(logrus.Fields{"worker": name, "interval": interval}).Info("Worker started")
+	go r.loop(ctx, w, gen, name, interval, tick)
+}
+
+// Go starts a worker running run until ctx is done. A run that panics is
+// restarted after a backoff; one that returns is reported stopped.
+func (r *WorkerRegistry) Go(ctx context.Context, name string, run func(ctx context.Context)) {
+	r.mu.Lock()
+	w := r.register(name, WorkerStream)
+	w.run = run
+	w.parent = ctx
+	r.startStream(w)
+	r.mu.Unlock()
+
+	r.logger.WithField("worker", name).Info("Worker started")
+}
+
+// register returns the worker called name, creating it if needed. Call with
+// the lock held.
+func (r *WorkerRegistry) register(name, kind string) *worker {
+	w, ok := r.workers[name]
+	if !ok {
+		w = &worker{trigger: make(chan struct{}, 1)}
+		r.workers[name] = w
+	}
+	w.status.Name = name
+	w.status.Kind = kind
+	w.status.StartedAt = time.Now()
+	w.status.State = WorkerRunning
+	if w.paused {
+		w.status.State = WorkerPaused
+	}
+	return w
+}
+
+// loop runs a periodic worker
+func (r *WorkerRegistry) loop(ctx context.Context, w *worker, gen int, name string, interval time.Duration, tick func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer r.stopped(w, gen)
+
+	r.setNextTick(w, interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.setNextTick(w, interval)
+			if r.waiting(w) {
+				continue
+			}
+		case <-w.trigger:
+		}
+		r.runTick(ctx, w, name, tick)
+	}
+}
+
+// runTick runs one pass of a periodic worker and records its outcome
+func (r *WorkerRegistry) runTick(ctx context.Context, w *worker, name string, tick func(ctx context.Context) error) {
+	start := time.Now()
+	err := callSafely(func() error { return tick(ctx) })
+
+	r.mu.Lock()
+	w.status.Ticks++
+	w.status.LastTickAt = &start
+	w.status.LastDurationMs = time.Since(start).Milliseconds()
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		delay, notify := r.recordPanic(w, panicErr)
+		r.mu.Unlock()
+		if notify {
+			r.notifyPanic(name, panicErr, delay)
+		}
+		return
+	}
+
+	w.streak = 0
+	w.status.RetryAt = nil
+	if w.status.State == WorkerFailed {
+		w.status.State = WorkerRunning
+	}
+	if err != nil {
+		w.status.Errors++
+		w.status.LastError = err.Error()
+		w.status.LastErrorAt = &start
+	}
+	r.mu.Unlock()
+
+	if err != nil {
+		r.logger.WithError(err).WithField("worker", name).Error("Worker tick failed")
+	}
+}
+
+// startStream runs a stream worker under a context of its own so it can be
+// paused. Call with the lock held.
+func (r *WorkerRegistry) startStream(w *worker) {
+	if w.paused || w.parent.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(w.parent)
+	w.cancel = cancel
+	w.gen++
+	gen := w.gen
+	w.status.State = WorkerRunning
+	w.status.RetryAt = nil
+	name, run, parent := w.status.Name, w.run, w.parent
+
+	go func() {
+		defer cancel()
+		started := time.Now()
+		err := callSafely(func() error {
+			run(ctx)
+			return nil
+		})
+
+		r.mu.Lock()
+		var panicErr *PanicError
+		if w.gen != gen || !errors.As(err, &panicErr) || parent.Err() != nil {
+			if w.gen == gen && !w.paused {
+				w.status.State = WorkerStopped
+			}
+			r.mu.Unlock()
+			return
+		}
+
+		if time.Since(started) > workerHealthyAfter {
+			w.streak = 0
+		}
+		delay, notify := r.recordPanic(w, panicErr)
+		time.AfterFunc(delay, func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if w.gen == gen && w.status.State == WorkerFailed {
+				w.status.Restarts++
+				r.startStream(w)
+			}
+		})
+		r.mu.Unlock()
+
+		if notify {
+			r.notifyPanic(name, panicErr, delay)
+		}
+	}()
+}
+
+// recordPanic counts a panic and schedules the worker's restart. It returns
+// the backoff and whether the panic starts a new run of them, which is
+// notified. Call with the lock held.
+func (r *WorkerRegistry) recordPanic(w *worker, panicErr *PanicError) (time.Duration, bool) {
+	now := time.Now()
+	w.streak++
+	delay := workerBackoff(w.streak)
+	retryAt := now.Add(delay)
+
+	w.status.Panics++
+	w.status.Errors++
+	w.status.LastError = panicErr.Error()
+	w.status.LastErrorAt = &now
+	w.status.RetryAt = &retryAt
+	if !w.paused {
+		w.status.State = WorkerFailed
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"worker":     w.status.Name,
+		"panic":      panicErr.Value,
+		"in_a_row":   w.streak,
+		"restart_in": delay,
+		"stack":      panicErr.Stack,
+	}).Error("Worker panicked")
+
+	return delay, w.streak == 1
+}
+
+// notifyPanic reports a worker that started panicking. Later panics in a
+// row are only logged, so a worker failing on every tick doesn't flood the
+// channels.
+func (r *WorkerRegistry) notifyPanic(name string, panicErr *PanicError, delay time.Duration) {
+	if r.notifier == nil {
+		return
+	}
+	r.notifier.Notify(context.Background(), NotifyCritical, "Background worker panicked",
+		fmt.Sprintf("%s panicked: %v. Restarting in %s.", name, panicErr.Value, delay),
+		map[string]interface{}{
+			"worker":     name,
+			"panic":      fmt.Sprint(panicErr.Value),
+			"restart_in": delay.String(),
+		})
+}
+
+// List returns the status of every worker, by name
+func (r *WorkerRegistry) List() []WorkerStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]WorkerStatus, 0, len(r.workers))
+	for _, w := range r.workers {
+		statuses = append(statuses, w.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Get returns the status of one worker
+func (r *WorkerRegistry) Get(name string) (*WorkerStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	status := w.status
+	return &status, nil
+}
+
+// Pause stops a worker until it is resumed. A periodic worker skips its
+// ticks; a stream is stopped.
+func (r *WorkerRegistry) Pause(name string) (*WorkerStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	w.paused = true
+	if w.status.State == WorkerRunning || w.status.State == WorkerFailed {
+		w.status.State = WorkerPaused
+	}
+	if w.status.Kind == WorkerStream && w.cancel != nil {
+		w.cancel()
+	}
+	r.logger.WithField("worker", name).Warn("Worker paused")
+
+	status := w.status
+	return &status, nil
+}
+
+// Resume restarts a paused worker
+func (r *WorkerRegistry) Resume(name string) (*WorkerStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if !w.paused {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("worker %s is not paused", name))
+	}
+	w.paused = false
+	if w.status.State == WorkerPaused {
+		w.status.State = WorkerRunning
+		if w.status.Kind == WorkerStream {
+			// A stream waiting out its backoff restarts now
+			r.startStream(w)
+		} else if w.status.RetryAt != nil && time.Now().Before(*w.status.RetryAt) {
+			w.status.State = WorkerFailed
+		}
+	}
+	r.logger.WithField("worker", name).Info("Worker resumed")
+
+	status := w.status
+	return &status, nil
+}
+
+// Trigger runs a periodic worker's next pass now, even while it is paused
+func (r *WorkerRegistry) Trigger(name string) (*WorkerStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if w.status.Kind != WorkerPeriodic {
+		return nil, WithErrorCode(ErrCodeInvalidRequest, fmt.Errorf("worker %s does not run on a schedule", name))
+	}
+	if w.status.State == WorkerStopped {
+		return nil, WithErrorCode(ErrCodeUnavailable, fmt.Errorf("worker %s is not running on this instance", name))
+	}
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+		// A pass is already due
+	}
+
+	status := w.status
+	return &status, nil
+}
+
+// lookup finds a worker by name. Call with the lock held.
+func (r *WorkerRegistry) lookup(name string) (*worker, error) {
+	w, ok := r.workers[name]
+	if !ok {
+		return nil, WithErrorCode(ErrCodeNotFound, fmt.Errorf("worker %s not found", name))
+	}
+	return w, nil
+}
+
+// waiting reports whether a periodic worker skips its tick: it is paused or
+// backing off after a panic
+func (r *WorkerRegistry) waiting(w *worker) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return w.paused || (w.status.RetryAt != nil && time.Now().Before(*w.status.RetryAt))
+}
+
+func (r *WorkerRegistry) setNextTick(w *worker, interval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := time.Now().Add(interval)
+	w.status.NextTickAt = &next
+}
+
+// stopped marks a periodic worker stopped once its loop ends, unless it has
+// been started again since
+func (r *WorkerRegistry) stopped(w *worker, gen int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if w.gen == gen {
+		w.status.State = WorkerStopped
+		w.status.NextTickAt = nil
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"prophet-trader/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterFundamentalsProvider("yahoo", func(cfg *config.Config) (FundamentalsProvider, error) {
+		return NewYahooFundamentalsProvider(), nil
+	})
+}
+
+const (
+	yahooCookieURL       = "https://fc.yahoo.com"
+	yahooCrumbURL        = "https://query2.finance.yahoo.com/v1/test/getcrumb"
+	yahooQuoteSummaryURL = "https://query2.finance.yahoo.com/v10/finance/quoteSummary/"
+	yahooUserAgent       = "Mozilla/5.0 (compatible; prophet-trader)"
+)
+
+// errYahooUnauthorized means the cookie session or crumb was rejected
+var errYahooUnauthorized = errors.New("Yahoo Finance session rejected")
+
+// YahooFundamentalsProvider reads fundamentals from Yahoo Finance's
+// quoteSummary API. Yahoo requires a session cookie and matching crumb,
+// which are obtained on first use and refreshed when rejected.
+type YahooFundamentalsProvider struct {
+	httpClient *http.Client
+	crumb      string
+	mu         sync.Mutex
+}
+
+// NewYahooFundamentalsProvider creates a Yahoo Finance fundamentals provider
+func NewYahooFundamentalsProvider() *YahooFundamentalsProvider {
+	jar, _ := cookiejar.New(nil)
+	return &YahooFundamentalsProvider{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Jar:     jar,
+		},
+	}
+}
+
+// yahooValue is Yahoo's {"raw": 1.23, "fmt": "1.23"} number wrapper
+type yahooValue struct {
+	Raw float64 `json:"raw"`
+}
+
+type yahooQuoteSummary struct {
+	QuoteSummary struct {
+		Result []struct {
+			Price struct {
+				ShortName          string     `json:"shortName"`
+				Currency           string     `json:"currency"`
+				RegularMarketPrice yahooValue `json:"regularMarketPrice"`
+				MarketCap          yahooValue `json:"marketCap"`
+			} `json:"price"`
+			SummaryDetail struct {
+				TrailingPE       yahooValue `json:"trailingPE"`
+				ForwardPE        yahooValue `json:"forwardPE"`
+				DividendYield    yahooValue `json:"dividendYield"`
+				Beta             yahooValue `json:"beta"`
+				FiftyTwoWeekHigh yahooValue `json:"fiftyTwoWeekHigh"`
+				FiftyTwoWeekLow  yahooValue `json:"fiftyTwoWeekLow"`
+			} `json:"summaryDetail"`
+			DefaultKeyStatistics struct {
+				TrailingEps yahooValue `json:"trailingEps"`
+				ForwardEps  yahooValue `json:"forwardEps"`
+				PegRatio    yahooValue `json:"pegRatio"`
+			} `json:"defaultKeyStatistics"`
+			FinancialData struct {
+				RevenueGrowth           yahooValue `json:"revenueGrowth"`
+				EarningsGrowth          yahooValue `json:"earningsGrowth"`
+				ProfitMargins           yahooValue `json:"profitMargins"`
+				TargetMeanPrice         yahooValue `json:"targetMeanPrice"`
+				TargetHighPrice         yahooValue `json:"targetHighPrice"`
+				TargetLowPrice          yahooValue `json:"targetLowPrice"`
+				NumberOfAnalystOpinions yahooValue `json:"numberOfAnalystOpinions"`
+				RecommendationKey       string     `json:"recommendationKey"`
+			} `json:"financialData"`
+			CalendarEvents struct {
+				Earnings struct {
+					EarningsDate []yahooValue `json:"earningsDate"` // epoch seconds
+				} `json:"earnings"`
+			} `json:"calendarEvents"`
+		} `json:"result"`
+		Error *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"quoteSummary"`
+}
+
+// GetFundamentals fetches fundamentals for symbol
+func (p *YahooFundamentalsProvider) GetFundamentals(ctx context.Context, symbol string) (*Fundamentals, error) {
+	summary, err := p.quoteSummary(ctx, symbol)
+	if err == errYahooUnauthorized {
+		// The crumb expired; get a new session and retry once
+		p.mu.Lock()
+		p.crumb = ""
+		p.mu.Unlock()
+		summary, err = p.quoteSummary(ctx, symbol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if summary.QuoteSummary.Error != nil {
+		return nil, fmt.Errorf("Yahoo Finance error: %s", summary.QuoteSummary.Error.Description)
+	}
+	if len(summary.QuoteSummary.Result) == 0 {
+		return nil, fmt.Errorf("no fundamentals found for symbol: %s", symbol)
+	}
+	r := summary.QuoteSummary.Result[0]
+
+	f := &Fundamentals{
+		Symbol:            symbol,
+		Name:              r.Price.ShortName,
+		Currency:          r.Price.Currency,
+		Price:             r.Price.RegularMarketPrice.Raw,
+		MarketCap:         r.Price.MarketCap.Raw,
+		TrailingPE:        r.SummaryDetail.TrailingPE.Raw,
+		ForwardPE:         r.SummaryDetail.ForwardPE.Raw,
+		PEGRatio:          r.DefaultKeyStatistics.PegRatio.Raw,
+		EPS:               r.DefaultKeyStatistics.TrailingEps.Raw,
+		ForwardEPS:        r.DefaultKeyStatistics.ForwardEps.Raw,
+		RevenueGrowthPct:  r.FinancialData.RevenueGrowth.Raw * 100,
+		EarningsGrowthPct: r.FinancialData.EarningsGrowth.Raw * 100,
+		ProfitMarginPct:   r.FinancialData.ProfitMargins.Raw * 100,
+		DividendYieldPct:  r.SummaryDetail.DividendYield.Raw * 100,
+		Beta:              r.SummaryDetail.Beta.Raw,
+		High52Week:        r.SummaryDetail.FiftyTwoWeekHigh.Raw,
+		Low52Week:         r.SummaryDetail.FiftyTwoWeekLow.Raw,
+		TargetMeanPrice:   r.FinancialData.TargetMeanPrice.Raw,
+		TargetHighPrice:   r.FinancialData.TargetHighPrice.Raw,
+		TargetLowPrice:    r.FinancialData.TargetLowPrice.Raw,
+		AnalystCount:      int(r.FinancialData.NumberOfAnalystOpinions.Raw),
+		Recommendation:    r.FinancialData.RecommendationKey,
+		Source:            "yahoo",
+		FetchedAt:         time.Now(),
+	}
+
+	// Earnings may be a range; the first upcoming date is the next report
+	for _, d := range r.CalendarEvents.Earnings.EarningsDate {
+		date := time.Unix(int64(d.Raw), 0)
+		if date.After(time.Now().Add(-24 * time.Hour)) {
+			f.NextEarningsDate = &date
+			break
+		}
+	}
+
+	return f, nil
+}
+
+func (p *YahooFundamentalsProvider) quoteSummary(ctx context.Context, symbol string) (*yahooQuoteSummary, error) {
+	crumb, err := p.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{
+		"modules": {"price,summaryDetail,defaultKeyStatistics,financialData,calendarEvents"},
+		"crumb":   {crumb},
+	}
+	body, status, err := p.get(ctx, yahooQuoteSummaryURL+url.PathEscape(strings.ToUpper(symbol))+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		return nil, errYahooUnauthorized
+	}
+
+	// Unknown symbols come back as 404 with the error in the body
+	var summary yahooQuoteSummary
+	if err := json.Unmarshal(body, &summary); err != nil {
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("Yahoo Finance API error (HTTP %d): %s", status, strings.TrimSpace(string(body)))
+		}
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &summary, nil
+}
+
+// session returns the crumb for the current cookie session, creating one if needed
+func (p *YahooFundamentalsProvider) session(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.crumb != "" {
+		return p.crumb, nil
+	}
+
+	// fc.yahoo.com answers 404 but sets the session cookie
+	if _, _, err := p.get(ctx, yahooCookieURL); err != nil {
+		return "", fmt.Errorf("failed to start Yahoo Finance session: %w", err)
+	}
+	body, status, err := p.get(ctx, yahooCrumbURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to get Yahoo Finance crumb: %w", err)
+	}
+	crumb := strings.TrimSpace(string(body))
+	if status != http.StatusOK || crumb == "" {
+		return "", fmt.Errorf("failed to get Yahoo Finance crumb (HTTP %d)", status)
+	}
+
+	p.crumb = crumb
+	return crumb, nil
+}
+
+func (p *YahooFundamentalsProvider) get(ctx context.Context, endpoint string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", yahooUserAgent)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
@@ -0,0 +1,47 @@
+package strategies
+
+import (
+	"context"
+	"prophet-trader/interfaces"
+)
+
+func init() {
+	Register("buy_and_hold", func() interfaces.StrategyExecutor { return &BuyAndHold{} })
+}
+
+// BuyAndHold buys on the first bar and never sells.
+// It is the baseline other strategies are compared against in backtests.
+type BuyAndHold struct {
+	holding bool
+}
+
+// Initialize implements interfaces.StrategyExecutor
+func (s *BuyAndHold) Initialize(config map[string]interface{}) error {
+	s.holding = false
+	return nil
+}
+
+// ShouldBuy buys once, sizing is left to the caller
+func (s *BuyAndHold) ShouldBuy(ctx context.Context, symbol string, data *interfaces.MarketData) (bool, *interfaces.OrderRequest) {
+	return !s.holding, nil
+}
+
+// ShouldSell never exits
+func (s *BuyAndHold) ShouldSell(ctx context.Context, symbol string, data *interfaces.MarketData) (bool, *interfaces.OrderRequest) {
+	return false, nil
+}
+
+// OnOrderFilled records that the position is open
+func (s *BuyAndHold) OnOrderFilled(order *interfaces.Order) {
+	if order.Side == "buy" {
+		s.holding = true
+	}
+}
+
+// OnMarketData implements interfaces.StrategyExecutor
+func (s *BuyAndHold) OnMarketData(data *interfaces.MarketData) {}
+
+// GetName implements interfaces.StrategyExecutor
+func (s *BuyAndHold) GetName() string {
+	return "buy_and_hold"
+}
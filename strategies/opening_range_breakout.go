@@ -0,0 +1,155 @@
+package strategies
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/interfaces"
+	"time"
+)
+
+func init() {
+	Register("opening_range_breakout", func() interfaces.StrategyExecutor { return &OpeningRangeBreakout{} })
+}
+
+// OpeningRangeBreakout buys when price closes above the high of the first
+// minutes of the session and is flat by the close. It needs intraday bars
+// (e.g. 5Min); on daily bars no range ever forms and it never trades.
+//
+// Parameters (config keys):
+//   - range_minutes: length of the opening range after 9:30 ET (default 30)
+//   - target_multiple: take profit this many range widths above the range
+//     high, 0 disables the target (default 2)
+//   - exit_minutes_before_close: sell this long before 16:00 ET (default 15)
+//
+// The stop is the low of the range. There is at most one entry per day.
+type OpeningRangeBreakout struct {
+	rangeMinutes    int
+	targetMultiple  float64
+	exitBeforeClose int
+
+	location  *time.Location
+	day       string
+	rangeHigh float64
+	rangeLow  float64
+	rangeDone bool
+	traded    bool
+	holding   bool
+}
+
+// Initialize implements interfaces.StrategyExecutor
+func (s *OpeningRangeBreakout) Initialize(config map[string]interface{}) error {
+	var err error
+	if s.rangeMinutes, err = intParam(config, "range_minutes", 30); err != nil {
+		return err
+	}
+	if s.targetMultiple, err = floatParam(config, "target_multiple", 2); err != nil {
+		return err
+	}
+	if s.exitBeforeClose, err = intParam(config, "exit_minutes_before_close", 15); err != nil {
+		return err
+	}
+
+	if s.rangeMinutes < 1 || s.rangeMinutes >= 390 {
+		return fmt.Errorf("range_minutes must be between 1 and 389")
+	}
+	if s.targetMultiple < 0 || s.exitBeforeClose < 0 {
+		return fmt.Errorf("target_multiple and exit_minutes_before_close cannot be negative")
+	}
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.UTC
+	}
+	s.location = loc
+	s.day = ""
+	s.holding = false
+	return nil
+}
+
+// sessionMinute is the bar's minute of the regular session (0 at 9:30 ET)
+// and its trading day
+func (s *OpeningRangeBreakout) sessionMinute(bar *interfaces.Bar) (int, string) {
+	t := bar.Timestamp.In(s.location)
+	return t.Hour()*60 + t.Minute() - (9*60 + 30), t.Format("2006-01-02")
+}
+
+// OnMarketData builds the day's opening range from bars starting inside it
+func (s *OpeningRangeBreakout) OnMarketData(data *interfaces.MarketData) {
+	bar := data.CurrentBar
+	if bar == nil {
+		return
+	}
+	minute, day := s.sessionMinute(bar)
+	if day != s.day {
+		s.day = day
+		s.rangeHigh = 0
+		s.rangeLow = 0
+		s.rangeDone = false
+		s.traded = false
+	}
+	if minute < 0 {
+		return // pre-market
+	}
+	if minute >= s.rangeMinutes {
+		s.rangeDone = s.rangeHigh > 0
+		return
+	}
+	if s.rangeHigh == 0 || bar.High > s.rangeHigh {
+		s.rangeHigh = bar.High
+	}
+	if s.rangeLow == 0 || bar.Low < s.rangeLow {
+		s.rangeLow = bar.Low
+	}
+}
+
+// ShouldBuy buys the first close above the range high
+func (s *OpeningRangeBreakout) ShouldBuy(ctx context.Context, symbol string, data *interfaces.MarketData) (bool, *interfaces.OrderRequest) {
+	if s.holding || s.traded || !s.rangeDone || data.CurrentBar == nil {
+		return false, nil
+	}
+	if s.pastExit(data.CurrentBar) {
+		return false, nil
+	}
+	return data.CurrentBar.Close > s.rangeHigh, nil
+}
+
+// ShouldSell exits at the range low, the target or near the close
+func (s *OpeningRangeBreakout) ShouldSell(ctx context.Context, symbol string, data *interfaces.MarketData) (bool, *interfaces.OrderRequest) {
+	if !s.holding || data.CurrentBar == nil {
+		return false, nil
+	}
+	bar := data.CurrentBar
+	// A position carried into a new day (missing bars) is closed at once
+	if !s.rangeDone {
+		return true, nil
+	}
+	if bar.Close < s.rangeLow || s.pastExit(bar) {
+		return true, nil
+	}
+	if s.targetMultiple > 0 {
+		target := s.rangeHigh + s.targetMultiple*(s.rangeHigh-s.rangeLow)
+		return bar.Close >= target, nil
+	}
+	return false, nil
+}
+
+// pastExit reports whether the bar is in the closing window
+func (s *OpeningRangeBreakout) pastExit(bar *interfaces.Bar) bool {
+	minute, _ := s.sessionMinute(bar)
+	return minute >= 390-s.exitBeforeClose
+}
+
+// OnOrderFilled tracks the open position
+func (s *OpeningRangeBreakout) OnOrderFilled(order *interfaces.Order) {
+	if order.Side == "buy" {
+		s.holding = true
+		s.traded = true
+		return
+	}
+	s.holding = false
+}
+
+// GetName implements interfaces.StrategyExecutor
+func (s *OpeningRangeBreakout) GetName() string {
+	return "opening_range_breakout"
+}
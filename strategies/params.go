@@ -0,0 +1,46 @@
+package strategies
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// floatParam reads a numeric parameter from a strategy config. Values may
+// be numbers (JSON configs) or strings (command line flags).
+func floatParam(config map[string]interface{}, key string, def float64) (float64, error) {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return def, nil
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parameter %s: %q is not a number", key, v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("parameter %s: unsupported type %T", key, value)
+	}
+}
+
+// intParam reads a whole number parameter from a strategy config
+func intParam(config map[string]interface{}, key string, def int) (int, error) {
+	f, err := floatParam(config, key, float64(def))
+	if err != nil {
+		return 0, err
+	}
+	if f != float64(int(f)) {
+		return 0, fmt.Errorf("parameter %s: %v is not a whole number", key, f)
+	}
+	return int(f), nil
+}
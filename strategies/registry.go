@@ -0,0 +1,42 @@
+package strategies
+
+import (
+	"fmt"
+	"prophet-trader/interfaces"
+	"sort"
+)
+
+// Factory creates a fresh, uninitialized strategy instance
+type Factory func() interfaces.StrategyExecutor
+
+var registry = map[string]Factory{}
+
+// Register makes a strategy available by name
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// New creates and initializes a registered strategy
+func New(name string, config map[string]interface{}) (interfaces.StrategyExecutor, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy: %s (available: %v)", name, Names())
+	}
+
+	strategy := factory()
+	if err := strategy.Initialize(config); err != nil {
+		return nil, fmt.Errorf("failed to initialize strategy %s: %w", name, err)
+	}
+
+	return strategy, nil
+}
+
+// Names returns the registered strategy names in sorted order
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
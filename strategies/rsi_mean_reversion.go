@@ -0,0 +1,133 @@
+package strategies
+
+import (
+	"context"
+	"fmt"
+	"prophet-trader/interfaces"
+	"prophet-trader/services"
+)
+
+func init() {
+	Register("rsi_mean_reversion", func() interfaces.StrategyExecutor { return &RSIMeanReversion{} })
+}
+
+// RSIMeanReversion buys oversold dips and sells into the bounce.
+//
+// Parameters (config keys):
+//   - rsi_period: RSI lookback in bars (default 14)
+//   - oversold: buy when RSI falls below this (default 30)
+//   - exit_rsi: sell when RSI rises above this (default 50)
+//   - trend_period: only buy above this simple moving average, 0 disables
+//     the filter (default 0); needs at least that many bars of lookback
+//   - stop_loss_pct: sell when price falls this far below entry, 0
+//     disables the stop (default 10)
+//   - max_hold_bars: sell after holding this many bars, 0 holds until
+//     another exit fires (default 20)
+type RSIMeanReversion struct {
+	rsiPeriod   int
+	oversold    float64
+	exitRSI     float64
+	trendPeriod int
+	stopLossPct float64
+	maxHoldBars int
+
+	holding    bool
+	entryPrice float64
+	barsHeld   int
+}
+
+// Initialize implements interfaces.StrategyExecutor
+func (s *RSIMeanReversion) Initialize(config map[string]interface{}) error {
+	var err error
+	if s.rsiPeriod, err = intParam(config, "rsi_period", 14); err != nil {
+		return err
+	}
+	if s.oversold, err = floatParam(config, "oversold", 30); err != nil {
+		return err
+	}
+	if s.exitRSI, err = floatParam(config, "exit_rsi", 50); err != nil {
+		return err
+	}
+	if s.trendPeriod, err = intParam(config, "trend_period", 0); err != nil {
+		return err
+	}
+	if s.stopLossPct, err = floatParam(config, "stop_loss_pct", 10); err != nil {
+		return err
+	}
+	if s.maxHoldBars, err = intParam(config, "max_hold_bars", 20); err != nil {
+		return err
+	}
+
+	if s.rsiPeriod < 2 {
+		return fmt.Errorf("rsi_period must be at least 2")
+	}
+	if s.oversold <= 0 || s.oversold >= s.exitRSI || s.exitRSI >= 100 {
+		return fmt.Errorf("need 0 < oversold < exit_rsi < 100")
+	}
+	if s.trendPeriod < 0 || s.stopLossPct < 0 || s.maxHoldBars < 0 {
+		return fmt.Errorf("trend_period, stop_loss_pct and max_hold_bars cannot be negative")
+	}
+
+	s.holding = false
+	s.entryPrice = 0
+	s.barsHeld = 0
+	return nil
+}
+
+// ShouldBuy buys when RSI is oversold, and above the trend average when
+// the filter is on
+func (s *RSIMeanReversion) ShouldBuy(ctx context.Context, symbol string, data *interfaces.MarketData) (bool, *interfaces.OrderRequest) {
+	if s.holding || len(data.RecentBars) < s.rsiPeriod+1 {
+		return false, nil
+	}
+	if services.CalculateRSI(data.RecentBars, s.rsiPeriod) >= s.oversold {
+		return false, nil
+	}
+	if s.trendPeriod > 0 {
+		sma := services.CalculateSMA(data.RecentBars, s.trendPeriod)
+		if sma == 0 || data.CurrentBar.Close < sma {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ShouldSell exits on the RSI recovery, the stop or the holding limit
+func (s *RSIMeanReversion) ShouldSell(ctx context.Context, symbol string, data *interfaces.MarketData) (bool, *interfaces.OrderRequest) {
+	if !s.holding {
+		return false, nil
+	}
+	if s.stopLossPct > 0 && data.CurrentBar.Close <= s.entryPrice*(1-s.stopLossPct/100) {
+		return true, nil
+	}
+	if s.maxHoldBars > 0 && s.barsHeld >= s.maxHoldBars {
+		return true, nil
+	}
+	return services.CalculateRSI(data.RecentBars, s.rsiPeriod) > s.exitRSI, nil
+}
+
+// OnOrderFilled tracks the open position
+func (s *RSIMeanReversion) OnOrderFilled(order *interfaces.Order) {
+	if order.Side == "buy" {
+		s.holding = true
+		s.barsHeld = 0
+		if order.FilledAvgPrice != nil {
+			s.entryPrice = order.FilledAvgPrice.InexactFloat64()
+		}
+		return
+	}
+	s.holding = false
+	s.entryPrice = 0
+}
+
+// OnMarketData counts the bars the position has been held
+func (s *RSIMeanReversion) OnMarketData(data *interfaces.MarketData) {
+	if s.holding {
+		s.barsHeld++
+	}
+}
+
+// GetName implements interfaces.StrategyExecutor
+func (s *RSIMeanReversion) GetName() string {
+	return "rsi_mean_reversion"
+}
@@ -0,0 +1,20 @@
+// Refreshes server-rendered dashboard sections. Each element with a
+// data-refresh URL is replaced with the fragment served at that URL every
+// data-interval seconds. No build step is needed.
+document.querySelectorAll('[data-refresh]').forEach((el) => {
+  const seconds = parseInt(el.dataset.interval, 10) || 30;
+
+  setInterval(async () => {
+    if (document.hidden) return;
+    try {
+      const resp = await fetch(el.dataset.refresh, { credentials: 'same-origin' });
+      if (resp.status === 401) {
+        window.location = '/dashboard/login';
+        return;
+      }
+      if (resp.ok) el.innerHTML = await resp.text();
+    } catch (err) {
+      // Keep showing the last rendered content while the bot is unreachable
+    }
+  }, seconds * 1000);
+});
@@ -0,0 +1,11 @@
+// Package web holds the dashboard templates and static assets. They are
+// embedded so the binary serves the dashboard without any files on disk;
+// set DASHBOARD_DIR to this directory to edit them without rebuilding.
+package web
+
+import "embed"
+
+// Assets contains templates/*.html and static/*
+//
+//go:embed templates static
+var Assets embed.FS